@@ -379,6 +379,7 @@ copy-templates:
 	# external istiod config cluster charts
 	cp manifests/charts/istio-control/istio-discovery/files/injection-template.yaml manifests/charts/istiod-remote/files
 	cp manifests/charts/istio-control/istio-discovery/files/gateway-injection-template.yaml manifests/charts/istiod-remote/files
+	cp manifests/charts/istio-control/istio-discovery/files/windows-injection-template.yaml manifests/charts/istiod-remote/files
 	cp manifests/charts/istio-control/istio-discovery/templates/istiod-injector-configmap.yaml manifests/charts/istiod-remote/templates
 	cp manifests/charts/istio-control/istio-discovery/templates/configmap.yaml manifests/charts/istiod-remote/templates
 	cp manifests/charts/istio-control/istio-discovery/templates/telemetryv2_*.yaml manifests/charts/istiod-remote/templates
@@ -424,6 +425,13 @@ ${ISTIO_OUT}/release/istioctl-osx-arm64: depend
 ${ISTIO_OUT}/release/istioctl-win.exe: depend
 	GOOS=windows LDFLAGS=$(RELEASE_LDFLAGS) common/scripts/gobuild.sh $@ ./istioctl/cmd/istioctl
 
+# The agent of the sidecars of Windows pods.
+${ISTIO_OUT}/release/pilot-agent-win.exe: depend
+	GOOS=windows GOARCH=amd64 LDFLAGS=$(RELEASE_LDFLAGS) common/scripts/gobuild.sh $@ -tags=agent ./pilot/cmd/pilot-agent
+
+.PHONY: build-windows
+build-windows: ${ISTIO_OUT}/release/pilot-agent-win.exe ## Builds the agent for Windows nodes.
+
 # generate the istioctl completion files
 ${ISTIO_OUT}/release/istioctl.bash: ${LOCAL_OUT}/istioctl
 	${LOCAL_OUT}/istioctl completion bash > ${ISTIO_OUT}/release/istioctl.bash
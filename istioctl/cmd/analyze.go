@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -74,6 +75,8 @@ var (
 	analysisTimeout   time.Duration
 	recursive         bool
 	ignoreUnknown     bool
+	analysisWatch     bool
+	watchTimeout      time.Duration
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -102,6 +105,9 @@ func Analyze() *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze the current live cluster again on every change of its resources, until interrupted
+  istioctl analyze --watch
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -118,6 +124,12 @@ func Analyze() *cobra.Command {
 				return nil
 			}
 
+			if analysisWatch && (!useKube || msgOutputFormat != formatting.LogFormat) {
+				return CommandParseError{
+					fmt.Errorf("--watch requires a live cluster (--use-kube) and the %s output format", formatting.LogFormat),
+				}
+			}
+
 			readers, err := gatherFiles(cmd, args)
 			if err != nil {
 				return err
@@ -214,6 +226,10 @@ func Analyze() *cobra.Command {
 				}
 			}
 
+			if analysisWatch {
+				return watchAnalysis(cmd, sa, cancel)
+			}
+
 			// Do the analysis
 			result, err := sa.Analyze(cancel)
 			if err != nil {
@@ -316,6 +332,11 @@ func Analyze() *cobra.Command {
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
 	analysisCmd.PersistentFlags().BoolVar(&ignoreUnknown, "ignore-unknown", false,
 		"Don't complain about un-parseable input documents, for cases where analyze should run only on k8s compliant inputs.")
+	analysisCmd.PersistentFlags().BoolVar(&analysisWatch, "watch", false,
+		"Keep watching the live cluster, analyzing it again when its resources change and printing the messages "+
+			"which appear or are resolved. The exit code is based on the messages left when watching stops.")
+	analysisCmd.PersistentFlags().DurationVar(&watchTimeout, "watch-timeout", 0,
+		"The duration to watch for with --watch. If 0, watch until interrupted.")
 	return analysisCmd
 }
 
@@ -401,6 +422,100 @@ func gatherFilesInDirectory(cmd *cobra.Command, dir string) ([]local.ReaderSourc
 	return readers, err
 }
 
+// watchAnalysisDebounce is the delay to wait for more changes before analyzing again, so that a burst of
+// changes, like the initial listing of the resources, is analyzed once.
+const watchAnalysisDebounce = time.Second
+
+// watchAnalysis analyzes the sources again on every change, printing the messages which appeared or were resolved
+// since the previous analysis, until interrupted or the watch timeout expires. Analyzers look across resources, so
+// all of them run again, but against the resources already cached from the live cluster.
+func watchAnalysis(cmd *cobra.Command, sa *local.IstiodAnalyzer, cancel chan struct{}) error {
+	changes := make(chan struct{}, 1)
+	sa.RegisterEventHandler(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	if err := sa.Init(cancel); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	var timeout <-chan time.Time
+	if watchTimeout > 0 {
+		timeout = time.After(watchTimeout)
+	}
+
+	var current diag.Messages
+	for {
+		result, err := sa.ReAnalyze(cancel)
+		if err != nil {
+			return err
+		}
+		messages := *result.Messages.SetDocRef("istioctl-analyze")
+		appeared, resolved := analysisChanges(current, messages)
+		current = messages
+		if err := printAnalysisChanges(cmd, appeared, resolved); err != nil {
+			return err
+		}
+
+		select {
+		case <-changes:
+			select {
+			case <-time.After(watchAnalysisDebounce):
+			case <-signals:
+				return errorIfMessagesExceedThreshold(current)
+			}
+		case <-signals:
+			return errorIfMessagesExceedThreshold(current)
+		case <-timeout:
+			return errorIfMessagesExceedThreshold(current)
+		}
+	}
+}
+
+// analysisChanges returns the messages of the current analysis missing from the previous one, and the messages of
+// the previous analysis missing from the current one.
+func analysisChanges(previous, current diag.Messages) (appeared, resolved diag.Messages) {
+	previousKeys := make(map[string]struct{}, len(previous))
+	for i := range previous {
+		previousKeys[previous[i].String()] = struct{}{}
+	}
+	currentKeys := make(map[string]struct{}, len(current))
+	for i := range current {
+		key := current[i].String()
+		currentKeys[key] = struct{}{}
+		if _, f := previousKeys[key]; !f {
+			appeared = append(appeared, current[i])
+		}
+	}
+	for i := range previous {
+		if _, f := currentKeys[previous[i].String()]; !f {
+			resolved = append(resolved, previous[i])
+		}
+	}
+	return appeared, resolved
+}
+
+func printAnalysisChanges(cmd *cobra.Command, appeared, resolved diag.Messages) error {
+	appeared = appeared.FilterOutLowerThan(outputThreshold.Level)
+	resolved = resolved.FilterOutLowerThan(outputThreshold.Level)
+	if len(appeared) > 0 {
+		output, err := formatting.Print(appeared, formatting.LogFormat, colorize)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), output)
+	}
+	for i := range resolved {
+		fmt.Fprintf(cmd.OutOrStdout(), "Resolved: %s\n", resolved[i].String())
+	}
+	return nil
+}
+
 func errorIfMessagesExceedThreshold(messages []diag.Message) error {
 	foundIssues := false
 	for _, m := range messages {
@@ -63,3 +63,23 @@ func TestNoErrorIfMessageLevelsBelowThreshold(t *testing.T) {
 
 	g.Expect(err).To(BeNil())
 }
+
+func TestAnalysisChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	kept := diag.NewMessage(diag.NewMessageType(diag.Warning, "A1", "Template: %q"), nil, "kept")
+	fixed := diag.NewMessage(diag.NewMessageType(diag.Error, "B1", "Template: %q"), nil, "fixed")
+	added := diag.NewMessage(diag.NewMessageType(diag.Error, "B1", "Template: %q"), nil, "added")
+
+	appeared, resolved := analysisChanges(nil, diag.Messages{kept, fixed})
+	g.Expect(appeared).To(Equal(diag.Messages{kept, fixed}))
+	g.Expect(resolved).To(BeEmpty())
+
+	appeared, resolved = analysisChanges(diag.Messages{kept, fixed}, diag.Messages{added, kept})
+	g.Expect(appeared).To(Equal(diag.Messages{added}))
+	g.Expect(resolved).To(Equal(diag.Messages{fixed}))
+
+	appeared, resolved = analysisChanges(diag.Messages{added, kept}, diag.Messages{added, kept})
+	g.Expect(appeared).To(BeEmpty())
+	g.Expect(resolved).To(BeEmpty())
+}
@@ -16,11 +16,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/istioctl/pkg/authz"
 	"istio.io/istio/istioctl/pkg/util/configdump"
@@ -46,6 +48,9 @@ The command also supports reading from a standalone config dump file with flag -
   # Check AuthorizationPolicy applied to one pod under a deployment
   istioctl x authz check deployment/productpage-v1
 
+  # Check AuthorizationPolicy applied to pod httpbin-88ddbcfdd-nt5jb as JSON:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb -o json
+
   # Check AuthorizationPolicy from Envoy config dump file:
   istioctl x authz check -f httpbin_config_dump.json`,
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -86,7 +91,23 @@ The command also supports reading from a standalone config dump file with flag -
 		if err != nil {
 			return err
 		}
-		analyzer.Print(cmd.OutOrStdout())
+		switch outputFormat {
+		case jsonOutput, yamlOutput:
+			out, err := json.MarshalIndent(analyzer.Policies(), "", "  ")
+			if err != nil {
+				return err
+			}
+			if outputFormat == yamlOutput {
+				if out, err = yaml.JSONToYAML(out); err != nil {
+					return err
+				}
+			} else {
+				out = append(out, '\n')
+			}
+			_, _ = cmd.OutOrStdout().Write(out)
+		default:
+			analyzer.Print(cmd.OutOrStdout())
+		}
 		return nil
 	},
 }
@@ -156,4 +177,6 @@ func AuthZ() *cobra.Command {
 func init() {
 	checkCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"The json file with Envoy config dump to be checked")
+	checkCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput,
+		"Output format: one of json|yaml|short")
 }
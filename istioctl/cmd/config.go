@@ -43,12 +43,17 @@ var settableFlags = map[string]interface{}{
 func configCmd() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "config SUBCOMMAND",
-		Short: "Configure istioctl defaults",
+		Short: "Configure istioctl defaults, and export or import the Istio configuration",
 		Args:  cobra.NoArgs,
 		Example: `  # list configuration parameters
-  istioctl config list`,
+  istioctl config list
+
+  # export the Istio configuration of all namespaces
+  istioctl x config export --all -o mesh-snapshot.tar.gz`,
 	}
 	configCmd.AddCommand(listCommand())
+	configCmd.AddCommand(configExportCommand())
+	configCmd.AddCommand(configImportCommand())
 	return configCmd
 }
 
@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/snapshot"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/pkg/version"
+)
+
+// snapshotMeshConfigMapName returns the name of the mesh config ConfigMap of the revision.
+func snapshotMeshConfigMapName() string {
+	if revision != "" {
+		return fmt.Sprintf("%s-%s", defaultMeshConfigMapName, revision)
+	}
+	return defaultMeshConfigMapName
+}
+
+func configExportCommand() *cobra.Command {
+	var all bool
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports the Istio configuration and the mesh config into an archive",
+		Long: `
+Exports the Istio configuration of the cluster, along with the mesh config, into a versioned archive which
+can be imported with 'istioctl x config import'. The archive is a gzipped tarball holding a manifest, the mesh
+config as set in its ConfigMap and with the defaults applied, and a YAML file per resource. The fields set by
+the cluster, such as the resource version and the status, are not exported.
+`,
+		Example: `  # Export the Istio configuration of all namespaces
+  istioctl x config export --all -o mesh-snapshot.tar.gz
+
+  # Export the Istio configuration of a namespace
+  istioctl x config export -n bookinfo -o bookinfo.tar.gz`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("export takes no arguments")
+			}
+			if all == (namespace != "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("export requires either --all or a --namespace")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			client, err := newKubeClientWithRevision(kubeconfig, configContext, revision)
+			if err != nil {
+				return err
+			}
+			s, err := snapshot.Export(context.Background(), client.Dynamic(), client.Kube(), snapshot.ExportOptions{
+				Schemas:        collections.PilotGatewayAPI,
+				Namespace:      namespace,
+				IstioNamespace: istioNamespace,
+				MeshConfigMap:  snapshotMeshConfigMapName(),
+				Version:        version.Info.Version,
+			})
+			if err != nil {
+				return err
+			}
+			out := c.OutOrStdout()
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			if err := snapshot.Write(out, s); err != nil {
+				return err
+			}
+			if output != "-" {
+				fmt.Fprintf(c.OutOrStdout(), "Exported %d resources and the mesh config to %s\n", s.Manifest.Resources, output)
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().BoolVar(&all, "all", false, "Export the configuration of all namespaces")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", "-", "File the archive is written to, or - for the standard output")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+func configImportCommand() *cobra.Command {
+	var file, strategy string
+	opts := snapshot.ImportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Imports an archive exported by 'istioctl x config export'",
+		Long: `
+Imports an archive exported by 'istioctl x config export' into the cluster. The resources missing from the
+cluster are created, and the ones which exist with the same content are left unchanged. The resources which
+exist with a different content are handled according to --conflict-strategy:
+
+  - skip: the existing resources are kept
+  - overwrite: the existing resources are replaced with the ones of the archive
+  - fail: nothing is imported if any resource conflicts
+
+The mesh config is only imported with --mesh-config, into the mesh config ConfigMap of the revision, and is
+subject to the same conflict strategy.
+`,
+		Example: `  # Check what importing an archive would change
+  istioctl x config import -f mesh-snapshot.tar.gz --dry-run
+
+  # Restore the configuration and the mesh config of a cluster
+  istioctl x config import -f mesh-snapshot.tar.gz --conflict-strategy overwrite --mesh-config`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 || file == "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("import requires a --file parameter and no arguments")
+			}
+			switch snapshot.ConflictStrategy(strategy) {
+			case snapshot.ConflictSkip, snapshot.ConflictOverwrite, snapshot.ConflictFail:
+			default:
+				return fmt.Errorf("unknown conflict strategy %q, expected skip, overwrite or fail", strategy)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			var in io.Reader = os.Stdin
+			if file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			s, err := snapshot.Read(in)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", file, err)
+			}
+			client, err := newKubeClientWithRevision(kubeconfig, configContext, revision)
+			if err != nil {
+				return err
+			}
+			opts.Strategy = snapshot.ConflictStrategy(strategy)
+			opts.IstioNamespace = istioNamespace
+			opts.MeshConfigMap = snapshotMeshConfigMapName()
+			results, err := snapshot.Import(context.Background(), client.Dynamic(), client.Kube(), s, collections.PilotGatewayAPI, opts)
+			if results != nil {
+				if perr := printSnapshotImport(c.OutOrStdout(), results, opts.DryRun); perr != nil {
+					return perr
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "", "Archive to import, or - for the standard input")
+	cmd.PersistentFlags().StringVar(&strategy, "conflict-strategy", string(snapshot.ConflictSkip),
+		"How the resources which exist with a different content are handled: skip, overwrite or fail")
+	cmd.PersistentFlags().BoolVar(&opts.MeshConfig, "mesh-config", false, "Also import the mesh config")
+	cmd.PersistentFlags().BoolVar(&opts.DryRun, "dry-run", false, "Only report what would be imported")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+func printSnapshotImport(writer io.Writer, results []snapshot.ImportedResource, dryRun bool) error {
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 5, ' ', 0)
+	fmt.Fprintf(w, "RESOURCE\tACTION\tERROR\n")
+	for _, r := range results {
+		errMsg := ""
+		if r.Error != nil {
+			errMsg = r.Error.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Resource, r.Action, errMsg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Fprintln(writer, "Dry run: no resource was written")
+	}
+	return nil
+}
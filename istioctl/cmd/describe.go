@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
@@ -36,6 +37,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s_labels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/networking/v1alpha3"
@@ -80,6 +82,105 @@ var (
 	ignoreUnmeshed = false
 )
 
+// describeReport is what `describe pod` and `describe service` print with --output json|yaml.
+type describeReport struct {
+	Pod                *podSummary                `json:"pod,omitempty"`
+	Services           []serviceSummary           `json:"services,omitempty"`
+	PeerAuthentication *peerAuthenticationSummary `json:"peerAuthentication,omitempty"`
+	Ingress            []ingressSummary           `json:"ingress,omitempty"`
+	Warnings           []string                   `json:"warnings,omitempty"`
+}
+
+type podSummary struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Ports     []string `json:"ports,omitempty"`
+	Meshed    bool     `json:"meshed"`
+}
+
+type serviceSummary struct {
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	Ports     []servicePortSummary `json:"ports,omitempty"`
+}
+
+type servicePortSummary struct {
+	Name            string                  `json:"name,omitempty"`
+	Port            int32                   `json:"port"`
+	Protocol        string                  `json:"protocol"`
+	DestinationRule *destinationRuleSummary `json:"destinationRule,omitempty"`
+	VirtualService  *virtualServiceSummary  `json:"virtualService,omitempty"`
+	RBACPolicies    []string                `json:"rbacPolicies,omitempty"`
+}
+
+type destinationRuleSummary struct {
+	Name               string   `json:"name"`
+	Namespace          string   `json:"namespace"`
+	Host               string   `json:"host"`
+	MatchingSubsets    []string `json:"matchingSubsets,omitempty"`
+	NonMatchingSubsets []string `json:"nonMatchingSubsets,omitempty"`
+	TLSMode            string   `json:"tlsMode,omitempty"`
+}
+
+type virtualServiceSummary struct {
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	Routes            []string `json:"routes,omitempty"`
+	NonMatchingRoutes []string `json:"nonMatchingRoutes,omitempty"`
+}
+
+type peerAuthenticationSummary struct {
+	Mode      string            `json:"mode"`
+	PortModes map[uint32]string `json:"portModes,omitempty"`
+	Applied   []string          `json:"applied,omitempty"`
+}
+
+type ingressSummary struct {
+	Service        string                 `json:"service"`
+	Port           int32                  `json:"port"`
+	URLs           []string               `json:"urls,omitempty"`
+	VirtualService *virtualServiceSummary `json:"virtualService"`
+}
+
+// warnf prints a warning line and keeps it for the structured output.
+func (r *describeReport) warnf(writer io.Writer, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprint(writer, msg)
+	r.Warnings = append(r.Warnings, strings.TrimSpace(msg))
+}
+
+// describeWriter returns where the human readable description goes: nowhere when
+// the report is printed as json or yaml instead.
+func describeWriter(cmd *cobra.Command) (io.Writer, error) {
+	switch outputFormat {
+	case jsonOutput, yamlOutput:
+		return io.Discard, nil
+	case summaryOutput:
+		return cmd.OutOrStdout(), nil
+	default:
+		return nil, fmt.Errorf("output format %q not supported", outputFormat)
+	}
+}
+
+func printDescribeReport(writer io.Writer, report *describeReport) error {
+	if outputFormat != jsonOutput && outputFormat != yamlOutput {
+		return nil
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if outputFormat == yamlOutput {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+	} else {
+		out = append(out, '\n')
+	}
+	_, err = writer.Write(out)
+	return err
+}
+
 func podDescribeCmd() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
 	cmd := &cobra.Command{
@@ -88,7 +189,10 @@ func podDescribeCmd() *cobra.Command {
 		Short:   "Describe pods and their Istio configuration [kube-only]",
 		Long: `Analyzes pod, its Services, DestinationRules, and VirtualServices and reports
 the configuration objects that affect that pod.`,
-		Example: `  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4`,
+		Example: `  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4
+
+  # Print the description as json
+  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4 -o json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return fmt.Errorf("expecting pod name")
@@ -96,79 +200,91 @@ the configuration objects that affect that pod.`,
 
 			podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
 
-			client, err := interfaceFactory(kubeconfig)
+			writer, err := describeWriter(cmd)
 			if err != nil {
 				return err
 			}
-			pod, err := client.CoreV1().Pods(ns).Get(context.TODO(), podName, metav1.GetOptions{})
-			if err != nil {
+			report := &describeReport{}
+			if err := describePod(writer, report, podName, ns, opts.Revision); err != nil {
 				return err
 			}
+			return printDescribeReport(cmd.OutOrStdout(), report)
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
 
-			writer := cmd.OutOrStdout()
-
-			podLabels := k8s_labels.Set(pod.ObjectMeta.Labels)
+	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
+		"Suppress warnings for unmeshed pods")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
 
-			printPod(writer, pod)
+// describePod prints the description of a pod and fills the report with it.
+func describePod(writer io.Writer, report *describeReport, podName, ns, revision string) error {
+	client, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return err
+	}
+	pod, err := client.CoreV1().Pods(ns).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
 
-			svcs, err := client.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				return err
-			}
+	podLabels := k8s_labels.Set(pod.ObjectMeta.Labels)
 
-			matchingServices := make([]v1.Service, 0, len(svcs.Items))
-			for _, svc := range svcs.Items {
-				if len(svc.Spec.Selector) > 0 {
-					svcSelector := k8s_labels.SelectorFromSet(svc.Spec.Selector)
-					if svcSelector.Matches(podLabels) {
-						matchingServices = append(matchingServices, svc)
-					}
-				}
-			}
-			// Validate Istio's "Service association" requirement
-			if len(matchingServices) == 0 && !ignoreUnmeshed {
-				fmt.Fprintf(cmd.OutOrStdout(),
-					"Warning: No Kubernetes Services select pod %s (see https://istio.io/docs/setup/kubernetes/additional-setup/requirements/ )\n", // nolint: lll
-					kname(pod.ObjectMeta))
-			}
-			// TODO look for port collisions between services targeting this pod
+	printPod(writer, report, pod)
 
-			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
-			if err != nil {
-				return err
-			}
+	svcs, err := client.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
 
-			var configClient istioclient.Interface
-			if configClient, err = configStoreFactory(); err != nil {
-				return err
+	matchingServices := make([]v1.Service, 0, len(svcs.Items))
+	for _, svc := range svcs.Items {
+		if len(svc.Spec.Selector) > 0 {
+			svcSelector := k8s_labels.SelectorFromSet(svc.Spec.Selector)
+			if svcSelector.Matches(podLabels) {
+				matchingServices = append(matchingServices, svc)
 			}
+		}
+	}
+	// Validate Istio's "Service association" requirement
+	if len(matchingServices) == 0 && !ignoreUnmeshed {
+		report.warnf(writer,
+			"Warning: No Kubernetes Services select pod %s (see https://istio.io/docs/setup/kubernetes/additional-setup/requirements/ )\n", // nolint: lll
+			kname(pod.ObjectMeta))
+	}
+	// TODO look for port collisions between services targeting this pod
 
-			podsLabels := []k8s_labels.Set{k8s_labels.Set(pod.ObjectMeta.Labels)}
-			fmt.Fprintf(writer, "--------------------\n")
-			err = describePodServices(writer, kubeClient, configClient, pod, matchingServices, podsLabels)
-			if err != nil {
-				return err
-			}
+	kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, revision)
+	if err != nil {
+		return err
+	}
 
-			// render PeerAuthentication info
-			fmt.Fprintf(writer, "--------------------\n")
-			err = describePeerAuthentication(writer, kubeClient, configClient, ns, k8s_labels.Set(pod.ObjectMeta.Labels))
-			if err != nil {
-				return err
-			}
+	var configClient istioclient.Interface
+	if configClient, err = configStoreFactory(); err != nil {
+		return err
+	}
 
-			// TODO find sidecar configs that select this workload and render them
+	podsLabels := []k8s_labels.Set{k8s_labels.Set(pod.ObjectMeta.Labels)}
+	fmt.Fprintf(writer, "--------------------\n")
+	err = describePodServices(writer, report, kubeClient, configClient, pod, matchingServices, podsLabels)
+	if err != nil {
+		return err
+	}
 
-			// Now look for ingress gateways
-			return printIngressInfo(writer, matchingServices, podsLabels, client, configClient, kubeClient)
-		},
-		ValidArgsFunction: validPodsNameArgs,
+	// render PeerAuthentication info
+	fmt.Fprintf(writer, "--------------------\n")
+	err = describePeerAuthentication(writer, report, kubeClient, configClient, ns, k8s_labels.Set(pod.ObjectMeta.Labels))
+	if err != nil {
+		return err
 	}
 
-	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
-		"Suppress warnings for unmeshed pods")
-	cmd.Long += "\n\n" + ExperimentalMsg
-	return cmd
+	// TODO find sidecar configs that select this workload and render them
+
+	// Now look for ingress gateways
+	return printIngressInfo(writer, report, matchingServices, podsLabels, client, configClient, kubeClient)
 }
 
 func describe() *cobra.Command {
@@ -229,14 +345,22 @@ func matchesAnyPod(subsetSelector k8s_labels.Selector, podsLabels []k8s_labels.S
 	return false
 }
 
-func printDestinationRule(writer io.Writer, dr *clientnetworking.DestinationRule, podsLabels []k8s_labels.Set) {
+func printDestinationRule(writer io.Writer, report *describeReport, dr *clientnetworking.DestinationRule,
+	podsLabels []k8s_labels.Set) *destinationRuleSummary {
 	fmt.Fprintf(writer, "DestinationRule: %s for %q\n", kname(dr.ObjectMeta), dr.Spec.Host)
 
 	matchingSubsets, nonmatchingSubsets := getDestRuleSubsets(dr.Spec.Subsets, podsLabels)
+	summary := &destinationRuleSummary{
+		Name:               dr.Name,
+		Namespace:          dr.Namespace,
+		Host:               dr.Spec.Host,
+		MatchingSubsets:    matchingSubsets,
+		NonMatchingSubsets: nonmatchingSubsets,
+	}
 
 	if len(matchingSubsets) != 0 || len(nonmatchingSubsets) != 0 {
 		if len(matchingSubsets) == 0 {
-			fmt.Fprintf(writer, "  WARNING POD DOES NOT MATCH ANY SUBSETS.  (Non matching subsets %s)\n",
+			report.warnf(writer, "  WARNING POD DOES NOT MATCH ANY SUBSETS.  (Non matching subsets %s)\n",
 				strings.Join(nonmatchingSubsets, ","))
 		}
 		fmt.Fprintf(writer, "   Matching subsets: %s\n", strings.Join(matchingSubsets, ","))
@@ -251,7 +375,8 @@ func printDestinationRule(writer io.Writer, dr *clientnetworking.DestinationRule
 		fmt.Fprintf(writer, "   No Traffic Policy\n")
 	} else {
 		if trafficPolicy.Tls != nil {
-			fmt.Fprintf(writer, "   Traffic Policy TLS Mode: %s\n", dr.Spec.TrafficPolicy.Tls.Mode.String())
+			summary.TLSMode = dr.Spec.TrafficPolicy.Tls.Mode.String()
+			fmt.Fprintf(writer, "   Traffic Policy TLS Mode: %s\n", summary.TLSMode)
 		}
 		extra := []string{}
 		if trafficPolicy.LoadBalancer != nil {
@@ -270,6 +395,7 @@ func printDestinationRule(writer io.Writer, dr *clientnetworking.DestinationRule
 			fmt.Fprintf(writer, "   %s\n", strings.Join(extra, "/"))
 		}
 	}
+	return summary
 }
 
 // httpRouteMatchSvc returns true if it matches and a slice of facts about the match
@@ -405,7 +531,7 @@ func renderMatch(match *v1alpha3.HTTPMatchRequest) string {
 	return strings.TrimSpace(retval)
 }
 
-func printPod(writer io.Writer, pod *v1.Pod) {
+func printPod(writer io.Writer, report *describeReport, pod *v1.Pod) {
 	ports := []string{}
 	UserID := int64(1337)
 	for _, container := range pod.Spec.Containers {
@@ -421,12 +547,18 @@ func printPod(writer io.Writer, pod *v1.Pod) {
 		if container.Name != "istio-proxy" && container.Name != "istio-operator" {
 			if container.SecurityContext != nil && container.SecurityContext.RunAsUser != nil {
 				if *container.SecurityContext.RunAsUser == UserID {
-					fmt.Fprintf(writer, "WARNING: User ID (UID) 1337 is reserved for the sidecar proxy.\n")
+					report.warnf(writer, "WARNING: User ID (UID) 1337 is reserved for the sidecar proxy.\n")
 				}
 			}
 		}
 	}
 
+	report.Pod = &podSummary{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Ports:     ports,
+		Meshed:    isMeshed(pod),
+	}
 	fmt.Fprintf(writer, "Pod: %s\n", kname(pod.ObjectMeta))
 	if len(ports) > 0 {
 		fmt.Fprintf(writer, "   Pod Ports: %s\n", strings.Join(ports, ", "))
@@ -435,13 +567,13 @@ func printPod(writer io.Writer, pod *v1.Pod) {
 	}
 
 	if pod.Status.Phase != v1.PodRunning {
-		fmt.Printf("   Pod is not %s (%s)\n", v1.PodRunning, pod.Status.Phase)
+		report.warnf(writer, "   Pod is not %s (%s)\n", v1.PodRunning, pod.Status.Phase)
 		return
 	}
 
 	for _, containerStatus := range pod.Status.ContainerStatuses {
 		if !containerStatus.Ready {
-			fmt.Fprintf(writer, "WARNING: Pod %s Container %s NOT READY\n", kname(pod.ObjectMeta), containerStatus.Name)
+			report.warnf(writer, "WARNING: Pod %s Container %s NOT READY\n", kname(pod.ObjectMeta), containerStatus.Name)
 		}
 	}
 
@@ -449,15 +581,15 @@ func printPod(writer io.Writer, pod *v1.Pod) {
 		return
 	}
 
-	if !isMeshed(pod) {
-		fmt.Fprintf(writer, "WARNING: %s is not part of mesh; no Istio sidecar\n", kname(pod.ObjectMeta))
+	if !report.Pod.Meshed {
+		report.warnf(writer, "WARNING: %s is not part of mesh; no Istio sidecar\n", kname(pod.ObjectMeta))
 		return
 	}
 
 	// Ref: https://istio.io/latest/docs/ops/deployment/requirements/#pod-requirements
 	if pod.Spec.SecurityContext != nil && pod.Spec.SecurityContext.RunAsUser != nil {
 		if *pod.Spec.SecurityContext.RunAsUser == UserID {
-			fmt.Fprintf(writer, "   WARNING: User ID (UID) 1337 is reserved for the sidecar proxy.\n")
+			report.warnf(writer, "   WARNING: User ID (UID) 1337 is reserved for the sidecar proxy.\n")
 		}
 	}
 
@@ -465,11 +597,11 @@ func printPod(writer io.Writer, pod *v1.Pod) {
 	// says "We recommend adding an explicit app label and version label to deployments."
 	app, ok := pod.ObjectMeta.Labels["app"]
 	if !ok || app == "" {
-		fmt.Fprintf(writer, "Suggestion: add 'app' label to pod for Istio telemetry.\n")
+		report.warnf(writer, "Suggestion: add 'app' label to pod for Istio telemetry.\n")
 	}
 	version, ok := pod.ObjectMeta.Labels["version"]
 	if !ok || version == "" {
-		fmt.Fprintf(writer, "Suggestion: add 'version' label to pod for Istio telemetry.\n")
+		report.warnf(writer, "Suggestion: add 'version' label to pod for Istio telemetry.\n")
 	}
 }
 
@@ -788,8 +920,12 @@ func getIstioDestinationRulePathForSvc(cd *configdump.Wrapper, svc v1.Service, p
 
 // TODO simplify this by showing for each matching Destination the negation of the previous HttpMatchRequest
 // and showing the non-matching Destinations.  (The current code is ad-hoc, and usually shows most of that information.)
-func printVirtualService(writer io.Writer, vs clientnetworking.VirtualService, svc v1.Service, matchingSubsets []string, nonmatchingSubsets []string, dr *clientnetworking.DestinationRule) { // nolint: lll
+func printVirtualService(writer io.Writer, report *describeReport, vs clientnetworking.VirtualService, svc v1.Service, matchingSubsets []string, nonmatchingSubsets []string, dr *clientnetworking.DestinationRule) *virtualServiceSummary { // nolint: lll
 	fmt.Fprintf(writer, "VirtualService: %s\n", kname(vs.ObjectMeta))
+	summary := &virtualServiceSummary{
+		Name:      vs.Name,
+		Namespace: vs.Namespace,
+	}
 
 	// There is no point in checking that 'port' uses HTTP (for HTTP route matches)
 	// or uses TCP (for TCP route matches) because if the port has the wrong name
@@ -806,6 +942,7 @@ func printVirtualService(writer io.Writer, vs clientnetworking.VirtualService, s
 				fmt.Fprintf(writer, "   %s\n", newfact)
 				facts++
 			}
+			summary.Routes = append(summary.Routes, newfacts...)
 		} else {
 			mismatchNotes = append(mismatchNotes, newfacts...)
 		}
@@ -821,22 +958,25 @@ func printVirtualService(writer io.Writer, vs clientnetworking.VirtualService, s
 				fmt.Fprintf(writer, "   %s\n", newfact)
 				facts++
 			}
+			summary.Routes = append(summary.Routes, newfacts...)
 		} else {
 			mismatchNotes = append(mismatchNotes, newfacts...)
 		}
 	}
 
+	summary.NonMatchingRoutes = mismatchNotes
+
 	if matches == 0 {
 		if len(vs.Spec.Http) > 0 {
-			fmt.Fprintf(writer, "   WARNING: No destinations match pod subsets (checked %d HTTP routes)\n", len(vs.Spec.Http))
+			report.warnf(writer, "   WARNING: No destinations match pod subsets (checked %d HTTP routes)\n", len(vs.Spec.Http))
 		}
 		if len(vs.Spec.Tcp) > 0 {
-			fmt.Fprintf(writer, "   WARNING: No destinations match pod subsets (checked %d TCP routes)\n", len(vs.Spec.Tcp))
+			report.warnf(writer, "   WARNING: No destinations match pod subsets (checked %d TCP routes)\n", len(vs.Spec.Tcp))
 		}
 		for _, mismatch := range mismatchNotes {
 			fmt.Fprintf(writer, "      %s\n", mismatch)
 		}
-		return
+		return summary
 	}
 
 	possibleDests := len(vs.Spec.Http) + len(vs.Spec.Tls) + len(vs.Spec.Tcp)
@@ -851,7 +991,7 @@ func printVirtualService(writer io.Writer, vs clientnetworking.VirtualService, s
 			}
 		}
 
-		return
+		return summary
 	}
 
 	if facts == 0 {
@@ -863,9 +1003,10 @@ func printVirtualService(writer io.Writer, vs clientnetworking.VirtualService, s
 			fmt.Fprintf(writer, "   %d TCP route(s)\n", len(vs.Spec.Tcp))
 		}
 	}
+	return summary
 }
 
-func printIngressInfo(writer io.Writer, matchingServices []v1.Service, podsLabels []k8s_labels.Set, kubeClient kubernetes.Interface, configClient istioclient.Interface, client kube.ExtendedClient) error { // nolint: lll
+func printIngressInfo(writer io.Writer, report *describeReport, matchingServices []v1.Service, podsLabels []k8s_labels.Set, kubeClient kubernetes.Interface, configClient istioclient.Interface, client kube.ExtendedClient) error { // nolint: lll
 
 	pods, err := kubeClient.CoreV1().Pods(istioNamespace).List(context.TODO(), metav1.ListOptions{
 		LabelSelector: "istio=ingressgateway",
@@ -914,7 +1055,7 @@ func printIngressInfo(writer io.Writer, matchingServices []v1.Service, podsLabel
 				if dr != nil {
 					matchingSubsets, nonmatchingSubsets = getDestRuleSubsets(dr.Spec.Subsets, podsLabels)
 				} else {
-					fmt.Fprintf(writer,
+					report.warnf(writer,
 						"WARNING: Proxy is stale; it references to non-existent destination rule %s.%s\n",
 						drName, drNamespace)
 				}
@@ -930,10 +1071,15 @@ func printIngressInfo(writer io.Writer, matchingServices []v1.Service, podsLabel
 						fmt.Fprintf(writer, "--------------------\n")
 					}
 
-					printIngressService(writer, &ingressSvcs.Items[0], &pod, ipIngress)
-					printVirtualService(writer, *vs, svc, matchingSubsets, nonmatchingSubsets, dr)
+					urls := printIngressService(writer, &ingressSvcs.Items[0], &pod, ipIngress)
+					report.Ingress = append(report.Ingress, ingressSummary{
+						Service:        kname(svc.ObjectMeta),
+						Port:           port.Port,
+						URLs:           urls,
+						VirtualService: printVirtualService(writer, report, *vs, svc, matchingSubsets, nonmatchingSubsets, dr),
+					})
 				} else {
-					fmt.Fprintf(writer,
+					report.warnf(writer,
 						"WARNING: Proxy is stale; it references to non-existent virtual service %s.%s\n",
 						vsName, vsNamespace)
 				}
@@ -944,7 +1090,7 @@ func printIngressInfo(writer io.Writer, matchingServices []v1.Service, podsLabel
 	return nil
 }
 
-func printIngressService(writer io.Writer, ingressSvc *v1.Service, ingressPod *v1.Pod, ip string) {
+func printIngressService(writer io.Writer, ingressSvc *v1.Service, ingressPod *v1.Pod, ip string) []string {
 	// The ingressgateway service offers a lot of ports but the pod doesn't listen to all
 	// of them.  For example, it doesn't listen on 443 without additional setup.  This prints
 	// the most basic output.
@@ -958,6 +1104,7 @@ func printIngressService(writer io.Writer, ingressSvc *v1.Service, ingressPod *v
 		"http": 80,
 	}
 
+	var urls []string
 	for _, port := range ingressSvc.Spec.Ports {
 		if port.Protocol != "TCP" || !portsToShow[port.Name] {
 			continue
@@ -974,9 +1121,12 @@ func printIngressService(writer io.Writer, ingressSvc *v1.Service, ingressPod *v
 			if schemePortDefault[scheme] != nport {
 				portSuffix = fmt.Sprintf(":%d", nport)
 			}
-			fmt.Fprintf(writer, "\nExposed on Ingress Gateway %s://%s%s\n", scheme, ip, portSuffix)
+			url := fmt.Sprintf("%s://%s%s", scheme, ip, portSuffix)
+			fmt.Fprintf(writer, "\nExposed on Ingress Gateway %s\n", url)
+			urls = append(urls, url)
 		}
 	}
+	return urls
 }
 
 func getIngressIP(service v1.Service, pod v1.Pod) string {
@@ -1003,7 +1153,10 @@ func svcDescribeCmd() *cobra.Command {
 		Short:   "Describe services and their Istio configuration [kube-only]",
 		Long: `Analyzes service, pods, DestinationRules, and VirtualServices and reports
 the configuration objects that affect that service.`,
-		Example: `  istioctl experimental describe service productpage`,
+		Example: `  istioctl experimental describe service productpage
+
+  # Print the description as yaml
+  istioctl experimental describe service productpage -o yaml`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				cmd.Println(cmd.UsageString())
@@ -1014,101 +1167,113 @@ the configuration objects that affect that service.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			svcName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
 
-			client, err := interfaceFactory(kubeconfig)
+			writer, err := describeWriter(cmd)
 			if err != nil {
 				return err
 			}
-			svc, err := client.CoreV1().Services(ns).Get(context.TODO(), svcName, metav1.GetOptions{})
-			if err != nil {
+			report := &describeReport{}
+			if err := describeService(writer, report, svcName, ns, opts.Revision); err != nil {
 				return err
 			}
+			return printDescribeReport(cmd.OutOrStdout(), report)
+		},
+		ValidArgsFunction: validServiceArgs,
+	}
 
-			writer := cmd.OutOrStdout()
+	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
+		"Suppress warnings for unmeshed pods")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
 
-			pods, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				return err
-			}
+// describeService prints the description of a service and fills the report with it.
+func describeService(writer io.Writer, report *describeReport, svcName, ns, revision string) error {
+	client, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return err
+	}
+	svc, err := client.CoreV1().Services(ns).Get(context.TODO(), svcName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
 
-			matchingPods := []v1.Pod{}
-			selectedPodCount := 0
-			if len(svc.Spec.Selector) > 0 {
-				svcSelector := k8s_labels.SelectorFromSet(svc.Spec.Selector)
-				for _, pod := range pods.Items {
-					if svcSelector.Matches(k8s_labels.Set(pod.ObjectMeta.Labels)) {
-						selectedPodCount++
-
-						if pod.Status.Phase != v1.PodRunning {
-							fmt.Printf("   Pod is not %s (%s)\n", v1.PodRunning, pod.Status.Phase)
-							continue
-						}
-
-						ready, err := containerReady(&pod, proxyContainerName)
-						if err != nil {
-							fmt.Fprintf(writer, "Pod %s: %s\n", kname(pod.ObjectMeta), err)
-							continue
-						}
-						if !ready {
-							fmt.Fprintf(writer, "WARNING: Pod %s Container %s NOT READY\n", kname(pod.ObjectMeta), proxyContainerName)
-							continue
-						}
-
-						matchingPods = append(matchingPods, pod)
-					}
+	pods, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	matchingPods := []v1.Pod{}
+	selectedPodCount := 0
+	if len(svc.Spec.Selector) > 0 {
+		svcSelector := k8s_labels.SelectorFromSet(svc.Spec.Selector)
+		for _, pod := range pods.Items {
+			if svcSelector.Matches(k8s_labels.Set(pod.ObjectMeta.Labels)) {
+				selectedPodCount++
+
+				if pod.Status.Phase != v1.PodRunning {
+					report.warnf(writer, "   Pod %s is not %s (%s)\n", kname(pod.ObjectMeta), v1.PodRunning, pod.Status.Phase)
+					continue
 				}
-			}
 
-			if len(matchingPods) == 0 {
-				if selectedPodCount == 0 {
-					fmt.Fprintf(writer, "Service %q has no pods.\n", kname(svc.ObjectMeta))
-					return nil
+				ready, err := containerReady(&pod, proxyContainerName)
+				if err != nil {
+					report.warnf(writer, "Pod %s: %s\n", kname(pod.ObjectMeta), err)
+					continue
+				}
+				if !ready {
+					report.warnf(writer, "WARNING: Pod %s Container %s NOT READY\n", kname(pod.ObjectMeta), proxyContainerName)
+					continue
 				}
-				fmt.Fprintf(writer, "Service %q has no Istio pods.  (%d pods in service).\n", kname(svc.ObjectMeta), selectedPodCount)
-				fmt.Fprintf(writer, "Use `istioctl experimental add-to-mesh`, `istioctl kube-inject`, or redeploy with Istio automatic sidecar injection.\n")
-				return nil
-			}
 
-			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
-			if err != nil {
-				return err
+				matchingPods = append(matchingPods, pod)
 			}
+		}
+	}
 
-			var configClient istioclient.Interface
-			if configClient, err = configStoreFactory(); err != nil {
-				return err
-			}
+	if len(matchingPods) == 0 {
+		if selectedPodCount == 0 {
+			report.warnf(writer, "Service %q has no pods.\n", kname(svc.ObjectMeta))
+			return nil
+		}
+		report.warnf(writer, "Service %q has no Istio pods.  (%d pods in service).\n", kname(svc.ObjectMeta), selectedPodCount)
+		fmt.Fprintf(writer, "Use `istioctl experimental add-to-mesh`, `istioctl kube-inject`, or redeploy with Istio automatic sidecar injection.\n")
+		return nil
+	}
 
-			// Get all the labels for all the matching pods.  We will used this to complain
-			// if NONE of the pods match a VirtualService
-			podsLabels := make([]k8s_labels.Set, len(matchingPods))
-			for i, pod := range matchingPods {
-				podsLabels[i] = k8s_labels.Set(pod.ObjectMeta.Labels)
-			}
+	kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, revision)
+	if err != nil {
+		return err
+	}
 
-			// Describe based on the Envoy config for this first pod only
-			pod := matchingPods[0]
+	var configClient istioclient.Interface
+	if configClient, err = configStoreFactory(); err != nil {
+		return err
+	}
 
-			// Only consider the service invoked with this command, not other services that might select the pod
-			svcs := []v1.Service{*svc}
+	// Get all the labels for all the matching pods.  We will used this to complain
+	// if NONE of the pods match a VirtualService
+	podsLabels := make([]k8s_labels.Set, len(matchingPods))
+	for i, pod := range matchingPods {
+		podsLabels[i] = k8s_labels.Set(pod.ObjectMeta.Labels)
+	}
 
-			err = describePodServices(writer, kubeClient, configClient, &pod, svcs, podsLabels)
-			if err != nil {
-				return err
-			}
+	// Describe based on the Envoy config for this first pod only
+	pod := matchingPods[0]
 
-			// Now look for ingress gateways
-			return printIngressInfo(writer, svcs, podsLabels, client, configClient, kubeClient)
-		},
-		ValidArgsFunction: validServiceArgs,
+	// Only consider the service invoked with this command, not other services that might select the pod
+	svcs := []v1.Service{*svc}
+
+	err = describePodServices(writer, report, kubeClient, configClient, &pod, svcs, podsLabels)
+	if err != nil {
+		return err
 	}
 
-	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
-		"Suppress warnings for unmeshed pods")
-	cmd.Long += "\n\n" + ExperimentalMsg
-	return cmd
+	// Now look for ingress gateways
+	return printIngressInfo(writer, report, svcs, podsLabels, client, configClient, kubeClient)
 }
 
-func describePodServices(writer io.Writer, kubeClient kube.ExtendedClient, configClient istioclient.Interface, pod *v1.Pod, matchingServices []v1.Service, podsLabels []k8s_labels.Set) error { // nolint: lll
+func describePodServices(writer io.Writer, report *describeReport, kubeClient kube.ExtendedClient, configClient istioclient.Interface, pod *v1.Pod, matchingServices []v1.Service, podsLabels []k8s_labels.Set) error { // nolint: lll
 	byConfigDump, err := kubeClient.EnvoyDo(context.TODO(), pod.ObjectMeta.Name, pod.ObjectMeta.Namespace, "GET", "config_dump")
 	if err != nil {
 		if ignoreUnmeshed {
@@ -1129,8 +1294,17 @@ func describePodServices(writer io.Writer, kubeClient kube.ExtendedClient, confi
 			fmt.Fprintf(writer, "--------------------\n")
 		}
 		printService(writer, svc, pod)
+		svcSummary := serviceSummary{
+			Name:      svc.Name,
+			Namespace: svc.Namespace,
+		}
 
 		for _, port := range svc.Spec.Ports {
+			portSummary := servicePortSummary{
+				Name:     port.Name,
+				Port:     port.Port,
+				Protocol: findProtocolForPort(&port),
+			}
 			matchingSubsets := []string{}
 			nonmatchingSubsets := []string{}
 			drName, drNamespace, err := getIstioDestinationRuleNameForSvc(&cd, svc, port.Port)
@@ -1145,10 +1319,10 @@ func describePodServices(writer io.Writer, kubeClient kube.ExtendedClient, confi
 						// If there is more than one port, prefix each DR by the port it applies to
 						fmt.Fprintf(writer, "%d ", port.Port)
 					}
-					printDestinationRule(writer, dr, podsLabels)
+					portSummary.DestinationRule = printDestinationRule(writer, report, dr, podsLabels)
 					matchingSubsets, nonmatchingSubsets = getDestRuleSubsets(dr.Spec.Subsets, podsLabels)
 				} else {
-					fmt.Fprintf(writer,
+					report.warnf(writer,
 						"WARNING: Proxy is stale; it references to non-existent destination rule %s.%s\n",
 						drName, drNamespace)
 				}
@@ -1162,9 +1336,9 @@ func describePodServices(writer io.Writer, kubeClient kube.ExtendedClient, confi
 						// If there is more than one port, prefix each DR by the port it applies to
 						fmt.Fprintf(writer, "%d ", port.Port)
 					}
-					printVirtualService(writer, *vs, svc, matchingSubsets, nonmatchingSubsets, dr)
+					portSummary.VirtualService = printVirtualService(writer, report, *vs, svc, matchingSubsets, nonmatchingSubsets, dr)
 				} else {
-					fmt.Fprintf(writer,
+					report.warnf(writer,
 						"WARNING: Proxy is stale; it references to non-existent virtual service %s.%s\n",
 						vsName, vsNamespace)
 				}
@@ -1182,7 +1356,10 @@ func describePodServices(writer io.Writer, kubeClient kube.ExtendedClient, confi
 
 				fmt.Fprintf(writer, "RBAC policies: %s\n", strings.Join(policies, ", "))
 			}
+			portSummary.RBACPolicies = policies
+			svcSummary.Ports = append(svcSummary.Ports, portSummary)
 		}
+		report.Services = append(report.Services, svcSummary)
 	}
 
 	return nil
@@ -1200,7 +1377,7 @@ func containerReady(pod *v1.Pod, containerName string) (bool, error) {
 // describePeerAuthentication fetches all PeerAuthentication in workload and root namespace.
 // It lists the ones applied to the pod, and the current active mTLS mode.
 // When the client doesn't have access to root namespace, it will only show workload namespace Peerauthentications.
-func describePeerAuthentication(writer io.Writer, kubeClient kube.ExtendedClient, configClient istioclient.Interface, workloadNamespace string, podsLabels k8s_labels.Set) error { // nolint: lll
+func describePeerAuthentication(writer io.Writer, report *describeReport, kubeClient kube.ExtendedClient, configClient istioclient.Interface, workloadNamespace string, podsLabels k8s_labels.Set) error { // nolint: lll
 	meshCfg, err := getMeshConfig(kubeClient)
 	if err != nil {
 		return fmt.Errorf("failed to fetch mesh config: %v", err)
@@ -1227,9 +1404,9 @@ func describePeerAuthentication(writer io.Writer, kubeClient kube.ExtendedClient
 
 	matchedPA := findMatchedConfigs(podsLabels, cfgs)
 	effectivePA := authnv1beta1.ComposePeerAuthentication(meshCfg.RootNamespace, matchedPA)
-	printPeerAuthentication(writer, effectivePA)
+	report.PeerAuthentication = printPeerAuthentication(writer, effectivePA)
 	if len(matchedPA) != 0 {
-		printConfigs(writer, matchedPA)
+		report.PeerAuthentication.Applied = printConfigs(writer, matchedPA)
 	}
 
 	return nil
@@ -1263,30 +1440,32 @@ func findMatchedConfigs(podsLabels k8s_labels.Set, configs []*config.Config) []*
 // printConfig prints the applied configs based on the member's type.
 // When there is the array is empty, caller should make sure the intended
 // log is handled in their methods.
-func printConfigs(writer io.Writer, configs []*config.Config) {
+func printConfigs(writer io.Writer, configs []*config.Config) []string {
 	if len(configs) == 0 {
-		return
+		return nil
 	}
 	fmt.Fprintf(writer, "Applied %s:\n", configs[0].Meta.GroupVersionKind.Kind)
-	var cfgNames string
-	for i, cfg := range configs {
-		cfgNames += cfg.Meta.Name + "." + cfg.Meta.Namespace
-		if i < len(configs)-1 {
-			cfgNames += ", "
-		}
+	cfgNames := make([]string, 0, len(configs))
+	for _, cfg := range configs {
+		cfgNames = append(cfgNames, cfg.Meta.Name+"."+cfg.Meta.Namespace)
 	}
-	fmt.Fprintf(writer, "   %s\n", cfgNames)
+	fmt.Fprintf(writer, "   %s\n", strings.Join(cfgNames, ", "))
+	return cfgNames
 }
 
-func printPeerAuthentication(writer io.Writer, pa *v1beta1.PeerAuthentication) {
+func printPeerAuthentication(writer io.Writer, pa *v1beta1.PeerAuthentication) *peerAuthenticationSummary {
+	summary := &peerAuthenticationSummary{Mode: pa.Mtls.Mode.String()}
 	fmt.Fprintf(writer, "Effective PeerAuthentication:\n")
-	fmt.Fprintf(writer, "   Workload mTLS mode: %s\n", pa.Mtls.Mode.String())
+	fmt.Fprintf(writer, "   Workload mTLS mode: %s\n", summary.Mode)
 	if len(pa.PortLevelMtls) != 0 {
+		summary.PortModes = make(map[uint32]string, len(pa.PortLevelMtls))
 		fmt.Fprintf(writer, "   Port Level mTLS mode:\n")
 		for port, mode := range pa.PortLevelMtls {
-			fmt.Fprintf(writer, "      %d: %s\n", port, mode.Mode.String())
+			summary.PortModes[port] = mode.Mode.String()
+			fmt.Fprintf(writer, "      %d: %s\n", port, summary.PortModes[port])
 		}
 	}
+	return summary
 }
 
 func getMeshConfig(kubeClient kube.ExtendedClient) (*meshconfig.MeshConfig, error) {
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
@@ -45,6 +46,14 @@ type execAndK8sConfigTestCase struct {
 
 // Tests Pilot /debug
 func TestDescribe(t *testing.T) {
+	podlessSvc := []runtime.Object{
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "productpage", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"app": "productpage"},
+			},
+		},
+	}
 	cases := []execAndK8sConfigTestCase{
 		{ // case 0
 			args:           strings.Split("experimental describe", " "),
@@ -69,6 +78,38 @@ func TestDescribe(t *testing.T) {
 			expectedString: "services \"not-a-service\" not found",
 			wantException:  true, // "istioctl experimental describe service not-a-service" should fail
 		},
+		{ // service without pods
+			k8sConfigs:     podlessSvc,
+			namespace:      "default",
+			args:           strings.Split("experimental describe service productpage", " "),
+			expectedOutput: "Service \"productpage\" has no pods.\n",
+		},
+		{ // service without pods, as json
+			k8sConfigs: podlessSvc,
+			namespace:  "default",
+			args:       strings.Split("experimental describe service productpage -o json", " "),
+			expectedOutput: `{
+  "warnings": [
+    "Service \"productpage\" has no pods."
+  ]
+}
+`,
+		},
+		{ // service without pods, as yaml
+			k8sConfigs: podlessSvc,
+			namespace:  "default",
+			args:       strings.Split("experimental describe service productpage -o yaml", " "),
+			expectedOutput: `warnings:
+- Service "productpage" has no pods.
+`,
+		},
+		{ // unsupported output format
+			k8sConfigs:     podlessSvc,
+			namespace:      "default",
+			args:           strings.Split("experimental describe service productpage -o wide", " "),
+			expectedString: `output format "wide" not supported`,
+			wantException:  true,
+		},
 	}
 
 	for i, c := range cases {
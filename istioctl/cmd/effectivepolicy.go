@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	envoy_corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func effectivePolicyCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var subset string
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "effective-policy <host>",
+		Short: "Shows the effective traffic policy of a service host, and where each setting comes from",
+		Long: `
+Shows the traffic policy the sidecars of a namespace apply to the requests to a service host: the connection pool,
+load balancing, outlier detection and TLS settings merged from the DestinationRules and the mesh config, and the
+timeouts and retries of the VirtualService routes. Each setting is shown with the resource it comes from. The
+namespace wide Sidecar of the namespace is taken into account, workload specific ones are not.
+`,
+		Example: `  # Show the traffic policy of the reviews service for the clients of the default namespace
+  istioctl x effective-policy reviews.default.svc.cluster.local
+
+  # Show the traffic policy of the v1 subset of the reviews service on port 9080 for the clients of the foo namespace
+  istioctl x effective-policy reviews.default.svc.cluster.local -n foo --subset v1 --port 9080
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			query.Set("host", args[0])
+			query.Set("namespace", handlers.HandleNamespace(namespace, defaultNamespace))
+			if subset != "" {
+				query.Set("subset", subset)
+			}
+			if port != 0 {
+				query.Set("port", strconv.Itoa(port))
+			}
+			xdsRequest := xdsapi.DiscoveryRequest{
+				ResourceNames: []string{fmt.Sprintf("effectivepolicyz?%s", query.Encode())},
+				Node: &envoy_corev3.Node{
+					Id: "debug~0.0.0.0~istioctl~cluster.local",
+				},
+				TypeUrl: v3.DebugType,
+			}
+			xdsResponse, err := multixds.RequestAndProcessXds(&xdsRequest, centralOpts, istioNamespace, kubeClient)
+			if err != nil {
+				return err
+			}
+			sw := pilot.EffectivePolicyWriter{Writer: c.OutOrStdout()}
+			return sw.Print(xdsResponse)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().StringVar(&subset, "subset", "", "Subset of the host defined by its DestinationRules")
+	cmd.PersistentFlags().IntVar(&port, "port", 0, "Port of the host, to apply the port level settings")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
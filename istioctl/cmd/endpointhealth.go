@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	envoy_corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func endpointHealthCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+
+	cmd := &cobra.Command{
+		Use:   "endpoint-health [<service>]",
+		Short: "Summarizes the health of service endpoints, as observed by the active health checks of proxies",
+		Long: `
+Summarizes the health of service endpoints, as observed by the active health checks of proxies.
+Health check events are only reported by proxies with ISTIO_META_HEALTH_CHECK_EVENT_LOG_PATH set, for
+clusters which have health checks configured.
+`,
+		Example: `  # Summarize the health of all the services with health checks
+  istioctl x endpoint-health
+
+  # Summarize the health of the reviews service
+  istioctl x endpoint-health reviews.default.svc.cluster.local
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			xdsRequest := xdsapi.DiscoveryRequest{
+				ResourceNames: []string{"healthcheckz?all=true"},
+				Node: &envoy_corev3.Node{
+					Id: "debug~0.0.0.0~istioctl~cluster.local",
+				},
+				TypeUrl: v3.DebugType,
+			}
+			// Each Istiod only knows about the proxies connected to it, so all of them are queried.
+			xdsResponses, err := multixds.MultiRequestAndProcessXds(true, &xdsRequest, centralOpts, istioNamespace,
+				"", "", kubeClient)
+			if err != nil {
+				return err
+			}
+			sw := pilot.EndpointHealthWriter{Writer: c.OutOrStdout()}
+			if len(args) > 0 {
+				sw.Service = args[0]
+			}
+			return sw.PrintAll(xdsResponses)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
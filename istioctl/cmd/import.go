@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/importer"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config/constants"
+)
+
+func importCmd() *cobra.Command {
+	var files []string
+	opts := importer.Options{}
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Converts the configuration of other ingress controllers and service meshes into Istio configuration",
+		Long: `
+Converts the configuration of other ingress controllers and service meshes into equivalent VirtualServices
+and DestinationRules, to ease migrations to Istio. The supported resources are:
+
+  - networking.k8s.io/v1 Ingresses, with the common annotations of the Ingress-NGINX controller
+  - Traefik IngressRoutes (traefik.containo.us/v1alpha1)
+  - Contour HTTPProxies (projectcontour.io/v1)
+  - Linkerd ServiceProfiles (linkerd.io/v1alpha2)
+
+The VirtualServices converted from ingress resources are bound to the --gateway gateway, whose servers must
+be configured separately. The generated configuration is written to the standard output, and the features
+which could not be converted are reported on the standard error.
+`,
+		Example: `  # Convert the Ingresses of a namespace
+  kubectl get ingress -n default -o yaml | istioctl x import -f - > istio.yaml
+
+  # Convert Linkerd ServiceProfiles and Contour HTTPProxies
+  istioctl x import -f service-profiles.yaml -f httpproxies.yaml --gateway istio-system/public
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 || len(files) == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("import requires at least one --file parameter and no arguments")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var objects []crd.IstioKind
+			for _, f := range files {
+				data, err := readFile(f)
+				if err != nil {
+					return err
+				}
+				parsed, err := importer.Parse(data)
+				if err != nil {
+					return fmt.Errorf("failed to parse %s: %v", f, err)
+				}
+				objects = append(objects, parsed...)
+			}
+			result, err := importer.Import(objects, opts)
+			if err != nil {
+				return err
+			}
+			return printImportResult(c.OutOrStdout(), c.ErrOrStderr(), result)
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil, "File of the resources to convert, or - for the standard input. May be repeated")
+	cmd.PersistentFlags().StringVar(&opts.Gateway, "gateway", "istio-system/ingressgateway",
+		"Gateway the VirtualServices converted from ingress resources are bound to, as namespace/name")
+	cmd.PersistentFlags().StringVar(&opts.DomainSuffix, "domain", constants.DefaultKubernetesDomain, "Domain suffix of the Kubernetes services")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+// printImportResult writes the converted configs as YAML documents, and reports the features which were not converted.
+func printImportResult(out, errOut io.Writer, result *importer.Result) error {
+	for i, cfg := range result.Configs {
+		obj, err := crd.ConvertConfig(cfg)
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		fmt.Fprint(out, string(data))
+	}
+	if len(result.Unsupported) > 0 {
+		fmt.Fprintf(errOut, "Features not converted (%d):\n", len(result.Unsupported))
+		for _, u := range result.Unsupported {
+			fmt.Fprintf(errOut, "  - %s\n", u)
+		}
+	}
+	return nil
+}
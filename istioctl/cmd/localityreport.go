@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+
+	envoy_corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func localityReportCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var level, cluster string
+
+	cmd := &cobra.Command{
+		Use:   "locality-report",
+		Short: "Reports the requests from each source locality to each destination locality",
+		Long: `
+Reports the requests from each source locality to each destination locality, and the share of the requests
+sent across localities, to verify that locality load balancing keeps the traffic local. The requests are
+reported through the load reporting service by the proxies started with ISTIO_META_LOAD_REPORTING=true.
+`,
+		Example: `  # Report the requests between zones
+  istioctl x locality-report
+
+  # Report the requests between regions to the reviews service
+  istioctl x locality-report --level region --cluster "outbound|9080||reviews.default.svc.cluster.local"
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			switch level {
+			case xds.LocalityLevelRegion, xds.LocalityLevelZone, xds.LocalityLevelSubzone:
+			default:
+				return fmt.Errorf("invalid level %q, must be one of %s, %s or %s", level,
+					xds.LocalityLevelRegion, xds.LocalityLevelZone, xds.LocalityLevelSubzone)
+			}
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			query := url.Values{"level": []string{level}}
+			if cluster != "" {
+				query.Set("cluster", cluster)
+			}
+			xdsRequest := xdsapi.DiscoveryRequest{
+				ResourceNames: []string{"localitymatrixz?" + query.Encode()},
+				Node: &envoy_corev3.Node{
+					Id: "debug~0.0.0.0~istioctl~cluster.local",
+				},
+				TypeUrl: v3.DebugType,
+			}
+			// Each Istiod only receives the load reports of the proxies connected to it, so all of them are queried.
+			xdsResponses, err := multixds.MultiRequestAndProcessXds(true, &xdsRequest, centralOpts, istioNamespace,
+				"", "", kubeClient)
+			if err != nil {
+				return err
+			}
+			sw := pilot.LocalityMatrixWriter{Writer: c.OutOrStdout()}
+			return sw.PrintAll(xdsResponses)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&level, "level", xds.LocalityLevelZone,
+		"Level at which localities are aggregated: region, zone or subzone")
+	cmd.PersistentFlags().StringVar(&cluster, "cluster", "",
+		"Only report the requests sent to this Envoy cluster, for example outbound|80||foo.default.svc.cluster.local")
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
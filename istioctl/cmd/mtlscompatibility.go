@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	envoy_corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func mtlsCompatibilityCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var window time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "mtls-compatibility [<service>]",
+		Short: "Summarizes the plaintext traffic still accepted by services, to tell when they can use STRICT mTLS",
+		Long: `
+Summarizes the inbound traffic of services by connection security, as reported by the proxies of their workloads.
+A service which accepted no plaintext traffic during the window is ready to be switched from PERMISSIVE to STRICT
+mode. Traffic is only reported by proxies with MTLS_COMPATIBILITY_REPORT_INTERVAL set, and requires the Istio
+standard metrics.
+`,
+		Example: `  # Summarize the mTLS compatibility of all the services
+  istioctl x mtls-compatibility
+
+  # Summarize the mTLS compatibility of the reviews service, which must not accept plaintext for 24 hours
+  istioctl x mtls-compatibility reviews.default.svc.cluster.local --window 24h
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			xdsRequest := xdsapi.DiscoveryRequest{
+				ResourceNames: []string{"mtlsz"},
+				Node: &envoy_corev3.Node{
+					Id: "debug~0.0.0.0~istioctl~cluster.local",
+				},
+				TypeUrl: v3.DebugType,
+			}
+			// Each Istiod only knows about the proxies connected to it, so all of them are queried.
+			xdsResponses, err := multixds.MultiRequestAndProcessXds(true, &xdsRequest, centralOpts, istioNamespace,
+				"", "", kubeClient)
+			if err != nil {
+				return err
+			}
+			sw := pilot.MTLSCompatibilityWriter{Writer: c.OutOrStdout(), Window: window}
+			if len(args) > 0 {
+				sw.Service = args[0]
+			}
+			return sw.PrintAll(xdsResponses)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().DurationVar(&window, "window", time.Hour,
+		"How long a service must have accepted no plaintext traffic to be ready for STRICT mode")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
@@ -794,6 +794,7 @@ func logCmd() *cobra.Command {
 
 func routeConfigCmd() *cobra.Command {
 	var podName, podNamespace string
+	var scoped bool
 
 	routeConfigCmd := &cobra.Command{
 		Use:   "route [<type>/]<name>[.<namespace>]",
@@ -808,6 +809,9 @@ func routeConfigCmd() *cobra.Command {
   # Retrieve full route dump for route 9080
   istioctl proxy-config route <pod-name[.namespace]> --name 9080 -o json
 
+  # Retrieve scoped route summary for route 80.
+  istioctl proxy-config route <pod-name[.namespace]> --name 80 --scoped
+
   # Retrieve route summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config routes --file envoy-config.json
@@ -838,6 +842,16 @@ func routeConfigCmd() *cobra.Command {
 				Name:    routeName,
 				Verbose: verboseProxyConfig,
 			}
+			if scoped {
+				switch outputFormat {
+				case summaryOutput:
+					return configWriter.PrintScopedRouteSummary(filter)
+				case jsonOutput, yamlOutput:
+					return configWriter.PrintScopedRouteDump(filter, outputFormat)
+				default:
+					return fmt.Errorf("output format %q not supported", outputFormat)
+				}
+			}
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintRouteSummary(filter)
@@ -853,6 +867,8 @@ func routeConfigCmd() *cobra.Command {
 	routeConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
 	routeConfigCmd.PersistentFlags().StringVar(&routeName, "name", "", "Filter listeners by route name field")
 	routeConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", true, "Output more information")
+	routeConfigCmd.PersistentFlags().BoolVar(&scoped, "scoped", false,
+		"Show scoped route configurations (SRDS) instead of route configurations")
 	routeConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -46,6 +46,9 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 		Example: `  # Retrieve sync status for all Envoys in a mesh
   istioctl proxy-status
 
+  # Retrieve sync status for all Envoys in a mesh as JSON
+  istioctl proxy-status -o json
+
   # Retrieve sync diff for a single Envoy and Istiod
   istioctl proxy-status istio-egressgateway-59585c5b9c-ndc59.istio-system
 
@@ -103,7 +106,7 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 			if err != nil {
 				return err
 			}
-			sw := pilot.StatusWriter{Writer: c.OutOrStdout()}
+			sw := pilot.StatusWriter{Writer: c.OutOrStdout(), OutputFormat: outputFormat}
 			return sw.PrintAll(statuses)
 		},
 	}
@@ -111,6 +114,8 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	opts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput,
+		"Output format of the sync status of all the proxies: one of json|yaml|short")
 
 	return statusCmd
 }
@@ -225,7 +230,7 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 			if err != nil {
 				return err
 			}
-			sw := pilot.XdsStatusWriter{Writer: c.OutOrStdout()}
+			sw := pilot.XdsStatusWriter{Writer: c.OutOrStdout(), OutputFormat: outputFormat}
 			return sw.PrintAll(xdsResponses)
 		},
 	}
@@ -234,6 +239,8 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	centralOpts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput,
+		"Output format of the sync status of all the proxies: one of json|yaml|short")
 
 	return statusCmd
 }
@@ -234,8 +234,15 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(workloadCommands())
 	experimentalCmd.AddCommand(revisionCommand())
 	experimentalCmd.AddCommand(debugCommand())
+	experimentalCmd.AddCommand(endpointHealthCommand())
+	experimentalCmd.AddCommand(mtlsCompatibilityCommand())
+	experimentalCmd.AddCommand(effectivePolicyCommand())
+	experimentalCmd.AddCommand(localityReportCommand())
 	experimentalCmd.AddCommand(preCheck())
 	experimentalCmd.AddCommand(statsConfigCmd())
+	experimentalCmd.AddCommand(simulateCmd())
+	experimentalCmd.AddCommand(tapCmd())
+	experimentalCmd.AddCommand(importCmd())
 
 	analyzeCmd := Analyze()
 	hideInheritedFlags(analyzeCmd, FlagIstioNamespace)
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+type simulateArgs struct {
+	address  string
+	port     int
+	host     string
+	path     string
+	headers  []string
+	sni      string
+	protocol string
+	tls      string
+	mode     string
+}
+
+func (a simulateArgs) call() (simulation.Call, error) {
+	if a.port <= 0 || a.port > 65535 {
+		return simulation.Call{}, fmt.Errorf("--port must be set to a valid port")
+	}
+	call := simulation.Call{
+		Address:    a.address,
+		Port:       a.port,
+		Path:       a.path,
+		HostHeader: a.host,
+		Headers:    http.Header{},
+		Sni:        a.sni,
+		Protocol:   simulation.Protocol(a.protocol),
+		TLS:        simulation.TLSMode(a.tls),
+		CallMode:   simulation.CallMode(a.mode),
+	}
+	switch call.Protocol {
+	case simulation.HTTP, simulation.HTTP2, simulation.TCP:
+	default:
+		return simulation.Call{}, fmt.Errorf("protocol %q not supported, must be one of http|http2|tcp", a.protocol)
+	}
+	switch call.TLS {
+	case simulation.Plaintext, simulation.TLS, simulation.MTLS:
+	default:
+		return simulation.Call{}, fmt.Errorf("tls mode %q not supported, must be one of plaintext|tls|mtls", a.tls)
+	}
+	switch call.CallMode {
+	case simulation.CallModeOutbound, simulation.CallModeInbound, simulation.CallModeGateway:
+	default:
+		return simulation.Call{}, fmt.Errorf("mode %q not supported, must be one of outbound|inbound|gateway", a.mode)
+	}
+	for _, h := range a.headers {
+		parts := strings.SplitN(h, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return simulation.Call{}, fmt.Errorf("invalid header %q, must be of the form key=value", h)
+		}
+		call.Headers.Add(parts[0], parts[1])
+	}
+	return call, nil
+}
+
+func simulateCmd() *cobra.Command {
+	var podName, podNamespace string
+	var sa simulateArgs
+
+	cmd := &cobra.Command{
+		Use:   "simulate [<type>/]<name>[.<namespace>]",
+		Short: "Simulates how the Envoy in the specified pod would handle a request",
+		Long: `
+Simulates how the Envoy in the specified pod would handle a request, using its current configuration.
+The listener, filter chain, virtual host, route and cluster selection is evaluated offline against the
+config dump of the proxy, and what would be selected is printed.
+`,
+		Example: `  # Simulate an outbound HTTP request to the reviews service
+  istioctl x simulate productpage-v1-6b746f74dc-9stvs.default --port 9080 --host reviews
+
+  # Simulate an outbound TLS request with SNI
+  istioctl x simulate productpage-v1-6b746f74dc-9stvs.default --port 443 --protocol tcp --tls tls --sni api.example.com
+
+  # Simulate an inbound request with a header
+  istioctl x simulate reviews-v1-545db77b95-2ps9c.default --mode inbound --address 10.0.0.1 --port 9080 \
+    --host reviews --header end-user=jason
+
+  # Simulate a request without using Kubernetes API
+  ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
+  istioctl x simulate --file envoy-config.json --port 9080 --host reviews
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) != (configDumpFile == "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires pod name or --file parameter")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			call, err := sa.call()
+			if err != nil {
+				return err
+			}
+			var configWriter *configdump.ConfigWriter
+			if len(args) == 1 {
+				if podName, podNamespace, err = getPodName(args[0]); err != nil {
+					return err
+				}
+				configWriter, err = setupPodConfigdumpWriter(podName, podNamespace, c.OutOrStdout())
+			} else {
+				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
+			}
+			if err != nil {
+				return err
+			}
+			switch outputFormat {
+			case summaryOutput, jsonOutput, yamlOutput:
+				return configWriter.PrintSimulation(call, outputFormat)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	cmd.PersistentFlags().StringVar(&sa.address, "address", "", "Destination IP address of the request")
+	cmd.PersistentFlags().IntVar(&sa.port, "port", 0, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&sa.host, "host", "", "Host header of the request")
+	cmd.PersistentFlags().StringVar(&sa.path, "path", "/", "Path of the request")
+	cmd.PersistentFlags().StringArrayVar(&sa.headers, "header", nil, "Header of the request, of the form key=value. May be repeated")
+	cmd.PersistentFlags().StringVar(&sa.sni, "sni", "", "SNI of the request. Defaults to the host for TLS requests")
+	cmd.PersistentFlags().StringVar(&sa.protocol, "protocol", string(simulation.HTTP), "Protocol of the request: one of http|http2|tcp")
+	cmd.PersistentFlags().StringVar(&sa.tls, "tls", string(simulation.Plaintext), "TLS mode of the request: one of plaintext|tls|mtls")
+	cmd.PersistentFlags().StringVar(&sa.mode, "mode", string(simulation.CallModeOutbound),
+		"How the request reaches the proxy: one of outbound|inbound|gateway")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	cmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"net/http"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestSimulateArgs(t *testing.T) {
+	defaults := simulateArgs{
+		port:     9080,
+		path:     "/",
+		protocol: "http",
+		tls:      "plaintext",
+		mode:     "outbound",
+	}
+	cases := []struct {
+		name    string
+		modify  func(a *simulateArgs)
+		want    simulation.Call
+		wantErr bool
+	}{
+		{
+			name: "headers",
+			modify: func(a *simulateArgs) {
+				a.host = "reviews"
+				a.headers = []string{"end-user=jason", "x-multi=a=b"}
+			},
+			want: simulation.Call{
+				Port:       9080,
+				Path:       "/",
+				HostHeader: "reviews",
+				Headers:    http.Header{"End-User": []string{"jason"}, "X-Multi": []string{"a=b"}},
+				Protocol:   simulation.HTTP,
+				TLS:        simulation.Plaintext,
+				CallMode:   simulation.CallModeOutbound,
+			},
+		},
+		{
+			name:    "missing port",
+			modify:  func(a *simulateArgs) { a.port = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "invalid header",
+			modify:  func(a *simulateArgs) { a.headers = []string{"end-user"} },
+			wantErr: true,
+		},
+		{
+			name:    "invalid protocol",
+			modify:  func(a *simulateArgs) { a.protocol = "udp" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			modify:  func(a *simulateArgs) { a.mode = "egress" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			a := defaults
+			tt.modify(&a)
+			got, err := a.call()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
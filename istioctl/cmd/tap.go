@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/config/common/matcher/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tapcfg "github.com/envoyproxy/go-control-plane/envoy/config/tap/v3"
+	tapdata "github.com/envoyproxy/go-control-plane/envoy/data/tap/v3"
+	envoymatcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+)
+
+type tapArgs struct {
+	headers      []string
+	path         string
+	maxBodyBytes uint32
+}
+
+// request builds the tap configuration streamed to the Envoy /tap admin endpoint.
+func (a tapArgs) request() (*admin.TapRequest, error) {
+	var headers []*route.HeaderMatcher
+	for _, h := range a.headers {
+		parts := strings.SplitN(h, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid header %q, must be of the form key=value", h)
+		}
+		headers = append(headers, exactHeaderMatcher(strings.ToLower(parts[0]), parts[1]))
+	}
+	if a.path != "" {
+		headers = append(headers, &route.HeaderMatcher{
+			Name: ":path",
+			HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{
+				StringMatch: &envoymatcher.StringMatcher{MatchPattern: &envoymatcher.StringMatcher_Prefix{Prefix: a.path}},
+			},
+		})
+	}
+
+	match := &matcher.MatchPredicate{Rule: &matcher.MatchPredicate_AnyMatch{AnyMatch: true}}
+	if len(headers) > 0 {
+		match = &matcher.MatchPredicate{Rule: &matcher.MatchPredicate_HttpRequestHeadersMatch{
+			HttpRequestHeadersMatch: &matcher.HttpHeadersMatch{Headers: headers},
+		}}
+	}
+	return &admin.TapRequest{
+		ConfigId: xdsfilters.TapConfigID,
+		TapConfig: &tapcfg.TapConfig{
+			Match: match,
+			OutputConfig: &tapcfg.OutputConfig{
+				Sinks: []*tapcfg.OutputSink{{
+					Format:         tapcfg.OutputSink_JSON_BODY_AS_STRING,
+					OutputSinkType: &tapcfg.OutputSink_StreamingAdmin{StreamingAdmin: &tapcfg.StreamingAdminSink{}},
+				}},
+				MaxBufferedRxBytes: wrapperspb.UInt32(a.maxBodyBytes),
+				MaxBufferedTxBytes: wrapperspb.UInt32(a.maxBodyBytes),
+			},
+		},
+	}, nil
+}
+
+func exactHeaderMatcher(name, value string) *route.HeaderMatcher {
+	return &route.HeaderMatcher{
+		Name: name,
+		HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{
+			StringMatch: &envoymatcher.StringMatcher{MatchPattern: &envoymatcher.StringMatcher_Exact{Exact: value}},
+		},
+	}
+}
+
+// streamTaps decodes the traces streamed by the /tap admin endpoint and writes them to out, until the stream
+// ends or maxTraces traces have been written. A maxTraces of 0 means no limit.
+func streamTaps(in io.Reader, out io.Writer, format string, maxTraces int) error {
+	dec := json.NewDecoder(in)
+	for n := 0; maxTraces == 0 || n < maxTraces; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			// The stream ends when the capture duration elapses.
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+				errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return fmt.Errorf("failed to read tap stream: %v", err)
+		}
+		trace := &tapdata.TraceWrapper{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, trace); err != nil {
+			return fmt.Errorf("failed to parse tap trace: %v", err)
+		}
+		switch format {
+		case jsonOutput:
+			b, err := protojson.Marshal(trace)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(b))
+		default:
+			printTrace(out, trace)
+		}
+	}
+	return nil
+}
+
+func printTrace(out io.Writer, trace *tapdata.TraceWrapper) {
+	t := trace.GetHttpBufferedTrace()
+	if t == nil {
+		return
+	}
+	req := headerMap(t.GetRequest().GetHeaders())
+	resp := headerMap(t.GetResponse().GetHeaders())
+	fmt.Fprintf(out, "%s %s%s -> %s\n", req[":method"], req[":authority"], req[":path"], resp[":status"])
+	printMessage(out, "request", t.GetRequest())
+	printMessage(out, "response", t.GetResponse())
+}
+
+func printMessage(out io.Writer, name string, m *tapdata.HttpBufferedTrace_Message) {
+	for _, h := range m.GetHeaders() {
+		if strings.HasPrefix(h.Key, ":") {
+			continue
+		}
+		fmt.Fprintf(out, "  %s header %s: %s\n", name, h.Key, h.Value)
+	}
+	if body := m.GetBody().GetAsString(); body != "" {
+		truncated := ""
+		if m.GetBody().GetTruncated() {
+			truncated = " (truncated)"
+		}
+		fmt.Fprintf(out, "  %s body%s: %s\n", name, truncated, body)
+	}
+}
+
+func headerMap(headers []*core.HeaderValue) map[string]string {
+	res := make(map[string]string, len(headers))
+	for _, h := range headers {
+		res[h.Key] = h.Value
+	}
+	return res
+}
+
+func tapCmd() *cobra.Command {
+	var ta tapArgs
+	var duration time.Duration
+	var maxTraces int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "tap [<type>/]<name>[.<namespace>]",
+		Short: "Captures the HTTP requests handled by the Envoy in the specified pod",
+		Long: `
+Captures the HTTP requests and responses, including headers and bodies, handled by the Envoy in the specified pod.
+Capturing is only possible for workloads annotated with ` + xdsfilters.TapAnnotation + `: "true", and only lasts
+as long as this command runs. Requests can be selected by header values and path prefix.
+`,
+		Example: `  # Capture requests to productpage for 30 seconds
+  istioctl x tap productpage-v1-6b746f74dc-9stvs.default
+
+  # Capture the first 10 requests from a given user to a path
+  istioctl x tap productpage-v1-6b746f74dc-9stvs.default --header end-user=jason --path /api --max-traces 10
+
+  # Capture requests as JSON, including up to 4KB of the bodies
+  istioctl x tap productpage-v1-6b746f74dc-9stvs.default --max-body-bytes 4096 -o json
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("tap requires pod name")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if format != summaryOutput && format != jsonOutput {
+				return fmt.Errorf("output format %q not supported", format)
+			}
+			tapRequest, err := ta.request()
+			if err != nil {
+				return err
+			}
+			body, err := protojson.Marshal(tapRequest)
+			if err != nil {
+				return err
+			}
+			podName, podNamespace, err := getPodName(args[0])
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			fw, err := kubeClient.NewPortForwarder(podName, podNamespace, "127.0.0.1", 0, 15000)
+			if err != nil {
+				return err
+			}
+			if err := fw.Start(); err != nil {
+				return fmt.Errorf("failure running port forward process: %v", err)
+			}
+			defer fw.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), duration)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("http://%s/tap", fw.Address()), bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return fmt.Errorf("failed to start tap on %s.%s: %v", podName, podNamespace, err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				msg, _ := io.ReadAll(resp.Body)
+				return fmt.Errorf("failed to start tap on %s.%s (is the pod annotated with %s?): %s",
+					podName, podNamespace, xdsfilters.TapAnnotation, strings.TrimSpace(string(msg)))
+			}
+			return streamTaps(resp.Body, c.OutOrStdout(), format, maxTraces)
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	cmd.PersistentFlags().StringArrayVar(&ta.headers, "header", nil,
+		"Only capture requests with this header, of the form key=value. May be repeated")
+	cmd.PersistentFlags().StringVar(&ta.path, "path", "", "Only capture requests with this path prefix")
+	cmd.PersistentFlags().Uint32Var(&ta.maxBodyBytes, "max-body-bytes", 0, "Maximum number of body bytes captured per request and response")
+	cmd.PersistentFlags().DurationVar(&duration, "duration", 30*time.Second, "How long to capture requests for")
+	cmd.PersistentFlags().IntVar(&maxTraces, "max-traces", 0, "Stop after capturing this many requests. 0 means no limit")
+	cmd.PersistentFlags().StringVarP(&format, "output", "o", summaryOutput, "Output format: one of json|short")
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestTapArgs(t *testing.T) {
+	req, err := tapArgs{}.request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, req.GetConfigId(), "istio-tap")
+	assert.Equal(t, req.GetTapConfig().GetMatch().GetAnyMatch(), true)
+	assert.Equal(t, req.GetTapConfig().GetOutputConfig().GetSinks()[0].GetStreamingAdmin() != nil, true)
+
+	req, err = tapArgs{headers: []string{"End-User=jason"}, path: "/api", maxBodyBytes: 1024}.request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := req.GetTapConfig().GetMatch().GetHttpRequestHeadersMatch().GetHeaders()
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 header matchers, got %v", headers)
+	}
+	assert.Equal(t, headers[0].GetName(), "end-user")
+	assert.Equal(t, headers[0].GetStringMatch().GetExact(), "jason")
+	assert.Equal(t, headers[1].GetName(), ":path")
+	assert.Equal(t, headers[1].GetStringMatch().GetPrefix(), "/api")
+	assert.Equal(t, req.GetTapConfig().GetOutputConfig().GetMaxBufferedRxBytes().GetValue(), uint32(1024))
+
+	if _, err := (tapArgs{headers: []string{"end-user"}}).request(); err == nil {
+		t.Fatal("expected error for invalid header")
+	}
+}
+
+const tapStream = `{
+ "http_buffered_trace": {
+  "request": {
+   "headers": [
+    {"key": ":authority", "value": "productpage:9080"},
+    {"key": ":path", "value": "/api/v1"},
+    {"key": ":method", "value": "GET"},
+    {"key": "end-user", "value": "jason"}
+   ]
+  },
+  "response": {
+   "headers": [{"key": ":status", "value": "200"}],
+   "body": {"as_string": "ok", "truncated": true}
+  }
+ }
+}
+{
+ "http_buffered_trace": {
+  "request": {"headers": [{"key": ":method", "value": "POST"}]},
+  "response": {"headers": [{"key": ":status", "value": "503"}]}
+ }
+}
+`
+
+func TestStreamTaps(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := streamTaps(strings.NewReader(tapStream), out, summaryOutput, 0); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, out.String(), `GET productpage:9080/api/v1 -> 200
+  request header end-user: jason
+  response body (truncated): ok
+POST  -> 503
+`)
+
+	out.Reset()
+	if err := streamTaps(strings.NewReader(tapStream), out, jsonOutput, 1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 {
+		t.Fatalf("expected a single trace, got %v", lines)
+	}
+
+	if err := streamTaps(strings.NewReader("{not json"), out, summaryOutput, 0); err == nil {
+		t.Fatal("expected error for malformed stream")
+	}
+}
@@ -44,6 +44,7 @@ var (
 	timeout      time.Duration
 	generation   string
 	verbose      bool
+	generated    bool
 	targetSchema collection.Schema
 	clientGetter func(string, string) (dynamic.Interface, error)
 )
@@ -62,6 +63,9 @@ func waitCmd() *cobra.Command {
 
   # Wait until 99% of the proxies receive the distribution, timing out after 5 minutes
   istioctl experimental wait --for=distribution --threshold=.99 --timeout=300 virtualservice bookinfo.default
+
+  # Wait until the routes generated from the bookinfo virtual service have been acked by all the proxies they apply to
+  istioctl experimental wait --for=distribution --generated-resources virtualservice bookinfo.default
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			printVerbosef(cmd, "kubeconfig %s", kubeconfig)
@@ -102,6 +106,11 @@ func waitCmd() *cobra.Command {
 				if err != nil {
 					return err
 				} else if float32(present)/float32(present+notpresent) >= threshold {
+					if generated {
+						_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Resources generated from %s acked by %d out of %d relevant sidecars "+
+							"for totally %d sidecars\n", targetResource, present, present+notpresent, sdcnum)
+						return nil
+					}
 					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Resource %s present on %d out of %d configurations for totally %d sidecars\n",
 						targetResource, present, present+notpresent, sdcnum)
 					return nil
@@ -141,6 +150,9 @@ func waitCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&generation, "generation", "",
 		"Wait for a specific generation of config to become current, rather than using whatever is latest in "+
 			"Kubernetes")
+	cmd.PersistentFlags().BoolVar(&generated, "generated-resources", false,
+		"Wait for the clusters and routes generated from the resource to be acked by the sidecars they apply to, "+
+			"rather than for the resource version to be distributed to all proxies")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enables verbose output")
 	_ = cmd.PersistentFlags().MarkHidden("verbose")
 	opts.AttachControlPlaneFlags(cmd)
@@ -185,6 +197,9 @@ func poll(cmd *cobra.Command,
 		return 0, 0, 0, err
 	}
 	path := fmt.Sprintf("/debug/config_distribution?resource=%s", targetResource)
+	if generated {
+		path += "&generated=true"
+	}
 	pilotResponses, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, path)
 	if err != nil {
 		return 0, 0, 0, fmt.Errorf("unable to query pilot for distribution "+
@@ -200,6 +215,12 @@ func poll(cmd *cobra.Command,
 		}
 		printVerbosef(cmd, "sync status: %+v", configVersions)
 		sdcnum += len(configVersions)
+		if generated {
+			p, np := countGeneratedResources(acceptedVersions, configVersions)
+			present += p
+			notpresent += np
+			continue
+		}
 		for _, configVersion := range configVersions {
 			countVersions(versionCount, configVersion.ClusterVersion)
 			countVersions(versionCount, configVersion.RouteVersion)
@@ -217,6 +238,30 @@ func poll(cmd *cobra.Command,
 	return present, notpresent, sdcnum, nil
 }
 
+// countGeneratedResources counts the proxies which acked an accepted version of all the resources generated from the
+// target resource, and the other proxies for which resources are generated from it.
+func countGeneratedResources(acceptedVersions []string, configVersions []xds.SyncedVersions) (present, notpresent int) {
+	for _, configVersion := range configVersions {
+		if len(configVersion.Resources) == 0 {
+			// The target resource does not apply to the proxy.
+			continue
+		}
+		acked := true
+		for _, r := range configVersion.Resources {
+			if !contains(acceptedVersions, r.Version) {
+				acked = false
+				break
+			}
+		}
+		if acked {
+			present++
+		} else {
+			notpresent++
+		}
+	}
+	return present, notpresent
+}
+
 func init() {
 	clientGetter = func(kubeconfig, context string) (dynamic.Interface, error) {
 		config, err := kube.DefaultRestConfig(kubeconfig, context)
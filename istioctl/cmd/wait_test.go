@@ -135,3 +135,21 @@ func newUnstructured(apiVersion, kind, namespace, name string, generation int64)
 		},
 	}
 }
+
+func TestCountGeneratedResources(t *testing.T) {
+	configVersions := []xds.SyncedVersions{
+		{ProxyID: "unrelated"},
+		{ProxyID: "acked", Resources: []xds.GeneratedResourceVersion{
+			{Type: "RDS", Name: "80", Version: "2"},
+			{Type: "CDS", Name: "outbound|80||foo.default.svc.cluster.local", Version: "1"},
+		}},
+		{ProxyID: "stale", Resources: []xds.GeneratedResourceVersion{
+			{Type: "RDS", Name: "80", Version: "2"},
+			{Type: "CDS", Name: "outbound|80||foo.default.svc.cluster.local", Version: "0"},
+		}},
+	}
+	present, notpresent := countGeneratedResources([]string{"1", "2"}, configVersions)
+	if present != 1 || notpresent != 1 {
+		t.Fatalf("got %d present and %d not present proxies, want 1 and 1", present, notpresent)
+	}
+}
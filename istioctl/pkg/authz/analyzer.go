@@ -45,6 +45,15 @@ func NewAnalyzer(envoyConfig *configdump.Wrapper) (*Analyzer, error) {
 
 // Print print sthe analyze results.
 func (a *Analyzer) Print(writer io.Writer) {
+	Print(writer, a.listeners())
+}
+
+// Policies returns the AuthorizationPolicies applied in the listeners of the pod.
+func (a *Analyzer) Policies() []Policy {
+	return Policies(a.listeners())
+}
+
+func (a *Analyzer) listeners() []*listener.Listener {
 	var listeners []*listener.Listener
 	for _, l := range a.listenerDump.DynamicListeners {
 		listenerTyped := &listener.Listener{}
@@ -52,9 +61,9 @@ func (a *Analyzer) Print(writer io.Writer) {
 		l.ActiveState.Listener.TypeUrl = v3.ListenerType
 		err := l.ActiveState.Listener.UnmarshalTo(listenerTyped)
 		if err != nil {
-			return
+			return nil
 		}
 		listeners = append(listeners, listenerTyped)
 	}
-	Print(writer, listeners)
+	return listeners
 }
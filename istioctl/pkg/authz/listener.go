@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
@@ -126,11 +127,21 @@ func extractName(name string) (string, string) {
 	return fmt.Sprintf("%s.%s", parts[2], parts[1]), parts[3]
 }
 
-// Print prints the AuthorizationPolicy in the listener.
-func Print(writer io.Writer, listeners []*listener.Listener) {
+// Policy is an AuthorizationPolicy applied in the listeners, as printed in the machine readable output formats.
+// Its fields are a stable schema for automation.
+type Policy struct {
+	Action string `json:"action"`
+	// Name is the name of the policy as name.namespace.
+	Name string `json:"name"`
+	// Rules is the number of rules of the policy.
+	Rules int `json:"rules"`
+}
+
+// Policies returns the AuthorizationPolicies applied in the listeners, ordered by action and then by name.
+func Policies(listeners []*listener.Listener) []Policy {
 	parsedListeners := parse(listeners)
 	if parsedListeners == nil {
-		return
+		return nil
 	}
 
 	actionToPolicy := map[rbacpb.RBAC_Action]map[string]struct{}{}
@@ -172,15 +183,32 @@ func Print(writer io.Writer, listeners []*listener.Listener) {
 		}
 	}
 
-	buf := strings.Builder{}
-	buf.WriteString("ACTION\tAuthorizationPolicy\tRULES\n")
+	policies := make([]Policy, 0)
 	for _, action := range []rbacpb.RBAC_Action{rbacpb.RBAC_DENY, rbacpb.RBAC_ALLOW, rbacpb.RBAC_LOG} {
-		if names, ok := actionToPolicy[action]; ok {
-			for name := range names {
-				buf.WriteString(fmt.Sprintf("%s\t%s\t%d\n", action, name, len(policyToRule[name])))
-			}
+		names := make([]string, 0, len(actionToPolicy[action]))
+		for name := range actionToPolicy[action] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			policies = append(policies, Policy{Action: action.String(), Name: name, Rules: len(policyToRule[name])})
 		}
 	}
+	return policies
+}
+
+// Print prints the AuthorizationPolicy in the listener.
+func Print(writer io.Writer, listeners []*listener.Listener) {
+	policies := Policies(listeners)
+	if policies == nil {
+		return
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString("ACTION\tAuthorizationPolicy\tRULES\n")
+	for _, p := range policies {
+		buf.WriteString(fmt.Sprintf("%s\t%s\t%d\n", p.Action, p.Name, p.Rules))
+	}
 
 	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
 	if _, err := fmt.Fprint(w, buf.String()); err != nil {
@@ -0,0 +1,271 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+)
+
+// contourHeaders are the header policies of a Contour route.
+type contourHeaders struct {
+	Set []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"set"`
+	Remove []string `json:"remove"`
+}
+
+// contourLoadBalancerPolicy is the load balancer policy of a Contour route.
+type contourLoadBalancerPolicy struct {
+	Strategy            string `json:"strategy"`
+	RequestHashPolicies []struct {
+		HeaderHashOptions *struct {
+			HeaderName string `json:"headerName"`
+		} `json:"headerHashOptions"`
+		HashSourceAddress bool `json:"hashSourceAddress"`
+	} `json:"requestHashPolicies"`
+}
+
+// httpProxySpec is the spec of a Contour HTTPProxy.
+type httpProxySpec struct {
+	VirtualHost *struct {
+		Fqdn string      `json:"fqdn"`
+		TLS  interface{} `json:"tls"`
+	} `json:"virtualhost"`
+	Includes []interface{} `json:"includes"`
+	TCPProxy interface{}   `json:"tcpproxy"`
+	Routes   []struct {
+		Conditions []struct {
+			Prefix string `json:"prefix"`
+			Header *struct {
+				Name     string `json:"name"`
+				Present  bool   `json:"present"`
+				Exact    string `json:"exact"`
+				Contains string `json:"contains"`
+			} `json:"header"`
+		} `json:"conditions"`
+		Services []struct {
+			Name     string `json:"name"`
+			Port     uint32 `json:"port"`
+			Weight   int64  `json:"weight"`
+			Protocol string `json:"protocol"`
+		} `json:"services"`
+		TimeoutPolicy *struct {
+			Response string `json:"response"`
+		} `json:"timeoutPolicy"`
+		RetryPolicy *struct {
+			Count         int32  `json:"count"`
+			PerTryTimeout string `json:"perTryTimeout"`
+		} `json:"retryPolicy"`
+		PathRewritePolicy *struct {
+			ReplacePrefix []struct {
+				Prefix      string `json:"prefix"`
+				Replacement string `json:"replacement"`
+			} `json:"replacePrefix"`
+		} `json:"pathRewritePolicy"`
+		LoadBalancerPolicy    *contourLoadBalancerPolicy `json:"loadBalancerPolicy"`
+		RequestHeadersPolicy  *contourHeaders            `json:"requestHeadersPolicy"`
+		ResponseHeadersPolicy *contourHeaders            `json:"responseHeadersPolicy"`
+	} `json:"routes"`
+}
+
+// convertHTTPProxy converts a root Contour HTTPProxy into a VirtualService bound to the gateway, and the load balancer
+// and protocol of its services into DestinationRules.
+func convertHTTPProxy(obj *crd.IstioKind, opts Options, r *Result) error {
+	spec := &httpProxySpec{}
+	if err := decodeSpec(obj, spec); err != nil {
+		return err
+	}
+	if spec.VirtualHost == nil {
+		r.unsupported(obj, "HTTPProxies without virtualhost must be merged into the VirtualService of their root, skipped")
+		return nil
+	}
+	if spec.VirtualHost.TLS != nil {
+		r.unsupported(obj, "tls must be configured on the servers of the gateway %s", opts.Gateway)
+	}
+	if len(spec.Includes) > 0 {
+		r.unsupported(obj, "includes are not supported, the routes of the included HTTPProxies must be added to the VirtualService")
+	}
+	if spec.TCPProxy != nil {
+		r.unsupported(obj, "tcpproxy is not supported")
+	}
+
+	vs := &networking.VirtualService{Hosts: []string{spec.VirtualHost.Fqdn}, Gateways: []string{opts.Gateway}}
+	policies := map[string]*networking.TrafficPolicy{}
+	for _, in := range spec.Routes {
+		route := &networking.HTTPRoute{}
+		match := &networking.HTTPMatchRequest{}
+		prefix := ""
+		for _, c := range in.Conditions {
+			prefix += c.Prefix
+			if h := c.Header; h != nil {
+				if match.Headers == nil {
+					match.Headers = map[string]*networking.StringMatch{}
+				}
+				switch {
+				case h.Exact != "":
+					match.Headers[strings.ToLower(h.Name)] = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: h.Exact}}
+				case h.Present:
+					match.Headers[strings.ToLower(h.Name)] = &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: ".*"}}
+				default:
+					r.unsupported(obj, "header condition on %s is not supported", h.Name)
+				}
+			}
+		}
+		if prefix != "" {
+			match.Uri = &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: prefix}}
+		}
+		if match.Uri != nil || match.Headers != nil {
+			route.Match = []*networking.HTTPMatchRequest{match}
+		}
+
+		loadBalancer := contourLoadBalancer(obj, r, in.LoadBalancerPolicy)
+		var destinations []*networking.Destination
+		var weights []int64
+		for _, s := range in.Services {
+			host := serviceHost(s.Name, obj.Namespace, opts)
+			destinations = append(destinations, destination(host, s.Port))
+			weights = append(weights, s.Weight)
+			switch s.Protocol {
+			case "":
+			case "tls", "h2":
+				trafficPolicy(policies, host).Tls = &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE}
+			default:
+				r.unsupported(obj, "protocol %s of service %s must be set by the name or appProtocol of its port", s.Protocol, s.Name)
+			}
+			if loadBalancer != nil {
+				trafficPolicy(policies, host).LoadBalancer = loadBalancer
+			}
+		}
+		if len(destinations) == 0 {
+			r.unsupported(obj, "route of prefix %q skipped: no service", prefix)
+			continue
+		}
+		route.Route = weightedDestinations(destinations, weights)
+
+		if t := in.TimeoutPolicy; t != nil && t.Response != "" && t.Response != "infinity" {
+			if d, err := duration(t.Response); err == nil {
+				route.Timeout = d
+			} else {
+				r.unsupported(obj, "invalid response timeout %q", t.Response)
+			}
+		}
+		if rp := in.RetryPolicy; rp != nil {
+			route.Retries = &networking.HTTPRetry{Attempts: rp.Count}
+			if rp.Count == 0 {
+				route.Retries.Attempts = 1
+			}
+			if rp.PerTryTimeout != "" {
+				if d, err := duration(rp.PerTryTimeout); err == nil {
+					route.Retries.PerTryTimeout = d
+				} else {
+					r.unsupported(obj, "invalid per try timeout %q", rp.PerTryTimeout)
+				}
+			}
+		}
+		if p := in.PathRewritePolicy; p != nil {
+			if len(p.ReplacePrefix) == 1 && (p.ReplacePrefix[0].Prefix == "" || p.ReplacePrefix[0].Prefix == prefix) {
+				route.Rewrite = &networking.HTTPRewrite{Uri: p.ReplacePrefix[0].Replacement}
+			} else {
+				r.unsupported(obj, "path rewrite policy of the route of prefix %q is not supported", prefix)
+			}
+		}
+		if in.RequestHeadersPolicy != nil || in.ResponseHeadersPolicy != nil {
+			route.Headers = &networking.Headers{
+				Request:  contourHeaderOperations(in.RequestHeadersPolicy),
+				Response: contourHeaderOperations(in.ResponseHeadersPolicy),
+			}
+		}
+		vs.Http = append(vs.Http, route)
+	}
+	// Contour selects the route of the longest prefix, and the routes with header conditions over the others.
+	sort.SliceStable(vs.Http, func(i, j int) bool {
+		pi, hi := contourRouteSpecificity(vs.Http[i])
+		pj, hj := contourRouteSpecificity(vs.Http[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return hi > hj
+	})
+	r.addVirtualService(obj, obj.Name, vs)
+	r.addDestinationRules(obj, policies)
+	return nil
+}
+
+// contourRouteSpecificity returns the length of the prefix and the number of headers matched by a converted route.
+func contourRouteSpecificity(route *networking.HTTPRoute) (prefix int, headers int) {
+	if len(route.Match) == 0 {
+		return 0, 0
+	}
+	return len(route.Match[0].GetUri().GetPrefix()), len(route.Match[0].Headers)
+}
+
+// contourLoadBalancer converts the load balancer strategy of a Contour route.
+func contourLoadBalancer(obj *crd.IstioKind, r *Result, policy *contourLoadBalancerPolicy) *networking.LoadBalancerSettings {
+	if policy == nil {
+		return nil
+	}
+	switch policy.Strategy {
+	case "", "RoundRobin":
+		return nil
+	case "WeightedLeastRequest":
+		return simpleLoadBalancer(networking.LoadBalancerSettings_LEAST_REQUEST)
+	case "Random":
+		return simpleLoadBalancer(networking.LoadBalancerSettings_RANDOM)
+	case "Cookie":
+		return consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+			HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpCookie{
+				HttpCookie: &networking.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{Name: "X-Contour-Session-Affinity", Ttl: &types.Duration{}},
+			},
+		})
+	case "RequestHash":
+		// Only the first hash policy is converted, as consistent hash load balancers hash a single key.
+		for _, p := range policy.RequestHashPolicies {
+			if p.HashSourceAddress {
+				return consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+					HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+				})
+			}
+			if p.HeaderHashOptions != nil && p.HeaderHashOptions.HeaderName != "" {
+				return consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+					HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: p.HeaderHashOptions.HeaderName},
+				})
+			}
+		}
+	}
+	r.unsupported(obj, "load balancer strategy %s is not supported", policy.Strategy)
+	return nil
+}
+
+// contourHeaderOperations converts the header policy of a Contour route.
+func contourHeaderOperations(policy *contourHeaders) *networking.Headers_HeaderOperations {
+	if policy == nil {
+		return nil
+	}
+	ops := &networking.Headers_HeaderOperations{Remove: policy.Remove}
+	for _, h := range policy.Set {
+		if ops.Set == nil {
+			ops.Set = map[string]string{}
+		}
+		ops.Set[h.Name] = h.Value
+	}
+	return ops
+}
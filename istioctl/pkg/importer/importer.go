@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package importer converts the traffic configuration of other ingress controllers and service meshes into Istio
+// VirtualServices and DestinationRules, reporting the features which have no equivalent.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// Options of the conversion.
+type Options struct {
+	// Gateway is the gateway the VirtualServices converted from ingress resources are bound to, such as
+	// istio-system/ingressgateway.
+	Gateway string
+	// DomainSuffix is the domain suffix of the Kubernetes services.
+	DomainSuffix string
+}
+
+// Result holds the converted configs, and the features of the imported resources which were not converted.
+type Result struct {
+	Configs     []config.Config
+	Unsupported []string
+}
+
+// converter converts a resource of another ingress controller or service mesh.
+type converter func(obj *crd.IstioKind, opts Options, r *Result) error
+
+// converters are the converters of the supported resources, by API version and kind.
+var converters = map[string]converter{
+	"networking.k8s.io/v1/Ingress":              convertIngress,
+	"traefik.containo.us/v1alpha1/IngressRoute": convertIngressRoute,
+	"projectcontour.io/v1/HTTPProxy":            convertHTTPProxy,
+	"linkerd.io/v1alpha2/ServiceProfile":        convertServiceProfile,
+}
+
+// Parse reads the resources of a YAML or JSON stream, expanding the lists such as those printed by kubectl get.
+func Parse(data []byte) ([]crd.IstioKind, error) {
+	var out []crd.IstioKind
+	decoder := kubeyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 512*1024)
+	for {
+		obj := struct {
+			crd.IstioKind `json:",inline"`
+			Items         []crd.IstioKind `json:"items"`
+		}{}
+		if err := decoder.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case obj.Kind == "List" || strings.HasSuffix(obj.Kind, "List"):
+			out = append(out, obj.Items...)
+		case obj.Kind != "":
+			out = append(out, obj.IstioKind)
+		}
+	}
+	return out, nil
+}
+
+// Import converts the resources into Istio configs. The resources of unsupported kinds are reported and skipped.
+func Import(objects []crd.IstioKind, opts Options) (*Result, error) {
+	r := &Result{}
+	for i := range objects {
+		obj := &objects[i]
+		if obj.Namespace == "" {
+			obj.Namespace = "default"
+		}
+		convert, f := converters[obj.APIVersion+"/"+obj.Kind]
+		if !f {
+			r.unsupported(obj, "kind %s is not supported, skipped", obj.APIVersion+"/"+obj.Kind)
+			continue
+		}
+		if err := convert(obj, opts, r); err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s/%s: %v", obj.Kind, obj.Namespace, obj.Name, err)
+		}
+	}
+	for _, cfg := range r.Configs {
+		schema := collections.IstioNetworkingV1Alpha3Virtualservices
+		if cfg.GroupVersionKind == gvk.DestinationRule {
+			schema = collections.IstioNetworkingV1Alpha3Destinationrules
+		}
+		if _, err := schema.Resource().ValidateConfig(cfg); err != nil {
+			r.Unsupported = append(r.Unsupported, fmt.Sprintf("%s %s/%s: generated config is invalid: %v",
+				cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, err))
+		}
+	}
+	return r, nil
+}
+
+// unsupported reports a feature of the resource which was not converted, once.
+func (r *Result) unsupported(obj *crd.IstioKind, format string, args ...interface{}) {
+	msg := fmt.Sprintf("%s %s/%s: ", obj.Kind, obj.Namespace, obj.Name) + fmt.Sprintf(format, args...)
+	for _, m := range r.Unsupported {
+		if m == msg {
+			return
+		}
+	}
+	r.Unsupported = append(r.Unsupported, msg)
+}
+
+// addVirtualService adds a VirtualService of the namespace of the resource.
+func (r *Result) addVirtualService(obj *crd.IstioKind, name string, vs *networking.VirtualService) {
+	r.Configs = append(r.Configs, config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: name, Namespace: obj.Namespace},
+		Spec: vs,
+	})
+}
+
+// addDestinationRules adds the DestinationRules of the traffic policies of the hosts, named after the resource and
+// the host.
+func (r *Result) addDestinationRules(obj *crd.IstioKind, policies map[string]*networking.TrafficPolicy) {
+	hosts := make([]string, 0, len(policies))
+	for h := range policies {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		r.Configs = append(r.Configs, config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.DestinationRule,
+				Name:             obj.Name + "-" + strings.SplitN(h, ".", 2)[0],
+				Namespace:        obj.Namespace,
+			},
+			Spec: &networking.DestinationRule{Host: h, TrafficPolicy: policies[h]},
+		})
+	}
+}
+
+// decodeSpec decodes the spec of the resource.
+func decodeSpec(obj *crd.IstioKind, out interface{}) error {
+	b, err := json.Marshal(obj.Spec)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// serviceHost returns the hostname of the Kubernetes service.
+func serviceHost(name, namespace string, opts Options) string {
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, opts.DomainSuffix)
+}
+
+// destination returns the destination of the service and port, which is omitted if 0.
+func destination(host string, port uint32) *networking.Destination {
+	d := &networking.Destination{Host: host}
+	if port != 0 {
+		d.Port = &networking.PortSelector{Number: port}
+	}
+	return d
+}
+
+// weightedDestinations returns the destinations with their weights converted to percentages. Destinations without
+// weight, or with a negative one, share the traffic equally when none has one, and get none otherwise.
+func weightedDestinations(destinations []*networking.Destination, weights []int64) []*networking.HTTPRouteDestination {
+	out := make([]*networking.HTTPRouteDestination, 0, len(destinations))
+	if len(destinations) == 1 {
+		return append(out, &networking.HTTPRouteDestination{Destination: destinations[0]})
+	}
+	total := int64(0)
+	for i, w := range weights {
+		if w < 0 {
+			weights[i] = 0
+			continue
+		}
+		total += w
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = int64(len(weights))
+	}
+	// Distribute the remainder of the rounding to the first destinations, so that the weights sum up to 100.
+	remainder := int32(100)
+	percents := make([]int32, len(weights))
+	for i, w := range weights {
+		percents[i] = int32(w * 100 / total)
+		remainder -= percents[i]
+	}
+	for i := 0; remainder > 0; i = (i + 1) % len(percents) {
+		if weights[i] > 0 {
+			percents[i]++
+			remainder--
+		}
+	}
+	for i, d := range destinations {
+		if percents[i] > 0 {
+			out = append(out, &networking.HTTPRouteDestination{Destination: d, Weight: percents[i]})
+		}
+	}
+	return out
+}
+
+// duration converts a Go duration string.
+func duration(value string) (*types.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, err
+	}
+	return types.DurationProto(d), nil
+}
+
+// trafficPolicy returns the traffic policy of the host, adding it if needed.
+func trafficPolicy(policies map[string]*networking.TrafficPolicy, host string) *networking.TrafficPolicy {
+	if policies[host] == nil {
+		policies[host] = &networking.TrafficPolicy{}
+	}
+	return policies[host]
+}
+
+// consistentHash returns a load balancer hashing the requests.
+func consistentHash(hash *networking.LoadBalancerSettings_ConsistentHashLB) *networking.LoadBalancerSettings {
+	return &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{ConsistentHash: hash},
+	}
+}
+
+// simpleLoadBalancer returns a load balancer of the policy.
+func simpleLoadBalancer(policy networking.LoadBalancerSettings_SimpleLB) *networking.LoadBalancerSettings {
+	return &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: policy},
+	}
+}
+
+// hostName returns a name for the config of the host, with the dots replaced.
+func hostName(host string) string {
+	if host == "" || host == "*" {
+		return "wildcard"
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(host, "*", "wildcard"), ".", "-")
+}
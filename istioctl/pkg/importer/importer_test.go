@@ -0,0 +1,302 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+var testOptions = Options{Gateway: "istio-system/ingressgateway", DomainSuffix: "cluster.local"}
+
+func importYAML(t *testing.T, input string) *Result {
+	t.Helper()
+	objects, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Import(objects, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range r.Unsupported {
+		if strings.Contains(u, "generated config is invalid") {
+			t.Fatal(u)
+		}
+	}
+	return r
+}
+
+func findConfig(t *testing.T, r *Result, kind, name string) config.Config {
+	t.Helper()
+	for _, cfg := range r.Configs {
+		if cfg.GroupVersionKind.Kind == kind && cfg.Name == name {
+			return cfg
+		}
+	}
+	t.Fatalf("%s %s not found in %v", kind, name, r.Configs)
+	return config.Config{}
+}
+
+func assertUnsupported(t *testing.T, r *Result, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		found := false
+		for _, u := range r.Unsupported {
+			found = found || strings.Contains(u, w)
+		}
+		if !found {
+			t.Errorf("%q not reported in %v", w, r.Unsupported)
+		}
+	}
+	if len(r.Unsupported) != len(want) {
+		t.Errorf("got unsupported %v, want %d entries", r.Unsupported, len(want))
+	}
+}
+
+func exact(s string) *networking.StringMatch {
+	return &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: s}}
+}
+
+func prefix(s string) *networking.StringMatch {
+	return &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: s}}
+}
+
+func TestImportIngress(t *testing.T) {
+	r := importYAML(t, `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: networking.k8s.io/v1
+  kind: Ingress
+  metadata:
+    name: web
+    namespace: shop
+    annotations:
+      nginx.ingress.kubernetes.io/proxy-read-timeout: "30"
+      nginx.ingress.kubernetes.io/proxy-next-upstream-tries: "3"
+      nginx.ingress.kubernetes.io/enable-cors: "true"
+      nginx.ingress.kubernetes.io/cors-allow-origin: "https://shop.example.com"
+      nginx.ingress.kubernetes.io/affinity: cookie
+      nginx.ingress.kubernetes.io/session-cookie-name: route
+      nginx.ingress.kubernetes.io/whitelist-source-range: 10.0.0.0/8
+  spec:
+    rules:
+    - host: shop.example.com
+      http:
+        paths:
+        - path: /
+          pathType: Prefix
+          backend:
+            service:
+              name: frontend
+              port:
+                number: 80
+        - path: /api
+          pathType: Prefix
+          backend:
+            service:
+              name: api
+              port:
+                name: http
+`)
+	assertUnsupported(t, r, "named port http of service api", "annotation nginx.ingress.kubernetes.io/whitelist-source-range",
+		"nginx.ingress.kubernetes.io/proxy-read-timeout bounds the time between two reads")
+	vs := findConfig(t, r, "VirtualService", "web").Spec.(*networking.VirtualService)
+	assert.Equal(t, vs.Hosts, []string{"shop.example.com"})
+	assert.Equal(t, vs.Gateways, []string{"istio-system/ingressgateway"})
+	assert.Equal(t, len(vs.Http), 2)
+	assert.Equal(t, vs.Http[0].Match, []*networking.HTTPMatchRequest{{Uri: exact("/api")}, {Uri: prefix("/api/")}})
+	assert.Equal(t, vs.Http[0].Route[0].Destination.Host, "api.shop.svc.cluster.local")
+	assert.Equal(t, vs.Http[1].Match, []*networking.HTTPMatchRequest{{Uri: prefix("/")}})
+	assert.Equal(t, vs.Http[1].Route[0].Destination.Port.Number, uint32(80))
+	assert.Equal(t, vs.Http[1].Timeout, (*types.Duration)(nil))
+	assert.Equal(t, vs.Http[1].Retries, &networking.HTTPRetry{Attempts: 2})
+	assert.Equal(t, vs.Http[1].CorsPolicy.AllowOrigins, []*networking.StringMatch{exact("https://shop.example.com")})
+
+	dr := findConfig(t, r, "DestinationRule", "web-frontend").Spec.(*networking.DestinationRule)
+	assert.Equal(t, dr.Host, "frontend.shop.svc.cluster.local")
+	assert.Equal(t, dr.TrafficPolicy.LoadBalancer.GetConsistentHash().GetHttpCookie().Name, "route")
+}
+
+func TestImportIngressRoute(t *testing.T) {
+	r := importYAML(t, `
+apiVersion: traefik.containo.us/v1alpha1
+kind: IngressRoute
+metadata:
+  name: blog
+spec:
+  routes:
+  - match: Host(`+"`blog.example.com`"+`)
+    kind: Rule
+    services:
+    - name: blog
+      port: 80
+  - match: Host(`+"`blog.example.com`"+`) && PathPrefix(`+"`/admin`"+`) && Headers(`+"`X-Canary`, `true`"+`)
+    kind: Rule
+    middlewares:
+    - name: auth
+    services:
+    - name: admin-v1
+      port: 80
+      weight: 3
+    - name: admin-v2
+      port: 80
+      weight: 1
+  - match: Host(`+"`blog.example.com`"+`) || Host(`+"`www.example.com`"+`)
+    kind: Rule
+    services:
+    - name: blog
+      port: 80
+`)
+	assertUnsupported(t, r, "middlewares of route", "only rules combining matchers with && are supported")
+	vs := findConfig(t, r, "VirtualService", "blog").Spec.(*networking.VirtualService)
+	assert.Equal(t, vs.Hosts, []string{"blog.example.com"})
+	assert.Equal(t, len(vs.Http), 2)
+	// The longest rule has the highest priority.
+	assert.Equal(t, vs.Http[0].Match, []*networking.HTTPMatchRequest{{
+		Uri:     prefix("/admin"),
+		Headers: map[string]*networking.StringMatch{"x-canary": exact("true")},
+	}})
+	assert.Equal(t, vs.Http[0].Route[0].Weight, int32(75))
+	assert.Equal(t, vs.Http[0].Route[1].Weight, int32(25))
+	assert.Equal(t, vs.Http[1].Route[0].Destination.Host, "blog.default.svc.cluster.local")
+}
+
+func TestImportHTTPProxy(t *testing.T) {
+	r := importYAML(t, `
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: store
+  namespace: shop
+spec:
+  virtualhost:
+    fqdn: store.example.com
+  routes:
+  - services:
+    - name: store
+      port: 8080
+  - conditions:
+    - prefix: /cart
+    services:
+    - name: cart
+      port: 8080
+      weight: 90
+    - name: cart-canary
+      port: 8080
+      weight: 10
+    timeoutPolicy:
+      response: 5s
+    retryPolicy:
+      count: 3
+      perTryTimeout: 500ms
+    pathRewritePolicy:
+      replacePrefix:
+      - replacement: /
+    loadBalancerPolicy:
+      strategy: RequestHash
+      requestHashPolicies:
+      - headerHashOptions:
+          headerName: x-user
+`)
+	assertUnsupported(t, r)
+	vs := findConfig(t, r, "VirtualService", "store").Spec.(*networking.VirtualService)
+	assert.Equal(t, len(vs.Http), 2)
+	cart := vs.Http[0]
+	assert.Equal(t, cart.Match, []*networking.HTTPMatchRequest{{Uri: prefix("/cart")}})
+	assert.Equal(t, cart.Route[0].Weight, int32(90))
+	assert.Equal(t, cart.Route[1].Weight, int32(10))
+	assert.Equal(t, cart.Timeout, &types.Duration{Seconds: 5})
+	assert.Equal(t, cart.Retries, &networking.HTTPRetry{Attempts: 3, PerTryTimeout: &types.Duration{Nanos: 500000000}})
+	assert.Equal(t, cart.Rewrite, &networking.HTTPRewrite{Uri: "/"})
+	assert.Equal(t, vs.Http[1].Match, []*networking.HTTPMatchRequest(nil))
+
+	dr := findConfig(t, r, "DestinationRule", "store-cart-canary").Spec.(*networking.DestinationRule)
+	assert.Equal(t, dr.TrafficPolicy.LoadBalancer.GetConsistentHash().GetHttpHeaderName(), "x-user")
+}
+
+func TestImportServiceProfile(t *testing.T) {
+	r := importYAML(t, `
+apiVersion: linkerd.io/v1alpha2
+kind: ServiceProfile
+metadata:
+  name: books.library.svc.cluster.local
+  namespace: library
+spec:
+  routes:
+  - name: GET /books/{id}
+    condition:
+      method: GET
+      pathRegex: /books/[^/]*
+    isRetryable: true
+    timeout: 300ms
+  - name: POST /books
+    condition:
+      all:
+      - method: POST
+      - pathRegex: /books
+  retryBudget:
+    retryRatio: 0.2
+    minRetriesPerSecond: 10
+    ttl: 10s
+  dstOverrides:
+  - authority: books.library.svc.cluster.local:7000
+    weight: 900m
+  - authority: books-v2.library.svc.cluster.local:7000
+    weight: 100m
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: books
+`)
+	assertUnsupported(t, r, "retryBudget is not supported", `route "POST /books" skipped`, "kind v1/Service is not supported")
+	vs := findConfig(t, r, "VirtualService", "books.library.svc.cluster.local").Spec.(*networking.VirtualService)
+	assert.Equal(t, vs.Hosts, []string{"books.library.svc.cluster.local"})
+	assert.Equal(t, len(vs.Http), 2)
+	get := vs.Http[0]
+	assert.Equal(t, get.Name, "GET /books/{id}")
+	assert.Equal(t, get.Match, []*networking.HTTPMatchRequest{{
+		Method: exact("GET"),
+		Uri:    &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "/books/[^/]*"}},
+	}})
+	assert.Equal(t, get.Timeout, &types.Duration{Nanos: 300000000})
+	assert.Equal(t, get.Retries.Attempts, int32(serviceProfileRetryAttempts))
+	assert.Equal(t, get.Route[0].Weight, int32(90))
+	assert.Equal(t, get.Route[1].Destination.Host, "books-v2.library.svc.cluster.local")
+	assert.Equal(t, get.Route[1].Destination.Port.Number, uint32(7000))
+	assert.Equal(t, vs.Http[1].Route, get.Route)
+}
+
+func TestWeightedDestinations(t *testing.T) {
+	destinations := []*networking.Destination{{Host: "a"}, {Host: "b"}, {Host: "c"}}
+	got := weightedDestinations(destinations, []int64{0, 0, 0})
+	assert.Equal(t, []int32{got[0].Weight, got[1].Weight, got[2].Weight}, []int32{34, 33, 33})
+	got = weightedDestinations(destinations, []int64{1, 0, 1})
+	assert.Equal(t, len(got), 2)
+	assert.Equal(t, []int32{got[0].Weight, got[1].Weight}, []int32{50, 50})
+	got = weightedDestinations(destinations, []int64{-1, -2, -3})
+	assert.Equal(t, []int32{got[0].Weight, got[1].Weight, got[2].Weight}, []int32{34, 33, 33})
+	got = weightedDestinations(destinations, []int64{-1, 3, 1})
+	assert.Equal(t, len(got), 2)
+	assert.Equal(t, []int32{got[0].Weight, got[1].Weight}, []int32{75, 25})
+}
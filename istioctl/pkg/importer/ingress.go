@@ -0,0 +1,352 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+	knetworking "k8s.io/api/networking/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+)
+
+// nginxAnnotationPrefix is the prefix of the annotations of the Ingress-NGINX controller.
+const nginxAnnotationPrefix = "nginx.ingress.kubernetes.io/"
+
+// Ingress-NGINX annotations converted into VirtualServices and DestinationRules.
+const (
+	nginxRewriteTarget          = nginxAnnotationPrefix + "rewrite-target"
+	nginxUseRegex               = nginxAnnotationPrefix + "use-regex"
+	nginxProxyReadTimeout       = nginxAnnotationPrefix + "proxy-read-timeout"
+	nginxProxyConnectTimeout    = nginxAnnotationPrefix + "proxy-connect-timeout"
+	nginxProxyNextUpstreamTries = nginxAnnotationPrefix + "proxy-next-upstream-tries"
+	nginxEnableCors             = nginxAnnotationPrefix + "enable-cors"
+	nginxCorsAllowOrigin        = nginxAnnotationPrefix + "cors-allow-origin"
+	nginxCorsAllowMethods       = nginxAnnotationPrefix + "cors-allow-methods"
+	nginxCorsAllowHeaders       = nginxAnnotationPrefix + "cors-allow-headers"
+	nginxCorsExposeHeaders      = nginxAnnotationPrefix + "cors-expose-headers"
+	nginxCorsAllowCredentials   = nginxAnnotationPrefix + "cors-allow-credentials"
+	nginxCorsMaxAge             = nginxAnnotationPrefix + "cors-max-age"
+	nginxLoadBalance            = nginxAnnotationPrefix + "load-balance"
+	nginxUpstreamHashBy         = nginxAnnotationPrefix + "upstream-hash-by"
+	nginxAffinity               = nginxAnnotationPrefix + "affinity"
+	nginxSessionCookieName      = nginxAnnotationPrefix + "session-cookie-name"
+	nginxSessionCookieMaxAge    = nginxAnnotationPrefix + "session-cookie-max-age"
+	nginxBackendProtocol        = nginxAnnotationPrefix + "backend-protocol"
+)
+
+// ingressRoute is an HTTP route of an Ingress, with the length of its path for sorting.
+type ingressRoute struct {
+	route *networking.HTTPRoute
+	path  string
+	exact bool
+}
+
+// convertIngress converts an Ingress, and the annotations of the Ingress-NGINX controller, into a VirtualService
+// bound to the gateway for each of its hosts.
+func convertIngress(obj *crd.IstioKind, opts Options, r *Result) error {
+	spec := &knetworking.IngressSpec{}
+	if err := decodeSpec(obj, spec); err != nil {
+		return err
+	}
+	a := nginxAnnotations{obj: obj, r: r}
+	if len(spec.TLS) > 0 {
+		r.unsupported(obj, "tls must be configured on the servers of the gateway %s", opts.Gateway)
+	}
+
+	policies := map[string]*networking.TrafficPolicy{}
+	routesByHost := map[string][]ingressRoute{}
+	var hosts []string
+	for _, rule := range spec.Rules {
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		if _, f := routesByHost[host]; !f {
+			hosts = append(hosts, host)
+			routesByHost[host] = nil
+		}
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			route := a.route(&p.Backend, obj.Namespace, opts, policies)
+			if route == nil {
+				continue
+			}
+			path, exact := p.Path, p.PathType != nil && *p.PathType == knetworking.PathTypeExact
+			if path == "" {
+				path = "/"
+			}
+			route.Match = a.matches(path, p.PathType)
+			routesByHost[host] = append(routesByHost[host], ingressRoute{route: route, path: path, exact: exact})
+		}
+	}
+	if spec.DefaultBackend != nil {
+		if len(hosts) == 0 {
+			hosts = append(hosts, "*")
+		}
+		for _, host := range hosts {
+			if route := a.route(spec.DefaultBackend, obj.Namespace, opts, policies); route != nil {
+				routesByHost[host] = append(routesByHost[host], ingressRoute{route: route})
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		routes := routesByHost[host]
+		if len(routes) == 0 {
+			continue
+		}
+		// The longest paths take precedence, and exact paths over prefixes of the same length. The default backend,
+		// without path, comes last.
+		sort.SliceStable(routes, func(i, j int) bool {
+			if len(routes[i].path) != len(routes[j].path) {
+				return len(routes[i].path) > len(routes[j].path)
+			}
+			return routes[i].exact && !routes[j].exact
+		})
+		vs := &networking.VirtualService{Hosts: []string{host}, Gateways: []string{opts.Gateway}}
+		for _, route := range routes {
+			vs.Http = append(vs.Http, route.route)
+		}
+		name := obj.Name
+		if len(hosts) > 1 {
+			name += "-" + hostName(host)
+		}
+		r.addVirtualService(obj, name, vs)
+	}
+	r.addDestinationRules(obj, policies)
+	a.reportUnsupported()
+	return nil
+}
+
+// nginxAnnotations converts the Ingress-NGINX annotations of an Ingress.
+type nginxAnnotations struct {
+	obj *crd.IstioKind
+	r   *Result
+}
+
+// get returns the value of the annotation.
+func (a nginxAnnotations) get(name string) (string, bool) {
+	v, f := a.obj.Annotations[name]
+	return strings.TrimSpace(v), f
+}
+
+// route returns the route of the backend, and sets the traffic policy of its service.
+func (a nginxAnnotations) route(backend *knetworking.IngressBackend, namespace string, opts Options,
+	policies map[string]*networking.TrafficPolicy) *networking.HTTPRoute {
+	if backend.Service == nil {
+		a.r.unsupported(a.obj, "resource backends are not supported, skipped")
+		return nil
+	}
+	host := serviceHost(backend.Service.Name, namespace, opts)
+	if backend.Service.Port.Name != "" {
+		a.r.unsupported(a.obj, "named port %s of service %s must be replaced by its number", backend.Service.Port.Name,
+			backend.Service.Name)
+	}
+	route := &networking.HTTPRoute{
+		Route: []*networking.HTTPRouteDestination{{Destination: destination(host, uint32(backend.Service.Port.Number))}},
+	}
+	if v, f := a.get(nginxRewriteTarget); f {
+		if strings.Contains(v, "$") {
+			a.r.unsupported(a.obj, "%s with capture groups is not supported", nginxRewriteTarget)
+		} else {
+			route.Rewrite = &networking.HTTPRewrite{Uri: v}
+		}
+	}
+	if _, f := a.get(nginxProxyReadTimeout); f {
+		a.r.unsupported(a.obj, "%s bounds the time between two reads of the response, set the timeout of the route "+
+			"to the longest expected response time instead", nginxProxyReadTimeout)
+	}
+	if v, f := a.get(nginxProxyNextUpstreamTries); f {
+		// The tries include the first attempt, which the retries of the route do not.
+		if tries, err := strconv.ParseInt(v, 10, 32); err == nil && tries > 0 {
+			route.Retries = &networking.HTTPRetry{Attempts: int32(tries - 1)}
+		} else {
+			a.r.unsupported(a.obj, "invalid %s %q", nginxProxyNextUpstreamTries, v)
+		}
+	}
+	if v, _ := a.get(nginxEnableCors); v == "true" {
+		route.CorsPolicy = a.corsPolicy()
+	}
+	a.trafficPolicy(host, policies)
+	return route
+}
+
+// matches returns the matches of the path of an Ingress. Ingress-NGINX matches the prefixes of the paths of the
+// ImplementationSpecific type, and the regular expressions of all the paths with the use-regex annotation.
+func (a nginxAnnotations) matches(path string, pathType *knetworking.PathType) []*networking.HTTPMatchRequest {
+	if v, _ := a.get(nginxUseRegex); v == "true" {
+		return []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: path + ".*"}}}}
+	}
+	if pathType != nil && *pathType == knetworking.PathTypeExact {
+		return []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: path}}}}
+	}
+	if pathType != nil && *pathType == knetworking.PathTypePrefix && path != "/" {
+		// Prefixes match whole path elements: /foo matches /foo and /foo/bar, but not /foobar.
+		path = strings.TrimSuffix(path, "/")
+		return []*networking.HTTPMatchRequest{
+			{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: path}}},
+			{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: path + "/"}}},
+		}
+	}
+	return []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: path}}}}
+}
+
+// corsPolicy returns the CORS policy of the cors annotations.
+func (a nginxAnnotations) corsPolicy() *networking.CorsPolicy {
+	cors := &networking.CorsPolicy{}
+	origins := "*"
+	if v, f := a.get(nginxCorsAllowOrigin); f {
+		origins = v
+	}
+	for _, origin := range splitList(origins) {
+		if origin == "*" {
+			cors.AllowOrigins = append(cors.AllowOrigins, &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: ".*"}})
+		} else {
+			cors.AllowOrigins = append(cors.AllowOrigins, &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: origin}})
+		}
+	}
+	if v, f := a.get(nginxCorsAllowMethods); f {
+		cors.AllowMethods = splitList(v)
+	}
+	if v, f := a.get(nginxCorsAllowHeaders); f {
+		cors.AllowHeaders = splitList(v)
+	}
+	if v, f := a.get(nginxCorsExposeHeaders); f {
+		cors.ExposeHeaders = splitList(v)
+	}
+	if v, f := a.get(nginxCorsAllowCredentials); f {
+		cors.AllowCredentials = &types.BoolValue{Value: v == "true"}
+	}
+	if v, f := a.get(nginxCorsMaxAge); f {
+		cors.MaxAge = a.seconds(nginxCorsMaxAge, v)
+	}
+	return cors
+}
+
+// trafficPolicy sets the traffic policy of the host from the annotations.
+func (a nginxAnnotations) trafficPolicy(host string, policies map[string]*networking.TrafficPolicy) {
+	if v, f := a.get(nginxProxyConnectTimeout); f {
+		if timeout := a.seconds(nginxProxyConnectTimeout, v); timeout != nil {
+			trafficPolicy(policies, host).ConnectionPool = &networking.ConnectionPoolSettings{
+				Tcp: &networking.ConnectionPoolSettings_TCPSettings{ConnectTimeout: timeout},
+			}
+		}
+	}
+	if v, f := a.get(nginxLoadBalance); f {
+		switch v {
+		case "round_robin":
+			trafficPolicy(policies, host).LoadBalancer = simpleLoadBalancer(networking.LoadBalancerSettings_ROUND_ROBIN)
+		case "ewma":
+			trafficPolicy(policies, host).LoadBalancer = simpleLoadBalancer(networking.LoadBalancerSettings_LEAST_REQUEST)
+		default:
+			a.r.unsupported(a.obj, "%s %q is not supported", nginxLoadBalance, v)
+		}
+	}
+	if v, f := a.get(nginxUpstreamHashBy); f {
+		switch {
+		case v == "$remote_addr" || v == "$binary_remote_addr":
+			trafficPolicy(policies, host).LoadBalancer = consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+			})
+		case strings.HasPrefix(v, "$http_") && !strings.ContainsAny(v[1:], "$ "):
+			header := strings.ReplaceAll(strings.TrimPrefix(v, "$http_"), "_", "-")
+			trafficPolicy(policies, host).LoadBalancer = consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: header},
+			})
+		case strings.HasPrefix(v, "$arg_") && !strings.ContainsAny(v[1:], "$ "):
+			trafficPolicy(policies, host).LoadBalancer = consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpQueryParameterName{
+					HttpQueryParameterName: strings.TrimPrefix(v, "$arg_"),
+				},
+			})
+		default:
+			a.r.unsupported(a.obj, "%s %q is not supported", nginxUpstreamHashBy, v)
+		}
+	}
+	if v, _ := a.get(nginxAffinity); v == "cookie" {
+		cookie := &networking.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{Name: "INGRESSCOOKIE", Ttl: &types.Duration{}}
+		if v, f := a.get(nginxSessionCookieName); f {
+			cookie.Name = v
+		}
+		if v, f := a.get(nginxSessionCookieMaxAge); f {
+			if ttl := a.seconds(nginxSessionCookieMaxAge, v); ttl != nil {
+				cookie.Ttl = ttl
+			}
+		}
+		trafficPolicy(policies, host).LoadBalancer = consistentHash(&networking.LoadBalancerSettings_ConsistentHashLB{
+			HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpCookie{HttpCookie: cookie},
+		})
+	}
+	if v, f := a.get(nginxBackendProtocol); f {
+		switch strings.ToUpper(v) {
+		case "HTTP":
+		case "HTTPS", "GRPCS":
+			trafficPolicy(policies, host).Tls = &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE}
+		default:
+			a.r.unsupported(a.obj, "%s %q must be set by the name or appProtocol of the port of the service", nginxBackendProtocol, v)
+		}
+	}
+}
+
+// seconds converts the value of an annotation in seconds.
+func (a nginxAnnotations) seconds(name, value string) *types.Duration {
+	s, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || s < 0 {
+		a.r.unsupported(a.obj, "invalid %s %q", name, value)
+		return nil
+	}
+	return &types.Duration{Seconds: s}
+}
+
+// convertedNginxAnnotations are the Ingress-NGINX annotations converted, or depending on those converted.
+var convertedNginxAnnotations = map[string]bool{
+	nginxRewriteTarget: true, nginxUseRegex: true, nginxProxyReadTimeout: true, nginxProxyConnectTimeout: true,
+	nginxProxyNextUpstreamTries: true, nginxEnableCors: true, nginxCorsAllowOrigin: true, nginxCorsAllowMethods: true,
+	nginxCorsAllowHeaders: true, nginxCorsExposeHeaders: true, nginxCorsAllowCredentials: true, nginxCorsMaxAge: true,
+	nginxLoadBalance: true, nginxUpstreamHashBy: true, nginxAffinity: true, nginxSessionCookieName: true,
+	nginxSessionCookieMaxAge: true, nginxBackendProtocol: true,
+}
+
+// reportUnsupported reports the Ingress-NGINX annotations which are not converted.
+func (a nginxAnnotations) reportUnsupported() {
+	names := make([]string, 0)
+	for name := range a.obj.Annotations {
+		if strings.HasPrefix(name, nginxAnnotationPrefix) && !convertedNginxAnnotations[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a.r.unsupported(a.obj, "annotation %s is not supported", name)
+	}
+}
+
+// splitList splits a comma separated list.
+func splitList(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
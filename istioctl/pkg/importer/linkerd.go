@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"net"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+)
+
+// serviceProfileSpec is the spec of a Linkerd ServiceProfile.
+type serviceProfileSpec struct {
+	Routes []struct {
+		Name      string `json:"name"`
+		Condition struct {
+			Method    string        `json:"method"`
+			PathRegex string        `json:"pathRegex"`
+			All       []interface{} `json:"all"`
+			Any       []interface{} `json:"any"`
+			Not       interface{}   `json:"not"`
+		} `json:"condition"`
+		ResponseClasses []interface{} `json:"responseClasses"`
+		IsRetryable     bool          `json:"isRetryable"`
+		Timeout         string        `json:"timeout"`
+	} `json:"routes"`
+	RetryBudget  interface{} `json:"retryBudget"`
+	DstOverrides []struct {
+		Authority string `json:"authority"`
+		Weight    string `json:"weight"`
+	} `json:"dstOverrides"`
+}
+
+// serviceProfileRetryAttempts is the number of retries of the retryable routes of a ServiceProfile. Linkerd bounds
+// the retries by a budget rather than by request.
+const serviceProfileRetryAttempts = 2
+
+// convertServiceProfile converts a Linkerd ServiceProfile into a VirtualService of its service, with a route for each
+// of the routes of the profile, and a default route.
+func convertServiceProfile(obj *crd.IstioKind, opts Options, r *Result) error {
+	spec := &serviceProfileSpec{}
+	if err := decodeSpec(obj, spec); err != nil {
+		return err
+	}
+	if spec.RetryBudget != nil {
+		r.unsupported(obj, "retryBudget is not supported, retryable routes are retried %d times", serviceProfileRetryAttempts)
+	}
+	host := obj.Name
+	destinations := []*networking.HTTPRouteDestination{{Destination: destination(host, 0)}}
+	if len(spec.DstOverrides) > 0 {
+		var dsts []*networking.Destination
+		var weights []int64
+		for _, o := range spec.DstOverrides {
+			d, err := authorityDestination(o.Authority)
+			if err != nil {
+				r.unsupported(obj, "invalid dstOverride authority %q: %v", o.Authority, err)
+				continue
+			}
+			weight, err := resource.ParseQuantity(o.Weight)
+			if err != nil {
+				r.unsupported(obj, "invalid dstOverride weight %q: %v", o.Weight, err)
+				continue
+			}
+			dsts = append(dsts, d)
+			weights = append(weights, weight.MilliValue())
+		}
+		if len(dsts) > 0 {
+			destinations = weightedDestinations(dsts, weights)
+		}
+	}
+
+	vs := &networking.VirtualService{Hosts: []string{host}}
+	for _, in := range spec.Routes {
+		c := in.Condition
+		if c.All != nil || c.Any != nil || c.Not != nil {
+			r.unsupported(obj, "route %q skipped: only conditions on the method and the path are supported", in.Name)
+			continue
+		}
+		if len(in.ResponseClasses) > 0 {
+			r.unsupported(obj, "responseClasses of route %q are not supported", in.Name)
+		}
+		match := &networking.HTTPMatchRequest{}
+		if c.Method != "" {
+			match.Method = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: c.Method}}
+		}
+		if c.PathRegex != "" {
+			match.Uri = &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: c.PathRegex}}
+		}
+		route := &networking.HTTPRoute{
+			Name:  in.Name,
+			Match: []*networking.HTTPMatchRequest{match},
+			Route: destinations,
+		}
+		if in.Timeout != "" {
+			if d, err := duration(in.Timeout); err == nil {
+				route.Timeout = d
+			} else {
+				r.unsupported(obj, "invalid timeout %q of route %q", in.Timeout, in.Name)
+			}
+		}
+		if in.IsRetryable {
+			route.Retries = &networking.HTTPRetry{Attempts: serviceProfileRetryAttempts, RetryOn: "5xx,gateway-error,connect-failure"}
+		} else {
+			route.Retries = &networking.HTTPRetry{}
+		}
+		vs.Http = append(vs.Http, route)
+	}
+	vs.Http = append(vs.Http, &networking.HTTPRoute{Route: destinations})
+	r.addVirtualService(obj, obj.Name, vs)
+	return nil
+}
+
+// authorityDestination converts the host and the optional port of an authority into a destination.
+func authorityDestination(authority string) (*networking.Destination, error) {
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		return destination(authority, 0), nil
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	return destination(host, uint32(p)), nil
+}
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+)
+
+// ingressRouteSpec is the spec of a Traefik IngressRoute.
+type ingressRouteSpec struct {
+	Routes []struct {
+		Match       string        `json:"match"`
+		Kind        string        `json:"kind"`
+		Priority    int           `json:"priority"`
+		Middlewares []interface{} `json:"middlewares"`
+		Services    []struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+			Kind      string `json:"kind"`
+			Port      uint32 `json:"port"`
+			Weight    *int64 `json:"weight"`
+		} `json:"services"`
+	} `json:"routes"`
+	TLS interface{} `json:"tls"`
+}
+
+// traefikMatcher is a matcher of a Traefik rule, such as PathPrefix(`/api`).
+var traefikMatcher = regexp.MustCompile("^([A-Za-z]+)\\((.*)\\)$")
+
+// traefikRoute is a converted route of an IngressRoute, with the hosts of its rule.
+type traefikRoute struct {
+	hosts    []string
+	priority int
+	route    *networking.HTTPRoute
+}
+
+// convertIngressRoute converts a Traefik IngressRoute into a VirtualService bound to the gateway for each of the
+// sets of hosts of its routes. The rules can only combine matchers with &&.
+func convertIngressRoute(obj *crd.IstioKind, opts Options, r *Result) error {
+	spec := &ingressRouteSpec{}
+	if err := decodeSpec(obj, spec); err != nil {
+		return err
+	}
+	if spec.TLS != nil {
+		r.unsupported(obj, "tls must be configured on the servers of the gateway %s", opts.Gateway)
+	}
+	var routes []traefikRoute
+	for _, in := range spec.Routes {
+		if len(in.Middlewares) > 0 {
+			r.unsupported(obj, "middlewares of route %q are not supported", in.Match)
+		}
+		hosts, match, err := traefikMatch(in.Match)
+		if err != nil {
+			r.unsupported(obj, "route %q skipped: %v", in.Match, err)
+			continue
+		}
+		route := &networking.HTTPRoute{Match: match}
+		var destinations []*networking.Destination
+		var weights []int64
+		for _, s := range in.Services {
+			if s.Kind != "" && s.Kind != "Service" {
+				r.unsupported(obj, "%s services of route %q are not supported", s.Kind, in.Match)
+				continue
+			}
+			namespace := s.Namespace
+			if namespace == "" {
+				namespace = obj.Namespace
+			}
+			destinations = append(destinations, destination(serviceHost(s.Name, namespace, opts), s.Port))
+			weight := int64(1)
+			if s.Weight != nil {
+				weight = *s.Weight
+			}
+			weights = append(weights, weight)
+		}
+		if len(destinations) == 0 {
+			r.unsupported(obj, "route %q skipped: no service", in.Match)
+			continue
+		}
+		route.Route = weightedDestinations(destinations, weights)
+		// Traefik defaults the priority of a route to the length of its rule.
+		priority := in.Priority
+		if priority == 0 {
+			priority = len(in.Match)
+		}
+		routes = append(routes, traefikRoute{hosts: hosts, priority: priority, route: route})
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].priority > routes[j].priority
+	})
+	var keys []string
+	byHosts := map[string]*networking.VirtualService{}
+	for _, route := range routes {
+		key := strings.Join(route.hosts, ",")
+		vs, f := byHosts[key]
+		if !f {
+			vs = &networking.VirtualService{Hosts: route.hosts, Gateways: []string{opts.Gateway}}
+			byHosts[key] = vs
+			keys = append(keys, key)
+		}
+		vs.Http = append(vs.Http, route.route)
+	}
+	for i, key := range keys {
+		name := obj.Name
+		if len(keys) > 1 {
+			name = fmt.Sprintf("%s-%d", obj.Name, i)
+		}
+		r.addVirtualService(obj, name, byHosts[key])
+	}
+	return nil
+}
+
+// traefikMatch converts a Traefik rule into its hosts and the matches of the requests.
+func traefikMatch(rule string) ([]string, []*networking.HTTPMatchRequest, error) {
+	if strings.Contains(rule, "||") || strings.Contains(rule, "!") {
+		return nil, nil, fmt.Errorf("only rules combining matchers with && are supported")
+	}
+	var hosts, paths []string
+	var exact bool
+	match := &networking.HTTPMatchRequest{}
+	for _, matcher := range strings.Split(rule, "&&") {
+		parts := traefikMatcher.FindStringSubmatch(strings.TrimSpace(matcher))
+		if parts == nil {
+			return nil, nil, fmt.Errorf("invalid matcher %q", matcher)
+		}
+		var args []string
+		for _, arg := range strings.Split(parts[2], ",") {
+			args = append(args, strings.Trim(strings.TrimSpace(arg), "`\""))
+		}
+		switch parts[1] {
+		case "Host":
+			hosts = append(hosts, args...)
+		case "Path":
+			paths, exact = args, true
+		case "PathPrefix":
+			paths = args
+		case "Method":
+			if len(args) != 1 {
+				return nil, nil, fmt.Errorf("only one method can be matched")
+			}
+			match.Method = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: args[0]}}
+		case "Headers":
+			if len(args) != 2 {
+				return nil, nil, fmt.Errorf("invalid matcher %q", matcher)
+			}
+			if match.Headers == nil {
+				match.Headers = map[string]*networking.StringMatch{}
+			}
+			match.Headers[strings.ToLower(args[0])] = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: args[1]}}
+		default:
+			return nil, nil, fmt.Errorf("matcher %s is not supported", parts[1])
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"*"}
+	}
+	if len(paths) == 0 {
+		return hosts, []*networking.HTTPMatchRequest{match}, nil
+	}
+	matches := make([]*networking.HTTPMatchRequest, 0, len(paths))
+	for _, path := range paths {
+		m := *match
+		if exact {
+			m.Uri = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: path}}
+		} else {
+			m.Uri = &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: path}}
+		}
+		matches = append(matches, &m)
+	}
+	return hosts, matches, nil
+}
@@ -0,0 +1,441 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot exports the Istio configuration of a cluster, along with its mesh config, into a versioned
+// archive, and imports such archives into a cluster. It is used for disaster recovery drills and to clone the
+// configuration of an environment.
+//
+// The archive is a gzipped tarball holding a manifest.yaml describing the snapshot, the mesh config under mesh/ and a
+// YAML file per resource under resources/<group>/<kind>/<namespace>/<name>.yaml.
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// FormatVersion is the version of the format of the archives. Archives of another version are rejected on import.
+const FormatVersion = "v1"
+
+const (
+	manifestFile            = "manifest.yaml"
+	meshConfigFile          = "mesh/meshconfig.yaml"
+	effectiveMeshConfigFile = "mesh/effective-meshconfig.yaml"
+	resourcesDir            = "resources"
+
+	// meshConfigKey is the key of the mesh config in its ConfigMap.
+	meshConfigKey = "mesh"
+	// lastAppliedAnnotation is dropped from the exported resources, as it records the state of the source cluster.
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// Manifest describes the snapshot.
+type Manifest struct {
+	Version         string    `json:"version"`
+	Created         time.Time `json:"created"`
+	IstioctlVersion string    `json:"istioctlVersion,omitempty"`
+	// Namespace is the namespace the resources were exported from, or empty for all namespaces.
+	Namespace      string `json:"namespace,omitempty"`
+	IstioNamespace string `json:"istioNamespace"`
+	// MeshConfigMap is the name of the ConfigMap of the mesh config, in the Istio namespace.
+	MeshConfigMap string `json:"meshConfigMap"`
+	Resources     int    `json:"resources"`
+}
+
+// Snapshot is the Istio configuration of a cluster.
+type Snapshot struct {
+	Manifest Manifest
+	// MeshConfig is the mesh config as set in its ConfigMap, which is what is imported.
+	MeshConfig string
+	// EffectiveMeshConfig is the mesh config with the defaults applied, for reference.
+	EffectiveMeshConfig string
+	Resources           []*unstructured.Unstructured
+}
+
+// ExportOptions select what is exported.
+type ExportOptions struct {
+	// Schemas are the kinds of resources exported.
+	Schemas collection.Schemas
+	// Namespace restricts the export to a namespace. All namespaces are exported if empty.
+	Namespace      string
+	IstioNamespace string
+	MeshConfigMap  string
+	Version        string
+}
+
+// Export reads the resources and the mesh config of the cluster. The kinds whose CRD is not installed are skipped.
+func Export(ctx context.Context, client dynamic.Interface, kube kubernetes.Interface, opts ExportOptions) (*Snapshot, error) {
+	s := &Snapshot{
+		Manifest: Manifest{
+			Version:         FormatVersion,
+			Created:         time.Now().UTC().Truncate(time.Second),
+			IstioctlVersion: opts.Version,
+			Namespace:       opts.Namespace,
+			IstioNamespace:  opts.IstioNamespace,
+			MeshConfigMap:   opts.MeshConfigMap,
+		},
+	}
+	for _, schema := range opts.Schemas.All() {
+		list, err := client.Resource(schema.Resource().GroupVersionResource()).Namespace(opts.Namespace).List(ctx, metav1.ListOptions{})
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", schema.Resource().Plural(), err)
+		}
+		for i := range list.Items {
+			s.Resources = append(s.Resources, sanitize(&list.Items[i]))
+		}
+	}
+	sortResources(s.Resources)
+	s.Manifest.Resources = len(s.Resources)
+
+	cm, err := kube.CoreV1().ConfigMaps(opts.IstioNamespace).Get(ctx, opts.MeshConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not read configmap %q from namespace %q: %v", opts.MeshConfigMap, opts.IstioNamespace, err)
+	}
+	s.MeshConfig = cm.Data[meshConfigKey]
+	effective, err := mesh.ApplyMeshConfigDefaults(s.MeshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing mesh config: %v", err)
+	}
+	if s.EffectiveMeshConfig, err = gogoprotomarshal.ToYAML(effective); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// sanitize drops the fields of the resource set by the source cluster.
+func sanitize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	for _, field := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields", "ownerReferences", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, lastAppliedAnnotation)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		obj.SetAnnotations(annotations)
+	}
+	return obj
+}
+
+func sortResources(resources []*unstructured.Unstructured) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resourcePath(resources[i]) < resourcePath(resources[j])
+	})
+}
+
+func resourcePath(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return path.Join(resourcesDir, gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName()+".yaml")
+}
+
+// Write writes the snapshot as a gzipped tarball.
+func Write(w io.Writer, s *Snapshot) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := yaml.Marshal(s.Manifest)
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{manifestFile, manifest},
+		{meshConfigFile, []byte(s.MeshConfig)},
+		{effectiveMeshConfigFile, []byte(s.EffectiveMeshConfig)},
+	}
+	for _, obj := range s.Resources {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		files = append(files, struct {
+			name string
+			data []byte
+		}{resourcePath(obj), data})
+	}
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), ModTime: s.Manifest.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads a snapshot written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	s := &Snapshot{}
+	var manifest []byte
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, tr); err != nil {
+			return nil, err
+		}
+		switch {
+		case hdr.Name == manifestFile:
+			manifest = buf.Bytes()
+		case hdr.Name == meshConfigFile:
+			s.MeshConfig = buf.String()
+		case hdr.Name == effectiveMeshConfigFile:
+			s.EffectiveMeshConfig = buf.String()
+		case strings.HasPrefix(hdr.Name, resourcesDir+"/"):
+			obj := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal(buf.Bytes(), &obj.Object); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", hdr.Name, err)
+			}
+			s.Resources = append(s.Resources, obj)
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("not a snapshot archive: missing %s", manifestFile)
+	}
+	if err := yaml.Unmarshal(manifest, &s.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", manifestFile, err)
+	}
+	if s.Manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %q, expected %q", s.Manifest.Version, FormatVersion)
+	}
+	return s, nil
+}
+
+// ConflictStrategy is how the resources of the snapshot which already exist in the cluster are imported.
+type ConflictStrategy string
+
+const (
+	// ConflictSkip keeps the existing resources.
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces the existing resources with the ones of the snapshot.
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictFail imports nothing if any resource of the snapshot already exists.
+	ConflictFail ConflictStrategy = "fail"
+)
+
+// ImportOptions control how a snapshot is imported.
+type ImportOptions struct {
+	Strategy ConflictStrategy
+	// MeshConfig also imports the mesh config into the MeshConfigMap ConfigMap of the Istio namespace.
+	MeshConfig     bool
+	IstioNamespace string
+	MeshConfigMap  string
+	// DryRun only reports what would be imported.
+	DryRun bool
+}
+
+// Action is what the import did with a resource.
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+	ActionSkipped   Action = "skipped"
+	ActionConflict  Action = "conflict"
+	ActionFailed    Action = "failed"
+)
+
+// ImportedResource is the result of the import of a resource.
+type ImportedResource struct {
+	// Resource is the resource, as Kind/namespace/name.
+	Resource string
+	Action   Action
+	Error    error
+}
+
+type target struct {
+	resource string
+	desired  *unstructured.Unstructured
+	existing *unstructured.Unstructured
+	client   dynamic.ResourceInterface
+}
+
+// Import imports the snapshot into the cluster. The conflicts are all detected before any resource is written, so
+// that the fail strategy leaves the cluster untouched. It returns an error if any resource failed to be imported.
+func Import(ctx context.Context, client dynamic.Interface, kube kubernetes.Interface, s *Snapshot, schemas collection.Schemas,
+	opts ImportOptions) ([]ImportedResource, error) {
+	targets := []target{}
+	conflicts := []string{}
+	for _, obj := range s.Resources {
+		gvk := obj.GroupVersionKind()
+		schema, found := schemas.FindByGroupVersionKind(config.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind})
+		if !found {
+			return nil, fmt.Errorf("unknown kind %s in the snapshot", gvk)
+		}
+		t := target{
+			resource: fmt.Sprintf("%s/%s/%s", gvk.Kind, obj.GetNamespace(), obj.GetName()),
+			desired:  obj,
+			client:   client.Resource(schema.Resource().GroupVersionResource()).Namespace(obj.GetNamespace()),
+		}
+		existing, err := t.client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s: %v", t.resource, err)
+		}
+		if err == nil {
+			t.existing = existing
+			if !specEqual(existing, obj) {
+				conflicts = append(conflicts, t.resource)
+			}
+		}
+		targets = append(targets, t)
+	}
+	var meshConfigMap *corev1.ConfigMap
+	meshResource := fmt.Sprintf("ConfigMap/%s/%s", opts.IstioNamespace, opts.MeshConfigMap)
+	if opts.MeshConfig {
+		cm, err := kube.CoreV1().ConfigMaps(opts.IstioNamespace).Get(ctx, opts.MeshConfigMap, metav1.GetOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s: %v", meshResource, err)
+		}
+		if err == nil {
+			meshConfigMap = cm
+			if cm.Data[meshConfigKey] != s.MeshConfig {
+				conflicts = append(conflicts, meshResource)
+			}
+		}
+	}
+	if opts.Strategy == ConflictFail && len(conflicts) > 0 {
+		return nil, fmt.Errorf("%d resources of the snapshot already exist with a different content: %s",
+			len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	results := []ImportedResource{}
+	failed := 0
+	for _, t := range targets {
+		res := ImportedResource{Resource: t.resource}
+		switch {
+		case t.existing == nil:
+			res.Action = ActionCreated
+			if !opts.DryRun {
+				_, res.Error = t.client.Create(ctx, t.desired, metav1.CreateOptions{})
+			}
+		case specEqual(t.existing, t.desired):
+			res.Action = ActionUnchanged
+		case opts.Strategy == ConflictOverwrite:
+			res.Action = ActionUpdated
+			if !opts.DryRun {
+				updated := t.desired.DeepCopy()
+				updated.SetResourceVersion(t.existing.GetResourceVersion())
+				_, res.Error = t.client.Update(ctx, updated, metav1.UpdateOptions{})
+			}
+		default:
+			res.Action = ActionSkipped
+		}
+		if res.Error != nil {
+			res.Action = ActionFailed
+			failed++
+		}
+		results = append(results, res)
+	}
+	if opts.MeshConfig {
+		res := importMeshConfig(ctx, kube, meshConfigMap, s.MeshConfig, opts)
+		res.Resource = meshResource
+		if res.Error != nil {
+			failed++
+		}
+		results = append(results, res)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("failed to import %d resources", failed)
+	}
+	return results, nil
+}
+
+func importMeshConfig(ctx context.Context, kube kubernetes.Interface, existing *corev1.ConfigMap, meshConfig string,
+	opts ImportOptions) ImportedResource {
+	res := ImportedResource{}
+	switch {
+	case existing == nil:
+		res.Action = ActionCreated
+		if !opts.DryRun {
+			_, res.Error = kube.CoreV1().ConfigMaps(opts.IstioNamespace).Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: opts.MeshConfigMap, Namespace: opts.IstioNamespace},
+				Data:       map[string]string{meshConfigKey: meshConfig},
+			}, metav1.CreateOptions{})
+		}
+	case existing.Data[meshConfigKey] == meshConfig:
+		res.Action = ActionUnchanged
+	case opts.Strategy == ConflictOverwrite:
+		res.Action = ActionUpdated
+		if !opts.DryRun {
+			updated := existing.DeepCopy()
+			if updated.Data == nil {
+				updated.Data = map[string]string{}
+			}
+			updated.Data[meshConfigKey] = meshConfig
+			_, res.Error = kube.CoreV1().ConfigMaps(opts.IstioNamespace).Update(ctx, updated, metav1.UpdateOptions{})
+		}
+	default:
+		res.Action = ActionSkipped
+	}
+	if res.Error != nil {
+		res.Action = ActionFailed
+	}
+	return res
+}
+
+// specEqual compares the content of the resources, ignoring the fields set by the cluster.
+func specEqual(existing, desired *unstructured.Unstructured) bool {
+	a, err := yaml.Marshal(sanitize(existing).Object)
+	if err != nil {
+		return false
+	}
+	b, err := yaml.Marshal(sanitize(desired).Object)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
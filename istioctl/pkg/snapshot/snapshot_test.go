@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+var schemas = collection.SchemasFor(collections.IstioNetworkingV1Alpha3Virtualservices, collections.IstioNetworkingV1Alpha3Destinationrules)
+
+func virtualService(namespace, name, host string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "VirtualService",
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       namespace,
+			"resourceVersion": "42",
+			"uid":             "1234",
+			"annotations":     map[string]interface{}{lastAppliedAnnotation: "{}"},
+		},
+		"spec": map[string]interface{}{"hosts": []interface{}{host}},
+	}}
+}
+
+func newClients(objects ...runtime.Object) (*dynamicfake.FakeDynamicClient, *fake.Clientset) {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, s := range schemas.All() {
+		listKinds[s.Resource().GroupVersionResource()] = s.Resource().Kind() + "List"
+	}
+	kube := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+		Data:       map[string]string{meshConfigKey: "ingressClass: nginx\n"},
+	})
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...), kube
+}
+
+func exportOptions() ExportOptions {
+	return ExportOptions{Schemas: schemas, IstioNamespace: "istio-system", MeshConfigMap: "istio", Version: "1.13.0"}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	client, kube := newClients(virtualService("default", "reviews", "reviews"), virtualService("bookinfo", "ratings", "ratings"))
+	s, err := Export(context.Background(), client, kube, exportOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Manifest.Version != FormatVersion || s.Manifest.Resources != 2 {
+		t.Fatalf("unexpected manifest %+v", s.Manifest)
+	}
+	if !strings.Contains(s.EffectiveMeshConfig, "ingressClass: nginx") || !strings.Contains(s.EffectiveMeshConfig, "rootNamespace: istio-system") {
+		t.Fatalf("expected the effective mesh config to have the defaults applied, got %s", s.EffectiveMeshConfig)
+	}
+	// The resources are sorted by path, so bookinfo/ratings comes first.
+	if got := s.Resources[0].GetName(); got != "ratings" {
+		t.Fatalf("expected the resources to be sorted, got %s first", got)
+	}
+	if got := s.Resources[0].GetResourceVersion(); got != "" {
+		t.Fatalf("expected the resource version not to be exported, got %q", got)
+	}
+	if got := s.Resources[0].GetAnnotations(); got != nil {
+		t.Fatalf("expected the last applied configuration not to be exported, got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, s) {
+		t.Fatalf("got %+v after a round trip, want %+v", read, s)
+	}
+}
+
+func TestExportNamespace(t *testing.T) {
+	client, kube := newClients(virtualService("default", "reviews", "reviews"), virtualService("bookinfo", "ratings", "ratings"))
+	opts := exportOptions()
+	opts.Namespace = "bookinfo"
+	s, err := Export(context.Background(), client, kube, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Resources) != 1 || s.Resources[0].GetNamespace() != "bookinfo" || s.Manifest.Namespace != "bookinfo" {
+		t.Fatalf("expected only the bookinfo namespace to be exported, got %+v", s)
+	}
+}
+
+func TestReadVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Snapshot{Manifest: Manifest{Version: "v0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil || !strings.Contains(err.Error(), "unsupported snapshot version") {
+		t.Fatalf("expected the version to be rejected, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	snap := &Snapshot{
+		MeshConfig: "enableTracing: true\n",
+		Resources: []*unstructured.Unstructured{
+			sanitize(virtualService("default", "new", "new")),
+			sanitize(virtualService("default", "same", "same")),
+			sanitize(virtualService("default", "changed", "changed")),
+		},
+	}
+	cases := []struct {
+		name     string
+		opts     ImportOptions
+		want     []Action
+		wantErr  bool
+		wantHost string
+	}{
+		{
+			name:     "skip",
+			opts:     ImportOptions{Strategy: ConflictSkip},
+			want:     []Action{ActionCreated, ActionUnchanged, ActionSkipped},
+			wantHost: "before",
+		},
+		{
+			name:     "overwrite",
+			opts:     ImportOptions{Strategy: ConflictOverwrite, MeshConfig: true},
+			want:     []Action{ActionCreated, ActionUnchanged, ActionUpdated, ActionUpdated},
+			wantHost: "changed",
+		},
+		{
+			name:     "overwrite dry run",
+			opts:     ImportOptions{Strategy: ConflictOverwrite, DryRun: true},
+			want:     []Action{ActionCreated, ActionUnchanged, ActionUpdated},
+			wantHost: "before",
+		},
+		{
+			name:     "fail",
+			opts:     ImportOptions{Strategy: ConflictFail},
+			wantErr:  true,
+			wantHost: "before",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client, kube := newClients(virtualService("default", "same", "same"), virtualService("default", "changed", "before"))
+			tt.opts.IstioNamespace = "istio-system"
+			tt.opts.MeshConfigMap = "istio"
+			results, err := Import(context.Background(), client, kube, snap, schemas, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			var got []Action
+			for _, r := range results {
+				got = append(got, r.Action)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got actions %v, want %v", got, tt.want)
+			}
+
+			gvr := collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionResource()
+			changed, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "changed", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hosts, _, _ := unstructured.NestedStringSlice(changed.Object, "spec", "hosts"); hosts[0] != tt.wantHost {
+				t.Fatalf("got hosts %v, want %s", hosts, tt.wantHost)
+			}
+			_, err = client.Resource(gvr).Namespace("default").Get(context.Background(), "new", metav1.GetOptions{})
+			if created := err == nil; created != (!tt.opts.DryRun && !tt.wantErr) {
+				t.Fatalf("unexpected creation of the new resource: %v", err)
+			}
+		})
+	}
+}
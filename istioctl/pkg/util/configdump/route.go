@@ -113,3 +113,17 @@ func (w *Wrapper) GetRouteConfigDump() (*adminapi.RoutesConfigDump, error) {
 	}
 	return routeDump, nil
 }
+
+// GetScopedRoutesConfigDump retrieves the scoped route config dump from the ConfigDump
+func (w *Wrapper) GetScopedRoutesConfigDump() (*adminapi.ScopedRoutesConfigDump, error) {
+	scopedRouteDumpAny, err := w.getSection(scopedRoutes)
+	if err != nil {
+		return nil, err
+	}
+	scopedRouteDump := &adminapi.ScopedRoutesConfigDump{}
+	err = scopedRouteDumpAny.UnmarshalTo(scopedRouteDump)
+	if err != nil {
+		return nil, err
+	}
+	return scopedRouteDump, nil
+}
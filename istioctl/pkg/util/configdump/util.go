@@ -24,11 +24,12 @@ type configTypeURL string
 
 // See https://www.envoyproxy.io/docs/envoy/latest/api-v3/admin/v3/config_dump.proto
 const (
-	bootstrap configTypeURL = "type.googleapis.com/envoy.admin.v3.BootstrapConfigDump"
-	listeners configTypeURL = "type.googleapis.com/envoy.admin.v3.ListenersConfigDump"
-	clusters  configTypeURL = "type.googleapis.com/envoy.admin.v3.ClustersConfigDump"
-	routes    configTypeURL = "type.googleapis.com/envoy.admin.v3.RoutesConfigDump"
-	secrets   configTypeURL = "type.googleapis.com/envoy.admin.v3.SecretsConfigDump"
+	bootstrap    configTypeURL = "type.googleapis.com/envoy.admin.v3.BootstrapConfigDump"
+	listeners    configTypeURL = "type.googleapis.com/envoy.admin.v3.ListenersConfigDump"
+	clusters     configTypeURL = "type.googleapis.com/envoy.admin.v3.ClustersConfigDump"
+	routes       configTypeURL = "type.googleapis.com/envoy.admin.v3.RoutesConfigDump"
+	scopedRoutes configTypeURL = "type.googleapis.com/envoy.admin.v3.ScopedRoutesConfigDump"
+	secrets      configTypeURL = "type.googleapis.com/envoy.admin.v3.SecretsConfigDump"
 )
 
 // getSection takes a TypeURL and returns the types.Any from the config dump corresponding to that URL
@@ -24,6 +24,7 @@ import (
 
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/anypb"
 	"sigs.k8s.io/yaml"
 
 	protio "istio.io/istio/istioctl/pkg/util/proto"
@@ -243,6 +244,89 @@ func (c *ConfigWriter) retrieveSortedRouteSlice() ([]*route.RouteConfiguration,
 	return routes, nil
 }
 
+// PrintScopedRouteSummary prints a summary of the scoped routes in the config dump to the ConfigWriter stdout
+func (c *ConfigWriter) PrintScopedRouteSummary(filter RouteFilter) error {
+	scopes, err := c.retrieveSortedScopedRouteSlice(filter)
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKEY\tROUTE CONFIGURATION")
+	for _, scope := range scopes {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", scope.Name, describeScopeKey(scope.GetKey()), scope.RouteConfigurationName)
+	}
+	return w.Flush()
+}
+
+// PrintScopedRouteDump prints the scoped routes in the config dump to the ConfigWriter stdout
+func (c *ConfigWriter) PrintScopedRouteDump(filter RouteFilter, outputFormat string) error {
+	scopes, err := c.retrieveSortedScopedRouteSlice(filter)
+	if err != nil {
+		return err
+	}
+	filteredScopes := make(protio.MessageSlice, 0, len(scopes))
+	for _, scope := range scopes {
+		filteredScopes = append(filteredScopes, scope)
+	}
+	out, err := json.MarshalIndent(filteredScopes, "", "    ")
+	if err != nil {
+		return err
+	}
+	if outputFormat == "yaml" {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(c.Stdout, string(out))
+	return nil
+}
+
+func describeScopeKey(key *route.ScopedRouteConfiguration_Key) string {
+	fragments := make([]string, 0, len(key.GetFragments()))
+	for _, f := range key.GetFragments() {
+		fragments = append(fragments, f.GetStringKey())
+	}
+	return strings.Join(fragments, ",")
+}
+
+// retrieveSortedScopedRouteSlice returns the scoped route configurations matching the filter. A scope
+// matches if either its own name or the name of the route configuration it points at matches.
+func (c *ConfigWriter) retrieveSortedScopedRouteSlice(filter RouteFilter) ([]*route.ScopedRouteConfiguration, error) {
+	if c.configDump == nil {
+		return nil, fmt.Errorf("config writer has not been primed")
+	}
+	scopedRouteDump, err := c.configDump.GetScopedRoutesConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	configs := make([]*anypb.Any, 0)
+	for _, s := range scopedRouteDump.DynamicScopedRouteConfigs {
+		configs = append(configs, s.ScopedRouteConfigs...)
+	}
+	for _, s := range scopedRouteDump.InlineScopedRouteConfigs {
+		configs = append(configs, s.ScopedRouteConfigs...)
+	}
+	scopes := make([]*route.ScopedRouteConfiguration, 0, len(configs))
+	for _, cfg := range configs {
+		scope := &route.ScopedRouteConfiguration{}
+		cfg.TypeUrl = v3.ScopedRouteType
+		if err := cfg.UnmarshalTo(scope); err != nil {
+			return nil, err
+		}
+		if filter.Name != "" && filter.Name != scope.Name && filter.Name != scope.RouteConfigurationName {
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("no scoped routes found")
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		return scopes[i].Name < scopes[j].Name
+	})
+	return scopes, nil
+}
+
 func isPassthrough(action interface{}) bool {
 	a, ok := action.(*route.Route_Route)
 	if !ok {
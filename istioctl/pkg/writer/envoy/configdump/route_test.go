@@ -13,3 +13,93 @@
 // limitations under the License.
 
 package configdump
+
+import (
+	"bytes"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func scopedRouteConfigWriter(t *testing.T, scopes ...*route.ScopedRouteConfiguration) *ConfigWriter {
+	t.Helper()
+	configs := make([]*anypb.Any, 0, len(scopes))
+	for _, s := range scopes {
+		a, err := anypb.New(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, a)
+	}
+	dump, err := anypb.New(&adminapi.ScopedRoutesConfigDump{
+		DynamicScopedRouteConfigs: []*adminapi.ScopedRoutesConfigDump_DynamicScopedRouteConfigs{{
+			Name:               "80",
+			ScopedRouteConfigs: configs,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ConfigWriter{
+		Stdout:     &bytes.Buffer{},
+		configDump: &configdump.Wrapper{ConfigDump: &adminapi.ConfigDump{Configs: []*anypb.Any{dump}}},
+	}
+}
+
+func scopedRoute(name, key, routeConfig string) *route.ScopedRouteConfiguration {
+	return &route.ScopedRouteConfiguration{
+		Name:                   name,
+		RouteConfigurationName: routeConfig,
+		Key: &route.ScopedRouteConfiguration_Key{
+			Fragments: []*route.ScopedRouteConfiguration_Key_Fragment{{
+				Type: &route.ScopedRouteConfiguration_Key_Fragment_StringKey{StringKey: key},
+			}},
+		},
+	}
+}
+
+func TestConfigWriter_PrintScopedRouteSummary(t *testing.T) {
+	cw := scopedRouteConfigWriter(t,
+		scopedRoute("80|b.example.com", "b.example.com", "80|example.com"),
+		scopedRoute("80|a.example.com", "a.example.com", "80|example.com"),
+		scopedRoute("80|a.default", "a.default", "80|default"),
+	)
+	tests := []struct {
+		name   string
+		filter RouteFilter
+		want   string
+	}{
+		{
+			name: "all",
+			want: `NAME                 KEY               ROUTE CONFIGURATION
+80|a.default         a.default         80|default
+80|a.example.com     a.example.com     80|example.com
+80|b.example.com     b.example.com     80|example.com
+`,
+		},
+		{
+			name:   "route configuration filter",
+			filter: RouteFilter{Name: "80|default"},
+			want: `NAME             KEY           ROUTE CONFIGURATION
+80|a.default     a.default     80|default
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			cw.Stdout = out
+			assert.NoError(t, cw.PrintScopedRouteSummary(tt.filter))
+			assert.Equal(t, out.String(), tt.want)
+		})
+	}
+
+	if err := cw.PrintScopedRouteSummary(RouteFilter{Name: "missing"}); err == nil {
+		t.Fatalf("expected error for unmatched filter")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+// simulationResult is the outcome of a simulated request, as printed by PrintSimulation.
+type simulationResult struct {
+	Listener    string `json:"listener,omitempty"`
+	FilterChain string `json:"filterChain,omitempty"`
+	RouteConfig string `json:"routeConfig,omitempty"`
+	VirtualHost string `json:"virtualHost,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Cluster     string `json:"cluster,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// PrintSimulation runs the listener, filter chain, virtual host and route selection of the proxy for the
+// call against the config dump, and prints what was selected to the ConfigWriter stdout.
+func (c *ConfigWriter) PrintSimulation(call simulation.Call, outputFormat string) error {
+	listeners, err := c.retrieveSortedListenerSlice()
+	if err != nil {
+		return err
+	}
+	// A proxy without HTTP listeners or without clusters can still be simulated.
+	routes, _ := c.retrieveSortedRouteSlice()
+	clusters, _ := c.retrieveSortedClusterSlice()
+
+	result, err := simulation.NewSimulationFromConfig(listeners, clusters, routes).RunCall(call)
+	if err != nil {
+		return fmt.Errorf("failed to simulate request: %v", err)
+	}
+	out := simulationResult{
+		Listener:    result.ListenerMatched,
+		FilterChain: result.FilterChainMatched,
+		RouteConfig: result.RouteConfigMatched,
+		VirtualHost: result.VirtualHostMatched,
+		Route:       result.RouteMatched,
+		Cluster:     result.ClusterMatched,
+	}
+	if result.Error != nil {
+		out.Error = result.Error.Error()
+	}
+
+	switch outputFormat {
+	case "json", "yaml":
+		b, err := json.MarshalIndent(out, "", "    ")
+		if err != nil {
+			return err
+		}
+		if outputFormat == "yaml" {
+			if b, err = yaml.JSONToYAML(b); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(c.Stdout, string(b))
+	default:
+		w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+		fmt.Fprintf(w, "LISTENER\t%s\n", out.Listener)
+		fmt.Fprintf(w, "FILTER CHAIN\t%s\n", out.FilterChain)
+		if out.RouteConfig != "" || out.VirtualHost != "" {
+			fmt.Fprintf(w, "ROUTE CONFIG\t%s\n", out.RouteConfig)
+			fmt.Fprintf(w, "VIRTUAL HOST\t%s\n", out.VirtualHost)
+			fmt.Fprintf(w, "ROUTE\t%s\n", out.Route)
+		}
+		fmt.Fprintf(w, "CLUSTER\t%s\n", out.Cluster)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if result.Error != nil {
+		return fmt.Errorf("request would fail: %v", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func simulationConfigWriter(t *testing.T) *ConfigWriter {
+	manager := &hcm.HttpConnectionManager{
+		RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{RouteConfigName: "9080"}},
+	}
+	l := &listener.Listener{
+		Name: "0.0.0.0_9080",
+		Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+			Address:       "0.0.0.0",
+			PortSpecifier: &core.SocketAddress_PortValue{PortValue: 9080},
+		}}},
+		FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name:       wellknown.HTTPConnectionManager,
+				ConfigType: &listener.Filter_TypedConfig{TypedConfig: mustAny(t, manager)},
+			}},
+		}},
+	}
+	rc := &route.RouteConfiguration{
+		Name: "9080",
+		VirtualHosts: []*route.VirtualHost{{
+			Name:    "reviews.default.svc.cluster.local:9080",
+			Domains: []string{"reviews", "reviews.default.svc.cluster.local"},
+			Routes: []*route.Route{{
+				Name:  "default",
+				Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}},
+				Action: &route.Route_Route{Route: &route.RouteAction{
+					ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "outbound|9080||reviews.default.svc.cluster.local"},
+				}},
+			}},
+		}},
+	}
+	dump := &adminapi.ConfigDump{Configs: []*anypb.Any{
+		mustAny(t, &adminapi.ListenersConfigDump{
+			DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+				Name:        l.Name,
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustAny(t, l)},
+			}},
+		}),
+		mustAny(t, &adminapi.RoutesConfigDump{
+			DynamicRouteConfigs: []*adminapi.RoutesConfigDump_DynamicRouteConfig{{RouteConfig: mustAny(t, rc)}},
+		}),
+	}}
+	return &ConfigWriter{configDump: &configdump.Wrapper{ConfigDump: dump}}
+}
+
+func TestConfigWriter_PrintSimulation(t *testing.T) {
+	cw := simulationConfigWriter(t)
+
+	out := &bytes.Buffer{}
+	cw.Stdout = out
+	assert.NoError(t, cw.PrintSimulation(simulation.Call{
+		Port:       9080,
+		HostHeader: "reviews",
+		Protocol:   simulation.HTTP,
+		CallMode:   simulation.CallModeOutbound,
+	}, "short"))
+	// The filter chain of the listener is unnamed.
+	want := "LISTENER         0.0.0.0_9080\n" +
+		"FILTER CHAIN     \n" +
+		"ROUTE CONFIG     9080\n" +
+		"VIRTUAL HOST     reviews.default.svc.cluster.local:9080\n" +
+		"ROUTE            default\n" +
+		"CLUSTER          outbound|9080||reviews.default.svc.cluster.local\n"
+	assert.Equal(t, out.String(), want)
+
+	out.Reset()
+	err := cw.PrintSimulation(simulation.Call{
+		Port:       9080,
+		HostHeader: "ratings",
+		Protocol:   simulation.HTTP,
+		CallMode:   simulation.CallModeOutbound,
+	}, "json")
+	if err == nil {
+		t.Fatalf("expected an error for an unknown virtual host")
+	}
+	want = `{
+    "listener": "0.0.0.0_9080",
+    "routeConfig": "9080",
+    "error": "no virtual host matched"
+}
+`
+	assert.Equal(t, out.String(), want)
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// EffectivePolicyWriter enables printing of the effective traffic policy of a service host returned by Istiod.
+type EffectivePolicyWriter struct {
+	Writer io.Writer
+}
+
+// Print takes the effectivepolicyz response of Istiod and outputs each setting with its source using a tabwriter.
+func (e *EffectivePolicyWriter) Print(response *xdsapi.DiscoveryResponse) error {
+	if len(response.Resources) == 0 {
+		return fmt.Errorf("empty effective policy response")
+	}
+	body := response.Resources[0].Value
+	var policy xds.EffectivePolicy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		// Errors are returned as plain text, after the status code.
+		return fmt.Errorf("failed to get effective policy: %s", strings.TrimSpace(string(body)))
+	}
+
+	_, _ = fmt.Fprintf(e.Writer, "Host: %s\nNamespace: %s\n", policy.Host, policy.Namespace)
+	if policy.Subset != "" {
+		_, _ = fmt.Fprintf(e.Writer, "Subset: %s\n", policy.Subset)
+	}
+	if policy.Port != 0 {
+		_, _ = fmt.Fprintf(e.Writer, "Port: %d\n", policy.Port)
+	}
+	if policy.SidecarScope != "" {
+		_, _ = fmt.Fprintf(e.Writer, "Sidecar: %s\n", policy.SidecarScope)
+	}
+	if policy.DestinationRule != "" {
+		_, _ = fmt.Fprintf(e.Writer, "DestinationRule: %s\n", policy.DestinationRule)
+	}
+	_, _ = fmt.Fprintln(e.Writer)
+
+	w := new(tabwriter.Writer).Init(e.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FIELD\tVALUE\tSOURCE")
+	for _, f := range policy.Fields {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", f.Field, f.Value, f.Source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestEffectivePolicyWriter(t *testing.T) {
+	response := debugResponse(t, xds.EffectivePolicy{
+		Host:            "reviews.default.svc.cluster.local",
+		Namespace:       "default",
+		Subset:          "v1",
+		DestinationRule: "default/reviews",
+		Fields: []xds.EffectivePolicyField{
+			{Field: "connectionPool.tcp.connectTimeout", Value: json.RawMessage(`"10s"`), Source: "MeshConfig connectTimeout"},
+			{Field: "outlierDetection", Value: json.RawMessage(`{"consecutive5xxErrors":3}`), Source: "DestinationRule default/reviews"},
+		},
+	})
+	out := &bytes.Buffer{}
+	sw := EffectivePolicyWriter{Writer: out}
+	err := sw.Print(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, out.String(), `Host: reviews.default.svc.cluster.local
+Namespace: default
+Subset: v1
+DestinationRule: default/reviews
+
+FIELD                                 VALUE                          SOURCE
+connectionPool.tcp.connectTimeout     "10s"                          MeshConfig connectTimeout
+outlierDetection                      {"consecutive5xxErrors":3}     DestinationRule default/reviews
+`)
+
+	err = sw.Print(&xdsapi.DiscoveryResponse{
+		TypeUrl:   v3.DebugType,
+		Resources: []*any.Any{{TypeUrl: v3.DebugType, Value: []byte(`{"statusCode":"404"}service foo is not visible`)}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a failed request")
+	}
+}
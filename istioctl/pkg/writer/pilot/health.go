@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// EndpointHealthWriter enables printing of the endpoints each service port and subset has, and how many of them
+// are reported unhealthy by the proxies, using the endpoint reports of multiple Istiod instances.
+type EndpointHealthWriter struct {
+	Writer io.Writer
+	// Service, if set, restricts the output to the services whose hostname starts with it.
+	Service string
+}
+
+type serviceHealthKey struct {
+	hostname string
+	port     int
+	subset   string
+}
+
+type serviceHealth struct {
+	endpoints sets.Set
+	unhealthy sets.Set
+	proxies   sets.Set
+}
+
+// PrintAll takes the healthcheckz responses of Istiod instances, merges the endpoints reported for the same cluster
+// by different instances, and outputs the endpoint counts per service port and subset using a tabwriter.
+func (s *EndpointHealthWriter) PrintAll(responses map[string]*xdsapi.DiscoveryResponse) error {
+	services := map[serviceHealthKey]*serviceHealth{}
+	for _, response := range responses {
+		for _, resource := range response.Resources {
+			var summaries []xds.EndpointReportSummary
+			if err := json.Unmarshal(resource.Value, &summaries); err != nil {
+				return fmt.Errorf("failed to parse endpoint health response: %v", err)
+			}
+			for _, summary := range summaries {
+				_, subset, hostname, port := model.ParseSubsetKey(summary.Cluster)
+				if hostname == "" || !strings.HasPrefix(string(hostname), s.Service) {
+					continue
+				}
+				key := serviceHealthKey{hostname: string(hostname), port: port, subset: subset}
+				sh, f := services[key]
+				if !f {
+					sh = &serviceHealth{endpoints: sets.NewSet(), unhealthy: sets.NewSet(), proxies: sets.NewSet()}
+					services[key] = sh
+				}
+				sh.endpoints.Insert(summary.Endpoint)
+				if len(summary.FailingProxies) > 0 {
+					sh.unhealthy.Insert(summary.Endpoint)
+					sh.proxies.Insert(summary.FailingProxies...)
+				}
+			}
+		}
+	}
+	keys := make([]serviceHealthKey, 0, len(services))
+	for k := range services {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].hostname != keys[j].hostname {
+			return keys[i].hostname < keys[j].hostname
+		}
+		if keys[i].port != keys[j].port {
+			return keys[i].port < keys[j].port
+		}
+		return keys[i].subset < keys[j].subset
+	})
+
+	w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SERVICE\tPORT\tSUBSET\tENDPOINTS\tUNHEALTHY\tPROXIES REPORTING UNHEALTHY")
+	for _, k := range keys {
+		sh := services[k]
+		subset := k.subset
+		if subset == "" {
+			subset = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\n", k.hostname, k.port, subset,
+			len(sh.endpoints), len(sh.unhealthy), len(sh.proxies))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"testing"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestEndpointHealthWriter(t *testing.T) {
+	responses := map[string]*xdsapi.DiscoveryResponse{
+		"istiod-1": debugResponse(t, []xds.EndpointReportSummary{
+			{Cluster: "outbound|80||foo.default.svc.cluster.local", Endpoint: "10.0.0.1:80",
+				FailingProxies: []string{"a", "b"}, ReportingProxies: 2},
+			{Cluster: "outbound|80||foo.default.svc.cluster.local", Endpoint: "10.0.0.2:80",
+				ReportingProxies: 2},
+			{Cluster: "outbound|80|v1|bar.default.svc.cluster.local", Endpoint: "10.0.0.3:80",
+				ReportingProxies: 1},
+		}),
+		"istiod-2": debugResponse(t, []xds.EndpointReportSummary{
+			{Cluster: "outbound|80||foo.default.svc.cluster.local", Endpoint: "10.0.0.1:80",
+				FailingProxies: []string{"c"}, ReportingProxies: 1},
+		}),
+	}
+
+	cases := []struct {
+		name    string
+		service string
+		want    string
+	}{
+		{
+			name: "all",
+			want: `SERVICE                           PORT     SUBSET     ENDPOINTS     UNHEALTHY     PROXIES REPORTING UNHEALTHY
+bar.default.svc.cluster.local     80       v1         1             0             0
+foo.default.svc.cluster.local     80       -          2             1             3
+`,
+		},
+		{
+			name:    "filtered",
+			service: "foo",
+			want: `SERVICE                           PORT     SUBSET     ENDPOINTS     UNHEALTHY     PROXIES REPORTING UNHEALTHY
+foo.default.svc.cluster.local     80       -          2             1             3
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			sw := EndpointHealthWriter{Writer: got, Service: tt.service}
+			if err := sw.PrintAll(responses); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, got.String(), tt.want)
+		})
+	}
+}
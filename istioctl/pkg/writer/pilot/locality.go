@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// LocalityMatrixWriter enables printing of the requests between localities using the locality matrix of multiple
+// Istiod instances.
+type LocalityMatrixWriter struct {
+	Writer io.Writer
+}
+
+// PrintAll takes the localitymatrixz responses of Istiod instances and outputs the requests from each source
+// locality to each destination locality using a tabwriter, followed by the share of the requests across localities.
+func (s *LocalityMatrixWriter) PrintAll(responses map[string]*xdsapi.DiscoveryResponse) error {
+	type cell struct{ source, destination string }
+	requests := map[cell]uint64{}
+	for _, response := range responses {
+		for _, resource := range response.Resources {
+			var traffic []xds.LocalityTraffic
+			if err := json.Unmarshal(resource.Value, &traffic); err != nil {
+				return fmt.Errorf("failed to parse locality matrix response: %v", err)
+			}
+			for _, t := range traffic {
+				requests[cell{source: t.Source, destination: t.Destination}] += t.Requests
+			}
+		}
+	}
+	matrix := make([]xds.LocalityTraffic, 0, len(requests))
+	for c, n := range requests {
+		matrix = append(matrix, xds.LocalityTraffic{Source: c.source, Destination: c.destination, Requests: n})
+	}
+	xds.SetLocalityShares(matrix)
+
+	var total, cross uint64
+	w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SOURCE\tDESTINATION\tREQUESTS\tSHARE")
+	for _, t := range matrix {
+		total += t.Requests
+		// The traffic of proxies without a locality cannot be classified.
+		if t.Source != "" && t.Destination != "" && t.Source != t.Destination {
+			cross += t.Requests
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%.1f%%\n", localityOrUnknown(t.Source), localityOrUnknown(t.Destination),
+			t.Requests, t.Share*100)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	crossShare := 0.0
+	if total > 0 {
+		crossShare = float64(cross) / float64(total) * 100
+	}
+	_, err := fmt.Fprintf(s.Writer, "\nCross-locality requests: %d of %d (%.1f%%)\n", cross, total, crossShare)
+	return err
+}
+
+func localityOrUnknown(locality string) string {
+	if locality == "" {
+		return "-"
+	}
+	return locality
+}
@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"testing"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestLocalityMatrixWriter(t *testing.T) {
+	responses := map[string]*xdsapi.DiscoveryResponse{
+		"istiod-1": debugResponse(t, []xds.LocalityTraffic{
+			{Source: "us/a", Destination: "us/a", Requests: 60},
+			{Source: "us/a", Destination: "us/b", Requests: 20},
+			{Source: "", Destination: "us/b", Requests: 5},
+		}),
+		"istiod-2": debugResponse(t, []xds.LocalityTraffic{
+			{Source: "us/a", Destination: "us/a", Requests: 20},
+			{Source: "us/b", Destination: "us/b", Requests: 15},
+		}),
+	}
+
+	got := &bytes.Buffer{}
+	sw := LocalityMatrixWriter{Writer: got}
+	if err := sw.PrintAll(responses); err != nil {
+		t.Fatal(err)
+	}
+	want := `SOURCE     DESTINATION     REQUESTS     SHARE
+-          us/b            5            100.0%
+us/a       us/a            80           80.0%
+us/a       us/b            20           20.0%
+us/b       us/b            15           100.0%
+
+Cross-locality requests: 20 of 120 (16.7%)
+`
+	assert.Equal(t, got.String(), want)
+}
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// MTLSCompatibilityWriter enables printing of the mTLS and plaintext traffic accepted by each service, and whether
+// it can be moved to STRICT mode, using the mTLS reports of multiple Istiod instances.
+type MTLSCompatibilityWriter struct {
+	Writer io.Writer
+	// Service, if set, restricts the output to the services whose hostname starts with it.
+	Service string
+	// Window is how long a service must have accepted no plaintext traffic to be ready for STRICT mode.
+	Window time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// PrintAll takes the mtlsz responses of Istiod instances, adds up the traffic reported for each service and keeps
+// its latest plaintext request, then outputs whether each service is ready for STRICT mode using a tabwriter.
+func (s *MTLSCompatibilityWriter) PrintAll(responses map[string]*xdsapi.DiscoveryResponse) error {
+	services := map[string]*xds.MTLSCompatibilitySummary{}
+	for _, response := range responses {
+		for _, resource := range response.Resources {
+			var summaries []xds.MTLSCompatibilitySummary
+			if err := json.Unmarshal(resource.Value, &summaries); err != nil {
+				return fmt.Errorf("failed to parse mTLS compatibility response: %v", err)
+			}
+			for _, summary := range summaries {
+				if !strings.HasPrefix(summary.Service, s.Service) {
+					continue
+				}
+				merged, f := services[summary.Service]
+				if !f {
+					merged = &xds.MTLSCompatibilitySummary{Service: summary.Service}
+					services[summary.Service] = merged
+				}
+				merged.MTLS += summary.MTLS
+				merged.Plaintext += summary.Plaintext
+				merged.ReportingProxies += summary.ReportingProxies
+				if summary.LastPlaintext != nil && (merged.LastPlaintext == nil || summary.LastPlaintext.After(*merged.LastPlaintext)) {
+					merged.LastPlaintext = summary.LastPlaintext
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SERVICE\tPROXIES\tMTLS\tPLAINTEXT\tLAST PLAINTEXT\tSTRICT READY")
+	for _, name := range names {
+		summary := services[name]
+		lastPlaintext := "-"
+		ready := "yes"
+		if summary.LastPlaintext != nil {
+			ago := now().Sub(*summary.LastPlaintext)
+			lastPlaintext = ago.Round(time.Second).String() + " ago"
+			if ago < s.Window {
+				ready = "no"
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%.0f\t%.0f\t%s\t%s\n", name, summary.ReportingProxies, summary.MTLS,
+			summary.Plaintext, lastPlaintext, ready)
+	}
+	return w.Flush()
+}
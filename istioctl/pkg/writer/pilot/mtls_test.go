@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestMTLSCompatibilityWriter(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Minute)
+	old := now.Add(-2 * time.Hour)
+	responses := map[string]*xdsapi.DiscoveryResponse{
+		"istiod-1": debugResponse(t, []xds.MTLSCompatibilitySummary{
+			{Service: "foo.default.svc.cluster.local", MTLS: 100, Plaintext: 5,
+				ReportingProxies: 2, LastPlaintext: &old},
+			{Service: "bar.default.svc.cluster.local", MTLS: 10, ReportingProxies: 1},
+		}),
+		"istiod-2": debugResponse(t, []xds.MTLSCompatibilitySummary{
+			{Service: "foo.default.svc.cluster.local", MTLS: 50, Plaintext: 1,
+				ReportingProxies: 1, LastPlaintext: &recent},
+		}),
+	}
+
+	cases := []struct {
+		name    string
+		service string
+		window  time.Duration
+		want    string
+	}{
+		{
+			name:   "all",
+			window: time.Hour,
+			want: `SERVICE                           PROXIES     MTLS     PLAINTEXT     LAST PLAINTEXT     STRICT READY
+bar.default.svc.cluster.local     1           10       0             -                  yes
+foo.default.svc.cluster.local     3           150      6             10m0s ago          no
+`,
+		},
+		{
+			name:    "filtered with short window",
+			service: "foo",
+			window:  5 * time.Minute,
+			want: `SERVICE                           PROXIES     MTLS     PLAINTEXT     LAST PLAINTEXT     STRICT READY
+foo.default.svc.cluster.local     3           150      6             10m0s ago          yes
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			sw := MTLSCompatibilityWriter{Writer: got, Service: tt.service, Window: tt.window, Now: func() time.Time { return now }}
+			if err := sw.PrintAll(responses); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, got.String(), tt.want)
+		})
+	}
+}
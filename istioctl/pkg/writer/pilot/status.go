@@ -24,6 +24,7 @@ import (
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/istioctl/pkg/multixds"
 	"istio.io/istio/pilot/pkg/xds"
@@ -31,9 +32,33 @@ import (
 	"istio.io/pkg/log"
 )
 
+const (
+	// JSONOutput and YAMLOutput are the machine readable output formats of the status writers. Any other format
+	// prints a table.
+	JSONOutput = "json"
+	YAMLOutput = "yaml"
+)
+
+// ProxyStatus is the sync status of a proxy, as printed in the machine readable output formats. Its fields are a
+// stable schema for automation.
+type ProxyStatus struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster,omitempty"`
+	CDS     string `json:"cds"`
+	LDS     string `json:"lds"`
+	EDS     string `json:"eds"`
+	RDS     string `json:"rds"`
+	Istiod  string `json:"istiod"`
+	Version string `json:"version"`
+	// Features is the fingerprint of the feature flags in effect for the config of the proxy.
+	Features string `json:"features,omitempty"`
+}
+
 // StatusWriter enables printing of sync status using multiple []byte Istiod responses
 type StatusWriter struct {
 	Writer io.Writer
+	// OutputFormat is the output format of PrintAll, JSONOutput, YAMLOutput or a table by default.
+	OutputFormat string
 }
 
 type writerStatus struct {
@@ -45,12 +70,15 @@ type writerStatus struct {
 type XdsStatusWriter struct {
 	Writer                 io.Writer
 	InternalDebugAllIstiod bool
+	// OutputFormat is the output format of PrintAll, JSONOutput, YAMLOutput or a table by default.
+	OutputFormat string
 }
 
 type xdsWriterStatus struct {
 	proxyID        string
 	istiodID       string
 	istiodVersion  string
+	features       string
 	clusterStatus  string
 	listenerStatus string
 	routeStatus    string
@@ -63,6 +91,13 @@ func (s *StatusWriter) PrintAll(statuses map[string][]byte) error {
 	if err != nil {
 		return err
 	}
+	if isStructuredOutput(s.OutputFormat) {
+		out := make([]ProxyStatus, 0, len(fullStatus))
+		for _, status := range fullStatus {
+			out = append(out, status.proxyStatus())
+		}
+		return printStructured(s.Writer, s.OutputFormat, out)
+	}
 	for _, status := range fullStatus {
 		if err := statusPrintln(w, status); err != nil {
 			return err
@@ -111,11 +146,7 @@ func (s *StatusWriter) setupStatusPrint(statuses map[string][]byte) (*tabwriter.
 	return w, fullStatus, nil
 }
 
-func statusPrintln(w io.Writer, status *writerStatus) error {
-	clusterSynced := xdsStatus(status.ClusterSent, status.ClusterAcked)
-	listenerSynced := xdsStatus(status.ListenerSent, status.ListenerAcked)
-	routeSynced := xdsStatus(status.RouteSent, status.RouteAcked)
-	endpointSynced := xdsStatus(status.EndpointSent, status.EndpointAcked)
+func (status *writerStatus) proxyStatus() ProxyStatus {
 	version := status.IstioVersion
 	if version == "" {
 		// If we can't find an Istio version (talking to a 1.1 pilot), fallback to the proxy version
@@ -123,8 +154,22 @@ func statusPrintln(w io.Writer, status *writerStatus) error {
 		// but it is better than not providing any information.
 		version = status.ProxyVersion + "*"
 	}
+	return ProxyStatus{
+		Name:    status.ProxyID,
+		Cluster: status.ClusterID,
+		CDS:     xdsStatus(status.ClusterSent, status.ClusterAcked),
+		LDS:     xdsStatus(status.ListenerSent, status.ListenerAcked),
+		EDS:     xdsStatus(status.EndpointSent, status.EndpointAcked),
+		RDS:     xdsStatus(status.RouteSent, status.RouteAcked),
+		Istiod:  status.pilot,
+		Version: version,
+	}
+}
+
+func statusPrintln(w io.Writer, status *writerStatus) error {
+	ps := status.proxyStatus()
 	_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
-		status.ProxyID, status.ClusterID, clusterSynced, listenerSynced, endpointSynced, routeSynced, status.pilot, version)
+		ps.Name, ps.Cluster, ps.CDS, ps.LDS, ps.EDS, ps.RDS, ps.Istiod, ps.Version)
 	return nil
 }
 
@@ -149,15 +194,62 @@ func (s *XdsStatusWriter) PrintAll(statuses map[string]*xdsapi.DiscoveryResponse
 	if err != nil {
 		return err
 	}
+	if isStructuredOutput(s.OutputFormat) {
+		out := make([]ProxyStatus, 0, len(fullStatus))
+		for _, status := range fullStatus {
+			out = append(out, ProxyStatus{
+				Name:     status.proxyID,
+				CDS:      status.clusterStatus,
+				LDS:      status.listenerStatus,
+				EDS:      status.endpointStatus,
+				RDS:      status.routeStatus,
+				Istiod:   status.istiodID,
+				Version:  status.istiodVersion,
+				Features: status.features,
+			})
+		}
+		return printStructured(s.Writer, s.OutputFormat, out)
+	}
 	for _, status := range fullStatus {
 		if err := xdsStatusPrintln(w, status); err != nil {
 			return err
 		}
 	}
 	if w != nil {
-		return w.Flush()
+		if err := w.Flush(); err != nil {
+			return err
+		}
 	}
-	return nil
+	return printFeatureMismatch(s.Writer, fullStatus)
+}
+
+// printFeatureMismatch warns when the config of the proxies was generated by istiods with different feature flags,
+// such as replicas or revisions configured differently.
+func printFeatureMismatch(w io.Writer, fullStatus []*xdsWriterStatus) error {
+	istiods := map[string]string{}
+	fingerprints := map[string]struct{}{}
+	for _, status := range fullStatus {
+		if status.features == "" {
+			continue
+		}
+		istiods[status.istiodID] = status.features
+		fingerprints[status.features] = struct{}{}
+	}
+	if len(fingerprints) < 2 {
+		return nil
+	}
+	ids := make([]string, 0, len(istiods))
+	for id := range istiods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	byIstiod := make([]string, 0, len(ids))
+	for _, id := range ids {
+		byIstiod = append(byIstiod, fmt.Sprintf("%s (%s)", id, istiods[id]))
+	}
+	_, err := fmt.Fprintf(w, "\nWARNING: the config of the proxies was generated with different feature flags by %s. "+
+		"Compare the flags with the /debug/featurez endpoint of each istiod.\n", strings.Join(byIstiod, ", "))
+	return err
 }
 
 func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*xdsapi.DiscoveryResponse) (*tabwriter.Writer, []*xdsWriterStatus, error) {
@@ -180,6 +272,7 @@ func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*xdsapi.DiscoveryRespo
 					proxyID:        clientConfig.GetNode().GetId(),
 					istiodID:       cp.ID,
 					istiodVersion:  cp.Info.Version,
+					features:       cp.Features,
 					clusterStatus:  cds,
 					listenerStatus: lds,
 					routeStatus:    rds,
@@ -220,6 +313,27 @@ func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*xdsapi.DiscoveryRespo
 	return w, fullStatus, nil
 }
 
+func isStructuredOutput(format string) bool {
+	return format == JSONOutput || format == YAMLOutput
+}
+
+// printStructured prints the value as indented JSON, or as YAML.
+func printStructured(w io.Writer, format string, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if format == YAMLOutput {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+	} else {
+		out = append(out, '\n')
+	}
+	_, err = w.Write(out)
+	return err
+}
+
 func xdsStatusPrintln(w io.Writer, status *xdsWriterStatus) error {
 	_, err := fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 		status.proxyID,
@@ -44,6 +44,7 @@ func TestStatusWriter_PrintAll(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   map[string][]xds.SyncStatus
+		format  string
 		want    string
 		wantErr bool
 	}{
@@ -63,6 +64,26 @@ func TestStatusWriter_PrintAll(t *testing.T) {
 			},
 			want: "testdata/multiStatusSinglePilot.txt",
 		},
+		{
+			name: "prints multiple istiod inputs as json",
+			input: map[string][]xds.SyncStatus{
+				"istiod1": statusInput1(),
+				"istiod2": statusInput2(),
+				"istiod3": statusInput3(),
+			},
+			format: JSONOutput,
+			want:   "testdata/multiStatusMultiPilot.json",
+		},
+		{
+			name: "prints multiple istiod inputs as yaml",
+			input: map[string][]xds.SyncStatus{
+				"istiod1": statusInput1(),
+				"istiod2": statusInput2(),
+				"istiod3": statusInput3(),
+			},
+			format: YAMLOutput,
+			want:   "testdata/multiStatusMultiPilot.yaml",
+		},
 		{
 			name: "error if given non-syncstatus info",
 			input: map[string][]xds.SyncStatus{
@@ -74,7 +95,7 @@ func TestStatusWriter_PrintAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := &bytes.Buffer{}
-			sw := StatusWriter{Writer: got}
+			sw := StatusWriter{Writer: got, OutputFormat: tt.format}
 			input := map[string][]byte{}
 			for key, ss := range tt.input {
 				b, _ := json.Marshal(ss)
@@ -299,6 +320,39 @@ func TestXdsStatusWriter_PrintAll(t *testing.T) {
 			},
 			want: "testdata/multiXdsStatusSinglePilot.txt",
 		},
+		{
+			name: "warns about istiods generating config with different feature flags",
+			input: map[string]*xdsapi.DiscoveryResponse{
+				"istiod1": xdsResponseInputWithFeatures("istiod1", "1111", []clientConfigInput{
+					{
+						proxyID:       "proxy1",
+						cdsSyncStatus: status.ConfigStatus_SYNCED,
+						ldsSyncStatus: status.ConfigStatus_SYNCED,
+						rdsSyncStatus: status.ConfigStatus_SYNCED,
+						edsSyncStatus: status.ConfigStatus_SYNCED,
+					},
+				}),
+				"istiod2": xdsResponseInputWithFeatures("istiod2", "2222", []clientConfigInput{
+					{
+						proxyID:       "proxy2",
+						cdsSyncStatus: status.ConfigStatus_SYNCED,
+						ldsSyncStatus: status.ConfigStatus_SYNCED,
+						rdsSyncStatus: status.ConfigStatus_SYNCED,
+						edsSyncStatus: status.ConfigStatus_SYNCED,
+					},
+				}),
+				"istiod3": xdsResponseInputWithFeatures("istiod3", "1111", []clientConfigInput{
+					{
+						proxyID:       "proxy3",
+						cdsSyncStatus: status.ConfigStatus_SYNCED,
+						ldsSyncStatus: status.ConfigStatus_SYNCED,
+						rdsSyncStatus: status.ConfigStatus_SYNCED,
+						edsSyncStatus: status.ConfigStatus_SYNCED,
+					},
+				}),
+			},
+			want: "testdata/multiXdsStatusFeatureMismatch.txt",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -361,12 +415,17 @@ func newXdsClientConfig(config clientConfigInput) *status.ClientConfig {
 }
 
 func xdsResponseInput(istiodID string, configInputs []clientConfigInput) *xdsapi.DiscoveryResponse {
+	return xdsResponseInputWithFeatures(istiodID, "", configInputs)
+}
+
+func xdsResponseInputWithFeatures(istiodID, features string, configInputs []clientConfigInput) *xdsapi.DiscoveryResponse {
 	icp := &xds.IstioControlPlaneInstance{
 		Component: "istiod",
 		ID:        istiodID,
 		Info: istioversion.BuildInfo{
 			Version: "1.1",
 		},
+		Features: features,
 	}
 	identifier, _ := json.Marshal(icp)
 
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package appexit detects the completion of the application containers of a pod, so that the sidecar of Jobs and
+// CronJobs can exit once the job is done instead of keeping the pod running forever.
+//
+// Detection relies on the pod sharing its process namespace (shareProcessNamespace: true): the processes of all the
+// containers are then visible in /proc. Processes running with the user of the agent (the agent itself and Envoy) and
+// the pod infrastructure process (PID 1) are ignored, all others are considered application processes. It does not
+// depend on how traffic is captured, so it works the same with the istio-init container and with the CNI plugin.
+package appexit
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+var watcherLog = log.RegisterScope("appexit", "application exit watcher", 0)
+
+// DefaultInterval is the default interval between two scans of the application processes.
+const DefaultInterval = time.Second
+
+// Watcher periodically scans the processes of the pod and reports when all the application processes have exited.
+type Watcher struct {
+	// procRoot is the mount point of the proc filesystem.
+	procRoot string
+	// selfPID is the PID of the agent.
+	selfPID int
+	// uid is the user of the agent and Envoy, whose processes are not application processes.
+	uid int
+	// interval between two scans.
+	interval time.Duration
+}
+
+// NewWatcher returns a Watcher for the processes of the current pod.
+func NewWatcher(interval time.Duration) *Watcher {
+	return &Watcher{
+		procRoot: "/proc",
+		selfPID:  os.Getpid(),
+		uid:      os.Getuid(),
+		interval: interval,
+	}
+}
+
+// Run blocks until the application processes exit, and then calls onExit. Application processes are only considered
+// exited once they have been seen running, so the agent does not exit before the application starts. Run returns
+// without calling onExit if the context is canceled first, or if the process namespace of the pod is not shared.
+func (w *Watcher) Run(ctx context.Context, onExit func()) {
+	if w.selfPID == 1 {
+		watcherLog.Warnf("process namespace of the pod is not shared, application exit cannot be detected. " +
+			"Set shareProcessNamespace: true in the pod spec")
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	seen := false
+	for {
+		running, err := w.appProcesses()
+		if err != nil {
+			watcherLog.Warnf("failed to list application processes, application exit will not be detected: %v", err)
+			return
+		}
+		if len(running) > 0 {
+			if !seen {
+				watcherLog.Infof("watching application processes %v", running)
+			}
+			seen = true
+		} else if seen {
+			watcherLog.Infof("all application processes exited, shutting down")
+			onExit()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// appProcesses returns the PIDs of the running application processes.
+func (w *Watcher) appProcesses() ([]int, error) {
+	entries, err := os.ReadDir(w.procRoot)
+	if err != nil {
+		return nil, err
+	}
+	var res []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == 1 || pid == w.selfPID {
+			continue
+		}
+		uid, state, err := w.processStatus(pid)
+		if err != nil {
+			// The process exited while listing.
+			continue
+		}
+		// Zombie processes have exited, but have not been reaped by the infrastructure process yet.
+		if uid == w.uid || state == "Z" {
+			continue
+		}
+		res = append(res, pid)
+	}
+	return res, nil
+}
+
+// processStatus returns the real user ID and the state of a process from /proc/<pid>/status.
+func (w *Watcher) processStatus(pid int) (int, string, error) {
+	f, err := os.Open(filepath.Join(w.procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	uid := -1
+	state := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "State:":
+			state = fields[1]
+		case "Uid:":
+			if uid, err = strconv.Atoi(fields[1]); err != nil {
+				return 0, "", err
+			}
+		}
+	}
+	return uid, state, scanner.Err()
+}
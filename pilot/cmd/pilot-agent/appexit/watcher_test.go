@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appexit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeProcess(t *testing.T, root string, pid, uid int, state string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	status := fmt.Sprintf("Name:\tproc\nState:\t%s (state)\nPid:\t%d\nUid:\t%d\t%d\t%d\t%d\n", state, pid, uid, uid, uid, uid)
+	if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestWatcher(t *testing.T) *Watcher {
+	root := t.TempDir()
+	// pause, agent and Envoy.
+	writeProcess(t, root, 1, 65535, "S")
+	writeProcess(t, root, 10, 1337, "S")
+	writeProcess(t, root, 20, 1337, "S")
+	if err := os.WriteFile(filepath.Join(root, "uptime"), []byte("1 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &Watcher{procRoot: root, selfPID: 10, uid: 1337, interval: time.Millisecond}
+}
+
+func TestAppProcesses(t *testing.T) {
+	w := newTestWatcher(t)
+	writeProcess(t, w.procRoot, 30, 0, "S")
+	writeProcess(t, w.procRoot, 31, 1000, "R")
+	writeProcess(t, w.procRoot, 32, 1000, "Z")
+	got, err := w.appProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[30 31]" {
+		t.Fatalf("got application processes %v, want [30 31]", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("exit after application exits", func(t *testing.T) {
+		w := newTestWatcher(t)
+		writeProcess(t, w.procRoot, 30, 0, "S")
+		exited := make(chan struct{})
+		go w.Run(context.Background(), func() { close(exited) })
+
+		select {
+		case <-exited:
+			t.Fatal("exited while the application is running")
+		case <-time.After(50 * time.Millisecond):
+		}
+		if err := os.RemoveAll(filepath.Join(w.procRoot, "30")); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			t.Fatal("application exit was not detected")
+		}
+	})
+
+	t.Run("wait for application to start", func(t *testing.T) {
+		w := newTestWatcher(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		exited := false
+		w.Run(ctx, func() { exited = true })
+		if exited {
+			t.Fatal("exited before the application started")
+		}
+	})
+
+	t.Run("process namespace not shared", func(t *testing.T) {
+		w := newTestWatcher(t)
+		w.selfPID = 1
+		exited := false
+		w.Run(context.Background(), func() { exited = true })
+		if exited {
+			t.Fatal("exited without a shared process namespace")
+		}
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	cleaniptables "istio.io/istio/tools/istio-clean-iptables/pkg/cmd"
+	iptables "istio.io/istio/tools/istio-iptables/pkg/cmd"
+	iptableslog "istio.io/istio/tools/istio-iptables/pkg/log"
+)
+
+// addIptablesCommands adds the commands setting up and cleaning the iptables traffic capture.
+func addIptablesCommands(root *cobra.Command) {
+	root.AddCommand(iptables.GetCommand())
+	root.AddCommand(cleaniptables.GetCommand())
+}
+
+// readIptablesLog logs the packets traced by the iptables rules, until ctx is done.
+func readIptablesLog(ctx context.Context) {
+	iptableslog.ReadNFLOGSocket(ctx)
+}
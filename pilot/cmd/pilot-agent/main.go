@@ -24,6 +24,7 @@ import (
 	"github.com/spf13/cobra/doc"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/cmd/pilot-agent/appexit"
 	"istio.io/istio/pilot/cmd/pilot-agent/config"
 	"istio.io/istio/pilot/cmd/pilot-agent/options"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
@@ -37,9 +38,6 @@ import (
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 	stsserver "istio.io/istio/security/pkg/stsservice/server"
 	"istio.io/istio/security/pkg/stsservice/tokenmanager"
-	cleaniptables "istio.io/istio/tools/istio-clean-iptables/pkg/cmd"
-	iptables "istio.io/istio/tools/istio-iptables/pkg/cmd"
-	iptableslog "istio.io/istio/tools/istio-iptables/pkg/log"
 	"istio.io/pkg/collateral"
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
@@ -147,11 +145,16 @@ var (
 				}
 			}
 
-			go iptableslog.ReadNFLOGSocket(ctx)
+			go readIptablesLog(ctx)
 
 			// On SIGINT or SIGTERM, cancel the context, triggering a graceful shutdown
 			go cmd.WaitSignalFunc(cancel)
 
+			// For Jobs, also shut down gracefully once the application has completed.
+			if options.ExitOnAppExitEnv.Get() {
+				go appexit.NewWatcher(appexit.DefaultInterval).Run(ctx, cancel)
+			}
+
 			// Start in process SDS, dns server, xds proxy, and Envoy.
 			wait, err := agent.Run(ctx)
 			if err != nil {
@@ -196,8 +199,7 @@ func init() {
 
 	rootCmd.AddCommand(proxyCmd)
 	rootCmd.AddCommand(version.CobraCommand())
-	rootCmd.AddCommand(iptables.GetCommand())
-	rootCmd.AddCommand(cleaniptables.GetCommand())
+	addIptablesCommands(rootCmd)
 
 	rootCmd.AddCommand(collateral.CobraCommand(rootCmd, &doc.GenManHeader{
 		Title:   "Istio Pilot Agent",
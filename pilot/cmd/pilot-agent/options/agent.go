@@ -41,6 +41,8 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		EnableDynamicProxyConfig:    enableProxyConfigXdsEnv,
 		EnableDynamicBootstrap:      enableBootstrapXdsEnv,
 		WASMInsecureRegistries:      strings.Split(wasmInsecureRegistries, ","),
+		WASMSignaturePublicKey:      wasmSignaturePublicKey,
+		WASMRequireSignature:        wasmRequireSignature,
 		ProxyIPAddresses:            proxy.IPAddresses,
 		ServiceNode:                 proxy.ServiceNode(),
 		EnvoyStatusPort:             envoyStatusPortEnv,
@@ -54,9 +56,20 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		ProxyXDSDebugViaAgentPort:   proxyXDSDebugViaAgentPort,
 		DNSCapture:                  DNSCaptureByAgent.Get(),
 		DNSAddr:                     DNSCaptureAddr.Get(),
+		DNSHoldTimeout:              DNSHoldTimeout.Get(),
+		DNSTTL:                      DNSTTL.Get(),
 		ProxyNamespace:              PodNamespaceVar.Get(),
 		ProxyDomain:                 proxy.DNSDomain,
 		IstiodSAN:                   istiodSAN.Get(),
+		ReportOutlierEvents:         reportOutlierEventsEnv,
+		HealthCheckEventLogPath:     healthCheckEventLogPathEnv,
+		MTLSReportInterval:          mtlsReportIntervalEnv,
+		XDSFallbackBundle:           xdsFallbackBundleEnv,
+		XDSFallbackBundleSHA256:     xdsFallbackBundleSHA256Env,
+		XDSFallbackTimeout:          xdsFallbackTimeoutEnv,
+	}
+	if xdsCacheEnv {
+		o.XDSCacheFile = filepath.Join(cfg.ConfigPath, "xds-cache.pb")
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
@@ -20,6 +20,7 @@ import (
 
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pkg/config/constants"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/security"
 	"istio.io/pkg/env"
@@ -95,6 +96,14 @@ var (
 	DNSCaptureAddr = env.RegisterStringVar("DNS_PROXY_ADDR", "localhost:15053",
 		"Custom address for the DNS proxy. If it ends with :53 and running as root allows running without iptable DNS capture")
 
+	DNSHoldTimeout = env.RegisterDurationVar("DNS_PROXY_HOLD_TIMEOUT", dnsClient.DefaultHoldTimeout,
+		"How long the DNS proxy holds queries received before it has the DNS table from istiod, instead of failing them. "+
+			"This avoids DNS failures of applications starting before the proxy is ready. Set to 0 to fail them right away")
+
+	DNSTTL = env.RegisterDurationVar("DNS_PROXY_TTL", dnsClient.DefaultTTL,
+		"TTL of the records answered by the DNS proxy for the hosts known to istiod. Lower values make clients "+
+			"pick up endpoint changes sooner, at the cost of more DNS queries. Rounded down to the second")
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.RegisterBoolVar("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
@@ -102,6 +111,19 @@ var (
 	wasmInsecureRegistries = env.RegisterStringVar("WASM_INSECURE_REGISTRIES", "",
 		"allow agent pull wasm plugin from insecure registries, for example: 'localhost:5000,docker-registry:5000'").Get()
 
+	wasmSignaturePublicKey = env.RegisterStringVar("WASM_SIGNATURE_PUBLIC_KEY", "",
+		"Path to a PEM encoded public key. If set, wasm plugin OCI images must have a valid cosign signature for this key").Get()
+
+	wasmRequireSignature = env.RegisterBoolVar("WASM_REQUIRE_SIGNATURE", false,
+		"If set to true, agent refuses to load wasm plugins whose signature cannot be verified, including all plugins "+
+			"downloaded over HTTP. Set it in the mesh wide proxy metadata to enforce signed plugins in the whole mesh").Get()
+
+	// ExitOnAppExitEnv shuts the agent and Envoy down once the application containers have exited, for Jobs and
+	// CronJobs. It requires the pod to share its process namespace.
+	ExitOnAppExitEnv = env.RegisterBoolVar("EXIT_ON_APP_EXIT", false,
+		"If set to true, agent and Envoy exit once all the application processes of the pod have exited. "+
+			"Requires shareProcessNamespace: true in the pod spec")
+
 	// Ability of istio-agent to retrieve bootstrap via XDS
 	enableBootstrapXdsEnv = env.RegisterBoolVar("BOOTSTRAP_XDS_AGENT", false,
 		"If set to true, agent retrieves the bootstrap configuration prior to starting Envoy").Get()
@@ -134,4 +156,35 @@ var (
 	exitOnZeroActiveConnectionsEnv = env.RegisterBoolVar("EXIT_ON_ZERO_ACTIVE_CONNECTIONS",
 		false,
 		"When set to true, terminates proxy when number of active connections become zero during draining").Get()
+
+	reportOutlierEventsEnv = env.RegisterBoolVar("REPORT_OUTLIER_EVENTS", false,
+		"If enabled, the agent reads the outlier detection event log written by Envoy and reports ejections to istiod. "+
+			"Requires --outlierLogPath to point to a file.").Get()
+
+	healthCheckEventLogPathEnv = env.RegisterStringVar("ISTIO_META_HEALTH_CHECK_EVENT_LOG_PATH", "",
+		"If set, the active health checks of clusters log their events to this file, and the agent reports "+
+			"them to istiod.").Get()
+
+	mtlsReportIntervalEnv = env.RegisterDurationVar("MTLS_COMPATIBILITY_REPORT_INTERVAL", 0,
+		"If set, the agent reports the inbound requests and connections of the services of the proxy, by connection "+
+			"security, to istiod at this interval. This shows whether plaintext traffic is still accepted in PERMISSIVE mode.").Get()
+
+	xdsFallbackBundleEnv = env.RegisterStringVar("XDS_FALLBACK_BUNDLE", "",
+		"If set, the path or the HTTPS URL of an Envoy config dump, as returned by /config_dump?include_eds, whose "+
+			"clusters, listeners, routes and endpoints are served to Envoy when istiod cannot be reached within "+
+			"XDS_FALLBACK_TIMEOUT of the startup and no config was cached by XDS_CACHE, so that a cold starting proxy "+
+			"gets the last known good config during a control plane outage. The config dump must come from a proxy "+
+			"of the same workload, as it holds the listeners of its ports. The certificates of the proxy are still "+
+			"served by its CA, so the mTLS clusters are only ready once it is reachable.").Get()
+
+	xdsFallbackBundleSHA256Env = env.RegisterStringVar("XDS_FALLBACK_BUNDLE_SHA256", "",
+		"The hex SHA-256 digest of XDS_FALLBACK_BUNDLE, required for a URL. The bundle is not served if it differs.").Get()
+
+	xdsFallbackTimeoutEnv = env.RegisterDurationVar("XDS_FALLBACK_TIMEOUT", 30*time.Second,
+		"How long the agent waits for istiod at startup before serving XDS_FALLBACK_BUNDLE to Envoy.").Get()
+
+	xdsCacheEnv = env.RegisterBoolVar("XDS_CACHE", false,
+		"If set to true, the agent persists the last clusters, listeners, routes and endpoints ACKed by Envoy in the "+
+			"proxy config directory, and replays them to Envoy when the proxy container restarts, before it connects "+
+			"to istiod. The directory must survive container restarts, as the emptyDir of injected sidecars does.").Get()
 )
@@ -108,6 +108,23 @@ func newDiscoveryCommand() *cobra.Command {
 	}
 }
 
+// addTLSServerFlags adds the flags of the TLS options of one of the istiod servers, prefixed by its name. The client
+// auth flag is only added for the servers authenticating their clients.
+func addTLSServerFlags(c *cobra.Command, name, server string, opts *bootstrap.TLSServerOptions, clientAuth bool) {
+	c.PersistentFlags().StringVar(&opts.MinVersion, name+"-tls-min-version", "",
+		"Minimum TLS version of the "+server+" server, one of TLSV1_0, TLSV1_1, TLSV1_2 or TLSV1_3. Defaults to TLSV1_2")
+	c.PersistentFlags().StringVar(&opts.MaxVersion, name+"-tls-max-version", "",
+		"Maximum TLS version of the "+server+" server. Defaults to the highest supported version")
+	c.PersistentFlags().StringSliceVar(&opts.CipherSuites, name+"-tls-cipher-suites", nil,
+		"Comma-separated list of cipher suites of the "+server+" server. Defaults to --tls-cipher-suites")
+	if !clientAuth {
+		return
+	}
+	c.PersistentFlags().StringVar(&opts.ClientAuth, name+"-tls-client-auth", "",
+		"Whether the "+server+" server ignores (NONE), verifies if presented (OPTIONAL) or requires (REQUIRED) "+
+			"client certificates, verified against the mesh root certificates")
+}
+
 func addFlags(c *cobra.Command) {
 	serverArgs = bootstrap.NewPilotArgs(func(p *bootstrap.PilotArgs) {
 		// Set Defaults
@@ -176,6 +193,12 @@ func addFlags(c *cobra.Command) {
 			"If omitted, the default Go cipher suites will be used. \n"+
 			"Preferred values: "+strings.Join(secureTLSCipherNames(), ", ")+". \n"+
 			"Insecure values: "+strings.Join(insecureTLSCipherNames(), ", ")+".")
+	addTLSServerFlags(c, "xds", "secure discovery", &serverArgs.ServerOptions.TLSOptions.XDS, true)
+	addTLSServerFlags(c, "webhook", "injection and validation webhook", &serverArgs.ServerOptions.TLSOptions.Webhook, false)
+	addTLSServerFlags(c, "debug", "monitoring and debug", &serverArgs.ServerOptions.TLSOptions.Debug, true)
+	c.PersistentFlags().BoolVar(&serverArgs.ServerOptions.MonitoringTLS, "monitoringTLS", false,
+		"Serve the monitoring and debug endpoints of --monitoringAddr over TLS, with the istiod certificate and the "+
+			"--debug-tls-* options. The debug endpoints are then no longer served on --httpAddr")
 
 	c.PersistentFlags().Float32Var(&serverArgs.RegistryOptions.KubeOptions.KubernetesAPIQPS, "kubernetesApiQPS", 80.0,
 		"Maximum QPS when communicating with the kubernetes API")
@@ -26,11 +26,14 @@ import (
 	ingressv1 "istio.io/istio/pilot/pkg/config/kube/ingressv1"
 	"istio.io/istio/pilot/pkg/config/memory"
 	configmonitor "istio.io/istio/pilot/pkg/config/monitor"
+	"istio.io/istio/pilot/pkg/controller/gatewaymigration"
+	"istio.io/istio/pilot/pkg/controller/rollout"
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/status/distribution"
+	"istio.io/istio/pilot/pkg/status/usage"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config/analysis/incluster"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -137,6 +140,13 @@ func (s *Server) initConfigController(args *PilotArgs) error {
 
 	// Create the config store.
 	s.environment.IstioConfigStore = model.MakeIstioStore(s.configController)
+	if features.EnableStagedRollouts {
+		s.XDSServer.StagedRollouts = rollout.NewController(s.configController)
+		s.XDSServer.StagedRollouts.BlastRadius = s.XDSServer.ConfigBlastRadius
+		s.XDSServer.StagedRollouts.RootNamespace = func() string { return s.environment.Mesh().GetRootNamespace() }
+		// The canary proxies are served the configs being rolled out, the other proxies are served a separate view.
+		s.environment.IstioConfigStore = model.MakeIstioStore(s.XDSServer.StagedRollouts.View(false))
+	}
 
 	// Defer starting the controller until after the service is created.
 	s.addStartFunc(func(stop <-chan struct{}) error {
@@ -214,6 +224,16 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 		return err
 	}
 	s.XDSServer.WorkloadEntryController = workloadentry.NewController(configController, args.PodName, args.KeepaliveOptions.MaxServerConnectionAge)
+	if features.ServiceEntryUnusedThreshold > 0 {
+		if features.EnableStatus {
+			s.initServiceEntryUsageController(args)
+		} else {
+			log.Warnf("PILOT_SERVICE_ENTRY_UNUSED_THRESHOLD is ignored, as it requires PILOT_ENABLE_STATUS")
+		}
+	}
+	if features.GatewayAPIMigration != "" {
+		s.initGatewayMigrationController(args)
+	}
 	return nil
 }
 
@@ -307,6 +327,57 @@ func (s *Server) initInprocessAnalysisController(args *PilotArgs) error {
 	return nil
 }
 
+// initServiceEntryUsageController tracks the traffic reported by the proxies, and marks the ServiceEntries
+// which did not receive any with a status condition. Every replica records the traffic of its proxies in the
+// condition, while only the leader marks the ServiceEntries unused.
+func (s *Server) initServiceEntryUsageController(args *PilotArgs) {
+	tracker := usage.NewTracker()
+	s.XDSServer.LoadReportSinks = append(s.XDSServer.LoadReportSinks, tracker)
+	controller := usage.NewController(s.RWConfigStore, tracker, s.statusManager, features.ServiceEntryUnusedThreshold)
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go controller.Run(stop)
+		return nil
+	})
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.ServiceEntryUsageController, args.Revision, s.kubeClient).
+			AddRunFunction(controller.Lead).
+			Run(stop)
+		return nil
+	})
+}
+
+// initGatewayMigrationController converts the Istio Gateways into gateway-api resources. In report mode, every
+// replica reports the conversion without writing it; in apply mode, only the leader writes the converted resources
+// and reports it. Any mode other than apply is a dry run.
+func (s *Server) initGatewayMigrationController(args *PilotArgs) {
+	mode := features.GatewayAPIMigration
+	if mode != gatewaymigration.ModeReport && mode != gatewaymigration.ModeApply {
+		log.Warnf("invalid PILOT_GATEWAY_API_MIGRATION %q, must be %s or %s; only reporting the conversion",
+			mode, gatewaymigration.ModeReport, gatewaymigration.ModeApply)
+		mode = gatewaymigration.ModeReport
+	}
+	controller := gatewaymigration.NewController(s.RWConfigStore, s.ServiceController(), args.RegistryOptions.KubeOptions.DomainSuffix, mode)
+	s.XDSServer.GatewayMigration = controller
+	if mode == gatewaymigration.ModeReport {
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go controller.Run(stop)
+			return nil
+		})
+		return
+	}
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.GatewayMigrationController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				log.Infof("Starting gateway migration controller")
+				controller.Run(leaderStop)
+			}).
+			Run(stop)
+		return nil
+	})
+}
+
 func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 	if s.statusManager == nil && writeStatus {
 		s.initStatusManager(args)
@@ -326,6 +397,9 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 		return nil
 	})
 	s.XDSServer.StatusReporter = s.statusReporter
+	if features.ConvergenceWebhookURL != "" && !writeStatus {
+		log.Warnf("PILOT_CONVERGENCE_WEBHOOK_URL is ignored, as it requires PILOT_ENABLE_STATUS")
+	}
 	if writeStatus {
 		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
 			leaderelection.
@@ -334,6 +408,12 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 					// Controller should be created for calling the run function every time, so it can
 					// avoid concurrently calling of informer Run() for controller in controller.Start
 					controller := distribution.NewController(s.kubeClient.RESTConfig(), args.Namespace, s.RWConfigStore, s.statusManager)
+					if features.ConvergenceWebhookURL != "" {
+						// Only the leader aggregates the reports of all the replicas, so it alone notifies the webhook.
+						webhook := distribution.NewConvergenceWebhook(features.ConvergenceWebhookURL)
+						controller.ConvergenceSinks = append(controller.ConvergenceSinks, webhook)
+						go webhook.Run(stop)
+					}
 					s.statusReporter.SetController(controller)
 					controller.Start(stop)
 				}).Run(stop)
@@ -117,6 +117,18 @@ func (s *Server) initMeshNetworks(args *PilotArgs, fileWatcher filewatcher.FileW
 	}
 }
 
+// initNetworkConfigMaps merges the networks defined by the ConfigMaps with the kubemesh.NetworkLabel into the mesh
+// networks configuration, so that networks can be added and changed without a rollout of the mesh config. Only the
+// ConfigMaps of the istiod namespace are watched.
+func (s *Server) initNetworkConfigMaps(args *PilotArgs) {
+	if s.kubeClient == nil || !features.EnableNetworkConfigMaps {
+		return
+	}
+	log.Info("initializing mesh networks from ConfigMaps")
+	s.environment.NetworksWatcher = kubemesh.NewNetworksWatcher(s.kubeClient, args.Namespace, s.environment.NetworksWatcher,
+		s.internalStop)
+}
+
 func getMeshConfigMapName(revision string) string {
 	name := defaultMeshConfigMapName
 	if revision == "" || revision == "default" {
@@ -15,6 +15,7 @@
 package bootstrap
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -69,7 +70,7 @@ func addMonitor(mux *http.ServeMux) error {
 
 // Deprecated: we shouldn't have 2 http ports. Will be removed after code using
 // this port is removed.
-func startMonitor(addr string, mux *http.ServeMux) (*monitor, error) {
+func startMonitor(addr string, mux *http.ServeMux, tlsConfig *tls.Config) (*monitor, error) {
 	m := &monitor{}
 
 	// get the network stuff setup
@@ -79,6 +80,9 @@ func startMonitor(addr string, mux *http.ServeMux) (*monitor, error) {
 		if listener, err = net.Listen("tcp", addr); err != nil {
 			return nil, fmt.Errorf("unable to listen on socket: %v", err)
 		}
+		if tlsConfig != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
 	}
 
 	// NOTE: this is a temporary solution to provide bare-bones debug functionality
@@ -115,10 +119,10 @@ func (m *monitor) Close() error {
 	return nil
 }
 
-// initMonitor initializes the configuration for the pilot monitoring server.
-func (s *Server) initMonitor(addr string) error { // nolint: unparam
+// initMonitor initializes the configuration for the pilot monitoring server, served over TLS if tlsConfig is not nil.
+func (s *Server) initMonitor(addr string, tlsConfig *tls.Config) error { // nolint: unparam
 	s.addStartFunc(func(stop <-chan struct{}) error {
-		monitor, err := startMonitor(addr, s.monitoringMux)
+		monitor, err := startMonitor(addr, s.monitoringMux, tlsConfig)
 		if err != nil {
 			return err
 		}
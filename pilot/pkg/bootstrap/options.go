@@ -17,9 +17,11 @@ package bootstrap
 import (
 	"crypto/tls"
 	"fmt"
+	"strings"
 	"time"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/keepalive"
@@ -87,6 +89,10 @@ type DiscoveryServerOptions struct {
 	// a port number is automatically chosen.
 	MonitoringAddr string
 
+	// MonitoringTLS serves the monitoring and debug endpoints of MonitoringAddr over TLS, with the Istiod certificate
+	// and the Debug TLS options. The debug endpoints are then no longer served on the plaintext HTTPAddr.
+	MonitoringTLS bool
+
 	EnableProfiling bool
 
 	// Optional TLS configuration
@@ -109,6 +115,88 @@ type TLSOptions struct {
 	KeyFile         string
 	TLSCipherSuites []string
 	CipherSuits     []uint16 // This is the parsed cipher suites
+
+	// XDS, Webhook and Debug are the TLS parameters of the secure discovery, injection webhook and debug servers.
+	// The webhook server does not authenticate its clients, as the API server presents no client certificate.
+	XDS     TLSServerOptions
+	Webhook TLSServerOptions
+	Debug   TLSServerOptions
+}
+
+// TLSServerOptions are the TLS parameters of one of the Istiod servers.
+type TLSServerOptions struct {
+	// MinVersion and MaxVersion are the TLS versions accepted, in the TLSV1_2 format. MinVersion defaults to TLSV1_2.
+	MinVersion string
+	MaxVersion string
+	// CipherSuites are the accepted cipher suites, defaulting to TLSCipherSuites.
+	CipherSuites []string
+	// ClientAuth is whether client certificates are ignored (NONE), verified if presented (OPTIONAL) or required
+	// (REQUIRED). They are verified against the roots of the mesh.
+	ClientAuth string
+}
+
+var tlsVersions = map[string]uint16{
+	"TLSV1_0": tls.VersionTLS10,
+	"TLSV1_1": tls.VersionTLS11,
+	"TLSV1_2": tls.VersionTLS12,
+	"TLSV1_3": tls.VersionTLS13,
+}
+
+var tlsClientAuths = map[string]tls.ClientAuthType{
+	"NONE":     tls.NoClientCert,
+	"OPTIONAL": tls.VerifyClientCertIfGiven,
+	"REQUIRED": tls.RequireAndVerifyClientCert,
+}
+
+func parseTLSVersion(version string, defaultVersion uint16) (uint16, error) {
+	if version == "" {
+		return defaultVersion, nil
+	}
+	v, f := tlsVersions[strings.ToUpper(version)]
+	if !f {
+		return 0, fmt.Errorf("unknown TLS version %s, expected one of TLSV1_0, TLSV1_1, TLSV1_2 or TLSV1_3", version)
+	}
+	return v, nil
+}
+
+// Versions returns the minimum and maximum TLS versions. The maximum is 0, that is the highest supported, if unset.
+func (o TLSServerOptions) Versions() (uint16, uint16, error) {
+	minVersion, err := parseTLSVersion(o.MinVersion, tls.VersionTLS12)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxVersion, err := parseTLSVersion(o.MaxVersion, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if maxVersion != 0 && maxVersion < minVersion {
+		return 0, 0, fmt.Errorf("maximum TLS version %s is lower than the minimum version", o.MaxVersion)
+	}
+	return minVersion, maxVersion, nil
+}
+
+// ClientAuthType returns the client authentication of the server, or defaultAuth if it is unset.
+func (o TLSServerOptions) ClientAuthType(defaultAuth tls.ClientAuthType) (tls.ClientAuthType, error) {
+	if o.ClientAuth == "" {
+		return defaultAuth, nil
+	}
+	auth, f := tlsClientAuths[strings.ToUpper(o.ClientAuth)]
+	if !f {
+		return 0, fmt.Errorf("unknown TLS client auth %s, expected one of NONE, OPTIONAL or REQUIRED", o.ClientAuth)
+	}
+	return auth, nil
+}
+
+// Validate returns an error if the TLS parameters are invalid.
+func (o TLSServerOptions) Validate() error {
+	if _, _, err := o.Versions(); err != nil {
+		return err
+	}
+	if _, err := o.ClientAuthType(tls.NoClientCert); err != nil {
+		return err
+	}
+	_, err := TLSCipherSuites(o.CipherSuites)
+	return err
 }
 
 var (
@@ -150,11 +238,29 @@ func (p *PilotArgs) applyDefaults() {
 }
 
 func (p *PilotArgs) Complete() error {
+	if err := leaderelection.ValidateScopes(); err != nil {
+		return err
+	}
 	cipherSuits, err := TLSCipherSuites(p.ServerOptions.TLSOptions.TLSCipherSuites)
 	if err != nil {
 		return err
 	}
 	p.ServerOptions.TLSOptions.CipherSuits = cipherSuits
+	for name, o := range map[string]TLSServerOptions{
+		"xds":     p.ServerOptions.TLSOptions.XDS,
+		"webhook": p.ServerOptions.TLSOptions.Webhook,
+		"debug":   p.ServerOptions.TLSOptions.Debug,
+	} {
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("invalid %s TLS options: %v", name, err)
+		}
+	}
+	if auth, _ := p.ServerOptions.TLSOptions.Webhook.ClientAuthType(tls.NoClientCert); auth != tls.NoClientCert {
+		return fmt.Errorf("invalid webhook TLS options: the webhook server does not support client authentication")
+	}
+	if p.ServerOptions.MonitoringTLS && p.ServerOptions.MonitoringAddr == "" {
+		return fmt.Errorf("serving the monitoring endpoints over TLS requires a monitoring address")
+	}
 	return nil
 }
 
@@ -47,6 +47,7 @@ import (
 	"istio.io/istio/pilot/pkg/keycertbundle"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pilot/pkg/server"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
@@ -236,6 +237,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	spiffe.SetTrustDomain(s.environment.Mesh().GetTrustDomain())
 
 	s.initMeshNetworks(args, s.fileWatcher)
+	s.initNetworkConfigMaps(args)
 	s.initMeshHandlers()
 	s.environment.Init()
 	if err := s.environment.InitNetworksManager(s.XDSServer); err != nil {
@@ -289,7 +291,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	var wh *inject.Webhook
 	// common https server for webhooks (e.g. injection, validation)
 	if s.kubeClient != nil {
-		s.initSecureWebhookServer(args)
+		if err := s.initSecureWebhookServer(args); err != nil {
+			return nil, fmt.Errorf("error initializing secure webhook server: %v", err)
+		}
 		wh, err = s.initSidecarInjector(args)
 		if err != nil {
 			return nil, fmt.Errorf("error initializing sidecar injector: %v", err)
@@ -312,6 +316,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	}
 	// This should be called only after controllers are initialized.
 	s.initRegistryEventHandlers()
+	s.initTrustDomainMigration()
 
 	s.initDiscoveryService(args)
 
@@ -524,7 +529,8 @@ func (s *Server) initSDSServer() {
 		creds := kubecredentials.NewMulticluster(s.clusterID)
 		creds.AddSecretHandler(func(name string, namespace string) {
 			s.XDSServer.ConfigUpdate(&model.PushRequest{
-				Full: false,
+				// The credentials of egress proxies are part of the listeners and routes of the sidecars.
+				Full: s.XDSServer.IsEgressProxyCredential(name, namespace),
 				ConfigsUpdated: map[model.ConfigKey]struct{}{
 					{
 						Kind:      gvk.Secret,
@@ -535,8 +541,9 @@ func (s *Server) initSDSServer() {
 				Reason: []model.TriggerReason{model.SecretTrigger},
 			})
 		})
-		s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(creds, s.XDSServer.Cache, s.clusterID)
+		s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(creds, s.XDSServer.Cache, s.clusterID, s.XDSServer)
 		s.multiclusterController.AddHandler(creds)
+		s.environment.CredentialsController = creds
 	}
 }
 
@@ -627,12 +634,21 @@ func (s *Server) initIstiodAdminServer(args *PilotArgs, whc func() map[string]st
 
 	// Debug handlers are currently added on monitoring mux and readiness mux.
 	// If monitoring addr is empty, the mux is shared and we only add it once on the shared mux .
-	if !shouldMultiplex {
+	// If the monitoring server is served over TLS, the debug handlers are not added on the plaintext readiness mux.
+	if !shouldMultiplex && !args.ServerOptions.MonitoringTLS {
 		s.XDSServer.AddDebugHandlers(s.httpMux, nil, args.ServerOptions.EnableProfiling, whc)
 	}
 
 	// Monitoring Server.
-	if err := s.initMonitor(args.ServerOptions.MonitoringAddr); err != nil {
+	var monitoringTLS *tls.Config
+	if args.ServerOptions.MonitoringTLS {
+		cfg, err := s.serverTLSConfig(args.ServerOptions.TLSOptions, args.ServerOptions.TLSOptions.Debug, tls.NoClientCert, nil)
+		if err != nil {
+			return fmt.Errorf("error initializing monitoring TLS: %v", err)
+		}
+		monitoringTLS = cfg
+	}
+	if err := s.initMonitor(args.ServerOptions.MonitoringAddr, monitoringTLS); err != nil {
 		return fmt.Errorf("error initializing monitor: %v", err)
 	}
 
@@ -753,19 +769,9 @@ func (s *Server) initSecureDiscoveryService(args *PilotArgs) error {
 		return nil
 	}
 	log.Info("initializing secure discovery service")
-	cfg := &tls.Config{
-		GetCertificate: s.getIstiodCertificate,
-		ClientAuth:     tls.VerifyClientCertIfGiven,
-		ClientCAs:      peerCertVerifier.GetGeneralCertPool(),
-		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-			err := peerCertVerifier.VerifyPeerCert(rawCerts, verifiedChains)
-			if err != nil {
-				log.Infof("Could not verify certificate: %v", err)
-			}
-			return err
-		},
-		MinVersion:   tls.VersionTLS12,
-		CipherSuites: args.ServerOptions.TLSOptions.CipherSuits,
+	cfg, err := s.serverTLSConfig(args.ServerOptions.TLSOptions, args.ServerOptions.TLSOptions.XDS, tls.VerifyClientCertIfGiven, peerCertVerifier)
+	if err != nil {
+		return err
 	}
 
 	tlsCreds := credentials.NewTLS(cfg)
@@ -1062,6 +1068,54 @@ func hasCustomTLSCerts(tlsOptions TLSOptions) bool {
 	return tlsOptions.CaCertFile != "" && tlsOptions.CertFile != "" && tlsOptions.KeyFile != ""
 }
 
+// serverTLSConfig returns the TLS config of an Istiod server, with its TLS options. The server serves the Istiod
+// certificate, which is looked up on every handshake so that it keeps rotating. Unless the client auth is NONE, the
+// client certificates are verified with the peer certificate verifier, which is created if nil.
+func (s *Server) serverTLSConfig(tlsOptions TLSOptions, opts TLSServerOptions, defaultAuth tls.ClientAuthType,
+	peerCertVerifier *spiffe.PeerCertVerifier) (*tls.Config, error) {
+	minVersion, maxVersion, err := opts.Versions()
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites := tlsOptions.CipherSuits
+	if len(opts.CipherSuites) > 0 {
+		if cipherSuites, err = TLSCipherSuites(opts.CipherSuites); err != nil {
+			return nil, err
+		}
+	}
+	clientAuth, err := opts.ClientAuthType(defaultAuth)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		GetCertificate: s.getIstiodCertificate,
+		ClientAuth:     clientAuth,
+		MinVersion:     minVersion,
+		MaxVersion:     maxVersion,
+		CipherSuites:   cipherSuites,
+	}
+	if clientAuth == tls.NoClientCert {
+		return cfg, nil
+	}
+	if peerCertVerifier == nil {
+		if peerCertVerifier, err = s.createPeerCertVerifier(tlsOptions); err != nil {
+			return nil, err
+		}
+		if peerCertVerifier == nil {
+			return nil, fmt.Errorf("client certificates cannot be verified without the mesh root certificates")
+		}
+	}
+	cfg.ClientCAs = peerCertVerifier.GetGeneralCertPool()
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		err := peerCertVerifier.VerifyPeerCert(rawCerts, verifiedChains)
+		if err != nil {
+			log.Infof("Could not verify certificate: %v", err)
+		}
+		return err
+	}
+	return cfg, nil
+}
+
 // getIstiodCertificate returns the istiod certificate.
 func (s *Server) getIstiodCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
 	s.certMu.RLock()
@@ -1276,6 +1330,39 @@ func (s *Server) isDisableCa() bool {
 	return false
 }
 
+// initTrustDomainMigration pushes the configuration to all proxies when the trust domain migration ends, so they
+// stop accepting identities in the previous trust domain.
+func (s *Server) initTrustDomainMigration() {
+	migration := trustdomain.CurrentMigration()
+	if migration == nil {
+		return
+	}
+	if !migration.Active(time.Now()) {
+		log.Infof("trust domain migration from %s ended at %v", migration.From, migration.End)
+		return
+	}
+	log.Infof("trust domain migration from %s to %s in progress", migration.From, s.environment.Mesh().TrustDomain)
+	if migration.End.IsZero() {
+		return
+	}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go func() {
+			t := time.NewTimer(time.Until(migration.End))
+			defer t.Stop()
+			select {
+			case <-t.C:
+				log.Infof("trust domain migration from %s ended", migration.From)
+				s.XDSServer.ConfigUpdate(&model.PushRequest{
+					Full:   true,
+					Reason: []model.TriggerReason{model.GlobalUpdate},
+				})
+			case <-stop:
+			}
+		}()
+		return nil
+	})
+}
+
 func (s *Server) initStatusManager(_ *PilotArgs) {
 	s.addStartFunc(func(stop <-chan struct{}) error {
 		s.statusManager = status.NewManager(s.RWConfigStore)
@@ -338,6 +338,7 @@ func TestIstiodCipherSuites(t *testing.T) {
 	cases := []struct {
 		name               string
 		serverCipherSuites []uint16
+		webhookTLS         TLSServerOptions
 		clientCipherSuites []uint16
 		expectSuccess      bool
 	}{
@@ -357,6 +358,20 @@ func TestIstiodCipherSuites(t *testing.T) {
 			clientCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
 			expectSuccess:      false,
 		},
+		{
+			name:               "webhook cipher suites override istiod cipher suites",
+			serverCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+			webhookTLS: TLSServerOptions{
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			},
+			clientCipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			expectSuccess:      true,
+		},
+		{
+			name:          "webhook minimum TLS version above the client",
+			webhookTLS:    TLSServerOptions{MinVersion: "TLSV1_3"},
+			expectSuccess: false,
+		},
 	}
 
 	for _, c := range cases {
@@ -379,6 +394,7 @@ func TestIstiodCipherSuites(t *testing.T) {
 					HTTPSAddr:      fmt.Sprintf(":%d", port),
 					TLSOptions: TLSOptions{
 						CipherSuits: c.serverCipherSuites,
+						Webhook:     c.webhookTLS,
 					},
 				}
 				p.RegistryOptions = RegistryOptions{
@@ -443,6 +459,104 @@ func TestIstiodCipherSuites(t *testing.T) {
 	}
 }
 
+func TestTLSServerOptions(t *testing.T) {
+	cases := []struct {
+		name       string
+		opts       TLSServerOptions
+		minVersion uint16
+		maxVersion uint16
+		clientAuth tls.ClientAuthType
+		err        bool
+	}{
+		{
+			name:       "defaults",
+			minVersion: tls.VersionTLS12,
+			clientAuth: tls.VerifyClientCertIfGiven,
+		},
+		{
+			name:       "explicit",
+			opts:       TLSServerOptions{MinVersion: "TLSV1_3", MaxVersion: "tlsv1_3", ClientAuth: "REQUIRED"},
+			minVersion: tls.VersionTLS13,
+			maxVersion: tls.VersionTLS13,
+			clientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name: "maximum below minimum",
+			opts: TLSServerOptions{MaxVersion: "TLSV1_1"},
+			err:  true,
+		},
+		{
+			name: "unknown version",
+			opts: TLSServerOptions{MinVersion: "SSLV3"},
+			err:  true,
+		},
+		{
+			name: "unknown client auth",
+			opts: TLSServerOptions{ClientAuth: "ALWAYS"},
+			err:  true,
+		},
+		{
+			name: "unknown cipher suite",
+			opts: TLSServerOptions{CipherSuites: []string{"TLS_NULL"}},
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.Validate()
+			if c.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			minVersion, maxVersion, _ := c.opts.Versions()
+			clientAuth, _ := c.opts.ClientAuthType(tls.VerifyClientCertIfGiven)
+			if minVersion != c.minVersion || maxVersion != c.maxVersion || clientAuth != c.clientAuth {
+				t.Fatalf("got versions %x-%x and client auth %v", minVersion, maxVersion, clientAuth)
+			}
+		})
+	}
+}
+
+func TestCompleteTLSServerOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opts DiscoveryServerOptions
+		err  bool
+	}{
+		{
+			name: "debug client auth",
+			opts: DiscoveryServerOptions{TLSOptions: TLSOptions{Debug: TLSServerOptions{ClientAuth: "REQUIRED"}}},
+		},
+		{
+			name: "webhook client auth",
+			opts: DiscoveryServerOptions{TLSOptions: TLSOptions{Webhook: TLSServerOptions{ClientAuth: "OPTIONAL"}}},
+			err:  true,
+		},
+		{
+			name: "monitoring TLS",
+			opts: DiscoveryServerOptions{MonitoringAddr: ":15014", MonitoringTLS: true},
+		},
+		{
+			name: "monitoring TLS without monitoring address",
+			opts: DiscoveryServerOptions{MonitoringTLS: true},
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args := &PilotArgs{ServerOptions: c.opts}
+			if err := args.Complete(); (err != nil) != c.err {
+				t.Fatalf("got error %v, want error %v", err, c.err)
+			}
+		})
+	}
+}
+
 func TestNewServerWithMockRegistry(t *testing.T) {
 	cases := []struct {
 		name             string
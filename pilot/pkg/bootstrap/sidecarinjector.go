@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/webhooks"
 	"istio.io/pkg/env"
@@ -90,16 +91,20 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 	// This requires RBAC permissions - a low-priv Istiod should not attempt to patch but rely on
 	// operator or CI/CD
 	if features.InjectionWebhookConfigName != "" {
-		s.addStartFunc(func(stop <-chan struct{}) error {
-			// No leader election - different istiod revisions will patch their own cert.
-			// update webhook configuration by watching the cabundle
-			patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, s.istiodCertBundleWatcher)
-			if err != nil {
-				log.Errorf("failed to create webhook cert patcher: %v", err)
-				return nil
-			}
-
-			go patcher.Run(stop)
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			// Elected per revision - different istiod revisions will patch their own cert.
+			leaderelection.
+				NewPerRevisionLeaderElection(args.Namespace, args.PodName, leaderelection.WebhookCertPatcherController, args.Revision, s.kubeClient).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					// update webhook configuration by watching the cabundle
+					patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, s.istiodCertBundleWatcher)
+					if err != nil {
+						log.Errorf("failed to create webhook cert patcher: %v", err)
+						return
+					}
+					patcher.Run(leaderStop)
+				}).
+				Run(stop)
 			return nil
 		})
 	}
@@ -16,6 +16,7 @@ package bootstrap
 
 import (
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/webhooks/validation/controller"
 	"istio.io/istio/pkg/webhooks/validation/server"
@@ -40,10 +41,15 @@ func (s *Server) initConfigValidation(args *PilotArgs) error {
 	}
 
 	if features.ValidationWebhookConfigName != "" && s.kubeClient != nil {
-		s.addStartFunc(func(stop <-chan struct{}) error {
-			log.Infof("Starting validation controller")
-			go controller.NewValidatingWebhookController(
-				s.kubeClient, args.Revision, args.Namespace, s.istiodCertBundleWatcher).Run(stop)
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			leaderelection.
+				NewPerRevisionLeaderElection(args.Namespace, args.PodName, leaderelection.ValidationController, args.Revision, s.kubeClient).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					log.Infof("Starting validation controller")
+					controller.NewValidatingWebhookController(
+						s.kubeClient, args.Revision, args.Namespace, s.istiodCertBundleWatcher).Run(leaderStop)
+				}).
+				Run(stop)
 			return nil
 		})
 	}
@@ -30,25 +30,25 @@ const (
 // initSSecureWebhookServer handles initialization for the HTTPS webhook server.
 // If https address is off the injection handlers will be registered on the main http endpoint, with
 // TLS handled by a proxy/gateway in front of Istiod.
-func (s *Server) initSecureWebhookServer(args *PilotArgs) {
+func (s *Server) initSecureWebhookServer(args *PilotArgs) error {
 	// create the https server for hosting the k8s injectionWebhook handlers.
 	if args.ServerOptions.HTTPSAddr == "" {
 		s.httpsMux = s.httpMux
 		log.Info("HTTPS port is disabled, multiplexing webhooks on the httpAddr ", args.ServerOptions.HTTPAddr)
-		return
+		return nil
 	}
 
 	log.Info("initializing secure webhook server for istiod webhooks")
+	tlsConfig, err := s.serverTLSConfig(args.ServerOptions.TLSOptions, args.ServerOptions.TLSOptions.Webhook, tls.NoClientCert, nil)
+	if err != nil {
+		return err
+	}
 	// create the https server for hosting the k8s injectionWebhook handlers.
 	s.httpsMux = http.NewServeMux()
 	s.httpsServer = &http.Server{
-		Addr:    args.ServerOptions.HTTPSAddr,
-		Handler: s.httpsMux,
-		TLSConfig: &tls.Config{
-			GetCertificate: s.getIstiodCertificate,
-			MinVersion:     tls.VersionTLS12,
-			CipherSuites:   args.ServerOptions.TLSOptions.CipherSuits,
-		},
+		Addr:      args.ServerOptions.HTTPSAddr,
+		Handler:   s.httpsMux,
+		TLSConfig: tlsConfig,
 	}
 
 	// setup our readiness handler and the corresponding client we'll use later to check it with.
@@ -64,6 +64,7 @@ func (s *Server) initSecureWebhookServer(args *PilotArgs) {
 		},
 	}
 	s.addReadinessProbe("Secure Webhook Server", s.webhookReadyHandler)
+	return nil
 }
 
 func (s *Server) webhookReadyHandler() (bool, error) {
@@ -458,6 +458,10 @@ func buildTCPVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 			reportError(err)
 			return nil
 		}
+		if len(route) == 0 {
+			// All backends have a zero weight, so connections are rejected.
+			continue
+		}
 		ir := &istio.TCPRoute{
 			Route: route,
 		}
@@ -465,6 +469,9 @@ func buildTCPVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 	}
 
 	reportError(nil)
+	if len(routes) == 0 {
+		return nil
+	}
 	vsConfig := config.Config{
 		Meta: config.Meta{
 			CreationTimestamp: obj.CreationTimestamp,
@@ -488,7 +495,7 @@ func buildTCPVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 func buildTLSVirtualService(obj config.Config, gateways map[parentKey]map[k8s.SectionName]*parentInfo, domain string) *config.Config {
 	route := obj.Spec.(*k8s.TLSRouteSpec)
 
-	parentRefs := extractParentReferenceInfo(gateways, route.ParentRefs, nil, gvk.TLSRoute, obj.Namespace)
+	parentRefs := extractParentReferenceInfo(gateways, route.ParentRefs, route.Hostnames, gvk.TLSRoute, obj.Namespace)
 
 	reportError := func(routeErr *ConfigError) {
 		obj.Status.(*kstatus.WrappedStatus).Mutate(func(s config.Status) config.Status {
@@ -497,6 +504,11 @@ func buildTLSVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 			return rs
 		})
 	}
+	gatewayNames := referencesToInternalNames(parentRefs)
+	if len(gatewayNames) == 0 {
+		reportError(nil)
+		return nil
+	}
 
 	routes := []*istio.TLSRoute{}
 	for _, r := range route.Rules {
@@ -506,7 +518,8 @@ func buildTLSVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 			return nil
 		}
 		if len(dest) == 0 {
-			return nil
+			// All backends have a zero weight, so connections are rejected.
+			continue
 		}
 		ir := &istio.TLSRoute{
 			Match: buildTLSMatch(route.Hostnames),
@@ -516,9 +529,7 @@ func buildTLSVirtualService(obj config.Config, gateways map[parentKey]map[k8s.Se
 	}
 
 	reportError(nil)
-	gatewayNames := referencesToInternalNames(parentRefs)
-	if len(gatewayNames) == 0 {
-		// TODO we need to properly return not admitted here
+	if len(routes) == 0 {
 		return nil
 	}
 	vsConfig := config.Config{
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewaymigration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("gatewaymigration", "Gateway API migration debugging", 0)
+
+const (
+	// ModeReport is a dry run: it only reports which resources can be converted, and outputs the converted resources
+	// without writing them. It is the default mode.
+	ModeReport = "report"
+	// ModeApply also creates and updates the converted Gateway API resources. It must be set explicitly.
+	ModeApply = "apply"
+
+	// reconcileInterval is how often the resources are converted.
+	reconcileInterval = time.Minute
+)
+
+// Controller periodically converts the Istio Gateways and the VirtualServices bound to them into Gateway API
+// resources, and keeps the compatibility report of the conversion. In apply mode, it creates the converted resources
+// and updates the ones it created. The converted resources are never deleted: once the migration of a gateway is
+// verified, the Istio resources are expected to be deleted, and the Gateway API resources kept.
+type Controller struct {
+	store     model.ConfigStore
+	discovery model.ServiceDiscovery
+	domain    string
+	apply     bool
+
+	mu     sync.RWMutex
+	report Report
+}
+
+func NewController(store model.ConfigStore, discovery model.ServiceDiscovery, domain string, mode string) *Controller {
+	return &Controller{
+		store:     store,
+		discovery: discovery,
+		domain:    domain,
+		apply:     mode == ModeApply,
+		report:    Report{Mode: mode, Resources: []ResourceReport{}},
+	}
+}
+
+// Run is blocking
+func (c *Controller) Run(stop <-chan struct{}) {
+	t := time.NewTicker(reconcileInterval)
+	defer t.Stop()
+	c.reconcile()
+	for {
+		select {
+		case <-t.C:
+			c.reconcile()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Report returns the compatibility report of the last conversion.
+func (c *Controller) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.report
+}
+
+// DebugReport returns the compatibility report of the last conversion, for the debug endpoint.
+func (c *Controller) DebugReport() interface{} {
+	return c.Report()
+}
+
+func (c *Controller) reconcile() {
+	gateways, err := c.store.List(gvk.Gateway, model.NamespaceAll)
+	if err != nil {
+		log.Errorf("failed to list Gateways: %v", err)
+		return
+	}
+	virtualServices, err := c.store.List(gvk.VirtualService, model.NamespaceAll)
+	if err != nil {
+		log.Errorf("failed to list VirtualServices: %v", err)
+		return
+	}
+	services, err := c.discovery.Services()
+	if err != nil {
+		log.Errorf("failed to list services: %v", err)
+		return
+	}
+	converted, report := Convert(gateways, virtualServices, services, c.domain)
+	report.Output = converted
+	if c.apply {
+		errs := map[string][]string{}
+		for _, cfg := range converted {
+			if err := c.write(cfg); err != nil {
+				from := cfg.Annotations[ConvertedFromAnnotation]
+				errs[from] = append(errs[from], err.Error())
+			}
+		}
+		for i, rr := range report.Resources {
+			report.Resources[i].Errors = errs[fmt.Sprintf("%s/%s/%s", rr.Kind, rr.Namespace, rr.Name)]
+		}
+	}
+	c.mu.Lock()
+	report.Mode = c.report.Mode
+	c.report = report
+	c.mu.Unlock()
+}
+
+// write creates the converted resource, or updates it if it was converted from the same resource.
+func (c *Controller) write(cfg config.Config) error {
+	current := c.store.Get(cfg.GroupVersionKind, cfg.Name, cfg.Namespace)
+	if current == nil {
+		log.Infof("creating %s converted from %s", resourceName(cfg), cfg.Annotations[ConvertedFromAnnotation])
+		_, err := c.store.Create(cfg)
+		return err
+	}
+	if current.Annotations[ConvertedFromAnnotation] != cfg.Annotations[ConvertedFromAnnotation] {
+		return fmt.Errorf("%s already exists and was not converted from this resource", resourceName(cfg))
+	}
+	// The specs are compared in JSON, as the empty lists of the converted spec are read back as nil.
+	currentSpec, err := json.Marshal(current.Spec)
+	if err != nil {
+		return err
+	}
+	spec, err := json.Marshal(cfg.Spec)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(currentSpec, spec) {
+		return nil
+	}
+	log.Infof("updating %s converted from %s", resourceName(cfg), cfg.Annotations[ConvertedFromAnnotation])
+	updated := current.DeepCopy()
+	updated.Spec = cfg.Spec
+	_, err = c.store.Update(updated)
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewaymigration
+
+import (
+	"testing"
+
+	k8s "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func newTestController(t *testing.T, mode string, configs ...config.Config) (*Controller, model.ConfigStore) {
+	store := memory.Make(collections.PilotGatewayAPI)
+	for _, cfg := range configs {
+		if _, err := store.Create(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	discovery := memregistry.NewServiceDiscovery([]*model.Service{ingressService, reviewsService})
+	return NewController(store, discovery, "cluster.local", mode), store
+}
+
+func TestControllerReport(t *testing.T) {
+	c, store := newTestController(t, ModeReport, gatewayConfig("bookinfo", httpServer))
+	c.reconcile()
+	if got := c.Report(); got.Mode != ModeReport || len(got.Resources) != 1 || len(got.Resources[0].Converted) != 1 || len(got.Output) != 1 {
+		t.Fatalf("unexpected report %+v", got)
+	}
+	if gws, _ := store.List(gvk.KubernetesGateway, model.NamespaceAll); len(gws) != 0 {
+		t.Fatalf("expected no resource to be written in report mode, got %v", gws)
+	}
+}
+
+func TestControllerApply(t *testing.T) {
+	c, store := newTestController(t, ModeApply, gatewayConfig("bookinfo", httpServer))
+	c.reconcile()
+	gw := store.Get(gvk.KubernetesGateway, "bookinfo", "istio-system")
+	if gw == nil || len(gw.Spec.(*k8s.GatewaySpec).Listeners) != 2 {
+		t.Fatalf("expected the converted Gateway to be created, got %v", gw)
+	}
+
+	// The converted resources are updated along with the Istio resources.
+	if _, err := store.Update(gatewayConfig("bookinfo", httpServer, httpsServer)); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcile()
+	if gw := store.Get(gvk.KubernetesGateway, "bookinfo", "istio-system"); len(gw.Spec.(*k8s.GatewaySpec).Listeners) != 3 {
+		t.Fatalf("expected the converted Gateway to be updated, got %v", gw)
+	}
+}
+
+func TestControllerApplyConflict(t *testing.T) {
+	existing := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.KubernetesGateway, Name: "bookinfo", Namespace: "istio-system"},
+		Spec: &k8s.GatewaySpec{GatewayClassName: "other"},
+	}
+	c, store := newTestController(t, ModeApply, gatewayConfig("bookinfo", httpServer), existing)
+	c.reconcile()
+	if gw := store.Get(gvk.KubernetesGateway, "bookinfo", "istio-system"); gw.Spec.(*k8s.GatewaySpec).GatewayClassName != "other" {
+		t.Fatalf("expected the existing Gateway not to be overwritten, got %v", gw)
+	}
+	if errs := c.Report().Resources[0].Errors; len(errs) != 1 {
+		t.Fatalf("expected the conflict to be reported, got %v", errs)
+	}
+}
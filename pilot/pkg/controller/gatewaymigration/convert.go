@@ -0,0 +1,744 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gatewaymigration converts the Istio Gateways and the VirtualServices bound to them into the equivalent
+// Gateway API resources, to migrate the ingress configuration to the Gateway API incrementally. The resources which
+// use features without Gateway API equivalent are not converted, and are listed in a compatibility report along with
+// the unsupported features.
+package gatewaymigration
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/kube/gateway"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// ConvertedFromAnnotation is set on the Gateway API resources converted from an Istio resource, to the kind,
+// namespace and name of that resource, such as "VirtualService/default/bookinfo". Only the resources with this
+// annotation are updated when the Istio resource changes.
+const ConvertedFromAnnotation = "gateway.istio.io/converted-from"
+
+// namespaceNameLabel is the label set by Kubernetes on the namespaces to their name.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// ResourceReport is the result of the conversion of an Istio Gateway or VirtualService.
+type ResourceReport struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Converted are the equivalent Gateway API resources, as Kind/namespace/name. It is empty when the resource
+	// cannot be converted.
+	Converted []string `json:"converted,omitempty"`
+	// Unsupported are the features of the resource without Gateway API equivalent.
+	Unsupported []string `json:"unsupported,omitempty"`
+	// Errors are the errors writing the converted resources.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Report is the compatibility report of the Istio Gateways and of the VirtualServices bound to them.
+type Report struct {
+	// Mode is the mode of the controller, report or apply.
+	Mode      string           `json:"mode"`
+	Resources []ResourceReport `json:"resources"`
+	// Output are the converted resources. In report mode, they are not written, and are to be reviewed and applied
+	// manually.
+	Output []config.Config `json:"output,omitempty"`
+}
+
+// Convert converts the Istio Gateways and the VirtualServices bound to them into Gateway API resources. The
+// services are used to find the Services of the gateway deployments and the ports of the destinations.
+func Convert(gateways, virtualServices []config.Config, services []*model.Service, domain string) ([]config.Config, Report) {
+	c := &converter{
+		domain:    domain,
+		services:  map[host.Name]*model.Service{},
+		converted: map[string][]string{},
+	}
+	for _, svc := range services {
+		c.services[svc.Hostname] = svc
+	}
+	sortConfigs(gateways)
+	sortConfigs(virtualServices)
+
+	out := []config.Config{}
+	report := Report{Resources: []ResourceReport{}}
+	for _, cfg := range gateways {
+		res, unsupported := c.convertGateway(cfg, services)
+		rr := ResourceReport{Kind: gvk.Gateway.Kind, Namespace: cfg.Namespace, Name: cfg.Name, Unsupported: unsupported}
+		if res != nil {
+			c.converted[cfg.Namespace+"/"+cfg.Name] = routeNamespaces(cfg)
+			out = append(out, *res)
+			rr.Converted = []string{resourceName(*res)}
+		}
+		report.Resources = append(report.Resources, rr)
+	}
+	for _, cfg := range virtualServices {
+		vs := cfg.Spec.(*networking.VirtualService)
+		if len(vs.Gateways) == 0 {
+			// Only bound to the sidecars.
+			continue
+		}
+		res, unsupported := c.convertVirtualService(cfg)
+		rr := ResourceReport{Kind: gvk.VirtualService.Kind, Namespace: cfg.Namespace, Name: cfg.Name, Unsupported: unsupported}
+		for _, r := range res {
+			out = append(out, r)
+			rr.Converted = append(rr.Converted, resourceName(r))
+		}
+		report.Resources = append(report.Resources, rr)
+	}
+	return out, report
+}
+
+type converter struct {
+	domain   string
+	services map[host.Name]*model.Service
+	// converted are the namespaces of the hosts of the Istio Gateways converted, by namespace/name.
+	converted map[string][]string
+}
+
+// routeNamespaces returns the namespaces of the hosts of the servers of the Gateway, from which VirtualServices may
+// be bound to it.
+func routeNamespaces(cfg config.Config) []string {
+	out := []string{}
+	for _, server := range cfg.Spec.(*networking.Gateway).Servers {
+		for _, h := range server.Hosts {
+			ns := "*"
+			if parts := strings.SplitN(h, "/", 2); len(parts) == 2 {
+				ns = parts[0]
+			}
+			if ns == "." {
+				ns = cfg.Namespace
+			}
+			out = append(out, ns)
+		}
+	}
+	return out
+}
+
+// allowsRoutesFrom returns whether the listeners of the converted Gateway allow the routes of the namespace.
+func allowsRoutesFrom(namespaces []string, namespace string) bool {
+	for _, ns := range namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *converter) convertGateway(cfg config.Config, services []*model.Service) (*config.Config, []string) {
+	gw := cfg.Spec.(*networking.Gateway)
+	var unsupported []string
+	addresses := gatewayAddresses(gw.Selector, services)
+	if len(addresses) == 0 {
+		unsupported = append(unsupported, fmt.Sprintf("no Service selects the gateway pods %v", labels.Instance(gw.Selector)))
+	}
+	listeners := []k8s.Listener{}
+	names := map[string]bool{}
+	for i, server := range gw.Servers {
+		ls, issues := convertServer(cfg.Namespace, server)
+		for _, issue := range issues {
+			unsupported = append(unsupported, fmt.Sprintf("server %d: %s", i, issue))
+		}
+		for _, l := range ls {
+			l.Name = uniqueName(string(l.Name), names)
+			listeners = append(listeners, l)
+		}
+	}
+	if len(unsupported) > 0 {
+		return nil, unsupported
+	}
+	spec := &k8s.GatewaySpec{
+		GatewayClassName: gateway.DefaultClassName,
+		Listeners:        listeners,
+	}
+	hostnameType := k8s.HostnameAddressType
+	for _, addr := range addresses {
+		spec.Addresses = append(spec.Addresses, k8s.GatewayAddress{Type: &hostnameType, Value: addr})
+	}
+	return convertedConfig(gvk.KubernetesGateway, cfg, cfg.Name, spec), nil
+}
+
+// gatewayAddresses returns the hostnames of the Kubernetes Services of the gateway pods, which select the same pods.
+func gatewayAddresses(selector map[string]string, services []*model.Service) []string {
+	if len(selector) == 0 {
+		return nil
+	}
+	addresses := []string{}
+	for _, svc := range services {
+		svcSelector := labels.Instance(svc.Attributes.LabelSelectors)
+		if svc.Attributes.ServiceRegistry != provider.Kubernetes || len(svcSelector) == 0 {
+			continue
+		}
+		if svcSelector.SubsetOf(selector) || labels.Instance(selector).SubsetOf(svcSelector) {
+			addresses = append(addresses, string(svc.Hostname))
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// convertServer converts a server of a Gateway into a listener for each of its hosts.
+func convertServer(namespace string, server *networking.Server) ([]k8s.Listener, []string) {
+	var unsupported []string
+	if server.Bind != "" {
+		unsupported = append(unsupported, "bind")
+	}
+	if server.DefaultEndpoint != "" {
+		unsupported = append(unsupported, "defaultEndpoint")
+	}
+	proto, tls, issues := convertServerProtocol(server)
+	unsupported = append(unsupported, issues...)
+	if len(unsupported) > 0 {
+		return nil, unsupported
+	}
+	name := server.GetPort().GetName()
+	if name == "" {
+		name = fmt.Sprintf("port-%d", server.GetPort().GetNumber())
+	}
+	listeners := make([]k8s.Listener, 0, len(server.Hosts))
+	for _, h := range server.Hosts {
+		ns, hostname := "*", h
+		if parts := strings.SplitN(h, "/", 2); len(parts) == 2 {
+			ns, hostname = parts[0], parts[1]
+		}
+		l := k8s.Listener{
+			Name:          k8s.SectionName(sanitizeName(name)),
+			Port:          k8s.PortNumber(server.GetPort().GetNumber()),
+			Protocol:      proto,
+			TLS:           tls,
+			AllowedRoutes: allowedRoutes(namespace, ns),
+		}
+		if hostname != "*" {
+			hn := k8s.Hostname(hostname)
+			l.Hostname = &hn
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func convertServerProtocol(server *networking.Server) (k8s.ProtocolType, *k8s.GatewayTLSConfig, []string) {
+	tls := server.GetTls()
+	p := protocol.Parse(server.GetPort().GetProtocol())
+	switch {
+	case p.IsHTTP():
+		if tls.GetHttpsRedirect() {
+			return "", nil, []string{"httpsRedirect"}
+		}
+		return k8s.HTTPProtocolType, nil, nil
+	case p.IsTLS():
+	case p.IsTCP():
+		return k8s.TCPProtocolType, nil, nil
+	default:
+		return "", nil, []string{fmt.Sprintf("protocol %s", server.GetPort().GetProtocol())}
+	}
+
+	var unsupported []string
+	if tls.GetServerCertificate() != "" || tls.GetPrivateKey() != "" || tls.GetCaCertificates() != "" {
+		unsupported = append(unsupported, "file mounted certificates")
+	}
+	if len(tls.GetSubjectAltNames()) > 0 || len(tls.GetVerifyCertificateSpki()) > 0 || len(tls.GetVerifyCertificateHash()) > 0 {
+		unsupported = append(unsupported, "client certificate verification")
+	}
+	if tls.GetMinProtocolVersion() != networking.ServerTLSSettings_TLS_AUTO ||
+		tls.GetMaxProtocolVersion() != networking.ServerTLSSettings_TLS_AUTO || len(tls.GetCipherSuites()) > 0 {
+		unsupported = append(unsupported, "TLS versions and cipher suites")
+	}
+	if tls.GetHttpsRedirect() {
+		unsupported = append(unsupported, "httpsRedirect")
+	}
+	out := &k8s.GatewayTLSConfig{}
+	switch tls.GetMode() {
+	case networking.ServerTLSSettings_SIMPLE:
+		if tls.GetCredentialName() == "" {
+			unsupported = append(unsupported, "TLS without credentialName")
+			break
+		}
+		mode := k8s.TLSModeTerminate
+		out.Mode = &mode
+		out.CertificateRefs = []*k8s.SecretObjectReference{{Name: k8s.ObjectName(tls.GetCredentialName())}}
+	case networking.ServerTLSSettings_PASSTHROUGH:
+		mode := k8s.TLSModePassthrough
+		out.Mode = &mode
+	default:
+		unsupported = append(unsupported, fmt.Sprintf("TLS mode %s", tls.GetMode()))
+	}
+	if len(unsupported) > 0 {
+		return "", nil, unsupported
+	}
+	if p.IsHTTPS() && tls.GetMode() == networking.ServerTLSSettings_SIMPLE {
+		return k8s.HTTPSProtocolType, out, nil
+	}
+	return k8s.TLSProtocolType, out, nil
+}
+
+// allowedRoutes converts the namespace of a host of a Gateway server into the namespaces whose routes may attach to
+// the listener.
+func allowedRoutes(gatewayNamespace, ns string) *k8s.AllowedRoutes {
+	from := k8s.NamespacesFromAll
+	var selector *metav1.LabelSelector
+	switch ns {
+	case "*":
+	case ".", gatewayNamespace:
+		from = k8s.NamespacesFromSame
+	default:
+		from = k8s.NamespacesFromSelector
+		selector = &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: ns}}
+	}
+	return &k8s.AllowedRoutes{Namespaces: &k8s.RouteNamespaces{From: &from, Selector: selector}}
+}
+
+func (c *converter) convertVirtualService(cfg config.Config) ([]config.Config, []string) {
+	vs := cfg.Spec.(*networking.VirtualService)
+	var unsupported []string
+	parents := []k8s.ParentRef{}
+	for _, gw := range vs.Gateways {
+		if gw == constants.IstioMeshGateway {
+			unsupported = append(unsupported, "routes of the mesh gateway")
+			continue
+		}
+		ns, name := cfg.Namespace, gw
+		if parts := strings.SplitN(gw, "/", 2); len(parts) == 2 {
+			ns, name = parts[0], parts[1]
+		}
+		namespaces, ok := c.converted[ns+"/"+name]
+		if !ok {
+			unsupported = append(unsupported, fmt.Sprintf("gateway %s/%s is not converted", ns, name))
+			continue
+		}
+		// The cross namespace parent references are not allowed by ReferencePolicies, but by the allowedRoutes of
+		// the listeners, which are converted from the namespaces of the hosts of the servers.
+		if !allowsRoutesFrom(namespaces, cfg.Namespace) {
+			unsupported = append(unsupported, fmt.Sprintf("gateway %s/%s does not allow routes from namespace %s", ns, name, cfg.Namespace))
+			continue
+		}
+		ref := k8s.ParentRef{Name: k8s.ObjectName(name)}
+		if ns != cfg.Namespace {
+			parentNs := k8s.Namespace(ns)
+			ref.Namespace = &parentNs
+		}
+		parents = append(parents, ref)
+	}
+	if len(vs.ExportTo) > 0 {
+		unsupported = append(unsupported, "exportTo")
+	}
+
+	out := []config.Config{}
+	// backends are the namespaces of the Services referenced from another namespace, by kind of route.
+	backends := map[config.GroupVersionKind]map[string]bool{}
+	if len(vs.Http) > 0 {
+		spec := &k8s.HTTPRouteSpec{CommonRouteSpec: k8s.CommonRouteSpec{ParentRefs: parents}, Hostnames: hostnames(vs.Hosts)}
+		for i, r := range vs.Http {
+			rule, issues := c.convertHTTPRoute(cfg.Namespace, r)
+			for _, issue := range issues {
+				unsupported = append(unsupported, fmt.Sprintf("http route %d: %s", i, issue))
+			}
+			spec.Rules = append(spec.Rules, rule)
+			for _, ref := range rule.BackendRefs {
+				addBackendNamespace(backends, gvk.HTTPRoute, ref.BackendObjectReference)
+			}
+		}
+		out = append(out, *convertedConfig(gvk.HTTPRoute, cfg, cfg.Name, spec))
+	}
+	if len(vs.Tcp) > 0 {
+		spec := &k8s.TCPRouteSpec{CommonRouteSpec: k8s.CommonRouteSpec{ParentRefs: parents}}
+		for i, r := range vs.Tcp {
+			if len(r.Match) > 0 {
+				unsupported = append(unsupported, fmt.Sprintf("tcp route %d: match", i))
+			}
+			refs, issues := c.convertBackendRefs(cfg.Namespace, r.Route)
+			for _, issue := range issues {
+				unsupported = append(unsupported, fmt.Sprintf("tcp route %d: %s", i, issue))
+			}
+			spec.Rules = append(spec.Rules, k8s.TCPRouteRule{BackendRefs: refs})
+			for _, ref := range refs {
+				addBackendNamespace(backends, gvk.TCPRoute, ref.BackendObjectReference)
+			}
+		}
+		out = append(out, *convertedConfig(gvk.TCPRoute, cfg, cfg.Name, spec))
+	}
+	if len(vs.Tls) > 0 {
+		spec := &k8s.TLSRouteSpec{CommonRouteSpec: k8s.CommonRouteSpec{ParentRefs: parents}}
+		if len(vs.Tls) > 1 {
+			unsupported = append(unsupported, "multiple tls routes")
+		}
+		r := vs.Tls[0]
+		for _, m := range r.Match {
+			if len(m.DestinationSubnets) > 0 || m.Port != 0 || len(m.SourceLabels) > 0 || len(m.Gateways) > 0 || m.SourceNamespace != "" {
+				unsupported = append(unsupported, "tls route 0: match other than sniHosts")
+			}
+			spec.Hostnames = append(spec.Hostnames, hostnames(m.SniHosts)...)
+		}
+		refs, issues := c.convertBackendRefs(cfg.Namespace, r.Route)
+		for _, issue := range issues {
+			unsupported = append(unsupported, fmt.Sprintf("tls route 0: %s", issue))
+		}
+		spec.Rules = []k8s.TLSRouteRule{{BackendRefs: refs}}
+		for _, ref := range refs {
+			addBackendNamespace(backends, gvk.TLSRoute, ref.BackendObjectReference)
+		}
+		out = append(out, *convertedConfig(gvk.TLSRoute, cfg, cfg.Name, spec))
+	}
+	if len(unsupported) > 0 {
+		return nil, unsupported
+	}
+	return append(out, referencePolicies(cfg, backends)...), nil
+}
+
+// addBackendNamespace records the namespace of the backend, if it is a Service of another namespace than the route.
+func addBackendNamespace(backends map[config.GroupVersionKind]map[string]bool, kind config.GroupVersionKind, ref k8s.BackendObjectReference) {
+	if ref.Namespace == nil || ref.Kind != nil {
+		return
+	}
+	if backends[kind] == nil {
+		backends[kind] = map[string]bool{}
+	}
+	backends[kind][string(*ref.Namespace)] = true
+}
+
+// referencePolicies returns the ReferencePolicies allowing the routes converted from the VirtualService to reference
+// the Services of other namespaces, as the VirtualServices could route to any namespace without them.
+func referencePolicies(cfg config.Config, backends map[config.GroupVersionKind]map[string]bool) []config.Config {
+	from := map[string][]k8s.ReferencePolicyFrom{}
+	for _, kind := range []config.GroupVersionKind{gvk.HTTPRoute, gvk.TCPRoute, gvk.TLSRoute} {
+		for ns := range backends[kind] {
+			from[ns] = append(from[ns], k8s.ReferencePolicyFrom{
+				Group:     k8s.Group(kind.Group),
+				Kind:      k8s.Kind(kind.Kind),
+				Namespace: k8s.Namespace(cfg.Namespace),
+			})
+		}
+	}
+	namespaces := make([]string, 0, len(from))
+	for ns := range from {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	out := make([]config.Config, 0, len(from))
+	for _, ns := range namespaces {
+		spec := &k8s.ReferencePolicySpec{
+			From: from[ns],
+			To:   []k8s.ReferencePolicyTo{{Group: "", Kind: k8s.Kind(gvk.Service.Kind)}},
+		}
+		policy := convertedConfig(gvk.ReferencePolicy, cfg, sanitizeName(cfg.Namespace+"-"+cfg.Name), spec)
+		policy.Namespace = ns
+		out = append(out, *policy)
+	}
+	return out
+}
+
+func (c *converter) convertHTTPRoute(namespace string, r *networking.HTTPRoute) (k8s.HTTPRouteRule, []string) {
+	var unsupported []string
+	for field, set := range map[string]bool{
+		"delegate":         r.Delegate != nil,
+		"rewrite":          r.Rewrite != nil,
+		"timeout":          r.Timeout != nil,
+		"retries":          r.Retries != nil,
+		"fault":            r.Fault != nil,
+		"corsPolicy":       r.CorsPolicy != nil,
+		"mirrorPercentage": r.MirrorPercentage != nil && r.MirrorPercentage.Value != 100 || r.MirrorPercent != nil && r.MirrorPercent.Value != 100,
+		"response headers": r.Headers.GetResponse() != nil,
+	} {
+		if set {
+			unsupported = append(unsupported, field)
+		}
+	}
+	rule := k8s.HTTPRouteRule{}
+	for _, m := range r.Match {
+		match, issues := convertHTTPMatch(m)
+		unsupported = append(unsupported, issues...)
+		rule.Matches = append(rule.Matches, match)
+	}
+	if req := r.Headers.GetRequest(); req != nil {
+		rule.Filters = append(rule.Filters, k8s.HTTPRouteFilter{
+			Type: k8s.HTTPRouteFilterRequestHeaderModifier,
+			RequestHeaderModifier: &k8s.HTTPRequestHeaderFilter{
+				Set:    headerList(req.Set),
+				Add:    headerList(req.Add),
+				Remove: req.Remove,
+			},
+		})
+	}
+	if r.Redirect != nil {
+		filter, issues := convertRedirect(r.Redirect)
+		unsupported = append(unsupported, issues...)
+		rule.Filters = append(rule.Filters, k8s.HTTPRouteFilter{Type: k8s.HTTPRouteFilterRequestRedirect, RequestRedirect: filter})
+	}
+	if r.Mirror != nil {
+		ref, issues := c.convertDestination(namespace, r.Mirror)
+		unsupported = append(unsupported, issues...)
+		rule.Filters = append(rule.Filters, k8s.HTTPRouteFilter{
+			Type:          k8s.HTTPRouteFilterRequestMirror,
+			RequestMirror: &k8s.HTTPRequestMirrorFilter{BackendRef: ref},
+		})
+	}
+	for _, dst := range r.Route {
+		if dst.Headers != nil {
+			unsupported = append(unsupported, "destination headers")
+		}
+		ref, issues := c.convertDestination(namespace, dst.Destination)
+		unsupported = append(unsupported, issues...)
+		backend := k8s.HTTPBackendRef{BackendRef: k8s.BackendRef{BackendObjectReference: ref}}
+		if len(r.Route) > 1 {
+			weight := dst.Weight
+			backend.Weight = &weight
+		}
+		rule.BackendRefs = append(rule.BackendRefs, backend)
+	}
+	sort.Strings(unsupported)
+	return rule, unsupported
+}
+
+func convertHTTPMatch(m *networking.HTTPMatchRequest) (k8s.HTTPRouteMatch, []string) {
+	var unsupported []string
+	for field, set := range map[string]bool{
+		"scheme match":          m.Scheme != nil,
+		"authority match":       m.Authority != nil,
+		"port match":            m.Port != 0,
+		"sourceLabels match":    len(m.SourceLabels) > 0,
+		"gateways match":        len(m.Gateways) > 0,
+		"ignoreUriCase":         m.IgnoreUriCase,
+		"withoutHeaders match":  len(m.WithoutHeaders) > 0,
+		"sourceNamespace match": m.SourceNamespace != "",
+	} {
+		if set {
+			unsupported = append(unsupported, field)
+		}
+	}
+	match := k8s.HTTPRouteMatch{}
+	if m.Uri != nil {
+		var t k8s.PathMatchType
+		var v string
+		switch {
+		case m.Uri.GetExact() != "":
+			t, v = k8s.PathMatchExact, m.Uri.GetExact()
+		case m.Uri.GetPrefix() != "":
+			t, v = k8s.PathMatchPathPrefix, m.Uri.GetPrefix()
+		default:
+			t, v = k8s.PathMatchRegularExpression, m.Uri.GetRegex()
+		}
+		match.Path = &k8s.HTTPPathMatch{Type: &t, Value: &v}
+	}
+	for _, name := range sortedMatchKeys(m.Headers) {
+		sm := m.Headers[name]
+		t := k8s.HeaderMatchExact
+		v := sm.GetExact()
+		if sm.GetPrefix() != "" {
+			unsupported = append(unsupported, fmt.Sprintf("prefix match of header %s", name))
+		} else if sm.GetRegex() != "" {
+			t, v = k8s.HeaderMatchRegularExpression, sm.GetRegex()
+		}
+		match.Headers = append(match.Headers, k8s.HTTPHeaderMatch{Type: &t, Name: k8s.HTTPHeaderName(name), Value: v})
+	}
+	for _, name := range sortedMatchKeys(m.QueryParams) {
+		sm := m.QueryParams[name]
+		t := k8s.QueryParamMatchExact
+		v := sm.GetExact()
+		if sm.GetPrefix() != "" {
+			unsupported = append(unsupported, fmt.Sprintf("prefix match of query parameter %s", name))
+		} else if sm.GetRegex() != "" {
+			t, v = k8s.QueryParamMatchRegularExpression, sm.GetRegex()
+		}
+		match.QueryParams = append(match.QueryParams, k8s.HTTPQueryParamMatch{Type: &t, Name: name, Value: v})
+	}
+	if m.Method != nil {
+		if m.Method.GetExact() == "" {
+			unsupported = append(unsupported, "method match other than exact")
+		}
+		method := k8s.HTTPMethod(m.Method.GetExact())
+		match.Method = &method
+	}
+	return match, unsupported
+}
+
+func convertRedirect(r *networking.HTTPRedirect) (*k8s.HTTPRequestRedirectFilter, []string) {
+	var unsupported []string
+	filter := &k8s.HTTPRequestRedirectFilter{}
+	if r.Uri != "" {
+		unsupported = append(unsupported, "redirect uri")
+	}
+	if r.GetDerivePort() != networking.HTTPRedirect_FROM_PROTOCOL_DEFAULT {
+		unsupported = append(unsupported, "redirect derivePort")
+	}
+	if r.Scheme != "" {
+		scheme := r.Scheme
+		filter.Scheme = &scheme
+	}
+	if r.Authority != "" {
+		hostname := k8s.PreciseHostname(r.Authority)
+		filter.Hostname = &hostname
+	}
+	if r.GetPort() != 0 {
+		port := k8s.PortNumber(r.GetPort())
+		filter.Port = &port
+	}
+	if r.RedirectCode != 0 {
+		code := int(r.RedirectCode)
+		if code != 301 && code != 302 {
+			unsupported = append(unsupported, fmt.Sprintf("redirect code %d", code))
+		}
+		filter.StatusCode = &code
+	}
+	return filter, unsupported
+}
+
+func (c *converter) convertBackendRefs(namespace string, route []*networking.RouteDestination) ([]k8s.BackendRef, []string) {
+	var unsupported []string
+	refs := make([]k8s.BackendRef, 0, len(route))
+	for _, dst := range route {
+		ref, issues := c.convertDestination(namespace, dst.Destination)
+		unsupported = append(unsupported, issues...)
+		backend := k8s.BackendRef{BackendObjectReference: ref}
+		if len(route) > 1 {
+			weight := dst.Weight
+			backend.Weight = &weight
+		}
+		refs = append(refs, backend)
+	}
+	return refs, unsupported
+}
+
+// convertDestination converts the destination into a reference to the Kubernetes Service of its host, or to the
+// Istio Hostname backend for the other hosts.
+func (c *converter) convertDestination(namespace string, dst *networking.Destination) (k8s.BackendObjectReference, []string) {
+	var unsupported []string
+	if dst.GetSubset() != "" {
+		unsupported = append(unsupported, fmt.Sprintf("subset %s of %s", dst.GetSubset(), dst.GetHost()))
+	}
+	// The hosts are resolved as in the VirtualServices: the short names are in the namespace of the VirtualService,
+	// and the other hosts are fully qualified, so that only name.namespace.svc.<domain> is a Kubernetes Service.
+	fqdn := string(model.ResolveShortnameToFQDN(dst.GetHost(), config.Meta{Namespace: namespace, Domain: c.domain}))
+	name, ns, isService := fqdn, namespace, false
+	if parts := strings.Split(fqdn, "."); strings.HasSuffix(fqdn, ".svc."+c.domain) && len(parts) == 4+strings.Count(c.domain, ".") {
+		name, ns, isService = parts[0], parts[1], true
+	}
+	ref := k8s.BackendObjectReference{Name: k8s.ObjectName(name)}
+	if isService {
+		if ns != namespace {
+			refNs := k8s.Namespace(ns)
+			ref.Namespace = &refNs
+		}
+	} else {
+		group, kind := k8s.Group(gvk.ServiceEntry.Group), k8s.Kind("Hostname")
+		ref.Group, ref.Kind = &group, &kind
+	}
+	port := dst.GetPort().GetNumber()
+	if port == 0 {
+		if svc := c.services[host.Name(fqdn)]; svc != nil && len(svc.Ports) == 1 {
+			port = uint32(svc.Ports[0].Port)
+		} else {
+			unsupported = append(unsupported, fmt.Sprintf("destination %s without port", dst.GetHost()))
+		}
+	}
+	if port != 0 {
+		p := k8s.PortNumber(port)
+		ref.Port = &p
+	}
+	return ref, unsupported
+}
+
+func convertedConfig(kind config.GroupVersionKind, from config.Config, name string, spec config.Spec) *config.Config {
+	return &config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: kind,
+			Namespace:        from.Namespace,
+			Name:             name,
+			Annotations:      map[string]string{ConvertedFromAnnotation: resourceName(from)},
+		},
+		Spec: spec,
+	}
+}
+
+func resourceName(cfg config.Config) string {
+	return fmt.Sprintf("%s/%s/%s", cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name)
+}
+
+func hostnames(hosts []string) []k8s.Hostname {
+	out := []k8s.Hostname{}
+	for _, h := range hosts {
+		if h != "*" {
+			out = append(out, k8s.Hostname(h))
+		}
+	}
+	return out
+}
+
+func headerList(headers map[string]string) []k8s.HTTPHeader {
+	out := []k8s.HTTPHeader{}
+	for _, name := range sortedKeys(headers) {
+		out = append(out, k8s.HTTPHeader{Name: k8s.HTTPHeaderName(name), Value: headers[name]})
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedMatchKeys(m map[string]*networking.StringMatch) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// sanitizeName turns the name of a Gateway port into a valid listener name.
+func sanitizeName(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		return "listener"
+	}
+	return name
+}
+
+func uniqueName(name string, used map[string]bool) k8s.SectionName {
+	unique := name
+	for i := 1; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[unique] = true
+	return k8s.SectionName(unique)
+}
+
+func sortConfigs(configs []config.Config) {
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Namespace != configs[j].Namespace {
+			return configs[i].Namespace < configs[j].Namespace
+		}
+		return configs[i].Name < configs[j].Name
+	})
+}
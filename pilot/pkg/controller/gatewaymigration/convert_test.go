@@ -0,0 +1,262 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewaymigration
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	k8s "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+var ingressService = &model.Service{
+	Hostname: "istio-ingressgateway.istio-system.svc.cluster.local",
+	Attributes: model.ServiceAttributes{
+		ServiceRegistry: provider.Kubernetes,
+		LabelSelectors:  map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+	},
+}
+
+var reviewsService = &model.Service{
+	Hostname:   "reviews.default.svc.cluster.local",
+	Ports:      model.PortList{{Name: "http", Port: 9080}},
+	Attributes: model.ServiceAttributes{ServiceRegistry: provider.Kubernetes, LabelSelectors: map[string]string{"app": "reviews"}},
+}
+
+func gatewayConfig(name string, servers ...*networking.Server) config.Config {
+	return config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.Gateway, Name: name, Namespace: "istio-system"},
+		Spec: &networking.Gateway{
+			Selector: map[string]string{"istio": "ingressgateway"},
+			Servers:  servers,
+		},
+	}
+}
+
+func virtualServiceConfig(name string, vs *networking.VirtualService) config.Config {
+	return config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: name, Namespace: "default"},
+		Spec: vs,
+	}
+}
+
+var httpServer = &networking.Server{
+	Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+	Hosts: []string{"default/bookinfo.example.com", "*/*.example.org"},
+}
+
+var httpsServer = &networking.Server{
+	Port:  &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+	Hosts: []string{"bookinfo.example.com"},
+	Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE, CredentialName: "bookinfo-cert"},
+}
+
+func TestConvertGateway(t *testing.T) {
+	gateways := []config.Config{
+		gatewayConfig("bookinfo", httpServer, httpsServer),
+		gatewayConfig("mtls", &networking.Server{
+			Port:  &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+			Hosts: []string{"*"},
+			Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_MUTUAL, CredentialName: "cert"},
+		}),
+	}
+	out, report := Convert(gateways, nil, []*model.Service{ingressService, reviewsService}, "cluster.local")
+	if len(out) != 1 {
+		t.Fatalf("expected a single converted Gateway, got %v", out)
+	}
+	if got := out[0].Annotations[ConvertedFromAnnotation]; got != "Gateway/istio-system/bookinfo" {
+		t.Fatalf("unexpected %s annotation %q", ConvertedFromAnnotation, got)
+	}
+	spec := out[0].Spec.(*k8s.GatewaySpec)
+	if len(spec.Addresses) != 1 || spec.Addresses[0].Value != "istio-ingressgateway.istio-system.svc.cluster.local" {
+		t.Fatalf("unexpected addresses %v", spec.Addresses)
+	}
+	names := []k8s.SectionName{}
+	for _, l := range spec.Listeners {
+		names = append(names, l.Name)
+	}
+	if want := []k8s.SectionName{"http", "http-1", "https"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got listeners %v, want %v", names, want)
+	}
+	if from := *spec.Listeners[0].AllowedRoutes.Namespaces.Selector; from.MatchLabels[namespaceNameLabel] != "default" {
+		t.Fatalf("expected the routes of the default namespace to be allowed, got %v", from)
+	}
+	if from := *spec.Listeners[1].AllowedRoutes.Namespaces.From; from != k8s.NamespacesFromAll || *spec.Listeners[1].Hostname != "*.example.org" {
+		t.Fatalf("unexpected listener %+v", spec.Listeners[1])
+	}
+	https := spec.Listeners[2]
+	if https.Protocol != k8s.HTTPSProtocolType || *https.TLS.Mode != k8s.TLSModeTerminate || https.TLS.CertificateRefs[0].Name != "bookinfo-cert" {
+		t.Fatalf("unexpected HTTPS listener %+v", https)
+	}
+
+	want := []ResourceReport{
+		{Kind: "Gateway", Namespace: "istio-system", Name: "bookinfo", Converted: []string{"Gateway/istio-system/bookinfo"}},
+		{Kind: "Gateway", Namespace: "istio-system", Name: "mtls", Unsupported: []string{"server 0: TLS mode MUTUAL"}},
+	}
+	if !reflect.DeepEqual(report.Resources, want) {
+		t.Fatalf("got report %+v, want %+v", report.Resources, want)
+	}
+}
+
+func TestConvertGatewayWithoutService(t *testing.T) {
+	_, report := Convert([]config.Config{gatewayConfig("bookinfo", httpServer)}, nil, []*model.Service{reviewsService}, "cluster.local")
+	if got := report.Resources[0].Unsupported; len(got) != 1 || report.Resources[0].Converted != nil {
+		t.Fatalf("expected the gateway without Service not to be converted, got %+v", report.Resources[0])
+	}
+}
+
+func TestConvertVirtualService(t *testing.T) {
+	gateways := []config.Config{gatewayConfig("bookinfo", httpServer)}
+	virtualServices := []config.Config{
+		virtualServiceConfig("bookinfo", &networking.VirtualService{
+			Hosts:    []string{"bookinfo.example.com"},
+			Gateways: []string{"istio-system/bookinfo"},
+			Http: []*networking.HTTPRoute{{
+				Match: []*networking.HTTPMatchRequest{{
+					Uri:     &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/reviews"}},
+					Headers: map[string]*networking.StringMatch{"x-user": {MatchType: &networking.StringMatch_Exact{Exact: "jason"}}},
+				}},
+				Route: []*networking.HTTPRouteDestination{
+					{Destination: &networking.Destination{Host: "reviews"}, Weight: 90},
+					{Destination: &networking.Destination{Host: "ratings.default.svc.cluster.local", Port: &networking.PortSelector{Number: 9080}}, Weight: 10},
+				},
+				Headers: &networking.Headers{Request: &networking.Headers_HeaderOperations{Set: map[string]string{"x-canary": "true"}}},
+			}, {
+				Redirect: &networking.HTTPRedirect{Authority: "www.example.com", RedirectCode: 301},
+			}},
+		}),
+		virtualServiceConfig("timeout", &networking.VirtualService{
+			Hosts:    []string{"bookinfo.example.com"},
+			Gateways: []string{"istio-system/bookinfo", "mesh"},
+			Http: []*networking.HTTPRoute{{
+				Route:   []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews", Subset: "v1"}}},
+				Timeout: &types.Duration{Seconds: 1},
+			}},
+		}),
+		virtualServiceConfig("mesh-only", &networking.VirtualService{
+			Hosts: []string{"reviews"},
+			Http:  []*networking.HTTPRoute{{Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}}}},
+		}),
+	}
+	out, report := Convert(gateways, virtualServices, []*model.Service{ingressService, reviewsService}, "cluster.local")
+	if len(out) != 2 || out[1].GroupVersionKind != gvk.HTTPRoute {
+		t.Fatalf("expected a Gateway and an HTTPRoute, got %v", out)
+	}
+	route := out[1].Spec.(*k8s.HTTPRouteSpec)
+	if len(route.ParentRefs) != 1 || route.ParentRefs[0].Name != "bookinfo" || *route.ParentRefs[0].Namespace != "istio-system" {
+		t.Fatalf("unexpected parents %v", route.ParentRefs)
+	}
+	if !reflect.DeepEqual(route.Hostnames, []k8s.Hostname{"bookinfo.example.com"}) {
+		t.Fatalf("unexpected hostnames %v", route.Hostnames)
+	}
+	rule := route.Rules[0]
+	if *rule.Matches[0].Path.Type != k8s.PathMatchPathPrefix || *rule.Matches[0].Path.Value != "/reviews" || rule.Matches[0].Headers[0].Value != "jason" {
+		t.Fatalf("unexpected matches %+v", rule.Matches)
+	}
+	reviews, ratings := rule.BackendRefs[0], rule.BackendRefs[1]
+	if reviews.Name != "reviews" || reviews.Namespace != nil || *reviews.Port != 9080 || *reviews.Weight != 90 {
+		t.Fatalf("unexpected backend %+v", reviews)
+	}
+	if ratings.Name != "ratings" || *ratings.Port != 9080 || *ratings.Weight != 10 {
+		t.Fatalf("unexpected backend %+v", ratings)
+	}
+	if rule.Filters[0].RequestHeaderModifier.Set[0].Name != "x-canary" {
+		t.Fatalf("unexpected filters %+v", rule.Filters)
+	}
+	if redirect := route.Rules[1].Filters[0].RequestRedirect; *redirect.Hostname != "www.example.com" || *redirect.StatusCode != 301 {
+		t.Fatalf("unexpected redirect %+v", redirect)
+	}
+
+	if len(report.Resources) != 3 {
+		t.Fatalf("expected the mesh only VirtualService not to be reported, got %+v", report.Resources)
+	}
+	want := []string{"routes of the mesh gateway", "http route 0: subset v1 of reviews", "http route 0: timeout"}
+	if got := report.Resources[2].Unsupported; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got unsupported %v, want %v", got, want)
+	}
+}
+
+func TestConvertVirtualServiceOfUnconvertedGateway(t *testing.T) {
+	vs := virtualServiceConfig("bookinfo", &networking.VirtualService{
+		Hosts:    []string{"bookinfo.example.com"},
+		Gateways: []string{"istio-system/bookinfo"},
+		Tcp:      []*networking.TCPRoute{{Route: []*networking.RouteDestination{{Destination: &networking.Destination{Host: "reviews"}}}}},
+	})
+	out, report := Convert(nil, []config.Config{vs}, []*model.Service{reviewsService}, "cluster.local")
+	if len(out) != 0 || !reflect.DeepEqual(report.Resources[0].Unsupported, []string{"gateway istio-system/bookinfo is not converted"}) {
+		t.Fatalf("unexpected conversion %v, report %+v", out, report)
+	}
+}
+
+func TestConvertDestinations(t *testing.T) {
+	gateways := []config.Config{gatewayConfig("bookinfo", httpServer)}
+	vs := virtualServiceConfig("bookinfo", &networking.VirtualService{
+		Hosts:    []string{"bookinfo.example.com"},
+		Gateways: []string{"istio-system/bookinfo"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{
+				{Destination: &networking.Destination{Host: "example.com", Port: &networking.PortSelector{Number: 443}}, Weight: 50},
+				{Destination: &networking.Destination{Host: "ratings.bookinfo.svc.cluster.local", Port: &networking.PortSelector{Number: 9080}}, Weight: 50},
+			},
+		}},
+	})
+	out, report := Convert(gateways, []config.Config{vs}, []*model.Service{ingressService, reviewsService}, "cluster.local")
+	if len(out) != 3 {
+		t.Fatalf("expected a Gateway, an HTTPRoute and a ReferencePolicy, got %v, report %+v", out, report)
+	}
+	refs := out[1].Spec.(*k8s.HTTPRouteSpec).Rules[0].BackendRefs
+	if external := refs[0]; external.Name != "example.com" || *external.Kind != "Hostname" || external.Namespace != nil {
+		t.Fatalf("expected the dotted host to be a Hostname, got %+v", external)
+	}
+	if ratings := refs[1]; ratings.Name != "ratings" || ratings.Kind != nil || *ratings.Namespace != "bookinfo" {
+		t.Fatalf("expected the ratings Service of the bookinfo namespace, got %+v", ratings)
+	}
+
+	policy := out[2]
+	if policy.GroupVersionKind != gvk.ReferencePolicy || policy.Namespace != "bookinfo" || policy.Name != "default-bookinfo" {
+		t.Fatalf("unexpected ReferencePolicy %v", policy.Meta)
+	}
+	want := &k8s.ReferencePolicySpec{
+		From: []k8s.ReferencePolicyFrom{{Group: k8s.Group(gvk.HTTPRoute.Group), Kind: "HTTPRoute", Namespace: "default"}},
+		To:   []k8s.ReferencePolicyTo{{Kind: "Service"}},
+	}
+	if !reflect.DeepEqual(policy.Spec, want) {
+		t.Fatalf("got ReferencePolicy %+v, want %+v", policy.Spec, want)
+	}
+}
+
+func TestConvertVirtualServiceOfOtherNamespace(t *testing.T) {
+	gateways := []config.Config{gatewayConfig("bookinfo", &networking.Server{
+		Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+		Hosts: []string{"./bookinfo.example.com"},
+	})}
+	vs := virtualServiceConfig("bookinfo", &networking.VirtualService{
+		Hosts:    []string{"bookinfo.example.com"},
+		Gateways: []string{"istio-system/bookinfo"},
+		Tcp:      []*networking.TCPRoute{{Route: []*networking.RouteDestination{{Destination: &networking.Destination{Host: "reviews"}}}}},
+	})
+	_, report := Convert(gateways, []config.Config{vs}, []*model.Service{ingressService, reviewsService}, "cluster.local")
+	want := []string{"gateway istio-system/bookinfo does not allow routes from namespace default"}
+	if got := report.Resources[1].Unsupported; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got unsupported %v, want %v", got, want)
+	}
+}
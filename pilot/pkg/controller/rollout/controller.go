@@ -0,0 +1,657 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rollout implements staged rollouts of config changes: the changes of the VirtualServices and
+// DestinationRules opting in are first pushed to a percentage of the proxies, the canary proxies, and are then
+// either promoted to all proxies or rolled back depending on the errors the canary proxies report.
+//
+// The changes of the mesh-wide Telemetry configs, in the root namespace, can instead be rolled out in waves: they are
+// pushed to the proxies of the namespaces of each wave in turn, and the rollout halts as soon as the proxies reached
+// report more errors than the others. The mesh-wide PeerAuthentication configs are not rolled out in waves, as they
+// apply to both the clients and the servers of the mTLS connections, which may be in different waves.
+//
+// Each rollout is staged independently: a proxy is served the changes of the rollouts to the canary proxies if it is
+// part of the canary, and the changes of the wave rollouts which reached its namespace.
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+	istiolog "istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+const (
+	// StrategyAnnotation on a VirtualService or DestinationRule selects how its changes are rolled out to the proxies.
+	StrategyAnnotation = "rollout.istio.io/strategy"
+	// StagedStrategy rolls out the changes to the canary proxies first.
+	StagedStrategy = "staged"
+	// AllowMeshWideAnnotation on a VirtualService or DestinationRule set to "true" pushes its changes to all proxies
+	// right away, even if they apply to more than the maximum percentage of the proxies. On a mesh-wide Telemetry, it
+	// pushes its changes to all proxies rather than in waves.
+	AllowMeshWideAnnotation = "rollout.istio.io/allow-mesh-wide"
+)
+
+var log = istiolog.RegisterScope("rollout", "staged config rollout debugging", 0)
+
+var (
+	phaseTag = monitoring.MustCreateLabel("phase")
+
+	stagedRollouts = monitoring.NewSum(
+		"pilot_staged_rollouts_total",
+		"Total number of staged config rollouts, by the phase they reached.",
+		monitoring.WithLabels(phaseTag),
+	)
+
+	guardedChanges = monitoring.NewSum(
+		"pilot_staged_rollouts_guarded_total",
+		"Total number of config changes staged because they apply to more than the maximum percentage of proxies.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(stagedRollouts, guardedChanges)
+}
+
+// Phase is the phase of a staged rollout.
+type Phase string
+
+const (
+	// PhaseProgressing is the phase of rollouts whose changes are only pushed to the canary proxies.
+	PhaseProgressing Phase = "Progressing"
+	// PhasePromoted is the phase of rollouts whose changes are pushed to all proxies.
+	PhasePromoted Phase = "Promoted"
+	// PhaseRolledBack is the phase of rollouts whose changes were reverted on all proxies. The previous version of the
+	// config is pushed until the config is changed again.
+	PhaseRolledBack Phase = "RolledBack"
+	// PhaseHalted is the phase of wave rollouts stopped because the proxies reached reported errors. The changes stay
+	// pushed to the waves reached until the rollout is overridden or the config is changed again.
+	PhaseHalted Phase = "Halted"
+)
+
+// Wave is a step of the rollout of the changes of mesh-wide configs, pushing them to the proxies of more namespaces.
+type Wave struct {
+	Namespaces []string
+	// BakeTime is the time during which the changes are only pushed up to this wave, if no error was detected.
+	BakeTime time.Duration
+}
+
+// ParseWaves parses the JSON list of waves, such as
+// [{"namespaces":["canary"],"bakeTime":"10m"},{"namespaces":["team-a","team-b"]}]. The waves without bake time
+// use the default bake time.
+func ParseWaves(s string, defaultBakeTime time.Duration) ([]Wave, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var raw []struct {
+		Namespaces []string `json:"namespaces"`
+		BakeTime   string   `json:"bakeTime"`
+	}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, err
+	}
+	waves := make([]Wave, 0, len(raw))
+	for i, w := range raw {
+		if len(w.Namespaces) == 0 {
+			return nil, fmt.Errorf("wave %d has no namespace", i+1)
+		}
+		wave := Wave{Namespaces: w.Namespaces, BakeTime: defaultBakeTime}
+		if w.BakeTime != "" {
+			d, err := time.ParseDuration(w.BakeTime)
+			if err != nil {
+				return nil, fmt.Errorf("wave %d: invalid bake time: %v", i+1, err)
+			}
+			wave.BakeTime = d
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// Rollout is the staged rollout of a change of a config.
+type Rollout struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     Phase  `json:"phase"`
+	// ResourceVersion is the version of the config being rolled out.
+	ResourceVersion string `json:"resourceVersion"`
+	// StableResourceVersion is the version of the config pushed to the proxies which are not part of the canary.
+	// It is empty when the config is new.
+	StableResourceVersion string    `json:"stableResourceVersion,omitempty"`
+	Start                 time.Time `json:"start"`
+	End                   time.Time `json:"end,omitempty"`
+	// Reason explains why the rollout was promoted or rolled back.
+	Reason string `json:"reason,omitempty"`
+	// GuardReason is set when the change was staged because it applies to too many proxies, rather than because
+	// the config opted in.
+	GuardReason string `json:"guardReason,omitempty"`
+	// Wave is the number of waves the changes of a mesh-wide config are pushed to, or 0 for the rollouts to the
+	// canary percentage of the proxies.
+	Wave int `json:"wave,omitempty"`
+
+	// stable is the previous version of the config, or nil if the config is new.
+	stable *config.Config
+	// waveStart is the time the last wave was reached.
+	waveStart time.Time
+}
+
+// diverging returns whether the proxies reached by the rollout and the other proxies are served different versions
+// of the config.
+func (r *Rollout) diverging() bool {
+	return r.Phase == PhaseProgressing || r.Phase == PhaseHalted
+}
+
+// Controller tracks the staged rollouts of config changes. It provides the views of the configs for the canary
+// proxies and the other proxies, and evaluates the errors reported by the proxies to promote or roll back the changes.
+type Controller struct {
+	store model.ConfigStoreCache
+
+	// BlastRadius returns the number of connected proxies a config applies to, and the number of connected proxies.
+	// If set, the changes applying to more than maxProxyPercentage of the proxies are staged.
+	BlastRadius func(cfg config.Config) (affected, total int)
+	// RootNamespace returns the mesh root namespace, whose Telemetry configs are rolled out in waves.
+	RootNamespace func() string
+
+	waves              []Wave
+	percentage         uint32
+	bakeTime           time.Duration
+	maxErrorDelta      float64
+	maxProxyPercentage float64
+	now                func() time.Time
+
+	mu       sync.RWMutex
+	rollouts map[model.ConfigKey]*Rollout
+	// errors holds the last time each proxy reported an error, while rollouts are in progress.
+	errors map[string]time.Time
+}
+
+// NewController creates a controller tracking the staged rollouts of the VirtualServices and DestinationRules of
+// the store, and of the mesh-wide Telemetry configs if waves are configured.
+func NewController(store model.ConfigStoreCache) *Controller {
+	waves, err := ParseWaves(features.StagedRolloutWaves, features.StagedRolloutBakeTime)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_STAGED_ROLLOUT_WAVES: %v", err)
+	}
+	c := &Controller{
+		store:              store,
+		RootNamespace:      func() string { return constants.IstioSystemNamespace },
+		waves:              waves,
+		percentage:         uint32(features.StagedRolloutPercentage),
+		bakeTime:           features.StagedRolloutBakeTime,
+		maxErrorDelta:      features.StagedRolloutMaxErrorDelta,
+		maxProxyPercentage: features.StagedRolloutMaxProxyPercentage,
+		now:                time.Now,
+		rollouts:           map[model.ConfigKey]*Rollout{},
+		errors:             map[string]time.Time{},
+	}
+	store.RegisterEventHandler(gvk.VirtualService, c.configHandler)
+	store.RegisterEventHandler(gvk.DestinationRule, c.configHandler)
+	if len(c.waves) > 0 {
+		store.RegisterEventHandler(gvk.Telemetry, c.configHandler)
+	}
+	return c
+}
+
+// stagedKinds are the kinds of the configs whose changes may be staged.
+var stagedKinds = map[config.GroupVersionKind]bool{
+	gvk.VirtualService:  true,
+	gvk.DestinationRule: true,
+	gvk.Telemetry:       true,
+}
+
+// meshWide returns whether the config is a Telemetry of the root namespace, applying to all the proxies of the mesh.
+func (c *Controller) meshWide(cfg config.Config) bool {
+	return cfg.GroupVersionKind == gvk.Telemetry && cfg.Namespace == c.RootNamespace()
+}
+
+func (c *Controller) configHandler(prev config.Config, curr config.Config, event model.Event) {
+	// The configs existing at startup are already pushed to the proxies.
+	if !c.store.HasSynced() {
+		return
+	}
+	key := model.ConfigKey{Kind: curr.GroupVersionKind, Name: curr.Name, Namespace: curr.Namespace}
+	changed := event != model.EventUpdate || specChanged(prev, curr)
+	staged, waved, guardReason := false, false, ""
+	if curr.GroupVersionKind == gvk.VirtualService || curr.GroupVersionKind == gvk.DestinationRule {
+		staged = curr.Annotations[StrategyAnnotation] == StagedStrategy
+		if event != model.EventDelete && !staged && changed {
+			guardReason = c.exceedsBlastRadius(curr)
+		}
+	} else {
+		waved = len(c.waves) > 0 && c.meshWide(curr) && curr.Annotations[AllowMeshWideAnnotation] != "true"
+		staged = waved
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing := c.rollouts[key]
+	// The metadata changes of a config staged because it applies to too many proxies keep it staged, unless it is
+	// annotated to allow mesh-wide changes.
+	if !changed && existing != nil && existing.GuardReason != "" && curr.Annotations[AllowMeshWideAnnotation] != "true" {
+		guardReason = existing.GuardReason
+	}
+	// Deletions, and changes of configs neither opting in nor applying to too many proxies, are pushed to all
+	// proxies right away.
+	if event == model.EventDelete || (!staged && guardReason == "") {
+		delete(c.rollouts, key)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	r := &Rollout{
+		Kind:            curr.GroupVersionKind.Kind,
+		Namespace:       curr.Namespace,
+		Name:            curr.Name,
+		Phase:           PhaseProgressing,
+		ResourceVersion: curr.ResourceVersion,
+		Start:           c.now(),
+		GuardReason:     guardReason,
+	}
+	if waved {
+		r.Wave = 1
+		r.waveStart = r.Start
+	}
+	switch {
+	case existing != nil && existing.Phase != PhasePromoted:
+		// The change of a config which is not fully rolled out is compared to the last version pushed to all proxies.
+		r.stable = existing.stable
+	case event == model.EventUpdate:
+		stable := prev
+		r.stable = &stable
+	}
+	if r.stable != nil {
+		r.StableResourceVersion = r.stable.ResourceVersion
+	}
+	c.rollouts[key] = r
+	if guardReason != "" {
+		guardedChanges.Increment()
+		log.Warnf("staging the change of %s %s/%s version %s: %s; annotate it with %s=true to push it to all proxies",
+			r.Kind, r.Namespace, r.Name, r.ResourceVersion, guardReason, AllowMeshWideAnnotation)
+		return
+	}
+	if waved {
+		log.Infof("starting wave rollout of %s %s/%s version %s to namespaces %v", r.Kind, r.Namespace, r.Name,
+			r.ResourceVersion, c.waves[0].Namespaces)
+		return
+	}
+	log.Infof("starting staged rollout of %s %s/%s version %s", r.Kind, r.Namespace, r.Name, r.ResourceVersion)
+}
+
+// exceedsBlastRadius returns why the change of the config must be staged if it applies to more than the maximum
+// percentage of the connected proxies, or an empty string.
+func (c *Controller) exceedsBlastRadius(cfg config.Config) string {
+	if c.maxProxyPercentage <= 0 || c.BlastRadius == nil || cfg.Annotations[AllowMeshWideAnnotation] == "true" {
+		return ""
+	}
+	affected, total := c.BlastRadius(cfg)
+	if total == 0 {
+		return ""
+	}
+	if percentage := 100 * float64(affected) / float64(total); percentage <= c.maxProxyPercentage {
+		return ""
+	}
+	return fmt.Sprintf("applies to %d of %d proxies, above the maximum of %g%%", affected, total, c.maxProxyPercentage)
+}
+
+// specChanged returns whether the spec of the config changed, as metadata changes are not rolled out.
+func specChanged(prev config.Config, curr config.Config) bool {
+	prevSpec, okPrev := prev.Spec.(gogoproto.Message)
+	currSpec, okCurr := curr.Spec.(gogoproto.Message)
+	if okPrev && okCurr {
+		return !gogoproto.Equal(prevSpec, currSpec)
+	}
+	return true
+}
+
+// InCanary returns whether the proxy is part of the canary, which receives the staged changes first. The canary is
+// a stable selection of the configured percentage of the proxies.
+func (c *Controller) InCanary(proxyID string) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(proxyID))
+	return h.Sum32()%100 < c.percentage
+}
+
+// Audience identifies the staged changes served to a proxy: the changes of the rollouts to the canary proxies if the
+// proxy is part of the canary, and the changes of the wave rollouts which reached the first wave including the
+// namespace of the proxy.
+type Audience struct {
+	Canary bool
+	// Wave is the first wave including the namespace of the proxy, or 0 if no wave includes it.
+	Wave int
+}
+
+// String returns the key of the audience.
+func (a Audience) String() string {
+	return fmt.Sprintf("canary=%t,wave=%d", a.Canary, a.Wave)
+}
+
+// receives returns whether the audience is served the changes of the rollout.
+func (a Audience) receives(r *Rollout) bool {
+	if r.Wave == 0 {
+		return a.Canary
+	}
+	return a.Wave > 0 && a.Wave <= r.Wave
+}
+
+// AudienceOf returns the audience of the proxy of the ID and config namespace.
+func (c *Controller) AudienceOf(proxyID, namespace string) Audience {
+	a := Audience{Canary: c.InCanary(proxyID)}
+	for i, w := range c.waves {
+		for _, ns := range w.Namespaces {
+			if ns == namespace {
+				a.Wave = i + 1
+				return a
+			}
+		}
+	}
+	return a
+}
+
+// Audiences returns the audiences served a part of the staged changes of the rollouts in progress or halted, by the
+// key of the changes they are served. Each audience of a key is served the same changes, the audiences served all the
+// changes are omitted.
+func (c *Controller) Audiences() map[string][]Audience {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var diverging []string
+	for key, r := range c.rollouts {
+		if r.diverging() {
+			diverging = append(diverging, key.String())
+		}
+	}
+	if len(diverging) == 0 {
+		return nil
+	}
+	out := map[string][]Audience{}
+	for _, canary := range []bool{false, true} {
+		for wave := 0; wave <= len(c.waves); wave++ {
+			a := Audience{Canary: canary, Wave: wave}
+			served := []string{}
+			for key, r := range c.rollouts {
+				if r.diverging() && a.receives(r) {
+					served = append(served, key.String())
+				}
+			}
+			if len(served) == len(diverging) {
+				continue
+			}
+			sort.Strings(served)
+			key := strings.Join(served, ",")
+			out[key] = append(out[key], a)
+		}
+	}
+	return out
+}
+
+// InProgress returns whether any staged rollout is in progress or halted, that is whether the canary proxies and
+// the other proxies are served different configs.
+func (c *Controller) InProgress() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, r := range c.rollouts {
+		if r.diverging() {
+			return true
+		}
+	}
+	return false
+}
+
+// Diverging returns the configs whose rollout is in progress or halted, which the canary proxies and the other
+// proxies are served different versions of.
+func (c *Controller) Diverging() map[model.ConfigKey]struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out map[model.ConfigKey]struct{}
+	for key, r := range c.rollouts {
+		if r.diverging() {
+			if out == nil {
+				out = map[model.ConfigKey]struct{}{}
+			}
+			out[key] = struct{}{}
+		}
+	}
+	return out
+}
+
+// ReportError records an error reported by a proxy, such as a rejected config or an endpoint ejected for 5xx errors.
+func (c *Controller) ReportError(proxyID string) {
+	if !c.InProgress() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors[proxyID] = c.now()
+}
+
+// Evaluate compares the errors reported by the proxies served the changes of each rollout in progress and the other
+// connected proxies since its start, to roll it back if the former report more errors, or promote it at the end of
+// the bake time. Wave rollouts are instead halted if the proxies reached report more errors since the last wave, or
+// moved to the next wave at the end of its bake time. If appliesTo is set, only the proxies the config being rolled out
+// applies to are compared. It returns the configs whose rollout ended or reached a new wave, which must be pushed
+// again.
+func (c *Controller) Evaluate(proxies []*model.Proxy, appliesTo func(cfg config.Config, proxy *model.Proxy) bool) []model.ConfigKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	audiences := make([]Audience, len(proxies))
+	for i, proxy := range proxies {
+		audiences[i] = c.AudienceOf(proxy.ID, proxy.ConfigNamespace)
+	}
+	var ended []model.ConfigKey
+	var oldestStart time.Time
+	for key, r := range c.rollouts {
+		if r.Phase != PhaseProgressing {
+			continue
+		}
+		start, bakeTime := r.Start, c.bakeTime
+		if r.Wave > 0 {
+			start, bakeTime = r.waveStart, c.waves[r.Wave-1].BakeTime
+		}
+		cfg := c.store.Get(key.Kind, key.Name, key.Namespace)
+		if cfg == nil {
+			cfg = r.stable
+		}
+		var canary, canaryErrors, others, otherErrors int
+		for i, proxy := range proxies {
+			if appliesTo != nil && cfg != nil && !appliesTo(*cfg, proxy) {
+				continue
+			}
+			failed := c.errors[proxy.ID].After(start)
+			if audiences[i].receives(r) {
+				canary++
+				if failed {
+					canaryErrors++
+				}
+			} else {
+				others++
+				if failed {
+					otherErrors++
+				}
+			}
+		}
+		switch {
+		case canary > 0 && ratio(canaryErrors, canary)-ratio(otherErrors, others) > c.maxErrorDelta:
+			if r.Wave > 0 {
+				// The changes stay pushed to the waves reached, and are no longer pushed to more proxies.
+				r.Phase = PhaseHalted
+			} else {
+				r.Phase = PhaseRolledBack
+			}
+		case now.Sub(start) >= bakeTime && r.Wave > 0 && r.Wave < len(c.waves):
+			r.Wave++
+			r.waveStart = now
+			log.Infof("wave rollout of %s %s/%s version %s reached wave %d, namespaces %v", r.Kind, r.Namespace,
+				r.Name, r.ResourceVersion, r.Wave, c.waves[r.Wave-1].Namespaces)
+			ended = append(ended, key)
+			continue
+		case now.Sub(start) >= bakeTime:
+			r.Phase = PhasePromoted
+		default:
+			if oldestStart.IsZero() || start.Before(oldestStart) {
+				oldestStart = start
+			}
+			continue
+		}
+		r.End = now
+		r.Reason = fmt.Sprintf("%d of %d canary proxies reported errors, against %d of %d other proxies",
+			canaryErrors, canary, otherErrors, others)
+		stagedRollouts.With(phaseTag.Value(string(r.Phase))).Increment()
+		log.Infof("staged rollout of %s %s/%s version %s %s: %s", r.Kind, r.Namespace, r.Name, r.ResourceVersion,
+			r.Phase, r.Reason)
+		// The views of halted rollouts are unchanged, there is nothing to push.
+		if r.Phase == PhaseHalted {
+			continue
+		}
+		ended = append(ended, key)
+	}
+	// Errors reported before the start of the rollouts in progress are no longer relevant.
+	for id, t := range c.errors {
+		if oldestStart.IsZero() || t.Before(oldestStart) {
+			delete(c.errors, id)
+		}
+	}
+	return ended
+}
+
+// Override ends the rollout in progress or halted of the config right away, promoting it to all proxies or rolling
+// it back depending on the phase. The config must then be pushed again.
+func (c *Controller) Override(key model.ConfigKey, phase Phase) error {
+	if phase != PhasePromoted && phase != PhaseRolledBack {
+		return fmt.Errorf("invalid phase %q, must be %s or %s", phase, PhasePromoted, PhaseRolledBack)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.rollouts[key]
+	if r == nil || !r.diverging() {
+		return fmt.Errorf("no staged rollout of %s %s/%s in progress", key.Kind.Kind, key.Namespace, key.Name)
+	}
+	r.Phase = phase
+	r.End = c.now()
+	r.Reason = "overridden"
+	stagedRollouts.With(phaseTag.Value(string(r.Phase))).Increment()
+	log.Infof("staged rollout of %s %s/%s version %s %s: %s", r.Kind, r.Namespace, r.Name, r.ResourceVersion,
+		r.Phase, r.Reason)
+	return nil
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// Rollouts returns the staged rollouts, sorted by config.
+func (c *Controller) Rollouts() []Rollout {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Rollout, 0, len(c.rollouts))
+	for _, r := range c.rollouts {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// View returns the view of the configs of the store serving all the staged changes, or none of them if stable is
+// true. Both views serve the previous version of the configs whose rollout was rolled back.
+func (c *Controller) View(stable bool) model.ConfigStore {
+	if stable {
+		return c.AudienceView(Audience{})
+	}
+	return &view{ConfigStore: c.store, c: c}
+}
+
+// AudienceView returns the view of the configs of the store served to the audience, which serves the previous version
+// of the configs whose rollout is in progress or halted and does not reach the audience.
+func (c *Controller) AudienceView(a Audience) model.ConfigStore {
+	return &view{ConfigStore: c.store, c: c, audience: &a}
+}
+
+// resolve returns the version of the config served to the audience, or to all the proxies if nil, or false if the
+// config is hidden from the view.
+func (c *Controller) resolve(cfg config.Config, audience *Audience) (config.Config, bool) {
+	r := c.rollouts[model.ConfigKey{Kind: cfg.GroupVersionKind, Name: cfg.Name, Namespace: cfg.Namespace}]
+	if r == nil || r.Phase == PhasePromoted || (r.diverging() && (audience == nil || audience.receives(r))) {
+		return cfg, true
+	}
+	if r.stable == nil {
+		return config.Config{}, false
+	}
+	return *r.stable, true
+}
+
+// view is a config store serving the versions of the staged configs served to an audience, or to all the proxies.
+type view struct {
+	model.ConfigStore
+	c        *Controller
+	audience *Audience
+}
+
+func (v *view) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	cfg := v.ConfigStore.Get(typ, name, namespace)
+	if cfg == nil || !stagedKinds[typ] {
+		return cfg
+	}
+	v.c.mu.RLock()
+	defer v.c.mu.RUnlock()
+	resolved, ok := v.c.resolve(*cfg, v.audience)
+	if !ok {
+		return nil
+	}
+	return &resolved
+}
+
+func (v *view) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	configs, err := v.ConfigStore.List(typ, namespace)
+	if err != nil || !stagedKinds[typ] {
+		return configs, err
+	}
+	v.c.mu.RLock()
+	defer v.c.mu.RUnlock()
+	if len(v.c.rollouts) == 0 {
+		return configs, nil
+	}
+	out := make([]config.Config, 0, len(configs))
+	for _, cfg := range configs {
+		if resolved, ok := v.c.resolve(cfg, v.audience); ok {
+			out = append(out, resolved)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,525 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollout
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	telemetry "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func virtualService(name string, staged bool, timeout int64) config.Config {
+	annotations := map[string]string{}
+	if staged {
+		annotations[StrategyAnnotation] = StagedStrategy
+	}
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.VirtualService,
+			Name:             name,
+			Namespace:        "default",
+			Annotations:      annotations,
+		},
+		Spec: &networking.VirtualService{
+			Hosts: []string{name},
+			Http: []*networking.HTTPRoute{{
+				Route:   []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: name}}},
+				Timeout: &types.Duration{Seconds: timeout},
+			}},
+		},
+	}
+}
+
+func telemetryConfig(namespace string, sampling float64) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.Telemetry,
+			Name:             "default",
+			Namespace:        namespace,
+		},
+		Spec: &telemetry.Telemetry{
+			Tracing: []*telemetry.Tracing{{RandomSamplingPercentage: &types.DoubleValue{Value: sampling}}},
+		},
+	}
+}
+
+func samplingOf(cfg *config.Config) float64 {
+	return cfg.Spec.(*telemetry.Telemetry).Tracing[0].RandomSamplingPercentage.Value
+}
+
+func timeoutOf(cfg *config.Config) int64 {
+	if cfg == nil {
+		return -1
+	}
+	return cfg.Spec.(*networking.VirtualService).Http[0].Timeout.Seconds
+}
+
+// newTestController creates a controller whose store holds the configs at startup.
+func newTestController(t *testing.T, configs ...config.Config) (*Controller, model.ConfigStoreCache, *time.Time) {
+	cs := memory.Make(collections.Pilot)
+	for _, cfg := range configs {
+		if _, err := cs.Create(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := memory.NewSyncController(cs)
+	c := NewController(store)
+	c.percentage = 50
+	c.bakeTime = time.Minute
+	c.maxErrorDelta = 0.1
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+	return c, store, &now
+}
+
+// update updates the config in the store, on top of its current version.
+func update(t *testing.T, store model.ConfigStore, cfg config.Config) (string, error) {
+	t.Helper()
+	cfg.ResourceVersion = store.Get(cfg.GroupVersionKind, cfg.Name, cfg.Namespace).ResourceVersion
+	return store.Update(cfg)
+}
+
+// proxies returns the IDs of n canary proxies and n other proxies of the namespace.
+func proxies(c *Controller, n int, namespace string) (canary []string, others []string) {
+	for i := 0; len(canary) < n || len(others) < n; i++ {
+		id := fmt.Sprintf("proxy-%d.%s", i, namespace)
+		if c.InCanary(id) {
+			if len(canary) < n {
+				canary = append(canary, id)
+			}
+		} else if len(others) < n {
+			others = append(others, id)
+		}
+	}
+	return canary, others
+}
+
+// toProxies returns the proxies of the IDs of the form <pod>.<namespace>.
+func toProxies(ids []string) []*model.Proxy {
+	out := make([]*model.Proxy, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, &model.Proxy{ID: id, ConfigNamespace: id[strings.LastIndex(id, ".")+1:]})
+	}
+	return out
+}
+
+// receives returns whether the proxy of the ID of the form <pod>.<namespace> is served the changes of the config.
+func receives(c *Controller, proxyID string, key model.ConfigKey) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r := c.rollouts[key]
+	return r != nil && r.diverging() && c.AudienceOf(proxyID, proxyID[strings.LastIndex(proxyID, ".")+1:]).receives(r)
+}
+
+func TestStagedRollout(t *testing.T) {
+	c, store, now := newTestController(t, virtualService("reviews", true, 1), virtualService("ratings", false, 1))
+	if c.InProgress() {
+		t.Fatalf("expected the configs existing at startup not to be staged")
+	}
+	initialVersion := store.Get(gvk.VirtualService, "reviews", "default").ResourceVersion
+	// Configs created after the store synced are only served to the canary proxies.
+	if _, err := store.Create(virtualService("details", true, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if !c.InProgress() {
+		t.Fatalf("expected the creation of details to be staged")
+	}
+	if got := c.View(true).Get(gvk.VirtualService, "details", "default"); got != nil {
+		t.Fatalf("expected details to be hidden from the stable view, got %v", got)
+	}
+	if _, err := update(t, store, virtualService("reviews", true, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := update(t, store, virtualService("ratings", false, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	canary, stable := c.View(false), c.View(true)
+	for _, tt := range []struct {
+		name           string
+		canary, stable int64
+	}{
+		{name: "reviews", canary: 2, stable: 1},
+		{name: "ratings", canary: 2, stable: 2},
+		{name: "details", canary: 1, stable: -1},
+	} {
+		if got := timeoutOf(canary.Get(gvk.VirtualService, tt.name, "default")); got != tt.canary {
+			t.Errorf("%s: got timeout %d in the canary view, want %d", tt.name, got, tt.canary)
+		}
+		if got := timeoutOf(stable.Get(gvk.VirtualService, tt.name, "default")); got != tt.stable {
+			t.Errorf("%s: got timeout %d in the stable view, want %d", tt.name, got, tt.stable)
+		}
+	}
+	configs, err := stable.List(gvk.VirtualService, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 configs in the stable view, got %v", configs)
+	}
+
+	canaryProxies, otherProxies := proxies(c, 10, "default")
+	all := append(append([]string{}, canaryProxies...), otherProxies...)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 0 {
+		t.Fatalf("unexpected end of rollouts %v", ended)
+	}
+
+	// The errors of other proxies do not roll back the changes.
+	*now = now.Add(time.Second)
+	c.ReportError(canaryProxies[0])
+	c.ReportError(otherProxies[0])
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 0 {
+		t.Fatalf("unexpected end of rollouts %v", ended)
+	}
+
+	c.ReportError(canaryProxies[1])
+	c.ReportError(canaryProxies[2])
+	ended := c.Evaluate(toProxies(all), nil)
+	if len(ended) != 2 {
+		t.Fatalf("expected both rollouts to end, got %v", ended)
+	}
+	for _, r := range c.Rollouts() {
+		if r.Phase != PhaseRolledBack {
+			t.Fatalf("expected rollout to be rolled back, got %+v", r)
+		}
+	}
+	if c.InProgress() {
+		t.Fatalf("expected no rollout in progress")
+	}
+	// The previous versions are served to all proxies after a roll back.
+	if got := timeoutOf(canary.Get(gvk.VirtualService, "reviews", "default")); got != 1 {
+		t.Fatalf("got timeout %d in the canary view after roll back, want 1", got)
+	}
+	if got := canary.Get(gvk.VirtualService, "details", "default"); got != nil {
+		t.Fatalf("expected details to be hidden after roll back, got %v", got)
+	}
+
+	// A new change is compared to the last version served to all proxies, and promoted after the bake time.
+	if _, err := update(t, store, virtualService("reviews", true, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Rollouts()[1]; got.Name != "reviews" || got.StableResourceVersion != initialVersion {
+		t.Fatalf("unexpected rollout %+v", got)
+	}
+	*now = now.Add(time.Minute)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 1 || ended[0].Name != "reviews" {
+		t.Fatalf("expected reviews to be promoted, got %v", ended)
+	}
+	if got := timeoutOf(stable.Get(gvk.VirtualService, "reviews", "default")); got != 3 {
+		t.Fatalf("got timeout %d in the stable view after promotion, want 3", got)
+	}
+
+	// Removing the annotation or deleting the config stops tracking it.
+	if _, err := update(t, store, virtualService("details", false, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := timeoutOf(stable.Get(gvk.VirtualService, "details", "default")); got != 1 {
+		t.Fatalf("got timeout %d for details without annotation, want 1", got)
+	}
+	if err := store.Delete(gvk.VirtualService, "reviews", "default", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Rollouts(); len(got) != 0 {
+		t.Fatalf("expected no rollout, got %v", got)
+	}
+}
+
+func TestStagedRolloutMetadataChange(t *testing.T) {
+	c, store, _ := newTestController(t, virtualService("reviews", true, 1))
+	updated := virtualService("reviews", true, 1)
+	updated.Labels = map[string]string{"app": "reviews"}
+	if _, err := update(t, store, updated); err != nil {
+		t.Fatal(err)
+	}
+	if c.InProgress() {
+		t.Fatalf("expected metadata changes not to be staged")
+	}
+}
+
+func TestGuardedRollout(t *testing.T) {
+	c, store, _ := newTestController(t, virtualService("reviews", false, 1), virtualService("ratings", false, 1))
+	c.maxProxyPercentage = 50
+	c.BlastRadius = func(cfg config.Config) (int, int) {
+		if cfg.Name == "reviews" {
+			return 8, 10
+		}
+		return 5, 10
+	}
+
+	if _, err := update(t, store, virtualService("ratings", false, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if c.InProgress() {
+		t.Fatalf("expected the change applying to half of the proxies not to be staged")
+	}
+	if _, err := update(t, store, virtualService("reviews", false, 2)); err != nil {
+		t.Fatal(err)
+	}
+	rollouts := c.Rollouts()
+	if len(rollouts) != 1 || rollouts[0].Name != "reviews" || rollouts[0].GuardReason == "" {
+		t.Fatalf("expected the change applying to most proxies to be staged, got %+v", rollouts)
+	}
+	if got := timeoutOf(c.View(true).Get(gvk.VirtualService, "reviews", "default")); got != 1 {
+		t.Fatalf("got timeout %d in the stable view, want 1", got)
+	}
+
+	// Metadata changes keep the change staged, unless mesh-wide changes are allowed.
+	updated := virtualService("reviews", false, 2)
+	updated.Labels = map[string]string{"app": "reviews"}
+	if _, err := update(t, store, updated); err != nil {
+		t.Fatal(err)
+	}
+	if !c.InProgress() {
+		t.Fatalf("expected the metadata change to keep the change staged")
+	}
+	updated.Annotations[AllowMeshWideAnnotation] = "true"
+	if _, err := update(t, store, updated); err != nil {
+		t.Fatal(err)
+	}
+	if c.InProgress() {
+		t.Fatalf("expected the change allowed mesh-wide to be pushed to all proxies")
+	}
+}
+
+func TestOverride(t *testing.T) {
+	c, store, _ := newTestController(t, virtualService("reviews", true, 1))
+	key := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	if err := c.Override(key, PhasePromoted); err == nil {
+		t.Fatalf("expected an error without rollout in progress")
+	}
+	if _, err := update(t, store, virtualService("reviews", true, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Override(key, PhaseProgressing); err == nil {
+		t.Fatalf("expected an error for an invalid phase")
+	}
+	if err := c.Override(key, PhasePromoted); err != nil {
+		t.Fatal(err)
+	}
+	if got := timeoutOf(c.View(true).Get(gvk.VirtualService, "reviews", "default")); got != 2 {
+		t.Fatalf("got timeout %d in the stable view after promotion, want 2", got)
+	}
+	if r := c.Rollouts()[0]; r.Phase != PhasePromoted || r.Reason != "overridden" {
+		t.Fatalf("unexpected rollout %+v", r)
+	}
+}
+
+func TestParseWaves(t *testing.T) {
+	waves, err := ParseWaves(`[{"namespaces":["canary"],"bakeTime":"10m"},{"namespaces":["a","b"]}]`, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waves) != 2 || waves[0].BakeTime != 10*time.Minute || waves[1].BakeTime != time.Minute ||
+		len(waves[1].Namespaces) != 2 {
+		t.Fatalf("unexpected waves %+v", waves)
+	}
+	for _, invalid := range []string{`{}`, `[{"namespaces":[]}]`, `[{"namespaces":["a"],"bakeTime":"soon"}]`} {
+		if _, err := ParseWaves(invalid, time.Minute); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
+
+// newWaveTestController creates a controller rolling out the mesh-wide configs to the canary namespace, then to the
+// team-a namespace.
+func newWaveTestController(t *testing.T) (*Controller, model.ConfigStoreCache, *time.Time) {
+	c, store, now := newTestController(t, telemetryConfig("istio-system", 1))
+	c.waves = []Wave{
+		{Namespaces: []string{"canary"}, BakeTime: time.Minute},
+		{Namespaces: []string{"team-a"}, BakeTime: 2 * time.Minute},
+	}
+	store.RegisterEventHandler(gvk.Telemetry, c.configHandler)
+	return c, store, now
+}
+
+var meshTelemetry = model.ConfigKey{Kind: gvk.Telemetry, Name: "default", Namespace: "istio-system"}
+
+func TestWaveRollout(t *testing.T) {
+	c, store, now := newWaveTestController(t)
+	// Namespace configs are not mesh-wide.
+	if _, err := store.Create(telemetryConfig("team-a", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if c.InProgress() {
+		t.Fatalf("expected the namespace config not to be staged")
+	}
+	if _, err := update(t, store, telemetryConfig("istio-system", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Rollouts(); len(r) != 1 || r[0].Wave != 1 || r[0].Phase != PhaseProgressing {
+		t.Fatalf("expected the mesh-wide change to be rolled out in waves, got %+v", r)
+	}
+	if got := samplingOf(c.View(true).Get(gvk.Telemetry, "default", "istio-system")); got != 1 {
+		t.Fatalf("got sampling %v in the stable view, want 1", got)
+	}
+
+	all := []string{"a.canary", "b.canary", "c.team-a", "d.team-a", "e.team-b", "f.team-b"}
+	for _, tt := range []struct {
+		proxy string
+		want  bool
+	}{
+		{proxy: "a.canary", want: true},
+		{proxy: "c.team-a", want: false},
+		{proxy: "e.team-b", want: false},
+	} {
+		if got := receives(c, tt.proxy, meshTelemetry); got != tt.want {
+			t.Errorf("%s: got %v receiving the changes of the first wave, want %v", tt.proxy, got, tt.want)
+		}
+	}
+
+	// At the end of the bake time of the first wave, the changes reach the next wave.
+	*now = now.Add(time.Minute)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 1 {
+		t.Fatalf("expected the rollout to reach the next wave, got %v", ended)
+	}
+	if r := c.Rollouts()[0]; r.Wave != 2 || r.Phase != PhaseProgressing {
+		t.Fatalf("unexpected rollout %+v", r)
+	}
+	if !receives(c, "c.team-a", meshTelemetry) || receives(c, "e.team-b", meshTelemetry) {
+		t.Fatalf("expected the second wave to only reach team-a")
+	}
+	*now = now.Add(time.Minute)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 0 {
+		t.Fatalf("unexpected end of the bake time of the second wave %v", ended)
+	}
+
+	// Errors of the proxies reached halt the rollout, which stays pushed to the waves reached.
+	c.ReportError("c.team-a")
+	c.ReportError("d.team-a")
+	*now = now.Add(time.Second)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 0 {
+		t.Fatalf("expected nothing to push after halting, got %v", ended)
+	}
+	if r := c.Rollouts()[0]; r.Wave != 2 || r.Phase != PhaseHalted {
+		t.Fatalf("expected the rollout to halt, got %+v", r)
+	}
+	*now = now.Add(time.Hour)
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 0 || !c.InProgress() || !receives(c, "c.team-a", meshTelemetry) {
+		t.Fatalf("expected the halted rollout to stay pushed to the waves reached")
+	}
+	if err := c.Override(meshTelemetry, PhaseRolledBack); err != nil {
+		t.Fatal(err)
+	}
+	if got := samplingOf(c.View(false).Get(gvk.Telemetry, "default", "istio-system")); got != 1 {
+		t.Fatalf("got sampling %v in the canary view after roll back, want 1", got)
+	}
+}
+
+func TestWaveRolloutPromotion(t *testing.T) {
+	c, store, now := newWaveTestController(t)
+	if _, err := update(t, store, telemetryConfig("istio-system", 2)); err != nil {
+		t.Fatal(err)
+	}
+	// Each rollout is staged independently: a percentage rollout in progress does not restrict the wave to its canary
+	// proxies, nor the wave the percentage rollout to the first wave.
+	if _, err := store.Create(virtualService("reviews", true, 1)); err != nil {
+		t.Fatal(err)
+	}
+	reviews := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	canary, others := proxies(c, 1, "canary")
+	canaryTeamB, othersTeamB := proxies(c, 1, "team-b")
+	for _, tt := range []struct {
+		proxy             string
+		telemetry, routes bool
+	}{
+		{proxy: canary[0], telemetry: true, routes: true},
+		{proxy: others[0], telemetry: true, routes: false},
+		{proxy: canaryTeamB[0], telemetry: false, routes: true},
+		{proxy: othersTeamB[0], telemetry: false, routes: false},
+	} {
+		if got := receives(c, tt.proxy, meshTelemetry); got != tt.telemetry {
+			t.Errorf("%s: got %v receiving the telemetry change, want %v", tt.proxy, got, tt.telemetry)
+		}
+		if got := receives(c, tt.proxy, reviews); got != tt.routes {
+			t.Errorf("%s: got %v receiving the route change, want %v", tt.proxy, got, tt.routes)
+		}
+	}
+	// The audiences served only a part of the changes are grouped by the changes they are served.
+	if got := c.Audiences(); len(got) != 3 || len(got[meshTelemetry.String()]) != 1 || len(got[reviews.String()]) != 2 {
+		t.Fatalf("unexpected audiences %v", got)
+	}
+	if got := timeoutOf(c.AudienceView(c.AudienceOf(others[0], "canary")).Get(gvk.VirtualService, "reviews", "default")); got != -1 {
+		t.Fatalf("expected reviews to be hidden from the proxies of the first wave outside of the canary, got %d", got)
+	}
+
+	all := []string{"a.canary", "c.team-a", "e.team-b"}
+	*now = now.Add(time.Minute)
+	c.Evaluate(toProxies(all), nil)
+	*now = now.Add(2 * time.Minute)
+	c.Evaluate(toProxies(all), nil)
+	var promoted *Rollout
+	for _, r := range c.Rollouts() {
+		if r.Kind == gvk.Telemetry.Kind {
+			r := r
+			promoted = &r
+		}
+	}
+	if promoted == nil || promoted.Phase != PhasePromoted || promoted.Wave != 2 {
+		t.Fatalf("expected the rollout to be promoted after the last wave, got %+v", promoted)
+	}
+	if got := samplingOf(c.View(true).Get(gvk.Telemetry, "default", "istio-system")); got != 2 {
+		t.Fatalf("got sampling %v in the stable view after promotion, want 2", got)
+	}
+
+	// Changes allowed mesh-wide are pushed to all proxies right away.
+	allowed := telemetryConfig("istio-system", 3)
+	allowed.Annotations = map[string]string{AllowMeshWideAnnotation: "true"}
+	if _, err := update(t, store, allowed); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range c.Rollouts() {
+		if r.Kind == gvk.Telemetry.Kind && r.Phase != PhasePromoted {
+			t.Fatalf("expected the change allowed mesh-wide not to be staged, got %+v", r)
+		}
+	}
+}
+
+func TestStagedRolloutErrorsOfOtherProxies(t *testing.T) {
+	c, store, now := newTestController(t)
+	if _, err := store.Create(virtualService("details", true, 1)); err != nil {
+		t.Fatal(err)
+	}
+	canaryProxies, otherProxies := proxies(c, 10, "default")
+	all := append(append([]string{}, canaryProxies...), otherProxies...)
+	*now = now.Add(time.Second)
+	for _, id := range canaryProxies {
+		c.ReportError(id)
+	}
+	// The errors of the proxies the config does not apply to are not attributed to its rollout.
+	unaffected := map[string]bool{}
+	for _, id := range canaryProxies {
+		unaffected[id] = true
+	}
+	appliesTo := func(cfg config.Config, proxy *model.Proxy) bool {
+		return !unaffected[proxy.ID]
+	}
+	if ended := c.Evaluate(toProxies(all), appliesTo); len(ended) != 0 || !c.InProgress() {
+		t.Fatalf("unexpected end of rollouts %v", ended)
+	}
+	if ended := c.Evaluate(toProxies(all), nil); len(ended) != 1 || c.Rollouts()[0].Phase != PhaseRolledBack {
+		t.Fatalf("expected details to be rolled back, got %v", c.Rollouts())
+	}
+}
@@ -139,6 +139,22 @@ func (a *AggregateController) GetCaCert(name, namespace string) (cert []byte, er
 	return nil, firstError
 }
 
+func (a *AggregateController) GetBasicAuth(name, namespace string) (username []byte, password []byte, err error) {
+	// Search through all clusters, find first non-empty result
+	var firstError error
+	for _, c := range a.controllers {
+		u, p, err := c.GetBasicAuth(name, namespace)
+		if err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+		} else {
+			return u, p, nil
+		}
+	}
+	return nil, nil, firstError
+}
+
 func (a *AggregateController) Authorize(serviceAccount, namespace string) error {
 	return a.authController.Authorize(serviceAccount, namespace)
 }
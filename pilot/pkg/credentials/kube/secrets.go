@@ -218,6 +218,15 @@ func (s *CredentialsController) GetCaCert(name, namespace string) (cert []byte,
 	return extractRoot(k8sSecret)
 }
 
+func (s *CredentialsController) GetBasicAuth(name, namespace string) (username []byte, password []byte, err error) {
+	k8sSecret, err := s.secretLister.Secrets(namespace).Get(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("secret %v/%v not found", namespace, name)
+	}
+
+	return extractBasicAuth(k8sSecret)
+}
+
 func hasKeys(d map[string][]byte, keys ...string) bool {
 	for _, k := range keys {
 		_, f := d[k]
@@ -290,6 +299,19 @@ func extractRoot(scrt *v1.Secret) (cert []byte, err error) {
 		GenericScrtCaCert, TLSSecretCaCert, found)
 }
 
+// extractBasicAuth extracts the username and password of a basic authentication secret
+func extractBasicAuth(scrt *v1.Secret) (username, password []byte, err error) {
+	if hasValue(scrt.Data, v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey) {
+		return scrt.Data[v1.BasicAuthUsernameKey], scrt.Data[v1.BasicAuthPasswordKey], nil
+	}
+	if hasKeys(scrt.Data, v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey) {
+		return nil, nil, fmt.Errorf("found keys %q and %q, but they were empty", v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey)
+	}
+	found := truncatedKeysMessage(scrt.Data)
+	return nil, nil, fmt.Errorf("found secret, but didn't have expected keys %s and %s; found: %s",
+		v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey, found)
+}
+
 func (s *CredentialsController) AddEventHandler(h func(name string, namespace string)) {
 	// register handler before informer starts
 	s.secretInformer.AddEventHandler(controllers.ObjectHandler(func(o controllers.Object) {
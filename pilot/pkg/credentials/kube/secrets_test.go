@@ -218,6 +218,68 @@ func TestSecretsController(t *testing.T) {
 	}
 }
 
+func TestSecretsControllerBasicAuth(t *testing.T) {
+	secrets := []runtime.Object{
+		makeSecret("basic-auth", map[string]string{
+			corev1.BasicAuthUsernameKey: "user", corev1.BasicAuthPasswordKey: "pass",
+		}),
+		makeSecret("empty-password", map[string]string{
+			corev1.BasicAuthUsernameKey: "user", corev1.BasicAuthPasswordKey: "",
+		}),
+		tlsCert,
+	}
+	client := kube.NewFakeClient(secrets...)
+	sc := NewCredentialsController(client, "")
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	client.RunAndWait(stop)
+	cases := []struct {
+		name          string
+		namespace     string
+		username      string
+		password      string
+		expectedError string
+	}{
+		{
+			name:      "basic-auth",
+			namespace: "default",
+			username:  "user",
+			password:  "pass",
+		},
+		{
+			name:          "empty-password",
+			namespace:     "default",
+			expectedError: `found keys "username" and "password", but they were empty`,
+		},
+		{
+			name:          "tls",
+			namespace:     "default",
+			expectedError: "found secret, but didn't have expected keys username and password; found: tls.crt, tls.key",
+		},
+		{
+			name:          "basic-auth",
+			namespace:     "wrong-namespace",
+			expectedError: `secret wrong-namespace/basic-auth not found`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			username, password, err := sc.GetBasicAuth(tt.name, tt.namespace)
+			if tt.username != string(username) {
+				t.Errorf("got username %q, wanted %q", string(username), tt.username)
+			}
+			if tt.password != string(password) {
+				t.Errorf("got password %q, wanted %q", string(password), tt.password)
+			}
+			if tt.expectedError != errString(err) {
+				t.Errorf("got err %q, wanted %q", errString(err), tt.expectedError)
+			}
+		})
+	}
+}
+
 func errString(e error) string {
 	if e == nil {
 		return ""
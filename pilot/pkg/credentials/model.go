@@ -19,6 +19,7 @@ import "istio.io/istio/pkg/cluster"
 type Controller interface {
 	GetKeyAndCert(name, namespace string) (key []byte, cert []byte, err error)
 	GetCaCert(name, namespace string) (cert []byte, err error)
+	GetBasicAuth(name, namespace string) (username []byte, password []byte, err error)
 	Authorize(serviceAccount, namespace string) error
 	AddEventHandler(func(name, namespace string))
 }
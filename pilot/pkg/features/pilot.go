@@ -42,7 +42,7 @@ var (
 	).Get()
 
 	traceSamplingVar = env.RegisterFloatVar(
-		"PILOT_TRACE_SAMPLING",
+		generation("PILOT_TRACE_SAMPLING"),
 		1.0,
 		"Sets the mesh-wide trace sampling percentage. Should be 0.0 - 100.0. Precision to 0.01. "+
 			"Default is 1.0.",
@@ -61,7 +61,7 @@ var (
 	// in trace spans. This is a temporary flag for controlling the feature that will be replaced by
 	// Telemetry API (or accepted as an always-on feature).
 	EnableIstioTags = env.RegisterBoolVar(
-		"PILOT_ENABLE_ISTIO_TAGS",
+		generation("PILOT_ENABLE_ISTIO_TAGS"),
 		true,
 		"Determines whether or not trace spans generated by Envoy will include Istio-specific tags.",
 	).Get()
@@ -72,16 +72,45 @@ var (
 		"Limits the number of concurrent pushes allowed. On larger machines this can be increased for faster pushes",
 	).Get()
 
+	FullPushThrottle = env.RegisterIntVar(
+		"PILOT_FULL_PUSH_THROTTLE",
+		0,
+		"Limits the number of concurrent full pushes, keeping the remaining of PILOT_PUSH_THROTTLE available to "+
+			"incremental pushes such as endpoint updates. If 0, full pushes are only limited by PILOT_PUSH_THROTTLE.",
+	).Get()
+
+	FullPushMinInterval = env.RegisterDurationVar(
+		"PILOT_FULL_PUSH_MIN_INTERVAL",
+		0,
+		"Minimum interval between two full pushes to the same proxy. Full pushes requested in between are merged "+
+			"and sent once the interval has elapsed, while incremental pushes are not delayed. If 0, full pushes are not rate limited.",
+	).Get()
+
 	RequestLimit = env.RegisterFloatVar(
 		"PILOT_MAX_REQUESTS_PER_SECOND",
 		25.0,
 		"Limits the number of incoming XDS requests per second. On larger machines this can be increased to handle more proxies concurrently.",
 	).Get()
 
+	MaxConnections = env.RegisterIntVar(
+		"PILOT_MAX_CONNECTIONS",
+		0,
+		"Limits the number of XDS connections of this instance. New connections above the limit are rejected, so that "+
+			"proxies connect to another instance instead. If 0, connections are not limited.",
+	).Get()
+
+	RequestPushThrottle = env.RegisterIntVar(
+		"PILOT_REQUEST_PUSH_THROTTLE",
+		0,
+		"Limits the number of concurrent responses to XDS requests, such as the initial requests of the proxies "+
+			"connecting after a restart of this instance. Requests above the limit wait for a response to complete. "+
+			"If 0, responses to requests are not limited.",
+	).Get()
+
 	// FilterGatewayClusterConfig controls if a subset of clusters(only those required) should be pushed to gateways
 	// TODO enable by default once https://github.com/istio/istio/issues/28315 is resolved
 	// Currently this may cause a bug when we go from N clusters -> 0 clusters -> N clusters
-	FilterGatewayClusterConfig = env.RegisterBoolVar("PILOT_FILTER_GATEWAY_CLUSTER_CONFIG", false, "").Get()
+	FilterGatewayClusterConfig = env.RegisterBoolVar(generation("PILOT_FILTER_GATEWAY_CLUSTER_CONFIG"), false, "").Get()
 
 	DebounceAfter = env.RegisterDurationVar(
 		"PILOT_DEBOUNCE_AFTER",
@@ -98,6 +127,34 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	DebounceAfterByKind = func() map[string]time.Duration {
+		v := env.RegisterStringVar(
+			"PILOT_DEBOUNCE_AFTER_BY_KIND",
+			"",
+			"Comma separated list of kind=duration pairs overriding PILOT_DEBOUNCE_AFTER for the updates of configs of "+
+				"the given kinds, for example `ServiceEntry=10ms,VirtualService=1s`. Updates with different delays are "+
+				"debounced separately, so fast changing kinds are not delayed by the others. Endpoint updates use the ServiceEntry kind.",
+		).Get()
+		if v == "" {
+			return nil
+		}
+		res := map[string]time.Duration{}
+		for _, kv := range strings.Split(v, ",") {
+			parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				log.Warnf("invalid PILOT_DEBOUNCE_AFTER_BY_KIND entry %q, ignoring", kv)
+				continue
+			}
+			d, err := time.ParseDuration(parts[1])
+			if err != nil || d < 0 {
+				log.Warnf("invalid PILOT_DEBOUNCE_AFTER_BY_KIND entry %q, ignoring", kv)
+				continue
+			}
+			res[parts[0]] = d
+		}
+		return res
+	}()
+
 	EnableEDSDebounce = env.RegisterBoolVar(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -106,14 +163,21 @@ var (
 	).Get()
 
 	SendUnhealthyEndpoints = env.RegisterBoolVar(
-		"PILOT_SEND_UNHEALTHY_ENDPOINTS",
+		generation("PILOT_SEND_UNHEALTHY_ENDPOINTS"),
 		true,
 		"If enabled, Pilot will include unhealthy endpoints in EDS pushes and even if they are sent Envoy does not use them for load balancing.",
 	).Get()
 
+	SendTerminatingServingEndpoints = env.RegisterBoolVar(
+		generation("PILOT_SEND_TERMINATING_SERVING_ENDPOINTS"),
+		true,
+		"If enabled, the EndpointSlice endpoints of terminating pods that are still serving are sent as degraded in EDS, "+
+			"instead of unhealthy. Envoy only sends them traffic when there are not enough healthy endpoints, as kube-proxy does.",
+	).Get()
+
 	// HTTP10 will add "accept_http_10" to http outbound listeners. Can also be set only for specific sidecars via meta.
 	HTTP10 = env.RegisterBoolVar(
-		"PILOT_HTTP10",
+		generation("PILOT_HTTP10"),
 		false,
 		"Enables the use of HTTP 1.0 in the outbound HTTP listeners, to support legacy applications.",
 	).Get()
@@ -121,7 +185,7 @@ var (
 	// EnableMysqlFilter enables injection of `envoy.filters.network.mysql_proxy` in the filter chain.
 	// Pilot injects this outbound filter if the service port name is `mysql`.
 	EnableMysqlFilter = env.RegisterBoolVar(
-		"PILOT_ENABLE_MYSQL_FILTER",
+		generation("PILOT_ENABLE_MYSQL_FILTER"),
 		false,
 		"EnableMysqlFilter enables injection of `envoy.filters.network.mysql_proxy` in the filter chain.",
 	).Get()
@@ -129,14 +193,14 @@ var (
 	// EnableRedisFilter enables injection of `envoy.filters.network.redis_proxy` in the filter chain.
 	// Pilot injects this outbound filter if the service port name is `redis`.
 	EnableRedisFilter = env.RegisterBoolVar(
-		"PILOT_ENABLE_REDIS_FILTER",
+		generation("PILOT_ENABLE_REDIS_FILTER"),
 		false,
 		"EnableRedisFilter enables injection of `envoy.filters.network.redis_proxy` in the filter chain.",
 	).Get()
 
 	// EnableMongoFilter enables injection of `envoy.filters.network.mongo_proxy` in the filter chain.
 	EnableMongoFilter = env.RegisterBoolVar(
-		"PILOT_ENABLE_MONGO_FILTER",
+		generation("PILOT_ENABLE_MONGO_FILTER"),
 		true,
 		"EnableMongoFilter enables injection of `envoy.filters.network.mongo_proxy` in the filter chain.",
 	).Get()
@@ -144,7 +208,7 @@ var (
 	// UseRemoteAddress sets useRemoteAddress to true for side car outbound listeners so that it picks up the localhost
 	// address of the sender, which is an internal address, so that trusted headers are not sanitized.
 	UseRemoteAddress = env.RegisterBoolVar(
-		"PILOT_SIDECAR_USE_REMOTE_ADDRESS",
+		generation("PILOT_SIDECAR_USE_REMOTE_ADDRESS"),
 		false,
 		"UseRemoteAddress sets useRemoteAddress to true for side car outbound listeners.",
 	).Get()
@@ -152,35 +216,35 @@ var (
 	// SkipValidateTrustDomain tells the server proxy to not to check the peer's trust domain when
 	// mTLS is enabled in authentication policy.
 	SkipValidateTrustDomain = env.RegisterBoolVar(
-		"PILOT_SKIP_VALIDATE_TRUST_DOMAIN",
+		generation("PILOT_SKIP_VALIDATE_TRUST_DOMAIN"),
 		false,
 		"Skip validating the peer is from the same trust domain when mTLS is enabled in authentication policy").Get()
 
 	EnableAutomTLSCheckPolicies = env.RegisterBoolVar(
-		"ENABLE_AUTO_MTLS_CHECK_POLICIES", true,
+		generation("ENABLE_AUTO_MTLS_CHECK_POLICIES"), true,
 		"Enable the auto mTLS EDS output to consult the PeerAuthentication Policy, only set the {tlsMode: istio} "+
 			" when server side policy enables mTLS PERMISSIVE or STRICT.").Get()
 
 	EnableProtocolSniffingForOutbound = env.RegisterBoolVar(
-		"PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_OUTBOUND",
+		generation("PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_OUTBOUND"),
 		true,
 		"If enabled, protocol sniffing will be used for outbound listeners whose port protocol is not specified or unsupported",
 	).Get()
 
 	EnableProtocolSniffingForInbound = env.RegisterBoolVar(
-		"PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_INBOUND",
+		generation("PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_INBOUND"),
 		true,
 		"If enabled, protocol sniffing will be used for inbound listeners whose port protocol is not specified or unsupported",
 	).Get()
 
 	EnableWasmTelemetry = env.RegisterBoolVar(
-		"ENABLE_WASM_TELEMETRY",
+		generation("ENABLE_WASM_TELEMETRY"),
 		false,
 		"If enabled, Wasm-based telemetry will be enabled.",
 	).Get()
 
 	ScopeGatewayToNamespace = env.RegisterBoolVar(
-		"PILOT_SCOPE_GATEWAY_TO_NAMESPACE",
+		generation("PILOT_SCOPE_GATEWAY_TO_NAMESPACE"),
 		false,
 		"If enabled, a gateway workload can only select gateway resources in the same namespace. "+
 			"Gateways with same selectors in different namespaces will not be applicable.",
@@ -188,13 +252,35 @@ var (
 
 	// nolint
 	InboundProtocolDetectionTimeout, InboundProtocolDetectionTimeoutSet = env.RegisterDurationVar(
-		"PILOT_INBOUND_PROTOCOL_DETECTION_TIMEOUT",
+		generation("PILOT_INBOUND_PROTOCOL_DETECTION_TIMEOUT"),
 		1*time.Second,
 		"Protocol detection timeout for inbound listener",
 	).Lookup()
 
+	// nolint
+	OutboundProtocolDetectionTimeout, OutboundProtocolDetectionTimeoutSet = env.RegisterDurationVar(
+		generation("PILOT_OUTBOUND_PROTOCOL_DETECTION_TIMEOUT"),
+		0,
+		"Protocol detection timeout for the outbound listeners of the sidecars. If not set, the protocolDetectionTimeout "+
+			"of the mesh config is used.",
+	).Lookup()
+
+	GatewayListenerFiltersTimeout = env.RegisterDurationVar(
+		generation("PILOT_GATEWAY_LISTENER_FILTERS_TIMEOUT"),
+		0,
+		"Timeout of the listener filters of the gateways, such as the TLS inspector, after which the connections are closed "+
+			"unless PILOT_GATEWAY_CONTINUE_ON_LISTENER_FILTERS_TIMEOUT is enabled. If 0, the Envoy default of 15s is used.",
+	).Get()
+
+	GatewayContinueOnListenerFiltersTimeout = env.RegisterBoolVar(
+		generation("PILOT_GATEWAY_CONTINUE_ON_LISTENER_FILTERS_TIMEOUT"),
+		false,
+		"If enabled, the connections of the gateways whose listener filters time out are handled by the filter chain "+
+			"matching what was inspected so far, instead of being closed.",
+	).Get()
+
 	EnableHeadlessService = env.RegisterBoolVar(
-		"PILOT_ENABLE_HEADLESS_SERVICE_POD_LISTENERS",
+		generation("PILOT_ENABLE_HEADLESS_SERVICE_POD_LISTENERS"),
 		true,
 		"If enabled, for a headless service/stateful set in Kubernetes, pilot will generate an "+
 			"outbound listener for each pod in a headless service. This feature should be disabled "+
@@ -202,14 +288,14 @@ var (
 	).Get()
 
 	EnableRemoteJwks = env.RegisterBoolVar(
-		"PILOT_JWT_ENABLE_REMOTE_JWKS",
+		generation("PILOT_JWT_ENABLE_REMOTE_JWKS"),
 		false,
 		"If enabled, checks to see if the configured JwksUri in RequestAuthentication is a mesh cluster URL "+
 			"and configures remote Jwks to let Envoy fetch the Jwks instead of Istiod.",
 	).Get()
 
 	EnableEDSForHeadless = env.RegisterBoolVar(
-		"PILOT_ENABLE_EDS_FOR_HEADLESS_SERVICES",
+		generation("PILOT_ENABLE_EDS_FOR_HEADLESS_SERVICES"),
 		false,
 		"If enabled, for headless service in Kubernetes, pilot will send endpoints over EDS, "+
 			"allowing the sidecar to load balance among pods in the headless service. This feature "+
@@ -253,7 +339,7 @@ var (
 	).Get()
 
 	EnableMCSServiceDiscovery = env.RegisterBoolVar(
-		"ENABLE_MCS_SERVICE_DISCOVERY",
+		generation("ENABLE_MCS_SERVICE_DISCOVERY"),
 		false,
 		"If enabled, istiod will enable Kubernetes Multi-Cluster "+
 			"Services (MCS) service discovery mode. In this mode, service "+
@@ -261,7 +347,7 @@ var (
 			"same cluster unless explicitly exported via ServiceExport.").Get()
 
 	EnableMCSHost = env.RegisterBoolVar(
-		"ENABLE_MCS_HOST",
+		generation("ENABLE_MCS_HOST"),
 		false,
 		"If enabled, istiod will configure a Kubernetes Multi-Cluster "+
 			"Services (MCS) host (<svc>.<namespace>.svc.clusterset.local) "+
@@ -273,7 +359,7 @@ var (
 		EnableMCSServiceDiscovery
 
 	EnableMCSClusterLocal = env.RegisterBoolVar(
-		"ENABLE_MCS_CLUSTER_LOCAL",
+		generation("ENABLE_MCS_CLUSTER_LOCAL"),
 		false,
 		"If enabled, istiod will treat the host "+
 			"`<svc>.<namespace>.svc.cluster.local` as defined by the "+
@@ -285,7 +371,7 @@ var (
 		EnableMCSHost
 
 	EnableLegacyLBAlgorithmDefault = env.RegisterBoolVar(
-		"ENABLE_LEGACY_LB_ALGORITHM_DEFAULT",
+		generation("ENABLE_LEGACY_LB_ALGORITHM_DEFAULT"),
 		false,
 		"If enabled, destinations for which no LB algorithm is specified will use the legacy "+
 			"default, ROUND_ROBIN. Care should be taken when using ROUND_ROBIN in general as it can "+
@@ -328,6 +414,13 @@ var (
 		" Pilot will use to keep configuration status up to date.  Smaller numbers will result in higher status latency, "+
 		"but larger numbers may impact CPU in high scale environments.").Get()
 
+	StatusDistributedThreshold = env.RegisterFloatVar(
+		"PILOT_STATUS_DISTRIBUTED_THRESHOLD",
+		0.99,
+		"If status is enabled, the fraction of the proxies which must be up to date with a generation of a resource "+
+			"for its Distributed condition to be true. Should be 0.0 - 1.0.",
+	).Get()
+
 	// IstiodServiceCustomHost allow user to bring a custom address for istiod server
 	// for examples: istiod.mycompany.com
 	IstiodServiceCustomHost = env.RegisterStringVar("ISTIOD_CUSTOM_HOST", "",
@@ -336,13 +429,13 @@ var (
 	PilotCertProvider = env.RegisterStringVar("PILOT_CERT_PROVIDER", constants.CertProviderIstiod,
 		"The provider of Pilot DNS certificate.").Get()
 
-	JwtPolicy = env.RegisterStringVar("JWT_POLICY", jwt.PolicyThirdParty,
+	JwtPolicy = env.RegisterStringVar(generation("JWT_POLICY"), jwt.PolicyThirdParty,
 		"The JWT validation policy.").Get()
 
 	// Default request timeout for virtual services if a timeout is not configured in virtual service. It defaults to zero
 	// which disables timeout when it is not configured, to preserve the current behavior.
 	defaultRequestTimeoutVar = env.RegisterDurationVar(
-		"ISTIO_DEFAULT_REQUEST_TIMEOUT",
+		generation("ISTIO_DEFAULT_REQUEST_TIMEOUT"),
 		0*time.Millisecond,
 		"Default Http and gRPC Request timeout",
 	)
@@ -351,11 +444,11 @@ var (
 		return durationpb.New(defaultRequestTimeoutVar.Get())
 	}()
 
-	LegacyIngressBehavior = env.RegisterBoolVar("PILOT_LEGACY_INGRESS_BEHAVIOR", false,
+	LegacyIngressBehavior = env.RegisterBoolVar(generation("PILOT_LEGACY_INGRESS_BEHAVIOR"), false,
 		"If this is set to true, istio ingress will perform the legacy behavior, "+
 			"which does not meet https://kubernetes.io/docs/concepts/services-networking/ingress/#multiple-matches.").Get()
 
-	EnableGatewayAPI = env.RegisterBoolVar("PILOT_ENABLE_GATEWAY_API", true,
+	EnableGatewayAPI = env.RegisterBoolVar(generation("PILOT_ENABLE_GATEWAY_API"), true,
 		"If this is set to true, support for Kubernetes gateway-api (github.com/kubernetes-sigs/gateway-api) will "+
 			" be enabled. In addition to this being enabled, the gateway-api CRDs need to be installed.").Get()
 
@@ -365,6 +458,12 @@ var (
 	EnableGatewayAPIDeploymentController = env.RegisterBoolVar("PILOT_ENABLE_GATEWAY_API_DEPLOYMENT_CONTROLLER", true,
 		"If this is set to true, gateway-api resources will automatically provision in cluster deployment, services, etc").Get()
 
+	GatewayAPIMigration = env.RegisterStringVar("PILOT_GATEWAY_API_MIGRATION", "",
+		"If set to report, Istiod reports which Gateways and VirtualServices bound to them can be converted into "+
+			"gateway-api resources at /debug/gateway_migrationz, along with the converted resources, without writing them. "+
+			"Only if set to apply, it also creates the converted gateway-api resources, and keeps them updated while the "+
+			"Istio resources exist.").Get()
+
 	ClusterName = env.RegisterStringVar("CLUSTER_ID", "Kubernetes",
 		"Defines the cluster and service registry that this Istiod instance is belongs to").Get()
 
@@ -389,11 +488,19 @@ var (
 		"If enabled, pilot will authorize XDS clients, to ensure they are acting only as namespaces they have permissions for.",
 	).Get()
 
-	EnableServiceEntrySelectPods = env.RegisterBoolVar("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS", true,
+	EnableXDSResourceAuthorization = env.RegisterBoolVar(
+		generation("PILOT_ENABLE_XDS_RESOURCE_AUTHORIZATION"),
+		false,
+		"If enabled, pilot will only send the endpoints of the services visible in the Sidecar scope of the XDS clients, "+
+			"ignoring the requests for other clusters instead of answering them with empty endpoints. "+
+			"Combined with PILOT_ENABLE_XDS_IDENTITY_CHECK, a proxy can only read the endpoints its own namespace can see.",
+	).Get()
+
+	EnableServiceEntrySelectPods = env.RegisterBoolVar(generation("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS"), true,
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
-	EnableK8SServiceSelectWorkloadEntries = env.RegisterBoolVar("PILOT_ENABLE_K8S_SELECT_WORKLOAD_ENTRIES", true,
+	EnableK8SServiceSelectWorkloadEntries = env.RegisterBoolVar(generation("PILOT_ENABLE_K8S_SELECT_WORKLOAD_ENTRIES"), true,
 		"If enabled, Kubernetes services with selectors will select workload entries with matching labels. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
@@ -440,6 +547,11 @@ var (
 		"If true, Istiod will set the pod fsGroup to 1337 on injection. This is required for Kubernetes 1.18 and older "+
 			`(see https://github.com/kubernetes/kubernetes/issues/57923 for details) unless JWT_POLICY is "first-party-jwt".`).Get()
 
+	EnableWindowsInjection = env.RegisterBoolVar("ENABLE_WINDOWS_INJECTION", false,
+		"If true, the pods scheduled on Windows nodes are injected with the windowsTemplates of the injection config, "+
+			"which run the windows variant of the proxy image. This is experimental: the windows image is not published, "+
+			"and must be built with a Windows build of Envoy.").Get()
+
 	XdsPushSendTimeout = env.RegisterDurationVar(
 		"PILOT_XDS_SEND_TIMEOUT",
 		0*time.Second,
@@ -453,15 +565,15 @@ var (
 			"Setting the timeout to 0 disables this behavior.",
 	).Get()
 
-	EnableTelemetryLabel = env.RegisterBoolVar("PILOT_ENABLE_TELEMETRY_LABEL", true,
+	EnableTelemetryLabel = env.RegisterBoolVar(generation("PILOT_ENABLE_TELEMETRY_LABEL"), true,
 		"If true, pilot will add telemetry related metadata to cluster and endpoint resources, which will be consumed by telemetry filter.",
 	).Get()
 
-	EndpointTelemetryLabel = env.RegisterBoolVar("PILOT_ENDPOINT_TELEMETRY_LABEL", true,
+	EndpointTelemetryLabel = env.RegisterBoolVar(generation("PILOT_ENDPOINT_TELEMETRY_LABEL"), true,
 		"If true, pilot will add telemetry related metadata to Endpoint resource, which will be consumed by telemetry filter.",
 	).Get()
 
-	MetadataExchange = env.RegisterBoolVar("PILOT_ENABLE_METADATA_EXCHANGE", true,
+	MetadataExchange = env.RegisterBoolVar(generation("PILOT_ENABLE_METADATA_EXCHANGE"), true,
 		"If true, pilot will add metadata exchange filters, which will be consumed by telemetry filter.",
 	).Get()
 
@@ -472,10 +584,16 @@ var (
 		"The amount of time an auto-registered workload can remain disconnected from all Pilot instances before the "+
 			"associated WorkloadEntry is cleaned up.").Get()
 
-	WorkloadEntryHealthChecks = env.RegisterBoolVar("PILOT_ENABLE_WORKLOAD_ENTRY_HEALTHCHECKS", true,
+	WorkloadEntryHealthChecks = env.RegisterBoolVar(generation("PILOT_ENABLE_WORKLOAD_ENTRY_HEALTHCHECKS"), true,
 		"Enables automatic health checks of WorkloadEntries based on the config provided in the associated WorkloadGroup").Get()
 
-	WorkloadEntryCrossCluster = env.RegisterBoolVar("PILOT_ENABLE_CROSS_CLUSTER_WORKLOAD_ENTRY", true,
+	DNSSRVRefreshInterval = env.RegisterDurationVar(
+		"PILOT_DNS_SRV_REFRESH_INTERVAL",
+		30*time.Second,
+		"The interval at which the SRV records of the hosts of ServiceEntries with the DNS_SRV resolution are resolved again.",
+	).Get()
+
+	WorkloadEntryCrossCluster = env.RegisterBoolVar(generation("PILOT_ENABLE_CROSS_CLUSTER_WORKLOAD_ENTRY"), true,
 		"If enabled, pilot will read WorkloadEntry from other clusters, selectable by Services in that cluster.").Get()
 
 	EnableFlowControl = env.RegisterBoolVar(
@@ -493,7 +611,7 @@ var (
 	).Get()
 
 	EnableDestinationRuleInheritance = env.RegisterBoolVar(
-		"PILOT_ENABLE_DESTINATION_RULE_INHERITANCE",
+		generation("PILOT_ENABLE_DESTINATION_RULE_INHERITANCE"),
 		false,
 		"If set, workload specific DestinationRules will inherit configurations settings from mesh and namespace level rules",
 	).Get()
@@ -509,7 +627,7 @@ var (
 	).Get()
 
 	EnableInboundPassthrough = env.RegisterBoolVar(
-		"PILOT_ENABLE_INBOUND_PASSTHROUGH",
+		generation("PILOT_ENABLE_INBOUND_PASSTHROUGH"),
 		true,
 		"If enabled, inbound clusters will be configured as ORIGINAL_DST clusters. When disabled, "+
 			"requests are always sent to localhost. The primary implication of this is that when enabled, binding to POD_IP "+
@@ -518,7 +636,7 @@ var (
 			"Regardless of this setting, the configuration can be overridden with the Sidecar.Ingress.DefaultEndpoint configuration.",
 	).Get()
 
-	StripHostPort = env.RegisterBoolVar("ISTIO_GATEWAY_STRIP_HOST_PORT", false,
+	StripHostPort = env.RegisterBoolVar(generation("ISTIO_GATEWAY_STRIP_HOST_PORT"), false,
 		"If enabled, Gateway will remove any port from host/authority header "+
 			"before any processing of request by HTTP filters or routing.").Get()
 
@@ -535,13 +653,13 @@ var (
 		"If enabled, pilot will only send the delta configs as opposed to the state of the world on a "+
 			"Resource Request. This feature uses the delta xds api, but does not currently send the actual deltas.").Get()
 
-	EnableLegacyIstioMutualCredentialName = env.RegisterBoolVar("PILOT_ENABLE_LEGACY_ISTIO_MUTUAL_CREDENTIAL_NAME",
+	EnableLegacyIstioMutualCredentialName = env.RegisterBoolVar(generation("PILOT_ENABLE_LEGACY_ISTIO_MUTUAL_CREDENTIAL_NAME"),
 		false,
 		"If enabled, Gateway's with ISTIO_MUTUAL mode and credentialName configured will use simple TLS. "+
 			"This is to retain legacy behavior only and not recommended for use beyond migration.").Get()
 
 	EnableLegacyAutoPassthrough = env.RegisterBoolVar(
-		"PILOT_ENABLE_LEGACY_AUTO_PASSTHROUGH",
+		generation("PILOT_ENABLE_LEGACY_AUTO_PASSTHROUGH"),
 		false,
 		"If enabled, pilot will allow any upstream cluster to be used with AUTO_PASSTHROUGH. "+
 			"This option is intended for backwards compatibility only and is not secure with untrusted downstreams; it will be removed in the future.").Get()
@@ -549,19 +667,19 @@ var (
 	SharedMeshConfig = env.RegisterStringVar("SHARED_MESH_CONFIG", "",
 		"Additional config map to load for shared MeshConfig settings. The standard mesh config will take precedence.").Get()
 
-	MultiRootMesh = env.RegisterBoolVar("ISTIO_MULTIROOT_MESH", false,
+	MultiRootMesh = env.RegisterBoolVar(generation("ISTIO_MULTIROOT_MESH"), false,
 		"If enabled, mesh will support certificates signed by more than one trustAnchor for ISTIO_MUTUAL mTLS").Get()
 
 	EnableEnvoyFilterMetrics = env.RegisterBoolVar("PILOT_ENVOY_FILTER_STATS", false,
 		"If true, Pilot will collect metrics for envoy filter operations.").Get()
 
-	EnableRouteCollapse = env.RegisterBoolVar("PILOT_ENABLE_ROUTE_COLLAPSE_OPTIMIZATION", true,
+	EnableRouteCollapse = env.RegisterBoolVar(generation("PILOT_ENABLE_ROUTE_COLLAPSE_OPTIMIZATION"), true,
 		"If true, Pilot will merge virtual hosts with the same routes into a single virtual host, as an optimization.").Get()
 
-	MulticlusterHeadlessEnabled = env.RegisterBoolVar("ENABLE_MULTICLUSTER_HEADLESS", true,
+	MulticlusterHeadlessEnabled = env.RegisterBoolVar(generation("ENABLE_MULTICLUSTER_HEADLESS"), true,
 		"If true, the DNS name table for a headless service will resolve to same-network endpoints in any cluster.").Get()
 
-	ResolveHostnameGateways = env.RegisterBoolVar("RESOLVE_HOSTNAME_GATEWAYS", true,
+	ResolveHostnameGateways = env.RegisterBoolVar(generation("RESOLVE_HOSTNAME_GATEWAYS"), true,
 		"If true, hostnames in the LoadBalancer addresses of a Service will be resolved at the control plane for use in cross-network gateways.").Get()
 
 	CertSignerDomain = env.RegisterStringVar("CERT_SIGNER_DOMAIN", "", "The cert signer domain info").Get()
@@ -578,19 +696,225 @@ var (
 		"If false, TCP probes will not be rewritten and therefor always succeed when a sidecar is used.",
 	).Get()
 
-	EnableQUICListeners = env.RegisterBoolVar("PILOT_ENABLE_QUIC_LISTENERS", false,
+	EnableQUICListeners = env.RegisterBoolVar(generation("PILOT_ENABLE_QUIC_LISTENERS"), false,
 		"If true, QUIC listeners will be generated wherever there are listeners terminating TLS on gateways "+
 			"if the gateway service exposes a UDP port with the same number (for example 443/TCP and 443/UDP)").Get()
 
-	VerifyCertAtClient = env.RegisterBoolVar("VERIFY_CERTIFICATE_AT_CLIENT", false,
+	VerifyCertAtClient = env.RegisterBoolVar(generation("VERIFY_CERTIFICATE_AT_CLIENT"), false,
 		"If enabled, certificates received by the proxy will be verified against the OS CA certificate bundle.").Get()
 
 	PrioritizedLeaderElection = env.RegisterBoolVar("PRIORITIZED_LEADER_ELECTION", true,
 		"If enabled, the default revision will steal leader locks from non-default revisions").Get()
 
-	EnableTLSOnSidecarIngress = env.RegisterBoolVar("ENABLE_TLS_ON_SIDECAR_INGRESS", false,
+	LeaderElectionScopes = env.RegisterStringVar("PILOT_LEADER_ELECTION_SCOPES", "",
+		"Comma separated list of election=scope, setting whether the controllers of each leader election run on the "+
+			"leader (leader, the default) or never run on this instance (none). The election * sets the scope of the "+
+			"elections not listed. For example, *=none on the instances dedicated to serving XDS, and *=leader on "+
+			"the instances running the controllers.").Get()
+
+	EnableTLSOnSidecarIngress = env.RegisterBoolVar(generation("ENABLE_TLS_ON_SIDECAR_INGRESS"), false,
 		"If enabled, the TLS configuration on Sidecar.ingress will take effect").Get()
 
+	EnableSidecarCredentialName = env.RegisterBoolVar(generation("PILOT_ENABLE_SIDECAR_CREDENTIAL_NAME"), false,
+		"If enabled, credentialName in a DestinationRule or Sidecar ingress TLS setting will take effect for sidecars. "+
+			"The certificate is served by Istiod over SDS from a Secret in the proxy's namespace, which requires the "+
+			"proxy's service account to be authorized to read Secrets in that namespace.").Get()
+
+	CertExpiryPushWindow = env.RegisterDurationVar("PILOT_CERT_EXPIRY_PUSH_WINDOW", 0,
+		"If set, Istiod pushes the certificates it serves over SDS again this long before they expire, so that proxies pick "+
+			"up a rotated certificate even if the update of its Secret was missed. Certificates still not rotated by then are "+
+			"reported by the pilot_sds_certificates_expiring_total metric. 0 disables it.").Get()
+
+	EnableNativeAccessLogFilters = env.RegisterBoolVar(generation("PILOT_ENABLE_NATIVE_ACCESS_LOG_FILTERS"), false,
+		"If enabled, simple Telemetry access log filter expressions on the response code, duration or request headers are "+
+			"translated into native Envoy access log filters instead of being evaluated with CEL.").Get()
+
+	EnableTCPAccessLogFormat = env.RegisterBoolVar(generation("PILOT_ENABLE_TCP_ACCESS_LOG_FORMAT"), true,
+		"If enabled, the access logs of TCP proxies use a default format logging the termination reasons, bytes, "+
+			"duration and SNI of the connections instead of the HTTP fields. Formats set in the mesh config or "+
+			"Telemetry API providers take precedence.").Get()
+
+	EnableInboundAdaptiveConcurrency = env.RegisterBoolVar(generation("PILOT_ENABLE_INBOUND_ADAPTIVE_CONCURRENCY"), false,
+		"If enabled, the adaptive concurrency filter is added to the inbound HTTP filter chains of sidecars, which "+
+			"reject requests with 503 when the latency of the service grows, instead of queuing them. Workloads can "+
+			"override it with the proxy.istio.io/inboundAdaptiveConcurrency annotation.").Get()
+
+	ScopedRoutesPort = env.RegisterIntVar(generation("PILOT_SCOPED_ROUTES_PORT"), 0,
+		"If set, the outbound HTTP route configuration of this port is sharded by domain suffix and served with scoped "+
+			"routes (SRDS) to sidecars in REGISTRY_ONLY mode, keyed by the :authority header. Only applies when none of "+
+			"the hosts on the port is a wildcard.").Get()
+
+	LoadReportingInterval = env.RegisterDurationVar("PILOT_LOAD_REPORTING_INTERVAL", 10*time.Second,
+		"The interval at which proxies which enabled load reporting send their load reports to istiod.").Get()
+
+	ServiceEntryUnusedThreshold = env.RegisterDurationVar("PILOT_SERVICE_ENTRY_UNUSED_THRESHOLD", 0,
+		"If set, ServiceEntries whose hosts received no traffic for this duration are marked with the Unused "+
+			"status condition. Traffic is observed through the load reports of the proxies which enabled load reporting.").Get()
+
+	ConvergenceWebhookURL = env.RegisterStringVar("PILOT_CONVERGENCE_WEBHOOK_URL", "",
+		"If set, the Istiod replica writing the distribution status posts a JSON event to this URL when a generation "+
+			"of a config was acknowledged by all the proxies of all the replicas. Requires PILOT_ENABLE_STATUS. "+
+			"Events may be repeated after a change of leader.").Get()
+
+	PeerIdentityMapping = env.RegisterStringVar(generation("PILOT_PEER_IDENTITY_MAPPING"), "",
+		"JSON list of mappings of the identities of peer certificates without SPIFFE URI SANs to Istio principals, "+
+			"used to match these peers in the principals and namespaces of authorization policies. Each mapping has a `san` RE2 "+
+			"regex matching the first DNS SAN of the certificate, or its subject if it has no DNS SAN, with the named groups "+
+			"`ns` and `sa`, and optionally `td`, and a `trustDomain` used if the regex has no `td` group.").Get()
+
+	TrustDomainMigrationFrom = env.RegisterStringVar(generation("PILOT_TRUST_DOMAIN_MIGRATION_FROM"), "",
+		"If set, the trust domain the mesh is migrating from. Until PILOT_TRUST_DOMAIN_MIGRATION_END, it is treated as "+
+			"an alias of the mesh trust domain: peers and servers presenting identities in either trust domain are accepted.").Get()
+
+	TrustDomainMigrationEnd = func() time.Time {
+		v := env.RegisterStringVar(generation("PILOT_TRUST_DOMAIN_MIGRATION_END"), "",
+			"The RFC3339 time at which the trust domain migration configured by PILOT_TRUST_DOMAIN_MIGRATION_FROM ends. "+
+				"If unset, the previous trust domain is accepted until PILOT_TRUST_DOMAIN_MIGRATION_FROM is removed.").Get()
+		if v == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Warnf("invalid PILOT_TRUST_DOMAIN_MIGRATION_END %q, ignoring: %v", v, err)
+			return time.Time{}
+		}
+		return t
+	}()
+
+	EnableStagedRollouts = env.RegisterBoolVar(generation("PILOT_ENABLE_STAGED_ROLLOUTS"), false,
+		"If enabled, changes of VirtualServices and DestinationRules annotated with rollout.istio.io/strategy=staged "+
+			"are first pushed to a percentage of the proxies, and are either promoted to all proxies or rolled back "+
+			"depending on the errors reported by these proxies. This is experimental: the rollouts are tracked in memory by "+
+			"each istiod replica, from the errors of the proxies connected to it, so replicas may promote or roll back a "+
+			"change at different times, and the rollouts restart when istiod restarts.").Get()
+
+	StagedRolloutPercentage = env.RegisterIntVar("PILOT_STAGED_ROLLOUT_PERCENTAGE", 10,
+		"The percentage of proxies receiving the staged config changes first.").Get()
+
+	StagedRolloutBakeTime = env.RegisterDurationVar("PILOT_STAGED_ROLLOUT_BAKE_TIME", 5*time.Minute,
+		"The time during which the staged config changes are only pushed to the canary proxies, before they are "+
+			"promoted to all proxies if no error was detected.").Get()
+
+	StagedRolloutMaxErrorDelta = env.RegisterFloatVar("PILOT_STAGED_ROLLOUT_MAX_ERROR_DELTA", 0.05,
+		"The maximum difference between the ratio of canary proxies reporting errors, that is rejecting clusters, "+
+			"listeners or routes or ejecting endpoints for 5xx errors, and the ratio of the other proxies reporting errors "+
+			"during a staged rollout. Only the proxies the staged config applies to are compared. "+
+			"Above it, the staged config changes are rolled back.").Get()
+
+	StagedRolloutMaxProxyPercentage = env.RegisterFloatVar("PILOT_STAGED_ROLLOUT_MAX_PROXY_PERCENTAGE", 0,
+		"If set with PILOT_ENABLE_STAGED_ROLLOUTS, the changes of VirtualServices and DestinationRules applying to more "+
+			"than this percentage of the proxies connected to istiod are staged, as if they were annotated with "+
+			"rollout.istio.io/strategy=staged, unless they are annotated with rollout.istio.io/allow-mesh-wide=true. "+
+			"This protects the mesh from accidental mesh-wide changes, such as a wildcard host. Each istiod replica only "+
+			"counts the proxies connected to it, as of the last evaluation of the staged rollouts.").Get()
+
+	StagedRolloutWaves = env.RegisterStringVar("PILOT_STAGED_ROLLOUT_WAVES", "",
+		"If set with PILOT_ENABLE_STAGED_ROLLOUTS, the changes of the Telemetry configs of the root namespace are "+
+			"pushed to the proxies of the namespaces of each wave in turn, before all proxies. The PeerAuthentication "+
+			"configs are not rolled out in waves, as the clients and servers of an mTLS connection may be in different "+
+			"waves. Each rollout is staged independently of the others, a proxy being served the changes of the waves "+
+			"reached by its config namespace. "+
+			"It is a JSON list such as [{\"namespaces\":[\"canary\"],\"bakeTime\":\"10m\"},{\"namespaces\":[\"team-a\"]}], "+
+			"the bake time defaulting to PILOT_STAGED_ROLLOUT_BAKE_TIME. The rollout halts when the proxies reached "+
+			"report more errors than the others, until it is promoted or rolled back through the rolloutz debug endpoint, "+
+			"or the config is changed again. Configs annotated with rollout.istio.io/allow-mesh-wide=true are pushed to "+
+			"all proxies right away.").Get()
+
+	EnableUDPServicePorts = env.RegisterBoolVar(generation("PILOT_ENABLE_UDP_SERVICE_PORTS"), false,
+		"If enabled, sidecars using TPROXY interception get a listener and a cluster for each UDP port of the services, "+
+			"proxying the datagrams sent to the service address with the UDP proxy filter, next to the listener and cluster "+
+			"of a TCP port with the same number. The UDP traffic must be redirected to the sidecar with TPROXY rules, as the "+
+			"default iptables rules only redirect the DNS queries.").Get()
+
+	EnableStableOutboundListenerFilters = env.RegisterBoolVar(generation("PILOT_ENABLE_STABLE_OUTBOUND_LISTENER_FILTERS"), false,
+		"If enabled, the TLS and HTTP inspectors are always added to the outbound TCP listeners of the sidecars, "+
+			"instead of only when one of their filter chains needs them. Envoy then updates the filter chains of these "+
+			"listeners in place when services are added or removed, instead of draining all their connections. "+
+			"It only applies when the mesh protocolDetectionTimeout is set, so that the inspectors do not stall "+
+			"server-first protocols, and not to the ports declaring an opaque TCP protocol, such as TCP or MySQL, "+
+			"whose server-first connections would otherwise wait out the timeout.").Get()
+
+	FilterBypassPaths = env.RegisterStringVar(generation("PILOT_FILTER_BYPASS_PATHS"), "",
+		"Comma separated list of the paths, such as /healthz or /metrics, whose inbound requests skip the JWT "+
+			"authentication and the authorization filters of the sidecars. A path ending with /* matches the paths "+
+			"under it. It can be overridden with the networking.istio.io/filter-bypass-paths annotation of a Sidecar "+
+			"of the root namespace, the annotation being ignored in the other namespaces.").Get()
+
+	EnableExtAuthzKillSwitch = env.RegisterBoolVar(generation("PILOT_ENABLE_EXT_AUTHZ_KILL_SWITCH"), false,
+		"If enabled, the HTTP ext_authz filters of the CUSTOM authorization policies are guarded by the "+
+			"istio.filters.http.ext_authz.enabled runtime key, served by istiod to all the proxies over RTDS from the "+
+			"runtimeValues of the defaultConfig of the mesh config. Setting it to 0 there disables the external "+
+			"authorization of the whole mesh during incidents, without changing the listeners. It must be set on "+
+			"both istiod and the proxies, whose bootstrap then reads the runtime layer from istiod.").Get()
+
+	IncludeAttemptCountInResponse = env.RegisterBoolVar(generation("PILOT_INCLUDE_ATTEMPT_COUNT_IN_RESPONSE"), false,
+		"If enabled, the proxies add the x-envoy-attempt-count header to the responses of the requests they route to "+
+			"services, with the number of attempts made upstream, so that the clients can see the retries.").Get()
+
+	PassthroughMetricsDestinationAddress = env.RegisterBoolVar(generation("PILOT_PASSTHROUGH_METRICS_DESTINATION_ADDRESS"), false,
+		"If enabled, the metrics of the passthrough and blackhole filter chains carry the destination_address label "+
+			"of the original destination IP. Each distinct IP creates new series, so the label is only added on demand, "+
+			"while the destination_port and requested_server_name labels are always added.").Get()
+
+	EnableListenerDrainMetrics = env.RegisterBoolVar(generation("PILOT_ENABLE_LISTENER_DRAIN_METRICS"), false,
+		"If enabled, the filter chains of the listeners pushed to each proxy are fingerprinted, and the ones changed "+
+			"or removed by a push acked by the proxy, whose connections are drained by Envoy, are counted by the "+
+			"pilot_xds_listener_drains metric by kind of the configs triggering the push, or \"multiple\" for several "+
+			"kinds. Fingerprinting hashes every listener generated, so it is disabled by default.").Get()
+
+	GatewayRouteValidation = env.RegisterStringVar(generation("PILOT_GATEWAY_ROUTE_VALIDATION"), "",
+		"If set, the route configurations of the gateways are validated before being pushed, checking their regular "+
+			"expressions against the RE2 syntax and program size limit of Envoy and their virtual host domains are "+
+			"unique. With \"audit\", the invalid ones are reported by the pilot_xds_route_validation_failures metric "+
+			"and pushed anyway. With \"enforce\", they are pushed without their invalid routes and virtual hosts.").Get()
+
+	EnableLBSubsetHeaders = env.RegisterBoolVar(generation("PILOT_ENABLE_LB_SUBSET_HEADERS"), false,
+		"If enabled, the header to metadata filter is added to the outbound and gateway HTTP filter chains, so that "+
+			"the networking.istio.io/lb-subset-headers annotation of the VirtualServices can select the endpoints of "+
+			"the subset load balancer of a DestinationRule from the headers of the requests.").Get()
+
+	EnableHeaderToMetadata = env.RegisterBoolVar(generation("PILOT_ENABLE_HEADER_TO_METADATA"), false,
+		"If enabled, the header to metadata filter is added to the outbound and gateway HTTP filter chains, so that "+
+			"the networking.istio.io/header-to-metadata annotation of the VirtualServices can copy the headers of the "+
+			"requests into their dynamic metadata.").Get()
+
+	EnableNetworkConfigMaps = env.RegisterBoolVar(generation("PILOT_ENABLE_NETWORK_CONFIGMAPS"), false,
+		"If enabled, the ConfigMaps of the istiod namespace with the topology.istio.io/mesh-network label are watched, "+
+			"and the networks they define are merged into the meshNetworks, taking precedence over the networks of the "+
+			"same name.").Get()
+
+	EnableNodeNetworkDetection = env.RegisterBoolVar(generation("PILOT_ENABLE_NODE_NETWORK_DETECTION"), false,
+		"If enabled, the endpoints without a topology.istio.io/network label are assigned to the network of the "+
+			"topology.istio.io/network label of their node, found by the node of their pod, or by the pod CIDRs and "+
+			"the addresses of the nodes. The network of the node takes precedence over the network of the system "+
+			"namespace and of the meshNetworks.").Get()
+
+	CrossNetworkTrafficPolicy = env.RegisterStringVar(generation("PILOT_CROSS_NETWORK_TRAFFIC_POLICY"), "",
+		"JSON list of policies tuning the upstream connections of the outbound clusters of the services with "+
+			"endpoints in another network than the proxy. Each policy has a `from` network of the proxies and a `to` "+
+			"network of the endpoints, empty for all the networks, and upgrades the HTTP/1.1 connections to HTTP/2 with "+
+			"`h2Upgrade` and sets the `initialStreamWindowSize` and `initialConnectionWindowSize` of the HTTP/2 "+
+			"connections. The first matching policy applies.").Get()
+
+	LocalityCosts = env.RegisterStringVar(generation("PILOT_LOCALITY_COSTS"), "",
+		"JSON list of the relative costs of the traffic between localities, such as the price of the traffic across "+
+			"zones. Each cost has a `from` locality of the proxies, a `to` locality of the endpoints, in the "+
+			"region/zone/subzone format with `*` wildcards, and a non-negative `cost`. With locality failover, the "+
+			"localities are prioritized by increasing integer part of their cost instead of by locality match, so "+
+			"that the traffic only fails over to more expensive localities when the cheaper ones are unhealthy, and "+
+			"the localities of the same priority are weighted by decreasing cost. The pairs without a cost have the "+
+			"cost of their locality match: 0 for the same subzone, 1 for the same zone, 2 for the same region and 3 "+
+			"otherwise.").Get()
+
+	PassthroughPlaintextTLSPorts = env.RegisterStringVar(generation("PILOT_PASSTHROUGH_PLAINTEXT_TLS_PORTS"), "",
+		"Comma separated list of ports which only accept TLS, such as 443. If set, the plaintext HTTP sent by sidecars "+
+			"with the ALLOW_ANY outbound traffic policy to unknown destinations on these ports is detected, and "+
+			"counted in the envoy_tcp_downstream_cx_total metric with the PassthroughPlaintextTLS stat prefix.").Get()
+
+	PushHistorySize = env.RegisterIntVar("PILOT_PUSH_HISTORY_SIZE", 0,
+		"The number of full pushes whose config versions and pushed proxies are retained, to inspect what changed "+
+			"between pushes at /debug/push_historyz. Disabled if 0.").Get()
+
 	InsecureKubeConfigOptions = func() sets.Set {
 		v := env.RegisterStringVar(
 			"PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS",
@@ -611,3 +935,19 @@ func EnableEndpointSliceController() (value bool, ok bool) {
 func UnsafeFeaturesEnabled() bool {
 	return EnableUnsafeAdminEndpoints || EnableUnsafeAssertions
 }
+
+// generationFlags are the names of the flags changing the config generated for the proxies.
+var generationFlags = map[string]bool{}
+
+// generation tags a flag at its registration as changing the config generated for the proxies. The flags tuning
+// istiod itself, such as the push throttling, the caches or the status reporting, are not tagged, as they may
+// legitimately differ across replicas.
+func generation(name string) string {
+	generationFlags[name] = true
+	return name
+}
+
+// IsGenerationFlag returns true if the flag changes the config generated for the proxies.
+func IsGenerationFlag(name string) bool {
+	return generationFlags[name]
+}
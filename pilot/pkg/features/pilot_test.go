@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import (
+	"testing"
+
+	"istio.io/pkg/env"
+)
+
+// istiodFlags are the flags which do not change the config generated for the proxies: they tune istiod itself, its
+// controllers, the sidecar injection or the agent. A new flag must either be tagged with generation at its
+// registration, or be added here.
+var istiodFlags = map[string]bool{
+	"AUTO_RELOAD_PLUGIN_CERTS":                       true,
+	"CERT_SIGNER_DOMAIN":                             true,
+	"CLUSTER_ID":                                     true,
+	"ENABLE_CA_SERVER":                               true,
+	"ENABLE_DEBUG_ON_HTTP":                           true,
+	"ENABLE_LEGACY_FSGROUP_INJECTION":                true,
+	"ENABLE_MCS_AUTO_EXPORT":                         true,
+	"ENABLE_WINDOWS_INJECTION":                       true,
+	"EXTERNAL_ISTIOD":                                true,
+	"INJECTION_WEBHOOK_CONFIG_NAME":                  true,
+	"ISTIOD_CUSTOM_HOST":                             true,
+	"ISTIO_AGENT_ENABLE_WASM_REMOTE_LOAD_CONVERSION": true,
+	"ISTIO_DELTA_XDS":                                true,
+	"ISTIO_GPRC_MAXRECVMSGSIZE":                      true,
+	"ISTIO_GPRC_MAXSTREAMS":                          true,
+	"MCS_API_GROUP":                                  true,
+	"MCS_API_VERSION":                                true,
+	"PILOT_CERT_EXPIRY_PUSH_WINDOW":                  true,
+	"PILOT_CERT_PROVIDER":                            true,
+	"PILOT_CONVERGENCE_WEBHOOK_URL":                  true,
+	"PILOT_DEBOUNCE_AFTER":                           true,
+	"PILOT_DEBOUNCE_AFTER_BY_KIND":                   true,
+	"PILOT_DEBOUNCE_MAX":                             true,
+	"PILOT_DISTRIBUTION_HISTORY_RETENTION":           true,
+	"PILOT_DNS_SRV_REFRESH_INTERVAL":                 true,
+	"PILOT_ENABLE_ANALYSIS":                          true,
+	"PILOT_ENABLE_CDS_CACHE":                         true,
+	"PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING":      true,
+	"PILOT_ENABLE_EDS_DEBOUNCE":                      true,
+	"PILOT_ENABLE_FLOW_CONTROL":                      true,
+	"PILOT_ENABLE_GATEWAY_API_DEPLOYMENT_CONTROLLER": true,
+	"PILOT_ENABLE_GATEWAY_API_STATUS":                true,
+	"PILOT_ENABLE_RDS_CACHE":                         true,
+	"PILOT_ENABLE_STATUS":                            true,
+	"PILOT_ENABLE_WORKLOAD_ENTRY_AUTOREGISTRATION":   true,
+	"PILOT_ENABLE_XDS_CACHE":                         true,
+	"PILOT_ENABLE_XDS_IDENTITY_CHECK":                true,
+	"PILOT_ENVOY_FILTER_STATS":                       true,
+	"PILOT_FLOW_CONTROL_TIMEOUT":                     true,
+	"PILOT_FULL_PUSH_MIN_INTERVAL":                   true,
+	"PILOT_FULL_PUSH_THROTTLE":                       true,
+	"PILOT_GATEWAY_API_MIGRATION":                    true,
+	"PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS": true,
+	"PILOT_JWT_PUB_KEY_REFRESH_INTERVAL":             true,
+	"PILOT_LEADER_ELECTION_SCOPES":                   true,
+	"PILOT_LOAD_REPORTING_INTERVAL":                  true,
+	"PILOT_MAX_CONNECTIONS":                          true,
+	"PILOT_MAX_REQUESTS_PER_SECOND":                  true,
+	"PILOT_PUSH_HISTORY_SIZE":                        true,
+	"PILOT_PUSH_THROTTLE":                            true,
+	"PILOT_REMOTE_CLUSTER_TIMEOUT":                   true,
+	"PILOT_REQUEST_PUSH_THROTTLE":                    true,
+	"PILOT_SERVICE_ENTRY_UNUSED_THRESHOLD":           true,
+	"PILOT_STAGED_ROLLOUT_BAKE_TIME":                 true,
+	"PILOT_STAGED_ROLLOUT_MAX_ERROR_DELTA":           true,
+	"PILOT_STAGED_ROLLOUT_MAX_PROXY_PERCENTAGE":      true,
+	"PILOT_STAGED_ROLLOUT_PERCENTAGE":                true,
+	"PILOT_STAGED_ROLLOUT_WAVES":                     true,
+	"PILOT_STATUS_BURST":                             true,
+	"PILOT_STATUS_DISTRIBUTED_THRESHOLD":             true,
+	"PILOT_STATUS_MAX_WORKERS":                       true,
+	"PILOT_STATUS_QPS":                               true,
+	"PILOT_STATUS_UPDATE_INTERVAL":                   true,
+	"PILOT_USE_ENDPOINT_SLICE":                       true,
+	"PILOT_WORKLOAD_ENTRY_GRACE_PERIOD":              true,
+	"PILOT_XDS_CACHE_SIZE":                           true,
+	"PILOT_XDS_CACHE_STATS":                          true,
+	"PILOT_XDS_SEND_TIMEOUT":                         true,
+	"PRIORITIZED_LEADER_ELECTION":                    true,
+	"REWRITE_TCP_PROBES":                             true,
+	"SHARED_MESH_CONFIG":                             true,
+	"SPIFFE_BUNDLE_ENDPOINTS":                        true,
+	"UNSAFE_ENABLE_ADMIN_ENDPOINTS":                  true,
+	"UNSAFE_PILOT_ENABLE_RUNTIME_ASSERTIONS":         true,
+	"VALIDATION_WEBHOOK_CONFIG_NAME":                 true,
+	"XDS_AUTH":                                       true,
+}
+
+func TestGenerationFlags(t *testing.T) {
+	for _, v := range env.VarDescriptions() {
+		tagged := IsGenerationFlag(v.Name)
+		if !tagged && !istiodFlags[v.Name] {
+			t.Errorf("%s is neither tagged with generation nor listed in istiodFlags", v.Name)
+		}
+		if tagged && istiodFlags[v.Name] {
+			t.Errorf("%s is both tagged with generation and listed in istiodFlags", v.Name)
+		}
+	}
+	for _, name := range []string{
+		"PILOT_GATEWAY_ROUTE_VALIDATION",
+		"PILOT_ENABLE_XDS_RESOURCE_AUTHORIZATION",
+		"PILOT_ENABLE_NODE_NETWORK_DETECTION",
+		"PILOT_ENABLE_NETWORK_CONFIGMAPS",
+		"PILOT_ENABLE_STAGED_ROLLOUTS",
+	} {
+		if !IsGenerationFlag(name) {
+			t.Errorf("expected %s to be tagged with generation", name)
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/revisions"
 	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
 )
 
 // Various locks used throughout the code
@@ -47,10 +48,39 @@ const (
 	// GatewayDeploymentController controls the Deployment/Service generation from Gateways. This is
 	// separate from GatewayStatusController to allow running in a separate process (for low priv).
 	GatewayDeploymentController = "istio-gateway-deployment-leader"
-	StatusController            = "istio-status-leader"
-	AnalyzeController           = "istio-analyze-leader"
+	// GatewayMigrationController writes the gateway-api resources converted from the Istio Gateways.
+	GatewayMigrationController = "istio-gateway-migration-leader"
+	StatusController           = "istio-status-leader"
+	AnalyzeController          = "istio-analyze-leader"
+	// ServiceEntryUsageController marks the ServiceEntries without traffic unused.
+	ServiceEntryUsageController = "istio-serviceentry-usage-leader"
+	// WebhookCertPatcherController and ValidationController patch the webhook configurations of their revision,
+	// and are elected per revision.
+	WebhookCertPatcherController = "istio-webhook-patcher-leader"
+	ValidationController         = "istio-validation-leader"
 )
 
+var (
+	electionTag = monitoring.MustCreateLabel("election")
+	eventTag    = monitoring.MustCreateLabel("event")
+
+	leaderGauge = monitoring.NewGauge(
+		"pilot_leader",
+		"Whether this instance is the leader of the election (1) or not (0).",
+		monitoring.WithLabels(electionTag),
+	)
+
+	leaderTransitions = monitoring.NewSum(
+		"pilot_leader_election_transitions",
+		"Total number of leader elections won (acquired) and lost (lost) by this instance.",
+		monitoring.WithLabels(electionTag, eventTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(leaderGauge, leaderTransitions)
+}
+
 type LeaderElection struct {
 	namespace string
 	name      string
@@ -67,6 +97,10 @@ type LeaderElection struct {
 	// This is mostly just for testing
 	cycle      *atomic.Int32
 	electionID string
+	// controller is the election ID the scope and the metrics of the election are keyed by, which does not include
+	// the revision of the elections per revision.
+	controller string
+	scope      Scope
 
 	// Store as field for testing
 	le *k8sleaderelection.LeaderElector
@@ -75,6 +109,12 @@ type LeaderElection struct {
 
 // Run will start leader election, calling all runFns when we become the leader.
 func (l *LeaderElection) Run(stop <-chan struct{}) {
+	if l.scope == DisabledScope {
+		log.Infof("leader election %v is disabled on this instance, not running its controllers", l.electionID)
+		leaderGauge.With(electionTag.Value(l.metricName())).Record(0)
+		<-stop
+		return
+	}
 	if l.prioritized && l.defaultWatcher != nil {
 		go l.defaultWatcher.Run(stop)
 	}
@@ -111,12 +151,16 @@ func (l *LeaderElection) create() (*k8sleaderelection.LeaderElector, error) {
 	callbacks := k8sleaderelection.LeaderCallbacks{
 		OnStartedLeading: func(ctx context.Context) {
 			log.Infof("leader election lock obtained: %v", l.electionID)
+			leaderGauge.With(electionTag.Value(l.metricName())).Record(1)
+			leaderTransitions.With(electionTag.Value(l.metricName()), eventTag.Value("acquired")).Increment()
 			for _, f := range l.runFns {
 				go f(ctx.Done())
 			}
 		},
 		OnStoppedLeading: func() {
 			log.Infof("leader election lock lost: %v", l.electionID)
+			leaderGauge.With(electionTag.Value(l.metricName())).Record(0)
+			leaderTransitions.With(electionTag.Value(l.metricName()), eventTag.Value("lost")).Increment()
 		},
 	}
 	lock := k8sresourcelock.ConfigMapLock{
@@ -160,8 +204,23 @@ func (l *LeaderElection) AddRunFunction(f func(stop <-chan struct{})) *LeaderEle
 }
 
 func NewLeaderElection(namespace, name, electionID, revision string, client kube.Client) *LeaderElection {
+	return newLeaderElection(namespace, name, electionID, electionID, revision, features.PrioritizedLeaderElection, client)
+}
+
+// NewPerRevisionLeaderElection returns a leader election among the instances of the revision, for the controllers
+// each revision runs for its own resources. The lock is never stolen by the default revision.
+func NewPerRevisionLeaderElection(namespace, name, electionID, revision string, client kube.Client) *LeaderElection {
+	lock := electionID
+	if revision != "" {
+		lock = electionID + "-" + revision
+	}
+	return newLeaderElection(namespace, name, lock, electionID, revision, false, client)
+}
+
+func newLeaderElection(namespace, name, electionID, controller, revision string, prioritized bool,
+	client kube.Client) *LeaderElection {
 	var watcher revisions.DefaultWatcher
-	if features.PrioritizedLeaderElection {
+	if prioritized {
 		watcher = revisions.NewDefaultWatcher(client, revision)
 	}
 	if name == "" {
@@ -173,8 +232,10 @@ func NewLeaderElection(namespace, name, electionID, revision string, client kube
 		name:           name,
 		client:         client,
 		electionID:     electionID,
+		controller:     controller,
+		scope:          scopeOf(scopes, controller),
 		revision:       revision,
-		prioritized:    features.PrioritizedLeaderElection,
+		prioritized:    prioritized,
 		defaultWatcher: watcher,
 		// Default to a 30s ttl. Overridable for tests
 		ttl:   time.Second * 30,
@@ -183,6 +244,14 @@ func NewLeaderElection(namespace, name, electionID, revision string, client kube
 	}
 }
 
+// metricName returns the election label of the metrics of the election.
+func (l *LeaderElection) metricName() string {
+	if l.controller != "" {
+		return l.controller
+	}
+	return l.electionID
+}
+
 func (l *LeaderElection) isLeader() bool {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -27,6 +27,7 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 
+	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/revisions"
 	"istio.io/istio/pkg/test/util/retry"
 )
@@ -191,3 +192,77 @@ func expectInt(t *testing.T, f func() int32, expected int32) {
 		return nil
 	}, retry.Timeout(time.Second))
 }
+
+func TestPerRevisionLeaderElection(t *testing.T) {
+	client := kube.NewFakeClient()
+	elect := func(name, revision string, expectLeader bool) chan struct{} {
+		t.Helper()
+		l := NewPerRevisionLeaderElection("ns", name, testLock, revision, client)
+		l.ttl = time.Second
+		stop := make(chan struct{})
+		go l.Run(stop)
+		retry.UntilOrFail(t, func() bool {
+			return l.isLeader() == expectLeader
+		}, retry.Converge(5), retry.Delay(time.Millisecond*100), retry.Timeout(time.Second*10))
+		return stop
+	}
+	// Each revision has its own leader.
+	stop := elect("pod1", "red", true)
+	stop2 := elect("pod2", "green", true)
+	stop3 := elect("pod3", "red", false)
+	close(stop)
+	close(stop2)
+	close(stop3)
+}
+
+func TestDisabledLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	l := &LeaderElection{
+		namespace:  "ns",
+		name:       "pod1",
+		electionID: testLock,
+		client:     client,
+		scope:      DisabledScope,
+		ttl:        time.Second,
+		cycle:      atomic.NewInt32(0),
+	}
+	ran := atomic.NewBool(false)
+	l.AddRunFunction(func(stop <-chan struct{}) {
+		ran.Store(true)
+	})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		l.Run(stop)
+		close(done)
+	}()
+	// Another instance becomes the leader, as the disabled instance does not join the election.
+	_, stop2 := createElection(t, "pod2", "", &fakeDefaultWatcher{}, false, true, client)
+	close(stop2)
+	close(stop)
+	<-done
+	if ran.Load() {
+		t.Fatalf("the controllers of a disabled election should not run")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes(" *=none, istio-leader=LEADER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scopeOf(scopes, IngressController); got != LeaderScope {
+		t.Fatalf("got scope %v for %s", got, IngressController)
+	}
+	if got := scopeOf(scopes, NamespaceController); got != DisabledScope {
+		t.Fatalf("got scope %v for %s", got, NamespaceController)
+	}
+	if got := scopeOf(nil, NamespaceController); got != LeaderScope {
+		t.Fatalf("got scope %v by default", got)
+	}
+	for _, invalid := range []string{"istio-leader", "=none", "istio-leader=all"} {
+		if _, err := ParseScopes(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// Scope is where the controllers of a leader election run.
+type Scope string
+
+const (
+	// LeaderScope runs the controllers on the leader of the election only.
+	LeaderScope Scope = "leader"
+	// DisabledScope never runs the controllers on this instance, which does not join the election. This allows
+	// dedicating some instances to serving XDS, while the others own the controllers.
+	DisabledScope Scope = "none"
+)
+
+// allElections is the election setting the scope of the elections not listed.
+const allElections = "*"
+
+// scopes are the scopes of PILOT_LEADER_ELECTION_SCOPES, by election ID. They are checked at startup by ValidateScopes.
+var scopes, scopesErr = ParseScopes(features.LeaderElectionScopes)
+
+// ValidateScopes returns an error if PILOT_LEADER_ELECTION_SCOPES is invalid. Istiod fails to start rather than
+// ignoring it, as that would run the controllers on the instances dedicated to serving XDS.
+func ValidateScopes() error {
+	if scopesErr != nil {
+		return fmt.Errorf("invalid PILOT_LEADER_ELECTION_SCOPES: %v", scopesErr)
+	}
+	return nil
+}
+
+// ParseScopes parses a comma separated list of election=scope.
+func ParseScopes(s string) (map[string]Scope, error) {
+	res := map[string]Scope{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected election=scope", entry)
+		}
+		scope := Scope(strings.ToLower(parts[1]))
+		if scope != LeaderScope && scope != DisabledScope {
+			return nil, fmt.Errorf("invalid scope %q of %s, expected %s or %s", parts[1], parts[0], LeaderScope, DisabledScope)
+		}
+		res[parts[0]] = scope
+	}
+	return res, nil
+}
+
+// scopeOf returns the scope of the election in the scopes, defaulting to LeaderScope.
+func scopeOf(scopes map[string]Scope, electionID string) Scope {
+	if s, f := scopes[electionID]; f {
+		return s
+	}
+	if s, f := scopes[allElections]; f {
+		return s
+	}
+	return LeaderScope
+}
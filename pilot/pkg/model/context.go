@@ -35,6 +35,7 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/credentials"
 	istionetworking "istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pkg/cluster"
@@ -91,6 +92,9 @@ type Environment struct {
 	clusterLocalServices ClusterLocalProvider
 
 	GatewayAPIController GatewayController
+
+	// CredentialsController reads the credentials of the Secrets referenced by the configs.
+	CredentialsController credentials.MulticlusterController
 }
 
 func (e *Environment) Mesh() *meshconfig.MeshConfig {
@@ -335,6 +339,34 @@ type WatchedResource struct {
 
 	// LastSent tracks the time of the generated push, to determine the time it takes the client to ack.
 	LastSent time.Time
+
+	// StaleSince is the time the oldest response not acked by the proxy was sent, or zero if the proxy
+	// acked the last response. The proxy runs on older config than sent to it since then.
+	StaleSince time.Time
+
+	// ListenerFingerprints are the fingerprints of the listeners last acked by the proxy, keyed by name. Only set for
+	// listeners, to count the filter chains drained by the next push.
+	ListenerFingerprints map[string]*ListenerFingerprint
+
+	// PendingListeners are the listeners last generated for the proxy and not acked yet. They replace the
+	// ListenerFingerprints once the proxy acks them, and are dropped when it nacks them.
+	PendingListeners *PendingListeners
+}
+
+// ListenerFingerprint is the hash of a listener without its filter chains, and the hashes of its filter chains. Envoy
+// updates the filter chains of a listener in place, only draining the connections of the changed or removed ones, as
+// long as the rest of the listener is unchanged.
+type ListenerFingerprint struct {
+	Listener     uint64
+	FilterChains map[uint64]struct{}
+}
+
+// PendingListeners are the fingerprints of the listeners generated for a proxy, keyed by name, along with the nonce of
+// the response sending them and the trigger of the push generating them.
+type PendingListeners struct {
+	Nonce        string
+	Fingerprints map[string]*ListenerFingerprint
+	Trigger      string
 }
 
 var istioVersionRegexp = regexp.MustCompile(`^([1-9]+)\.([0-9]+)(\.([0-9]+))?`)
@@ -570,6 +602,10 @@ type NodeMetadata struct {
 	// If not set, default timeout is 1 hour.
 	IdleTimeout string `json:"IDLE_TIMEOUT,omitempty"`
 
+	// TCPMaxConnectionDuration specifies the maximum duration of the TCP connections proxied by the proxy, in
+	// duration format (24h). If not set, connections are not limited.
+	TCPMaxConnectionDuration string `json:"TCP_MAX_CONNECTION_DURATION,omitempty"`
+
 	// HTTP10 indicates the application behind the sidecar is making outbound http requests with HTTP/1.0
 	// protocol. It will enable the "AcceptHttp_10" option on the http options for outbound HTTP listeners.
 	// Alpha in 1.1, based on feedback may be turned into an API or change. Set to "1" to enable.
@@ -586,6 +622,14 @@ type NodeMetadata struct {
 	// This depends on DNSCapture.
 	DNSAutoAllocate StringBool `json:"DNS_AUTO_ALLOCATE,omitempty"`
 
+	// HealthCheckEventLogPath is the file the active health checks of clusters log their events to.
+	// The agent reports these events to istiod.
+	HealthCheckEventLogPath string `json:"HEALTH_CHECK_EVENT_LOG_PATH,omitempty"`
+
+	// LoadReporting indicates whether the proxy should report per-cluster and per-locality load
+	// to istiod through the Load Reporting Service.
+	LoadReporting StringBool `json:"LOAD_REPORTING,omitempty"`
+
 	// AutoRegister will enable auto registration of the connected endpoint to the service registry using the given WorkloadGroup name
 	AutoRegisterGroup string `json:"AUTO_REGISTER_GROUP,omitempty"`
 
@@ -609,13 +653,22 @@ type NodeMetadata struct {
 	ExitOnZeroActiveConnections StringBool `json:"EXIT_ON_ZERO_ACTIVE_CONNECTIONS,omitempty"`
 
 	// InboundListenerExactBalance sets connection balance config to use exact_balance for virtualInbound,
-	// as long as QUIC, since it uses UDP, isn't also used.
+	// as long as QUIC, since it uses UDP, isn't also used. It also applies to inbound listeners bound to a port
+	// through the Sidecar ingress API, and to the tcp listeners of gateways.
 	InboundListenerExactBalance StringBool `json:"INBOUND_LISTENER_EXACT_BALANCE,omitempty"`
 
 	// OutboundListenerExactBalance sets connection balance config to use exact_balance for outbound
-	// redirected tcp listeners. This does not change the virtualOutbound listener.
+	// redirected tcp listeners and the tcp listeners of gateways. This does not change the virtualOutbound listener.
 	OutboundListenerExactBalance StringBool `json:"OUTBOUND_LISTENER_EXACT_BALANCE,omitempty"`
 
+	// ListenerSocketOptions is a comma separated list of NAME=value socket options set on the tcp listeners
+	// of the proxy, for example "SO_RCVBUF=262144,DSCP=46".
+	ListenerSocketOptions string `json:"LISTENER_SOCKET_OPTIONS,omitempty"`
+
+	// ClusterSocketOptions is a comma separated list of NAME=value socket options set on the upstream
+	// connections of outbound clusters, for example "TCP_NODELAY=1,DSCP=46".
+	ClusterSocketOptions string `json:"CLUSTER_SOCKET_OPTIONS,omitempty"`
+
 	// Contains a copy of the raw metadata. This is needed to lookup arbitrary values.
 	// If a value is known ahead of time it should be added to the struct rather than reading from here,
 	Raw map[string]interface{} `json:"-"`
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+// egressGatewayNamePrefix prefixes the names of the configs generated for the gateway.EgressGatewayAnnotation.
+const egressGatewayNamePrefix = "auto-egress-"
+
+// egressGatewayRef is the egress gateway of a gateway.EgressGatewayAnnotation.
+type egressGatewayRef struct {
+	hostname  string
+	namespace string
+	port      uint32
+}
+
+// gatewayName returns the namespace/name of the Gateway generated for the egress gateway.
+func (r egressGatewayRef) gatewayName() string {
+	return r.namespace + "/" + r.generatedName()
+}
+
+func (r egressGatewayRef) generatedName() string {
+	return fmt.Sprintf("%s%s-%d", egressGatewayNamePrefix, strings.SplitN(r.hostname, ".", 2)[0], r.port)
+}
+
+// buildEgressGatewayRoutes generates the Gateways and VirtualServices routing the hosts of the ServiceEntries with
+// the gateway.EgressGatewayAnnotation through their egress gateway. For each ServiceEntry, a VirtualService bound to the
+// sidecars sends its TLS ports to the gateway, and a VirtualService bound to the gateway sends the connections to
+// the hosts by SNI. Hosts of the user VirtualServices bound to the sidecars are skipped.
+func buildEgressGatewayRoutes(serviceEntries, virtualServices []config.Config) (vses, gateways []config.Config) {
+	routed := meshVirtualServiceHosts(virtualServices)
+	gatewayHosts := map[egressGatewayRef][]string{}
+	for _, cfg := range serviceEntries {
+		value, f := cfg.Annotations[gateway.EgressGatewayAnnotation]
+		if !f {
+			continue
+		}
+		hostname, port, err := gateway.ParseEgressGatewayAnnotation(value)
+		if err != nil {
+			log.Warnf("ignoring invalid %s annotation of ServiceEntry %s/%s: %v", gateway.EgressGatewayAnnotation, cfg.Namespace, cfg.Name, err)
+			continue
+		}
+		gw := egressGatewayRef{hostname: hostname, namespace: strings.Split(hostname, ".")[1], port: port}
+		se := cfg.Spec.(*networking.ServiceEntry)
+		var tlsPorts []*networking.Port
+		for _, p := range se.Ports {
+			if protocol.Parse(p.Protocol).IsTLS() {
+				tlsPorts = append(tlsPorts, p)
+			}
+		}
+		var hosts []string
+		for _, h := range se.Hosts {
+			if !routed[string(ResolveShortnameToFQDN(h, cfg.Meta))] && !strings.HasPrefix(h, "*") {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(tlsPorts) == 0 || len(hosts) == 0 {
+			continue
+		}
+		gatewayHosts[gw] = append(gatewayHosts[gw], hosts...)
+
+		meshRoute := &networking.VirtualService{
+			Hosts:    hosts,
+			Gateways: []string{constants.IstioMeshGateway},
+			ExportTo: se.ExportTo,
+		}
+		gatewayRoute := &networking.VirtualService{
+			Hosts:    hosts,
+			Gateways: []string{gw.gatewayName()},
+			ExportTo: []string{string(visibility.Private)},
+		}
+		for _, h := range hosts {
+			for _, p := range tlsPorts {
+				meshRoute.Tls = append(meshRoute.Tls, &networking.TLSRoute{
+					Match: []*networking.TLSMatchAttributes{{Port: p.Number, SniHosts: []string{h}}},
+					Route: []*networking.RouteDestination{{Destination: &networking.Destination{
+						Host: gw.hostname,
+						Port: &networking.PortSelector{Number: gw.port},
+					}}},
+				})
+			}
+			// The gateway only sees the SNI of the connections, they are sent to the first TLS port of the host.
+			gatewayRoute.Tls = append(gatewayRoute.Tls, &networking.TLSRoute{
+				Match: []*networking.TLSMatchAttributes{{Port: gw.port, SniHosts: []string{h}}},
+				Route: []*networking.RouteDestination{{Destination: &networking.Destination{
+					Host: h,
+					Port: &networking.PortSelector{Number: tlsPorts[0].Number},
+				}}},
+			})
+		}
+		name := egressGatewayNamePrefix + cfg.Name
+		vses = append(vses,
+			egressGatewayConfig(gvk.VirtualService, name, cfg.Namespace, cfg, meshRoute),
+			egressGatewayConfig(gvk.VirtualService, cfg.Namespace+"-"+name, gw.namespace, cfg, gatewayRoute))
+	}
+
+	refs := make([]egressGatewayRef, 0, len(gatewayHosts))
+	for gw := range gatewayHosts {
+		refs = append(refs, gw)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].gatewayName() < refs[j].gatewayName()
+	})
+	for _, gw := range refs {
+		hosts := gatewayHosts[gw]
+		sort.Strings(hosts)
+		cfg := egressGatewayConfig(gvk.Gateway, gw.generatedName(), gw.namespace, config.Config{}, &networking.Gateway{
+			Servers: []*networking.Server{{
+				Port: &networking.Port{
+					Number:   gw.port,
+					Protocol: string(protocol.TLS),
+					Name:     fmt.Sprintf("tls-%d", gw.port),
+				},
+				Hosts: hosts,
+				Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_PASSTHROUGH},
+			}},
+		})
+		cfg.Annotations = map[string]string{InternalGatewayServiceAnnotation: gw.hostname}
+		gateways = append(gateways, cfg)
+	}
+	return vses, gateways
+}
+
+func egressGatewayConfig(kind config.GroupVersionKind, name, namespace string, owner config.Config, spec config.Spec) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind:  kind,
+			Name:              name,
+			Namespace:         namespace,
+			Domain:            owner.Domain,
+			CreationTimestamp: owner.CreationTimestamp,
+		},
+		Spec: spec,
+	}
+}
+
+// meshVirtualServiceHosts returns the fully qualified hosts of the VirtualServices bound to the sidecars.
+func meshVirtualServiceHosts(virtualServices []config.Config) map[string]bool {
+	hosts := map[string]bool{}
+	for _, cfg := range virtualServices {
+		vs := cfg.Spec.(*networking.VirtualService)
+		mesh := len(vs.Gateways) == 0
+		for _, g := range vs.Gateways {
+			if g == constants.IstioMeshGateway {
+				mesh = true
+			}
+		}
+		if !mesh {
+			continue
+		}
+		for _, h := range vs.Hosts {
+			hosts[string(ResolveShortnameToFQDN(h, cfg.Meta))] = true
+		}
+	}
+	return hosts
+}
+
+// hasEgressGatewayRoutes returns whether any of the ServiceEntries has the gateway.EgressGatewayAnnotation.
+func hasEgressGatewayRoutes(serviceEntries []config.Config) bool {
+	for _, cfg := range serviceEntries {
+		if _, f := cfg.Annotations[gateway.EgressGatewayAnnotation]; f {
+			return true
+		}
+	}
+	return false
+}
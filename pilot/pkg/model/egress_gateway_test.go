@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestBuildEgressGatewayRoutes(t *testing.T) {
+	serviceEntry := func(name string, hosts []string, protocol string, annotation string) config.Config {
+		c := config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.ServiceEntry, Name: name, Namespace: "default"},
+			Spec: &networking.ServiceEntry{
+				Hosts:      hosts,
+				Ports:      []*networking.Port{{Number: 443, Name: "port", Protocol: protocol}},
+				Resolution: networking.ServiceEntry_DNS,
+			},
+		}
+		if annotation != "" {
+			c.Annotations = map[string]string{gateway.EgressGatewayAnnotation: annotation}
+		}
+		return c
+	}
+	egress := "istio-egressgateway.istio-system.svc.cluster.local:8443"
+	serviceEntries := []config.Config{
+		serviceEntry("tls", []string{"a.example.com", "b.example.com"}, "TLS", egress),
+		serviceEntry("http", []string{"c.example.com"}, "HTTP", egress),
+		serviceEntry("other", []string{"d.example.com"}, "HTTPS", ""),
+		serviceEntry("invalid", []string{"e.example.com"}, "HTTPS", "egress"),
+	}
+	userRoutes := []config.Config{{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "b", Namespace: "default"},
+		Spec: &networking.VirtualService{Hosts: []string{"b.example.com"}},
+	}}
+
+	vses, gateways := buildEgressGatewayRoutes(serviceEntries, userRoutes)
+	if len(vses) != 2 || len(gateways) != 1 {
+		t.Fatalf("expected 2 virtual services and 1 gateway, got %v and %v", vses, gateways)
+	}
+	mesh := vses[0].Spec.(*networking.VirtualService)
+	if len(mesh.Hosts) != 1 || mesh.Hosts[0] != "a.example.com" || mesh.Gateways[0] != "mesh" {
+		t.Fatalf("unexpected mesh virtual service %v", mesh)
+	}
+	if dst := mesh.Tls[0].Route[0].Destination; dst.Host != "istio-egressgateway.istio-system.svc.cluster.local" || dst.Port.Number != 8443 {
+		t.Fatalf("unexpected mesh destination %v", dst)
+	}
+	gw := vses[1]
+	if gw.Namespace != "istio-system" || gw.Spec.(*networking.VirtualService).Gateways[0] != "istio-system/auto-egress-istio-egressgateway-8443" {
+		t.Fatalf("unexpected gateway virtual service %v", gw)
+	}
+	g := gateways[0]
+	if g.Name != "auto-egress-istio-egressgateway-8443" || g.Namespace != "istio-system" ||
+		g.Annotations[InternalGatewayServiceAnnotation] != "istio-egressgateway.istio-system.svc.cluster.local" {
+		t.Fatalf("unexpected gateway %v", g)
+	}
+	if server := g.Spec.(*networking.Gateway).Servers[0]; server.Port.Number != 8443 || len(server.Hosts) != 1 ||
+		server.Tls.Mode != networking.ServerTLSSettings_PASSTHROUGH {
+		t.Fatalf("unexpected gateway server %v", server)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	extensions "istio.io/api/extensions/v1alpha1"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 )
 
@@ -44,6 +45,10 @@ type WasmPluginWrapper struct {
 	Name      string
 	Namespace string
 
+	// Anchor is the position of the plugin relative to another HTTP filter, set with the
+	// WasmPluginAnchorAnnotation. If nil, the plugin is positioned according to its phase.
+	Anchor *xds.WasmPluginAnchor
+
 	ExtensionConfiguration *envoyCoreV3.TypedExtensionConfig
 }
 
@@ -93,10 +98,19 @@ func convertToWasmPluginWrapper(plugin *config.Config) *WasmPluginWrapper {
 		Name:        plugin.Namespace + "." + plugin.Name,
 		TypedConfig: typedConfig,
 	}
+	var anchor *xds.WasmPluginAnchor
+	if v, f := plugin.Annotations[xds.WasmPluginAnchorAnnotation]; f {
+		if a, err := xds.ParseWasmPluginAnchor(v); err != nil {
+			log.Warnf("ignoring invalid anchor of wasmplugin %v/%v: %s", plugin.Namespace, plugin.Name, err)
+		} else {
+			anchor = &a
+		}
+	}
 	return &WasmPluginWrapper{
 		Name:                   plugin.Name,
 		Namespace:              plugin.Namespace,
 		WasmPlugin:             *wasmPlugin,
+		Anchor:                 anchor,
 		ExtensionConfiguration: ec,
 	}
 }
@@ -71,6 +71,14 @@ type MergedGateway struct {
 	// This mapping is used to generate alt-svc header that is needed for HTTP/3 server discovery.
 	HTTP3AdvertisingRoutes map[string]struct{}
 
+	// DownstreamProtocols maps from server to the protocols set with the gateway.DownstreamProtocolsAnnotation of its
+	// gateway, if any.
+	DownstreamProtocols map[*networking.Server]*gateway.DownstreamProtocols
+
+	// ClientTrust maps from ISTIO_MUTUAL server to the trust of its clients set with the gateway.ClientTrustAnnotation
+	// of its gateway, if any.
+	ClientTrust map[*networking.Server]*gateway.ClientTrust
+
 	// GatewayNameForServer maps from server to the owning gateway name.
 	// Used for select the set of virtual services that apply to a port.
 	GatewayNameForServer map[*networking.Server]string
@@ -140,6 +148,8 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 	serversByRouteName := make(map[string][]*networking.Server)
 	tlsServerInfo := make(map[*networking.Server]*TLSServerInfo)
 	gatewayNameForServer := make(map[*networking.Server]string)
+	downstreamProtocols := make(map[*networking.Server]*gateway.DownstreamProtocols)
+	clientTrust := make(map[*networking.Server]*gateway.ClientTrust)
 	verifiedCertificateReferences := sets.NewSet()
 	http3AdvertisingRoutes := make(map[string]struct{})
 	tlsHostsByPort := map[uint32]sets.Set{} // port -> host set
@@ -151,6 +161,20 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 		gatewayName := gatewayConfig.Namespace + "/" + gatewayConfig.Name // Format: %s/%s
 		gatewayCfg := gatewayConfig.Spec.(*networking.Gateway)
 		log.Debugf("MergeGateways: merging gateway %q :\n%v", gatewayName, gatewayCfg)
+		var serverProtocols map[string]*gateway.DownstreamProtocols
+		if value, f := gatewayConfig.Annotations[gateway.DownstreamProtocolsAnnotation]; f {
+			var err error
+			if serverProtocols, err = gateway.ParseDownstreamProtocolsAnnotation(value); err != nil {
+				log.Warnf("ignoring invalid %s annotation of Gateway %s: %v", gateway.DownstreamProtocolsAnnotation, gatewayName, err)
+			}
+		}
+		var serverTrust map[string]*gateway.ClientTrust
+		if value, f := gatewayConfig.Annotations[gateway.ClientTrustAnnotation]; f {
+			var err error
+			if serverTrust, err = gateway.ParseClientTrustAnnotation(value); err != nil {
+				log.Warnf("ignoring invalid %s annotation of Gateway %s: %v", gateway.ClientTrustAnnotation, gatewayName, err)
+			}
+		}
 		snames := sets.Set{}
 		for _, s := range gatewayCfg.Servers {
 			if len(s.Name) > 0 {
@@ -169,6 +193,12 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 			}
 			sanitizeServerHostNamespace(s, gatewayConfig.Namespace)
 			gatewayNameForServer[s] = gatewayName
+			if p := serverProtocols[s.Name]; p != nil && s.Name != "" {
+				downstreamProtocols[s] = p
+			}
+			if t := serverTrust[s.Name]; t != nil && s.Name != "" && s.GetTls().GetMode() == networking.ServerTLSSettings_ISTIO_MUTUAL {
+				clientTrust[s] = t
+			}
 			log.Debugf("MergeGateways: gateway %q processing server %s :%v", gatewayName, s.Name, s.Hosts)
 
 			cn := s.GetTls().GetCredentialName()
@@ -280,7 +310,7 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 						// We have TLS settings defined and we have already taken care of unique route names
 						// if it is HTTPS. So we can construct a QUIC server on the same port. It is okay as
 						// QUIC listens on UDP port, not TCP
-						if features.EnableQUICListeners && gateway.IsEligibleForHTTP3Upgrade(s) &&
+						if http3Enabled(s, downstreamProtocols[s]) &&
 							udpSupportedPort(s.GetPort().GetNumber(), gwAndInstance.instances) {
 							log.Debugf("Server at port %d eligible for HTTP3 upgrade. Add UDP listener for QUIC", serverPort.Number)
 							if mergedQUICServers[serverPort] == nil {
@@ -299,7 +329,7 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 					if gateway.IsHTTPServer(s) {
 						serversByRouteName[routeName] = []*networking.Server{s}
 
-						if features.EnableQUICListeners && gateway.IsEligibleForHTTP3Upgrade(s) &&
+						if http3Enabled(s, downstreamProtocols[s]) &&
 							udpSupportedPort(s.GetPort().GetNumber(), gwAndInstance.instances) {
 							log.Debugf("Server at port %d eligible for HTTP3 upgrade. So QUIC listener will be added", serverPort.Number)
 							http3AdvertisingRoutes[routeName] = struct{}{}
@@ -326,6 +356,8 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 		MergedQUICTransportServers:      mergedQUICServers,
 		ServerPorts:                     serverPorts,
 		GatewayNameForServer:            gatewayNameForServer,
+		DownstreamProtocols:             downstreamProtocols,
+		ClientTrust:                     clientTrust,
 		TLSServerInfo:                   tlsServerInfo,
 		ServersByRouteName:              serversByRouteName,
 		HTTP3AdvertisingRoutes:          http3AdvertisingRoutes,
@@ -335,6 +367,15 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 	}
 }
 
+// http3Enabled returns true if the server is served over QUIC, as set by its protocols or by default.
+func http3Enabled(server *networking.Server, protocols *gateway.DownstreamProtocols) bool {
+	enabled := features.EnableQUICListeners
+	if protocols != nil && protocols.HTTP3 != nil {
+		enabled = *protocols.HTTP3
+	}
+	return enabled && gateway.IsHTTP3CapableServer(server)
+}
+
 func udpSupportedPort(number uint32, instances []*ServiceInstance) bool {
 	for _, w := range instances {
 		if int(number) == w.ServicePort.Port && w.ServicePort.Protocol == protocol.UDP {
@@ -20,6 +20,8 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/protocol"
 )
 
 // nolint lll
@@ -160,6 +162,78 @@ func TestMergeGateways(t *testing.T) {
 	}
 }
 
+func TestMergeGatewaysDownstreamProtocols(t *testing.T) {
+	gw := makeConfig("foo-simple", "not-default", "*.example.com", "https", "HTTPS", 443, "ingressgateway", "", networking.ServerTLSSettings_SIMPLE)
+	server := gw.Spec.(*networking.Gateway).Servers[0]
+	server.Name = "https"
+	instances := []*ServiceInstance{{
+		Service:     &Service{},
+		ServicePort: &Port{Port: 443, Protocol: protocol.UDP},
+		Endpoint:    &IstioEndpoint{EndpointPort: 443},
+	}}
+
+	cases := []struct {
+		name       string
+		annotation string
+		http2      gateway.HTTP2Mode
+		http3      bool
+	}{
+		{name: "no annotation"},
+		{name: "http3 enabled", annotation: `{"https": {"http2": "only", "http3": true}}`, http2: gateway.HTTP2Only, http3: true},
+		{name: "other server", annotation: `{"other": {"http3": true}}`},
+		{name: "invalid annotation", annotation: `{"https": {"http2": "sometimes", "http3": true}}`},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gw.DeepCopy()
+			if tt.annotation != "" {
+				c.Annotations = map[string]string{gateway.DownstreamProtocolsAnnotation: tt.annotation}
+			}
+			s := c.Spec.(*networking.Gateway).Servers[0]
+			mgw := MergeGateways([]gatewayWithInstances{{c, true, instances}}, &Proxy{}, nil)
+			var http2 gateway.HTTP2Mode
+			if p := mgw.DownstreamProtocols[s]; p != nil {
+				http2 = p.HTTP2
+			}
+			if http2 != tt.http2 {
+				t.Errorf("got http2 mode %q, want %q", http2, tt.http2)
+			}
+			if got := len(mgw.MergedQUICTransportServers) > 0; got != tt.http3 {
+				t.Errorf("got QUIC servers %v, want %v", got, tt.http3)
+			}
+			if _, got := mgw.HTTP3AdvertisingRoutes["https.443.https.foo-simple.not-default"]; got != tt.http3 {
+				t.Errorf("got HTTP/3 advertisement %v, want %v", got, tt.http3)
+			}
+		})
+	}
+}
+
+func TestMergeGatewaysClientTrust(t *testing.T) {
+	annotation := `{"partner": {"trustDomains": ["partner.example.com"], "caCredentialName": "partner-ca"}}`
+	cases := []struct {
+		name       string
+		mode       networking.ServerTLSSettings_TLSmode
+		annotation string
+		trusted    bool
+	}{
+		{name: "istio mutual", mode: networking.ServerTLSSettings_ISTIO_MUTUAL, annotation: annotation, trusted: true},
+		{name: "simple", mode: networking.ServerTLSSettings_SIMPLE, annotation: annotation},
+		{name: "missing trust domains", mode: networking.ServerTLSSettings_ISTIO_MUTUAL, annotation: `{"partner": {}}`},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := makeConfig("east-west", "istio-system", "*.local", "tls", "HTTPS", 15443, "eastwestgateway", "", tt.mode)
+			c.Annotations = map[string]string{gateway.ClientTrustAnnotation: tt.annotation}
+			s := c.Spec.(*networking.Gateway).Servers[0]
+			s.Name = "partner"
+			mgw := MergeGateways([]gatewayWithInstances{{c, true, nil}}, &Proxy{}, nil)
+			if got := mgw.ClientTrust[s] != nil; got != tt.trusted {
+				t.Errorf("got client trust %v, want %v", got, tt.trusted)
+			}
+		})
+	}
+}
+
 func makeConfig(name, namespace, host, portName, portProtocol string, portNumber uint32, gw string, bind string,
 	mode networking.ServerTLSSettings_TLSmode) config.Config {
 	c := config.Config{
@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/api/annotation"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// InboundCaptureMode describes how traffic to an inbound port of a proxy reaches it.
+type InboundCaptureMode string
+
+const (
+	// InboundCaptureRedirect means the traffic is redirected to the virtual inbound listener by iptables.
+	InboundCaptureRedirect InboundCaptureMode = "Redirect"
+	// InboundCaptureBind means the proxy binds the port itself.
+	InboundCaptureBind InboundCaptureMode = "Bind"
+	// InboundCaptureNone means the traffic is not captured, and goes directly to the application.
+	InboundCaptureNone InboundCaptureMode = "None"
+)
+
+// InboundPortSource is the configuration which decided the protocol or capture mode of an inbound port.
+type InboundPortSource string
+
+const (
+	InboundPortSourceSidecar             InboundPortSource = "Sidecar"
+	InboundPortSourceService             InboundPortSource = "Service"
+	InboundPortSourceProtocolSniffing    InboundPortSource = "ProtocolSniffing"
+	InboundPortSourcePassthrough         InboundPortSource = "Passthrough"
+	InboundPortSourceInterceptionMode    InboundPortSource = "InterceptionMode"
+	InboundPortSourceExcludeInboundPorts InboundPortSource = "ExcludeInboundPorts"
+	InboundPortSourceIncludeInboundPorts InboundPortSource = "IncludeInboundPorts"
+	InboundPortSourceDefault             InboundPortSource = "Default"
+)
+
+// InboundPortResolution describes how a proxy handles the traffic to one of its inbound ports, and which
+// configuration decided it.
+//
+// The protocol is decided, from highest to lowest precedence, by:
+//  1. the port of a Sidecar ingress listener. When the Sidecar has ingress listeners, the ports of the services
+//     are not used, and their traffic is handled by the inbound passthrough filter chain;
+//  2. the service port, from its appProtocol, its name or its well-known number;
+//  3. protocol sniffing when the service port protocol is unknown, or TCP if inbound sniffing is disabled.
+//
+// The capture mode is decided, from highest to lowest precedence, by:
+//  1. the NONE interception mode of the proxy, in which case the proxy binds all its ingress listener ports;
+//  2. the NONE capture mode of a Sidecar ingress listener, in which case the proxy binds the port;
+//  3. the traffic.sidecar.istio.io/excludeInboundPorts and includeInboundPorts annotations, which make
+//     iptables skip the port;
+//  4. the default iptables redirection.
+type InboundPortResolution struct {
+	Port           int                `json:"port"`
+	Protocol       protocol.Instance  `json:"protocol,omitempty"`
+	ProtocolSource InboundPortSource  `json:"protocolSource"`
+	CaptureMode    InboundCaptureMode `json:"captureMode"`
+	CaptureSource  InboundPortSource  `json:"captureSource"`
+	// Service is the hostname of the service whose port decided the protocol, if any.
+	Service host.Name `json:"service,omitempty"`
+	// Ignored describes the lower precedence configuration of the port which was ignored.
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// ResolveInboundPorts returns how the proxy handles the traffic to each of its inbound ports, sorted by port.
+func ResolveInboundPorts(node *Proxy) []*InboundPortResolution {
+	ports := map[int]*InboundPortResolution{}
+	for _, instance := range node.ServiceInstances {
+		if instance.Endpoint == nil || instance.ServicePort == nil || instance.Service == nil {
+			continue
+		}
+		port := int(instance.Endpoint.EndpointPort)
+		proto := instance.ServicePort.Protocol
+		if r, f := ports[port]; f {
+			if r.Protocol != proto {
+				r.Ignored = append(r.Ignored, fmt.Sprintf("protocol %s of service %s", proto, instance.Service.Hostname))
+			}
+			continue
+		}
+		r := &InboundPortResolution{
+			Port:           port,
+			Protocol:       proto,
+			ProtocolSource: InboundPortSourceService,
+			Service:        instance.Service.Hostname,
+		}
+		if proto.IsUnsupported() {
+			r.ProtocolSource = InboundPortSourceProtocolSniffing
+			if !features.EnableProtocolSniffingForInbound {
+				r.Protocol = protocol.TCP
+				r.ProtocolSource = InboundPortSourceDefault
+			}
+		}
+		ports[port] = r
+	}
+
+	var ingress []*networking.IstioIngressListener
+	if node.SidecarScope.HasIngressListener() {
+		ingress = node.SidecarScope.Sidecar.Ingress
+		// The service ports are not used when the Sidecar defines the ingress listeners.
+		for _, r := range ports {
+			r.Ignored = append(r.Ignored, fmt.Sprintf("protocol %s of service %s", r.Protocol, r.Service))
+			r.Protocol = ""
+			r.ProtocolSource = InboundPortSourcePassthrough
+			r.Service = ""
+		}
+	}
+	captureModes := map[int]networking.CaptureMode{}
+	for _, il := range ingress {
+		port := int(il.GetPort().GetNumber())
+		proto := protocol.Parse(il.GetPort().GetProtocol())
+		r, f := ports[port]
+		if !f {
+			r = &InboundPortResolution{Port: port}
+			ports[port] = r
+		}
+		if r.ProtocolSource == InboundPortSourceSidecar {
+			r.Ignored = append(r.Ignored, fmt.Sprintf("protocol %s of a Sidecar ingress listener", proto))
+			continue
+		}
+		r.Protocol = proto
+		r.ProtocolSource = InboundPortSourceSidecar
+		captureModes[port] = il.CaptureMode
+	}
+
+	out := make([]*InboundPortResolution, 0, len(ports))
+	for port, r := range ports {
+		r.CaptureMode, r.CaptureSource = resolveInboundCapture(node, port, captureModes[port], len(ingress) > 0)
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Port < out[j].Port
+	})
+	return out
+}
+
+// IsInboundPortCaptured returns true if traffic to the inbound port is redirected to the proxy by iptables.
+// It does not account for ports set up by Sidecar ingress listeners.
+func (node *Proxy) IsInboundPortCaptured(port int) bool {
+	mode, _ := resolveInboundCapture(node, port, networking.CaptureMode_DEFAULT, false)
+	return mode == InboundCaptureRedirect
+}
+
+func resolveInboundCapture(node *Proxy, port int, mode networking.CaptureMode, hasIngress bool) (InboundCaptureMode, InboundPortSource) {
+	if node.GetInterceptionMode() == InterceptionNone {
+		if hasIngress {
+			return InboundCaptureBind, InboundPortSourceInterceptionMode
+		}
+		return InboundCaptureNone, InboundPortSourceInterceptionMode
+	}
+	if mode == networking.CaptureMode_NONE {
+		return InboundCaptureBind, InboundPortSourceSidecar
+	}
+	var annotations map[string]string
+	if node.Metadata != nil {
+		annotations = node.Metadata.Annotations
+	}
+	if excluded, f := annotations[annotation.SidecarTrafficExcludeInboundPorts.Name]; f && portListContains(excluded, port) {
+		return InboundCaptureNone, InboundPortSourceExcludeInboundPorts
+	}
+	if included, f := annotations[annotation.SidecarTrafficIncludeInboundPorts.Name]; f && !portListContains(included, port) {
+		return InboundCaptureNone, InboundPortSourceIncludeInboundPorts
+	}
+	return InboundCaptureRedirect, InboundPortSourceDefault
+}
+
+// portListContains returns true if the comma separated list of ports contains the port or the "*" wildcard.
+func portListContains(ports string, port int) bool {
+	for _, p := range strings.Split(ports, ",") {
+		p = strings.TrimSpace(p)
+		if p == "*" || p == strconv.Itoa(port) {
+			return true
+		}
+	}
+	return false
+}
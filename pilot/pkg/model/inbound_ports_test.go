@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"testing"
+
+	"istio.io/api/annotation"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestResolveInboundPorts(t *testing.T) {
+	instance := func(hostname string, port int, proto protocol.Instance) *model.ServiceInstance {
+		return &model.ServiceInstance{
+			Service:     &model.Service{Hostname: host.Name(hostname)},
+			ServicePort: &model.Port{Name: "port", Port: port, Protocol: proto},
+			Endpoint:    &model.IstioEndpoint{EndpointPort: uint32(port)},
+		}
+	}
+	sidecar := func(ingress ...*networking.IstioIngressListener) *model.SidecarScope {
+		return &model.SidecarScope{Sidecar: &networking.Sidecar{Ingress: ingress}}
+	}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		mode        model.TrafficInterceptionMode
+		instances   []*model.ServiceInstance
+		sidecar     *model.SidecarScope
+		want        []*model.InboundPortResolution
+	}{
+		{
+			name:      "service ports",
+			instances: []*model.ServiceInstance{instance("a.com", 8080, protocol.HTTP), instance("b.com", 9090, protocol.Unsupported)},
+			want: []*model.InboundPortResolution{
+				{
+					Port: 8080, Protocol: protocol.HTTP, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+				},
+				{
+					Port: 9090, Protocol: protocol.Unsupported, ProtocolSource: model.InboundPortSourceProtocolSniffing, Service: "b.com",
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+				},
+			},
+		},
+		{
+			name:      "conflicting service ports",
+			instances: []*model.ServiceInstance{instance("a.com", 8080, protocol.HTTP), instance("b.com", 8080, protocol.TCP)},
+			want: []*model.InboundPortResolution{
+				{
+					Port: 8080, Protocol: protocol.HTTP, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+					Ignored: []string{"protocol TCP of service b.com"},
+				},
+			},
+		},
+		{
+			name:        "excluded port",
+			annotations: map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "9090, 9091"},
+			instances:   []*model.ServiceInstance{instance("a.com", 8080, protocol.HTTP), instance("a.com", 9090, protocol.GRPC)},
+			want: []*model.InboundPortResolution{
+				{
+					Port: 8080, Protocol: protocol.HTTP, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+				},
+				{
+					Port: 9090, Protocol: protocol.GRPC, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureNone, CaptureSource: model.InboundPortSourceExcludeInboundPorts,
+				},
+			},
+		},
+		{
+			name:        "included ports",
+			annotations: map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "8080"},
+			instances:   []*model.ServiceInstance{instance("a.com", 8080, protocol.HTTP), instance("a.com", 9090, protocol.GRPC)},
+			want: []*model.InboundPortResolution{
+				{
+					Port: 8080, Protocol: protocol.HTTP, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+				},
+				{
+					Port: 9090, Protocol: protocol.GRPC, ProtocolSource: model.InboundPortSourceService, Service: "a.com",
+					CaptureMode: model.InboundCaptureNone, CaptureSource: model.InboundPortSourceIncludeInboundPorts,
+				},
+			},
+		},
+		{
+			name:      "sidecar ingress overrides services",
+			instances: []*model.ServiceInstance{instance("a.com", 8080, protocol.HTTP)},
+			sidecar: sidecar(
+				&networking.IstioIngressListener{Port: &networking.Port{Number: 9090, Protocol: "GRPC", Name: "grpc"}},
+				&networking.IstioIngressListener{
+					Port:        &networking.Port{Number: 9091, Protocol: "TCP", Name: "tcp"},
+					CaptureMode: networking.CaptureMode_NONE,
+				},
+			),
+			want: []*model.InboundPortResolution{
+				{
+					Port: 8080, ProtocolSource: model.InboundPortSourcePassthrough,
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+					Ignored: []string{"protocol HTTP of service a.com"},
+				},
+				{
+					Port: 9090, Protocol: protocol.GRPC, ProtocolSource: model.InboundPortSourceSidecar,
+					CaptureMode: model.InboundCaptureRedirect, CaptureSource: model.InboundPortSourceDefault,
+				},
+				{
+					Port: 9091, Protocol: protocol.TCP, ProtocolSource: model.InboundPortSourceSidecar,
+					CaptureMode: model.InboundCaptureBind, CaptureSource: model.InboundPortSourceSidecar,
+				},
+			},
+		},
+		{
+			name: "interception mode none",
+			mode: model.InterceptionNone,
+			sidecar: sidecar(
+				&networking.IstioIngressListener{Port: &networking.Port{Number: 9090, Protocol: "HTTP", Name: "http"}},
+			),
+			want: []*model.InboundPortResolution{
+				{
+					Port: 9090, Protocol: protocol.HTTP, ProtocolSource: model.InboundPortSourceSidecar,
+					CaptureMode: model.InboundCaptureBind, CaptureSource: model.InboundPortSourceInterceptionMode,
+				},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &model.Proxy{
+				Metadata:         &model.NodeMetadata{Annotations: tt.annotations, InterceptionMode: tt.mode},
+				ServiceInstances: tt.instances,
+				SidecarScope:     tt.sidecar,
+			}
+			assert.Equal(t, model.ResolveInboundPorts(proxy), tt.want)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+// LocalitiesAnnotation can be set on a VirtualService or a DestinationRule to restrict it to the proxies in the given
+// localities, for example to roll out a change region by region. The value is a comma separated list of localities of
+// the form region[/zone[/subzone]], matching the proxies in it, or excluding them when prefixed with "!", for example
+// "us-east1" or "!us-east1,!us-west1/us-west1-a".
+//
+// A proxy only applies a pinned config if its locality is matched by the entries, and not excluded, and pinned configs
+// take precedence over the configs without the annotation for the same hosts. Canarying a change in a region is then done by pinning
+// the new config to the region, and the previous config, if it has to be kept apart, to the other regions with "!".
+// Proxies of unknown locality ignore the pinned configs.
+const LocalitiesAnnotation = "networking.istio.io/localities"
+
+// localityPinned returns true if the config is restricted to some localities.
+func localityPinned(cfg *config.Config) bool {
+	_, f := cfg.Annotations[LocalitiesAnnotation]
+	return f
+}
+
+// appliesToLocality returns true if the config applies to the proxy, that is if it is not pinned to localities, or is
+// pinned to the locality of the proxy.
+func appliesToLocality(cfg *config.Config, proxy *Proxy) bool {
+	value, f := cfg.Annotations[LocalitiesAnnotation]
+	if !f {
+		return true
+	}
+	if proxy == nil || proxy.Locality == nil || proxy.Locality.Region == "" {
+		return false
+	}
+	locality := []string{proxy.Locality.Region, proxy.Locality.Zone, proxy.Locality.SubZone}
+	included, hasIncludes := false, false
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if excluded := strings.TrimPrefix(entry, "!"); excluded != entry {
+			if excluded != "" && localityContains(strings.Split(excluded, "/"), locality) {
+				return false
+			}
+			continue
+		}
+		if entry != "" {
+			hasIncludes = true
+			included = included || localityContains(strings.Split(entry, "/"), locality)
+		}
+	}
+	return included || !hasIncludes
+}
+
+// localityContains returns true if the locality is in the region, zone or subzone of the parts.
+func localityContains(parts []string, locality []string) bool {
+	if len(parts) > len(locality) {
+		return false
+	}
+	for i, part := range parts {
+		if part != locality[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalityPinnedConfigs returns the configs applying to the locality of the proxy, the ones pinned to its locality
+// first so that they take precedence over the others for the same hosts.
+func LocalityPinnedConfigs(proxy *Proxy, configs []config.Config) []config.Config {
+	pinned := false
+	for i := range configs {
+		if localityPinned(&configs[i]) {
+			pinned = true
+			break
+		}
+	}
+	if !pinned {
+		return configs
+	}
+	out := make([]config.Config, 0, len(configs))
+	for i := range configs {
+		if localityPinned(&configs[i]) && appliesToLocality(&configs[i], proxy) {
+			out = append(out, configs[i])
+		}
+	}
+	for i := range configs {
+		if !localityPinned(&configs[i]) {
+			out = append(out, configs[i])
+		}
+	}
+	return out
+}
+
+// localityPinnedDestinationRule returns the oldest destination rule pinned to the locality of the proxy for the
+// service, if any. Pinned destination rules are expected to be few and short-lived, so they are not indexed.
+func (ps *PushContext) localityPinnedDestinationRule(proxy *Proxy, service *Service) *config.Config {
+	if proxy.Locality == nil {
+		return nil
+	}
+	for i := range ps.destinationRuleIndex.localityPinned {
+		cfg := &ps.destinationRuleIndex.localityPinned[i]
+		rule := cfg.Spec.(*networking.DestinationRule)
+		if !service.Hostname.SubsetOf(host.Name(rule.Host)) || !ps.destinationRuleVisible(cfg, proxy.ConfigNamespace) {
+			continue
+		}
+		if appliesToLocality(cfg, proxy) {
+			return cfg
+		}
+	}
+	return nil
+}
+
+// destinationRuleVisible returns true if the destination rule is exported to the namespace.
+func (ps *PushContext) destinationRuleVisible(cfg *config.Config, namespace string) bool {
+	exportTo := cfg.Spec.(*networking.DestinationRule).ExportTo
+	if len(exportTo) == 0 {
+		return cfg.Namespace == namespace || !ps.exportToDefaults.destinationRule[visibility.Private]
+	}
+	for _, e := range exportTo {
+		switch visibility.Instance(e) {
+		case visibility.Public:
+			return true
+		case visibility.Private:
+			if cfg.Namespace == namespace {
+				return true
+			}
+		default:
+			if e == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addLocalityPinnedDependencies adds the dest rules pinned to localities to the dependencies of the sidecar scope, as
+// they are resolved for each proxy rather than for the scope.
+func (sc *SidecarScope) addLocalityPinnedDependencies(ps *PushContext) {
+	for _, dr := range ps.destinationRuleIndex.localityPinned {
+		sc.AddConfigDependencies(ConfigKey{
+			Kind:      gvk.DestinationRule,
+			Name:      dr.Name,
+			Namespace: dr.Namespace,
+		})
+	}
+}
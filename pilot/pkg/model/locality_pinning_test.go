@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+func pinnedConfig(name string, localities string) config.Config {
+	cfg := config.Config{
+		Meta: config.Meta{Name: name, Namespace: "test"},
+		Spec: &networking.DestinationRule{Host: "reviews.test.svc.cluster.local"},
+	}
+	if localities != "" {
+		cfg.Annotations = map[string]string{LocalitiesAnnotation: localities}
+	}
+	return cfg
+}
+
+func proxyInLocality(region, zone string) *Proxy {
+	return &Proxy{ConfigNamespace: "test", Locality: &core.Locality{Region: region, Zone: zone}}
+}
+
+func TestAppliesToLocality(t *testing.T) {
+	cases := []struct {
+		name       string
+		localities string
+		proxy      *Proxy
+		want       bool
+	}{
+		{name: "not pinned", proxy: &Proxy{}, want: true},
+		{name: "unknown locality", localities: "us-east1", proxy: &Proxy{}, want: false},
+		{name: "region", localities: "us-east1", proxy: proxyInLocality("us-east1", "us-east1-b"), want: true},
+		{name: "other region", localities: "us-east1", proxy: proxyInLocality("us-west1", "us-west1-a"), want: false},
+		{name: "zone", localities: "us-west1, us-east1/us-east1-b", proxy: proxyInLocality("us-east1", "us-east1-b"), want: true},
+		{name: "other zone", localities: "us-east1/us-east1-c", proxy: proxyInLocality("us-east1", "us-east1-b"), want: false},
+		{name: "excluded", localities: "!us-east1", proxy: proxyInLocality("us-east1", "us-east1-b"), want: false},
+		{name: "not excluded", localities: "!us-east1,!us-west1/us-west1-a", proxy: proxyInLocality("us-west1", "us-west1-b"), want: true},
+		{name: "excluded zone", localities: "us-west1,!us-west1/us-west1-a", proxy: proxyInLocality("us-west1", "us-west1-a"), want: false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := pinnedConfig("reviews", tt.localities)
+			if got := appliesToLocality(&cfg, tt.proxy); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalityPinnedConfigs(t *testing.T) {
+	configs := []config.Config{pinnedConfig("stable", ""), pinnedConfig("east", "us-east1"), pinnedConfig("west", "us-west1")}
+	got := LocalityPinnedConfigs(proxyInLocality("us-east1", "us-east1-b"), configs)
+	if len(got) != 2 || got[0].Name != "east" || got[1].Name != "stable" {
+		t.Errorf("got %v, want the east config before the stable config", got)
+	}
+	if got := LocalityPinnedConfigs(&Proxy{}, configs); len(got) != 1 || got[0].Name != "stable" {
+		t.Errorf("got %v for a proxy of unknown locality, want the stable config", got)
+	}
+}
+
+func TestLocalityPinnedDestinationRule(t *testing.T) {
+	ps := NewPushContext()
+	ps.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
+	ps.exportToDefaults.destinationRule = map[visibility.Instance]bool{visibility.Public: true}
+	stable := pinnedConfig("stable", "")
+	canary := pinnedConfig("canary", "us-east1")
+	canary.CreationTimestamp = time.Now()
+	ps.SetDestinationRules([]config.Config{stable, canary})
+
+	service := &Service{Hostname: host.Name("reviews.test.svc.cluster.local"), Attributes: ServiceAttributes{Namespace: "test"}}
+	for _, tt := range []struct {
+		name  string
+		proxy *Proxy
+		want  string
+	}{
+		{name: "pinned locality", proxy: proxyInLocality("us-east1", "us-east1-b"), want: "canary"},
+		{name: "other locality", proxy: proxyInLocality("us-west1", "us-west1-a"), want: "stable"},
+		{name: "unknown locality", proxy: &Proxy{ConfigNamespace: "test"}, want: "stable"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ps.DestinationRule(tt.proxy, service); got == nil || got.Name != tt.want {
+				t.Errorf("got destination rule %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
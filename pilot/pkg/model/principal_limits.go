@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InboundPrincipalConcurrencyAnnotation is the annotation of workloads isolating the inbound HTTP requests of their
+// clients, so that a noisy client cannot starve the others. Its value is a comma separated list of principal=limit,
+// where principal is the mTLS identity of a client, in the format of the principals of AuthorizationPolicies with an
+// optional leading or trailing *, and limit the number of concurrent requests of the client the sidecar forwards to
+// the workload, for example "cluster.local/ns/batch/sa/reporter=10,cluster.local/ns/web/sa/*=100". The * principal
+// sets the limit shared by all the other requests, including the plaintext ones; they are not limited if it is not
+// set. The requests above the limits are rejected with 503.
+const InboundPrincipalConcurrencyAnnotation = "proxy.istio.io/inboundPrincipalConcurrency"
+
+// PrincipalConcurrencyLimit is the limit of the concurrent requests of a principal.
+type PrincipalConcurrencyLimit struct {
+	Principal string
+	Requests  uint32
+}
+
+// InboundPrincipalConcurrency are the limits of the InboundPrincipalConcurrencyAnnotation.
+type InboundPrincipalConcurrency struct {
+	// Principals are the limits of the principals, sorted by principal.
+	Principals []PrincipalConcurrencyLimit
+	// Others is the limit shared by the requests of the other clients, or 0 if they are not limited.
+	Others uint32
+}
+
+// ParseInboundPrincipalConcurrency parses the value of the InboundPrincipalConcurrencyAnnotation.
+func ParseInboundPrincipalConcurrency(v string) (*InboundPrincipalConcurrency, error) {
+	limits := &InboundPrincipalConcurrency{}
+	seen := map[string]struct{}{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idx := strings.LastIndex(entry, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid entry %q, expected principal=limit", entry)
+		}
+		principal := strings.TrimSpace(entry[:idx])
+		if principal == "" {
+			return nil, fmt.Errorf("invalid entry %q, the principal is empty", entry)
+		}
+		if principal != "*" && strings.Contains(strings.TrimSuffix(strings.TrimPrefix(principal, "*"), "*"), "*") {
+			return nil, fmt.Errorf("invalid principal %q, * is only allowed at the start or the end", principal)
+		}
+		if strings.ContainsAny(principal, ";\"") {
+			return nil, fmt.Errorf("invalid principal %q", principal)
+		}
+		if _, f := seen[principal]; f {
+			return nil, fmt.Errorf("duplicate principal %q", principal)
+		}
+		seen[principal] = struct{}{}
+		requests, err := strconv.ParseUint(strings.TrimSpace(entry[idx+1:]), 10, 32)
+		if err != nil || requests == 0 {
+			return nil, fmt.Errorf("invalid limit of principal %q, expected a positive number of concurrent requests", principal)
+		}
+		if principal == "*" {
+			limits.Others = uint32(requests)
+			continue
+		}
+		limits.Principals = append(limits.Principals, PrincipalConcurrencyLimit{Principal: principal, Requests: uint32(requests)})
+	}
+	if len(limits.Principals) == 0 && limits.Others == 0 {
+		return nil, fmt.Errorf("no limit is set")
+	}
+	sort.Slice(limits.Principals, func(i, j int) bool {
+		return limits.Principals[i].Principal < limits.Principals[j].Principal
+	})
+	return limits, nil
+}
@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInboundPrincipalConcurrency(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *InboundPrincipalConcurrency
+		wantErr bool
+	}{
+		{
+			value: "cluster.local/ns/web/sa/frontend=500, cluster.local/ns/batch/sa/reporter=50",
+			want: &InboundPrincipalConcurrency{Principals: []PrincipalConcurrencyLimit{
+				{Principal: "cluster.local/ns/batch/sa/reporter", Requests: 50},
+				{Principal: "cluster.local/ns/web/sa/frontend", Requests: 500},
+			}},
+		},
+		{
+			value: "cluster.local/ns/batch/sa/*=50,*=100",
+			want: &InboundPrincipalConcurrency{
+				Principals: []PrincipalConcurrencyLimit{{Principal: "cluster.local/ns/batch/sa/*", Requests: 50}},
+				Others:     100,
+			},
+		},
+		{value: "*=100", want: &InboundPrincipalConcurrency{Others: 100}},
+		{value: "", wantErr: true},
+		{value: "cluster.local/ns/batch/sa/reporter", wantErr: true},
+		{value: "=50", wantErr: true},
+		{value: "cluster.local/ns/*/sa/reporter=50", wantErr: true},
+		{value: "cluster.local/ns/batch/sa/reporter;URI=x=50", wantErr: true},
+		{value: "cluster.local/ns/batch/sa/reporter=0", wantErr: true},
+		{value: "cluster.local/ns/batch/sa/reporter=fast", wantErr: true},
+		{value: "cluster.local/ns/batch/sa/reporter=50,cluster.local/ns/batch/sa/reporter=60", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseInboundPrincipalConcurrency(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 	istiolog "istio.io/pkg/log"
 )
@@ -36,6 +37,19 @@ type ProxyConfigs struct {
 
 	// root namespace
 	rootNamespace string
+
+	// securityHeaders holds the security.SecurityHeadersAnnotation values of the ProxyConfig resources.
+	securityHeaders map[*v1beta1.ProxyConfig]string
+
+	// names holds the namespace/name of the ProxyConfig resources.
+	names map[*v1beta1.ProxyConfig]string
+}
+
+// ProxyConfigSource is a ProxyConfig merged into the effective ProxyConfig of a proxy, along with where it comes from.
+type ProxyConfigSource struct {
+	// Source is "ProxyConfig <namespace>/<name>" for ProxyConfig resources, or the proxy.istio.io/config annotation.
+	Source string
+	Config *meshconfig.ProxyConfig
 }
 
 // EffectiveProxyConfig generates the correct merged ProxyConfig for a given ProxyConfigTarget.
@@ -73,10 +87,40 @@ func (p *ProxyConfigs) EffectiveProxyConfig(meta *NodeMetadata, mc *meshconfig.M
 	return effectiveProxyConfig
 }
 
+// EffectiveProxyConfigSources returns the ProxyConfigs merged by EffectiveProxyConfig on top of the default config of
+// the mesh config, highest precedence first.
+func (p *ProxyConfigs) EffectiveProxyConfigSources(meta *NodeMetadata) []ProxyConfigSource {
+	if p == nil || meta == nil {
+		return nil
+	}
+	var out []ProxyConfigSource
+	if pc := p.workloadConfig(meta.Namespace, meta.Labels); pc != nil {
+		out = append(out, ProxyConfigSource{Source: "ProxyConfig " + p.names[pc], Config: toMeshConfigProxyConfig(pc)})
+	}
+	if v, ok := meta.Annotations[annotation.ProxyConfig.Name]; ok {
+		if pca, err := proxyConfigFromAnnotation(v); err == nil {
+			out = append(out, ProxyConfigSource{Source: annotation.ProxyConfig.Name + " annotation", Config: pca})
+		}
+	}
+	if meta.Namespace != p.rootNamespace {
+		if pc := p.namespaceConfig(meta.Namespace); pc != nil {
+			out = append(out, ProxyConfigSource{Source: "ProxyConfig " + p.names[pc], Config: toMeshConfigProxyConfig(pc)})
+		}
+	}
+	if p.rootNamespace != "" {
+		if pc := p.namespaceConfig(p.rootNamespace); pc != nil {
+			out = append(out, ProxyConfigSource{Source: "ProxyConfig " + p.names[pc], Config: toMeshConfigProxyConfig(pc)})
+		}
+	}
+	return out
+}
+
 func GetProxyConfigs(store ConfigStore, mc *meshconfig.MeshConfig) (*ProxyConfigs, error) {
 	proxyconfigs := &ProxyConfigs{
 		namespaceToProxyConfigs: map[string][]*v1beta1.ProxyConfig{},
 		rootNamespace:           mc.GetRootNamespace(),
+		securityHeaders:         map[*v1beta1.ProxyConfig]string{},
+		names:                   map[*v1beta1.ProxyConfig]string{},
 	}
 	resources, err := store.List(collections.IstioNetworkingV1Beta1Proxyconfigs.Resource().GroupVersionKind(), NamespaceAll)
 	if err != nil {
@@ -85,7 +129,12 @@ func GetProxyConfigs(store ConfigStore, mc *meshconfig.MeshConfig) (*ProxyConfig
 	sortConfigByCreationTime(resources)
 	ns := proxyconfigs.namespaceToProxyConfigs
 	for _, resource := range resources {
-		ns[resource.Namespace] = append(ns[resource.Namespace], resource.Spec.(*v1beta1.ProxyConfig))
+		pc := resource.Spec.(*v1beta1.ProxyConfig)
+		ns[resource.Namespace] = append(ns[resource.Namespace], pc)
+		proxyconfigs.names[pc] = resource.Namespace + "/" + resource.Name
+		if v, f := resource.Annotations[security.SecurityHeadersAnnotation]; f {
+			proxyconfigs.securityHeaders[pc] = v
+		}
 	}
 	return proxyconfigs, nil
 }
@@ -96,18 +145,34 @@ func (p *ProxyConfigs) mergedGlobalConfig() *meshconfig.ProxyConfig {
 
 // mergedWorkloadConfig merges ProxyConfig resources matching the given namespace.
 func (p *ProxyConfigs) mergedNamespaceConfig(namespace string) *meshconfig.ProxyConfig {
+	if pc := p.namespaceConfig(namespace); pc != nil {
+		return toMeshConfigProxyConfig(pc)
+	}
+	return nil
+}
+
+// mergedWorkloadConfig merges ProxyConfig resources matching the given namespace and labels.
+func (p *ProxyConfigs) mergedWorkloadConfig(namespace string, l map[string]string) *meshconfig.ProxyConfig {
+	if pc := p.workloadConfig(namespace, l); pc != nil {
+		return toMeshConfigProxyConfig(pc)
+	}
+	return nil
+}
+
+// namespaceConfig returns the ProxyConfig of the given namespace, without selector.
+func (p *ProxyConfigs) namespaceConfig(namespace string) *v1beta1.ProxyConfig {
 	for _, pc := range p.namespaceToProxyConfigs[namespace] {
 		if pc.GetSelector() == nil {
 			// return the first match. this is consistent since
 			// we sort the resources by creation time beforehand.
-			return toMeshConfigProxyConfig(pc)
+			return pc
 		}
 	}
 	return nil
 }
 
-// mergedWorkloadConfig merges ProxyConfig resources matching the given namespace and labels.
-func (p *ProxyConfigs) mergedWorkloadConfig(namespace string, l map[string]string) *meshconfig.ProxyConfig {
+// workloadConfig returns the ProxyConfig selecting the given labels in the given namespace.
+func (p *ProxyConfigs) workloadConfig(namespace string, l map[string]string) *v1beta1.ProxyConfig {
 	for _, pc := range p.namespaceToProxyConfigs[namespace] {
 		if len(pc.GetSelector().GetMatchLabels()) == 0 {
 			continue
@@ -117,8 +182,33 @@ func (p *ProxyConfigs) mergedWorkloadConfig(namespace string, l map[string]strin
 		if match.IsSupersetOf(selector) {
 			// return the first match. this is consistent since
 			// we sort the resources by creation time beforehand.
-			return toMeshConfigProxyConfig(pc)
+			return pc
+		}
+	}
+	return nil
+}
+
+// SecurityHeaders returns the security headers policy of a proxy: the security.SecurityHeadersAnnotation of the ProxyConfig
+// of its workload, of its namespace or of the root namespace, whichever is the most specific.
+func (p *ProxyConfigs) SecurityHeaders(meta *NodeMetadata) *security.SecurityHeadersPolicy {
+	if p == nil || meta == nil || len(p.securityHeaders) == 0 {
+		return nil
+	}
+	candidates := []*v1beta1.ProxyConfig{p.workloadConfig(meta.Namespace, meta.Labels), p.namespaceConfig(meta.Namespace)}
+	if p.rootNamespace != "" && meta.Namespace != p.rootNamespace {
+		candidates = append(candidates, p.namespaceConfig(p.rootNamespace))
+	}
+	for _, pc := range candidates {
+		v, f := p.securityHeaders[pc]
+		if pc == nil || !f {
+			continue
+		}
+		policy, err := security.ParseSecurityHeadersPolicy(v)
+		if err != nil {
+			pclog.Warnf("ignoring invalid %s annotation: %v", security.SecurityHeadersAnnotation, err)
+			continue
 		}
+		return policy
 	}
 	return nil
 }
@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/security"
 )
 
 var now = time.Now()
@@ -447,3 +448,92 @@ func v(x int32) *types.Int32Value {
 func selector(l map[string]string) *istioTypes.WorkloadSelector {
 	return &istioTypes.WorkloadSelector{MatchLabels: l}
 }
+
+func TestSecurityHeaders(t *testing.T) {
+	withSecurityHeaders := func(c config.Config, value string) config.Config {
+		c.Annotations = map[string]string{security.SecurityHeadersAnnotation: value}
+		return c
+	}
+	configs := []config.Config{
+		withSecurityHeaders(newProxyConfig("mesh", istioRootNamespace, &v1beta1.ProxyConfig{}), `{}`),
+		withSecurityHeaders(newProxyConfig("ns", "web", &v1beta1.ProxyConfig{}), `{"sidecars": true}`),
+		newProxyConfig("ns", "plain", &v1beta1.ProxyConfig{}),
+		withSecurityHeaders(newProxyConfig("workload", "plain", &v1beta1.ProxyConfig{
+			Selector: selector(map[string]string{"app": "legacy"}),
+		}), `{"disabled": true}`),
+		withSecurityHeaders(newProxyConfig("invalid", "broken", &v1beta1.ProxyConfig{}), `{`),
+	}
+	pcs, err := GetProxyConfigs(newProxyConfigStore(t, configs), &meshconfig.MeshConfig{RootNamespace: istioRootNamespace})
+	if err != nil {
+		t.Fatalf("failed to list proxyconfigs: %v", err)
+	}
+	cases := []struct {
+		name string
+		meta *NodeMetadata
+		want *security.SecurityHeadersPolicy
+	}{
+		{name: "mesh default", meta: newMeta("default", nil, nil), want: &security.SecurityHeadersPolicy{}},
+		{name: "namespace", meta: newMeta("web", nil, nil), want: &security.SecurityHeadersPolicy{Sidecars: true}},
+		{name: "namespace without annotation", meta: newMeta("plain", nil, nil), want: &security.SecurityHeadersPolicy{}},
+		{
+			name: "workload",
+			meta: newMeta("plain", map[string]string{"app": "legacy"}, nil),
+			want: &security.SecurityHeadersPolicy{Disabled: true},
+		},
+		{name: "invalid annotation", meta: newMeta("broken", nil, nil), want: &security.SecurityHeadersPolicy{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, pcs.SecurityHeaders(tc.meta)); diff != "" {
+				t.Fatalf("unexpected policy: %s", diff)
+			}
+		})
+	}
+}
+
+func TestEffectiveProxyConfigSources(t *testing.T) {
+	configs := []config.Config{
+		newProxyConfig("mesh", istioRootNamespace, &v1beta1.ProxyConfig{Concurrency: v(1)}),
+		newProxyConfig("ns", "web", &v1beta1.ProxyConfig{Concurrency: v(2)}),
+		newProxyConfig("workload", "web", &v1beta1.ProxyConfig{
+			Selector:    selector(map[string]string{"app": "legacy"}),
+			Concurrency: v(3),
+		}),
+	}
+	pcs, err := GetProxyConfigs(newProxyConfigStore(t, configs), &meshconfig.MeshConfig{RootNamespace: istioRootNamespace})
+	if err != nil {
+		t.Fatalf("failed to list proxyconfigs: %v", err)
+	}
+	cases := []struct {
+		name string
+		meta *NodeMetadata
+		want []string
+	}{
+		{name: "mesh", meta: newMeta("default", nil, nil), want: []string{"ProxyConfig istio-system/mesh"}},
+		{name: "root namespace", meta: newMeta(istioRootNamespace, nil, nil), want: []string{"ProxyConfig istio-system/mesh"}},
+		{name: "namespace", meta: newMeta("web", nil, nil), want: []string{"ProxyConfig web/ns", "ProxyConfig istio-system/mesh"}},
+		{
+			name: "workload and annotation",
+			meta: newMeta("web", map[string]string{"app": "legacy"}, map[string]string{
+				annotation.ProxyConfig.Name: "{ \"concurrency\": 4 }",
+			}),
+			want: []string{
+				"ProxyConfig web/workload",
+				annotation.ProxyConfig.Name + " annotation",
+				"ProxyConfig web/ns",
+				"ProxyConfig istio-system/mesh",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, s := range pcs.EffectiveProxyConfigSources(tc.meta) {
+				got = append(got, s.Source)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("unexpected sources: %s", diff)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	extensions "istio.io/api/extensions/v1alpha1"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/credentials"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/sets"
@@ -40,6 +41,7 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
+	"istio.io/istio/pkg/network"
 	"istio.io/pkg/monitoring"
 )
 
@@ -68,6 +70,9 @@ type serviceIndex struct {
 	// to avoid recomputations during push. This caches instanceByPort calls with empty labels.
 	// Call InstancesByPort directly when instances need to be filtered by actual labels.
 	instancesByPort map[string]map[int][]*ServiceInstance
+
+	// networksByPort contains a map of service key and networks of the instances by port.
+	networksByPort map[string]map[int][]network.ID
 }
 
 func newServiceIndex() serviceIndex {
@@ -77,6 +82,7 @@ func newServiceIndex() serviceIndex {
 		exportedToNamespace:  map[string][]*Service{},
 		HostnameAndNamespace: map[host.Name]map[string]*Service{},
 		instancesByPort:      map[string]map[int][]*ServiceInstance{},
+		networksByPort:       map[string]map[int][]network.ID{},
 	}
 }
 
@@ -116,6 +122,9 @@ type destinationRuleIndex struct {
 	rootNamespaceLocal  *processedDestRules
 	// mesh/namespace dest rules to be inherited
 	inheritedByNamespace map[string]*config.Config
+	// localityPinned contains the dest rules restricted to some localities with the LocalitiesAnnotation, sorted by
+	// creation time. They are not merged with the other dest rules.
+	localityPinned []config.Config
 }
 
 func newDestinationRuleIndex() destinationRuleIndex {
@@ -197,6 +206,10 @@ type PushContext struct {
 	// gatewayIndex is the index of gateways.
 	gatewayIndex gatewayIndex
 
+	// egressGatewayRoutes is true if the virtual services and gateways include the routes generated for
+	// ServiceEntries with the EgressGatewayAnnotation.
+	egressGatewayRoutes bool
+
 	// clusterLocalHosts extracted from the MeshConfig
 	clusterLocalHosts ClusterLocalHosts
 
@@ -240,6 +253,15 @@ type PushContext struct {
 	// GatewayAPIController holds a reference to the gateway API controller.
 	GatewayAPIController GatewayController
 
+	// CredentialsController holds a reference to the credentials controller of the environment, reading the
+	// credentials of the Secrets referenced by the configs.
+	CredentialsController credentials.MulticlusterController
+
+	// StagedViews are the push contexts of the proxies served only a part of the changes of the staged config
+	// rollouts, in which the other configs being rolled out have their previous version, by the key of the audience
+	// of the proxies. It is nil when no staged rollout is in progress.
+	StagedViews map[string]*PushContext `json:"-"`
+
 	// cache gateways addresses for each network
 	// this is mainly used for kubernetes multi-cluster scenario
 	networkMgr *NetworkManager
@@ -591,6 +613,13 @@ var (
 		"Duplicate subsets across destination rules for same host",
 	)
 
+	// WasmPluginPriorityConflicts tracks WasmPlugins with the same priority as another plugin at the same
+	// insertion point, which are ordered by creation time.
+	WasmPluginPriorityConflicts = monitoring.NewGauge(
+		"pilot_wasmplugin_priority_conflicts",
+		"WasmPlugins with the same priority as another WasmPlugin at the same insertion point.",
+	)
+
 	// totalVirtualServices tracks the total number of virtual service
 	totalVirtualServices = monitoring.NewGauge(
 		"pilot_virt_services",
@@ -617,6 +646,7 @@ var (
 		ProxyStatusClusterNoInstances,
 		DuplicatedDomains,
 		DuplicatedSubsets,
+		WasmPluginPriorityConflicts,
 	}
 )
 
@@ -654,6 +684,10 @@ func (ps *PushContext) AddServiceInstances(service *Service, instances map[int][
 			ps.ServiceIndex.instancesByPort[svcKey] = make(map[int][]*ServiceInstance)
 		}
 		ps.ServiceIndex.instancesByPort[svcKey][port] = append(ps.ServiceIndex.instancesByPort[svcKey][port], inst...)
+		if _, exists := ps.ServiceIndex.networksByPort[svcKey]; !exists {
+			ps.ServiceIndex.networksByPort[svcKey] = make(map[int][]network.ID)
+		}
+		ps.ServiceIndex.networksByPort[svcKey][port] = instancesNetworks(ps.ServiceIndex.instancesByPort[svcKey][port])
 	}
 }
 
@@ -831,12 +865,19 @@ func (ps *PushContext) IsServiceVisible(service *Service, namespace string) bool
 // VirtualServicesForGateway lists all virtual services bound to the specified gateways
 // This replaces store.VirtualServices. Used only by the gateways
 // Sidecars use the egressListener.VirtualServices().
+// Virtual services pinned to localities only apply to the proxies in these localities.
 func (ps *PushContext) VirtualServicesForGateway(proxy *Proxy, gateway string) []config.Config {
-	res := make([]config.Config, 0, len(ps.virtualServiceIndex.privateByNamespaceAndGateway[proxy.ConfigNamespace][gateway])+
-		len(ps.virtualServiceIndex.exportedToNamespaceByGateway[proxy.ConfigNamespace][gateway])+
+	return LocalityPinnedConfigs(proxy, ps.virtualServicesForGateway(proxy.ConfigNamespace, gateway))
+}
+
+// virtualServicesForGateway lists all virtual services bound to the specified gateways visible from the namespace,
+// regardless of their localities.
+func (ps *PushContext) virtualServicesForGateway(namespace string, gateway string) []config.Config {
+	res := make([]config.Config, 0, len(ps.virtualServiceIndex.privateByNamespaceAndGateway[namespace][gateway])+
+		len(ps.virtualServiceIndex.exportedToNamespaceByGateway[namespace][gateway])+
 		len(ps.virtualServiceIndex.publicByGateway[gateway]))
-	res = append(res, ps.virtualServiceIndex.privateByNamespaceAndGateway[proxy.ConfigNamespace][gateway]...)
-	res = append(res, ps.virtualServiceIndex.exportedToNamespaceByGateway[proxy.ConfigNamespace][gateway]...)
+	res = append(res, ps.virtualServiceIndex.privateByNamespaceAndGateway[namespace][gateway]...)
+	res = append(res, ps.virtualServiceIndex.exportedToNamespaceByGateway[namespace][gateway]...)
 	res = append(res, ps.virtualServiceIndex.publicByGateway[gateway]...)
 	return res
 }
@@ -941,6 +982,11 @@ func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *config.C
 		return nil
 	}
 
+	// Dest rules pinned to the locality of the proxy take precedence over the others.
+	if dr := ps.localityPinnedDestinationRule(proxy, service); dr != nil {
+		return dr
+	}
+
 	// If proxy has a sidecar scope that is user supplied, then get the destination rules from the sidecar scope
 	// sidecarScope.config is nil if there is no sidecar scope for the namespace
 	if proxy.SidecarScope != nil && proxy.Type == SidecarProxy {
@@ -1094,6 +1140,7 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 	}
 
 	ps.networkMgr = env.NetworkManager
+	ps.CredentialsController = env.CredentialsController
 
 	ps.clusterLocalHosts = env.ClusterLocal().GetClusterLocalHosts()
 
@@ -1174,6 +1221,8 @@ func (ps *PushContext) updateContext(
 			gatewayChanged = true
 		case gvk.Sidecar:
 			sidecarsChanged = true
+			// Sidecars set the default CORS policies of the VirtualServices of their namespace.
+			virtualServicesChanged = true
 		case gvk.WasmPlugin:
 			wasmPluginsChanged = true
 		case gvk.EnvoyFilter:
@@ -1195,6 +1244,18 @@ func (ps *PushContext) updateContext(
 		}
 	}
 
+	if servicesChanged && !virtualServicesChanged {
+		// The routes through egress gateways are derived from ServiceEntries.
+		serviceEntries, err := env.List(gvk.ServiceEntry, NamespaceAll)
+		if err != nil {
+			return err
+		}
+		if oldPushContext.egressGatewayRoutes || hasEgressGatewayRoutes(serviceEntries) {
+			virtualServicesChanged = true
+			gatewayChanged = true
+		}
+	}
+
 	if servicesChanged {
 		// Services have changed. initialize service registry
 		if err := ps.initServiceRegistry(env); err != nil {
@@ -1219,6 +1280,7 @@ func (ps *PushContext) updateContext(
 		}
 	} else {
 		ps.virtualServiceIndex = oldPushContext.virtualServiceIndex
+		ps.egressGatewayRoutes = oldPushContext.egressGatewayRoutes
 	}
 
 	if destinationRulesChanged {
@@ -1294,6 +1356,7 @@ func (ps *PushContext) updateContext(
 		}
 	} else {
 		ps.sidecarIndex.sidecarsByNamespace = oldPushContext.sidecarIndex.sidecarsByNamespace
+		ps.sidecarIndex.rootConfig = oldPushContext.sidecarIndex.rootConfig
 	}
 
 	return nil
@@ -1318,6 +1381,10 @@ func (ps *PushContext) initServiceRegistry(env *Environment) error {
 			instances := make([]*ServiceInstance, 0)
 			instances = append(instances, env.InstancesByPort(s, port.Port, nil)...)
 			ps.ServiceIndex.instancesByPort[svcKey][port.Port] = instances
+			if _, ok := ps.ServiceIndex.networksByPort[svcKey]; !ok {
+				ps.ServiceIndex.networksByPort[svcKey] = make(map[int][]network.ID)
+			}
+			ps.ServiceIndex.networksByPort[svcKey][port.Port] = instancesNetworks(instances)
 		}
 
 		if _, f := ps.ServiceIndex.HostnameAndNamespace[s.Hostname]; !f {
@@ -1432,8 +1499,22 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 		resolveVirtualServiceShortnames(r.Spec.(*networking.VirtualService), r.Meta)
 	}
 
+	serviceEntries, err := env.List(gvk.ServiceEntry, NamespaceAll)
+	if err != nil {
+		return err
+	}
+	egressRoutes, _ := buildEgressGatewayRoutes(serviceEntries, vservices)
+	ps.egressGatewayRoutes = len(egressRoutes) > 0
+	vservices = append(vservices, egressRoutes...)
+
 	vservices, ps.virtualServiceIndex.delegates = mergeVirtualServicesIfNeeded(vservices, ps.exportToDefaults.virtualService)
 
+	sidecars, err := env.List(gvk.Sidecar, NamespaceAll)
+	if err != nil {
+		return err
+	}
+	applyDefaultCorsPolicies(vservices, defaultCorsPolicies(sidecars), meshDefaultCorsPolicy(env.Mesh()))
+
 	for _, virtualService := range vservices {
 		ns := virtualService.Namespace
 		rule := virtualService.Spec.(*networking.VirtualService)
@@ -1583,21 +1664,37 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 	// Hold reference root namespace's sidecar config
 	// Root namespace can have only one sidecar config object
 	// Currently we expect that it has no workloadSelectors
+	// It is set before the conversions, as the Sidecars of the other namespaces inherit some of its annotations.
 	var rootNSConfig *config.Config
-	ps.sidecarIndex.sidecarsByNamespace = make(map[string][]*SidecarScope, sidecarNum)
 	for i, sidecarConfig := range sidecarConfigs {
-		ps.sidecarIndex.sidecarsByNamespace[sidecarConfig.Namespace] = append(ps.sidecarIndex.sidecarsByNamespace[sidecarConfig.Namespace],
-			ConvertToSidecarScope(ps, &sidecarConfig, sidecarConfig.Namespace))
-		if rootNSConfig == nil && sidecarConfig.Namespace == ps.Mesh.RootNamespace &&
+		if sidecarConfig.Namespace == ps.Mesh.RootNamespace &&
 			sidecarConfig.Spec.(*networking.Sidecar).WorkloadSelector == nil {
 			rootNSConfig = &sidecarConfigs[i]
+			break
 		}
 	}
 	ps.sidecarIndex.rootConfig = rootNSConfig
 
+	ps.sidecarIndex.sidecarsByNamespace = make(map[string][]*SidecarScope, sidecarNum)
+	for _, sidecarConfig := range sidecarConfigs {
+		ps.sidecarIndex.sidecarsByNamespace[sidecarConfig.Namespace] = append(ps.sidecarIndex.sidecarsByNamespace[sidecarConfig.Namespace],
+			ConvertToSidecarScope(ps, &sidecarConfig, sidecarConfig.Namespace))
+	}
+
 	return nil
 }
 
+// IsEgressProxyCredential returns whether the Secret holds the credentials of the egress proxy of a Sidecar. The
+// listeners and routes of the sidecars include the credentials, so changes to the Secret require a full push.
+func (ps *PushContext) IsEgressProxyCredential(name, namespace string) bool {
+	for _, sc := range ps.sidecarIndex.sidecarsByNamespace[namespace] {
+		if sc.EgressProxy != nil && sc.EgressProxy.CredentialName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Split out of DestinationRule expensive conversions - once per push.
 func (ps *PushContext) initDestinationRules(env *Environment) error {
 	configs, err := env.List(gvk.DestinationRule, NamespaceAll)
@@ -1636,10 +1733,17 @@ func (ps *PushContext) SetDestinationRules(configs []config.Config) {
 	exportedDestRulesByNamespace := make(map[string]*processedDestRules)
 	rootNamespaceLocalDestRules := newProcessedDestRules()
 	inheritedConfigs := make(map[string]*config.Config)
+	var localityPinnedDestRules []config.Config
 
 	for i := range configs {
 		rule := configs[i].Spec.(*networking.DestinationRule)
 
+		if localityPinned(&configs[i]) {
+			rule.Host = string(ResolveShortnameToFQDN(rule.Host, configs[i].Meta))
+			localityPinnedDestRules = append(localityPinnedDestRules, configs[i])
+			continue
+		}
+
 		if features.EnableDestinationRuleInheritance && rule.Host == "" {
 			if t, ok := inheritedConfigs[configs[i].Namespace]; ok {
 				log.Warnf(
@@ -1723,6 +1827,7 @@ func (ps *PushContext) SetDestinationRules(configs []config.Config) {
 	ps.destinationRuleIndex.exportedByNamespace = exportedDestRulesByNamespace
 	ps.destinationRuleIndex.rootNamespaceLocal = rootNamespaceLocalDestRules
 	ps.destinationRuleIndex.inheritedByNamespace = inheritedConfigs
+	ps.destinationRuleIndex.localityPinned = localityPinnedDestRules
 }
 
 func (ps *PushContext) initAuthorizationPolicies(env *Environment) error {
@@ -1815,10 +1920,42 @@ func (ps *PushContext) WasmPlugins(proxy *Proxy) map[extensions.PluginPhase][]*W
 		})
 		matchedPlugins[i] = slice
 	}
+	ps.addWasmPluginPriorityConflicts(proxy, matchedPlugins)
 
 	return matchedPlugins
 }
 
+// addWasmPluginPriorityConflicts reports the plugins of a proxy that have the same priority as another plugin at
+// the same insertion point, as their relative order is only decided by their creation time.
+func (ps *PushContext) addWasmPluginPriorityConflicts(proxy *Proxy, plugins map[extensions.PluginPhase][]*WasmPluginWrapper) {
+	phases := make([]extensions.PluginPhase, 0, len(plugins))
+	for phase := range plugins {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i] < phases[j] })
+	seen := map[string]*WasmPluginWrapper{}
+	for _, phase := range phases {
+		for _, plugin := range plugins[phase] {
+			if plugin.Priority == nil {
+				continue
+			}
+			point := phase.String()
+			if plugin.Anchor != nil {
+				point = plugin.Anchor.String()
+			}
+			key := point + "/" + strconv.FormatInt(plugin.Priority.Value, 10)
+			other, f := seen[key]
+			if !f {
+				seen[key] = plugin
+				continue
+			}
+			ps.AddMetric(WasmPluginPriorityConflicts, plugin.Namespace+"/"+plugin.Name, proxy.ID,
+				fmt.Sprintf("WasmPlugin %s/%s has the same priority %d at %s as WasmPlugin %s/%s",
+					plugin.Namespace, plugin.Name, plugin.Priority.Value, point, other.Namespace, other.Name))
+		}
+	}
+}
+
 // pre computes envoy filters per namespace
 func (ps *PushContext) initEnvoyFilters(env *Environment) error {
 	envoyFilterConfigs, err := env.List(gvk.EnvoyFilter, NamespaceAll)
@@ -1928,6 +2065,18 @@ func (ps *PushContext) initGateways(env *Environment) error {
 	if err != nil {
 		return err
 	}
+	serviceEntries, err := env.List(gvk.ServiceEntry, NamespaceAll)
+	if err != nil {
+		return err
+	}
+	if hasEgressGatewayRoutes(serviceEntries) {
+		virtualServices, err := env.List(gvk.VirtualService, NamespaceAll)
+		if err != nil {
+			return err
+		}
+		_, egressGateways := buildEgressGatewayRoutes(serviceEntries, virtualServices)
+		gatewayConfigs = append(gatewayConfigs, egressGateways...)
+	}
 
 	sortConfigByCreationTime(gatewayConfigs)
 
@@ -2164,6 +2313,28 @@ func (ps *PushContext) ServiceInstancesByPort(svc *Service, port int, labels lab
 	return out
 }
 
+// ServiceNetworks returns the networks of the instances of the service port, sorted. They are only updated by full
+// pushes, which the endpoint updates changing them trigger when PILOT_CROSS_NETWORK_TRAFFIC_POLICY is set.
+func (ps *PushContext) ServiceNetworks(svc *Service, port int) []network.ID {
+	return ps.ServiceIndex.networksByPort[svc.Key()][port]
+}
+
+// instancesNetworks returns the sorted networks of the instances.
+func instancesNetworks(instances []*ServiceInstance) []network.ID {
+	var networks []network.ID
+	seen := map[network.ID]bool{}
+	for _, instance := range instances {
+		if nw := instance.Endpoint.Network; !seen[nw] {
+			seen[nw] = true
+			networks = append(networks, nw)
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i] < networks[j]
+	})
+	return networks
+}
+
 // initKubernetesGateways initializes Kubernetes gateway-api objects
 func (ps *PushContext) initKubernetesGateways(env *Environment) error {
 	if env.GatewayAPIController != nil {
@@ -43,6 +43,7 @@ import (
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
+	"istio.io/istio/pkg/config/xds"
 )
 
 func TestMergeUpdateRequest(t *testing.T) {
@@ -634,6 +635,55 @@ func TestWasmPlugins(t *testing.T) {
 	}
 }
 
+func TestWasmPluginPriorityConflicts(t *testing.T) {
+	env := &Environment{}
+	store := istioConfigStore{ConfigStore: NewFakeStore()}
+	plugin := func(name string, phase extensions.PluginPhase, priority int64, anchor string) config.Config {
+		c := config.Config{
+			Meta: config.Meta{Name: name, Namespace: "testns", GroupVersionKind: gvk.WasmPlugin},
+			Spec: &extensions.WasmPlugin{Phase: phase, Priority: &types.Int64Value{Value: priority}},
+		}
+		if anchor != "" {
+			c.Annotations = map[string]string{xds.WasmPluginAnchorAnnotation: anchor}
+		}
+		return c
+	}
+	for _, c := range []config.Config{
+		plugin("authn-a", extensions.PluginPhase_AUTHN, 10, ""),
+		plugin("authn-b", extensions.PluginPhase_AUTHN, 10, ""),
+		plugin("authz", extensions.PluginPhase_AUTHZ, 10, ""),
+		plugin("anchored-authn", extensions.PluginPhase_AUTHN, 20, "before:ext_authz"),
+		plugin("anchored-authz", extensions.PluginPhase_AUTHZ, 20, "before:ext_authz"),
+		plugin("anchored-after", extensions.PluginPhase_AUTHZ, 20, "after:ext_authz"),
+	} {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.IstioConfigStore = &store
+	m := mesh.DefaultMeshConfig()
+	env.Watcher = mesh.NewFixedWatcher(&m)
+	env.Init()
+
+	pc := NewPushContext()
+	pc.Mesh = &m
+	if err := pc.initWasmPlugins(env); err != nil {
+		t.Fatal(err)
+	}
+	pc.WasmPlugins(&Proxy{ID: "app.testns", ConfigNamespace: "testns"})
+
+	conflicts := pc.ProxyStatus[WasmPluginPriorityConflicts.Name()]
+	if len(conflicts) != 2 {
+		t.Fatalf("got conflicts %v, want 2", conflicts)
+	}
+	// The plugins are ordered by creation time, and the later one is reported.
+	for _, name := range []string{"testns/authn-b", "testns/anchored-authz"} {
+		if _, f := conflicts[name]; !f {
+			t.Errorf("missing conflict of %s in %v", name, conflicts)
+		}
+	}
+}
+
 func TestServiceIndex(t *testing.T) {
 	g := NewWithT(t)
 	env := &Environment{}
@@ -206,6 +206,9 @@ const (
 	TrafficDirectionInbound TrafficDirection = "inbound"
 	// TrafficDirectionOutbound indicates outbound traffic
 	TrafficDirectionOutbound TrafficDirection = "outbound"
+	// TrafficDirectionOutboundUDP indicates outbound UDP traffic. It is only used in the names of the clusters of UDP
+	// service ports, which may have the same port number as a TCP service port.
+	TrafficDirectionOutboundUDP TrafficDirection = "outbound-udp"
 
 	// trafficDirectionOutboundSrvPrefix the prefix for a DNS SRV type subset key
 	trafficDirectionOutboundSrvPrefix = string(TrafficDirectionOutbound) + "_"
@@ -395,6 +398,9 @@ const (
 	Healthy HealthStatus = 0
 	// Unhealthy.
 	UnHealthy HealthStatus = 1
+	// Terminating is an endpoint shutting down but still serving. It only receives traffic when there are not
+	// enough healthy endpoints.
+	Terminating HealthStatus = 2
 )
 
 // IstioEndpoint defines a network address (IP:port) associated with an instance of the
@@ -436,6 +442,9 @@ type IstioEndpoint struct {
 	// Network holds the network where this endpoint is present
 	Network network.ID
 
+	// NetworkSource tells how the network of the endpoint was resolved.
+	NetworkSource NetworkSource
+
 	// The locality where the endpoint is present.
 	Locality Locality
 
@@ -491,6 +500,24 @@ func (ep *IstioEndpoint) IsDiscoverableFromProxy(p *Proxy) bool {
 	return ep.DiscoverabilityPolicy.IsDiscoverableFromProxy(ep, p)
 }
 
+// NetworkSource tells how the network of an endpoint was resolved. In order of precedence, the network of an endpoint
+// is read from its labels or the metadata of its proxy, from its node, from the system namespace, and from the
+// meshNetworks.
+type NetworkSource string
+
+const (
+	// NetworkSourceLabel is the topology.istio.io/network label of the pod or the WorkloadEntry.
+	NetworkSourceLabel NetworkSource = "label"
+	// NetworkSourceProxyMetadata is the network of the metadata of the proxy.
+	NetworkSourceProxyMetadata NetworkSource = "proxyMetadata"
+	// NetworkSourceNode is the topology.istio.io/network label of the node of the endpoint.
+	NetworkSourceNode NetworkSource = "node"
+	// NetworkSourceSystemNamespace is the topology.istio.io/network label of the system namespace.
+	NetworkSourceSystemNamespace NetworkSource = "systemNamespace"
+	// NetworkSourceMeshNetworks is a fromCidr or fromRegistry endpoint of the meshNetworks.
+	NetworkSourceMeshNetworks NetworkSource = "meshNetworks"
+)
+
 // EndpointDiscoverabilityPolicy determines the discoverability of an endpoint throughout the mesh.
 type EndpointDiscoverabilityPolicy interface {
 	// IsDiscoverableFromProxy indicates whether an endpoint is discoverable from the given Proxy.
@@ -546,6 +573,11 @@ type ServiceAttributes struct {
 	// a namespace when the namespace is imported.
 	ExportTo map[visibility.Instance]bool
 
+	// ExportToClusters is the set of remote clusters whose proxies can reach the endpoints of the service
+	// residing in other clusters, from the ExportToClustersAnnotation. It is nil if the service is exported to
+	// all the clusters.
+	ExportToClusters map[cluster.ID]bool
+
 	// LabelSelectors are the labels used by the service to select workloads.
 	// Applicable to both Kubernetes and ServiceEntries.
 	LabelSelectors map[string]string
@@ -566,6 +598,36 @@ type ServiceAttributes struct {
 	ClusterExternalPorts map[cluster.ID]map[uint32]uint32
 }
 
+// ExportToClustersAnnotation is the annotation of Services and ServiceEntries scoping the clusters they are
+// exported to in a multicluster mesh. The value is a comma separated list of cluster IDs, or "*" for all the
+// clusters. Proxies in the other clusters only reach the endpoints of the service residing in their own cluster.
+const ExportToClustersAnnotation = "networking.istio.io/exportToClusters"
+
+// ParseExportToClusters parses the value of the ExportToClustersAnnotation. It returns nil if the service is
+// exported to all the clusters.
+func ParseExportToClusters(v string) map[cluster.ID]bool {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	clusters := map[cluster.ID]bool{}
+	for _, c := range strings.Split(v, ",") {
+		c = strings.TrimSpace(c)
+		if c == "*" {
+			return nil
+		}
+		if c != "" {
+			clusters[cluster.ID(c)] = true
+		}
+	}
+	return clusters
+}
+
+// ExportedToCluster returns true if the endpoints of the service residing in other clusters can be reached from
+// the given cluster.
+func (s *ServiceAttributes) ExportedToCluster(clusterID cluster.ID) bool {
+	return s.ExportToClusters == nil || s.ExportToClusters[clusterID]
+}
+
 // DeepCopy creates a deep copy of ServiceAttributes, but skips internal mutexes.
 func (s *ServiceAttributes) DeepCopy() ServiceAttributes {
 	// Nested mutexes are configured to be ignored by copystructure.Copy.
@@ -681,6 +743,17 @@ func (ports PortList) GetByPort(num int) (*Port, bool) {
 	return nil, false
 }
 
+// GetUDPByPort retrieves the UDP port declaration by port value. A service may declare the same port value for
+// TCP and UDP, such as DNS.
+func (ports PortList) GetUDPByPort(num int) (*Port, bool) {
+	for _, port := range ports {
+		if port.Port == num && port.Protocol == protocol.UDP {
+			return port, true
+		}
+	}
+	return nil, false
+}
+
 // External predicate checks whether the service is external
 func (s *Service) External() bool {
 	return s.MeshExternal
@@ -692,6 +765,11 @@ func BuildSubsetKey(direction TrafficDirection, subsetName string, hostname host
 	return string(direction) + "|" + strconv.Itoa(port) + "|" + subsetName + "|" + string(hostname)
 }
 
+// BuildUDPSubsetKey generates a unique string referencing the service instances of the UDP port of a service.
+func BuildUDPSubsetKey(hostname host.Name, port int) string {
+	return BuildSubsetKey(TrafficDirectionOutboundUDP, "", hostname, port)
+}
+
 // BuildInboundSubsetKey generates a unique string referencing service instances with port.
 func BuildInboundSubsetKey(port int) string {
 	return BuildSubsetKey(TrafficDirectionInbound, "", "", port)
@@ -15,10 +15,13 @@
 package model
 
 import (
+	"reflect"
 	"testing"
 
+	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
 )
 
 func TestGetByPort(t *testing.T) {
@@ -35,6 +38,23 @@ func TestGetByPort(t *testing.T) {
 	}
 }
 
+func TestGetUDPByPort(t *testing.T) {
+	ports := PortList{
+		{Name: "dns-tcp", Port: 53, Protocol: protocol.TCP},
+		{Name: "dns", Port: 53, Protocol: protocol.UDP},
+	}
+
+	if port, exists := ports.GetByPort(53); !exists || port.Name != "dns-tcp" {
+		t.Errorf("GetByPort(53) => want dns-tcp but got %v, %t", port, exists)
+	}
+	if port, exists := ports.GetUDPByPort(53); !exists || port.Name != "dns" {
+		t.Errorf("GetUDPByPort(53) => want dns but got %v, %t", port, exists)
+	}
+	if port, exists := ports.GetUDPByPort(80); exists || port != nil {
+		t.Errorf("GetUDPByPort(80) => want none but got %v, %t", port, exists)
+	}
+}
+
 func BenchmarkParseSubsetKey(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		ParseSubsetKey("outbound|80|v1|example.com")
@@ -55,6 +75,7 @@ func TestParseSubsetKey(t *testing.T) {
 		{"|||", "", "", "", 0},
 		{"outbound_.8080_.v1_.foo.example.org", TrafficDirectionOutbound, "v1", "foo.example.org", 8080},
 		{"inbound_.8080_.v1_.foo.example.org", TrafficDirectionInbound, "v1", "foo.example.org", 8080},
+		{BuildUDPSubsetKey("dns.example.org", 53), TrafficDirectionOutboundUDP, "", "dns.example.org", 53},
 	}
 
 	for _, tt := range tests {
@@ -277,3 +298,23 @@ func BenchmarkBuildSubsetKey(b *testing.B) {
 		_ = BuildSubsetKey(TrafficDirectionInbound, "v1", "someHost", 80)
 	}
 }
+
+func TestParseExportToClusters(t *testing.T) {
+	cases := []struct {
+		value string
+		want  map[cluster.ID]bool
+	}{
+		{value: "", want: nil},
+		{value: "*", want: nil},
+		{value: "cluster-1", want: map[cluster.ID]bool{"cluster-1": true}},
+		{value: "cluster-1, cluster-2,", want: map[cluster.ID]bool{"cluster-1": true, "cluster-2": true}},
+		{value: "cluster-1,*", want: nil},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParseExportToClusters(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
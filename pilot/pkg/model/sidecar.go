@@ -16,13 +16,18 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -40,6 +45,7 @@ var (
 		gvk.VirtualService:  {},
 		gvk.DestinationRule: {},
 		gvk.Sidecar:         {},
+		gvk.Secret:          {},
 	}
 
 	// clusterScopedConfigTypes includes configs when they are in root namespace,
@@ -104,6 +110,18 @@ type SidecarScope struct {
 	// be forwarded.
 	OutboundTrafficPolicy *networking.OutboundTrafficPolicy
 
+	// EgressProxy is the forward proxy the traffic to the external services is sent through, set with the
+	// gateway.EgressProxyAnnotation of the Sidecar. Nil if the traffic is sent to the services directly.
+	EgressProxy *EgressProxy
+
+	// PassthroughTLSOrigination is whether TLS is originated for the plaintext HTTP sent to unknown destinations on
+	// the ports which only accept TLS, set with the gateway.PassthroughTLSOriginationAnnotation of the Sidecar.
+	PassthroughTLSOrigination bool
+
+	// FilterBypassPaths are the paths whose inbound requests skip the JWT authentication, the authorization and the
+	// stats filters, set with the FilterBypassPathsAnnotation of the Sidecar or features.FilterBypassPaths.
+	FilterBypassPaths []string
+
 	// Set of known configs this sidecar depends on.
 	// This field will be used to determine the config/resource scope
 	// which means which config changes will affect the proxies within this scope.
@@ -125,6 +143,8 @@ func (sc *SidecarScope) MarshalJSON() ([]byte, error) {
 		"name":                  sc.Name,
 		"namespace":             sc.Namespace,
 		"outboundTrafficPolicy": sc.OutboundTrafficPolicy,
+		"egressProxy":           sc.EgressProxy,
+		"filterBypassPaths":     sc.FilterBypassPaths,
 		"services":              sc.services,
 		"sidecar":               sc.Sidecar,
 		"destinationRules":      sc.destinationRules,
@@ -167,6 +187,112 @@ type IstioEgressListenerWrapper struct {
 
 const defaultSidecar = "default-sidecar"
 
+// EgressProxy is a forward proxy accepting HTTP CONNECT requests, such as a corporate HTTP proxy.
+type EgressProxy struct {
+	// Hostname is the hostname of the service of the proxy.
+	Hostname host.Name `json:"hostname"`
+	// Port is the port of the service the proxy accepts the CONNECT requests on.
+	Port int `json:"port"`
+	// CredentialName is the name of the basic authentication Secret of the credentials sent to the proxy, set with
+	// the gateway.EgressProxyCredentialAnnotation of the Sidecar. Empty if no credentials are sent.
+	CredentialName string `json:"credentialName,omitempty"`
+	// Namespace is the namespace of the Sidecar, holding the Secret.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// CredentialKey returns the config key of the Secret of the credentials, or nil if no credentials are sent.
+func (p *EgressProxy) CredentialKey() *ConfigKey {
+	if p == nil || p.CredentialName == "" {
+		return nil
+	}
+	return &ConfigKey{Kind: gvk.Secret, Name: p.CredentialName, Namespace: p.Namespace}
+}
+
+// addCredentialDependencies adds the Secrets referenced by the credentialName of the client TLS settings of the
+// DestinationRules to the dependencies of the sidecar scope, as the sidecars fetch them from istiod when
+// features.EnableSidecarCredentialName is set. A credentialName without namespace refers to the scope namespace.
+func (sc *SidecarScope) addCredentialDependencies() {
+	if !features.EnableSidecarCredentialName {
+		return
+	}
+	for _, dr := range sc.destinationRules {
+		rule := dr.Spec.(*networking.DestinationRule)
+		policies := []*networking.TrafficPolicy{rule.TrafficPolicy}
+		for _, subset := range rule.Subsets {
+			policies = append(policies, subset.TrafficPolicy)
+		}
+		for _, policy := range policies {
+			tls := []*networking.ClientTLSSettings{policy.GetTls()}
+			for _, port := range policy.GetPortLevelSettings() {
+				tls = append(tls, port.GetTls())
+			}
+			for _, settings := range tls {
+				if settings.GetCredentialName() == "" {
+					continue
+				}
+				secret, err := credentials.ParseResourceName(credentials.ToResourceName(settings.CredentialName), sc.Namespace, "", "")
+				if err != nil {
+					continue
+				}
+				sc.AddConfigDependencies(ConfigKey{Kind: gvk.Secret, Name: secret.Name, Namespace: secret.Namespace})
+			}
+		}
+	}
+}
+
+// FilterBypassPathsAnnotation can be set on a Sidecar to the comma separated list of the paths, such as /healthz or
+// /metrics, whose inbound requests skip the JWT authentication, the authorization and the stats filters of its
+// workloads. A path ending with /* matches the paths under it. A Sidecar without it inherits the one of the Sidecar of
+// the root namespace, and then features.FilterBypassPaths. An empty value bypasses no path.
+const FilterBypassPathsAnnotation = "networking.istio.io/filter-bypass-paths"
+
+// ParseFilterBypassPaths parses the paths of the FilterBypassPathsAnnotation.
+func ParseFilterBypassPaths(v string) ([]string, error) {
+	var paths []string
+	for _, path := range strings.Split(v, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") || strings.Contains(strings.TrimSuffix(path, "/*"), "*") {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// filterBypassPaths returns the paths of the FilterBypassPathsAnnotation of the Sidecar, or else of the Sidecar of the
+// root namespace, or else of features.FilterBypassPaths. An invalid annotation is ignored.
+func filterBypassPaths(sidecarConfig, rootConfig *config.Config) []string {
+	for _, cfg := range []*config.Config{sidecarConfig, rootConfig} {
+		if cfg == nil {
+			continue
+		}
+		value, f := cfg.Annotations[FilterBypassPathsAnnotation]
+		if !f {
+			continue
+		}
+		paths, err := ParseFilterBypassPaths(value)
+		if err != nil {
+			log.Warnf("ignoring invalid %s annotation of Sidecar %s/%s: %v", FilterBypassPathsAnnotation, cfg.Namespace, cfg.Name, err)
+			continue
+		}
+		return paths
+	}
+	return defaultFilterBypassPaths()
+}
+
+// defaultFilterBypassPaths returns the paths of features.FilterBypassPaths.
+func defaultFilterBypassPaths() []string {
+	paths, err := ParseFilterBypassPaths(features.FilterBypassPaths)
+	if err != nil {
+		log.Warnf("ignoring invalid PILOT_FILTER_BYPASS_PATHS: %v", err)
+		return nil
+	}
+	return paths
+}
+
 // DefaultSidecarScopeForNamespace is a sidecar scope object with a default catch all egress listener
 // that matches the default Istio behavior: a sidecar has listeners for all services in the mesh
 // We use this scope when the user has not set any sidecar Config for a given config namespace.
@@ -181,7 +307,7 @@ func DefaultSidecarScopeForNamespace(ps *PushContext, configNamespace string) *S
 		},
 	}
 	defaultEgressListener.services = ps.Services(&dummyNode)
-	defaultEgressListener.virtualServices = ps.VirtualServicesForGateway(&dummyNode, constants.IstioMeshGateway)
+	defaultEgressListener.virtualServices = ps.virtualServicesForGateway(configNamespace, constants.IstioMeshGateway)
 
 	out := &SidecarScope{
 		Name:               defaultSidecar,
@@ -227,6 +353,8 @@ func DefaultSidecarScopeForNamespace(ps *PushContext, configNamespace string) *S
 			Namespace: dr.Namespace,
 		})
 	}
+	out.addLocalityPinnedDependencies(ps)
+	out.addCredentialDependencies()
 
 	for _, el := range out.EgressListeners {
 		// add dependencies on delegate virtual services
@@ -249,6 +377,8 @@ func DefaultSidecarScopeForNamespace(ps *PushContext, configNamespace string) *S
 		}
 	}
 
+	out.FilterBypassPaths = filterBypassPaths(nil, ps.sidecarIndex.rootConfig)
+
 	return out
 }
 
@@ -415,6 +545,8 @@ func ConvertToSidecarScope(ps *PushContext, sidecarConfig *config.Config, config
 			})
 		}
 	}
+	out.addLocalityPinnedDependencies(ps)
+	out.addCredentialDependencies()
 
 	if sidecar.OutboundTrafficPolicy == nil {
 		if ps.Mesh.OutboundTrafficPolicy != nil {
@@ -426,6 +558,31 @@ func ConvertToSidecarScope(ps *PushContext, sidecarConfig *config.Config, config
 		out.OutboundTrafficPolicy = sidecar.OutboundTrafficPolicy
 	}
 
+	if value, f := sidecarConfig.Annotations[gateway.EgressProxyAnnotation]; f {
+		if hostname, port, err := gateway.ParseEgressProxyAnnotation(value); err != nil {
+			log.Warnf("ignoring invalid %s annotation of Sidecar %s/%s: %v",
+				gateway.EgressProxyAnnotation, sidecarConfig.Namespace, sidecarConfig.Name, err)
+		} else {
+			out.EgressProxy = &EgressProxy{Hostname: host.Name(hostname), Port: int(port)}
+			if credential := sidecarConfig.Annotations[gateway.EgressProxyCredentialAnnotation]; credential != "" {
+				out.EgressProxy.CredentialName = credential
+				out.EgressProxy.Namespace = sidecarConfig.Namespace
+				out.AddConfigDependencies(*out.EgressProxy.CredentialKey())
+			}
+		}
+	}
+
+	if value, f := sidecarConfig.Annotations[gateway.PassthroughTLSOriginationAnnotation]; f {
+		if enabled, err := strconv.ParseBool(value); err != nil {
+			log.Warnf("ignoring invalid %s annotation of Sidecar %s/%s: %v",
+				gateway.PassthroughTLSOriginationAnnotation, sidecarConfig.Namespace, sidecarConfig.Name, err)
+		} else {
+			out.PassthroughTLSOrigination = enabled
+		}
+	}
+
+	out.FilterBypassPaths = filterBypassPaths(sidecarConfig, ps.sidecarIndex.rootConfig)
+
 	return out
 }
 
@@ -455,7 +612,7 @@ func convertIstioListenerToWrapper(ps *PushContext, configNamespace string,
 		ConfigNamespace: configNamespace,
 	}
 
-	vses := ps.VirtualServicesForGateway(&dummyNode, constants.IstioMeshGateway)
+	vses := ps.virtualServicesForGateway(configNamespace, constants.IstioMeshGateway)
 	out.virtualServices = SelectVirtualServices(vses, out.listenerHosts)
 	svces := ps.Services(&dummyNode)
 	out.services = out.selectServices(svces, configNamespace, out.listenerHosts)
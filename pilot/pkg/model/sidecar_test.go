@@ -22,8 +22,10 @@ import (
 
 	"istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -1856,3 +1858,175 @@ outboundTrafficPolicy:
 		})
 	}
 }
+
+func TestSidecarFilterBypassPaths(t *testing.T) {
+	defaultValue := features.FilterBypassPaths
+	features.FilterBypassPaths = "/healthz, /metrics"
+	defer func() { features.FilterBypassPaths = defaultValue }()
+
+	sidecar := func(namespace string, annotations map[string]string) *config.Config {
+		return &config.Config{
+			Meta: config.Meta{
+				Name:        "foo",
+				Namespace:   namespace,
+				Annotations: annotations,
+			},
+			Spec: &networking.Sidecar{},
+		}
+	}
+	rootSidecar := sidecar("istio-system", map[string]string{FilterBypassPathsAnnotation: "/ready"})
+	tests := []struct {
+		name    string
+		sidecar *config.Config
+		root    *config.Config
+		want    []string
+	}{
+		{
+			name: "no Sidecar",
+			want: []string{"/healthz", "/metrics"},
+		},
+		{
+			name:    "Sidecar without annotation",
+			sidecar: sidecar("not-default", nil),
+			want:    []string{"/healthz", "/metrics"},
+		},
+		{
+			name:    "Sidecar with annotation",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: "/ready,/stats/*"}),
+			want:    []string{"/ready", "/stats/*"},
+		},
+		{
+			name:    "Sidecar with empty annotation",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: ""}),
+		},
+		{
+			name:    "Sidecar with invalid annotation",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: "healthz"}),
+			want:    []string{"/healthz", "/metrics"},
+		},
+		{
+			name:    "Sidecar with prefix not ending with a path segment",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: "/metrics*"}),
+			want:    []string{"/healthz", "/metrics"},
+		},
+		{
+			name: "no Sidecar with annotated root namespace Sidecar",
+			root: rootSidecar,
+			want: []string{"/ready"},
+		},
+		{
+			name:    "Sidecar without annotation inheriting the root namespace one",
+			sidecar: sidecar("not-default", nil),
+			root:    rootSidecar,
+			want:    []string{"/ready"},
+		},
+		{
+			name:    "Sidecar with annotation overriding the root namespace one",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: "/admin/*"}),
+			root:    rootSidecar,
+			want:    []string{"/admin/*"},
+		},
+		{
+			name:    "Sidecar with invalid annotation inheriting the root namespace one",
+			sidecar: sidecar("not-default", map[string]string{FilterBypassPathsAnnotation: "admin"}),
+			root:    rootSidecar,
+			want:    []string{"/ready"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ps := NewPushContext()
+			m := mesh.DefaultMeshConfig()
+			ps.Mesh = &m
+			ps.sidecarIndex.rootConfig = test.root
+			var sidecarScope *SidecarScope
+			if test.sidecar == nil {
+				sidecarScope = DefaultSidecarScopeForNamespace(ps, "not-default")
+			} else {
+				sidecarScope = ConvertToSidecarScope(ps, test.sidecar, "not-default")
+			}
+			if !reflect.DeepEqual(test.want, sidecarScope.FilterBypassPaths) {
+				t.Errorf("got filter bypass paths %v, want %v", sidecarScope.FilterBypassPaths, test.want)
+			}
+		})
+	}
+}
+
+func TestSidecarEgressProxyCredential(t *testing.T) {
+	ps := NewPushContext()
+	m := mesh.DefaultMeshConfig()
+	ps.Mesh = &m
+	sidecarConfig := &config.Config{
+		Meta: config.Meta{
+			Name:      "foo",
+			Namespace: "ns1",
+			Annotations: map[string]string{
+				gateway.EgressProxyAnnotation:           "proxy.corp.example.com:3128",
+				gateway.EgressProxyCredentialAnnotation: "proxy-credential",
+			},
+		},
+		Spec: &networking.Sidecar{},
+	}
+	sidecarScope := ConvertToSidecarScope(ps, sidecarConfig, "ns1")
+	want := &EgressProxy{Hostname: "proxy.corp.example.com", Port: 3128, CredentialName: "proxy-credential", Namespace: "ns1"}
+	if !reflect.DeepEqual(sidecarScope.EgressProxy, want) {
+		t.Fatalf("got egress proxy %+v, want %+v", sidecarScope.EgressProxy, want)
+	}
+	if !sidecarScope.DependsOnConfig(ConfigKey{Kind: gvk.Secret, Name: "proxy-credential", Namespace: "ns1"}) {
+		t.Errorf("expected the scope to depend on the Secret of the credential")
+	}
+	if sidecarScope.DependsOnConfig(ConfigKey{Kind: gvk.Secret, Name: "other", Namespace: "ns1"}) {
+		t.Errorf("expected the scope not to depend on other Secrets")
+	}
+}
+
+func TestSidecarDestinationRuleCredentials(t *testing.T) {
+	ps := NewPushContext()
+	m := mesh.DefaultMeshConfig()
+	ps.Mesh = &m
+	ps.ServiceIndex.public = append(ps.ServiceIndex.public, &Service{
+		Hostname:   "api.example.com",
+		Attributes: ServiceAttributes{Namespace: "ns1"},
+	})
+	ps.SetDestinationRules([]config.Config{{
+		Meta: config.Meta{Name: "api", Namespace: "ns1"},
+		Spec: &networking.DestinationRule{
+			Host:     "api.example.com",
+			ExportTo: []string{"*"},
+			TrafficPolicy: &networking.TrafficPolicy{
+				Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_MUTUAL, CredentialName: "api-cert"},
+				PortLevelSettings: []*networking.TrafficPolicy_PortTrafficPolicy{{
+					Port: &networking.PortSelector{Number: 8443},
+					Tls:  &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_MUTUAL, CredentialName: "kubernetes://certs/api-port-cert"},
+				}},
+			},
+			Subsets: []*networking.Subset{{
+				Name: "v2",
+				TrafficPolicy: &networking.TrafficPolicy{
+					Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE, CredentialName: "api-v2-ca"},
+				},
+			}},
+		},
+	}})
+
+	secrets := map[ConfigKey]bool{
+		{Kind: gvk.Secret, Name: "api-cert", Namespace: "ns1"}:        true,
+		{Kind: gvk.Secret, Name: "api-port-cert", Namespace: "certs"}: true,
+		{Kind: gvk.Secret, Name: "api-v2-ca", Namespace: "ns1"}:       true,
+		{Kind: gvk.Secret, Name: "api-cert", Namespace: "other"}:      false,
+		{Kind: gvk.Secret, Name: "other", Namespace: "ns1"}:           false,
+	}
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("sidecar credentialName %v", enabled), func(t *testing.T) {
+			defer func(v bool) { features.EnableSidecarCredentialName = v }(features.EnableSidecarCredentialName)
+			features.EnableSidecarCredentialName = enabled
+
+			sidecarScope := DefaultSidecarScopeForNamespace(ps, "ns1")
+			for secret, want := range secrets {
+				if got := sidecarScope.DependsOnConfig(secret); got != (want && enabled) {
+					t.Errorf("got dependency on %v %v, want %v", secret, got, want && enabled)
+				}
+			}
+		})
+	}
+}
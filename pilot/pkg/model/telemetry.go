@@ -15,7 +15,11 @@
 package model
 
 import (
+	"fmt"
+	"net/url"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +38,7 @@ import (
 	"istio.io/api/envoy/extensions/stats"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config/labels"
@@ -49,6 +54,50 @@ type Telemetry struct {
 	Name      string         `json:"name"`
 	Namespace string         `json:"namespace"`
 	Spec      *tpb.Telemetry `json:"spec"`
+	// CorrelationID is the value of the CorrelationIDAnnotation of the Telemetry, if any.
+	CorrelationID string `json:"correlationID,omitempty"`
+	// Baggage is the value of the BaggageAnnotation of the Telemetry, if any.
+	Baggage *string `json:"baggage,omitempty"`
+	// ErrorResponse is the value of the ErrorResponseAnnotation of the Telemetry, if any.
+	ErrorResponse string `json:"errorResponse,omitempty"`
+	// GatewaySource is the value of the GatewaySourceAnnotation of the Telemetry, if any.
+	GatewaySource string `json:"gatewaySource,omitempty"`
+}
+
+// CorrelationIDAnnotation enables a mesh wide correlation ID for the workloads a Telemetry applies to, when set
+// to "true". Their proxies generate an x-request-id for requests without one, keep the x-request-id received from
+// outside the mesh instead of replacing it, and add it to spans as the istio.correlation_id tag, so that the same
+// ID is found in the access logs and traces of every hop. Like other Telemetry settings, the value of a workload
+// Telemetry overrides the one of its namespace, which overrides the one of the root namespace.
+const CorrelationIDAnnotation = "telemetry.istio.io/correlation-id"
+
+// BaggageAnnotation lists the mesh entries added to the W3C baggage of the requests sent by the workloads a
+// Telemetry applies to, when the requests have no baggage yet, so that they are set by the first hop and
+// propagated to the next ones. The value is a comma separated list of namespace, service and revision, which add
+// the istio.namespace, istio.canonical_service and istio.canonical_revision entries identifying the workload, with
+// the same values as the span tags of the proxy. An empty value adds none.
+const BaggageAnnotation = "telemetry.istio.io/baggage"
+
+// ErrorResponseAnnotation classifies as errors, in the metrics of the workloads a Telemetry applies to, the
+// responses of backends reporting errors without an HTTP error status. The value is a comma separated list of
+// classifiers: header:<name> matches the responses with the given header, and grpc-status matches the gRPC responses
+// with a status other than OK. The response_code of the classified responses is reported as 500.
+const ErrorResponseAnnotation = "telemetry.istio.io/error-response"
+
+// GatewaySourceAnnotation derives the source attributes of the requests received by the gateways a Telemetry applies
+// to from the authenticated identity of the clients, which are reported as unknown when they are outside the mesh.
+// The value is a comma separated list of <dimension>=<field> entries. The dimension is one of the source dimensions
+// of the standard metrics, such as source_workload or source_principal. The field is jwt:<claim> for a claim of the
+// JWT validated by a RequestAuthentication, or cert:subject or cert:uri_san for the client certificate. The attributes
+// are set in the dimensions of the metrics, and appended to the default format of the access logs of the Telemetry
+// API providers. JWT claims only apply to HTTP traffic.
+const GatewaySourceAnnotation = "telemetry.istio.io/gateway-source"
+
+// baggageEntries maps the values of the BaggageAnnotation to the keys of the baggage entries they add.
+var baggageEntries = map[string]string{
+	"namespace": "istio.namespace",
+	"service":   "istio.canonical_service",
+	"revision":  "istio.canonical_revision",
 }
 
 // Telemetries organizes Telemetry configuration by namespace.
@@ -91,6 +140,8 @@ type metricsKey struct {
 	telemetryKey
 	Class    networking.ListenerClass
 	Protocol networking.ListenerProtocol
+	// Passthrough is set for the filters of the catch-all passthrough and blackhole filter chains.
+	Passthrough bool
 }
 
 // getTelemetries returns the Telemetry configurations for the given environment.
@@ -113,6 +164,18 @@ func getTelemetries(env *Environment) (*Telemetries, error) {
 			Namespace: config.Namespace,
 			Spec:      config.Spec.(*tpb.Telemetry),
 		}
+		if v, f := config.Annotations[CorrelationIDAnnotation]; f {
+			telemetry.CorrelationID = v
+		}
+		if v, f := config.Annotations[BaggageAnnotation]; f {
+			telemetry.Baggage = &v
+		}
+		if v, f := config.Annotations[ErrorResponseAnnotation]; f {
+			telemetry.ErrorResponse = v
+		}
+		if v, f := config.Annotations[GatewaySourceAnnotation]; f {
+			telemetry.GatewaySource = v
+		}
 		telemetries.namespaceToTelemetries[config.Namespace] = append(telemetries.namespaceToTelemetries[config.Namespace], telemetry)
 	}
 
@@ -130,6 +193,10 @@ type telemetryFilterConfig struct {
 	Metrics       bool
 	AccessLogging bool
 	LogsFilter    *tpb.AccessLogging_Filter
+	// ErrorResponseCondition is the expression of the responses classified as errors by the ErrorResponseAnnotation.
+	ErrorResponseCondition string
+	// SourceDimensions are the expressions of the source dimensions set by the GatewaySourceAnnotation.
+	SourceDimensions map[string]string
 }
 
 func (t telemetryFilterConfig) MetricsForClass(c networking.ListenerClass) []metricsOverride {
@@ -162,8 +229,17 @@ type tagOverride struct {
 type computedTelemetries struct {
 	telemetryKey
 	Metrics []*tpb.Metrics
-	Logging []*tpb.AccessLogging
+	// Logging are the access logging configs of each Telemetry, from the root namespace to the workload.
+	Logging [][]*tpb.AccessLogging
 	Tracing []*tpb.Tracing
+	// CorrelationID is the most specific CorrelationIDAnnotation value, if any.
+	CorrelationID string
+	// Baggage is the most specific BaggageAnnotation value, if any.
+	Baggage *string
+	// ErrorResponse is the most specific ErrorResponseAnnotation value, if any.
+	ErrorResponse string
+	// GatewaySource is the most specific GatewaySourceAnnotation value, if any.
+	GatewaySource string
 }
 
 type TracingConfig struct {
@@ -176,7 +252,8 @@ type TracingConfig struct {
 
 type LoggingConfig struct {
 	Providers []*meshconfig.MeshConfig_ExtensionProvider
-	Filter    *tpb.AccessLogging_Filter
+	// Filters are the filters of the providers, by provider name. The providers without a filter log all requests.
+	Filters map[string]*tpb.AccessLogging_Filter
 }
 
 // AccessLogging returns the logging configuration for a given proxy. If nil is returned, access logs
@@ -188,8 +265,8 @@ func (t *Telemetries) AccessLogging(proxy *Proxy) *LoggingConfig {
 		return nil
 	}
 	cfg := LoggingConfig{}
-	providers, f := mergeLogs(ct.Logging, t.meshConfig)
-	cfg.Filter = f
+	providers, filters := mergeLogs(ct.Logging, t.meshConfig)
+	cfg.Filters = filters
 	for _, p := range providers.SortedList() {
 		fp := t.fetchProvider(p)
 		if fp != nil {
@@ -199,6 +276,27 @@ func (t *Telemetries) AccessLogging(proxy *Proxy) *LoggingConfig {
 	return &cfg
 }
 
+// GatewaySourceAttributes returns the source attributes derived from the identity of the clients of a gateway by the
+// GatewaySourceAnnotation of the Telemetries applying to it, or nil if there are none or the proxy is not a gateway.
+func (t *Telemetries) GatewaySourceAttributes(proxy *Proxy) []GatewaySourceAttribute {
+	if proxy.Type != Router {
+		return nil
+	}
+	return t.gatewaySourceAttributes(proxy, t.applicableTelemetries(proxy).GatewaySource)
+}
+
+func (t *Telemetries) gatewaySourceAttributes(proxy *Proxy, v string) []GatewaySourceAttribute {
+	if v == "" {
+		return nil
+	}
+	attrs, err := ParseGatewaySource(v)
+	if err != nil {
+		telemetryLog.Warnf("ignoring invalid %s annotation value %q for proxy %s: %v", GatewaySourceAnnotation, v, proxy.ID, err)
+		return nil
+	}
+	return attrs
+}
+
 // Tracing returns the logging tracing for a given proxy. If nil is returned, tracing
 // are not configured via Telemetry and should use fallback mechanisms. If a non-nil but disabled is set,
 // then tracing is explicitly disabled
@@ -271,9 +369,61 @@ func (t *Telemetries) Tracing(proxy *Proxy) *TracingConfig {
 	return &cfg
 }
 
+// CorrelationID returns true if the mesh correlation ID is enabled for a given proxy by the
+// CorrelationIDAnnotation of the Telemetries applying to it.
+func (t *Telemetries) CorrelationID(proxy *Proxy) bool {
+	v := t.applicableTelemetries(proxy).CorrelationID
+	if v == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		telemetryLog.Warnf("ignoring invalid %s annotation value %q for proxy %s", CorrelationIDAnnotation, v, proxy.ID)
+		return false
+	}
+	return enabled
+}
+
+// Baggage returns the W3C baggage of the mesh entries to add to the requests sent by a given proxy, as configured
+// by the BaggageAnnotation of the Telemetries applying to it, or an empty string if there are none.
+func (t *Telemetries) Baggage(proxy *Proxy) string {
+	v := t.applicableTelemetries(proxy).Baggage
+	if v == nil {
+		return ""
+	}
+	values := map[string]string{
+		"namespace": proxy.Metadata.Namespace,
+		"service":   proxy.Metadata.Labels[IstioCanonicalServiceLabelName],
+		"revision":  proxy.Metadata.Labels[IstioCanonicalServiceRevisionLabelName],
+	}
+	if values["revision"] == "" {
+		values["revision"] = "latest"
+	}
+	if values["service"] == "" {
+		values["service"] = "unknown"
+	}
+	var entries []string
+	for _, name := range strings.Split(*v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key, f := baggageEntries[name]
+		if !f {
+			telemetryLog.Warnf("ignoring unknown entry %q of the %s annotation for proxy %s", name, BaggageAnnotation, proxy.ID)
+			continue
+		}
+		if values[name] == "" {
+			continue
+		}
+		entries = append(entries, key+"="+url.PathEscape(values[name]))
+	}
+	return strings.Join(entries, ",")
+}
+
 // HTTPFilters computes the HttpFilter for a given proxy/class
 func (t *Telemetries) HTTPFilters(proxy *Proxy, class networking.ListenerClass) []*hcm.HttpFilter {
-	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolHTTP); res != nil {
+	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolHTTP, false); res != nil {
 		return res.([]*hcm.HttpFilter)
 	}
 	return nil
@@ -281,7 +431,17 @@ func (t *Telemetries) HTTPFilters(proxy *Proxy, class networking.ListenerClass)
 
 // TCPFilters computes the TCPFilters for a given proxy/class
 func (t *Telemetries) TCPFilters(proxy *Proxy, class networking.ListenerClass) []*listener.Filter {
-	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolTCP); res != nil {
+	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolTCP, false); res != nil {
+		return res.([]*listener.Filter)
+	}
+	return nil
+}
+
+// PassthroughTCPFilters computes the TCPFilters for the passthrough and blackhole filter chains of a given
+// proxy/class. Traffic on these chains has no known destination service, so the Prometheus metrics are
+// attributed to the original destination address and requested server name instead.
+func (t *Telemetries) PassthroughTCPFilters(proxy *Proxy, class networking.ListenerClass) []*listener.Filter {
+	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolTCP, true); res != nil {
 		return res.([]*listener.Filter)
 	}
 	return nil
@@ -296,17 +456,13 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 	namespace := proxy.ConfigNamespace
 	workload := labels.Collection{proxy.Metadata.Labels}
 	// Order here matters. The latter elements will override the first elements
-	ms := []*tpb.Metrics{}
-	ls := []*tpb.AccessLogging{}
-	ts := []*tpb.Tracing{}
+	scopes := []Telemetry{}
 	key := telemetryKey{}
 	if t.rootNamespace != "" {
 		telemetry := t.namespaceWideTelemetryConfig(t.rootNamespace)
 		if telemetry != (Telemetry{}) {
 			key.Root = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
-			ms = append(ms, telemetry.Spec.GetMetrics()...)
-			ls = append(ls, telemetry.Spec.GetAccessLogging()...)
-			ts = append(ts, telemetry.Spec.GetTracing()...)
+			scopes = append(scopes, telemetry)
 		}
 	}
 
@@ -314,9 +470,7 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 		telemetry := t.namespaceWideTelemetryConfig(namespace)
 		if telemetry != (Telemetry{}) {
 			key.Namespace = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
-			ms = append(ms, telemetry.Spec.GetMetrics()...)
-			ls = append(ls, telemetry.Spec.GetAccessLogging()...)
-			ts = append(ts, telemetry.Spec.GetTracing()...)
+			scopes = append(scopes, telemetry)
 		}
 	}
 
@@ -328,18 +482,42 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 		selector := labels.Instance(spec.GetSelector().GetMatchLabels())
 		if workload.IsSupersetOf(selector) {
 			key.Workload = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
-			ms = append(ms, spec.GetMetrics()...)
-			ls = append(ls, spec.GetAccessLogging()...)
-			ts = append(ts, spec.GetTracing()...)
+			scopes = append(scopes, telemetry)
 			break
 		}
 	}
 
-	return computedTelemetries{
+	ct := computedTelemetries{
 		telemetryKey: key,
-		Metrics:      ms,
-		Logging:      ls,
-		Tracing:      ts,
+		Metrics:      []*tpb.Metrics{},
+		Logging:      [][]*tpb.AccessLogging{},
+		Tracing:      []*tpb.Tracing{},
+	}
+	for _, telemetry := range scopes {
+		ct.merge(telemetry)
+	}
+	return ct
+}
+
+// merge adds the settings of a Telemetry to the computed ones, overriding the annotation values
+// it sets. Telemetries must be merged from the least to the most specific one.
+func (ct *computedTelemetries) merge(telemetry Telemetry) {
+	ct.Metrics = append(ct.Metrics, telemetry.Spec.GetMetrics()...)
+	if len(telemetry.Spec.GetAccessLogging()) > 0 {
+		ct.Logging = append(ct.Logging, telemetry.Spec.GetAccessLogging())
+	}
+	ct.Tracing = append(ct.Tracing, telemetry.Spec.GetTracing()...)
+	if telemetry.CorrelationID != "" {
+		ct.CorrelationID = telemetry.CorrelationID
+	}
+	if telemetry.Baggage != nil {
+		ct.Baggage = telemetry.Baggage
+	}
+	if telemetry.ErrorResponse != "" {
+		ct.ErrorResponse = telemetry.ErrorResponse
+	}
+	if telemetry.GatewaySource != "" {
+		ct.GatewaySource = telemetry.GatewaySource
 	}
 }
 
@@ -347,7 +525,8 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 // set of applicable Telemetries, merges them, then translates to the appropriate filters based on the
 // extension providers in the mesh config. Where possible, the result is cached.
 // Currently, this includes metrics and access logging, as some providers are implemented in filters.
-func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerClass, protocol networking.ListenerProtocol) interface{} {
+func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerClass, protocol networking.ListenerProtocol,
+	passthrough bool) interface{} {
 	if t == nil {
 		return nil
 	}
@@ -358,6 +537,7 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 		telemetryKey: c.telemetryKey,
 		Class:        class,
 		Protocol:     protocol,
+		Passthrough:  passthrough,
 	}
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -369,7 +549,16 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 	// First, take all the metrics configs and transform them into a normalized form
 	tmm := mergeMetrics(c.Metrics, t.meshConfig)
 	// Additionally, fetch relevant access logging configurations
-	tml, logsFilter := mergeLogs(c.Logging, t.meshConfig)
+	tml, logsFilters := mergeLogs(c.Logging, t.meshConfig)
+	errorResponseCondition, err := errorResponseCondition(c.ErrorResponse)
+	if err != nil {
+		telemetryLog.Warnf("ignoring invalid %s annotation value %q for proxy %s: %v", ErrorResponseAnnotation,
+			c.ErrorResponse, proxy.ID, err)
+	}
+	var sourceDimensions map[string]string
+	if class == networking.ListenerClassGateway {
+		sourceDimensions = gatewaySourceDimensions(t.gatewaySourceAttributes(proxy, c.GatewaySource), protocol)
+	}
 
 	// The above result is in a nested map to deduplicate responses. This loses ordering, so we convert to
 	// a list to retain stable naming
@@ -386,11 +575,13 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 		_, logging := tml[k]
 		_, metrics := tmm[k]
 		cfg := telemetryFilterConfig{
-			Provider:      p,
-			metricsConfig: tmm[k],
-			AccessLogging: logging,
-			Metrics:       metrics,
-			LogsFilter:    logsFilter,
+			Provider:               p,
+			metricsConfig:          tmm[k],
+			AccessLogging:          logging,
+			Metrics:                metrics,
+			LogsFilter:             logsFilters[k],
+			ErrorResponseCondition: errorResponseCondition,
+			SourceDimensions:       sourceDimensions,
 		}
 		m = append(m, cfg)
 	}
@@ -401,7 +592,7 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 	case networking.ListenerProtocolHTTP:
 		res = buildHTTPTelemetryFilter(class, m)
 	default:
-		res = buildTCPTelemetryFilter(class, m)
+		res = buildTCPTelemetryFilter(class, m, passthrough)
 	}
 
 	// Update cache
@@ -409,55 +600,73 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 	return res
 }
 
-// mergeLogs returns the set of providers for the given logging configuration.
-// This currently is just the names of providers as there is no access logging configuration, but
-// in the future it will likely be extended
-func mergeLogs(logs []*tpb.AccessLogging, mesh *meshconfig.MeshConfig) (sets.Set, *tpb.AccessLogging_Filter) {
+// mergeLogs returns the set of providers for the given logging configuration, and their filters.
+// Each level, from the root namespace to the workload, is the list of the access logging configs of a Telemetry.
+// The providers of a level are those of all its configs, so that several providers can be used with different
+// filters, and the configs without providers apply to the providers of the parent level. The providers of the most
+// specific level setting some override the ones of the parent levels, and the filter of a provider is the one of
+// the most specific config setting one.
+func mergeLogs(levels [][]*tpb.AccessLogging, mesh *meshconfig.MeshConfig) (sets.Set, map[string]*tpb.AccessLogging_Filter) {
 	providers := sets.NewSet()
 
-	if len(logs) == 0 {
+	if len(levels) == 0 {
 		for _, dp := range mesh.GetDefaultProviders().GetAccessLogging() {
 			// Insert the default provider.
 			providers.Insert(dp)
 		}
 		return providers, nil
 	}
-	var loggingFilter *tpb.AccessLogging_Filter
-	providerNames := mesh.GetDefaultProviders().GetAccessLogging()
-	for _, m := range logs {
-		names := getProviderNames(m.Providers)
-		if len(names) > 0 {
-			providerNames = names
-		}
 
-		if m.Filter != nil {
-			loggingFilter = m.Filter
+	// Resolve the providers each config applies to, and the providers in scope.
+	parentProviders := mesh.GetDefaultProviders().GetAccessLogging()
+	inScopeProviders := sets.NewSet(parentProviders...)
+	names := make([][][]string, len(levels))
+	for i, logs := range levels {
+		levelProviders := sets.NewSet()
+		explicit := false
+		names[i] = make([][]string, len(logs))
+		for j, m := range logs {
+			providerNames := getProviderNames(m.Providers)
+			if len(providerNames) > 0 {
+				explicit = true
+			} else {
+				providerNames = parentProviders
+			}
+			names[i][j] = providerNames
+			levelProviders.Insert(providerNames...)
+		}
+		if explicit {
+			inScopeProviders = levelProviders
 		}
+		parentProviders = levelProviders.SortedList()
 	}
-	inScopeProviders := sets.NewSet(providerNames...)
 
-	parentProviders := mesh.GetDefaultProviders().GetAccessLogging()
-	for _, m := range logs {
-		providerNames := getProviderNames(m.Providers)
-		if len(providerNames) == 0 {
-			providerNames = parentProviders
-		}
-		parentProviders = providerNames
-		for _, provider := range providerNames {
-			if !inScopeProviders.Contains(provider) {
-				// We don't care about this, remove it
-				// This occurs when a top level provider is later disabled by a lower level
-				continue
-			}
-			if m.GetDisabled().GetValue() {
-				providers.Delete(provider)
-				continue
+	var filters map[string]*tpb.AccessLogging_Filter
+	for i, logs := range levels {
+		for j, m := range logs {
+			for _, provider := range names[i][j] {
+				if !inScopeProviders.Contains(provider) {
+					// We don't care about this, remove it
+					// This occurs when a top level provider is later disabled by a lower level
+					continue
+				}
+				if m.GetDisabled().GetValue() {
+					providers.Delete(provider)
+					delete(filters, provider)
+					continue
+				}
+				providers.Insert(provider)
+				if m.Filter != nil {
+					if filters == nil {
+						filters = map[string]*tpb.AccessLogging_Filter{}
+					}
+					filters[provider] = m.Filter
+				}
 			}
-			providers.Insert(provider)
 		}
 	}
 
-	return providers, loggingFilter
+	return providers, filters
 }
 
 func (t *Telemetries) namespaceWideTelemetryConfig(namespace string) Telemetry {
@@ -677,7 +886,7 @@ func buildHTTPTelemetryFilter(class networking.ListenerClass, filterConfigs []te
 				// No logging for prometheus
 				continue
 			}
-			cfg := generateStatsConfig(class, cfg)
+			cfg := generateStatsConfig(class, cfg, true)
 			vmConfig := ConstructVMConfig("/etc/istio/extensions/stats-filter.compiled.wasm", "envoy.wasm.stats")
 			root := statsRootIDForClass(class)
 			vmConfig.VmConfig.VmId = root
@@ -721,12 +930,15 @@ func buildHTTPTelemetryFilter(class networking.ListenerClass, filterConfigs []te
 	return res
 }
 
-func buildTCPTelemetryFilter(class networking.ListenerClass, telemetryConfigs []telemetryFilterConfig) []*listener.Filter {
+func buildTCPTelemetryFilter(class networking.ListenerClass, telemetryConfigs []telemetryFilterConfig, passthrough bool) []*listener.Filter {
 	res := []*listener.Filter{}
 	for _, telemetryCfg := range telemetryConfigs {
 		switch telemetryCfg.Provider.GetProvider().(type) {
 		case *meshconfig.MeshConfig_ExtensionProvider_Prometheus:
-			cfg := generateStatsConfig(class, telemetryCfg)
+			cfg := generateStatsConfig(class, telemetryCfg, false)
+			if passthrough {
+				cfg = generatePassthroughStatsConfig(class, telemetryCfg)
+			}
 			vmConfig := ConstructVMConfig("/etc/istio/extensions/stats-filter.compiled.wasm", "envoy.wasm.stats")
 			root := statsRootIDForClass(class)
 			vmConfig.VmConfig.VmId = "tcp_" + root
@@ -882,10 +1094,186 @@ var metricToPrometheusMetric = map[string]string{
 	"GRPC_RESPONSE_MESSAGES": "response_messages_total",
 }
 
-func generateStatsConfig(class networking.ListenerClass, metricsCfg telemetryFilterConfig) *anypb.Any {
+// passthroughDimensions are the dimensions added to the metrics of the passthrough and blackhole filter
+// chains, so that traffic to unknown destinations can be attributed.
+var passthroughDimensions = map[string]string{
+	"destination_port":      "string(destination.port)",
+	"requested_server_name": "connection.requested_server_name",
+}
+
+// passthroughAddressDimension is the passthrough dimension of the original destination IP. Its cardinality is not
+// bounded, so it is only added with features.PassthroughMetricsDestinationAddress.
+const passthroughAddressDimension = "destination_address"
+
+// errorResponseHeaderName matches the header names allowed in the ErrorResponseAnnotation, which are quoted in
+// expressions.
+var errorResponseHeaderName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// errorResponseCondition translates the value of the ErrorResponseAnnotation to the expression of the responses
+// classified as errors.
+func errorResponseCondition(v string) (string, error) {
+	var conditions []string
+	for _, classifier := range strings.Split(v, ",") {
+		classifier = strings.TrimSpace(classifier)
+		switch {
+		case classifier == "":
+			continue
+		case classifier == "grpc-status":
+			// The status is in the trailers, or in the headers of trailers-only responses.
+			conditions = append(conditions,
+				"('grpc-status' in response.headers && response.headers['grpc-status'] != '0')",
+				"('grpc-status' in response.trailers && response.trailers['grpc-status'] != '0')")
+		case strings.HasPrefix(classifier, "header:"):
+			name := strings.ToLower(strings.TrimPrefix(classifier, "header:"))
+			if !errorResponseHeaderName.MatchString(name) {
+				return "", fmt.Errorf("invalid header name %q", name)
+			}
+			conditions = append(conditions, fmt.Sprintf("'%s' in response.headers", name))
+		default:
+			return "", fmt.Errorf("unknown classifier %q", classifier)
+		}
+	}
+	return strings.Join(conditions, " || "), nil
+}
+
+// gatewaySourceDimensionNames are the dimensions which can be set by the GatewaySourceAnnotation.
+var gatewaySourceDimensionNames = sets.NewSet("source_principal", "source_workload", "source_workload_namespace", "source_app",
+	"source_version", "source_canonical_service", "source_canonical_revision", "source_cluster")
+
+// gatewaySourceClaim matches the JWT claims allowed in the GatewaySourceAnnotation, which are quoted in expressions.
+var gatewaySourceClaim = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.:/-]*$`)
+
+// GatewaySourceAttribute is a source dimension derived from the identity of the client by the GatewaySourceAnnotation.
+type GatewaySourceAttribute struct {
+	Dimension string
+	// Claim is the JWT claim the dimension is derived from, if any.
+	Claim string
+	// CertField is the field of the client certificate the dimension is derived from, subject or uri_san, if any.
+	CertField string
+}
+
+// Expression returns the expression of the value of the attribute, evaluated by the stats filter.
+func (a GatewaySourceAttribute) Expression() string {
+	switch {
+	case a.Claim != "":
+		return fmt.Sprintf("metadata.filter_metadata['istio_authn']['request.auth.claims']['%s'][0]", a.Claim)
+	case a.CertField == "subject":
+		return "connection.subject_peer_certificate"
+	default:
+		return "connection.uri_san_peer_certificate"
+	}
+}
+
+// LogOperator returns the command operator of the value of the attribute in the access log formats.
+func (a GatewaySourceAttribute) LogOperator() string {
+	switch {
+	case a.Claim != "":
+		return fmt.Sprintf("%%DYNAMIC_METADATA(istio_authn:request.auth.claims:%s)%%", a.Claim)
+	case a.CertField == "subject":
+		return "%DOWNSTREAM_PEER_SUBJECT%"
+	default:
+		return "%DOWNSTREAM_PEER_URI_SAN%"
+	}
+}
+
+// ParseGatewaySource parses the value of the GatewaySourceAnnotation.
+func ParseGatewaySource(v string) ([]GatewaySourceAttribute, error) {
+	var attrs []GatewaySourceAttribute
+	seen := sets.NewSet()
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid entry %q, expected <dimension>=<field>", entry)
+		}
+		attr := GatewaySourceAttribute{Dimension: strings.TrimSpace(parts[0])}
+		if !gatewaySourceDimensionNames.Contains(attr.Dimension) {
+			return nil, fmt.Errorf("unknown source dimension %q", attr.Dimension)
+		}
+		if seen.Contains(attr.Dimension) {
+			return nil, fmt.Errorf("duplicate source dimension %q", attr.Dimension)
+		}
+		seen.Insert(attr.Dimension)
+		field := strings.TrimSpace(parts[1])
+		switch {
+		case strings.HasPrefix(field, "jwt:"):
+			attr.Claim = strings.TrimPrefix(field, "jwt:")
+			if !gatewaySourceClaim.MatchString(attr.Claim) {
+				return nil, fmt.Errorf("invalid claim %q", attr.Claim)
+			}
+		case field == "cert:subject", field == "cert:uri_san":
+			attr.CertField = strings.TrimPrefix(field, "cert:")
+		default:
+			return nil, fmt.Errorf("unknown field %q, expected jwt:<claim>, cert:subject or cert:uri_san", field)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// gatewaySourceDimensions returns the expressions of the source dimensions of the attributes. The JWT claims are only
+// available to HTTP filters.
+func gatewaySourceDimensions(attrs []GatewaySourceAttribute, protocol networking.ListenerProtocol) map[string]string {
+	var dims map[string]string
+	for _, attr := range attrs {
+		if attr.Claim != "" && protocol != networking.ListenerProtocolHTTP {
+			continue
+		}
+		if dims == nil {
+			dims = map[string]string{}
+		}
+		dims[attr.Dimension] = attr.Expression()
+	}
+	return dims
+}
+
+// generateStatsConfig generates the stats config of the filter chains. The response_code dimension of the
+// responses classified as errors, only set on the HTTP filter chains since it is built from the HTTP response, and the
+// source dimensions of gateways come first, so that user overrides take precedence.
+func generateStatsConfig(class networking.ListenerClass, metricsCfg telemetryFilterConfig, http bool) *anypb.Any {
+	cfg := stats.PluginConfig{
+		DisableHostHeaderFallback: disableHostHeaderFallback(class),
+	}
+	if http && metricsCfg.ErrorResponseCondition != "" {
+		cfg.Metrics = []*stats.MetricConfig{{
+			Dimensions: map[string]string{
+				"response_code": fmt.Sprintf("(%s) ? '500' : string(response.code)", metricsCfg.ErrorResponseCondition),
+			},
+		}}
+	}
+	if len(metricsCfg.SourceDimensions) > 0 {
+		if cfg.Metrics == nil {
+			cfg.Metrics = []*stats.MetricConfig{{Dimensions: map[string]string{}}}
+		}
+		for k, v := range metricsCfg.SourceDimensions {
+			cfg.Metrics[0].Dimensions[k] = v
+		}
+	}
+	return marshalStatsConfig(class, metricsCfg, &cfg)
+}
+
+// generatePassthroughStatsConfig generates the stats config of the passthrough and blackhole filter chains.
+// The passthrough dimensions apply to all metrics, and come first so that user overrides take precedence.
+func generatePassthroughStatsConfig(class networking.ListenerClass, metricsCfg telemetryFilterConfig) *anypb.Any {
 	cfg := stats.PluginConfig{
 		DisableHostHeaderFallback: disableHostHeaderFallback(class),
+		Metrics: []*stats.MetricConfig{{
+			Dimensions: map[string]string{},
+		}},
+	}
+	for k, v := range passthroughDimensions {
+		cfg.Metrics[0].Dimensions[k] = v
 	}
+	if features.PassthroughMetricsDestinationAddress {
+		cfg.Metrics[0].Dimensions[passthroughAddressDimension] = "destination.address"
+	}
+	return marshalStatsConfig(class, metricsCfg, &cfg)
+}
+
+func marshalStatsConfig(class networking.ListenerClass, metricsCfg telemetryFilterConfig, cfg *stats.PluginConfig) *anypb.Any {
 	for _, override := range metricsCfg.MetricsForClass(class) {
 		metricName, f := metricToPrometheusMetric[override.Name]
 		if !f {
@@ -907,7 +1295,7 @@ func generateStatsConfig(class networking.ListenerClass, metricsCfg telemetryFil
 		cfg.Metrics = append(cfg.Metrics, mc)
 	}
 	// In WASM we are not actually processing protobuf at all, so we need to encode this to JSON
-	cfgJSON, _ := protomarshal.MarshalProtoNames(&cfg)
+	cfgJSON, _ := protomarshal.MarshalProtoNames(cfg)
 	return networking.MessageToAny(&wrappers.StringValue{Value: string(cfgJSON)})
 }
 
@@ -24,10 +24,13 @@ import (
 	wasmfilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/wasm/v3"
 	"github.com/gogo/protobuf/types"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/mesh"
@@ -286,8 +289,10 @@ func TestAccessLoggingWithFilter(t *testing.T) {
 			sidecar,
 			[]string{"custom-provider"},
 			&LoggingConfig{
-				Filter: &tpb.AccessLogging_Filter{
-					Expression: "response.code >= 400",
+				Filters: map[string]*tpb.AccessLogging_Filter{
+					"custom-provider": {
+						Expression: "response.code >= 400",
+					},
 				},
 			},
 		},
@@ -297,8 +302,10 @@ func TestAccessLoggingWithFilter(t *testing.T) {
 			sidecar,
 			[]string{"custom-provider"},
 			&LoggingConfig{
-				Filter: &tpb.AccessLogging_Filter{
-					Expression: "response.code >= 500",
+				Filters: map[string]*tpb.AccessLogging_Filter{
+					"custom-provider": {
+						Expression: "response.code >= 500",
+					},
 				},
 			},
 		},
@@ -315,6 +322,68 @@ func TestAccessLoggingWithFilter(t *testing.T) {
 	}
 }
 
+func TestAccessLoggingMultipleProviders(t *testing.T) {
+	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
+	errors := &tpb.AccessLogging_Filter{Expression: "response.code >= 400"}
+	serverErrors := &tpb.AccessLogging_Filter{Expression: "response.code >= 500"}
+	envoy := &tpb.Telemetry{
+		AccessLogging: []*tpb.AccessLogging{{Providers: []*tpb.ProviderRef{{Name: "envoy"}}}},
+	}
+	multiple := &tpb.Telemetry{
+		AccessLogging: []*tpb.AccessLogging{
+			{Providers: []*tpb.ProviderRef{{Name: "envoy"}}},
+			{Providers: []*tpb.ProviderRef{{Name: "stackdriver"}}, Filter: serverErrors},
+		},
+	}
+	inherited := &tpb.Telemetry{
+		AccessLogging: []*tpb.AccessLogging{
+			{Filter: errors},
+			{Providers: []*tpb.ProviderRef{{Name: "stackdriver"}}, Filter: serverErrors},
+		},
+	}
+	tests := []struct {
+		name        string
+		cfgs        []config.Config
+		want        []string
+		wantFilters map[string]*tpb.AccessLogging_Filter
+	}{
+		{
+			"providers of a Telemetry",
+			[]config.Config{newTelemetry("default", multiple)},
+			[]string{"envoy", "stackdriver"},
+			map[string]*tpb.AccessLogging_Filter{"stackdriver": serverErrors},
+		},
+		{
+			"providers of the parent level",
+			[]config.Config{newTelemetry("istio-system", envoy), newTelemetry("default", inherited)},
+			[]string{"envoy", "stackdriver"},
+			map[string]*tpb.AccessLogging_Filter{"envoy": errors, "stackdriver": serverErrors},
+		},
+		{
+			"providers overridden",
+			[]config.Config{newTelemetry("istio-system", multiple), newTelemetry("default", envoy)},
+			[]string{"envoy"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := createTestTelemetries(tt.cfgs, t)
+			al := telemetry.AccessLogging(sidecar)
+			got := []string{}
+			for _, p := range al.Providers {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got providers %v want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(al.Filters, tt.wantFilters) {
+				t.Fatalf("got filters %v want %v", al.Filters, tt.wantFilters)
+			}
+		})
+	}
+}
+
 func newTracingConfig(providerName string, disabled bool) *TracingConfig {
 	return &TracingConfig{
 		Provider:                     &meshconfig.MeshConfig_ExtensionProvider{Name: providerName},
@@ -542,6 +611,120 @@ func TestTracing(t *testing.T) {
 	}
 }
 
+func TestCorrelationID(t *testing.T) {
+	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
+	withCorrelationID := func(cfg config.Config, v string) config.Config {
+		cfg.Annotations = map[string]string{CorrelationIDAnnotation: v}
+		return cfg
+	}
+	workload := newTelemetry("default", &tpb.Telemetry{
+		Selector: &v1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "test"}},
+	})
+	workload.Name = "workload"
+	tests := []struct {
+		name string
+		cfgs []config.Config
+		want bool
+	}{
+		{"empty", nil, false},
+		{"no annotation", []config.Config{newTelemetry("istio-system", &tpb.Telemetry{})}, false},
+		{"root", []config.Config{withCorrelationID(newTelemetry("istio-system", &tpb.Telemetry{}), "true")}, true},
+		{
+			"namespace override",
+			[]config.Config{
+				withCorrelationID(newTelemetry("istio-system", &tpb.Telemetry{}), "true"),
+				withCorrelationID(newTelemetry("default", &tpb.Telemetry{}), "false"),
+			},
+			false,
+		},
+		{
+			"namespace without annotation",
+			[]config.Config{
+				withCorrelationID(newTelemetry("istio-system", &tpb.Telemetry{}), "true"),
+				newTelemetry("default", &tpb.Telemetry{}),
+			},
+			true,
+		},
+		{
+			"workload override",
+			[]config.Config{
+				withCorrelationID(newTelemetry("default", &tpb.Telemetry{}), "false"),
+				withCorrelationID(workload, "true"),
+			},
+			true,
+		},
+		{"invalid", []config.Config{withCorrelationID(newTelemetry("istio-system", &tpb.Telemetry{}), "yes please")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := createTestTelemetries(tt.cfgs, t)
+			if got := telemetry.CorrelationID(sidecar); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{
+		Namespace: "default",
+		Labels: map[string]string{
+			"app":                                  "test",
+			IstioCanonicalServiceLabelName:         "reviews",
+			IstioCanonicalServiceRevisionLabelName: "v2",
+		},
+	}}
+	withBaggage := func(cfg config.Config, v string) config.Config {
+		cfg.Annotations = map[string]string{BaggageAnnotation: v}
+		return cfg
+	}
+	tests := []struct {
+		name string
+		cfgs []config.Config
+		want string
+	}{
+		{"empty", nil, ""},
+		{
+			"root",
+			[]config.Config{withBaggage(newTelemetry("istio-system", &tpb.Telemetry{}), "namespace, service,revision")},
+			"istio.namespace=default,istio.canonical_service=reviews,istio.canonical_revision=v2",
+		},
+		{
+			"namespace override",
+			[]config.Config{
+				withBaggage(newTelemetry("istio-system", &tpb.Telemetry{}), "namespace,service,revision"),
+				withBaggage(newTelemetry("default", &tpb.Telemetry{}), "namespace"),
+			},
+			"istio.namespace=default",
+		},
+		{
+			"namespace disabled",
+			[]config.Config{
+				withBaggage(newTelemetry("istio-system", &tpb.Telemetry{}), "namespace"),
+				withBaggage(newTelemetry("default", &tpb.Telemetry{}), ""),
+			},
+			"",
+		},
+		{
+			"namespace without annotation",
+			[]config.Config{
+				withBaggage(newTelemetry("istio-system", &tpb.Telemetry{}), "revision"),
+				newTelemetry("default", &tpb.Telemetry{}),
+			},
+			"istio.canonical_revision=v2",
+		},
+		{"unknown entry", []config.Config{withBaggage(newTelemetry("istio-system", &tpb.Telemetry{}), "user,namespace")}, "istio.namespace=default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := createTestTelemetries(tt.cfgs, t)
+			if got := telemetry.Baggage(sidecar); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTelemetryFilters(t *testing.T) {
 	overrides := []*tpb.MetricsOverrides{{
 		Match: &tpb.MetricSelector{
@@ -801,7 +984,7 @@ func TestTelemetryFilters(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			telemetry := createTestTelemetries(tt.cfgs, t)
 			telemetry.meshConfig.DefaultProviders = tt.defaultProviders
-			got := telemetry.telemetryFilters(tt.proxy, tt.class, tt.protocol)
+			got := telemetry.telemetryFilters(tt.proxy, tt.class, tt.protocol, false)
 			res := map[string]string{}
 			http, ok := got.([]*httppb.HttpFilter)
 			if ok {
@@ -845,3 +1028,237 @@ func TestTelemetryFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestPassthroughTelemetryFilters(t *testing.T) {
+	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
+	prometheus := &tpb.Telemetry{
+		Metrics: []*tpb.Metrics{
+			{
+				Providers: []*tpb.ProviderRef{{Name: "prometheus"}},
+				Overrides: []*tpb.MetricsOverrides{{
+					Match: &tpb.MetricSelector{
+						MetricMatch: &tpb.MetricSelector_Metric{
+							Metric: tpb.MetricSelector_TCP_OPENED_CONNECTIONS,
+						},
+					},
+					TagOverrides: map[string]*tpb.MetricsOverrides_TagOverride{
+						"requested_server_name": {
+							Operation: tpb.MetricsOverrides_TagOverride_REMOVE,
+						},
+					},
+				}},
+			},
+		},
+	}
+	telemetry := createTestTelemetries([]config.Config{newTelemetry("istio-system", prometheus)}, t)
+
+	decode := func(filters []*listener.Filter) string {
+		if len(filters) != 1 {
+			t.Fatalf("expected a single filter, got %v", filters)
+		}
+		w := &wasmfilter.Wasm{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &wrapperspb.StringValue{}
+		if err := w.GetConfig().GetConfiguration().UnmarshalTo(cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg.GetValue()
+	}
+
+	want := `{"metrics":[{"dimensions":{` +
+		`"destination_port":"string(destination.port)","requested_server_name":"connection.requested_server_name"}},` +
+		`{"name":"tcp_connections_opened_total","tags_to_remove":["requested_server_name"]}]}`
+	if got := decode(telemetry.PassthroughTCPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != want {
+		t.Errorf("passthrough filters: got %v, want %v", got, want)
+	}
+	// The destination address is only added on demand, as its cardinality is not bounded
+	defer func(v bool) { features.PassthroughMetricsDestinationAddress = v }(features.PassthroughMetricsDestinationAddress)
+	features.PassthroughMetricsDestinationAddress = true
+	want = `{"metrics":[{"dimensions":{"destination_address":"destination.address",` +
+		`"destination_port":"string(destination.port)","requested_server_name":"connection.requested_server_name"}},` +
+		`{"name":"tcp_connections_opened_total","tags_to_remove":["requested_server_name"]}]}`
+	if got := decode(createTestTelemetries([]config.Config{newTelemetry("istio-system", prometheus)}, t).
+		PassthroughTCPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != want {
+		t.Errorf("passthrough filters with the destination address: got %v, want %v", got, want)
+	}
+	// The regular filters must not be affected by the passthrough ones
+	want = `{"metrics":[{"name":"tcp_connections_opened_total","tags_to_remove":["requested_server_name"]}]}`
+	if got := decode(telemetry.TCPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != want {
+		t.Errorf("filters: got %v, want %v", got, want)
+	}
+}
+
+func TestErrorResponseTelemetryFilters(t *testing.T) {
+	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
+	prometheus := &tpb.Telemetry{
+		Metrics: []*tpb.Metrics{{Providers: []*tpb.ProviderRef{{Name: "prometheus"}}}},
+	}
+	withErrorResponse := func(cfg config.Config, v string) config.Config {
+		cfg.Annotations = map[string]string{ErrorResponseAnnotation: v}
+		return cfg
+	}
+
+	decode := func(filters []*httppb.HttpFilter) string {
+		if len(filters) != 1 {
+			t.Fatalf("expected a single filter, got %v", filters)
+		}
+		w := &httpwasm.Wasm{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &wrapperspb.StringValue{}
+		if err := w.GetConfig().GetConfiguration().UnmarshalTo(cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg.GetValue()
+	}
+	decodeTCP := func(filters []*listener.Filter) string {
+		if len(filters) != 1 {
+			t.Fatalf("expected a single filter, got %v", filters)
+		}
+		w := &wasmfilter.Wasm{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &wrapperspb.StringValue{}
+		if err := w.GetConfig().GetConfiguration().UnmarshalTo(cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg.GetValue()
+	}
+
+	tests := []struct {
+		name string
+		cfgs []config.Config
+		want string
+	}{
+		{"none", []config.Config{newTelemetry("istio-system", prometheus)}, `{}`},
+		{
+			"header",
+			[]config.Config{withErrorResponse(newTelemetry("istio-system", prometheus), "header:X-Error")},
+			`{"metrics":[{"dimensions":{"response_code":"('x-error' in response.headers) ? '500' : string(response.code)"}}]}`,
+		},
+		{
+			"namespace override",
+			[]config.Config{
+				withErrorResponse(newTelemetry("istio-system", prometheus), "header:x-error"),
+				withErrorResponse(newTelemetry("default", &tpb.Telemetry{}), "grpc-status"),
+			},
+			`{"metrics":[{"dimensions":{"response_code":"(('grpc-status' in response.headers \u0026\u0026 ` +
+				`response.headers['grpc-status'] != '0') || ('grpc-status' in response.trailers \u0026\u0026 ` +
+				`response.trailers['grpc-status'] != '0')) ? '500' : string(response.code)"}}]}`,
+		},
+		{"invalid", []config.Config{withErrorResponse(newTelemetry("istio-system", prometheus), "header:x'error")}, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := createTestTelemetries(tt.cfgs, t)
+			if got := decode(telemetry.HTTPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			// The condition is built from the HTTP response, so it is not added to the TCP metrics
+			if got := decodeTCP(telemetry.TCPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != `{}` {
+				t.Fatalf("got TCP config %v, want {}", got)
+			}
+		})
+	}
+}
+
+func TestParseGatewaySource(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []GatewaySourceAttribute
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{
+			value: "source_workload=jwt:sub, source_principal=cert:uri_san",
+			want: []GatewaySourceAttribute{
+				{Dimension: "source_workload", Claim: "sub"},
+				{Dimension: "source_principal", CertField: "uri_san"},
+			},
+		},
+		{value: "source_app=cert:subject", want: []GatewaySourceAttribute{{Dimension: "source_app", CertField: "subject"}}},
+		{value: "source_workload", wantErr: true},
+		{value: "destination_workload=jwt:sub", wantErr: true},
+		{value: "source_workload=jwt:sub,source_workload=jwt:iss", wantErr: true},
+		{value: "source_workload=jwt:s'ub", wantErr: true},
+		{value: "source_workload=cert:issuer", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseGatewaySource(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGatewaySourceTelemetryFilters(t *testing.T) {
+	gateway := &Proxy{
+		Type:            Router,
+		ConfigNamespace: "istio-system",
+		Metadata:        &NodeMetadata{Labels: map[string]string{"istio": "ingressgateway"}},
+	}
+	sidecar := &Proxy{Type: SidecarProxy, ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
+	cfg := newTelemetry("istio-system", &tpb.Telemetry{
+		Metrics: []*tpb.Metrics{{Providers: []*tpb.ProviderRef{{Name: "prometheus"}}}},
+	})
+	cfg.Annotations = map[string]string{GatewaySourceAnnotation: "source_workload=jwt:sub,source_principal=cert:uri_san"}
+	telemetry := createTestTelemetries([]config.Config{cfg}, t)
+
+	decode := func(any *anypb.Any) string {
+		cfg := &wrapperspb.StringValue{}
+		if err := any.UnmarshalTo(cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg.GetValue()
+	}
+	httpConfig := func(filters []*httppb.HttpFilter) string {
+		if len(filters) != 1 {
+			t.Fatalf("expected a single filter, got %v", filters)
+		}
+		w := &httpwasm.Wasm{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+			t.Fatal(err)
+		}
+		return decode(w.GetConfig().GetConfiguration())
+	}
+	tcpConfig := func(filters []*listener.Filter) string {
+		if len(filters) != 1 {
+			t.Fatalf("expected a single filter, got %v", filters)
+		}
+		w := &wasmfilter.Wasm{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+			t.Fatal(err)
+		}
+		return decode(w.GetConfig().GetConfiguration())
+	}
+
+	want := `{"disable_host_header_fallback":true,"metrics":[{"dimensions":{` +
+		`"source_principal":"connection.uri_san_peer_certificate",` +
+		`"source_workload":"metadata.filter_metadata['istio_authn']['request.auth.claims']['sub'][0]"}}]}`
+	if got := httpConfig(telemetry.HTTPFilters(gateway, networking.ListenerClassGateway)); got != want {
+		t.Errorf("gateway HTTP filters: got %v, want %v", got, want)
+	}
+	// JWT claims are not available to TCP filters
+	want = `{"disable_host_header_fallback":true,"metrics":[{"dimensions":{"source_principal":"connection.uri_san_peer_certificate"}}]}`
+	if got := tcpConfig(telemetry.TCPFilters(gateway, networking.ListenerClassGateway)); got != want {
+		t.Errorf("gateway TCP filters: got %v, want %v", got, want)
+	}
+	if got := httpConfig(telemetry.HTTPFilters(sidecar, networking.ListenerClassSidecarOutbound)); got != `{}` {
+		t.Errorf("sidecar HTTP filters: got %v, want {}", got)
+	}
+	if got := telemetry.GatewaySourceAttributes(sidecar); got != nil {
+		t.Errorf("sidecar attributes: got %v, want none", got)
+	}
+	if got := telemetry.GatewaySourceAttributes(gateway); len(got) != 2 {
+		t.Errorf("gateway attributes: got %v, want 2", got)
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/gogo/protobuf/jsonpb"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
@@ -26,6 +27,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
 )
 
 // SelectVirtualServices selects the virtual services by matching given services' host names.
@@ -74,6 +76,67 @@ func SelectVirtualServices(virtualServices []config.Config, hosts map[string][]h
 	return importedVirtualServices
 }
 
+// meshDefaultCorsPolicy returns the default CORS policy of the mesh, from the constants.DefaultCorsPolicyKey of the
+// proxyMetadata of the default proxy config of the mesh.
+func meshDefaultCorsPolicy(mesh *meshconfig.MeshConfig) *networking.CorsPolicy {
+	v, f := mesh.GetDefaultConfig().GetProxyMetadata()[constants.DefaultCorsPolicyKey]
+	if !f {
+		return nil
+	}
+	policy := &networking.CorsPolicy{}
+	if err := gogoprotomarshal.ApplyJSON(v, policy); err != nil {
+		log.Warnf("ignoring invalid %s proxy metadata of the mesh config: %v", constants.DefaultCorsPolicyKey, err)
+		return nil
+	}
+	return policy
+}
+
+// defaultCorsPolicies returns the default CORS policies of the namespaces, from the
+// constants.DefaultCorsPolicyAnnotation of their oldest Sidecar without workload selector.
+func defaultCorsPolicies(sidecars []config.Config) map[string]*networking.CorsPolicy {
+	sortConfigByCreationTime(sidecars)
+	out := map[string]*networking.CorsPolicy{}
+	for _, sc := range sidecars {
+		if _, f := out[sc.Namespace]; f || sc.Spec.(*networking.Sidecar).GetWorkloadSelector() != nil {
+			continue
+		}
+		v, f := sc.Annotations[constants.DefaultCorsPolicyAnnotation]
+		if !f {
+			continue
+		}
+		policy := &networking.CorsPolicy{}
+		if err := gogoprotomarshal.ApplyJSON(v, policy); err != nil {
+			log.Warnf("ignoring invalid %s annotation on Sidecar %s/%s: %v", constants.DefaultCorsPolicyAnnotation,
+				sc.Namespace, sc.Name, err)
+			continue
+		}
+		out[sc.Namespace] = policy
+	}
+	return out
+}
+
+// applyDefaultCorsPolicies sets the default CORS policy of their namespace, or else of the mesh, on the HTTP routes
+// of the VirtualServices which have no CORS policy.
+func applyDefaultCorsPolicies(vses []config.Config, defaults map[string]*networking.CorsPolicy, meshDefault *networking.CorsPolicy) {
+	if len(defaults) == 0 && meshDefault == nil {
+		return
+	}
+	for _, vs := range vses {
+		policy, f := defaults[vs.Namespace]
+		if !f {
+			policy = meshDefault
+		}
+		if policy == nil {
+			continue
+		}
+		for _, route := range vs.Spec.(*networking.VirtualService).Http {
+			if route.CorsPolicy == nil {
+				route.CorsPolicy = policy
+			}
+		}
+	}
+}
+
 func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta config.Meta) {
 	// resolve top level hosts
 	for i, h := range rule.Hosts {
@@ -24,12 +24,15 @@ import (
 	"github.com/google/go-cmp/cmp"
 	fuzz "github.com/google/gofuzz"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
 )
 
@@ -2093,3 +2096,80 @@ func buildHTTPService(hostname string, v visibility.Instance, ip, namespace stri
 	service.Ports = Ports
 	return service
 }
+
+func TestDefaultCorsPolicies(t *testing.T) {
+	now := time.Now()
+	sidecar := func(name, ns, cors string, created time.Time, selector *networking.WorkloadSelector) config.Config {
+		sc := config.Config{
+			Meta: config.Meta{
+				GroupVersionKind:  gvk.Sidecar,
+				Name:              name,
+				Namespace:         ns,
+				CreationTimestamp: created,
+			},
+			Spec: &networking.Sidecar{WorkloadSelector: selector},
+		}
+		if cors != "" {
+			sc.Annotations = map[string]string{constants.DefaultCorsPolicyAnnotation: cors}
+		}
+		return sc
+	}
+	mesh := meshDefaultCorsPolicy(&meshconfig.MeshConfig{DefaultConfig: &meshconfig.ProxyConfig{
+		ProxyMetadata: map[string]string{constants.DefaultCorsPolicyKey: `{"allowMethods": ["GET"]}`},
+	}})
+	if !reflect.DeepEqual(mesh, &networking.CorsPolicy{AllowMethods: []string{"GET"}}) {
+		t.Fatalf("unexpected default CORS policy of the mesh %v", mesh)
+	}
+	invalid := meshDefaultCorsPolicy(&meshconfig.MeshConfig{DefaultConfig: &meshconfig.ProxyConfig{
+		ProxyMetadata: map[string]string{constants.DefaultCorsPolicyKey: `{"allowMethods": "GET"}`},
+	}})
+	if invalid != nil {
+		t.Fatalf("expected no default CORS policy of the mesh for an invalid value, got %v", invalid)
+	}
+
+	ns := &networking.CorsPolicy{AllowOrigins: []*networking.StringMatch{
+		{MatchType: &networking.StringMatch_Exact{Exact: "https://example.com"}},
+	}}
+	defaults := defaultCorsPolicies([]config.Config{
+		sidecar("newer", "ns", `{"allowMethods": ["POST"]}`, now, nil),
+		sidecar("ns", "ns", `{"allowOrigins": [{"exact": "https://example.com"}]}`, now.Add(-time.Minute), nil),
+		sidecar("workload", "workload", `{"allowMethods": ["GET"]}`, now,
+			&networking.WorkloadSelector{Labels: map[string]string{"app": "a"}}),
+		sidecar("invalid", "invalid", `{"allowMethods": "GET"}`, now, nil),
+		sidecar("none", "none", "", now, nil),
+	})
+	expected := map[string]*networking.CorsPolicy{"ns": ns}
+	if !reflect.DeepEqual(defaults, expected) {
+		t.Fatalf("expected default CORS policies %v, got %v", expected, defaults)
+	}
+
+	virtualService := func(ns string, routes ...*networking.HTTPRoute) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: ns},
+			Spec: &networking.VirtualService{Hosts: []string{"example.com"}, Http: routes},
+		}
+	}
+	explicit := &networking.CorsPolicy{AllowMethods: []string{"PUT"}}
+	optOut := &networking.CorsPolicy{}
+	vses := []config.Config{
+		virtualService("ns", &networking.HTTPRoute{}, &networking.HTTPRoute{CorsPolicy: explicit}),
+		virtualService("other", &networking.HTTPRoute{}, &networking.HTTPRoute{CorsPolicy: optOut}),
+	}
+	applyDefaultCorsPolicies(vses, defaults, mesh)
+	cases := []struct {
+		vs       int
+		route    int
+		expected *networking.CorsPolicy
+	}{
+		{0, 0, ns},
+		{0, 1, explicit},
+		{1, 0, mesh},
+		{1, 1, optOut},
+	}
+	for _, tt := range cases {
+		got := vses[tt.vs].Spec.(*networking.VirtualService).Http[tt.route].CorsPolicy
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("route %d of VirtualService %d: expected CORS policy %v, got %v", tt.route, tt.vs, tt.expected, got)
+		}
+	}
+}
@@ -44,6 +44,9 @@ type ConfigGenerator interface {
 	// BuildHTTPRoutes returns the list of HTTP routes for the given proxy. This is the RDS output
 	BuildHTTPRoutes(node *model.Proxy, req *model.PushRequest, routeNames []string) ([]*discovery.Resource, model.XdsLogDetails)
 
+	// BuildScopedRoutes returns the list of scoped route configurations for the given proxy. This is the SRDS output
+	BuildScopedRoutes(node *model.Proxy, req *model.PushRequest) []*discovery.Resource
+
 	// BuildNameTable returns list of hostnames and the associated IPs
 	BuildNameTable(node *model.Proxy, push *model.PushContext) *dnsProto.NameTable
 
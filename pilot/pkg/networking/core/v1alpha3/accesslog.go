@@ -15,8 +15,11 @@
 package v1alpha3
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -31,9 +34,14 @@ import (
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	pbtypes "github.com/gogo/protobuf/types"
 	otlpcommon "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config/xds"
@@ -54,6 +62,14 @@ const (
 		"%UPSTREAM_CLUSTER% %UPSTREAM_LOCAL_ADDRESS% %DOWNSTREAM_LOCAL_ADDRESS% " +
 		"%DOWNSTREAM_REMOTE_ADDRESS% %REQUESTED_SERVER_NAME% %ROUTE_NAME%\n"
 
+	// EnvoyTCPTextLogFormat is the format of the envoy text based access logs of TCP proxies. Request fields are
+	// replaced by the reasons of the termination of the connection: the response flags tell which side terminated or
+	// failed the connection, along with the termination details and the upstream transport failure reason.
+	EnvoyTCPTextLogFormat = "[%START_TIME%] %RESPONSE_FLAGS% %CONNECTION_TERMINATION_DETAILS% " +
+		"\"%UPSTREAM_TRANSPORT_FAILURE_REASON%\" %BYTES_RECEIVED% %BYTES_SENT% %DURATION% " +
+		"\"%UPSTREAM_HOST%\" %UPSTREAM_CLUSTER% %UPSTREAM_LOCAL_ADDRESS% %DOWNSTREAM_LOCAL_ADDRESS% " +
+		"%DOWNSTREAM_REMOTE_ADDRESS% %REQUESTED_SERVER_NAME% %CONNECTION_ID%\n"
+
 	// EnvoyServerName for istio's envoy
 	EnvoyServerName = "istio-envoy"
 
@@ -74,6 +90,19 @@ const (
 	devStdout = "/dev/stdout"
 
 	celFilter = "envoy.access_loggers.extension_filters.cel"
+
+	// GrpcAccessLogBufferFlushIntervalKey is the key of the proxyMetadata of the default proxy config of the mesh
+	// setting the interval at which the proxies flush the access logs buffered for the gRPC access log services, as a
+	// duration such as "5s". Envoy flushes them every second by default.
+	GrpcAccessLogBufferFlushIntervalKey = "GRPC_ACCESS_LOG_BUFFER_FLUSH_INTERVAL"
+	// GrpcAccessLogBufferSizeBytesKey is the key of the proxyMetadata of the default proxy config of the mesh setting
+	// the size of the buffer of access logs the proxies keep for the gRPC access log services before flushing it.
+	// Envoy buffers 16KiB by default.
+	GrpcAccessLogBufferSizeBytesKey = "GRPC_ACCESS_LOG_BUFFER_SIZE_BYTES"
+	// GrpcAccessLogStreamRetriesKey is the key of the proxyMetadata of the default proxy config of the mesh setting
+	// the number of times the proxies retry to open the stream to the gRPC access log services, with an exponential
+	// backoff, before dropping the buffered access logs. The stream is not retried by default.
+	GrpcAccessLogStreamRetriesKey = "GRPC_ACCESS_LOG_STREAM_RETRIES"
 )
 
 var (
@@ -109,6 +138,29 @@ var (
 		},
 	}
 
+	// EnvoyTCPJSONLogFormatIstio is the map of values of the envoy json based access logs of TCP proxies.
+	EnvoyTCPJSONLogFormatIstio = &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"start_time":                        {Kind: &structpb.Value_StringValue{StringValue: "%START_TIME%"}},
+			"response_flags":                    {Kind: &structpb.Value_StringValue{StringValue: "%RESPONSE_FLAGS%"}},
+			"connection_termination_details":    {Kind: &structpb.Value_StringValue{StringValue: "%CONNECTION_TERMINATION_DETAILS%"}},
+			"upstream_transport_failure_reason": {Kind: &structpb.Value_StringValue{StringValue: "%UPSTREAM_TRANSPORT_FAILURE_REASON%"}},
+			"bytes_received":                    {Kind: &structpb.Value_StringValue{StringValue: "%BYTES_RECEIVED%"}},
+			"bytes_sent":                        {Kind: &structpb.Value_StringValue{StringValue: "%BYTES_SENT%"}},
+			"duration":                          {Kind: &structpb.Value_StringValue{StringValue: "%DURATION%"}},
+			"upstream_host":                     {Kind: &structpb.Value_StringValue{StringValue: "%UPSTREAM_HOST%"}},
+			"upstream_cluster":                  {Kind: &structpb.Value_StringValue{StringValue: "%UPSTREAM_CLUSTER%"}},
+			"upstream_local_address":            {Kind: &structpb.Value_StringValue{StringValue: "%UPSTREAM_LOCAL_ADDRESS%"}},
+			"downstream_local_address":          {Kind: &structpb.Value_StringValue{StringValue: "%DOWNSTREAM_LOCAL_ADDRESS%"}},
+			"downstream_remote_address":         {Kind: &structpb.Value_StringValue{StringValue: "%DOWNSTREAM_REMOTE_ADDRESS%"}},
+			"requested_server_name":             {Kind: &structpb.Value_StringValue{StringValue: "%REQUESTED_SERVER_NAME%"}},
+			"connection_id":                     {Kind: &structpb.Value_StringValue{StringValue: "%CONNECTION_ID%"}},
+		},
+	}
+
+	// httpLogFormat is the default format of the access logs of HTTP connection managers and listeners.
+	httpLogFormat = logFormat{text: EnvoyTextLogFormat, json: EnvoyJSONLogFormatIstio}
+
 	// State logged by the metadata exchange filter about the upstream and downstream service instances
 	// We need to propagate these as part of access log service stream
 	// Logging them by default on the console may be an issue as the base64 encoded string is bound to be a big one.
@@ -127,26 +179,54 @@ var (
 	}
 )
 
+// logFormat is the default text and json format of an access log, used when none is configured.
+type logFormat struct {
+	text string
+	json *structpb.Struct
+}
+
+// tcpLogFormat returns the default format of the access logs of TCP proxies.
+func tcpLogFormat() logFormat {
+	if !features.EnableTCPAccessLogFormat {
+		return httpLogFormat
+	}
+	return logFormat{text: EnvoyTCPTextLogFormat, json: EnvoyTCPJSONLogFormatIstio}
+}
+
+// withGatewaySource appends the source attributes derived from the identity of the clients of a gateway by the
+// GatewaySourceAnnotation to the format. The JWT claims are only available to HTTP connection managers.
+func (f logFormat) withGatewaySource(attrs []model.GatewaySourceAttribute, http bool) logFormat {
+	if len(attrs) == 0 {
+		return f
+	}
+	text := strings.TrimSuffix(f.text, "\n")
+	json := proto.Clone(f.json).(*structpb.Struct)
+	for _, attr := range attrs {
+		if attr.Claim != "" && !http {
+			continue
+		}
+		text += fmt.Sprintf(" %s=%s", attr.Dimension, attr.LogOperator())
+		json.Fields[attr.Dimension] = structpb.NewStringValue(attr.LogOperator())
+	}
+	return logFormat{text: text + "\n", json: json}
+}
+
 type AccessLogBuilder struct {
+	// file and gRPC accessLog which are cached and reset on MeshConfig change.
+	mutex                 sync.RWMutex
+	fileAccesslog         *accesslog.AccessLog
+	tcpFileAccessLog      *accesslog.AccessLog
+	listenerFileAccessLog *accesslog.AccessLog
 	// tcpGrpcAccessLog is used when access log service is enabled in mesh config.
 	tcpGrpcAccessLog *accesslog.AccessLog
 	// httpGrpcAccessLog is used when access log service is enabled in mesh config.
 	httpGrpcAccessLog *accesslog.AccessLog
 	// tcpGrpcListenerAccessLog is used when access log service is enabled in mesh config.
 	tcpGrpcListenerAccessLog *accesslog.AccessLog
-
-	// file accessLog which is cached and reset on MeshConfig change.
-	mutex                 sync.RWMutex
-	fileAccesslog         *accesslog.AccessLog
-	listenerFileAccessLog *accesslog.AccessLog
 }
 
 func newAccessLogBuilder() *AccessLogBuilder {
-	return &AccessLogBuilder{
-		tcpGrpcAccessLog:         buildTCPGrpcAccessLog(false),
-		httpGrpcAccessLog:        buildHTTPGrpcAccessLog(),
-		tcpGrpcListenerAccessLog: buildTCPGrpcAccessLog(true),
-	}
+	return &AccessLogBuilder{}
 }
 
 func (b *AccessLogBuilder) setTCPAccessLog(push *model.PushContext, proxy *model.Proxy, tcp *tcp.TcpProxy) {
@@ -156,61 +236,75 @@ func (b *AccessLogBuilder) setTCPAccessLog(push *model.PushContext, proxy *model
 	if cfg == nil {
 		// No Telemetry API configured, fall back to legacy mesh config setting
 		if mesh.AccessLogFile != "" {
-			tcp.AccessLog = append(tcp.AccessLog, b.buildFileAccessLog(mesh))
+			tcp.AccessLog = append(tcp.AccessLog, b.buildTCPFileAccessLog(mesh))
 		}
 
 		if mesh.EnableEnvoyAccessLogService {
 			// Setting it to TCP as the low level one.
-			tcp.AccessLog = append(tcp.AccessLog, b.tcpGrpcAccessLog)
+			tcp.AccessLog = append(tcp.AccessLog, b.cachedGrpcAccessLog(&b.tcpGrpcAccessLog, func() *accesslog.AccessLog {
+				return buildTCPGrpcAccessLog(mesh, false)
+			}))
 		}
 		return
 	}
 
-	if al := buildAccessLogFromTelemetry(push, cfg, false); len(al) != 0 {
+	format := tcpLogFormat().withGatewaySource(push.Telemetry.GatewaySourceAttributes(proxy), false)
+	if al := buildAccessLogFromTelemetryWithFormat(push, cfg, false, format); len(al) != 0 {
 		tcp.AccessLog = append(tcp.AccessLog, al...)
 	}
 }
 
 func buildAccessLogFromTelemetry(push *model.PushContext, spec *model.LoggingConfig, forListener bool) []*accesslog.AccessLog {
-	als := make([]*accesslog.AccessLog, 0)
-	telFilter := buildAccessLogFilterFromTelemetry(spec)
-	filters := []*accesslog.AccessLogFilter{}
-	if forListener {
-		filters = append(filters, addAccessLogFilter())
-	}
-	if telFilter != nil {
-		filters = append(filters, telFilter)
-	}
+	return buildAccessLogFromTelemetryWithFormat(push, spec, forListener, httpLogFormat)
+}
 
+// buildAccessLogFromTelemetryWithFormat builds the access logs of the providers of the Telemetry API, with the
+// default format used by the providers which do not set one. Each access log has the filter of its provider.
+func buildAccessLogFromTelemetryWithFormat(push *model.PushContext, spec *model.LoggingConfig, forListener bool,
+	format logFormat) []*accesslog.AccessLog {
+	als := make([]*accesslog.AccessLog, 0)
 	for _, p := range spec.Providers {
 		var al *accesslog.AccessLog
 		switch prov := p.Provider.(type) {
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLog:
-			al = buildEnvoyFileAccessLogHelper(prov.EnvoyFileAccessLog)
+			al = buildEnvoyFileAccessLogHelper(prov.EnvoyFileAccessLog, format)
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyHttpAls:
 			al = buildHTTPGrpcAccessLogHelper(push, prov.EnvoyHttpAls)
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyTcpAls:
 			al = buildTCPGrpcAccessLogHelper(push, prov.EnvoyTcpAls)
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyOtelAls:
-			al = buildOpenTelemetryLogHelper(push, prov.EnvoyOtelAls)
+			al = buildOpenTelemetryLogHelper(push, prov.EnvoyOtelAls, format)
 		}
 		if al == nil {
 			continue
 		}
 
+		filters := []*accesslog.AccessLogFilter{}
+		if forListener {
+			filters = append(filters, addAccessLogFilter())
+		}
+		if telFilter := buildAccessLogFilterFromTelemetry(spec.Filters[p.Name]); telFilter != nil {
+			filters = append(filters, telFilter)
+		}
 		al.Filter = buildAccessLogFilter(filters...)
 		als = append(als, al)
 	}
 	return als
 }
 
-func buildAccessLogFilterFromTelemetry(spec *model.LoggingConfig) *accesslog.AccessLogFilter {
-	if spec == nil || spec.Filter == nil {
+func buildAccessLogFilterFromTelemetry(filter *tpb.AccessLogging_Filter) *accesslog.AccessLogFilter {
+	if filter == nil {
 		return nil
 	}
 
+	if features.EnableNativeAccessLogFilters {
+		if filters, ok := buildNativeAccessLogFilters(filter.Expression); ok {
+			return buildAccessLogFilter(filters...)
+		}
+	}
+
 	fl := &cel.ExpressionFilter{
-		Expression: spec.Filter.Expression,
+		Expression: filter.Expression,
 	}
 
 	return &accesslog.AccessLogFilter{
@@ -234,12 +328,15 @@ func (b *AccessLogBuilder) setHTTPAccessLog(opts buildListenerOpts, connectionMa
 		}
 
 		if mesh.EnableEnvoyAccessLogService {
-			connectionManager.AccessLog = append(connectionManager.AccessLog, b.httpGrpcAccessLog)
+			connectionManager.AccessLog = append(connectionManager.AccessLog, b.cachedGrpcAccessLog(&b.httpGrpcAccessLog, func() *accesslog.AccessLog {
+				return buildHTTPGrpcAccessLog(mesh)
+			}))
 		}
 		return
 	}
 
-	if al := buildAccessLogFromTelemetry(opts.push, cfg, false); len(al) != 0 {
+	format := httpLogFormat.withGatewaySource(opts.push.Telemetry.GatewaySourceAttributes(opts.proxy), true)
+	if al := buildAccessLogFromTelemetryWithFormat(opts.push, cfg, false, format); len(al) != 0 {
 		connectionManager.AccessLog = append(connectionManager.AccessLog, al...)
 	}
 }
@@ -259,7 +356,9 @@ func (b *AccessLogBuilder) setListenerAccessLog(push *model.PushContext, proxy *
 
 		if mesh.EnableEnvoyAccessLogService {
 			// Setting it to TCP as the low level one.
-			listener.AccessLog = append(listener.AccessLog, b.tcpGrpcListenerAccessLog)
+			listener.AccessLog = append(listener.AccessLog, b.cachedGrpcAccessLog(&b.tcpGrpcListenerAccessLog, func() *accesslog.AccessLog {
+				return buildTCPGrpcAccessLog(mesh, true)
+			}))
 		}
 		return
 	}
@@ -287,18 +386,7 @@ func buildTCPGrpcAccessLogHelper(push *model.PushContext, prov *meshconfig.MeshC
 	}
 
 	fl := &grpcaccesslog.TcpGrpcAccessLogConfig{
-		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
-			LogName: logName,
-			GrpcService: &core.GrpcService{
-				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
-					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
-						ClusterName: cluster,
-					},
-				},
-			},
-			TransportApiVersion:     core.ApiVersion_V3,
-			FilterStateObjectsToLog: filterObjects,
-		},
+		CommonConfig: buildCommonGrpcAccessLogConfig(push.Mesh, logName, cluster, filterObjects),
 	}
 
 	return &accesslog.AccessLog{
@@ -307,7 +395,8 @@ func buildTCPGrpcAccessLogHelper(push *model.PushContext, prov *meshconfig.MeshC
 	}
 }
 
-func buildEnvoyFileAccessLogHelper(prov *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider) *accesslog.AccessLog {
+func buildEnvoyFileAccessLogHelper(prov *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider,
+	format logFormat) *accesslog.AccessLog {
 	p := prov.Path
 	if p == "" {
 		p = devStdout
@@ -320,12 +409,12 @@ func buildEnvoyFileAccessLogHelper(prov *meshconfig.MeshConfig_ExtensionProvider
 	if prov.LogFormat != nil {
 		switch logFormat := prov.LogFormat.LogFormat.(type) {
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat_Text:
-			fl.AccessLogFormat, needsFormatter = buildFileAccessTextLogFormat(logFormat.Text)
+			fl.AccessLogFormat, needsFormatter = buildFileAccessTextLogFormat(logFormat.Text, format)
 		case *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat_Labels:
-			fl.AccessLogFormat, needsFormatter = buildFileAccessJSONLogFormat(logFormat)
+			fl.AccessLogFormat, needsFormatter = buildFileAccessJSONLogFormat(logFormat, format)
 		}
 	} else {
-		fl.AccessLogFormat, needsFormatter = buildFileAccessTextLogFormat("")
+		fl.AccessLogFormat, needsFormatter = buildFileAccessTextLogFormat("", format)
 	}
 	if needsFormatter {
 		fl.GetLogFormat().Formatters = accessLogFormatters
@@ -339,8 +428,8 @@ func buildEnvoyFileAccessLogHelper(prov *meshconfig.MeshConfig_ExtensionProvider
 	return al
 }
 
-func buildFileAccessTextLogFormat(text string) (*fileaccesslog.FileAccessLog_LogFormat, bool) {
-	formatString := EnvoyTextLogFormat
+func buildFileAccessTextLogFormat(text string, format logFormat) (*fileaccesslog.FileAccessLog_LogFormat, bool) {
+	formatString := format.text
 	if text != "" {
 		formatString = text
 	}
@@ -359,15 +448,16 @@ func buildFileAccessTextLogFormat(text string) (*fileaccesslog.FileAccessLog_Log
 }
 
 func buildFileAccessJSONLogFormat(
-	logFormat *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat_Labels) (*fileaccesslog.FileAccessLog_LogFormat, bool) {
+	logFormat *meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat_Labels,
+	format logFormat) (*fileaccesslog.FileAccessLog_LogFormat, bool) {
 	jsonLogStruct := &structpb.Struct{}
 	if logFormat.Labels != nil {
 		if err := xds.GogoStructToMessage(logFormat.Labels, jsonLogStruct, false); err != nil {
 			log.Errorf("error parsing provided json log format, default log format will be used: %v", err)
-			jsonLogStruct = EnvoyJSONLogFormatIstio
+			jsonLogStruct = format.json
 		}
 	} else {
-		jsonLogStruct = EnvoyJSONLogFormatIstio
+		jsonLogStruct = format.json
 	}
 
 	needsFormatter := false
@@ -404,18 +494,7 @@ func buildHTTPGrpcAccessLogHelper(push *model.PushContext, prov *meshconfig.Mesh
 	}
 
 	fl := &grpcaccesslog.HttpGrpcAccessLogConfig{
-		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
-			LogName: logName,
-			GrpcService: &core.GrpcService{
-				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
-					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
-						ClusterName: cluster,
-					},
-				},
-			},
-			TransportApiVersion:     core.ApiVersion_V3,
-			FilterStateObjectsToLog: filterObjects,
-		},
+		CommonConfig:                    buildCommonGrpcAccessLogConfig(push.Mesh, logName, cluster, filterObjects),
 		AdditionalRequestHeadersToLog:   prov.AdditionalRequestHeadersToLog,
 		AdditionalResponseHeadersToLog:  prov.AdditionalResponseHeadersToLog,
 		AdditionalResponseTrailersToLog: prov.AdditionalResponseTrailersToLog,
@@ -427,7 +506,7 @@ func buildHTTPGrpcAccessLogHelper(push *model.PushContext, prov *meshconfig.Mesh
 	}
 }
 
-func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig) *accesslog.AccessLog {
+func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig, format logFormat) *accesslog.AccessLog {
 	// We need to build access log. This is needed either on first access or when mesh config changes.
 	fl := &fileaccesslog.FileAccessLog{
 		Path: path,
@@ -435,7 +514,7 @@ func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig) *accessl
 	needsFormatter := false
 	switch mesh.AccessLogEncoding {
 	case meshconfig.MeshConfig_TEXT:
-		formatString := EnvoyTextLogFormat
+		formatString := format.text
 		if mesh.AccessLogFormat != "" {
 			formatString = mesh.AccessLogFormat
 		}
@@ -452,7 +531,7 @@ func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig) *accessl
 			},
 		}
 	case meshconfig.MeshConfig_JSON:
-		jsonLogStruct := EnvoyJSONLogFormatIstio
+		jsonLogStruct := format.json
 		if len(mesh.AccessLogFormat) > 0 {
 			parsedJSONLogStruct := structpb.Struct{}
 			if err := protomarshal.UnmarshalAllowUnknown([]byte(mesh.AccessLogFormat), &parsedJSONLogStruct); err != nil {
@@ -489,7 +568,7 @@ func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig) *accessl
 }
 
 func buildOpenTelemetryLogHelper(pushCtx *model.PushContext,
-	provider *meshconfig.MeshConfig_ExtensionProvider_EnvoyOpenTelemetryLogProvider) *accesslog.AccessLog {
+	provider *meshconfig.MeshConfig_ExtensionProvider_EnvoyOpenTelemetryLogProvider, format logFormat) *accesslog.AccessLog {
 	_, cluster, err := clusterLookupFn(pushCtx, provider.Service, int(provider.Port))
 	if err != nil {
 		log.Errorf("could not find cluster for open telemetry provider %q: %v", provider, err)
@@ -501,7 +580,7 @@ func buildOpenTelemetryLogHelper(pushCtx *model.PushContext,
 		logName = otelEnvoyAccessLogFriendlyName
 	}
 
-	f := EnvoyTextLogFormat
+	f := format.text
 	if provider.LogFormat != nil && provider.LogFormat.Text != "" {
 		f = provider.LogFormat.Text
 	}
@@ -511,7 +590,7 @@ func buildOpenTelemetryLogHelper(pushCtx *model.PushContext,
 		labels = provider.LogFormat.Labels
 	}
 
-	cfg := buildOpenTelemetryAccessLogConfig(logName, cluster, f, labels)
+	cfg := buildOpenTelemetryAccessLogConfig(pushCtx.Mesh, logName, cluster, f, labels)
 
 	return &accesslog.AccessLog{
 		Name:       otelEnvoyALSName,
@@ -519,20 +598,10 @@ func buildOpenTelemetryLogHelper(pushCtx *model.PushContext,
 	}
 }
 
-func buildOpenTelemetryAccessLogConfig(logName, clusterName, format string, labels *pbtypes.Struct) *otelaccesslog.OpenTelemetryAccessLogConfig {
+func buildOpenTelemetryAccessLogConfig(mesh *meshconfig.MeshConfig, logName, clusterName, format string,
+	labels *pbtypes.Struct) *otelaccesslog.OpenTelemetryAccessLogConfig {
 	cfg := &otelaccesslog.OpenTelemetryAccessLogConfig{
-		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
-			LogName: logName,
-			GrpcService: &core.GrpcService{
-				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
-					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
-						ClusterName: clusterName,
-					},
-				},
-			},
-			TransportApiVersion:     core.ApiVersion_V3,
-			FilterStateObjectsToLog: envoyWasmStateToLog,
-		},
+		CommonConfig: buildCommonGrpcAccessLogConfig(mesh, logName, clusterName, envoyWasmStateToLog),
 	}
 
 	if format != "" {
@@ -573,7 +642,7 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 	}
 
 	// We need to build access log. This is needed either on first access or when mesh config changes.
-	al := buildFileAccessLogHelper(mesh.AccessLogFile, mesh)
+	al := buildFileAccessLogHelper(mesh.AccessLogFile, mesh, httpLogFormat)
 
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
@@ -582,6 +651,21 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 	return al
 }
 
+func (b *AccessLogBuilder) buildTCPFileAccessLog(mesh *meshconfig.MeshConfig) *accesslog.AccessLog {
+	if cal := b.cachedTCPFileAccessLog(); cal != nil {
+		return cal
+	}
+
+	// We need to build access log. This is needed either on first access or when mesh config changes.
+	al := buildFileAccessLogHelper(mesh.AccessLogFile, mesh, tcpLogFormat())
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tcpFileAccessLog = al
+
+	return al
+}
+
 func addAccessLogFilter() *accesslog.AccessLogFilter {
 	return &accesslog.AccessLogFilter{
 		FilterSpecifier: &accesslog.AccessLogFilter_ResponseFlagFilter{
@@ -614,7 +698,7 @@ func (b *AccessLogBuilder) buildListenerFileAccessLog(mesh *meshconfig.MeshConfi
 	}
 
 	// We need to build access log. This is needed either on first access or when mesh config changes.
-	lal := buildFileAccessLogHelper(mesh.AccessLogFile, mesh)
+	lal := buildFileAccessLogHelper(mesh.AccessLogFile, mesh, httpLogFormat)
 	// We add ResponseFlagFilter here, as we want to get listener access logs only on scenarios where we might
 	// not get filter Access Logs like in cases like NR to upstream.
 	lal.Filter = addAccessLogFilter()
@@ -632,30 +716,25 @@ func (b *AccessLogBuilder) cachedFileAccessLog() *accesslog.AccessLog {
 	return b.fileAccesslog
 }
 
+func (b *AccessLogBuilder) cachedTCPFileAccessLog() *accesslog.AccessLog {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.tcpFileAccessLog
+}
+
 func (b *AccessLogBuilder) cachedListenerFileAccessLog() *accesslog.AccessLog {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 	return b.listenerFileAccessLog
 }
 
-func buildTCPGrpcAccessLog(isListener bool) *accesslog.AccessLog {
+func buildTCPGrpcAccessLog(mesh *meshconfig.MeshConfig, isListener bool) *accesslog.AccessLog {
 	accessLogFriendlyName := tcpEnvoyAccessLogFriendlyName
 	if isListener {
 		accessLogFriendlyName = listenerEnvoyAccessLogFriendlyName
 	}
 	fl := &grpcaccesslog.TcpGrpcAccessLogConfig{
-		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
-			LogName: accessLogFriendlyName,
-			GrpcService: &core.GrpcService{
-				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
-					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
-						ClusterName: EnvoyAccessLogCluster,
-					},
-				},
-			},
-			TransportApiVersion:     core.ApiVersion_V3,
-			FilterStateObjectsToLog: envoyWasmStateToLog,
-		},
+		CommonConfig: buildCommonGrpcAccessLogConfig(mesh, accessLogFriendlyName, EnvoyAccessLogCluster, envoyWasmStateToLog),
 	}
 
 	var filter *accesslog.AccessLogFilter
@@ -669,20 +748,9 @@ func buildTCPGrpcAccessLog(isListener bool) *accesslog.AccessLog {
 	}
 }
 
-func buildHTTPGrpcAccessLog() *accesslog.AccessLog {
+func buildHTTPGrpcAccessLog(mesh *meshconfig.MeshConfig) *accesslog.AccessLog {
 	fl := &grpcaccesslog.HttpGrpcAccessLogConfig{
-		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
-			LogName: httpEnvoyAccessLogFriendlyName,
-			GrpcService: &core.GrpcService{
-				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
-					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
-						ClusterName: EnvoyAccessLogCluster,
-					},
-				},
-			},
-			TransportApiVersion:     core.ApiVersion_V3,
-			FilterStateObjectsToLog: envoyWasmStateToLog,
-		},
+		CommonConfig: buildCommonGrpcAccessLogConfig(mesh, httpEnvoyAccessLogFriendlyName, EnvoyAccessLogCluster, envoyWasmStateToLog),
 	}
 
 	return &accesslog.AccessLog{
@@ -691,9 +759,77 @@ func buildHTTPGrpcAccessLog() *accesslog.AccessLog {
 	}
 }
 
+// cachedGrpcAccessLog returns the gRPC access log cached in the field, building it on first access or when mesh
+// config changes.
+func (b *AccessLogBuilder) cachedGrpcAccessLog(field **accesslog.AccessLog, build func() *accesslog.AccessLog) *accesslog.AccessLog {
+	b.mutex.RLock()
+	al := *field
+	b.mutex.RUnlock()
+	if al != nil {
+		return al
+	}
+
+	al = build()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	*field = al
+
+	return al
+}
+
 func (b *AccessLogBuilder) reset() {
 	b.mutex.Lock()
 	b.fileAccesslog = nil
+	b.tcpFileAccessLog = nil
 	b.listenerFileAccessLog = nil
+	b.tcpGrpcAccessLog = nil
+	b.httpGrpcAccessLog = nil
+	b.tcpGrpcListenerAccessLog = nil
 	b.mutex.Unlock()
 }
+
+// buildCommonGrpcAccessLogConfig builds the config shared by the gRPC access logs sent to the cluster, including
+// how the proxies buffer the access logs and retry the stream to the access log service, read from the proxyMetadata
+// of the default proxy config of the mesh. Invalid values are ignored.
+func buildCommonGrpcAccessLogConfig(mesh *meshconfig.MeshConfig, logName, cluster string,
+	filterObjects []string) *grpcaccesslog.CommonGrpcAccessLogConfig {
+	cfg := &grpcaccesslog.CommonGrpcAccessLogConfig{
+		LogName: logName,
+		GrpcService: &core.GrpcService{
+			TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+				EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
+					ClusterName: cluster,
+				},
+			},
+		},
+		TransportApiVersion:     core.ApiVersion_V3,
+		FilterStateObjectsToLog: filterObjects,
+	}
+	metadata := mesh.GetDefaultConfig().GetProxyMetadata()
+	if v, f := metadata[GrpcAccessLogBufferFlushIntervalKey]; f {
+		if interval, err := time.ParseDuration(v); err != nil || interval < 0 {
+			log.Warnf("ignoring invalid %s proxy metadata %q of the mesh", GrpcAccessLogBufferFlushIntervalKey, v)
+		} else if interval > 0 {
+			cfg.BufferFlushInterval = durationpb.New(interval)
+		}
+	}
+	if v, f := metadata[GrpcAccessLogBufferSizeBytesKey]; f {
+		if size, err := strconv.ParseUint(v, 10, 32); err != nil {
+			log.Warnf("ignoring invalid %s proxy metadata %q of the mesh", GrpcAccessLogBufferSizeBytesKey, v)
+		} else if size > 0 {
+			cfg.BufferSizeBytes = wrapperspb.UInt32(uint32(size))
+		}
+	}
+	if v, f := metadata[GrpcAccessLogStreamRetriesKey]; f {
+		if retries, err := strconv.ParseUint(v, 10, 32); err != nil {
+			log.Warnf("ignoring invalid %s proxy metadata %q of the mesh", GrpcAccessLogStreamRetriesKey, v)
+		} else if retries > 0 {
+			// Envoy backs off exponentially between the retries, from 1s up to 10s by default.
+			cfg.GrpcStreamRetryPolicy = &core.RetryPolicy{
+				NumRetries: wrapperspb.UInt32(uint32(retries)),
+			}
+		}
+	}
+	return cfg
+}
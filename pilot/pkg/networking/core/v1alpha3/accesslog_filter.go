@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+const (
+	statusCodeFilterRuntimeKey = "access_log.telemetry.status_code"
+	durationFilterRuntimeKey   = "access_log.telemetry.duration"
+)
+
+var (
+	statusCodeTerm = regexp.MustCompile(`^response\.code\s*(>=|<=|==|>|<)\s*(\d+)$`)
+	durationTerm   = regexp.MustCompile(`^response\.duration\s*(>=|<=|==|>|<)\s*duration\(\s*["']([^"']+)["']\s*\)$`)
+	headerHasTerm  = regexp.MustCompile(`^(!?)\s*has\(\s*request\.headers\[\s*["']([^"']+)["']\s*\]\s*\)$`)
+	headerInTerm   = regexp.MustCompile(`^["']([^"']+)["']\s+in\s+request\.headers$`)
+)
+
+// buildNativeAccessLogFilters translates a CEL filter expression into the equivalent native Envoy access log
+// filters, which are cheaper to evaluate. Only conjunctions of comparisons of the response code or duration
+// with a constant, and of checks of the presence of request headers are supported, for example
+// `response.code >= 500 && has(request.headers["x-debug"])`. If the expression can't be translated, false is
+// returned and the expression should be evaluated with CEL.
+func buildNativeAccessLogFilters(expression string) ([]*accesslog.AccessLogFilter, bool) {
+	if strings.Contains(expression, "||") {
+		return nil, false
+	}
+	var filters []*accesslog.AccessLogFilter
+	for _, term := range strings.Split(expression, "&&") {
+		f, ok := buildNativeAccessLogFilter(strings.TrimSpace(term))
+		if !ok {
+			return nil, false
+		}
+		filters = append(filters, f)
+	}
+	return filters, true
+}
+
+func buildNativeAccessLogFilter(term string) (*accesslog.AccessLogFilter, bool) {
+	if m := statusCodeTerm.FindStringSubmatch(term); m != nil {
+		v, err := strconv.ParseUint(m[2], 10, 32)
+		if err != nil {
+			return nil, false
+		}
+		cmp, ok := buildComparisonFilter(m[1], v, statusCodeFilterRuntimeKey)
+		if !ok {
+			return nil, false
+		}
+		return &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+				StatusCodeFilter: &accesslog.StatusCodeFilter{Comparison: cmp},
+			},
+		}, true
+	}
+	if m := durationTerm.FindStringSubmatch(term); m != nil {
+		d, err := time.ParseDuration(m[2])
+		// The duration filter has a millisecond granularity.
+		if err != nil || d < 0 || d%time.Millisecond != 0 {
+			return nil, false
+		}
+		cmp, ok := buildComparisonFilter(m[1], uint64(d.Milliseconds()), durationFilterRuntimeKey)
+		if !ok {
+			return nil, false
+		}
+		return &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_DurationFilter{
+				DurationFilter: &accesslog.DurationFilter{Comparison: cmp},
+			},
+		}, true
+	}
+	if m := headerHasTerm.FindStringSubmatch(term); m != nil {
+		return buildHeaderPresentFilter(m[2], m[1] == "!"), true
+	}
+	if m := headerInTerm.FindStringSubmatch(term); m != nil {
+		return buildHeaderPresentFilter(m[1], false), true
+	}
+	return nil, false
+}
+
+// buildComparisonFilter builds a comparison with a constant. Envoy only supports the EQ, GE and LE
+// operators, strict comparisons are converted to the equivalent non strict ones.
+func buildComparisonFilter(op string, v uint64, runtimeKey string) (*accesslog.ComparisonFilter, bool) {
+	cmp := &accesslog.ComparisonFilter{}
+	switch op {
+	case "==":
+		cmp.Op = accesslog.ComparisonFilter_EQ
+	case ">=":
+		cmp.Op = accesslog.ComparisonFilter_GE
+	case "<=":
+		cmp.Op = accesslog.ComparisonFilter_LE
+	case ">":
+		cmp.Op = accesslog.ComparisonFilter_GE
+		v++
+	case "<":
+		if v == 0 {
+			return nil, false
+		}
+		cmp.Op = accesslog.ComparisonFilter_LE
+		v--
+	default:
+		return nil, false
+	}
+	if v > math.MaxUint32 {
+		return nil, false
+	}
+	cmp.Value = &core.RuntimeUInt32{
+		DefaultValue: uint32(v),
+		RuntimeKey:   runtimeKey,
+	}
+	return cmp, true
+}
+
+func buildHeaderPresentFilter(name string, invert bool) *accesslog.AccessLogFilter {
+	return &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_HeaderFilter{
+			HeaderFilter: &accesslog.HeaderFilter{
+				Header: &route.HeaderMatcher{
+					Name:                 strings.ToLower(name),
+					HeaderMatchSpecifier: &route.HeaderMatcher_PresentMatch{PresentMatch: true},
+					InvertMatch:          invert,
+				},
+			},
+		},
+	}
+}
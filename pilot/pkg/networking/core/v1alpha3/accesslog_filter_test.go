@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+)
+
+func statusCodeFilter(op accesslog.ComparisonFilter_Op, v uint32) *accesslog.AccessLogFilter {
+	return &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+			StatusCodeFilter: &accesslog.StatusCodeFilter{
+				Comparison: &accesslog.ComparisonFilter{
+					Op:    op,
+					Value: &core.RuntimeUInt32{DefaultValue: v, RuntimeKey: statusCodeFilterRuntimeKey},
+				},
+			},
+		},
+	}
+}
+
+func durationFilter(op accesslog.ComparisonFilter_Op, v uint32) *accesslog.AccessLogFilter {
+	return &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_DurationFilter{
+			DurationFilter: &accesslog.DurationFilter{
+				Comparison: &accesslog.ComparisonFilter{
+					Op:    op,
+					Value: &core.RuntimeUInt32{DefaultValue: v, RuntimeKey: durationFilterRuntimeKey},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildNativeAccessLogFilters(t *testing.T) {
+	cases := []struct {
+		expression string
+		want       []*accesslog.AccessLogFilter
+		ok         bool
+	}{
+		{
+			expression: "response.code >= 400",
+			want:       []*accesslog.AccessLogFilter{statusCodeFilter(accesslog.ComparisonFilter_GE, 400)},
+			ok:         true,
+		},
+		{
+			expression: "response.code > 499 && response.code < 600",
+			want: []*accesslog.AccessLogFilter{
+				statusCodeFilter(accesslog.ComparisonFilter_GE, 500),
+				statusCodeFilter(accesslog.ComparisonFilter_LE, 599),
+			},
+			ok: true,
+		},
+		{
+			expression: `response.code == 503 && response.duration >= duration("1.5s")`,
+			want: []*accesslog.AccessLogFilter{
+				statusCodeFilter(accesslog.ComparisonFilter_EQ, 503),
+				durationFilter(accesslog.ComparisonFilter_GE, 1500),
+			},
+			ok: true,
+		},
+		{
+			expression: `has(request.headers["X-Debug"]) && !has(request.headers['x-skip'])`,
+			want: []*accesslog.AccessLogFilter{
+				buildHeaderPresentFilter("x-debug", false),
+				buildHeaderPresentFilter("x-skip", true),
+			},
+			ok: true,
+		},
+		{
+			expression: `"x-debug" in request.headers`,
+			want:       []*accesslog.AccessLogFilter{buildHeaderPresentFilter("x-debug", false)},
+			ok:         true,
+		},
+		{expression: "response.code >= 500 || response.code == 429"},
+		{expression: "response.code != 200"},
+		{expression: "response.code < 0"},
+		{expression: "response.code >= 5000000000"},
+		{expression: `response.duration > duration("1us")`},
+		{expression: `request.headers["x-debug"] == "true"`},
+		{expression: "response.code >= 400 && connection.mtls"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, ok := buildNativeAccessLogFilters(tt.expression)
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected filters (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildAccessLogFilterFromTelemetryNative(t *testing.T) {
+	defer func(v bool) { features.EnableNativeAccessLogFilters = v }(features.EnableNativeAccessLogFilters)
+	features.EnableNativeAccessLogFilters = true
+
+	filter := &tpb.AccessLogging_Filter{Expression: "response.code >= 500 && response.code <= 599"}
+	want := buildAccessLogFilter(statusCodeFilter(accesslog.ComparisonFilter_GE, 500), statusCodeFilter(accesslog.ComparisonFilter_LE, 599))
+	if diff := cmp.Diff(want, buildAccessLogFilterFromTelemetry(filter), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected filter (-want +got):\n%s", diff)
+	}
+
+	// Expressions which can't be translated are still evaluated with CEL.
+	filter = &tpb.AccessLogging_Filter{Expression: "response.code != 200"}
+	if got := buildAccessLogFilterFromTelemetry(filter).GetExtensionFilter().GetName(); got != celFilter {
+		t.Fatalf("expected a CEL filter, got %v", got)
+	}
+}
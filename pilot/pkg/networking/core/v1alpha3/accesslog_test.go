@@ -15,7 +15,9 @@
 package v1alpha3
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -31,7 +33,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	otlpcommon "go.opentelemetry.io/proto/otlp/common/v1"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	tpb "istio.io/api/telemetry/v1alpha1"
@@ -53,12 +57,15 @@ var (
 
 func TestListenerAccessLog(t *testing.T) {
 	defaultFormatJSON, _ := protomarshal.ToJSON(EnvoyJSONLogFormatIstio)
+	defaultTCPFormatJSON, _ := protomarshal.ToJSON(EnvoyTCPJSONLogFormatIstio)
 
 	for _, tc := range []struct {
 		name       string
 		encoding   meshconfig.MeshConfig_AccessLogEncoding
 		format     string
 		wantFormat string
+		// wantTCPFormat is the format of the tcp_proxy access logs, if different from wantFormat.
+		wantTCPFormat string
 	}{
 		{
 			name:       "valid json object",
@@ -73,32 +80,37 @@ func TestListenerAccessLog(t *testing.T) {
 			wantFormat: `{"foo":{"bar":"ha"}}`,
 		},
 		{
-			name:       "invalid json object",
-			encoding:   meshconfig.MeshConfig_JSON,
-			format:     `foo`,
-			wantFormat: defaultFormatJSON,
+			name:          "invalid json object",
+			encoding:      meshconfig.MeshConfig_JSON,
+			format:        `foo`,
+			wantFormat:    defaultFormatJSON,
+			wantTCPFormat: defaultTCPFormatJSON,
 		},
 		{
-			name:       "incorrect json type",
-			encoding:   meshconfig.MeshConfig_JSON,
-			format:     `[]`,
-			wantFormat: defaultFormatJSON,
+			name:          "incorrect json type",
+			encoding:      meshconfig.MeshConfig_JSON,
+			format:        `[]`,
+			wantFormat:    defaultFormatJSON,
+			wantTCPFormat: defaultTCPFormatJSON,
 		},
 		{
-			name:       "incorrect json type",
-			encoding:   meshconfig.MeshConfig_JSON,
-			format:     `"{}"`,
-			wantFormat: defaultFormatJSON,
+			name:          "incorrect json type",
+			encoding:      meshconfig.MeshConfig_JSON,
+			format:        `"{}"`,
+			wantFormat:    defaultFormatJSON,
+			wantTCPFormat: defaultTCPFormatJSON,
 		},
 		{
-			name:       "default json format",
-			encoding:   meshconfig.MeshConfig_JSON,
-			wantFormat: defaultFormatJSON,
+			name:          "default json format",
+			encoding:      meshconfig.MeshConfig_JSON,
+			wantFormat:    defaultFormatJSON,
+			wantTCPFormat: defaultTCPFormatJSON,
 		},
 		{
-			name:       "default text format",
-			encoding:   meshconfig.MeshConfig_TEXT,
-			wantFormat: EnvoyTextLogFormat,
+			name:          "default text format",
+			encoding:      meshconfig.MeshConfig_TEXT,
+			wantFormat:    EnvoyTextLogFormat,
+			wantTCPFormat: EnvoyTCPTextLogFormat,
 		},
 	} {
 		tc := tc
@@ -144,7 +156,11 @@ func TestListenerAccessLog(t *testing.T) {
 							}
 
 							// Verify tcp proxy access log.
-							verify(t, tc.encoding, tcpConfig.AccessLog[0], tc.wantFormat)
+							wantTCPFormat := tc.wantTCPFormat
+							if wantTCPFormat == "" {
+								wantTCPFormat = tc.wantFormat
+							}
+							verify(t, tc.encoding, tcpConfig.AccessLog[0], wantTCPFormat)
 						case wellknown.HTTPConnectionManager:
 							httpConfig := &httppb.HttpConnectionManager{}
 							if err := filter.GetTypedConfig().UnmarshalTo(httpConfig); err != nil {
@@ -205,8 +221,10 @@ func TestBuildAccessLogFromTelemetry(t *testing.T) {
 				},
 			},
 		},
-		Filter: &tpb.AccessLogging_Filter{
-			Expression: httpCodeExpress,
+		Filters: map[string]*tpb.AccessLogging_Filter{
+			"": {
+				Expression: httpCodeExpress,
+			},
 		},
 	}
 
@@ -293,6 +311,15 @@ func TestBuildAccessLogFromTelemetry(t *testing.T) {
 		},
 	}
 
+	multiCfgWithFilter := &model.LoggingConfig{
+		Providers: multiCfg.Providers,
+		Filters: map[string]*tpb.AccessLogging_Filter{
+			"stderr": {
+				Expression: httpCodeExpress,
+			},
+		},
+	}
+
 	fakeFilterStateObjects := []string{"fake-filter-state-object1", "fake-filter-state-object1"}
 	grpcCfg := &model.LoggingConfig{
 		Providers: []*meshconfig.MeshConfig_ExtensionProvider{
@@ -686,6 +713,32 @@ func TestBuildAccessLogFromTelemetry(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "multi-with-filter",
+			meshConfig: &meshconfig.MeshConfig{
+				AccessLogEncoding: meshconfig.MeshConfig_TEXT,
+			},
+			spec:        multiCfgWithFilter,
+			forListener: false,
+			expected: []*accesslog.AccessLog{
+				{
+					Name:       wellknown.FileAccessLog,
+					ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(stdout)},
+				},
+				{
+					Name: wellknown.FileAccessLog,
+					Filter: &accesslog.AccessLogFilter{
+						FilterSpecifier: &accesslog.AccessLogFilter_ExtensionFilter{
+							ExtensionFilter: &accesslog.ExtensionFilter{
+								Name:       celFilter,
+								ConfigType: &accesslog.ExtensionFilter_TypedConfig{TypedConfig: util.MessageToAny(httpCodeFilter)},
+							},
+						},
+					},
+					ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(errout)},
+				},
+			},
+		},
 		{
 			name: "multi-listener",
 			meshConfig: &meshconfig.MeshConfig{
@@ -765,13 +818,106 @@ func TestBuildAccessLogFromTelemetry(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			got := buildAccessLogFromTelemetry(tc.ctx, tc.spec, tc.forListener)
+			push := tc.ctx
+			if push == nil {
+				push = ctx
+			}
+			got := buildAccessLogFromTelemetry(push, tc.spec, tc.forListener)
 
 			assert.Equal(t, tc.expected, got)
 		})
 	}
 }
 
+func TestTCPAccessLogFromTelemetry(t *testing.T) {
+	fileProvider := func(text string) *model.LoggingConfig {
+		prov := &meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider{Path: devStdout}
+		if text != "" {
+			prov.LogFormat = &meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat{
+				LogFormat: &meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLogProvider_LogFormat_Text{Text: text},
+			}
+		}
+		return &model.LoggingConfig{Providers: []*meshconfig.MeshConfig_ExtensionProvider{{
+			Provider: &meshconfig.MeshConfig_ExtensionProvider_EnvoyFileAccessLog{EnvoyFileAccessLog: prov},
+		}}}
+	}
+	for _, tc := range []struct {
+		name       string
+		spec       *model.LoggingConfig
+		wantFormat string
+	}{
+		{
+			name:       "default format",
+			spec:       fileProvider(""),
+			wantFormat: EnvoyTCPTextLogFormat,
+		},
+		{
+			name:       "provider format",
+			spec:       fileProvider("%RESPONSE_FLAGS% %DURATION%\n"),
+			wantFormat: "%RESPONSE_FLAGS% %DURATION%\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := buildAccessLogFromTelemetryWithFormat(&model.PushContext{}, tc.spec, false, tcpLogFormat())
+			if len(got) != 1 {
+				t.Fatalf("expected 1 access log, got %v", got)
+			}
+			verify(t, meshconfig.MeshConfig_TEXT, got[0], tc.wantFormat)
+		})
+	}
+}
+
+func TestLogFormatWithGatewaySource(t *testing.T) {
+	attrs := []model.GatewaySourceAttribute{
+		{Dimension: "source_workload", Claim: "sub"},
+		{Dimension: "source_principal", CertField: "uri_san"},
+	}
+	for _, tc := range []struct {
+		name     string
+		format   logFormat
+		http     bool
+		wantText string
+		wantJSON map[string]string
+	}{
+		{
+			name:   "http",
+			format: httpLogFormat,
+			http:   true,
+			wantText: strings.TrimSuffix(EnvoyTextLogFormat, "\n") +
+				" source_workload=%DYNAMIC_METADATA(istio_authn:request.auth.claims:sub)% source_principal=%DOWNSTREAM_PEER_URI_SAN%\n",
+			wantJSON: map[string]string{
+				"source_workload":  "%DYNAMIC_METADATA(istio_authn:request.auth.claims:sub)%",
+				"source_principal": "%DOWNSTREAM_PEER_URI_SAN%",
+			},
+		},
+		{
+			name:     "tcp",
+			format:   tcpLogFormat(),
+			wantText: strings.TrimSuffix(EnvoyTCPTextLogFormat, "\n") + " source_principal=%DOWNSTREAM_PEER_URI_SAN%\n",
+			wantJSON: map[string]string{"source_principal": "%DOWNSTREAM_PEER_URI_SAN%"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := len(tc.format.json.Fields)
+			got := tc.format.withGatewaySource(attrs, tc.http)
+			if got.text != tc.wantText {
+				t.Errorf("got text %q, want %q", got.text, tc.wantText)
+			}
+			if len(got.json.Fields) != fields+len(tc.wantJSON) {
+				t.Errorf("got %d JSON fields, want %d", len(got.json.Fields), fields+len(tc.wantJSON))
+			}
+			for k, v := range tc.wantJSON {
+				if got.json.Fields[k].GetStringValue() != v {
+					t.Errorf("got JSON field %s %v, want %s", k, got.json.Fields[k], v)
+				}
+			}
+			if len(tc.format.json.Fields) != fields {
+				t.Errorf("the default JSON format must not be modified")
+			}
+		})
+	}
+}
+
 func TestAccessLogPatch(t *testing.T) {
 	// Regression test for https://github.com/istio/istio/issues/35778
 	cg := NewConfigGenTest(t, TestOptions{
@@ -896,7 +1042,72 @@ func TestBuildOpenTelemetryAccessLogConfig(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			got := buildOpenTelemetryAccessLogConfig(tc.logName, tc.clusterName, tc.body, tc.labels)
+			got := buildOpenTelemetryAccessLogConfig(&meshconfig.MeshConfig{}, tc.logName, tc.clusterName, tc.body, tc.labels)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestBuildCommonGrpcAccessLogConfig(t *testing.T) {
+	fakeCluster := "outbound|9000||als.monitoring.svc.cluster.local"
+	grpcService := &core.GrpcService{
+		TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
+				ClusterName: fakeCluster,
+			},
+		},
+	}
+	for _, tc := range []struct {
+		name     string
+		metadata map[string]string
+		expected *grpcaccesslog.CommonGrpcAccessLogConfig
+	}{
+		{
+			name: "default",
+			expected: &grpcaccesslog.CommonGrpcAccessLogConfig{
+				LogName:                 httpEnvoyAccessLogFriendlyName,
+				GrpcService:             grpcService,
+				TransportApiVersion:     core.ApiVersion_V3,
+				FilterStateObjectsToLog: envoyWasmStateToLog,
+			},
+		},
+		{
+			name: "invalid",
+			metadata: map[string]string{
+				GrpcAccessLogBufferFlushIntervalKey: "5",
+				GrpcAccessLogBufferSizeBytesKey:     "-1",
+				GrpcAccessLogStreamRetriesKey:       "many",
+			},
+			expected: &grpcaccesslog.CommonGrpcAccessLogConfig{
+				LogName:                 httpEnvoyAccessLogFriendlyName,
+				GrpcService:             grpcService,
+				TransportApiVersion:     core.ApiVersion_V3,
+				FilterStateObjectsToLog: envoyWasmStateToLog,
+			},
+		},
+		{
+			name: "buffered with retries",
+			metadata: map[string]string{
+				GrpcAccessLogBufferFlushIntervalKey: "5s",
+				GrpcAccessLogBufferSizeBytesKey:     "65536",
+				GrpcAccessLogStreamRetriesKey:       "3",
+			},
+			expected: &grpcaccesslog.CommonGrpcAccessLogConfig{
+				LogName:                 httpEnvoyAccessLogFriendlyName,
+				GrpcService:             grpcService,
+				TransportApiVersion:     core.ApiVersion_V3,
+				FilterStateObjectsToLog: envoyWasmStateToLog,
+				BufferFlushInterval:     durationpb.New(5 * time.Second),
+				BufferSizeBytes:         wrapperspb.UInt32(65536),
+				GrpcStreamRetryPolicy: &core.RetryPolicy{
+					NumRetries: wrapperspb.UInt32(3),
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mesh := &meshconfig.MeshConfig{DefaultConfig: &meshconfig.ProxyConfig{ProxyMetadata: tc.metadata}}
+			got := buildCommonGrpcAccessLogConfig(mesh, httpEnvoyAccessLogFriendlyName, fakeCluster, envoyWasmStateToLog)
 			assert.Equal(t, tc.expected, got)
 		})
 	}
@@ -37,10 +37,13 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/destinationrule"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/util/gogo"
+	"istio.io/pkg/log"
 )
 
 // defaultTransportSocketMatch applies to endpoints that have no security.istio.io/tlsMode label
@@ -129,28 +132,40 @@ func (configgen *ConfigGeneratorImpl) buildClusters(proxy *model.Proxy, req *mod
 	clusters := make([]*cluster.Cluster, 0)
 	resources := model.Resources{}
 	envoyFilterPatches := req.Push.EnvoyFilters(proxy)
+	healthCheckEventLogPath := proxy.Metadata.HealthCheckEventLogPath
 	cb := NewClusterBuilder(proxy, req, configgen.Cache)
 	instances := proxy.ServiceInstances
 	cacheStats := cacheStats{}
 	switch proxy.Type {
 	case model.SidecarProxy:
 		// Setup outbound clusters
-		outboundPatcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_SIDECAR_OUTBOUND}
+		outboundPatcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_SIDECAR_OUTBOUND, healthCheckEventLogPath: healthCheckEventLogPath}
 		ob, cs := configgen.buildOutboundClusters(cb, proxy, outboundPatcher, services)
 		cacheStats = cacheStats.merge(cs)
 		resources = append(resources, ob...)
+		clusters = append(clusters, configgen.buildOutboundUDPClusters(cb, proxy, outboundPatcher, services)...)
 		// Add a blackhole and passthrough cluster for catching traffic to unresolved routes
 		clusters = outboundPatcher.conditionallyAppend(clusters, nil, cb.buildBlackHoleCluster(), cb.buildDefaultPassthroughCluster())
+		if detectPassthroughPlaintextTLS(proxy) && proxy.SidecarScope.PassthroughTLSOrigination {
+			clusters = outboundPatcher.conditionallyAppend(clusters, nil, cb.buildPassthroughTLSOriginationCluster())
+		}
 		clusters = append(clusters, outboundPatcher.insertedClusters()...)
 
 		// Setup inbound clusters
-		inboundPatcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_SIDECAR_INBOUND}
-		clusters = append(clusters, configgen.buildInboundClusters(cb, proxy, instances, inboundPatcher)...)
+		inboundPatcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_SIDECAR_INBOUND, healthCheckEventLogPath: healthCheckEventLogPath}
+		inboundClusters := configgen.buildInboundClusters(cb, proxy, instances, inboundPatcher)
+		if limits := inboundPrincipalConcurrencyForProxy(proxy); limits != nil {
+			inboundClusters = append(inboundClusters, buildPrincipalConcurrencyClusters(limits, inboundClusters)...)
+		}
+		clusters = append(clusters, inboundClusters...)
 		// Pass through clusters for inbound traffic. These cluster bind loopback-ish src address to access node local service.
 		clusters = inboundPatcher.conditionallyAppend(clusters, nil, cb.buildInboundPassthroughClusters()...)
 		clusters = append(clusters, inboundPatcher.insertedClusters()...)
 	default: // Gateways
-		patcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_GATEWAY}
+		if proxy.Type == model.Router {
+			cb.grpcHealthCheckClusters = gatewayGRPCHealthCheckClusters(proxy, req.Push)
+		}
+		patcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_GATEWAY, healthCheckEventLogPath: healthCheckEventLogPath}
 		ob, cs := configgen.buildOutboundClusters(cb, proxy, patcher, services)
 		cacheStats = cacheStats.merge(cs)
 		resources = append(resources, ob...)
@@ -219,6 +234,12 @@ func buildClusterKey(service *model.Service, port *model.Port, cb *ClusterBuilde
 		metadataCerts:   cb.metadataCerts,
 		peerAuthVersion: cb.req.Push.AuthnPolicies.GetVersion(),
 		serviceAccounts: cb.req.Push.ServiceAccounts[service.Hostname][port.Port],
+
+		healthCheckEventLogPath: proxy.Metadata.HealthCheckEventLogPath,
+		socketOptions:           cb.socketOptionsKey,
+		netAdmin:                cb.netAdmin,
+		grpcHealthCheck:         cb.grpcHealthCheckClusters.Contains(clusterName),
+		crossNetwork:            cb.crossNetworkPolicy(service, port).key(),
 	}
 	return clusterKey
 }
@@ -261,6 +282,15 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder,
 
 			subsetClusters := cb.applyDestinationRule(defaultCluster, DefaultClusterMode, service, port,
 				clusterKey.networkView, clusterKey.destinationRule, clusterKey.serviceAccounts)
+			if features.EnableRedisFilter && port.Protocol == protocol.Redis && redisSettingsForDestinationRule(clusterKey.destinationRule).cluster {
+				applyRedisCluster(defaultCluster.cluster, service, port)
+			}
+			if clusterKey.grpcHealthCheck && port.Protocol.IsHTTP2() {
+				applyGRPCHealthCheck(defaultCluster.cluster)
+				for _, ss := range subsetClusters {
+					applyGRPCHealthCheck(ss)
+				}
+			}
 
 			if patched := cp.applyResource(nil, defaultCluster.build()); patched != nil {
 				resources = append(resources, patched)
@@ -287,6 +317,8 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder,
 type clusterPatcher struct {
 	efw  *model.EnvoyFilterWrapper
 	pctx networking.EnvoyFilter_PatchContext
+	// healthCheckEventLogPath, if set, is the event log path set on the health checks of patched clusters.
+	healthCheckEventLogPath string
 }
 
 func (p clusterPatcher) applyResource(hosts []host.Name, c *cluster.Cluster) *discovery.Resource {
@@ -301,7 +333,9 @@ func (p clusterPatcher) apply(hosts []host.Name, c *cluster.Cluster) *cluster.Cl
 	if !envoyfilter.ShouldKeepCluster(p.pctx, p.efw, c, hosts) {
 		return nil
 	}
-	return envoyfilter.ApplyClusterMerge(p.pctx, p.efw, c, hosts)
+	c = envoyfilter.ApplyClusterMerge(p.pctx, p.efw, c, hosts)
+	p.setHealthCheckEventLogPath(c)
+	return c
 }
 
 func (p clusterPatcher) conditionallyAppend(l []*cluster.Cluster, hosts []host.Name, clusters ...*cluster.Cluster) []*cluster.Cluster {
@@ -317,7 +351,25 @@ func (p clusterPatcher) conditionallyAppend(l []*cluster.Cluster, hosts []host.N
 }
 
 func (p clusterPatcher) insertedClusters() []*cluster.Cluster {
-	return envoyfilter.InsertedClusters(p.pctx, p.efw)
+	clusters := envoyfilter.InsertedClusters(p.pctx, p.efw)
+	for _, c := range clusters {
+		p.setHealthCheckEventLogPath(c)
+	}
+	return clusters
+}
+
+// setHealthCheckEventLogPath makes the active health checks of the cluster log their events to the
+// event log of the proxy, so that they can be reported to istiod by the agent. Health checks are only
+// configured via EnvoyFilter, so this only applies to patched or inserted clusters.
+func (p clusterPatcher) setHealthCheckEventLogPath(c *cluster.Cluster) {
+	if p.healthCheckEventLogPath == "" {
+		return
+	}
+	for _, hc := range c.GetHealthChecks() {
+		if hc.EventLogPath == "" {
+			hc.EventLogPath = p.healthCheckEventLogPath
+		}
+	}
 }
 
 func (p clusterPatcher) hasPatches() bool {
@@ -395,6 +447,7 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, p
 	noneMode := proxy.GetInterceptionMode() == model.InterceptionNone
 
 	_, actualLocalHost := getActualWildcardAndLocalHost(proxy)
+	tlsSettings := inboundTLSSettings(proxy)
 
 	// No user supplied sidecar scope or the user supplied one has no ingress listeners
 	if !sidecarScope.HasIngressListener() {
@@ -423,6 +476,7 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, p
 		for epPort, instances := range clustersToBuild {
 			// The inbound cluster port equals to endpoint port.
 			localCluster := cb.buildInboundClusterForPortOrUDS(epPort, bind, proxy, instances[0], instances)
+			cb.applyInboundUpstreamTLS(localCluster, tlsSettings, uint32(epPort))
 			// If inbound cluster match has service, we should see if it matches with any host name across all instances.
 			hosts := make([]host.Name, 0, len(instances))
 			for _, si := range instances {
@@ -430,6 +484,13 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, p
 			}
 			clusters = cp.conditionallyAppend(clusters, hosts, localCluster.build())
 		}
+		if dispatches := inboundPortDispatches(proxy); len(dispatches) > 0 {
+			dispatchBind := actualLocalHost
+			if features.EnableInboundPassthrough {
+				dispatchBind = cb.proxyIPAddresses[0]
+			}
+			clusters = append(clusters, buildInboundDispatchClusters(cb, proxy, instances, dispatches, dispatchBind, cp)...)
+		}
 		return clusters
 	}
 
@@ -479,6 +540,12 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, p
 		instance.Endpoint.EndpointPort = uint32(port)
 
 		localCluster := cb.buildInboundClusterForPortOrUDS(int(ingressListener.Port.Number), endpointAddress, proxy, instance, nil)
+		// Without a default endpoint, the connections are forwarded to the listener port.
+		tlsPort := instance.Endpoint.EndpointPort
+		if tlsPort == 0 {
+			tlsPort = ingressListener.Port.Number
+		}
+		cb.applyInboundUpstreamTLS(localCluster, tlsSettings, tlsPort)
 		clusters = cp.conditionallyAppend(clusters, []host.Name{instance.Service.Hostname}, localCluster.build())
 	}
 
@@ -586,6 +653,36 @@ type buildClusterOpts struct {
 	// Indicates the service registry of the cluster being built.
 	serviceRegistry provider.ID
 	cache           model.XdsCache
+	// warmupAggression is the aggression of the slow start of new endpoints, from the WarmupAggressionAnnotation
+	// of the DestinationRule. Zero uses the Envoy default.
+	warmupAggression float64
+	// tlsSessionCacheSize is the number of TLS sessions cached for resumption, from the TLSSessionCacheSizeAnnotation
+	// of the DestinationRule. Nil uses the Envoy default.
+	tlsSessionCacheSize *wrappers.UInt32Value
+	// alpnProtocols replace the ALPN protocols advertised to the upstream hosts with the SIMPLE or MUTUAL TLS modes
+	// when not nil, from the UpstreamALPNProtocolsAnnotation of the DestinationRule. An empty list advertises no ALPN
+	// protocol.
+	alpnProtocols []string
+	// connectionPoolPerDownstreamConnection partitions the connection pools by downstream connection, from the
+	// ConnectionPoolPerDownstreamConnectionAnnotation of the DestinationRule.
+	connectionPoolPerDownstreamConnection bool
+	// preconnectPolicy establishes upstream connections ahead of the requests, from the PreconnectPolicyAnnotation of
+	// the DestinationRule.
+	preconnectPolicy *cluster.Cluster_PreconnectPolicy
+	// consistentHashLocalityFailover keeps the affinity of consistent hash load balancing with locality failover, from
+	// the ConsistentHashLocalityFailoverAnnotation of the DestinationRule.
+	consistentHashLocalityFailover bool
+	// upstreamSocketOptions are added to the socket options of upstream connections, from the
+	// UpstreamSocketOptionsAnnotation of the DestinationRule.
+	upstreamSocketOptions []*core.SocketOption
+	// upstreamIPv6 is whether the upstream connections use IPv6, to only set the socket options of their family.
+	upstreamIPv6 bool
+	// lbSubsetKeys are the subset selectors of the subset load balancer of EDS clusters, from the
+	// SubsetKeysAnnotation of the DestinationRule.
+	lbSubsetKeys [][]string
+	// crossNetwork tunes the connections of the clusters of services with endpoints in another network than the
+	// proxy, from PILOT_CROSS_NETWORK_TRAFFIC_POLICY.
+	crossNetwork *crossNetworkPolicy
 }
 
 type upgradeTuple struct {
@@ -749,6 +846,156 @@ func applyLoadBalancer(c *cluster.Cluster, lb *networking.LoadBalancerSettings,
 	ApplyRingHashLoadBalancer(c, lb)
 }
 
+// WarmupAggressionAnnotation is the annotation of DestinationRules setting the aggression of the slow start of the
+// endpoints configured with warmupDurationSecs. It is a positive number; 1 increases the traffic of new endpoints
+// linearly over the warmup duration, and larger values send them less traffic at the start. Defaults to 1.
+const WarmupAggressionAnnotation = "networking.istio.io/warmup-aggression"
+
+// warmupAggressionForDestinationRule reads the WarmupAggressionAnnotation of the DestinationRule. Invalid values
+// are ignored.
+func warmupAggressionForDestinationRule(dr *config.Config) float64 {
+	if dr == nil {
+		return 0
+	}
+	v, f := dr.Annotations[WarmupAggressionAnnotation]
+	if !f {
+		return 0
+	}
+	a, err := strconv.ParseFloat(v, 64)
+	if err != nil || a <= 0 || math.IsInf(a, 0) {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", WarmupAggressionAnnotation, v, dr.Namespace, dr.Name)
+		return 0
+	}
+	return a
+}
+
+// TLSSessionCacheSizeAnnotation is the annotation of DestinationRules setting the number of TLS sessions cached by
+// the proxy for each cluster originating TLS, which are resumed instead of doing full handshakes with the upstream
+// hosts. 0 disables session resumption. Defaults to 1.
+const TLSSessionCacheSizeAnnotation = "networking.istio.io/tls-session-cache-size"
+
+// tlsSessionCacheSizeForDestinationRule reads the TLSSessionCacheSizeAnnotation of the DestinationRule. Invalid
+// values are ignored.
+func tlsSessionCacheSizeForDestinationRule(dr *config.Config) *wrappers.UInt32Value {
+	if dr == nil {
+		return nil
+	}
+	v, f := dr.Annotations[TLSSessionCacheSizeAnnotation]
+	if !f {
+		return nil
+	}
+	size, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", TLSSessionCacheSizeAnnotation, v, dr.Namespace, dr.Name)
+		return nil
+	}
+	return &wrappers.UInt32Value{Value: uint32(size)}
+}
+
+// alpnProtocolsForDestinationRule reads the UpstreamALPNProtocolsAnnotation of the DestinationRule. It returns nil if
+// the annotation is not set or invalid.
+func alpnProtocolsForDestinationRule(dr *config.Config) []string {
+	if dr == nil {
+		return nil
+	}
+	v, f := dr.Annotations[destinationrule.UpstreamALPNProtocolsAnnotation]
+	if !f {
+		return nil
+	}
+	protocols, err := destinationrule.ParseUpstreamALPNProtocolsAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s: %v",
+			destinationrule.UpstreamALPNProtocolsAnnotation, v, dr.Namespace, dr.Name, err)
+		return nil
+	}
+	return protocols
+}
+
+// ConnectionPoolPerDownstreamConnectionAnnotation is the annotation of DestinationRules partitioning the upstream
+// connection pools of the proxy by downstream connection when set to "true", instead of sharing them among all the
+// downstream clients. As a downstream connection belongs to a single source principal, a noisy client can then only
+// exhaust its own connections to a shared backend. This also pins the requests of a downstream connection to its
+// upstream connections, as required by upstreams using connection-oriented authentication such as NTLM or Kerberos.
+// This is mostly useful on gateways, and multiplies the number of upstream connections.
+const ConnectionPoolPerDownstreamConnectionAnnotation = "networking.istio.io/connection-pool-per-downstream-connection"
+
+// connectionPoolPerDownstreamConnectionForDestinationRule reads the ConnectionPoolPerDownstreamConnectionAnnotation
+// of the DestinationRule. Invalid values are ignored.
+func connectionPoolPerDownstreamConnectionForDestinationRule(dr *config.Config) bool {
+	if dr == nil {
+		return false
+	}
+	v, f := dr.Annotations[ConnectionPoolPerDownstreamConnectionAnnotation]
+	if !f {
+		return false
+	}
+	perConnection, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", ConnectionPoolPerDownstreamConnectionAnnotation, v,
+			dr.Namespace, dr.Name)
+		return false
+	}
+	return perConnection
+}
+
+// preconnectPolicyForDestinationRule reads the destinationrule.PreconnectPolicyAnnotation of the DestinationRule.
+// Invalid values are ignored; they are rejected by the validation of the DestinationRule.
+func preconnectPolicyForDestinationRule(dr *config.Config) *cluster.Cluster_PreconnectPolicy {
+	if dr == nil {
+		return nil
+	}
+	v, f := dr.Annotations[destinationrule.PreconnectPolicyAnnotation]
+	if !f {
+		return nil
+	}
+	policy, err := destinationrule.ParsePreconnectPolicyAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s: %v", destinationrule.PreconnectPolicyAnnotation, v,
+			dr.Namespace, dr.Name, err)
+		return nil
+	}
+	if policy == nil {
+		return nil
+	}
+	out := &cluster.Cluster_PreconnectPolicy{}
+	if policy.PerUpstream > 0 {
+		out.PerUpstreamPreconnectRatio = &wrappers.DoubleValue{Value: policy.PerUpstream}
+	}
+	if policy.Predictive > 0 {
+		out.PredictivePreconnectRatio = &wrappers.DoubleValue{Value: policy.Predictive}
+	}
+	return out
+}
+
+// applySlowStart configures the slow start mode of the load balancer, so that new endpoints receive gradually
+// increasing traffic over the warmup duration. Envoy only supports it for the ROUND_ROBIN and LEAST_REQUEST policies.
+func applySlowStart(c *cluster.Cluster, lb *networking.LoadBalancerSettings, aggression float64) {
+	if lb.GetWarmupDurationSecs() == nil {
+		return
+	}
+	slowStart := &cluster.Cluster_SlowStartConfig{
+		SlowStartWindow: gogo.DurationToProtoDuration(lb.GetWarmupDurationSecs()),
+	}
+	if aggression > 0 {
+		slowStart.Aggression = &core.RuntimeDouble{
+			DefaultValue: aggression,
+			RuntimeKey:   "upstream." + c.Name + ".slow_start_aggression",
+		}
+	}
+	switch c.LbPolicy {
+	case cluster.Cluster_ROUND_ROBIN:
+		c.LbConfig = &cluster.Cluster_RoundRobinLbConfig_{
+			RoundRobinLbConfig: &cluster.Cluster_RoundRobinLbConfig{SlowStartConfig: slowStart},
+		}
+	case cluster.Cluster_LEAST_REQUEST:
+		c.LbConfig = &cluster.Cluster_LeastRequestLbConfig_{
+			LeastRequestLbConfig: &cluster.Cluster_LeastRequestLbConfig{SlowStartConfig: slowStart},
+		}
+	default:
+		log.Debugf("ignoring warmupDurationSecs for cluster %s with load balancing policy %v", c.Name, c.LbPolicy)
+	}
+}
+
 // ApplyRingHashLoadBalancer will set the LbPolicy and create an LbConfig for RING_HASH if  used in LoadBalancerSettings
 func ApplyRingHashLoadBalancer(c *cluster.Cluster, lb *networking.LoadBalancerSettings) {
 	consistentHash := lb.GetConsistentHash()
@@ -781,6 +1028,14 @@ func applyLocalityLBSetting(locality *core.Locality, proxyLabels map[string]stri
 	}
 }
 
+// applyConsistentHashLocalityFailover keeps the affinity of consistent hash load balancing across the priorities of
+// the locality failover, for the clusters with inline endpoints. The endpoints of EDS clusters are handled as they
+// are generated.
+func applyConsistentHashLocalityFailover(c *cluster.Cluster, lb *networking.LoadBalancerSettings, meshConfig *meshconfig.MeshConfig) {
+	localityLbSetting := loadbalancer.GetLocalityLbSetting(meshConfig.GetLocalityLbSetting(), lb.GetLocalityLbSetting())
+	loadbalancer.ApplyConsistentHashLocalityFailover(c.LoadAssignment, lb, localityLbSetting, c.OutlierDetection != nil)
+}
+
 func addTelemetryMetadata(opts buildClusterOpts, service *model.Service, direction model.TrafficDirection, instances []*model.ServiceInstance) {
 	if !features.EnableTelemetryLabel {
 		return
@@ -39,6 +39,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
 	"istio.io/istio/pilot/pkg/networking/util"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
@@ -117,6 +118,12 @@ type ClusterBuilder struct {
 	networkView       map[network.ID]bool      // Proxy network view.
 	proxyIPAddresses  []string                 // IP addresses on which proxy is listening on.
 	configNamespace   string                   // Proxy config namespace.
+	socketOptions     []*core.SocketOption     // Socket options of upstream connections of outbound clusters.
+	socketOptionsKey  string                   // Raw socket options metadata, for the cluster cache key.
+	netAdmin          bool                     // Whether the proxy has the NET_ADMIN capability, to set SO_MARK.
+	network           network.ID               // Network of the proxy.
+	// grpcHealthCheckClusters are the service clusters actively health checked with gRPC by a gateway.
+	grpcHealthCheckClusters sets.Set
 	// PushRequest to look for updates.
 	req   *model.PushRequest
 	cache model.XdsCache
@@ -133,6 +140,7 @@ func NewClusterBuilder(proxy *model.Proxy, req *model.PushRequest, cache model.X
 		passThroughBindIP: getPassthroughBindIP(proxy),
 		supportsIPv4:      proxy.SupportsIPv4(),
 		supportsIPv6:      proxy.SupportsIPv6(),
+		netAdmin:          proxy.GetInterceptionMode() == model.InterceptionTproxy,
 		locality:          proxy.Locality,
 		proxyLabels:       proxy.Metadata.Labels,
 		networkView:       proxy.GetNetworkView(),
@@ -150,6 +158,16 @@ func NewClusterBuilder(proxy *model.Proxy, req *model.PushRequest, cache model.X
 			}
 		}
 		cb.clusterID = string(proxy.Metadata.ClusterID)
+		cb.network = proxy.Metadata.Network
+		if proxy.Metadata.ClusterSocketOptions != "" {
+			opts, err := parseSocketOptions(proxy.Metadata.ClusterSocketOptions)
+			if err != nil {
+				log.Warnf("ignoring cluster socket options of proxy %s: %v", proxy.ID, err)
+			} else {
+				cb.socketOptions = opts
+				cb.socketOptionsKey = proxy.Metadata.ClusterSocketOptions
+			}
+		}
 	}
 	return cb
 }
@@ -241,14 +259,23 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 	// merge applicable port level traffic policy settings
 	trafficPolicy := MergeTrafficPolicy(nil, destinationRule.GetTrafficPolicy(), port)
 	opts := buildClusterOpts{
-		mesh:             cb.req.Push.Mesh,
-		serviceInstances: cb.serviceInstances,
-		mutable:          mc,
-		policy:           trafficPolicy,
-		port:             port,
-		clusterMode:      clusterMode,
-		direction:        model.TrafficDirectionOutbound,
-		cache:            cb.cache,
+		mesh:                                  cb.req.Push.Mesh,
+		serviceInstances:                      cb.serviceInstances,
+		mutable:                               mc,
+		policy:                                trafficPolicy,
+		port:                                  port,
+		clusterMode:                           clusterMode,
+		direction:                             model.TrafficDirectionOutbound,
+		cache:                                 cb.cache,
+		warmupAggression:                      warmupAggressionForDestinationRule(destRule),
+		tlsSessionCacheSize:                   tlsSessionCacheSizeForDestinationRule(destRule),
+		alpnProtocols:                         alpnProtocolsForDestinationRule(destRule),
+		connectionPoolPerDownstreamConnection: connectionPoolPerDownstreamConnectionForDestinationRule(destRule),
+		preconnectPolicy:                      preconnectPolicyForDestinationRule(destRule),
+		consistentHashLocalityFailover:        loadbalancer.ConsistentHashLocalityFailoverForDestinationRule(destRule),
+		upstreamSocketOptions:                 upstreamSocketOptionsForDestinationRule(destRule),
+		upstreamIPv6:                          cb.upstreamIPv6(service),
+		lbSubsetKeys:                          loadbalancer.SubsetKeysForDestinationRule(destRule),
 	}
 
 	if clusterMode == DefaultClusterMode {
@@ -258,6 +285,7 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 		opts.meshExternal = service.MeshExternal
 		opts.serviceRegistry = service.Attributes.ServiceRegistry
 		opts.serviceMTLSMode = cb.req.Push.BestEffortInferServiceMTLSMode(destinationRule.GetTrafficPolicy(), service, port)
+		opts.crossNetwork = cb.crossNetworkPolicy(service, port)
 	}
 	// Apply traffic policy for the main default cluster.
 	cb.applyTrafficPolicy(opts)
@@ -396,6 +424,10 @@ func (cb *ClusterBuilder) buildDefaultCluster(name string, discoveryType cluster
 		opts.meshExternal = service.MeshExternal
 	}
 
+	if direction == model.TrafficDirectionOutbound && len(cb.socketOptions) > 0 {
+		c.UpstreamBindConfig = cb.upstreamBindConfig(cb.socketOptions, cb.upstreamIPv6(service))
+	}
+
 	cb.setUpstreamProtocol(ec, port, direction)
 	addTelemetryMetadata(opts, service, direction, allInstances)
 	addNetworkingMetadata(opts, service, direction)
@@ -424,6 +456,12 @@ type clusterCache struct {
 	envoyFilterKeys []string
 	peerAuthVersion string   // identifies the versions of all peer authentications
 	serviceAccounts []string // contains all the service accounts associated with the service
+
+	healthCheckEventLogPath string // set on the health checks added to clusters by envoyfilter patches
+	socketOptions           string // socket options of upstream connections
+	netAdmin                bool   // whether the proxy can set SO_MARK on upstream connections
+	grpcHealthCheck         bool   // whether the clusters are actively health checked with gRPC
+	crossNetwork            string // cross network policy of the service port
 }
 
 func (t *clusterCache) Key() string {
@@ -452,6 +490,8 @@ func (t *clusterCache) Key() string {
 	params = append(params, t.envoyFilterKeys...)
 	params = append(params, t.peerAuthVersion)
 	params = append(params, t.serviceAccounts...)
+	params = append(params, t.healthCheckEventLogPath, t.socketOptions, strconv.FormatBool(t.netAdmin),
+		strconv.FormatBool(t.grpcHealthCheck), t.crossNetwork)
 
 	hash := md5.New()
 	for _, param := range params {
@@ -781,8 +821,19 @@ func (cb *ClusterBuilder) applyTrafficPolicy(opts buildClusterOpts) {
 	cb.applyConnectionPool(opts.mesh, opts.mutable, connectionPool)
 	if opts.direction != model.TrafficDirectionInbound {
 		cb.applyH2Upgrade(opts, connectionPool)
+		cb.applyCrossNetworkPolicy(opts, connectionPool)
 		applyOutlierDetection(opts.mutable.cluster, outlierDetection)
 		applyLoadBalancer(opts.mutable.cluster, loadBalancer, opts.port, cb.locality, cb.proxyLabels, opts.mesh)
+		if opts.consistentHashLocalityFailover {
+			applyConsistentHashLocalityFailover(opts.mutable.cluster, loadBalancer, opts.mesh)
+		}
+		applySlowStart(opts.mutable.cluster, loadBalancer, opts.warmupAggression)
+		opts.mutable.cluster.ConnectionPoolPerDownstreamConnection = opts.connectionPoolPerDownstreamConnection
+		opts.mutable.cluster.PreconnectPolicy = opts.preconnectPolicy
+		cb.applyUpstreamSocketOptions(opts.mutable.cluster, opts.upstreamSocketOptions, opts.upstreamIPv6)
+		if opts.mutable.cluster.GetType() == cluster.Cluster_EDS {
+			loadbalancer.ApplySubsetConfig(opts.mutable.cluster, opts.lbSubsetKeys)
+		}
 		if opts.clusterMode != SniDnatClusterMode {
 			autoMTLSEnabled := opts.mesh.GetEnableAutoMtls().Value
 			tls, mtlsCtxType := cb.buildAutoMtlsSettings(tls, opts.serviceAccounts, opts.istioMtlsSni,
@@ -1006,8 +1057,9 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 	c := opts.mutable
 
 	// Hack to avoid egress sds cluster config generation for sidecar when
-	// CredentialName is set in DestinationRule
-	if tls.CredentialName != "" && cb.sidecarProxy() {
+	// CredentialName is set in DestinationRule, unless sidecars are allowed to fetch
+	// the credential from Istiod.
+	if tls.CredentialName != "" && cb.sidecarProxy() && !features.EnableSidecarCredentialName {
 		if tls.Mode == networking.ClientTLSSettings_SIMPLE || tls.Mode == networking.ClientTLSSettings_MUTUAL {
 			return nil, nil
 		}
@@ -1144,6 +1196,12 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 			tlsContext.CommonTlsContext.AlpnProtocols = util.ALPNH2Only
 		}
 	}
+	if tlsContext != nil {
+		tlsContext.MaxSessionKeys = opts.tlsSessionCacheSize
+		if opts.alpnProtocols != nil && (tls.Mode == networking.ClientTLSSettings_SIMPLE || tls.Mode == networking.ClientTLSSettings_MUTUAL) {
+			tlsContext.CommonTlsContext.AlpnProtocols = opts.alpnProtocols
+		}
+	}
 	return tlsContext, nil
 }
 
@@ -2469,6 +2469,48 @@ func TestBuildUpstreamClusterTLSContext(t *testing.T) {
 	}
 }
 
+func TestBuildUpstreamClusterTLSContextSidecarCredentialName(t *testing.T) {
+	defer func(v bool) { features.EnableSidecarCredentialName = v }(features.EnableSidecarCredentialName)
+	features.EnableSidecarCredentialName = true
+
+	cb := NewClusterBuilder(newSidecarProxy(), nil, model.DisabledCache{})
+	opts := &buildClusterOpts{mutable: newTestCluster()}
+	ret, err := cb.buildUpstreamClusterTLSContext(opts, &networking.ClientTLSSettings{
+		Mode:            networking.ClientTLSSettings_MUTUAL,
+		CredentialName:  "egress-cred",
+		SubjectAltNames: []string{"SAN"},
+		Sni:             "some-sni.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &tls.UpstreamTlsContext{
+		CommonTlsContext: &tls.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{
+				{
+					Name:      "kubernetes://egress-cred",
+					SdsConfig: authn_model.SDSAdsConfig,
+				},
+			},
+			ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext: &tls.CertificateValidationContext{
+						MatchSubjectAltNames: util.StringToExactMatch([]string{"SAN"}),
+					},
+					ValidationContextSdsSecretConfig: &tls.SdsSecretConfig{
+						Name:      "kubernetes://egress-cred" + authn_model.SdsCaSuffix,
+						SdsConfig: authn_model.SDSAdsConfig,
+					},
+				},
+			},
+		},
+		Sni: "some-sni.com",
+	}
+	if diff := cmp.Diff(expected, ret, protocmp.Transform()); diff != "" {
+		t.Errorf("got diff: `%v", diff)
+	}
+}
+
 func newTestCluster() *MutableCluster {
 	return NewMutableCluster(&cluster.Cluster{
 		Name: "test-cluster",
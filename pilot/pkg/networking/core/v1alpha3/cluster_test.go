@@ -25,6 +25,7 @@ import (
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	http "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
@@ -1862,6 +1863,99 @@ func TestEnvoyFilterPatching(t *testing.T) {
 	}
 }
 
+func TestHealthCheckEventLogPath(t *testing.T) {
+	service := &model.Service{
+		Hostname: host.Name("static.test"),
+		Ports: []*model.Port{
+			{
+				Name:     "default",
+				Port:     8080,
+				Protocol: protocol.HTTP,
+			},
+		},
+		Resolution: model.Passthrough,
+	}
+	healthCheck := `{"timeout":"1s","interval":"5s","unhealthy_threshold":3,"healthy_threshold":1,"http_health_check":{"path":"/healthz"}}`
+	ef := &networking.EnvoyFilter{
+		ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			{
+				ApplyTo: networking.EnvoyFilter_CLUSTER,
+				Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Context: networking.EnvoyFilter_SIDECAR_OUTBOUND,
+					ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Cluster{
+						Cluster: &networking.EnvoyFilter_ClusterMatch{Name: "outbound|8080||static.test"},
+					},
+				},
+				Patch: &networking.EnvoyFilter_Patch{
+					Operation: networking.EnvoyFilter_Patch_MERGE,
+					Value:     buildPatchStruct(`{"health_checks":[` + healthCheck + `]}`),
+				},
+			},
+			{
+				ApplyTo: networking.EnvoyFilter_CLUSTER,
+				Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Context: networking.EnvoyFilter_SIDECAR_OUTBOUND,
+				},
+				Patch: &networking.EnvoyFilter_Patch{
+					Operation: networking.EnvoyFilter_Patch_ADD,
+					Value: buildPatchStruct(`{"name":"new-cluster1","health_checks":[` + healthCheck + `,` +
+						`{"timeout":"1s","interval":"5s","event_log_path":"/dev/stdout","tcp_health_check":{}}]}`),
+				},
+			},
+		},
+	}
+	cfgs := []config.Config{{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.EnvoyFilter,
+			Name:             "health-check",
+			Namespace:        "default",
+		},
+		Spec: ef,
+	}}
+
+	cases := []struct {
+		name string
+		path string
+		want map[string][]string
+	}{
+		{
+			name: "not configured",
+			want: map[string][]string{
+				"outbound|8080||static.test": {""},
+				"new-cluster1":               {"", "/dev/stdout"},
+			},
+		},
+		{
+			name: "configured",
+			path: "/var/log/health.log",
+			want: map[string][]string{
+				"outbound|8080||static.test": {"/var/log/health.log"},
+				"new-cluster1":               {"/var/log/health.log", "/dev/stdout"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{Configs: cfgs, Services: []*model.Service{service}})
+			proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{HealthCheckEventLogPath: tt.path}})
+			clusters := xdstest.ExtractClusters(cg.Clusters(proxy))
+			for name, want := range tt.want {
+				c, f := clusters[name]
+				if !f {
+					t.Fatalf("cluster %v not found", name)
+				}
+				got := []string{}
+				for _, hc := range c.HealthChecks {
+					got = append(got, hc.EventLogPath)
+				}
+				if !cmp.Equal(got, want) {
+					t.Fatalf("%v: want event log paths %v got %v", name, want, got)
+				}
+			}
+		})
+	}
+}
+
 func TestTelemetryMetadata(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -2421,3 +2515,322 @@ func TestVerifyCertAtClient(t *testing.T) {
 		})
 	}
 }
+
+const slowStartConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - foo.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      version: v1
+  - address: 2.2.2.2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: foo
+  namespace: default
+  annotations:
+    networking.istio.io/warmup-aggression: "2.5"
+spec:
+  host: foo.example.com
+  trafficPolicy:
+    loadBalancer:
+      simple: LEAST_REQUEST
+      warmupDurationSecs: 30s
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+    trafficPolicy:
+      loadBalancer:
+        simple: ROUND_ROBIN
+        warmupDurationSecs: 60s
+  - name: v2
+    labels:
+      version: v2
+    trafficPolicy:
+      loadBalancer:
+        simple: RANDOM
+        warmupDurationSecs: 60s
+`
+
+func TestSlowStart(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: slowStartConfig})
+	clusters := cg.Clusters(cg.SetupProxy(nil))
+
+	c := xdstest.ExtractCluster("outbound|80||foo.example.com", clusters)
+	slowStart := c.GetLeastRequestLbConfig().GetSlowStartConfig()
+	if got := slowStart.GetSlowStartWindow().AsDuration(); got != 30*time.Second {
+		t.Errorf("got slow start window %v, want 30s", got)
+	}
+	if got := slowStart.GetAggression().GetDefaultValue(); got != 2.5 {
+		t.Errorf("got aggression %v, want 2.5", got)
+	}
+
+	c = xdstest.ExtractCluster("outbound|80|v1|foo.example.com", clusters)
+	if got := c.GetRoundRobinLbConfig().GetSlowStartConfig().GetSlowStartWindow().AsDuration(); got != time.Minute {
+		t.Errorf("got slow start window %v, want 1m", got)
+	}
+
+	c = xdstest.ExtractCluster("outbound|80|v2|foo.example.com", clusters)
+	if c.LbConfig != nil {
+		t.Errorf("expected no lb config for RANDOM, got %v", c.LbConfig)
+	}
+}
+
+func TestWarmupAggressionForDestinationRule(t *testing.T) {
+	cases := []struct {
+		value string
+		want  float64
+	}{
+		{value: "1", want: 1},
+		{value: "2.5", want: 2.5},
+		{value: "0"},
+		{value: "-1"},
+		{value: "fast"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Annotations: map[string]string{WarmupAggressionAnnotation: tt.value}}}
+			if got := warmupAggressionForDestinationRule(dr); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const tlsSessionCacheConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  ports:
+  - number: 443
+    name: https
+    protocol: HTTPS
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: api
+  namespace: default
+  annotations:
+    networking.istio.io/tls-session-cache-size: "100"
+spec:
+  host: api.example.com
+  trafficPolicy:
+    tls:
+      mode: SIMPLE
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+func TestTLSSessionCacheSize(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: tlsSessionCacheConfig})
+	clusters := cg.Clusters(cg.SetupProxy(nil))
+
+	for _, name := range []string{"outbound|443||api.example.com", "outbound|443|v1|api.example.com"} {
+		c := xdstest.ExtractCluster(name, clusters)
+		tlsContext := &tls.UpstreamTlsContext{}
+		if err := c.GetTransportSocket().GetTypedConfig().UnmarshalTo(tlsContext); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := tlsContext.GetMaxSessionKeys().GetValue(); got != 100 {
+			t.Errorf("%s: got max session keys %v, want 100", name, got)
+		}
+	}
+}
+
+func TestTLSSessionCacheSizeForDestinationRule(t *testing.T) {
+	cases := []struct {
+		value string
+		want  *wrappers.UInt32Value
+	}{
+		{value: "100", want: &wrappers.UInt32Value{Value: 100}},
+		{value: "0", want: &wrappers.UInt32Value{Value: 0}},
+		{value: "-1"},
+		{value: "large"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Annotations: map[string]string{TLSSessionCacheSizeAnnotation: tt.value}}}
+			if got := tlsSessionCacheSizeForDestinationRule(dr); !cmp.Equal(got, tt.want, protocmp.Transform()) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const upstreamALPNConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  ports:
+  - number: 443
+    name: http2
+    protocol: HTTP2
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: api
+  namespace: default
+  annotations:
+    networking.istio.io/upstream-alpn-protocols: "%s"
+spec:
+  host: api.example.com
+  trafficPolicy:
+    tls:
+      mode: %s
+`
+
+func TestUpstreamALPNProtocols(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		mode  string
+		want  []string
+	}{
+		{name: "protocols", value: "http/1.1, h2", mode: "SIMPLE", want: []string{"http/1.1", "h2"}},
+		{name: "no protocol", value: "", mode: "SIMPLE", want: nil},
+		// The peer metadata exchange of the ISTIO_MUTUAL connections needs its protocols.
+		{name: "istio mutual", value: "http/1.1", mode: "ISTIO_MUTUAL", want: util.ALPNInMeshH2WithMxc},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{ConfigString: fmt.Sprintf(upstreamALPNConfig, tt.value, tt.mode)})
+			c := xdstest.ExtractCluster("outbound|443||api.example.com", cg.Clusters(cg.SetupProxy(nil)))
+			tlsContext := &tls.UpstreamTlsContext{}
+			if err := c.GetTransportSocket().GetTypedConfig().UnmarshalTo(tlsContext); err != nil {
+				t.Fatal(err)
+			}
+			if got := tlsContext.GetCommonTlsContext().GetAlpnProtocols(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got ALPN protocols %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const connectionPoolPartitionConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: backend
+  namespace: default
+spec:
+  hosts:
+  - backend.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: backend
+  namespace: default
+  annotations:
+    networking.istio.io/connection-pool-per-downstream-connection: "true"
+spec:
+  host: backend.example.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+func TestConnectionPoolPerDownstreamConnection(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: connectionPoolPartitionConfig})
+	clusters := cg.Clusters(cg.SetupProxy(nil))
+
+	for _, name := range []string{"outbound|80||backend.example.com", "outbound|80|v1|backend.example.com"} {
+		if c := xdstest.ExtractCluster(name, clusters); !c.GetConnectionPoolPerDownstreamConnection() {
+			t.Errorf("%s: expected connection pool per downstream connection", name)
+		}
+	}
+}
+
+func TestConnectionPoolPerDownstreamConnectionForDestinationRule(t *testing.T) {
+	cases := []struct {
+		value string
+		want  bool
+	}{
+		{value: "true", want: true},
+		{value: "false", want: false},
+		{value: "maybe", want: false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Annotations: map[string]string{ConnectionPoolPerDownstreamConnectionAnnotation: tt.value}}}
+			if got := connectionPoolPerDownstreamConnectionForDestinationRule(dr); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if connectionPoolPerDownstreamConnectionForDestinationRule(nil) {
+		t.Fatalf("expected no partitioning without DestinationRule")
+	}
+}
+
+func TestPreconnectPolicy(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		ConfigString: strings.Replace(connectionPoolPartitionConfig, "connection-pool-per-downstream-connection: \"true\"",
+			"preconnect-policy: \"perUpstream=1.5,predictive=2\"", 1),
+	})
+	clusters := cg.Clusters(cg.SetupProxy(nil))
+
+	for _, name := range []string{"outbound|80||backend.example.com", "outbound|80|v1|backend.example.com"} {
+		c := xdstest.ExtractCluster(name, clusters)
+		if got := c.GetPreconnectPolicy().GetPerUpstreamPreconnectRatio().GetValue(); got != 1.5 {
+			t.Errorf("%s: got per upstream preconnect ratio %v, want 1.5", name, got)
+		}
+		if got := c.GetPreconnectPolicy().GetPredictivePreconnectRatio().GetValue(); got != 2 {
+			t.Errorf("%s: got predictive preconnect ratio %v, want 2", name, got)
+		}
+	}
+}
+
+func TestPreconnectPolicyForDestinationRule(t *testing.T) {
+	if preconnectPolicyForDestinationRule(nil) != nil {
+		t.Fatalf("expected no preconnect policy without DestinationRule")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+// Annotations of a workload tuning the downstream connections of its proxy, for example larger buffers and HTTP/2
+// windows for bulk transfer services, or smaller ones to save memory on small services. Envoy defaults apply to the
+// settings that are not set.
+const (
+	// ListenerBufferLimitAnnotation sets the soft limit in bytes of the read and write buffers of the connections
+	// accepted by the listeners of the proxy.
+	ListenerBufferLimitAnnotation = "proxy.istio.io/listenerBufferLimitBytes"
+	// HTTP2InitialStreamWindowSizeAnnotation sets the initial HTTP/2 stream window size of the downstream
+	// connections, between 65535 and 2147483647 bytes.
+	HTTP2InitialStreamWindowSizeAnnotation = "proxy.istio.io/http2InitialStreamWindowSize"
+	// HTTP2InitialConnectionWindowSizeAnnotation sets the initial HTTP/2 connection window size of the downstream
+	// connections, between 65535 and 2147483647 bytes.
+	HTTP2InitialConnectionWindowSizeAnnotation = "proxy.istio.io/http2InitialConnectionWindowSize"
+	// HTTP2MaxConcurrentStreamsAnnotation sets the maximum number of concurrent HTTP/2 streams of a downstream
+	// connection.
+	HTTP2MaxConcurrentStreamsAnnotation = "proxy.istio.io/http2MaxConcurrentStreams"
+)
+
+const (
+	minHTTP2WindowSize = 65535
+	maxHTTP2Value      = math.MaxInt32
+)
+
+// applyListenerBufferLimit sets the connection buffer limit of a listener from the annotation of the workload.
+func applyListenerBufferLimit(node *model.Proxy, l *listener.Listener) {
+	if v, ok := connectionTuningValue(node, ListenerBufferLimitAnnotation, 1, math.MaxUint32); ok {
+		l.PerConnectionBufferLimitBytes = &wrappers.UInt32Value{Value: v}
+	}
+}
+
+// applyHTTP2Tuning sets the HTTP/2 options of the connection manager of a listener from the annotations of the
+// workload.
+func applyHTTP2Tuning(node *model.Proxy, connectionManager *hcm.HttpConnectionManager) {
+	streamWindow, hasStreamWindow := connectionTuningValue(node, HTTP2InitialStreamWindowSizeAnnotation,
+		minHTTP2WindowSize, maxHTTP2Value)
+	connectionWindow, hasConnectionWindow := connectionTuningValue(node, HTTP2InitialConnectionWindowSizeAnnotation,
+		minHTTP2WindowSize, maxHTTP2Value)
+	maxStreams, hasMaxStreams := connectionTuningValue(node, HTTP2MaxConcurrentStreamsAnnotation, 1, maxHTTP2Value)
+	if !hasStreamWindow && !hasConnectionWindow && !hasMaxStreams {
+		return
+	}
+	if connectionManager.Http2ProtocolOptions == nil {
+		connectionManager.Http2ProtocolOptions = &core.Http2ProtocolOptions{}
+	}
+	options := connectionManager.Http2ProtocolOptions
+	if hasStreamWindow {
+		options.InitialStreamWindowSize = &wrappers.UInt32Value{Value: streamWindow}
+	}
+	if hasConnectionWindow {
+		options.InitialConnectionWindowSize = &wrappers.UInt32Value{Value: connectionWindow}
+	}
+	if hasMaxStreams {
+		options.MaxConcurrentStreams = &wrappers.UInt32Value{Value: maxStreams}
+	}
+}
+
+// connectionTuningValue returns the value of a connection tuning annotation of the workload, if it is set and valid.
+func connectionTuningValue(node *model.Proxy, annotation string, min, max uint64) (uint32, bool) {
+	v, f := node.Metadata.Annotations[annotation]
+	if !f {
+		return 0, false
+	}
+	n, err := parseConnectionTuningValue(v, min, max)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation of proxy %s: %v", annotation, node.ID, err)
+		return 0, false
+	}
+	return n, true
+}
+
+func parseConnectionTuningValue(v string, min, max uint64) (uint32, error) {
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid value %q, must be between %d and %d", v, min, max)
+	}
+	return uint32(n), nil
+}
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestApplyListenerBufferLimit(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *wrappers.UInt32Value
+	}{
+		{name: "default"},
+		{
+			name:        "limit",
+			annotations: map[string]string{ListenerBufferLimitAnnotation: "4194304"},
+			want:        &wrappers.UInt32Value{Value: 4194304},
+		},
+		{name: "zero", annotations: map[string]string{ListenerBufferLimitAnnotation: "0"}},
+		{name: "invalid", annotations: map[string]string{ListenerBufferLimitAnnotation: "4Mi"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &listener.Listener{}
+			applyListenerBufferLimit(&model.Proxy{Metadata: &model.NodeMetadata{Annotations: tt.annotations}}, l)
+			if diff := cmp.Diff(tt.want, l.PerConnectionBufferLimitBytes, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestApplyHTTP2Tuning(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		existing    *core.Http2ProtocolOptions
+		want        *core.Http2ProtocolOptions
+	}{
+		{name: "default"},
+		{
+			name: "all",
+			annotations: map[string]string{
+				HTTP2InitialStreamWindowSizeAnnotation:     "1048576",
+				HTTP2InitialConnectionWindowSizeAnnotation: "16777216",
+				HTTP2MaxConcurrentStreamsAnnotation:        "1000",
+			},
+			want: &core.Http2ProtocolOptions{
+				InitialStreamWindowSize:     &wrappers.UInt32Value{Value: 1048576},
+				InitialConnectionWindowSize: &wrappers.UInt32Value{Value: 16777216},
+				MaxConcurrentStreams:        &wrappers.UInt32Value{Value: 1000},
+			},
+		},
+		{
+			name:        "existing options",
+			annotations: map[string]string{HTTP2MaxConcurrentStreamsAnnotation: "10"},
+			existing:    &core.Http2ProtocolOptions{AllowConnect: true},
+			want: &core.Http2ProtocolOptions{
+				AllowConnect:         true,
+				MaxConcurrentStreams: &wrappers.UInt32Value{Value: 10},
+			},
+		},
+		{
+			name:        "window too small",
+			annotations: map[string]string{HTTP2InitialStreamWindowSizeAnnotation: "1024"},
+		},
+		{
+			name:        "window too large",
+			annotations: map[string]string{HTTP2InitialConnectionWindowSizeAnnotation: "4294967295"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			connectionManager := &hcm.HttpConnectionManager{Http2ProtocolOptions: tt.existing}
+			applyHTTP2Tuning(&model.Proxy{Metadata: &model.NodeMetadata{Annotations: tt.annotations}}, connectionManager)
+			if diff := cmp.Diff(tt.want, connectionManager.Http2ProtocolOptions, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/network"
+	"istio.io/pkg/log"
+)
+
+// crossNetworkPolicy tunes the upstream connections of the clusters of the services with endpoints in another
+// network than the proxy. The mTLS connections across networks are tunneled through the east-west gateways, which
+// cannot compress them; multiplexing the requests over HTTP/2 connections with larger windows reduces the overhead
+// and the number of connections of the traffic across regions instead.
+type crossNetworkPolicy struct {
+	// From is the network of the proxies the policy applies to. Empty for all the networks.
+	From network.ID `json:"from"`
+	// To is the network of the endpoints the policy applies to. Empty for all the networks but the one of the proxy.
+	To network.ID `json:"to"`
+	// H2Upgrade upgrades the HTTP/1.1 connections to HTTP/2, unless the DestinationRule sets DO_NOT_UPGRADE.
+	H2Upgrade bool `json:"h2Upgrade"`
+	// InitialStreamWindowSize and InitialConnectionWindowSize set the HTTP/2 windows of the connections, between
+	// 65535 and 2147483647 bytes.
+	InitialStreamWindowSize     uint32 `json:"initialStreamWindowSize"`
+	InitialConnectionWindowSize uint32 `json:"initialConnectionWindowSize"`
+}
+
+var crossNetworkPolicies = func() []*crossNetworkPolicy {
+	p, err := parseCrossNetworkPolicies(features.CrossNetworkTrafficPolicy)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_CROSS_NETWORK_TRAFFIC_POLICY: %v", err)
+	}
+	return p
+}()
+
+// parseCrossNetworkPolicies parses a JSON list of cross network policies, for example
+// [{"from": "network-east", "to": "network-west", "h2Upgrade": true, "initialConnectionWindowSize": 16777216}].
+func parseCrossNetworkPolicies(config string) ([]*crossNetworkPolicy, error) {
+	if config == "" {
+		return nil, nil
+	}
+	var policies []*crossNetworkPolicy
+	if err := json.Unmarshal([]byte(config), &policies); err != nil {
+		return nil, err
+	}
+	for _, p := range policies {
+		if p.From != "" && p.From == p.To {
+			return nil, fmt.Errorf("policy from network %s to the same network", p.From)
+		}
+		for _, size := range []uint32{p.InitialStreamWindowSize, p.InitialConnectionWindowSize} {
+			if size != 0 && (size < minHTTP2WindowSize || size > maxHTTP2Value) {
+				return nil, fmt.Errorf("invalid window size %d, must be between %d and %d", size, minHTTP2WindowSize, maxHTTP2Value)
+			}
+		}
+	}
+	return policies, nil
+}
+
+// key identifies the policy in the cluster cache key.
+func (p *crossNetworkPolicy) key() string {
+	if p == nil {
+		return ""
+	}
+	return string(p.From) + "~" + string(p.To) + "~" + strconv.FormatBool(p.H2Upgrade) + "~" +
+		strconv.FormatUint(uint64(p.InitialStreamWindowSize), 10) + "~" + strconv.FormatUint(uint64(p.InitialConnectionWindowSize), 10)
+}
+
+// crossNetworkPolicy returns the first cross network policy from the network of the proxy to a network of the
+// endpoints of the service port, or nil. The networks of the endpoints are indexed by the push context, whose full
+// pushes the endpoint updates changing them trigger.
+func (cb *ClusterBuilder) crossNetworkPolicy(service *model.Service, port *model.Port) *crossNetworkPolicy {
+	if len(crossNetworkPolicies) == 0 {
+		return nil
+	}
+	var candidates []*crossNetworkPolicy
+	for _, p := range crossNetworkPolicies {
+		if p.From == "" || p.From == cb.network {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	networks := map[network.ID]bool{}
+	for _, nw := range cb.req.Push.ServiceNetworks(service, port.Port) {
+		if nw != cb.network {
+			networks[nw] = true
+		}
+	}
+	for _, p := range candidates {
+		if (p.To == "" && len(networks) > 0) || networks[p.To] {
+			return p
+		}
+	}
+	return nil
+}
+
+// applyCrossNetworkPolicy upgrades an outbound cluster to HTTP/2 and sets its windows from its cross network policy.
+func (cb *ClusterBuilder) applyCrossNetworkPolicy(opts buildClusterOpts, connectionPool *networking.ConnectionPoolSettings) {
+	p := opts.crossNetwork
+	if p == nil {
+		return
+	}
+	mc := opts.mutable
+	if p.H2Upgrade && !cb.IsHttp2Cluster(mc) && opts.port != nil && opts.port.Protocol.IsHTTP() &&
+		connectionPool.GetHttp().GetH2UpgradePolicy() != networking.ConnectionPoolSettings_HTTPSettings_DO_NOT_UPGRADE {
+		cb.setH2Options(mc)
+	}
+	if !cb.IsHttp2Cluster(mc) {
+		return
+	}
+	options := mc.httpProtocolOptions.GetExplicitHttpConfig().GetHttp2ProtocolOptions()
+	if p.InitialStreamWindowSize != 0 {
+		options.InitialStreamWindowSize = &wrappers.UInt32Value{Value: p.InitialStreamWindowSize}
+	}
+	if p.InitialConnectionWindowSize != 0 {
+		options.InitialConnectionWindowSize = &wrappers.UInt32Value{Value: p.InitialConnectionWindowSize}
+	}
+}
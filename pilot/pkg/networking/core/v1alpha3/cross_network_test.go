@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+)
+
+func TestParseCrossNetworkPolicies(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{name: "empty", in: ""},
+		{name: "policies", in: `[{"from": "east", "to": "west", "h2Upgrade": true}, {"initialConnectionWindowSize": 16777216}]`, want: 2},
+		{name: "same network", in: `[{"from": "east", "to": "east"}]`, wantErr: true},
+		{name: "small window", in: `[{"initialStreamWindowSize": 1024}]`, wantErr: true},
+		{name: "invalid json", in: `{"from": "east"}`, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCrossNetworkPolicies(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d policies, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+const crossNetworkConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: remote
+  namespace: default
+spec:
+  hosts:
+  - remote.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+    network: west
+  - address: 3.3.3.3
+    network: east
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: local
+  namespace: default
+spec:
+  hosts:
+  - local.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 4.4.4.4
+    network: east
+`
+
+func TestCrossNetworkPolicy(t *testing.T) {
+	defaultValue := crossNetworkPolicies
+	crossNetworkPolicies = []*crossNetworkPolicy{
+		{From: "north", H2Upgrade: true},
+		{From: "east", To: "west", H2Upgrade: true, InitialStreamWindowSize: 1048576, InitialConnectionWindowSize: 16777216},
+	}
+	defer func() { crossNetworkPolicies = defaultValue }()
+
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: crossNetworkConfig})
+	proxy := newSidecarProxy()
+	proxy.IPAddresses = []string{"10.0.0.1"}
+	proxy.Metadata.Network = "east"
+	clusters := cg.Clusters(cg.SetupProxy(proxy))
+
+	options := func(name string) *http.HttpProtocolOptions {
+		t.Helper()
+		c := xdstest.ExtractCluster(name, clusters)
+		if c == nil {
+			t.Fatalf("cluster %s not found", name)
+		}
+		out := &http.HttpProtocolOptions{}
+		if anyOptions := c.TypedExtensionProtocolOptions[v3.HttpProtocolOptionsType]; anyOptions != nil {
+			if err := anyOptions.UnmarshalTo(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out
+	}
+
+	h2 := options("outbound|80||remote.example.com").GetExplicitHttpConfig().GetHttp2ProtocolOptions()
+	if h2 == nil {
+		t.Fatalf("expected the cluster of the service with endpoints in another network to be upgraded to HTTP/2")
+	}
+	if h2.GetInitialStreamWindowSize().GetValue() != 1048576 || h2.GetInitialConnectionWindowSize().GetValue() != 16777216 {
+		t.Fatalf("unexpected HTTP/2 windows: %v", h2)
+	}
+	if options("outbound|80||local.example.com").GetExplicitHttpConfig().GetHttp2ProtocolOptions() != nil {
+		t.Fatalf("did not expect the cluster of the service in the network of the proxy to be upgraded to HTTP/2")
+	}
+
+	cb := NewClusterBuilder(cg.SetupProxy(proxy), &model.PushRequest{Push: cg.PushContext()}, nil)
+	svc := cg.PushContext().ServiceForHostname(proxy, "remote.example.com")
+	if p := cb.crossNetworkPolicy(svc, svc.Ports[0]); p != crossNetworkPolicies[1] {
+		t.Fatalf("got policy %v, want the policy from east to west", p)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"encoding/base64"
+	"net"
+	"strconv"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/pkg/log"
+)
+
+// proxyAuthorizationHeader is the header sending the credentials of the egress proxy.
+const proxyAuthorizationHeader = "Proxy-Authorization"
+
+// egressProxyClusterName returns the name of the outbound cluster of the egress proxy of the sidecar.
+func egressProxyClusterName(egressProxy *model.EgressProxy) string {
+	return model.BuildSubsetKey(model.TrafficDirectionOutbound, "", egressProxy.Hostname, egressProxy.Port)
+}
+
+// egressProxyAuthorization returns the header sending the credentials of the egress proxy of the sidecar, read
+// from the Secret of the cluster of the proxy. It returns nil if the egress proxy has no credentials or they cannot
+// be read, in which case the requests are sent without credentials and rejected by the proxy.
+func egressProxyAuthorization(node *model.Proxy, push *model.PushContext) *core.HeaderValueOption {
+	egressProxy := node.SidecarScope.EgressProxy
+	if egressProxy.CredentialName == "" {
+		return nil
+	}
+	if push.CredentialsController == nil {
+		log.Warnf("cannot read the egress proxy credential %s/%s of %s: Secrets are not read",
+			egressProxy.Namespace, egressProxy.CredentialName, node.ID)
+		return nil
+	}
+	creds, err := push.CredentialsController.ForCluster(node.Metadata.ClusterID)
+	var username, password []byte
+	if err == nil {
+		username, password, err = creds.GetBasicAuth(egressProxy.CredentialName, egressProxy.Namespace)
+	}
+	if err != nil {
+		log.Warnf("cannot read the egress proxy credential %s/%s of %s: %v",
+			egressProxy.Namespace, egressProxy.CredentialName, node.ID, err)
+		return nil
+	}
+	return &core.HeaderValueOption{
+		Header: &core.HeaderValue{
+			Key:   proxyAuthorizationHeader,
+			Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(string(username)+":"+string(password))),
+		},
+		Append: &wrappers.BoolValue{Value: false},
+	}
+}
+
+// originatesTLS returns whether the destination rule originates TLS to the port of the service.
+func originatesTLS(dr *networking.DestinationRule, port *model.Port) bool {
+	if dr == nil {
+		return false
+	}
+	policy := MergeTrafficPolicy(nil, dr.TrafficPolicy, port)
+	return policy.GetTls() != nil && policy.GetTls().Mode != networking.ClientTLSSettings_DISABLE
+}
+
+// applyEgressProxyHTTPRoutes sends the requests to the HTTP ports of the external services without a VirtualService
+// to the egress proxy of the sidecar, with the absolute URL of the request as path and the credentials of the proxy.
+// The ports a DestinationRule originates TLS to are left untouched, as the proxy would receive them in plaintext.
+func applyEgressProxyHTTPRoutes(node *model.Proxy, push *model.PushContext, virtualHostWrappers []istio_route.VirtualHostWrapper,
+	routeCache *istio_route.Cache) {
+	if node.Type != model.SidecarProxy || node.SidecarScope == nil || node.SidecarScope.EgressProxy == nil {
+		return
+	}
+	egressProxy := node.SidecarScope.EgressProxy
+	if routeCache != nil {
+		routeCache.EgressProxy = egressProxy
+	}
+	var authorization *core.HeaderValueOption
+	authorizationRead := false
+	for _, wrapper := range virtualHostWrappers {
+		if len(wrapper.VirtualServiceHosts) > 0 || len(wrapper.Services) != 1 || !useEgressProxy(node, wrapper.Services[0]) {
+			continue
+		}
+		svc := wrapper.Services[0]
+		port, f := svc.Ports.GetByPort(wrapper.Port)
+		if !f || port.Protocol != protocol.HTTP {
+			continue
+		}
+		dr := push.DestinationRule(node, svc)
+		if dr != nil && routeCache != nil {
+			routeCache.DestinationRules = append(routeCache.DestinationRules, dr)
+		}
+		if originatesTLS(CastDestinationRule(dr), port) {
+			continue
+		}
+		if !authorizationRead {
+			authorization = egressProxyAuthorization(node, push)
+			authorizationRead = true
+		}
+		url := "http://" + string(svc.Hostname)
+		if port.Port != 80 {
+			url = "http://" + net.JoinHostPort(string(svc.Hostname), strconv.Itoa(port.Port))
+		}
+		clusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", svc.Hostname, port.Port)
+		for _, r := range wrapper.Routes {
+			action := r.GetRoute()
+			if action.GetCluster() != clusterName || r.GetMatch().GetPrefix() != "/" {
+				continue
+			}
+			action.ClusterSpecifier = &route.RouteAction_Cluster{Cluster: egressProxyClusterName(egressProxy)}
+			action.PrefixRewrite = url + "/"
+			if authorization != nil {
+				r.RequestHeadersToAdd = append(r.RequestHeadersToAdd, authorization)
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/pilot/pkg/credentials"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const egressProxyConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: default
+  namespace: istio-system
+  annotations:
+    networking.istio.io/egress-proxy: proxy.corp.example.com:3128
+    networking.istio.io/egress-proxy-credential: proxy-credential
+spec:
+  egress:
+  - hosts:
+    - "*/*"
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: corp-proxy
+  namespace: default
+spec:
+  hosts:
+  - proxy.corp.example.com
+  ports:
+  - number: 3128
+    name: tcp-proxy
+    protocol: TCP
+  location: MESH_EXTERNAL
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: external
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  addresses:
+  - 2.2.2.2
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  - number: 80
+    name: http
+    protocol: HTTP
+  - number: 8080
+    name: http-alt
+    protocol: HTTP
+  location: MESH_EXTERNAL
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: originated
+  namespace: default
+spec:
+  hosts:
+  - originated.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  location: MESH_EXTERNAL
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: originated
+  namespace: default
+spec:
+  host: originated.example.com
+  trafficPolicy:
+    tls:
+      mode: SIMPLE
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: internal
+  namespace: default
+spec:
+  hosts:
+  - internal.example.com
+  addresses:
+  - 3.3.3.3
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  - number: 80
+    name: http
+    protocol: HTTP
+  location: MESH_INTERNAL
+  resolution: DNS
+`
+
+type fakeBasicAuthCredentials struct {
+	secrets map[string][2]string
+}
+
+var (
+	_ credentials.MulticlusterController = fakeBasicAuthCredentials{}
+	_ credentials.Controller             = fakeBasicAuthCredentials{}
+)
+
+func (f fakeBasicAuthCredentials) ForCluster(cluster.ID) (credentials.Controller, error) {
+	return f, nil
+}
+
+func (f fakeBasicAuthCredentials) GetKeyAndCert(name, namespace string) (key []byte, cert []byte, err error) {
+	return nil, nil, fmt.Errorf("secret %v/%v not found", namespace, name)
+}
+
+func (f fakeBasicAuthCredentials) GetCaCert(name, namespace string) (cert []byte, err error) {
+	return nil, fmt.Errorf("secret %v/%v not found", namespace, name)
+}
+
+func (f fakeBasicAuthCredentials) GetBasicAuth(name, namespace string) (username []byte, password []byte, err error) {
+	s, found := f.secrets[namespace+"/"+name]
+	if !found {
+		return nil, nil, fmt.Errorf("secret %v/%v not found", namespace, name)
+	}
+	return []byte(s[0]), []byte(s[1]), nil
+}
+
+func (f fakeBasicAuthCredentials) Authorize(serviceAccount, namespace string) error {
+	return nil
+}
+
+func (f fakeBasicAuthCredentials) AddEventHandler(func(name, namespace string)) {}
+
+func TestEgressProxy(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		ConfigString: egressProxyConfig,
+		CredentialsController: fakeBasicAuthCredentials{secrets: map[string][2]string{
+			"istio-system/proxy-credential": {"user", "pass"},
+		}},
+	})
+	proxy := cg.SetupProxy(nil)
+	listeners := cg.Listeners(proxy)
+	// base64("user:pass")
+	authorization := "Basic dXNlcjpwYXNz"
+
+	tcpProxy := xdstest.ExtractTCPProxy(t, xdstest.ExtractListener("2.2.2.2_443", listeners).GetFilterChains()[0])
+	assert.Equal(t, tcpProxy.GetCluster(), "outbound|3128||proxy.corp.example.com")
+	assert.Equal(t, tcpProxy.GetTunnelingConfig().GetHostname(), "api.example.com:443")
+	assert.Equal(t, len(tcpProxy.GetTunnelingConfig().GetHeadersToAdd()), 1)
+	assert.Equal(t, tcpProxy.GetTunnelingConfig().GetHeadersToAdd()[0].GetHeader().GetKey(), "Proxy-Authorization")
+	assert.Equal(t, tcpProxy.GetTunnelingConfig().GetHeadersToAdd()[0].GetHeader().GetValue(), authorization)
+
+	tcpProxy = xdstest.ExtractTCPProxy(t, xdstest.ExtractListener("3.3.3.3_443", listeners).GetFilterChains()[0])
+	assert.Equal(t, tcpProxy.GetCluster(), "outbound|443||internal.example.com")
+	if tcpProxy.GetTunnelingConfig() != nil {
+		t.Fatalf("unexpected tunneling config for an internal service: %v", tcpProxy.GetTunnelingConfig())
+	}
+
+	routes := xdstest.ExtractRouteConfigurations(cg.Routes(proxy))
+	cases := []struct {
+		route         string
+		host          string
+		cluster       string
+		prefixRewrite string
+		authorization string
+	}{
+		{
+			route:         "80",
+			host:          "api.example.com:80",
+			cluster:       "outbound|3128||proxy.corp.example.com",
+			prefixRewrite: "http://api.example.com/",
+			authorization: authorization,
+		},
+		{
+			route:         "8080",
+			host:          "api.example.com:8080",
+			cluster:       "outbound|3128||proxy.corp.example.com",
+			prefixRewrite: "http://api.example.com:8080/",
+			authorization: authorization,
+		},
+		{
+			route:   "80",
+			host:    "originated.example.com:80",
+			cluster: "outbound|80||originated.example.com",
+		},
+		{
+			route:   "80",
+			host:    "internal.example.com:80",
+			cluster: "outbound|80||internal.example.com",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.host, func(t *testing.T) {
+			var vhost *route.VirtualHost
+			for _, vh := range routes[tt.route].GetVirtualHosts() {
+				if vh.Name == tt.host {
+					vhost = vh
+				}
+			}
+			if vhost == nil {
+				t.Fatalf("virtual host %s not found in route %s", tt.host, tt.route)
+			}
+			r := vhost.GetRoutes()[0]
+			assert.Equal(t, r.GetRoute().GetCluster(), tt.cluster)
+			assert.Equal(t, r.GetRoute().GetPrefixRewrite(), tt.prefixRewrite)
+			got := ""
+			for _, h := range r.GetRequestHeadersToAdd() {
+				if h.GetHeader().GetKey() == "Proxy-Authorization" {
+					got = h.GetHeader().GetValue()
+				}
+			}
+			assert.Equal(t, got, tt.authorization)
+		})
+	}
+}
+
+func TestEgressProxyMissingCredential(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		ConfigString:          egressProxyConfig,
+		CredentialsController: fakeBasicAuthCredentials{},
+	})
+	proxy := cg.SetupProxy(nil)
+	tcpProxy := xdstest.ExtractTCPProxy(t, xdstest.ExtractListener("2.2.2.2_443", cg.Listeners(proxy)).GetFilterChains()[0])
+	assert.Equal(t, tcpProxy.GetCluster(), "outbound|3128||proxy.corp.example.com")
+	assert.Equal(t, len(tcpProxy.GetTunnelingConfig().GetHeadersToAdd()), 0)
+}
@@ -15,6 +15,9 @@
 package extension
 
 import (
+	"math"
+	"sort"
+
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	hcm_filter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
@@ -67,12 +70,23 @@ func AddWasmPluginsToMutableObjects(
 }
 
 func injectExtensions(filterChain []*hcm_filter.HttpFilter, exts map[extensions.PluginPhase][]*model.WasmPluginWrapper) []*hcm_filter.HttpFilter {
-	// copy map as we'll manipulate it in the loop
+	present := sets.NewSet()
+	for _, httpFilter := range filterChain {
+		present.Insert(httpFilter.Name)
+	}
+	// copy map as we'll manipulate it in the loop. Plugins anchored to a filter of the chain are
+	// set aside, the others are positioned according to their phase.
 	extMap := make(map[extensions.PluginPhase][]*model.WasmPluginWrapper)
+	anchored := anchoredExtensions{before: map[string][]*model.WasmPluginWrapper{}, after: map[string][]*model.WasmPluginWrapper{}}
 	for phase, list := range exts {
 		extMap[phase] = []*model.WasmPluginWrapper{}
-		extMap[phase] = append(extMap[phase], list...)
+		for _, ext := range list {
+			if !anchored.add(ext, present) {
+				extMap[phase] = append(extMap[phase], ext)
+			}
+		}
 	}
+	anchored.sort()
 	newHTTPFilters := make([]*hcm_filter.HttpFilter, 0)
 	// The following algorithm tries to make as few assumptions as possible about the filter
 	// chain - it might contain any number of filters that will have to retain their ordering.
@@ -85,27 +99,27 @@ func injectExtensions(filterChain []*hcm_filter.HttpFilter, exts map[extensions.
 	//
 	// 1. Istio JWT, 2. Istio AuthN, 3. RBAC, 4. Stats, 5. Metadata Exchange
 	//
-	// TODO: how to deal with ext-authz? RBAC will be in the chain twice in that case
+	// WasmPlugins anchored to a filter are injected right before or after it, whatever their phase.
+	// This is how plugins are positioned relative to ext-authz, which adds RBAC to the chain twice.
 	for _, httpFilter := range filterChain {
 		switch httpFilter.Name {
 		case securitymodel.EnvoyJwtFilterName:
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHN)
-			newHTTPFilters = append(newHTTPFilters, httpFilter)
 		case securitymodel.AuthnFilterName:
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHN)
-			newHTTPFilters = append(newHTTPFilters, httpFilter)
 		case wellknown.HTTPRoleBasedAccessControl:
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHN)
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHZ)
-			newHTTPFilters = append(newHTTPFilters, httpFilter)
 		case statsFilterName:
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHN)
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHZ)
 			newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_STATS)
-			newHTTPFilters = append(newHTTPFilters, httpFilter)
-		default:
-			newHTTPFilters = append(newHTTPFilters, httpFilter)
 		}
+		newHTTPFilters = appendAll(newHTTPFilters, anchored.before[httpFilter.Name])
+		delete(anchored.before, httpFilter.Name)
+		newHTTPFilters = append(newHTTPFilters, httpFilter)
+		newHTTPFilters = appendAll(newHTTPFilters, anchored.after[httpFilter.Name])
+		delete(anchored.after, httpFilter.Name)
 	}
 	// append all remaining extensions at the end. This is required because not all builtin filters
 	// are always present (e.g. RBAC is only present when an AuthorizationPolicy was created), so
@@ -114,15 +128,69 @@ func injectExtensions(filterChain []*hcm_filter.HttpFilter, exts map[extensions.
 	newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_AUTHZ)
 	newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_STATS)
 	newHTTPFilters = popAppend(newHTTPFilters, extMap, extensions.PluginPhase_UNSPECIFIED_PHASE)
+	// the router is added after these filters, so the router upstream plugins come last.
+	newHTTPFilters = appendAll(newHTTPFilters, anchored.routerUpstream)
 	return newHTTPFilters
 }
 
+// anchoredExtensions are the WasmPlugins anchored to the filters of a filter chain.
+type anchoredExtensions struct {
+	before         map[string][]*model.WasmPluginWrapper
+	after          map[string][]*model.WasmPluginWrapper
+	routerUpstream []*model.WasmPluginWrapper
+}
+
+// add adds a plugin if it is anchored to the router or to one of the present filters.
+func (a *anchoredExtensions) add(ext *model.WasmPluginWrapper, present sets.Set) bool {
+	switch {
+	case ext.Anchor == nil:
+		return false
+	case ext.Anchor.Filter == "":
+		a.routerUpstream = append(a.routerUpstream, ext)
+	case !present.Contains(ext.Anchor.Filter):
+		return false
+	case ext.Anchor.Before:
+		a.before[ext.Anchor.Filter] = append(a.before[ext.Anchor.Filter], ext)
+	default:
+		a.after[ext.Anchor.Filter] = append(a.after[ext.Anchor.Filter], ext)
+	}
+	return true
+}
+
+// sort orders the plugins at each anchor by priority, as they may come from different phases.
+func (a *anchoredExtensions) sort() {
+	for _, list := range a.before {
+		sortByPriority(list)
+	}
+	for _, list := range a.after {
+		sortByPriority(list)
+	}
+	sortByPriority(a.routerUpstream)
+}
+
+func sortByPriority(list []*model.WasmPluginWrapper) {
+	priority := func(ext *model.WasmPluginWrapper) int64 {
+		if ext.Priority == nil {
+			return math.MinInt64
+		}
+		return ext.Priority.Value
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return priority(list[i]) > priority(list[j])
+	})
+}
+
+func appendAll(list []*hcm_filter.HttpFilter, exts []*model.WasmPluginWrapper) []*hcm_filter.HttpFilter {
+	for _, ext := range exts {
+		list = append(list, toEnvoyHTTPFilter(ext))
+	}
+	return list
+}
+
 func popAppend(list []*hcm_filter.HttpFilter,
 	filterMap map[extensions.PluginPhase][]*model.WasmPluginWrapper,
 	phase extensions.PluginPhase) []*hcm_filter.HttpFilter {
-	for _, ext := range filterMap[phase] {
-		list = append(list, toEnvoyHTTPFilter(ext))
-	}
+	list = appendAll(list, filterMap[phase])
 	filterMap[phase] = []*model.WasmPluginWrapper{}
 	return list
 }
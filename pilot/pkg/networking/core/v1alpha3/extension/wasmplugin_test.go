@@ -28,6 +28,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	securitymodel "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/config/xds"
 )
 
 var (
@@ -43,6 +44,9 @@ var (
 	istioStats = &http_conn.HttpFilter{
 		Name: "istio.stats",
 	}
+	extAuthZ = &http_conn.HttpFilter{
+		Name: wellknown.HTTPExternalAuthorization,
+	}
 	unknown = &http_conn.HttpFilter{
 		Name: "unknown.filter",
 	}
@@ -76,6 +80,44 @@ var (
 			Name: "istio-system.someAuthZFilter",
 		},
 	}
+	beforeExtAuthZFilter = &model.WasmPluginWrapper{
+		Name:      "beforeExtAuthZFilter",
+		Namespace: "istio-system",
+		Anchor:    &xds.WasmPluginAnchor{Filter: wellknown.HTTPExternalAuthorization, Before: true},
+		ExtensionConfiguration: &envoy_config_core_v3.TypedExtensionConfig{
+			Name: "istio-system.beforeExtAuthZFilter",
+		},
+	}
+	afterJWTFilter = &model.WasmPluginWrapper{
+		Name:      "afterJWTFilter",
+		Namespace: "istio-system",
+		WasmPlugin: extensions.WasmPlugin{
+			Priority: &types.Int64Value{Value: 1},
+		},
+		Anchor: &xds.WasmPluginAnchor{Filter: securitymodel.EnvoyJwtFilterName},
+		ExtensionConfiguration: &envoy_config_core_v3.TypedExtensionConfig{
+			Name: "istio-system.afterJWTFilter",
+		},
+	}
+	importantAfterJWTFilter = &model.WasmPluginWrapper{
+		Name:      "importantAfterJWTFilter",
+		Namespace: "istio-system",
+		WasmPlugin: extensions.WasmPlugin{
+			Priority: &types.Int64Value{Value: 100},
+		},
+		Anchor: &xds.WasmPluginAnchor{Filter: securitymodel.EnvoyJwtFilterName},
+		ExtensionConfiguration: &envoy_config_core_v3.TypedExtensionConfig{
+			Name: "istio-system.importantAfterJWTFilter",
+		},
+	}
+	routerUpstreamFilter = &model.WasmPluginWrapper{
+		Name:      "routerUpstreamFilter",
+		Namespace: "istio-system",
+		Anchor:    &xds.WasmPluginAnchor{},
+		ExtensionConfiguration: &envoy_config_core_v3.TypedExtensionConfig{
+			Name: "istio-system.routerUpstreamFilter",
+		},
+	}
 )
 
 func TestAddWasmPluginsToMutableObjects(t *testing.T) {
@@ -178,6 +220,80 @@ func TestAddWasmPluginsToMutableObjects(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "anchors",
+			filterChains: []networking.FilterChain{
+				{
+					ListenerProtocol: networking.ListenerProtocolHTTP,
+					HTTP: []*http_conn.HttpFilter{
+						istioJWT,
+						istioAuthN,
+						istioAuthZ,
+						extAuthZ,
+						istioStats,
+					},
+				},
+			},
+			extensions: map[extensions.PluginPhase][]*model.WasmPluginWrapper{
+				extensions.PluginPhase_AUTHN: {
+					someAuthNFilter,
+					afterJWTFilter,
+				},
+				extensions.PluginPhase_AUTHZ: {
+					importantAfterJWTFilter,
+					beforeExtAuthZFilter,
+				},
+				extensions.PluginPhase_UNSPECIFIED_PHASE: {
+					routerUpstreamFilter,
+				},
+			},
+			expectedResult: []networking.FilterChain{
+				{
+					ListenerProtocol: networking.ListenerProtocolHTTP,
+					HTTP: []*http_conn.HttpFilter{
+						toEnvoyHTTPFilter(someAuthNFilter),
+						istioJWT,
+						toEnvoyHTTPFilter(importantAfterJWTFilter),
+						toEnvoyHTTPFilter(afterJWTFilter),
+						istioAuthN,
+						istioAuthZ,
+						toEnvoyHTTPFilter(beforeExtAuthZFilter),
+						extAuthZ,
+						istioStats,
+						toEnvoyHTTPFilter(routerUpstreamFilter),
+					},
+				},
+			},
+		},
+		{
+			name: "missing anchor filter",
+			filterChains: []networking.FilterChain{
+				{
+					ListenerProtocol: networking.ListenerProtocolHTTP,
+					HTTP: []*http_conn.HttpFilter{
+						istioAuthN,
+						istioAuthZ,
+						istioStats,
+					},
+				},
+			},
+			extensions: map[extensions.PluginPhase][]*model.WasmPluginWrapper{
+				extensions.PluginPhase_AUTHZ: {
+					beforeExtAuthZFilter,
+				},
+			},
+			expectedResult: []networking.FilterChain{
+				{
+					ListenerProtocol: networking.ListenerProtocolHTTP,
+					HTTP: []*http_conn.HttpFilter{
+						istioAuthN,
+						toEnvoyHTTPFilter(beforeExtAuthZFilter),
+						istioAuthZ,
+						istioStats,
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
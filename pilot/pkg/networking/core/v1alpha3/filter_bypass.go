@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"regexp"
+	"strings"
+
+	xdscore "github.com/cncf/xds/go/xds/core/v3"
+	xdsmatcher "github.com/cncf/xds/go/xds/type/matcher/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matching "github.com/envoyproxy/go-control-plane/envoy/extensions/common/matching/v3"
+	skipaction "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/matcher/action/v3"
+	extauthz "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	jwt "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/jwt_authn/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/any"
+
+	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
+	"istio.io/istio/pilot/pkg/networking/util"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+)
+
+// filterBypassRouteName is the name of the inbound routes of the paths skipping the authentication and authorization
+// filters.
+const filterBypassRouteName = "filter-bypass"
+
+// filterBypassConfig disables the JWT authentication and the authorization filters on a route. An RBAC per route
+// config without policy disables all the RBAC filters, that is those of the ALLOW, DENY and AUDIT policies.
+var filterBypassConfig = map[string]*any.Any{
+	authn_model.EnvoyJwtFilterName: util.MessageToAny(&jwt.PerRouteConfig{
+		RequirementSpecifier: &jwt.PerRouteConfig_Disabled{Disabled: true},
+	}),
+	wellknown.HTTPRoleBasedAccessControl: util.MessageToAny(&rbachttp.RBACPerRoute{}),
+	wellknown.HTTPExternalAuthorization: util.MessageToAny(&extauthz.ExtAuthzPerRoute{
+		Override: &extauthz.ExtAuthzPerRoute_Disabled{Disabled: true},
+	}),
+}
+
+// filterBypassRoutes returns the inbound routes of the given paths to the cluster, which must come before the default
+// route. A path ending with /* matches the paths under it, the prefix keeping its trailing / so that it only matches
+// whole path segments.
+func filterBypassRoutes(paths []string, clusterName string, operation string) []*route.Route {
+	routes := make([]*route.Route, 0, len(paths))
+	for _, path := range paths {
+		r := istio_route.BuildDefaultHTTPInboundRoute(clusterName, operation)
+		r.Name = filterBypassRouteName
+		if prefix := strings.TrimSuffix(path, "*"); strings.HasSuffix(path, "/*") {
+			r.Match.PathSpecifier = &route.RouteMatch_Prefix{Prefix: prefix}
+		} else {
+			r.Match.PathSpecifier = &route.RouteMatch_Path{Path: path}
+		}
+		r.TypedPerFilterConfig = filterBypassConfig
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// skipStatsOnFilterBypassPaths wraps the stats filter of the inbound HTTP filters in a matcher skipping it on the
+// requests of the given paths, so that their metrics and custom dimensions are not computed. The stats filter has no
+// per route config to disable it on the filter bypass routes, hence the matcher on the :path header. The filters are
+// shared between the proxies, so they are copied rather than modified.
+func skipStatsOnFilterBypassPaths(filters []*hcm.HttpFilter, paths []string) []*hcm.HttpFilter {
+	if len(paths) == 0 {
+		return filters
+	}
+	out := make([]*hcm.HttpFilter, 0, len(filters))
+	for _, filter := range filters {
+		if filter.Name == xdsfilters.StatsFilterName {
+			filter = &hcm.HttpFilter{
+				Name: filter.Name,
+				ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(&matching.ExtensionWithMatcher{
+					XdsMatcher: filterBypassPathMatcher(paths),
+					ExtensionConfig: &core.TypedExtensionConfig{
+						Name:        filter.Name,
+						TypedConfig: filter.GetTypedConfig(),
+					},
+				})},
+			}
+		}
+		out = append(out, filter)
+	}
+	return out
+}
+
+// filterBypassPathMatcher returns the matcher skipping the wrapped filter on the requests of the given paths. As the
+// :path header includes the query string, a path without /* matches it followed by an optional query string.
+func filterBypassPathMatcher(paths []string) *xdsmatcher.Matcher {
+	input := &xdscore.TypedExtensionConfig{
+		Name:        "request-headers",
+		TypedConfig: util.MessageToAny(&matcher.HttpRequestHeaderMatchInput{HeaderName: ":path"}),
+	}
+	skip := &xdsmatcher.Matcher_OnMatch{
+		OnMatch: &xdsmatcher.Matcher_OnMatch_Action{Action: &xdscore.TypedExtensionConfig{
+			Name:        "skip",
+			TypedConfig: util.MessageToAny(&skipaction.SkipFilter{}),
+		}},
+	}
+	matchers := make([]*xdsmatcher.Matcher_MatcherList_FieldMatcher, 0, len(paths))
+	for _, path := range paths {
+		value := &xdsmatcher.StringMatcher{}
+		if strings.HasSuffix(path, "/*") {
+			value.MatchPattern = &xdsmatcher.StringMatcher_Prefix{Prefix: strings.TrimSuffix(path, "*")}
+		} else {
+			value.MatchPattern = &xdsmatcher.StringMatcher_SafeRegex{SafeRegex: &xdsmatcher.RegexMatcher{
+				EngineType: &xdsmatcher.RegexMatcher_GoogleRe2{GoogleRe2: &xdsmatcher.RegexMatcher_GoogleRE2{}},
+				Regex:      "^" + regexp.QuoteMeta(path) + `(\?.*)?$`,
+			}}
+		}
+		matchers = append(matchers, &xdsmatcher.Matcher_MatcherList_FieldMatcher{
+			Predicate: &xdsmatcher.Matcher_MatcherList_Predicate{
+				MatchType: &xdsmatcher.Matcher_MatcherList_Predicate_SinglePredicate_{
+					SinglePredicate: &xdsmatcher.Matcher_MatcherList_Predicate_SinglePredicate{
+						Input:   input,
+						Matcher: &xdsmatcher.Matcher_MatcherList_Predicate_SinglePredicate_ValueMatch{ValueMatch: value},
+					},
+				},
+			},
+			OnMatch: skip,
+		})
+	}
+	return &xdsmatcher.Matcher{
+		MatcherType: &xdsmatcher.Matcher_MatcherList_{MatcherList: &xdsmatcher.Matcher_MatcherList{Matchers: matchers}},
+	}
+}
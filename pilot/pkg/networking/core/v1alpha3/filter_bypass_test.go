@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	matching "github.com/envoyproxy/go-control-plane/envoy/extensions/common/matching/v3"
+	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+)
+
+func TestFilterBypassRoutes(t *testing.T) {
+	routes := filterBypassRoutes([]string{"/healthz", "/stats/*"}, "inbound|8080||", "op")
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+	if got := routes[0].GetMatch().GetPath(); got != "/healthz" {
+		t.Errorf("got path %q, want /healthz", got)
+	}
+	if got := routes[1].GetMatch().GetPrefix(); got != "/stats/" {
+		t.Errorf("got prefix %q, want /stats/", got)
+	}
+	for _, r := range routes {
+		if got := r.GetRoute().GetCluster(); got != "inbound|8080||" {
+			t.Errorf("got cluster %q", got)
+		}
+		for _, filter := range []string{authn_model.EnvoyJwtFilterName, wellknown.HTTPRoleBasedAccessControl, wellknown.HTTPExternalAuthorization} {
+			if _, f := r.TypedPerFilterConfig[filter]; !f {
+				t.Errorf("expected %s to be disabled on route %v", filter, r.GetMatch())
+			}
+		}
+	}
+	if routes := filterBypassRoutes(nil, "inbound|8080||", "op"); len(routes) != 0 {
+		t.Fatalf("got %d routes without paths, want 0", len(routes))
+	}
+}
+
+func TestSkipStatsOnFilterBypassPaths(t *testing.T) {
+	stats := &hcm.HttpFilter{
+		Name:       xdsfilters.StatsFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(&httpwasm.Wasm{})},
+	}
+	filters := []*hcm.HttpFilter{xdsfilters.Cors, stats}
+
+	if got := skipStatsOnFilterBypassPaths(filters, nil); got[1] != stats {
+		t.Fatalf("expected the stats filter to be kept without paths, got %v", got[1])
+	}
+
+	got := skipStatsOnFilterBypassPaths(filters, []string{"/healthz", "/stats/*"})
+	if filters[1] != stats {
+		t.Fatalf("the shared filters were modified")
+	}
+	if got[0] != xdsfilters.Cors {
+		t.Errorf("expected the other filters to be kept, got %v", got[0])
+	}
+	if got[1].Name != xdsfilters.StatsFilterName {
+		t.Fatalf("got filter %q, want %q", got[1].Name, xdsfilters.StatsFilterName)
+	}
+	wrapped := &matching.ExtensionWithMatcher{}
+	if err := got[1].GetTypedConfig().UnmarshalTo(wrapped); err != nil {
+		t.Fatalf("the stats filter is not wrapped in a matcher: %v", err)
+	}
+	if err := wrapped.Validate(); err != nil {
+		t.Fatalf("invalid matcher: %v", err)
+	}
+	if wrapped.GetExtensionConfig().GetName() != xdsfilters.StatsFilterName {
+		t.Errorf("got wrapped filter %q, want %q", wrapped.GetExtensionConfig().GetName(), xdsfilters.StatsFilterName)
+	}
+	matchers := wrapped.GetXdsMatcher().GetMatcherList().GetMatchers()
+	if len(matchers) != 2 {
+		t.Fatalf("got %d matchers, want 2", len(matchers))
+	}
+	if got := matchers[0].GetPredicate().GetSinglePredicate().GetValueMatch().GetSafeRegex().GetRegex(); got != `^/healthz(\?.*)?$` {
+		t.Errorf("got regex %q for /healthz", got)
+	}
+	if got := matchers[1].GetPredicate().GetSinglePredicate().GetValueMatch().GetPrefix(); got != "/stats/" {
+		t.Errorf("got prefix %q for /stats/*, want /stats/", got)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"math"
+	"strconv"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	customheader "github.com/envoyproxy/go-control-plane/envoy/extensions/http/original_ip_detection/custom_header/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/pkg/log"
+)
+
+// Annotations of a workload configuring how the HTTP connection managers of its gateway listeners, or of its inbound
+// listeners for sidecars, detect the address of the client behind intermediate proxies. The detected address is used
+// in the access logs and by the ipBlocks of the authorization policies.
+const (
+	// XFFNumTrustedHopsAnnotation sets the number of proxies in front of the workload whose X-Forwarded-For entries
+	// are trusted. For gateways, it takes precedence over the numTrustedProxies of the gateway topology.
+	XFFNumTrustedHopsAnnotation = "proxy.istio.io/xffNumTrustedHops"
+	// OriginalIPHeaderAnnotation sets the name of a header set by the proxies in front of the workload to the address
+	// of the client, such as x-real-ip. It takes precedence over the X-Forwarded-For header and the number of trusted
+	// hops, and requests without the header keep the address of the downstream connection.
+	OriginalIPHeaderAnnotation = "proxy.istio.io/originalIPHeader"
+	// SkipXFFAppendAnnotation, when "true", does not append the address of the downstream connection to the
+	// X-Forwarded-For header of the requests.
+	SkipXFFAppendAnnotation = "proxy.istio.io/skipXffAppend"
+)
+
+// customHeaderOriginalIPDetection is the name of the original IP detection extension reading a custom header.
+const customHeaderOriginalIPDetection = "envoy.http.original_ip_detection.custom_header"
+
+// applyForwardedHeaders sets how the connection manager of the HTTP options detects the client address from the
+// annotations of the workload.
+func applyForwardedHeaders(node *model.Proxy, httpOpts *httpListenerOpts) {
+	annotations := node.Metadata.Annotations
+	connectionManager := httpOpts.connectionManager
+	if v, f := annotations[XFFNumTrustedHopsAnnotation]; f {
+		if n, err := parseConnectionTuningValue(v, 0, math.MaxUint32); err == nil {
+			connectionManager.XffNumTrustedHops = n
+		} else {
+			log.Warnf("ignoring invalid %s annotation of proxy %s: %v", XFFNumTrustedHopsAnnotation, node.ID, err)
+		}
+	}
+	if v, f := annotations[SkipXFFAppendAnnotation]; f {
+		if skip, err := strconv.ParseBool(v); err == nil {
+			connectionManager.SkipXffAppend = skip
+		} else {
+			log.Warnf("ignoring invalid %s annotation %q of proxy %s", SkipXFFAppendAnnotation, v, node.ID)
+		}
+	}
+	if header := annotations[OriginalIPHeaderAnnotation]; header != "" {
+		// Original IP detection extensions cannot be used along with use_remote_address and xff_num_trusted_hops.
+		httpOpts.useRemoteAddress = false
+		connectionManager.XffNumTrustedHops = 0
+		connectionManager.OriginalIpDetectionExtensions = []*core.TypedExtensionConfig{{
+			Name: customHeaderOriginalIPDetection,
+			TypedConfig: util.MessageToAny(&customheader.CustomHeaderConfig{
+				HeaderName:                          header,
+				AllowExtensionToSetAddressAsTrusted: true,
+			}),
+		}}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	customheader "github.com/envoyproxy/go-control-plane/envoy/extensions/http/original_ip_detection/custom_header/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+func TestApplyForwardedHeaders(t *testing.T) {
+	cases := []struct {
+		name                 string
+		annotations          map[string]string
+		want                 *hcm.HttpConnectionManager
+		wantUseRemoteAddress bool
+	}{
+		{
+			name:                 "default",
+			want:                 &hcm.HttpConnectionManager{XffNumTrustedHops: 1},
+			wantUseRemoteAddress: true,
+		},
+		{
+			name: "trusted hops and skip append",
+			annotations: map[string]string{
+				XFFNumTrustedHopsAnnotation: "2",
+				SkipXFFAppendAnnotation:     "true",
+			},
+			want:                 &hcm.HttpConnectionManager{XffNumTrustedHops: 2, SkipXffAppend: true},
+			wantUseRemoteAddress: true,
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				XFFNumTrustedHopsAnnotation: "-1",
+				SkipXFFAppendAnnotation:     "maybe",
+			},
+			want:                 &hcm.HttpConnectionManager{XffNumTrustedHops: 1},
+			wantUseRemoteAddress: true,
+		},
+		{
+			name: "original IP header",
+			annotations: map[string]string{
+				XFFNumTrustedHopsAnnotation: "2",
+				OriginalIPHeaderAnnotation:  "x-real-ip",
+			},
+			want: &hcm.HttpConnectionManager{
+				OriginalIpDetectionExtensions: []*core.TypedExtensionConfig{{
+					Name: customHeaderOriginalIPDetection,
+					TypedConfig: util.MessageToAny(&customheader.CustomHeaderConfig{
+						HeaderName:                          "x-real-ip",
+						AllowExtensionToSetAddressAsTrusted: true,
+					}),
+				}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			httpOpts := &httpListenerOpts{
+				useRemoteAddress:  true,
+				connectionManager: &hcm.HttpConnectionManager{XffNumTrustedHops: 1},
+			}
+			applyForwardedHeaders(&model.Proxy{Metadata: &model.NodeMetadata{Annotations: tt.annotations}}, httpOpts)
+			if diff := cmp.Diff(tt.want, httpOpts.connectionManager, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+			if httpOpts.useRemoteAddress != tt.wantUseRemoteAddress {
+				t.Fatalf("got useRemoteAddress %v, want %v", httpOpts.useRemoteAddress, tt.wantUseRemoteAddress)
+			}
+		})
+	}
+}
@@ -39,6 +39,7 @@ import (
 	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/networking/util"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/gateway"
@@ -199,10 +200,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayTCPBasedFilterChains(
 		// We only need to look at the first server in the list as the merge logic
 		// ensures that all servers are of same type.
 		port := &networking.Port{Number: port.Number, Protocol: port.Protocol}
-		opts.filterChainOpts = []*filterChainOpts{
-			configgen.createGatewayHTTPFilterChainOpts(builder.node, port, nil, serversForPort.RouteName,
-				proxyConfig, istionetworking.ListenerProtocolTCP),
-		}
+		httpChainOpts := configgen.createGatewayHTTPFilterChainOpts(builder.node, port, nil, serversForPort.RouteName,
+			proxyConfig, istionetworking.ListenerProtocolTCP, mergedGateway.DownstreamProtocols[serversForPort.Servers[0]])
+		httpChainOpts.httpOpts.healthCheckFilters = buildGRPCHealthCheckFilters(builder.node, builder.push,
+			serverGatewayNames(mergedGateway, serversForPort.Servers))
+		opts.filterChainOpts = []*filterChainOpts{httpChainOpts}
 		newFilterChains = append(newFilterChains, istionetworking.FilterChain{
 			ListenerProtocol: istionetworking.ListenerProtocolHTTP,
 		})
@@ -217,8 +219,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayTCPBasedFilterChains(
 			if gateway.IsTLSServer(server) && gateway.IsHTTPServer(server) {
 				routeName := mergedGateway.TLSServerInfo[server].RouteName
 				// This is a HTTPS server, where we are doing TLS termination. Build a http connection manager with TLS context
-				tcpFilterChainOpts = append(tcpFilterChainOpts, configgen.createGatewayHTTPFilterChainOpts(builder.node, server.Port, server,
-					routeName, proxyConfig, istionetworking.TransportProtocolTCP))
+				httpChainOpts := configgen.createGatewayHTTPFilterChainOpts(builder.node, server.Port, server,
+					routeName, proxyConfig, istionetworking.TransportProtocolTCP, mergedGateway.DownstreamProtocols[server])
+				httpChainOpts.httpOpts.healthCheckFilters = buildGRPCHealthCheckFilters(builder.node, builder.push,
+					serverGatewayNames(mergedGateway, []*networking.Server{server}))
+				tcpFilterChainOpts = append(tcpFilterChainOpts, httpChainOpts)
 				newFilterChains = append(newFilterChains, istionetworking.FilterChain{
 					ListenerProtocol:   istionetworking.ListenerProtocolHTTP,
 					IstioMutualGateway: server.Tls.Mode == networking.ServerTLSSettings_ISTIO_MUTUAL,
@@ -257,8 +262,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTP3FilterChains(
 		// Here it is assumed that this HTTP/3 server is a mirror of an existing HTTPS
 		// server. So the same route name would be reused instead of creating new one.
 		routeName := mergedGateway.TLSServerInfo[server].RouteName
-		quicFilterChainOpts = append(quicFilterChainOpts, configgen.createGatewayHTTPFilterChainOpts(builder.node, server.Port, server,
-			routeName, proxyConfig, istionetworking.TransportProtocolQUIC))
+		httpChainOpts := configgen.createGatewayHTTPFilterChainOpts(builder.node, server.Port, server,
+			routeName, proxyConfig, istionetworking.TransportProtocolQUIC, nil)
+		httpChainOpts.httpOpts.healthCheckFilters = buildGRPCHealthCheckFilters(builder.node, builder.push,
+			serverGatewayNames(mergedGateway, []*networking.Server{server}))
+		quicFilterChainOpts = append(quicFilterChainOpts, httpChainOpts)
 		newFilterChains = append(newFilterChains, istionetworking.FilterChain{
 			// Make sure that this is set to HTTP so that JWT and Authorization
 			// filters that are applied to HTTPS are also applied to this chain.
@@ -276,7 +284,7 @@ func getListenerName(bind string, port int, transport istionetworking.TransportP
 	switch transport {
 	case istionetworking.TransportProtocolTCP:
 		return bind + "_" + strconv.Itoa(port)
-	case istionetworking.TransportProtocolQUIC:
+	case istionetworking.TransportProtocolQUIC, istionetworking.TransportProtocolUDP:
 		return "udp_" + bind + "_" + strconv.Itoa(port)
 	}
 	return "unknown"
@@ -412,10 +420,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(node *model.Pr
 					}
 				} else {
 					newVHost := &route.VirtualHost{
-						Name:                       util.DomainName(string(hostname), port),
-						Domains:                    buildGatewayVirtualHostDomains(string(hostname), port),
-						Routes:                     routes,
-						IncludeRequestAttemptCount: true,
+						Name:                          util.DomainName(string(hostname), port),
+						Domains:                       buildGatewayVirtualHostDomains(string(hostname), port),
+						Routes:                        routes,
+						IncludeRequestAttemptCount:    true,
+						IncludeAttemptCountInResponse: features.IncludeAttemptCountInResponse,
 					}
 					if server.Tls != nil && server.Tls.HttpsRedirect {
 						newVHost.RequireTls = route.VirtualHost_ALL
@@ -436,10 +445,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(node *model.Pr
 				continue
 			}
 			newVHost := &route.VirtualHost{
-				Name:                       util.DomainName(hostname, port),
-				Domains:                    buildGatewayVirtualHostDomains(hostname, port),
-				IncludeRequestAttemptCount: true,
-				RequireTls:                 route.VirtualHost_ALL,
+				Name:                          util.DomainName(hostname, port),
+				Domains:                       buildGatewayVirtualHostDomains(hostname, port),
+				IncludeRequestAttemptCount:    true,
+				IncludeAttemptCountInResponse: features.IncludeAttemptCountInResponse,
+				RequireTls:                    route.VirtualHost_ALL,
 			}
 			vHostDedupMap[host.Name(hostname)] = newVHost
 		}
@@ -472,6 +482,8 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(node *model.Pr
 		VirtualHosts:     virtualHosts,
 		ValidateClusters: proto.BoolFalse,
 	}
+	applyJwtClaimHeaders(node, push, routeCfg)
+	applySecurityHeaders(node, push, routeCfg)
 
 	return routeCfg
 }
@@ -559,24 +571,29 @@ func routesEqual(a, b []*route.Route) bool {
 	return true
 }
 
-// builds a HTTP connection manager for servers of type HTTP or HTTPS (mode: simple/mutual)
+// builds a HTTP connection manager for servers of type HTTP or HTTPS (mode: simple/mutual), accepting the HTTP versions
+// of the downstream protocols, if set
 func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *model.Proxy, port *networking.Port, server *networking.Server,
-	routeName string, proxyConfig *meshconfig.ProxyConfig, transportProtocol istionetworking.TransportProtocol) *filterChainOpts {
+	routeName string, proxyConfig *meshconfig.ProxyConfig, transportProtocol istionetworking.TransportProtocol,
+	protocols *gateway.DownstreamProtocols) *filterChainOpts {
 	serverProto := protocol.Parse(port.Protocol)
 
 	if serverProto.IsHTTP() {
+		httpOpts := &httpListenerOpts{
+			rds:               routeName,
+			useRemoteAddress:  true,
+			connectionManager: buildGatewayConnectionManager(proxyConfig, node, false /* http3SupportEnabled */),
+			addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
+		}
+		applyForwardedHeaders(node, httpOpts)
+		applyDownstreamProtocols(httpOpts.connectionManager, nil, protocols)
 		return &filterChainOpts{
 			// This works because we validate that only HTTPS servers can have same port but still different port names
 			// and that no two non-HTTPS servers can be on same port or share port names.
 			// Validation is done per gateway and also during merging
 			sniHosts:   nil,
 			tlsContext: nil,
-			httpOpts: &httpListenerOpts{
-				rds:               routeName,
-				useRemoteAddress:  true,
-				connectionManager: buildGatewayConnectionManager(proxyConfig, node, false /* http3SupportEnabled */),
-				addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
-			},
+			httpOpts:   httpOpts,
 		}
 	}
 
@@ -584,20 +601,47 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 	// We know that this is a HTTPS server because this function is called only for ports of type HTTP/HTTPS
 	// where HTTPS server's TLS mode is not passthrough and not nil
 	http3Enabled := transportProtocol == istionetworking.TransportProtocolQUIC
+	httpOpts := &httpListenerOpts{
+		rds:               routeName,
+		useRemoteAddress:  true,
+		connectionManager: buildGatewayConnectionManager(proxyConfig, node, http3Enabled),
+		addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
+		statPrefix:        server.Name,
+		http3Only:         http3Enabled,
+	}
+	applyForwardedHeaders(node, httpOpts)
+	tlsContext := buildGatewayListenerTLSContext(server, node, transportProtocol, configgen)
+	applyDownstreamProtocols(httpOpts.connectionManager, tlsContext, protocols)
 	return &filterChainOpts{
 		// This works because we validate that only HTTPS servers can have same port but still different port names
 		// and that no two non-HTTPS servers can be on same port or share port names.
 		// Validation is done per gateway and also during merging
 		sniHosts:   node.MergedGateway.TLSServerInfo[server].SNIHosts,
-		tlsContext: buildGatewayListenerTLSContext(server, node, transportProtocol, configgen),
-		httpOpts: &httpListenerOpts{
-			rds:               routeName,
-			useRemoteAddress:  true,
-			connectionManager: buildGatewayConnectionManager(proxyConfig, node, http3Enabled),
-			addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
-			statPrefix:        server.Name,
-			http3Only:         http3Enabled,
-		},
+		tlsContext: tlsContext,
+		httpOpts:   httpOpts,
+	}
+}
+
+// applyDownstreamProtocols restricts the HTTP versions accepted by the connection manager, and offered by the TLS
+// context if any, to those of the downstream protocols of the gateway.DownstreamProtocolsAnnotation.
+func applyDownstreamProtocols(connectionManager *hcm.HttpConnectionManager, tlsContext *tls.DownstreamTlsContext,
+	protocols *gateway.DownstreamProtocols) {
+	if protocols == nil {
+		return
+	}
+	var alpn []string
+	switch protocols.HTTP2 {
+	case gateway.HTTP2Disabled:
+		connectionManager.CodecType = hcm.HttpConnectionManager_HTTP1
+		alpn = []string{"http/1.1"}
+	case gateway.HTTP2Only:
+		connectionManager.CodecType = hcm.HttpConnectionManager_HTTP2
+		alpn = util.ALPNH2Only
+	default:
+		return
+	}
+	if tlsContext != nil && tlsContext.CommonTlsContext != nil {
+		tlsContext.CommonTlsContext.AlpnProtocols = alpn
 	}
 }
 
@@ -663,7 +707,13 @@ func buildGatewayListenerTLSContext(
 	}
 
 	server.Tls.CipherSuites = filteredGatewayCipherSuites(server)
-	return configgen.BuildListenerTLSContext(server.Tls, proxy, transportProtocol)
+	ctx := configgen.BuildListenerTLSContext(server.Tls, proxy, transportProtocol)
+	if proxy.MergedGateway != nil {
+		if trust := proxy.MergedGateway.ClientTrust[server]; trust != nil {
+			authn_model.ApplyClientTrustToServerCommonTLSContext(ctx.CommonTlsContext, trust.TrustDomains, trust.CACredentialName)
+		}
+	}
+	return ctx
 }
 
 func convertTLSProtocol(in networking.ServerTLSSettings_TLSProtocol) tls.TlsParameters_TlsProtocol {
@@ -805,6 +855,14 @@ func buildGatewayNetworkFiltersFromTLSRoutes(node *model.Proxy, push *model.Push
 				continue
 			}
 
+			// The TLS routes of the virtual service may additionally be restricted to a set of ALPN values.
+			alpn := gateway.ParseTLSRouteALPN(v.Annotations[gateway.TLSRouteALPNAnnotation])
+			sniKeySuffix := ""
+			if len(alpn) > 0 {
+				sort.Strings(alpn)
+				sniKeySuffix = "/" + strings.Join(alpn, ",")
+			}
+
 			// For every matching TLS block, generate a filter chain with sni match
 			// TODO: Bug..if there is a single virtual service with *.foo.com, and multiple TLS block
 			// matches, one for 1.foo.com, another for 2.foo.com, this code will produce duplicate filter
@@ -813,9 +871,16 @@ func buildGatewayNetworkFiltersFromTLSRoutes(node *model.Proxy, push *model.Push
 				for i, match := range tls.Match {
 					if l4SingleMatch(convertTLSMatchToL4Match(match), server, gatewayName) {
 						// Envoy will reject config that has multiple filter chain matches with the same matching rules
-						// To avoid this, we need to make sure we don't have duplicated SNI hosts, which will become
-						// SNI filter chain matches
-						if duplicateSniHosts := model.CheckDuplicates(match.SniHosts, tlsSniHosts); len(duplicateSniHosts) != 0 {
+						// To avoid this, we need to make sure we don't have duplicated SNI hosts (and ALPN, if set),
+						// which will become SNI filter chain matches
+						sniKeys := match.SniHosts
+						if sniKeySuffix != "" {
+							sniKeys = make([]string, 0, len(match.SniHosts))
+							for _, h := range match.SniHosts {
+								sniKeys = append(sniKeys, h+sniKeySuffix)
+							}
+						}
+						if duplicateSniHosts := model.CheckDuplicates(sniKeys, tlsSniHosts); len(duplicateSniHosts) != 0 {
 							log.Debugf(
 								"skipping VirtualService %s rule #%v on server port %d of gateway %s, duplicate SNI host names: %v",
 								v.Meta.Name, i, port.Port, gatewayName, duplicateSniHosts)
@@ -824,8 +889,13 @@ func buildGatewayNetworkFiltersFromTLSRoutes(node *model.Proxy, push *model.Push
 						}
 
 						// the sni hosts in the match will become part of a filter chain match
+						var fcMatch *listener.FilterChainMatch
+						if len(alpn) > 0 {
+							fcMatch = &listener.FilterChainMatch{ApplicationProtocols: alpn}
+						}
 						filterChains = append(filterChains, &filterChainOpts{
 							sniHosts:       match.SniHosts,
+							match:          fcMatch,
 							tlsContext:     nil, // NO TLS context because this is passthrough
 							networkFilters: buildOutboundNetworkFilters(node, tls.Route, push, port, v.Meta),
 						})
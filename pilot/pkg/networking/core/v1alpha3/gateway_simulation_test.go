@@ -665,6 +665,92 @@ spec:
 				},
 			},
 		},
+		simulationTest{
+			name: "tls virtual service with alpn",
+			config: `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: ingressgateway
+  namespace: istio-system
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - hosts:
+    - '*.example.com'
+    port:
+      name: https
+      number: 443
+      protocol: HTTPS
+    tls:
+      mode: PASSTHROUGH
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs-h2
+  namespace: default
+  annotations:
+    networking.istio.io/tls-alpn: h2
+spec:
+  gateways:
+  - istio-system/ingressgateway
+  hosts:
+  - mysite.example.com
+  tls:
+  - match:
+    - port: 443
+      sniHosts:
+      - mysite.example.com
+    route:
+    - destination:
+        host: mysite-h2.default.svc.cluster.local
+        port:
+          number: 443
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs-h1
+  namespace: default
+  annotations:
+    networking.istio.io/tls-alpn: http/1.1
+spec:
+  gateways:
+  - istio-system/ingressgateway
+  hosts:
+  - mysite.example.com
+  tls:
+  - match:
+    - port: 443
+      sniHosts:
+      - mysite.example.com
+    route:
+    - destination:
+        host: mysite-h1.default.svc.cluster.local
+        port:
+          number: 443
+`,
+			calls: []simulation.Expect{
+				{
+					"h2",
+					simulation.Call{Port: 443, Protocol: simulation.HTTP2, TLS: simulation.TLS, HostHeader: "mysite.example.com"},
+					simulation.Result{
+						ListenerMatched: "0.0.0.0_443",
+						ClusterMatched:  "outbound|443||mysite-h2.default.svc.cluster.local",
+					},
+				},
+				{
+					"http/1.1",
+					simulation.Call{Port: 443, Protocol: simulation.HTTP, TLS: simulation.TLS, HostHeader: "mysite.example.com"},
+					simulation.Result{
+						ListenerMatched: "0.0.0.0_443",
+						ClusterMatched:  "outbound|443||mysite-h1.default.svc.cluster.local",
+					},
+				},
+			},
+		},
 		simulationTest{
 			name: "duplicate tls virtual service",
 			// Create the same virtual service in two namespaces
@@ -1898,3 +1984,103 @@ spec:
 			},
 		})
 }
+
+func TestEgressGatewayAnnotation(t *testing.T) {
+	config := `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: egressgateway
+  namespace: istio-system
+spec:
+  hosts:
+  - istio-egressgateway.istio-system.svc.cluster.local
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+    targetPort: 8443
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: api
+  namespace: default
+  annotations:
+    networking.istio.io/egressGateway: istio-egressgateway.istio-system.svc.cluster.local
+spec:
+  hosts:
+  - api.example.com
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: direct
+  namespace: default
+spec:
+  hosts:
+  - direct.example.com
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: DNS
+`
+	sidecar := &model.Proxy{
+		Type:     model.SidecarProxy,
+		Metadata: &model.NodeMetadata{Namespace: "default"},
+	}
+	runSimulationTest(t, sidecar, xds.FakeOptions{}, simulationTest{
+		name:   "sidecar",
+		config: config,
+		calls: []simulation.Expect{
+			{
+				Name: "annotated host",
+				Call: simulation.Call{Port: 443, Protocol: simulation.TCP, TLS: simulation.TLS, Sni: "api.example.com"},
+				Result: simulation.Result{
+					ClusterMatched: "outbound|443||istio-egressgateway.istio-system.svc.cluster.local",
+				},
+			},
+			{
+				Name: "other host",
+				Call: simulation.Call{Port: 443, Protocol: simulation.TCP, TLS: simulation.TLS, Sni: "direct.example.com"},
+				Result: simulation.Result{
+					ClusterMatched: "outbound|443||direct.example.com",
+				},
+			},
+		},
+	})
+	gateway := &model.Proxy{
+		Type:            model.Router,
+		ConfigNamespace: "istio-system",
+		Metadata:        &model.NodeMetadata{Namespace: "istio-system", Labels: map[string]string{"istio": "egressgateway"}},
+	}
+	runSimulationTest(t, gateway, xds.FakeOptions{}, simulationTest{
+		name:   "gateway",
+		config: config,
+		calls: []simulation.Expect{
+			{
+				Name: "annotated host",
+				Call: simulation.Call{Port: 8443, Protocol: simulation.TCP, TLS: simulation.TLS, Sni: "api.example.com"},
+				Result: simulation.Result{
+					ListenerMatched: "0.0.0.0_8443",
+					ClusterMatched:  "outbound|443||api.example.com",
+				},
+			},
+			{
+				Name:   "other host",
+				Call:   simulation.Call{Port: 8443, Protocol: simulation.TCP, TLS: simulation.TLS, Sni: "direct.example.com"},
+				Result: simulation.Result{Error: simulation.ErrNoFilterChain},
+			},
+		},
+	})
+}
@@ -24,6 +24,8 @@ import (
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -39,6 +41,7 @@ import (
 	"istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
@@ -646,6 +649,72 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 	}
 }
 
+func TestBuildGatewayListenerTLSContextClientTrust(t *testing.T) {
+	server := &networking.Server{
+		Name:  "partner",
+		Hosts: []string{"*.partner.example.com"},
+		Port:  &networking.Port{Name: "tls", Number: 15443, Protocol: "HTTPS"},
+		Tls: &networking.ServerTLSSettings{
+			Mode:            networking.ServerTLSSettings_ISTIO_MUTUAL,
+			SubjectAltNames: []string{"spiffe://cluster.local/ns/istio-system/sa/peer"},
+		},
+	}
+	cases := []struct {
+		name      string
+		trust     *gateway.ClientTrust
+		sans      []*matcher.StringMatcher
+		rootCerts string
+	}{
+		{
+			name:      "mesh trust",
+			sans:      []*matcher.StringMatcher{util.StringToExactMatch(server.Tls.SubjectAltNames)[0]},
+			rootCerts: "ROOTCA",
+		},
+		{
+			name:  "partner trust domains",
+			trust: &gateway.ClientTrust{TrustDomains: []string{"partner.example.com", "*.partner.example.com"}},
+			sans: []*matcher.StringMatcher{
+				util.StringToExactMatch(server.Tls.SubjectAltNames)[0],
+				{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "spiffe://partner.example.com/"}},
+				{MatchPattern: &matcher.StringMatcher_Suffix{Suffix: ".partner.example.com"}},
+			},
+			rootCerts: "ROOTCA",
+		},
+		{
+			name:  "partner roots",
+			trust: &gateway.ClientTrust{TrustDomains: []string{"partner.example.com"}, CACredentialName: "partner-ca"},
+			sans: []*matcher.StringMatcher{
+				util.StringToExactMatch(server.Tls.SubjectAltNames)[0],
+				{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "spiffe://partner.example.com/"}},
+			},
+			rootCerts: "kubernetes://partner-ca-cacert",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &pilot_model.Proxy{
+				Metadata:      &pilot_model.NodeMetadata{},
+				MergedGateway: &pilot_model.MergedGateway{ClientTrust: map[*networking.Server]*gateway.ClientTrust{}},
+			}
+			if tt.trust != nil {
+				proxy.MergedGateway.ClientTrust[server] = tt.trust
+			}
+			cgi := NewConfigGenerator([]plugin.Plugin{}, &pilot_model.DisabledCache{})
+			ctx := buildGatewayListenerTLSContext(server, proxy, istionetworking.TransportProtocolTCP, cgi)
+			combined := ctx.CommonTlsContext.GetCombinedValidationContext()
+			if diff := cmp.Diff(tt.sans, combined.DefaultValidationContext.MatchSubjectAltNames, protocmp.Transform()); diff != "" {
+				t.Errorf("got diff in subject alt names: %v", diff)
+			}
+			if got := combined.ValidationContextSdsSecretConfig.Name; got != tt.rootCerts {
+				t.Errorf("got root certificates %q, want %q", got, tt.rootCerts)
+			}
+			if got := ctx.CommonTlsContext.TlsCertificateSdsSecretConfigs[0].Name; got != "default" {
+				t.Errorf("got certificate %q, want the workload certificate", got)
+			}
+		})
+	}
+}
+
 func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 	var stripPortMode *hcm.HttpConnectionManager_StripAnyHostPort
 	testCases := []struct {
@@ -1278,7 +1347,7 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 				tc.server: {SNIHosts: pilot_model.GetSNIHostsForServer(tc.server)},
 			}}
 			ret := cgi.createGatewayHTTPFilterChainOpts(tc.node, tc.server.Port, tc.server,
-				tc.routeName, tc.proxyConfig, tc.transportProtocol)
+				tc.routeName, tc.proxyConfig, tc.transportProtocol, nil)
 			if diff := cmp.Diff(tc.result.tlsContext, ret.tlsContext, protocmp.Transform()); diff != "" {
 				t.Errorf("got diff in tls context: %v", diff)
 			}
@@ -1292,6 +1361,67 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 	}
 }
 
+func TestCreateGatewayHTTPFilterChainOptsDownstreamProtocols(t *testing.T) {
+	httpsServer := &networking.Server{
+		Name:  "https",
+		Port:  &networking.Port{Protocol: "HTTPS"},
+		Hosts: []string{"example.org"},
+		Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE, CredentialName: "example"},
+	}
+	httpServer := &networking.Server{Name: "http", Port: &networking.Port{Protocol: "HTTP"}}
+	cases := []struct {
+		name      string
+		server    *networking.Server
+		protocols *gateway.DownstreamProtocols
+		codec     hcm.HttpConnectionManager_CodecType
+		alpn      []string
+	}{
+		{
+			name:   "https default",
+			server: httpsServer,
+			codec:  hcm.HttpConnectionManager_AUTO,
+			alpn:   util.ALPNHttp,
+		},
+		{
+			name:      "https http2 only",
+			server:    httpsServer,
+			protocols: &gateway.DownstreamProtocols{HTTP2: gateway.HTTP2Only},
+			codec:     hcm.HttpConnectionManager_HTTP2,
+			alpn:      []string{"h2"},
+		},
+		{
+			name:      "https http2 disabled",
+			server:    httpsServer,
+			protocols: &gateway.DownstreamProtocols{HTTP2: gateway.HTTP2Disabled},
+			codec:     hcm.HttpConnectionManager_HTTP1,
+			alpn:      []string{"http/1.1"},
+		},
+		{
+			name:      "http without h2c",
+			server:    httpServer,
+			protocols: &gateway.DownstreamProtocols{HTTP2: gateway.HTTP2Disabled},
+			codec:     hcm.HttpConnectionManager_HTTP1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cgi := NewConfigGenerator([]plugin.Plugin{}, &pilot_model.DisabledCache{})
+			node := &pilot_model.Proxy{Metadata: &pilot_model.NodeMetadata{}}
+			node.MergedGateway = &pilot_model.MergedGateway{TLSServerInfo: map[*networking.Server]*pilot_model.TLSServerInfo{
+				tc.server: {SNIHosts: pilot_model.GetSNIHostsForServer(tc.server)},
+			}}
+			ret := cgi.createGatewayHTTPFilterChainOpts(node, tc.server.Port, tc.server, "some-route", nil,
+				istionetworking.TransportProtocolTCP, tc.protocols)
+			if got := ret.httpOpts.connectionManager.CodecType; got != tc.codec {
+				t.Errorf("got codec %v, want %v", got, tc.codec)
+			}
+			if got := ret.tlsContext.GetCommonTlsContext().GetAlpnProtocols(); !reflect.DeepEqual(got, tc.alpn) {
+				t.Errorf("got ALPN %v, want %v", got, tc.alpn)
+			}
+		})
+	}
+}
+
 func TestGatewayHTTPRouteConfig(t *testing.T) {
 	httpRedirectGateway := config.Config{
 		Meta: config.Meta{
@@ -2139,6 +2269,148 @@ func TestBuildGatewayListeners(t *testing.T) {
 	}
 }
 
+func TestBuildGatewayListenersExactBalance(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		Configs: []config.Config{{
+			Meta: config.Meta{Name: "gateway", Namespace: "default", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{
+				Servers: []*networking.Server{{
+					Hosts: []string{"*"},
+					Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+				}},
+			},
+		}},
+	})
+	proxy := cg.SetupProxy(&pilot_model.Proxy{
+		Type:            pilot_model.Router,
+		ConfigNamespace: "default",
+		Metadata:        &pilot_model.NodeMetadata{InboundListenerExactBalance: true},
+	})
+	builder := cg.ConfigGen.buildGatewayListeners(&ListenerBuilder{node: proxy, push: cg.PushContext()})
+	if len(builder.gatewayListeners) == 0 {
+		t.Fatalf("expected gateway listeners")
+	}
+	for _, l := range builder.gatewayListeners {
+		if l.ConnectionBalanceConfig.GetExactBalance() == nil {
+			t.Fatalf("expected connection balance config to be set to exact_balance for %v, found %v", l.Name, l.ConnectionBalanceConfig)
+		}
+	}
+}
+
+func TestBuildGatewayListenersOriginalSrc(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		Configs: []config.Config{{
+			Meta: config.Meta{Name: "gateway", Namespace: "default", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{
+				Servers: []*networking.Server{
+					{
+						Hosts: []string{"*"},
+						Port:  &networking.Port{Name: "tcp-postgres", Number: 5432, Protocol: "TCP"},
+					},
+					{
+						Hosts: []string{"*"},
+						Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+					},
+				},
+			},
+		}, {
+			Meta: config.Meta{Name: "postgres", Namespace: "default", GroupVersionKind: gvk.VirtualService},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{"*"},
+				Gateways: []string{"gateway"},
+				Tcp: []*networking.TCPRoute{{
+					Route: []*networking.RouteDestination{{
+						Destination: &networking.Destination{Host: "postgres.example.com"},
+					}},
+				}},
+			},
+		}},
+	})
+	proxy := cg.SetupProxy(&pilot_model.Proxy{
+		Type:            pilot_model.Router,
+		ConfigNamespace: "default",
+		Metadata: &pilot_model.NodeMetadata{
+			Annotations: map[string]string{OriginalSrcPortsAnnotation: "5432, 3306"},
+		},
+	})
+	builder := cg.ConfigGen.buildGatewayListeners(&ListenerBuilder{node: proxy, push: cg.PushContext()})
+	if len(builder.gatewayListeners) != 2 {
+		t.Fatalf("expected 2 gateway listeners, found %d", len(builder.gatewayListeners))
+	}
+	for _, l := range builder.gatewayListeners {
+		want := l.Address.GetSocketAddress().GetPortValue() == 5432
+		got := false
+		for _, f := range l.ListenerFilters {
+			if f.Name == wellknown.OriginalSource {
+				got = true
+			}
+		}
+		if got != want {
+			t.Errorf("listener %v: got original_src filter %v, want %v", l.Name, got, want)
+		}
+	}
+}
+
+func TestBuildGatewayListenersProxyProtocol(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		Configs: []config.Config{{
+			Meta: config.Meta{Name: "gateway", Namespace: "default", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{
+				Servers: []*networking.Server{
+					{
+						Hosts: []string{"*.example.com"},
+						Port:  &networking.Port{Name: "tls", Number: 443, Protocol: "TLS"},
+						Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_PASSTHROUGH},
+					},
+					{
+						Hosts: []string{"*"},
+						Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+					},
+				},
+			},
+		}, {
+			Meta: config.Meta{Name: "passthrough", Namespace: "default", GroupVersionKind: gvk.VirtualService},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{"*.example.com"},
+				Gateways: []string{"gateway"},
+				Tls: []*networking.TLSRoute{{
+					Match: []*networking.TLSMatchAttributes{{SniHosts: []string{"*.example.com"}}},
+					Route: []*networking.RouteDestination{{
+						Destination: &networking.Destination{Host: "backend.example.com"},
+					}},
+				}},
+			},
+		}},
+	})
+	proxy := cg.SetupProxy(&pilot_model.Proxy{
+		Type:            pilot_model.Router,
+		ConfigNamespace: "default",
+		Metadata: &pilot_model.NodeMetadata{
+			Annotations: map[string]string{
+				ProxyProtocolPortsAnnotation: "443",
+				OriginalSrcPortsAnnotation:   "*",
+			},
+		},
+	})
+	listeners := cg.Listeners(proxy)
+	if len(listeners) != 2 {
+		t.Fatalf("expected 2 gateway listeners, found %d", len(listeners))
+	}
+	for _, l := range listeners {
+		var got []string
+		for _, f := range l.ListenerFilters {
+			got = append(got, f.Name)
+		}
+		want := []string{wellknown.OriginalSource}
+		if l.Address.GetSocketAddress().GetPortValue() == 443 {
+			want = []string{wellknown.ProxyProtocol, wellknown.OriginalSource, wellknown.TlsInspector}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("listener %v: got listener filters %v, want %v", l.Name, got, want)
+		}
+	}
+}
+
 func TestBuildNameToServiceMapForHttpRoutes(t *testing.T) {
 	virtualServiceSpec := &networking.VirtualService{
 		Hosts: []string{"*.example.org"},
@@ -0,0 +1,187 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	healthcheck "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/health_check/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/log"
+)
+
+// Settings of the active gRPC health checks of the destinations of the gateway.GRPCHealthCheckAnnotation.
+const (
+	grpcHealthCheckInterval           = 5 * time.Second
+	grpcHealthCheckTimeout            = time.Second
+	grpcHealthCheckUnhealthyThreshold = 2
+	grpcHealthCheckHealthyThreshold   = 1
+)
+
+// grpcHealthCheckMinHealthyPercent is the smallest percentage of healthy endpoints, so that the health endpoint
+// succeeds as long as a single endpoint of each destination is healthy.
+const grpcHealthCheckMinHealthyPercent = math.SmallestNonzeroFloat64
+
+// grpcHealthCheckDestination is a destination of the HTTP routes of a VirtualService with the
+// gateway.GRPCHealthCheckAnnotation.
+type grpcHealthCheckDestination struct {
+	// cluster is the name of the cluster of the destination, including its subset.
+	cluster string
+	// serviceCluster is the name of the cluster of the destination port of the service, without subset.
+	serviceCluster string
+}
+
+// grpcHealthCheckDestinations returns the health endpoints of the VirtualServices with the
+// gateway.GRPCHealthCheckAnnotation bound to the gateways, keyed by path, with the destinations they check.
+func grpcHealthCheckDestinations(node *model.Proxy, push *model.PushContext,
+	gateways []string) map[string][]grpcHealthCheckDestination {
+	out := map[string][]grpcHealthCheckDestination{}
+	seen := sets.NewSet()
+	for _, gw := range gateways {
+		for _, cfg := range push.VirtualServicesForGateway(node, gw) {
+			value, f := cfg.Annotations[gateway.GRPCHealthCheckAnnotation]
+			if !f || seen.Contains(cfg.Namespace+"/"+cfg.Name) {
+				continue
+			}
+			seen.Insert(cfg.Namespace + "/" + cfg.Name)
+			path, err := gateway.ParseGRPCHealthCheckPath(value)
+			if err != nil {
+				log.Warnf("ignoring invalid %s annotation of VirtualService %s/%s: %v",
+					gateway.GRPCHealthCheckAnnotation, cfg.Namespace, cfg.Name, err)
+				continue
+			}
+			for _, r := range cfg.Spec.(*networking.VirtualService).Http {
+				for _, d := range r.Route {
+					if dest, ok := resolveGRPCHealthCheckDestination(node, push, d.Destination); ok {
+						out[path] = append(out[path], dest)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// resolveGRPCHealthCheckDestination returns the clusters of a route destination. The port of the destination may
+// only be omitted if the service has a single port.
+func resolveGRPCHealthCheckDestination(node *model.Proxy, push *model.PushContext,
+	d *networking.Destination) (grpcHealthCheckDestination, bool) {
+	if d == nil {
+		return grpcHealthCheckDestination{}, false
+	}
+	port := int(d.GetPort().GetNumber())
+	if port == 0 {
+		svc := push.ServiceForHostname(node, host.Name(d.Host))
+		if svc == nil || len(svc.Ports) != 1 {
+			return grpcHealthCheckDestination{}, false
+		}
+		port = svc.Ports[0].Port
+	}
+	return grpcHealthCheckDestination{
+		cluster:        model.BuildSubsetKey(model.TrafficDirectionOutbound, d.Subset, host.Name(d.Host), port),
+		serviceCluster: model.BuildSubsetKey(model.TrafficDirectionOutbound, "", host.Name(d.Host), port),
+	}, true
+}
+
+// buildGRPCHealthCheckFilters builds a health check filter for each health endpoint of the VirtualServices bound to
+// the gateways. Requests to the path of an endpoint are answered by the gateway from the active health checks of
+// its destinations, without being forwarded: Envoy cannot translate them into gRPC health check requests.
+func buildGRPCHealthCheckFilters(node *model.Proxy, push *model.PushContext, gateways []string) []*hcm.HttpFilter {
+	endpoints := grpcHealthCheckDestinations(node, push, gateways)
+	paths := make([]string, 0, len(endpoints))
+	for path := range endpoints {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	filters := make([]*hcm.HttpFilter, 0, len(paths))
+	for _, path := range paths {
+		clusters := map[string]*xdstype.Percent{}
+		for _, d := range endpoints[path] {
+			clusters[d.cluster] = &xdstype.Percent{Value: grpcHealthCheckMinHealthyPercent}
+		}
+		filters = append(filters, &hcm.HttpFilter{
+			Name: wellknown.HealthCheck,
+			ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(&healthcheck.HealthCheck{
+				PassThroughMode: &wrappers.BoolValue{Value: false},
+				Headers: []*route.HeaderMatcher{{
+					Name: ":path",
+					HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{StringMatch: &matcher.StringMatcher{
+						MatchPattern: &matcher.StringMatcher_Exact{Exact: path},
+					}},
+				}},
+				ClusterMinHealthyPercentages: clusters,
+			})},
+		})
+	}
+	return filters
+}
+
+// gatewayGRPCHealthCheckClusters returns the service clusters of the destinations of the health endpoints of the
+// VirtualServices bound to the gateways of the proxy.
+func gatewayGRPCHealthCheckClusters(node *model.Proxy, push *model.PushContext) sets.Set {
+	clusters := sets.NewSet()
+	if node.MergedGateway == nil {
+		return clusters
+	}
+	gateways := sets.NewSet()
+	for _, gw := range node.MergedGateway.GatewayNameForServer {
+		gateways.Insert(gw)
+	}
+	for _, destinations := range grpcHealthCheckDestinations(node, push, gateways.SortedList()) {
+		for _, d := range destinations {
+			clusters.Insert(d.serviceCluster)
+		}
+	}
+	return clusters
+}
+
+// serverGatewayNames returns the names of the gateways of the servers.
+func serverGatewayNames(mergedGateway *model.MergedGateway, servers []*networking.Server) []string {
+	gateways := sets.NewSet()
+	for _, s := range servers {
+		gateways.Insert(mergedGateway.GatewayNameForServer[s])
+	}
+	return gateways.SortedList()
+}
+
+// applyGRPCHealthCheck adds an active gRPC health check to a cluster.
+func applyGRPCHealthCheck(c *cluster.Cluster) {
+	c.HealthChecks = append(c.HealthChecks, &core.HealthCheck{
+		Timeout:            durationpb.New(grpcHealthCheckTimeout),
+		Interval:           durationpb.New(grpcHealthCheckInterval),
+		UnhealthyThreshold: &wrappers.UInt32Value{Value: grpcHealthCheckUnhealthyThreshold},
+		HealthyThreshold:   &wrappers.UInt32Value{Value: grpcHealthCheckHealthyThreshold},
+		HealthChecker: &core.HealthCheck_GrpcHealthCheck_{
+			GrpcHealthCheck: &core.HealthCheck_GrpcHealthCheck{},
+		},
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	healthcheck "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/health_check/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+)
+
+func TestGRPCHealthCheck(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: gateway
+  namespace: default
+spec:
+  servers:
+  - hosts:
+    - "*"
+    port:
+      name: http
+      number: 80
+      protocol: HTTP
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: grpc
+  namespace: default
+  annotations:
+    networking.istio.io/grpc-health-check: /healthz
+spec:
+  hosts:
+  - "*"
+  gateways:
+  - gateway
+  http:
+  - route:
+    - destination:
+        host: grpc.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: grpc
+  namespace: default
+spec:
+  hosts:
+  - grpc.example.com
+  ports:
+  - number: 9000
+    name: grpc
+    protocol: GRPC
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 2.2.2.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: http
+  namespace: default
+spec:
+  hosts:
+  - http.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 3.3.3.3
+`})
+	proxy := cg.SetupProxy(&model.Proxy{Type: model.Router, ConfigNamespace: "default"})
+
+	l := xdstest.ExtractListener("0.0.0.0_80", cg.Listeners(proxy))
+	if l == nil {
+		t.Fatal("missing gateway listener")
+	}
+	filters := xdstest.ExtractHTTPConnectionManager(t, l.FilterChains[0]).HttpFilters
+	if len(filters) == 0 || filters[0].Name != wellknown.HealthCheck {
+		t.Fatalf("expected the health check filter first, got %v", filters)
+	}
+	hc := &healthcheck.HealthCheck{}
+	if err := filters[0].GetTypedConfig().UnmarshalTo(hc); err != nil {
+		t.Fatal(err)
+	}
+	if hc.PassThroughMode.GetValue() {
+		t.Errorf("expected the health endpoint to be answered by the gateway")
+	}
+	if got := hc.Headers[0].GetStringMatch().GetExact(); got != "/healthz" {
+		t.Errorf("got path %q, want /healthz", got)
+	}
+	if _, f := hc.ClusterMinHealthyPercentages["outbound|9000||grpc.example.com"]; !f || len(hc.ClusterMinHealthyPercentages) != 1 {
+		t.Errorf("got clusters %v, want outbound|9000||grpc.example.com", hc.ClusterMinHealthyPercentages)
+	}
+
+	clusters := xdstest.ExtractClusters(cg.Clusters(proxy))
+	if got := clusters["outbound|9000||grpc.example.com"].GetHealthChecks(); len(got) != 1 || got[0].GetGrpcHealthCheck() == nil {
+		t.Errorf("expected a gRPC health check, got %v", got)
+	}
+	if got := clusters["outbound|80||http.example.com"].GetHealthChecks(); len(got) != 0 {
+		t.Errorf("expected no health check, got %v", got)
+	}
+}
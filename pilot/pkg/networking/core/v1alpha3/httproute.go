@@ -20,6 +20,7 @@ import (
 	"strconv"
 	"strings"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
@@ -29,11 +30,13 @@ import (
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/envoyfilter"
 	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/security/authn/factory"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/proto"
 )
@@ -41,6 +44,7 @@ import (
 const (
 	wildcardDomainPrefix     = "*."
 	inboundVirtualHostPrefix = string(model.TrafficDirectionInbound) + "|http|"
+	baggageHeader            = "baggage"
 )
 
 // BuildHTTPRoutes produces a list of routes for the proxy
@@ -58,7 +62,13 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(
 		// dependent envoyfilters' key, calculate in front once to prevent calc for each route.
 		envoyfilterKeys := efw.Keys()
 		for _, routeName := range routeNames {
-			rc, cached := configgen.buildSidecarOutboundHTTPRouteConfig(node, req, routeName, vHostCache, efw, envoyfilterKeys)
+			var rc *discovery.Resource
+			cached := false
+			if port, suffix, ok := parseScopedRouteConfigName(routeName); ok {
+				rc = configgen.buildSidecarOutboundScopedHTTPRouteConfig(node, req, routeName, port, suffix, vHostCache, efw)
+			} else {
+				rc, cached = configgen.buildSidecarOutboundHTTPRouteConfig(node, req, routeName, vHostCache, efw, envoyfilterKeys)
+			}
 			if cached && !features.EnableUnsafeAssertions {
 				hit++
 			} else {
@@ -103,10 +113,19 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPRouteConfig(
 	traceOperation := util.TraceOperation(string(instance.Service.Hostname), instance.ServicePort.Port)
 	defaultRoute := istio_route.BuildDefaultHTTPInboundRoute(clusterName, traceOperation)
 
+	// Filter bypass routes and dispatch routes are matched before the default route.
+	routes := filterBypassRoutes(node.SidecarScope.FilterBypassPaths, clusterName, traceOperation)
+	var inboundRoutes []*route.Route
+	if !node.SidecarScope.HasIngressListener() {
+		inboundRoutes = inboundDispatchRoutes(inboundPortDispatches(node), instance.ServicePort.Port, traceOperation)
+	}
+	inboundRoutes = append(inboundRoutes, defaultRoute)
+	routes = append(routes, applyPrincipalConcurrency(node, inboundRoutes)...)
+
 	inboundVHost := &route.VirtualHost{
 		Name:    inboundVirtualHostPrefix + strconv.Itoa(instance.ServicePort.Port), // Format: "inbound|http|%d"
 		Domains: []string{"*"},
-		Routes:  []*route.Route{defaultRoute},
+		Routes:  routes,
 	}
 
 	r := &route.RouteConfiguration{
@@ -114,11 +133,38 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPRouteConfig(
 		VirtualHosts:     []*route.VirtualHost{inboundVHost},
 		ValidateClusters: proto.BoolFalse,
 	}
+	applyJwtClaimHeaders(node, push, r)
+	applySecurityHeaders(node, push, r)
 	efw := push.EnvoyFilters(node)
 	r = envoyfilter.ApplyRouteConfigurationPatches(networking.EnvoyFilter_SIDECAR_INBOUND, node, efw, r)
 	return r
 }
 
+// applyJwtClaimHeaders copies claims of validated JWTs into request headers, as configured by the
+// RequestAuthentication policies applied to the proxy.
+func applyJwtClaimHeaders(node *model.Proxy, push *model.PushContext, r *route.RouteConfiguration) {
+	applier := factory.NewPolicyApplier(push, node.Metadata.Namespace, labels.Collection{node.Metadata.Labels})
+	toAdd, toRemove := applier.JwtClaimHeaders()
+	r.RequestHeadersToAdd = append(r.RequestHeadersToAdd, toAdd...)
+	r.RequestHeadersToRemove = append(r.RequestHeadersToRemove, toRemove...)
+}
+
+// applyBaggage adds the mesh entries of the W3C baggage, configured with the Telemetry API, to the requests
+// routed by r. They are only added to requests without baggage, so that they are set by the first hop and
+// propagated unchanged by the next ones.
+func applyBaggage(baggage string, r *route.RouteConfiguration) {
+	if baggage == "" {
+		return
+	}
+	r.RequestHeadersToAdd = append(r.RequestHeadersToAdd, &core.HeaderValueOption{
+		Header: &core.HeaderValue{
+			Key:   baggageHeader,
+			Value: baggage,
+		},
+		AppendAction: core.HeaderValueOption_ADD_IF_ABSENT,
+	})
+}
+
 // buildSidecarOutboundHTTPRouteConfig builds an outbound HTTP Route for sidecar.
 // Based on port, will determine all virtual hosts that listen on the port.
 func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPRouteConfig(
@@ -198,6 +244,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPRouteConfig(
 		VirtualHosts:     virtualHosts,
 		ValidateClusters: proto.BoolFalse,
 	}
+	applyBaggage(req.Push.Telemetry.Baggage(node), out)
 
 	// apply envoy filter patches
 	out = envoyfilter.ApplyRouteConfigurationPatches(networking.EnvoyFilter_SIDECAR_OUTBOUND, node, efw, out)
@@ -237,7 +284,7 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 	services = egressListener.Services()
 	// To maintain correctness, we should only use the virtualservices for
 	// this listener and not all virtual services accessible to this proxy.
-	virtualServices = egressListener.VirtualServices()
+	virtualServices = model.LocalityPinnedConfigs(node, egressListener.VirtualServices())
 
 	// When generating RDS for ports created via the SidecarScope, we treat ports as HTTP proxy style ports
 	// if ports protocol is HTTP_PROXY.
@@ -294,6 +341,7 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 			DNSDomain:               node.DNSDomain,
 			DNSCapture:              bool(node.Metadata.DNSCapture),
 			DNSAutoAllocate:         bool(node.Metadata.DNSAutoAllocate),
+			Baggage:                 push.Telemetry.Baggage(node),
 			ListenerPort:            listenerPort,
 			Services:                services,
 			VirtualServices:         virtualServices,
@@ -304,6 +352,7 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 
 	// Get list of virtual services bound to the mesh gateway
 	virtualHostWrappers := istio_route.BuildSidecarVirtualHostWrapper(routeCache, node, push, servicesByName, virtualServices, listenerPort)
+	applyEgressProxyHTTPRoutes(node, push, virtualHostWrappers, routeCache)
 
 	resource, exist := xdsCache.Get(routeCache)
 	if exist {
@@ -349,10 +398,11 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 		}
 		if len(domains) > 0 {
 			return &route.VirtualHost{
-				Name:                       name,
-				Domains:                    domains,
-				Routes:                     vhwrapper.Routes,
-				IncludeRequestAttemptCount: true,
+				Name:                          name,
+				Domains:                       domains,
+				Routes:                        vhwrapper.Routes,
+				IncludeRequestAttemptCount:    true,
+				IncludeAttemptCountInResponse: features.IncludeAttemptCountInResponse,
 			}
 		}
 
@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/log"
+)
+
+// InboundPortDispatchAnnotation is the annotation of a workload dispatching the inbound HTTP requests of a service
+// port to other ports of the application, by path prefix and headers. It is a JSON list of rules, for example
+// [{"port": 8080, "prefix": "/api/v2", "targetPort": 9090}, {"port": 8080, "headers": {"x-version": "v2"},
+// "targetPort": 9091}]. The first matching rule wins, and the requests matching no rule go to the target port of
+// the service port as usual. This lets a monolith be split into several processes behind a stable Service.
+const InboundPortDispatchAnnotation = "sidecar.istio.io/inboundPortDispatch"
+
+// inboundDispatchSubset is the subset name of the inbound clusters of the dispatch target ports. They are distinct
+// from the inbound clusters of the service ports, which may use the original destination of the connection.
+const inboundDispatchSubset = "dispatch"
+
+// inboundPortDispatch is a rule of the InboundPortDispatchAnnotation.
+type inboundPortDispatch struct {
+	// Port is the service port whose requests are dispatched.
+	Port int `json:"port"`
+	// Prefix is the path prefix of the requests. Defaults to all paths.
+	Prefix string `json:"prefix"`
+	// Headers are the exact values of the headers of the requests.
+	Headers map[string]string `json:"headers"`
+	// TargetPort is the port of the application the requests are sent to.
+	TargetPort int `json:"targetPort"`
+}
+
+// inboundPortDispatches returns the inbound port dispatch rules of the workload.
+func inboundPortDispatches(node *model.Proxy) []inboundPortDispatch {
+	v, f := node.Metadata.Annotations[InboundPortDispatchAnnotation]
+	if !f {
+		return nil
+	}
+	dispatches, err := parseInboundPortDispatches(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation of proxy %s: %v", InboundPortDispatchAnnotation, node.ID, err)
+		return nil
+	}
+	return dispatches
+}
+
+func parseInboundPortDispatches(v string) ([]inboundPortDispatch, error) {
+	var dispatches []inboundPortDispatch
+	if err := json.Unmarshal([]byte(v), &dispatches); err != nil {
+		return nil, err
+	}
+	for _, d := range dispatches {
+		if d.Port <= 0 || d.Port > 65535 || d.TargetPort <= 0 || d.TargetPort > 65535 {
+			return nil, fmt.Errorf("invalid port %d or target port %d", d.Port, d.TargetPort)
+		}
+	}
+	return dispatches, nil
+}
+
+// inboundDispatchClusterName returns the name of the inbound cluster of a dispatch target port.
+func inboundDispatchClusterName(targetPort int) string {
+	return model.BuildSubsetKey(model.TrafficDirectionInbound, inboundDispatchSubset, "", targetPort)
+}
+
+// inboundDispatchRoutes returns the inbound routes dispatching the requests of the given service port, which must
+// come before the default route.
+func inboundDispatchRoutes(dispatches []inboundPortDispatch, port int, operation string) []*route.Route {
+	var routes []*route.Route
+	for _, d := range dispatches {
+		if d.Port != port {
+			continue
+		}
+		r := istio_route.BuildDefaultHTTPInboundRoute(inboundDispatchClusterName(d.TargetPort), operation)
+		r.Name = fmt.Sprintf("dispatch-%d", d.TargetPort)
+		if d.Prefix != "" {
+			r.Match.PathSpecifier = &route.RouteMatch_Prefix{Prefix: d.Prefix}
+		}
+		names := make([]string, 0, len(d.Headers))
+		for name := range d.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r.Match.Headers = append(r.Match.Headers, &route.HeaderMatcher{
+				Name:                 name,
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: d.Headers[name]},
+			})
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// buildInboundDispatchClusters builds the inbound clusters of the dispatch target ports. They send the requests to
+// bind, as the original destination of the connections is the service target port.
+func buildInboundDispatchClusters(cb *ClusterBuilder, proxy *model.Proxy, instances []*model.ServiceInstance,
+	dispatches []inboundPortDispatch, bind string, cp clusterPatcher) []*cluster.Cluster {
+	byServicePort := map[int]*model.ServiceInstance{}
+	for _, instance := range instances {
+		if _, f := byServicePort[instance.ServicePort.Port]; !f {
+			byServicePort[instance.ServicePort.Port] = instance
+		}
+	}
+	tlsSettings := inboundTLSSettings(proxy)
+	var clusters []*cluster.Cluster
+	built := map[int]bool{}
+	for _, d := range dispatches {
+		instance := byServicePort[d.Port]
+		if instance == nil || built[d.TargetPort] {
+			continue
+		}
+		built[d.TargetPort] = true
+		dispatchInstance := instance.DeepCopy()
+		dispatchInstance.Endpoint.EndpointPort = uint32(d.TargetPort)
+		localCluster := cb.buildInboundClusterForPortOrUDS(d.TargetPort, bind, proxy, dispatchInstance, nil)
+		localCluster.cluster.Name = inboundDispatchClusterName(d.TargetPort)
+		cb.applyInboundUpstreamTLS(localCluster, tlsSettings, uint32(d.TargetPort))
+		clusters = cp.conditionallyAppend(clusters, []host.Name{instance.Service.Hostname}, localCluster.build())
+	}
+	return clusters
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestParseInboundPortDispatches(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "empty list", value: `[]`},
+		{name: "valid", value: `[{"port": 8080, "prefix": "/api/v2", "targetPort": 9090}, {"port": 8080, "headers": {"x-version": "v2"}, "targetPort": 9091}]`, want: 2},
+		{name: "invalid json", value: `{`, wantErr: true},
+		{name: "missing target port", value: `[{"port": 8080, "prefix": "/api"}]`, wantErr: true},
+		{name: "invalid port", value: `[{"port": 70000, "targetPort": 9090}]`, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInboundPortDispatches(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d rules, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestInboundDispatchRoutes(t *testing.T) {
+	dispatches := []inboundPortDispatch{
+		{Port: 8080, Prefix: "/api/v2", TargetPort: 9090},
+		{Port: 8080, Headers: map[string]string{"x-version": "v2", "x-canary": "true"}, TargetPort: 9091},
+		{Port: 8081, TargetPort: 9092},
+	}
+	routes := inboundDispatchRoutes(dispatches, 8080, "op")
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+	if got := routes[0].GetMatch().GetPrefix(); got != "/api/v2" {
+		t.Errorf("got prefix %q, want /api/v2", got)
+	}
+	if got := routes[0].GetRoute().GetCluster(); got != "inbound|9090|dispatch|" {
+		t.Errorf("got cluster %q", got)
+	}
+	wantHeaders := []*route.HeaderMatcher{
+		{Name: "x-canary", HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "true"}},
+		{Name: "x-version", HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "v2"}},
+	}
+	if diff := cmp.Diff(routes[1].GetMatch().GetHeaders(), wantHeaders, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected headers: %v", diff)
+	}
+	if got := routes[1].GetRoute().GetCluster(); got != "inbound|9091|dispatch|" {
+		t.Errorf("got cluster %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/pkg/log"
+)
+
+// InboundTLSAnnotation is the annotation of a workload originating TLS from its sidecar to the application, for
+// applications that require TLS even on the local hop. It is a JSON object from the ports of the application to the
+// TLS settings of the connections to them, in the format of the DestinationRule TLS settings, for example
+// {"8443": {"mode": "SIMPLE", "caCertificates": "/etc/app-certs/ca.pem", "sni": "app.local"}}. Only the SIMPLE and
+// MUTUAL modes are supported. The certificates are either files mounted in the proxy, or a credentialName, which
+// requires the sidecars to be allowed to fetch credentials from Istiod.
+const InboundTLSAnnotation = "sidecar.istio.io/inboundTLS"
+
+// inboundTLSSettings returns the TLS settings of the connections from the sidecar to the ports of the application.
+func inboundTLSSettings(node *model.Proxy) map[uint32]*networking.ClientTLSSettings {
+	v, f := node.Metadata.Annotations[InboundTLSAnnotation]
+	if !f {
+		return nil
+	}
+	settings, err := parseInboundTLSSettings(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation of proxy %s: %v", InboundTLSAnnotation, node.ID, err)
+		return nil
+	}
+	return settings
+}
+
+func parseInboundTLSSettings(v string) (map[uint32]*networking.ClientTLSSettings, error) {
+	var raw map[string]*networking.ClientTLSSettings
+	if err := json.Unmarshal([]byte(v), &raw); err != nil {
+		return nil, err
+	}
+	settings := make(map[uint32]*networking.ClientTLSSettings, len(raw))
+	for p, tls := range raw {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		if tls.GetMode() != networking.ClientTLSSettings_SIMPLE && tls.GetMode() != networking.ClientTLSSettings_MUTUAL {
+			return nil, fmt.Errorf("unsupported TLS mode %v for port %d, must be SIMPLE or MUTUAL", tls.GetMode(), port)
+		}
+		settings[uint32(port)] = tls
+	}
+	return settings, nil
+}
+
+// applyInboundUpstreamTLS originates TLS to the application on the given port from an inbound cluster, if the
+// workload requires it.
+func (cb *ClusterBuilder) applyInboundUpstreamTLS(mc *MutableCluster, settings map[uint32]*networking.ClientTLSSettings, port uint32) {
+	tls := settings[port]
+	if tls == nil {
+		return
+	}
+	opts := &buildClusterOpts{mutable: mc, direction: model.TrafficDirectionInbound}
+	// The settings are modified while building the TLS context.
+	tlsContext, err := cb.buildUpstreamClusterTLSContext(opts, tls.DeepCopy())
+	if err != nil {
+		log.Errorf("failed to build the inbound TLS context of cluster %s: %v", mc.cluster.Name, err)
+		return
+	}
+	if tlsContext == nil {
+		log.Warnf("ignoring the inbound TLS settings of cluster %s, the credential cannot be fetched by sidecars", mc.cluster.Name)
+		return
+	}
+	mc.cluster.TransportSocket = &core.TransportSocket{
+		Name:       util.EnvoyTLSSocketName,
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(tlsContext)},
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+func TestParseInboundTLSSettings(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		ports   []uint32
+		wantErr bool
+	}{
+		{name: "simple", value: `{"8443": {"mode": "SIMPLE", "caCertificates": "/etc/app/ca.pem"}}`, ports: []uint32{8443}},
+		{
+			name:  "mutual",
+			value: `{"8443": {"mode": "SIMPLE"}, "9443": {"mode": "MUTUAL", "clientCertificate": "/c.pem", "privateKey": "/k.pem"}}`,
+			ports: []uint32{8443, 9443},
+		},
+		{name: "invalid json", value: "{", wantErr: true},
+		{name: "invalid port", value: `{"https": {"mode": "SIMPLE"}}`, wantErr: true},
+		{name: "port out of range", value: `{"70000": {"mode": "SIMPLE"}}`, wantErr: true},
+		{name: "istio mutual", value: `{"8443": {"mode": "ISTIO_MUTUAL"}}`, wantErr: true},
+		{name: "disable", value: `{"8443": {"mode": "DISABLE"}}`, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInboundTLSSettings(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.ports) {
+				t.Fatalf("got %d ports, want %v", len(got), tt.ports)
+			}
+			for _, p := range tt.ports {
+				if got[p] == nil {
+					t.Errorf("missing settings for port %d", p)
+				}
+			}
+		})
+	}
+}
+
+func TestInboundUpstreamTLS(t *testing.T) {
+	servicePort := &model.Port{Name: "https", Port: 443, Protocol: protocol.HTTPS}
+	service := &model.Service{
+		Hostname:   host.Name("backend.default.svc.cluster.local"),
+		Ports:      model.PortList{servicePort},
+		Resolution: model.ClientSideLB,
+	}
+	instances := []*model.ServiceInstance{{
+		Service:     service,
+		ServicePort: servicePort,
+		Endpoint:    &model.IstioEndpoint{Address: "1.1.1.1", EndpointPort: 8443},
+	}}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		wantTLS     bool
+	}{
+		{name: "no annotation"},
+		{
+			name:        "simple",
+			annotations: map[string]string{InboundTLSAnnotation: `{"8443": {"mode": "SIMPLE", "sni": "app.local"}}`},
+			wantTLS:     true,
+		},
+		{
+			name:        "other port",
+			annotations: map[string]string{InboundTLSAnnotation: `{"9443": {"mode": "SIMPLE"}}`},
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{InboundTLSAnnotation: `{"8443": {"mode": "ISTIO_MUTUAL"}}`},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{
+				Services:  []*model.Service{service},
+				Instances: instances,
+			})
+			proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Annotations: tt.annotations}})
+			c := xdstest.ExtractCluster("inbound|8443||", cg.Clusters(proxy))
+			if c == nil {
+				t.Fatal("inbound cluster not found")
+			}
+			if !tt.wantTLS {
+				if c.TransportSocket != nil {
+					t.Fatalf("unexpected transport socket %v", c.TransportSocket)
+				}
+				return
+			}
+			if c.TransportSocket.GetName() != util.EnvoyTLSSocketName {
+				t.Fatalf("got transport socket %v, want TLS", c.TransportSocket)
+			}
+			ctx := &tls.UpstreamTlsContext{}
+			if err := c.TransportSocket.GetTypedConfig().UnmarshalTo(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if ctx.Sni != "app.local" {
+				t.Errorf("got SNI %q, want app.local", ctx.Sni)
+			}
+		})
+	}
+}
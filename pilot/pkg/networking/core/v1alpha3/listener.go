@@ -26,6 +26,7 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	proxyprotocol "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/proxy_protocol/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	envoyquicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
 	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
@@ -124,8 +125,13 @@ func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
 		builder = configgen.buildGatewayListeners(builder)
 	}
 
+	builder.applySocketOptions()
 	builder.patchListeners()
-	return builder.getListeners()
+	listeners := builder.getListeners()
+	for _, l := range listeners {
+		orderListenerFilters(l)
+	}
+	return listeners
 }
 
 func (configgen *ConfigGeneratorImpl) BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
@@ -276,6 +282,11 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundListeners(
 		//
 		for _, instance := range node.ServiceInstances {
 			endpoint := instance.Endpoint
+			// Traffic to ports excluded from capture goes directly to the application, so the proxy never sees it.
+			if !node.IsInboundPortCaptured(int(endpoint.EndpointPort)) {
+				log.Debugf("skipping inbound listener:%d for %s: the port is not captured", endpoint.EndpointPort, node.ID)
+				continue
+			}
 			// Inbound listeners will be aggregated into a single virtual listener (port 15006)
 			// As a result, we don't need to worry about binding to the endpoint IP; we already know
 			// all traffic for these listeners is inbound.
@@ -414,6 +425,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPListenerOptsForPort
 			AcceptHttp_10: true,
 		}
 	}
+	applyForwardedHeaders(node, httpOpts)
 
 	return httpOpts
 }
@@ -570,7 +582,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 	for _, egressListener := range node.SidecarScope.EgressListeners {
 
 		services := egressListener.Services()
-		virtualServices := egressListener.VirtualServices()
+		virtualServices := model.LocalityPinnedConfigs(node, egressListener.VirtualServices())
 
 		// determine the bindToPort setting for listeners
 		bindToPort := false
@@ -755,7 +767,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 		configgen.appendListenerFallthroughRouteForCompleteListener(listener, node, push)
 	}
 	removeListenerFilterTimeout(tcpListeners)
-	return tcpListeners
+	return append(tcpListeners, buildSidecarOutboundUDPListeners(node, push)...)
 }
 
 func (configgen *ConfigGeneratorImpl) buildHTTPProxy(node *model.Proxy,
@@ -895,6 +907,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPListenerOptsForPor
 		// such as "x-envoy-upstream-rq-timeout-ms" set by the calling application.
 		useRemoteAddress: features.UseRemoteAddress,
 		rds:              rdsName,
+		scopedRoutes:     rdsName == strconv.Itoa(listenerOpts.port.Port) && useScopedRoutes(listenerOpts.proxy, listenerOpts.port.Port),
 	}
 
 	if features.HTTP10 || enableHTTP10(listenerOpts.proxy.Metadata.HTTP10) {
@@ -1297,6 +1310,8 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListenerForPortOrUDS(l
 type httpListenerOpts struct {
 	routeConfig *route.RouteConfiguration
 	rds         string
+	// scopedRoutes serves the route configuration rds with scoped routes, sharded by domain suffix.
+	scopedRoutes bool
 	// If set, use this as a basis
 	connectionManager *hcm.HttpConnectionManager
 	// stat prefix for the http connection manager
@@ -1306,6 +1321,8 @@ type httpListenerOpts struct {
 	// should be added.
 	addGRPCWebFilter bool
 	useRemoteAddress bool
+	// healthCheckFilters answer the requests to health endpoints before any other filter.
+	healthCheckFilters []*hcm.HttpFilter
 
 	// http3Only indicates that the HTTP codec used
 	// is HTTP/3 over QUIC transport (uses UDP)
@@ -1386,6 +1403,8 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 	websocketUpgrade := &hcm.HttpConnectionManager_UpgradeConfig{UpgradeType: "websocket"}
 	connectionManager.UpgradeConfigs = []*hcm.HttpConnectionManager_UpgradeConfig{websocketUpgrade}
 
+	applyHTTP2Tuning(listenerOpts.proxy, connectionManager)
+
 	idleTimeout, err := time.ParseDuration(listenerOpts.proxy.Metadata.IdleTimeout)
 	if err == nil {
 		connectionManager.CommonHttpProtocolOptions = &core.HttpProtocolOptions{
@@ -1396,7 +1415,9 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 	notimeout := durationpb.New(0 * time.Second)
 	connectionManager.StreamIdleTimeout = notimeout
 
-	if httpOpts.rds != "" {
+	if httpOpts.rds != "" && httpOpts.scopedRoutes {
+		connectionManager.RouteSpecifier = buildScopedRoutes(httpOpts.rds)
+	} else if httpOpts.rds != "" {
 		rds := &hcm.HttpConnectionManager_Rds{
 			Rds: &hcm.Rds{
 				ConfigSource: &core.ConfigSource{
@@ -1417,9 +1438,19 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 	accessLogBuilder.setHTTPAccessLog(listenerOpts, connectionManager)
 
 	routerFilterCtx, reqIDExtensionCtx := configureTracing(listenerOpts, connectionManager)
+	configureCorrelationID(listenerOpts, connectionManager)
 
-	filters := make([]*hcm.HttpFilter, len(httpFilters))
-	copy(filters, httpFilters)
+	filters := make([]*hcm.HttpFilter, 0, len(httpFilters)+1)
+	// The tap filter is first so captured requests are seen as received from downstream.
+	if listenerOpts.proxy.Metadata.Annotations[xdsfilters.TapAnnotation] == "true" {
+		filters = append(filters, xdsfilters.Tap)
+	}
+	filters = append(filters, httpOpts.healthCheckFilters...)
+	// Requests are shed before being authorized or otherwise processed.
+	if listenerOpts.class == istionetworking.ListenerClassSidecarInbound && useInboundAdaptiveConcurrency(listenerOpts.proxy) {
+		filters = append(filters, adaptiveConcurrencyFilter)
+	}
+	filters = append(filters, httpFilters...)
 
 	if features.MetadataExchange && util.CheckProxyVerionForMX(listenerOpts.push, listenerOpts.proxy.IstioVersion) {
 		filters = append(filters, xdsfilters.HTTPMx)
@@ -1438,9 +1469,17 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 		filters = append(filters, xdsfilters.Alpn)
 	}
 
+	if (features.EnableLBSubsetHeaders || features.EnableHeaderToMetadata) && listenerOpts.class != istionetworking.ListenerClassSidecarInbound {
+		filters = append(filters, xdsfilters.HeaderToMetadata)
+	}
+
 	// TypedPerFilterConfig in route needs these filters.
 	filters = append(filters, xdsfilters.Fault, xdsfilters.Cors)
-	filters = append(filters, listenerOpts.push.Telemetry.HTTPFilters(listenerOpts.proxy, listenerOpts.class)...)
+	telemetryFilters := listenerOpts.push.Telemetry.HTTPFilters(listenerOpts.proxy, listenerOpts.class)
+	if listenerOpts.class == istionetworking.ListenerClassSidecarInbound && listenerOpts.proxy.SidecarScope != nil {
+		telemetryFilters = skipStatsOnFilterBypassPaths(telemetryFilters, listenerOpts.proxy.SidecarScope.FilterBypassPaths)
+	}
+	filters = append(filters, telemetryFilters...)
 	filters = append(filters, xdsfilters.BuildRouterFilter(routerFilterCtx))
 
 	connectionManager.HttpFilters = filters
@@ -1449,6 +1488,116 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 	return connectionManager
 }
 
+// useExactBalance returns true if a TCP listener built for the proxy in the given direction should
+// balance connections across worker threads with exact_balance. Gateway listeners accept downstream
+// connections, so either setting enables it for gateways.
+func useExactBalance(proxy *model.Proxy, trafficDirection core.TrafficDirection) bool {
+	if proxy.Type == model.Router {
+		return bool(proxy.Metadata.InboundListenerExactBalance) || bool(proxy.Metadata.OutboundListenerExactBalance)
+	}
+	switch trafficDirection {
+	case core.TrafficDirection_INBOUND:
+		return bool(proxy.Metadata.InboundListenerExactBalance)
+	case core.TrafficDirection_OUTBOUND:
+		return bool(proxy.Metadata.OutboundListenerExactBalance)
+	}
+	return false
+}
+
+// OriginalSrcPortsAnnotation is the annotation of gateway workloads listing the ports of the gateway servers, such
+// as "5432,3306", whose upstream connections are bound to the IP of the downstream client with the original_src
+// filter, for example so that databases outside the mesh can allow clients by IP through an egress gateway. "*"
+// selects all the ports. The responses must be routed back to the gateway, which needs the NET_ADMIN capability and
+// the rules set up by istio-iptables --original-src.
+const OriginalSrcPortsAnnotation = "proxy.istio.io/originalSrcPorts"
+
+// ProxyProtocolPortsAnnotation is the annotation of gateway workloads listing the ports of the gateway servers, such
+// as "443,8443", whose connections start with a PROXY protocol header, for example from a load balancer in front of
+// the gateway. "*" selects all the ports. The proxy_protocol listener filter is added before the other listener
+// filters, so that the TLS and HTTP inspectors see the connection without the header.
+const ProxyProtocolPortsAnnotation = "proxy.istio.io/proxyProtocolPorts"
+
+// ProxyProtocolTLVsAnnotation is the annotation of gateway workloads mapping the type-length-value (TLV) entries of the
+// PROXY protocol v2 headers of the ports selected by ProxyProtocolPortsAnnotation to dynamic metadata keys, such as
+// "0xE0=tenant_id,0xE1=region". The values of the TLVs present are set as dynamic metadata of the downstream connection
+// under the envoy.filters.listener.proxy_protocol namespace, where the access logs can report them. The
+// AuthorizationPolicies with conditions on them, such as experimental.envoy.filters.listener.proxy_protocol[tenant_id],
+// are enforced on the connection by the network RBAC filter, before and in addition to the other policies of HTTP
+// servers, as the HTTP RBAC filter only sees the metadata of the requests.
+const ProxyProtocolTLVsAnnotation = "proxy.istio.io/proxyProtocolTLVs"
+
+// proxyProtocolFilter returns the proxy_protocol listener filter of a gateway, setting the TLVs mapped by the
+// ProxyProtocolTLVsAnnotation as dynamic metadata. Invalid entries are ignored.
+func proxyProtocolFilter(proxy *model.Proxy) *listener.ListenerFilter {
+	v, f := proxy.Metadata.Annotations[ProxyProtocolTLVsAnnotation]
+	if !f {
+		return xdsfilters.ProxyProtocol
+	}
+	var rules []*proxyprotocol.ProxyProtocol_Rule
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			log.Warnf("ignoring invalid entry %q of %s annotation of proxy %s", entry, ProxyProtocolTLVsAnnotation, proxy.ID)
+			continue
+		}
+		tlvType, err := strconv.ParseUint(strings.TrimSpace(kv[0]), 0, 8)
+		if err != nil {
+			log.Warnf("ignoring invalid entry %q of %s annotation of proxy %s", entry, ProxyProtocolTLVsAnnotation, proxy.ID)
+			continue
+		}
+		rules = append(rules, &proxyprotocol.ProxyProtocol_Rule{
+			TlvType: uint32(tlvType),
+			OnTlvPresent: &proxyprotocol.ProxyProtocol_KeyValuePair{
+				MetadataNamespace: wellknown.ProxyProtocol,
+				Key:               strings.TrimSpace(kv[1]),
+			},
+		})
+	}
+	if len(rules) == 0 {
+		return xdsfilters.ProxyProtocol
+	}
+	return &listener.ListenerFilter{
+		Name: wellknown.ProxyProtocol,
+		ConfigType: &listener.ListenerFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&proxyprotocol.ProxyProtocol{Rules: rules}),
+		},
+	}
+}
+
+// useOriginalSrc returns true if the upstream connections of the listener of a gateway for the given port should
+// be bound to the IP of the downstream client.
+func useOriginalSrc(proxy *model.Proxy, port int) bool {
+	return gatewayPortAnnotated(proxy, OriginalSrcPortsAnnotation, port)
+}
+
+// useProxyProtocol returns true if the connections to the listener of a gateway for the given port start with a
+// PROXY protocol header.
+func useProxyProtocol(proxy *model.Proxy, port int) bool {
+	return gatewayPortAnnotated(proxy, ProxyProtocolPortsAnnotation, port)
+}
+
+// gatewayPortAnnotated returns true if the given port is listed by the annotation of a gateway.
+func gatewayPortAnnotated(proxy *model.Proxy, annotation string, port int) bool {
+	if proxy.Type != model.Router {
+		return false
+	}
+	ports, f := proxy.Metadata.Annotations[annotation]
+	if !f {
+		return false
+	}
+	for _, p := range strings.Split(ports, ",") {
+		p = strings.TrimSpace(p)
+		if p == "*" || p == strconv.Itoa(port) {
+			return true
+		}
+	}
+	return false
+}
+
 // buildListener builds and initializes a Listener proto based on the provided opts. It does not set any filters.
 // Optionally for HTTP filters with TLS enabled, HTTP/3 can be supported by generating QUIC Mirror filters for the
 // same port (it is fine as QUIC uses UDP)
@@ -1460,7 +1609,14 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 	// add a TLS inspector if we need to detect ServerName or ALPN
 	// (this is not applicable for QUIC listeners)
 	needTLSInspector := false
-	if opts.transport == istionetworking.TransportProtocolTCP {
+	// Envoy updates the filter chains of a listener in place, but drains all its connections when any other field
+	// changes. Keeping the inspectors regardless of the filter chains avoids draining the connections of the untouched
+	// chains when a chain needing them is added or removed.
+	if stableListenerFilters(opts) {
+		needTLSInspector = true
+		opts.needHTTPInspector = true
+	}
+	if opts.transport == istionetworking.TransportProtocolTCP && !needTLSInspector {
 		for _, chain := range opts.filterChainOpts {
 			needsALPN := chain.tlsContext != nil && chain.tlsContext.CommonTlsContext != nil && len(chain.tlsContext.CommonTlsContext.AlpnProtocols) > 0
 			if len(chain.sniHosts) > 0 || needsALPN {
@@ -1470,10 +1626,18 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 		}
 	}
 
+	if opts.class == istionetworking.ListenerClassGateway && opts.port != nil && useProxyProtocol(opts.proxy, opts.port.Port) {
+		listenerFiltersMap[wellknown.ProxyProtocol] = true
+		listenerFilters = append(listenerFilters, proxyProtocolFilter(opts.proxy))
+	}
 	if opts.proxy.GetInterceptionMode() == model.InterceptionTproxy && trafficDirection == core.TrafficDirection_INBOUND {
 		listenerFiltersMap[wellknown.OriginalSource] = true
 		listenerFilters = append(listenerFilters, xdsfilters.OriginalSrc)
 	}
+	if opts.class == istionetworking.ListenerClassGateway && opts.port != nil && useOriginalSrc(opts.proxy, opts.port.Port) {
+		listenerFiltersMap[wellknown.OriginalSource] = true
+		listenerFilters = append(listenerFilters, xdsfilters.OriginalSrc)
+	}
 
 	// We add a TLS inspector when http inspector is needed for outbound only. This
 	// is because if we ever set ALPN in the match without
@@ -1563,9 +1727,9 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 			bindToPort = proto.BoolFalse
 		}
 
-		// only use to exact_balance for tcp outbound listeners; virtualOutbound listener should
+		// only use to exact_balance for tcp listeners; virtualOutbound listener should
 		// not have this set per Envoy docs for redirected listeners
-		if opts.proxy.Metadata.OutboundListenerExactBalance && trafficDirection == core.TrafficDirection_OUTBOUND {
+		if useExactBalance(opts.proxy, trafficDirection) {
 			connectionBalance = &listener.Listener_ConnectionBalanceConfig{
 				BalanceType: &listener.Listener_ConnectionBalanceConfig_ExactBalance_{
 					ExactBalance: &listener.Listener_ConnectionBalanceConfig_ExactBalance{},
@@ -1584,12 +1748,7 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 			ConnectionBalanceConfig: connectionBalance,
 		}
 
-		if opts.proxy.Type != model.Router {
-			res.ListenerFiltersTimeout = gogo.DurationToProtoDuration(opts.push.Mesh.ProtocolDetectionTimeout)
-			if res.ListenerFiltersTimeout != nil {
-				res.ContinueOnListenerFiltersTimeout = true
-			}
-		}
+		res.ListenerFiltersTimeout, res.ContinueOnListenerFiltersTimeout = listenerFiltersTimeout(opts)
 	case istionetworking.TransportProtocolQUIC:
 		// TODO: switch on TransportProtocolQUIC is in too many places now. Once this is a bit
 		//       mature, refactor some of these to an interface so that they kick off the process
@@ -1612,11 +1771,51 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 		}
 	}
 
+	applyListenerBufferLimit(opts.proxy, res)
 	accessLogBuilder.setListenerAccessLog(opts.push, opts.proxy, res)
 
 	return res
 }
 
+// listenerFiltersTimeout returns the timeout of the listener filters of the listener, and whether the connections
+// whose listener filters time out are handled rather than closed. Sidecars always handle them, as protocol sniffing
+// then falls back to TCP for server-first protocols.
+func listenerFiltersTimeout(opts buildListenerOpts) (*durationpb.Duration, bool) {
+	if opts.proxy.Type == model.Router {
+		if features.GatewayListenerFiltersTimeout <= 0 {
+			return nil, false
+		}
+		return durationpb.New(features.GatewayListenerFiltersTimeout), features.GatewayContinueOnListenerFiltersTimeout
+	}
+	var timeout *durationpb.Duration
+	switch {
+	case opts.class == istionetworking.ListenerClassSidecarInbound && features.InboundProtocolDetectionTimeoutSet:
+		timeout = durationpb.New(features.InboundProtocolDetectionTimeout)
+	case opts.class == istionetworking.ListenerClassSidecarOutbound && features.OutboundProtocolDetectionTimeoutSet:
+		timeout = durationpb.New(features.OutboundProtocolDetectionTimeout)
+	default:
+		timeout = gogo.DurationToProtoDuration(opts.push.Mesh.ProtocolDetectionTimeout)
+	}
+	return timeout, timeout != nil
+}
+
+// stableListenerFilters returns whether the listener filters of the listener are kept the same regardless of its
+// filter chains. It is limited to the outbound listeners of the sidecars with a protocol detection timeout, as the
+// inspectors otherwise wait forever for the first bytes of server-first protocols. The ports declaring an opaque TCP
+// protocol, such as TCP or MySQL, are left out as well: they carry the server-first protocols, whose connections
+// would wait out the timeout before being proxied.
+func stableListenerFilters(opts buildListenerOpts) bool {
+	if !features.EnableStableOutboundListenerFilters || opts.class != istionetworking.ListenerClassSidecarOutbound ||
+		opts.transport != istionetworking.TransportProtocolTCP || opts.proxy.Type == model.Router {
+		return false
+	}
+	if opts.port == nil || (opts.port.Protocol.IsTCP() && !opts.port.Protocol.IsTLS()) {
+		return false
+	}
+	timeout, _ := listenerFiltersTimeout(opts)
+	return timeout.AsDuration() > 0
+}
+
 func getMatchAllFilterChain(l *listener.Listener) (int, *listener.FilterChain) {
 	for i, fc := range l.FilterChains {
 		if isMatchAllFilterChain(fc) {
@@ -37,6 +37,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/proto"
 	"istio.io/pkg/log"
 )
@@ -320,6 +321,43 @@ func listenerPredicateIncludePorts(ports []int) *listener.ListenerFilterChainMat
 	}}
 }
 
+// inspectsConnection returns whether the listener filter is one of the filters generated by Istio reading the first
+// bytes or the addresses of the connections, which must run after the proxy_protocol filter consuming its header.
+func inspectsConnection(name string) bool {
+	switch name {
+	case wellknown.OriginalDestination, wellknown.OriginalSource, wellknown.TlsInspector, wellknown.HttpInspector:
+		return true
+	default:
+		return false
+	}
+}
+
+// orderListenerFilters moves the proxy_protocol filter of a listener, whether generated or added by an EnvoyFilter,
+// before the first listener filter generated by Istio inspecting the connections, so that it composes with protocol
+// sniffing. The other listener filters, including those added by EnvoyFilters, keep their order.
+func orderListenerFilters(l *listener.Listener) {
+	first := -1
+	for i, f := range l.ListenerFilters {
+		name := f.Name
+		if canonical, ok := xds.ReverseDeprecatedFilterNames[name]; ok {
+			name = canonical
+		}
+		if first < 0 && inspectsConnection(name) {
+			first = i
+		}
+		if name != wellknown.ProxyProtocol || first < 0 {
+			continue
+		}
+		filters := make([]*listener.ListenerFilter, 0, len(l.ListenerFilters))
+		filters = append(filters, l.ListenerFilters[:first]...)
+		filters = append(filters, f)
+		filters = append(filters, l.ListenerFilters[first:i]...)
+		filters = append(filters, l.ListenerFilters[i+1:]...)
+		l.ListenerFilters = filters
+		return
+	}
+}
+
 func NewListenerBuilder(node *model.Proxy, push *model.PushContext) *ListenerBuilder {
 	builder := &ListenerBuilder{
 		node: node,
@@ -368,6 +406,19 @@ func (lb *ListenerBuilder) buildVirtualOutboundListener(configgen *ConfigGenerat
 		FilterChains:     filterChains,
 		TrafficDirection: core.TrafficDirection_OUTBOUND,
 	}
+	if plaintextTLSChains := buildPassthroughPlaintextTLSFilterChains(lb.push, lb.node); len(plaintextTLSChains) > 0 {
+		// Envoy does not fall back to the chains matching fewer criteria, so the TLS and non HTTP traffic on the
+		// ports of the plaintext TLS chains is sent to the catch all chain as the default filter chain.
+		ipTablesListener.FilterChains = append(plaintextTLSChains, ipTablesListener.FilterChains...)
+		ipTablesListener.DefaultFilterChain = &listener.FilterChain{
+			Name:    util.PassthroughFilterChain,
+			Filters: filterChains[len(filterChains)-1].Filters,
+		}
+		ipTablesListener.ListenerFilters = passthroughPlaintextTLSListenerFilters()
+		ipTablesListener.ListenerFiltersTimeout, ipTablesListener.ContinueOnListenerFiltersTimeout = listenerFiltersTimeout(
+			buildListenerOpts{push: lb.push, proxy: lb.node, class: istionetworking.ListenerClassSidecarOutbound})
+	}
+	applyListenerBufferLimit(lb.node, ipTablesListener)
 	accessLogBuilder.setListenerAccessLog(lb.push, lb.node, ipTablesListener)
 	lb.virtualOutboundListener = ipTablesListener
 	return lb
@@ -403,6 +454,7 @@ func (lb *ListenerBuilder) buildVirtualInboundListener(configgen *ConfigGenerato
 		FilterChains:            filterChains,
 		ConnectionBalanceConfig: connectionBalance,
 	}
+	applyListenerBufferLimit(lb.node, lb.virtualInboundListener)
 	accessLogBuilder.setListenerAccessLog(lb.push, lb.node, lb.virtualInboundListener)
 	lb.aggregateVirtualInboundListener(passthroughInspector)
 
@@ -615,17 +667,29 @@ func (configgen *ConfigGeneratorImpl) buildInboundFilterchains(in *plugin.InputP
 		}
 	}
 
-	if listenerOpts.tlsSettings != nil && !hasMTLs {
-		newOpts = []*fcOpts{}
-		opt := fcOpts{matchOpts: FilterChainMatchOptions{IsCustomTLS: true}}
-		opt.fc.FilterChainMatch = &listener.FilterChainMatch{
-			TransportProtocol: xdsfilters.TLSTransportProtocol,
-			DestinationPort:   &wrappers.UInt32Value{Value: uint32(listenerOpts.port.Port)},
+	if listenerOpts.tlsSettings != nil {
+		switch {
+		case !hasMTLs:
+			newOpts = []*fcOpts{configgen.buildCustomTLSFilterChainOpts(in, listenerOpts)}
+		case isPermissive(mtlsConfigs):
+			// The clients using Istio mTLS are still served with the workload certificate, while the other TLS
+			// clients are served with the TLS settings of the port instead of being passed through to the
+			// application. This lets clients pinning the certificate of the application migrate to the mesh.
+			customOpt := configgen.buildCustomTLSFilterChainOpts(in, listenerOpts)
+			opts := make([]*fcOpts, 0, len(newOpts)+1)
+			for _, opt := range newOpts {
+				if opt.matchOpts.TransportProtocol == xdsfilters.TLSTransportProtocol && !opt.matchOpts.MTLS {
+					continue
+				}
+				if opt.matchOpts.MTLS {
+					// The filter chain must be as specific as the mTLS filter chains.
+					customOpt.fc.FilterChainMatch.DestinationPort = opt.fc.FilterChainMatch.DestinationPort
+					customOpt.fc.FilterChainMatch.PrefixRanges = opt.fc.FilterChainMatch.PrefixRanges
+				}
+				opts = append(opts, opt)
+			}
+			newOpts = append(opts, customOpt)
 		}
-		opt.fc.ListenerProtocol = listenerOpts.protocol
-		listenerOpts.tlsSettings.CipherSuites = filteredSidecarCipherSuites(listenerOpts.tlsSettings.CipherSuites)
-		opt.fc.TLSContext = configgen.BuildListenerTLSContext(listenerOpts.tlsSettings, in.Node, istionetworking.TransportProtocolTCP)
-		newOpts = append(newOpts, &opt)
 	}
 
 	// Run our filter chains through the plugin
@@ -687,6 +751,29 @@ func (configgen *ConfigGeneratorImpl) buildInboundFilterchains(in *plugin.InputP
 	return fcOpts
 }
 
+// buildCustomTLSFilterChainOpts builds the filter chain terminating TLS with the TLS settings of a Sidecar ingress listener.
+func (configgen *ConfigGeneratorImpl) buildCustomTLSFilterChainOpts(in *plugin.InputParams, listenerOpts buildListenerOpts) *fcOpts {
+	opt := fcOpts{matchOpts: FilterChainMatchOptions{IsCustomTLS: true}}
+	opt.fc.FilterChainMatch = &listener.FilterChainMatch{
+		TransportProtocol: xdsfilters.TLSTransportProtocol,
+		DestinationPort:   &wrappers.UInt32Value{Value: uint32(listenerOpts.port.Port)},
+	}
+	opt.fc.ListenerProtocol = listenerOpts.protocol
+	listenerOpts.tlsSettings.CipherSuites = filteredSidecarCipherSuites(listenerOpts.tlsSettings.CipherSuites)
+	opt.fc.TLSContext = configgen.BuildListenerTLSContext(listenerOpts.tlsSettings, in.Node, istionetworking.TransportProtocolTCP)
+	return &opt
+}
+
+// isPermissive returns true if the mTLS settings of a port accept both mTLS and plaintext.
+func isPermissive(mtlsConfigs []plugin.MTLSSettings) bool {
+	for _, mtlsConfig := range mtlsConfigs {
+		if mtlsConfig.Mode == model.MTLSPermissive {
+			return true
+		}
+	}
+	return false
+}
+
 func buildOutboundCatchAllNetworkFiltersOnly(push *model.PushContext, node *model.Proxy) []*listener.Filter {
 	var egressCluster string
 
@@ -709,7 +796,7 @@ func buildOutboundCatchAllNetworkFiltersOnly(push *model.PushContext, node *mode
 		StatPrefix:       egressCluster,
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: egressCluster},
 	}
-	filterStack := buildMetricsNetworkFilters(push, node, istionetworking.ListenerClassSidecarOutbound)
+	filterStack := buildPassthroughMetricsNetworkFilters(push, node, istionetworking.ListenerClassSidecarOutbound)
 	accessLogBuilder.setTCPAccessLog(push, node, tcpProxy)
 	filterStack = append(filterStack, &listener.Filter{
 		Name:       wellknown.TCPProxy,
@@ -744,7 +831,7 @@ func blackholeFilterChain(push *model.PushContext, node *model.Proxy) *listener.
 			DestinationPort: &wrappers.UInt32Value{Value: uint32(push.Mesh.ProxyListenPort)},
 		},
 		Filters: append(
-			buildMetricsNetworkFilters(push, node, istionetworking.ListenerClassSidecarOutbound),
+			buildPassthroughMetricsNetworkFilters(push, node, istionetworking.ListenerClassSidecarOutbound),
 			&listener.Filter{
 				Name: wellknown.TCPProxy,
 				ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(&tcp.TcpProxy{
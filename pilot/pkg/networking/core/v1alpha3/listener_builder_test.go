@@ -33,6 +33,7 @@ import (
 	istionetworking "istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/networking/util"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/protocol"
@@ -925,3 +926,95 @@ func (t TestAuthnPlugin) OnInboundPassthrough(in *plugin.InputParams, mutable *i
 func (t TestAuthnPlugin) InboundMTLSConfiguration(in *plugin.InputParams, passthrough bool) []plugin.MTLSSettings {
 	return t.mtlsSettings
 }
+
+func TestOrderListenerFilters(t *testing.T) {
+	deprecatedProxyProtocol := &listener.ListenerFilter{Name: "envoy.listener.proxy_protocol"}
+	custom := &listener.ListenerFilter{Name: "custom"}
+	names := func(fs []*listener.ListenerFilter) []string {
+		out := make([]string, 0, len(fs))
+		for _, f := range fs {
+			out = append(out, f.Name)
+		}
+		return out
+	}
+	cases := []struct {
+		name    string
+		filters []*listener.ListenerFilter
+		want    []string
+	}{
+		{
+			name:    "proxy protocol merged last",
+			filters: []*listener.ListenerFilter{xdsfilters.OriginalDestination, xdsfilters.TLSInspector, xdsfilters.HTTPInspector, xdsfilters.ProxyProtocol},
+			want:    []string{wellknown.ProxyProtocol, wellknown.OriginalDestination, wellknown.TlsInspector, wellknown.HttpInspector},
+		},
+		{
+			name:    "deprecated proxy protocol name",
+			filters: []*listener.ListenerFilter{xdsfilters.TLSInspector, deprecatedProxyProtocol},
+			want:    []string{deprecatedProxyProtocol.Name, wellknown.TlsInspector},
+		},
+		{
+			name: "filters inserted by EnvoyFilters keep their order",
+			filters: []*listener.ListenerFilter{
+				custom, xdsfilters.OriginalDestination, xdsfilters.TLSInspector, custom, xdsfilters.HTTPInspector, xdsfilters.ProxyProtocol, custom,
+			},
+			want: []string{
+				"custom", wellknown.ProxyProtocol, wellknown.OriginalDestination, wellknown.TlsInspector, "custom", wellknown.HttpInspector, "custom",
+			},
+		},
+		{
+			name:    "proxy protocol already first",
+			filters: []*listener.ListenerFilter{xdsfilters.ProxyProtocol, custom, xdsfilters.OriginalDestination, xdsfilters.TLSInspector},
+			want:    []string{wellknown.ProxyProtocol, "custom", wellknown.OriginalDestination, wellknown.TlsInspector},
+		},
+		{
+			name:    "without proxy protocol",
+			filters: []*listener.ListenerFilter{xdsfilters.HTTPInspector, xdsfilters.OriginalDestination},
+			want:    []string{wellknown.HttpInspector, wellknown.OriginalDestination},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &listener.Listener{ListenerFilters: tt.filters}
+			orderListenerFilters(l)
+			if got := names(l.ListenerFilters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVirtualInboundListenerProxyProtocolEnvoyFilter(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: proxy-protocol
+  namespace: istio-system
+spec:
+  configPatches:
+  - applyTo: LISTENER
+    match:
+      context: SIDECAR_INBOUND
+      listener:
+        name: virtualInbound
+    patch:
+      operation: MERGE
+      value:
+        listener_filters:
+        - name: envoy.filters.listener.proxy_protocol
+          typed_config:
+            "@type": type.googleapis.com/envoy.extensions.filters.listener.proxy_protocol.v3.ProxyProtocol
+`})
+	l := xdstest.ExtractListener(model.VirtualInboundListenerName, cg.Listeners(cg.SetupProxy(nil)))
+	if l == nil {
+		t.Fatalf("virtual inbound listener not found")
+	}
+	var got []string
+	for _, f := range l.ListenerFilters {
+		got = append(got, f.Name)
+	}
+	want := []string{wellknown.ProxyProtocol, wellknown.OriginalDestination, wellknown.TlsInspector, wellknown.HttpInspector}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got listener filters %v, want %v", got, want)
+	}
+}
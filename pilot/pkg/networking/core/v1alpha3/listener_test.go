@@ -23,6 +23,7 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	proxyprotocol "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/proxy_protocol/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
@@ -36,6 +37,7 @@ import (
 	"google.golang.org/protobuf/testing/protocmp"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
+	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
@@ -133,6 +135,55 @@ var (
 	}
 )
 
+// TestInboundListenerStableAcrossRouteAndClusterChanges checks that the inbound listeners do not depend on the
+// VirtualServices and DestinationRules of the services of the proxy, so that Envoy never drains their connections,
+// such as long-lived gRPC streams, on route-only or cluster-only config changes.
+func TestInboundListenerStableAcrossRouteAndClusterChanges(t *testing.T) {
+	for _, p := range []protocol.Instance{protocol.HTTP, protocol.GRPC, protocol.TCP} {
+		t.Run(string(p), func(t *testing.T) {
+			svc := buildService("test.com", "10.0.0.1", p, tnow)
+			vs := &config.Config{
+				Meta: config.Meta{Name: "vs", Namespace: "not-default", GroupVersionKind: gvk.VirtualService},
+				Spec: &networking.VirtualService{
+					Hosts: []string{"test.com"},
+					Http: []*networking.HTTPRoute{{
+						Route:   []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "test.com"}}},
+						Timeout: &types.Duration{Seconds: 5},
+					}},
+				},
+			}
+			dr := &config.Config{
+				Meta: config.Meta{Name: "dr", Namespace: "not-default", GroupVersionKind: gvk.DestinationRule},
+				Spec: &networking.DestinationRule{
+					Host: "test.com",
+					TrafficPolicy: &networking.TrafficPolicy{ConnectionPool: &networking.ConnectionPoolSettings{
+						Http: &networking.ConnectionPoolSettings_HTTPSettings{Http1MaxPendingRequests: 3},
+					}},
+				},
+			}
+			build := func(virtualServices, destinationRules []*config.Config) []*listener.Listener {
+				env := buildListenerEnvWithAdditionalConfig([]*model.Service{svc}, virtualServices, destinationRules)
+				if err := env.PushContext.InitContext(env, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+				proxy := getProxy()
+				proxy.SetServiceInstances(env)
+				proxy.IstioVersion = model.ParseIstioVersion(proxy.Metadata.IstioVersion)
+				proxy.SidecarScope = model.DefaultSidecarScopeForNamespace(env.PushContext, "not-default")
+				configgen := NewConfigGenerator([]plugin.Plugin{}, &model.DisabledCache{})
+				return configgen.buildSidecarInboundListeners(proxy, env.PushContext)
+			}
+			want := build(nil, nil)
+			if diff := cmp.Diff(want, build([]*config.Config{vs}, nil), protocmp.Transform()); diff != "" {
+				t.Errorf("inbound listeners changed with a VirtualService: %v", diff)
+			}
+			if diff := cmp.Diff(want, build(nil, []*config.Config{dr}), protocmp.Transform()); diff != "" {
+				t.Errorf("inbound listeners changed with a DestinationRule: %v", diff)
+			}
+		})
+	}
+}
+
 func TestInboundListenerConfig(t *testing.T) {
 	for _, p := range []*model.Proxy{getProxy(), &proxyHTTP10} {
 		testInboundListenerConfig(t, p,
@@ -149,6 +200,90 @@ func TestInboundListenerConfig(t *testing.T) {
 		buildService("test1.com", wildcardIP, protocol.GRPC, tnow.Add(1*time.Second)))
 }
 
+func TestInboundListenerExcludedPorts(t *testing.T) {
+	hasPort := func(l *listener.Listener, port uint32) bool {
+		for _, fc := range l.FilterChains {
+			if fc.GetFilterChainMatch().GetDestinationPort().GetValue() == port {
+				return true
+			}
+		}
+		return false
+	}
+	p := &fakePlugin{}
+	listeners := buildInboundListeners(t, p, getProxy(), nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if len(listeners) != 1 || !hasPort(listeners[0], 8080) {
+		t.Fatalf("expected a filter chain for port 8080")
+	}
+
+	proxy := getProxy()
+	proxy.Metadata.Annotations = map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "8080"}
+	listeners = buildInboundListeners(t, p, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	for _, l := range listeners {
+		if hasPort(l, 8080) {
+			t.Fatalf("expected no filter chain for excluded port 8080")
+		}
+	}
+}
+
+func TestInboundListenerTapFilter(t *testing.T) {
+	firstFilter := func(l *listener.Listener) string {
+		hcm := &hcm.HttpConnectionManager{}
+		if err := getFilterConfig(getHTTPFilter(getHTTPFilterChain(t, l)), hcm); err != nil {
+			t.Fatalf("failed to get HCM, config %v", hcm)
+		}
+		return hcm.HttpFilters[0].Name
+	}
+	p := &fakePlugin{}
+	listeners := buildInboundListeners(t, p, getProxy(), nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if got := firstFilter(listeners[0]); got == xdsfilters.TapFilterName {
+		t.Fatalf("unexpected tap filter")
+	}
+
+	proxy := getProxy()
+	proxy.Metadata.Annotations = map[string]string{xdsfilters.TapAnnotation: "true"}
+	listeners = buildInboundListeners(t, p, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if got := firstFilter(listeners[0]); got != xdsfilters.TapFilterName {
+		t.Fatalf("expected tap filter first, got %v", got)
+	}
+}
+
+func TestInboundListenerAdaptiveConcurrency(t *testing.T) {
+	hasFilter := func(l *listener.Listener) bool {
+		hcm := &hcm.HttpConnectionManager{}
+		if err := getFilterConfig(getHTTPFilter(getHTTPFilterChain(t, l)), hcm); err != nil {
+			t.Fatalf("failed to get HCM, config %v", hcm)
+		}
+		for _, f := range hcm.HttpFilters {
+			if f.Name == adaptiveConcurrencyFilter.Name {
+				return true
+			}
+		}
+		return false
+	}
+	p := &fakePlugin{}
+	listeners := buildInboundListeners(t, p, getProxy(), nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if hasFilter(listeners[0]) {
+		t.Fatalf("unexpected adaptive concurrency filter")
+	}
+
+	proxy := getProxy()
+	proxy.Metadata.Annotations = map[string]string{InboundAdaptiveConcurrencyAnnotation: "true"}
+	listeners = buildInboundListeners(t, p, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if !hasFilter(listeners[0]) {
+		t.Fatalf("expected adaptive concurrency filter")
+	}
+
+	defaultValue := features.EnableInboundAdaptiveConcurrency
+	features.EnableInboundAdaptiveConcurrency = true
+	defer func() { features.EnableInboundAdaptiveConcurrency = defaultValue }()
+	proxy = getProxy()
+	proxy.Metadata.Annotations = map[string]string{InboundAdaptiveConcurrencyAnnotation: "false"}
+	listeners = buildInboundListeners(t, p, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if hasFilter(listeners[0]) {
+		t.Fatalf("unexpected adaptive concurrency filter on an opted out workload")
+	}
+}
+
 func TestOutboundListenerConflict_HTTPWithCurrentUnknown(t *testing.T) {
 	defaultValue := features.EnableProtocolSniffingForOutbound
 	features.EnableProtocolSniffingForOutbound = true
@@ -334,6 +469,153 @@ func TestOutboundListenerConflict_TCPWithCurrentTCP(t *testing.T) {
 	verifyOutboundTCPListenerHostname(t, listeners[0], oldestService.Hostname)
 }
 
+func TestProxyProtocolFilter(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       []*proxyprotocol.ProxyProtocol_Rule
+	}{
+		{name: "no annotation"},
+		{
+			name:       "TLVs",
+			annotation: "0xE0=tenant_id, 225=region",
+			want: []*proxyprotocol.ProxyProtocol_Rule{
+				{TlvType: 0xE0, OnTlvPresent: &proxyprotocol.ProxyProtocol_KeyValuePair{MetadataNamespace: wellknown.ProxyProtocol, Key: "tenant_id"}},
+				{TlvType: 225, OnTlvPresent: &proxyprotocol.ProxyProtocol_KeyValuePair{MetadataNamespace: wellknown.ProxyProtocol, Key: "region"}},
+			},
+		},
+		{
+			name:       "invalid entries",
+			annotation: "0x100=tenant_id,0xE0,0xE1=,tenant=tenant_id",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &model.Proxy{Metadata: &model.NodeMetadata{Annotations: map[string]string{}}}
+			if tt.annotation != "" {
+				proxy.Metadata.Annotations[ProxyProtocolTLVsAnnotation] = tt.annotation
+			}
+			filter := proxyProtocolFilter(proxy)
+			if filter.Name != wellknown.ProxyProtocol {
+				t.Fatalf("unexpected filter %v", filter.Name)
+			}
+			proxyProtocol := &proxyprotocol.ProxyProtocol{}
+			if err := filter.GetTypedConfig().UnmarshalTo(proxyProtocol); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, proxyProtocol.Rules, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestListenerFiltersTimeout(t *testing.T) {
+	defaultOutbound, defaultOutboundSet := features.OutboundProtocolDetectionTimeout, features.OutboundProtocolDetectionTimeoutSet
+	defaultGateway, defaultGatewayContinue := features.GatewayListenerFiltersTimeout, features.GatewayContinueOnListenerFiltersTimeout
+	defer func() {
+		features.OutboundProtocolDetectionTimeout, features.OutboundProtocolDetectionTimeoutSet = defaultOutbound, defaultOutboundSet
+		features.GatewayListenerFiltersTimeout, features.GatewayContinueOnListenerFiltersTimeout = defaultGateway, defaultGatewayContinue
+	}()
+
+	m := mesh.DefaultMeshConfig()
+	m.ProtocolDetectionTimeout = types.DurationProto(100 * time.Millisecond)
+	push := &model.PushContext{Mesh: &m}
+	sidecar := &model.Proxy{Type: model.SidecarProxy}
+	gateway := &model.Proxy{Type: model.Router}
+	cases := []struct {
+		name         string
+		opts         buildListenerOpts
+		setup        func()
+		wantTimeout  time.Duration
+		wantContinue bool
+	}{
+		{
+			name:         "outbound mesh default",
+			opts:         buildListenerOpts{push: push, proxy: sidecar, class: istionetworking.ListenerClassSidecarOutbound},
+			wantTimeout:  100 * time.Millisecond,
+			wantContinue: true,
+		},
+		{
+			name: "outbound override",
+			opts: buildListenerOpts{push: push, proxy: sidecar, class: istionetworking.ListenerClassSidecarOutbound},
+			setup: func() {
+				features.OutboundProtocolDetectionTimeout, features.OutboundProtocolDetectionTimeoutSet = time.Second, true
+			},
+			wantTimeout:  time.Second,
+			wantContinue: true,
+		},
+		{
+			name: "gateway default",
+			opts: buildListenerOpts{push: push, proxy: gateway, class: istionetworking.ListenerClassGateway},
+		},
+		{
+			name: "gateway override",
+			opts: buildListenerOpts{push: push, proxy: gateway, class: istionetworking.ListenerClassGateway},
+			setup: func() {
+				features.GatewayListenerFiltersTimeout, features.GatewayContinueOnListenerFiltersTimeout = 5*time.Second, true
+			},
+			wantTimeout:  5 * time.Second,
+			wantContinue: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			timeout, continueOnTimeout := listenerFiltersTimeout(tt.opts)
+			if timeout.AsDuration() != tt.wantTimeout || continueOnTimeout != tt.wantContinue {
+				t.Fatalf("got timeout %v and continue %v, want %v and %v", timeout.AsDuration(), continueOnTimeout,
+					tt.wantTimeout, tt.wantContinue)
+			}
+		})
+	}
+}
+
+func TestOutboundListenerStableListenerFilters(t *testing.T) {
+	defaultValue := features.EnableStableOutboundListenerFilters
+	features.EnableStableOutboundListenerFilters = true
+	defer func() { features.EnableStableOutboundListenerFilters = defaultValue }()
+
+	listenerFilters := func(timeout *types.Duration, services ...*model.Service) []string {
+		m := mesh.DefaultMeshConfig()
+		m.ProtocolDetectionTimeout = timeout
+		cg := NewConfigGenTest(t, TestOptions{Services: services, MeshConfig: &m})
+		listeners := cg.ConfigGen.buildSidecarOutboundListeners(cg.SetupProxy(getProxy()), cg.env.PushContext)
+		xdstest.ValidateListeners(t, listeners)
+		if len(listeners) != 1 {
+			t.Fatalf("expected 1 listener, found %d", len(listeners))
+		}
+		var names []string
+		for _, f := range listeners[0].ListenerFilters {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	tlsService := buildService("tls.com", "10.10.0.0/24", protocol.TLS, tnow)
+	sniffedService := buildService("sniffed.com", "10.10.10.0/24", protocol.Unsupported, tnow.Add(time.Second))
+	want := []string{wellknown.TlsInspector, wellknown.HttpInspector}
+
+	// Adding a service requiring protocol sniffing does not change the listener filters.
+	if got := listenerFilters(&types.Duration{Seconds: 1}, tlsService); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected listener filters %v, got %v", want, got)
+	}
+	if got := listenerFilters(&types.Duration{Seconds: 1}, tlsService, sniffedService); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected listener filters %v, got %v", want, got)
+	}
+	// Without protocol detection timeout, the inspectors would stall server-first protocols.
+	if got := listenerFilters(&types.Duration{}, tlsService); !reflect.DeepEqual(got, []string{wellknown.TlsInspector}) {
+		t.Fatalf("expected only the TLS inspector without protocol detection timeout, got %v", got)
+	}
+	// The connections to the opaque TCP ports would wait out the timeout before the server sends its first bytes.
+	for _, p := range []protocol.Instance{protocol.TCP, protocol.MySQL} {
+		if got := listenerFilters(&types.Duration{Seconds: 1}, buildService("tcp.com", "10.10.0.0/24", p, tnow)); len(got) != 0 {
+			t.Fatalf("expected no listener filters for %s, got %v", p, got)
+		}
+	}
+}
+
 func TestOutboundListenerTCPWithVS(t *testing.T) {
 	tests := []struct {
 		name           string
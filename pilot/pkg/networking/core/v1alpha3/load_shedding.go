@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strconv"
+	"time"
+
+	adaptiveconcurrency "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/adaptive_concurrency/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/pkg/log"
+)
+
+// InboundAdaptiveConcurrencyAnnotation is the annotation of workloads enabling, when set to "true", or disabling,
+// when set to "false", the adaptive concurrency filter on the inbound HTTP filter chains of the sidecar. It overrides
+// PILOT_ENABLE_INBOUND_ADAPTIVE_CONCURRENCY. The filter limits the number of concurrent requests sent to the service
+// from its latency, and rejects the requests above the limit with 503 instead of queuing them. Its stats contain
+// "adaptive_concurrency", and can be exported with the sidecar.istio.io/statsInclusionRegexps annotation.
+const InboundAdaptiveConcurrencyAnnotation = "proxy.istio.io/inboundAdaptiveConcurrency"
+
+// Settings of the gradient controller of the adaptive concurrency filter. The limit is recomputed frequently from
+// the 90th percentile of the latency, compared to the latency measured periodically at a low concurrency.
+const (
+	adaptiveConcurrencyLatencyPercentile = 90
+	adaptiveConcurrencyUpdateInterval    = 100 * time.Millisecond
+	adaptiveConcurrencyMinRTTInterval    = time.Minute
+	adaptiveConcurrencyMinRTTRequests    = 50
+)
+
+// useInboundAdaptiveConcurrency returns whether the inbound HTTP filter chains of the sidecar shed load with the
+// adaptive concurrency filter. Invalid annotation values are ignored.
+func useInboundAdaptiveConcurrency(node *model.Proxy) bool {
+	if node.Type != model.SidecarProxy {
+		return false
+	}
+	v, f := node.Metadata.Annotations[InboundAdaptiveConcurrencyAnnotation]
+	if !f {
+		return features.EnableInboundAdaptiveConcurrency
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on proxy %s", InboundAdaptiveConcurrencyAnnotation, v, node.ID)
+		return features.EnableInboundAdaptiveConcurrency
+	}
+	return enabled
+}
+
+// adaptiveConcurrencyFilter is the adaptive concurrency filter of the inbound HTTP filter chains.
+var adaptiveConcurrencyFilter = &hcm.HttpFilter{
+	Name: "envoy.filters.http.adaptive_concurrency",
+	ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(&adaptiveconcurrency.AdaptiveConcurrency{
+		ConcurrencyControllerConfig: &adaptiveconcurrency.AdaptiveConcurrency_GradientControllerConfig{
+			GradientControllerConfig: &adaptiveconcurrency.GradientControllerConfig{
+				SampleAggregatePercentile: &xdstype.Percent{Value: adaptiveConcurrencyLatencyPercentile},
+				ConcurrencyLimitParams: &adaptiveconcurrency.GradientControllerConfig_ConcurrencyLimitCalculationParams{
+					ConcurrencyUpdateInterval: durationpb.New(adaptiveConcurrencyUpdateInterval),
+				},
+				MinRttCalcParams: &adaptiveconcurrency.GradientControllerConfig_MinimumRTTCalculationParams{
+					Interval:     durationpb.New(adaptiveConcurrencyMinRTTInterval),
+					RequestCount: &wrappers.UInt32Value{Value: adaptiveConcurrencyMinRTTRequests},
+				},
+			},
+		},
+	})},
+}
@@ -16,18 +16,139 @@
 package loadbalancer
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
 	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/log"
 )
 
+// ConsistentHashLocalityFailoverAnnotation is the annotation of DestinationRules keeping the affinity of consistent
+// hash load balancing with locality failover when set to "true". A priority then keeps all the traffic as long as
+// one of its endpoints is healthy, and the requests are hashed again among the endpoints of the next priority only
+// once all the endpoints of a priority are unhealthy. By default, Envoy spills a share of the traffic of a partially
+// unhealthy priority to the next one, which breaks the affinity of the requests of that share.
+const ConsistentHashLocalityFailoverAnnotation = "networking.istio.io/consistent-hash-locality-failover"
+
+// ConsistentHashLocalityFailoverForDestinationRule reads the ConsistentHashLocalityFailoverAnnotation of the
+// DestinationRule. Invalid values are ignored.
+func ConsistentHashLocalityFailoverForDestinationRule(dr *config.Config) bool {
+	if dr == nil {
+		return false
+	}
+	v, f := dr.Annotations[ConsistentHashLocalityFailoverAnnotation]
+	if !f {
+		return false
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", ConsistentHashLocalityFailoverAnnotation, v,
+			dr.Namespace, dr.Name)
+		return false
+	}
+	return enabled
+}
+
+// SubsetKeysAnnotation is the annotation of DestinationRules enabling the subset load balancer of Envoy on labels of
+// the endpoints. Its value is a semicolon separated list of subset selectors, each a comma separated list of label
+// keys, such as "shard" or "shard;region,shard". The values of these labels are added to the envoy.lb metadata of the
+// endpoints, so that requests are load balanced among the endpoints matching the metadata set for them, for instance
+// by a header to metadata filter. Requests matching no subset are load balanced among all the endpoints.
+const SubsetKeysAnnotation = "networking.istio.io/lb-subset-keys"
+
+// SubsetKeysForDestinationRule reads the SubsetKeysAnnotation of the DestinationRule. Invalid values are ignored.
+func SubsetKeysForDestinationRule(dr *config.Config) [][]string {
+	if dr == nil {
+		return nil
+	}
+	v, f := dr.Annotations[SubsetKeysAnnotation]
+	if !f {
+		return nil
+	}
+	selectors, err := parseSubsetKeys(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s: %v", SubsetKeysAnnotation, v,
+			dr.Namespace, dr.Name, err)
+		return nil
+	}
+	return selectors
+}
+
+func parseSubsetKeys(v string) ([][]string, error) {
+	var selectors [][]string
+	for _, selector := range strings.Split(v, ";") {
+		keys := strings.Split(selector, ",")
+		seen := map[string]bool{}
+		for i, key := range keys {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return nil, fmt.Errorf("empty label key in subset selector %q", selector)
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("duplicate label key %q in subset selector %q", key, selector)
+			}
+			seen[key] = true
+			keys[i] = key
+		}
+		selectors = append(selectors, keys)
+	}
+	return selectors, nil
+}
+
+// ApplySubsetConfig enables the subset load balancer of the cluster on the subset selectors.
+func ApplySubsetConfig(c *cluster.Cluster, selectors [][]string) {
+	if len(selectors) == 0 {
+		return
+	}
+	subsetConfig := &cluster.Cluster_LbSubsetConfig{
+		FallbackPolicy: cluster.Cluster_LbSubsetConfig_ANY_ENDPOINT,
+	}
+	for _, keys := range selectors {
+		subsetConfig.SubsetSelectors = append(subsetConfig.SubsetSelectors, &cluster.Cluster_LbSubsetConfig_LbSubsetSelector{Keys: keys})
+	}
+	c.LbSubsetConfig = subsetConfig
+}
+
+// ApplySubsetMetadata returns the endpoint with the values of its labels used by the subset selectors added to its
+// envoy.lb metadata. The endpoint is copied rather than modified, as it is shared by the clusters of the service.
+func ApplySubsetMetadata(ep *endpoint.LbEndpoint, labels map[string]string, selectors [][]string) *endpoint.LbEndpoint {
+	fields := map[string]*structpb.Value{}
+	for _, keys := range selectors {
+		for _, key := range keys {
+			if v, f := labels[key]; f {
+				fields[key] = structpb.NewStringValue(v)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return ep
+	}
+	out := proto.Clone(ep).(*endpoint.LbEndpoint)
+	if out.Metadata == nil {
+		out.Metadata = &core.Metadata{}
+	}
+	if out.Metadata.FilterMetadata == nil {
+		out.Metadata.FilterMetadata = map[string]*structpb.Struct{}
+	}
+	out.Metadata.FilterMetadata[util.EnvoyLbMetadataKey] = &structpb.Struct{Fields: fields}
+	return out
+}
+
 func GetLocalityLbSetting(
 	mesh *v1alpha3.LocalityLoadBalancerSetting,
 	destrule *v1alpha3.LocalityLoadBalancerSetting,
@@ -79,10 +200,51 @@ func ApplyLocalityLBSetting(
 			applyPriorityFailover(loadAssignment, wrappedLocalityLbEndpoints, proxyLabels, localityLB.FailoverPriority)
 			return
 		}
+		if len(localityLB.Failover) == 0 && len(localityCosts) > 0 {
+			applyLocalityCostFailover(locality, loadAssignment, localityCosts)
+			return
+		}
 		applyLocalityFailover(locality, loadAssignment, localityLB.Failover)
 	}
 }
 
+// ApplyConsistentHashLocalityFailover keeps the affinity of consistent hash load balancing across the priorities
+// set by the locality failover: it raises the overprovisioning factor of the load assignment so that a priority
+// with any healthy endpoint keeps all the traffic. It only applies to consistent hash load balancing with locality
+// failover, as set by ApplyLocalityLBSetting.
+func ApplyConsistentHashLocalityFailover(
+	loadAssignment *endpoint.ClusterLoadAssignment,
+	lb *v1alpha3.LoadBalancerSettings,
+	localityLB *v1alpha3.LocalityLoadBalancerSetting,
+	enableFailover bool,
+) {
+	if loadAssignment == nil || lb.GetConsistentHash() == nil || localityLB == nil || !enableFailover ||
+		localityLB.GetDistribute() != nil || (localityLB.Enabled != nil && !localityLB.Enabled.Value) {
+		return
+	}
+	// The health of a priority is its ratio of healthy endpoints multiplied by the overprovisioning factor, in
+	// percent. A factor of 100 per endpoint keeps a single healthy endpoint at full health.
+	endpoints := 0
+	for _, localityLbEndpoints := range loadAssignment.Endpoints {
+		endpoints += len(localityLbEndpoints.LbEndpoints)
+	}
+	factor := uint32(100 * endpoints)
+	if factor < defaultOverprovisioningFactor {
+		factor = defaultOverprovisioningFactor
+	}
+	// The policy may be shared with other load assignments, so it is replaced rather than mutated.
+	policy := &endpoint.ClusterLoadAssignment_Policy{}
+	if loadAssignment.Policy != nil {
+		policy.DropOverloads = loadAssignment.Policy.DropOverloads
+		policy.EndpointStaleAfter = loadAssignment.Policy.EndpointStaleAfter
+	}
+	policy.OverprovisioningFactor = &wrappers.UInt32Value{Value: factor}
+	loadAssignment.Policy = policy
+}
+
+// defaultOverprovisioningFactor is the overprovisioning factor of Envoy when unset.
+const defaultOverprovisioningFactor = 140
+
 // set locality loadbalancing weight
 func applyLocalityWeight(
 	locality *core.Locality,
@@ -193,6 +355,95 @@ func applyLocalityFailover(
 	}
 }
 
+// localityCost is the relative cost of the traffic from the proxies of a locality to the endpoints of another.
+type localityCost struct {
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Cost float64 `json:"cost"`
+}
+
+var localityCosts = func() []localityCost {
+	c, err := parseLocalityCosts(features.LocalityCosts)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_LOCALITY_COSTS: %v", err)
+	}
+	return c
+}()
+
+// parseLocalityCosts parses a JSON list of locality costs, for example
+// [{"from": "us-east1/us-east1-a", "to": "us-east1/*", "cost": 1}, {"from": "us-east1/*", "to": "us-west1/*", "cost": 5}].
+func parseLocalityCosts(config string) ([]localityCost, error) {
+	if config == "" {
+		return nil, nil
+	}
+	var costs []localityCost
+	if err := json.Unmarshal([]byte(config), &costs); err != nil {
+		return nil, err
+	}
+	for _, c := range costs {
+		if c.From == "" || c.To == "" {
+			return nil, fmt.Errorf("cost %v must have a from and a to locality", c)
+		}
+		if c.Cost < 0 {
+			return nil, fmt.Errorf("cost %v from %s to %s must not be negative", c.Cost, c.From, c.To)
+		}
+	}
+	return costs, nil
+}
+
+// costOf returns the cost of the first locality cost from the locality of the proxy to the locality of endpoints,
+// or the priority of their locality match.
+func costOf(locality, endpointsLocality *core.Locality, costs []localityCost) float64 {
+	// the same locality is never more expensive than the others
+	if util.LbPriority(locality, endpointsLocality) == 0 {
+		return 0
+	}
+	for _, c := range costs {
+		if util.LocalityMatch(locality, c.From) && util.LocalityMatch(endpointsLocality, c.To) {
+			return c.Cost
+		}
+	}
+	return float64(util.LbPriority(locality, endpointsLocality))
+}
+
+// costWeightScale is the load balancing weight of a locality of cost 0 per endpoint weight. The weight of a locality
+// is divided by one plus its cost.
+const costWeightScale = 100
+
+// set locality loadbalancing priority by increasing cost tier from the proxy locality, the integer part of the cost,
+// so that the traffic only fails over to a more expensive tier when the cheaper ones are unhealthy. The localities of
+// a tier share its priority, and are weighted by decreasing cost.
+func applyLocalityCostFailover(
+	locality *core.Locality,
+	loadAssignment *endpoint.ClusterLoadAssignment,
+	costs []localityCost) {
+	tiers := make([]float64, len(loadAssignment.Endpoints))
+	distinct := map[float64]bool{}
+	for i, localityEndpoint := range loadAssignment.Endpoints {
+		cost := costOf(locality, localityEndpoint.Locality, costs)
+		tiers[i] = math.Floor(cost)
+		distinct[tiers[i]] = true
+
+		originalWeight := uint32(1)
+		if localityEndpoint.LoadBalancingWeight != nil {
+			originalWeight = localityEndpoint.LoadBalancingWeight.Value
+		}
+		weight := math.Ceil(float64(originalWeight) * costWeightScale / (1 + cost))
+		if weight > math.MaxUint32 {
+			weight = math.MaxUint32
+		}
+		localityEndpoint.LoadBalancingWeight = &wrappers.UInt32Value{Value: uint32(weight)}
+	}
+	sorted := make([]float64, 0, len(distinct))
+	for tier := range distinct {
+		sorted = append(sorted, tier)
+	}
+	sort.Float64s(sorted)
+	for i, tier := range tiers {
+		loadAssignment.Endpoints[i].Priority = uint32(sort.SearchFloat64s(sorted, tier))
+	}
+}
+
 // WrappedLocalityLbEndpoints contain an envoy LocalityLbEndpoints
 // and the original IstioEndpoints used to generate it.
 // It is used to do failover priority label match with proxy labels.
@@ -29,6 +29,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/mesh"
@@ -497,6 +498,63 @@ func TestApplyLocalitySetting(t *testing.T) {
 	})
 }
 
+func TestApplyLocalityCostFailover(t *testing.T) {
+	locality := &core.Locality{Region: "region1", Zone: "zone1", SubZone: "subzone1"}
+	costs, err := parseLocalityCosts(`[
+  {"from": "region1/zone1", "to": "region1/zone1/subzone3", "cost": 1},
+  {"from": "region1/*", "to": "region1/zone2", "cost": 1.5},
+  {"from": "region1", "to": "region3", "cost": 2.5}
+]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultValue := localityCosts
+	localityCosts = costs
+	defer func() { localityCosts = defaultValue }()
+
+	t.Run("priorities by cost", func(t *testing.T) {
+		cluster := buildFakeCluster()
+		cluster.LoadAssignment.Endpoints[2].LoadBalancingWeight = &wrappers.UInt32Value{Value: 2}
+		ApplyLocalityLBSetting(cluster.LoadAssignment, nil, locality, nil, &networking.LocalityLoadBalancerSetting{}, true)
+		priorities := make([]int, 0)
+		weights := make([]int, 0)
+		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
+			priorities = append(priorities, int(localityEndpoint.Priority))
+			weights = append(weights, int(localityEndpoint.LoadBalancingWeight.GetValue()))
+		}
+		// region2 has no cost, and the cost 3 of a different region
+		if want := []int{0, 0, 1, 1, 1, 3, 2}; !reflect.DeepEqual(priorities, want) {
+			t.Errorf("Got priorities %v expected %v", priorities, want)
+		}
+		// within the priority of the costs 1 and 1.5, zone2 of cost 1.5 has a lower weight
+		if want := []int{100, 100, 100, 50, 40, 25, 29}; !reflect.DeepEqual(weights, want) {
+			t.Errorf("Got weights %v expected %v", weights, want)
+		}
+	})
+
+	t.Run("explicit failover", func(t *testing.T) {
+		cluster := buildFakeCluster()
+		ApplyLocalityLBSetting(cluster.LoadAssignment, nil, locality, nil, &networking.LocalityLoadBalancerSetting{
+			Failover: []*networking.LocalityLoadBalancerSetting_Failover{{From: "region1", To: "region2"}},
+		}, true)
+		priorities := make([]int, 0)
+		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
+			priorities = append(priorities, int(localityEndpoint.Priority))
+		}
+		if want := []int{0, 0, 1, 1, 2, 3, 4}; !reflect.DeepEqual(priorities, want) {
+			t.Errorf("Got priorities %v expected %v", priorities, want)
+		}
+	})
+
+	t.Run("invalid costs", func(t *testing.T) {
+		for _, config := range []string{`[{"from": "region1", "cost": 1}]`, `[{"from": "region1", "to": "region2", "cost": -1}]`, `{}`} {
+			if _, err := parseLocalityCosts(config); err == nil {
+				t.Errorf("expected %s to be invalid", config)
+			}
+		}
+	})
+}
+
 func TestGetLocalityLbSetting(t *testing.T) {
 	// dummy config for test
 	failover := []*networking.LocalityLoadBalancerSetting_Failover{nil}
@@ -792,6 +850,169 @@ func buildFakeCluster() *cluster.Cluster {
 	}
 }
 
+func TestApplyConsistentHashLocalityFailover(t *testing.T) {
+	consistentHash := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+			ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+			},
+		},
+	}
+	roundRobin := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+	}
+	failover := &networking.LocalityLoadBalancerSetting{}
+	distribute := &networking.LocalityLoadBalancerSetting{
+		Distribute: []*networking.LocalityLoadBalancerSetting_Distribute{{From: "region1/*", To: map[string]uint32{"region1/*": 100}}},
+	}
+	dropOverloads := []*endpoint.ClusterLoadAssignment_Policy_DropOverload{{Category: "throttle"}}
+	cases := []struct {
+		name           string
+		endpoints      int
+		policy         *endpoint.ClusterLoadAssignment_Policy
+		lb             *networking.LoadBalancerSettings
+		localityLB     *networking.LocalityLoadBalancerSetting
+		enableFailover bool
+		expected       *endpoint.ClusterLoadAssignment_Policy
+	}{
+		{
+			name:           "consistent hash with failover",
+			endpoints:      5,
+			lb:             consistentHash,
+			localityLB:     failover,
+			enableFailover: true,
+			expected:       &endpoint.ClusterLoadAssignment_Policy{OverprovisioningFactor: &wrappers.UInt32Value{Value: 500}},
+		},
+		{
+			name:           "keeps the other policy settings",
+			endpoints:      5,
+			policy:         &endpoint.ClusterLoadAssignment_Policy{DropOverloads: dropOverloads},
+			lb:             consistentHash,
+			localityLB:     failover,
+			enableFailover: true,
+			expected: &endpoint.ClusterLoadAssignment_Policy{
+				DropOverloads:          dropOverloads,
+				OverprovisioningFactor: &wrappers.UInt32Value{Value: 500},
+			},
+		},
+		{
+			name:           "single endpoint keeps the default factor",
+			endpoints:      1,
+			lb:             consistentHash,
+			localityLB:     failover,
+			enableFailover: true,
+			expected:       &endpoint.ClusterLoadAssignment_Policy{OverprovisioningFactor: &wrappers.UInt32Value{Value: 140}},
+		},
+		{
+			name:           "no consistent hash",
+			endpoints:      5,
+			lb:             roundRobin,
+			localityLB:     failover,
+			enableFailover: true,
+		},
+		{
+			name:       "no failover without outlier detection",
+			endpoints:  5,
+			lb:         consistentHash,
+			localityLB: failover,
+		},
+		{
+			name:           "no failover with distribute",
+			endpoints:      5,
+			lb:             consistentHash,
+			localityLB:     distribute,
+			enableFailover: true,
+		},
+		{
+			name:           "no locality load balancing",
+			endpoints:      5,
+			lb:             consistentHash,
+			enableFailover: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			loadAssignment := &endpoint.ClusterLoadAssignment{
+				Endpoints: []*endpoint.LocalityLbEndpoints{{Priority: 0}, {Priority: 1}},
+				Policy:    tt.policy,
+			}
+			for i := 0; i < tt.endpoints; i++ {
+				localityLbEndpoints := loadAssignment.Endpoints[i%2]
+				localityLbEndpoints.LbEndpoints = append(localityLbEndpoints.LbEndpoints, &endpoint.LbEndpoint{})
+			}
+			ApplyConsistentHashLocalityFailover(loadAssignment, tt.lb, tt.localityLB, tt.enableFailover)
+			expected := tt.expected
+			if expected == nil {
+				expected = tt.policy
+			}
+			if !reflect.DeepEqual(expected, loadAssignment.Policy) {
+				t.Fatalf("Expected: %v, got: %v", expected, loadAssignment.Policy)
+			}
+		})
+	}
+}
+
+func TestConsistentHashLocalityFailoverForDestinationRule(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{"unset", nil, false},
+		{"enabled", map[string]string{ConsistentHashLocalityFailoverAnnotation: "true"}, true},
+		{"disabled", map[string]string{ConsistentHashLocalityFailoverAnnotation: "false"}, false},
+		{"invalid", map[string]string{ConsistentHashLocalityFailoverAnnotation: "sticky"}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Name: "dr", Namespace: "default", Annotations: tt.annotations}}
+			if got := ConsistentHashLocalityFailoverForDestinationRule(dr); got != tt.expected {
+				t.Fatalf("Expected: %v, got: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSubsetKeysForDestinationRule(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		expected [][]string
+	}{
+		{"single key", "shard", [][]string{{"shard"}}},
+		{"selectors", "shard; region, shard", [][]string{{"shard"}, {"region", "shard"}}},
+		{"empty key", "shard,", nil},
+		{"duplicate key", "shard,shard", nil},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Name: "dr", Namespace: "default", Annotations: map[string]string{SubsetKeysAnnotation: tt.value}}}
+			if got := SubsetKeysForDestinationRule(dr); !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("Expected: %v, got: %v", tt.expected, got)
+			}
+		})
+	}
+	if got := SubsetKeysForDestinationRule(nil); got != nil {
+		t.Fatalf("Expected no subset keys without DestinationRule, got: %v", got)
+	}
+}
+
+func TestApplySubsetMetadata(t *testing.T) {
+	ep := &endpoint.LbEndpoint{}
+	selectors := [][]string{{"shard"}, {"region", "shard"}}
+	if got := ApplySubsetMetadata(ep, map[string]string{"app": "foo"}, selectors); got != ep {
+		t.Fatalf("Expected the endpoint without subset labels to be unchanged, got: %v", got)
+	}
+	got := ApplySubsetMetadata(ep, map[string]string{"app": "foo", "shard": "a"}, selectors)
+	if got == ep || ep.Metadata != nil {
+		t.Fatalf("Expected the endpoint to be copied")
+	}
+	fields := got.GetMetadata().GetFilterMetadata()[util.EnvoyLbMetadataKey].GetFields()
+	if len(fields) != 1 || fields["shard"].GetStringValue() != "a" {
+		t.Fatalf("Unexpected envoy.lb metadata: %v", fields)
+	}
+}
+
 func buildSmallCluster() *cluster.Cluster {
 	return &cluster.Cluster{
 		Name: "outbound|8080||test.example.org",
@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/log"
+)
+
+// Annotations of a DestinationRule configuring the Mongo proxy of the ports of its host with the mongo protocol.
+// The Mongo proxy decodes both the legacy wire operations and the OP_MSG messages of modern MongoDB versions. It has
+// no slow query threshold: the slow queries are found with the reply_time_ms histograms of the command-level and
+// collection-level stats, included with the sidecar.istio.io/statsInclusionPrefixes annotation of the pods.
+const (
+	// MongoCommandsAnnotation is the comma separated list of the commands, such as "find,aggregate,insert", producing
+	// command-level stats. Defaults to the delete, insert and update commands.
+	MongoCommandsAnnotation = "networking.istio.io/mongo-commands"
+	// MongoDynamicMetadataAnnotation, when "true", emits the operation and the collection of each message as dynamic
+	// metadata, for telemetry and authorization.
+	MongoDynamicMetadataAnnotation = "networking.istio.io/mongo-dynamic-metadata"
+	// MongoAccessLogAnnotation, when "true", logs the operations to the standard output of the proxy. The path of the
+	// access log is not configurable, so that the DestinationRule authors cannot have the proxies write to any file.
+	MongoAccessLogAnnotation = "networking.istio.io/mongo-access-log"
+)
+
+// mongoAccessLogPath is the path of the access log of the Mongo proxy, when enabled.
+const mongoAccessLogPath = "/dev/stdout"
+
+// mongoSettings are the Mongo proxy settings read from the annotations of a DestinationRule.
+type mongoSettings struct {
+	commands        []string
+	dynamicMetadata bool
+	accessLog       string
+}
+
+// mongoSettingsForDestinationRule reads the Mongo proxy settings from the annotations of the DestinationRule.
+// Invalid values are ignored.
+func mongoSettingsForDestinationRule(dr *config.Config) mongoSettings {
+	s := mongoSettings{}
+	if dr == nil {
+		return s
+	}
+	a := dr.Annotations
+	if v, f := a[MongoCommandsAnnotation]; f {
+		for _, command := range strings.Split(v, ",") {
+			if command = strings.TrimSpace(command); command != "" {
+				s.commands = append(s.commands, command)
+			}
+		}
+	}
+	if v, f := a[MongoDynamicMetadataAnnotation]; f {
+		if b, err := strconv.ParseBool(v); err == nil {
+			s.dynamicMetadata = b
+		} else {
+			log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", MongoDynamicMetadataAnnotation, v, dr.Namespace, dr.Name)
+		}
+	}
+	if v, f := a[MongoAccessLogAnnotation]; f {
+		if b, err := strconv.ParseBool(v); err != nil {
+			log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", MongoAccessLogAnnotation, v, dr.Namespace, dr.Name)
+		} else if b {
+			s.accessLog = mongoAccessLogPath
+		}
+	}
+	return s
+}
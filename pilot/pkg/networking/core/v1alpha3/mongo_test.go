@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"reflect"
+	"testing"
+
+	mongo "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/mongo_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestMongoSettingsForDestinationRule(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        mongoSettings
+	}{
+		{
+			name: "defaults",
+			want: mongoSettings{},
+		},
+		{
+			name: "all set",
+			annotations: map[string]string{
+				MongoCommandsAnnotation:        "find, aggregate,,insert",
+				MongoDynamicMetadataAnnotation: "true",
+				MongoAccessLogAnnotation:       "true",
+			},
+			want: mongoSettings{
+				commands:        []string{"find", "aggregate", "insert"},
+				dynamicMetadata: true,
+				accessLog:       "/dev/stdout",
+			},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				MongoDynamicMetadataAnnotation: "maybe",
+				MongoAccessLogAnnotation:       "/etc/istio/proxy/envoy-rev0.json",
+			},
+			want: mongoSettings{},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Name: "mongo", Namespace: "default", Annotations: tt.annotations}}
+			if got := mongoSettingsForDestinationRule(dr); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+const mongoConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: mongo
+  namespace: default
+spec:
+  hosts:
+  - mongo.default.svc.cluster.local
+  addresses:
+  - 1.1.1.1
+  ports:
+  - number: 27017
+    name: mongo
+    protocol: MONGO
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: mongo
+  namespace: default
+  annotations:
+    networking.istio.io/mongo-commands: find,aggregate
+    networking.istio.io/mongo-dynamic-metadata: "true"
+    networking.istio.io/mongo-access-log: "true"
+spec:
+  host: mongo.default.svc.cluster.local
+`
+
+func TestMongoFilter(t *testing.T) {
+	defaultValue := features.EnableMongoFilter
+	features.EnableMongoFilter = true
+	defer func() { features.EnableMongoFilter = defaultValue }()
+
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: mongoConfig})
+	proxy := cg.SetupProxy(nil)
+
+	l := xdstest.ExtractListener("1.1.1.1_27017", cg.Listeners(proxy))
+	if l == nil {
+		t.Fatalf("mongo listener not found")
+	}
+	var proxyConfig *mongo.MongoProxy
+	for _, f := range l.GetFilterChains()[0].GetFilters() {
+		if f.GetName() == wellknown.MongoProxy {
+			proxyConfig = &mongo.MongoProxy{}
+			if err := f.GetTypedConfig().UnmarshalTo(proxyConfig); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if proxyConfig == nil {
+		t.Fatalf("mongo proxy filter not found")
+	}
+	assert.Equal(t, proxyConfig.GetCommands(), []string{"find", "aggregate"})
+	assert.Equal(t, proxyConfig.GetEmitDynamicMetadata(), true)
+	assert.Equal(t, proxyConfig.GetAccessLog(), "/dev/stdout")
+}
@@ -15,9 +15,12 @@
 package v1alpha3
 
 import (
+	"net"
+	"strconv"
 	"time"
 
 	mysql "github.com/envoyproxy/go-control-plane/contrib/envoy/extensions/filters/network/mysql_proxy/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	mongo "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/mongo_proxy/v3"
 	redis "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/redis_proxy/v3"
@@ -58,6 +61,12 @@ func buildMetricsNetworkFilters(push *model.PushContext, proxy *model.Proxy, cla
 	return push.Telemetry.TCPFilters(proxy, class)
 }
 
+// buildPassthroughMetricsNetworkFilters builds the metrics filters of the passthrough and blackhole filter chains,
+// which attribute the traffic to its original destination.
+func buildPassthroughMetricsNetworkFilters(push *model.PushContext, proxy *model.Proxy, class istionetworking.ListenerClass) []*listener.Filter {
+	return push.Telemetry.PassthroughTCPFilters(proxy, class)
+}
+
 // buildInboundNetworkFilters generates a TCP proxy network filter on the inbound path
 func buildInboundNetworkFilters(push *model.PushContext, proxy *model.Proxy, instance *model.ServiceInstance, clusterName string) []*listener.Filter {
 	statPrefix := clusterName
@@ -70,16 +79,17 @@ func buildInboundNetworkFilters(push *model.PushContext, proxy *model.Proxy, ins
 		StatPrefix:       statPrefix,
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: clusterName},
 	}
-	idleTimeout, err := time.ParseDuration(proxy.Metadata.IdleTimeout)
-	if err == nil {
-		tcpProxy.IdleTimeout = durationpb.New(idleTimeout)
+	port := instance.ServicePort.Port
+	if instance.Endpoint != nil {
+		port = int(instance.Endpoint.EndpointPort)
 	}
+	applyTCPProxyTimeouts(proxy, port, tcpProxy)
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, proxy, tcpProxy)
 
 	var filters []*listener.Filter
 	filters = append(filters, buildMetadataExchangeNetworkFilters(push, istionetworking.ListenerClassSidecarInbound, proxy.IstioVersion)...)
 	filters = append(filters, buildMetricsNetworkFilters(push, proxy, istionetworking.ListenerClassSidecarInbound)...)
-	filters = append(filters, buildNetworkFiltersStack(instance.ServicePort, tcpFilter, statPrefix, clusterName)...)
+	filters = append(filters, buildNetworkFiltersStack(push, proxy, instance.ServicePort, tcpFilter, statPrefix, clusterName)...)
 	return filters
 }
 
@@ -103,18 +113,50 @@ func buildOutboundNetworkFiltersWithSingleDestination(push *model.PushContext, n
 		StatPrefix:       statPrefix,
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: clusterName},
 	}
+	maybeSetHashPolicy(destinationRule, tcpProxy, subsetName)
+	return buildOutboundNetworkFiltersWithTCPProxy(push, node, statPrefix, clusterName, port, tcpProxy)
+}
 
-	idleTimeout, err := time.ParseDuration(node.Metadata.IdleTimeout)
-	if err == nil {
-		tcpProxy.IdleTimeout = durationpb.New(idleTimeout)
+// buildOutboundEgressProxyNetworkFilters builds a stack of network filters tunneling the connections to a port of
+// an external service through the egress proxy of the sidecar, with a CONNECT request to the host and port carrying
+// the credentials of the proxy.
+func buildOutboundEgressProxyNetworkFilters(push *model.PushContext, node *model.Proxy,
+	statPrefix string, service *model.Service, servicePort int, port *model.Port) []*listener.Filter {
+	clusterName := egressProxyClusterName(node.SidecarScope.EgressProxy)
+	tcpProxy := &tcp.TcpProxy{
+		StatPrefix:       statPrefix,
+		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: clusterName},
+		TunnelingConfig: &tcp.TcpProxy_TunnelingConfig{
+			Hostname: net.JoinHostPort(string(service.Hostname), strconv.Itoa(servicePort)),
+		},
+	}
+	if authorization := egressProxyAuthorization(node, push); authorization != nil {
+		tcpProxy.TunnelingConfig.HeadersToAdd = []*core.HeaderValueOption{authorization}
+	}
+	return buildOutboundNetworkFiltersWithTCPProxy(push, node, statPrefix, clusterName, port, tcpProxy)
+}
+
+// useEgressProxy returns whether the connections to the external service are tunneled through the egress proxy
+// of the sidecar. Wildcard hosts cannot be tunneled, as the proxy needs the host to connect to.
+func useEgressProxy(node *model.Proxy, service *model.Service) bool {
+	return node.Type == model.SidecarProxy && node.SidecarScope != nil && node.SidecarScope.EgressProxy != nil &&
+		service != nil && service.MeshExternal && !service.Hostname.IsWildCarded()
+}
+
+// buildOutboundNetworkFiltersWithTCPProxy builds a stack of network filters ending with the TCP proxy.
+func buildOutboundNetworkFiltersWithTCPProxy(push *model.PushContext, node *model.Proxy,
+	statPrefix, clusterName string, port *model.Port, tcpProxy *tcp.TcpProxy) []*listener.Filter {
+	if port != nil {
+		applyTCPProxyTimeouts(node, port.Port, tcpProxy)
+	} else {
+		applyTCPProxyTimeouts(node, 0, tcpProxy)
 	}
-	maybeSetHashPolicy(destinationRule, tcpProxy, subsetName)
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, node, tcpProxy)
 
 	var filters []*listener.Filter
 	filters = append(filters, buildMetadataExchangeNetworkFilters(push, model.OutboundListenerClass(node.Type), node.IstioVersion)...)
 	filters = append(filters, buildMetricsNetworkFilters(push, node, model.OutboundListenerClass(node.Type))...)
-	filters = append(filters, buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)...)
+	filters = append(filters, buildNetworkFiltersStack(push, node, port, tcpFilter, statPrefix, clusterName)...)
 	return filters
 }
 
@@ -132,10 +174,7 @@ func buildOutboundNetworkFiltersWithWeightedClusters(node *model.Proxy, routes [
 		ClusterSpecifier: clusterSpecifier,
 	}
 
-	idleTimeout, err := time.ParseDuration(node.Metadata.IdleTimeout)
-	if err == nil {
-		tcpProxy.IdleTimeout = durationpb.New(idleTimeout)
-	}
+	applyTCPProxyTimeouts(node, port.Port, tcpProxy)
 
 	for _, route := range routes {
 		service := push.ServiceForHostname(node, host.Name(route.Destination.Host))
@@ -158,7 +197,7 @@ func buildOutboundNetworkFiltersWithWeightedClusters(node *model.Proxy, routes [
 	var filters []*listener.Filter
 	filters = append(filters, buildMetadataExchangeNetworkFilters(push, model.OutboundListenerClass(node.Type), node.IstioVersion)...)
 	filters = append(filters, buildMetricsNetworkFilters(push, node, model.OutboundListenerClass(node.Type))...)
-	filters = append(filters, buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)...)
+	filters = append(filters, buildNetworkFiltersStack(push, node, port, tcpFilter, statPrefix, clusterName)...)
 	return filters
 }
 
@@ -191,19 +230,22 @@ func maybeSetHashPolicy(destinationRule *networking.DestinationRule, tcpProxy *t
 
 // buildNetworkFiltersStack builds a slice of network filters based on
 // the protocol in use and the given TCP filter instance.
-func buildNetworkFiltersStack(port *model.Port, tcpFilter *listener.Filter, statPrefix string, clusterName string) []*listener.Filter {
+func buildNetworkFiltersStack(push *model.PushContext, node *model.Proxy, port *model.Port, tcpFilter *listener.Filter,
+	statPrefix string, clusterName string) []*listener.Filter {
 	filterstack := make([]*listener.Filter, 0)
 	switch port.Protocol {
 	case protocol.Mongo:
 		if features.EnableMongoFilter {
-			filterstack = append(filterstack, buildMongoFilter(statPrefix), tcpFilter)
+			settings := mongoSettingsForDestinationRule(destinationRuleForCluster(push, node, clusterName))
+			filterstack = append(filterstack, buildMongoFilter(statPrefix, settings), tcpFilter)
 		} else {
 			filterstack = append(filterstack, tcpFilter)
 		}
 	case protocol.Redis:
 		if features.EnableRedisFilter {
 			// redis filter has route config, it is a terminating filter, no need append tcp filter.
-			filterstack = append(filterstack, buildRedisFilter(statPrefix, clusterName))
+			settings := redisSettingsForCluster(push, node, clusterName)
+			filterstack = append(filterstack, buildRedisFilter(statPrefix, clusterName, port, settings))
 		} else {
 			filterstack = append(filterstack, tcpFilter)
 		}
@@ -243,13 +285,16 @@ func buildOutboundNetworkFilters(node *model.Proxy,
 }
 
 // buildMongoFilter builds an outbound Envoy MongoProxy filter.
-func buildMongoFilter(statPrefix string) *listener.Filter {
+func buildMongoFilter(statPrefix string, settings mongoSettings) *listener.Filter {
 	// TODO: add a watcher for /var/lib/istio/mongo/certs
 	// if certs are found use, TLS or mTLS clusters for talking to MongoDB.
 	// User is responsible for mounting those certs in the pod.
 	mongoProxy := &mongo.MongoProxy{
 		StatPrefix: statPrefix, // mongo stats are prefixed with mongo.<statPrefix> by Envoy
 		// TODO enable faults in mongo
+		Commands:            settings.commands,
+		EmitDynamicMetadata: settings.dynamicMetadata,
+		AccessLog:           settings.accessLog,
 	}
 
 	out := &listener.Filter{
@@ -278,17 +323,28 @@ func buildOutboundAutoPassthroughFilterStack(push *model.PushContext, node *mode
 // buildRedisFilter builds an outbound Envoy RedisProxy filter.
 // Currently, if multiple clusters are defined, one of them will be picked for
 // configuring the Redis proxy.
-func buildRedisFilter(statPrefix, clusterName string) *listener.Filter {
+func buildRedisFilter(statPrefix, clusterName string, port *model.Port, settings redisSettings) *listener.Filter {
+	route := &redis.RedisProxy_PrefixRoutes_Route{
+		Cluster: clusterName,
+	}
+	if settings.mirrorHost != "" {
+		route.RequestMirrorPolicy = []*redis.RedisProxy_PrefixRoutes_Route_RequestMirrorPolicy{{
+			Cluster:             model.BuildSubsetKey(model.TrafficDirectionOutbound, "", settings.mirrorHost, port.Port),
+			ExcludeReadCommands: settings.mirrorExcludeReads,
+		}}
+	}
 	redisProxy := &redis.RedisProxy{
 		LatencyInMicros: true,       // redis latency stats are captured in micro seconds which is typically the case.
 		StatPrefix:      statPrefix, // redis stats are prefixed with redis.<statPrefix> by Envoy
 		Settings: &redis.RedisProxy_ConnPoolSettings{
-			OpTimeout: durationpb.New(redisOpTimeout), // TODO: Make this user configurable
+			OpTimeout: durationpb.New(settings.opTimeout),
+			// With a Redis Cluster, commands are sent to the node owning their key. Hash tags allow multi key
+			// commands on keys served by the same node, and redirections are followed while the topology changes.
+			EnableHashtagging: settings.cluster,
+			EnableRedirection: settings.cluster,
 		},
 		PrefixRoutes: &redis.RedisProxy_PrefixRoutes{
-			CatchAllRoute: &redis.RedisProxy_PrefixRoutes_Route{
-				Cluster: clusterName,
-			},
+			CatchAllRoute: route,
 		},
 	}
 
@@ -33,7 +33,7 @@ import (
 )
 
 func TestBuildRedisFilter(t *testing.T) {
-	redisFilter := buildRedisFilter("redis", "redis-cluster")
+	redisFilter := buildRedisFilter("redis", "redis-cluster", &model.Port{Port: 6379}, defaultRedisSettings())
 	if redisFilter.Name != wellknown.RedisProxy {
 		t.Errorf("redis filter name is %s not %s", redisFilter.Name, wellknown.RedisProxy)
 	}
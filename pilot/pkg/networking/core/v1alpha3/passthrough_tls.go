@@ -0,0 +1,218 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	any "google.golang.org/protobuf/types/known/anypb"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	istionetworking "istio.io/istio/pilot/pkg/networking"
+	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
+	"istio.io/istio/pilot/pkg/networking/util"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/proto"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/util/gogo"
+	"istio.io/pkg/log"
+)
+
+const (
+	// passthroughPlaintextTLSStatPrefix is the stat prefix of the plaintext HTTP sent to unknown destinations on
+	// ports which only accept TLS.
+	passthroughPlaintextTLSStatPrefix = "PassthroughPlaintextTLS"
+	// passthroughTLSOriginationCACertificates are the OS CA certificates of the proxy image, which verify the
+	// certificates of the servers TLS is originated to.
+	passthroughTLSOriginationCACertificates = "/etc/ssl/certs/ca-certificates.crt"
+)
+
+var passthroughPlaintextTLSPorts = func() []int {
+	p, err := parsePassthroughPlaintextTLSPorts(features.PassthroughPlaintextTLSPorts)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_PASSTHROUGH_PLAINTEXT_TLS_PORTS: %v", err)
+	}
+	return p
+}()
+
+// passthroughTLSOriginationHTTPProtocolOptions set the SNI of the upstream connections of the
+// PassthroughTLSOriginationCluster from the Host header, and verify the certificate of the server against it.
+var passthroughTLSOriginationHTTPProtocolOptions = util.MessageToAny(&http.HttpProtocolOptions{
+	UpstreamHttpProtocolOptions: &core.UpstreamHttpProtocolOptions{
+		AutoSni:           true,
+		AutoSanValidation: true,
+	},
+	UpstreamProtocolOptions: &http.HttpProtocolOptions_ExplicitHttpConfig_{
+		ExplicitHttpConfig: &http.HttpProtocolOptions_ExplicitHttpConfig{
+			ProtocolConfig: &http.HttpProtocolOptions_ExplicitHttpConfig_HttpProtocolOptions{},
+		},
+	},
+})
+
+// parsePassthroughPlaintextTLSPorts parses a comma separated list of ports, such as "443,8443".
+func parsePassthroughPlaintextTLSPorts(config string) ([]int, error) {
+	if config == "" {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	var ports []int
+	for _, p := range strings.Split(config, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// detectPassthroughPlaintextTLS returns whether the plaintext HTTP sent by the proxy to unknown destinations on the
+// passthroughPlaintextTLSPorts is detected. It is only sent to the PassthroughCluster with the ALLOW_ANY outbound
+// traffic policy, without an egress proxy.
+func detectPassthroughPlaintextTLS(node *model.Proxy) bool {
+	return len(passthroughPlaintextTLSPorts) > 0 && node.Type == model.SidecarProxy && util.IsAllowAnyOutbound(node) &&
+		node.SidecarScope.OutboundTrafficPolicy.EgressProxy == nil
+}
+
+// buildPassthroughPlaintextTLSFilterChains builds the filter chains of the virtual outbound listener catching the
+// plaintext HTTP sent to unknown destinations on the passthroughPlaintextTLSPorts. Such requests usually fail
+// with opaque connection resets, as the application expected the sidecar to originate TLS. They are counted with
+// the PassthroughPlaintextTLS stat prefix, and either passed through or originated as TLS.
+func buildPassthroughPlaintextTLSFilterChains(push *model.PushContext, node *model.Proxy) []*listener.FilterChain {
+	if !detectPassthroughPlaintextTLS(node) {
+		return nil
+	}
+	var filters []*listener.Filter
+	if node.SidecarScope.PassthroughTLSOrigination {
+		filters = []*listener.Filter{buildPassthroughTLSOriginationFilter(push, node)}
+	} else {
+		tcpProxy := &tcp.TcpProxy{
+			StatPrefix:       passthroughPlaintextTLSStatPrefix,
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: util.PassthroughCluster},
+		}
+		accessLogBuilder.setTCPAccessLog(push, node, tcpProxy)
+		filters = append(buildPassthroughMetricsNetworkFilters(push, node, istionetworking.ListenerClassSidecarOutbound), &listener.Filter{
+			Name:       wellknown.TCPProxy,
+			ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(tcpProxy)},
+		})
+	}
+	chains := make([]*listener.FilterChain, 0, len(passthroughPlaintextTLSPorts))
+	for _, port := range passthroughPlaintextTLSPorts {
+		chains = append(chains, &listener.FilterChain{
+			Name: util.PassthroughPlaintextTLSFilterChain + "_" + strconv.Itoa(port),
+			FilterChainMatch: &listener.FilterChainMatch{
+				DestinationPort:      &wrappers.UInt32Value{Value: uint32(port)},
+				TransportProtocol:    xdsfilters.RawBufferTransportProtocol,
+				ApplicationProtocols: plaintextHTTPALPNs,
+			},
+			Filters: filters,
+		})
+	}
+	return chains
+}
+
+// buildPassthroughTLSOriginationFilter builds the HTTP connection manager routing the plaintext HTTP detected on the
+// passthroughPlaintextTLSPorts to the PassthroughTLSOriginationCluster.
+func buildPassthroughTLSOriginationFilter(push *model.PushContext, node *model.Proxy) *listener.Filter {
+	opts := buildListenerOpts{push: push, proxy: node, class: istionetworking.ListenerClassSidecarOutbound}
+	connectionManager := buildHTTPConnectionManager(opts, &httpListenerOpts{
+		statPrefix: passthroughPlaintextTLSStatPrefix,
+		routeConfig: &route.RouteConfiguration{
+			Name: util.PassthroughTLSOriginationCluster,
+			VirtualHosts: []*route.VirtualHost{{
+				Name:    util.PassthroughTLSOriginationCluster,
+				Domains: []string{"*"},
+				Routes: []*route.Route{
+					istio_route.BuildDefaultHTTPOutboundRoute(util.PassthroughTLSOriginationCluster, util.PassthroughTLSOriginationCluster, push.Mesh),
+				},
+			}},
+			ValidateClusters: proto.BoolFalse,
+		},
+	}, nil)
+	return &listener.Filter{
+		Name:       wellknown.HTTPConnectionManager,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(connectionManager)},
+	}
+}
+
+// passthroughPlaintextTLSListenerFilters returns the TLS and HTTP inspectors detecting the plaintext HTTP on the
+// passthroughPlaintextTLSPorts. They are disabled on the other ports, so that the passthrough traffic is not delayed.
+func passthroughPlaintextTLSListenerFilters() []*listener.ListenerFilter {
+	predicate := listenerPredicateIncludePorts(passthroughPlaintextTLSPorts)
+	return []*listener.ListenerFilter{
+		{
+			Name:           wellknown.TlsInspector,
+			ConfigType:     xdsfilters.TLSInspector.ConfigType,
+			FilterDisabled: predicate,
+		},
+		{
+			Name:           wellknown.HttpInspector,
+			ConfigType:     xdsfilters.HTTPInspector.ConfigType,
+			FilterDisabled: predicate,
+		},
+	}
+}
+
+// buildPassthroughTLSOriginationCluster generates a cluster originating TLS to the original destination of the
+// plaintext HTTP detected on the passthroughPlaintextTLSPorts. The SNI is set from the Host header, and the
+// certificate of the server is verified against the OS CA certificates and the Host header.
+func (cb *ClusterBuilder) buildPassthroughTLSOriginationCluster() *cluster.Cluster {
+	res := security.SdsCertificateConfig{CaCertificatePath: passthroughTLSOriginationCACertificates}
+	tlsContext := &auth.UpstreamTlsContext{
+		CommonTlsContext: &auth.CommonTlsContext{
+			ValidationContextType: &auth.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &auth.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext:         &auth.CertificateValidationContext{},
+					ValidationContextSdsSecretConfig: authn_model.ConstructSdsSecretConfig(res.GetRootResourceName()),
+				},
+			},
+		},
+	}
+	c := &cluster.Cluster{
+		Name:                 util.PassthroughTLSOriginationCluster,
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_ORIGINAL_DST},
+		ConnectTimeout:       gogo.DurationToProtoDuration(cb.req.Push.Mesh.ConnectTimeout),
+		LbPolicy:             cluster.Cluster_CLUSTER_PROVIDED,
+		TypedExtensionProtocolOptions: map[string]*any.Any{
+			v3.HttpProtocolOptionsType: passthroughTLSOriginationHTTPProtocolOptions,
+		},
+		TransportSocket: &core.TransportSocket{
+			Name:       util.EnvoyTLSSocketName,
+			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(tlsContext)},
+		},
+	}
+	cb.applyConnectionPool(cb.req.Push.Mesh, NewMutableCluster(c), &networking.ConnectionPoolSettings{})
+	return c
+}
@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestParsePassthroughPlaintextTLSPorts(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{name: "empty", in: ""},
+		{name: "ports", in: "8443, 443,443", want: []int{443, 8443}},
+		{name: "invalid port", in: "443,https", wantErr: true},
+		{name: "out of range", in: "70000", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePassthroughPlaintextTLSPorts(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPassthroughPlaintextTLS(t *testing.T) {
+	defaultPorts := passthroughPlaintextTLSPorts
+	passthroughPlaintextTLSPorts = []int{443}
+	defer func() { passthroughPlaintextTLSPorts = defaultPorts }()
+	tlsOriginationSidecar := `
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: default
+  namespace: istio-system
+  annotations:
+    networking.istio.io/passthrough-tls-origination: "true"
+spec:
+  egress:
+  - hosts:
+    - "*/*"
+`
+
+	virtualOutbound := func(t *testing.T, configs string) (*listener.Listener, *listener.FilterChain) {
+		t.Helper()
+		cg := NewConfigGenTest(t, TestOptions{ConfigString: configs})
+		l := xdstest.ExtractListener(model.VirtualOutboundListenerName, cg.Listeners(cg.SetupProxy(nil)))
+		if l == nil {
+			t.Fatalf("virtual outbound listener not found")
+		}
+		for _, fc := range l.FilterChains {
+			if fc.Name == util.PassthroughPlaintextTLSFilterChain+"_443" {
+				return l, fc
+			}
+		}
+		t.Fatalf("plaintext TLS filter chain not found in %v", l.FilterChains)
+		return nil, nil
+	}
+
+	t.Run("passthrough", func(t *testing.T) {
+		l, fc := virtualOutbound(t, "")
+		if got := fc.FilterChainMatch.GetDestinationPort().GetValue(); got != 443 {
+			t.Fatalf("got destination port %d, want 443", got)
+		}
+		tcpProxy := xdstest.ExtractTCPProxy(t, fc)
+		if tcpProxy.StatPrefix != passthroughPlaintextTLSStatPrefix || tcpProxy.GetCluster() != util.PassthroughCluster {
+			t.Fatalf("unexpected tcp proxy: %v", tcpProxy)
+		}
+		if l.DefaultFilterChain.GetName() != util.PassthroughFilterChain {
+			t.Fatalf("expected the passthrough default filter chain, got %v", l.DefaultFilterChain)
+		}
+		filters := xdstest.ExtractListenerFilters(l)
+		if filters[wellknown.TlsInspector] == nil || filters[wellknown.HttpInspector] == nil {
+			t.Fatalf("expected the TLS and HTTP inspectors, got %v", filters)
+		}
+	})
+
+	t.Run("origination", func(t *testing.T) {
+		_, fc := virtualOutbound(t, tlsOriginationSidecar)
+		connectionManager := xdstest.ExtractHTTPConnectionManager(t, fc)
+		routes := connectionManager.GetRouteConfig().GetVirtualHosts()[0].GetRoutes()
+		if got := routes[0].GetRoute().GetCluster(); got != util.PassthroughTLSOriginationCluster {
+			t.Fatalf("got cluster %q, want %q", got, util.PassthroughTLSOriginationCluster)
+		}
+		cg := NewConfigGenTest(t, TestOptions{ConfigString: tlsOriginationSidecar})
+		c := xdstest.ExtractCluster(util.PassthroughTLSOriginationCluster, cg.Clusters(cg.SetupProxy(nil)))
+		if c == nil || c.TransportSocket == nil {
+			t.Fatalf("expected the TLS origination cluster, got %v", c)
+		}
+	})
+
+	t.Run("registry only", func(t *testing.T) {
+		m := mesh.DefaultMeshConfig()
+		m.OutboundTrafficPolicy = &meshconfig.MeshConfig_OutboundTrafficPolicy{Mode: meshconfig.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY}
+		cg := NewConfigGenTest(t, TestOptions{MeshConfig: &m})
+		l := xdstest.ExtractListener(model.VirtualOutboundListenerName, cg.Listeners(cg.SetupProxy(nil)))
+		if l.DefaultFilterChain != nil || len(l.FilterChains) != 2 {
+			t.Fatalf("did not expect plaintext TLS filter chains with REGISTRY_ONLY, got %v", l.FilterChains)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"regexp"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/pkg/log"
+)
+
+// principalClusterSeparator separates the name of an inbound cluster from the principal of its copy limiting the
+// concurrent requests of the principal.
+const principalClusterSeparator = "~"
+
+// inboundPrincipalConcurrencyForProxy reads the InboundPrincipalConcurrencyAnnotation of the sidecar. Invalid values
+// are ignored; they are rejected by the injection of the sidecar.
+func inboundPrincipalConcurrencyForProxy(node *model.Proxy) *model.InboundPrincipalConcurrency {
+	if node.Type != model.SidecarProxy {
+		return nil
+	}
+	v, f := node.Metadata.Annotations[model.InboundPrincipalConcurrencyAnnotation]
+	if !f {
+		return nil
+	}
+	limits, err := model.ParseInboundPrincipalConcurrency(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on proxy %s: %v", model.InboundPrincipalConcurrencyAnnotation, v, node.ID, err)
+		return nil
+	}
+	return limits
+}
+
+// principalClusterName returns the name of the copy of the inbound cluster limiting the requests of the principal.
+func principalClusterName(clusterName, principal string) string {
+	return clusterName + principalClusterSeparator + principal
+}
+
+// withConcurrencyLimit sets the circuit breaker thresholds of the cluster to the limit of concurrent requests. The
+// pending requests and the connections to the workload are limited as well, since HTTP/1.1 connections only carry
+// one request at a time.
+func withConcurrencyLimit(c *cluster.Cluster, requests uint32) {
+	thresholds := getDefaultCircuitBreakerThresholds()
+	if t := c.GetCircuitBreakers().GetThresholds(); len(t) > 0 {
+		thresholds = proto.Clone(t[0]).(*cluster.CircuitBreakers_Thresholds)
+	}
+	limit := &wrappers.UInt32Value{Value: requests}
+	thresholds.MaxRequests = limit
+	thresholds.MaxPendingRequests = limit
+	thresholds.MaxConnections = limit
+	c.CircuitBreakers = &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{thresholds}}
+}
+
+// buildPrincipalConcurrencyClusters limits the concurrent requests of the principals to the inbound clusters. Each
+// principal gets its own copy of each cluster, whose circuit breakers cap its requests, while the inbound clusters
+// themselves cap the requests of the other clients. It returns the copies of the clusters.
+func buildPrincipalConcurrencyClusters(limits *model.InboundPrincipalConcurrency, clusters []*cluster.Cluster) []*cluster.Cluster {
+	out := make([]*cluster.Cluster, 0, len(clusters)*len(limits.Principals))
+	for _, c := range clusters {
+		for _, l := range limits.Principals {
+			pc := proto.Clone(c).(*cluster.Cluster)
+			pc.Name = principalClusterName(c.Name, l.Principal)
+			withConcurrencyLimit(pc, l.Requests)
+			out = append(out, pc)
+		}
+		if limits.Others > 0 {
+			withConcurrencyLimit(c, limits.Others)
+		}
+	}
+	return out
+}
+
+// principalCertMatcher matches the requests sent over mTLS by the principal. The sidecar appends the URI SAN of the
+// client certificate to the x-forwarded-client-cert header before the route is selected, so the last element of the
+// header identifies the client. Plaintext requests are not matched, as their header is set by the client.
+func principalCertMatcher(principal string) *route.HeaderMatcher {
+	uri := regexp.QuoteMeta(spiffe.URIPrefix + strings.Trim(principal, "*"))
+	if strings.HasPrefix(principal, "*") {
+		uri = spiffe.URIPrefix + "[^;,]*" + regexp.QuoteMeta(strings.Trim(principal, "*"))
+	}
+	if strings.HasSuffix(principal, "*") {
+		uri += "[^;,]*"
+	}
+	return &route.HeaderMatcher{
+		Name: "x-forwarded-client-cert",
+		HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: &matcher.RegexMatcher{
+			EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+			Regex:      `.*;URI=` + uri + `(;DNS=[^;,]*)*`,
+		}},
+	}
+}
+
+// applyPrincipalConcurrency routes the requests of the principals to their copies of the inbound clusters, matched
+// before each route to an inbound cluster. The circuit breakers of the clusters are only taken by the router filter,
+// after the authorization filters, so the requests denied by them are not counted.
+func applyPrincipalConcurrency(node *model.Proxy, routes []*route.Route) []*route.Route {
+	limits := inboundPrincipalConcurrencyForProxy(node)
+	if limits == nil || len(limits.Principals) == 0 {
+		return routes
+	}
+	out := make([]*route.Route, 0, len(routes)*(len(limits.Principals)+1))
+	for _, r := range routes {
+		clusterName := r.GetRoute().GetCluster()
+		if clusterName == "" {
+			out = append(out, r)
+			continue
+		}
+		for _, l := range limits.Principals {
+			pr := proto.Clone(r).(*route.Route)
+			pr.Match.Headers = append(pr.Match.Headers, principalCertMatcher(l.Principal))
+			pr.Match.TlsContext = &route.RouteMatch_TlsContextMatchOptions{Presented: &wrappers.BoolValue{Value: true}}
+			pr.GetRoute().ClusterSpecifier = &route.RouteAction_Cluster{Cluster: principalClusterName(clusterName, l.Principal)}
+			out = append(out, pr)
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+func TestBuildPrincipalConcurrencyClusters(t *testing.T) {
+	limits := &model.InboundPrincipalConcurrency{
+		Principals: []model.PrincipalConcurrencyLimit{{Principal: "cluster.local/ns/batch/sa/reporter", Requests: 10}},
+		Others:     100,
+	}
+	inbound := &cluster.Cluster{Name: "inbound|8080||"}
+	copies := buildPrincipalConcurrencyClusters(limits, []*cluster.Cluster{inbound})
+	if len(copies) != 1 || copies[0].Name != "inbound|8080||~cluster.local/ns/batch/sa/reporter" {
+		t.Fatalf("unexpected copies of the inbound cluster %v", copies)
+	}
+	if got := copies[0].CircuitBreakers.Thresholds[0].MaxRequests.GetValue(); got != 10 {
+		t.Errorf("got %d max requests for the principal, want 10", got)
+	}
+	if got := inbound.CircuitBreakers.Thresholds[0].MaxRequests.GetValue(); got != 100 {
+		t.Errorf("got %d max requests for the other clients, want 100", got)
+	}
+}
+
+func TestPrincipalCertMatcher(t *testing.T) {
+	cases := []struct {
+		principal string
+		regex     string
+	}{
+		{"cluster.local/ns/batch/sa/reporter", `.*;URI=spiffe://cluster\.local/ns/batch/sa/reporter(;DNS=[^;,]*)*`},
+		{"cluster.local/ns/batch/*", `.*;URI=spiffe://cluster\.local/ns/batch/[^;,]*(;DNS=[^;,]*)*`},
+		{"*/sa/reporter", `.*;URI=spiffe://[^;,]*/sa/reporter(;DNS=[^;,]*)*`},
+	}
+	for _, tt := range cases {
+		t.Run(tt.principal, func(t *testing.T) {
+			if got := principalCertMatcher(tt.principal).GetSafeRegexMatch().GetRegex(); got != tt.regex {
+				t.Errorf("got %q, want %q", got, tt.regex)
+			}
+		})
+	}
+}
+
+func TestInboundListenerPrincipalConcurrency(t *testing.T) {
+	getRoutes := func(proxyAnnotations map[string]string) []string {
+		proxy := getProxy()
+		proxy.Metadata.Annotations = proxyAnnotations
+		listeners := buildInboundListeners(t, &fakePlugin{}, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+		h := &hcm.HttpConnectionManager{}
+		if err := getFilterConfig(getHTTPFilter(getHTTPFilterChain(t, listeners[0])), h); err != nil {
+			t.Fatalf("failed to get HCM, config %v", h)
+		}
+		var clusters []string
+		for _, vh := range h.GetRouteConfig().GetVirtualHosts() {
+			for _, r := range vh.Routes {
+				clusters = append(clusters, r.GetRoute().GetCluster())
+			}
+		}
+		return clusters
+	}
+
+	if clusters := getRoutes(nil); len(clusters) != 1 {
+		t.Fatalf("unexpected routes %v", clusters)
+	}
+	clusters := getRoutes(map[string]string{model.InboundPrincipalConcurrencyAnnotation: "cluster.local/ns/batch/sa/reporter=10"})
+	if len(clusters) != 2 || clusters[0] != principalClusterName(clusters[1], "cluster.local/ns/batch/sa/reporter") {
+		t.Fatalf("expected the requests of the principal to be routed to its cluster first, got %v", clusters)
+	}
+	if clusters := getRoutes(map[string]string{model.InboundPrincipalConcurrencyAnnotation: "cluster.local/ns/batch/sa/reporter"}); len(clusters) != 1 {
+		t.Fatalf("unexpected routes for an invalid annotation %v", clusters)
+	}
+}
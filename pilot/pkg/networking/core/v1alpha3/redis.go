@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strconv"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	rediscluster "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/redis/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/log"
+)
+
+// Annotations of a DestinationRule configuring the Redis proxy of the ports of its host with the redis protocol.
+const (
+	// RedisClusterAnnotation, when "true", makes the proxy aware of the Redis Cluster topology of the host. The
+	// proxy discovers the cluster nodes and the slots they serve from the host, and sends each command to the node
+	// owning its key, following MOVED and ASK redirections.
+	RedisClusterAnnotation = "networking.istio.io/redis-cluster"
+	// RedisOpTimeoutAnnotation is the timeout of each Redis command, for example "500ms". Defaults to 5s.
+	RedisOpTimeoutAnnotation = "networking.istio.io/redis-op-timeout"
+	// RedisMirrorAnnotation is the hostname of a service the Redis commands are mirrored to, on the same port.
+	RedisMirrorAnnotation = "networking.istio.io/redis-mirror"
+	// RedisMirrorExcludeReadsAnnotation, when "true", only mirrors write commands.
+	RedisMirrorExcludeReadsAnnotation = "networking.istio.io/redis-mirror-exclude-reads"
+)
+
+// redisClusterType is the Envoy cluster extension discovering the topology of a Redis Cluster.
+const redisClusterType = "envoy.clusters.redis"
+
+// redisClusterRefreshRate is the interval at which the topology of a Redis Cluster is refreshed.
+var redisClusterRefreshRate = 5 * time.Second
+
+// redisSettings are the Redis proxy settings read from the annotations of a DestinationRule.
+type redisSettings struct {
+	cluster            bool
+	opTimeout          time.Duration
+	mirrorHost         host.Name
+	mirrorExcludeReads bool
+}
+
+func defaultRedisSettings() redisSettings {
+	return redisSettings{opTimeout: redisOpTimeout}
+}
+
+// redisSettingsForDestinationRule reads the Redis proxy settings from the annotations of the DestinationRule.
+// Invalid values are ignored.
+func redisSettingsForDestinationRule(dr *config.Config) redisSettings {
+	s := defaultRedisSettings()
+	if dr == nil {
+		return s
+	}
+	a := dr.Annotations
+	s.cluster = a[RedisClusterAnnotation] == "true"
+	if v, f := a[RedisOpTimeoutAnnotation]; f {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			s.opTimeout = d
+		} else {
+			log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", RedisOpTimeoutAnnotation, v, dr.Namespace, dr.Name)
+		}
+	}
+	s.mirrorHost = host.Name(a[RedisMirrorAnnotation])
+	if v, f := a[RedisMirrorExcludeReadsAnnotation]; f {
+		if b, err := strconv.ParseBool(v); err == nil {
+			s.mirrorExcludeReads = b
+		} else {
+			log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s", RedisMirrorExcludeReadsAnnotation, v, dr.Namespace, dr.Name)
+		}
+	}
+	return s
+}
+
+// redisSettingsForCluster returns the Redis proxy settings of the service targeted by an outbound cluster.
+func redisSettingsForCluster(push *model.PushContext, node *model.Proxy, clusterName string) redisSettings {
+	return redisSettingsForDestinationRule(destinationRuleForCluster(push, node, clusterName))
+}
+
+// destinationRuleForCluster returns the DestinationRule of the service targeted by an outbound cluster, if any.
+func destinationRuleForCluster(push *model.PushContext, node *model.Proxy, clusterName string) *config.Config {
+	direction, _, hostname, _ := model.ParseSubsetKey(clusterName)
+	if direction != model.TrafficDirectionOutbound || hostname == "" {
+		return nil
+	}
+	service := push.ServiceForHostname(node, hostname)
+	if service == nil {
+		return nil
+	}
+	return push.DestinationRule(node, service)
+}
+
+// applyRedisCluster turns an outbound cluster into a Redis Cluster aware cluster. The service hostname is used
+// as the seed from which the cluster topology is discovered, instead of the endpoints of the service.
+func applyRedisCluster(c *cluster.Cluster, service *model.Service, port *model.Port) {
+	c.ClusterDiscoveryType = &cluster.Cluster_ClusterType{
+		ClusterType: &cluster.Cluster_CustomClusterType{
+			Name: redisClusterType,
+			TypedConfig: util.MessageToAny(&rediscluster.RedisClusterConfig{
+				ClusterRefreshRate: durationpb.New(redisClusterRefreshRate),
+			}),
+		},
+	}
+	c.EdsClusterConfig = nil
+	c.LbPolicy = cluster.Cluster_CLUSTER_PROVIDED
+	c.LbConfig = nil
+	if c.CommonLbConfig != nil {
+		c.CommonLbConfig.LocalityConfigSpecifier = nil
+	}
+	c.LoadAssignment = &endpoint.ClusterLoadAssignment{
+		ClusterName: c.Name,
+		Endpoints: []*endpoint.LocalityLbEndpoints{{
+			LbEndpoints: []*endpoint.LbEndpoint{{
+				HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+					Endpoint: &endpoint.Endpoint{Address: util.BuildAddress(string(service.Hostname), uint32(port.Port))},
+				},
+			}},
+		}},
+	}
+}
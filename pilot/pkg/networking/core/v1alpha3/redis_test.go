@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	redis "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/redis_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestRedisSettingsForDestinationRule(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        redisSettings
+	}{
+		{
+			name: "defaults",
+			want: redisSettings{opTimeout: redisOpTimeout},
+		},
+		{
+			name: "all set",
+			annotations: map[string]string{
+				RedisClusterAnnotation:            "true",
+				RedisOpTimeoutAnnotation:          "250ms",
+				RedisMirrorAnnotation:             "redis-shadow.default.svc.cluster.local",
+				RedisMirrorExcludeReadsAnnotation: "true",
+			},
+			want: redisSettings{
+				cluster:            true,
+				opTimeout:          250 * time.Millisecond,
+				mirrorHost:         "redis-shadow.default.svc.cluster.local",
+				mirrorExcludeReads: true,
+			},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				RedisOpTimeoutAnnotation:          "soon",
+				RedisMirrorExcludeReadsAnnotation: "maybe",
+			},
+			want: redisSettings{opTimeout: redisOpTimeout},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Name: "redis", Namespace: "default", Annotations: tt.annotations}}
+			if got := redisSettingsForDestinationRule(dr); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+const redisConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: redis
+  namespace: default
+spec:
+  hosts:
+  - redis.default.svc.cluster.local
+  addresses:
+  - 1.1.1.1
+  ports:
+  - number: 6379
+    name: redis
+    protocol: REDIS
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: redis
+  namespace: default
+  annotations:
+    networking.istio.io/redis-cluster: "true"
+    networking.istio.io/redis-op-timeout: "250ms"
+    networking.istio.io/redis-mirror: redis-shadow.default.svc.cluster.local
+spec:
+  host: redis.default.svc.cluster.local
+`
+
+func TestRedisClusterAndFilter(t *testing.T) {
+	defaultValue := features.EnableRedisFilter
+	features.EnableRedisFilter = true
+	defer func() { features.EnableRedisFilter = defaultValue }()
+
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: redisConfig})
+	proxy := cg.SetupProxy(nil)
+
+	c := xdstest.ExtractCluster("outbound|6379||redis.default.svc.cluster.local", cg.Clusters(proxy))
+	assert.Equal(t, c.GetClusterType().GetName(), redisClusterType)
+	assert.Equal(t, c.GetLbPolicy(), cluster.Cluster_CLUSTER_PROVIDED)
+	seed := c.GetLoadAssignment().GetEndpoints()[0].GetLbEndpoints()[0].GetEndpoint().GetAddress().GetSocketAddress()
+	assert.Equal(t, seed.GetAddress(), "redis.default.svc.cluster.local")
+	assert.Equal(t, seed.GetPortValue(), uint32(6379))
+
+	l := xdstest.ExtractListener("1.1.1.1_6379", cg.Listeners(proxy))
+	if l == nil {
+		t.Fatalf("redis listener not found")
+	}
+	var proxyConfig *redis.RedisProxy
+	for _, f := range l.GetFilterChains()[0].GetFilters() {
+		if f.GetName() == wellknown.RedisProxy {
+			proxyConfig = &redis.RedisProxy{}
+			if err := f.GetTypedConfig().UnmarshalTo(proxyConfig); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if proxyConfig == nil {
+		t.Fatalf("redis proxy filter not found")
+	}
+	assert.Equal(t, proxyConfig.GetSettings().GetOpTimeout().AsDuration(), 250*time.Millisecond)
+	assert.Equal(t, proxyConfig.GetSettings().GetEnableRedirection(), true)
+	assert.Equal(t, proxyConfig.GetSettings().GetEnableHashtagging(), true)
+	mirror := proxyConfig.GetPrefixRoutes().GetCatchAllRoute().GetRequestMirrorPolicy()
+	if len(mirror) != 1 {
+		t.Fatalf("expected one mirror policy, got %v", mirror)
+	}
+	assert.Equal(t, mirror[0].GetCluster(), model.BuildSubsetKey(model.TrafficDirectionOutbound, "", "redis-shadow.default.svc.cluster.local", 6379))
+}
@@ -24,6 +24,7 @@ import (
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	xdsfault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
 	xdshttpfault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	headertometadata "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/header_to_metadata/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
@@ -39,9 +40,12 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	authz "istio.io/istio/pilot/pkg/security/authz/model"
 	"istio.io/istio/pilot/pkg/util/constant"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/dynamicmetadata"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/httproute"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/proto"
 	"istio.io/istio/pkg/util/gogo"
@@ -58,6 +62,17 @@ const (
 // DefaultRouteName is the name assigned to a route generated by default in absence of a virtual service.
 const DefaultRouteName = "default"
 
+// HedgeOnPerTryTimeoutAnnotation is the annotation of VirtualServices enabling request hedging on their HTTP routes:
+// when the per try timeout of the retry policy of a route expires, a retry is sent without canceling the original
+// request, and the first response is used. It has no effect on routes without a per try timeout.
+const HedgeOnPerTryTimeoutAnnotation = "networking.istio.io/hedge-on-per-try-timeout"
+
+// AutoHostRewriteAnnotation is the annotation of VirtualServices rewriting the Host header of the requests of their
+// HTTP routes to the hostname of the upstream endpoint they are forwarded to, as required by many external virtual
+// hosted services. It only has an effect on the destinations resolved by DNS, such as the ServiceEntries with the DNS
+// resolution, and on the routes without an authority rewrite, which takes precedence.
+const AutoHostRewriteAnnotation = "networking.istio.io/auto-host-rewrite"
+
 var regexEngine = &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}}
 
 // VirtualHostWrapper is a context-dependent virtual host entry with guarded routes.
@@ -429,6 +444,25 @@ func translateRoute(
 		applyRedirect(out, in.Redirect, listenPort)
 	} else {
 		applyHTTPRouteDestination(out, node, in, mesh, authority, serviceRegistry, listenPort, hashByDestination)
+		if hedgeOnPerTryTimeout(virtualService) {
+			out.GetRoute().HedgePolicy = &route.HedgePolicy{HedgeOnPerTryTimeout: true}
+		}
+		if autoHostRewrite(virtualService) && !hasHostRewrite(out.GetRoute()) {
+			out.GetRoute().HostRewriteSpecifier = &route.RouteAction_AutoHostRewrite{AutoHostRewrite: &wrappers.BoolValue{Value: true}}
+		}
+		if rewrite := pathRewrite(virtualService, in.Name); rewrite != nil {
+			out.GetRoute().PrefixRewrite = ""
+			out.GetRoute().RegexRewrite = rewrite
+		}
+		if policy := out.GetRoute().GetRetryPolicy(); policy != nil {
+			if headers := retriableResponseHeaders(virtualService, in.Name); len(headers) > 0 {
+				policy.RetriableHeaders = headers
+				policy.RetryOn += ",retriable-headers"
+			}
+			if timeout := retryPerTryIdleTimeout(virtualService); timeout != nil {
+				policy.PerTryIdleTimeout = timeout
+			}
+		}
 	}
 
 	out.Decorator = &route.Decorator{
@@ -438,6 +472,23 @@ func translateRoute(
 		out.TypedPerFilterConfig = make(map[string]*any.Any)
 		out.TypedPerFilterConfig[wellknown.Fault] = util.MessageToAny(translateFault(in.Fault))
 	}
+	if in.Redirect == nil {
+		var rules []*headertometadata.Config_Rule
+		if features.EnableHeaderToMetadata {
+			rules = headerToMetadata(virtualService, in.Name)
+		}
+		if features.EnableLBSubsetHeaders {
+			rules = append(rules, lbSubsetHeaders(virtualService, in.Name)...)
+		}
+		if len(rules) > 0 {
+			if out.TypedPerFilterConfig == nil {
+				out.TypedPerFilterConfig = make(map[string]*any.Any)
+			}
+			out.TypedPerFilterConfig[xdsfilters.HeaderToMetadataFilterName] = util.MessageToAny(&headertometadata.Config{
+				RequestRules: rules,
+			})
+		}
+	}
 
 	if isHTTP3AltSvcHeaderNeeded {
 		http3AltSvcHeader := buildHTTP3AltSvcHeader(listenPort, util.ALPNHttp3OverQUIC)
@@ -450,6 +501,207 @@ func translateRoute(
 	return out
 }
 
+// hedgeOnPerTryTimeout reads the HedgeOnPerTryTimeoutAnnotation of the VirtualService. Invalid values are ignored.
+func hedgeOnPerTryTimeout(virtualService config.Config) bool {
+	v, f := virtualService.Annotations[HedgeOnPerTryTimeoutAnnotation]
+	if !f {
+		return false
+	}
+	hedge, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s", HedgeOnPerTryTimeoutAnnotation, v,
+			virtualService.Namespace, virtualService.Name)
+		return false
+	}
+	return hedge
+}
+
+// retryPerTryIdleTimeout reads the httproute.RetryPerTryIdleTimeoutAnnotation of the VirtualService. Invalid values
+// are ignored; they are rejected by the validation of the VirtualService.
+func retryPerTryIdleTimeout(virtualService config.Config) *durationpb.Duration {
+	v, f := virtualService.Annotations[httproute.RetryPerTryIdleTimeoutAnnotation]
+	if !f {
+		return nil
+	}
+	timeout, err := httproute.ParseRetryPerTryIdleTimeoutAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s: %v", httproute.RetryPerTryIdleTimeoutAnnotation, v,
+			virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	return durationpb.New(timeout)
+}
+
+// headerToMetadata reads the rules of the route from the dynamicmetadata.HeaderToMetadataAnnotation of the
+// VirtualService. Invalid values are ignored.
+func headerToMetadata(virtualService config.Config, routeName string) []*headertometadata.Config_Rule {
+	v, f := virtualService.Annotations[dynamicmetadata.HeaderToMetadataAnnotation]
+	if !f {
+		return nil
+	}
+	specs, err := dynamicmetadata.ParseHeaderToMetadataAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s: %v", dynamicmetadata.HeaderToMetadataAnnotation,
+			v, virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	var rules []*headertometadata.Config_Rule
+	for _, spec := range specs {
+		if !spec.AppliesTo(routeName) {
+			continue
+		}
+		valueType := headertometadata.Config_STRING
+		if spec.Type == dynamicmetadata.NumberType {
+			valueType = headertometadata.Config_NUMBER
+		}
+		rule := &headertometadata.Config_Rule{
+			Header: spec.Header,
+			OnHeaderPresent: &headertometadata.Config_KeyValuePair{
+				MetadataNamespace: spec.Namespace,
+				Key:               spec.Key,
+				Type:              valueType,
+			},
+			Remove: spec.Remove,
+		}
+		if spec.OnMissing != "" {
+			rule.OnHeaderMissing = &headertometadata.Config_KeyValuePair{
+				MetadataNamespace: spec.Namespace,
+				Key:               spec.Key,
+				Value:             spec.OnMissing,
+				Type:              valueType,
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// lbSubsetHeaders reads the rules of the route from the httproute.LBSubsetHeadersAnnotation of the VirtualService,
+// setting the envoy.lb metadata of the requests from their headers. Invalid values are ignored; they are rejected by
+// the validation of the VirtualService.
+func lbSubsetHeaders(virtualService config.Config, routeName string) []*headertometadata.Config_Rule {
+	v, f := virtualService.Annotations[httproute.LBSubsetHeadersAnnotation]
+	if !f || routeName == "" {
+		return nil
+	}
+	specs, err := httproute.ParseLBSubsetHeadersAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s: %v", httproute.LBSubsetHeadersAnnotation, v,
+			virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	headers := specs[routeName]
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rules := make([]*headertometadata.Config_Rule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, &headertometadata.Config_Rule{
+			Header: name,
+			OnHeaderPresent: &headertometadata.Config_KeyValuePair{
+				MetadataNamespace: util.EnvoyLbMetadataKey,
+				Key:               headers[name],
+				Type:              headertometadata.Config_STRING,
+			},
+		})
+	}
+	return rules
+}
+
+// autoHostRewrite reads the AutoHostRewriteAnnotation of the VirtualService. Invalid values are ignored.
+func autoHostRewrite(virtualService config.Config) bool {
+	v, f := virtualService.Annotations[AutoHostRewriteAnnotation]
+	if !f {
+		return false
+	}
+	rewrite, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s", AutoHostRewriteAnnotation, v,
+			virtualService.Namespace, virtualService.Name)
+		return false
+	}
+	return rewrite
+}
+
+// hasHostRewrite returns whether the route action, or any of its weighted clusters, rewrites the authority.
+func hasHostRewrite(action *route.RouteAction) bool {
+	if action.HostRewriteSpecifier != nil {
+		return true
+	}
+	for _, c := range action.GetWeightedClusters().GetClusters() {
+		if c.HostRewriteSpecifier != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pathRewrite reads the rewrite of the HTTP route in the httproute.PathRewriteAnnotation of the VirtualService.
+// Invalid values are ignored; they are rejected by the validation of the VirtualService.
+func pathRewrite(virtualService config.Config, routeName string) *matcher.RegexMatchAndSubstitute {
+	v, f := virtualService.Annotations[httproute.PathRewriteAnnotation]
+	if !f || routeName == "" {
+		return nil
+	}
+	specs, err := httproute.ParsePathRewriteAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s: %v", httproute.PathRewriteAnnotation, v,
+			virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	spec, f := specs[routeName]
+	if !f {
+		return nil
+	}
+	return &matcher.RegexMatchAndSubstitute{
+		Pattern: &matcher.RegexMatcher{
+			EngineType: regexEngine,
+			Regex:      spec.Regex,
+		},
+		Substitution: spec.Substitution,
+	}
+}
+
+// retriableResponseHeaders reads the header matches of the HTTP route in the
+// httproute.RetriableResponseHeadersAnnotation of the VirtualService. Invalid values are ignored; they are rejected by
+// the validation of the VirtualService.
+func retriableResponseHeaders(virtualService config.Config, routeName string) []*route.HeaderMatcher {
+	v, f := virtualService.Annotations[httproute.RetriableResponseHeadersAnnotation]
+	if !f || routeName == "" {
+		return nil
+	}
+	specs, err := httproute.ParseRetriableResponseHeadersAnnotation(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on VirtualService %s/%s: %v", httproute.RetriableResponseHeadersAnnotation,
+			v, virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	headers := specs[routeName]
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]*route.HeaderMatcher, 0, len(names))
+	for _, name := range names {
+		spec := headers[name]
+		// A nil match matches the presence of the header.
+		var match *networking.StringMatch
+		switch {
+		case spec.Exact != "":
+			match = &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: spec.Exact}}
+		case spec.Prefix != "":
+			match = &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: spec.Prefix}}
+		case spec.Regex != "":
+			match = &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: spec.Regex}}
+		}
+		out = append(out, translateHeaderMatch(strings.ToLower(name), match))
+	}
+	return out
+}
+
 func applyHTTPRouteDestination(
 	out *route.Route,
 	node *model.Proxy,
@@ -509,6 +761,9 @@ func applyHTTPRouteDestination(
 
 	// TODO: eliminate this logic and use the total_weight option in envoy route
 	weighted := make([]*route.WeightedCluster_ClusterWeight, 0)
+	// Destinations without header manipulations which resolve to the same cluster are merged into a single
+	// weighted cluster, to keep the size of the route table bounded.
+	mergeable := map[string]*route.WeightedCluster_ClusterWeight{}
 	for _, dst := range in.Route {
 		weight := &wrappers.UInt32Value{Value: uint32(dst.Weight)}
 		if dst.Weight == 0 {
@@ -522,11 +777,23 @@ func applyHTTPRouteDestination(
 		}
 		hostname := host.Name(dst.GetDestination().GetHost())
 		n := GetDestinationCluster(dst.Destination, serviceRegistry[hostname], listenerPort)
+		hashPolicy := consistentHashToHashPolicy(hashByDestination[dst])
+		if hashPolicy != nil {
+			action.HashPolicy = append(action.HashPolicy, hashPolicy)
+		}
+		if dst.Headers == nil {
+			if existing, f := mergeable[n]; f {
+				existing.Weight.Value += weight.Value
+				continue
+			}
+		}
 		clusterWeight := &route.WeightedCluster_ClusterWeight{
 			Name:   n,
 			Weight: weight,
 		}
-		if dst.Headers != nil {
+		if dst.Headers == nil {
+			mergeable[n] = clusterWeight
+		} else {
 			var operations headersOperations
 			// https://github.com/envoyproxy/envoy/issues/16775 Until 1.12, we could not rewrite authority in weighted cluster
 			if util.IsIstioVersionGE112(node.IstioVersion) {
@@ -546,11 +813,6 @@ func applyHTTPRouteDestination(
 		}
 
 		weighted = append(weighted, clusterWeight)
-		hash := hashByDestination[dst]
-		hashPolicy := consistentHashToHashPolicy(hash)
-		if hashPolicy != nil {
-			action.HashPolicy = append(action.HashPolicy, hashPolicy)
-		}
 	}
 
 	// rewrite to a single cluster if there is only weighted cluster
@@ -42,6 +42,8 @@ type Cache struct {
 	// This allows resolving ServiceEntries, which is especially useful for distinguishing TCP traffic
 	// This depends on DNSCapture.
 	DNSAutoAllocate bool
+	// Baggage is the W3C baggage of the mesh entries added to the requests of the proxy.
+	Baggage string
 
 	ListenerPort            int
 	Services                []*model.Service
@@ -49,6 +51,8 @@ type Cache struct {
 	DelegateVirtualServices []model.ConfigKey
 	DestinationRules        []*config.Config
 	EnvoyFilterKeys         []string
+	// EgressProxy is the egress proxy the requests to the external services are sent to, if any.
+	EgressProxy *model.EgressProxy
 }
 
 func (r *Cache) Cacheable() bool {
@@ -94,17 +98,22 @@ func (r *Cache) DependentConfigs() []model.ConfigKey {
 		items := strings.Split(efKey, "/")
 		configs = append(configs, model.ConfigKey{Kind: gvk.EnvoyFilter, Name: items[1], Namespace: items[0]})
 	}
+	// The routes send the credentials of the egress proxy.
+	if key := r.EgressProxy.CredentialKey(); key != nil {
+		configs = append(configs, *key)
+	}
 	return configs
 }
 
 func (r *Cache) DependentTypes() []config.GroupVersionKind {
-	return nil
+	// Sidecars set the default CORS policies of the routes.
+	return []config.GroupVersionKind{gvk.Sidecar}
 }
 
 func (r *Cache) Key() string {
 	params := []string{
 		r.RouteName, r.ProxyVersion, r.ClusterID, r.DNSDomain,
-		strconv.FormatBool(r.DNSCapture), strconv.FormatBool(r.DNSAutoAllocate),
+		strconv.FormatBool(r.DNSCapture), strconv.FormatBool(r.DNSAutoAllocate), r.Baggage,
 	}
 	for _, svc := range r.Services {
 		params = append(params, string(svc.Hostname)+"/"+svc.Attributes.Namespace)
@@ -116,6 +125,10 @@ func (r *Cache) Key() string {
 		params = append(params, dr.Name+"/"+dr.Namespace)
 	}
 	params = append(params, r.EnvoyFilterKeys...)
+	if r.EgressProxy != nil {
+		params = append(params, string(r.EgressProxy.Hostname)+":"+strconv.Itoa(r.EgressProxy.Port),
+			r.EgressProxy.CredentialName+"/"+r.EgressProxy.Namespace)
+	}
 
 	hash := md5.New()
 	for _, param := range params {
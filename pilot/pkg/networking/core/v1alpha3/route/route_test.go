@@ -22,6 +22,7 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoyroute "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	headertometadata "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/header_to_metadata/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/gogo/protobuf/types"
 	"github.com/onsi/gomega"
@@ -33,9 +34,12 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
 	"istio.io/istio/pilot/pkg/networking/util"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/dynamicmetadata"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/httproute"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -769,6 +773,247 @@ func TestBuildHTTPRoutes(t *testing.T) {
 		}
 	})
 
+	t.Run("for duplicate weighted destinations", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		dst := func(subset string, weight int32, headers *networking.Headers) *networking.HTTPRouteDestination {
+			return &networking.HTTPRouteDestination{
+				Destination: &networking.Destination{
+					Host:   "*.example.org",
+					Subset: subset,
+					Port:   &networking.PortSelector{Number: 8484},
+				},
+				Weight:  weight,
+				Headers: headers,
+			}
+		}
+		headers := &networking.Headers{Request: &networking.Headers_HeaderOperations{Set: map[string]string{"x-canary": "true"}}}
+		vs.Spec.(*networking.VirtualService).Http[0].Route = []*networking.HTTPRouteDestination{
+			dst("v1", 30, nil),
+			dst("v2", 20, nil),
+			dst("v1", 20, nil),
+			dst("v1", 10, headers),
+			dst("v2", 20, nil),
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+
+		clusters := routes[0].GetRoute().GetWeightedClusters().GetClusters()
+		g.Expect(len(clusters)).To(gomega.Equal(3))
+		g.Expect(clusters[0].Name).To(gomega.Equal("outbound|8484|v1|*.example.org"))
+		g.Expect(clusters[0].Weight.Value).To(gomega.Equal(uint32(50)))
+		g.Expect(clusters[1].Name).To(gomega.Equal("outbound|8484|v2|*.example.org"))
+		g.Expect(clusters[1].Weight.Value).To(gomega.Equal(uint32(40)))
+		g.Expect(clusters[2].Name).To(gomega.Equal("outbound|8484|v1|*.example.org"))
+		g.Expect(clusters[2].Weight.Value).To(gomega.Equal(uint32(10)))
+	})
+
+	t.Run("for virtual service with hedging on per try timeout", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Annotations = map[string]string{route.HedgeOnPerTryTimeoutAnnotation: "true"}
+		vs.Spec.(*networking.VirtualService).Http[0].Retries = &networking.HTTPRetry{
+			Attempts:      2,
+			PerTryTimeout: &types.Duration{Nanos: 100000000},
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		g.Expect(routes[0].GetRoute().GetHedgePolicy().GetHedgeOnPerTryTimeout()).To(gomega.BeTrue())
+
+		vs.Annotations[route.HedgeOnPerTryTimeoutAnnotation] = "yes please"
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetHedgePolicy()).To(gomega.BeNil())
+	})
+
+	t.Run("for virtual service with retry per try idle timeout", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Annotations = map[string]string{httproute.RetryPerTryIdleTimeoutAnnotation: "1s"}
+		vs.Spec.(*networking.VirtualService).Http[0].Retries = &networking.HTTPRetry{Attempts: 2}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		g.Expect(routes[0].GetRoute().GetRetryPolicy().GetPerTryIdleTimeout().AsDuration()).To(gomega.Equal(time.Second))
+
+		vs.Annotations[httproute.RetryPerTryIdleTimeoutAnnotation] = "soon"
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRetryPolicy().GetPerTryIdleTimeout()).To(gomega.BeNil())
+
+		// Routes without retries are not changed.
+		vs.Annotations[httproute.RetryPerTryIdleTimeoutAnnotation] = "1s"
+		vs.Spec.(*networking.VirtualService).Http[0].Retries = &networking.HTTPRetry{Attempts: 0}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRetryPolicy()).To(gomega.BeNil())
+	})
+
+	t.Run("for virtual service with lb subset headers", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		defaultValue := features.EnableLBSubsetHeaders
+		features.EnableLBSubsetHeaders = true
+		defer func() { features.EnableLBSubsetHeaders = defaultValue }()
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Spec.(*networking.VirtualService).Http[0].Name = "inference"
+		vs.Annotations = map[string]string{httproute.LBSubsetHeadersAnnotation: `{"inference": {"x-gpu": "gpu", "x-shard": "shard"}}`}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		config := &headertometadata.Config{}
+		g.Expect(routes[0].TypedPerFilterConfig[xdsfilters.HeaderToMetadataFilterName].UnmarshalTo(config)).To(gomega.Succeed())
+		g.Expect(len(config.RequestRules)).To(gomega.Equal(2))
+		g.Expect(config.RequestRules[0].Header).To(gomega.Equal("x-gpu"))
+		g.Expect(config.RequestRules[0].OnHeaderPresent.MetadataNamespace).To(gomega.Equal("envoy.lb"))
+		g.Expect(config.RequestRules[0].OnHeaderPresent.Key).To(gomega.Equal("gpu"))
+
+		// Other routes and invalid values are ignored.
+		vs.Annotations[httproute.LBSubsetHeadersAnnotation] = `{"other": {"x-gpu": "gpu"}}`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].TypedPerFilterConfig).To(gomega.BeNil())
+		vs.Annotations[httproute.LBSubsetHeadersAnnotation] = `{"inference": {"x-gpu": ""}}`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].TypedPerFilterConfig).To(gomega.BeNil())
+	})
+
+	t.Run("for virtual service with header to metadata", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		defaultValue := features.EnableHeaderToMetadata
+		features.EnableHeaderToMetadata = true
+		defer func() { features.EnableHeaderToMetadata = defaultValue }()
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Spec.(*networking.VirtualService).Http[0].Name = "api"
+		vs.Annotations = map[string]string{dynamicmetadata.HeaderToMetadataAnnotation: `[
+			{"header": "x-tenant", "key": "tenant", "namespace": "istio.tenant", "onMissing": "none", "remove": true},
+			{"header": "x-tier", "key": "tier", "type": "NUMBER", "routes": ["web"]}]`}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		config := &headertometadata.Config{}
+		g.Expect(routes[0].TypedPerFilterConfig[xdsfilters.HeaderToMetadataFilterName].UnmarshalTo(config)).To(gomega.Succeed())
+		g.Expect(len(config.RequestRules)).To(gomega.Equal(1))
+		rule := config.RequestRules[0]
+		g.Expect(rule.Header).To(gomega.Equal("x-tenant"))
+		g.Expect(rule.Remove).To(gomega.BeTrue())
+		g.Expect(rule.OnHeaderPresent.MetadataNamespace).To(gomega.Equal("istio.tenant"))
+		g.Expect(rule.OnHeaderPresent.Key).To(gomega.Equal("tenant"))
+		g.Expect(rule.OnHeaderMissing.Value).To(gomega.Equal("none"))
+
+		// Reserved namespaces are ignored.
+		vs.Annotations[dynamicmetadata.HeaderToMetadataAnnotation] = `[{"header": "x-principal", "key": "principal", "namespace": "istio_authn"}]`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].TypedPerFilterConfig).To(gomega.BeNil())
+	})
+
+	t.Run("for virtual service with auto host rewrite", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Annotations = map[string]string{route.AutoHostRewriteAnnotation: "true"}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		g.Expect(routes[0].GetRoute().GetAutoHostRewrite().GetValue()).To(gomega.BeTrue())
+
+		// An authority rewrite takes precedence.
+		vs.Spec.(*networking.VirtualService).Http[0].Rewrite = &networking.HTTPRewrite{Authority: "foo.example.org"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetHostRewriteLiteral()).To(gomega.Equal("foo.example.org"))
+
+		vs.Spec.(*networking.VirtualService).Http[0].Rewrite = nil
+		vs.Annotations[route.AutoHostRewriteAnnotation] = "yes please"
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetHostRewriteSpecifier()).To(gomega.BeNil())
+	})
+
+	t.Run("for virtual service with path rewrite", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Spec.(*networking.VirtualService).Http[0].Name = "items"
+		vs.Spec.(*networking.VirtualService).Http[0].Rewrite = &networking.HTTPRewrite{Uri: "/"}
+		vs.Annotations = map[string]string{
+			httproute.PathRewriteAnnotation: `{"items": {"pathTemplate": "/v1/{name}/items/{id=**}", "rewrite": "/items/{id}/owner/{name}"}}`,
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		g.Expect(routes[0].GetRoute().GetPrefixRewrite()).To(gomega.Equal(""))
+		g.Expect(routes[0].GetRoute().GetRegexRewrite().GetPattern().GetRegex()).To(gomega.Equal(`^/v1/([^/]+)/items/(.*)$`))
+		g.Expect(routes[0].GetRoute().GetRegexRewrite().GetSubstitution()).To(gomega.Equal(`/items/\2/owner/\1`))
+
+		vs.Annotations[httproute.PathRewriteAnnotation] = `{"items": {"regex": "^/v1/(.*)$", "substitution": "/v2/\\1"}}`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRegexRewrite().GetPattern().GetRegex()).To(gomega.Equal(`^/v1/(.*)$`))
+		g.Expect(routes[0].GetRoute().GetRegexRewrite().GetSubstitution()).To(gomega.Equal(`/v2/\1`))
+
+		vs.Annotations[httproute.PathRewriteAnnotation] = `{"items": {"regex": "^/v1/(.*$"}}`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRegexRewrite()).To(gomega.BeNil())
+		g.Expect(routes[0].GetRoute().GetPrefixRewrite()).To(gomega.Equal("/"))
+	})
+
+	t.Run("for virtual service with retriable response headers", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		vs := virtualServicePlain.DeepCopy()
+		vs.Spec.(*networking.VirtualService).Http[0].Name = "items"
+		vs.Annotations = map[string]string{
+			httproute.RetriableResponseHeadersAnnotation: `{"items": {"X-Overloaded": {"exact": "true"}, "x-retry-after": {}}}`,
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		xdstest.ValidateRoutes(t, routes)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		policy := routes[0].GetRoute().GetRetryPolicy()
+		g.Expect(policy.GetRetryOn()).To(gomega.HaveSuffix(",retriable-headers"))
+		g.Expect(policy.GetRetriableHeaders()).To(gomega.Equal([]*envoyroute.HeaderMatcher{
+			{Name: "x-overloaded", HeaderMatchSpecifier: &envoyroute.HeaderMatcher_ExactMatch{ExactMatch: "true"}},
+			{Name: "x-retry-after", HeaderMatchSpecifier: &envoyroute.HeaderMatcher_PresentMatch{PresentMatch: true}},
+		}))
+
+		vs.Spec.(*networking.VirtualService).Http[0].Retries = &networking.HTTPRetry{Attempts: 0}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRetryPolicy()).To(gomega.BeNil())
+
+		vs.Spec.(*networking.VirtualService).Http[0].Retries = nil
+		vs.Annotations[httproute.RetriableResponseHeadersAnnotation] = `{"items": {"x-overloaded": {"exact": "true", "prefix": "t"}}}`
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, vs, serviceRegistry, nil, 8080, gatewayNames, false, nil)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetRetryPolicy().GetRetriableHeaders()).To(gomega.BeNil())
+		g.Expect(routes[0].GetRoute().GetRetryPolicy().GetRetryOn()).NotTo(gomega.ContainSubstring("retriable-headers"))
+	})
+
 	t.Run("for redirect code", func(t *testing.T) {
 		g := gomega.NewWithT(t)
 
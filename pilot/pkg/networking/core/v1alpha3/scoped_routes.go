@@ -0,0 +1,210 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/envoyfilter"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/proto"
+)
+
+// scopedRouteSeparator separates the route name of the port from the domain suffix in the names of
+// the route configuration shards, for example "80|default.svc.cluster.local".
+const scopedRouteSeparator = "|"
+
+// authorityHeader is the header the scope keys are built from.
+const authorityHeader = ":authority"
+
+// useScopedRoutes returns whether the outbound HTTP route configuration of the port is served with scoped
+// routes. Scoped routes have no catch all, so requests for unknown hosts get a 404. This is only used when the
+// proxy blocks unknown destinations anyway, and when none of the hosts of the port is a wildcard, since
+// scope keys must match the :authority exactly.
+func useScopedRoutes(node *model.Proxy, port int) bool {
+	if features.ScopedRoutesPort == 0 || port != features.ScopedRoutesPort {
+		return false
+	}
+	if node.Type != model.SidecarProxy || node.SidecarScope == nil || util.IsAllowAnyOutbound(node) {
+		return false
+	}
+	egressListener := node.SidecarScope.GetEgressListenerForRDS(port, strconv.Itoa(port))
+	if egressListener == nil {
+		return false
+	}
+	for _, svc := range egressListener.Services() {
+		if svc.Hostname.IsWildCarded() {
+			return false
+		}
+	}
+	for _, vs := range egressListener.VirtualServices() {
+		for _, h := range vs.Spec.(*networking.VirtualService).Hosts {
+			if host.Name(h).IsWildCarded() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// buildScopedRoutes builds the route specifier of an HTTP connection manager using scoped routes for the
+// route configuration routeName.
+func buildScopedRoutes(routeName string) *hcm.HttpConnectionManager_ScopedRoutes {
+	ads := &core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{
+			Ads: &core.AggregatedConfigSource{},
+		},
+		InitialFetchTimeout: durationpb.New(0),
+		ResourceApiVersion:  core.ApiVersion_V3,
+	}
+	return &hcm.HttpConnectionManager_ScopedRoutes{
+		ScopedRoutes: &hcm.ScopedRoutes{
+			Name: routeName,
+			ScopeKeyBuilder: &hcm.ScopedRoutes_ScopeKeyBuilder{
+				Fragments: []*hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder{{
+					Type: &hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor_{
+						HeaderValueExtractor: &hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor{
+							// With no separator, the whole header value is the key.
+							Name: authorityHeader,
+						},
+					},
+				}},
+			},
+			RdsConfigSource: ads,
+			ConfigSpecifier: &hcm.ScopedRoutes_ScopedRds{
+				ScopedRds: &hcm.ScopedRds{ScopedRdsConfigSource: ads},
+			},
+		},
+	}
+}
+
+// scopedRouteConfigName returns the name of the shard of the route configuration routeName holding the
+// virtual hosts of the domain suffix.
+func scopedRouteConfigName(routeName, suffix string) string {
+	return routeName + scopedRouteSeparator + suffix
+}
+
+// parseScopedRouteConfigName parses the name of a route configuration shard into its port and domain suffix.
+func parseScopedRouteConfigName(name string) (int, string, bool) {
+	parts := strings.SplitN(name, scopedRouteSeparator, 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, "", false
+	}
+	port, err := strconv.Atoi(parts[0])
+	if err != nil || port == 0 {
+		return 0, "", false
+	}
+	return port, parts[1], true
+}
+
+// virtualHostDomainSuffix returns the domain suffix a virtual host is sharded by: its hostname without the
+// first label, for example default.svc.cluster.local for reviews.default.svc.cluster.local.
+func virtualHostDomainSuffix(vhost *route.VirtualHost) string {
+	hostname := vhost.Name
+	if i := strings.LastIndex(hostname, ":"); i != -1 {
+		hostname = hostname[:i]
+	}
+	if i := strings.Index(hostname, "."); i != -1 && i < len(hostname)-1 {
+		return hostname[i+1:]
+	}
+	return hostname
+}
+
+// sidecarOutboundPortVirtualHosts returns the outbound virtual hosts of a port, without using the route cache
+// since the shards are not cached.
+func sidecarOutboundPortVirtualHosts(node *model.Proxy, push *model.PushContext, port int,
+	vHostCache map[int][]*route.VirtualHost) []*route.VirtualHost {
+	if vhosts, f := vHostCache[port]; f {
+		return vhosts
+	}
+	vhosts, _, _ := BuildSidecarOutboundVirtualHosts(node, push, strconv.Itoa(port), port, nil, model.DisabledCache{})
+	vHostCache[port] = vhosts
+	return vhosts
+}
+
+// buildSidecarOutboundScopedHTTPRouteConfig builds a shard of the route configuration of a port, holding the
+// virtual hosts of a domain suffix.
+func (configgen *ConfigGeneratorImpl) buildSidecarOutboundScopedHTTPRouteConfig(node *model.Proxy, req *model.PushRequest,
+	routeName string, port int, suffix string, vHostCache map[int][]*route.VirtualHost, efw *model.EnvoyFilterWrapper) *discovery.Resource {
+	if !useScopedRoutes(node, port) {
+		return nil
+	}
+	virtualHosts := make([]*route.VirtualHost, 0)
+	for _, vhost := range sidecarOutboundPortVirtualHosts(node, req.Push, port, vHostCache) {
+		if virtualHostDomainSuffix(vhost) == suffix {
+			virtualHosts = append(virtualHosts, vhost)
+		}
+	}
+	util.SortVirtualHosts(virtualHosts)
+
+	out := &route.RouteConfiguration{
+		Name:             routeName,
+		VirtualHosts:     virtualHosts,
+		ValidateClusters: proto.BoolFalse,
+	}
+	out = envoyfilter.ApplyRouteConfigurationPatches(networking.EnvoyFilter_SIDECAR_OUTBOUND, node, efw, out)
+	return &discovery.Resource{
+		Name:     out.Name,
+		Resource: util.MessageToAny(out),
+	}
+}
+
+// BuildScopedRoutes produces the scoped route configurations of the proxy: one per domain of the virtual hosts
+// of the port using scoped routes, pointing to the shard of its domain suffix.
+func (configgen *ConfigGeneratorImpl) BuildScopedRoutes(node *model.Proxy, req *model.PushRequest) []*discovery.Resource {
+	port := features.ScopedRoutesPort
+	if !useScopedRoutes(node, port) {
+		return nil
+	}
+	routeName := strconv.Itoa(port)
+	out := make([]*discovery.Resource, 0)
+	for _, vhost := range sidecarOutboundPortVirtualHosts(node, req.Push, port, map[int][]*route.VirtualHost{}) {
+		shard := scopedRouteConfigName(routeName, virtualHostDomainSuffix(vhost))
+		for _, domain := range vhost.Domains {
+			if strings.Contains(domain, "*") {
+				continue
+			}
+			scope := &route.ScopedRouteConfiguration{
+				Name:                   scopedRouteConfigName(routeName, domain),
+				RouteConfigurationName: shard,
+				Key: &route.ScopedRouteConfiguration_Key{
+					Fragments: []*route.ScopedRouteConfiguration_Key_Fragment{{
+						Type: &route.ScopedRouteConfiguration_Key_Fragment_StringKey{StringKey: domain},
+					}},
+				},
+			}
+			out = append(out, &discovery.Resource{
+				Name:     scope.Name,
+				Resource: util.MessageToAny(scope),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
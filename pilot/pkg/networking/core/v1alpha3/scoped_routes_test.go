@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/google/go-cmp/cmp"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+func TestScopedRoutes(t *testing.T) {
+	defer func(v int) { features.ScopedRoutesPort = v }(features.ScopedRoutesPort)
+	features.ScopedRoutesPort = 80
+
+	registryOnly := func() *meshconfig.MeshConfig {
+		m := mesh.DefaultMeshConfig()
+		m.OutboundTrafficPolicy = &meshconfig.MeshConfig_OutboundTrafficPolicy{Mode: meshconfig.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY}
+		return &m
+	}
+	services := []*model.Service{
+		buildHTTPService("a.default.svc.cluster.local", visibility.Public, "10.0.0.1", "default", 80),
+		buildHTTPService("b.default.svc.cluster.local", visibility.Public, "10.0.0.2", "default", 80),
+		buildHTTPService("c.other.svc.cluster.local", visibility.Public, "10.0.0.3", "other", 80),
+	}
+
+	t.Run("sharded", func(t *testing.T) {
+		cg := NewConfigGenTest(t, TestOptions{Services: services, MeshConfig: registryOnly()})
+		proxy := cg.SetupProxy(nil)
+
+		l := xdstest.ExtractListener("0.0.0.0_80", cg.Listeners(proxy))
+		if l == nil {
+			t.Fatal("listener not found")
+		}
+		var scoped *hcm.ScopedRoutes
+		for _, fc := range l.FilterChains {
+			if h := xdstest.ExtractHTTPConnectionManager(t, fc); h != nil {
+				scoped = h.GetScopedRoutes()
+			}
+		}
+		if scoped.GetName() != "80" {
+			t.Fatalf("expected scoped routes for port 80, got %v", scoped)
+		}
+
+		shards := map[string]string{}
+		for _, r := range cg.ConfigGen.BuildScopedRoutes(proxy, &model.PushRequest{Push: cg.PushContext()}) {
+			scope := &route.ScopedRouteConfiguration{}
+			if err := r.Resource.UnmarshalTo(scope); err != nil {
+				t.Fatal(err)
+			}
+			shards[scope.Key.Fragments[0].GetStringKey()] = scope.RouteConfigurationName
+		}
+		for domain, shard := range map[string]string{
+			"a.default.svc.cluster.local":    "80|default.svc.cluster.local",
+			"a.default.svc.cluster.local:80": "80|default.svc.cluster.local",
+			"b":                              "80|default.svc.cluster.local",
+			"10.0.0.3":                       "80|other.svc.cluster.local",
+		} {
+			if shards[domain] != shard {
+				t.Errorf("expected domain %v in shard %v, got %v", domain, shard, shards[domain])
+			}
+		}
+
+		resources, _ := cg.ConfigGen.BuildHTTPRoutes(proxy, &model.PushRequest{Push: cg.PushContext()},
+			[]string{"80|default.svc.cluster.local"})
+		if len(resources) != 1 {
+			t.Fatalf("expected a single route configuration, got %v", resources)
+		}
+		rc := &route.RouteConfiguration{}
+		if err := resources[0].Resource.UnmarshalTo(rc); err != nil {
+			t.Fatal(err)
+		}
+		xdstest.ValidateRouteConfiguration(t, rc)
+		got := []string{}
+		for _, vh := range rc.VirtualHosts {
+			got = append(got, vh.Name)
+		}
+		want := []string{"a.default.svc.cluster.local:80", "b.default.svc.cluster.local:80"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected virtual hosts: %v", diff)
+		}
+	})
+
+	t.Run("allow any", func(t *testing.T) {
+		cg := NewConfigGenTest(t, TestOptions{Services: services})
+		proxy := cg.SetupProxy(nil)
+		if useScopedRoutes(proxy, 80) {
+			t.Fatal("scoped routes must not be used when unknown destinations are allowed")
+		}
+		if got := cg.ConfigGen.BuildScopedRoutes(proxy, &model.PushRequest{Push: cg.PushContext()}); len(got) != 0 {
+			t.Fatalf("expected no scoped routes, got %v", got)
+		}
+	})
+
+	t.Run("wildcard host", func(t *testing.T) {
+		wildcard := append([]*model.Service{
+			buildHTTPService("*.example.com", visibility.Public, wildcardIP, "default", 80),
+		}, services...)
+		cg := NewConfigGenTest(t, TestOptions{Services: wildcard, MeshConfig: registryOnly()})
+		if useScopedRoutes(cg.SetupProxy(nil), 80) {
+			t.Fatal("scoped routes must not be used with wildcard hosts")
+		}
+	})
+}
+
+func TestVirtualHostDomainSuffix(t *testing.T) {
+	cases := map[string]string{
+		"a.default.svc.cluster.local:80": "default.svc.cluster.local",
+		"bookinfo.com:80":                "com",
+		"foo:80":                         "foo",
+		"foo.":                           "foo.",
+	}
+	for name, want := range cases {
+		if got := virtualHostDomainSuffix(&route.VirtualHost{Name: name}); got != want {
+			t.Errorf("%v: got %v, want %v", name, got, want)
+		}
+	}
+	if port, suffix, ok := parseScopedRouteConfigName("80|default.svc.cluster.local"); !ok || port != 80 || suffix != "default.svc.cluster.local" {
+		t.Errorf("unexpected parse result %v %v %v", port, suffix, ok)
+	}
+	for _, name := range []string{"80", "http_proxy", "outbound|80||foo", "80|"} {
+		if _, _, ok := parseScopedRouteConfigName(name); ok {
+			t.Errorf("%v must not parse as a scoped route configuration", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"sort"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// applySecurityHeaders adds the headers of the security headers policy of the proxy to the responses of a route
+// configuration. Gateways always apply the policy, sidecars only if the policy includes them. The headers are only
+// added if absent, so that applications can set stricter values.
+func applySecurityHeaders(node *model.Proxy, push *model.PushContext, r *route.RouteConfiguration) {
+	policy := push.ProxyConfigs.SecurityHeaders(node.Metadata)
+	if policy == nil || (node.Type == model.SidecarProxy && !policy.Sidecars) {
+		return
+	}
+	headers := policy.ResponseHeaders()
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r.ResponseHeadersToAdd = append(r.ResponseHeadersToAdd, &core.HeaderValueOption{
+			Header:       &core.HeaderValue{Key: name, Value: headers[name]},
+			AppendAction: core.HeaderValueOption_ADD_IF_ABSENT,
+		})
+	}
+}
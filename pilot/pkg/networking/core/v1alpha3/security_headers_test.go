@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/security"
+)
+
+func TestApplySecurityHeaders(t *testing.T) {
+	store := model.NewFakeStore()
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.ProxyConfig,
+			Name:             "mesh",
+			Namespace:        "istio-system",
+			Annotations: map[string]string{
+				security.SecurityHeadersAnnotation: `{"headers": {"Content-Security-Policy": "default-src 'self'"}}`,
+			},
+		},
+		Spec: &v1beta1.ProxyConfig{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	pcs, err := model.GetProxyConfigs(model.MakeIstioStore(store), &meshconfig.MeshConfig{RootNamespace: "istio-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := &model.PushContext{ProxyConfigs: pcs}
+
+	cases := []struct {
+		name  string
+		proxy *model.Proxy
+		want  []string
+	}{
+		{
+			name:  "gateway",
+			proxy: &model.Proxy{Type: model.Router, Metadata: &model.NodeMetadata{Namespace: "istio-ingress"}},
+			want:  []string{"Content-Security-Policy", "Strict-Transport-Security", "X-Content-Type-Options"},
+		},
+		{
+			name:  "sidecar",
+			proxy: &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{Namespace: "default"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &route.RouteConfiguration{}
+			applySecurityHeaders(tt.proxy, push, r)
+			if len(r.ResponseHeadersToAdd) != len(tt.want) {
+				t.Fatalf("got headers %v, want %v", r.ResponseHeadersToAdd, tt.want)
+			}
+			for i, h := range r.ResponseHeadersToAdd {
+				if h.Header.Key != tt.want[i] || h.AppendAction != core.HeaderValueOption_ADD_IF_ABSENT {
+					t.Errorf("got header %v, want %s added if absent", h, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1535,6 +1535,41 @@ spec:
 				},
 			},
 		},
+		{
+			name:   "sidecar http over TLS simple mode with peer auth on port permissive",
+			config: peerAuthConfig("PERMISSIVE") + sidecarSimple("HTTPS"),
+			calls: []simulation.Expect{
+				{
+					Name: "http over tls",
+					Call: mkCall(9080, simulation.HTTP, simulation.TLS, []simulation.CustomFilterChainValidation{expectedTLSContext}, ""),
+					Result: simulation.Result{
+						FilterChainMatched: "1.1.1.1_9080",
+						ClusterMatched:     "inbound|9080||",
+						VirtualHostMatched: "inbound|http|9080",
+						RouteMatched:       "default",
+						ListenerMatched:    "virtualInbound",
+					},
+				},
+				{
+					Name: "http over mtls",
+					Call: mkCall(9080, simulation.HTTP, simulation.MTLS, nil, "default"),
+					Result: simulation.Result{
+						FilterChainMatched: "1.1.1.1_9080",
+						ClusterMatched:     "inbound|9080||",
+						ListenerMatched:    "virtualInbound",
+					},
+				},
+				{
+					Name: "plaintext",
+					Call: mkCall(9080, simulation.HTTP, simulation.Plaintext, nil, ""),
+					Result: simulation.Result{
+						FilterChainMatched: "1.1.1.1_9080",
+						ClusterMatched:     "inbound|9080||",
+						ListenerMatched:    "virtualInbound",
+					},
+				},
+			},
+		},
 		{
 			name:   "sidecar http over TLS SIMPLE mode with peer auth on port STRICT",
 			config: peerAuthConfig("STRICT") + sidecarMutual("TLS"),
@@ -1575,3 +1610,45 @@ spec:
 		})
 	}
 }
+
+func TestInboundPortDispatch(t *testing.T) {
+	svc := `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+spec:
+  hosts:
+  - foo.bar
+  endpoints:
+  - address: 1.1.1.1
+  location: MESH_INTERNAL
+  resolution: STATIC
+  ports:
+  - name: http
+    number: 80
+    protocol: HTTP
+---
+`
+	proxy := &model.Proxy{Metadata: &model.NodeMetadata{Annotations: map[string]string{
+		v1alpha3.InboundPortDispatchAnnotation: `[{"port": 80, "prefix": "/api/v2", "targetPort": 9090}]`,
+	}}}
+	mkCall := func(path string) simulation.Call {
+		return simulation.Call{Port: 80, Path: path, Protocol: simulation.HTTP, CallMode: simulation.CallModeInbound}
+	}
+	runSimulationTest(t, proxy, xds.FakeOptions{}, simulationTest{
+		config: svc,
+		calls: []simulation.Expect{
+			{
+				Name:   "default",
+				Call:   mkCall("/"),
+				Result: simulation.Result{ClusterMatched: "inbound|80||"},
+			},
+			{
+				Name:   "prefix",
+				Call:   mkCall("/api/v2/users"),
+				Result: simulation.Result{ClusterMatched: "inbound|9090|dispatch|"},
+			},
+		},
+	})
+}
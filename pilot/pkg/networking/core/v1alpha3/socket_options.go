@@ -0,0 +1,242 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/log"
+)
+
+// Linux socket option levels and names. Envoy passes these through to setsockopt as is.
+const (
+	solSocket   = 1
+	ipprotoIP   = 0
+	ipprotoTCP  = 6
+	ipprotoIPv6 = 41
+
+	soSndbuf    = 7
+	soRcvbuf    = 8
+	soKeepalive = 9
+	soMark      = 36
+	tcpNodelay  = 1
+	ipTos       = 1
+	ipv6Tclass  = 67
+)
+
+// tproxyMark is the firewall mark of the connections of the TPROXY interception mode, set by the
+// INBOUND_TPROXY_MARK of istio-iptables. Setting it with SO_MARK would make the connections loop back to the proxy.
+const tproxyMark = 1337
+
+type socketOptionName struct {
+	level int64
+	name  int64
+}
+
+// supportedSocketOptions are the socket options that can be set by name in the LISTENER_SOCKET_OPTIONS
+// and CLUSTER_SOCKET_OPTIONS proxy metadata, and in the UpstreamSocketOptionsAnnotation of DestinationRules.
+var supportedSocketOptions = map[string][]socketOptionName{
+	"TCP_NODELAY":  {{ipprotoTCP, tcpNodelay}},
+	"SO_RCVBUF":    {{solSocket, soRcvbuf}},
+	"SO_SNDBUF":    {{solSocket, soSndbuf}},
+	"SO_KEEPALIVE": {{solSocket, soKeepalive}},
+	// SO_MARK sets the firewall mark of the packets, which requires the NET_ADMIN capability.
+	"SO_MARK":     {{solSocket, soMark}},
+	"IP_TOS":      {{ipprotoIP, ipTos}},
+	"IPV6_TCLASS": {{ipprotoIPv6, ipv6Tclass}},
+	// DSCP sets the differentiated services code point of both IPv4 and IPv6 traffic. It occupies the
+	// upper six bits of the TOS and traffic class fields.
+	"DSCP": {{ipprotoIP, ipTos}, {ipprotoIPv6, ipv6Tclass}},
+}
+
+// socketOptionsForFamily returns the socket options applying to the sockets of the address family. Setting an IPv4
+// option on an IPv6 socket, or an IPv6 option on an IPv4 socket, fails and makes Envoy reject the socket.
+func socketOptionsForFamily(opts []*core.SocketOption, ipv6 bool) []*core.SocketOption {
+	out := make([]*core.SocketOption, 0, len(opts))
+	for _, opt := range opts {
+		if (ipv6 && opt.Level == ipprotoIP) || (!ipv6 && opt.Level == ipprotoIPv6) {
+			continue
+		}
+		out = append(out, opt)
+	}
+	return out
+}
+
+// parseSocketOptions parses a comma separated list of NAME=value socket options, for example
+// "TCP_NODELAY=1,SO_RCVBUF=262144,DSCP=46", into Envoy socket options applied before the socket is bound.
+func parseSocketOptions(s string) ([]*core.SocketOption, error) {
+	var res []*core.SocketOption
+	for _, opt := range strings.Split(s, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid socket option %q, must be of the form NAME=value", opt)
+		}
+		name := strings.ToUpper(strings.TrimSpace(kv[0]))
+		names, f := supportedSocketOptions[name]
+		if !f {
+			return nil, fmt.Errorf("unsupported socket option %q", name)
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 32)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid value for socket option %s: %q", name, kv[1])
+		}
+		if name == "SO_MARK" && (value == 0 || value == tproxyMark) {
+			return nil, fmt.Errorf("invalid value for socket option SO_MARK: %d, must not be 0 or the TPROXY mark %d",
+				value, tproxyMark)
+		}
+		if name == "DSCP" {
+			if value > 63 {
+				return nil, fmt.Errorf("invalid value for socket option DSCP: %d, must be at most 63", value)
+			}
+			value <<= 2
+		}
+		for _, n := range names {
+			res = append(res, &core.SocketOption{
+				Level: n.level,
+				Name:  n.name,
+				Value: &core.SocketOption_IntValue{IntValue: value},
+				State: core.SocketOption_STATE_PREBIND,
+			})
+		}
+	}
+	return res, nil
+}
+
+// UpstreamSocketOptionsAnnotation is the annotation of DestinationRules setting socket options on the upstream
+// connections to the destination, with the same NAME=value syntax as the CLUSTER_SOCKET_OPTIONS proxy metadata. For
+// example "DSCP=46" marks the traffic to latency critical services as expedited forwarding, so that the network can
+// prioritize it. The options are added to the ones of the proxy metadata.
+const UpstreamSocketOptionsAnnotation = "networking.istio.io/upstream-socket-options"
+
+// upstreamSocketOptionsForDestinationRule reads the UpstreamSocketOptionsAnnotation of the DestinationRule. Invalid
+// values are ignored.
+func upstreamSocketOptionsForDestinationRule(dr *config.Config) []*core.SocketOption {
+	if dr == nil {
+		return nil
+	}
+	v, f := dr.Annotations[UpstreamSocketOptionsAnnotation]
+	if !f {
+		return nil
+	}
+	opts, err := parseSocketOptions(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q on DestinationRule %s/%s: %v", UpstreamSocketOptionsAnnotation, v,
+			dr.Namespace, dr.Name, err)
+		return nil
+	}
+	return opts
+}
+
+// applyUpstreamSocketOptions adds the socket options of the UpstreamSocketOptionsAnnotation to the upstream
+// connections of the cluster, of the IPv6 address family if ipv6 is true, or of the IPv4 address family otherwise.
+// SO_MARK requires the NET_ADMIN capability, which only the sidecars of the TPROXY interception mode are granted, so it
+// is dropped for the other proxies rather than failing their upstream connections.
+func (cb *ClusterBuilder) applyUpstreamSocketOptions(c *cluster.Cluster, opts []*core.SocketOption, ipv6 bool) {
+	opts = socketOptionsForFamily(opts, ipv6)
+	if !cb.netAdmin {
+		allowed := make([]*core.SocketOption, 0, len(opts))
+		for _, opt := range opts {
+			if opt.Level == solSocket && opt.Name == soMark {
+				log.Debugf("ignoring SO_MARK upstream socket option of cluster %s for proxy %s without NET_ADMIN", c.Name, cb.proxyID)
+				continue
+			}
+			allowed = append(allowed, opt)
+		}
+		opts = allowed
+	}
+	if len(opts) == 0 {
+		return
+	}
+	if c.UpstreamBindConfig == nil {
+		c.UpstreamBindConfig = cb.upstreamBindConfig(nil, ipv6)
+	}
+	// The socket options of the proxy metadata are shared by all clusters, so they must not be appended to in place.
+	merged := make([]*core.SocketOption, 0, len(c.UpstreamBindConfig.SocketOptions)+len(opts))
+	merged = append(merged, c.UpstreamBindConfig.SocketOptions...)
+	c.UpstreamBindConfig.SocketOptions = append(merged, opts...)
+}
+
+// upstreamIPv6 returns whether the upstream connections to the service use IPv6: those to a service with an IPv6
+// address, or to a service without address from a proxy without IPv4 address.
+func (cb *ClusterBuilder) upstreamIPv6(service *model.Service) bool {
+	if service != nil {
+		if ip := net.ParseIP(service.DefaultAddress); ip != nil && !ip.IsUnspecified() {
+			return ip.To4() == nil
+		}
+	}
+	return !cb.supportsIPv4
+}
+
+// upstreamBindConfig returns a bind config setting the socket options of upstream connections of the IPv6 address
+// family if ipv6 is true, or of the IPv4 address family otherwise. Socket options of upstream connections can only be
+// set through a bind config. Binding to the wildcard address of the family with port 0 keeps the source address
+// selection of the kernel.
+func (cb *ClusterBuilder) upstreamBindConfig(opts []*core.SocketOption, ipv6 bool) *core.BindConfig {
+	wildcard := WildcardAddress
+	if ipv6 {
+		wildcard = WildcardIPv6Address
+	}
+	return &core.BindConfig{
+		SourceAddress: &core.SocketAddress{
+			Address:       wildcard,
+			PortSpecifier: &core.SocketAddress_PortValue{PortValue: 0},
+		},
+		SocketOptions: socketOptionsForFamily(opts, ipv6),
+	}
+}
+
+// applySocketOptions sets the socket options configured in the proxy metadata on all TCP listeners.
+func (lb *ListenerBuilder) applySocketOptions() {
+	if lb.node.Metadata.ListenerSocketOptions == "" {
+		return
+	}
+	opts, err := parseSocketOptions(lb.node.Metadata.ListenerSocketOptions)
+	if err != nil {
+		log.Warnf("ignoring listener socket options of proxy %s: %v", lb.node.ID, err)
+		return
+	}
+	apply := func(l *listener.Listener) {
+		if l == nil || l.GetAddress().GetSocketAddress().GetProtocol() == core.SocketAddress_UDP {
+			return
+		}
+		ip := net.ParseIP(l.GetAddress().GetSocketAddress().GetAddress())
+		l.SocketOptions = append(l.SocketOptions, socketOptionsForFamily(opts, ip != nil && ip.To4() == nil)...)
+	}
+	for _, l := range lb.inboundListeners {
+		apply(l)
+	}
+	for _, l := range lb.outboundListeners {
+		apply(l)
+	}
+	for _, l := range lb.gatewayListeners {
+		apply(l)
+	}
+	apply(lb.httpProxyListener)
+	apply(lb.virtualOutboundListener)
+	apply(lb.virtualInboundListener)
+}
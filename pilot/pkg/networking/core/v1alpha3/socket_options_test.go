@@ -0,0 +1,281 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+func intSocketOption(level, name, value int64) *core.SocketOption {
+	return &core.SocketOption{
+		Level: level,
+		Name:  name,
+		Value: &core.SocketOption_IntValue{IntValue: value},
+		State: core.SocketOption_STATE_PREBIND,
+	}
+}
+
+func TestParseSocketOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []*core.SocketOption
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+		},
+		{
+			name: "tcp and buffers",
+			in:   "TCP_NODELAY=1, so_rcvbuf=262144",
+			want: []*core.SocketOption{
+				intSocketOption(6, 1, 1),
+				intSocketOption(1, 8, 262144),
+			},
+		},
+		{
+			name: "dscp sets tos and traffic class",
+			in:   "DSCP=46",
+			want: []*core.SocketOption{
+				intSocketOption(0, 1, 184),
+				intSocketOption(41, 67, 184),
+			},
+		},
+		{
+			name:    "unsupported option",
+			in:      "SO_REUSEPORT=1",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			in:      "TCP_NODELAY",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			in:      "SO_RCVBUF=big",
+			wantErr: true,
+		},
+		{
+			name:    "dscp out of range",
+			in:      "DSCP=64",
+			wantErr: true,
+		},
+		{
+			name:    "mark colliding with the TPROXY mark",
+			in:      "SO_MARK=1337",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSocketOptions(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected socket options: %v", diff)
+			}
+		})
+	}
+}
+
+func TestListenerSocketOptions(t *testing.T) {
+	proxy := getProxy()
+	proxy.Metadata.ListenerSocketOptions = "SO_RCVBUF=262144"
+	listeners := buildOutboundListeners(t, &fakePlugin{}, proxy, nil, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+	if len(listeners) == 0 {
+		t.Fatalf("expected listeners")
+	}
+
+	builder := NewListenerBuilder(proxy, nil)
+	builder.outboundListeners = listeners
+	builder.applySocketOptions()
+	want := []*core.SocketOption{intSocketOption(1, 8, 262144)}
+	for _, l := range builder.outboundListeners {
+		if diff := cmp.Diff(want, l.SocketOptions, protocmp.Transform()); diff != "" {
+			t.Fatalf("unexpected socket options on %s: %v", l.Name, diff)
+		}
+	}
+}
+
+func TestSocketOptionsForFamily(t *testing.T) {
+	opts, err := parseSocketOptions("TCP_NODELAY=1,DSCP=46")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*core.SocketOption{intSocketOption(6, 1, 1), intSocketOption(0, 1, 184)}
+	if diff := cmp.Diff(want, socketOptionsForFamily(opts, false), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected IPv4 socket options: %v", diff)
+	}
+	want = []*core.SocketOption{intSocketOption(6, 1, 1), intSocketOption(41, 67, 184)}
+	if diff := cmp.Diff(want, socketOptionsForFamily(opts, true), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected IPv6 socket options: %v", diff)
+	}
+}
+
+func TestClusterSocketOptions(t *testing.T) {
+	proxy := newSidecarProxy()
+	proxy.IPAddresses = []string{"10.0.0.1"}
+	proxy.Metadata.ClusterSocketOptions = "DSCP=10"
+	cg := NewConfigGenTest(t, TestOptions{})
+	cb := NewClusterBuilder(cg.SetupProxy(proxy), &model.PushRequest{Push: cg.PushContext()}, nil)
+	port := &model.Port{Name: "http", Port: 8080, Protocol: protocol.HTTP}
+	c := cb.buildDefaultCluster("outbound|8080||foo", cluster.Cluster_EDS, nil, model.TrafficDirectionOutbound, port, nil, nil)
+	bind := c.cluster.GetUpstreamBindConfig()
+	if bind.GetSourceAddress().GetAddress() != WildcardAddress {
+		t.Fatalf("expected wildcard source address, got %v", bind)
+	}
+	want := []*core.SocketOption{intSocketOption(0, 1, 40)}
+	if diff := cmp.Diff(want, bind.GetSocketOptions(), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected socket options: %v", diff)
+	}
+
+	// The connections to an IPv6 service bind to the IPv6 wildcard address, with the IPv6 options only.
+	ipv6Service := &model.Service{Hostname: "foo", DefaultAddress: "2001:db8::1"}
+	c = cb.buildDefaultCluster("outbound|8080||foo", cluster.Cluster_EDS, nil, model.TrafficDirectionOutbound, port, ipv6Service, nil)
+	bind = c.cluster.GetUpstreamBindConfig()
+	if bind.GetSourceAddress().GetAddress() != WildcardIPv6Address {
+		t.Fatalf("expected IPv6 wildcard source address, got %v", bind)
+	}
+	want = []*core.SocketOption{intSocketOption(41, 67, 40)}
+	if diff := cmp.Diff(want, bind.GetSocketOptions(), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected IPv6 socket options: %v", diff)
+	}
+
+	inbound := cb.buildDefaultCluster("inbound|8080||", cluster.Cluster_STATIC,
+		[]*endpoint.LocalityLbEndpoints{{}}, model.TrafficDirectionInbound, port, nil, nil)
+	if inbound.cluster.GetUpstreamBindConfig() != nil {
+		t.Fatalf("expected no bind config for inbound clusters")
+	}
+}
+
+const upstreamSocketOptionsConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: backend
+  namespace: default
+spec:
+  hosts:
+  - backend.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: backend
+  namespace: default
+  annotations:
+    networking.istio.io/upstream-socket-options: "DSCP=46,SO_MARK=7"
+spec:
+  host: backend.example.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+func TestUpstreamSocketOptions(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: upstreamSocketOptionsConfig})
+	proxy := newSidecarProxy()
+	proxy.IPAddresses = []string{"10.0.0.1"}
+	proxy.Metadata.ClusterSocketOptions = "TCP_NODELAY=1"
+	proxy.Metadata.InterceptionMode = model.InterceptionTproxy
+	clusters := cg.Clusters(cg.SetupProxy(proxy))
+
+	want := []*core.SocketOption{
+		intSocketOption(6, 1, 1),
+		intSocketOption(0, 1, 184),
+		intSocketOption(1, 36, 7),
+	}
+	for _, name := range []string{"outbound|80||backend.example.com", "outbound|80|v1|backend.example.com"} {
+		bind := xdstest.ExtractCluster(name, clusters).GetUpstreamBindConfig()
+		if bind.GetSourceAddress().GetAddress() != WildcardAddress {
+			t.Fatalf("%s: expected wildcard source address, got %v", name, bind)
+		}
+		if diff := cmp.Diff(want, bind.GetSocketOptions(), protocmp.Transform()); diff != "" {
+			t.Fatalf("%s: unexpected socket options: %v", name, diff)
+		}
+	}
+	// The socket options of the DestinationRule do not leak to other clusters.
+	for _, c := range clusters {
+		if c.Name == "outbound|80||backend.example.com" || c.Name == "outbound|80|v1|backend.example.com" {
+			continue
+		}
+		if len(c.GetUpstreamBindConfig().GetSocketOptions()) > 1 {
+			t.Fatalf("%s: unexpected socket options %v", c.Name, c.GetUpstreamBindConfig().GetSocketOptions())
+		}
+	}
+}
+
+func TestUpstreamSocketOptionsWithoutNetAdmin(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: upstreamSocketOptionsConfig})
+	proxy := newSidecarProxy()
+	proxy.IPAddresses = []string{"10.0.0.1"}
+	proxy.Metadata.InterceptionMode = model.InterceptionRedirect
+	clusters := cg.Clusters(cg.SetupProxy(proxy))
+
+	// SO_MARK is dropped for the proxies without the NET_ADMIN capability.
+	want := []*core.SocketOption{intSocketOption(0, 1, 184)}
+	bind := xdstest.ExtractCluster("outbound|80||backend.example.com", clusters).GetUpstreamBindConfig()
+	if diff := cmp.Diff(want, bind.GetSocketOptions(), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected socket options: %v", diff)
+	}
+}
+
+func TestUpstreamSocketOptionsForDestinationRule(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []*core.SocketOption
+	}{
+		{value: "SO_MARK=255", want: []*core.SocketOption{intSocketOption(1, 36, 255)}},
+		{value: "DSCP=64", want: nil},
+		{value: "SO_MARK=1337", want: nil},
+		{value: "SO_MARK=0", want: nil},
+		{value: "unknown=1", want: nil},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			dr := &config.Config{Meta: config.Meta{Annotations: map[string]string{UpstreamSocketOptionsAnnotation: tt.value}}}
+			if diff := cmp.Diff(tt.want, upstreamSocketOptionsForDestinationRule(dr), protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected socket options: %v", diff)
+			}
+		})
+	}
+	if upstreamSocketOptionsForDestinationRule(nil) != nil {
+		t.Fatalf("expected no socket options without DestinationRule")
+	}
+}
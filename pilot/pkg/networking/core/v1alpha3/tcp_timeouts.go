@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+// Annotations of a workload overriding the timeouts of the TCP proxy of its sidecar per port. The value is a
+// duration applying to all the ports, or a comma separated list of port=duration, for example "5672=24h,5671=24h".
+// The port is the port of the workload for inbound traffic, and the port of the service for outbound traffic.
+const (
+	// TCPIdleTimeoutAnnotation overrides the idle timeout of TCP connections, after which connections with no
+	// traffic in either direction are closed. "0s" disables the idle timeout.
+	TCPIdleTimeoutAnnotation = "sidecar.istio.io/tcpIdleTimeout"
+	// TCPMaxConnectionDurationAnnotation overrides the maximum duration of TCP connections, after which they are
+	// closed even if they are not idle.
+	TCPMaxConnectionDurationAnnotation = "sidecar.istio.io/tcpMaxConnectionDuration"
+)
+
+// applyTCPProxyTimeouts sets the idle timeout and maximum connection duration of a TCP proxy filter for the given
+// port. The per-port annotations of the workload take precedence over the IDLE_TIMEOUT and
+// TCP_MAX_CONNECTION_DURATION proxy metadata, which can be set for the whole mesh in its default proxy config.
+func applyTCPProxyTimeouts(node *model.Proxy, port int, tcpProxy *tcp.TcpProxy) {
+	if d, ok := tcpTimeout(node, TCPIdleTimeoutAnnotation, node.Metadata.IdleTimeout, port); ok {
+		tcpProxy.IdleTimeout = durationpb.New(d)
+	}
+	if d, ok := tcpTimeout(node, TCPMaxConnectionDurationAnnotation, node.Metadata.TCPMaxConnectionDuration, port); ok && d > 0 {
+		tcpProxy.MaxDownstreamConnectionDuration = durationpb.New(d)
+	}
+}
+
+// tcpTimeout returns the timeout for the given port from the workload annotation, or from the proxy metadata value.
+func tcpTimeout(node *model.Proxy, annotation, metadata string, port int) (time.Duration, bool) {
+	if v, f := node.Metadata.Annotations[annotation]; f {
+		d, ok, err := parsePortDurations(v, port)
+		if err != nil {
+			log.Warnf("ignoring invalid %s annotation of proxy %s: %v", annotation, node.ID, err)
+		} else if ok {
+			return d, true
+		}
+	}
+	d, err := time.ParseDuration(metadata)
+	return d, err == nil
+}
+
+// parsePortDurations returns the duration for the given port from a duration, or a comma separated list of
+// port=duration. It returns false if the port has no duration.
+func parsePortDurations(v string, port int) (time.Duration, bool, error) {
+	if !strings.Contains(v, "=") {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return 0, false, fmt.Errorf("invalid duration %q", v)
+		}
+		return d, true, nil
+	}
+	for _, pd := range strings.Split(v, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pd), "=", 2)
+		if len(kv) != 2 {
+			return 0, false, fmt.Errorf("invalid port duration %q, must be of the form port=duration", pd)
+		}
+		p, err := strconv.Atoi(strings.TrimSpace(kv[0]))
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid port %q", kv[0])
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil || d < 0 {
+			return 0, false, fmt.Errorf("invalid duration %q for port %d", kv[1], p)
+		}
+		if p == port {
+			return d, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+	"time"
+
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestParsePortDurations(t *testing.T) {
+	cases := []struct {
+		value   string
+		port    int
+		want    time.Duration
+		wantOk  bool
+		wantErr bool
+	}{
+		{value: "1h", port: 5672, want: time.Hour, wantOk: true},
+		{value: "5672=24h, 5671=12h", port: 5671, want: 12 * time.Hour, wantOk: true},
+		{value: "5672=24h", port: 8080},
+		{value: "5672=0s", port: 5672, want: 0, wantOk: true},
+		{value: "soon", port: 5672, wantErr: true},
+		{value: "amqp=24h", port: 5672, wantErr: true},
+		{value: "5672=-1h", port: 5672, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok, err := parsePortDurations(tt.value, tt.port)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want || ok != tt.wantOk {
+				t.Fatalf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestApplyTCPProxyTimeouts(t *testing.T) {
+	cases := []struct {
+		name        string
+		metadata    *model.NodeMetadata
+		port        int
+		idle        time.Duration
+		maxDuration time.Duration
+	}{
+		{
+			name:     "defaults",
+			metadata: &model.NodeMetadata{},
+			port:     5672,
+		},
+		{
+			name:        "proxy metadata",
+			metadata:    &model.NodeMetadata{IdleTimeout: "2h", TCPMaxConnectionDuration: "24h"},
+			port:        5672,
+			idle:        2 * time.Hour,
+			maxDuration: 24 * time.Hour,
+		},
+		{
+			name: "annotations override proxy metadata",
+			metadata: &model.NodeMetadata{
+				IdleTimeout:              "2h",
+				TCPMaxConnectionDuration: "24h",
+				Annotations: map[string]string{
+					TCPIdleTimeoutAnnotation:           "5672=0s",
+					TCPMaxConnectionDurationAnnotation: "5672=168h",
+				},
+			},
+			port:        5672,
+			idle:        0,
+			maxDuration: 168 * time.Hour,
+		},
+		{
+			name: "annotations for other ports",
+			metadata: &model.NodeMetadata{
+				IdleTimeout: "2h",
+				Annotations: map[string]string{TCPIdleTimeoutAnnotation: "5671=0s"},
+			},
+			port: 5672,
+			idle: 2 * time.Hour,
+		},
+		{
+			name: "invalid annotation",
+			metadata: &model.NodeMetadata{
+				IdleTimeout: "2h",
+				Annotations: map[string]string{TCPIdleTimeoutAnnotation: "forever"},
+			},
+			port: 5672,
+			idle: 2 * time.Hour,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tcpProxy := &tcp.TcpProxy{}
+			applyTCPProxyTimeouts(&model.Proxy{Metadata: tt.metadata}, tt.port, tcpProxy)
+			if got := tcpProxy.GetIdleTimeout().AsDuration(); got != tt.idle {
+				t.Errorf("got idle timeout %v, want %v", got, tt.idle)
+			}
+			if got := tcpProxy.GetMaxDownstreamConnectionDuration().AsDuration(); got != tt.maxDuration {
+				t.Errorf("got max connection duration %v, want %v", got, tt.maxDuration)
+			}
+		})
+	}
+}
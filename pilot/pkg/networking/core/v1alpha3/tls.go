@@ -19,6 +19,8 @@ import (
 	"sort"
 	"strings"
 
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -195,12 +197,18 @@ func buildSidecarOutboundTLSFilterChainOpts(node *model.Proxy, push *model.PushC
 		if len(destinationCIDR) > 0 || len(svcListenAddress) == 0 || (svcListenAddress == actualWildcard && bind == actualWildcard) {
 			sniHosts = []string{string(service.Hostname)}
 		}
-		destRule := push.DestinationRule(node, service)
-		destinationRule := CastDestinationRule(destRule)
+		var networkFilters []*listener.Filter
+		if useEgressProxy(node, service) {
+			networkFilters = buildOutboundEgressProxyNetworkFilters(push, node, statPrefix, service, port, listenPort)
+		} else {
+			destRule := push.DestinationRule(node, service)
+			destinationRule := CastDestinationRule(destRule)
+			networkFilters = buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, "", listenPort, destinationRule)
+		}
 		out = append(out, &filterChainOpts{
 			sniHosts:         sniHosts,
 			destinationCIDRs: []string{destinationCIDR},
-			networkFilters:   buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, "", listenPort, destinationRule),
+			networkFilters:   networkFilters,
 		})
 	}
 
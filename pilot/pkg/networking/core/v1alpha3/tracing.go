@@ -42,6 +42,13 @@ import (
 	"istio.io/pkg/log"
 )
 
+const (
+	// requestIDHeader is the header carrying the ID of requests, used as the mesh correlation ID.
+	requestIDHeader = "x-request-id"
+	// correlationIDTag is the tag of spans holding the mesh correlation ID.
+	correlationIDTag = "istio.correlation_id"
+)
+
 // this is used for testing. it should not be changed in regular code.
 var clusterLookupFn = extensionproviders.LookupCluster
 
@@ -106,6 +113,30 @@ func configureTracingFromSpec(tracing *model.TracingConfig, opts buildListenerOp
 	return routerFilterCtx, reqIDExtension
 }
 
+// configureCorrelationID makes the x-request-id of requests a correlation ID shared by every hop, when it is enabled
+// for the proxy by the Telemetry API. Requests without one are given a new ID, IDs received from outside the mesh
+// are kept rather than replaced, and the ID is added to spans. The default access log formats already include it.
+func configureCorrelationID(opts buildListenerOpts, hcm *hpb.HttpConnectionManager) {
+	if !opts.push.Telemetry.CorrelationID(opts.proxy) {
+		return
+	}
+	hcm.GenerateRequestId = wrapperspb.Bool(true)
+	hcm.PreserveExternalRequestId = true
+	if hcm.Tracing != nil {
+		hcm.Tracing.CustomTags = append(hcm.Tracing.CustomTags, &tracing.CustomTag{
+			Tag: correlationIDTag,
+			Type: &tracing.CustomTag_RequestHeader{
+				RequestHeader: &tracing.CustomTag_Header{
+					Name: requestIDHeader,
+				},
+			},
+		})
+		sort.Slice(hcm.Tracing.CustomTags, func(i, j int) bool {
+			return hcm.Tracing.CustomTags[i].Tag < hcm.Tracing.CustomTags[j].Tag
+		})
+	}
+}
+
 // TODO: follow-on work to enable bootstrapping of clusters for $(HOST_IP):PORT addresses.
 
 func configureFromProviderConfig(pushCtx *model.PushContext, meta *model.NodeMetadata,
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	udp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	istionetworking "istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/proto"
+)
+
+// udpProxyListenerFilter is the name of the UDP listener filter proxying the datagrams to a cluster.
+const udpProxyListenerFilter = "envoy.filters.udp_listener.udp_proxy"
+
+// udpServicePort is a UDP port of a service, proxied by the sidecar.
+type udpServicePort struct {
+	service *model.Service
+	port    *model.Port
+}
+
+// udpServicePorts returns the UDP ports of the services visible to the proxy which are proxied by the sidecar. Only
+// sidecars using TPROXY interception can receive the UDP traffic to the service addresses, and only the services
+// whose endpoints are discovered with EDS are proxied.
+func udpServicePorts(proxy *model.Proxy, services []*model.Service) []udpServicePort {
+	if !features.EnableUDPServicePorts || proxy.Type != model.SidecarProxy ||
+		proxy.GetInterceptionMode() != model.InterceptionTproxy {
+		return nil
+	}
+	var out []udpServicePort
+	for _, service := range services {
+		if service.MeshExternal || service.Resolution != model.ClientSideLB {
+			continue
+		}
+		for _, port := range service.Ports {
+			if port.Protocol == protocol.UDP {
+				out = append(out, udpServicePort{service: service, port: port})
+			}
+		}
+	}
+	return out
+}
+
+// buildOutboundUDPClusters builds a cluster for each UDP port of the services proxied by the sidecar. The clusters
+// are named outbound-udp|<port>||<hostname>, as a TCP port of the service may have the same number. The destination
+// rules do not apply to them, as TLS and the connection pool settings are specific to TCP.
+func (configgen *ConfigGeneratorImpl) buildOutboundUDPClusters(cb *ClusterBuilder, proxy *model.Proxy, cp clusterPatcher,
+	services []*model.Service) []*cluster.Cluster {
+	clusters := make([]*cluster.Cluster, 0)
+	for _, sp := range udpServicePorts(proxy, services) {
+		clusterName := model.BuildUDPSubsetKey(sp.service.Hostname, sp.port.Port)
+		defaultCluster := cb.buildDefaultCluster(clusterName, cluster.Cluster_EDS, nil, model.TrafficDirectionOutbound,
+			sp.port, sp.service, nil)
+		if defaultCluster == nil {
+			continue
+		}
+		cb.applyDefaultConnectionPool(defaultCluster.cluster)
+		maybeApplyEdsConfig(defaultCluster.cluster)
+		clusters = cp.conditionallyAppend(clusters, nil, defaultCluster.build())
+	}
+	return clusters
+}
+
+// buildSidecarOutboundUDPListeners builds a listener for each UDP port of the services proxied by the sidecar,
+// bound to the service address, proxying the datagrams to the cluster of the port. The listeners are transparent, so
+// that the UDP traffic redirected to the sidecar with TPROXY rules reaches them. The statistics of the sessions are
+// reported under udp.<cluster name>.
+func buildSidecarOutboundUDPListeners(node *model.Proxy, push *model.PushContext) []*listener.Listener {
+	var listeners []*listener.Listener
+	for _, sp := range udpServicePorts(node, push.Services(node)) {
+		address := sp.service.GetAddressForProxy(node)
+		if address == "" || address == constants.UnspecifiedIP {
+			continue
+		}
+		clusterName := model.BuildUDPSubsetKey(sp.service.Hostname, sp.port.Port)
+		listeners = append(listeners, &listener.Listener{
+			Name:             getListenerName(address, sp.port.Port, istionetworking.TransportProtocolUDP),
+			Address:          util.BuildNetworkAddress(address, uint32(sp.port.Port), istionetworking.TransportProtocolUDP),
+			TrafficDirection: core.TrafficDirection_OUTBOUND,
+			Transparent:      proto.BoolTrue,
+			Freebind:         proto.BoolTrue,
+			EnableReusePort:  proto.BoolTrue,
+			UdpListenerConfig: &listener.UdpListenerConfig{
+				DownstreamSocketConfig: &core.UdpSocketConfig{},
+			},
+			ListenerFilters: []*listener.ListenerFilter{{
+				Name: udpProxyListenerFilter,
+				ConfigType: &listener.ListenerFilter_TypedConfig{TypedConfig: util.MessageToAny(&udp.UdpProxyConfig{
+					StatPrefix:     clusterName,
+					RouteSpecifier: &udp.UdpProxyConfig_Cluster{Cluster: clusterName},
+				})},
+			}},
+		})
+	}
+	return listeners
+}
@@ -98,6 +98,8 @@ const (
 	TransportProtocolTCP = iota
 	// TransportProtocolQUIC is a QUIC listener
 	TransportProtocolQUIC
+	// TransportProtocolUDP is a UDP listener proxying datagrams
+	TransportProtocolUDP
 )
 
 func (tp TransportProtocol) String() string {
@@ -106,12 +108,14 @@ func (tp TransportProtocol) String() string {
 		return "tcp"
 	case TransportProtocolQUIC:
 		return "quic"
+	case TransportProtocolUDP:
+		return "udp"
 	}
 	return "unknown"
 }
 
 func (tp TransportProtocol) ToEnvoySocketProtocol() core.SocketAddress_Protocol {
-	if tp == TransportProtocolQUIC {
+	if tp == TransportProtocolQUIC || tp == TransportProtocolUDP {
 		return core.SocketAddress_UDP
 	}
 	return core.SocketAddress_TCP
@@ -239,7 +243,8 @@ func BuildCatchAllVirtualHost(allowAnyoutbound bool, sidecarDestination string)
 					},
 				},
 			},
-			IncludeRequestAttemptCount: true,
+			IncludeRequestAttemptCount:    true,
+			IncludeAttemptCountInResponse: features.IncludeAttemptCountInResponse,
 		}
 	}
 
@@ -259,7 +264,8 @@ func BuildCatchAllVirtualHost(allowAnyoutbound bool, sidecarDestination string)
 				},
 			},
 		},
-		IncludeRequestAttemptCount: true,
+		IncludeRequestAttemptCount:    true,
+		IncludeAttemptCountInResponse: features.IncludeAttemptCountInResponse,
 	}
 }
 
@@ -17,15 +17,25 @@ package authn
 import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pilot/pkg/util/sets"
 )
 
+// TrustDomainsForValidation returns the trust domains whose identities are accepted by inbound mTLS: the trust domain
+// of the mesh, its aliases and, in a multi-root mesh, the trust domains of the additional trust anchors configured in
+// caCertificates. The latter allows accepting mTLS from another mesh signed by these trust anchors, for example while
+// migrating from it.
 func TrustDomainsForValidation(meshConfig *meshconfig.MeshConfig) []string {
 	if features.SkipValidateTrustDomain {
 		return nil
 	}
 
-	tds := append([]string{meshConfig.TrustDomain}, meshConfig.TrustDomainAliases...)
+	tds := append([]string{meshConfig.TrustDomain}, trustdomain.Aliases(meshConfig)...)
+	if features.MultiRootMesh {
+		for _, ca := range meshConfig.GetCaCertificates() {
+			tds = append(tds, ca.GetTrustDomains()...)
+		}
+	}
 	return dedupTrustDomains(tds)
 }
 
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 )
 
 func TestTrustDomainsForValidation(t *testing.T) {
@@ -69,3 +70,34 @@ func TestTrustDomainsForValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestTrustDomainsForValidationMultiRoot(t *testing.T) {
+	meshConfig := &meshconfig.MeshConfig{
+		TrustDomain:        "cluster.local",
+		TrustDomainAliases: []string{"alias.domain"},
+		CaCertificates: []*meshconfig.MeshConfig_CertificateData{
+			{
+				CertificateData: &meshconfig.MeshConfig_CertificateData_Pem{Pem: "linkerd-root"},
+				TrustDomains:    []string{"*.identity.linkerd.cluster.local"},
+			},
+			{
+				CertificateData: &meshconfig.MeshConfig_CertificateData_Pem{Pem: "consul-root"},
+				TrustDomains:    []string{"11111111-2222-3333-4444-555555555555.consul", "alias.domain"},
+			},
+		},
+	}
+
+	if got, want := TrustDomainsForValidation(meshConfig), []string{"cluster.local", "alias.domain"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trustDomainsForValidation() = %#v, want %#v", got, want)
+	}
+
+	defaultValue := features.MultiRootMesh
+	features.MultiRootMesh = true
+	defer func() { features.MultiRootMesh = defaultValue }()
+	want := []string{
+		"cluster.local", "alias.domain", "*.identity.linkerd.cluster.local", "11111111-2222-3333-4444-555555555555.consul",
+	}
+	if got := TrustDomainsForValidation(meshConfig); !reflect.DeepEqual(got, want) {
+		t.Errorf("trustDomainsForValidation() = %#v, want %#v", got, want)
+	}
+}
@@ -17,6 +17,7 @@ package authz
 import (
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
@@ -79,7 +80,7 @@ func (p Plugin) buildFilter(in *plugin.InputParams, mutable *networking.MutableO
 	}
 
 	meshConfig := in.Push.Mesh
-	tdBundle := trustdomain.NewBundle(meshConfig.TrustDomain, meshConfig.TrustDomainAliases)
+	tdBundle := trustdomain.NewBundle(meshConfig.TrustDomain, trustdomain.Aliases(meshConfig))
 	option := builder.Option{
 		IsCustomBuilder: p.actionType == Custom,
 		Logger:          &builder.AuthzLogger{},
@@ -89,12 +90,19 @@ func (p Plugin) buildFilter(in *plugin.InputParams, mutable *networking.MutableO
 	if b == nil {
 		return
 	}
+	// The HTTP RBAC filter does not see the PROXY protocol TLVs set on the connections of gateways, so the policies
+	// matching them are enforced by the network RBAC filter on the HTTP filter chains too.
+	httpBuilder, connBuilder := b, (*builder.Builder)(nil)
+	if in.Node.Type == model.Router {
+		httpBuilder, connBuilder = b.SplitConnectionPolicies(wellknown.ProxyProtocol)
+	}
 
 	// We will lazily build filters for tcp/http as needed
 	httpBuilt := false
 	tcpBuilt := false
 	var httpFilters []*httppb.HttpFilter
 	var tcpFilters []*tcppb.Filter
+	var connFilters []*tcppb.Filter
 
 	for cnum := range mutable.FilterChains {
 		switch mutable.FilterChains[cnum].ListenerProtocol {
@@ -107,10 +115,16 @@ func (p Plugin) buildFilter(in *plugin.InputParams, mutable *networking.MutableO
 			mutable.FilterChains[cnum].TCP = append(mutable.FilterChains[cnum].TCP, tcpFilters...)
 		case networking.ListenerProtocolHTTP:
 			if !httpBuilt {
-				httpFilters = b.BuildHTTP()
+				if httpBuilder != nil {
+					httpFilters = httpBuilder.BuildHTTP()
+				}
+				if connBuilder != nil {
+					connFilters = connBuilder.BuildTCP()
+				}
 				httpBuilt = true
 			}
-			option.Logger.AppendDebugf("added %d HTTP filters to filter chain %d", len(httpFilters), cnum)
+			option.Logger.AppendDebugf("added %d TCP and %d HTTP filters to filter chain %d", len(connFilters), len(httpFilters), cnum)
+			mutable.FilterChains[cnum].TCP = append(mutable.FilterChains[cnum].TCP, connFilters...)
 			mutable.FilterChains[cnum].HTTP = append(mutable.FilterChains[cnum].HTTP, httpFilters...)
 		}
 	}
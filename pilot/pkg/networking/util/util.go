@@ -63,6 +63,12 @@ const (
 	Passthrough = istionetworking.Passthrough
 	// PassthroughFilterChain to catch traffic that doesn't match other filter chains.
 	PassthroughFilterChain = "PassthroughFilterChain"
+	// PassthroughPlaintextTLSFilterChain catches the plaintext HTTP sent to unknown destinations on ports which
+	// only accept TLS.
+	PassthroughPlaintextTLSFilterChain = "PassthroughPlaintextTLSFilterChain"
+	// PassthroughTLSOriginationCluster originates TLS to the original destination of the plaintext HTTP caught by the
+	// PassthroughPlaintextTLSFilterChain.
+	PassthroughTLSOriginationCluster = "PassthroughTLSOriginationCluster"
 
 	// Inbound pass through cluster need to the bind the loopback ip address for the security and loop avoidance.
 	InboundPassthroughClusterIpv4 = "InboundPassthroughClusterIpv4"
@@ -79,6 +85,10 @@ const (
 	// which determines the endpoint level transport socket configuration.
 	EnvoyTransportSocketMetadataKey = "envoy.transport_socket_match"
 
+	// EnvoyLbMetadataKey is the key under which metadata is added to an endpoint
+	// which determines the subsets of the subset load balancer the endpoint belongs to.
+	EnvoyLbMetadataKey = "envoy.lb"
+
 	// EnvoyRawBufferSocketName matched with hardcoded built-in Envoy transport name which determines
 	// endpoint level plantext transport socket configuration
 	EnvoyRawBufferSocketName = wellknown.TransportSocketRawBuffer
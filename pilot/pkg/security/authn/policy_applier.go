@@ -15,6 +15,7 @@
 package authn
 
 import (
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 
 	"istio.io/api/security/v1beta1"
@@ -36,6 +37,10 @@ type PolicyApplier interface {
 	// It may return nil, if no authentication is needed.
 	AuthNFilter(forSidecar bool) *http_conn.HttpFilter
 
+	// JwtClaimHeaders returns the request headers to add and to remove in order to copy claims of validated
+	// JWTs into request headers. They are applied on the route configuration, after the JWT filter has run.
+	JwtClaimHeaders() ([]*core.HeaderValueOption, []string)
+
 	// PortLevelSetting returns port level mTLS settings.
 	PortLevelSetting() map[uint32]*v1beta1.PeerAuthentication_MutualTLS
 
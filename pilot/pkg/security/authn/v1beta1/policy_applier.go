@@ -41,6 +41,8 @@ import (
 	authn_utils "istio.io/istio/pilot/pkg/security/authn/utils"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/security"
+	"istio.io/istio/pkg/proto"
 	"istio.io/pkg/log"
 )
 
@@ -55,6 +57,10 @@ type v1beta1PolicyApplier struct {
 	// processedJwtRules is the consolidate JWT rules from all jwtPolicies.
 	processedJwtRules []*v1beta1.JWTRule
 
+	// claimToHeaders holds, for each JWT rule, the claims to copy into request headers once the token
+	// is validated.
+	claimToHeaders map[*v1beta1.JWTRule][]security.ClaimToHeader
+
 	consolidatedPeerPolicy *v1beta1.PeerAuthentication
 
 	push *model.PushContext
@@ -65,7 +71,7 @@ func (a *v1beta1PolicyApplier) JwtFilter() *http_conn.HttpFilter {
 		return nil
 	}
 
-	filterConfigProto := convertToEnvoyJwtConfig(a.processedJwtRules, a.push)
+	filterConfigProto := convertToEnvoyJwtConfig(a.processedJwtRules, a.claimToHeaders, a.push)
 
 	if filterConfigProto == nil {
 		return nil
@@ -107,17 +113,44 @@ func (a *v1beta1PolicyApplier) setAuthnFilterForRequestAuthn(config *authn_filte
 	// Always bind request.auth.principal from JWT origin. In v2 policy, authorization config specifies what principal to
 	// choose from instead, rather than in authn config.
 	p.PrincipalBinding = authn_alpha.PrincipalBinding_USE_ORIGIN
-	for _, jwt := range a.processedJwtRules {
+	for i, jwt := range a.processedJwtRules {
 		p.Origins = append(p.Origins, &authn_alpha.OriginAuthenticationMethod{
 			Jwt: &authn_alpha.Jwt{
 				// used for getting the filter data, and all other fields are irrelevant.
-				Issuer: jwt.GetIssuer(),
+				Issuer: payloadMetadataKey(i, jwt, a.claimToHeaders),
 			},
 		})
 	}
 	return config
 }
 
+// JwtClaimHeaders returns the request header operations copying claims of a validated JWT into request
+// headers. Each configured header is removed first, so that it can't be spoofed by the downstream.
+func (a *v1beta1PolicyApplier) JwtClaimHeaders() ([]*core.HeaderValueOption, []string) {
+	var toAdd []*core.HeaderValueOption
+	var toRemove []string
+	removed := map[string]struct{}{}
+	for i, jwt := range a.processedJwtRules {
+		key := payloadMetadataKey(i, jwt, a.claimToHeaders)
+		for _, c := range a.claimToHeaders[jwt] {
+			// The header is not added if the claim is missing, which is also the case when the token
+			// was not provided or issued by another provider.
+			toAdd = append(toAdd, &core.HeaderValueOption{
+				Header: &core.HeaderValue{
+					Key:   c.Header,
+					Value: fmt.Sprintf("%%DYNAMIC_METADATA(%s:%s:%s)%%", authn_model.EnvoyJwtFilterName, key, c.Claim),
+				},
+				Append: proto.BoolFalse,
+			})
+			if _, f := removed[c.Header]; !f {
+				removed[c.Header] = struct{}{}
+				toRemove = append(toRemove, c.Header)
+			}
+		}
+	}
+	return toAdd, toRemove
+}
+
 // AuthNFilter returns the Istio authn filter config:
 // - If RequestAuthentication is used, it overwrite the settings for request principal validation and extraction based on the new API.
 // - If RequestAuthentication is used, principal binding is always set to ORIGIN.
@@ -159,12 +192,30 @@ func NewPolicyApplier(rootNamespace string,
 	peerPolicies []*config.Config,
 	push *model.PushContext) authn.PolicyApplier {
 	processedJwtRules := []*v1beta1.JWTRule{}
+	var claimToHeaders map[*v1beta1.JWTRule][]security.ClaimToHeader
 
 	// TODO(diemtvu) should we need to deduplicate JWT with the same issuer.
 	// https://github.com/istio/istio/issues/19245
 	for idx := range jwtPolicies {
 		spec := jwtPolicies[idx].Spec.(*v1beta1.RequestAuthentication)
 		processedJwtRules = append(processedJwtRules, spec.JwtRules...)
+		if v, f := jwtPolicies[idx].Annotations[security.OutputClaimToHeadersAnnotation]; f {
+			mappings, err := security.ParseOutputClaimToHeaders(v)
+			if err != nil {
+				authnLog.Warnf("ignoring invalid %s annotation on %s/%s: %v",
+					security.OutputClaimToHeadersAnnotation, jwtPolicies[idx].Namespace, jwtPolicies[idx].Name, err)
+				continue
+			}
+			if len(mappings) == 0 {
+				continue
+			}
+			if claimToHeaders == nil {
+				claimToHeaders = map[*v1beta1.JWTRule][]security.ClaimToHeader{}
+			}
+			for _, rule := range spec.JwtRules {
+				claimToHeaders[rule] = mappings
+			}
+		}
 	}
 
 	// Sort the jwt rules by the issuer alphabetically to make the later-on generated filter
@@ -178,6 +229,7 @@ func NewPolicyApplier(rootNamespace string,
 		jwtPolicies:            jwtPolicies,
 		peerPolices:            peerPolicies,
 		processedJwtRules:      processedJwtRules,
+		claimToHeaders:         claimToHeaders,
 		consolidatedPeerPolicy: ComposePeerAuthentication(rootNamespace, peerPolicies),
 		push:                   push,
 	}
@@ -187,7 +239,8 @@ func NewPolicyApplier(rootNamespace string,
 // Each rule is expected corresponding to one JWT issuer (provider).
 // The behavior of the filter should reject all requests with invalid token. On the other hand,
 // if no token provided, the request is allowed.
-func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, push *model.PushContext) *envoy_jwt.JwtAuthentication {
+func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, claimToHeaders map[*v1beta1.JWTRule][]security.ClaimToHeader,
+	push *model.PushContext) *envoy_jwt.JwtAuthentication {
 	if len(jwtRules) == 0 {
 		return nil
 	}
@@ -208,7 +261,7 @@ func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, push *model.PushContex
 			Audiences:            jwtRule.Audiences,
 			Forward:              jwtRule.ForwardOriginalToken,
 			ForwardPayloadHeader: jwtRule.OutputPayloadToHeader,
-			PayloadInMetadata:    jwtRule.Issuer,
+			PayloadInMetadata:    payloadMetadataKey(i, jwtRule, claimToHeaders),
 		}
 
 		for _, location := range jwtRule.FromHeaders {
@@ -260,7 +313,7 @@ func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, push *model.PushContex
 			provider.JwksSourceSpecifier = push.JwtKeyResolver.BuildLocalJwks(jwtRule.JwksUri, jwtRule.Issuer, jwtRule.Jwks)
 		}
 
-		name := fmt.Sprintf("origins-%d", i)
+		name := jwtProviderName(i)
 		providers[name] = provider
 		innerAndList = append(innerAndList, &envoy_jwt.JwtRequirement{
 			RequiresType: &envoy_jwt.JwtRequirement_RequiresAny{
@@ -344,6 +397,21 @@ func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, push *model.PushContex
 	}
 }
 
+// jwtProviderName returns the name of the JWT provider generated for the i-th JWT rule.
+func jwtProviderName(i int) string {
+	return fmt.Sprintf("origins-%d", i)
+}
+
+// payloadMetadataKey returns the key under which the JWT filter stores the payload of a validated token.
+// The payload is keyed by issuer, unless claims are copied into headers: the header formatter uses ':' as
+// path separator, which most issuers contain, so the provider name is used instead.
+func payloadMetadataKey(i int, jwtRule *v1beta1.JWTRule, claimToHeaders map[*v1beta1.JWTRule][]security.ClaimToHeader) string {
+	if len(claimToHeaders[jwtRule]) > 0 {
+		return jwtProviderName(i)
+	}
+	return jwtRule.Issuer
+}
+
 func (a *v1beta1PolicyApplier) PortLevelSetting() map[uint32]*v1beta1.PeerAuthentication_MutualTLS {
 	return a.consolidatedPeerPolicy.PortLevelMtls
 }
@@ -42,6 +42,7 @@ import (
 	pilotutil "istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/security"
 	protovalue "istio.io/istio/pkg/proto"
 )
 
@@ -1020,7 +1021,7 @@ func TestConvertToEnvoyJwtConfig(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			if got := convertToEnvoyJwtConfig(c.in, push); !reflect.DeepEqual(c.expected, got) {
+			if got := convertToEnvoyJwtConfig(c.in, nil, push); !reflect.DeepEqual(c.expected, got) {
 				t.Errorf("got:\n%s\nwanted:\n%s\n", spew.Sdump(got), spew.Sdump(c.expected))
 			}
 		})
@@ -1088,6 +1089,45 @@ func TestAuthnFilterConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "beta-jwt-with-claim-to-headers",
+			jwtIn: []*config.Config{
+				{
+					Meta: config.Meta{
+						Annotations: map[string]string{
+							security.OutputClaimToHeadersAnnotation: "x-jwt-sub:sub",
+						},
+					},
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{
+							{
+								Issuer:  "https://secret.foo.com",
+								JwksUri: jwksURI,
+							},
+						},
+					},
+				},
+			},
+			expected: &http_conn.HttpFilter{
+				Name: "istio_authn",
+				ConfigType: &http_conn.HttpFilter_TypedConfig{
+					TypedConfig: pilotutil.MessageToAny(&authn_filter.FilterConfig{
+						SkipValidateTrustDomain: true,
+						Policy: &authn_alpha.Policy{
+							Origins: []*authn_alpha.OriginAuthenticationMethod{
+								{
+									Jwt: &authn_alpha.Jwt{
+										Issuer: "origins-0",
+									},
+								},
+							},
+							OriginIsOptional: true,
+							PrincipalBinding: authn_alpha.PrincipalBinding_USE_ORIGIN,
+						},
+					}),
+				},
+			},
+		},
 		{
 			name:       "beta-jwt-for-sidecar",
 			forSidecar: true,
@@ -1278,6 +1318,103 @@ func TestAuthnFilterConfig(t *testing.T) {
 	}
 }
 
+func TestJwtClaimHeaders(t *testing.T) {
+	cases := []struct {
+		name           string
+		in             []*config.Config
+		expectedAdd    []*core.HeaderValueOption
+		expectedRemove []string
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name: "no annotation",
+			in: []*config.Config{
+				{
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{{Issuer: "https://secret.foo.com"}},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid annotation",
+			in: []*config.Config{
+				{
+					Meta: config.Meta{
+						Annotations: map[string]string{security.OutputClaimToHeadersAnnotation: "x-jwt-sub"},
+					},
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{{Issuer: "https://secret.foo.com"}},
+					},
+				},
+			},
+		},
+		{
+			name: "multiple policies",
+			in: []*config.Config{
+				{
+					Meta: config.Meta{
+						Annotations: map[string]string{security.OutputClaimToHeadersAnnotation: "x-jwt-sub:sub,x-jwt-email:email"},
+					},
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{{Issuer: "https://secret.foo.com"}},
+					},
+				},
+				{
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{{Issuer: "https://a.secret.foo.com"}},
+					},
+				},
+				{
+					Meta: config.Meta{
+						Annotations: map[string]string{security.OutputClaimToHeadersAnnotation: "x-jwt-sub:user"},
+					},
+					Spec: &v1beta1.RequestAuthentication{
+						JwtRules: []*v1beta1.JWTRule{{Issuer: "https://z.secret.foo.com"}},
+					},
+				},
+			},
+			expectedAdd: []*core.HeaderValueOption{
+				{
+					Header: &core.HeaderValue{
+						Key:   "x-jwt-sub",
+						Value: "%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:origins-1:sub)%",
+					},
+					Append: protovalue.BoolFalse,
+				},
+				{
+					Header: &core.HeaderValue{
+						Key:   "x-jwt-email",
+						Value: "%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:origins-1:email)%",
+					},
+					Append: protovalue.BoolFalse,
+				},
+				{
+					Header: &core.HeaderValue{
+						Key:   "x-jwt-sub",
+						Value: "%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:origins-2:user)%",
+					},
+					Append: protovalue.BoolFalse,
+				},
+			},
+			expectedRemove: []string{"x-jwt-sub", "x-jwt-email"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			toAdd, toRemove := NewPolicyApplier("root-namespace", c.in, nil, &model.PushContext{}).JwtClaimHeaders()
+			if diff := cmp.Diff(c.expectedAdd, toAdd, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected headers to add: %v", diff)
+			}
+			if !reflect.DeepEqual(c.expectedRemove, toRemove) {
+				t.Errorf("got headers to remove %v, wanted %v", toRemove, c.expectedRemove)
+			}
+		})
+	}
+}
+
 func TestInboundMTLSSettings(t *testing.T) {
 	now := time.Now()
 	tlsContext := &tls.DownstreamTlsContext{
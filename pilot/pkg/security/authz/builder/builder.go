@@ -17,6 +17,7 @@ package builder
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
@@ -152,6 +153,61 @@ func (b Builder) BuildTCP() []*tcppb.Filter {
 	return filters
 }
 
+// SplitConnectionPolicies splits out the DENY and AUDIT policies with conditions on the dynamic metadata set on the
+// downstream connection by the given listener filter, such as the PROXY protocol TLVs of gateways. The HTTP RBAC
+// filter only sees the metadata of the requests, so these policies must be enforced on the connection with BuildTCP,
+// in addition to the HTTP policies. Envoy requires every RBAC filter to allow a request, so the ALLOW policies are
+// only split out if all of them are on the metadata of the connection; otherwise they are kept together in the HTTP
+// filter, where the ones on the metadata of the connection never match. It returns the builder of the other policies
+// and the builder of the split policies; either is nil if it has no policy.
+func (b Builder) SplitConnectionPolicies(listenerFilter string) (*Builder, *Builder) {
+	if b.option.IsCustomBuilder {
+		return &b, nil
+	}
+	prefix := "experimental." + listenerFilter + "["
+	other := &Builder{trustDomainBundle: b.trustDomainBundle, option: b.option, isIstioVersionGE112: b.isIstioVersionGE112}
+	conn := &Builder{trustDomainBundle: b.trustDomainBundle, option: b.option, isIstioVersionGE112: b.isIstioVersionGE112}
+	split := func(policies []model.AuthorizationPolicy) (others, conns []model.AuthorizationPolicy) {
+		for _, policy := range policies {
+			if hasConditionPrefix(policy, prefix) {
+				conns = append(conns, policy)
+			} else {
+				others = append(others, policy)
+			}
+		}
+		return others, conns
+	}
+	other.denyPolicies, conn.denyPolicies = split(b.denyPolicies)
+	other.allowPolicies, conn.allowPolicies = split(b.allowPolicies)
+	if len(conn.allowPolicies) > 0 && len(other.allowPolicies) > 0 {
+		b.option.Logger.AppendError(fmt.Errorf("the %d ALLOW policies on the metadata of %s never match with other "+
+			"ALLOW policies on the workload", len(conn.allowPolicies), listenerFilter))
+		other.allowPolicies, conn.allowPolicies = b.allowPolicies, nil
+	}
+	other.auditPolicies, conn.auditPolicies = split(b.auditPolicies)
+	if len(conn.denyPolicies) == 0 && len(conn.allowPolicies) == 0 && len(conn.auditPolicies) == 0 {
+		return &b, nil
+	}
+	b.option.Logger.AppendDebugf("split %d DENY actions, %d ALLOW actions, %d AUDIT actions on the metadata of %s",
+		len(conn.denyPolicies), len(conn.allowPolicies), len(conn.auditPolicies), listenerFilter)
+	if len(other.denyPolicies) == 0 && len(other.allowPolicies) == 0 && len(other.auditPolicies) == 0 {
+		return nil, conn
+	}
+	return other, conn
+}
+
+// hasConditionPrefix returns true if a condition of a rule of the policy has a key with the given prefix.
+func hasConditionPrefix(policy model.AuthorizationPolicy, prefix string) bool {
+	for _, rule := range policy.Spec.GetRules() {
+		for _, cond := range rule.GetWhen() {
+			if strings.HasPrefix(cond.GetKey(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type builtConfigs struct {
 	http []*httppb.HttpFilter
 	tcp  []*tcppb.Filter
@@ -16,16 +16,22 @@ package builder
 
 import (
 	"os"
+	"reflect"
+	"sort"
 	"testing"
 
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	extauthzhttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/gogo/protobuf/types"
 	"google.golang.org/protobuf/proto"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/security/trustdomain"
@@ -274,6 +280,40 @@ func TestGenerator_GenerateHTTP(t *testing.T) {
 	}
 }
 
+func TestGenerator_ExtAuthzKillSwitch(t *testing.T) {
+	defaultValue := features.EnableExtAuthzKillSwitch
+	features.EnableExtAuthzKillSwitch = true
+	defer func() { features.EnableExtAuthzKillSwitch = defaultValue }()
+
+	for _, mc := range []*meshconfig.MeshConfig{meshConfigGRPC, meshConfigHTTP} {
+		option := Option{IsCustomBuilder: true, Logger: &AuthzLogger{}}
+		g := New(trustdomain.Bundle{}, inputParams(t, "http/custom-simple-http-in.yaml", mc, nil), option)
+		if g == nil {
+			t.Fatalf("failed to create generator")
+		}
+		var found bool
+		for _, filter := range g.BuildHTTP() {
+			if filter.Name != wellknown.HTTPExternalAuthorization {
+				continue
+			}
+			found = true
+			extAuthz := &extauthzhttp.ExtAuthz{}
+			if err := filter.GetTypedConfig().UnmarshalTo(extAuthz); err != nil {
+				t.Fatal(err)
+			}
+			if got := extAuthz.GetFilterEnabled().GetRuntimeKey(); got != ExtAuthzEnabledRuntimeKey {
+				t.Errorf("got runtime key %q, want %q", got, ExtAuthzEnabledRuntimeKey)
+			}
+			if got := extAuthz.GetFilterEnabled().GetDefaultValue().GetNumerator(); got != 100 {
+				t.Errorf("got default percentage %d, want 100", got)
+			}
+		}
+		if !found {
+			t.Fatalf("expected an ext_authz filter")
+		}
+	}
+}
+
 func TestGenerator_GenerateTCP(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -340,6 +380,78 @@ func TestGenerator_GenerateTCP(t *testing.T) {
 	}
 }
 
+func TestGenerator_SplitConnectionPolicies(t *testing.T) {
+	policies := func(filter *tcppb.Filter) []string {
+		rbac := &rbactcppb.RBAC{}
+		if err := filter.GetTypedConfig().UnmarshalTo(rbac); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for name := range rbac.GetRules().GetPolicies() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	t.Run("ALLOW policies on the TLVs only", func(t *testing.T) {
+		option := Option{Logger: &AuthzLogger{}}
+		g := New(trustdomain.Bundle{}, inputParams(t, "split/proxy-protocol-only-in.yaml", nil, nil), option)
+		if g == nil {
+			t.Fatalf("failed to create generator")
+		}
+		httpBuilder, connBuilder := g.SplitConnectionPolicies(wellknown.ProxyProtocol)
+		if httpBuilder == nil || connBuilder == nil {
+			t.Fatalf("got builders %v and %v, want both", httpBuilder, connBuilder)
+		}
+		if got := len(httpBuilder.BuildHTTP()); got != 1 {
+			t.Errorf("got %d HTTP filters, want 1 for the DENY policy on the path", got)
+		}
+		tcp := connBuilder.BuildTCP()
+		if len(tcp) != 2 {
+			t.Fatalf("got %d TCP filters, want 2 for the DENY and ALLOW policies on the TLV", len(tcp))
+		}
+		if got, want := policies(tcp[0]), []string{"ns[foo]-policy[deny-tenant]-rule[0]"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got DENY policies %v, want %v", got, want)
+		}
+		if got, want := policies(tcp[1]), []string{"ns[foo]-policy[tenant]-rule[0]"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got ALLOW policies %v, want %v", got, want)
+		}
+		if option.Logger.errMsg != nil {
+			t.Errorf("unexpected error %v", option.Logger.errMsg)
+		}
+	})
+
+	t.Run("mixed ALLOW policies", func(t *testing.T) {
+		option := Option{Logger: &AuthzLogger{}}
+		g := New(trustdomain.Bundle{}, inputParams(t, "split/proxy-protocol-in.yaml", nil, nil), option)
+		if g == nil {
+			t.Fatalf("failed to create generator")
+		}
+		// Splitting the ALLOW policies would require both of them to match, so they are kept in the HTTP filter.
+		httpBuilder, connBuilder := g.SplitConnectionPolicies(wellknown.ProxyProtocol)
+		if httpBuilder == nil || connBuilder != nil {
+			t.Fatalf("got builders %v and %v, want only the HTTP builder", httpBuilder, connBuilder)
+		}
+		if got := len(httpBuilder.BuildHTTP()); got != 2 {
+			t.Errorf("got %d HTTP filters, want 2 for the DENY and ALLOW policies", got)
+		}
+		if got := len(httpBuilder.allowPolicies); got != 2 {
+			t.Errorf("got %d ALLOW policies in the HTTP builder, want 2", got)
+		}
+		if option.Logger.errMsg == nil {
+			t.Errorf("expected an error for the ALLOW policies on the TLV")
+		}
+	})
+
+	t.Run("other listener filter", func(t *testing.T) {
+		g := New(trustdomain.Bundle{}, inputParams(t, "split/proxy-protocol-only-in.yaml", nil, nil), Option{Logger: &AuthzLogger{}})
+		if httpBuilder, connBuilder := g.SplitConnectionPolicies("envoy.filters.listener.other"); httpBuilder == nil || connBuilder != nil {
+			t.Errorf("got builders %v and %v, want only the HTTP builder", httpBuilder, connBuilder)
+		}
+	})
+}
+
 func verify(t *testing.T, gots []proto.Message, baseDir string, wants []string, forTCP bool) {
 	t.Helper()
 
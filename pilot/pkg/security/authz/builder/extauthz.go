@@ -35,6 +35,7 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/extensionproviders"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
 	"istio.io/istio/pkg/config/validation"
@@ -43,6 +44,12 @@ import (
 
 const (
 	extAuthzMatchPrefix = "istio-ext-authz"
+
+	// ExtAuthzEnabledRuntimeKey is the runtime key of the percentage of the HTTP requests checked by the ext_authz
+	// filters of the CUSTOM action, when features.EnableExtAuthzKillSwitch is enabled. It is served to all the proxies
+	// over RTDS from the runtime values of the default proxy config of the mesh: setting it to 0 there disables the
+	// external authorization of the mesh, and the requests are then allowed, without changing the listeners.
+	ExtAuthzEnabledRuntimeKey = "istio.filters.http.ext_authz.enabled"
 )
 
 var (
@@ -282,6 +289,7 @@ func generateHTTPConfig(hostname, cluster string, status *envoytypev3.HttpStatus
 		Services: &extauthzhttp.ExtAuthz_HttpService{
 			HttpService: service,
 		},
+		FilterEnabled:         extAuthzFilterEnabled(),
 		FilterEnabledMetadata: generateFilterMatcher(wellknown.HTTPRoleBasedAccessControl),
 		WithRequestBody:       withBodyRequest(config.IncludeRequestBodyInCheck),
 	}
@@ -309,6 +317,7 @@ func generateGRPCConfig(cluster string, config *meshconfig.MeshConfig_ExtensionP
 		Services: &extauthzhttp.ExtAuthz_GrpcService{
 			GrpcService: grpc,
 		},
+		FilterEnabled:         extAuthzFilterEnabled(),
 		FilterEnabledMetadata: generateFilterMatcher(wellknown.HTTPRoleBasedAccessControl),
 		TransportApiVersion:   envoy_config_core_v3.ApiVersion_V3,
 		WithRequestBody:       withBodyRequest(config.IncludeRequestBodyInCheck),
@@ -323,6 +332,17 @@ func generateGRPCConfig(cluster string, config *meshconfig.MeshConfig_ExtensionP
 	return &builtExtAuthz{http: http, tcp: tcp}
 }
 
+// extAuthzFilterEnabled returns the runtime switch of the HTTP ext_authz filters, enabled by default.
+func extAuthzFilterEnabled() *envoy_config_core_v3.RuntimeFractionalPercent {
+	if !features.EnableExtAuthzKillSwitch {
+		return nil
+	}
+	return &envoy_config_core_v3.RuntimeFractionalPercent{
+		DefaultValue: &envoytypev3.FractionalPercent{Numerator: 100, Denominator: envoytypev3.FractionalPercent_HUNDRED},
+		RuntimeKey:   ExtAuthzEnabledRuntimeKey,
+	}
+}
+
 func generateHeaders(headers []string) *envoy_type_matcher_v3.ListStringMatcher {
 	if len(headers) == 0 {
 		return nil
@@ -126,6 +126,9 @@ func (srcNamespaceGenerator) permission(_, _ string, _ bool) (*rbacpb.Permission
 func (srcNamespaceGenerator) principal(_, value string, _ bool) (*rbacpb.Principal, error) {
 	v := strings.Replace(value, "*", ".*", -1)
 	m := matcher.StringMatcherRegex(fmt.Sprintf(".*/ns/%s/.*", v))
+	if mapped := mappedNamespacePrincipals(value); len(mapped) > 0 {
+		return principalOr(append([]*rbacpb.Principal{principalAuthenticated(m)}, mapped...)), nil
+	}
 	return principalAuthenticated(m), nil
 }
 
@@ -137,6 +140,9 @@ func (srcPrincipalGenerator) permission(_, _ string, _ bool) (*rbacpb.Permission
 
 func (srcPrincipalGenerator) principal(key, value string, _ bool) (*rbacpb.Principal, error) {
 	m := matcher.StringMatcherWithPrefix(value, spiffe.URIPrefix)
+	if mapped := mappedPrincipals(value); len(mapped) > 0 {
+		return principalOr(append([]*rbacpb.Principal{principalAuthenticated(m)}, mapped...)), nil
+	}
 	return principalAuthenticated(m), nil
 }
 
@@ -0,0 +1,197 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/security/authz/matcher"
+	"istio.io/pkg/log"
+)
+
+// identityMapping maps the peer identities of certificates without SPIFFE URI SANs to Istio principals, so that
+// these peers can be matched by the principals and namespaces of authorization policies.
+type identityMapping struct {
+	// san matches the identity of the peer: its first DNS SAN, or its subject if it has no DNS SAN. The named
+	// capture groups "ns", "sa" and optionally "td" are the namespace, service account and trust domain of the
+	// principal the identity is mapped to.
+	san *syntax.Regexp
+	// trustDomain is the trust domain of the principal, if the san regex has no "td" group.
+	trustDomain string
+}
+
+// identityMappingConfig is the JSON representation of an identity mapping.
+type identityMappingConfig struct {
+	SAN         string `json:"san"`
+	TrustDomain string `json:"trustDomain"`
+}
+
+var identityMappings = func() []identityMapping {
+	m, err := parseIdentityMappings(features.PeerIdentityMapping)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_PEER_IDENTITY_MAPPING: %v", err)
+	}
+	return m
+}()
+
+// parseIdentityMappings parses a JSON list of identity mappings, for example
+// [{"san": "(?P<sa>[^.]+)\\.(?P<ns>[^.]+)\\.svc\\.corp\\.example\\.com", "trustDomain": "cluster.local"}].
+func parseIdentityMappings(config string) ([]identityMapping, error) {
+	if config == "" {
+		return nil, nil
+	}
+	var configs []identityMappingConfig
+	if err := json.Unmarshal([]byte(config), &configs); err != nil {
+		return nil, err
+	}
+	res := make([]identityMapping, 0, len(configs))
+	for _, c := range configs {
+		compiled, err := regexp.Compile(c.SAN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid san regex %q: %v", c.SAN, err)
+		}
+		groups := map[string]bool{}
+		for _, name := range compiled.SubexpNames() {
+			groups[name] = true
+		}
+		re, err := syntax.Parse(c.SAN, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid san regex %q: %v", c.SAN, err)
+		}
+		if !groups["ns"] || !groups["sa"] {
+			return nil, fmt.Errorf("san regex %q must have the named groups ns and sa", c.SAN)
+		}
+		if !groups["td"] && c.TrustDomain == "" {
+			return nil, fmt.Errorf("san regex %q must have the named group td, or trustDomain must be set", c.SAN)
+		}
+		res = append(res, identityMapping{san: re, trustDomain: c.TrustDomain})
+	}
+	return res, nil
+}
+
+// regex returns the regex matching the identities mapped to a principal with the given trust domain, namespace
+// and service account. Each of them can contain "*" wildcards. It returns an empty string if the trust domain
+// of the mapping does not match.
+func (m identityMapping) regex(td, ns, sa string) string {
+	values := map[string]string{"td": td, "ns": ns, "sa": sa}
+	if m.trustDomain != "" {
+		if !regexp.MustCompile("^" + globToRegex(td, ".*") + "$").MatchString(m.trustDomain) {
+			return ""
+		}
+		delete(values, "td")
+	}
+	return substituteGroups(m.san, values).String()
+}
+
+// substituteGroups returns a copy of re where the named capture groups are replaced by the given glob values.
+// Groups whose value is "*" are kept, so they still only match what the mapping allows.
+func substituteGroups(re *syntax.Regexp, values map[string]string) *syntax.Regexp {
+	if re.Op == syntax.OpCapture {
+		if v, f := values[re.Name]; f && v != "*" {
+			sub, err := syntax.Parse(globToRegex(v, groupWildcard(re)), syntax.Perl)
+			if err == nil {
+				return sub
+			}
+		}
+	}
+	cp := *re
+	cp.Sub = make([]*syntax.Regexp, 0, len(re.Sub))
+	for _, sub := range re.Sub {
+		cp.Sub = append(cp.Sub, substituteGroups(sub, values))
+	}
+	return &cp
+}
+
+// groupWildcard returns the regex matching any sequence of the characters allowed by a capture group, such as
+// "[^.]*" for the group "(?P<ns>[^.]+)". Wildcards in values are replaced by it, so that they do not match across
+// the other parts of the identity.
+func groupWildcard(group *syntax.Regexp) string {
+	if len(group.Sub) == 1 {
+		switch rep := group.Sub[0]; rep.Op {
+		case syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+			switch rep.Sub[0].Op {
+			case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL, syntax.OpLiteral:
+				return "(?:" + rep.Sub[0].String() + ")*"
+			}
+		}
+	}
+	return ".*"
+}
+
+// globToRegex converts a value with "*" wildcards to a regex, where the wildcards are replaced by wildcard.
+func globToRegex(v, wildcard string) string {
+	parts := strings.Split(v, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return strings.Join(parts, wildcard)
+}
+
+// principalParts splits a principal of the form <trust domain>/ns/<namespace>/sa/<service account> into its trust
+// domain, namespace and service account. A leading or trailing "*" wildcard matches the missing parts.
+func principalParts(v string) (td, ns, sa string, ok bool) {
+	parts := strings.Split(v, "/")
+	full := []string{"*", "ns", "*", "sa", "*"}
+	if n := len(parts); n < len(full) {
+		switch {
+		case parts[n-1] == "*":
+			parts = append(parts[:n-1], full[n-1:]...)
+		case parts[0] == "*":
+			parts = append(append([]string{}, full[:len(full)-n+1]...), parts[1:]...)
+		}
+	}
+	if len(parts) != len(full) || parts[1] != "ns" || parts[3] != "sa" {
+		return "", "", "", false
+	}
+	return parts[0], parts[2], parts[4], true
+}
+
+// mappedPrincipals returns the principals matching the identities mapped to the given principal value.
+func mappedPrincipals(value string) []*rbacpb.Principal {
+	if len(identityMappings) == 0 || value == "*" {
+		return nil
+	}
+	td, ns, sa, ok := principalParts(value)
+	if !ok {
+		log.Debugf("principal %q cannot be matched with the peer identity mapping", value)
+		return nil
+	}
+	return principalsForIdentityMappings(td, ns, sa)
+}
+
+// mappedNamespacePrincipals returns the principals matching the identities mapped to the given namespace.
+func mappedNamespacePrincipals(ns string) []*rbacpb.Principal {
+	if len(identityMappings) == 0 {
+		return nil
+	}
+	return principalsForIdentityMappings("*", ns, "*")
+}
+
+func principalsForIdentityMappings(td, ns, sa string) []*rbacpb.Principal {
+	var res []*rbacpb.Principal
+	for _, m := range identityMappings {
+		if re := m.regex(td, ns, sa); re != "" {
+			res = append(res, principalAuthenticated(matcher.StringMatcherRegex(re)))
+		}
+	}
+	return res
+}
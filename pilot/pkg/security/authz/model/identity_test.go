@@ -0,0 +1,203 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"regexp"
+	"testing"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+const testIdentityMapping = `[
+  {"san": "(?P<sa>[^.]+)\\.(?P<ns>[^.]+)\\.svc\\.corp\\.example\\.com", "trustDomain": "cluster.local"},
+  {"san": "CN=(?P<sa>\\w+),OU=(?P<ns>\\w+),O=(?P<td>[a-z.]+)"}
+]`
+
+func TestParseIdentityMappings(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "valid", config: testIdentityMapping},
+		{name: "invalid json", config: "{", wantErr: true},
+		{name: "invalid regex", config: `[{"san": "(?P<ns>", "trustDomain": "td"}]`, wantErr: true},
+		{name: "missing group", config: `[{"san": "(?P<ns>.+)", "trustDomain": "td"}]`, wantErr: true},
+		{name: "missing trust domain", config: `[{"san": "(?P<ns>.+)/(?P<sa>.+)"}]`, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseIdentityMappings(tt.config); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIdentityMappingRegex(t *testing.T) {
+	mappings, err := parseIdentityMappings(testIdentityMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dns, subject := mappings[0], mappings[1]
+	cases := []struct {
+		principal    string
+		mapping      identityMapping
+		matches      []string
+		doesNotMatch []string
+	}{
+		{
+			principal:    "cluster.local/ns/foo/sa/bar",
+			mapping:      dns,
+			matches:      []string{"bar.foo.svc.corp.example.com"},
+			doesNotMatch: []string{"baz.foo.svc.corp.example.com", "bar.foo.svc.corp.example.org"},
+		},
+		{
+			principal:    "other.domain/ns/foo/sa/bar",
+			mapping:      dns,
+			doesNotMatch: []string{"bar.foo.svc.corp.example.com"},
+		},
+		{
+			principal:    "cluster.local/ns/foo/*",
+			mapping:      dns,
+			matches:      []string{"bar.foo.svc.corp.example.com", "baz.foo.svc.corp.example.com"},
+			doesNotMatch: []string{"bar.other.svc.corp.example.com"},
+		},
+		{
+			principal:    "*/sa/bar",
+			mapping:      dns,
+			matches:      []string{"bar.foo.svc.corp.example.com", "bar.other.svc.corp.example.com"},
+			doesNotMatch: []string{"baz.foo.svc.corp.example.com"},
+		},
+		{
+			// The wildcard does not match across the parts of the identity.
+			principal:    "cluster.local/ns/f*/sa/bar",
+			mapping:      dns,
+			matches:      []string{"bar.foo.svc.corp.example.com"},
+			doesNotMatch: []string{"bar.f.x.svc.corp.example.com"},
+		},
+		{
+			principal:    "corp.example/ns/payments/sa/billing",
+			mapping:      subject,
+			matches:      []string{"CN=billing,OU=payments,O=corp.example"},
+			doesNotMatch: []string{"CN=billing,OU=payments,O=other.example"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.principal, func(t *testing.T) {
+			td, ns, sa, ok := principalParts(tt.principal)
+			if !ok {
+				t.Fatalf("failed to parse principal %q", tt.principal)
+			}
+			re := tt.mapping.regex(td, ns, sa)
+			if re == "" {
+				if len(tt.matches) > 0 {
+					t.Fatalf("expected a regex for principal %q", tt.principal)
+				}
+				return
+			}
+			// Envoy regex matchers match the whole value.
+			compiled := regexp.MustCompile("^(?:" + re + ")$")
+			for _, v := range tt.matches {
+				if !compiled.MatchString(v) {
+					t.Errorf("regex %q does not match %q", re, v)
+				}
+			}
+			for _, v := range tt.doesNotMatch {
+				if compiled.MatchString(v) {
+					t.Errorf("regex %q matches %q", re, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratorIdentityMapping(t *testing.T) {
+	mappings, err := parseIdentityMappings(`[{"san": "(?P<sa>[^.]+)\\.(?P<ns>[^.]+)\\.corp", "trustDomain": "td"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultValue := identityMappings
+	identityMappings = mappings
+	defer func() { identityMappings = defaultValue }()
+
+	cases := []struct {
+		name  string
+		g     generator
+		value string
+		want  *rbacpb.Principal
+	}{
+		{
+			name:  "principal",
+			g:     srcPrincipalGenerator{},
+			value: "td/ns/foo/sa/bar",
+			want: yamlPrincipal(t, `
+         orIds:
+          ids:
+          - authenticated:
+              principalName:
+                exact: spiffe://td/ns/foo/sa/bar
+          - authenticated:
+              principalName:
+                safeRegex:
+                  googleRe2: {}
+                  regex: bar\.foo\.corp`),
+		},
+		{
+			name:  "any principal",
+			g:     srcPrincipalGenerator{},
+			value: "*",
+			want: yamlPrincipal(t, `
+         authenticated:
+          principalName:
+            safeRegex:
+              googleRe2: {}
+              regex: .+`),
+		},
+		{
+			name:  "namespace",
+			g:     srcNamespaceGenerator{},
+			value: "foo",
+			want: yamlPrincipal(t, `
+         orIds:
+          ids:
+          - authenticated:
+              principalName:
+                safeRegex:
+                  googleRe2: {}
+                  regex: .*/ns/foo/.*
+          - authenticated:
+              principalName:
+                safeRegex:
+                  googleRe2: {}
+                  regex: (?P<sa>[^\.]+)\.foo\.corp`),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.g.principal("source.principal", tt.value, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, tt.want, protocmp.Transform()); diff != "" {
+				t.Errorf("diff detected: %v", diff)
+			}
+		})
+	}
+}
@@ -15,10 +15,12 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"google.golang.org/protobuf/types/known/durationpb"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -170,10 +172,18 @@ func ConstructSdsSecretConfig(name string) *tls.SdsSecretConfig {
 	return cfg
 }
 
-func appendURIPrefixToTrustDomain(trustDomainAliases []string) []string {
-	var res []string
-	for _, td := range trustDomainAliases {
-		res = append(res, spiffe.URIPrefix+td+"/")
+// trustDomainSANMatchers returns the SAN matchers accepting the identities of the given trust domains. A trust domain
+// is matched as the prefix of SPIFFE URI SANs, except for wildcard trust domains such as
+// "*.serviceaccount.identity.linkerd.cluster.local", which are matched as the suffix of DNS SANs. The latter allows
+// accepting the identities of meshes not using SPIFFE, for example while migrating from them.
+func trustDomainSANMatchers(trustDomains []string) []*matcher.StringMatcher {
+	var res []*matcher.StringMatcher
+	for _, td := range trustDomains {
+		if strings.HasPrefix(td, "*.") {
+			res = append(res, &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Suffix{Suffix: td[1:]}})
+			continue
+		}
+		res = append(res, &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: spiffe.URIPrefix + td + "/"}})
 	}
 	return res
 }
@@ -194,7 +204,7 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 	// TODO: if user explicitly specifies SANs - should we alter his explicit config by adding all spifee aliases?
 	matchSAN := util.StringToExactMatch(subjectAltNames)
 	if len(trustDomainAliases) > 0 {
-		matchSAN = append(matchSAN, util.StringToPrefixMatch(appendURIPrefixToTrustDomain(trustDomainAliases))...)
+		matchSAN = append(matchSAN, trustDomainSANMatchers(trustDomainAliases)...)
 	}
 
 	// configure server listeners with SDS.
@@ -211,6 +221,24 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 	}
 }
 
+// ApplyClientTrustToServerCommonTLSContext restricts the clients of a server CommonTlsContext validating client
+// certificates, as built by ApplyToCommonTLSContext, to the SPIFFE identities of the trust domains, in addition to its
+// subject alt names. The client certificates are validated with the root certificates of the credential, if set.
+func ApplyClientTrustToServerCommonTLSContext(tlsContext *tls.CommonTlsContext, trustDomains []string, caCredentialName string) {
+	combined := tlsContext.GetCombinedValidationContext()
+	if combined == nil {
+		return
+	}
+	if combined.DefaultValidationContext == nil {
+		combined.DefaultValidationContext = &tls.CertificateValidationContext{}
+	}
+	combined.DefaultValidationContext.MatchSubjectAltNames = append(combined.DefaultValidationContext.MatchSubjectAltNames,
+		trustDomainSANMatchers(trustDomains)...)
+	if caCredentialName != "" {
+		combined.ValidationContextSdsSecretConfig = ConstructSdsSecretConfigForCredential(caCredentialName + SdsCaSuffix)
+	}
+}
+
 // ApplyCustomSDSToClientCommonTLSContext applies the customized sds to CommonTlsContext
 // Used for building upstream TLS context for egress gateway's TLS/mTLS origination
 func ApplyCustomSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext, tlsOpts *networking.ClientTLSSettings) {
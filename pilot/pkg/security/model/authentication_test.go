@@ -148,7 +148,7 @@ func TestApplyToCommonTLSContext(t *testing.T) {
 				Metadata: &model.NodeMetadata{},
 			},
 			validateClient:     true,
-			trustDomainAliases: []string{"alias-1.domain", "some-other-alias-1.domain", "alias-2.domain"},
+			trustDomainAliases: []string{"alias-1.domain", "some-other-alias-1.domain", "alias-2.domain", "*.identity.linkerd.cluster.local"},
 			expected: &auth.CommonTlsContext{
 				TlsCertificateSdsSecretConfigs: []*auth.SdsSecretConfig{
 					{
@@ -179,6 +179,7 @@ func TestApplyToCommonTLSContext(t *testing.T) {
 							{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: spiffe.URIPrefix + "alias-1.domain" + "/"}},
 							{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: spiffe.URIPrefix + "some-other-alias-1.domain" + "/"}},
 							{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: spiffe.URIPrefix + "alias-2.domain" + "/"}},
+							{MatchPattern: &matcher.StringMatcher_Suffix{Suffix: ".identity.linkerd.cluster.local"}},
 						}},
 						ValidationContextSdsSecretConfig: &auth.SdsSecretConfig{
 							Name: "ROOTCA",
@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustdomain
+
+import (
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// Migration is a migration of the mesh from a previous trust domain. While the migration is in
+// progress, the previous trust domain is treated as an alias of the mesh trust domain, so workloads
+// can be moved to their new identity gradually.
+type Migration struct {
+	// From is the trust domain the mesh is migrating from.
+	From string
+	// End is the time at which the previous trust domain stops being accepted. If zero, the migration
+	// does not end until it is removed from the configuration.
+	End time.Time
+}
+
+// CurrentMigration returns the trust domain migration configured for istiod, or nil if there is none.
+func CurrentMigration() *Migration {
+	if features.TrustDomainMigrationFrom == "" {
+		return nil
+	}
+	return &Migration{From: features.TrustDomainMigrationFrom, End: features.TrustDomainMigrationEnd}
+}
+
+// Active returns true if the previous trust domain is still accepted at the time.
+func (m *Migration) Active(now time.Time) bool {
+	if m == nil || m.From == "" {
+		return false
+	}
+	return m.End.IsZero() || now.Before(m.End)
+}
+
+// Aliases returns the trust domain aliases of the mesh, including the previous trust domain while the
+// migration is active.
+func (m *Migration) Aliases(meshConfig *meshconfig.MeshConfig, now time.Time) []string {
+	aliases := meshConfig.GetTrustDomainAliases()
+	if !m.Active(now) || m.From == meshConfig.GetTrustDomain() {
+		return aliases
+	}
+	for _, alias := range aliases {
+		if alias == m.From {
+			return aliases
+		}
+	}
+	return append(append(make([]string, 0, len(aliases)+1), aliases...), m.From)
+}
+
+// Aliases returns the trust domain aliases of the mesh, including the previous trust domain of the
+// current trust domain migration, if any.
+func Aliases(meshConfig *meshconfig.MeshConfig) []string {
+	return CurrentMigration().Aliases(meshConfig, time.Now())
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustdomain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+func TestMigrationAliases(t *testing.T) {
+	now := time.Now()
+	meshConfig := &meshconfig.MeshConfig{TrustDomain: "td2", TrustDomainAliases: []string{"td3"}}
+	cases := []struct {
+		name      string
+		migration *Migration
+		want      []string
+	}{
+		{
+			name: "no migration",
+			want: []string{"td3"},
+		},
+		{
+			name:      "migration without end",
+			migration: &Migration{From: "td1"},
+			want:      []string{"td3", "td1"},
+		},
+		{
+			name:      "migration in progress",
+			migration: &Migration{From: "td1", End: now.Add(time.Hour)},
+			want:      []string{"td3", "td1"},
+		},
+		{
+			name:      "migration ended",
+			migration: &Migration{From: "td1", End: now.Add(-time.Hour)},
+			want:      []string{"td3"},
+		},
+		{
+			name:      "previous trust domain already an alias",
+			migration: &Migration{From: "td3"},
+			want:      []string{"td3"},
+		},
+		{
+			name:      "previous trust domain is the mesh trust domain",
+			migration: &Migration{From: "td2"},
+			want:      []string{"td3"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.migration.Aliases(meshConfig, now); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if !reflect.DeepEqual(meshConfig.TrustDomainAliases, []string{"td3"}) {
+				t.Errorf("mesh config aliases modified: %v", meshConfig.TrustDomainAliases)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/cluster"
@@ -414,7 +415,7 @@ func (c *Controller) GetIstioServiceAccounts(svc *model.Service, ports []int) []
 	if c.meshHolder != nil {
 		m := c.meshHolder.Mesh()
 		if m != nil {
-			tds = m.TrustDomainAliases
+			tds = trustdomain.Aliases(m)
 		}
 	}
 	expanded := spiffe.ExpandWithTrustDomains(result, tds)
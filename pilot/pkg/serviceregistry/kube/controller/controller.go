@@ -211,7 +211,7 @@ type kubernetesNode struct {
 // controllerInterface is a simplified interface for the Controller used for testing.
 type controllerInterface interface {
 	getPodLocality(pod *v1.Pod) string
-	Network(endpointIP string, labels labels.Instance) network.ID
+	resolveNetwork(endpointIP, nodeName string, labels labels.Instance) (network.ID, model.NetworkSource)
 	Cluster() cluster.ID
 }
 
@@ -482,22 +482,36 @@ func (c *Controller) networkFromSystemNamespace() network.ID {
 }
 
 func (c *Controller) Network(endpointIP string, labels labels.Instance) network.ID {
+	nw, _ := c.resolveNetwork(endpointIP, "", labels)
+	return nw
+}
+
+// resolveNetwork returns the network of an endpoint from its IP, the name of its node if known, and its labels, and
+// how the network was resolved.
+func (c *Controller) resolveNetwork(endpointIP, nodeName string, labels labels.Instance) (network.ID, model.NetworkSource) {
 	// 1. check the pod/workloadEntry label
 	if nw := labels[label.TopologyNetwork.Name]; nw != "" {
-		return network.ID(nw)
+		return network.ID(nw), model.NetworkSourceLabel
+	}
+
+	// 2. check the node labels
+	if features.EnableNodeNetworkDetection {
+		if nw := c.networkFromNode(endpointIP, nodeName); nw != "" {
+			return nw, model.NetworkSourceNode
+		}
 	}
 
-	// 2. check the system namespace labels
+	// 3. check the system namespace labels
 	if nw := c.networkFromSystemNamespace(); nw != "" {
-		return nw
+		return nw, model.NetworkSourceSystemNamespace
 	}
 
-	// 3. check the meshNetworks config
+	// 4. check the meshNetworks config
 	if nw := c.networkFromMeshNetworks(endpointIP); nw != "" {
-		return nw
+		return nw, model.NetworkSourceMeshNetworks
 	}
 
-	return ""
+	return "", ""
 }
 
 func (c *Controller) Cleanup() error {
@@ -625,6 +639,11 @@ func (c *Controller) onNodeEvent(obj interface{}, event model.Event) error {
 			return nil
 		}
 	}
+	if features.EnableNodeNetworkDetection && c.updateNodeNetwork(node, event) && c.initialSync.Load() {
+		// the network of the endpoints of the node changed; during the initial sync, the nodes are synced first
+		c.onDefaultNetworkChange()
+	}
+
 	var updatedNeeded bool
 	if event == model.EventDelete {
 		updatedNeeded = true
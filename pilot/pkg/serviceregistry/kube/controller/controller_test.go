@@ -442,6 +442,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 					ServiceAccount:  "account",
 					Address:         "1.1.1.1",
 					Network:         networkID,
+					NetworkSource:   model.NetworkSourceSystemNamespace,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -506,6 +507,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 				Endpoint: &model.IstioEndpoint{
 					Address:         "129.0.0.1",
 					Network:         networkID,
+					NetworkSource:   model.NetworkSourceSystemNamespace,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -574,6 +576,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 				Endpoint: &model.IstioEndpoint{
 					Address:         "129.0.0.2",
 					Network:         networkID,
+					NetworkSource:   model.NetworkSourceSystemNamespace,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -33,6 +33,8 @@ type EndpointBuilder struct {
 
 	labels         labels.Instance
 	metaNetwork    network.ID
+	networkSource  model.NetworkSource
+	nodeName       string
 	serviceAccount string
 	locality       model.Locality
 	tlsMode        string
@@ -47,7 +49,7 @@ type EndpointBuilder struct {
 }
 
 func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
-	locality, sa, namespace, hostname, subdomain, ip := "", "", "", "", "", ""
+	locality, sa, namespace, hostname, subdomain, ip, nodeName := "", "", "", "", "", "", ""
 	var podLabels labels.Instance
 	if pod != nil {
 		locality = c.getPodLocality(pod)
@@ -62,10 +64,13 @@ func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 			}
 		}
 		ip = pod.Status.PodIP
+		nodeName = pod.Spec.NodeName
 	}
 	dm, _ := kubeUtil.GetDeployMetaFromPod(pod)
 	out := &EndpointBuilder{
 		controller:     c,
+		labels:         podLabels,
+		nodeName:       nodeName,
 		serviceAccount: sa,
 		locality: model.Locality{
 			Label:     locality,
@@ -117,6 +122,9 @@ func (b *EndpointBuilder) buildIstioEndpoint(
 		networkID = b.endpointNetwork(endpointAddress)
 		b.labels[label.TopologyNetwork.Name] = string(networkID)
 	}
+	if b.networkSource == "" && networkID != "" {
+		b.networkSource = model.NetworkSourceLabel
+	}
 
 	return &model.IstioEndpoint{
 		Labels:                b.labels,
@@ -127,6 +135,7 @@ func (b *EndpointBuilder) buildIstioEndpoint(
 		EndpointPort:          uint32(endpointPort),
 		ServicePortName:       svcPortName,
 		Network:               networkID,
+		NetworkSource:         b.networkSource,
 		WorkloadName:          b.workloadName,
 		Namespace:             b.namespace,
 		HostName:              b.hostname,
@@ -139,8 +148,11 @@ func (b *EndpointBuilder) buildIstioEndpoint(
 func (b *EndpointBuilder) endpointNetwork(endpointIP string) network.ID {
 	// If we're building the endpoint based on proxy meta, prefer the injected ISTIO_META_NETWORK value.
 	if b.metaNetwork != "" {
+		b.networkSource = model.NetworkSourceProxyMetadata
 		return b.metaNetwork
 	}
 
-	return b.controller.Network(endpointIP, b.labels)
+	nw, source := b.controller.resolveNetwork(endpointIP, b.nodeName, b.labels)
+	b.networkSource = source
+	return nw
 }
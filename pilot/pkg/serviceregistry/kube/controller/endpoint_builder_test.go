@@ -233,8 +233,11 @@ func (c testController) getPodLocality(*v1.Pod) string {
 	return c.locality
 }
 
-func (c testController) Network(ip string, instance labels.Instance) network.ID {
-	return c.network
+func (c testController) resolveNetwork(ip, nodeName string, instance labels.Instance) (network.ID, model.NetworkSource) {
+	if c.network == "" {
+		return "", ""
+	}
+	return c.network, model.NetworkSourceSystemNamespace
 }
 
 func (c testController) Cluster() cluster2.ID {
@@ -228,13 +228,11 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 	discoverabilityPolicy := esc.c.exports.EndpointDiscoverabilityPolicy(esc.c.GetService(hostName))
 
 	for _, e := range slice.Endpoints() {
-		if !features.SendUnhealthyEndpoints {
-			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
-				// Ignore not ready endpoints
-				continue
-			}
+		healthStatus := endpointHealthStatus(e.Conditions)
+		if !features.SendUnhealthyEndpoints && healthStatus == model.UnHealthy {
+			// Ignore not ready endpoints
+			continue
 		}
-		ready := e.Conditions.Ready == nil || *e.Conditions.Ready
 		for _, a := range e.Addresses {
 			pod, expectedPod := getPod(esc.c, a, &metav1.ObjectMeta{Name: slice.Name, Namespace: slice.Namespace}, e.TargetRef, hostName)
 			if pod == nil && expectedPod {
@@ -253,11 +251,7 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 				}
 
 				istioEndpoint := builder.buildIstioEndpoint(a, portNum, portName, discoverabilityPolicy)
-				if ready {
-					istioEndpoint.HealthStatus = model.Healthy
-				} else {
-					istioEndpoint.HealthStatus = model.UnHealthy
-				}
+				istioEndpoint.HealthStatus = healthStatus
 				endpoints = append(endpoints, istioEndpoint)
 			}
 		}
@@ -265,6 +259,20 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 	esc.endpointCache.Update(hostName, slice.Name, endpoints)
 }
 
+// endpointHealthStatus returns the health status of an endpoint from its conditions. Like kube-proxy, the endpoints
+// of terminating pods that are still serving are kept, so that the connections keep being served while a service
+// only has terminating endpoints, e.g. during a rollout of a single replica.
+func endpointHealthStatus(conditions v1.EndpointConditions) model.HealthStatus {
+	if conditions.Ready == nil || *conditions.Ready {
+		return model.Healthy
+	}
+	if features.SendTerminatingServingEndpoints &&
+		conditions.Serving != nil && *conditions.Serving && conditions.Terminating != nil && *conditions.Terminating {
+		return model.Terminating
+	}
+	return model.UnHealthy
+}
+
 func (esc *endpointSliceController) buildIstioEndpointsWithService(name, namespace string, hostName host.Name, updateCache bool) []*model.IstioEndpoint {
 	esLabelSelector := endpointSliceSelectorForService(name)
 	slices, err := esc.listSlices(namespace, esLabelSelector)
@@ -521,7 +529,7 @@ func (esw *endpointSliceWrapper) Endpoints() []v1.Endpoint {
 			Conditions: v1.EndpointConditions{
 				Ready:       ep.Conditions.Ready,
 				Serving:     ep.Conditions.Serving,
-				Terminating: ep.Conditions.Serving,
+				Terminating: ep.Conditions.Terminating,
 			},
 			Hostname:           ep.Hostname,
 			TargetRef:          ep.TargetRef,
@@ -20,6 +20,8 @@ import (
 	"time"
 
 	coreV1 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/discovery/v1"
+	"k8s.io/api/discovery/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
 
@@ -206,3 +208,41 @@ func testEndpointsEqual(a, b []*model.IstioEndpoint) bool {
 	}
 	return reflect.DeepEqual(m1, m2)
 }
+
+func TestEndpointHealthStatus(t *testing.T) {
+	yes, no := true, false
+	cases := []struct {
+		name       string
+		conditions v1.EndpointConditions
+		want       model.HealthStatus
+	}{
+		{"unknown", v1.EndpointConditions{}, model.Healthy},
+		{"ready", v1.EndpointConditions{Ready: &yes, Serving: &yes, Terminating: &no}, model.Healthy},
+		{"not ready", v1.EndpointConditions{Ready: &no, Serving: &no, Terminating: &no}, model.UnHealthy},
+		{"terminating and serving", v1.EndpointConditions{Ready: &no, Serving: &yes, Terminating: &yes}, model.Terminating},
+		{"terminating and not serving", v1.EndpointConditions{Ready: &no, Serving: &no, Terminating: &yes}, model.UnHealthy},
+		{"terminating without serving condition", v1.EndpointConditions{Ready: &no, Terminating: &yes}, model.UnHealthy},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := endpointHealthStatus(tt.conditions); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointSliceWrapperConditions(t *testing.T) {
+	yes, no := true, false
+	slice := &v1beta1.EndpointSlice{
+		Endpoints: []v1beta1.Endpoint{{
+			Addresses:  []string{"1.2.3.4"},
+			Conditions: v1beta1.EndpointConditions{Ready: &no, Serving: &no, Terminating: &yes},
+		}},
+	}
+	got := wrapEndpointSlice(slice).Endpoints()[0].Conditions
+	want := v1.EndpointConditions{Ready: &no, Serving: &no, Terminating: &yes}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got conditions %v, want %v", got, want)
+	}
+}
@@ -232,19 +232,32 @@ func (m *Multicluster) ClusterAdded(cluster *multicluster.Cluster, clusterStopCh
 		// Patch injection webhook cert
 		// This requires RBAC permissions - a low-priv Istiod should not attempt to patch but rely on
 		// operator or CI/CD
+		// The istiods of the revision in all the primary clusters elect the one patching the remote cluster.
 		if features.InjectionWebhookConfigName != "" {
-			// TODO prevent istiods in primary clusters from trying to patch eachother. should we also leader-elect?
-			log.Infof("initializing webhook cert patch for cluster %s", cluster.ID)
-			patcher, err := webhooks.NewWebhookCertPatcher(client, m.revision, webhookName, m.caBundleWatcher)
-			if err != nil {
-				log.Errorf("could not initialize webhook cert patcher: %v", err)
-			} else {
-				go patcher.Run(clusterStopCh)
-			}
+			m.s.RunComponentAsyncAndWait(func(_ <-chan struct{}) error {
+				leaderelection.
+					NewPerRevisionLeaderElection(options.SystemNamespace, m.serverID, leaderelection.WebhookCertPatcherController, m.revision, client).
+					AddRunFunction(func(leaderStop <-chan struct{}) {
+						log.Infof("initializing webhook cert patch for cluster %s", cluster.ID)
+						patcher, err := webhooks.NewWebhookCertPatcher(client, m.revision, webhookName, m.caBundleWatcher)
+						if err != nil {
+							log.Errorf("could not initialize webhook cert patcher: %v", err)
+							return
+						}
+						patcher.Run(leaderStop)
+					}).Run(clusterStopCh)
+				return nil
+			})
 		}
 		// Patch validation webhook cert
-		go controller.NewValidatingWebhookController(client, m.revision, m.secretNamespace, m.caBundleWatcher).Run(clusterStopCh)
-
+		m.s.RunComponentAsyncAndWait(func(_ <-chan struct{}) error {
+			leaderelection.
+				NewPerRevisionLeaderElection(options.SystemNamespace, m.serverID, leaderelection.ValidationController, m.revision, client).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					controller.NewValidatingWebhookController(client, m.revision, m.secretNamespace, m.caBundleWatcher).Run(leaderStop)
+				}).Run(clusterStopCh)
+			return nil
+		})
 	}
 
 	// setting up the serviceexport controller if and only if it is turned on in the meshconfig.
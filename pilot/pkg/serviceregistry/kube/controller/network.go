@@ -16,9 +16,11 @@ package controller
 
 import (
 	"net"
+	"reflect"
 	"strconv"
 
 	"github.com/yl2chen/cidranger"
+	v1 "k8s.io/api/core/v1"
 
 	"istio.io/api/label"
 	"istio.io/istio/pilot/pkg/model"
@@ -41,10 +43,21 @@ type multinetwork struct {
 	registryServiceNameGateways map[host.Name][]model.NetworkGateway
 	// gateways for each service
 	networkGatewaysBySvc map[host.Name]model.NetworkGatewaySet
+	// networks of the nodes with a topology.istio.io/network label, by node name. Only tracked if
+	// PILOT_ENABLE_NODE_NETWORK_DETECTION is enabled.
+	nodeNetworks map[string]nodeNetwork
+	// CIDR ranger of the pod CIDRs and the addresses of the nodeNetworks
+	nodeRanger cidranger.Ranger
 	// implements NetworkGatewaysWatcher; we need to call c.NotifyGatewayHandlers when our gateways change
 	model.NetworkGatewaysHandler
 }
 
+// nodeNetwork is the network of a node, with the pod CIDRs and the addresses of the node.
+type nodeNetwork struct {
+	network network.ID
+	cidrs   []net.IPNet
+}
+
 func initMultinetwork() multinetwork {
 	return multinetwork{
 		// zero values are a workaround structcheck issue: https://github.com/golangci/golangci-lint/issues/826
@@ -53,6 +66,8 @@ func initMultinetwork() multinetwork {
 		networkForRegistry:          "",
 		registryServiceNameGateways: make(map[host.Name][]model.NetworkGateway),
 		networkGatewaysBySvc:        make(map[host.Name]model.NetworkGatewaySet),
+		nodeNetworks:                make(map[string]nodeNetwork),
+		nodeRanger:                  cidranger.NewPCTrieRanger(),
 	}
 }
 
@@ -80,6 +95,93 @@ func (c *Controller) onDefaultNetworkChange() {
 	c.reloadNetworkGateways()
 }
 
+// updateNodeNetwork tracks the network, pod CIDRs and addresses of a node, and returns true if its network label
+// changed, which requires a resync of the endpoints.
+func (c *Controller) updateNodeNetwork(node *v1.Node, event model.Event) bool {
+	var nn nodeNetwork
+	if event != model.EventDelete {
+		nn = buildNodeNetwork(node)
+	}
+	c.Lock()
+	defer c.Unlock()
+	old := c.nodeNetworks[node.Name]
+	if reflect.DeepEqual(old, nn) {
+		return false
+	}
+	for _, cidr := range old.cidrs {
+		_, _ = c.nodeRanger.Remove(cidr)
+	}
+	if nn.network == "" {
+		delete(c.nodeNetworks, node.Name)
+		return old.network != ""
+	}
+	c.nodeNetworks[node.Name] = nn
+	for _, cidr := range nn.cidrs {
+		_ = c.nodeRanger.Insert(namedRangerEntry{name: nn.network, network: cidr})
+	}
+	// changes of the pod CIDRs and addresses alone only apply to the endpoints built later
+	return old.network != nn.network
+}
+
+// buildNodeNetwork reads the network label, the pod CIDRs and the addresses of a node. The network is empty if the
+// node has no network label.
+func buildNodeNetwork(node *v1.Node) nodeNetwork {
+	nw := node.Labels[label.TopologyNetwork.Name]
+	if nw == "" {
+		return nodeNetwork{}
+	}
+	nn := nodeNetwork{network: network.ID(nw)}
+	podCIDRs := node.Spec.PodCIDRs
+	if len(podCIDRs) == 0 && node.Spec.PodCIDR != "" {
+		podCIDRs = []string{node.Spec.PodCIDR}
+	}
+	for _, cidr := range podCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Warnf("unable to parse pod CIDR %q of node %s", cidr, node.Name)
+			continue
+		}
+		nn.cidrs = append(nn.cidrs, *ipNet)
+	}
+	// the addresses of the node are those of the pods on the host network
+	for _, address := range node.Status.Addresses {
+		if address.Type != v1.NodeInternalIP && address.Type != v1.NodeExternalIP {
+			continue
+		}
+		ip := net.ParseIP(address.Address)
+		if ip == nil {
+			continue
+		}
+		bits := net.IPv6len * 8
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = net.IPv4len * 8
+		}
+		nn.cidrs = append(nn.cidrs, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nn
+}
+
+// networkFromNode returns the network of the named node or, if the node is unknown, of the node whose pod CIDRs or
+// addresses include the endpoint IP. Empty string if not found.
+func (c *Controller) networkFromNode(endpointIP, nodeName string) network.ID {
+	c.RLock()
+	defer c.RUnlock()
+	if nn, f := c.nodeNetworks[nodeName]; f {
+		return nn.network
+	}
+	ip := net.ParseIP(endpointIP)
+	if ip == nil {
+		return ""
+	}
+	entries, err := c.nodeRanger.ContainingNetworks(ip)
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	// the most specific entry is the last one, an address of a node being more specific than a pod CIDR
+	return entries[len(entries)-1].(namedRangerEntry).name
+}
+
 // reloadNetworkLookup refreshes the meshNetworks configuration, network for each endpoint, and
 // recomputes network gateways.
 func (c *Controller) reloadNetworkLookup() {
@@ -28,8 +28,10 @@ import (
 
 	"istio.io/api/label"
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/test/util/retry"
 )
 
@@ -177,3 +179,78 @@ func addMeshNetworksFromRegistryGateway(t *testing.T, c *FakeController, watcher
 		},
 	}})
 }
+
+func TestResolveNetworkFromNode(t *testing.T) {
+	defaultValue := features.EnableNodeNetworkDetection
+	features.EnableNodeNetworkDetection = true
+	defer func() { features.EnableNodeNetworkDetection = defaultValue }()
+
+	meshNetworks := mesh.NewFixedNetworksWatcher(&meshconfig.MeshNetworks{Networks: map[string]*meshconfig.Network{
+		"nw-cidr": {Endpoints: []*meshconfig.Network_NetworkEndpoints{{
+			Ne: &meshconfig.Network_NetworkEndpoints_FromCidr{FromCidr: "10.0.0.0/8"},
+		}}},
+	}})
+	c, _ := NewFakeControllerWithOptions(FakeControllerOptions{ClusterID: "Kubernetes", NetworksWatcher: meshNetworks})
+	defer close(c.stop)
+	c.reloadMeshNetworks()
+
+	node := generateNode("node1", map[string]string{label.TopologyNetwork.Name: "nw-node"})
+	node.Spec.PodCIDRs = []string{"10.1.0.0/24"}
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.2.0.1"}}
+	if !c.updateNodeNetwork(node, model.EventAdd) {
+		t.Fatal("expected the network of the node to change")
+	}
+	if c.updateNodeNetwork(node, model.EventUpdate) {
+		t.Fatal("did not expect the network of the node to change")
+	}
+	// a change of the addresses of the node alone does not change its network, but is tracked
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.2.0.1"}, {Type: corev1.NodeExternalIP, Address: "10.4.0.1"}}
+	if c.updateNodeNetwork(node, model.EventUpdate) {
+		t.Fatal("did not expect the network of the node to change with its addresses")
+	}
+	if nw, _ := c.resolveNetwork("10.4.0.1", "", nil); nw != "nw-node" {
+		t.Fatalf("got network %q for the new address of the node, want nw-node", nw)
+	}
+
+	cases := []struct {
+		name     string
+		ip       string
+		nodeName string
+		labels   map[string]string
+		network  network.ID
+		source   model.NetworkSource
+	}{
+		{"pod label", "10.1.0.5", "node1", map[string]string{label.TopologyNetwork.Name: "nw-pod"}, "nw-pod", model.NetworkSourceLabel},
+		{"node name", "192.168.0.1", "node1", nil, "nw-node", model.NetworkSourceNode},
+		{"pod CIDR", "10.1.0.5", "", nil, "nw-node", model.NetworkSourceNode},
+		{"node address", "10.2.0.1", "", nil, "nw-node", model.NetworkSourceNode},
+		{"meshNetworks", "10.3.0.1", "", nil, "nw-cidr", model.NetworkSourceMeshNetworks},
+		{"unknown", "192.168.0.1", "", nil, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			nw, source := c.resolveNetwork(tc.ip, tc.nodeName, tc.labels)
+			if nw != tc.network || source != tc.source {
+				t.Fatalf("got network %q from %q, want %q from %q", nw, source, tc.network, tc.source)
+			}
+		})
+	}
+
+	// the system namespace takes precedence over the meshNetworks, but not over the nodes
+	c.network = "nw-default"
+	if nw, source := c.resolveNetwork("10.1.0.5", "", nil); nw != "nw-node" || source != model.NetworkSourceNode {
+		t.Fatalf("got network %q from %q, want the network of the node", nw, source)
+	}
+	if nw, source := c.resolveNetwork("10.3.0.1", "", nil); nw != "nw-default" || source != model.NetworkSourceSystemNamespace {
+		t.Fatalf("got network %q from %q, want the network of the system namespace", nw, source)
+	}
+
+	// removing the label of the node removes its network
+	delete(node.Labels, label.TopologyNetwork.Name)
+	if !c.updateNodeNetwork(node, model.EventUpdate) {
+		t.Fatal("expected the network of the node to change")
+	}
+	if nw, _ := c.resolveNetwork("10.2.0.1", "node1", nil); nw != "nw-default" {
+		t.Fatalf("got network %q, want the network of the system namespace", nw)
+	}
+}
@@ -107,12 +107,13 @@ func ConvertService(svc coreV1.Service, domainSuffix string, clusterID cluster.I
 		CreationTime:    svc.CreationTimestamp.Time,
 		ResourceVersion: svc.ResourceVersion,
 		Attributes: model.ServiceAttributes{
-			ServiceRegistry: provider.Kubernetes,
-			Name:            svc.Name,
-			Namespace:       svc.Namespace,
-			Labels:          svc.Labels,
-			ExportTo:        exportTo,
-			LabelSelectors:  svc.Spec.Selector,
+			ServiceRegistry:  provider.Kubernetes,
+			Name:             svc.Name,
+			Namespace:        svc.Namespace,
+			Labels:           svc.Labels,
+			ExportTo:         exportTo,
+			ExportToClusters: model.ParseExportToClusters(svc.Annotations[model.ExportToClustersAnnotation]),
+			LabelSelectors:   svc.Spec.Selector,
 		},
 	}
 
@@ -199,13 +199,14 @@ func convertServices(cfg config.Config) []*model.Service {
 		}
 	}
 
+	exportToClusters := model.ParseExportToClusters(cfg.Annotations[model.ExportToClustersAnnotation])
 	return buildServices(hostAddresses, cfg.Namespace, svcPorts, serviceEntry.Location, resolution,
-		exportTo, labelSelectors, serviceEntry.SubjectAltNames, creationTime, cfg.Labels)
+		exportTo, exportToClusters, labelSelectors, serviceEntry.SubjectAltNames, creationTime, cfg.Labels)
 }
 
 func buildServices(hostAddresses []*HostAddress, namespace string, ports model.PortList, location networking.ServiceEntry_Location,
-	resolution model.Resolution, exportTo map[visibility.Instance]bool, selectors map[string]string, saccounts []string,
-	ctime time.Time, labels map[string]string) []*model.Service {
+	resolution model.Resolution, exportTo map[visibility.Instance]bool, exportToClusters map[cluster.ID]bool,
+	selectors map[string]string, saccounts []string, ctime time.Time, labels map[string]string) []*model.Service {
 	out := make([]*model.Service, 0, len(hostAddresses))
 	for _, ha := range hostAddresses {
 		out = append(out, &model.Service{
@@ -216,12 +217,13 @@ func buildServices(hostAddresses []*HostAddress, namespace string, ports model.P
 			Ports:          ports,
 			Resolution:     resolution,
 			Attributes: model.ServiceAttributes{
-				ServiceRegistry: provider.External,
-				Name:            ha.host,
-				Namespace:       namespace,
-				Labels:          labels,
-				ExportTo:        exportTo,
-				LabelSelectors:  selectors,
+				ServiceRegistry:  provider.External,
+				Name:             ha.host,
+				Namespace:        namespace,
+				Labels:           labels,
+				ExportTo:         exportTo,
+				ExportToClusters: exportToClusters,
+				LabelSelectors:   selectors,
 			},
 			ServiceAccounts: saccounts,
 		})
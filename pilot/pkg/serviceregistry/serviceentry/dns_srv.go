@@ -0,0 +1,260 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+)
+
+// srvEndpoint is an endpoint of a host, as advertised by its SRV records.
+type srvEndpoint struct {
+	address string
+	port    uint32
+	weight  uint32
+}
+
+// srvResolver tracks the SRV records of the hosts of the ServiceEntries with the DNS_SRV resolution.
+type srvResolver struct {
+	mu sync.Mutex
+	// entries are the ServiceEntries with the DNS_SRV resolution.
+	entries map[types.NamespacedName]config.Config
+	// endpoints are the last resolved endpoints of the hosts of each ServiceEntry.
+	endpoints map[types.NamespacedName]map[string][]srvEndpoint
+	// resolve is triggered when a ServiceEntry is added, to resolve it before the next refresh.
+	resolve chan struct{}
+
+	interval   time.Duration
+	lookupSRV  func(service, proto, name string) (string, []*net.SRV, error)
+	lookupHost func(host string) ([]string, error)
+}
+
+func newSRVResolver(interval time.Duration) *srvResolver {
+	return &srvResolver{
+		entries:    map[types.NamespacedName]config.Config{},
+		endpoints:  map[types.NamespacedName]map[string][]srvEndpoint{},
+		resolve:    make(chan struct{}, 1),
+		interval:   interval,
+		lookupSRV:  net.LookupSRV,
+		lookupHost: net.LookupHost,
+	}
+}
+
+// isDNSSRV returns whether the endpoints of the ServiceEntry are resolved from the SRV records of its hosts.
+func isDNSSRV(cfg config.Config) bool {
+	return cfg.Annotations[constants.ServiceEntryResolutionAnnotation] == constants.DNSSRVResolution
+}
+
+// update tracks the SRV records of the ServiceEntry, or stops tracking them if it was deleted or no longer has
+// the DNS_SRV resolution.
+func (r *srvResolver) update(cfg config.Config, event model.Event) {
+	key := types.NamespacedName{Namespace: cfg.Namespace, Name: cfg.Name}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event == model.EventDelete || !isDNSSRV(cfg) {
+		delete(r.entries, key)
+		delete(r.endpoints, key)
+		return
+	}
+	r.entries[key] = cfg
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+// get returns the ServiceEntry, if it is tracked.
+func (r *srvResolver) get(key types.NamespacedName) (config.Config, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg, f := r.entries[key]
+	return cfg, f
+}
+
+// workloadEntries returns the last resolved endpoints of the hosts of the ServiceEntry as WorkloadEntries, keyed
+// by host. The port advertised by a SRV record is used for all the ports of the ServiceEntry.
+func (r *srvResolver) workloadEntries(key types.NamespacedName, se *networking.ServiceEntry) map[string][]*networking.WorkloadEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := map[string][]*networking.WorkloadEntry{}
+	for h, endpoints := range r.endpoints[key] {
+		for _, ep := range endpoints {
+			wle := &networking.WorkloadEntry{
+				Address: ep.address,
+				Ports:   make(map[string]uint32, len(se.Ports)),
+				Weight:  ep.weight,
+			}
+			for _, p := range se.Ports {
+				wle.Ports[p.Name] = ep.port
+			}
+			out[h] = append(out[h], wle)
+		}
+	}
+	return out
+}
+
+// run resolves the SRV records of the ServiceEntries periodically, and when one is added, until stop is closed.
+// onChange is called with the ServiceEntries whose endpoints changed.
+func (r *srvResolver) run(stop <-chan struct{}, onChange func([]config.Config)) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-r.resolve:
+		}
+		if changed := r.resolveAll(); len(changed) > 0 {
+			onChange(changed)
+		}
+	}
+}
+
+// resolveAll resolves the SRV records of the hosts of all the ServiceEntries, and returns the ServiceEntries
+// whose endpoints changed. The endpoints of a host are kept if its records cannot be resolved.
+func (r *srvResolver) resolveAll() []config.Config {
+	r.mu.Lock()
+	entries := make([]config.Config, 0, len(r.entries))
+	for _, cfg := range r.entries {
+		entries = append(entries, cfg)
+	}
+	r.mu.Unlock()
+
+	var changed []config.Config
+	for _, cfg := range entries {
+		key := types.NamespacedName{Namespace: cfg.Namespace, Name: cfg.Name}
+		r.mu.Lock()
+		old := r.endpoints[key]
+		r.mu.Unlock()
+		endpoints := map[string][]srvEndpoint{}
+		for _, h := range cfg.Spec.(*networking.ServiceEntry).Hosts {
+			if host.Name(h).IsWildCarded() {
+				continue
+			}
+			eps, err := r.lookup(h)
+			if err != nil {
+				log.Warnf("failed to resolve SRV records of host %s of ServiceEntry %s/%s: %v", h, cfg.Namespace, cfg.Name, err)
+				eps = old[h]
+			}
+			if len(eps) > 0 {
+				endpoints[h] = eps
+			}
+		}
+		if reflect.DeepEqual(old, endpoints) {
+			continue
+		}
+		r.mu.Lock()
+		// The ServiceEntry may have been deleted or updated while resolving.
+		if current, f := r.entries[key]; f && current.ResourceVersion == cfg.ResourceVersion {
+			r.endpoints[key] = endpoints
+			changed = append(changed, cfg)
+		}
+		r.mu.Unlock()
+	}
+	return changed
+}
+
+// lookup returns the endpoints advertised by the SRV records of the host, sorted.
+func (r *srvResolver) lookup(h string) ([]srvEndpoint, error) {
+	_, records, err := r.lookupSRV("", "", h)
+	if err != nil {
+		return nil, err
+	}
+	var out []srvEndpoint
+	for _, srv := range records {
+		target := strings.TrimSuffix(srv.Target, ".")
+		addresses := []string{target}
+		if net.ParseIP(target) == nil {
+			if addresses, err = r.lookupHost(target); err != nil {
+				return nil, err
+			}
+		}
+		for _, address := range addresses {
+			out = append(out, srvEndpoint{address: address, port: uint32(srv.Port), weight: uint32(srv.Weight)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].address != out[j].address {
+			return out[i].address < out[j].address
+		}
+		return out[i].port < out[j].port
+	})
+	return out, nil
+}
+
+// buildSRVServiceInstances converts the last resolved endpoints of the hosts of a ServiceEntry with the DNS_SRV
+// resolution into service instances.
+func (s *ServiceEntryStore) buildSRVServiceInstances(curr config.Config, services []*model.Service) []*model.ServiceInstance {
+	se := curr.Spec.(*networking.ServiceEntry)
+	entries := s.srvResolver.workloadEntries(types.NamespacedName{Namespace: curr.Namespace, Name: curr.Name}, se)
+	var out []*model.ServiceInstance
+	for _, svc := range services {
+		cfg := curr
+		cfg.Spec = &networking.ServiceEntry{
+			Hosts:      se.Hosts,
+			Ports:      se.Ports,
+			Resolution: se.Resolution,
+			Endpoints:  entries[string(svc.Hostname)],
+		}
+		out = append(out, s.convertServiceEntryToInstances(cfg, []*model.Service{svc})...)
+	}
+	return out
+}
+
+// updateSRVServiceInstances replaces the service instances of the ServiceEntries whose SRV records changed, and
+// pushes their endpoints.
+func (s *ServiceEntryStore) updateSRVServiceInstances(changed []config.Config) {
+	keys := map[instancesKey]struct{}{}
+	s.mutex.Lock()
+	for _, c := range changed {
+		key := types.NamespacedName{Namespace: c.Namespace, Name: c.Name}
+		// Skip the ServiceEntries deleted since they were resolved.
+		cfg, f := s.srvResolver.get(key)
+		if !f {
+			continue
+		}
+		services := s.services.getServices(key)
+		serviceInstancesByConfig, _ := s.buildServiceInstancesForSE(cfg, services)
+		for ckey, old := range s.serviceInstances.getServiceEntryInstances(key) {
+			s.serviceInstances.deleteInstances(ckey, old)
+		}
+		for ckey, value := range serviceInstancesByConfig {
+			s.serviceInstances.addInstances(ckey, value)
+		}
+		s.serviceInstances.updateServiceEntryInstances(key, serviceInstancesByConfig)
+		for _, svc := range services {
+			keys[instancesKey{hostname: svc.Hostname, namespace: cfg.Namespace}] = struct{}{}
+		}
+	}
+	s.mutex.Unlock()
+
+	s.edsQueue.Push(func() error {
+		s.edsUpdateByKeys(keys, true)
+		return nil
+	})
+}
@@ -0,0 +1,143 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var srvServiceEntry = &config.Config{
+	Meta: config.Meta{
+		GroupVersionKind:  gvk.ServiceEntry,
+		Name:              "consul-db",
+		Namespace:         "srv",
+		CreationTimestamp: GlobalTime,
+		Annotations:       map[string]string{constants.ServiceEntryResolutionAnnotation: constants.DNSSRVResolution},
+	},
+	Spec: &networking.ServiceEntry{
+		Hosts: []string{"db.service.consul"},
+		Ports: []*networking.Port{
+			{Number: 5432, Name: "tcp-db", Protocol: "TCP"},
+		},
+		Location:   networking.ServiceEntry_MESH_EXTERNAL,
+		Resolution: networking.ServiceEntry_STATIC,
+	},
+}
+
+// fakeSRVRecords serves the SRV records of a single host.
+type fakeSRVRecords struct {
+	mu      sync.Mutex
+	records []*net.SRV
+	err     error
+}
+
+func (f *fakeSRVRecords) set(records []*net.SRV, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records, f.err = records, err
+}
+
+func (f *fakeSRVRecords) lookupSRV(_, _, _ string) (string, []*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return "", f.records, f.err
+}
+
+func expectSRVEndpoints(t *testing.T, sd *ServiceEntryStore, want ...string) {
+	t.Helper()
+	svc := convertServices(*srvServiceEntry)[0]
+	retry.UntilSuccessOrFail(t, func() error {
+		var got []string
+		for _, i := range sd.InstancesByPort(svc, 5432, nil) {
+			got = append(got, fmt.Sprintf("%s:%d", i.Endpoint.Address, i.Endpoint.EndpointPort))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("got endpoints %v, want %v", got, want)
+		}
+		return nil
+	}, retry.Timeout(time.Second))
+}
+
+func TestServiceEntryDNSSRV(t *testing.T) {
+	store, sd := initServiceDiscoveryWithoutEvents(t)
+	records := &fakeSRVRecords{}
+	sd.srvResolver.lookupSRV = records.lookupSRV
+	sd.srvResolver.lookupHost = func(h string) ([]string, error) {
+		if h == "node1.node.consul" {
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, fmt.Errorf("unknown host %s", h)
+	}
+	refresh := func() {
+		sd.updateSRVServiceInstances(sd.srvResolver.resolveAll())
+	}
+	key := types.NamespacedName{Namespace: srvServiceEntry.Namespace, Name: srvServiceEntry.Name}
+
+	records.set([]*net.SRV{
+		{Target: "node1.node.consul.", Port: 21000, Weight: 1},
+		{Target: "10.0.0.2", Port: 21001, Weight: 1},
+	}, nil)
+	createConfigs([]*config.Config{srvServiceEntry}, store, t)
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, f := sd.srvResolver.get(key); !f {
+			return fmt.Errorf("ServiceEntry not tracked")
+		}
+		return nil
+	}, retry.Timeout(time.Second))
+
+	t.Run("resolve", func(t *testing.T) {
+		refresh()
+		expectSRVEndpoints(t, sd, "10.0.0.1:21000", "10.0.0.2:21001")
+	})
+
+	t.Run("port change", func(t *testing.T) {
+		records.set([]*net.SRV{{Target: "10.0.0.2", Port: 22000}}, nil)
+		refresh()
+		expectSRVEndpoints(t, sd, "10.0.0.2:22000")
+	})
+
+	t.Run("lookup failure keeps endpoints", func(t *testing.T) {
+		records.set(nil, fmt.Errorf("no such host"))
+		if changed := sd.srvResolver.resolveAll(); len(changed) != 0 {
+			t.Fatalf("expected no change, got %v", changed)
+		}
+		expectSRVEndpoints(t, sd, "10.0.0.2:22000")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleteConfigs([]*config.Config{srvServiceEntry}, store, t)
+		retry.UntilSuccessOrFail(t, func() error {
+			if _, f := sd.srvResolver.get(key); f {
+				return fmt.Errorf("ServiceEntry still tracked")
+			}
+			return nil
+		}, retry.Timeout(time.Second))
+	})
+}
@@ -90,6 +90,9 @@ type ServiceEntryStore struct { // nolint:golint
 	// If all share one lock, then all the threads can have an obvious performance downgrade.
 	edsQueue queue.Instance
 
+	// srvResolver resolves the endpoints of the ServiceEntries with the DNS_SRV resolution.
+	srvResolver *srvResolver
+
 	workloadHandlers []func(*model.WorkloadInstance, model.Event)
 
 	// cb function used to get the networkID according to workload ip and labels.
@@ -142,6 +145,7 @@ func NewServiceDiscovery(
 			servicesBySE: map[types.NamespacedName][]*model.Service{},
 		},
 		edsQueue:            queue.NewQueue(time.Second),
+		srvResolver:         newSRVResolver(features.DNSSRVRefreshInterval),
 		processServiceEntry: true,
 	}
 	for _, o := range options {
@@ -294,6 +298,7 @@ func (s *ServiceEntryStore) serviceEntryHandler(_, curr config.Config, event mod
 	cs := convertServices(curr)
 	configsUpdated := map[model.ConfigKey]struct{}{}
 	key := types.NamespacedName{Namespace: curr.Namespace, Name: curr.Name}
+	s.srvResolver.update(curr, event)
 
 	s.mutex.Lock()
 	// If it is add/delete event we should always do a full push. If it is update event, we should do full push,
@@ -505,6 +510,7 @@ func (s *ServiceEntryStore) AppendWorkloadHandler(h func(*model.WorkloadInstance
 
 // Run is used by some controllers to execute background jobs after init is done.
 func (s *ServiceEntryStore) Run(stopCh <-chan struct{}) {
+	go s.srvResolver.run(stopCh, s.updateSRVServiceInstances)
 	s.edsQueue.Run(stopCh)
 }
 
@@ -866,7 +872,11 @@ func (s *ServiceEntryStore) buildServiceInstancesForSE(
 			serviceInstancesByConfig[ckey] = instances
 		}
 	} else {
-		serviceInstances = s.convertServiceEntryToInstances(curr, services)
+		if isDNSSRV(curr) && len(currentServiceEntry.Endpoints) == 0 {
+			serviceInstances = s.buildSRVServiceInstances(curr, services)
+		} else {
+			serviceInstances = s.convertServiceEntryToInstances(curr, services)
+		}
 		ckey := configKey{
 			kind:      serviceEntryConfigType,
 			name:      curr.Name,
@@ -208,7 +208,7 @@ func (r Result) Matches(t *testing.T, want Result) {
 }
 
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
@@ -228,21 +228,46 @@ func NewSimulation(t *testing.T, s *xds.FakeDiscoveryServer, proxy *model.Proxy)
 	return NewSimulationFromConfigGen(t, s.ConfigGenTest, proxy)
 }
 
+// NewSimulationFromConfig creates a simulation of already generated configuration, for example read from
+// an Envoy config dump. It is meant to be used outside of tests, with RunCall.
+func NewSimulationFromConfig(listeners []*listener.Listener, clusters []*cluster.Cluster,
+	routes []*route.RouteConfiguration) *Simulation {
+	return &Simulation{
+		Listeners: listeners,
+		Clusters:  clusters,
+		Routes:    routes,
+	}
+}
+
 // withT swaps out the testing struct. This allows executing sub tests.
-func (sim *Simulation) withT(t *testing.T) *Simulation {
+func (sim *Simulation) withT(t test.Failer) *Simulation {
 	cpy := *sim
 	cpy.t = t
 	return &cpy
 }
 
 func (sim *Simulation) RunExpectations(es []Expect) {
+	st, ok := sim.t.(*testing.T)
+	if !ok {
+		sim.t.Fatalf("expectations can only be run in a test")
+	}
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		st.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
 }
 
+// RunCall simulates the call like Run, but returns an error instead of failing the test when the
+// configuration cannot be evaluated.
+func (sim *Simulation) RunCall(input Call) (Result, error) {
+	var result Result
+	err := test.Wrap(func(t test.Failer) {
+		result = sim.withT(t).Run(input)
+	})
+	return result, err
+}
+
 func hasFilterOnPort(l *listener.Listener, filter string, port int) bool {
 	got, f := xdstest.ExtractListenerFilters(l)[filter]
 	if !f {
@@ -422,7 +447,7 @@ func (sim *Simulation) matchRoute(vh *route.VirtualHost, input Call) *route.Rout
 
 func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host string) *route.VirtualHost {
 	// Exact match
-	for _, vh := range rc.VirtualHosts {
+	for _, vh := range rc.GetVirtualHosts() {
 		for _, d := range vh.Domains {
 			if d == host {
 				return vh
@@ -432,7 +457,7 @@ func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host strin
 	// prefix match
 	var bestMatch *route.VirtualHost
 	longest := 0
-	for _, vh := range rc.VirtualHosts {
+	for _, vh := range rc.GetVirtualHosts() {
 		for _, d := range vh.Domains {
 			if d[0] != '*' {
 				continue
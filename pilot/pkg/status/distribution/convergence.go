@@ -0,0 +1,162 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"istio.io/istio/pilot/pkg/status"
+)
+
+const (
+	// convergenceWebhookQueueSize bounds the number of events waiting to be sent to the webhook.
+	convergenceWebhookQueueSize = 1000
+	convergenceWebhookTimeout   = 10 * time.Second
+)
+
+// ConvergenceEvent reports that a generation of a config was acknowledged by all the proxies connected to all the
+// istiod replicas. The events are sent at least once: a generation may be reported again after a change of leader.
+type ConvergenceEvent struct {
+	Group      string `json:"group"`
+	Version    string `json:"version"`
+	Resource   string `json:"resource"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Generation string `json:"generation"`
+	// Proxies is the number of proxies which acknowledged the generation.
+	Proxies int `json:"proxies"`
+}
+
+// ConvergenceSink receives the convergence events of the configs, for example to let deployment pipelines
+// sequence their steps on the actual convergence of the mesh.
+type ConvergenceSink interface {
+	// Converged is called when all the proxies acknowledged a generation of a config. It must not block.
+	Converged(event ConvergenceEvent)
+}
+
+func newConvergenceEvent(res status.Resource, progress Progress) ConvergenceEvent {
+	return ConvergenceEvent{
+		Group:      res.Group,
+		Version:    res.Version,
+		Resource:   res.GroupVersionResource.Resource,
+		Namespace:  res.Namespace,
+		Name:       res.Name,
+		Generation: res.Generation,
+		Proxies:    progress.TotalInstances,
+	}
+}
+
+// convergenceKey identifies a config regardless of its generation.
+func convergenceKey(res status.Resource) string {
+	res.Generation = ""
+	return res.String()
+}
+
+// notifyConverged sends the event of the generation of the config to the sinks if it was acknowledged by all the
+// proxies and neither it nor a later generation was notified yet. It is only called by the status writer, which
+// owns the converged generations.
+func (c *Controller) notifyConverged(res status.Resource, progress Progress, seen map[string]struct{}) {
+	key := convergenceKey(res)
+	seen[key] = struct{}{}
+	if progress.TotalInstances == 0 || progress.AckedInstances < progress.TotalInstances {
+		return
+	}
+	generation, err := strconv.ParseInt(res.Generation, 10, 64)
+	if err != nil {
+		return
+	}
+	if notified, f := c.converged[key]; f && notified >= generation {
+		return
+	}
+	c.converged[key] = generation
+	event := newConvergenceEvent(res, progress)
+	for _, sink := range c.ConvergenceSinks {
+		sink.Converged(event)
+	}
+}
+
+// pruneConverged forgets the generations of the configs which are no longer tracked.
+func (c *Controller) pruneConverged(seen map[string]struct{}) {
+	for key := range c.converged {
+		if _, f := seen[key]; !f {
+			delete(c.converged, key)
+		}
+	}
+}
+
+// ConvergenceWebhook posts the convergence events as JSON to a URL.
+type ConvergenceWebhook struct {
+	url    string
+	client *http.Client
+	events chan ConvergenceEvent
+}
+
+var _ ConvergenceSink = &ConvergenceWebhook{}
+
+// NewConvergenceWebhook returns a webhook posting the convergence events to the URL. Run must be called to send
+// them.
+func NewConvergenceWebhook(url string) *ConvergenceWebhook {
+	return &ConvergenceWebhook{
+		url:    url,
+		client: &http.Client{Timeout: convergenceWebhookTimeout},
+		events: make(chan ConvergenceEvent, convergenceWebhookQueueSize),
+	}
+}
+
+// Converged queues the event, or drops it if the webhook is too slow.
+func (w *ConvergenceWebhook) Converged(event ConvergenceEvent) {
+	select {
+	case w.events <- event:
+	default:
+		scope.Warnf("dropping convergence event of %s/%s generation %s: webhook queue is full",
+			event.Namespace, event.Name, event.Generation)
+	}
+}
+
+// Run sends the queued events until stop is closed.
+func (w *ConvergenceWebhook) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-w.events:
+			if err := w.send(event); err != nil {
+				scope.Warnf("failed to send convergence event of %s/%s generation %s: %v",
+					event.Namespace, event.Name, event.Generation, err)
+			}
+		}
+	}
+}
+
+func (w *ConvergenceWebhook) send(event ConvergenceEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/status"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+type fakeConvergenceSink struct {
+	events []ConvergenceEvent
+}
+
+func (f *fakeConvergenceSink) Converged(event ConvergenceEvent) {
+	f.events = append(f.events, event)
+}
+
+func TestControllerConvergence(t *testing.T) {
+	sink := &fakeConvergenceSink{}
+	c := &Controller{
+		CurrentState:     make(map[status.Resource]map[string]Progress),
+		ObservationTime:  make(map[string]time.Time),
+		StaleInterval:    time.Minute,
+		clock:            clock.RealClock{},
+		workers:          status.NewManager(memory.Make(collections.Pilot)).CreateGenericController(nil),
+		ConvergenceSinks: []ConvergenceSink{sink},
+		converged:        make(map[string]int64),
+	}
+	gen1 := "networking.istio.io/v1alpha3/virtualservices/default/vs/1"
+	gen2 := "networking.istio.io/v1alpha3/virtualservices/default/vs/2"
+	want := func(generation string, proxies int) ConvergenceEvent {
+		return ConvergenceEvent{
+			Group:      "networking.istio.io",
+			Version:    "v1alpha3",
+			Resource:   "virtualservices",
+			Namespace:  "default",
+			Name:       "vs",
+			Generation: generation,
+			Proxies:    proxies,
+		}
+	}
+
+	// The proxies of a replica acked the config, but not those of the other one.
+	c.handleReport(Report{Reporter: "istiod-a", DataPlaneCount: 2, InProgressResources: map[string]int{gen1: 2}})
+	c.handleReport(Report{Reporter: "istiod-b", DataPlaneCount: 3, InProgressResources: map[string]int{gen1: 1}})
+	c.writeAllStatus()
+	if len(sink.events) != 0 {
+		t.Fatalf("unexpected events before all the proxies acked: %+v", sink.events)
+	}
+
+	c.handleReport(Report{Reporter: "istiod-b", DataPlaneCount: 3, InProgressResources: map[string]int{gen1: 3}})
+	c.writeAllStatus()
+	c.writeAllStatus()
+	if !reflect.DeepEqual(sink.events, []ConvergenceEvent{want("1", 5)}) {
+		t.Fatalf("got %+v, want a single event for generation 1", sink.events)
+	}
+
+	// The older generation is still tracked, but only the new one is notified.
+	c.handleReport(Report{Reporter: "istiod-a", DataPlaneCount: 2, InProgressResources: map[string]int{gen2: 2}})
+	c.handleReport(Report{Reporter: "istiod-b", DataPlaneCount: 3, InProgressResources: map[string]int{gen2: 3}})
+	c.writeAllStatus()
+	c.writeAllStatus()
+	if !reflect.DeepEqual(sink.events, []ConvergenceEvent{want("1", 5), want("2", 5)}) {
+		t.Fatalf("got %+v, want events for generations 1 and 2", sink.events)
+	}
+}
+
+func TestConvergenceWebhook(t *testing.T) {
+	received := make(chan ConvergenceEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := ConvergenceEvent{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer srv.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	webhook := NewConvergenceWebhook(srv.URL)
+	go webhook.Run(stop)
+	want := ConvergenceEvent{Namespace: "a", Name: "vs", Generation: "1", Proxies: 2}
+	webhook.Converged(want)
+	select {
+	case got := <-received:
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+}
@@ -62,12 +62,17 @@ type Controller struct {
 	workers         *status.Controller
 	StaleInterval   time.Duration
 	cmInformer      cache.SharedIndexInformer
+	// ConvergenceSinks receive the generations of the configs acknowledged by all the proxies.
+	ConvergenceSinks []ConvergenceSink
+	// converged is the last generation of each config notified to the ConvergenceSinks.
+	converged map[string]int64
 }
 
 func NewController(restConfig *rest.Config, namespace string, cs model.ConfigStore, m *status.Manager) *Controller {
 	c := &Controller{
 		CurrentState:    make(map[status.Resource]map[string]Progress),
 		ObservationTime: make(map[string]time.Time),
+		converged:       make(map[string]int64),
 		UpdateInterval:  200 * time.Millisecond,
 		StaleInterval:   time.Minute,
 		clock:           clock.RealClock{},
@@ -144,6 +149,7 @@ func (c *Controller) handleReport(d Report) {
 func (c *Controller) writeAllStatus() (staleReporters []string) {
 	defer c.mu.RUnlock()
 	c.mu.RLock()
+	seen := map[string]struct{}{}
 	for config, fractions := range c.CurrentState {
 		var distributionState Progress
 		for reporter, w := range fractions {
@@ -159,6 +165,12 @@ func (c *Controller) writeAllStatus() (staleReporters []string) {
 		if distributionState.TotalInstances > 0 { // this is necessary when all reports are stale.
 			c.queueWriteStatus(config, distributionState)
 		}
+		if len(c.ConvergenceSinks) > 0 {
+			c.notifyConverged(config, distributionState, seen)
+		}
+	}
+	if len(c.ConvergenceSinks) > 0 {
+		c.pruneConverged(seen)
 	}
 	return
 }
@@ -196,36 +208,45 @@ func boolToConditionStatus(b bool) string {
 	return "False"
 }
 
+const (
+	reconciledCondition = "Reconciled"
+	// distributedCondition lets health checks, for example those of GitOps tools, consider a generation of a
+	// resource rolled out once most of the proxies are up to date, rather than waiting on every last one.
+	distributedCondition = "Distributed"
+)
+
+// Distributed returns whether at least the threshold fraction of the proxies are up to date.
+func (p Progress) Distributed(threshold float64) bool {
+	return float64(p.AckedInstances) >= threshold*float64(p.TotalInstances)
+}
+
 func ReconcileStatuses(current *v1alpha1.IstioStatus, desired Progress) (bool, *v1alpha1.IstioStatus) {
-	needsReconcile := false
+	message := fmt.Sprintf("%d/%d proxies up to date.", desired.AckedInstances, desired.TotalInstances)
+	current = current.DeepCopy()
+	needsReconcile := setCondition(current, reconciledCondition, desired.AckedInstances == desired.TotalInstances, message)
+	if setCondition(current, distributedCondition, desired.Distributed(features.StatusDistributedThreshold), message) {
+		needsReconcile = true
+	}
+	return needsReconcile, current
+}
+
+// setCondition sets the condition of the given type in the status, and returns whether its status or message changed.
+func setCondition(current *v1alpha1.IstioStatus, conditionType string, status bool, message string) bool {
 	desiredCondition := v1alpha1.IstioCondition{
-		Type:               "Reconciled",
-		Status:             boolToConditionStatus(desired.AckedInstances == desired.TotalInstances),
+		Type:               conditionType,
+		Status:             boolToConditionStatus(status),
 		LastProbeTime:      types.TimestampNow(),
 		LastTransitionTime: types.TimestampNow(),
-		Message:            fmt.Sprintf("%d/%d proxies up to date.", desired.AckedInstances, desired.TotalInstances),
+		Message:            message,
 	}
-	current = current.DeepCopy()
-	var currentCondition *v1alpha1.IstioCondition
-	conditionIndex := -1
 	for i, c := range current.Conditions {
-		if c.Type == "Reconciled" {
-			currentCondition = current.Conditions[i]
-			conditionIndex = i
-			break
+		if c.Type == conditionType {
+			current.Conditions[i] = &desiredCondition
+			return c.Message != desiredCondition.Message || c.Status != desiredCondition.Status
 		}
 	}
-	if currentCondition == nil ||
-		currentCondition.Message != desiredCondition.Message ||
-		currentCondition.Status != desiredCondition.Status {
-		needsReconcile = true
-	}
-	if conditionIndex > -1 {
-		current.Conditions[conditionIndex] = &desiredCondition
-	} else {
-		current.Conditions = append(current.Conditions, &desiredCondition)
-	}
-	return needsReconcile, current
+	current.Conditions = append(current.Conditions, &desiredCondition)
+	return true
 }
 
 type DistroReportHandler struct {
@@ -36,6 +36,11 @@ var statusStillPropagating = &v1alpha1.IstioStatus{
 			Status:  "False",
 			Message: "1/2 proxies up to date.",
 		},
+		{
+			Type:    "Distributed",
+			Status:  "False",
+			Message: "1/2 proxies up to date.",
+		},
 	},
 	ValidationMessages: nil,
 }
@@ -77,6 +82,11 @@ func TestReconcileStatuses(t *testing.T) {
 						Status:  "False",
 						Message: "1/3 proxies up to date.",
 					},
+					{
+						Type:    "Distributed",
+						Status:  "False",
+						Message: "1/3 proxies up to date.",
+					},
 				},
 				ValidationMessages: nil,
 			},
@@ -99,6 +109,11 @@ func TestReconcileStatuses(t *testing.T) {
 						Status:  "True",
 						Message: "2/2 proxies up to date.",
 					},
+					{
+						Type:    "Distributed",
+						Status:  "True",
+						Message: "2/2 proxies up to date.",
+					},
 				},
 				ValidationMessages: nil,
 			},
@@ -121,6 +136,38 @@ func TestReconcileStatuses(t *testing.T) {
 						Status:  "False",
 						Message: "2/3 proxies up to date.",
 					},
+					{
+						Type:    "Distributed",
+						Status:  "False",
+						Message: "2/3 proxies up to date.",
+					},
+				},
+			},
+		},
+		{
+			name: "Distributed before all proxies are up to date",
+			args: args{
+				current: &config.Config{Status: statusStillPropagating},
+				desired: Progress{99, 100},
+			},
+			want: true,
+			want1: &v1alpha1.IstioStatus{
+				Conditions: []*v1alpha1.IstioCondition{
+					{
+						Type:    "PassedValidation",
+						Status:  "True",
+						Message: "just a test, here",
+					},
+					{
+						Type:    "Reconciled",
+						Status:  "False",
+						Message: "99/100 proxies up to date.",
+					},
+					{
+						Type:    "Distributed",
+						Status:  "True",
+						Message: "99/100 proxies up to date.",
+					},
 				},
 			},
 		},
@@ -0,0 +1,257 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/status"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/pkg/log"
+)
+
+var scope = log.RegisterScope("usage", "ServiceEntry usage tracking", 0)
+
+const (
+	// ConditionType is the type of the status condition marking ServiceEntries which did not receive traffic.
+	ConditionType = "Unused"
+
+	// checkInterval is how often the ServiceEntries are evaluated.
+	checkInterval = time.Minute
+)
+
+// Tracker records when traffic was last sent to each host, from the load reports of the proxies.
+// It implements xds.LoadReportSink.
+type Tracker struct {
+	mu       sync.RWMutex
+	lastSeen map[host.Name]time.Time
+	now      func() time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		lastSeen: map[host.Name]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// Report records the outbound clusters of the load report which received requests.
+func (t *Tracker) Report(_ *model.Proxy, stats []*endpoint.ClusterStats) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, cs := range stats {
+		if !hasTraffic(cs) {
+			continue
+		}
+		direction, _, hostname, _ := model.ParseSubsetKey(cs.GetClusterName())
+		if direction != model.TrafficDirectionOutbound || hostname == "" {
+			continue
+		}
+		t.lastSeen[hostname] = now
+	}
+}
+
+// LastSeen returns the last time traffic was reported to any of the hosts, or the zero time if none was.
+func (t *Tracker) LastSeen(hosts []string) time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var last time.Time
+	for _, h := range hosts {
+		if seen := t.lastSeen[host.Name(h)]; seen.After(last) {
+			last = seen
+		}
+	}
+	return last
+}
+
+func hasTraffic(cs *endpoint.ClusterStats) bool {
+	for _, ls := range cs.GetUpstreamLocalityStats() {
+		if ls.GetTotalIssuedRequests() > 0 || ls.GetTotalSuccessfulRequests() > 0 ||
+			ls.GetTotalErrorRequests() > 0 || ls.GetTotalRequestsInProgress() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Controller marks the ServiceEntries whose hosts have not received traffic for a period with the
+// Unused condition.
+//
+// The condition also records the observation window, so that it is shared by all istiod replicas and
+// survives restarts: while its status is Unknown, the last probe time is when tracking started; while its
+// status is False, it is the last time a replica observed traffic. Each replica only observes the traffic of
+// the proxies connected to it, so every replica clears the condition when its proxies report traffic. Only the
+// elected leader starts tracking new ServiceEntries and marks them unused once the window is older than the
+// threshold.
+type Controller struct {
+	store     model.ConfigStore
+	tracker   *Tracker
+	statusctl *status.Controller
+	threshold time.Duration
+	now       func() time.Time
+	leading   *atomic.Bool
+}
+
+func NewController(store model.ConfigStore, tracker *Tracker, statusManager *status.Manager, threshold time.Duration) *Controller {
+	return &Controller{
+		store:   store,
+		tracker: tracker,
+		statusctl: statusManager.CreateIstioStatusController(func(status *v1alpha1.IstioStatus, context interface{}) *v1alpha1.IstioStatus {
+			return setCondition(status, context.(*v1alpha1.IstioCondition))
+		}),
+		threshold: threshold,
+		now:       time.Now,
+		leading:   atomic.NewBool(false),
+	}
+}
+
+// Lead marks the ServiceEntries unused until the stop channel is closed. It is run by the elected leader.
+func (c *Controller) Lead(stop <-chan struct{}) {
+	c.leading.Store(true)
+	<-stop
+	c.leading.Store(false)
+}
+
+// Run is blocking
+func (c *Controller) Run(stop <-chan struct{}) {
+	t := time.NewTicker(checkInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.reconcile()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *Controller) reconcile() {
+	entries, err := c.store.List(gvk.ServiceEntry, model.NamespaceAll)
+	if err != nil {
+		scope.Errorf("failed to list ServiceEntries: %v", err)
+		return
+	}
+	now := c.now()
+	leading := c.leading.Load()
+	for _, cfg := range entries {
+		desired := c.desiredCondition(cfg, now, leading)
+		if desired == nil {
+			continue
+		}
+		scope.Debugf("updating %s condition of ServiceEntry %s/%s to %s", ConditionType, cfg.Namespace, cfg.Name, desired.Status)
+		c.statusctl.EnqueueStatusUpdateResource(desired, status.ResourceFromModelConfig(cfg))
+	}
+}
+
+// desiredCondition returns the condition to write to the ServiceEntry, or nil if the current one is up to date.
+// Only the leader starts tracking the ServiceEntry or marks it unused.
+func (c *Controller) desiredCondition(cfg config.Config, now time.Time, leading bool) *v1alpha1.IstioCondition {
+	se, ok := cfg.Spec.(*networking.ServiceEntry)
+	if !ok {
+		return nil
+	}
+	lastSeen := c.tracker.LastSeen(se.Hosts)
+	current := getCondition(cfg.Status)
+	if current == nil {
+		if !lastSeen.IsZero() {
+			return newCondition(nil, "False", lastSeen, "Traffic was observed")
+		}
+		if !leading {
+			return nil
+		}
+		return newCondition(nil, "Unknown", now, "No traffic was observed since the last probe time")
+	}
+	since := timestampToTime(current.LastProbeTime)
+	// The observed traffic is refreshed often enough for the other replicas, without writing on every
+	// report. The probe time may thus be up to refresh older than the last traffic seen by another replica.
+	refresh := c.threshold / 4
+	if lastSeen.After(since) && (current.Status == "True" || lastSeen.Sub(since) > refresh) {
+		return newCondition(current, "False", lastSeen, "Traffic was observed")
+	}
+	if leading && current.Status != "True" && !since.IsZero() && now.Sub(since) > c.threshold+refresh && now.Sub(lastSeen) > c.threshold {
+		return newCondition(current, "True", since,
+			fmt.Sprintf("No traffic was observed for %v. The ServiceEntry may be removed.", c.threshold))
+	}
+	return nil
+}
+
+func newCondition(current *v1alpha1.IstioCondition, conditionStatus string, probe time.Time, message string) *v1alpha1.IstioCondition {
+	probeTime, _ := types.TimestampProto(probe)
+	transitionTime := types.TimestampNow()
+	if current != nil && current.Status == conditionStatus {
+		transitionTime = current.LastTransitionTime
+	}
+	return &v1alpha1.IstioCondition{
+		Type:               ConditionType,
+		Status:             conditionStatus,
+		LastProbeTime:      probeTime,
+		LastTransitionTime: transitionTime,
+		Message:            message,
+	}
+}
+
+func getCondition(s config.Status) *v1alpha1.IstioCondition {
+	istioStatus, ok := s.(*v1alpha1.IstioStatus)
+	if !ok {
+		return nil
+	}
+	for _, cond := range istioStatus.Conditions {
+		if cond.Type == ConditionType {
+			return cond
+		}
+	}
+	return nil
+}
+
+// setCondition returns a copy of current with the Unused condition replaced by desired.
+func setCondition(current *v1alpha1.IstioStatus, desired *v1alpha1.IstioCondition) *v1alpha1.IstioStatus {
+	if current == nil {
+		current = &v1alpha1.IstioStatus{}
+	}
+	current = current.DeepCopy()
+	for i, cond := range current.Conditions {
+		if cond.Type == ConditionType {
+			current.Conditions[i] = desired
+			return current
+		}
+	}
+	current.Conditions = append(current.Conditions, desired)
+	return current
+}
+
+func timestampToTime(ts *types.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, err := types.TimestampFromProto(ts)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
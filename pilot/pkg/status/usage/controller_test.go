@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"testing"
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func clusterStats(name string, requests uint64) *endpoint.ClusterStats {
+	return &endpoint.ClusterStats{
+		ClusterName: name,
+		UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{{
+			TotalSuccessfulRequests: requests,
+			TotalIssuedRequests:     requests,
+		}},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+	tracker.Report(nil, []*endpoint.ClusterStats{
+		clusterStats("outbound|443||api.example.com", 3),
+		clusterStats("outbound|443||idle.example.com", 0),
+		clusterStats("inbound|8080||", 5),
+		clusterStats("BlackHoleCluster", 1),
+	})
+
+	assert.Equal(t, tracker.LastSeen([]string{"api.example.com"}), now)
+	assert.Equal(t, tracker.LastSeen([]string{"idle.example.com", "other.example.com"}).IsZero(), true)
+
+	later := now.Add(time.Minute)
+	tracker.now = func() time.Time { return later }
+	tracker.Report(nil, []*endpoint.ClusterStats{clusterStats("outbound|80|v1|web.example.com", 1)})
+	assert.Equal(t, tracker.LastSeen([]string{"api.example.com", "web.example.com"}), later)
+}
+
+func condition(conditionStatus string, probe time.Time) *v1alpha1.IstioCondition {
+	ts, _ := types.TimestampProto(probe)
+	return &v1alpha1.IstioCondition{Type: ConditionType, Status: conditionStatus, LastProbeTime: ts}
+}
+
+func TestDesiredCondition(t *testing.T) {
+	threshold := 4 * time.Hour
+	now := time.Unix(100000, 0)
+	tests := []struct {
+		name       string
+		current    *v1alpha1.IstioCondition
+		lastSeen   time.Time
+		follower   bool
+		wantStatus string
+		wantProbe  time.Time
+	}{
+		{
+			name:       "start tracking",
+			wantStatus: "Unknown",
+			wantProbe:  now,
+		},
+		{
+			name:       "start tracking with traffic",
+			lastSeen:   now.Add(-time.Minute),
+			wantStatus: "False",
+			wantProbe:  now.Add(-time.Minute),
+		},
+		{
+			name:     "start tracking on a follower",
+			follower: true,
+		},
+		{
+			name:       "start tracking with traffic on a follower",
+			lastSeen:   now.Add(-time.Minute),
+			follower:   true,
+			wantStatus: "False",
+			wantProbe:  now.Add(-time.Minute),
+		},
+		{
+			name:    "tracking within threshold",
+			current: condition("Unknown", now.Add(-time.Hour)),
+		},
+		{
+			name:       "no traffic past threshold",
+			current:    condition("Unknown", now.Add(-6*time.Hour)),
+			wantStatus: "True",
+			wantProbe:  now.Add(-6 * time.Hour),
+		},
+		{
+			name:     "no traffic past threshold on a follower",
+			current:  condition("Unknown", now.Add(-6*time.Hour)),
+			follower: true,
+		},
+		{
+			name:     "recent traffic within refresh",
+			current:  condition("False", now.Add(-30*time.Minute)),
+			lastSeen: now.Add(-time.Minute),
+		},
+		{
+			name:       "recent traffic past refresh",
+			current:    condition("False", now.Add(-2*time.Hour)),
+			lastSeen:   now.Add(-time.Minute),
+			wantStatus: "False",
+			wantProbe:  now.Add(-time.Minute),
+		},
+		{
+			name:    "traffic within refresh slack of another replica",
+			current: condition("False", now.Add(-threshold-time.Minute)),
+		},
+		{
+			name:    "already unused",
+			current: condition("True", now.Add(-10*time.Hour)),
+		},
+		{
+			name:       "traffic to unused",
+			current:    condition("True", now.Add(-10*time.Hour)),
+			lastSeen:   now.Add(-time.Minute),
+			wantStatus: "False",
+			wantProbe:  now.Add(-time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker()
+			if !tt.lastSeen.IsZero() {
+				tracker.lastSeen["api.example.com"] = tt.lastSeen
+			}
+			c := &Controller{tracker: tracker, threshold: threshold}
+			cfg := config.Config{
+				Meta: config.Meta{Name: "api", Namespace: "default"},
+				Spec: &networking.ServiceEntry{Hosts: []string{"api.example.com"}},
+			}
+			if tt.current != nil {
+				cfg.Status = &v1alpha1.IstioStatus{Conditions: []*v1alpha1.IstioCondition{tt.current}}
+			}
+			got := c.desiredCondition(cfg, now, !tt.follower)
+			if tt.wantStatus == "" {
+				if got != nil {
+					t.Fatalf("expected no update, got %v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected condition %s, got none", tt.wantStatus)
+			}
+			assert.Equal(t, got.Status, tt.wantStatus)
+			assert.Equal(t, timestampToTime(got.LastProbeTime), tt.wantProbe)
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	reconciled := &v1alpha1.IstioCondition{Type: "Reconciled", Status: "True"}
+	current := &v1alpha1.IstioStatus{Conditions: []*v1alpha1.IstioCondition{reconciled}}
+
+	updated := setCondition(current, &v1alpha1.IstioCondition{Type: ConditionType, Status: "Unknown"})
+	assert.Equal(t, len(current.Conditions), 1)
+	assert.Equal(t, len(updated.Conditions), 2)
+
+	updated = setCondition(updated, &v1alpha1.IstioCondition{Type: ConditionType, Status: "True"})
+	assert.Equal(t, len(updated.Conditions), 2)
+	assert.Equal(t, updated.Conditions[1].Status, "True")
+
+	assert.Equal(t, len(setCondition(nil, &v1alpha1.IstioCondition{Type: ConditionType}).Conditions), 1)
+}
@@ -110,6 +110,10 @@ type Connection struct {
 	// (last push not ACKed). When we get an ACK from Envoy, if the type is populated here, we will trigger
 	// the push.
 	blockedPushes map[string]*model.PushRequest
+
+	// lastFullPush is the time the last full push to this connection was dequeued. It is guarded by
+	// the lock of the push queue.
+	lastFullPush time.Time
 }
 
 // Event represents a config or registry event that results in a push.
@@ -167,6 +171,10 @@ func (s *DiscoveryServer) receive(con *Connection, identities []string) {
 				log.Warnf("ADS: %q %s send health check probe before normal xDS request", con.PeerAddr, con.ConID)
 				continue
 			}
+			if req.TypeUrl == v3.OutlierEventType || req.TypeUrl == v3.HealthCheckEventType || req.TypeUrl == v3.MTLSReportType {
+				log.Warnf("ADS: %q %s send endpoint events before normal xDS request", con.PeerAddr, con.ConID)
+				continue
+			}
 			firstRequest = false
 			if req.Node == nil || req.Node.Id == "" {
 				con.errorChan <- status.New(codes.InvalidArgument, "missing node information").Err()
@@ -240,6 +248,13 @@ func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *C
 	if con.proxy.SidecarScope != nil && con.proxy.SidecarScope.Version != push.PushVersion {
 		s.computeProxyState(con.proxy, request)
 	}
+	if shouldRespond {
+		release, err := s.waitForRequestPushLimit(con.stream.Context())
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
 	return s.pushXds(con, push, con.Watched(req.TypeUrl), request)
 }
 
@@ -275,9 +290,8 @@ func (s *DiscoveryServer) Stream(stream DiscoveryStream) error {
 		peerAddr = peerInfo.Addr.String()
 	}
 
-	if err := s.WaitForRequestLimit(stream.Context()); err != nil {
-		log.Warnf("ADS: %q exceeded rate limit: %v", peerAddr, err)
-		return status.Errorf(codes.ResourceExhausted, "request rate limit exceeded: %v", err)
+	if err := s.admitConnection(stream.Context(), peerAddr); err != nil {
+		return err
 	}
 
 	ids, err := s.authenticate(ctx)
@@ -350,12 +364,17 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 		errCode := codes.Code(request.ErrorDetail.Code)
 		log.Warnf("ADS:%s: ACK ERROR %s %s:%s", stype, con.ConID, errCode.String(), request.ErrorDetail.GetMessage())
 		incrementXDSRejects(request.TypeUrl, con.proxy.ID, errCode.String())
+		s.reportStagedRolloutNack(con.proxy, request.TypeUrl)
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, request)
 		}
 		con.proxy.Lock()
 		if w, f := con.proxy.WatchedResources[request.TypeUrl]; f {
 			w.NonceNacked = request.ResponseNonce
+			ackedListeners(w, request.ResponseNonce, true)
+			if request.ResponseNonce == w.NonceSent && !w.LastSent.IsZero() {
+				recordAckTime(request.TypeUrl, con.proxy.Type, true, time.Since(w.LastSent))
+			}
 		}
 		con.proxy.Unlock()
 		return false
@@ -403,8 +422,13 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	// the ack details and respond if there is a change in resource names.
 	con.proxy.Lock()
 	previousResources := con.proxy.WatchedResources[request.TypeUrl].ResourceNames
+	if previousInfo.NonceAcked != request.ResponseNonce && !previousInfo.LastSent.IsZero() {
+		recordAckTime(request.TypeUrl, con.proxy.Type, false, time.Since(previousInfo.LastSent))
+	}
 	con.proxy.WatchedResources[request.TypeUrl].NonceAcked = request.ResponseNonce
+	ackedListeners(con.proxy.WatchedResources[request.TypeUrl], request.ResponseNonce, false)
 	con.proxy.WatchedResources[request.TypeUrl].NonceNacked = ""
+	con.proxy.WatchedResources[request.TypeUrl].StaleSince = time.Time{}
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = request.ResourceNames
 	con.proxy.Unlock()
 
@@ -641,6 +665,7 @@ func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.P
 			}
 		}
 	}
+	push = s.pushContextFor(proxy, push)
 	// compute the sidecarscope for both proxy types whenever it changes.
 	if sidecar {
 		proxy.SetSidecarScope(push)
@@ -653,6 +678,18 @@ func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.P
 
 // shouldProcessRequest returns whether or not to continue with the request.
 func (s *DiscoveryServer) shouldProcessRequest(proxy *model.Proxy, req *discovery.DiscoveryRequest) bool {
+	if req.TypeUrl == v3.OutlierEventType {
+		s.recordOutlierEvents(proxy, req)
+		return false
+	}
+	if req.TypeUrl == v3.HealthCheckEventType {
+		s.recordHealthCheckEvents(proxy, req)
+		return false
+	}
+	if req.TypeUrl == v3.MTLSReportType {
+		s.recordMTLSReports(proxy, req)
+		return false
+	}
 	if req.TypeUrl != v3.HealthInfoType {
 		return true
 	}
@@ -736,6 +773,7 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 		// Report all events for unwatched resources. Watched resources will be reported in pushXds or on ack.
 		reportAllEvents(s.StatusReporter, con.ConID, pushRequest.Push.LedgerVersion, con.proxy.WatchedResources)
 	}
+	s.recordPushedProxy(con, pushRequest)
 
 	proxiesConvergeDelay.Record(time.Since(pushRequest.Start).Seconds())
 	return nil
@@ -743,15 +781,16 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 
 // PushOrder defines the order that updates will be pushed in. Any types not listed here will be pushed in random
 // order after the types listed here
-var PushOrder = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType, v3.SecretType}
+var PushOrder = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.ScopedRouteType, v3.RouteType, v3.SecretType}
 
 // KnownOrderedTypeUrls has typeUrls for which we know the order of push.
 var KnownOrderedTypeUrls = map[string]struct{}{
-	v3.ClusterType:  {},
-	v3.EndpointType: {},
-	v3.ListenerType: {},
-	v3.RouteType:    {},
-	v3.SecretType:   {},
+	v3.ClusterType:     {},
+	v3.EndpointType:    {},
+	v3.ListenerType:    {},
+	v3.ScopedRouteType: {},
+	v3.RouteType:       {},
+	v3.SecretType:      {},
 }
 
 // orderWatchedResources orders the resources in accordance with known push order.
@@ -873,6 +912,7 @@ func (s *DiscoveryServer) addCon(conID string, con *Connection) {
 	defer s.adsClientsMutex.Unlock()
 	s.adsClients[conID] = con
 	recordXDSClients(con.proxy.Metadata.IstioVersion, 1)
+	recordXDSTrustDomainClients(con.proxy, 1)
 }
 
 func (s *DiscoveryServer) removeCon(conID string) {
@@ -885,6 +925,7 @@ func (s *DiscoveryServer) removeCon(conID string) {
 	} else {
 		delete(s.adsClients, conID)
 		recordXDSClients(con.proxy.Metadata.IstioVersion, -1)
+		recordXDSTrustDomainClients(con.proxy, -1)
 	}
 }
 
@@ -909,6 +950,12 @@ func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
 			conn.proxy.WatchedResources[res.TypeUrl].NonceSent = res.Nonce
 			conn.proxy.WatchedResources[res.TypeUrl].VersionSent = res.VersionInfo
 			conn.proxy.WatchedResources[res.TypeUrl].LastSent = time.Now()
+			if conn.proxy.WatchedResources[res.TypeUrl].StaleSince.IsZero() {
+				conn.proxy.WatchedResources[res.TypeUrl].StaleSince = conn.proxy.WatchedResources[res.TypeUrl].LastSent
+			}
+			if res.TypeUrl == v3.ListenerType {
+				sentListeners(conn.proxy.WatchedResources[res.TypeUrl], res.Nonce)
+			}
 			conn.proxy.Unlock()
 		}
 	} else if status.Convert(err).Code() == codes.DeadlineExceeded {
@@ -950,6 +997,17 @@ func (conn *Connection) NonceSent(typeUrl string) string {
 	return ""
 }
 
+// Staleness returns how long the proxy has been running on older config of the given type than the last
+// config sent to it, or zero if it acked the last config.
+func (conn *Connection) Staleness(typeURL string, now time.Time) time.Duration {
+	conn.proxy.RLock()
+	defer conn.proxy.RUnlock()
+	if w := conn.proxy.WatchedResources[typeURL]; w != nil && !w.StaleSince.IsZero() {
+		return now.Sub(w.StaleSince)
+	}
+	return 0
+}
+
 func (conn *Connection) Clusters() []string {
 	conn.proxy.RLock()
 	defer conn.proxy.RUnlock()
@@ -27,6 +27,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/env"
 )
@@ -103,3 +104,30 @@ func checkConnectionIdentity(proxy *model.Proxy, identities []string) (*spiffe.I
 	}
 	return nil, fmt.Errorf("no identities (%v) matched %v/%v", identities, proxy.ConfigNamespace, proxy.Metadata.ServiceAccount)
 }
+
+// authorizeCluster returns whether the proxy may read the endpoints of the cluster, that is whether the cluster
+// is an outbound cluster of a service port visible in the SidecarScope of the proxy.
+func authorizeCluster(proxy *model.Proxy, push *model.PushContext, clusterName string) bool {
+	if proxy.SidecarScope == nil {
+		return false
+	}
+	direction, _, hostname, port := model.ParseSubsetKey(clusterName)
+	if direction != model.TrafficDirectionOutbound {
+		return false
+	}
+	svc := push.ServiceForHostname(proxy, hostname)
+	if svc == nil {
+		return false
+	}
+	_, f := svc.Ports.GetByPort(port)
+	return f
+}
+
+// reportUnauthorizedClusters records the clusters the proxy requested the endpoints of without being authorized to.
+func reportUnauthorizedClusters(proxy *model.Proxy, clusterNames []string) {
+	if len(clusterNames) == 0 {
+		return
+	}
+	log.Warnf("Unauthorized EDS: %s requested clusters outside of its Sidecar scope: %v", proxy.ID, clusterNames)
+	xdsUnauthorizedResources.With(typeTag.Value(v3.GetMetricType(v3.EndpointType))).RecordInt(int64(len(clusterNames)))
+}
@@ -118,6 +118,9 @@ type SyncStatus struct {
 	RouteAcked    string `json:"route_acked,omitempty"`
 	EndpointSent  string `json:"endpoint_sent,omitempty"`
 	EndpointAcked string `json:"endpoint_acked,omitempty"`
+	// StalenessSeconds is how long the proxy has been running on older config than the last config sent to it,
+	// by xDS type. Types whose last config was acked are omitted.
+	StalenessSeconds map[string]float64 `json:"staleness_seconds,omitempty"`
 }
 
 // SyncedVersions shows what resourceVersion of a given resource has been acked by Envoy.
@@ -126,6 +129,9 @@ type SyncedVersions struct {
 	ClusterVersion  string `json:"cluster_acked,omitempty"`
 	ListenerVersion string `json:"listener_acked,omitempty"`
 	RouteVersion    string `json:"route_acked,omitempty"`
+	// Resources are the clusters and route configurations generated from the resource for the proxy, when requested
+	// with the generated query parameter.
+	Resources []GeneratedResourceVersion `json:"resources,omitempty"`
 }
 
 // InitDebug initializes the debug handlers and adds a debug in-memory registry.
@@ -187,6 +193,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?clear=true", "Clear the XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/configz", "Debug support for config", s.configz)
 	s.addDebugHandler(mux, internalMux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
+	s.addDebugHandler(mux, internalMux, "/debug/inboundz", "Protocol and capture mode of the inbound ports of a proxy, "+
+		"and the configuration which decided them", s.inboundz)
 	s.addDebugHandler(mux, internalMux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 
@@ -195,13 +203,32 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
 	s.addDebugHandler(mux, internalMux, "/debug/push_status", "Last PushContext Details", s.pushStatusHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/pushcontext", "Debug support for current push context", s.pushContextHandler)
+	s.addDebugHandler(mux, internalMux, "/debug/push_historyz", "Last full pushes, and what changed between a push and the "+
+		"previous one with ?diff=<id>", s.pushHistoryz)
 	s.addDebugHandler(mux, internalMux, "/debug/connections", "Info about the connected XDS clients", s.connectionsHandler)
 
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.injectTemplateHandler(webhook))
-	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.meshHandler)
+	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config, or with ?provenance=true the source of each of its "+
+		"fields, and of the effective ProxyConfig of the proxy if a proxyID is passed", s.meshHandler)
+	s.addDebugHandler(mux, internalMux, "/debug/featurez", "Feature flags in effect for the generated config, and their fingerprint",
+		s.featurez)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
+	s.addDebugHandler(mux, internalMux, "/debug/endpointnetworkz", "Network of each endpoint, and how it was resolved", s.endpointNetworkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
+	s.addDebugHandler(mux, internalMux, "/debug/outlierz", "Endpoints ejected by outlier detection, as reported by proxies", s.outlierz)
+	s.addDebugHandler(mux, internalMux, "/debug/rolloutz", "Staged rollouts of config changes", s.rolloutz)
+	s.addDebugHandler(mux, internalMux, "/debug/gateway_migrationz", "Conversion of the Istio Gateways and VirtualServices "+
+		"into gateway-api resources", s.gatewayMigrationz)
+	s.addDebugHandler(mux, internalMux, "/debug/healthcheckz", "Endpoints failing active health checks, as reported by proxies", s.healthcheckz)
+	s.addDebugHandler(mux, internalMux, "/debug/filterchainz", "Summary of the filter chains generated for a proxy", s.filterchainz)
+	s.addDebugHandler(mux, internalMux, "/debug/memoryz", "Estimated Envoy memory usage for the config generated for a proxy", s.memoryz)
+	s.addDebugHandler(mux, internalMux, "/debug/loadz", "Upstream load by source and destination locality, as reported by proxies", s.loadz)
+	s.addDebugHandler(mux, internalMux, "/debug/localitymatrixz", "Requests from each source locality to each destination locality, "+
+		"as reported by proxies", s.localitymatrixz)
+	s.addDebugHandler(mux, internalMux, "/debug/mtlsz", "Inbound traffic of services by connection security, as reported by proxies", s.mtlsz)
+	s.addDebugHandler(mux, internalMux, "/debug/effectivepolicyz", "Effective traffic policy of a service host for a namespace, "+
+		"with the source of each setting", s.effectivePolicyz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.List)
 }
@@ -261,27 +288,44 @@ func isRequestFromLocalhost(r *http.Request) bool {
 // Syncz dumps the synchronization status of all Envoys connected to this Pilot instance
 func (s *DiscoveryServer) Syncz(w http.ResponseWriter, _ *http.Request) {
 	syncz := make([]SyncStatus, 0)
+	now := time.Now()
 	for _, con := range s.Clients() {
 		node := con.proxy
 		if node != nil {
 			syncz = append(syncz, SyncStatus{
-				ProxyID:       node.ID,
-				ClusterID:     node.Metadata.ClusterID.String(),
-				IstioVersion:  node.Metadata.IstioVersion,
-				ClusterSent:   con.NonceSent(v3.ClusterType),
-				ClusterAcked:  con.NonceAcked(v3.ClusterType),
-				ListenerSent:  con.NonceSent(v3.ListenerType),
-				ListenerAcked: con.NonceAcked(v3.ListenerType),
-				RouteSent:     con.NonceSent(v3.RouteType),
-				RouteAcked:    con.NonceAcked(v3.RouteType),
-				EndpointSent:  con.NonceSent(v3.EndpointType),
-				EndpointAcked: con.NonceAcked(v3.EndpointType),
+				ProxyID:          node.ID,
+				ClusterID:        node.Metadata.ClusterID.String(),
+				IstioVersion:     node.Metadata.IstioVersion,
+				ClusterSent:      con.NonceSent(v3.ClusterType),
+				ClusterAcked:     con.NonceAcked(v3.ClusterType),
+				ListenerSent:     con.NonceSent(v3.ListenerType),
+				ListenerAcked:    con.NonceAcked(v3.ListenerType),
+				RouteSent:        con.NonceSent(v3.RouteType),
+				RouteAcked:       con.NonceAcked(v3.RouteType),
+				EndpointSent:     con.NonceSent(v3.EndpointType),
+				EndpointAcked:    con.NonceAcked(v3.EndpointType),
+				StalenessSeconds: configStaleness(con, now),
 			})
 		}
 	}
 	writeJSON(w, syncz)
 }
 
+// configStaleness returns the config staleness of the connection in seconds, for each xDS type whose last
+// config was not acked.
+func configStaleness(con *Connection, now time.Time) map[string]float64 {
+	var res map[string]float64
+	for _, typeURL := range stalenessTypes {
+		if d := con.Staleness(typeURL, now); d > 0 {
+			if res == nil {
+				res = map[string]float64{}
+			}
+			res[v3.GetMetricType(typeURL)] = d.Seconds()
+		}
+	}
+	return res
+}
+
 // registryz providees debug support for registry - adding and listing model items.
 // Can be combined with the push debug interface to reproduce changes.
 func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
@@ -389,14 +433,25 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 	if resourceID := req.URL.Query().Get("resource"); resourceID != "" {
 		proxyNamespace := req.URL.Query().Get("proxy_namespace")
 		knownVersions := make(map[string]string)
+		generated := req.URL.Query().Get("generated") == "true"
+		var meta config.Meta
+		if generated {
+			var err error
+			if meta, err = configMetaForKey(resourceID); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(w, "%v\n", err)
+				return
+			}
+		}
 		var results []SyncedVersions
 		for _, con := range s.Clients() {
 			// wrap this in independent scope so that panic's don't bypass Unlock...
 			con.proxy.RLock()
 
+			var synced *SyncedVersions
 			if con.proxy != nil && (proxyNamespace == "" || proxyNamespace == con.proxy.ConfigNamespace) {
 				// read nonces from our statusreporter to allow for skipped nonces, etc.
-				results = append(results, SyncedVersions{
+				synced = &SyncedVersions{
 					ProxyID: con.proxy.ID,
 					ClusterVersion: s.getResourceVersion(s.StatusReporter.QueryLastNonce(con.ConID, v3.ClusterType),
 						resourceID, knownVersions),
@@ -404,9 +459,16 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 						resourceID, knownVersions),
 					RouteVersion: s.getResourceVersion(s.StatusReporter.QueryLastNonce(con.ConID, v3.RouteType),
 						resourceID, knownVersions),
-				})
+				}
+				if generated {
+					synced.Resources = s.generatedResourceVersions(con, meta, synced)
+				}
 			}
 			con.proxy.RUnlock()
+			if synced == nil {
+				continue
+			}
+			results = append(results, *synced)
 		}
 
 		writeJSON(w, results)
@@ -478,6 +540,16 @@ func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, con.proxy.SidecarScope)
 }
 
+// inboundz dumps how a proxy handles each of its inbound ports.
+func (s *DiscoveryServer) inboundz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	writeJSON(w, model.ResolveInboundPorts(con.proxy))
+}
+
 // Resource debugging.
 func (s *DiscoveryServer) resourcez(w http.ResponseWriter, _ *http.Request) {
 	schemas := make([]config.GroupVersionKind, 0)
@@ -696,8 +768,12 @@ func (s *DiscoveryServer) injectTemplateHandler(webhook func() map[string]string
 	}
 }
 
-// meshHandler dumps the mesh config
+// meshHandler dumps the mesh config, or the source of each of its fields if provenance is requested.
 func (s *DiscoveryServer) meshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("provenance") == "true" {
+		s.meshProvenance(w, r)
+		return
+	}
 	writeJSON(w, s.Env.Mesh())
 }
 
@@ -720,16 +796,33 @@ func (s *DiscoveryServer) pushStatusHandler(w http.ResponseWriter, req *http.Req
 
 // PushContextDebug holds debug information for push context.
 type PushContextDebug struct {
+	PushVersion           string
 	AuthorizationPolicies *model.AuthorizationPolicies
 	NetworkGateways       map[network.ID][]model.NetworkGateway
+	// Services are the services of the push context, sorted by hostname and namespace.
+	Services []*model.Service
 }
 
 // pushContextHandler dumps the current PushContext
 func (s *DiscoveryServer) pushContextHandler(w http.ResponseWriter, _ *http.Request) {
+	pc := s.globalPushContext()
 	push := PushContextDebug{
-		AuthorizationPolicies: s.globalPushContext().AuthzPolicies,
-		NetworkGateways:       s.globalPushContext().NetworkManager().GatewaysByNetwork(),
+		PushVersion:           pc.PushVersion,
+		AuthorizationPolicies: pc.AuthzPolicies,
+		NetworkGateways:       pc.NetworkManager().GatewaysByNetwork(),
+		Services:              make([]*model.Service, 0),
+	}
+	for _, byNamespace := range pc.ServiceIndex.HostnameAndNamespace {
+		for _, svc := range byNamespace {
+			push.Services = append(push.Services, svc)
+		}
 	}
+	sort.Slice(push.Services, func(i, j int) bool {
+		if push.Services[i].Hostname != push.Services[j].Hostname {
+			return push.Services[i].Hostname < push.Services[j].Hostname
+		}
+		return push.Services[i].Attributes.Namespace < push.Services[j].Attributes.Namespace
+	})
 
 	writeJSON(w, push)
 }
@@ -863,6 +956,77 @@ func (s *DiscoveryServer) networkz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, s.Env.NetworkManager.AllGateways())
 }
 
+// EndpointNetwork is the network of an endpoint of a service, as shown by /debug/endpointnetworkz.
+type EndpointNetwork struct {
+	Service   string              `json:"service"`
+	Namespace string              `json:"namespace"`
+	Cluster   string              `json:"cluster"`
+	Address   string              `json:"address"`
+	Network   network.ID          `json:"network"`
+	Source    model.NetworkSource `json:"source"`
+}
+
+// endpointNetworkz lists the endpoints of the services with their network, and how their network was resolved: from
+// their labels or the metadata of their proxy, their node, the system namespace or the meshNetworks.
+func (s *DiscoveryServer) endpointNetworkz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.endpointNetworks())
+}
+
+func (s *DiscoveryServer) endpointNetworks() []EndpointNetwork {
+	out := []EndpointNetwork{}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for svc, byNamespace := range s.EndpointShardsByService {
+		for ns, shards := range byNamespace {
+			shards.mutex.RLock()
+			for key, eps := range shards.Shards {
+				// the endpoints of each port of a workload share the address
+				seen := map[string]bool{}
+				for _, ep := range eps {
+					if seen[ep.Address] {
+						continue
+					}
+					seen[ep.Address] = true
+					out = append(out, EndpointNetwork{
+						Service:   svc,
+						Namespace: ns,
+						Cluster:   key.Cluster().String(),
+						Address:   ep.Address,
+						Network:   ep.Network,
+						Source:    ep.NetworkSource,
+					})
+				}
+			}
+			shards.mutex.RUnlock()
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		if out[i].Cluster != out[j].Cluster {
+			return out[i].Cluster < out[j].Cluster
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// gatewayMigrationz shows the compatibility report of the conversion of the Istio Gateways and of the VirtualServices
+// bound to them into gateway-api resources.
+func (s *DiscoveryServer) gatewayMigrationz(w http.ResponseWriter, _ *http.Request) {
+	if s.GatewayMigration == nil {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprint(w, "Gateway API migration is disabled. Please set the "+
+			"PILOT_GATEWAY_API_MIGRATION environment variable to report or apply to enable.")
+		return
+	}
+	writeJSON(w, s.GatewayMigration.DebugReport())
+}
+
 func (s *DiscoveryServer) mcsz(w http.ResponseWriter, _ *http.Request) {
 	svcs := sortMCSServices(s.Env.MCSServices())
 	writeJSON(w, svcs)
@@ -123,6 +123,11 @@ func verifySyncStatus(t *testing.T, s *xds.DiscoveryServer, nodeID string, wantS
 				if (ss.EndpointAcked != "") != wantAcked {
 					errorHandler("wanted EndpointAcked set %v got %v for %v", wantAcked, ss.EndpointAcked, nodeID)
 				}
+				for _, metricType := range []string{"cds", "lds", "rds", "eds"} {
+					if _, stale := ss.StalenessSeconds[metricType]; stale != (wantSent && !wantAcked) {
+						errorHandler("wanted %s staleness set %v got %v for %v", metricType, wantSent && !wantAcked, ss.StalenessSeconds, nodeID)
+					}
+				}
 				return
 			}
 		}
@@ -213,3 +218,26 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestMeshProvenanceHandler(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	req, err := http.NewRequest("GET", "/debug/mesh?provenance=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = "127.0.0.1:12345"
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	got := xds.ConfigProvenance{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.MeshConfig) == 0 {
+		t.Fatalf("expected the sources of the mesh config fields, got %s", rr.Body.String())
+	}
+}
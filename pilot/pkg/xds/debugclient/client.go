@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugclient is a client of the debug endpoints of Istiod, served on its monitoring port (15014), which
+// decodes their responses into the types Istiod encodes them from.
+package debugclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// Error is returned when Istiod responds to a debug request with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("istiod responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsProxyNotConnected returns true if the error is returned because the requested proxy is not connected to the
+// Istiod instance. It may be connected to another instance.
+func IsProxyNotConnected(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Client sends requests to the debug endpoints of an Istiod instance.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a client of the debug endpoints of the Istiod instance at the given address, such as
+// "http://istiod.istio-system:15014". The default HTTP client is used if client is nil.
+func New(address string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(address, "/"),
+		client:  client,
+	}
+}
+
+// Commands returns the debug endpoints of the Istiod instance, without their /debug/ prefix.
+func (c *Client) Commands(ctx context.Context) ([]string, error) {
+	var out []string
+	if err := c.getJSON(ctx, "list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SyncStatus returns the synchronization status of the proxies connected to the Istiod instance.
+func (c *Client) SyncStatus(ctx context.Context) ([]xds.SyncStatus, error) {
+	var out []xds.SyncStatus
+	if err := c.getJSON(ctx, "syncz", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConfigDistribution returns the versions of the configuration acknowledged by the proxies connected to the Istiod
+// instance.
+func (c *Client) ConfigDistribution(ctx context.Context) ([]xds.SyncedVersions, error) {
+	var out []xds.SyncedVersions
+	if err := c.getJSON(ctx, "config_distribution", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Connections returns the proxies connected to the Istiod instance, with their metadata and watched resources.
+// All the proxies are returned if proxyID is empty.
+func (c *Client) Connections(ctx context.Context, proxyID string) (*xds.AdsClients, error) {
+	out := &xds.AdsClients{}
+	if err := c.getJSON(ctx, "adsz", proxyQuery(proxyID), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConfigDump returns the configuration the Istiod instance generates for a connected proxy, in the form of the
+// config dump of the Envoy admin API.
+func (c *Client) ConfigDump(ctx context.Context, proxyID string) (*adminapi.ConfigDump, error) {
+	body, err := c.get(ctx, "config_dump", proxyQuery(proxyID))
+	if err != nil {
+		return nil, err
+	}
+	out := &adminapi.ConfigDump{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to decode config dump: %v", err)
+	}
+	return out, nil
+}
+
+// FilterChains returns the summary of the filter chains the Istiod instance generates for a connected proxy.
+func (c *Client) FilterChains(ctx context.Context, proxyID string) (*xds.ProxyFilterChains, error) {
+	out := &xds.ProxyFilterChains{}
+	if err := c.getJSON(ctx, "filterchainz", proxyQuery(proxyID), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PushContext returns the current push context of the Istiod instance.
+func (c *Client) PushContext(ctx context.Context) (*xds.PushContextDebug, error) {
+	out := &xds.PushContextDebug{}
+	if err := c.getJSON(ctx, "pushcontext", nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func proxyQuery(proxyID string) url.Values {
+	if proxyID == "" {
+		return nil
+	}
+	return url.Values{"proxyID": []string{proxyID}}
+}
+
+func (c *Client) getJSON(ctx context.Context, command string, query url.Values, out interface{}) error {
+	body, err := c.get(ctx, command, query)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %v", command, err)
+	}
+	return nil
+}
+
+// get returns the body of the response to the debug command.
+func (c *Client) get(ctx context.Context, command string, query url.Values) ([]byte, error) {
+	u := c.baseURL + "/debug/" + command
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
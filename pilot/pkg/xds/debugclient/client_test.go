@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestClient(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, http.NewServeMux(), false, nil)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+
+	proxyID := "test.default"
+	ctx := context.Background()
+	c := New(srv.URL+"/", nil)
+
+	t.Run("commands", func(t *testing.T) {
+		commands, err := c.Commands(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(commands) == 0 {
+			t.Fatal("expected debug commands")
+		}
+	})
+
+	t.Run("sync status", func(t *testing.T) {
+		status, err := c.SyncStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status) != 1 || status[0].ProxyID != proxyID {
+			t.Fatalf("unexpected sync status: %+v", status)
+		}
+	})
+
+	t.Run("connections", func(t *testing.T) {
+		clients, err := c.Connections(ctx, proxyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clients.Total != 1 || clients.Connected[0].Watches[v3.ClusterType] == nil {
+			t.Fatalf("unexpected connections: %+v", clients)
+		}
+	})
+
+	t.Run("config dump", func(t *testing.T) {
+		dump, err := c.ConfigDump(ctx, proxyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dump.Configs) == 0 {
+			t.Fatal("expected configs in the config dump")
+		}
+	})
+
+	t.Run("filter chains", func(t *testing.T) {
+		chains, err := c.FilterChains(ctx, proxyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chains.ProxyID != proxyID {
+			t.Fatalf("unexpected filter chains: %+v", chains)
+		}
+	})
+
+	t.Run("push context", func(t *testing.T) {
+		if _, err := c.PushContext(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("proxy not connected", func(t *testing.T) {
+		_, err := c.ConfigDump(ctx, "unknown.default")
+		if !IsProxyNotConnected(err) {
+			t.Fatalf("expected proxy not connected error, got %v", err)
+		}
+	})
+}
@@ -60,9 +60,8 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 		peerAddr = peerInfo.Addr.String()
 	}
 
-	if err := s.WaitForRequestLimit(stream.Context()); err != nil {
-		log.Warnf("ADS: %q exceeded rate limit: %v", peerAddr, err)
-		return status.Errorf(codes.ResourceExhausted, "request rate limit exceeded: %v", err)
+	if err := s.admitConnection(stream.Context(), peerAddr); err != nil {
+		return err
 	}
 
 	ids, err := s.authenticate(ctx)
@@ -256,6 +255,12 @@ func (conn *Connection) sendDelta(res *discovery.DeltaDiscoveryResponse) error {
 			conn.proxy.WatchedResources[res.TypeUrl].NonceSent = res.Nonce
 			conn.proxy.WatchedResources[res.TypeUrl].VersionSent = res.SystemVersionInfo
 			conn.proxy.WatchedResources[res.TypeUrl].LastSent = time.Now()
+			if conn.proxy.WatchedResources[res.TypeUrl].StaleSince.IsZero() {
+				conn.proxy.WatchedResources[res.TypeUrl].StaleSince = conn.proxy.WatchedResources[res.TypeUrl].LastSent
+			}
+			if res.TypeUrl == v3.ListenerType {
+				sentListeners(conn.proxy.WatchedResources[res.TypeUrl], res.Nonce)
+			}
 			conn.proxy.Unlock()
 		}
 	} else {
@@ -313,6 +318,13 @@ func (s *DiscoveryServer) processDeltaRequest(req *discovery.DeltaDiscoveryReque
 	if con.proxy.SidecarScope != nil && con.proxy.SidecarScope.Version != push.PushVersion {
 		s.computeProxyState(con.proxy, request)
 	}
+	if shouldRespond {
+		release, err := s.waitForRequestPushLimit(con.deltaStream.Context())
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
 	return s.pushDeltaXds(con, push, con.Watched(req.TypeUrl), req.ResourceNamesSubscribe, request)
 }
 
@@ -328,12 +340,17 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 		errCode := codes.Code(request.ErrorDetail.Code)
 		log.Warnf("ADS:%s: ACK ERROR %s %s:%s", stype, con.ConID, errCode.String(), request.ErrorDetail.GetMessage())
 		incrementXDSRejects(request.TypeUrl, con.proxy.ID, errCode.String())
+		s.reportStagedRolloutNack(con.proxy, request.TypeUrl)
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, deltaToSotwRequest(request))
 		}
 		con.proxy.Lock()
 		if w, f := con.proxy.WatchedResources[request.TypeUrl]; f {
 			w.NonceNacked = request.ResponseNonce
+			ackedListeners(w, request.ResponseNonce, true)
+			if request.ResponseNonce == w.NonceSent && !w.LastSent.IsZero() {
+				recordAckTime(request.TypeUrl, con.proxy.Type, true, time.Since(w.LastSent))
+			}
 		}
 		con.proxy.Unlock()
 		return false
@@ -379,8 +396,15 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 	con.proxy.Lock()
 	defer con.proxy.Unlock()
 	previousResources := con.proxy.WatchedResources[request.TypeUrl].ResourceNames
+	if request.ResponseNonce != "" && previousInfo.NonceAcked != request.ResponseNonce && !previousInfo.LastSent.IsZero() {
+		recordAckTime(request.TypeUrl, con.proxy.Type, false, time.Since(previousInfo.LastSent))
+	}
 	con.proxy.WatchedResources[request.TypeUrl].NonceAcked = request.ResponseNonce
+	ackedListeners(con.proxy.WatchedResources[request.TypeUrl], request.ResponseNonce, false)
 	con.proxy.WatchedResources[request.TypeUrl].NonceNacked = ""
+	if request.ResponseNonce != "" {
+		con.proxy.WatchedResources[request.TypeUrl].StaleSince = time.Time{}
+	}
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = deltaWatchedResources(previousResources, request)
 
 	oldAck := listEqualUnordered(previousResources, con.proxy.WatchedResources[request.TypeUrl].ResourceNames)
@@ -435,6 +459,7 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 	var logdata model.XdsLogDetails
 	var usedDelta bool
 	var err error
+	push, req = s.stagedRolloutView(con.proxy, push, req)
 	switch g := gen.(type) {
 	case model.XdsDeltaResourceGenerator:
 		res, deletedRes, logdata, usedDelta, err = g.GenerateDeltas(con.proxy, push, req, w)
@@ -22,11 +22,13 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	"github.com/google/uuid"
 	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 
+	"istio.io/istio/pilot/pkg/controller/rollout"
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -70,6 +72,47 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// debounceAfterByKind overrides debounceAfter for the events updating configs of the given kinds.
+	// Events with different delays are debounced separately, so that fast changing kinds are not delayed
+	// by the others.
+	debounceAfterByKind map[string]time.Duration
+}
+
+// debounceAfterFor returns the debounce delay of a push request. This is the longest delay of the kinds of
+// the updated configs, or debounceAfter if any of them has no specific delay.
+func (o debounceOptions) debounceAfterFor(req *model.PushRequest) time.Duration {
+	if len(o.debounceAfterByKind) == 0 || len(req.ConfigsUpdated) == 0 {
+		return o.debounceAfter
+	}
+	var after time.Duration
+	for key := range req.ConfigsUpdated {
+		d, f := o.debounceAfterByKind[key.Kind.Kind]
+		if !f {
+			return o.debounceAfter
+		}
+		if d > after {
+			after = d
+		}
+	}
+	return after
+}
+
+// debounceBucket holds the push requests debounced with the same delay.
+type debounceBucket struct {
+	req *model.PushRequest
+	// events is the number of events merged in req.
+	events int
+	// start is the time of the first event.
+	start time.Time
+	// lastUpdate is the time of the last event.
+	lastUpdate time.Time
+}
+
+// GatewayMigrationReporter reports the conversion of the Istio Gateways into gateway-api resources. The controller is
+// held behind this interface, so that the XDS server, which the agent also builds, does not depend on it.
+type GatewayMigrationReporter interface {
+	DebugReport() interface{}
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -99,6 +142,9 @@ type DiscoveryServer struct {
 	concurrentPushLimit chan struct{}
 	// requestRateLimit limits the number of new XDS requests allowed. This helps prevent thundering hurd of incoming requests.
 	requestRateLimit *rate.Limiter
+	// requestPushLimit is a semaphore that limits the amount of concurrent responses to XDS requests, or nil if they
+	// are not limited.
+	requestPushLimit chan struct{}
 
 	// InboundUpdates describes the number of configuration updates the discovery server has received
 	InboundUpdates *atomic.Int64
@@ -141,6 +187,18 @@ type DiscoveryServer struct {
 	StatusGen               *StatusGen
 	WorkloadEntryController *workloadentry.Controller
 
+	// StagedRollouts tracks the staged rollouts of config changes, if enabled.
+	StagedRollouts *rollout.Controller
+
+	// GatewayMigration converts the Istio Gateways into gateway-api resources, if enabled.
+	GatewayMigration GatewayMigrationReporter
+
+	// stagedRolloutConfigs holds the map[model.ConfigKey]struct{} of the configs being rolled out, which the canary
+	// proxies and the other proxies are served different versions of.
+	stagedRolloutConfigs atomic.Value
+	// stagedRolloutProxies holds the *stagedRolloutProxies snapshot of the connected proxies.
+	stagedRolloutProxies atomic.Value
+
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady atomic.Bool
 
@@ -160,6 +218,24 @@ type DiscoveryServer struct {
 	// ClusterAliases are aliase names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
+
+	// outlierReports holds the outlier detection ejections reported by the agents.
+	outlierReports *endpointReports
+
+	// healthCheckReports holds the endpoint states of active health checks reported by the agents.
+	healthCheckReports *endpointReports
+
+	// mtlsReports holds the inbound traffic of services by connection security reported by the agents.
+	mtlsReports *mtlsReports
+
+	// loadReports aggregates the load reported by the proxies through the load reporting service.
+	loadReports *loadReports
+
+	// LoadReportSinks receive the load reported by the proxies, in addition to the built-in aggregation.
+	LoadReportSinks []LoadReportSink
+
+	// pushHistory retains the snapshots of the last full pushes, if enabled.
+	pushHistory *pushHistory
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -180,6 +256,10 @@ type EndpointShards struct {
 	// Due to the larger time, it is still possible that connection errors will occur while
 	// CDS is updated.
 	ServiceAccounts sets.Set
+
+	// Networks has the networks of the endpoints, only tracked with PILOT_CROSS_NETWORK_TRAFFIC_POLICY. A full push is
+	// forced when they change, as the policies of the clusters depend on them.
+	Networks sets.Set
 }
 
 // NewDiscoveryServer creates DiscoveryServer that sources data from Pilot's internal mesh data structures
@@ -192,19 +272,25 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 		EndpointShardsByService: map[string]map[string]*EndpointShards{},
 		concurrentPushLimit:     make(chan struct{}, features.PushThrottle),
 		requestRateLimit:        rate.NewLimiter(rate.Limit(features.RequestLimit), 1),
+		requestPushLimit:        newRequestPushLimit(features.RequestPushThrottle),
 		InboundUpdates:          atomic.NewInt64(0),
 		CommittedUpdates:        atomic.NewInt64(0),
 		pushChannel:             make(chan *model.PushRequest, 10),
-		pushQueue:               NewPushQueue(),
+		pushQueue:               newPushQueue(features.FullPushThrottle, features.FullPushMinInterval),
 		debugHandlers:           map[string]string{},
 		adsClients:              map[string]*Connection{},
 		debounceOptions: debounceOptions{
-			debounceAfter:     features.DebounceAfter,
-			debounceMax:       features.DebounceMax,
-			enableEDSDebounce: features.EnableEDSDebounce,
+			debounceAfter:       features.DebounceAfter,
+			debounceMax:         features.DebounceMax,
+			enableEDSDebounce:   features.EnableEDSDebounce,
+			debounceAfterByKind: features.DebounceAfterByKind,
 		},
-		Cache:      model.DisabledCache{},
-		instanceID: instanceID,
+		Cache:              model.DisabledCache{},
+		instanceID:         instanceID,
+		outlierReports:     newEndpointReports(),
+		healthCheckReports: newEndpointReports(),
+		loadReports:        newLoadReports(),
+		mtlsReports:        newMTLSReports(),
 	}
 
 	out.ClusterAliases = make(map[cluster.ID]cluster.ID)
@@ -214,8 +300,15 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 
 	out.initJwksResolver()
 
+	if features.PushHistorySize > 0 {
+		out.pushHistory = newPushHistory(features.PushHistorySize)
+	}
+
 	if features.EnableXDSCaching {
 		out.Cache = model.NewXdsCache()
+		if features.EnableStagedRollouts {
+			out.Cache = stagedRolloutCache{XdsCache: out.Cache, diverging: &out.stagedRolloutConfigs}
+		}
 	}
 
 	out.ConfigGenerator = core.NewConfigGenerator(plugins, out.Cache)
@@ -250,6 +343,7 @@ func (s *DiscoveryServer) closeJwksResolver() {
 func (s *DiscoveryServer) Register(rpcs *grpc.Server) {
 	// Register v3 server
 	discovery.RegisterAggregatedDiscoveryServiceServer(rpcs, s)
+	lrs.RegisterLoadReportingServiceServer(rpcs, s)
 }
 
 var processStartTime = time.Now()
@@ -266,6 +360,9 @@ func (s *DiscoveryServer) IsServerReady() bool {
 
 func (s *DiscoveryServer) Start(stopCh <-chan struct{}) {
 	go s.WorkloadEntryController.Run(stopCh)
+	if s.StagedRollouts != nil {
+		go s.evaluateStagedRollouts(stopCh)
+	}
 	go s.handleUpdates(stopCh)
 	go s.periodicRefreshMetrics(stopCh)
 	go s.sendPushes(stopCh)
@@ -311,6 +408,7 @@ func (s *DiscoveryServer) periodicRefreshMetrics(stopCh <-chan struct{}) {
 				}
 			}
 			model.LastPushMutex.Unlock()
+			recordConfigStaleness(s.Clients(), time.Now())
 		case <-stopCh:
 			return
 		}
@@ -361,6 +459,8 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	version = versionLocal
 	versionMutex.Unlock()
 
+	s.recordPushHistory(push, req)
+
 	req.Push = push
 	s.AdsPushAll(versionLocal, req)
 }
@@ -382,6 +482,12 @@ func (s *DiscoveryServer) globalPushContext() *model.PushContext {
 	return s.Env.PushContext
 }
 
+// IsEgressProxyCredential returns whether the Secret holds the credentials of the egress proxy of a Sidecar in the
+// current push context.
+func (s *DiscoveryServer) IsEgressProxyCredential(name, namespace string) bool {
+	return s.globalPushContext().IsEgressProxyCredential(name, namespace)
+}
+
 // ConfigUpdate implements ConfigUpdater interface, used to request pushes.
 // It replaces the 'clear cache' from v1.
 func (s *DiscoveryServer) ConfigUpdate(req *model.PushRequest) {
@@ -402,14 +508,12 @@ func (s *DiscoveryServer) handleUpdates(stopCh <-chan struct{}) {
 // The debounce helper function is implemented to enable mocking
 func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceOptions, pushFn func(req *model.PushRequest), updateSent *atomic.Int64) {
 	var timeChan <-chan time.Time
-	var startDebounce time.Time
-	var lastConfigUpdateTime time.Time
+	var timerDeadline time.Time
 
 	pushCounter := 0
-	debouncedEvents := 0
 
-	// Keeps track of the push requests. If updates are debounce they will be merged.
-	var req *model.PushRequest
+	// Keeps track of the push requests by debounce delay. If updates are debounce they will be merged.
+	buckets := map[time.Duration]*debounceBucket{}
 
 	free := true
 	freeCh := make(chan struct{}, 1)
@@ -420,29 +524,50 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 		freeCh <- struct{}{}
 	}
 
+	// schedule wakes up the push worker after d, unless it is already scheduled earlier.
+	schedule := func(d time.Duration) {
+		deadline := time.Now().Add(d)
+		if timeChan == nil || deadline.Before(timerDeadline) {
+			timeChan = time.After(d)
+			timerDeadline = deadline
+		}
+	}
+
 	pushWorker := func() {
-		eventDelay := time.Since(startDebounce)
-		quietTime := time.Since(lastConfigUpdateTime)
-		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
-			if req != nil {
-				pushCounter++
-				if req.ConfigsUpdated == nil {
+		now := time.Now()
+		var req *model.PushRequest
+		debouncedEvents := 0
+		var next time.Duration
+		for after, b := range buckets {
+			eventDelay := now.Sub(b.start)
+			quietTime := now.Sub(b.lastUpdate)
+			// it has been too long or quiet enough
+			if eventDelay >= opts.debounceMax || quietTime >= after {
+				if req == nil {
+					pushCounter++
+				}
+				if b.req.ConfigsUpdated == nil {
 					log.Infof("Push debounce stable[%d] %d: %v since last change, %v since last push, full=%v",
-						pushCounter, debouncedEvents,
-						quietTime, eventDelay, req.Full)
+						pushCounter, b.events,
+						quietTime, eventDelay, b.req.Full)
 				} else {
 					log.Infof("Push debounce stable[%d] %d for config %s: %v since last change, %v since last push, full=%v",
-						pushCounter, debouncedEvents, configsUpdated(req),
-						quietTime, eventDelay, req.Full)
+						pushCounter, b.events, configsUpdated(b.req),
+						quietTime, eventDelay, b.req.Full)
 				}
-				free = false
-				go push(req, debouncedEvents)
-				req = nil
-				debouncedEvents = 0
+				req = req.Merge(b.req)
+				debouncedEvents += b.events
+				delete(buckets, after)
+			} else if wait := after - quietTime; next == 0 || wait < next {
+				next = wait
 			}
-		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+		}
+		if req != nil {
+			free = false
+			go push(req, debouncedEvents)
+		}
+		if next > 0 {
+			schedule(next)
 		}
 	}
 
@@ -465,15 +590,20 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 				continue
 			}
 
-			lastConfigUpdateTime = time.Now()
-			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
-				startDebounce = lastConfigUpdateTime
+			after := opts.debounceAfterFor(r)
+			lastConfigUpdateTime := time.Now()
+			b := buckets[after]
+			if b == nil {
+				b = &debounceBucket{start: lastConfigUpdateTime}
+				buckets[after] = b
+				schedule(after)
 			}
-			debouncedEvents++
+			b.lastUpdate = lastConfigUpdateTime
+			b.events++
 
-			req = req.Merge(r)
+			b.req = b.req.Merge(r)
 		case <-timeChan:
+			timeChan = nil
 			if free {
 				pushWorker()
 			}
@@ -518,7 +648,7 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 				<-semaphore
 			}
 
-			proxiesQueueTime.Record(time.Since(push.Start).Seconds())
+			recordProxyQueueTime(push)
 			var closed <-chan struct{}
 			if client.stream != nil {
 				closed = client.stream.Context().Done()
@@ -562,8 +692,16 @@ func (s *DiscoveryServer) initPushContext(req *model.PushRequest, oldPushContext
 		return nil, err
 	}
 
+	diverging, err := s.initStablePushContext(req, oldPushContext, push)
+	if err != nil {
+		log.Errorf("XDS: failed to init push context of staged rollouts: %v", err)
+		pushContextErrors.Increment()
+		return nil, err
+	}
+
 	s.updateMutex.Lock()
 	s.Env.PushContext = push
+	s.stagedRolloutConfigs.Store(diverging)
 	// Ensure we drop the cache in the lock to avoid races, where we drop the cache, fill it back up, then update push context
 	s.dropCacheForRequest(req)
 	s.updateMutex.Unlock()
@@ -582,10 +720,12 @@ func (s *DiscoveryServer) InitGenerators(env *model.Environment, systemNameSpace
 	s.Generators[v3.ClusterType] = &CdsGenerator{Server: s}
 	s.Generators[v3.ListenerType] = &LdsGenerator{Server: s}
 	s.Generators[v3.RouteType] = &RdsGenerator{Server: s}
+	s.Generators[v3.ScopedRouteType] = &SrdsGenerator{Server: s}
 	s.Generators[v3.EndpointType] = edsGen
 	s.Generators[v3.NameTableType] = &NdsGenerator{Server: s}
 	s.Generators[v3.ExtensionConfigurationType] = &EcdsGenerator{Server: s}
 	s.Generators[v3.ProxyConfigType] = &PcdsGenerator{Server: s, TrustBundle: env.TrustBundle}
+	s.Generators[v3.RuntimeType] = &RtdsGenerator{Server: s}
 
 	s.Generators["grpc"] = &grpcgen.GrpcConfigGenerator{}
 	s.Generators["grpc/"+v3.EndpointType] = edsGen
@@ -29,6 +29,8 @@ import (
 
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/test/util/retry"
 )
 
@@ -326,6 +328,78 @@ func TestDebounce(t *testing.T) {
 	}
 }
 
+func TestDebounceAfterFor(t *testing.T) {
+	opts := debounceOptions{
+		debounceAfter: 100 * time.Millisecond,
+		debounceAfterByKind: map[string]time.Duration{
+			gvk.ServiceEntry.Kind:   10 * time.Millisecond,
+			gvk.VirtualService.Kind: time.Second,
+		},
+	}
+	cases := []struct {
+		name  string
+		kinds []config.GroupVersionKind
+		want  time.Duration
+	}{
+		{"no configs", nil, 100 * time.Millisecond},
+		{"single kind", []config.GroupVersionKind{gvk.ServiceEntry}, 10 * time.Millisecond},
+		{"longest delay", []config.GroupVersionKind{gvk.ServiceEntry, gvk.VirtualService}, time.Second},
+		{"kind without delay", []config.GroupVersionKind{gvk.ServiceEntry, gvk.Gateway}, 100 * time.Millisecond},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &model.PushRequest{}
+			if len(tt.kinds) > 0 {
+				req.ConfigsUpdated = map[model.ConfigKey]struct{}{}
+				for _, k := range tt.kinds {
+					req.ConfigsUpdated[model.ConfigKey{Kind: k, Name: "name"}] = struct{}{}
+				}
+			}
+			if got := opts.debounceAfterFor(req); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebounceByKind(t *testing.T) {
+	opts := debounceOptions{
+		debounceAfter:       time.Second,
+		debounceMax:         10 * time.Second,
+		enableEDSDebounce:   true,
+		debounceAfterByKind: map[string]time.Duration{gvk.ServiceEntry.Kind: 0},
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	updateCh := make(chan *model.PushRequest)
+	pushes := make(chan *model.PushRequest, 10)
+	go debounce(updateCh, stopCh, opts, func(req *model.PushRequest) { pushes <- req }, uatomic.NewInt64(0))
+
+	request := func(kind config.GroupVersionKind) *model.PushRequest {
+		return &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: kind, Name: "name"}: {}}}
+	}
+	updateCh <- request(gvk.VirtualService)
+	updateCh <- request(gvk.ServiceEntry)
+
+	// The ServiceEntry update is pushed right away, without waiting for the VirtualService update.
+	select {
+	case req := <-pushes:
+		if _, f := req.ConfigsUpdated[model.ConfigKey{Kind: gvk.ServiceEntry, Name: "name"}]; !f || len(req.ConfigsUpdated) != 1 {
+			t.Fatalf("expected ServiceEntry push, got %v", req.ConfigsUpdated)
+		}
+	case <-time.After(opts.debounceAfter / 2):
+		t.Fatalf("ServiceEntry update was not pushed before the default debounce delay")
+	}
+	select {
+	case req := <-pushes:
+		if _, f := req.ConfigsUpdated[model.ConfigKey{Kind: gvk.VirtualService, Name: "name"}]; !f || len(req.ConfigsUpdated) != 1 {
+			t.Fatalf("expected VirtualService push, got %v", req.ConfigsUpdated)
+		}
+	case <-time.After(opts.debounceAfter * 2):
+		t.Fatalf("VirtualService update was not pushed")
+	}
+}
+
 func TestShouldRespond(t *testing.T) {
 	tests := []struct {
 		name       string
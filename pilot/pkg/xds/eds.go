@@ -145,7 +145,10 @@ func (s *DiscoveryServer) edsCacheUpdate(shard model.ShardKey, hostname string,
 		// but we should not delete the keys from EndpointShardsByService map - that will trigger
 		// unnecessary full push which can become a real problem if a pod is in crashloop and thus endpoints
 		// flip flopping between 1 and 0.
-		s.deleteEndpointShards(shard, hostname, namespace)
+		if s.deleteEndpointShards(shard, hostname, namespace) {
+			log.Infof("Full push, service %s at shard %v has no endpoints and its endpoint networks changed", hostname, shard)
+			return FullPush
+		}
 		log.Infof("Incremental push, service %s at shard %v has no endpoints", hostname, shard)
 		return IncrementalPush
 	}
@@ -170,6 +173,10 @@ func (s *DiscoveryServer) edsCacheUpdate(shard model.ShardKey, hostname string,
 		log.Infof("Full push, service accounts changed, %v", hostname)
 		pushType = FullPush
 	}
+	if features.CrossNetworkTrafficPolicy != "" && updateServiceNetworks(ep) {
+		log.Infof("Full push, endpoint networks changed, %v", hostname)
+		pushType = FullPush
+	}
 	// Clear the cache here. While it would likely be cleared later when we trigger a push, a race
 	// condition is introduced where an XDS response may be generated before the update, but not
 	// completed until after a response after the update. Essentially, we transition from v0 -> v1 ->
@@ -255,8 +262,9 @@ func (s *DiscoveryServer) getOrCreateEndpointShard(serviceName, namespace string
 }
 
 // deleteEndpointShards deletes matching endpoint shards from EndpointShardsByService map. This is called when
-// endpoints are deleted.
-func (s *DiscoveryServer) deleteEndpointShards(shard model.ShardKey, serviceName, namespace string) {
+// endpoints are deleted. It returns true if the tracked networks of the endpoints changed.
+func (s *DiscoveryServer) deleteEndpointShards(shard model.ShardKey, serviceName, namespace string) bool {
+	networksChanged := false
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	if s.EndpointShardsByService[serviceName] != nil &&
@@ -272,6 +280,7 @@ func (s *DiscoveryServer) deleteEndpointShards(shard model.ShardKey, serviceName
 				}
 			}
 		}
+		networksChanged = features.CrossNetworkTrafficPolicy != "" && updateServiceNetworks(epShards)
 		// Clear the cache here to avoid race in cache writes (see edsCacheUpdate for details).
 		s.Cache.Clear(map[model.ConfigKey]struct{}{{
 			Kind:      gvk.ServiceEntry,
@@ -280,6 +289,7 @@ func (s *DiscoveryServer) deleteEndpointShards(shard model.ShardKey, serviceName
 		}: {}})
 		epShards.mutex.Unlock()
 	}
+	return networksChanged
 }
 
 // deleteService deletes all service related references from EndpointShardsByService. This is called
@@ -337,6 +347,21 @@ func (s *DiscoveryServer) UpdateServiceAccount(shards *EndpointShards, serviceNa
 	return false
 }
 
+// updateServiceNetworks updates the networks of the endpoints of the shards, returning true if they changed.
+func updateServiceNetworks(shards *EndpointShards) bool {
+	networks := sets.Set{}
+	for _, epShards := range shards.Shards {
+		for _, ep := range epShards {
+			networks.Insert(string(ep.Network))
+		}
+	}
+	if shards.Networks.Equals(networks) {
+		return false
+	}
+	shards.Networks = networks
+	return true
+}
+
 // llbEndpointAndOptionsForCluster return the endpoints for a cluster
 // Initial implementation is computing the endpoints on the flight - caching will be added as needed, based on
 // perf tests.
@@ -359,7 +384,7 @@ func (s *DiscoveryServer) llbEndpointAndOptionsForCluster(b EndpointBuilder) ([]
 		return nil, fmt.Errorf("cluster %s in eds cluster", b.clusterName)
 	}
 
-	svcPort, f := b.service.Ports.GetByPort(b.port)
+	svcPort, f := b.servicePort()
 	if !f {
 		// Shouldn't happen here
 		log.Debugf("can not find the service port %d for cluster %s", b.port, b.clusterName)
@@ -414,6 +439,9 @@ func (s *DiscoveryServer) generateEndpoints(b EndpointBuilder) *endpoint.Cluster
 			}
 		}
 		loadbalancer.ApplyLocalityLBSetting(l, wrappedLocalityLbEndpoints, b.locality, b.proxy.Metadata.Labels, lbSetting, enableFailover)
+		if loadbalancer.ConsistentHashLocalityFailoverForDestinationRule(b.destinationRule) {
+			loadbalancer.ApplyConsistentHashLocalityFailover(l, lb, lbSetting, enableFailover)
+		}
 	}
 	return l
 }
@@ -557,6 +585,7 @@ func (eds *EdsGenerator) buildEndpoints(proxy *model.Proxy,
 	empty := 0
 	cached := 0
 	regenerated := 0
+	var unauthorized []string
 	for _, clusterName := range w.ResourceNames {
 		if edsUpdatedServices != nil {
 			_, _, hostname, _ := model.ParseSubsetKey(clusterName)
@@ -566,6 +595,10 @@ func (eds *EdsGenerator) buildEndpoints(proxy *model.Proxy,
 				continue
 			}
 		}
+		if features.EnableXDSResourceAuthorization && !authorizeCluster(proxy, push, clusterName) {
+			unauthorized = append(unauthorized, clusterName)
+			continue
+		}
 		builder := NewEndpointBuilder(clusterName, proxy, push)
 		if marshalledEndpoint, f := eds.Server.Cache.Get(builder); f && !features.EnableUnsafeAssertions {
 			// We skip cache if assertions are enabled, so that the cache will assert our eviction logic is correct
@@ -589,6 +622,7 @@ func (eds *EdsGenerator) buildEndpoints(proxy *model.Proxy,
 			eds.Server.Cache.Add(builder, req, resource)
 		}
 	}
+	reportUnauthorizedClusters(proxy, unauthorized)
 	return resources, model.XdsLogDetails{
 		Incremental:    len(edsUpdatedServices) != 0,
 		AdditionalInfo: fmt.Sprintf("empty:%v cached:%v/%v", empty, cached, cached+regenerated),
@@ -606,6 +640,7 @@ func (eds *EdsGenerator) buildDeltaEndpoints(proxy *model.Proxy,
 	empty := 0
 	cached := 0
 	regenerated := 0
+	var unauthorized []string
 
 	for _, clusterName := range w.ResourceNames {
 		// filter out eds that are not updated for clusters
@@ -613,6 +648,11 @@ func (eds *EdsGenerator) buildDeltaEndpoints(proxy *model.Proxy,
 		if _, ok := edsUpdatedServices[string(hostname)]; !ok {
 			continue
 		}
+		if features.EnableXDSResourceAuthorization && !authorizeCluster(proxy, push, clusterName) {
+			unauthorized = append(unauthorized, clusterName)
+			removed = append(removed, clusterName)
+			continue
+		}
 
 		builder := NewEndpointBuilder(clusterName, proxy, push)
 		if marshalledEndpoint, f := eds.Server.Cache.Get(builder); f && !features.EnableUnsafeAssertions {
@@ -641,6 +681,7 @@ func (eds *EdsGenerator) buildDeltaEndpoints(proxy *model.Proxy,
 			eds.Server.Cache.Add(builder, req, resource)
 		}
 	}
+	reportUnauthorizedClusters(proxy, unauthorized)
 	return resources, removed, model.XdsLogDetails{
 		Incremental:    len(edsUpdatedServices) != 0,
 		AdditionalInfo: fmt.Sprintf("empty:%v cached:%v/%v", empty, cached, cached+regenerated),
@@ -33,12 +33,15 @@ import (
 
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	uatomic "go.uber.org/atomic"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
@@ -444,6 +447,36 @@ func TestEDSUnhealthyEndpoints(t *testing.T) {
 			t.Fatal("expected endpoint to be unhealthy, but got healthy")
 		}
 	}
+
+	// Now change the status of endpoint to Terminating and validate Eds is pushed with a degraded endpoint.
+	s.Discovery.MemRegistry.SetEndpoints("unhealthy.svc.cluster.local", "",
+		[]*model.IstioEndpoint{
+			{
+				Address:         "10.0.0.53",
+				EndpointPort:    53,
+				ServicePortName: "tcp-dns",
+				HealthStatus:    model.Terminating,
+			},
+			{
+				Address:         "10.0.0.54",
+				EndpointPort:    53,
+				ServicePortName: "tcp-dns",
+				HealthStatus:    model.Healthy,
+			},
+		})
+
+	upd, _ = adscon.Wait(5*time.Second, v3.EndpointType)
+
+	if len(upd) > 0 && !contains(upd, v3.EndpointType) {
+		t.Fatalf("Expecting EDS push as endpoint health is changed. But received %v", upd)
+	}
+
+	lbe = adscon.GetEndpoints()["outbound|53||unhealthy.svc.cluster.local"]
+	for _, lbe := range lbe.Endpoints[0].LbEndpoints {
+		if lbe.GetEndpoint().Address.GetSocketAddress().Address == "10.0.0.53" && lbe.HealthStatus != envoy_config_core_v3.HealthStatus_DEGRADED {
+			t.Fatalf("expected endpoint to be degraded, but got %v", lbe.HealthStatus)
+		}
+	}
 }
 
 // Validates the behavior when Service resolution type is updated after initial EDS push.
@@ -653,6 +686,31 @@ func TestZeroEndpointShardSA(t *testing.T) {
 	}
 }
 
+func TestEndpointShardNetworks(t *testing.T) {
+	defaultValue := features.CrossNetworkTrafficPolicy
+	features.CrossNetworkTrafficPolicy = `[{"h2Upgrade": true}]`
+	defer func() { features.CrossNetworkTrafficPolicy = defaultValue }()
+
+	s := new(xds.DiscoveryServer)
+	s.EndpointShardsByService = make(map[string]map[string]*xds.EndpointShards)
+	s.Cache = model.DisabledCache{}
+	networks := func() []string {
+		return s.EndpointShardsByService["test"]["test"].Networks.SortedList()
+	}
+	s.EDSCacheUpdate("c1", "test", "test", []*model.IstioEndpoint{{Address: "10.172.0.1", Network: "east"}})
+	if got := networks(); !reflect.DeepEqual(got, []string{"east"}) {
+		t.Fatalf("got networks %v, want east", got)
+	}
+	s.EDSCacheUpdate("c2", "test", "test", []*model.IstioEndpoint{{Address: "10.244.0.1", Network: "west"}})
+	if got := networks(); !reflect.DeepEqual(got, []string{"east", "west"}) {
+		t.Fatalf("got networks %v, want east and west", got)
+	}
+	s.EDSCacheUpdate("c2", "test", "test", []*model.IstioEndpoint{})
+	if got := networks(); !reflect.DeepEqual(got, []string{"east"}) {
+		t.Fatalf("got networks %v, want east", got)
+	}
+}
+
 func fullPush(s *xds.FakeDiscoveryServer) {
 	s.Discovery.Push(&model.PushRequest{Full: true})
 }
@@ -1287,3 +1345,73 @@ func contains(s []string, e string) bool {
 	}
 	return false
 }
+
+func TestEdsResourceAuthorization(t *testing.T) {
+	original := features.EnableXDSResourceAuthorization
+	t.Cleanup(func() {
+		features.EnableXDSResourceAuthorization = original
+	})
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: visible
+  namespace: default
+spec:
+  hosts:
+  - visible.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: hidden
+  namespace: other
+spec:
+  hosts:
+  - hidden.example.com
+  exportTo:
+  - .
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+`})
+	clusters := []string{
+		"outbound|80||visible.example.com",
+		"outbound|81||visible.example.com",
+		"outbound|80||hidden.example.com",
+	}
+	responseClusters := func(t *testing.T) []string {
+		ads := s.ConnectADS().WithType(v3.EndpointType)
+		resp := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{ResourceNames: clusters})
+		var got []string
+		for _, cla := range xdstest.UnmarshalClusterLoadAssignment(t, resp.GetResources()) {
+			got = append(got, cla.ClusterName)
+		}
+		return got
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		features.EnableXDSResourceAuthorization = false
+		if got := responseClusters(t); !reflect.DeepEqual(got, clusters) {
+			t.Fatalf("expected clusters %v, got %v", clusters, got)
+		}
+	})
+	t.Run("enabled", func(t *testing.T) {
+		features.EnableXDSResourceAuthorization = true
+		want := []string{"outbound|80||visible.example.com"}
+		if got := responseClusters(t); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected clusters %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,489 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// EffectivePolicyField is a setting of the effective traffic policy of a service, along with the configuration it
+// comes from.
+type EffectivePolicyField struct {
+	Field string          `json:"field"`
+	Value json.RawMessage `json:"value"`
+	// Source is the resource setting the value, such as "DestinationRule default/reviews", "MeshConfig connectTimeout"
+	// or "default" for the built-in defaults.
+	Source string `json:"source"`
+}
+
+// EffectivePolicy is the traffic policy the sidecars of a namespace apply to the requests to a service host.
+type EffectivePolicy struct {
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	Subset    string `json:"subset,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	// SidecarScope is the namespace/name of the Sidecar scoping the namespace, if any.
+	SidecarScope string `json:"sidecarScope,omitempty"`
+	// DestinationRule is the namespace/name of the DestinationRule applied to the host, if any.
+	DestinationRule string                 `json:"destinationRule,omitempty"`
+	Fields          []EffectivePolicyField `json:"fields"`
+}
+
+// trafficPolicyField reads a field of a traffic policy, returning nil if it is not set.
+type trafficPolicyField struct {
+	name string
+	get  func(p *networking.TrafficPolicy) interface{}
+	// fallback returns the value used when the field is not set and its source, or nil if there is none.
+	fallback func(push *model.PushContext, p *networking.TrafficPolicy) (interface{}, string)
+}
+
+func nonZero(v int32) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func nonNil(m gogoproto.Message, isNil bool) interface{} {
+	if isNil {
+		return nil
+	}
+	return m
+}
+
+var trafficPolicyFields = []trafficPolicyField{
+	{
+		name: "connectionPool.tcp.maxConnections",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			return nonZero(p.GetConnectionPool().GetTcp().GetMaxConnections())
+		},
+	},
+	{
+		name: "connectionPool.tcp.connectTimeout",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			d := p.GetConnectionPool().GetTcp().GetConnectTimeout()
+			return nonNil(d, d == nil)
+		},
+		fallback: func(push *model.PushContext, _ *networking.TrafficPolicy) (interface{}, string) {
+			d := push.Mesh.GetConnectTimeout()
+			return nonNil(d, d == nil), "MeshConfig connectTimeout"
+		},
+	},
+	{
+		name: "connectionPool.tcp.tcpKeepalive",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			k := p.GetConnectionPool().GetTcp().GetTcpKeepalive()
+			return nonNil(k, k == nil)
+		},
+		fallback: func(push *model.PushContext, _ *networking.TrafficPolicy) (interface{}, string) {
+			k := push.Mesh.GetTcpKeepalive()
+			return nonNil(k, k == nil), "MeshConfig tcpKeepalive"
+		},
+	},
+	{
+		name: "connectionPool.http.http1MaxPendingRequests",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			return nonZero(p.GetConnectionPool().GetHttp().GetHttp1MaxPendingRequests())
+		},
+	},
+	{
+		name: "connectionPool.http.http2MaxRequests",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			return nonZero(p.GetConnectionPool().GetHttp().GetHttp2MaxRequests())
+		},
+	},
+	{
+		name: "connectionPool.http.maxRequestsPerConnection",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			return nonZero(p.GetConnectionPool().GetHttp().GetMaxRequestsPerConnection())
+		},
+	},
+	{
+		name: "connectionPool.http.maxRetries",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			return nonZero(p.GetConnectionPool().GetHttp().GetMaxRetries())
+		},
+	},
+	{
+		name: "connectionPool.http.idleTimeout",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			d := p.GetConnectionPool().GetHttp().GetIdleTimeout()
+			return nonNil(d, d == nil)
+		},
+	},
+	{
+		name: "connectionPool.http.h2UpgradePolicy",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			if policy := p.GetConnectionPool().GetHttp().GetH2UpgradePolicy(); policy != networking.ConnectionPoolSettings_HTTPSettings_DEFAULT {
+				return policy.String()
+			}
+			return nil
+		},
+		fallback: func(push *model.PushContext, _ *networking.TrafficPolicy) (interface{}, string) {
+			return push.Mesh.GetH2UpgradePolicy().String(), "MeshConfig h2UpgradePolicy"
+		},
+	},
+	{
+		name: "loadBalancer.simple",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			if p.GetLoadBalancer().GetLbPolicy() == nil {
+				return nil
+			}
+			if _, ok := p.GetLoadBalancer().GetLbPolicy().(*networking.LoadBalancerSettings_Simple); !ok {
+				return nil
+			}
+			return p.GetLoadBalancer().GetSimple().String()
+		},
+		fallback: func(_ *model.PushContext, p *networking.TrafficPolicy) (interface{}, string) {
+			if p.GetLoadBalancer().GetConsistentHash() != nil {
+				return nil, ""
+			}
+			if features.EnableLegacyLBAlgorithmDefault {
+				return networking.LoadBalancerSettings_ROUND_ROBIN.String(), "default"
+			}
+			return networking.LoadBalancerSettings_LEAST_REQUEST.String(), "default"
+		},
+	},
+	{
+		name: "loadBalancer.consistentHash",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			h := p.GetLoadBalancer().GetConsistentHash()
+			return nonNil(h, h == nil)
+		},
+	},
+	{
+		name: "loadBalancer.localityLbSetting",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			l := p.GetLoadBalancer().GetLocalityLbSetting()
+			return nonNil(l, l == nil)
+		},
+		fallback: func(push *model.PushContext, _ *networking.TrafficPolicy) (interface{}, string) {
+			l := push.Mesh.GetLocalityLbSetting()
+			return nonNil(l, l == nil), "MeshConfig localityLbSetting"
+		},
+	},
+	{
+		name: "outlierDetection",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			o := p.GetOutlierDetection()
+			return nonNil(o, o == nil)
+		},
+	},
+	{
+		name: "tls",
+		get: func(p *networking.TrafficPolicy) interface{} {
+			t := p.GetTls()
+			return nonNil(t, t == nil)
+		},
+		fallback: func(push *model.PushContext, _ *networking.TrafficPolicy) (interface{}, string) {
+			if push.Mesh.GetEnableAutoMtls().GetValue() {
+				return "ISTIO_MUTUAL if the destination workload has a sidecar", "MeshConfig enableAutoMtls"
+			}
+			return nil, ""
+		},
+	},
+}
+
+// policyValue marshals a value of a policy field to JSON.
+func policyValue(v interface{}) (json.RawMessage, error) {
+	if m, ok := v.(gogoproto.Message); ok {
+		s, err := gogoprotomarshal.ToJSON(m)
+		return json.RawMessage(s), err
+	}
+	return json.Marshal(v)
+}
+
+// effectiveTrafficPolicy merges the traffic policy of the DestinationRule for the subset and port, as the cluster
+// builder does.
+func effectiveTrafficPolicy(dr *networking.DestinationRule, subset string, port *model.Port) *networking.TrafficPolicy {
+	policy := v1alpha3.MergeTrafficPolicy(nil, dr.GetTrafficPolicy(), port)
+	for _, s := range dr.GetSubsets() {
+		if s.GetName() == subset {
+			policy = v1alpha3.MergeTrafficPolicy(policy, s.GetTrafficPolicy(), port)
+		}
+	}
+	return policy
+}
+
+// destinationRuleSources returns the DestinationRules which may have contributed to the effective one, in the order
+// they are merged: the ones for the same host in creation order, then the namespace and mesh wide ones if
+// DestinationRule inheritance is enabled.
+func (s *DiscoveryServer) destinationRuleSources(push *model.PushContext, effective *config.Config) []config.Config {
+	configs, err := s.Env.List(gvk.DestinationRule, effective.Namespace)
+	if err != nil {
+		return nil
+	}
+	if features.EnableDestinationRuleInheritance && effective.Namespace != push.Mesh.GetRootNamespace() {
+		if root, err := s.Env.List(gvk.DestinationRule, push.Mesh.GetRootNamespace()); err == nil {
+			configs = append(configs, root...)
+		}
+	}
+	// Same order as the push context, which merges the DestinationRules by creation time, then name and namespace.
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].CreationTimestamp == configs[j].CreationTimestamp {
+			return configs[i].Name+"."+configs[i].Namespace < configs[j].Name+"."+configs[j].Namespace
+		}
+		return configs[i].CreationTimestamp.Before(configs[j].CreationTimestamp)
+	})
+
+	effectiveHost := model.ResolveShortnameToFQDN(effective.Spec.(*networking.DestinationRule).GetHost(), effective.Meta)
+	var sameHost, inherited []config.Config
+	for _, c := range configs {
+		dr := c.Spec.(*networking.DestinationRule)
+		switch {
+		case dr.GetHost() == "":
+			if features.EnableDestinationRuleInheritance {
+				inherited = append(inherited, c)
+			}
+		case c.Namespace == effective.Namespace && model.ResolveShortnameToFQDN(dr.GetHost(), c.Meta) == effectiveHost:
+			sameHost = append(sameHost, c)
+		}
+	}
+	return append(sameHost, inherited...)
+}
+
+// EffectivePolicy returns the traffic policy the sidecars of the namespace apply to the requests to the host, with
+// the source of each setting.
+func (s *DiscoveryServer) EffectivePolicy(push *model.PushContext, hostname host.Name, namespace, subset string,
+	portNumber int) (*EffectivePolicy, error) {
+	// The namespace wide Sidecar applies, as the workload is not known.
+	proxy := &model.Proxy{
+		Type:            model.SidecarProxy,
+		ConfigNamespace: namespace,
+		Metadata:        &model.NodeMetadata{Namespace: namespace},
+	}
+	proxy.SetSidecarScope(push)
+	svc := push.ServiceForHostname(proxy, hostname)
+	if svc == nil {
+		return nil, fmt.Errorf("service %s is not visible from namespace %s", hostname, namespace)
+	}
+	var port *model.Port
+	if portNumber != 0 {
+		p, f := svc.Ports.GetByPort(portNumber)
+		if !f {
+			return nil, fmt.Errorf("service %s has no port %d", hostname, portNumber)
+		}
+		port = p
+	}
+
+	out := &EffectivePolicy{
+		Host:      string(hostname),
+		Namespace: namespace,
+		Subset:    subset,
+		Port:      portNumber,
+		Fields:    make([]EffectivePolicyField, 0),
+	}
+	if sc := proxy.SidecarScope; sc != nil && sc.Sidecar != nil {
+		out.SidecarScope = sc.Namespace + "/" + sc.Name
+	}
+
+	var policy *networking.TrafficPolicy
+	var sources []config.Config
+	drConfig := push.DestinationRule(proxy, svc)
+	if drConfig != nil {
+		dr := drConfig.Spec.(*networking.DestinationRule)
+		if subset != "" && !hasSubset(dr, subset) {
+			return nil, fmt.Errorf("DestinationRule %s/%s has no subset %s", drConfig.Namespace, drConfig.Name, subset)
+		}
+		out.DestinationRule = drConfig.Namespace + "/" + drConfig.Name
+		policy = effectiveTrafficPolicy(dr, subset, port)
+		sources = s.destinationRuleSources(push, drConfig)
+	} else if subset != "" {
+		return nil, fmt.Errorf("no DestinationRule defines subset %s of %s", subset, hostname)
+	}
+
+	for _, f := range trafficPolicyFields {
+		var value interface{}
+		source := ""
+		if v := f.get(policy); v != nil {
+			value = v
+			source = "DestinationRule " + out.DestinationRule
+			// Attribute the value to the first merged DestinationRule which sets it.
+			for _, c := range sources {
+				if cv := f.get(effectiveTrafficPolicy(c.Spec.(*networking.DestinationRule), subset, port)); cv != nil && samePolicyValue(cv, v) {
+					source = "DestinationRule " + c.Namespace + "/" + c.Name
+					break
+				}
+			}
+		} else if f.fallback != nil {
+			value, source = f.fallback(push, policy)
+		}
+		if value == nil {
+			continue
+		}
+		raw, err := policyValue(value)
+		if err != nil {
+			return nil, err
+		}
+		out.Fields = append(out.Fields, EffectivePolicyField{Field: f.name, Value: raw, Source: source})
+	}
+
+	routeFields, err := routePolicyFields(push, proxy, svc)
+	if err != nil {
+		return nil, err
+	}
+	out.Fields = append(out.Fields, routeFields...)
+	return out, nil
+}
+
+func hasSubset(dr *networking.DestinationRule, subset string) bool {
+	for _, s := range dr.GetSubsets() {
+		if s.GetName() == subset {
+			return true
+		}
+	}
+	return false
+}
+
+func samePolicyValue(a, b interface{}) bool {
+	ra, err := policyValue(a)
+	if err != nil {
+		return false
+	}
+	rb, err := policyValue(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ra, rb)
+}
+
+// routePolicyFields returns the timeout and retries of the HTTP routes of the VirtualService of the mesh gateway
+// sending traffic to the service, or the defaults if there is none.
+func routePolicyFields(push *model.PushContext, proxy *model.Proxy, svc *model.Service) ([]EffectivePolicyField, error) {
+	var fields []EffectivePolicyField
+	add := func(field string, value interface{}, source string) error {
+		raw, err := policyValue(value)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, EffectivePolicyField{Field: field, Value: raw, Source: source})
+		return nil
+	}
+
+	for _, vs := range push.VirtualServicesForGateway(proxy, constants.IstioMeshGateway) {
+		rule := vs.Spec.(*networking.VirtualService)
+		if !virtualServiceHasHost(rule, vs.Meta, svc.Hostname) {
+			continue
+		}
+		source := "VirtualService " + vs.Namespace + "/" + vs.Name
+		for i, r := range rule.GetHttp() {
+			if !routeHasDestination(r, vs.Meta, svc.Hostname) {
+				continue
+			}
+			timeout, timeoutSource := interface{}("0s"), "default"
+			if r.GetTimeout() != nil {
+				timeout, timeoutSource = r.GetTimeout(), source
+			}
+			retries, retriesSource := interface{}(defaultRetries()), "default"
+			if r.GetRetries() != nil {
+				retries, retriesSource = r.GetRetries(), source
+			}
+			if err := add(fmt.Sprintf("http[%d].timeout", i), timeout, timeoutSource); err != nil {
+				return nil, err
+			}
+			if err := add(fmt.Sprintf("http[%d].retries", i), retries, retriesSource); err != nil {
+				return nil, err
+			}
+		}
+		// The first VirtualService for the host is the one applied.
+		return fields, nil
+	}
+
+	if err := add("http.timeout", "0s", "default"); err != nil {
+		return nil, err
+	}
+	if err := add("http.retries", defaultRetries(), "default"); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// defaultRetries returns the retries applied to the routes without retry policy, as set by retry.DefaultPolicy.
+func defaultRetries() *networking.HTTPRetry {
+	return &networking.HTTPRetry{
+		Attempts: 2,
+		RetryOn:  "connect-failure,refused-stream,unavailable,cancelled,retriable-status-codes",
+	}
+}
+
+func virtualServiceHasHost(vs *networking.VirtualService, meta config.Meta, hostname host.Name) bool {
+	for _, h := range vs.GetHosts() {
+		if model.ResolveShortnameToFQDN(h, meta).Matches(hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+func routeHasDestination(r *networking.HTTPRoute, meta config.Meta, hostname host.Name) bool {
+	for _, d := range r.GetRoute() {
+		if model.ResolveShortnameToFQDN(d.GetDestination().GetHost(), meta) == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// effectivePolicyz returns the effective traffic policy of a service host, as applied by the sidecars of a namespace.
+func (s *DiscoveryServer) effectivePolicyz(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Failed to parse request\n"))
+		return
+	}
+	hostname := req.Form.Get("host")
+	if hostname == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a host in the query string\n"))
+		return
+	}
+	namespace := req.Form.Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+	port := 0
+	if p := req.Form.Get("port"); p != "" {
+		var err error
+		if port, err = strconv.Atoi(p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(fmt.Sprintf("Invalid port %q\n", p)))
+			return
+		}
+	}
+	out, err := s.EffectivePolicy(s.globalPushContext(), host.Name(hostname), namespace, req.Form.Get("subset"), port)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	writeJSON(w, out)
+}
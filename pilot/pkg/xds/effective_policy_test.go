@@ -0,0 +1,154 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const effectivePolicyConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  - number: 9090
+    name: http-admin
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: a-reviews
+  namespace: default
+spec:
+  host: reviews.example.com
+  trafficPolicy:
+    connectionPool:
+      http:
+        maxRetries: 5
+    portLevelSettings:
+    - port:
+        number: 9090
+      loadBalancer:
+        simple: RANDOM
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: b-reviews
+  namespace: default
+spec:
+  host: reviews.example.com
+  subsets:
+  - name: v2
+    labels:
+      version: v2
+    trafficPolicy:
+      outlierDetection:
+        consecutive5xxErrors: 3
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  http:
+  - timeout: 3s
+    route:
+    - destination:
+        host: reviews.example.com
+`
+
+func TestEffectivePolicyz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: effectivePolicyConfig})
+
+	get := func(query string, wantCode int) map[string]EffectivePolicyField {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/debug/effectivepolicyz?"+query, nil)
+		http.HandlerFunc(s.Discovery.effectivePolicyz).ServeHTTP(rr, req)
+		if rr.Code != wantCode {
+			t.Fatalf("%q: got code %d, want %d: %s", query, rr.Code, wantCode, rr.Body.String())
+		}
+		if wantCode != http.StatusOK {
+			return nil
+		}
+		var out EffectivePolicy
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		fields := map[string]EffectivePolicyField{}
+		for _, f := range out.Fields {
+			fields[f.Field] = f
+		}
+		return fields
+	}
+	expect := func(fields map[string]EffectivePolicyField, field, value, source string) {
+		t.Helper()
+		f, ok := fields[field]
+		if !ok {
+			t.Fatalf("missing field %s in %v", field, fields)
+		}
+		if string(f.Value) != value || f.Source != source {
+			t.Fatalf("%s: got %s from %q, want %s from %q", field, f.Value, f.Source, value, source)
+		}
+	}
+
+	fields := get("host=reviews.example.com&namespace=default", http.StatusOK)
+	expect(fields, "connectionPool.http.maxRetries", "5", "DestinationRule default/a-reviews")
+	expect(fields, "connectionPool.tcp.connectTimeout", `"10s"`, "MeshConfig connectTimeout")
+	expect(fields, "loadBalancer.simple", `"LEAST_REQUEST"`, "default")
+	expect(fields, "http[0].timeout", `"3s"`, "VirtualService default/reviews")
+	expect(fields, "http[0].retries", `{"attempts":2,"retryOn":"connect-failure,refused-stream,unavailable,cancelled,retriable-status-codes"}`,
+		"default")
+	if _, f := fields["outlierDetection"]; f {
+		t.Fatalf("unexpected outlier detection: %v", fields["outlierDetection"])
+	}
+
+	// Port level settings replace the top level ones.
+	fields = get("host=reviews.example.com&namespace=default&port=9090", http.StatusOK)
+	expect(fields, "loadBalancer.simple", `"RANDOM"`, "DestinationRule default/a-reviews")
+	if _, f := fields["connectionPool.http.maxRetries"]; f {
+		t.Fatalf("unexpected max retries for port 9090: %v", fields["connectionPool.http.maxRetries"])
+	}
+
+	// The subset merged from the second DestinationRule is attributed to it.
+	fields = get("host=reviews.example.com&namespace=default&subset=v2", http.StatusOK)
+	expect(fields, "outlierDetection", `{"consecutive5xxErrors":3}`, "DestinationRule default/b-reviews")
+	expect(fields, "connectionPool.http.maxRetries", "5", "DestinationRule default/a-reviews")
+
+	get("namespace=default", http.StatusBadRequest)
+	get("host=reviews.example.com&port=http", http.StatusBadRequest)
+	get("host=reviews.example.com&subset=v3", http.StatusNotFound)
+	get("host=unknown.example.com", http.StatusNotFound)
+}
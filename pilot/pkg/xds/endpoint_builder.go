@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
 	"istio.io/istio/pkg/cluster"
@@ -75,14 +76,19 @@ type EndpointBuilder struct {
 	subsetName string
 	hostname   host.Name
 	port       int
-	push       *model.PushContext
-	proxy      *model.Proxy
+	// udp is whether the cluster is the cluster of a UDP service port.
+	udp   bool
+	push  *model.PushContext
+	proxy *model.Proxy
+	// lbSubsetKeys are the subset selectors of the subset load balancer, whose label values are added to the
+	// endpoints.
+	lbSubsetKeys [][]string
 
 	mtlsChecker *mtlsChecker
 }
 
 func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.PushContext) EndpointBuilder {
-	_, subsetName, hostname, port := model.ParseSubsetKey(clusterName)
+	direction, subsetName, hostname, port := model.ParseSubsetKey(clusterName)
 	svc := push.ServiceForHostname(proxy, hostname)
 	dr := push.DestinationRule(proxy, svc)
 	b := EndpointBuilder{
@@ -96,11 +102,13 @@ func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.Push
 		destinationRule: dr,
 		tunnelType:      GetTunnelBuilderType(clusterName, proxy, push),
 
-		push:       push,
-		proxy:      proxy,
-		subsetName: subsetName,
-		hostname:   hostname,
-		port:       port,
+		push:         push,
+		proxy:        proxy,
+		subsetName:   subsetName,
+		hostname:     hostname,
+		port:         port,
+		udp:          direction == model.TrafficDirectionOutboundUDP,
+		lbSubsetKeys: loadbalancer.SubsetKeysForDestinationRule(dr),
 	}
 
 	// We need this for multi-network, or for clusters meant for use with AUTO_PASSTHROUGH.
@@ -111,6 +119,14 @@ func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.Push
 	return b
 }
 
+// servicePort returns the service port of the cluster, the UDP port for the clusters of UDP service ports.
+func (b EndpointBuilder) servicePort() (*model.Port, bool) {
+	if b.udp {
+		return b.service.Ports.GetUDPByPort(b.port)
+	}
+	return b.service.Ports.GetByPort(b.port)
+}
+
 func (b EndpointBuilder) DestinationRule() *networkingapi.DestinationRule {
 	if b.destinationRule == nil {
 		return nil
@@ -295,6 +311,10 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 		if isClusterLocal && (shardKey.Cluster() != b.clusterID) {
 			continue
 		}
+		// Endpoints in other clusters are only included if the service is exported to the cluster of the proxy.
+		if shardKey.Cluster() != b.clusterID && b.service != nil && !b.service.Attributes.ExportedToCluster(b.clusterID) {
+			continue
+		}
 		for _, ep := range endpoints {
 			// TODO(nmittler): Consider merging discoverability policy with cluster-local
 			if !ep.IsDiscoverableFromProxy(b.proxy) {
@@ -336,7 +356,11 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 					}
 				}
 			}
-			locLbEps.append(ep, ep.EnvoyEndpoint, ep.TunnelAbility)
+			lbEp := ep.EnvoyEndpoint
+			if len(b.lbSubsetKeys) > 0 {
+				lbEp = loadbalancer.ApplySubsetMetadata(lbEp, ep.Labels, b.lbSubsetKeys)
+			}
+			locLbEps.append(ep, lbEp, ep.TunnelAbility)
 		}
 	}
 	shards.mutex.Unlock()
@@ -399,8 +423,12 @@ func (b *EndpointBuilder) createClusterLoadAssignment(llbOpts []*LocLbEndpointsA
 func buildEnvoyLbEndpoint(e *model.IstioEndpoint) *endpoint.LbEndpoint {
 	addr := util.BuildAddress(e.Address, e.EndpointPort)
 	healthStatus := core.HealthStatus_HEALTHY
-	if e.HealthStatus == model.UnHealthy {
+	switch e.HealthStatus {
+	case model.UnHealthy:
 		healthStatus = core.HealthStatus_UNHEALTHY
+	case model.Terminating:
+		// Envoy only sends traffic to degraded endpoints when there are not enough healthy ones.
+		healthStatus = core.HealthStatus_DEGRADED
 	}
 
 	ep := &endpoint.LbEndpoint{
@@ -0,0 +1,149 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/util/sets"
+)
+
+const (
+	// endpointReportRetention is how long the report of a proxy about an endpoint is kept.
+	endpointReportRetention = 30 * time.Minute
+	// maxReportedEndpoints bounds the number of endpoints tracked by an endpointReports.
+	maxReportedEndpoints = 10000
+)
+
+type endpointReportKey struct {
+	cluster  string
+	endpoint string
+}
+
+// endpointReport is the state of an upstream endpoint, as last reported by a proxy.
+type endpointReport struct {
+	failing bool
+	reason  string
+	time    time.Time
+}
+
+// EndpointReportSummary is the state of an upstream endpoint aggregated across the proxies reporting it.
+type EndpointReportSummary struct {
+	Cluster  string `json:"cluster"`
+	Endpoint string `json:"endpoint"`
+	// FailingProxies lists the proxies currently reporting the endpoint as failing.
+	FailingProxies []string `json:"failingProxies,omitempty"`
+	// Reasons counts the failing proxies by failure reason.
+	Reasons map[string]int `json:"reasons,omitempty"`
+	// ReportingProxies is the number of proxies which reported on the endpoint.
+	ReportingProxies int       `json:"reportingProxies"`
+	LastUpdate       time.Time `json:"lastUpdate"`
+}
+
+// endpointReports aggregates the state of upstream endpoints as observed by proxies, for example ejections
+// by outlier detection. Only the latest report of each proxy is kept, for a bounded time.
+type endpointReports struct {
+	mu        sync.Mutex
+	endpoints map[endpointReportKey]map[string]endpointReport
+	now       func() time.Time
+}
+
+func newEndpointReports() *endpointReports {
+	return &endpointReports{
+		endpoints: map[endpointReportKey]map[string]endpointReport{},
+		now:       time.Now,
+	}
+}
+
+// Record stores the state of an endpoint as reported by a proxy.
+func (r *endpointReports) Record(proxyID, cluster, endpoint string, failing bool, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := endpointReportKey{cluster: cluster, endpoint: endpoint}
+	reports, f := r.endpoints[key]
+	if !f {
+		if len(r.endpoints) >= maxReportedEndpoints {
+			r.pruneLocked()
+			if len(r.endpoints) >= maxReportedEndpoints {
+				log.Debugf("dropping report for endpoint %s of %s: too many endpoints tracked", endpoint, cluster)
+				return
+			}
+		}
+		reports = map[string]endpointReport{}
+		r.endpoints[key] = reports
+	}
+	reports[proxyID] = endpointReport{failing: failing, reason: reason, time: r.now()}
+}
+
+// pruneLocked removes expired reports. The lock must be held.
+func (r *endpointReports) pruneLocked() {
+	cutoff := r.now().Add(-endpointReportRetention)
+	for key, reports := range r.endpoints {
+		for proxy, report := range reports {
+			if report.time.Before(cutoff) {
+				delete(reports, proxy)
+			}
+		}
+		if len(reports) == 0 {
+			delete(r.endpoints, key)
+		}
+	}
+}
+
+// Summaries returns the aggregated state of the reported endpoints, sorted by cluster and endpoint. If
+// failingOnly is set, only endpoints reported as failing by at least one proxy are returned.
+func (r *endpointReports) Summaries(failingOnly bool) []EndpointReportSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	out := make([]EndpointReportSummary, 0, len(r.endpoints))
+	for key, reports := range r.endpoints {
+		s := EndpointReportSummary{
+			Cluster:          key.cluster,
+			Endpoint:         key.endpoint,
+			ReportingProxies: len(reports),
+		}
+		failing := sets.NewSet()
+		for proxy, report := range reports {
+			if report.time.After(s.LastUpdate) {
+				s.LastUpdate = report.time
+			}
+			if !report.failing {
+				continue
+			}
+			failing.Insert(proxy)
+			if s.Reasons == nil {
+				s.Reasons = map[string]int{}
+			}
+			s.Reasons[report.reason]++
+		}
+		if failingOnly && len(failing) == 0 {
+			continue
+		}
+		if len(failing) > 0 {
+			s.FailingProxies = failing.SortedList()
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Cluster != out[j].Cluster {
+			return out[i].Cluster < out[j].Cluster
+		}
+		return out[i].Endpoint < out[j].Endpoint
+	})
+	return out
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/data/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/google/go-cmp/cmp"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestEndpointReports(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newEndpointReports()
+	r.now = func() time.Time { return now }
+
+	r.Record("a", "outbound|80||foo", "10.0.0.1:80", true, "CONSECUTIVE_5XX")
+	r.Record("b", "outbound|80||foo", "10.0.0.1:80", true, "SUCCESS_RATE")
+	r.Record("c", "outbound|80||foo", "10.0.0.1:80", true, "CONSECUTIVE_5XX")
+	r.Record("a", "outbound|80||foo", "10.0.0.2:80", true, "CONSECUTIVE_5XX")
+	now = now.Add(time.Minute)
+	r.Record("a", "outbound|80||foo", "10.0.0.2:80", false, "CONSECUTIVE_5XX")
+	r.Record("c", "outbound|80||foo", "10.0.0.1:80", false, "CONSECUTIVE_5XX")
+
+	failing := []EndpointReportSummary{
+		{
+			Cluster:          "outbound|80||foo",
+			Endpoint:         "10.0.0.1:80",
+			FailingProxies:   []string{"a", "b"},
+			Reasons:          map[string]int{"CONSECUTIVE_5XX": 1, "SUCCESS_RATE": 1},
+			ReportingProxies: 3,
+			LastUpdate:       now,
+		},
+	}
+	if diff := cmp.Diff(failing, r.Summaries(true)); diff != "" {
+		t.Fatalf("unexpected failing summaries: %v", diff)
+	}
+	all := append(failing, EndpointReportSummary{
+		Cluster:          "outbound|80||foo",
+		Endpoint:         "10.0.0.2:80",
+		ReportingProxies: 1,
+		LastUpdate:       now,
+	})
+	if diff := cmp.Diff(all, r.Summaries(false)); diff != "" {
+		t.Fatalf("unexpected summaries: %v", diff)
+	}
+
+	// Reports expire after the retention period.
+	now = now.Add(endpointReportRetention)
+	r.Record("a", "outbound|80||bar", "10.0.0.3:80", true, "CONSECUTIVE_5XX")
+	now = now.Add(time.Second)
+	want := []EndpointReportSummary{
+		{
+			Cluster:          "outbound|80||bar",
+			Endpoint:         "10.0.0.3:80",
+			FailingProxies:   []string{"a"},
+			Reasons:          map[string]int{"CONSECUTIVE_5XX": 1},
+			ReportingProxies: 1,
+			LastUpdate:       now.Add(-time.Second),
+		},
+	}
+	if diff := cmp.Diff(want, r.Summaries(false)); diff != "" {
+		t.Fatalf("unexpected summaries after expiry: %v", diff)
+	}
+}
+
+func TestRecordOutlierEvents(t *testing.T) {
+	s := &DiscoveryServer{outlierReports: newEndpointReports()}
+	events := []*cluster.OutlierDetectionEvent{
+		{
+			Type:        cluster.OutlierEjectionType_CONSECUTIVE_5XX,
+			ClusterName: "outbound|80||foo",
+			UpstreamUrl: "10.0.0.1:80",
+			Action:      cluster.Action_EJECT,
+			Enforced:    true,
+		},
+		{
+			Type:        cluster.OutlierEjectionType_SUCCESS_RATE,
+			ClusterName: "outbound|80||foo",
+			UpstreamUrl: "10.0.0.2:80",
+			Action:      cluster.Action_EJECT,
+			Enforced:    false,
+		},
+	}
+	details := []*any.Any{}
+	for _, e := range events {
+		a, err := any.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details = append(details, a)
+	}
+	req := &discovery.DiscoveryRequest{
+		TypeUrl:     v3.OutlierEventType,
+		ErrorDetail: &google_rpc.Status{Details: details},
+	}
+	if s.shouldProcessRequest(&model.Proxy{ID: "a"}, req) {
+		t.Fatalf("outlier events should not be processed as a discovery request")
+	}
+	got := s.outlierReports.Summaries(true)
+	if len(got) != 1 || got[0].Endpoint != "10.0.0.1:80" || len(got[0].FailingProxies) != 1 {
+		t.Fatalf("unexpected summaries: %+v", got)
+	}
+}
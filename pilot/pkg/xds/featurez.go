@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"sort"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/pkg/env"
+)
+
+// FeaturesMetadataKey is the key of the node metadata echoed in the client configs of the proxies, holding the
+// fingerprint of the feature flags in effect for the config generated for the proxy.
+const FeaturesMetadataKey = "ISTIOD_FEATURES"
+
+// GenerationFeatures are the feature flags in effect for the config generated by istiod, tagged at their registration
+// in the features package. The flags are read at startup, so all the proxies connected to an istiod are generated with
+// the same flags, but the replicas and revisions of istiod may differ.
+type GenerationFeatures struct {
+	// Fingerprint identifies the values of the flags, to compare them across istiods.
+	Fingerprint string `json:"fingerprint"`
+	// Flags are the values of the flags, set explicitly or defaulted.
+	Flags map[string]string `json:"flags"`
+}
+
+var generationFeatures = newGenerationFeatures(env.VarDescriptions(), os.LookupEnv)
+
+func newGenerationFeatures(vars []env.Var, lookup func(string) (string, bool)) GenerationFeatures {
+	gf := GenerationFeatures{Flags: map[string]string{}}
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if !features.IsGenerationFlag(v.Name) {
+			continue
+		}
+		value, ok := lookup(v.Name)
+		if !ok {
+			value = v.DefaultValue
+		}
+		gf.Flags[v.Name] = value
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	h := fnv.New64a()
+	for _, name := range names {
+		_, _ = fmt.Fprintf(h, "%s=%s\n", name, gf.Flags[name])
+	}
+	gf.Fingerprint = fmt.Sprintf("%016x", h.Sum64())
+	return gf
+}
+
+// featurez lists the feature flags in effect for the config generated by this istiod, and their fingerprint. The
+// fingerprint is also part of the control plane identifier of the xDS responses, to detect proxies whose config was
+// generated by istiods with different flags.
+func (s *DiscoveryServer) featurez(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, generationFeatures)
+}
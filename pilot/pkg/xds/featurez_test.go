@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	"istio.io/pkg/env"
+)
+
+func TestGenerationFeatures(t *testing.T) {
+	vars := []env.Var{
+		{Name: "PILOT_ENABLE_QUIC_LISTENERS", DefaultValue: "false"},
+		{Name: "PILOT_INBOUND_PROTOCOL_DETECTION_TIMEOUT", DefaultValue: "1s"},
+		{Name: "ISTIO_GATEWAY_STRIP_HOST_PORT", DefaultValue: "false"},
+		{Name: "PILOT_PUSH_THROTTLE", DefaultValue: "100"},
+		{Name: "POD_NAME"},
+	}
+	lookup := func(set map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			v, ok := set[name]
+			return v, ok
+		}
+	}
+
+	defaults := newGenerationFeatures(vars, lookup(map[string]string{"POD_NAME": "istiod-1"}))
+	if len(defaults.Flags) != 3 || defaults.Flags["PILOT_INBOUND_PROTOCOL_DETECTION_TIMEOUT"] != "1s" {
+		t.Fatalf("unexpected flags %v", defaults.Flags)
+	}
+	if _, f := defaults.Flags["POD_NAME"]; f {
+		t.Fatalf("expected the instance variables not to be part of the flags")
+	}
+	if _, f := defaults.Flags["PILOT_PUSH_THROTTLE"]; f {
+		t.Fatalf("expected the flags tuning istiod not to be part of the flags")
+	}
+
+	// The fingerprint only depends on the values of the flags.
+	if got := newGenerationFeatures(vars, lookup(map[string]string{"POD_NAME": "istiod-2", "PILOT_PUSH_THROTTLE": "10"})); got.Fingerprint != defaults.Fingerprint {
+		t.Fatalf("expected the same fingerprint across instances, got %s and %s", got.Fingerprint, defaults.Fingerprint)
+	}
+	if got := newGenerationFeatures(vars, lookup(map[string]string{"PILOT_ENABLE_QUIC_LISTENERS": "false"})); got.Fingerprint != defaults.Fingerprint {
+		t.Fatalf("expected flags set to their default to keep the fingerprint")
+	}
+	enabled := newGenerationFeatures(vars, lookup(map[string]string{"PILOT_ENABLE_QUIC_LISTENERS": "true"}))
+	if enabled.Fingerprint == defaults.Fingerprint || enabled.Flags["PILOT_ENABLE_QUIC_LISTENERS"] != "true" {
+		t.Fatalf("expected a different fingerprint for a different flag, got %+v", enabled)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+)
+
+// FilterChainSummary summarizes a filter chain generated for a proxy: what it matches, and where it sends the
+// traffic.
+type FilterChainSummary struct {
+	Listener             string   `json:"listener"`
+	Name                 string   `json:"name,omitempty"`
+	DestinationPort      uint32   `json:"destinationPort,omitempty"`
+	PrefixRanges         []string `json:"prefixRanges,omitempty"`
+	ServerNames          []string `json:"serverNames,omitempty"`
+	TransportProtocol    string   `json:"transportProtocol,omitempty"`
+	ApplicationProtocols []string `json:"applicationProtocols,omitempty"`
+	Filters              []string `json:"filters"`
+	// Cluster is the destination of the TCP proxy, if any.
+	Cluster string `json:"cluster,omitempty"`
+	// RouteConfig is the route configuration of the HTTP connection manager, if any.
+	RouteConfig string `json:"routeConfig,omitempty"`
+}
+
+// ProxyFilterChains is the summary of the filter chains generated for a proxy.
+type ProxyFilterChains struct {
+	ProxyID string `json:"proxyID"`
+	// SidecarScope is the namespace/name of the Sidecar scope of the proxy.
+	SidecarScope string               `json:"sidecarScope,omitempty"`
+	FilterChains []FilterChainSummary `json:"filterChains"`
+}
+
+// summarizeFilterChains summarizes the filter chains of the listeners, including their default filter chains.
+func summarizeFilterChains(listeners []*listener.Listener) []FilterChainSummary {
+	out := make([]FilterChainSummary, 0)
+	for _, l := range listeners {
+		chains := l.GetFilterChains()
+		if l.GetDefaultFilterChain() != nil {
+			chains = append(chains[:len(chains):len(chains)], l.GetDefaultFilterChain())
+		}
+		for _, fc := range chains {
+			out = append(out, summarizeFilterChain(l.GetName(), fc))
+		}
+	}
+	return out
+}
+
+func summarizeFilterChain(listenerName string, fc *listener.FilterChain) FilterChainSummary {
+	match := fc.GetFilterChainMatch()
+	s := FilterChainSummary{
+		Listener:             listenerName,
+		Name:                 fc.GetName(),
+		DestinationPort:      match.GetDestinationPort().GetValue(),
+		ServerNames:          match.GetServerNames(),
+		TransportProtocol:    match.GetTransportProtocol(),
+		ApplicationProtocols: match.GetApplicationProtocols(),
+		Filters:              make([]string, 0, len(fc.GetFilters())),
+	}
+	for _, r := range match.GetPrefixRanges() {
+		s.PrefixRanges = append(s.PrefixRanges, fmt.Sprintf("%s/%d", r.GetAddressPrefix(), r.GetPrefixLen().GetValue()))
+	}
+	for _, f := range fc.GetFilters() {
+		s.Filters = append(s.Filters, f.GetName())
+		switch f.GetName() {
+		case wellknown.TCPProxy:
+			tcpProxy := &tcp.TcpProxy{}
+			if f.GetTypedConfig().UnmarshalTo(tcpProxy) == nil {
+				s.Cluster = tcpProxy.GetCluster()
+			}
+		case wellknown.HTTPConnectionManager:
+			cm := &hcm.HttpConnectionManager{}
+			if f.GetTypedConfig().UnmarshalTo(cm) == nil {
+				if cm.GetRds() != nil {
+					s.RouteConfig = cm.GetRds().GetRouteConfigName()
+				} else {
+					s.RouteConfig = cm.GetRouteConfig().GetName()
+				}
+			}
+		}
+	}
+	return s
+}
+
+// filterchainz summarizes the filter chains generated for a proxy, to replay the configuration generation decisions
+// offline. The listeners are generated for the request, so a proxy must be given.
+func (s *DiscoveryServer) filterchainz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	out := ProxyFilterChains{ProxyID: con.proxy.ID}
+	if sc := con.proxy.SidecarScope; sc != nil {
+		out.SidecarScope = sc.Namespace + "/" + sc.Name
+	}
+	out.FilterChains = summarizeFilterChains(s.ConfigGenerator.BuildListeners(con.proxy, s.globalPushContext()))
+	writeJSON(w, out)
+}
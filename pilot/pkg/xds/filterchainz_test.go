@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+const filterchainzConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: db
+  namespace: default
+spec:
+  hosts:
+  - db.example.com
+  addresses:
+  - 240.240.0.1
+  ports:
+  - number: 5432
+    name: tcp-db
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+`
+
+func TestFilterchainz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: filterchainzConfig})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+
+	get := func(query string, wantCode int) ProxyFilterChains {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/debug/filterchainz?"+query, nil)
+		http.HandlerFunc(s.Discovery.filterchainz).ServeHTTP(rr, req)
+		if rr.Code != wantCode {
+			t.Fatalf("got code %d, want %d: %s", rr.Code, wantCode, rr.Body.String())
+		}
+		var out ProxyFilterChains
+		if wantCode == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out
+	}
+
+	out := get("proxyID=test.default", http.StatusOK)
+	if out.ProxyID != "test.default" {
+		t.Fatalf("unexpected proxy: %+v", out)
+	}
+	found := false
+	for _, fc := range out.FilterChains {
+		if fc.Listener == "240.240.0.1_5432" && fc.Cluster == "outbound|5432||db.example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("filter chain of db.example.com not found: %+v", out.FilterChains)
+	}
+	// The listeners are only generated for a single proxy.
+	get("", http.StatusBadRequest)
+	get("proxyID=not-found", http.StatusNotFound)
+}
+
+func TestPushContextHandler(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: filterchainzConfig})
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/debug/pushcontext", nil)
+	http.HandlerFunc(s.Discovery.pushContextHandler).ServeHTTP(rr, req)
+	out := struct {
+		PushVersion string
+		Services    []struct {
+			Hostname string `json:"hostname"`
+		}
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.PushVersion == "" {
+		t.Fatalf("expected push version")
+	}
+	found := false
+	for _, svc := range out.Services {
+		if svc.Hostname == "db.example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("service db.example.com not found: %s", rr.Body.String())
+	}
+}
@@ -17,15 +17,19 @@ package filters
 import (
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tapcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/tap/v3"
 	cors "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
 	fault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
 	grpcstats "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_stats/v3"
 	grpcweb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
+	headertometadata "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/header_to_metadata/v3"
 	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	httptap "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/tap/v3"
 	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	httpinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/http_inspector/v3"
 	originaldst "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/original_dst/v3"
 	originalsrc "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/original_src/v3"
+	proxyprotocol "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/proxy_protocol/v3"
 	tlsinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
@@ -48,6 +52,17 @@ const (
 	MxFilterName          = "istio.metadata_exchange"
 	StatsFilterName       = "istio.stats"
 	StackdriverFilterName = "istio.stackdriver"
+
+	TapFilterName = "envoy.filters.http.tap"
+	// TapConfigID is the admin config ID of the tap filter. The filter captures nothing until a tap
+	// configuration with this ID is streamed to the /tap admin endpoint of the proxy.
+	TapConfigID = "istio-tap"
+	// TapAnnotation, when set to "true" on a workload, adds the tap filter to its HTTP filter chains.
+	TapAnnotation = "sidecar.istio.io/enableTap"
+
+	// HeaderToMetadataFilterName is the name of the filter setting the dynamic metadata of the requests from their
+	// headers, configured per route, for example to select the endpoints of the subset load balancer.
+	HeaderToMetadataFilterName = "envoy.filters.http.header_to_metadata"
 )
 
 // Define static filters to be reused across the codebase. This avoids duplicate marshaling/unmarshaling
@@ -65,6 +80,25 @@ var (
 			TypedConfig: util.MessageToAny(&fault.HTTPFault{}),
 		},
 	}
+	Tap = &hcm.HttpFilter{
+		Name: TapFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&httptap.Tap{
+				CommonConfig: &tapcommon.CommonExtensionConfig{
+					ConfigType: &tapcommon.CommonExtensionConfig_AdminConfig{
+						AdminConfig: &tapcommon.AdminConfig{ConfigId: TapConfigID},
+					},
+				},
+			}),
+		},
+	}
+	// HeaderToMetadata has no rules, which are set per route.
+	HeaderToMetadata = &hcm.HttpFilter{
+		Name: HeaderToMetadataFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&headertometadata.Config{}),
+		},
+	}
 	Router = &hcm.HttpFilter{
 		Name: wellknown.Router,
 		ConfigType: &hcm.HttpFilter_TypedConfig{
@@ -114,6 +148,12 @@ var (
 			}),
 		},
 	}
+	ProxyProtocol = &listener.ListenerFilter{
+		Name: wellknown.ProxyProtocol,
+		ConfigType: &listener.ListenerFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&proxyprotocol.ProxyProtocol{}),
+		},
+	}
 	Alpn = &hcm.HttpFilter{
 		Name: AlpnFilterName,
 		ConfigType: &hcm.HttpFilter_TypedConfig{
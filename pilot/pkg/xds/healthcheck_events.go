@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	envoycore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/data/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// recordHealthCheckEvents stores the active health check events reported by the agent of a proxy. Only
+// events changing the health of an endpoint are tracked.
+func (s *DiscoveryServer) recordHealthCheckEvents(proxy *model.Proxy, req *discovery.DiscoveryRequest) {
+	for _, a := range req.GetErrorDetail().GetDetails() {
+		event := &core.HealthCheckEvent{}
+		if err := a.UnmarshalTo(event); err != nil {
+			log.Debugf("ADS: invalid health check event from %s: %v", proxy.ID, err)
+			continue
+		}
+		endpoint := healthCheckEventHost(event.Host)
+		switch e := event.Event.(type) {
+		case *core.HealthCheckEvent_EjectUnhealthyEvent:
+			reason := e.EjectUnhealthyEvent.FailureType.String()
+			totalHealthCheckEjections.With(clusterTag.Value(event.ClusterName), typeTag.Value(reason)).Increment()
+			s.healthCheckReports.Record(proxy.ID, event.ClusterName, endpoint, true, reason)
+		case *core.HealthCheckEvent_AddHealthyEvent:
+			s.healthCheckReports.Record(proxy.ID, event.ClusterName, endpoint, false, "")
+		}
+	}
+}
+
+// healthCheckEventHost formats the address of the host of a health check event.
+func healthCheckEventHost(addr *envoycore.Address) string {
+	if sa := addr.GetSocketAddress(); sa != nil {
+		return net.JoinHostPort(sa.Address, strconv.Itoa(int(sa.GetPortValue())))
+	}
+	if pipe := addr.GetPipe(); pipe != nil {
+		return pipe.Path
+	}
+	return ""
+}
+
+// healthcheckz lists the endpoints marked unhealthy by the active health checks of proxies, as reported by
+// their agents. With ?all=true, endpoints which are healthy for all proxies are included as well.
+func (s *DiscoveryServer) healthcheckz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.healthCheckReports.Summaries(req.URL.Query().Get("all") != "true"))
+}
@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	envoycore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/data/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/google/go-cmp/cmp"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func healthCheckEventRequest(t *testing.T, events ...*core.HealthCheckEvent) *discovery.DiscoveryRequest {
+	t.Helper()
+	details := []*any.Any{}
+	for _, e := range events {
+		a, err := any.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details = append(details, a)
+	}
+	return &discovery.DiscoveryRequest{
+		TypeUrl:     v3.HealthCheckEventType,
+		ErrorDetail: &google_rpc.Status{Details: details},
+	}
+}
+
+func TestRecordHealthCheckEvents(t *testing.T) {
+	s := &DiscoveryServer{healthCheckReports: newEndpointReports()}
+	eject := func(addr string) *core.HealthCheckEvent {
+		return &core.HealthCheckEvent{
+			ClusterName: "outbound|80||foo",
+			Host:        util.BuildAddress(addr, 80),
+			Event: &core.HealthCheckEvent_EjectUnhealthyEvent{
+				EjectUnhealthyEvent: &core.HealthCheckEjectUnhealthy{FailureType: core.HealthCheckFailureType_ACTIVE},
+			},
+		}
+	}
+	add := func(addr string) *core.HealthCheckEvent {
+		return &core.HealthCheckEvent{
+			ClusterName: "outbound|80||foo",
+			Host:        util.BuildAddress(addr, 80),
+			Event:       &core.HealthCheckEvent_AddHealthyEvent{AddHealthyEvent: &core.HealthCheckAddHealthy{}},
+		}
+	}
+	failure := &core.HealthCheckEvent{
+		ClusterName: "outbound|80||foo",
+		Host:        util.BuildAddress("10.0.0.3", 80),
+		Event: &core.HealthCheckEvent_HealthCheckFailureEvent{
+			HealthCheckFailureEvent: &core.HealthCheckFailure{FailureType: core.HealthCheckFailureType_NETWORK},
+		},
+	}
+
+	if s.shouldProcessRequest(&model.Proxy{ID: "a"}, healthCheckEventRequest(t, eject("10.0.0.1"), eject("10.0.0.2"), failure)) {
+		t.Fatalf("health check events should not be processed as a discovery request")
+	}
+	s.shouldProcessRequest(&model.Proxy{ID: "b"}, healthCheckEventRequest(t, eject("10.0.0.1")))
+	s.shouldProcessRequest(&model.Proxy{ID: "a"}, healthCheckEventRequest(t, add("10.0.0.2")))
+
+	got := s.healthCheckReports.Summaries(false)
+	for i := range got {
+		got[i].LastUpdate = got[0].LastUpdate
+	}
+	want := []EndpointReportSummary{
+		{
+			Cluster:          "outbound|80||foo",
+			Endpoint:         "10.0.0.1:80",
+			FailingProxies:   []string{"a", "b"},
+			Reasons:          map[string]int{"ACTIVE": 2},
+			ReportingProxies: 2,
+			LastUpdate:       got[0].LastUpdate,
+		},
+		{
+			Cluster:          "outbound|80||foo",
+			Endpoint:         "10.0.0.2:80",
+			ReportingProxies: 1,
+			LastUpdate:       got[0].LastUpdate,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected summaries: %v", diff)
+	}
+}
+
+func TestHealthCheckEventHost(t *testing.T) {
+	cases := []struct {
+		addr *envoycore.Address
+		want string
+	}{
+		{util.BuildAddress("10.0.0.1", 8080), "10.0.0.1:8080"},
+		{util.BuildAddress("::1", 8080), "[::1]:8080"},
+		{util.BuildAddress("unix:///var/run/sock", 0), "/var/run/sock"},
+		{nil, ""},
+	}
+	for _, tt := range cases {
+		if got := healthCheckEventHost(tt.addr); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
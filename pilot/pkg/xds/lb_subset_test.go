@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/test/xdstest"
+)
+
+const lbSubsetConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: shards
+  namespace: default
+spec:
+  hosts:
+  - shards.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      shard: a
+  - address: 1.1.1.2
+    labels:
+      shard: b
+  - address: 1.1.1.3
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: shards
+  namespace: default
+  annotations:
+    networking.istio.io/lb-subset-keys: shard
+spec:
+  host: shards.example.com
+`
+
+func TestLbSubsetKeys(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: lbSubsetConfig})
+	proxy := s.SetupProxy(&model.Proxy{ConfigNamespace: "default"})
+	clusterName := "outbound|80||shards.example.com"
+
+	c := xdstest.ExtractCluster(clusterName, s.Clusters(proxy))
+	selectors := c.GetLbSubsetConfig().GetSubsetSelectors()
+	if len(selectors) != 1 || len(selectors[0].Keys) != 1 || selectors[0].Keys[0] != "shard" {
+		t.Fatalf("unexpected subset selectors %v", selectors)
+	}
+
+	shards := map[string]string{}
+	for _, cla := range s.Endpoints(proxy) {
+		if cla.ClusterName != clusterName {
+			continue
+		}
+		for _, llb := range cla.Endpoints {
+			for _, ep := range llb.LbEndpoints {
+				address := ep.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()
+				shards[address] = ep.GetMetadata().GetFilterMetadata()[util.EnvoyLbMetadataKey].GetFields()["shard"].GetStringValue()
+			}
+		}
+	}
+	want := map[string]string{"1.1.1.1": "a", "1.1.1.2": "b", "1.1.1.3": ""}
+	if len(shards) != len(want) {
+		t.Fatalf("got endpoints %v, want %v", shards, want)
+	}
+	for address, shard := range want {
+		if shards[address] != shard {
+			t.Fatalf("got shard %q for %s, want %q", shards[address], address, shard)
+		}
+	}
+}
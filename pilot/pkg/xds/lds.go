@@ -17,6 +17,7 @@ package xds
 import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
@@ -36,10 +37,10 @@ var skippedLdsConfigs = map[model.NodeType]map[config.GroupVersionKind]struct{}{
 		gvk.WorkloadGroup: {},
 		gvk.Secret:        {},
 	},
+	// Secrets are not skipped for sidecars, as they may hold the credentials of the egress proxy.
 	model.SidecarProxy: {
 		gvk.DestinationRule: {},
 		gvk.WorkloadGroup:   {},
-		gvk.Secret:          {},
 	},
 }
 
@@ -69,6 +70,9 @@ func (l LdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *m
 		return nil, model.DefaultXdsLogDetails, nil
 	}
 	listeners := l.Server.ConfigGenerator.BuildListeners(proxy, push)
+	if features.EnableListenerDrainMetrics && w != nil {
+		generatedListeners(w, listeners, req)
+	}
 	resources := model.Resources{}
 	for _, c := range listeners {
 		resources = append(resources, &discovery.Resource{
@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"hash/fnv"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// hashMessage returns the hash of the deterministic encoding of the message.
+func hashMessage(msg proto.Message) uint64 {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
+
+// fingerprintListener returns the fingerprint of the listener. The filter chains are cleared while hashing the rest
+// of the listener, so the listener must not be shared yet.
+func fingerprintListener(l *listener.Listener) *model.ListenerFingerprint {
+	filterChains, defaultFilterChain := l.FilterChains, l.DefaultFilterChain
+	l.FilterChains, l.DefaultFilterChain = nil, nil
+	fp := &model.ListenerFingerprint{
+		Listener:     hashMessage(l),
+		FilterChains: make(map[uint64]struct{}, len(filterChains)+1),
+	}
+	l.FilterChains, l.DefaultFilterChain = filterChains, defaultFilterChain
+	for _, fc := range filterChains {
+		fp.FilterChains[hashMessage(fc)] = struct{}{}
+	}
+	if defaultFilterChain != nil {
+		fp.FilterChains[hashMessage(defaultFilterChain)] = struct{}{}
+	}
+	return fp
+}
+
+// countListenerDrains returns the number of filter chains of the previous listeners drained by the update to the
+// current listeners: all of them when the rest of their listener changed or the listener was removed, and the changed
+// or removed ones otherwise.
+func countListenerDrains(previous, current map[string]*model.ListenerFingerprint) int {
+	drains := 0
+	for name, prev := range previous {
+		cur := current[name]
+		if cur == nil || cur.Listener != prev.Listener {
+			drains += len(prev.FilterChains)
+			continue
+		}
+		for h := range prev.FilterChains {
+			if _, f := cur.FilterChains[h]; !f {
+				drains++
+			}
+		}
+	}
+	return drains
+}
+
+// multipleTriggers labels the drains of pushes triggered by configs of several kinds, so that they are counted once.
+const multipleTriggers = "multiple"
+
+// listenerPushTrigger returns the kind of the configs updated by the push request, multipleTriggers if they are of
+// several kinds, or UnknownTrigger if there are none.
+func listenerPushTrigger(req *model.PushRequest) string {
+	trigger := ""
+	if req != nil {
+		for key := range req.ConfigsUpdated {
+			if trigger != "" && trigger != key.Kind.Kind {
+				return multipleTriggers
+			}
+			trigger = key.Kind.Kind
+		}
+	}
+	if trigger == "" {
+		return string(model.UnknownTrigger)
+	}
+	return trigger
+}
+
+// generatedListeners keeps the fingerprints of the listeners generated for the proxy watching them until the response
+// sending them is acked.
+func generatedListeners(w *model.WatchedResource, listeners []*listener.Listener, req *model.PushRequest) {
+	fingerprints := make(map[string]*model.ListenerFingerprint, len(listeners))
+	for _, l := range listeners {
+		fingerprints[l.Name] = fingerprintListener(l)
+	}
+	w.PendingListeners = &model.PendingListeners{Fingerprints: fingerprints, Trigger: listenerPushTrigger(req)}
+}
+
+// sentListeners records the nonce of the response sending the pending listeners. Must be called with the proxy lock.
+func sentListeners(w *model.WatchedResource, nonce string) {
+	if w.PendingListeners != nil && w.PendingListeners.Nonce == "" {
+		w.PendingListeners.Nonce = nonce
+	}
+}
+
+// ackedListeners records the filter chains drained by the proxy applying the pending listeners acked with the nonce,
+// and keeps their fingerprints for the next push. Nacked listeners are dropped, as the proxy keeps running the acked
+// ones. Must be called with the proxy lock.
+func ackedListeners(w *model.WatchedResource, nonce string, nack bool) {
+	pending := w.PendingListeners
+	if pending == nil || pending.Nonce != nonce {
+		return
+	}
+	w.PendingListeners = nil
+	if nack {
+		return
+	}
+	previous := w.ListenerFingerprints
+	w.ListenerFingerprints = pending.Fingerprints
+	if previous == nil {
+		return
+	}
+	if drains := countListenerDrains(previous, pending.Fingerprints); drains > 0 {
+		listenerDrains.With(typeTag.Value(pending.Trigger)).RecordInt(int64(drains))
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func testListener(name string, stats string, chains ...string) *listener.Listener {
+	l := &listener.Listener{Name: name, StatPrefix: stats}
+	for _, c := range chains {
+		l.FilterChains = append(l.FilterChains, &listener.FilterChain{Name: c})
+	}
+	return l
+}
+
+func TestListenerDrains(t *testing.T) {
+	cases := []struct {
+		name    string
+		updated []*listener.Listener
+		drains  int
+	}{
+		{
+			name:    "unchanged",
+			updated: []*listener.Listener{testListener("inbound", "", "a", "b"), testListener("outbound", "", "c")},
+		},
+		{
+			name:    "reordered and added filter chains",
+			updated: []*listener.Listener{testListener("inbound", "", "b", "a", "d"), testListener("outbound", "", "c")},
+		},
+		{
+			name:    "changed filter chain",
+			updated: []*listener.Listener{testListener("inbound", "", "a", "b2"), testListener("outbound", "", "c")},
+			drains:  1,
+		},
+		{
+			name:    "changed listener",
+			updated: []*listener.Listener{testListener("inbound", "inbound", "a", "b"), testListener("outbound", "", "c")},
+			drains:  2,
+		},
+		{
+			name:    "removed listener",
+			updated: []*listener.Listener{testListener("inbound", "", "a", "b")},
+			drains:  1,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &model.WatchedResource{}
+			req := &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: "vs"}: {}}}
+			generatedListeners(w, []*listener.Listener{testListener("inbound", "", "a", "b"), testListener("outbound", "", "c")}, req)
+			sentListeners(w, "1")
+			ackedListeners(w, "1", false)
+			previous := w.ListenerFingerprints
+			generatedListeners(w, tt.updated, req)
+			if got := countListenerDrains(previous, w.PendingListeners.Fingerprints); got != tt.drains {
+				t.Errorf("got %d drains, want %d", got, tt.drains)
+			}
+			if len(tt.updated[0].FilterChains) == 0 {
+				t.Errorf("expected the filter chains of the listener to be kept")
+			}
+		})
+	}
+}
+
+func TestListenerDrainsAcked(t *testing.T) {
+	w := &model.WatchedResource{}
+	generatedListeners(w, []*listener.Listener{testListener("inbound", "", "a", "b")}, nil)
+	sentListeners(w, "1")
+	ackedListeners(w, "1", false)
+	acked := w.ListenerFingerprints
+
+	// Nacked listeners are never applied by the proxy, so they are dropped.
+	generatedListeners(w, []*listener.Listener{testListener("inbound", "", "a")}, nil)
+	sentListeners(w, "2")
+	ackedListeners(w, "2", true)
+	if w.PendingListeners != nil || len(w.ListenerFingerprints["inbound"].FilterChains) != len(acked["inbound"].FilterChains) {
+		t.Fatalf("expected the nacked listeners to be dropped")
+	}
+
+	// Listeners generated but not acked yet are not counted.
+	generatedListeners(w, []*listener.Listener{testListener("inbound", "", "c")}, nil)
+	sentListeners(w, "3")
+	ackedListeners(w, "2", false)
+	if w.PendingListeners == nil || len(w.ListenerFingerprints["inbound"].FilterChains) != 2 {
+		t.Fatalf("expected the listeners to stay pending until acked")
+	}
+	ackedListeners(w, "3", false)
+	if w.PendingListeners != nil || len(w.ListenerFingerprints["inbound"].FilterChains) != 1 {
+		t.Fatalf("expected the acked listeners to be kept")
+	}
+}
+
+func TestListenerPushTrigger(t *testing.T) {
+	cases := []struct {
+		name string
+		req  *model.PushRequest
+		want string
+	}{
+		{"no request", nil, string(model.UnknownTrigger)},
+		{"no configs", &model.PushRequest{}, string(model.UnknownTrigger)},
+		{"single kind", &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.VirtualService, Name: "a"}: {},
+			{Kind: gvk.VirtualService, Name: "b"}: {},
+		}}, gvk.VirtualService.Kind},
+		{"several kinds", &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.VirtualService, Name: "a"}:  {},
+			{Kind: gvk.DestinationRule, Name: "b"}: {},
+		}}, multipleTriggers},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listenerPushTrigger(tt.req); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
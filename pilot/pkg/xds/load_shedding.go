@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// Reasons for rejecting new XDS connections.
+const (
+	rejectRateLimit      = "rate_limit"
+	rejectMaxConnections = "max_connections"
+)
+
+// admitConnection rejects a new XDS connection when this instance is over budget, so that the proxy retries with
+// backoff or connects to another instance rather than adding load to this one.
+func (s *DiscoveryServer) admitConnection(ctx context.Context, peerAddr string) error {
+	if err := s.WaitForRequestLimit(ctx); err != nil {
+		log.Warnf("ADS: %q exceeded rate limit: %v", peerAddr, err)
+		xdsRejectedConnections.With(reasonTag.Value(rejectRateLimit)).Increment()
+		return status.Errorf(codes.ResourceExhausted, "request rate limit exceeded: %v", err)
+	}
+	if features.MaxConnections > 0 && s.adsClientCount() >= features.MaxConnections {
+		log.Warnf("ADS: %q rejected, %d connections limit reached", peerAddr, features.MaxConnections)
+		xdsRejectedConnections.With(reasonTag.Value(rejectMaxConnections)).Increment()
+		return status.Errorf(codes.ResourceExhausted, "connections limit of %d reached", features.MaxConnections)
+	}
+	return nil
+}
+
+func newRequestPushLimit(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	return make(chan struct{}, limit)
+}
+
+// waitForRequestPushLimit waits until the response to an XDS request can be generated without exceeding the limit of
+// concurrent responses, and returns the function to call once the response is sent. Unlike the pushes, responses to
+// requests are only limited when PILOT_REQUEST_PUSH_THROTTLE is set, to bound the memory used to generate the
+// initial configuration of all the proxies reconnecting at once.
+func (s *DiscoveryServer) waitForRequestPushLimit(ctx context.Context) (func(), error) {
+	if s.requestPushLimit == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.requestPushLimit <- struct{}{}:
+	default:
+		xdsThrottledRequests.Increment()
+		select {
+		case s.requestPushLimit <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-s.requestPushLimit }, nil
+}
@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+func TestAdmitConnection(t *testing.T) {
+	defaultValue := features.MaxConnections
+	features.MaxConnections = 2
+	defer func() { features.MaxConnections = defaultValue }()
+
+	s := &DiscoveryServer{
+		requestRateLimit: rate.NewLimiter(0, 1),
+		adsClients:       map[string]*Connection{"a": {}},
+	}
+	if err := s.admitConnection(context.Background(), "1.1.1.1"); err != nil {
+		t.Fatalf("expected the connection to be admitted, got %v", err)
+	}
+	s.adsClients["b"] = &Connection{}
+	err := s.admitConnection(context.Background(), "1.1.1.1")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the connection to be rejected, got %v", err)
+	}
+}
+
+func TestWaitForRequestPushLimit(t *testing.T) {
+	s := &DiscoveryServer{}
+	release, err := s.waitForRequestPushLimit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	s.requestPushLimit = newRequestPushLimit(1)
+	release, err = s.waitForRequestPushLimit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.waitForRequestPushLimit(ctx); err == nil {
+		t.Fatalf("expected the response to wait for the limit")
+	}
+	release()
+	release, err = s.waitForRequestPushLimit(ctx)
+	if err != nil {
+		t.Fatalf("expected the response not to wait once the limit is released, got %v", err)
+	}
+	release()
+}
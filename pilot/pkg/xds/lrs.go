@@ -0,0 +1,343 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/features"
+	istiogrpc "istio.io/istio/pilot/pkg/grpc"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+const (
+	// loadReportRetention is how long the load between two localities is kept after it was last reported.
+	loadReportRetention = 30 * time.Minute
+	// maxLoadReportEntries bounds the number of entries tracked by a loadReports.
+	maxLoadReportEntries = 10000
+)
+
+// LoadReportSink receives the load reported by the proxies through the load reporting service, for
+// example to export it to an external system.
+type LoadReportSink interface {
+	// Report is called with the stats of every load report sent by the proxy.
+	Report(proxy *model.Proxy, stats []*endpoint.ClusterStats)
+}
+
+type loadReportKey struct {
+	sourceLocality      string
+	cluster             string
+	destinationLocality string
+}
+
+// loadReport is the load sent to the endpoints of a locality, accumulated across reports.
+type loadReport struct {
+	successfulRequests uint64
+	errorRequests      uint64
+	issuedRequests     uint64
+	time               time.Time
+}
+
+// LoadReportSummary is the load sent by the proxies of a locality to the endpoints of a cluster in a locality.
+type LoadReportSummary struct {
+	SourceLocality      string    `json:"sourceLocality"`
+	Cluster             string    `json:"cluster"`
+	DestinationLocality string    `json:"destinationLocality"`
+	SuccessfulRequests  uint64    `json:"successfulRequests"`
+	ErrorRequests       uint64    `json:"errorRequests"`
+	IssuedRequests      uint64    `json:"issuedRequests"`
+	LastUpdate          time.Time `json:"lastUpdate"`
+}
+
+// loadReports aggregates the load reported by proxies by source locality, cluster and destination
+// locality. Entries which are not reported for a bounded time are dropped.
+type loadReports struct {
+	mu      sync.Mutex
+	entries map[loadReportKey]*loadReport
+	now     func() time.Time
+}
+
+func newLoadReports() *loadReports {
+	return &loadReports{
+		entries: map[loadReportKey]*loadReport{},
+		now:     time.Now,
+	}
+}
+
+// Record adds the load reported by a proxy in sourceLocality.
+func (r *loadReports) Record(sourceLocality string, stats []*endpoint.ClusterStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cs := range stats {
+		for _, ls := range cs.GetUpstreamLocalityStats() {
+			destinationLocality := util.LocalityToString(ls.GetLocality())
+			key := loadReportKey{
+				sourceLocality:      sourceLocality,
+				cluster:             cs.GetClusterName(),
+				destinationLocality: destinationLocality,
+			}
+			report, f := r.entries[key]
+			if !f {
+				if len(r.entries) >= maxLoadReportEntries {
+					r.pruneLocked()
+					if len(r.entries) >= maxLoadReportEntries {
+						log.Debugf("dropping load report for %s: too many entries tracked", cs.GetClusterName())
+						continue
+					}
+				}
+				report = &loadReport{}
+				r.entries[key] = report
+			}
+			report.successfulRequests += ls.GetTotalSuccessfulRequests()
+			report.errorRequests += ls.GetTotalErrorRequests()
+			report.issuedRequests += ls.GetTotalIssuedRequests()
+			report.time = r.now()
+
+			totalLoadReportRequests.With(sourceLocalityTag.Value(sourceLocality),
+				destinationLocalityTag.Value(destinationLocality), typeTag.Value("success")).
+				RecordInt(int64(ls.GetTotalSuccessfulRequests()))
+			totalLoadReportRequests.With(sourceLocalityTag.Value(sourceLocality),
+				destinationLocalityTag.Value(destinationLocality), typeTag.Value("error")).
+				RecordInt(int64(ls.GetTotalErrorRequests()))
+		}
+	}
+}
+
+// pruneLocked removes expired entries. The lock must be held.
+func (r *loadReports) pruneLocked() {
+	cutoff := r.now().Add(-loadReportRetention)
+	for key, report := range r.entries {
+		if report.time.Before(cutoff) {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// Summaries returns the aggregated load, sorted by source locality, cluster and destination locality.
+func (r *loadReports) Summaries() []LoadReportSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	out := make([]LoadReportSummary, 0, len(r.entries))
+	for key, report := range r.entries {
+		out = append(out, LoadReportSummary{
+			SourceLocality:      key.sourceLocality,
+			Cluster:             key.cluster,
+			DestinationLocality: key.destinationLocality,
+			SuccessfulRequests:  report.successfulRequests,
+			ErrorRequests:       report.errorRequests,
+			IssuedRequests:      report.issuedRequests,
+			LastUpdate:          report.time,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SourceLocality != out[j].SourceLocality {
+			return out[i].SourceLocality < out[j].SourceLocality
+		}
+		if out[i].Cluster != out[j].Cluster {
+			return out[i].Cluster < out[j].Cluster
+		}
+		return out[i].DestinationLocality < out[j].DestinationLocality
+	})
+	return out
+}
+
+// StreamLoadStats implements the load reporting service. istiod asks the proxies to report the load of
+// all their clusters, and aggregates the reports by locality.
+func (s *DiscoveryServer) StreamLoadStats(stream lrs.LoadReportingService_StreamLoadStatsServer) error {
+	if !s.IsServerReady() {
+		return status.Error(codes.Unavailable, "server is not ready to serve load reports")
+	}
+
+	ctx := stream.Context()
+	peerAddr := "0.0.0.0"
+	if peerInfo, ok := peer.FromContext(ctx); ok {
+		peerAddr = peerInfo.Addr.String()
+	}
+	ids, err := s.authenticate(ctx)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if req.GetNode() == nil || req.GetNode().GetId() == "" {
+		return status.New(codes.InvalidArgument, "missing node information").Err()
+	}
+	proxy, err := s.initProxyMetadata(req.GetNode())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if features.EnableXDSIdentityCheck && ids != nil {
+		if _, err := checkConnectionIdentity(proxy, ids); err != nil {
+			log.Warnf("Unauthorized LRS: %v with identity %v: %v", peerAddr, ids, err)
+			return status.Newf(codes.PermissionDenied, "authorization failed: %v", err).Err()
+		}
+	}
+	sourceLocality := loadReportSourceLocality(proxy, req.GetNode())
+	log.Debugf("LRS: %s connected from %s in locality %q", proxy.ID, peerAddr, sourceLocality)
+
+	if err := stream.Send(&lrs.LoadStatsResponse{
+		SendAllClusters:       true,
+		LoadReportingInterval: durationpb.New(features.LoadReportingInterval),
+	}); err != nil {
+		return err
+	}
+
+	for {
+		s.recordLoadStats(proxy, sourceLocality, req.GetClusterStats())
+		req, err = stream.Recv()
+		if err != nil {
+			if istiogrpc.IsExpectedGRPCError(err) {
+				log.Debugf("LRS: %s terminated: %v", proxy.ID, err)
+				return nil
+			}
+			log.Warnf("LRS: %s terminated with error: %v", proxy.ID, err)
+			return err
+		}
+	}
+}
+
+func (s *DiscoveryServer) recordLoadStats(proxy *model.Proxy, sourceLocality string, stats []*endpoint.ClusterStats) {
+	if len(stats) == 0 {
+		return
+	}
+	s.loadReports.Record(sourceLocality, stats)
+	for _, sink := range s.LoadReportSinks {
+		sink.Report(proxy, stats)
+	}
+}
+
+// loadReportSourceLocality returns the locality of the reporting proxy, preferring the one sent by Envoy.
+func loadReportSourceLocality(proxy *model.Proxy, node *core.Node) string {
+	if l := util.LocalityToString(node.GetLocality()); l != "" {
+		return l
+	}
+	return model.GetLocalityLabelOrDefault(proxy.Metadata.Labels[model.LocalityLabel], "")
+}
+
+func (s *DiscoveryServer) loadz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.loadReports.Summaries())
+}
+
+// Levels at which the localities of the locality matrix are aggregated.
+const (
+	LocalityLevelRegion  = "region"
+	LocalityLevelZone    = "zone"
+	LocalityLevelSubzone = "subzone"
+)
+
+// LocalityTraffic is the number of requests sent by the proxies of a source locality to the endpoints of a
+// destination locality, and the share of all the requests of the source locality it represents.
+type LocalityTraffic struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	Requests    uint64  `json:"requests"`
+	Share       float64 `json:"share"`
+}
+
+// LocalityMatrix aggregates the load of the summaries by source and destination locality, truncated to the
+// given level. If cluster is set, only the load sent to that cluster is aggregated.
+func LocalityMatrix(summaries []LoadReportSummary, level, cluster string) []LocalityTraffic {
+	type cell struct{ source, destination string }
+	requests := map[cell]uint64{}
+	for _, summary := range summaries {
+		if cluster != "" && summary.Cluster != cluster {
+			continue
+		}
+		c := cell{source: truncateLocality(summary.SourceLocality, level), destination: truncateLocality(summary.DestinationLocality, level)}
+		requests[c] += summary.IssuedRequests
+	}
+	out := make([]LocalityTraffic, 0, len(requests))
+	for c, n := range requests {
+		out = append(out, LocalityTraffic{Source: c.source, Destination: c.destination, Requests: n})
+	}
+	SetLocalityShares(out)
+	return out
+}
+
+// SetLocalityShares sets the share of the requests of its source locality of each entry, and sorts the entries
+// by source and destination locality.
+func SetLocalityShares(traffic []LocalityTraffic) {
+	totals := map[string]uint64{}
+	for _, t := range traffic {
+		totals[t.Source] += t.Requests
+	}
+	for i := range traffic {
+		if total := totals[traffic[i].Source]; total > 0 {
+			traffic[i].Share = float64(traffic[i].Requests) / float64(total)
+		} else {
+			traffic[i].Share = 0
+		}
+	}
+	sort.Slice(traffic, func(i, j int) bool {
+		if traffic[i].Source != traffic[j].Source {
+			return traffic[i].Source < traffic[j].Source
+		}
+		return traffic[i].Destination < traffic[j].Destination
+	})
+}
+
+// truncateLocality truncates a locality to its region, or its region and zone.
+func truncateLocality(locality, level string) string {
+	if locality == "" {
+		return ""
+	}
+	region, zone, _ := model.SplitLocalityLabel(locality)
+	switch level {
+	case LocalityLevelRegion:
+		return region
+	case LocalityLevelZone:
+		if zone == "" {
+			return region
+		}
+		return region + "/" + zone
+	default:
+		return locality
+	}
+}
+
+// localitymatrixz lists the requests sent from each source locality to each destination locality, as reported by
+// the proxies, to verify that locality load balancing keeps the traffic local. The localities are aggregated by
+// ?level=region|zone|subzone, zone by default. With ?cluster=<name>, only the load sent to that cluster is counted.
+func (s *DiscoveryServer) localitymatrixz(w http.ResponseWriter, req *http.Request) {
+	level := req.URL.Query().Get("level")
+	switch level {
+	case "":
+		level = LocalityLevelZone
+	case LocalityLevelRegion, LocalityLevelZone, LocalityLevelSubzone:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "invalid level %q, must be one of %s, %s or %s\n", level,
+			LocalityLevelRegion, LocalityLevelZone, LocalityLevelSubzone)
+		return
+	}
+	writeJSON(w, LocalityMatrix(s.loadReports.Summaries(), level, req.URL.Query().Get("cluster")))
+}
@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/google/go-cmp/cmp"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func localityStats(region, zone string, success, errors uint64) *endpoint.UpstreamLocalityStats {
+	return &endpoint.UpstreamLocalityStats{
+		Locality:                &core.Locality{Region: region, Zone: zone},
+		TotalSuccessfulRequests: success,
+		TotalErrorRequests:      errors,
+		TotalIssuedRequests:     success + errors,
+	}
+}
+
+func TestLoadReports(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newLoadReports()
+	r.now = func() time.Time { return now }
+
+	r.Record("us/a", []*endpoint.ClusterStats{
+		{
+			ClusterName: "outbound|80||foo",
+			UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{
+				localityStats("us", "a", 10, 1),
+				localityStats("us", "b", 5, 0),
+			},
+		},
+	})
+	now = now.Add(time.Minute)
+	r.Record("us/a", []*endpoint.ClusterStats{
+		{
+			ClusterName:           "outbound|80||foo",
+			UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{localityStats("us", "a", 4, 2)},
+		},
+	})
+	r.Record("eu/c", []*endpoint.ClusterStats{
+		{
+			ClusterName:           "outbound|80||foo",
+			UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{localityStats("us", "b", 3, 0)},
+		},
+	})
+
+	expected := []LoadReportSummary{
+		{
+			SourceLocality:      "eu/c",
+			Cluster:             "outbound|80||foo",
+			DestinationLocality: "us/b",
+			SuccessfulRequests:  3,
+			IssuedRequests:      3,
+			LastUpdate:          now,
+		},
+		{
+			SourceLocality:      "us/a",
+			Cluster:             "outbound|80||foo",
+			DestinationLocality: "us/a",
+			SuccessfulRequests:  14,
+			ErrorRequests:       3,
+			IssuedRequests:      17,
+			LastUpdate:          now,
+		},
+		{
+			SourceLocality:      "us/a",
+			Cluster:             "outbound|80||foo",
+			DestinationLocality: "us/b",
+			SuccessfulRequests:  5,
+			IssuedRequests:      5,
+			LastUpdate:          now.Add(-time.Minute),
+		},
+	}
+	if diff := cmp.Diff(expected, r.Summaries()); diff != "" {
+		t.Fatalf("unexpected summaries: %v", diff)
+	}
+
+	now = now.Add(loadReportRetention)
+	if got := r.Summaries(); len(got) != 2 {
+		t.Fatalf("expected the stale entry to be pruned, got %v", got)
+	}
+}
+
+type fakeLoadReportSink struct {
+	proxies []string
+}
+
+func (f *fakeLoadReportSink) Report(proxy *model.Proxy, _ []*endpoint.ClusterStats) {
+	f.proxies = append(f.proxies, proxy.ID)
+}
+
+func TestRecordLoadStats(t *testing.T) {
+	sink := &fakeLoadReportSink{}
+	s := &DiscoveryServer{loadReports: newLoadReports(), LoadReportSinks: []LoadReportSink{sink}}
+	proxy := &model.Proxy{ID: "app.ns"}
+	s.recordLoadStats(proxy, "us/a", nil)
+	s.recordLoadStats(proxy, "us/a", []*endpoint.ClusterStats{
+		{
+			ClusterName:           "outbound|80||foo",
+			UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{localityStats("us", "a", 1, 0)},
+		},
+	})
+	if diff := cmp.Diff([]string{"app.ns"}, sink.proxies); diff != "" {
+		t.Fatalf("unexpected sink reports: %v", diff)
+	}
+	if got := s.loadReports.Summaries(); len(got) != 1 {
+		t.Fatalf("expected one aggregated entry, got %v", got)
+	}
+}
+
+func TestLoadReportSourceLocality(t *testing.T) {
+	proxy := &model.Proxy{Metadata: &model.NodeMetadata{Labels: map[string]string{model.LocalityLabel: "us.a.b"}}}
+	if got := loadReportSourceLocality(proxy, &core.Node{}); got != "us/a/b" {
+		t.Fatalf("expected locality from labels, got %q", got)
+	}
+	node := &core.Node{Locality: &core.Locality{Region: "eu", Zone: "c"}}
+	if got := loadReportSourceLocality(proxy, node); got != "eu/c" {
+		t.Fatalf("expected locality from node, got %q", got)
+	}
+}
+
+func TestLocalityMatrix(t *testing.T) {
+	summaries := []LoadReportSummary{
+		{SourceLocality: "us/a/1", Cluster: "outbound|80||foo", DestinationLocality: "us/a/2", IssuedRequests: 6},
+		{SourceLocality: "us/a/1", Cluster: "outbound|80||foo", DestinationLocality: "us/b/1", IssuedRequests: 2},
+		{SourceLocality: "us/a/2", Cluster: "outbound|80||bar", DestinationLocality: "eu/c/1", IssuedRequests: 2},
+		{SourceLocality: "", Cluster: "outbound|80||foo", DestinationLocality: "us/a", IssuedRequests: 1},
+	}
+
+	expected := []LocalityTraffic{
+		{Source: "", Destination: "us/a", Requests: 1, Share: 1},
+		{Source: "us/a", Destination: "eu/c", Requests: 2, Share: 0.2},
+		{Source: "us/a", Destination: "us/a", Requests: 6, Share: 0.6},
+		{Source: "us/a", Destination: "us/b", Requests: 2, Share: 0.2},
+	}
+	if diff := cmp.Diff(expected, LocalityMatrix(summaries, LocalityLevelZone, "")); diff != "" {
+		t.Fatalf("unexpected zone matrix: %v", diff)
+	}
+
+	expected = []LocalityTraffic{
+		{Source: "us", Destination: "us", Requests: 8, Share: 1},
+	}
+	matrix := LocalityMatrix(summaries[:2], LocalityLevelRegion, "")
+	if diff := cmp.Diff(expected, matrix); diff != "" {
+		t.Fatalf("unexpected region matrix: %v", diff)
+	}
+
+	expected = []LocalityTraffic{
+		{Source: "us/a/2", Destination: "eu/c/1", Requests: 2, Share: 1},
+	}
+	if diff := cmp.Diff(expected, LocalityMatrix(summaries, LocalityLevelSubzone, "outbound|80||bar")); diff != "" {
+		t.Fatalf("unexpected cluster matrix: %v", diff)
+	}
+}
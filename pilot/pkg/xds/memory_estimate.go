@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// Coarse approximations of the memory used by Envoy for each kind of resource. They are meant to catch
+// configuration which is an order of magnitude too large for the proxy, not to predict its actual usage.
+const (
+	envoyBaseMemory = 30 << 20
+	clusterMemory   = 20 << 10
+	// Envoy creates about a hundred stats for each cluster.
+	clusterStatsMemory = 12 << 10
+	endpointMemory     = 2 << 10
+	listenerMemory     = 10 << 10
+	filterChainMemory  = 4 << 10
+	virtualHostMemory  = 1 << 10
+	routeMemory        = 512
+)
+
+// MemoryEstimate is the estimated memory used by Envoy for the configuration generated for a proxy.
+type MemoryEstimate struct {
+	Clusters     int `json:"clusters"`
+	Endpoints    int `json:"endpoints"`
+	Listeners    int `json:"listeners"`
+	FilterChains int `json:"filterChains"`
+	VirtualHosts int `json:"virtualHosts"`
+	Routes       int `json:"routes"`
+	// Bytes is the estimated memory by kind of resource.
+	Bytes      map[string]uint64 `json:"bytes"`
+	TotalBytes uint64            `json:"totalBytes"`
+	// MemoryRequestBytes is the memory request of the proxy, if known.
+	MemoryRequestBytes uint64 `json:"memoryRequestBytes,omitempty"`
+	Warning            string `json:"warning,omitempty"`
+}
+
+// estimateMemory estimates the memory used by Envoy for the configuration. endpoints returns the number of
+// endpoints of a cluster.
+func estimateMemory(clusters []*cluster.Cluster, endpoints func(c *cluster.Cluster) int,
+	listeners []*listener.Listener, routes []*route.RouteConfiguration) MemoryEstimate {
+	e := MemoryEstimate{Clusters: len(clusters), Listeners: len(listeners)}
+	for _, c := range clusters {
+		e.Endpoints += endpoints(c)
+	}
+	for _, l := range listeners {
+		e.FilterChains += len(l.GetFilterChains())
+		if l.GetDefaultFilterChain() != nil {
+			e.FilterChains++
+		}
+	}
+	for _, rc := range routes {
+		e.VirtualHosts += len(rc.GetVirtualHosts())
+		for _, vh := range rc.GetVirtualHosts() {
+			e.Routes += len(vh.GetRoutes())
+		}
+	}
+	e.Bytes = map[string]uint64{
+		"base":         envoyBaseMemory,
+		"clusters":     uint64(e.Clusters) * clusterMemory,
+		"clusterStats": uint64(e.Clusters) * clusterStatsMemory,
+		"endpoints":    uint64(e.Endpoints) * endpointMemory,
+		"listeners":    uint64(e.Listeners)*listenerMemory + uint64(e.FilterChains)*filterChainMemory,
+		"routes":       uint64(e.VirtualHosts)*virtualHostMemory + uint64(e.Routes)*routeMemory,
+	}
+	for _, b := range e.Bytes {
+		e.TotalBytes += b
+	}
+	return e
+}
+
+// setMemoryRequest records the memory request of the proxy, and warns if the estimate exceeds it.
+func (e *MemoryEstimate) setMemoryRequest(request uint64) {
+	e.MemoryRequestBytes = request
+	if request > 0 && e.TotalBytes > request {
+		e.Warning = fmt.Sprintf("estimated memory %s exceeds the memory request %s of the proxy",
+			resource.NewQuantity(int64(e.TotalBytes), resource.BinarySI), resource.NewQuantity(int64(request), resource.BinarySI))
+	}
+}
+
+// proxyMemoryRequest returns the memory request of the proxy, from the override if set or from the
+// sidecar.istio.io/proxyMemory annotation of the pod. It returns 0 if it is not known.
+func proxyMemoryRequest(proxy *model.Proxy, override string) (uint64, error) {
+	request := override
+	if request == "" {
+		request = proxy.Metadata.Annotations[annotation.SidecarProxyMemory.Name]
+	}
+	if request == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(request)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory request %q: %v", request, err)
+	}
+	if q.Sign() < 0 {
+		return 0, fmt.Errorf("invalid memory request %q: must not be negative", request)
+	}
+	return uint64(q.Value()), nil
+}
+
+// endpointCount returns the number of endpoints the proxy is sent for the cluster.
+func (s *DiscoveryServer) endpointCount(proxy *model.Proxy, push *model.PushContext, c *cluster.Cluster) int {
+	la := c.GetLoadAssignment()
+	if c.GetType() == cluster.Cluster_EDS {
+		la = s.generateEndpoints(NewEndpointBuilder(c.Name, proxy, push))
+	}
+	count := 0
+	for _, llb := range la.GetEndpoints() {
+		count += len(llb.GetLbEndpoints())
+	}
+	return count
+}
+
+// memoryz estimates the memory used by Envoy for the configuration generated for a proxy.
+func (s *DiscoveryServer) memoryz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	request, err := proxyMemoryRequest(con.proxy, req.URL.Query().Get("memoryRequest"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	pushReq := &model.PushRequest{Push: s.globalPushContext(), Start: time.Now()}
+	clusters := make([]*cluster.Cluster, 0)
+	clusterResources, _ := s.ConfigGenerator.BuildClusters(con.proxy, pushReq)
+	for _, r := range clusterResources {
+		c := &cluster.Cluster{}
+		if err := r.GetResource().UnmarshalTo(c); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		clusters = append(clusters, c)
+	}
+	routes := make([]*route.RouteConfiguration, 0)
+	routeResources, _ := s.ConfigGenerator.BuildHTTPRoutes(con.proxy, pushReq, con.Routes())
+	for _, r := range routeResources {
+		rc := &route.RouteConfiguration{}
+		if err := r.GetResource().UnmarshalTo(rc); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		routes = append(routes, rc)
+	}
+	listeners := s.ConfigGenerator.BuildListeners(con.proxy, pushReq.Push)
+
+	estimate := estimateMemory(clusters, func(c *cluster.Cluster) int {
+		return s.endpointCount(con.proxy, pushReq.Push, c)
+	}, listeners, routes)
+	estimate.setMemoryRequest(request)
+	writeJSON(w, estimate)
+}
@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	clusters := []*cluster.Cluster{{Name: "a"}, {Name: "b"}}
+	listeners := []*listener.Listener{{
+		FilterChains:       []*listener.FilterChain{{}, {}},
+		DefaultFilterChain: &listener.FilterChain{},
+	}}
+	routes := []*route.RouteConfiguration{{
+		VirtualHosts: []*route.VirtualHost{
+			{Routes: []*route.Route{{}, {}}},
+			{Routes: []*route.Route{{}}},
+		},
+	}}
+	e := estimateMemory(clusters, func(c *cluster.Cluster) int { return 10 }, listeners, routes)
+	if e.Clusters != 2 || e.Endpoints != 20 || e.Listeners != 1 || e.FilterChains != 3 || e.VirtualHosts != 2 || e.Routes != 3 {
+		t.Fatalf("unexpected counts: %+v", e)
+	}
+	want := uint64(envoyBaseMemory + 2*clusterMemory + 2*clusterStatsMemory + 20*endpointMemory +
+		listenerMemory + 3*filterChainMemory + 2*virtualHostMemory + 3*routeMemory)
+	if e.TotalBytes != want {
+		t.Fatalf("got total %d, want %d", e.TotalBytes, want)
+	}
+
+	e.setMemoryRequest(e.TotalBytes)
+	if e.Warning != "" {
+		t.Fatalf("unexpected warning: %v", e.Warning)
+	}
+	e.setMemoryRequest(1 << 20)
+	if !strings.Contains(e.Warning, "exceeds the memory request 1Mi") {
+		t.Fatalf("expected warning, got %q", e.Warning)
+	}
+}
+
+func TestProxyMemoryRequest(t *testing.T) {
+	proxy := &model.Proxy{Metadata: &model.NodeMetadata{
+		Annotations: map[string]string{"sidecar.istio.io/proxyMemory": "128Mi"},
+	}}
+	cases := []struct {
+		name     string
+		proxy    *model.Proxy
+		override string
+		want     uint64
+		wantErr  bool
+	}{
+		{name: "annotation", proxy: proxy, want: 128 << 20},
+		{name: "override", proxy: proxy, override: "1Gi", want: 1 << 30},
+		{name: "unknown", proxy: &model.Proxy{Metadata: &model.NodeMetadata{}}},
+		{name: "invalid", proxy: proxy, override: "lots", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := proxyMemoryRequest(tt.proxy, tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: api
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  addresses:
+  - 240.240.0.1
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+  - address: 10.0.0.2
+`})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+
+	get := func(query string, wantCode int) MemoryEstimate {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/debug/memoryz?"+query, nil)
+		http.HandlerFunc(s.Discovery.memoryz).ServeHTTP(rr, req)
+		if rr.Code != wantCode {
+			t.Fatalf("got code %d, want %d: %s", rr.Code, wantCode, rr.Body.String())
+		}
+		e := MemoryEstimate{}
+		if wantCode == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return e
+	}
+
+	e := get("proxyID=test.default", http.StatusOK)
+	if e.Clusters == 0 || e.Endpoints < 2 || e.Listeners == 0 || e.TotalBytes <= envoyBaseMemory {
+		t.Fatalf("unexpected estimate: %+v", e)
+	}
+	if e.Warning != "" || e.MemoryRequestBytes != 0 {
+		t.Fatalf("unexpected warning without memory request: %+v", e)
+	}
+	if e := get("proxyID=test.default&memoryRequest=1Mi", http.StatusOK); e.Warning == "" {
+		t.Fatalf("expected warning for a small memory request: %+v", e)
+	}
+	get("proxyID=test.default&memoryRequest=lots", http.StatusBadRequest)
+	get("proxyID=not-found", http.StatusNotFound)
+}
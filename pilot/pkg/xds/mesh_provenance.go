@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// ConfigProvenance is the source of each field of the mesh config, and of the effective ProxyConfig of a proxy.
+type ConfigProvenance struct {
+	MeshConfig  []mesh.FieldSource `json:"meshConfig"`
+	ProxyID     string             `json:"proxyID,omitempty"`
+	ProxyConfig []mesh.FieldSource `json:"proxyConfig,omitempty"`
+}
+
+// meshProvenance returns the layer supplying each field of the mesh config: the defaults, the shared mesh config or
+// the revision mesh config. If a proxyID is given, the fields of the effective ProxyConfig of the proxy are added,
+// with the ProxyConfig resource, annotation or mesh config they come from.
+func (s *DiscoveryServer) meshProvenance(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if proxyID != "" && con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+
+	meshConfig := s.Env.Mesh()
+	var layers []mesh.Layer
+	if lw, ok := s.Env.Watcher.(mesh.LayeredWatcher); ok {
+		layers = lw.Layers()
+	}
+	meshSources, err := mesh.MeshConfigFieldSources(meshConfig, layers)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	out := ConfigProvenance{MeshConfig: meshSources}
+	if con == nil {
+		writeJSON(w, out)
+		return
+	}
+
+	// Fields set by no ProxyConfig come from the default config of the mesh config.
+	defaultConfigSources := map[string]string{}
+	for _, f := range meshSources {
+		defaultConfigSources[f.Field] = f.Source
+	}
+	fallback := func(field string) string {
+		source := defaultConfigSources["defaultConfig."+field]
+		if source == "" || source == mesh.DefaultSource {
+			return mesh.DefaultSource
+		}
+		return "mesh config defaultConfig (" + source + ")"
+	}
+
+	push := s.globalPushContext()
+	pc := push.ProxyConfigs.EffectiveProxyConfig(con.proxy.Metadata, meshConfig)
+	if pc == nil {
+		writeJSON(w, out)
+		return
+	}
+	effective, err := gogoprotomarshal.ToJSONMap(pc)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	var proxyLayers []mesh.FieldLayer
+	for _, l := range push.ProxyConfigs.EffectiveProxyConfigSources(con.proxy.Metadata) {
+		fields, err := gogoprotomarshal.ToJSONMap(l.Config)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		proxyLayers = append(proxyLayers, mesh.FieldLayer{Source: l.Source, Fields: fields})
+	}
+	out.ProxyID = proxyID
+	if out.ProxyConfig, err = mesh.FieldSources(effective, proxyLayers, fallback); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	writeJSON(w, out)
+}
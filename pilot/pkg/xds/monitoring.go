@@ -14,6 +14,7 @@
 package xds
 
 import (
+	"strings"
 	"sync"
 	"time"
 
@@ -26,10 +27,15 @@ import (
 )
 
 var (
-	errTag     = monitoring.MustCreateLabel("err")
-	nodeTag    = monitoring.MustCreateLabel("node")
-	typeTag    = monitoring.MustCreateLabel("type")
-	versionTag = monitoring.MustCreateLabel("version")
+	errTag         = monitoring.MustCreateLabel("err")
+	nodeTag        = monitoring.MustCreateLabel("node")
+	typeTag        = monitoring.MustCreateLabel("type")
+	versionTag     = monitoring.MustCreateLabel("version")
+	clusterTag     = monitoring.MustCreateLabel("cluster")
+	trustDomainTag = monitoring.MustCreateLabel("trust_domain")
+	proxyTypeTag   = monitoring.MustCreateLabel("proxy_type")
+	responseTag    = monitoring.MustCreateLabel("response")
+	reasonTag      = monitoring.MustCreateLabel("reason")
 
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
@@ -65,6 +71,18 @@ var (
 		monitoring.WithLabels(typeTag),
 	)
 
+	listenerDrains = monitoring.NewSum(
+		"pilot_xds_listener_drains",
+		"Total number of listener filter chains changed or removed by pushes acked by the proxies, whose connections "+
+			"are drained by the proxies, by kind of the configs triggering the pushes.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	routeValidationFailures = monitoring.NewSum(
+		"pilot_xds_route_validation_failures",
+		"Total number of invalid route configurations generated for gateways.",
+	)
+
 	// Number of delayed pushes. Currently this happens only when the last push has not been ACKed
 	totalDelayedPushes = monitoring.NewSum(
 		"pilot_xds_delayed_pushes_total",
@@ -80,12 +98,50 @@ var (
 		monitoring.WithLabels(typeTag),
 	)
 
+	xdsRejectedConnections = monitoring.NewSum(
+		"pilot_xds_rejected_connections_total",
+		"Total number of XDS connections rejected by pilot to shed load, by reason.",
+		monitoring.WithLabels(reasonTag),
+	)
+
+	xdsThrottledRequests = monitoring.NewSum(
+		"pilot_xds_throttled_requests_total",
+		"Total number of XDS requests whose response waited for PILOT_REQUEST_PUSH_THROTTLE.",
+	)
+
+	xdsUnauthorizedResources = monitoring.NewSum(
+		"pilot_xds_unauthorized_resources_total",
+		"Total number of XDS resources requested by proxies outside of their Sidecar scope, and ignored.",
+		monitoring.WithLabels(typeTag),
+	)
+
 	xdsExpiredNonce = monitoring.NewSum(
 		"pilot_xds_expired_nonce",
 		"Total number of XDS requests with an expired nonce.",
 		monitoring.WithLabels(typeTag),
 	)
 
+	totalOutlierEjections = monitoring.NewSum(
+		"pilot_outlier_ejections_total",
+		"Total number of endpoint ejections by outlier detection, as reported by the proxies.",
+		monitoring.WithLabels(clusterTag, typeTag),
+	)
+
+	totalHealthCheckEjections = monitoring.NewSum(
+		"pilot_health_check_ejections_total",
+		"Total number of endpoints marked unhealthy by active health checks, as reported by the proxies.",
+		monitoring.WithLabels(clusterTag, typeTag),
+	)
+
+	sourceLocalityTag      = monitoring.MustCreateLabel("source_locality")
+	destinationLocalityTag = monitoring.MustCreateLabel("destination_locality")
+
+	totalLoadReportRequests = monitoring.NewSum(
+		"pilot_lrs_upstream_requests_total",
+		"Total number of upstream requests, as reported by the proxies through the load reporting service.",
+		monitoring.WithLabels(sourceLocalityTag, destinationLocalityTag, typeTag),
+	)
+
 	monServices = monitoring.NewGauge(
 		"pilot_services",
 		"Total services known to pilot.",
@@ -101,6 +157,15 @@ var (
 	xdsClientTrackerMutex = &sync.Mutex{}
 	xdsClientTracker      = make(map[string]float64)
 
+	// xdsTrustDomainClients tracks how many workloads still present an identity in the previous trust domain
+	// during a trust domain migration.
+	xdsTrustDomainClients = monitoring.NewGauge(
+		"pilot_xds_trust_domain",
+		"Number of endpoints connected to this pilot using XDS, by the trust domain of their verified identity.",
+		monitoring.WithLabels(trustDomainTag),
+	)
+	xdsTrustDomainTracker = make(map[string]float64)
+
 	xdsResponseWriteTimeouts = monitoring.NewSum(
 		"pilot_xds_write_timeout",
 		"Pilot XDS response write timeouts.",
@@ -140,9 +205,12 @@ var (
 	// only supported dimension is millis, unfortunately. default to unitdimensionless.
 	proxiesQueueTime = monitoring.NewDistribution(
 		"pilot_proxy_queue_time",
-		"Time in seconds, a proxy is in the push queue before being dequeued.",
+		"Time in seconds, a proxy is in the push queue before being dequeued, by type of push (full or incremental).",
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(typeTag),
 	)
+	fullPushQueueTime        = proxiesQueueTime.With(typeTag.Value("full"))
+	incrementalPushQueueTime = proxiesQueueTime.With(typeTag.Value("incremental"))
 
 	pushTriggers = monitoring.NewSum(
 		"pilot_push_triggers",
@@ -178,6 +246,27 @@ var (
 		"Total number of failures to fetch SDS key and certificate.",
 	)
 
+	pilotSDSCertificatesExpiring = monitoring.NewSum(
+		"pilot_sds_certificates_expiring_total",
+		"Total number of SDS certificates which were not rotated before reaching the expiry push window.",
+	)
+
+	xdsConfigStaleness = monitoring.NewGauge(
+		"pilot_xds_config_staleness_seconds",
+		"Longest time in seconds a connected proxy has been running on older config than the last config sent to it, "+
+			"because it did not ack it yet or rejected it.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	// only supported dimension is millis, unfortunately. default to unitdimensionless.
+	xdsAckTime = monitoring.NewDistribution(
+		"pilot_xds_ack_time",
+		"Time in seconds between pilot sending a configuration and the proxy acking or rejecting it, "+
+			"by xDS type, type of proxy and response (ack or nack).",
+		[]float64{.01, .1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(typeTag, proxyTypeTag, responseTag),
+	)
+
 	inboundConfigUpdates  = inboundUpdates.With(typeTag.Value("config"))
 	inboundEDSUpdates     = inboundUpdates.With(typeTag.Value("eds"))
 	inboundServiceUpdates = inboundUpdates.With(typeTag.Value("svc"))
@@ -202,6 +291,17 @@ func recordXDSClients(version string, delta float64) {
 	xdsClients.With(versionTag.Value(version)).Record(xdsClientTracker[version])
 }
 
+func recordXDSTrustDomainClients(proxy *model.Proxy, delta float64) {
+	if proxy.VerifiedIdentity == nil {
+		return
+	}
+	td := proxy.VerifiedIdentity.TrustDomain
+	xdsClientTrackerMutex.Lock()
+	defer xdsClientTrackerMutex.Unlock()
+	xdsTrustDomainTracker[td] += delta
+	xdsTrustDomainClients.With(trustDomainTag.Value(td)).Record(xdsTrustDomainTracker[td])
+}
+
 // triggerMetric is a precomputed monitoring.Metric for each trigger type. This saves on a lot of allocations
 var triggerMetric = map[model.TriggerReason]monitoring.Metric{
 	model.EndpointUpdate:  pushTriggers.With(typeTag.Value(string(model.EndpointUpdate))),
@@ -218,6 +318,14 @@ var triggerMetric = map[model.TriggerReason]monitoring.Metric{
 	model.ClusterUpdate:   pushTriggers.With(typeTag.Value(string(model.ClusterUpdate))),
 }
 
+func recordProxyQueueTime(req *model.PushRequest) {
+	if req.Full {
+		fullPushQueueTime.Record(time.Since(req.Start).Seconds())
+	} else {
+		incrementalPushQueueTime.Record(time.Since(req.Start).Seconds())
+	}
+}
+
 func recordPushTriggers(reasons ...model.TriggerReason) {
 	for _, r := range reasons {
 		t, f := triggerMetric[r]
@@ -271,6 +379,54 @@ func incrementXDSRejects(xdsType string, node, errCode string) {
 	}
 }
 
+// stalenessTypes are the xDS types always reported by the config staleness metric, so that it drops back to
+// zero once the proxies acked their config.
+var stalenessTypes = []string{v3.ClusterType, v3.ListenerType, v3.RouteType, v3.EndpointType}
+
+// recordConfigStaleness records the longest config staleness of the given connections for each xDS type.
+func recordConfigStaleness(clients []*Connection, now time.Time) {
+	staleness := map[string]time.Duration{}
+	for _, typeURL := range stalenessTypes {
+		staleness[v3.GetMetricType(typeURL)] = 0
+	}
+	for _, con := range clients {
+		if con.proxy == nil {
+			continue
+		}
+		con.proxy.RLock()
+		for typeURL, w := range con.proxy.WatchedResources {
+			if w.StaleSince.IsZero() || strings.HasPrefix(typeURL, v3.DebugType) {
+				continue
+			}
+			metricType := v3.GetMetricType(typeURL)
+			if d := now.Sub(w.StaleSince); d > staleness[metricType] {
+				staleness[metricType] = d
+			}
+		}
+		con.proxy.RUnlock()
+	}
+	for metricType, d := range staleness {
+		xdsConfigStaleness.With(typeTag.Value(metricType)).Record(d.Seconds())
+	}
+}
+
+// recordAckTime records the time between sending a configuration of the given type to a proxy and the proxy
+// acking or rejecting it.
+func recordAckTime(xdsType string, proxyType model.NodeType, nack bool, duration time.Duration) {
+	if strings.HasPrefix(xdsType, v3.DebugType) {
+		return
+	}
+	response := "ack"
+	if nack {
+		response = "nack"
+	}
+	xdsAckTime.With(
+		typeTag.Value(v3.GetMetricType(xdsType)),
+		proxyTypeTag.Value(string(proxyType)),
+		responseTag.Value(response),
+	).Record(duration.Seconds())
+}
+
 func recordSendTime(duration time.Duration) {
 	sendTime.Record(duration.Seconds())
 }
@@ -287,9 +443,15 @@ func init() {
 		ldsReject,
 		rdsReject,
 		xdsExpiredNonce,
+		xdsRejectedConnections,
+		xdsUnauthorizedResources,
+		xdsThrottledRequests,
 		totalXDSRejects,
+		listenerDrains,
+		routeValidationFailures,
 		monServices,
 		xdsClients,
+		xdsTrustDomainClients,
 		xdsResponseWriteTimeouts,
 		pushes,
 		pushTime,
@@ -303,6 +465,12 @@ func init() {
 		totalDelayedPushes,
 		totalDelayedPushTimeouts,
 		pilotSDSCertificateErrors,
+		pilotSDSCertificatesExpiring,
 		configSizeBytes,
+		totalOutlierEjections,
+		totalHealthCheckEjections,
+		totalLoadReportRequests,
+		xdsConfigStaleness,
+		xdsAckTime,
 	)
 }
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// maxReportedServices bounds the number of services tracked by an mtlsReports.
+const maxReportedServices = 10000
+
+// mtlsReport is the inbound traffic of a service, as last reported by a proxy.
+type mtlsReport struct {
+	// mtls and plaintext are the cumulative counts of requests and connections reported by the proxy.
+	mtls      float64
+	plaintext float64
+	// lastPlaintext is the last time the plaintext count of the proxy was seen increasing.
+	lastPlaintext time.Time
+	time          time.Time
+}
+
+// MTLSCompatibilitySummary is the inbound traffic of a service by connection security, aggregated across the
+// proxies reporting it.
+type MTLSCompatibilitySummary struct {
+	Service string `json:"service"`
+	// MTLS and Plaintext are the cumulative counts of requests and connections accepted over mTLS and in plaintext
+	// by the reporting proxies.
+	MTLS      float64 `json:"mtls"`
+	Plaintext float64 `json:"plaintext"`
+	// ReportingProxies is the number of proxies which reported on the service.
+	ReportingProxies int `json:"reportingProxies"`
+	// LastPlaintext is the last time a proxy was seen accepting plaintext traffic for the service. It is unset
+	// if no plaintext traffic was seen.
+	LastPlaintext *time.Time `json:"lastPlaintext,omitempty"`
+	LastUpdate    time.Time  `json:"lastUpdate"`
+}
+
+// mtlsReports aggregates the inbound traffic of services by connection security, as reported by proxies, to
+// tell whether plaintext traffic is still accepted in PERMISSIVE mode. Only the latest report of each proxy is
+// kept, for a bounded time.
+type mtlsReports struct {
+	mu       sync.Mutex
+	services map[string]map[string]mtlsReport
+	now      func() time.Time
+}
+
+func newMTLSReports() *mtlsReports {
+	return &mtlsReports{
+		services: map[string]map[string]mtlsReport{},
+		now:      time.Now,
+	}
+}
+
+// Record stores the cumulative inbound traffic of a service as reported by a proxy.
+func (r *mtlsReports) Record(proxyID, service string, mtls, plaintext float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	reports, f := r.services[service]
+	if !f {
+		if len(r.services) >= maxReportedServices {
+			r.pruneLocked()
+			if len(r.services) >= maxReportedServices {
+				log.Debugf("dropping mTLS report for service %s: too many services tracked", service)
+				return
+			}
+		}
+		reports = map[string]mtlsReport{}
+		r.services[service] = reports
+	}
+	prev, f := reports[proxyID]
+	report := mtlsReport{mtls: mtls, plaintext: plaintext, lastPlaintext: prev.lastPlaintext, time: now}
+	// The plaintext count increased, or this is the first report or the counters were reset by a restart of the
+	// proxy: the plaintext traffic may be recent. A reset without plaintext traffic keeps the last one seen.
+	if plaintext > 0 && (!f || plaintext != prev.plaintext) {
+		report.lastPlaintext = now
+	}
+	reports[proxyID] = report
+}
+
+// pruneLocked removes expired reports. The lock must be held.
+func (r *mtlsReports) pruneLocked() {
+	cutoff := r.now().Add(-endpointReportRetention)
+	for service, reports := range r.services {
+		for proxy, report := range reports {
+			if report.time.Before(cutoff) {
+				delete(reports, proxy)
+			}
+		}
+		if len(reports) == 0 {
+			delete(r.services, service)
+		}
+	}
+}
+
+// Summaries returns the aggregated inbound traffic of the reported services, sorted by service. If service is
+// set, only that service is returned.
+func (r *mtlsReports) Summaries(service string) []MTLSCompatibilitySummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	out := make([]MTLSCompatibilitySummary, 0, len(r.services))
+	for name, reports := range r.services {
+		if service != "" && name != service {
+			continue
+		}
+		s := MTLSCompatibilitySummary{Service: name, ReportingProxies: len(reports)}
+		for _, report := range reports {
+			s.MTLS += report.mtls
+			s.Plaintext += report.plaintext
+			if report.time.After(s.LastUpdate) {
+				s.LastUpdate = report.time
+			}
+			if !report.lastPlaintext.IsZero() && (s.LastPlaintext == nil || report.lastPlaintext.After(*s.LastPlaintext)) {
+				t := report.lastPlaintext
+				s.LastPlaintext = &t
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Service < out[j].Service
+	})
+	return out
+}
+
+// recordMTLSReports stores the inbound traffic of services reported by the agent of a proxy.
+func (s *DiscoveryServer) recordMTLSReports(proxy *model.Proxy, req *discovery.DiscoveryRequest) {
+	for _, a := range req.GetErrorDetail().GetDetails() {
+		report := &structpb.Struct{}
+		if err := a.UnmarshalTo(report); err != nil {
+			log.Debugf("ADS: invalid mTLS report from %s: %v", proxy.ID, err)
+			continue
+		}
+		fields := report.GetFields()
+		service := fields["service"].GetStringValue()
+		if service == "" {
+			continue
+		}
+		s.mtlsReports.Record(proxy.ID, service, fields["mtls"].GetNumberValue(), fields["plaintext"].GetNumberValue())
+	}
+}
+
+// mtlsz lists the inbound traffic of services by connection security, as reported by the agents of their proxies.
+// A service which accepted no plaintext traffic for long enough can be switched to STRICT mode. With
+// ?service=<hostname>, only that service is listed.
+func (s *DiscoveryServer) mtlsz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.mtlsReports.Summaries(req.URL.Query().Get("service")))
+}
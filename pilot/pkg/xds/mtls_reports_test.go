@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/google/go-cmp/cmp"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	any "google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestMTLSReports(t *testing.T) {
+	start := time.Unix(1000, 0)
+	now := start
+	r := newMTLSReports()
+	r.now = func() time.Time { return now }
+
+	r.Record("a", "foo.default.svc.cluster.local", 10, 2)
+	r.Record("b", "foo.default.svc.cluster.local", 5, 0)
+	r.Record("a", "bar.default.svc.cluster.local", 3, 0)
+	now = now.Add(time.Minute)
+	// No new plaintext traffic.
+	r.Record("a", "foo.default.svc.cluster.local", 20, 2)
+	r.Record("a", "bar.default.svc.cluster.local", 6, 0)
+	// The proxy restarted without receiving plaintext traffic since: its last plaintext traffic is kept.
+	r.Record("a", "baz.default.svc.cluster.local", 1, 1)
+	r.Record("a", "baz.default.svc.cluster.local", 2, 0)
+	now = now.Add(time.Minute)
+	// The proxy restarted, its counters were reset.
+	r.Record("b", "foo.default.svc.cluster.local", 1, 1)
+
+	restart := now
+	lastPlaintext := start.Add(time.Minute)
+	want := []MTLSCompatibilitySummary{
+		{
+			Service:          "bar.default.svc.cluster.local",
+			MTLS:             6,
+			ReportingProxies: 1,
+			LastUpdate:       start.Add(time.Minute),
+		},
+		{
+			Service:          "baz.default.svc.cluster.local",
+			MTLS:             2,
+			ReportingProxies: 1,
+			LastPlaintext:    &lastPlaintext,
+			LastUpdate:       start.Add(time.Minute),
+		},
+		{
+			Service:          "foo.default.svc.cluster.local",
+			MTLS:             21,
+			Plaintext:        3,
+			ReportingProxies: 2,
+			LastPlaintext:    &restart,
+			LastUpdate:       now,
+		},
+	}
+	if diff := cmp.Diff(want, r.Summaries("")); diff != "" {
+		t.Fatalf("unexpected summaries: %v", diff)
+	}
+	if diff := cmp.Diff(want[2:], r.Summaries("foo.default.svc.cluster.local")); diff != "" {
+		t.Fatalf("unexpected filtered summaries: %v", diff)
+	}
+
+	// Reports expire after the retention period.
+	now = now.Add(endpointReportRetention)
+	r.Record("a", "bar.default.svc.cluster.local", 6, 1)
+	latest := now
+	want = []MTLSCompatibilitySummary{
+		{
+			Service:          "bar.default.svc.cluster.local",
+			MTLS:             6,
+			Plaintext:        1,
+			ReportingProxies: 1,
+			LastPlaintext:    &latest,
+			LastUpdate:       now,
+		},
+	}
+	now = now.Add(time.Second)
+	if diff := cmp.Diff(want, r.Summaries("")); diff != "" {
+		t.Fatalf("unexpected summaries after expiry: %v", diff)
+	}
+}
+
+func TestRecordMTLSReports(t *testing.T) {
+	s := &DiscoveryServer{mtlsReports: newMTLSReports()}
+	report, err := structpb.NewStruct(map[string]interface{}{
+		"service":   "foo.default.svc.cluster.local",
+		"mtls":      10,
+		"plaintext": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := any.New(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := any.New(&structpb.Struct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.recordMTLSReports(&model.Proxy{ID: "a"}, &discovery.DiscoveryRequest{
+		ErrorDetail: &google_rpc.Status{Details: []*any.Any{a, invalid}},
+	})
+	got := s.mtlsReports.Summaries("")
+	if len(got) != 1 || got[0].Service != "foo.default.svc.cluster.local" || got[0].MTLS != 10 ||
+		got[0].Plaintext != 1 || got[0].LastPlaintext == nil {
+		t.Fatalf("unexpected summaries: %+v", got)
+	}
+}
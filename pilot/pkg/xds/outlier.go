@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/data/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// recordOutlierEvents stores the outlier detection events reported by the agent of a proxy.
+func (s *DiscoveryServer) recordOutlierEvents(proxy *model.Proxy, req *discovery.DiscoveryRequest) {
+	for _, a := range req.GetErrorDetail().GetDetails() {
+		event := &cluster.OutlierDetectionEvent{}
+		if err := a.UnmarshalTo(event); err != nil {
+			log.Debugf("ADS: invalid outlier detection event from %s: %v", proxy.ID, err)
+			continue
+		}
+		// Ejections which are not enforced are only logged by Envoy, the endpoint still receives traffic.
+		ejected := event.Action == cluster.Action_EJECT && event.Enforced
+		if ejected {
+			totalOutlierEjections.With(clusterTag.Value(event.ClusterName), typeTag.Value(event.Type.String())).Increment()
+			if is5xxEjection(event.Type) {
+				s.reportStagedRolloutError(proxy)
+			}
+		}
+		s.outlierReports.Record(proxy.ID, event.ClusterName, event.UpstreamUrl, ejected, event.Type.String())
+	}
+}
+
+// is5xxEjection returns whether the ejection type is caused by 5xx errors.
+func is5xxEjection(t cluster.OutlierEjectionType) bool {
+	switch t {
+	case cluster.OutlierEjectionType_CONSECUTIVE_5XX, cluster.OutlierEjectionType_CONSECUTIVE_GATEWAY_FAILURE,
+		cluster.OutlierEjectionType_SUCCESS_RATE, cluster.OutlierEjectionType_FAILURE_PERCENTAGE:
+		return true
+	}
+	return false
+}
+
+// outlierz lists the endpoints ejected by the outlier detection of proxies, as reported by their agents.
+// With ?all=true, endpoints which are not currently ejected by any proxy are included as well.
+func (s *DiscoveryServer) outlierz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.outlierReports.Summaries(req.URL.Query().Get("all") != "true"))
+}
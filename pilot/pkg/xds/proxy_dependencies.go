@@ -23,7 +23,8 @@ import (
 // configKindAffectedProxyTypes contains known config types which may affect certain node types.
 var configKindAffectedProxyTypes = map[config.GroupVersionKind][]model.NodeType{
 	gvk.Gateway: {model.Router},
-	gvk.Secret:  {model.Router},
+	// Sidecars depend on the Secrets of the credentials of their egress proxy and of their DestinationRules.
+	gvk.Secret:  {model.Router, model.SidecarProxy},
 	gvk.Sidecar: {model.SidecarProxy},
 }
 
@@ -32,6 +32,7 @@ func TestProxyNeedsPush(t *testing.T) {
 		drName      = "dr1"
 		vsName      = "vs1"
 		scName      = "sc1"
+		secretName  = "secret1"
 		nsName      = "ns1"
 		nsRoot      = "rootns"
 		generalName = "name1"
@@ -54,6 +55,7 @@ func TestProxyNeedsPush(t *testing.T) {
 
 	sidecarScopeKindNames := map[config.GroupVersionKind]string{
 		gvk.ServiceEntry: svcName, gvk.VirtualService: vsName, gvk.DestinationRule: drName, gvk.Sidecar: scName,
+		gvk.Secret: secretName,
 	}
 	for kind, name := range sidecarScopeKindNames {
 		sidecar.SidecarScope.AddConfigDependencies(model.ConfigKey{Kind: kind, Name: name, Namespace: nsName})
@@ -0,0 +1,345 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// maxPushSnapshotProxies bounds the number of pushed proxies recorded by a push snapshot.
+const maxPushSnapshotProxies = 10000
+
+// pushSnapshot is the digest of the PushContext of a full push: the configs which changed since the previous push,
+// the services it was built from, and the proxies which were pushed with it, including by the incremental pushes
+// which followed. The configs themselves are not retained, to bound the memory.
+type pushSnapshot struct {
+	id      int
+	version string
+	time    time.Time
+	reasons []model.TriggerReason
+	// configsUpdated are the configs whose change triggered the push, as kind/namespace/name.
+	configsUpdated []string
+	// addedConfigs, removedConfigs and modifiedConfigs are the configs which changed since the previous push, as
+	// kind/namespace/name.
+	addedConfigs    []string
+	removedConfigs  []string
+	modifiedConfigs []string
+	// configCount is the number of configs of the push.
+	configCount int
+	// services are the services, as namespace/hostname.
+	services map[string]struct{}
+	meshHash uint64
+	proxies  map[string]struct{}
+	// droppedProxies counts the pushed proxies not recorded once maxPushSnapshotProxies is reached.
+	droppedProxies int
+}
+
+// PushSnapshotSummary describes a retained push.
+type PushSnapshotSummary struct {
+	ID          int                   `json:"id"`
+	PushVersion string                `json:"pushVersion"`
+	Time        time.Time             `json:"time"`
+	Reasons     []model.TriggerReason `json:"reasons,omitempty"`
+	// ConfigsUpdated are the configs whose change triggered the push. It is empty for pushes of all the configs.
+	ConfigsUpdated []string `json:"configsUpdated,omitempty"`
+	Configs        int      `json:"configs"`
+	Services       int      `json:"services"`
+	PushedProxies  int      `json:"pushedProxies"`
+}
+
+// PushDiff is what changed between two consecutive pushes, and which proxies were pushed the later one.
+type PushDiff struct {
+	From              PushSnapshotSummary `json:"from"`
+	To                PushSnapshotSummary `json:"to"`
+	AddedConfigs      []string            `json:"addedConfigs,omitempty"`
+	RemovedConfigs    []string            `json:"removedConfigs,omitempty"`
+	ModifiedConfigs   []string            `json:"modifiedConfigs,omitempty"`
+	AddedServices     []string            `json:"addedServices,omitempty"`
+	RemovedServices   []string            `json:"removedServices,omitempty"`
+	MeshConfigChanged bool                `json:"meshConfigChanged"`
+	// PushedProxies are the IDs of the proxies pushed with the later push. Proxies which did not need the push,
+	// for example because their Sidecar scope does not import the changed configs, are not listed.
+	PushedProxies []string `json:"pushedProxies"`
+	// DroppedProxies counts the pushed proxies which were not recorded, to bound the memory.
+	DroppedProxies int `json:"droppedProxies,omitempty"`
+}
+
+// pushHistory retains the snapshots of the last full pushes, for post-incident analysis of what changed in the
+// mesh and which proxies received the change.
+type pushHistory struct {
+	mu        sync.RWMutex
+	size      int
+	nextID    int
+	snapshots []*pushSnapshot
+	// configs are the resource versions of the configs of the last push, by kind/namespace/name. Nil until the first
+	// push is recorded.
+	configs map[string]string
+}
+
+func newPushHistory(size int) *pushHistory {
+	return &pushHistory{size: size, nextID: 1}
+}
+
+// newPushSnapshot takes the snapshot of the services of the push context. The changes of the configs are recorded
+// when the snapshot is added to the history.
+func newPushSnapshot(push *model.PushContext, req *model.PushRequest) *pushSnapshot {
+	snap := &pushSnapshot{
+		version:  push.PushVersion,
+		time:     time.Now(),
+		services: map[string]struct{}{},
+		proxies:  map[string]struct{}{},
+	}
+	if req != nil {
+		snap.reasons = append(snap.reasons, req.Reason...)
+		for key := range req.ConfigsUpdated {
+			snap.configsUpdated = append(snap.configsUpdated, key.String())
+		}
+		sort.Strings(snap.configsUpdated)
+	}
+	for _, svc := range push.Services(nil) {
+		snap.services[svc.Attributes.Namespace+"/"+string(svc.Hostname)] = struct{}{}
+	}
+	if push.Mesh != nil {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(push.Mesh.String()))
+		snap.meshHash = h.Sum64()
+	}
+	return snap
+}
+
+// add retains the snapshot, dropping the oldest one if the history is full. The configs updated by the push are read
+// from the store to record what changed since the previous push. All the configs are only listed for the first push
+// and for the pushes of all the configs, such as after a change of the mesh config.
+func (h *pushHistory) add(snap *pushSnapshot, store model.IstioConfigStore, req *model.PushRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if store != nil {
+		if h.configs == nil || req == nil || len(req.ConfigsUpdated) == 0 {
+			h.listConfigs(snap, store)
+		} else {
+			h.getConfigs(snap, store, req.ConfigsUpdated)
+		}
+		snap.configCount = len(h.configs)
+	}
+	snap.id = h.nextID
+	h.nextID++
+	h.snapshots = append(h.snapshots, snap)
+	if len(h.snapshots) > h.size {
+		h.snapshots[0] = nil
+		h.snapshots = h.snapshots[1:]
+	}
+}
+
+// listConfigs lists all the configs of the store, and records the changes since the previous push in the snapshot.
+func (h *pushHistory) listConfigs(snap *pushSnapshot, store model.IstioConfigStore) {
+	configs := map[string]string{}
+	store.Schemas().ForEach(func(schema collection.Schema) bool {
+		list, err := store.List(schema.Resource().GroupVersionKind(), "")
+		if err != nil {
+			log.Warnf("push history: failed to list %s: %v", schema.Resource().Kind(), err)
+			return false
+		}
+		for _, cfg := range list {
+			configs[model.ConfigKey{Kind: cfg.GroupVersionKind, Name: cfg.Name, Namespace: cfg.Namespace}.String()] = cfg.ResourceVersion
+		}
+		return false
+	})
+	if h.configs != nil {
+		for key, version := range configs {
+			prev, f := h.configs[key]
+			switch {
+			case !f:
+				snap.addedConfigs = append(snap.addedConfigs, key)
+			case prev != version:
+				snap.modifiedConfigs = append(snap.modifiedConfigs, key)
+			}
+		}
+		for key := range h.configs {
+			if _, f := configs[key]; !f {
+				snap.removedConfigs = append(snap.removedConfigs, key)
+			}
+		}
+	}
+	h.configs = configs
+}
+
+// getConfigs reads the updated configs from the store, and records their changes since the previous push in the
+// snapshot. The updated configs of the kinds the store does not hold, such as the Kubernetes Services, are skipped.
+func (h *pushHistory) getConfigs(snap *pushSnapshot, store model.IstioConfigStore, updated map[model.ConfigKey]struct{}) {
+	for key := range updated {
+		if _, f := store.Schemas().FindByGroupVersionKind(key.Kind); !f {
+			continue
+		}
+		k := key.String()
+		prev, f := h.configs[k]
+		cfg := store.Get(key.Kind, key.Name, key.Namespace)
+		switch {
+		case cfg == nil && f:
+			snap.removedConfigs = append(snap.removedConfigs, k)
+			delete(h.configs, k)
+		case cfg == nil:
+		case !f:
+			snap.addedConfigs = append(snap.addedConfigs, k)
+			h.configs[k] = cfg.ResourceVersion
+		case prev != cfg.ResourceVersion:
+			snap.modifiedConfigs = append(snap.modifiedConfigs, k)
+			h.configs[k] = cfg.ResourceVersion
+		}
+	}
+}
+
+// pushed records that the proxy was pushed with the push context of the version.
+func (h *pushHistory) pushed(version, proxyID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.snapshots) - 1; i >= 0; i-- {
+		snap := h.snapshots[i]
+		if snap.version != version {
+			continue
+		}
+		if _, f := snap.proxies[proxyID]; f {
+			return
+		}
+		if len(snap.proxies) >= maxPushSnapshotProxies {
+			snap.droppedProxies++
+			return
+		}
+		snap.proxies[proxyID] = struct{}{}
+		return
+	}
+}
+
+func (snap *pushSnapshot) summary() PushSnapshotSummary {
+	return PushSnapshotSummary{
+		ID:             snap.id,
+		PushVersion:    snap.version,
+		Time:           snap.time,
+		Reasons:        snap.reasons,
+		ConfigsUpdated: snap.configsUpdated,
+		Configs:        snap.configCount,
+		Services:       len(snap.services),
+		PushedProxies:  len(snap.proxies) + snap.droppedProxies,
+	}
+}
+
+// Summaries returns the summaries of the retained pushes, oldest first.
+func (h *pushHistory) Summaries() []PushSnapshotSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]PushSnapshotSummary, 0, len(h.snapshots))
+	for _, snap := range h.snapshots {
+		out = append(out, snap.summary())
+	}
+	return out
+}
+
+// Diff returns what changed between the push of the id and the previous one. Both must still be retained.
+func (h *pushHistory) Diff(id int) (*PushDiff, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var from, to *pushSnapshot
+	for _, snap := range h.snapshots {
+		switch snap.id {
+		case id - 1:
+			from = snap
+		case id:
+			to = snap
+		}
+	}
+	if to == nil || from == nil {
+		return nil, fmt.Errorf("pushes %d and %d are not both retained", id-1, id)
+	}
+	diff := &PushDiff{
+		From:              from.summary(),
+		To:                to.summary(),
+		AddedConfigs:      append([]string(nil), to.addedConfigs...),
+		RemovedConfigs:    append([]string(nil), to.removedConfigs...),
+		ModifiedConfigs:   append([]string(nil), to.modifiedConfigs...),
+		MeshConfigChanged: from.meshHash != to.meshHash,
+		PushedProxies:     make([]string, 0, len(to.proxies)),
+		DroppedProxies:    to.droppedProxies,
+	}
+	for svc := range to.services {
+		if _, f := from.services[svc]; !f {
+			diff.AddedServices = append(diff.AddedServices, svc)
+		}
+	}
+	for svc := range from.services {
+		if _, f := to.services[svc]; !f {
+			diff.RemovedServices = append(diff.RemovedServices, svc)
+		}
+	}
+	for proxy := range to.proxies {
+		diff.PushedProxies = append(diff.PushedProxies, proxy)
+	}
+	for _, l := range [][]string{diff.AddedConfigs, diff.RemovedConfigs, diff.ModifiedConfigs, diff.AddedServices, diff.RemovedServices, diff.PushedProxies} {
+		sort.Strings(l)
+	}
+	return diff, nil
+}
+
+// recordPushHistory retains the snapshot of the push context of a full push, if the push history is enabled.
+func (s *DiscoveryServer) recordPushHistory(push *model.PushContext, req *model.PushRequest) {
+	if s.pushHistory == nil {
+		return
+	}
+	s.pushHistory.add(newPushSnapshot(push, req), s.Env.IstioConfigStore, req)
+}
+
+// recordPushedProxy records that the proxy was pushed, if the push history is enabled.
+func (s *DiscoveryServer) recordPushedProxy(con *Connection, req *model.PushRequest) {
+	if s.pushHistory == nil || req.Push == nil {
+		return
+	}
+	s.pushHistory.pushed(req.Push.PushVersion, con.proxy.ID)
+}
+
+// pushHistoryz lists the retained pushes or, with ?diff=<id>, returns what changed between the push of the id and
+// the previous one, and which proxies it was sent to.
+func (s *DiscoveryServer) pushHistoryz(w http.ResponseWriter, req *http.Request) {
+	if s.pushHistory == nil {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprint(w, "Push history is disabled. Please set the "+
+			"PILOT_PUSH_HISTORY_SIZE environment variable to the number of pushes to retain to enable.")
+		return
+	}
+	param := req.URL.Query().Get("diff")
+	if param == "" {
+		writeJSON(w, s.pushHistory.Summaries())
+		return
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "invalid push id %q\n", param)
+		return
+	}
+	diff, err := s.pushHistory.Diff(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	writeJSON(w, diff)
+}
@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestPushHistory(t *testing.T) {
+	store := model.MakeIstioStore(memory.MakeSkipValidation(collections.Pilot))
+	newConfig := func(kind config.GroupVersionKind, name string) config.Config {
+		return config.Config{Meta: config.Meta{GroupVersionKind: kind, Name: name, Namespace: "a"}}
+	}
+	updated := func(configs ...config.Config) *model.PushRequest {
+		req := &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{}}
+		for _, cfg := range configs {
+			req.ConfigsUpdated[model.ConfigKey{Kind: cfg.GroupVersionKind, Name: cfg.Name, Namespace: cfg.Namespace}] = struct{}{}
+		}
+		return req
+	}
+	snapshot := func(version string, meshHash uint64) *pushSnapshot {
+		return &pushSnapshot{version: version, meshHash: meshHash, services: map[string]struct{}{}, proxies: map[string]struct{}{}}
+	}
+
+	h := newPushHistory(2)
+	vs := newConfig(gvk.VirtualService, "vs")
+	version, err := store.Create(vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.add(snapshot("1", 0), store, updated(vs))
+
+	dr := newConfig(gvk.DestinationRule, "dr")
+	if _, err := store.Create(dr); err != nil {
+		t.Fatal(err)
+	}
+	h.add(snapshot("2", 0), store, updated(dr))
+
+	vs.ResourceVersion = version
+	if _, err := store.Update(vs); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(gvk.DestinationRule, "dr", "a", nil); err != nil {
+		t.Fatal(err)
+	}
+	// The Kubernetes Services are not held by the store.
+	h.add(snapshot("3", 1), store, updated(vs, dr, newConfig(gvk.Service, "reviews")))
+	h.pushed("3", "proxy-b")
+	h.pushed("3", "proxy-a")
+	h.pushed("3", "proxy-a")
+	h.pushed("unknown", "proxy-c")
+
+	summaries := h.Summaries()
+	if len(summaries) != 2 || summaries[0].ID != 2 || summaries[0].Configs != 2 || summaries[1].Configs != 1 ||
+		summaries[1].PushedProxies != 2 {
+		t.Fatalf("expected the last two pushes to be retained, got %+v", summaries)
+	}
+	if _, err := h.Diff(2); err == nil {
+		t.Fatalf("expected the diff with a dropped push to fail")
+	}
+	diff, err := h.Diff(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PushDiff{
+		From:              summaries[0],
+		To:                summaries[1],
+		RemovedConfigs:    []string{"DestinationRule/a/dr"},
+		ModifiedConfigs:   []string{"VirtualService/a/vs"},
+		MeshConfigChanged: true,
+		PushedProxies:     []string{"proxy-a", "proxy-b"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("got diff %+v, want %+v", diff, want)
+	}
+
+	// A push of all the configs lists the store.
+	if _, err := store.Create(dr); err != nil {
+		t.Fatal(err)
+	}
+	h.add(snapshot("4", 1), store, &model.PushRequest{Full: true})
+	diff, err = h.Diff(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.AddedConfigs, []string{"DestinationRule/a/dr"}) || len(diff.RemovedConfigs) != 0 ||
+		len(diff.ModifiedConfigs) != 0 || diff.MeshConfigChanged {
+		t.Fatalf("unexpected diff of a push of all the configs %+v", diff)
+	}
+}
+
+func TestPushHistoryDiscovery(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	s.Discovery.pushHistory = newPushHistory(5)
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ConfigUpdate}})
+	ads.ExpectResponse(t)
+
+	vs := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "reviews", Namespace: "default"},
+		Spec: &networking.VirtualService{Hosts: []string{"reviews.example.com"}},
+	}
+	if _, err := s.Store().Create(vs); err != nil {
+		t.Fatal(err)
+	}
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ConfigUpdate}})
+	ads.ExpectResponse(t)
+
+	// The creation of the config may be pushed along with the update, or separately before it.
+	retry.UntilSuccessOrFail(t, func() error {
+		summaries := s.Discovery.pushHistory.Summaries()
+		if last := summaries[len(summaries)-1]; last.PushedProxies != 1 {
+			return fmt.Errorf("expected the last push to be sent to the proxy, got %+v", last)
+		}
+		for _, summary := range summaries[1:] {
+			diff, err := s.Discovery.pushHistory.Diff(summary.ID)
+			if err != nil {
+				return err
+			}
+			if reflect.DeepEqual(diff.AddedConfigs, []string{"VirtualService/default/reviews"}) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no push added the VirtualService: %+v", summaries)
+	}, retry.Timeout(time.Second))
+}
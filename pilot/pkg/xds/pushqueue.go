@@ -16,6 +16,7 @@ package xds
 
 import (
 	"sync"
+	"time"
 
 	"istio.io/istio/pilot/pkg/model"
 )
@@ -27,22 +28,51 @@ type PushQueue struct {
 	// the PushRequest will be merged.
 	pending map[*Connection]*model.PushRequest
 
-	// queue maintains ordering of the queue
+	// queue maintains ordering of the connections pending a full push.
 	queue []*Connection
 
+	// incrementalQueue maintains ordering of the connections pending only an incremental push, such as
+	// an endpoint health change. These are dequeued before full pushes, so they are not delayed by
+	// the recomputation of all the resources of other proxies.
+	// A connection whose pending push becomes full is appended to queue as well, and skipped here.
+	incrementalQueue []*Connection
+
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
 	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
 	processing map[*Connection]*model.PushRequest
 
+	// fullProcessing stores the connections in processing for a full push.
+	fullProcessing map[*Connection]struct{}
+
+	// fullPushLimit is the maximum number of concurrent full pushes, so that the remaining pushes
+	// are available to incremental pushes. If zero, full pushes are not limited.
+	fullPushLimit int
+
+	// fullPushInterval is the minimum interval between two full pushes to the same connection.
+	// Full pushes requested in between are merged and delayed.
+	fullPushInterval time.Duration
+
+	// wakeup wakes up Dequeue once a delayed full push can be sent.
+	wakeup *time.Timer
+
 	shuttingDown bool
 }
 
 func NewPushQueue() *PushQueue {
+	return newPushQueue(0, 0)
+}
+
+// newPushQueue returns a PushQueue limiting the number of concurrent full pushes to fullPushLimit,
+// and sending at most one full push to a connection per fullPushInterval.
+func newPushQueue(fullPushLimit int, fullPushInterval time.Duration) *PushQueue {
 	return &PushQueue{
-		pending:    make(map[*Connection]*model.PushRequest),
-		processing: make(map[*Connection]*model.PushRequest),
-		cond:       sync.NewCond(&sync.Mutex{}),
+		pending:          make(map[*Connection]*model.PushRequest),
+		processing:       make(map[*Connection]*model.PushRequest),
+		fullProcessing:   make(map[*Connection]struct{}),
+		fullPushLimit:    fullPushLimit,
+		fullPushInterval: fullPushInterval,
+		cond:             sync.NewCond(&sync.Mutex{}),
 	}
 }
 
@@ -63,12 +93,27 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 	}
 
 	if request, f := p.pending[con]; f {
-		p.pending[con] = request.CopyMerge(pushRequest)
+		merged := request.CopyMerge(pushRequest)
+		p.pending[con] = merged
+		if !request.Full && merged.Full {
+			// The connection now waits for a full push.
+			p.queue = append(p.queue, con)
+			p.cond.Signal()
+		}
 		return
 	}
 
-	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
+	p.add(con, pushRequest)
+}
+
+// add appends a connection to the queue matching its push request. Must be called with the lock held.
+func (p *PushQueue) add(con *Connection, request *model.PushRequest) {
+	p.pending[con] = request
+	if request.Full {
+		p.queue = append(p.queue, con)
+	} else {
+		p.incrementalQueue = append(p.incrementalQueue, con)
+	}
 	// Signal waiters on Dequeue that a new item is available
 	p.cond.Signal()
 }
@@ -79,42 +124,107 @@ func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shut
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	for {
+		if p.shuttingDown && len(p.pending) == 0 {
+			return nil, nil, true
+		}
+		if con = p.next(); con != nil {
+			break
+		}
+		if p.shuttingDown {
+			// Only delayed full pushes remain, there is no need to wait for them.
+			return nil, nil, true
+		}
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
-		// We must be shutting down.
-		return nil, nil, true
-	}
-
-	con = p.queue[0]
-	// The underlying array will still exist, despite the slice changing, so the object may not GC without this
-	// See https://github.com/grpc/grpc-go/issues/4758
-	p.queue[0] = nil
-	p.queue = p.queue[1:]
-
 	request = p.pending[con]
 	delete(p.pending, con)
 
 	// Mark the connection as in progress
 	p.processing[con] = nil
+	if request.Full {
+		p.fullProcessing[con] = struct{}{}
+		con.lastFullPush = time.Now()
+	}
 
 	return con, request, false
 }
 
+// next removes and returns the next connection to push, or nil if no push can be sent now.
+// Incremental pushes are returned first. Must be called with the lock held.
+func (p *PushQueue) next() *Connection {
+	for len(p.incrementalQueue) > 0 {
+		con := p.incrementalQueue[0]
+		// The underlying array will still exist, despite the slice changing, so the object may not GC without this
+		// See https://github.com/grpc/grpc-go/issues/4758
+		p.incrementalQueue[0] = nil
+		p.incrementalQueue = p.incrementalQueue[1:]
+		// Skip connections already dequeued, or now waiting for a full push.
+		if request, f := p.pending[con]; f && !request.Full {
+			return con
+		}
+	}
+
+	if p.fullPushLimit > 0 && len(p.fullProcessing) >= p.fullPushLimit {
+		// MarkDone will signal once a full push completes.
+		return nil
+	}
+	now := time.Now()
+	var wait time.Duration
+	// Connections pushed too recently are moved to the back of the queue, so look at each at most once.
+	for n := len(p.queue); n > 0; n-- {
+		con := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		if request, f := p.pending[con]; !f || !request.Full {
+			continue
+		}
+		if p.fullPushInterval > 0 && !con.lastFullPush.IsZero() {
+			if remaining := p.fullPushInterval - now.Sub(con.lastFullPush); remaining > 0 {
+				p.queue = append(p.queue, con)
+				if wait == 0 || remaining < wait {
+					wait = remaining
+				}
+				continue
+			}
+		}
+		return con
+	}
+	if wait > 0 {
+		p.wakeAfter(wait)
+	}
+	return nil
+}
+
+// wakeAfter wakes up the waiters of Dequeue after the given duration. Must be called with the lock held.
+func (p *PushQueue) wakeAfter(d time.Duration) {
+	if p.wakeup == nil {
+		p.wakeup = time.AfterFunc(d, func() {
+			p.cond.L.Lock()
+			defer p.cond.L.Unlock()
+			p.cond.Broadcast()
+		})
+		return
+	}
+	p.wakeup.Reset(d)
+}
+
 func (p *PushQueue) MarkDone(con *Connection) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 	request := p.processing[con]
 	delete(p.processing, con)
+	if _, f := p.fullProcessing[con]; f {
+		delete(p.fullProcessing, con)
+		// A full push waiting for the limit on concurrent full pushes may be sent now.
+		p.cond.Signal()
+	}
 
 	// If the info is present, that means Enqueue was called while connection was not yet marked done.
 	// This means we need to add it back to the queue.
 	if request != nil {
-		p.pending[con] = request
-		p.queue = append(p.queue, con)
-		p.cond.Signal()
+		p.add(con, request)
 	}
 }
 
@@ -122,7 +232,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return len(p.pending)
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
@@ -132,5 +242,8 @@ func (p *PushQueue) ShutDown() {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 	p.shuttingDown = true
+	if p.wakeup != nil {
+		p.wakeup.Stop()
+	}
 	p.cond.Broadcast()
 }
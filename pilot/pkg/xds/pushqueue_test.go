@@ -371,6 +371,99 @@ func TestProxyQueue(t *testing.T) {
 	})
 }
 
+func TestPushQueuePriority(t *testing.T) {
+	proxies := make([]*Connection, 0, 3)
+	for p := 0; p < 3; p++ {
+		proxies = append(proxies, &Connection{ConID: fmt.Sprintf("proxy-%d", p)})
+	}
+
+	t.Run("incremental before full", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		p.Enqueue(proxies[0], &model.PushRequest{Full: true})
+		p.Enqueue(proxies[1], &model.PushRequest{Full: true})
+		p.Enqueue(proxies[2], &model.PushRequest{})
+
+		ExpectDequeue(t, p, proxies[2])
+		ExpectDequeue(t, p, proxies[0])
+		ExpectDequeue(t, p, proxies[1])
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("incremental merged with full", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		p.Enqueue(proxies[0], &model.PushRequest{})
+		p.Enqueue(proxies[1], &model.PushRequest{})
+		p.Enqueue(proxies[0], &model.PushRequest{Full: true})
+
+		ExpectDequeue(t, p, proxies[1])
+		_, request, _ := p.Dequeue()
+		if !request.Full {
+			t.Fatalf("expected merged request to be full")
+		}
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("full push limit", func(t *testing.T) {
+		p := newPushQueue(1, 0)
+		defer p.ShutDown()
+
+		p.Enqueue(proxies[0], &model.PushRequest{Full: true})
+		p.Enqueue(proxies[1], &model.PushRequest{Full: true})
+		ExpectDequeue(t, p, proxies[0])
+		// The second full push waits for the first one, but incremental pushes are not limited.
+		p.Enqueue(proxies[2], &model.PushRequest{})
+		ExpectDequeue(t, p, proxies[2])
+		result := make(chan *Connection, 1)
+		go func() {
+			con, _, _ := p.Dequeue()
+			result <- con
+		}()
+		select {
+		case con := <-result:
+			t.Fatalf("got %v while the full push limit is reached", con)
+		case <-time.After(time.Millisecond * 100):
+		}
+		p.MarkDone(proxies[0])
+		select {
+		case con := <-result:
+			if con != proxies[1] {
+				t.Fatalf("Expected proxy %v, got %v", proxies[1], con)
+			}
+		case <-time.After(time.Millisecond * 500):
+			t.Fatalf("Timed out")
+		}
+	})
+
+	t.Run("full push interval", func(t *testing.T) {
+		p := newPushQueue(0, 200*time.Millisecond)
+		defer p.ShutDown()
+		cons := []*Connection{{ConID: "proxy-0"}, {ConID: "proxy-1"}}
+
+		p.Enqueue(cons[0], &model.PushRequest{Full: true})
+		ExpectDequeue(t, p, cons[0])
+		p.MarkDone(cons[0])
+
+		// Pushed too recently, the full push of the first proxy is delayed behind the other proxy.
+		start := time.Now()
+		p.Enqueue(cons[0], &model.PushRequest{Full: true})
+		p.Enqueue(cons[1], &model.PushRequest{Full: true})
+		ExpectDequeue(t, p, cons[1])
+		ExpectDequeue(t, p, cons[0])
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Fatalf("full push was sent after %v, before the minimum interval", elapsed)
+		}
+		p.MarkDone(cons[0])
+
+		// Incremental pushes are not delayed.
+		p.Enqueue(cons[0], &model.PushRequest{})
+		ExpectDequeue(t, p, cons[0])
+	})
+}
+
 // TestPushQueueLeak is a regression test for https://github.com/grpc/grpc-go/issues/4758
 func TestPushQueueLeak(t *testing.T) {
 	ds := NewFakeDiscoveryServer(t, FakeOptions{})
@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -26,18 +27,18 @@ type RdsGenerator struct {
 
 var _ model.XdsResourceGenerator = &RdsGenerator{}
 
-// Map of all configs that do not impact RDS
+// Map of all configs that do not impact RDS. RequestAuthentication is not part of it, as claims of
+// validated JWTs can be copied into request headers by the route configuration.
 var skippedRdsConfigs = map[config.GroupVersionKind]struct{}{
-	gvk.WorkloadEntry:         {},
-	gvk.WorkloadGroup:         {},
-	gvk.AuthorizationPolicy:   {},
-	gvk.RequestAuthentication: {},
-	gvk.PeerAuthentication:    {},
-	gvk.Secret:                {},
-	gvk.WasmPlugin:            {},
+	gvk.WorkloadEntry:       {},
+	gvk.WorkloadGroup:       {},
+	gvk.AuthorizationPolicy: {},
+	gvk.PeerAuthentication:  {},
+	gvk.Secret:              {},
+	gvk.WasmPlugin:          {},
 }
 
-func rdsNeedsPush(req *model.PushRequest) bool {
+func rdsNeedsPush(proxy *model.Proxy, req *model.PushRequest) bool {
 	if req == nil {
 		return true
 	}
@@ -50,6 +51,10 @@ func rdsNeedsPush(req *model.PushRequest) bool {
 		return true
 	}
 	for config := range req.ConfigsUpdated {
+		// The routes of sidecars send the credentials of their egress proxy.
+		if config.Kind == gvk.Secret && proxy.Type == model.SidecarProxy {
+			return true
+		}
 		if _, f := skippedRdsConfigs[config.Kind]; !f {
 			return true
 		}
@@ -59,9 +64,13 @@ func rdsNeedsPush(req *model.PushRequest) bool {
 
 func (c RdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
 	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
-	if !rdsNeedsPush(req) {
+	if !rdsNeedsPush(proxy, req) {
 		return nil, model.DefaultXdsLogDetails, nil
 	}
 	resources, logDetails := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, req, w.ResourceNames)
+	if proxy.Type == model.Router && (features.GatewayRouteValidation == routeValidationAudit ||
+		features.GatewayRouteValidation == routeValidationEnforce) {
+		resources = gateRouteConfigurations(proxy, resources)
+	}
 	return resources, logDetails, nil
 }
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// GeneratedResourceVersion shows what resourceVersion of a given resource has been acked by Envoy in a cluster or
+// route configuration generated from it.
+type GeneratedResourceVersion struct {
+	// Type is the short xDS type of the generated resource, CDS or RDS.
+	Type string `json:"type"`
+	// Name is the name of the generated cluster or route configuration.
+	Name string `json:"name"`
+	// Routes are the routes of the route configuration generated from the resource, as virtual host/route names.
+	Routes []string `json:"routes,omitempty"`
+	// Version is the resourceVersion of the resource in the last generated resources of the type acked by Envoy.
+	Version string `json:"acked,omitempty"`
+}
+
+// configMetaForKey returns the metadata identifying the config, from the key of the config as used by the
+// distribution tracking.
+func configMetaForKey(key string) (config.Meta, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 5 {
+		return config.Meta{}, fmt.Errorf("invalid resource %q, expected group/version/kind/namespace/name", key)
+	}
+	return config.Meta{
+		GroupVersionKind: config.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]},
+		Namespace:        parts[3],
+		Name:             parts[4],
+	}, nil
+}
+
+// generatedResourceVersions returns the clusters and route configurations generated for the proxy from the
+// VirtualService or DestinationRule, along with the resourceVersion of the config the proxy acked for their type, as
+// read from the nonces. Rather than generating the configuration of the proxy, the names of the resources are derived
+// from the services the config applies to in the sidecar scope of the proxy and the routes the proxy watches, so only
+// the sidecars are supported. The proxy must be read locked.
+func (s *DiscoveryServer) generatedResourceVersions(con *Connection, meta config.Meta,
+	synced *SyncedVersions) []GeneratedResourceVersion {
+	proxy := con.proxy
+	if proxy.Type != model.SidecarProxy || proxy.SidecarScope == nil {
+		return nil
+	}
+	kind, f := collections.All.FindByGroupVersionKind(meta.GroupVersionKind)
+	if !f {
+		return nil
+	}
+	key := model.ConfigKey{Kind: kind.Resource().GroupVersionKind(), Name: meta.Name, Namespace: meta.Namespace}
+	if !proxy.SidecarScope.DependsOnConfig(key) {
+		return nil
+	}
+	push := s.pushContextFor(proxy, s.globalPushContext())
+	var out []GeneratedResourceVersion
+	switch key.Kind {
+	case gvk.DestinationRule:
+		cfg := s.Env.IstioConfigStore.Get(key.Kind, key.Name, key.Namespace)
+		if cfg == nil {
+			return nil
+		}
+		dr := cfg.Spec.(*networking.DestinationRule)
+		svc := push.ServiceForHostname(proxy, host.Name(dr.Host))
+		if svc == nil {
+			return nil
+		}
+		for _, port := range svc.Ports {
+			for _, subset := range append([]string{""}, subsetNames(dr)...) {
+				out = append(out, GeneratedResourceVersion{
+					Type:    v3.GetShortType(v3.ClusterType),
+					Name:    model.BuildSubsetKey(model.TrafficDirectionOutbound, subset, svc.Hostname, port.Port),
+					Version: synced.ClusterVersion,
+				})
+			}
+		}
+	case gvk.VirtualService:
+		cfg := s.Env.IstioConfigStore.Get(key.Kind, key.Name, key.Namespace)
+		if cfg == nil {
+			return nil
+		}
+		vs := cfg.Spec.(*networking.VirtualService)
+		watched := map[string]bool{}
+		if w := proxy.WatchedResources[v3.RouteType]; w != nil {
+			for _, name := range w.ResourceNames {
+				watched[name] = true
+			}
+		}
+		routes := map[string][]string{}
+		var names []string
+		for _, h := range vs.Hosts {
+			svc := push.ServiceForHostname(proxy, host.Name(h))
+			if svc == nil {
+				continue
+			}
+			for _, port := range svc.Ports {
+				name := strconv.Itoa(port.Port)
+				if !watched[name] {
+					continue
+				}
+				if _, f := routes[name]; !f {
+					names = append(names, name)
+				}
+				vhost := fmt.Sprintf("%s:%d", svc.Hostname, port.Port)
+				for _, r := range vs.Http {
+					if r.Name != "" {
+						routes[name] = append(routes[name], vhost+"/"+r.Name)
+					} else {
+						routes[name] = append(routes[name], vhost)
+					}
+				}
+			}
+		}
+		for _, name := range names {
+			out = append(out, GeneratedResourceVersion{
+				Type:    v3.GetShortType(v3.RouteType),
+				Name:    name,
+				Routes:  routes[name],
+				Version: synced.RouteVersion,
+			})
+		}
+	}
+	return out
+}
+
+// subsetNames returns the names of the subsets of the DestinationRule.
+func subsetNames(dr *networking.DestinationRule) []string {
+	names := make([]string, 0, len(dr.Subsets))
+	for _, subset := range dr.Subsets {
+		names = append(names, subset.Name)
+	}
+	return names
+}
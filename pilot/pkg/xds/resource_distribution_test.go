@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+const resourceDistributionConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  host: reviews.example.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  http:
+  - name: primary
+    route:
+    - destination:
+        host: reviews.example.com
+        subset: v1
+`
+
+func TestGeneratedResourceVersions(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: resourceDistributionConfig})
+	con := newConnection("", nil)
+	con.proxy = s.SetupProxy(&model.Proxy{ConfigNamespace: "default"})
+	con.proxy.WatchedResources = map[string]*model.WatchedResource{
+		v3.RouteType: {TypeUrl: v3.RouteType, ResourceNames: []string{"80"}},
+	}
+	synced := &SyncedVersions{ClusterVersion: "1", RouteVersion: "2"}
+
+	meta, err := configMetaForKey("networking.istio.io/v1alpha3/VirtualService/default/reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GeneratedResourceVersion{{Type: "RDS", Name: "80", Routes: []string{"reviews.example.com:80/primary"}, Version: "2"}}
+	if got := s.Discovery.generatedResourceVersions(con, meta, synced); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	meta, err = configMetaForKey("networking.istio.io/v1alpha3/DestinationRule/default/reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []GeneratedResourceVersion{
+		{Type: "CDS", Name: "outbound|80||reviews.example.com", Version: "1"},
+		{Type: "CDS", Name: "outbound|80|v1|reviews.example.com", Version: "1"},
+	}
+	if got := s.Discovery.generatedResourceVersions(con, meta, synced); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// No resource is generated from the configs the proxy does not depend on.
+	meta, err = configMetaForKey("networking.istio.io/v1alpha3/DestinationRule/default/ratings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Discovery.generatedResourceVersions(con, meta, synced); len(got) != 0 {
+		t.Fatalf("got %+v for a missing config, want none", got)
+	}
+
+	if _, err := configMetaForKey("reviews.default"); err == nil {
+		t.Fatalf("expected an error for an invalid resource key")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// Modes of the validation of the route configurations of the gateways, set with PILOT_GATEWAY_ROUTE_VALIDATION.
+const (
+	// routeValidationAudit reports the invalid route configurations and pushes them anyway.
+	routeValidationAudit = "audit"
+	// routeValidationEnforce removes the invalid routes and virtual hosts from the pushed route configurations.
+	routeValidationEnforce = "enforce"
+)
+
+// regexMatcherName is the name of the message of the regular expressions of the route configurations.
+const regexMatcherName protoreflect.FullName = "envoy.type.matcher.v3.RegexMatcher"
+
+// maxRegexProgramSize is the re2.max_program_size.error_level set by the bootstrap of the proxies, above which Envoy
+// rejects a regular expression.
+const maxRegexProgramSize = 32768
+
+// validateRegex returns an error if Envoy would reject the regular expression. The RE2 syntax is parsed with the Go
+// parser, which accepts the same syntax except for \C, so the regular expressions using it are not checked. The size
+// of the program compiled by Go approximates the size of the RE2 program Envoy limits.
+func validateRegex(regex string) error {
+	if strings.Contains(regex, `\C`) {
+		return nil
+	}
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %v", regex, err)
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %v", regex, err)
+	}
+	if len(prog.Inst) > maxRegexProgramSize {
+		return fmt.Errorf("regex %q is too large, its program size %d exceeds %d", regex, len(prog.Inst), maxRegexProgramSize)
+	}
+	return nil
+}
+
+// validateRouteConfiguration returns an error if the route configuration would be rejected by Envoy because of an
+// invalid regular expression or duplicated virtual host domains.
+func validateRouteConfiguration(rc *route.RouteConfiguration) error {
+	domains := map[string]string{}
+	for _, vh := range rc.VirtualHosts {
+		for _, d := range vh.Domains {
+			if other, f := domains[d]; f {
+				return fmt.Errorf("domain %q of virtual host %s is also a domain of virtual host %s", d, vh.Name, other)
+			}
+			domains[d] = vh.Name
+		}
+	}
+	return validateRegexes(rc.ProtoReflect())
+}
+
+// validateRegexes validates the regular expressions of the message and of its nested messages. Typed configs are not
+// inspected.
+func validateRegexes(m protoreflect.Message) error {
+	if m.Descriptor().FullName() == regexMatcherName {
+		return validateRegex(m.Get(m.Descriptor().Fields().ByName("regex")).String())
+	}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len() && err == nil; i++ {
+				err = validateRegexes(v.List().Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = validateRegexes(mv.Message())
+				return err == nil
+			})
+		case fd.Message() != nil && !fd.IsMap():
+			err = validateRegexes(v.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+// removeInvalidRoutes removes from the route configuration the routes with an invalid regular expression, the virtual
+// hosts with one outside of their routes, and the domains of the virtual hosts already served by a previous virtual
+// host, dropping the virtual hosts left without domain. It returns the errors of the removed parts.
+func removeInvalidRoutes(rc *route.RouteConfiguration) []error {
+	var errs []error
+	domains := map[string]string{}
+	vhs := make([]*route.VirtualHost, 0, len(rc.VirtualHosts))
+	for _, vh := range rc.VirtualHosts {
+		vhDomains := make([]string, 0, len(vh.Domains))
+		for _, d := range vh.Domains {
+			if other, f := domains[d]; f {
+				errs = append(errs, fmt.Errorf("domain %q of virtual host %s is also a domain of virtual host %s", d, vh.Name, other))
+				continue
+			}
+			domains[d] = vh.Name
+			vhDomains = append(vhDomains, d)
+		}
+		if len(vhDomains) == 0 {
+			continue
+		}
+		vh.Domains = vhDomains
+		routes := vh.Routes
+		vh.Routes = nil
+		if err := validateRegexes(vh.ProtoReflect()); err != nil {
+			errs = append(errs, fmt.Errorf("virtual host %s: %v", vh.Name, err))
+			continue
+		}
+		for _, r := range routes {
+			if err := validateRegexes(r.ProtoReflect()); err != nil {
+				errs = append(errs, fmt.Errorf("route %s of virtual host %s: %v", r.Name, vh.Name, err))
+				continue
+			}
+			vh.Routes = append(vh.Routes, r)
+		}
+		vhs = append(vhs, vh)
+	}
+	rc.VirtualHosts = vhs
+	return errs
+}
+
+// gateRouteConfigurations validates the route configurations generated for the gateway. The invalid ones are reported
+// and, when the validation is enforced, pushed without their invalid routes and virtual hosts. The rest of the route
+// configurations is always the one generated for the current push, so it only references the clusters pushed with it.
+func gateRouteConfigurations(proxy *model.Proxy, resources model.Resources) model.Resources {
+	enforce := features.GatewayRouteValidation == routeValidationEnforce
+	for i, r := range resources {
+		rc := &route.RouteConfiguration{}
+		if err := r.GetResource().UnmarshalTo(rc); err != nil {
+			continue
+		}
+		err := validateRouteConfiguration(rc)
+		if err == nil {
+			continue
+		}
+		routeValidationFailures.Increment()
+		if !enforce {
+			log.Warnf("pushing invalid route configuration %s to %s: %v", r.Name, proxy.ID, err)
+			continue
+		}
+		for _, err := range removeInvalidRoutes(rc) {
+			log.Warnf("removing from route configuration %s of %s: %v", r.Name, proxy.ID, err)
+		}
+		resources[i] = &discovery.Resource{Name: r.Name, Resource: util.MessageToAny(rc)}
+	}
+	return resources
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+func routeConfigWithRegex(regex string, domains ...string) *route.RouteConfiguration {
+	rc := &route.RouteConfiguration{Name: "http.8080"}
+	for i, d := range domains {
+		rc.VirtualHosts = append(rc.VirtualHosts, &route.VirtualHost{
+			Name:    d + string(rune('a'+i)),
+			Domains: []string{d},
+			Routes: []*route.Route{{
+				Match: &route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+					Headers: []*route.HeaderMatcher{{
+						Name: "x-user",
+						HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{StringMatch: &matcher.StringMatcher{
+							MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: regex}},
+						}},
+					}},
+				},
+			}},
+		})
+	}
+	return rc
+}
+
+func TestValidateRouteConfiguration(t *testing.T) {
+	cases := []struct {
+		name  string
+		rc    *route.RouteConfiguration
+		valid bool
+	}{
+		{name: "valid", rc: routeConfigWithRegex("user-[0-9]+", "foo.com", "bar.com"), valid: true},
+		{name: "invalid regex", rc: routeConfigWithRegex("user-[0-9+", "foo.com")},
+		{name: "unsupported regex", rc: routeConfigWithRegex("(?!admin).*", "foo.com")},
+		{name: "duplicate domains", rc: routeConfigWithRegex("user-[0-9]+", "foo.com", "foo.com")},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRouteConfiguration(tt.rc); (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateRegex(t *testing.T) {
+	cases := []struct {
+		regex string
+		valid bool
+	}{
+		{regex: "user-[0-9]+", valid: true},
+		{regex: `prefix\C`, valid: true},
+		{regex: "user-[0-9+"},
+		{regex: "(?!admin).*"},
+		{regex: "(((a{100}){100}){100})"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.regex, func(t *testing.T) {
+			if err := validateRegex(tt.regex); (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestGateRouteConfigurations(t *testing.T) {
+	defaultValue := features.GatewayRouteValidation
+	features.GatewayRouteValidation = routeValidationEnforce
+	defer func() { features.GatewayRouteValidation = defaultValue }()
+
+	proxy := &model.Proxy{ID: "gateway.istio-system", Type: model.Router}
+	valid := &discovery.Resource{Name: "http.8080", Resource: util.MessageToAny(routeConfigWithRegex("user-[0-9]+", "foo.com"))}
+	if got := gateRouteConfigurations(proxy, model.Resources{valid}); got[0] != valid {
+		t.Fatalf("expected the valid route configuration to be pushed")
+	}
+
+	rc := routeConfigWithRegex("user-[0-9]+", "foo.com", "foo.com", "bar.com")
+	rc.VirtualHosts[2].Routes = append(rc.VirtualHosts[2].Routes, routeConfigWithRegex("user-[0-9+", "bar.com").VirtualHosts[0].Routes...)
+	invalid := &discovery.Resource{Name: "http.8080", Resource: util.MessageToAny(rc)}
+	got := &route.RouteConfiguration{}
+	if err := gateRouteConfigurations(proxy, model.Resources{invalid})[0].GetResource().UnmarshalTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.VirtualHosts) != 2 || got.VirtualHosts[0].Name != rc.VirtualHosts[0].Name || got.VirtualHosts[1].Name != rc.VirtualHosts[2].Name {
+		t.Fatalf("expected the virtual host with a duplicated domain to be removed, got %v", got.VirtualHosts)
+	}
+	if len(got.VirtualHosts[1].Routes) != 1 {
+		t.Fatalf("expected the route with an invalid regex to be removed, got %v", got.VirtualHosts[1].Routes)
+	}
+	if err := validateRouteConfiguration(got); err != nil {
+		t.Fatalf("expected a valid route configuration, got %v", err)
+	}
+
+	features.GatewayRouteValidation = routeValidationAudit
+	if got := gateRouteConfigurations(proxy, model.Resources{invalid}); got[0] != invalid {
+		t.Fatalf("expected the invalid route configuration to be pushed in audit mode")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	runtime "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+)
+
+// RuntimeLayerName is the name of the runtime layer served to the proxies, set by the rtds_layer of their bootstrap.
+const RuntimeLayerName = "istio"
+
+// RtdsGenerator generates the runtime layer of the proxies. It holds the runtime values of the mesh config which
+// switch off expensive filters, so that they can be changed on all the proxies without rebuilding their listeners.
+type RtdsGenerator struct {
+	Server *DiscoveryServer
+}
+
+var _ model.XdsResourceGenerator = &RtdsGenerator{}
+
+// rtdsNeedsPush returns whether the runtime layer is sent. The requests of the proxy are always answered, as Envoy
+// waits for the layers of its bootstrap until their initial fetch timeout.
+func rtdsNeedsPush(req *model.PushRequest) bool {
+	if req == nil {
+		return true
+	}
+	for _, reason := range req.Reason {
+		if reason == model.ProxyRequest {
+			return true
+		}
+	}
+	if !features.EnableExtAuthzKillSwitch || !req.Full {
+		return false
+	}
+	// The runtime values are read from the mesh config, whose changes trigger a push without configs.
+	return len(req.ConfigsUpdated) == 0
+}
+
+// Generate returns the runtime layer of the proxy, with the ext_authz switch of the runtime values of the default
+// proxy config of the mesh. The layer is empty if it is not set or the switch is disabled, so the value of the
+// bootstrap of the proxy applies.
+func (e *RtdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	if !rtdsNeedsPush(req) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	layer := map[string]interface{}{}
+	if v, f := push.Mesh.GetDefaultConfig().GetRuntimeValues()[builder.ExtAuthzEnabledRuntimeKey]; f && features.EnableExtAuthzKillSwitch {
+		layer[builder.ExtAuthzEnabledRuntimeKey] = v
+	}
+	s, err := structpb.NewStruct(layer)
+	if err != nil {
+		return nil, model.DefaultXdsLogDetails, err
+	}
+	return model.Resources{&discovery.Resource{
+		Name:     RuntimeLayerName,
+		Resource: util.MessageToAny(&runtime.Runtime{Name: RuntimeLayerName, Layer: s}),
+	}}, model.DefaultXdsLogDetails, nil
+}
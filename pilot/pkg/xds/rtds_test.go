@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	runtime "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestRTDS(t *testing.T) {
+	defaultValue := features.EnableExtAuthzKillSwitch
+	features.EnableExtAuthzKillSwitch = true
+	defer func() { features.EnableExtAuthzKillSwitch = defaultValue }()
+
+	m := mesh.DefaultMeshConfig()
+	m.DefaultConfig.RuntimeValues = map[string]string{builder.ExtAuthzEnabledRuntimeKey: "0"}
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{MeshConfig: &m})
+
+	ads := s.ConnectADS().WithType(v3.RuntimeType)
+	res := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{
+		Node:          &corev3.Node{Id: ads.ID},
+		ResourceNames: []string{xds.RuntimeLayerName},
+	})
+	if len(res.Resources) != 1 {
+		t.Fatalf("got %d resources, want 1", len(res.Resources))
+	}
+	rt := &runtime.Runtime{}
+	if err := res.Resources[0].UnmarshalTo(rt); err != nil {
+		t.Fatal(err)
+	}
+	if rt.Name != xds.RuntimeLayerName {
+		t.Errorf("got runtime layer %q, want %q", rt.Name, xds.RuntimeLayerName)
+	}
+	if got := rt.GetLayer().GetFields()[builder.ExtAuthzEnabledRuntimeKey].GetStringValue(); got != "0" {
+		t.Errorf("got %s %q, want %q", builder.ExtAuthzEnabledRuntimeKey, got, "0")
+	}
+}
+
+func TestRTDSDisabled(t *testing.T) {
+	defaultValue := features.EnableExtAuthzKillSwitch
+	features.EnableExtAuthzKillSwitch = false
+	defer func() { features.EnableExtAuthzKillSwitch = defaultValue }()
+
+	m := mesh.DefaultMeshConfig()
+	m.DefaultConfig.RuntimeValues = map[string]string{builder.ExtAuthzEnabledRuntimeKey: "0"}
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{MeshConfig: &m})
+
+	// The initial request is answered with an empty layer, so that Envoy does not wait for it.
+	ads := s.ConnectADS().WithType(v3.RuntimeType)
+	res := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{
+		Node:          &corev3.Node{Id: ads.ID},
+		ResourceNames: []string{xds.RuntimeLayerName},
+	})
+	if len(res.Resources) != 1 {
+		t.Fatalf("got %d resources, want 1", len(res.Resources))
+	}
+	rt := &runtime.Runtime{}
+	if err := res.Resources[0].UnmarshalTo(rt); err != nil {
+		t.Fatal(err)
+	}
+	if rt.Name != xds.RuntimeLayerName || len(rt.GetLayer().GetFields()) != 0 {
+		t.Errorf("got runtime layer %v, want an empty %q layer", rt, xds.RuntimeLayerName)
+	}
+}
@@ -54,7 +54,7 @@ func (sr SecretResource) Cacheable() bool {
 }
 
 func sdsNeedsPush(proxy *model.Proxy, updates model.XdsUpdates) bool {
-	if proxy.Type != model.Router {
+	if proxy.Type != model.Router && !(proxy.Type == model.SidecarProxy && features.EnableSidecarCredentialName) {
 		return false
 	}
 	if len(updates) == 0 {
@@ -163,6 +163,7 @@ func (s *SecretGen) Generate(proxy *model.Proxy, push *model.PushContext, w *mod
 				res := toEnvoyKeyCertSecret(sr.ResourceName, key, cert)
 				results = append(results, res)
 				s.cache.Add(sr, req, res)
+				s.certExpiry.schedule(model.ConfigKey{Kind: gvk.Secret, Name: sr.Name, Namespace: sr.Namespace}, cert)
 			}
 		}
 	}
@@ -327,16 +328,26 @@ type SecretGen struct {
 	// Cache for XDS resources
 	cache         model.XdsCache
 	configCluster cluster.ID
+	// certExpiry pushes the certificates again shortly before they expire.
+	certExpiry *certExpiryScheduler
 }
 
 var _ model.XdsResourceGenerator = &SecretGen{}
 
-func NewSecretGen(sc credscontroller.MulticlusterController, cache model.XdsCache, configCluster cluster.ID) *SecretGen {
+func NewSecretGen(sc credscontroller.MulticlusterController, cache model.XdsCache, configCluster cluster.ID,
+	xdsUpdater model.XDSUpdater) *SecretGen {
 	// TODO: Currently we only have a single credentials controller (Kubernetes). In the future, we will need a mapping
 	// of resource type to secret controller (ie kubernetes:// -> KubernetesController, vault:// -> VaultController)
 	return &SecretGen{
 		secrets:       sc,
 		cache:         cache,
 		configCluster: configCluster,
+		certExpiry: newCertExpiryScheduler(features.CertExpiryPushWindow, func(key model.ConfigKey) {
+			xdsUpdater.ConfigUpdate(&model.PushRequest{
+				Full:           false,
+				ConfigsUpdated: map[model.ConfigKey]struct{}{key: {}},
+				Reason:         []model.TriggerReason{model.SecretTrigger},
+			})
+		}),
 	}
 }
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// certExpiryCheck is the pending check of the expiry of a certificate.
+type certExpiryCheck struct {
+	notAfter time.Time
+	// timer pushes the certificate at the start of the window. It is nil once fired.
+	timer *time.Timer
+	// reported is set once the certificate was found in the window.
+	reported bool
+}
+
+// certExpiryScheduler pushes the certificates served over SDS again shortly before they expire, so that proxies
+// pick up a rotated certificate even if the update of its Secret was missed, and reports the certificates which
+// reach that window without being rotated.
+type certExpiryScheduler struct {
+	mu     sync.Mutex
+	checks map[model.ConfigKey]*certExpiryCheck
+	window time.Duration
+	clock  func() time.Time
+	push   func(key model.ConfigKey)
+}
+
+func newCertExpiryScheduler(window time.Duration, push func(key model.ConfigKey)) *certExpiryScheduler {
+	return &certExpiryScheduler{
+		checks: map[model.ConfigKey]*certExpiryCheck{},
+		window: window,
+		clock:  time.Now,
+		push:   push,
+	}
+}
+
+// schedule schedules the push of the certificate of the Secret at the start of its expiry window. A certificate
+// already in the window is reported instead, once.
+func (s *certExpiryScheduler) schedule(key model.ConfigKey, cert []byte) {
+	if s == nil || s.window <= 0 {
+		return
+	}
+	notAfter, err := certNotAfter(cert)
+	if err != nil {
+		log.Debugf("cannot schedule the expiry push of %s: %v", key, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, f := s.checks[key]; f {
+		if c.notAfter.Equal(notAfter) && (c.timer != nil || c.reported) {
+			return
+		}
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+	}
+	c := &certExpiryCheck{notAfter: notAfter}
+	s.checks[key] = c
+	delay := notAfter.Add(-s.window).Sub(s.clock())
+	if delay <= 0 {
+		c.reported = true
+		pilotSDSCertificatesExpiring.Increment()
+		log.Warnf("certificate of %s expires at %v and was not rotated", key, notAfter)
+		return
+	}
+	c.timer = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		if s.checks[key] != c {
+			s.mu.Unlock()
+			return
+		}
+		c.timer = nil
+		s.mu.Unlock()
+		s.push(key)
+	})
+}
+
+// certNotAfter returns the expiry of the first certificate of the PEM encoded chain.
+func certNotAfter(chain []byte) (time.Time, error) {
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			return time.Time{}, fmt.Errorf("no certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return cert.NotAfter, nil
+	}
+}
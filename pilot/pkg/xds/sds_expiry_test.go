@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func genExpiringCert(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	cert, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "example.com",
+		NotBefore:    notAfter.Add(-time.Hour),
+		TTL:          time.Hour,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCertExpiryScheduler(t *testing.T) {
+	key := model.ConfigKey{Kind: gvk.Secret, Name: "gateway", Namespace: "istio-system"}
+	pushed := make(chan model.ConfigKey, 10)
+	s := newCertExpiryScheduler(time.Hour, func(key model.ConfigKey) {
+		pushed <- key
+	})
+	// Certificates expire on whole seconds.
+	now := time.Now().Truncate(time.Second)
+	clock := now.Add(-50 * time.Millisecond)
+	s.clock = func() time.Time {
+		return clock
+	}
+
+	// The push is scheduled at the start of the window.
+	s.schedule(key, genExpiringCert(t, now.Add(time.Hour)))
+	select {
+	case got := <-pushed:
+		if got != key {
+			t.Fatalf("pushed %v, want %v", got, key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("certificate not pushed")
+	}
+
+	// The same certificate, still not rotated once pushed, is reported once.
+	clock = now
+	before := s.checks[key]
+	s.schedule(key, genExpiringCert(t, before.notAfter))
+	if c := s.checks[key]; !c.reported || c.timer != nil {
+		t.Fatalf("expected the certificate to be reported, got %+v", c)
+	}
+	s.schedule(key, genExpiringCert(t, before.notAfter))
+
+	// A rotated certificate is scheduled again.
+	s.schedule(key, genExpiringCert(t, now.Add(24*time.Hour)))
+	if c := s.checks[key]; c.reported || c.timer == nil {
+		t.Fatalf("expected the rotated certificate to be scheduled, got %+v", c)
+	}
+	s.checks[key].timer.Stop()
+	select {
+	case got := <-pushed:
+		t.Fatalf("unexpected push of %v", got)
+	default:
+	}
+}
@@ -29,6 +29,7 @@ import (
 	k8stesting "k8s.io/client-go/testing"
 
 	credentials "istio.io/istio/pilot/pkg/credentials/kube"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xdstest"
@@ -288,6 +289,35 @@ func TestGenerate(t *testing.T) {
 	}
 }
 
+func TestGenerateSidecarCredentialName(t *testing.T) {
+	defer func(v bool) { features.EnableSidecarCredentialName = v }(features.EnableSidecarCredentialName)
+	features.EnableSidecarCredentialName = true
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{
+		KubernetesObjects: []runtime.Object{genericCert},
+		KubeClientModifier: func(c kube.Client) {
+			cc := c.Kube().(*fake.Clientset)
+			credentials.DisableAuthorizationForTest(cc)
+		},
+	})
+	proxy := &model.Proxy{
+		VerifiedIdentity: &spiffe.Identity{Namespace: "istio-system"},
+		Type:             model.SidecarProxy,
+		Metadata:         &model.NodeMetadata{ClusterID: "Kubernetes"},
+	}
+	gen := s.Discovery.Generators[v3.SecretType]
+	secrets, _, _ := gen.Generate(s.SetupProxy(proxy), s.PushContext(),
+		&model.WatchedResource{ResourceNames: []string{"kubernetes://generic"}}, &model.PushRequest{Full: true, Start: time.Now()})
+	raw := xdstest.ExtractTLSSecrets(t, model.ResourcesToAny(secrets))
+	scrt, f := raw["kubernetes://generic"]
+	if !f || len(raw) != 1 {
+		t.Fatalf("expected generic secret for sidecar, got %v", raw)
+	}
+	if got := string(scrt.GetTlsCertificate().GetCertificateChain().GetInlineBytes()); got != "generic-cert" {
+		t.Fatalf("unexpected certificate %q", got)
+	}
+}
+
 // TestCaching ensures we don't have cross-proxy cache generation issues. This is split from TestGenerate
 // since it is order dependant.
 // Regression test for https://github.com/istio/istio/issues/33368
@@ -0,0 +1,397 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.uber.org/atomic"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/controller/rollout"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+// stagedRolloutEvaluationInterval is the interval at which the errors reported by the proxies are evaluated to
+// promote or roll back the staged rollouts.
+var stagedRolloutEvaluationInterval = 10 * time.Second
+
+// initStablePushContext builds the push contexts of the audiences served only a part of the changes of the staged
+// rollouts in progress, if any, and returns the configs being rolled out. The audiences served the same changes share
+// a push context. As the push context of the proxies, each is updated incrementally from the previous one: the changes
+// of the rollouts are pushed as updates of their configs.
+func (s *DiscoveryServer) initStablePushContext(req *model.PushRequest, oldPushContext *model.PushContext,
+	push *model.PushContext) (map[model.ConfigKey]struct{}, error) {
+	if s.StagedRollouts == nil {
+		return nil, nil
+	}
+	diverging := s.StagedRollouts.Diverging()
+	if len(diverging) == 0 {
+		return nil, nil
+	}
+	views := map[string]*model.PushContext{}
+	for _, audiences := range s.StagedRollouts.Audiences() {
+		env := *s.Env
+		env.IstioConfigStore = model.MakeIstioStore(s.StagedRollouts.AudienceView(audiences[0]))
+		view := model.NewPushContext()
+		view.PushVersion = push.PushVersion
+		view.JwtKeyResolver = push.JwtKeyResolver
+		var oldView *model.PushContext
+		if oldPushContext != nil {
+			oldView = oldPushContext.StagedViews[audiences[0].String()]
+		}
+		if err := view.InitContext(&env, oldView, req); err != nil {
+			return nil, err
+		}
+		for _, a := range audiences {
+			views[a.String()] = view
+		}
+	}
+	push.StagedViews = views
+	return diverging, nil
+}
+
+// pushContextFor returns the push context the configuration of the proxy is generated from.
+func (s *DiscoveryServer) pushContextFor(proxy *model.Proxy, push *model.PushContext) *model.PushContext {
+	if push == nil || len(push.StagedViews) == 0 || s.StagedRollouts == nil {
+		return push
+	}
+	if view := push.StagedViews[s.StagedRollouts.AudienceOf(proxy.ID, proxy.ConfigNamespace).String()]; view != nil {
+		return view
+	}
+	return push
+}
+
+// stagedRolloutView returns the push context and push request the configuration of the proxy is generated from.
+func (s *DiscoveryServer) stagedRolloutView(proxy *model.Proxy, push *model.PushContext,
+	req *model.PushRequest) (*model.PushContext, *model.PushRequest) {
+	stable := s.pushContextFor(proxy, push)
+	if stable == push {
+		return push, req
+	}
+	if req != nil {
+		copied := *req
+		copied.Push = stable
+		req = &copied
+	}
+	return stable, req
+}
+
+// stagedRolloutCache bypasses the XDS cache for the entries depending on the configs being rolled out, as the canary
+// proxies and the other proxies are served different versions of these entries, under the same cache keys. The other
+// entries are cached as usual.
+type stagedRolloutCache struct {
+	model.XdsCache
+	// diverging holds the map[model.ConfigKey]struct{} of the configs being rolled out.
+	diverging *atomic.Value
+}
+
+func (c stagedRolloutCache) bypass(entry model.XdsCacheEntry) bool {
+	diverging, _ := c.diverging.Load().(map[model.ConfigKey]struct{})
+	if len(diverging) == 0 {
+		return false
+	}
+	for _, key := range entry.DependentConfigs() {
+		if _, f := diverging[key]; f {
+			return true
+		}
+	}
+	for _, kind := range entry.DependentTypes() {
+		for key := range diverging {
+			if key.Kind == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c stagedRolloutCache) Add(entry model.XdsCacheEntry, pushRequest *model.PushRequest, value *discovery.Resource) {
+	if c.bypass(entry) {
+		return
+	}
+	c.XdsCache.Add(entry, pushRequest, value)
+}
+
+func (c stagedRolloutCache) Get(entry model.XdsCacheEntry) (*discovery.Resource, bool) {
+	if c.bypass(entry) {
+		return nil, false
+	}
+	return c.XdsCache.Get(entry)
+}
+
+// stagedRolloutTypes are the types generated from the configs whose changes may be staged. Only their rejections are
+// attributed to the staged rollouts.
+var stagedRolloutTypes = map[string]bool{
+	v3.ClusterType:  true,
+	v3.ListenerType: true,
+	v3.RouteType:    true,
+}
+
+// reportStagedRolloutNack records the rejection of a config by a proxy for the evaluation of the staged rollouts.
+func (s *DiscoveryServer) reportStagedRolloutNack(proxy *model.Proxy, typeURL string) {
+	if stagedRolloutTypes[typeURL] {
+		s.reportStagedRolloutError(proxy)
+	}
+}
+
+// reportStagedRolloutError records an error reported by a proxy for the evaluation of the staged rollouts.
+func (s *DiscoveryServer) reportStagedRolloutError(proxy *model.Proxy) {
+	if s.StagedRollouts != nil {
+		s.StagedRollouts.ReportError(proxy.ID)
+	}
+}
+
+// evaluateStagedRollouts periodically promotes or rolls back the staged rollouts in progress, and pushes the configs
+// whose rollout ended or reached a new wave to the proxies.
+func (s *DiscoveryServer) evaluateStagedRollouts(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(stagedRolloutEvaluationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			snapshot := s.refreshStagedRolloutProxies()
+			proxies := make([]*model.Proxy, 0, len(snapshot.byID))
+			for _, proxy := range snapshot.byID {
+				proxies = append(proxies, proxy)
+			}
+			meshConfig := s.Env.Mesh()
+			// Only the errors of the proxies a config applies to are attributed to its rollout.
+			s.pushEndedRollouts(s.StagedRollouts.Evaluate(proxies, func(cfg config.Config, proxy *model.Proxy) bool {
+				return configAppliesTo(cfg, proxy, meshConfig)
+			}))
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// pushEndedRollouts pushes the configs whose staged rollout ended or reached a new wave to the proxies.
+func (s *DiscoveryServer) pushEndedRollouts(ended []model.ConfigKey) {
+	if len(ended) == 0 {
+		return
+	}
+	configsUpdated := map[model.ConfigKey]struct{}{}
+	for _, key := range ended {
+		configsUpdated[key] = struct{}{}
+	}
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: configsUpdated,
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	})
+}
+
+// stagedRolloutProxies is a snapshot of the connected proxies, taken at each evaluation of the staged rollouts so that
+// the config event handlers do not read the proxies. The proxies of the same type and namespace, sharing a sidecar
+// scope, are grouped as the configs apply to them alike.
+type stagedRolloutProxies struct {
+	// byID are copies of the proxies, by ID.
+	byID map[string]*model.Proxy
+	// groups are the numbers of proxies of each group, by a proxy of the group.
+	groups map[*model.Proxy]int
+}
+
+type proxyGroupKey struct {
+	proxyType    model.NodeType
+	namespace    string
+	sidecarScope *model.SidecarScope
+}
+
+// refreshStagedRolloutProxies takes a snapshot of the connected proxies, read under their locks.
+func (s *DiscoveryServer) refreshStagedRolloutProxies() *stagedRolloutProxies {
+	clients := s.Clients()
+	snapshot := &stagedRolloutProxies{
+		byID:   make(map[string]*model.Proxy, len(clients)),
+		groups: map[*model.Proxy]int{},
+	}
+	groups := map[proxyGroupKey]*model.Proxy{}
+	for _, con := range clients {
+		con.proxy.RLock()
+		proxy := &model.Proxy{
+			ID:              con.proxy.ID,
+			Type:            con.proxy.Type,
+			ConfigNamespace: con.proxy.ConfigNamespace,
+			SidecarScope:    con.proxy.SidecarScope,
+		}
+		con.proxy.RUnlock()
+		snapshot.byID[proxy.ID] = proxy
+		key := proxyGroupKey{proxyType: proxy.Type, namespace: proxy.ConfigNamespace, sidecarScope: proxy.SidecarScope}
+		if groups[key] == nil {
+			groups[key] = proxy
+		}
+		snapshot.groups[groups[key]]++
+	}
+	s.stagedRolloutProxies.Store(snapshot)
+	return snapshot
+}
+
+// ConfigBlastRadius returns the number of connected proxies a VirtualService or DestinationRule applies to, and the
+// number of connected proxies, as of the last evaluation of the staged rollouts. A config applies to the proxies of
+// the namespaces it is exported to which import a service matching one of its hosts, and the VirtualServices bound to
+// gateways apply to all the gateways they are exported to. Only the proxies connected to this istiod are counted.
+func (s *DiscoveryServer) ConfigBlastRadius(cfg config.Config) (affected, total int) {
+	snapshot, _ := s.stagedRolloutProxies.Load().(*stagedRolloutProxies)
+	if snapshot == nil {
+		return 0, 0
+	}
+	meshConfig := s.Env.Mesh()
+	for proxy, n := range snapshot.groups {
+		total += n
+		if configAppliesTo(cfg, proxy, meshConfig) {
+			affected += n
+		}
+	}
+	return affected, total
+}
+
+func configAppliesTo(cfg config.Config, proxy *model.Proxy, meshConfig *meshconfig.MeshConfig) bool {
+	var hosts, exportTo []string
+	mesh, gateways := true, true
+	switch spec := cfg.Spec.(type) {
+	case *networking.VirtualService:
+		hosts, exportTo = spec.Hosts, spec.ExportTo
+		if len(exportTo) == 0 {
+			exportTo = meshConfig.GetDefaultVirtualServiceExportTo()
+		}
+		mesh, gateways = len(spec.Gateways) == 0, false
+		for _, gw := range spec.Gateways {
+			if gw == constants.IstioMeshGateway {
+				mesh = true
+			} else {
+				gateways = true
+			}
+		}
+	case *networking.DestinationRule:
+		hosts, exportTo = []string{spec.Host}, spec.ExportTo
+		if len(exportTo) == 0 {
+			exportTo = meshConfig.GetDefaultDestinationRuleExportTo()
+		}
+	default:
+		return true
+	}
+	if !exportedTo(exportTo, cfg.Namespace, proxy.ConfigNamespace) {
+		return false
+	}
+	if proxy.Type == model.Router && cfg.GroupVersionKind == gvk.VirtualService {
+		return gateways
+	}
+	if proxy.Type != model.Router && !mesh {
+		return false
+	}
+	if proxy.SidecarScope == nil {
+		return true
+	}
+	for _, h := range hosts {
+		name := model.ResolveShortnameToFQDN(h, cfg.Meta)
+		for _, listener := range proxy.SidecarScope.EgressListeners {
+			for _, svc := range listener.Services() {
+				if name.Matches(svc.Hostname) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// exportedTo returns whether a config of the namespace with the exportTo is visible in the namespace of a proxy.
+// Configs without exportTo, nor default exportTo in the mesh config, are visible in all the namespaces.
+func exportedTo(exportTo []string, configNamespace, proxyNamespace string) bool {
+	if len(exportTo) == 0 {
+		return true
+	}
+	for _, e := range exportTo {
+		switch visibility.Instance(e) {
+		case visibility.Public:
+			return true
+		case visibility.Private:
+			if configNamespace == proxyNamespace {
+				return true
+			}
+		default:
+			if e == proxyNamespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rolloutz lists the staged rollouts of config changes. A POST with ?promote=<kind>/<namespace>/<name> or
+// ?rollback=<kind>/<namespace>/<name> ends the rollout in progress or halted of the config right away, for example to
+// push a change staged because it applies to too many proxies.
+func (s *DiscoveryServer) rolloutz(w http.ResponseWriter, req *http.Request) {
+	for param, phase := range map[string]rollout.Phase{"promote": rollout.PhasePromoted, "rollback": rollout.PhaseRolledBack} {
+		target := req.URL.Query().Get(param)
+		if target == "" {
+			continue
+		}
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = fmt.Fprintf(w, "%s requires a POST request\n", param)
+			return
+		}
+		if err := s.overrideRollout(target, phase); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "%v\n", err)
+			return
+		}
+	}
+	rollouts := []rollout.Rollout{}
+	if s.StagedRollouts != nil {
+		rollouts = s.StagedRollouts.Rollouts()
+	}
+	writeJSON(w, rollouts)
+}
+
+// overrideRollout ends the rollout of the config identified by <kind>/<namespace>/<name>, and pushes the config.
+func (s *DiscoveryServer) overrideRollout(target string, phase rollout.Phase) error {
+	if s.StagedRollouts == nil {
+		return fmt.Errorf("staged rollouts are not enabled")
+	}
+	parts := strings.Split(target, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid config %q, must be <kind>/<namespace>/<name>", target)
+	}
+	var kind config.GroupVersionKind
+	switch parts[0] {
+	case gvk.VirtualService.Kind:
+		kind = gvk.VirtualService
+	case gvk.DestinationRule.Kind:
+		kind = gvk.DestinationRule
+	case gvk.Telemetry.Kind:
+		kind = gvk.Telemetry
+	default:
+		return fmt.Errorf("invalid kind %q, must be %s, %s or %s", parts[0], gvk.VirtualService.Kind,
+			gvk.DestinationRule.Kind, gvk.Telemetry.Kind)
+	}
+	key := model.ConfigKey{Kind: kind, Namespace: parts[1], Name: parts[2]}
+	if err := s.StagedRollouts.Override(key, phase); err != nil {
+		return err
+	}
+	s.pushEndedRollouts([]model.ConfigKey{key})
+	return nil
+}
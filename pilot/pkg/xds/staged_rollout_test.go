@@ -0,0 +1,240 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/gogo/protobuf/types"
+	"go.uber.org/atomic"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/controller/rollout"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+const stagedRolloutConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+  annotations:
+    rollout.istio.io/strategy: staged
+spec:
+  hosts:
+  - reviews.example.com
+  http:
+  - timeout: 1s
+    route:
+    - destination:
+        host: reviews.example.com
+`
+
+func TestStagedRolloutPushContext(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: stagedRolloutConfig})
+	rollouts := rollout.NewController(s.Store())
+	s.Discovery.StagedRollouts = rollouts
+	s.Env().IstioConfigStore = model.MakeIstioStore(rollouts.View(false))
+
+	var canary, other *model.Proxy
+	for i := 0; canary == nil || other == nil; i++ {
+		proxy := &model.Proxy{ID: fmt.Sprintf("proxy-%d.default", i), ConfigNamespace: "default"}
+		if rollouts.InCanary(proxy.ID) {
+			canary = proxy
+		} else {
+			other = proxy
+		}
+	}
+	timeout := func(push *model.PushContext, proxy *model.Proxy) int64 {
+		t.Helper()
+		vses := s.Discovery.pushContextFor(proxy, push).VirtualServicesForGateway(proxy, constants.IstioMeshGateway)
+		if len(vses) != 1 {
+			t.Fatalf("expected a single virtual service for %s, got %v", proxy.ID, vses)
+		}
+		return vses[0].Spec.(*networking.VirtualService).Http[0].Timeout.Seconds
+	}
+
+	push, err := s.Discovery.initPushContext(&model.PushRequest{Full: true}, s.PushContext(), "initial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if push.StagedViews != nil {
+		t.Fatalf("expected no staged view without staged rollout")
+	}
+
+	vs := s.Store().Get(gvk.VirtualService, "reviews", "default").DeepCopy()
+	vs.Spec.(*networking.VirtualService).Http[0].Timeout = &types.Duration{Seconds: 5}
+	if _, err := s.Store().Update(vs); err != nil {
+		t.Fatal(err)
+	}
+	push, err = s.Discovery.initPushContext(&model.PushRequest{Full: true}, push, "staged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diverging, _ := s.Discovery.stagedRolloutConfigs.Load().(map[model.ConfigKey]struct{})
+	if _, f := diverging[model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}]; len(push.StagedViews) == 0 || !f {
+		t.Fatalf("expected staged views during the staged rollout")
+	}
+	if got := timeout(push, canary); got != 5 {
+		t.Fatalf("got timeout %ds for the canary proxy, want 5s", got)
+	}
+	if got := timeout(push, other); got != 1 {
+		t.Fatalf("got timeout %ds for the other proxy, want 1s", got)
+	}
+}
+
+type stagedRolloutCacheEntry struct {
+	key     string
+	configs []model.ConfigKey
+	types   []config.GroupVersionKind
+}
+
+func (e stagedRolloutCacheEntry) Key() string                               { return e.key }
+func (e stagedRolloutCacheEntry) DependentTypes() []config.GroupVersionKind { return e.types }
+func (e stagedRolloutCacheEntry) DependentConfigs() []model.ConfigKey       { return e.configs }
+func (e stagedRolloutCacheEntry) Cacheable() bool                           { return true }
+
+func TestStagedRolloutCache(t *testing.T) {
+	staged := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	other := model.ConfigKey{Kind: gvk.VirtualService, Name: "ratings", Namespace: "default"}
+	var diverging atomic.Value
+	diverging.Store(map[model.ConfigKey]struct{}{staged: {}})
+	c := stagedRolloutCache{XdsCache: model.NewLenientXdsCache(), diverging: &diverging}
+
+	bypassed := map[string]bool{
+		"staged":  true,
+		"other":   false,
+		"typed":   true,
+		"untyped": false,
+	}
+	all := []stagedRolloutCacheEntry{
+		{key: "staged", configs: []model.ConfigKey{staged, other}},
+		{key: "other", configs: []model.ConfigKey{other}},
+		{key: "typed", types: []config.GroupVersionKind{gvk.VirtualService}},
+		{key: "untyped", types: []config.GroupVersionKind{gvk.DestinationRule}},
+	}
+	req := &model.PushRequest{Start: time.Now()}
+	for _, e := range all {
+		c.Add(e, req, &discovery.Resource{Name: e.key})
+	}
+	for _, e := range all {
+		if _, f := c.Get(e); f == bypassed[e.key] {
+			t.Errorf("entry %s cached: %v, want %v", e.key, f, !bypassed[e.key])
+		}
+	}
+}
+
+func TestConfigAppliesTo(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: stagedRolloutConfig})
+	push := s.PushContext()
+	sidecar := func(ns string) *model.Proxy {
+		return &model.Proxy{Type: model.SidecarProxy, ConfigNamespace: ns, SidecarScope: model.DefaultSidecarScopeForNamespace(push, ns)}
+	}
+	gateway := &model.Proxy{Type: model.Router, ConfigNamespace: "istio-system",
+		SidecarScope: model.DefaultSidecarScopeForNamespace(push, "istio-system")}
+
+	vs := func(hosts, gateways, exportTo []string) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: "default"},
+			Spec: &networking.VirtualService{Hosts: hosts, Gateways: gateways, ExportTo: exportTo},
+		}
+	}
+	privateByDefault := &meshconfig.MeshConfig{DefaultVirtualServiceExportTo: []string{"."}}
+	cases := []struct {
+		name  string
+		cfg   config.Config
+		proxy *model.Proxy
+		mesh  *meshconfig.MeshConfig
+		want  bool
+	}{
+		{name: "matching host", cfg: vs([]string{"reviews.example.com"}, nil, nil), proxy: sidecar("other"), want: true},
+		{name: "wildcard host", cfg: vs([]string{"*"}, nil, nil), proxy: sidecar("other"), want: true},
+		{name: "other host", cfg: vs([]string{"details.example.com"}, nil, nil), proxy: sidecar("other"), want: false},
+		{name: "not exported", cfg: vs([]string{"*"}, nil, []string{"."}), proxy: sidecar("other"), want: false},
+		{name: "exported", cfg: vs([]string{"*"}, nil, []string{"."}), proxy: sidecar("default"), want: true},
+		{name: "not exported by default", cfg: vs([]string{"*"}, nil, nil), proxy: sidecar("other"), mesh: privateByDefault, want: false},
+		{name: "exported by default", cfg: vs([]string{"*"}, nil, nil), proxy: sidecar("default"), mesh: privateByDefault, want: true},
+		{name: "explicitly exported", cfg: vs([]string{"*"}, nil, []string{"*"}), proxy: sidecar("other"), mesh: privateByDefault, want: true},
+		{name: "gateway only", cfg: vs([]string{"*"}, []string{"istio-system/gw"}, nil), proxy: sidecar("default"), want: false},
+		{name: "bound to gateway", cfg: vs([]string{"*"}, []string{"istio-system/gw"}, nil), proxy: gateway, want: true},
+		{name: "not bound to gateway", cfg: vs([]string{"*"}, nil, nil), proxy: gateway, want: false},
+		{
+			name: "destination rule",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.DestinationRule, Name: "dr", Namespace: "default"},
+				Spec: &networking.DestinationRule{Host: "*.example.com"},
+			},
+			proxy: gateway,
+			want:  true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configAppliesTo(tt.cfg, tt.proxy, tt.mesh); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutzOverride(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: stagedRolloutConfig})
+	rollouts := rollout.NewController(s.Store())
+	s.Discovery.StagedRollouts = rollouts
+	vs := s.Store().Get(gvk.VirtualService, "reviews", "default").DeepCopy()
+	vs.Spec.(*networking.VirtualService).Http[0].Timeout = &types.Duration{Seconds: 5}
+	if _, err := s.Store().Update(vs); err != nil {
+		t.Fatal(err)
+	}
+	if !rollouts.InProgress() {
+		t.Fatalf("expected the change to be staged")
+	}
+
+	// Rollouts are only overridden by POST requests.
+	rec := httptest.NewRecorder()
+	s.Discovery.rolloutz(rec, httptest.NewRequest(http.MethodGet, "/debug/rolloutz?promote=VirtualService/default/reviews", nil))
+	if rec.Code != http.StatusMethodNotAllowed || !rollouts.InProgress() {
+		t.Fatalf("expected the GET request to be rejected, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.Discovery.rolloutz(rec, httptest.NewRequest(http.MethodPost, "/debug/rolloutz?promote=VirtualService/default/reviews", nil))
+	if rec.Code != http.StatusOK || rollouts.InProgress() {
+		t.Fatalf("expected the rollout to be promoted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
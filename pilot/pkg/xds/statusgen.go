@@ -22,6 +22,7 @@ import (
 	status "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
 	"google.golang.org/protobuf/proto"
 	any "google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -135,6 +136,9 @@ func (sg *StatusGen) debugSyncz() model.Resources {
 			clientConfig := &status.ClientConfig{
 				Node: &core.Node{
 					Id: con.proxy.ID,
+					Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+						FeaturesMetadataKey: structpb.NewStringValue(generationFeatures.Fingerprint),
+					}},
 				},
 				GenericXdsConfigs: xdsConfigs,
 			}
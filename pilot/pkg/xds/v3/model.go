@@ -28,12 +28,21 @@ const (
 	EndpointType               = resource.EndpointType
 	ListenerType               = resource.ListenerType
 	RouteType                  = resource.RouteType
+	ScopedRouteType            = resource.ScopedRouteType
+	RuntimeType                = resource.RuntimeType
 	SecretType                 = resource.SecretType
 	ExtensionConfigurationType = resource.ExtensionConfigType
 
 	NameTableType   = apiTypePrefix + "istio.networking.nds.v1.NameTable"
 	HealthInfoType  = apiTypePrefix + "istio.v1.HealthInformation"
 	ProxyConfigType = apiTypePrefix + "istio.mesh.v1alpha1.ProxyConfig"
+	// OutlierEventType carries the outlier detection events read by the agent from the Envoy event log.
+	OutlierEventType = apiTypePrefix + "envoy.data.cluster.v3.OutlierDetectionEvent"
+	// HealthCheckEventType carries the active health check events read by the agent from the Envoy event log.
+	HealthCheckEventType = apiTypePrefix + "envoy.data.core.v3.HealthCheckEvent"
+	// MTLSReportType carries the inbound traffic of the services of a proxy by connection security, read by the
+	// agent from the Envoy stats.
+	MTLSReportType = apiTypePrefix + "istio.v1.MTLSCompatibilityReport"
 	// DebugType requests debug info from istio, a secured implementation for istio debug interface.
 	DebugType     = "istio.io/debug"
 	BootstrapType = apiTypePrefix + "envoy.config.bootstrap.v3.Bootstrap"
@@ -51,6 +60,10 @@ func GetShortType(typeURL string) string {
 		return "LDS"
 	case RouteType:
 		return "RDS"
+	case ScopedRouteType:
+		return "SRDS"
+	case RuntimeType:
+		return "RTDS"
 	case EndpointType:
 		return "EDS"
 	case SecretType:
@@ -75,6 +88,10 @@ func GetMetricType(typeURL string) string {
 		return "lds"
 	case RouteType:
 		return "rds"
+	case ScopedRouteType:
+		return "srds"
+	case RuntimeType:
+		return "rtds"
 	case EndpointType:
 		return "eds"
 	case SecretType:
@@ -24,7 +24,9 @@ import (
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 
 	"istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/mesh"
@@ -554,3 +556,122 @@ spec:
 		})
 	}
 }
+
+func TestExportToClusters(t *testing.T) {
+	k8sObjects := map[cluster.ID]string{}
+	for i := 1; i <= 3; i++ {
+		k8sObjects[cluster.ID(fmt.Sprintf("cluster-%d", i))] = fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: echo-app
+  namespace: default
+  annotations:
+    networking.istio.io/exportToClusters: cluster-2
+spec:
+  clusterIP: 1.2.3.4
+  selector:
+    app: echo-app
+  ports:
+  - name: grpc
+    port: 7070
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: echo-app
+  namespace: default
+  labels:
+    app: echo-app
+subsets:
+- addresses:
+  - ip: 10.0.0.%d
+  ports:
+  - name: grpc
+    port: 7070
+`, i)
+	}
+	s := NewFakeDiscoveryServer(t, FakeOptions{
+		DefaultClusterName:              "cluster-1",
+		KubernetesObjectStringByCluster: k8sObjects,
+	})
+	want := map[cluster.ID][]string{
+		"cluster-1": {"10.0.0.1:7070"},
+		"cluster-2": {"10.0.0.1:7070", "10.0.0.2:7070", "10.0.0.3:7070"},
+		"cluster-3": {"10.0.0.3:7070"},
+	}
+	for clusterID, want := range want {
+		p := &model.Proxy{Metadata: &model.NodeMetadata{ClusterID: clusterID}}
+		eps := xdstest.ExtractLoadAssignments(s.Endpoints(p))["outbound|7070||echo-app.default.svc.cluster.local"]
+		if !listEqualUnordered(eps, want) {
+			t.Errorf("got %v but want %v for %s", eps, want, clusterID)
+		}
+	}
+}
+
+func TestUDPServicePorts(t *testing.T) {
+	defer func(enabled bool) { features.EnableUDPServicePorts = enabled }(features.EnableUDPServicePorts)
+	features.EnableUDPServicePorts = true
+	s := NewFakeDiscoveryServer(t, FakeOptions{KubernetesObjectString: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: dns
+  namespace: default
+spec:
+  clusterIP: 1.2.3.4
+  selector:
+    app: dns
+  ports:
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+    targetPort: 5353
+  - name: dns
+    port: 53
+    protocol: UDP
+    targetPort: 5354
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: dns
+  namespace: default
+  labels:
+    app: dns
+subsets:
+- addresses:
+  - ip: 10.0.0.1
+  ports:
+  - name: dns-tcp
+    port: 5353
+    protocol: TCP
+  - name: dns
+    port: 5354
+    protocol: UDP
+`})
+	tcpCluster := "outbound|53||dns.default.svc.cluster.local"
+	udpCluster := "outbound-udp|53||dns.default.svc.cluster.local"
+
+	proxy := func() *model.Proxy {
+		return s.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{InterceptionMode: model.InterceptionTproxy}})
+	}
+	eps := xdstest.ExtractLoadAssignments(s.Endpoints(proxy()))
+	if got := eps[tcpCluster]; !listEqualUnordered(got, []string{"10.0.0.1:5353"}) {
+		t.Errorf("got endpoints %v for the TCP port", got)
+	}
+	if got := eps[udpCluster]; !listEqualUnordered(got, []string{"10.0.0.1:5354"}) {
+		t.Errorf("got endpoints %v for the UDP port", got)
+	}
+
+	listeners := sets.NewSet(xdstest.ExtractListenerNames(s.Listeners(proxy()))...)
+	if !listeners.Contains("1.2.3.4_53") || !listeners.Contains("udp_1.2.3.4_53") {
+		t.Errorf("expected a TCP and a UDP listener for port 53, got %v", listeners.SortedList())
+	}
+
+	// Sidecars redirecting the traffic with iptables REDIRECT cannot receive the UDP traffic to the service address.
+	redirect := s.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{InterceptionMode: model.InterceptionRedirect}})
+	if clusters := xdstest.ExtractClusters(s.Clusters(redirect)); clusters[udpCluster] != nil {
+		t.Errorf("unexpected UDP cluster for a sidecar without TPROXY interception")
+	}
+}
@@ -37,6 +37,8 @@ type IstioControlPlaneInstance struct {
 	ID string
 	// The Istio version
 	Info istioversion.BuildInfo
+	// The fingerprint of the feature flags in effect for the generated config
+	Features string `json:",omitempty"`
 }
 
 var controlPlane *corev3.ControlPlane
@@ -53,6 +55,7 @@ func init() {
 		Component: "istiod",
 		ID:        podName,
 		Info:      istioversion.Info,
+		Features:  generationFeatures.Fingerprint,
 	})
 	if err != nil {
 		log.Warnf("XDS: Could not serialize control plane id: %v", err)
@@ -98,6 +101,7 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
+	push, req = s.stagedRolloutView(con.proxy, push, req)
 	res, logdata, err := gen.Generate(con.proxy, push, w, req)
 	if err != nil || res == nil {
 		// If we have nothing to send, report that we got an ACK for this version.
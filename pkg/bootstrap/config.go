@@ -56,7 +56,23 @@ const (
 
 	rbacEnvoyStatsMatcherInclusionSuffix = "rbac.allowed,rbac.denied,shadow_allowed,shadow_denied"
 
-	requiredEnvoyStatsMatcherInclusionSuffixes = rbacEnvoyStatsMatcherInclusionSuffix + ",downstream_cx_active" // Needed for draining.
+	// listenerFiltersTimeoutEnvoyStatsMatcherInclusionSuffix counts the connections whose listener filters, such as
+	// the TLS and HTTP inspectors, timed out.
+	listenerFiltersTimeoutEnvoyStatsMatcherInclusionSuffix = "downstream_pre_cx_timeout"
+
+	// passthroughPlaintextTLSEnvoyStatsMatcherInclusionSuffix counts the connections sending plaintext HTTP to unknown
+	// destinations on the ports which only accept TLS, set by PILOT_PASSTHROUGH_PLAINTEXT_TLS_PORTS.
+	passthroughPlaintextTLSEnvoyStatsMatcherInclusionSuffix = "PassthroughPlaintextTLS.downstream_cx_total"
+
+	// udpProxyEnvoyStatsMatcherInclusionSuffix counts the sessions and datagrams proxied to the UDP service ports, set
+	// by PILOT_ENABLE_UDP_SERVICE_PORTS.
+	udpProxyEnvoyStatsMatcherInclusionSuffix = "downstream_sess_total,downstream_sess_rx_datagrams,downstream_sess_tx_datagrams"
+
+	defaultEnvoyStatsMatcherInclusionSuffixes = rbacEnvoyStatsMatcherInclusionSuffix + "," +
+		listenerFiltersTimeoutEnvoyStatsMatcherInclusionSuffix + "," + passthroughPlaintextTLSEnvoyStatsMatcherInclusionSuffix + "," +
+		udpProxyEnvoyStatsMatcherInclusionSuffix
+
+	requiredEnvoyStatsMatcherInclusionSuffixes = defaultEnvoyStatsMatcherInclusionSuffixes + ",downstream_cx_active" // Needed for draining.
 
 	// Prefixes of V2 metrics.
 	// "reporter" prefix is for istio standard metrics.
@@ -86,10 +102,12 @@ func (cfg Config) toTemplateParams() (map[string]interface{}, error) {
 		option.NodeType(cfg.ID),
 		option.PilotSubjectAltName(cfg.Metadata.PilotSubjectAltName),
 		option.OutlierLogPath(cfg.Metadata.OutlierLogPath),
+		option.LoadReporting(bool(cfg.Metadata.LoadReporting)),
 		option.ProvCert(cfg.Metadata.ProvCert),
 		option.DiscoveryHost(discHost),
 		option.Metadata(cfg.Metadata),
-		option.XdsType(xdsType))
+		option.XdsType(xdsType),
+		option.RuntimeDiscovery(features.EnableExtAuthzKillSwitch))
 
 	// Add GCPProjectNumber to access in bootstrap template.
 	md := cfg.Metadata.PlatformMetadata
@@ -186,6 +204,9 @@ var DefaultStatTags = []string{
 	"destination_canonical_service",
 	"source_canonical_revision",
 	"destination_canonical_revision",
+	// Attribution of the traffic to unknown destinations, on the passthrough and blackhole filter chains.
+	"destination_address",
+	"requested_server_name",
 }
 
 func getStatsOptions(meta *model.BootstrapNodeMetadata) []option.Instance {
@@ -239,7 +260,7 @@ func getStatsOptions(meta *model.BootstrapNodeMetadata) []option.Instance {
 		proxyConfigSuffixes = config.ProxyStatsMatcher.InclusionSuffixes
 		proxyConfigRegexps = config.ProxyStatsMatcher.InclusionRegexps
 	}
-	inclusionSuffixes := rbacEnvoyStatsMatcherInclusionSuffix
+	inclusionSuffixes := defaultEnvoyStatsMatcherInclusionSuffixes
 	if meta.ExitOnZeroActiveConnections {
 		inclusionSuffixes = requiredEnvoyStatsMatcherInclusionSuffixes
 	}
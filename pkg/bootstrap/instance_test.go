@@ -274,6 +274,12 @@ func TestGolden(t *testing.T) {
 		{
 			base: "tracing_tls_custom_sni",
 		},
+		{
+			base: "load_reporting",
+			envVars: map[string]string{
+				"ISTIO_META_LOAD_REPORTING": "true",
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -474,9 +480,9 @@ func checkStatsMatcher(t *testing.T, got, want *bootstrap.Bootstrap, stats stats
 		stats.prefixes = v2Prefixes + stats.prefixes + "," + requiredEnvoyStatsMatcherInclusionPrefixes + v2Suffix
 	}
 	if stats.suffixes == "" {
-		stats.suffixes = rbacEnvoyStatsMatcherInclusionSuffix
+		stats.suffixes = defaultEnvoyStatsMatcherInclusionSuffixes
 	} else {
-		stats.suffixes += "," + rbacEnvoyStatsMatcherInclusionSuffix
+		stats.suffixes += "," + defaultEnvoyStatsMatcherInclusionSuffixes
 	}
 
 	if err := gsm.Validate(); err != nil {
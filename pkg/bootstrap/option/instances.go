@@ -108,6 +108,10 @@ func OutlierLogPath(value string) Instance {
 	return newOptionOrSkipIfZero("outlier_log_path", value)
 }
 
+func LoadReporting(value bool) Instance {
+	return newOptionOrSkipIfZero("load_reporting", value)
+}
+
 func LightstepAddress(value string) Instance {
 	return newOptionOrSkipIfZero("lightstep", value).withConvert(addressConverter(value))
 }
@@ -240,6 +244,10 @@ func STSEnabled(value bool) Instance {
 	return newOption("sts", value)
 }
 
+func RuntimeDiscovery(value bool) Instance {
+	return newOption("runtime_discovery", value)
+}
+
 func ProvCert(value string) Instance {
 	return newOption("provisioned_cert", value)
 }
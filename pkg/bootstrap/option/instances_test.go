@@ -639,6 +639,12 @@ func TestOptions(t *testing.T) {
 			option:   option.STSEnabled(true),
 			expected: true,
 		},
+		{
+			testName: "runtime discovery",
+			key:      "runtime_discovery",
+			option:   option.RuntimeDiscovery(true),
+			expected: true,
+		},
 		{
 			testName: "sts port",
 			key:      "sts_port",
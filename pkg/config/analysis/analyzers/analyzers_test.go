@@ -253,6 +253,17 @@ var testGrid = []testCase{
 		analyzer:   &service.PortNameAnalyzer{},
 		expected:   []message{},
 	},
+	{
+		name:       "servicePortProtocolConflict",
+		inputFiles: []string{"testdata/service-protocol-conflict.yaml"},
+		analyzer:   &service.ProtocolConflictAnalyzer{},
+		expected: []message{
+			{msg.ServicePortProtocolConflict, "Service bookinfo/details"},
+			{msg.ServicePortProtocolConflict, "Service bookinfo/details-tcp"},
+			{msg.ServicePortProtocolConflict, "Service bookinfo/reviews"},
+			{msg.ServicePortProtocolConflict, "Service bookinfo/reviews-grpc"},
+		},
+	},
 	{
 		name:       "sidecarDefaultSelector",
 		inputFiles: []string{"testdata/sidecar-default-selector.yaml"},
@@ -463,6 +474,46 @@ var testGrid = []testCase{
 		analyzer: &destinationrule.CaCertificateAnalyzer{},
 		expected: []message{},
 	},
+	{
+		name: "destinationrule tls mode conflicting with peerauthentication",
+		inputFiles: []string{
+			"testdata/destinationrule-mtls-conflict.yaml",
+		},
+		analyzer: &destinationrule.MTLSConflictAnalyzer{},
+		expected: []message{
+			{msg.DestinationRuleMTLSConflict, "DestinationRule bookinfo/reviews"},
+			{msg.DestinationRuleMTLSConflict, "DestinationRule bookinfo/ratings"},
+		},
+	},
+	{
+		name: "destinationrule locality failover without outlier detection",
+		inputFiles: []string{
+			"testdata/destinationrule-locality-lb.yaml",
+		},
+		analyzer: &destinationrule.LocalityLBAnalyzer{},
+		expected: []message{
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/reviews"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+		},
+	},
+	{
+		name: "mesh locality failover without outlier detection",
+		inputFiles: []string{
+			"testdata/destinationrule-locality-lb.yaml",
+		},
+		meshConfigFile: "testdata/mesh-with-locality-failover.yaml",
+		analyzer:       &destinationrule.LocalityLBAnalyzer{},
+		expected: []message{
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/reviews"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/httpbin"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+			{msg.LocalityLoadBalancingWithoutOutlierDetection, "DestinationRule default/productpage"},
+		},
+	},
 	{
 		name: "dupmatches",
 		inputFiles: []string{
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// LocalityLBAnalyzer checks if locality failover is enabled for DestinationRule traffic policies without outlier
+// detection, as the traffic then never fails over.
+type LocalityLBAnalyzer struct{}
+
+var _ analysis.Analyzer = &LocalityLBAnalyzer{}
+
+func (l *LocalityLBAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.LocalityLBAnalyzer",
+		Description: "Checks if locality failover is enabled without outlier detection",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+		},
+	}
+}
+
+func (l *LocalityLBAnalyzer) Analyze(ctx analysis.Context) {
+	var meshLocalityLB *v1alpha3.LocalityLoadBalancerSetting
+	ctx.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		meshLocalityLB = r.Message.(*v1alpha1.MeshConfig).GetLocalityLbSetting()
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		l.analyzeDestinationRule(r, ctx, meshLocalityLB)
+		return true
+	})
+}
+
+func (l *LocalityLBAnalyzer) analyzeDestinationRule(r *resource.Instance, ctx analysis.Context,
+	meshLocalityLB *v1alpha3.LocalityLoadBalancerSetting) {
+	dr := r.Message.(*v1alpha3.DestinationRule)
+	policy := dr.GetTrafficPolicy()
+	check := func(lb *v1alpha3.LoadBalancerSettings, od *v1alpha3.OutlierDetection, trafficPolicy, path string) {
+		source := "MeshConfig"
+		if lb.GetLocalityLbSetting() != nil {
+			source = "DestinationRule"
+		} else if len(meshLocalityLB.GetFailover()) == 0 && len(meshLocalityLB.GetFailoverPriority()) == 0 {
+			// Only report the default locality load balancing of the mesh if it explicitly configures failover.
+			return
+		}
+		if od != nil || !localityFailoverEnabled(meshLocalityLB, lb) {
+			return
+		}
+		m := msg.NewLocalityLoadBalancingWithoutOutlierDetection(r, source, trafficPolicy)
+		if line, ok := util.ErrorLine(r, path); ok {
+			m.Line = line
+		}
+		ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), m)
+	}
+
+	check(policy.GetLoadBalancer(), policy.GetOutlierDetection(), "the traffic policy", util.DestinationRuleHost)
+	// Port level settings do not inherit the settings of the traffic policy.
+	for i, p := range policy.GetPortLevelSettings() {
+		check(p.GetLoadBalancer(), p.GetOutlierDetection(), fmt.Sprintf("the traffic policy of port %d", p.GetPort().GetNumber()),
+			fmt.Sprintf(util.DestinationRulePortLevelPort, i))
+	}
+	for i, s := range dr.GetSubsets() {
+		sp := s.GetTrafficPolicy()
+		// Subsets only differ from the traffic policy if they override its load balancer or outlier detection.
+		if sp.GetLoadBalancer() != nil || sp.GetOutlierDetection() != nil {
+			lb, od := policy.GetLoadBalancer(), policy.GetOutlierDetection()
+			if sp.GetLoadBalancer() != nil {
+				lb = sp.GetLoadBalancer()
+			}
+			if sp.GetOutlierDetection() != nil {
+				od = sp.GetOutlierDetection()
+			}
+			check(lb, od, fmt.Sprintf("the traffic policy of subset %s", s.GetName()), fmt.Sprintf(util.DestinationRuleSubsetName, i))
+		}
+		for j, p := range sp.GetPortLevelSettings() {
+			check(p.GetLoadBalancer(), p.GetOutlierDetection(),
+				fmt.Sprintf("the traffic policy of port %d of subset %s", p.GetPort().GetNumber(), s.GetName()),
+				fmt.Sprintf(util.DestinationRuleSubsetPortLevelPort, i, j))
+		}
+	}
+}
+
+// localityFailoverEnabled returns whether locality load balancing applies to the load balancer settings, in failover
+// mode rather than with weights distributing the traffic among the localities.
+func localityFailoverEnabled(mesh *v1alpha3.LocalityLoadBalancerSetting, lb *v1alpha3.LoadBalancerSettings) bool {
+	setting := loadbalancer.GetLocalityLbSetting(mesh, lb.GetLocalityLbSetting())
+	return setting != nil && len(setting.GetDistribute()) == 0
+}
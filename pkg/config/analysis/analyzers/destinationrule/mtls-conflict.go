@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// MTLSConflictAnalyzer checks if the TLS mode of a DestinationRule conflicts with the mTLS mode of the
+// PeerAuthentications applying to the pods of its host, for each port of the host.
+type MTLSConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &MTLSConflictAnalyzer{}
+
+func (c *MTLSConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.MTLSConflictAnalyzer",
+		Description: "Checks if the TLS mode of a DestinationRule conflicts with the mTLS mode of the PeerAuthentications of its host",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioSecurityV1Beta1Peerauthentications.Name(),
+			collections.K8SCoreV1Namespaces.Name(),
+			collections.K8SCoreV1Pods.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+func (c *MTLSConflictAnalyzer) Analyze(ctx analysis.Context) {
+	rootNamespace := resource.Namespace("")
+	ctx.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		rootNamespace = resource.Namespace(r.Message.(*v1alpha1.MeshConfig).GetRootNamespace())
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	var policies []*resource.Instance
+	ctx.ForEach(collections.IstioSecurityV1Beta1Peerauthentications.Name(), func(r *resource.Instance) bool {
+		policies = append(policies, r)
+		return true
+	})
+	// The oldest policy wins when several apply at the same level, as in istiod.
+	sort.SliceStable(policies, func(i, j int) bool {
+		if !policies[i].Metadata.CreateTime.Equal(policies[j].Metadata.CreateTime) {
+			return policies[i].Metadata.CreateTime.Before(policies[j].Metadata.CreateTime)
+		}
+		return policies[i].Metadata.FullName.String() < policies[j].Metadata.FullName.String()
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		c.analyzeDestinationRule(r, ctx, rootNamespace, policies)
+		return true
+	})
+}
+
+func (c *MTLSConflictAnalyzer) analyzeDestinationRule(r *resource.Instance, ctx analysis.Context,
+	rootNamespace resource.Namespace, policies []*resource.Instance) {
+	dr := r.Message.(*v1alpha3.DestinationRule)
+	svcName := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, dr.GetHost())
+	svc := ctx.Find(collections.K8SCoreV1Services.Name(), svcName)
+	if svc == nil {
+		return
+	}
+	spec := svc.Message.(*v1.ServiceSpec)
+	if len(spec.Selector) == 0 {
+		return
+	}
+	pods := selectedMeshPods(ctx, svcName.Namespace, k8s_labels.SelectorFromSet(spec.Selector))
+
+	for _, port := range spec.Ports {
+		mode, path := destinationRuleTLSMode(dr, uint32(port.Port))
+		if path == "" {
+			continue
+		}
+		for _, pod := range pods {
+			targetPort := util.PodTargetPort(pod.Message.(*v1.PodSpec), port)
+			if targetPort == 0 {
+				continue
+			}
+			policy, policyMode := peerAuthenticationMode(pod, uint32(targetPort), rootNamespace, policies)
+			if !mtlsModesConflict(mode, policyMode) {
+				continue
+			}
+			m := msg.NewDestinationRuleMTLSConflict(r, mode.String(), int(port.Port), dr.GetHost(),
+				policy.Metadata.FullName.String(), policyMode.String(), targetPort, pod.Metadata.FullName.String())
+			if line, ok := util.ErrorLine(r, path); ok {
+				m.Line = line
+			}
+			ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), m)
+			// Report each port once, the other pods most likely share the same policy.
+			break
+		}
+	}
+}
+
+// selectedMeshPods returns the pods of the namespace in the mesh matching the selector, sorted by name.
+func selectedMeshPods(ctx analysis.Context, ns resource.Namespace, selector k8s_labels.Selector) []*resource.Instance {
+	var pods []*resource.Instance
+	ctx.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if r.Metadata.FullName.Namespace == ns && selector.Matches(k8s_labels.Set(r.Metadata.Labels)) && util.PodInMesh(r, ctx) {
+			pods = append(pods, r)
+		}
+		return true
+	})
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Metadata.FullName.String() < pods[j].Metadata.FullName.String()
+	})
+	return pods
+}
+
+// destinationRuleTLSMode returns the TLS mode the DestinationRule sets for the port, and the path of the mode in
+// the DestinationRule. The path is empty if the DestinationRule does not set TLS settings for the port.
+func destinationRuleTLSMode(dr *v1alpha3.DestinationRule, port uint32) (v1alpha3.ClientTLSSettings_TLSmode, string) {
+	for i, p := range dr.GetTrafficPolicy().GetPortLevelSettings() {
+		if p.GetPort().GetNumber() == port && p.GetTls() != nil {
+			return p.GetTls().GetMode(), fmt.Sprintf(util.DestinationRuleTLSPortLevelMode, i)
+		}
+	}
+	if tls := dr.GetTrafficPolicy().GetTls(); tls != nil {
+		return tls.GetMode(), util.DestinationRuleTLSMode
+	}
+	return v1alpha3.ClientTLSSettings_DISABLE, ""
+}
+
+// peerAuthenticationMode returns the PeerAuthentication setting the mTLS mode of the port of the pod, and the mode.
+// The workload policy takes precedence over the namespace policy, which takes precedence over the mesh policy,
+// each of them inheriting the mode of the previous one if unset. The returned policy is nil if none sets the mode,
+// which is PERMISSIVE by default.
+func peerAuthenticationMode(pod *resource.Instance, port uint32, rootNamespace resource.Namespace,
+	policies []*resource.Instance) (*resource.Instance, v1beta1.PeerAuthentication_MutualTLS_Mode) {
+	var mesh, namespace, workload *resource.Instance
+	podLabels := k8s_labels.Set(pod.Metadata.Labels)
+	for _, p := range policies {
+		pa := p.Message.(*v1beta1.PeerAuthentication)
+		ns := p.Metadata.FullName.Namespace
+		switch {
+		case ns == pod.Metadata.FullName.Namespace && pa.GetSelector() != nil:
+			if workload == nil && k8s_labels.SelectorFromSet(pa.GetSelector().GetMatchLabels()).Matches(podLabels) {
+				workload = p
+			}
+		case ns == pod.Metadata.FullName.Namespace:
+			if namespace == nil {
+				namespace = p
+			}
+		case ns == rootNamespace && pa.GetSelector() == nil:
+			if mesh == nil {
+				mesh = p
+			}
+		}
+	}
+
+	var policy *resource.Instance
+	mode := v1beta1.PeerAuthentication_MutualTLS_PERMISSIVE
+	for _, p := range []*resource.Instance{mesh, namespace, workload} {
+		if p == nil {
+			continue
+		}
+		pa := p.Message.(*v1beta1.PeerAuthentication)
+		m := pa.GetMtls().GetMode()
+		if p == workload {
+			if portMTLS, f := pa.GetPortLevelMtls()[port]; f && portMTLS.GetMode() != v1beta1.PeerAuthentication_MutualTLS_UNSET {
+				m = portMTLS.GetMode()
+			}
+		}
+		if m != v1beta1.PeerAuthentication_MutualTLS_UNSET {
+			policy, mode = p, m
+		}
+	}
+	return policy, mode
+}
+
+// mtlsModesConflict returns whether a client using the TLS mode of a DestinationRule fails to connect to a server
+// with the mTLS mode of a PeerAuthentication.
+func mtlsModesConflict(mode v1alpha3.ClientTLSSettings_TLSmode, policyMode v1beta1.PeerAuthentication_MutualTLS_Mode) bool {
+	switch policyMode {
+	case v1beta1.PeerAuthentication_MutualTLS_STRICT:
+		return mode != v1alpha3.ClientTLSSettings_ISTIO_MUTUAL
+	case v1beta1.PeerAuthentication_MutualTLS_DISABLE:
+		return mode == v1alpha3.ClientTLSSettings_ISTIO_MUTUAL
+	default:
+		return false
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	configKube "istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ProtocolConflictAnalyzer checks if Services selecting the same pods declare different protocols, through their
+// port names or appProtocols, for the same target port.
+type ProtocolConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &ProtocolConflictAnalyzer{}
+
+// Metadata implements Analyzer
+func (s *ProtocolConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "service.ProtocolConflictAnalyzer",
+		Description: "Checks if Services selecting the same pods declare different protocols for the same target port",
+		Inputs: collection.Names{
+			collections.K8SCoreV1Namespaces.Name(),
+			collections.K8SCoreV1Pods.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// servicePort is a port of a Service, along with its index in the Service ports.
+type servicePort struct {
+	service  *resource.Instance
+	index    int
+	port     v1.ServicePort
+	protocol protocol.Instance
+}
+
+// targetPortKey identifies a port of a pod. The transport protocol is part of the key, as a Service may expose the
+// same port number for both TCP and UDP.
+type targetPortKey struct {
+	port      int
+	transport v1.Protocol
+}
+
+// Analyze implements Analyzer
+func (s *ProtocolConflictAnalyzer) Analyze(c analysis.Context) {
+	services := map[resource.Namespace][]*resource.Instance{}
+	c.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		ns := r.Metadata.FullName.Namespace
+		if util.IsSystemNamespace(ns) || len(r.Message.(*v1.ServiceSpec).Selector) == 0 {
+			return true
+		}
+		services[ns] = append(services[ns], r)
+		return true
+	})
+
+	var pods []*resource.Instance
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if len(services[r.Metadata.FullName.Namespace]) > 0 && util.PodInMesh(r, c) {
+			pods = append(pods, r)
+		}
+		return true
+	})
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Metadata.FullName.String() < pods[j].Metadata.FullName.String()
+	})
+
+	// Report each conflicting pair of ports once, the other pods most likely have the same ports.
+	reported := map[string]bool{}
+	for _, pod := range pods {
+		s.analyzePod(pod, services[pod.Metadata.FullName.Namespace], reported, c)
+	}
+}
+
+func (s *ProtocolConflictAnalyzer) analyzePod(pod *resource.Instance, services []*resource.Instance,
+	reported map[string]bool, c analysis.Context) {
+	podLabels := k8s_labels.Set(pod.Metadata.Labels)
+	ports := map[targetPortKey]servicePort{}
+	for _, svc := range services {
+		spec := svc.Message.(*v1.ServiceSpec)
+		if !k8s_labels.SelectorFromSet(spec.Selector).Matches(podLabels) {
+			continue
+		}
+		for i, port := range spec.Ports {
+			targetPort := util.PodTargetPort(pod.Message.(*v1.PodSpec), port)
+			if targetPort == 0 {
+				continue
+			}
+			sp := servicePort{
+				service:  svc,
+				index:    i,
+				port:     port,
+				protocol: configKube.ConvertProtocol(port.Port, port.Name, port.Protocol, port.AppProtocol),
+			}
+			key := targetPortKey{port: targetPort, transport: port.Protocol}
+			other, f := ports[key]
+			if !f {
+				ports[key] = sp
+				continue
+			}
+			if other.protocol == sp.protocol {
+				continue
+			}
+			s.report(sp, other, targetPort, pod, reported, c)
+			s.report(other, sp, targetPort, pod, reported, c)
+		}
+	}
+}
+
+func (s *ProtocolConflictAnalyzer) report(sp, other servicePort, targetPort int, pod *resource.Instance,
+	reported map[string]bool, c analysis.Context) {
+	key := fmt.Sprintf("%s/%d/%s/%d", sp.service.Metadata.FullName, sp.port.Port, other.service.Metadata.FullName, other.port.Port)
+	if reported[key] {
+		return
+	}
+	reported[key] = true
+
+	m := msg.NewServicePortProtocolConflict(sp.service, int(sp.port.Port), protocolName(sp.protocol),
+		int(other.port.Port), other.service.Metadata.FullName.String(), protocolName(other.protocol),
+		targetPort, pod.Metadata.FullName.String())
+	if line, ok := util.ErrorLine(sp.service, fmt.Sprintf(util.PortInPorts, sp.index)); ok {
+		m.Line = line
+	}
+	c.Report(collections.K8SCoreV1Services.Name(), m)
+}
+
+// protocolName returns the name of the protocol as shown to users.
+func protocolName(p protocol.Instance) string {
+	if p.IsUnsupported() {
+		return "auto-detected"
+	}
+	return string(p)
+}
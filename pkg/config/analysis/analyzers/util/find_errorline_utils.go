@@ -101,6 +101,30 @@ const (
 	// Path for DestinationRule port-level tls certificate.
 	// Required parameters: portLevelSettings index.
 	DestinationRuleTLSPortLevelCert = "{.spec.trafficPolicy.portLevelSettings[%d].tls.caCertificates}"
+
+	// Path for DestinationRule tls mode.
+	// Required parameters: none.
+	DestinationRuleTLSMode = "{.spec.trafficPolicy.tls.mode}"
+
+	// Path for DestinationRule port-level tls mode.
+	// Required parameters: portLevelSettings index.
+	DestinationRuleTLSPortLevelMode = "{.spec.trafficPolicy.portLevelSettings[%d].tls.mode}"
+
+	// Path for DestinationRule host.
+	// Required parameters: none.
+	DestinationRuleHost = "{.spec.host}"
+
+	// Path for DestinationRule port-level port number.
+	// Required parameters: portLevelSettings index.
+	DestinationRulePortLevelPort = "{.spec.trafficPolicy.portLevelSettings[%d].port.number}"
+
+	// Path for DestinationRule subset name.
+	// Required parameters: subset index.
+	DestinationRuleSubsetName = "{.spec.subsets[%d].name}"
+
+	// Path for DestinationRule subset port-level port number.
+	// Required parameters: subset index, portLevelSettings index.
+	DestinationRuleSubsetPortLevelPort = "{.spec.subsets[%d].trafficPolicy.portLevelSettings[%d].port.number}"
 )
 
 // ErrorLine returns the line number of the input path key in the resource
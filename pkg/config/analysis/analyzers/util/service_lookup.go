@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"istio.io/api/annotation"
 	"istio.io/api/networking/v1alpha3"
@@ -110,3 +111,23 @@ func GetDestinationHost(sourceNs resource.Namespace, host string, serviceEntryHo
 
 	return nil
 }
+
+// PodTargetPort returns the port of the pod the traffic to the service port is sent to, or 0 if the pod does not
+// expose the named target port.
+func PodTargetPort(pod *corev1.PodSpec, port corev1.ServicePort) int {
+	switch {
+	case port.TargetPort.Type == intstr.String:
+		for _, c := range pod.Containers {
+			for _, p := range c.Ports {
+				if p.Name == port.TargetPort.StrVal {
+					return int(p.ContainerPort)
+				}
+			}
+		}
+		return 0
+	case port.TargetPort.IntVal != 0:
+		return int(port.TargetPort.IntVal)
+	default:
+		return int(port.Port)
+	}
+}
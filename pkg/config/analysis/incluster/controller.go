@@ -17,10 +17,15 @@
 package incluster
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+
 	v1alpha12 "istio.io/api/analysis/v1alpha1"
 	"istio.io/api/meta/v1alpha1"
 	"istio.io/istio/pilot/pkg/config/kube/crdclient"
@@ -29,6 +34,7 @@ import (
 	"istio.io/istio/pkg/config/analysis/analyzers"
 	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/local"
+	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -36,11 +42,17 @@ import (
 	"istio.io/pkg/log"
 )
 
+// ServicePortProtocolConflictCondition is the type of the status condition marking Services declaring a protocol
+// for a target port which conflicts with the one of another Service selecting the same pods.
+const ServicePortProtocolConflictCondition = "PortProtocolConflict"
+
 // Controller manages repeatedly running analyzers in istiod, and reporting results
 // via istio status fields.
 type Controller struct {
-	analyzer  *local.IstiodAnalyzer
-	statusctl *status.Controller
+	analyzer   *local.IstiodAnalyzer
+	statusctl  *status.Controller
+	kubeClient kube.Client
+	services   listerv1.ServiceLister
 }
 
 func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreCache,
@@ -56,6 +68,8 @@ func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreCache,
 		return nil, fmt.Errorf("unable to load common types for analysis, releasing lease: %v", err)
 	}
 	ia.AddSource(store)
+	// The Services are read from the informer, which must be registered before the client is started.
+	services := kubeClient.KubeInformer().Core().V1().Services().Lister()
 	kubeClient.RunAndWait(stop)
 	err = ia.Init(stop)
 	if err != nil {
@@ -70,7 +84,7 @@ func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreCache,
 		}
 		return status
 	})
-	return &Controller{analyzer: ia, statusctl: ctl}, nil
+	return &Controller{analyzer: ia, statusctl: ctl, kubeClient: kubeClient, services: services}, nil
 }
 
 // Run is blocking
@@ -106,6 +120,8 @@ func (c *Controller) Run(stop <-chan struct{}) {
 				if strings.HasSuffix(r.Group, "istio.io") {
 					log.Debugf("enqueueing update for %s/%s", r.Namespace, r.Name)
 					c.statusctl.EnqueueStatusUpdateResource(m, r)
+				} else if r.Group == "" && r.Resource == "services" {
+					c.updateServiceCondition(r, m)
 				}
 			}
 			oldmsgs = res.Messages
@@ -116,3 +132,42 @@ func (c *Controller) Run(stop <-chan struct{}) {
 		}
 	}
 }
+
+// updateServiceCondition sets the PortProtocolConflict condition of a Service from its analysis messages. Services are
+// not istio types, so the condition is written directly rather than through the status controller.
+func (c *Controller) updateServiceCondition(r status.Resource, msgs diag.Messages) {
+	var conflicts []string
+	for _, m := range msgs {
+		if m.Type == msg.ServicePortProtocolConflict {
+			conflicts = append(conflicts, fmt.Sprintf(m.Type.Template(), m.Parameters...))
+		}
+	}
+	svc, err := c.services.Services(r.Namespace).Get(r.Name)
+	if err != nil {
+		log.Debugf("unable to get Service %s/%s: %v", r.Namespace, r.Name, err)
+		return
+	}
+	current := meta.FindStatusCondition(svc.Status.Conditions, ServicePortProtocolConflictCondition)
+	desired := metav1.Condition{
+		Type:               ServicePortProtocolConflictCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: svc.Generation,
+		Reason:             "NoConflict",
+		Message:            "No port protocol conflict with other Services",
+	}
+	if len(conflicts) > 0 {
+		desired.Status = metav1.ConditionTrue
+		desired.Reason = "ConflictingProtocols"
+		desired.Message = strings.Join(conflicts, "; ")
+	} else if current == nil {
+		return
+	}
+	if current != nil && current.Status == desired.Status && current.Message == desired.Message {
+		return
+	}
+	svc = svc.DeepCopy()
+	meta.SetStatusCondition(&svc.Status.Conditions, desired)
+	if _, err := c.kubeClient.CoreV1().Services(r.Namespace).UpdateStatus(context.TODO(), svc, metav1.UpdateOptions{}); err != nil {
+		log.Warnf("unable to update %s condition of Service %s/%s: %v", ServicePortProtocolConflictCondition, r.Namespace, r.Name, err)
+	}
+}
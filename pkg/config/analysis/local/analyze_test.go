@@ -25,12 +25,16 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/kube"
 )
 
@@ -297,3 +301,31 @@ func tempFileFromString(t *testing.T, content string) *os.File {
 	}
 	return tmpfile
 }
+
+func TestRegisterEventHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	cancel := make(chan struct{})
+	defer close(cancel)
+
+	sa := NewSourceAnalyzer(blankCombinedAnalyzer, "", "", nil, false, timeout)
+	store := memory.NewSyncController(memory.Make(collection.SchemasFor(collections.IstioNetworkingV1Alpha3Serviceentries)))
+	sa.AddSource(store)
+
+	changes := 0
+	sa.RegisterEventHandler(func() {
+		changes++
+	})
+	g.Expect(sa.Init(cancel)).To(Succeed())
+
+	_, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.IstioNetworkingV1Alpha3Serviceentries.Resource().GroupVersionKind(),
+			Name:             "se",
+			Namespace:        "default",
+		},
+		Spec: &networking.ServiceEntry{Hosts: []string{"example.com"}},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(changes).To(Equal(1))
+}
@@ -295,6 +295,18 @@ func (sa *IstiodAnalyzer) AddRunningKubeSource(c kubelib.Client) {
 	}
 }
 
+// RegisterEventHandler registers a handler called when a resource of the live sources changes, for example to
+// analyze again with ReAnalyze. It must be called before Init.
+func (sa *IstiodAnalyzer) RegisterEventHandler(handler func()) {
+	for _, store := range sa.stores {
+		for _, s := range store.Schemas().All() {
+			store.RegisterEventHandler(s.Resource().GroupVersionKind(), func(config.Config, config.Config, model.Event) {
+				handler()
+			})
+		}
+	}
+}
+
 // AddSource adds a source based on user supplied configstore to the current IstiodAnalyzer
 // Assumes that the source has same or subset of resource types that this analyzer is configured with.
 // This can be used by external users who import the analyzer as a module within their own controllers.
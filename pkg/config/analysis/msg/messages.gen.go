@@ -196,6 +196,18 @@ var (
 	// ExternalNameServiceTypeInvalidPortName defines a diag.MessageType for message "ExternalNameServiceTypeInvalidPortName".
 	// Description: Proxy may prevent tcp named ports and unmatched traffic for ports serving TCP protocol from being forwarded correctly for ExternalName services.
 	ExternalNameServiceTypeInvalidPortName = diag.NewMessageType(diag.Warning, "IST0150", "Port name for ExternalName service is invalid. Proxy may prevent tcp named ports and unmatched traffic for ports serving TCP protocol from being forwarded correctly")
+
+	// DestinationRuleMTLSConflict defines a diag.MessageType for message "DestinationRuleMTLSConflict".
+	// Description: The TLS mode of a DestinationRule conflicts with the mTLS mode of the PeerAuthentication applying to the destination workloads, so the requests fail with 503 UC.
+	DestinationRuleMTLSConflict = diag.NewMessageType(diag.Error, "IST0151", "DestinationRule sets TLS mode %s for port %d of host %s, but PeerAuthentication %s sets mTLS mode %s for the target port %d of pod %s. Requests to this port will fail with 503 UC.")
+
+	// LocalityLoadBalancingWithoutOutlierDetection defines a diag.MessageType for message "LocalityLoadBalancingWithoutOutlierDetection".
+	// Description: Locality failover is enabled for a DestinationRule traffic policy without outlier detection, so the traffic never fails over to other localities.
+	LocalityLoadBalancingWithoutOutlierDetection = diag.NewMessageType(diag.Warning, "IST0152", "Locality failover enabled by %s is not applied to %s, as it has no outlier detection. Add an outlierDetection to it so that unhealthy endpoints are ejected and the traffic fails over to other localities.")
+
+	// ServicePortProtocolConflict defines a diag.MessageType for message "ServicePortProtocolConflict".
+	// Description: Services selecting the same pods declare different protocols for the same target port, so the behavior of the proxy for this port is undefined.
+	ServicePortProtocolConflict = diag.NewMessageType(diag.Error, "IST0153", "Port %d of the Service is %s, but port %d of Service %s is %s for the same target port %d of pod %s. The protocol used by the proxy for this port is undefined.")
 )
 
 // All returns a list of all known message types.
@@ -248,6 +260,9 @@ func All() []*diag.MessageType {
 		NamespaceInjectionEnabledByDefault,
 		JwtClaimBasedRoutingWithoutRequestAuthN,
 		ExternalNameServiceTypeInvalidPortName,
+		DestinationRuleMTLSConflict,
+		LocalityLoadBalancingWithoutOutlierDetection,
+		ServicePortProtocolConflict,
 	}
 }
 
@@ -719,3 +734,43 @@ func NewExternalNameServiceTypeInvalidPortName(r *resource.Instance) diag.Messag
 		r,
 	)
 }
+
+// NewDestinationRuleMTLSConflict returns a new diag.Message based on DestinationRuleMTLSConflict.
+func NewDestinationRuleMTLSConflict(r *resource.Instance, mode string, port int, host string, peerAuthentication string, peerAuthenticationMode string, targetPort int, pod string) diag.Message {
+	return diag.NewMessage(
+		DestinationRuleMTLSConflict,
+		r,
+		mode,
+		port,
+		host,
+		peerAuthentication,
+		peerAuthenticationMode,
+		targetPort,
+		pod,
+	)
+}
+
+// NewLocalityLoadBalancingWithoutOutlierDetection returns a new diag.Message based on LocalityLoadBalancingWithoutOutlierDetection.
+func NewLocalityLoadBalancingWithoutOutlierDetection(r *resource.Instance, source string, trafficPolicy string) diag.Message {
+	return diag.NewMessage(
+		LocalityLoadBalancingWithoutOutlierDetection,
+		r,
+		source,
+		trafficPolicy,
+	)
+}
+
+// NewServicePortProtocolConflict returns a new diag.Message based on ServicePortProtocolConflict.
+func NewServicePortProtocolConflict(r *resource.Instance, port int, protocol string, otherPort int, otherService string, otherProtocol string, targetPort int, pod string) diag.Message {
+	return diag.NewMessage(
+		ServicePortProtocolConflict,
+		r,
+		port,
+		protocol,
+		otherPort,
+		otherService,
+		otherProtocol,
+		targetPort,
+		pod,
+	)
+}
@@ -139,4 +139,22 @@ const (
 	// CertProviderNone does not create any certificates for the control plane. It is assumed that some external
 	// load balancer, such as an Istio Gateway, is terminating the TLS.
 	CertProviderNone = "none"
+
+	// ServiceEntryResolutionAnnotation overrides the resolution of a ServiceEntry with a resolution not supported by
+	// the ServiceEntry API.
+	ServiceEntryResolutionAnnotation = "networking.istio.io/resolution"
+	// DNSSRVResolution resolves the endpoints of the hosts of a ServiceEntry from their DNS SRV records, using the
+	// ports advertised by the records. The ServiceEntry must have the STATIC resolution and no endpoints.
+	DNSSRVResolution = "DNS_SRV"
+
+	// DefaultCorsPolicyKey is the key of the proxyMetadata of the default proxy config of the mesh setting the CORS
+	// policy of the HTTP routes of the VirtualServices which have none. The value is a JSON Istio CorsPolicy. A route
+	// opts out of the default with an empty corsPolicy.
+	DefaultCorsPolicyKey = "DEFAULT_CORS_POLICY"
+
+	// DefaultCorsPolicyAnnotation can be set on a Sidecar without workload selector to override the DefaultCorsPolicyKey
+	// of the mesh for the VirtualServices of its namespace. The value is a JSON Istio CorsPolicy, which replaces the
+	// default of the mesh as a whole. The Sidecar of the root namespace only applies to the VirtualServices of the root
+	// namespace.
+	DefaultCorsPolicyAnnotation = "networking.istio.io/defaultCorsPolicy"
 )
@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package destinationrule holds the annotations of DestinationRules configuring their clusters.
+package destinationrule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PreconnectPolicyAnnotation is the annotation of DestinationRules establishing upstream connections ahead of the
+// requests of latency critical services, so that bursts do not wait for the connection handshakes. Its value is a
+// comma separated list of RATIO=value, where perUpstream is the number of connections established to an upstream
+// host for each connection in use, and predictive the number of connections anticipated across the hosts of the
+// cluster for each connection in use, useful for low QPS services with the ROUND_ROBIN or RANDOM load balancers. For
+// example "perUpstream=1.5,predictive=2". The ratios are between 1 and 3; 1 disables preconnecting.
+const PreconnectPolicyAnnotation = "networking.istio.io/preconnect-policy"
+
+// MaxPreconnectRatio is the largest preconnect ratio accepted by Envoy.
+const MaxPreconnectRatio = 3
+
+// PreconnectPolicy are the ratios of the PreconnectPolicyAnnotation. A ratio is 0 if it is not set.
+type PreconnectPolicy struct {
+	PerUpstream float64
+	Predictive  float64
+}
+
+// ParsePreconnectPolicyAnnotation parses the value of the PreconnectPolicyAnnotation. It returns nil if no ratio is
+// set.
+func ParsePreconnectPolicyAnnotation(value string) (*PreconnectPolicy, error) {
+	policy := &PreconnectPolicy{}
+	for _, opt := range strings.Split(value, ",") {
+		if strings.TrimSpace(opt) == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid preconnect ratio %q, expected RATIO=value", opt)
+		}
+		name := strings.TrimSpace(kv[0])
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || ratio < 1 || ratio > MaxPreconnectRatio {
+			return nil, fmt.Errorf("invalid value for preconnect ratio %s: %q, must be between 1 and %d", name, kv[1], MaxPreconnectRatio)
+		}
+		switch name {
+		case "perUpstream":
+			policy.PerUpstream = ratio
+		case "predictive":
+			policy.Predictive = ratio
+		default:
+			return nil, fmt.Errorf("unknown preconnect ratio %q", name)
+		}
+	}
+	if policy.PerUpstream == 0 && policy.Predictive == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// UpstreamALPNProtocolsAnnotation is the annotation of DestinationRules replacing the ALPN protocols the proxy
+// advertises when originating TLS to the upstream hosts with the SIMPLE or MUTUAL modes, for external servers rejecting
+// the default ones. Its value is a comma separated list of protocols, such as "h2,http/1.1". An empty value advertises
+// no ALPN protocol. The ISTIO_MUTUAL connections keep their protocols, which the exchange of the peer metadata needs.
+const UpstreamALPNProtocolsAnnotation = "networking.istio.io/upstream-alpn-protocols"
+
+// maxALPNProtocolLength is the largest length of an ALPN protocol name allowed by TLS.
+const maxALPNProtocolLength = 255
+
+// ParseUpstreamALPNProtocolsAnnotation parses the value of the UpstreamALPNProtocolsAnnotation. It returns an empty
+// list for an empty value.
+func ParseUpstreamALPNProtocolsAnnotation(value string) ([]string, error) {
+	protocols := []string{}
+	if strings.TrimSpace(value) == "" {
+		return protocols, nil
+	}
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return nil, fmt.Errorf("empty ALPN protocol in %q", value)
+		}
+		if len(p) > maxALPNProtocolLength || strings.ContainsAny(p, " \t") {
+			return nil, fmt.Errorf("invalid ALPN protocol %q", p)
+		}
+		protocols = append(protocols, p)
+	}
+	return protocols, nil
+}
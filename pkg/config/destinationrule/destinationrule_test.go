@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePreconnectPolicyAnnotation(t *testing.T) {
+	cases := []struct {
+		value       string
+		perUpstream float64
+		predictive  float64
+		err         bool
+	}{
+		{value: "perUpstream=1.5", perUpstream: 1.5},
+		{value: "predictive=3", predictive: 3},
+		{value: " perUpstream = 1.05 , predictive=2", perUpstream: 1.05, predictive: 2},
+		{value: ""},
+		{value: "perUpstream=0.5", err: true},
+		{value: "predictive=4", err: true},
+		{value: "perUpstream", err: true},
+		{value: "eager=2", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePreconnectPolicyAnnotation(tt.value)
+			if tt.err != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			var perUpstream, predictive float64
+			if got != nil {
+				perUpstream, predictive = got.PerUpstream, got.Predictive
+			}
+			if perUpstream != tt.perUpstream || predictive != tt.predictive {
+				t.Fatalf("got %+v, want perUpstream=%v predictive=%v", got, tt.perUpstream, tt.predictive)
+			}
+		})
+	}
+}
+
+func TestParseUpstreamALPNProtocolsAnnotation(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []string
+		err   bool
+	}{
+		{value: "h2", want: []string{"h2"}},
+		{value: "http/1.1, h2", want: []string{"http/1.1", "h2"}},
+		{value: "", want: []string{}},
+		{value: "h2,,http/1.1", err: true},
+		{value: "h2,", err: true},
+		{value: "http 1.1", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseUpstreamALPNProtocolsAnnotation(tt.value)
+			if tt.err != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dynamicmetadata holds the annotations setting the dynamic metadata of the requests.
+package dynamicmetadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/util/sets"
+)
+
+// HeaderToMetadataAnnotation can be set on a VirtualService to copy headers of the requests of its HTTP routes into
+// their dynamic metadata, for the subset load balancer (with the "envoy.lb" namespace), the rate limit descriptors or
+// the access logs. The value is a JSON list of rules, for example
+// [{"header": "x-tenant", "key": "tenant", "namespace": "istio.tenant", "remove": true, "routes": ["api"]}].
+//
+// A rule sets the key of the namespace, which defaults to "envoy.filters.http.header_to_metadata", to the value of the
+// header, or to onMissing when the request does not have it. The type of the value is STRING, the default, or NUMBER.
+// When remove is set, the header is removed from the request. The rule applies to the routes with the given names, or
+// to all the HTTP routes of the VirtualService if none are given. The namespaces of the metadata the authentication
+// and authorization filters rely on cannot be set. It requires PILOT_ENABLE_HEADER_TO_METADATA.
+const HeaderToMetadataAnnotation = "networking.istio.io/header-to-metadata"
+
+// Types of the values of the HeaderToMetadataRules.
+const (
+	StringType = "STRING"
+	NumberType = "NUMBER"
+)
+
+// reservedNamespaces are the metadata namespaces written by the authentication and authorization filters, which the
+// requests must not be able to set from their headers.
+var reservedNamespaces = sets.NewSet("istio_authn", "envoy.filters.http.jwt_authn", "envoy.filters.http.rbac",
+	"envoy.filters.http.ext_authz")
+
+// HeaderToMetadataRule copies a request header into the dynamic metadata of the request.
+type HeaderToMetadataRule struct {
+	Header    string   `json:"header"`
+	Key       string   `json:"key"`
+	Namespace string   `json:"namespace,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	OnMissing string   `json:"onMissing,omitempty"`
+	Remove    bool     `json:"remove,omitempty"`
+	Routes    []string `json:"routes,omitempty"`
+}
+
+// AppliesTo returns true if the rule applies to the HTTP route with the given name.
+func (r HeaderToMetadataRule) AppliesTo(routeName string) bool {
+	if len(r.Routes) == 0 {
+		return true
+	}
+	for _, name := range r.Routes {
+		if name == routeName {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHeaderToMetadataAnnotation parses the value of the HeaderToMetadataAnnotation.
+func ParseHeaderToMetadataAnnotation(value string) ([]HeaderToMetadataRule, error) {
+	var rules []HeaderToMetadataRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	for i, r := range rules {
+		if r.Header == "" || strings.ContainsAny(r.Header, " \t:") || strings.ToLower(r.Header) != r.Header {
+			return nil, fmt.Errorf("rule %d: invalid header %q, must be a non-empty lowercase header name", i, r.Header)
+		}
+		if r.Key == "" {
+			return nil, fmt.Errorf("rule %d: key must be set", i)
+		}
+		if reservedNamespaces.Contains(r.Namespace) {
+			return nil, fmt.Errorf("rule %d: namespace %q is reserved", i, r.Namespace)
+		}
+		switch r.Type {
+		case "", StringType, NumberType:
+		default:
+			return nil, fmt.Errorf("rule %d: invalid type %q, must be %s or %s", i, r.Type, StringType, NumberType)
+		}
+		for _, name := range r.Routes {
+			if name == "" {
+				return nil, fmt.Errorf("rule %d: route names must be non-empty", i)
+			}
+		}
+	}
+	return rules, nil
+}
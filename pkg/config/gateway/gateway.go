@@ -15,11 +15,33 @@
 package gateway
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config/protocol"
 )
 
+// TLSRouteALPNAnnotation can be set on a VirtualService bound to a gateway to additionally match the TLS routes
+// of the VirtualService on the ALPN offered by the client. The value is a comma separated list of protocols,
+// for example "h2" or "http/1.1". This allows routing connections with the same SNI to different backends
+// depending on the negotiated protocol on PASSTHROUGH servers.
+const TLSRouteALPNAnnotation = "networking.istio.io/tls-alpn"
+
+// ParseTLSRouteALPN parses the value of the TLSRouteALPNAnnotation into a list of protocols.
+func ParseTLSRouteALPN(value string) []string {
+	var alpn []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			alpn = append(alpn, p)
+		}
+	}
+	return alpn
+}
+
 // IsTLSServer returns true if this server is non HTTP, with some TLS settings for termination/passthrough
 func IsTLSServer(server *v1alpha3.Server) bool {
 	if server.Tls != nil && !protocol.Parse(server.Port.Protocol).IsHTTP() {
@@ -49,6 +71,11 @@ func IsEligibleForHTTP3Upgrade(server *v1alpha3.Server) bool {
 	if !features.EnableQUICListeners {
 		return false
 	}
+	return IsHTTP3CapableServer(server)
+}
+
+// IsHTTP3CapableServer returns true if the server can be served over QUIC, regardless of whether QUIC is enabled.
+func IsHTTP3CapableServer(server *v1alpha3.Server) bool {
 	p := protocol.Parse(server.Port.Protocol)
 	return p == protocol.HTTPS && server.Tls != nil && !IsPassThroughServer(server)
 }
@@ -66,3 +93,178 @@ func IsPassThroughServer(server *v1alpha3.Server) bool {
 
 	return false
 }
+
+// EgressGatewayAnnotation can be set on a ServiceEntry to route the TLS traffic of sidecars to its hosts through an
+// egress gateway, without writing the Gateway and VirtualServices otherwise required. The value is the hostname of
+// the Service of the egress gateway, optionally followed by the port of the Service the gateway receives the
+// traffic on, for example "istio-egressgateway.istio-system.svc.cluster.local:443". The port defaults to 443.
+//
+// Only the ports of the ServiceEntry with the TLS or HTTPS protocol are routed: the sidecars forward the TLS
+// connections to the gateway, which routes them by SNI to the hosts. Hosts which already have a VirtualService
+// bound to the sidecars are left untouched.
+const EgressGatewayAnnotation = "networking.istio.io/egressGateway"
+
+// defaultEgressGatewayPort is the port of the egress gateway Service if the EgressGatewayAnnotation has none.
+const defaultEgressGatewayPort = 443
+
+// ParseEgressGatewayAnnotation parses the value of the EgressGatewayAnnotation. The hostname must be the
+// fully qualified name of a Kubernetes Service, as the namespace of the gateway is derived from it.
+func ParseEgressGatewayAnnotation(value string) (hostname string, port uint32, err error) {
+	hostname, port = value, defaultEgressGatewayPort
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		p, perr := strconv.ParseUint(value[i+1:], 10, 16)
+		if perr != nil || p == 0 {
+			return "", 0, fmt.Errorf("invalid port in %q", value)
+		}
+		hostname, port = value[:i], uint32(p)
+	}
+	if len(strings.Split(hostname, ".")) < 3 {
+		return "", 0, fmt.Errorf("invalid egress gateway %q, must be the hostname of a Service, such as "+
+			"istio-egressgateway.istio-system.svc.cluster.local", value)
+	}
+	return hostname, port, nil
+}
+
+// GRPCHealthCheckAnnotation can be set on a VirtualService bound to a gateway to expose a plain HTTP health
+// endpoint for the gRPC backends of its HTTP routes, for external load balancers which cannot send gRPC health
+// checks. The value is the path of the endpoint, for example "/healthz". The gateway actively checks the health of
+// the destinations of the routes with the gRPC health checking protocol, and answers the requests to the path on
+// any host of its HTTP servers with 200 while each destination has a healthy endpoint, and 503 otherwise.
+//
+// The destinations must use the gRPC or HTTP/2 protocol.
+const GRPCHealthCheckAnnotation = "networking.istio.io/grpc-health-check"
+
+// ParseGRPCHealthCheckPath parses the value of the GRPCHealthCheckAnnotation into the path of the health endpoint.
+func ParseGRPCHealthCheckPath(value string) (string, error) {
+	path := strings.TrimSpace(value)
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?# \t") {
+		return "", fmt.Errorf("invalid health check path %q, must be an absolute path without query", value)
+	}
+	return path, nil
+}
+
+// EgressProxyAnnotation can be set on a Sidecar to tunnel the TLS traffic of its workloads to the MESH_EXTERNAL
+// services through a forward proxy, such as a corporate HTTP proxy, instead of setting proxy environment variables
+// in each application. The value is the hostname and port of the service of the proxy, for example
+// "proxy.corp.example.com:3128". The proxy must be known to the mesh, typically through a ServiceEntry, and must
+// accept HTTP CONNECT requests. A Sidecar in the root namespace sets the proxy of the whole mesh.
+//
+// The ports of the external services with the TLS or HTTPS protocol without a VirtualService are tunneled: the
+// sidecars send the connections to the proxy with a CONNECT request to the host and port of the service. The
+// requests to their ports with the HTTP protocol are sent to the proxy with the absolute URL of the request, as
+// HTTP/1.1, unless a DestinationRule originates TLS to the port.
+const EgressProxyAnnotation = "networking.istio.io/egress-proxy"
+
+// EgressProxyCredentialAnnotation can be set on a Sidecar with the EgressProxyAnnotation to authenticate to the
+// proxy. The value is the name of a Secret of type kubernetes.io/basic-auth, with the username and password keys,
+// in the namespace of the Sidecar. The sidecars send its credentials in the Proxy-Authorization header of the
+// CONNECT requests and of the HTTP requests sent to the proxy.
+const EgressProxyCredentialAnnotation = "networking.istio.io/egress-proxy-credential"
+
+// ParseEgressProxyAnnotation parses the value of the EgressProxyAnnotation.
+func ParseEgressProxyAnnotation(value string) (hostname string, port uint32, err error) {
+	i := strings.LastIndex(value, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid egress proxy %q, must be a hostname and port, such as proxy.corp.example.com:3128", value)
+	}
+	p, err := strconv.ParseUint(value[i+1:], 10, 16)
+	if err != nil || p == 0 {
+		return "", 0, fmt.Errorf("invalid port in %q", value)
+	}
+	if strings.Contains(value[:i], "*") {
+		return "", 0, fmt.Errorf("invalid egress proxy %q, hostname may not be a wildcard", value)
+	}
+	return value[:i], uint32(p), nil
+}
+
+// PassthroughTLSOriginationAnnotation can be set to "true" on a Sidecar to originate TLS for the plaintext HTTP its
+// workloads send to unknown destinations on the PILOT_PASSTHROUGH_PLAINTEXT_TLS_PORTS, instead of passing it through.
+// The SNI is set from the Host header, and the certificate of the server is verified against the OS CA certificates
+// of the proxy. A Sidecar in the root namespace sets the policy of the whole mesh.
+const PassthroughTLSOriginationAnnotation = "networking.istio.io/passthrough-tls-origination"
+
+// DownstreamProtocolsAnnotation can be set on a Gateway to set the HTTP versions its HTTP and HTTPS servers accept
+// from the clients, instead of the defaults of the mesh. The value is a JSON object mapping the name of servers to
+// their protocols, for example {"https": {"http2": "only", "http3": true}}.
+//
+// http2 is "auto" by default, accepting HTTP/1.1 and HTTP/2, including HTTP/2 without TLS (h2c) on HTTP servers.
+// "disabled" only accepts HTTP/1.1, and "only" only accepts HTTP/2. As the HTTP servers sharing a port are served by
+// the same connection manager, the protocols of the first of them apply to all. http3, when set, overrides
+// PILOT_ENABLE_QUIC_LISTENERS for an HTTPS server: it is then also served over QUIC, when the port of the Service
+// of the gateway accepts UDP, and advertised to the clients with the alt-svc header of the responses.
+const DownstreamProtocolsAnnotation = "networking.istio.io/downstream-protocols"
+
+// HTTP2Mode is whether a server accepts HTTP/2 from the clients.
+type HTTP2Mode string
+
+const (
+	// HTTP2Auto accepts HTTP/1.1 and HTTP/2.
+	HTTP2Auto HTTP2Mode = "auto"
+	// HTTP2Disabled only accepts HTTP/1.1.
+	HTTP2Disabled HTTP2Mode = "disabled"
+	// HTTP2Only only accepts HTTP/2.
+	HTTP2Only HTTP2Mode = "only"
+)
+
+// DownstreamProtocols are the protocols of a server of the DownstreamProtocolsAnnotation.
+type DownstreamProtocols struct {
+	HTTP2 HTTP2Mode `json:"http2"`
+	HTTP3 *bool     `json:"http3"`
+}
+
+// ParseDownstreamProtocolsAnnotation parses the value of the DownstreamProtocolsAnnotation into the protocols of the
+// servers, keyed by name.
+func ParseDownstreamProtocolsAnnotation(value string) (map[string]*DownstreamProtocols, error) {
+	var out map[string]*DownstreamProtocols
+	if err := json.Unmarshal([]byte(value), &out); err != nil {
+		return nil, err
+	}
+	for name, p := range out {
+		if p == nil {
+			return nil, fmt.Errorf("missing protocols of server %q", name)
+		}
+		switch p.HTTP2 {
+		case "", HTTP2Auto, HTTP2Disabled, HTTP2Only:
+		default:
+			return nil, fmt.Errorf("invalid http2 mode %q of server %q, must be one of auto, disabled or only", p.HTTP2, name)
+		}
+	}
+	return out, nil
+}
+
+// ClientTrustAnnotation can be set on a Gateway to accept clients from other trust domains on its ISTIO_MUTUAL servers,
+// such as the partner meshes of an east-west gateway. The value is a JSON object mapping the name of servers to the
+// trust domains of their clients and, optionally, the credential holding the root certificates of these trust domains,
+// for example {"partner": {"trustDomains": ["partner.example.com"], "caCredentialName": "partner-ca"}}.
+//
+// As servers are matched by SNI, each server only accepts the SPIFFE identities of its own trust domains, validated with
+// the root certificates of its credential, in the ca.crt key of the secret, or of the mesh if unset. Authorization
+// policies can be scoped to the clients of a server with the connection.sni condition, and their source.namespaces
+// match the namespaces of the clients in any trust domain.
+const ClientTrustAnnotation = "networking.istio.io/client-trust"
+
+// ClientTrust is the trust of the clients of a server of the ClientTrustAnnotation.
+type ClientTrust struct {
+	TrustDomains     []string `json:"trustDomains"`
+	CACredentialName string   `json:"caCredentialName"`
+}
+
+// ParseClientTrustAnnotation parses the value of the ClientTrustAnnotation into the client trust of the servers, keyed
+// by name.
+func ParseClientTrustAnnotation(value string) (map[string]*ClientTrust, error) {
+	var out map[string]*ClientTrust
+	if err := json.Unmarshal([]byte(value), &out); err != nil {
+		return nil, err
+	}
+	for name, t := range out {
+		if t == nil || len(t.TrustDomains) == 0 {
+			return nil, fmt.Errorf("missing trust domains of server %q", name)
+		}
+		for _, td := range t.TrustDomains {
+			if td == "" || strings.Contains(td, "/") {
+				return nil, fmt.Errorf("invalid trust domain %q of server %q", td, name)
+			}
+		}
+	}
+	return out, nil
+}
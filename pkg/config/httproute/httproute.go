@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httproute holds the annotations of VirtualServices configuring their HTTP routes.
+package httproute
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathRewriteAnnotation is the annotation of VirtualServices rewriting the path of the requests of their HTTP routes
+// beyond the prefix rewrite. Its value is a JSON object mapping the name of HTTP routes to either a RE2 regex and its
+// substitution, such as {"regex": "^/v1/(.*)$", "substitution": "/\\1"}, or a path template and the rewritten path
+// template, such as {"pathTemplate": "/v1/{name}/items/{id=**}", "rewrite": "/items/{id}/owner/{name}"}. Requests
+// whose path does not match are forwarded unchanged. It takes precedence over the uri of the rewrite of the route.
+const PathRewriteAnnotation = "networking.istio.io/path-rewrite"
+
+// RetriableResponseHeadersAnnotation is the annotation of VirtualServices retrying the requests of their HTTP routes
+// whose response has a header matching. Its value is a JSON object mapping the name of HTTP routes to their header
+// matches, in the format of the headers of the HTTP matches, such as {"reviews": {"x-overloaded": {"exact": "true"}}}.
+// An empty match matches the presence of the header. It has no effect on routes whose retries are disabled.
+const RetriableResponseHeadersAnnotation = "networking.istio.io/retriable-response-headers"
+
+// RetryPerTryIdleTimeoutAnnotation is the annotation of VirtualServices setting the per try idle timeout of the retry
+// policy of their HTTP routes, such as "1s": a retry is sent when no data was received from the upstream endpoint
+// during this duration, which bounds the attempts of long streaming requests on which a per try timeout cannot be set.
+// It has no effect on routes whose retries are disabled.
+const RetryPerTryIdleTimeoutAnnotation = "networking.istio.io/retry-per-try-idle-timeout"
+
+// LBSubsetHeadersAnnotation is the annotation of VirtualServices selecting the endpoints of the requests of their HTTP
+// routes from their headers, among the subsets of the networking.istio.io/lb-subset-keys annotation of the
+// DestinationRule of the destination. Its value is a JSON object mapping the name of HTTP routes to the label keys
+// set from the headers, such as {"inference": {"x-gpu": "gpu"}}: the requests with the x-gpu: true header are then
+// load balanced among the endpoints with the gpu=true label. It requires PILOT_ENABLE_LB_SUBSET_HEADERS.
+const LBSubsetHeadersAnnotation = "networking.istio.io/lb-subset-headers"
+
+// PathRewrite is the path rewrite of an HTTP route in the PathRewriteAnnotation.
+type PathRewrite struct {
+	Regex        string `json:"regex,omitempty"`
+	Substitution string `json:"substitution,omitempty"`
+	PathTemplate string `json:"pathTemplate,omitempty"`
+	Rewrite      string `json:"rewrite,omitempty"`
+}
+
+// ParsePathRewriteAnnotation parses the value of the PathRewriteAnnotation, returning the path rewrite of each HTTP
+// route with the path templates translated to their regex and substitution.
+func ParsePathRewriteAnnotation(value string) (map[string]PathRewrite, error) {
+	specs := map[string]PathRewrite{}
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		return nil, err
+	}
+	out := make(map[string]PathRewrite, len(specs))
+	for routeName, spec := range specs {
+		if routeName == "" {
+			return nil, fmt.Errorf("route names must be non-empty")
+		}
+		switch {
+		case spec.PathTemplate != "" && spec.Regex != "":
+			return nil, fmt.Errorf("route %s: regex and pathTemplate are mutually exclusive", routeName)
+		case spec.PathTemplate != "":
+			regex, substitution, err := translatePathTemplate(spec.PathTemplate, spec.Rewrite)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: %v", routeName, err)
+			}
+			spec = PathRewrite{Regex: regex, Substitution: substitution}
+		case spec.Regex == "":
+			return nil, fmt.Errorf("route %s: either regex or pathTemplate is required", routeName)
+		case spec.Rewrite != "":
+			return nil, fmt.Errorf("route %s: rewrite requires pathTemplate", routeName)
+		}
+		if _, err := regexp.Compile(spec.Regex); err != nil {
+			return nil, fmt.Errorf("route %s: %v", routeName, err)
+		}
+		out[routeName] = spec
+	}
+	return out, nil
+}
+
+// ParseRetryPerTryIdleTimeoutAnnotation parses the value of the RetryPerTryIdleTimeoutAnnotation.
+func ParseRetryPerTryIdleTimeoutAnnotation(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < time.Millisecond {
+		return 0, fmt.Errorf("timeout %s must be at least 1ms", timeout)
+	}
+	return timeout, nil
+}
+
+// ParseLBSubsetHeadersAnnotation parses the value of the LBSubsetHeadersAnnotation, returning the label key set from
+// each header, by HTTP route.
+func ParseLBSubsetHeadersAnnotation(value string) (map[string]map[string]string, error) {
+	specs := map[string]map[string]string{}
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		return nil, err
+	}
+	for routeName, headers := range specs {
+		if routeName == "" {
+			return nil, fmt.Errorf("route names must be non-empty")
+		}
+		for name, key := range headers {
+			if name == "" || strings.ContainsAny(name, " \t:") {
+				return nil, fmt.Errorf("route %s: invalid header %q", routeName, name)
+			}
+			if key == "" {
+				return nil, fmt.Errorf("route %s: header %s: the label key must be set", routeName, name)
+			}
+		}
+	}
+	return specs, nil
+}
+
+// HeaderMatch is a header match in the RetriableResponseHeadersAnnotation. At most one of its fields is set; none
+// matches the presence of the header.
+type HeaderMatch struct {
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+}
+
+// ParseRetriableResponseHeadersAnnotation parses the value of the RetriableResponseHeadersAnnotation, returning the
+// header matches of each HTTP route.
+func ParseRetriableResponseHeadersAnnotation(value string) (map[string]map[string]HeaderMatch, error) {
+	specs := map[string]map[string]HeaderMatch{}
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		return nil, err
+	}
+	for routeName, headers := range specs {
+		if routeName == "" {
+			return nil, fmt.Errorf("route names must be non-empty")
+		}
+		for name, match := range headers {
+			if name == "" || strings.ContainsAny(name, " \t:") {
+				return nil, fmt.Errorf("route %s: invalid header %q", routeName, name)
+			}
+			set := 0
+			for _, m := range []string{match.Exact, match.Prefix, match.Regex} {
+				if m != "" {
+					set++
+				}
+			}
+			if set > 1 {
+				return nil, fmt.Errorf("route %s: header %s: exact, prefix and regex are mutually exclusive", routeName, name)
+			}
+			if match.Regex != "" {
+				if _, err := regexp.Compile(match.Regex); err != nil {
+					return nil, fmt.Errorf("route %s: header %s: %v", routeName, name, err)
+				}
+			}
+		}
+	}
+	return specs, nil
+}
+
+var pathTemplateVariable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// translatePathTemplate translates a RFC 6570 style path template and the template of the rewritten path to a regex
+// and its substitution. A {name} variable matches a single path segment, a {name=**} variable matches the rest of the
+// path, including slashes.
+func translatePathTemplate(pathTemplate, rewrite string) (string, string, error) {
+	if !strings.HasPrefix(pathTemplate, "/") {
+		return "", "", fmt.Errorf("path template %q must start with /", pathTemplate)
+	}
+	if !strings.HasPrefix(rewrite, "/") {
+		return "", "", fmt.Errorf("rewrite %q must start with /", rewrite)
+	}
+	variables := map[string]int{}
+	regex := &strings.Builder{}
+	regex.WriteString("^")
+	err := forEachTemplatePart(pathTemplate, func(literal, variable string) error {
+		if variable == "" {
+			regex.WriteString(regexp.QuoteMeta(literal))
+			return nil
+		}
+		name, pattern := variable, "([^/]+)"
+		if strings.HasSuffix(variable, "=**") {
+			name, pattern = strings.TrimSuffix(variable, "=**"), "(.*)"
+		}
+		if !pathTemplateVariable.MatchString(name) {
+			return fmt.Errorf("invalid variable %q in path template %q", variable, pathTemplate)
+		}
+		if _, f := variables[name]; f {
+			return fmt.Errorf("duplicate variable %q in path template %q", name, pathTemplate)
+		}
+		variables[name] = len(variables) + 1
+		regex.WriteString(pattern)
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	regex.WriteString("$")
+
+	substitution := &strings.Builder{}
+	err = forEachTemplatePart(rewrite, func(literal, variable string) error {
+		if variable == "" {
+			substitution.WriteString(literal)
+			return nil
+		}
+		index, f := variables[variable]
+		if !f {
+			return fmt.Errorf("unknown variable %q in rewrite %q", variable, rewrite)
+		}
+		substitution.WriteString(`\` + strconv.Itoa(index))
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return regex.String(), substitution.String(), nil
+}
+
+// forEachTemplatePart calls fn with each literal and each {variable} of the template, in order.
+func forEachTemplatePart(template string, fn func(literal, variable string) error) error {
+	for template != "" {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			return fn(template, "")
+		}
+		if template[start] == '}' {
+			return fmt.Errorf("unbalanced braces in template %q", template)
+		}
+		if start > 0 {
+			if err := fn(template[:start], ""); err != nil {
+				return err
+			}
+		}
+		end := strings.IndexAny(template[start+1:], "{}")
+		if end < 0 || template[start+1+end] == '{' {
+			return fmt.Errorf("unbalanced braces in template %q", template)
+		}
+		if err := fn("", template[start+1:start+1+end]); err != nil {
+			return err
+		}
+		template = template[start+1+end+1:]
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httproute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslatePathTemplate(t *testing.T) {
+	cases := []struct {
+		name             string
+		pathTemplate     string
+		rewrite          string
+		wantRegex        string
+		wantSubstitution string
+		wantErr          bool
+	}{
+		{
+			name:             "segments",
+			pathTemplate:     "/v1/{name}/items/{id}",
+			rewrite:          "/items/{id}/owner/{name}",
+			wantRegex:        `^/v1/([^/]+)/items/([^/]+)$`,
+			wantSubstitution: `/items/\2/owner/\1`,
+		},
+		{
+			name:             "rest of path",
+			pathTemplate:     "/static.v1/{path=**}",
+			rewrite:          "/{path}",
+			wantRegex:        `^/static\.v1/(.*)$`,
+			wantSubstitution: `/\1`,
+		},
+		{
+			name:         "unknown variable",
+			pathTemplate: "/v1/{name}",
+			rewrite:      "/{id}",
+			wantErr:      true,
+		},
+		{
+			name:         "duplicate variable",
+			pathTemplate: "/v1/{name}/{name}",
+			rewrite:      "/{name}",
+			wantErr:      true,
+		},
+		{
+			name:         "invalid variable",
+			pathTemplate: "/v1/{na-me}",
+			rewrite:      "/",
+			wantErr:      true,
+		},
+		{
+			name:         "unbalanced braces",
+			pathTemplate: "/v1/{name",
+			rewrite:      "/",
+			wantErr:      true,
+		},
+		{
+			name:         "relative path",
+			pathTemplate: "v1/{name}",
+			rewrite:      "/{name}",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			regex, substitution, err := translatePathTemplate(tt.pathTemplate, tt.rewrite)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if regex != tt.wantRegex || substitution != tt.wantSubstitution {
+				t.Fatalf("got %q -> %q, want %q -> %q", regex, substitution, tt.wantRegex, tt.wantSubstitution)
+			}
+		})
+	}
+}
+
+func TestParsePathRewriteAnnotation(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    map[string]PathRewrite
+		wantErr bool
+	}{
+		{
+			name:  "regex",
+			value: `{"items": {"regex": "^/v1/(.*)$", "substitution": "/v2/\\1"}}`,
+			want:  map[string]PathRewrite{"items": {Regex: `^/v1/(.*)$`, Substitution: `/v2/\1`}},
+		},
+		{
+			name:  "path template",
+			value: `{"items": {"pathTemplate": "/v1/{name}", "rewrite": "/{name}"}}`,
+			want:  map[string]PathRewrite{"items": {Regex: `^/v1/([^/]+)$`, Substitution: `/\1`}},
+		},
+		{
+			name:    "not json",
+			value:   `items=/v1`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			value:   `{"items": {"regex": "^/v1/(.*$"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "regex and path template",
+			value:   `{"items": {"regex": "^/v1/(.*)$", "pathTemplate": "/v1/{name}", "rewrite": "/{name}"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "no regex",
+			value:   `{"items": {"substitution": "/"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "rewrite without path template",
+			value:   `{"items": {"regex": "^/v1/(.*)$", "rewrite": "/{name}"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty route name",
+			value:   `{"": {"regex": "^/v1/(.*)$"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePathRewriteAnnotation(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubemesh
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	informersv1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/pkg/log"
+)
+
+// NetworkLabel is set on the ConfigMaps defining a network of the mesh, to the name of the network. The "network" key
+// of their data is the network in the format of the meshNetworks, for example:
+//
+//	endpoints:
+//	- fromRegistry: cluster-east
+//	gateways:
+//	- registryServiceName: istio-eastwestgateway.istio-system.svc.cluster.local
+//	  port: 15443
+//
+// Such ConfigMaps are only read from the istiod namespace, as they change the routing of the whole mesh. Adding,
+// changing or removing them updates the networks of the mesh, and the networks of the endpoints, without a rollout of
+// the mesh config. They take precedence over the networks of the same name of the meshNetworks. When several
+// ConfigMaps define a network, the first one by name is used.
+const NetworkLabel = "topology.istio.io/mesh-network"
+
+// networkKey is the key of the network in the data of the ConfigMaps.
+const networkKey = "network"
+
+// networksWatcher merges the networks of the labeled ConfigMaps into the networks of another watcher.
+type networksWatcher struct {
+	base     mesh.NetworksWatcher
+	informer informersv1.ConfigMapInformer
+	queue    controllers.Queue
+
+	mutex    sync.RWMutex
+	networks *meshconfig.MeshNetworks
+	// defined are the networks of the ConfigMaps, by name.
+	defined  map[string]*meshconfig.Network
+	handlers []func()
+}
+
+var _ mesh.NetworksWatcher = &networksWatcher{}
+
+// NewNetworksWatcher returns a watcher of the networks of the base watcher and of the ConfigMaps with the
+// NetworkLabel, which are watched in the namespace.
+func NewNetworksWatcher(client kube.Client, namespace string, base mesh.NetworksWatcher, stop <-chan struct{}) mesh.NetworksWatcher {
+	w := &networksWatcher{
+		base:     base,
+		defined:  map[string]*meshconfig.Network{},
+		networks: base.Networks(),
+	}
+	// A separate informer factory limits the watch to the labeled ConfigMaps of the namespace.
+	w.informer = informers.NewSharedInformerFactoryWithOptions(client.Kube(), 12*time.Hour,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = NetworkLabel
+		})).
+		Core().V1().ConfigMaps()
+	w.queue = controllers.NewQueue("mesh networks", controllers.WithReconciler(func(types.NamespacedName) error {
+		w.reload()
+		return nil
+	}))
+	w.informer.Informer().AddEventHandler(controllers.ObjectHandler(w.queue.AddObject))
+	base.AddNetworksHandler(w.merge)
+
+	go w.informer.Informer().Run(stop)
+	if !cache.WaitForCacheSync(stop, w.informer.Informer().HasSynced) {
+		log.Error("failed to wait for mesh networks ConfigMaps cache sync")
+	}
+	w.reload()
+	go w.queue.Run(stop)
+	return w
+}
+
+// Networks returns the networks of the base watcher, and of the ConfigMaps.
+func (w *networksWatcher) Networks() *meshconfig.MeshNetworks {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.networks
+}
+
+// SetNetworks sets the networks of the base watcher.
+func (w *networksWatcher) SetNetworks(networks *meshconfig.MeshNetworks) {
+	w.base.SetNetworks(networks)
+}
+
+// AddNetworksHandler registers a handler called when the networks change. As for the base watchers, the last handler
+// added runs first.
+func (w *networksWatcher) AddNetworksHandler(h func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handlers = append([]func(){h}, w.handlers...)
+}
+
+// reload reads the networks of the ConfigMaps.
+func (w *networksWatcher) reload() {
+	cms, err := w.informer.Lister().List(klabels.Everything())
+	if err != nil {
+		log.Errorf("failed to list mesh networks ConfigMaps: %v", err)
+		return
+	}
+	sort.Slice(cms, func(i, j int) bool {
+		if cms[i].Namespace != cms[j].Namespace {
+			return cms[i].Namespace < cms[j].Namespace
+		}
+		return cms[i].Name < cms[j].Name
+	})
+	defined := map[string]*meshconfig.Network{}
+	for _, cm := range cms {
+		if _, f := cm.Labels[NetworkLabel]; !f {
+			continue
+		}
+		name, network, err := ReadNetworkConfigMap(cm)
+		if err != nil {
+			log.Warnf("ignoring mesh network ConfigMap %s/%s: %v", cm.Namespace, cm.Name, err)
+			continue
+		}
+		if _, f := defined[name]; f {
+			log.Warnf("ignoring mesh network ConfigMap %s/%s: network %s is already defined", cm.Namespace, cm.Name, name)
+			continue
+		}
+		defined[name] = network
+	}
+	w.mutex.Lock()
+	w.defined = defined
+	w.mutex.Unlock()
+	w.merge()
+}
+
+// merge merges the networks of the ConfigMaps into those of the base watcher, and calls the handlers if they changed.
+func (w *networksWatcher) merge() {
+	w.mutex.Lock()
+	networks := w.base.Networks()
+	if len(w.defined) > 0 {
+		merged := mesh.EmptyMeshNetworks()
+		if networks != nil {
+			for name, network := range networks.Networks {
+				merged.Networks[name] = network
+			}
+		}
+		for name, network := range w.defined {
+			merged.Networks[name] = network
+		}
+		networks = &merged
+	}
+	changed := !proto.Equal(networks, w.networks)
+	w.networks = networks
+	handlers := append([]func(){}, w.handlers...)
+	w.mutex.Unlock()
+
+	if changed {
+		log.Infof("mesh networks changed: %d networks", len(networks.GetNetworks()))
+		for _, h := range handlers {
+			h()
+		}
+	}
+}
+
+// ReadNetworkConfigMap reads the name and the network of a ConfigMap with the NetworkLabel.
+func ReadNetworkConfigMap(cm *v1.ConfigMap) (string, *meshconfig.Network, error) {
+	name := cm.Labels[NetworkLabel]
+	if name == "" {
+		return "", nil, fmt.Errorf("missing network name in label %s", NetworkLabel)
+	}
+	network := &meshconfig.Network{}
+	if err := gogoprotomarshal.ApplyYAML(cm.Data[networkKey], network); err != nil {
+		return "", nil, fmt.Errorf("failed reading network: %v", err)
+	}
+	if err := validation.ValidateMeshNetworks(&meshconfig.MeshNetworks{
+		Networks: map[string]*meshconfig.Network{name: network},
+	}); err != nil {
+		return "", nil, err
+	}
+	return name, network, nil
+}
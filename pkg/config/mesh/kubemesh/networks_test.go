@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubemesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func makeNetworkConfigMap(namespace, name, network, data string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{NetworkLabel: network},
+		},
+		Data: map[string]string{networkKey: data},
+	}
+}
+
+func TestNetworksWatcher(t *testing.T) {
+	client := kube.NewFakeClient()
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	base := mesh.NewFixedNetworksWatcher(&meshconfig.MeshNetworks{Networks: map[string]*meshconfig.Network{
+		"network-1": {Endpoints: []*meshconfig.Network_NetworkEndpoints{{Ne: &meshconfig.Network_NetworkEndpoints_FromRegistry{
+			FromRegistry: "cluster-1",
+		}}}},
+	}})
+	w := NewNetworksWatcher(client, "istio-system", base, stop)
+	changes := atomic.NewInt32(0)
+	w.AddNetworksHandler(func() { changes.Inc() })
+
+	networkEndpoints := func(name string) string {
+		network := w.Networks().GetNetworks()[name]
+		if network == nil || len(network.Endpoints) == 0 {
+			return ""
+		}
+		return network.Endpoints[0].GetFromRegistry() + network.Endpoints[0].GetFromCidr()
+	}
+	waitFor := func(name, want string) {
+		t.Helper()
+		retry.UntilOrFail(t, func() bool { return networkEndpoints(name) == want }, retry.Delay(time.Millisecond), retry.Timeout(time.Second))
+	}
+
+	cms := client.Kube().CoreV1().ConfigMaps("istio-system")
+	east := makeNetworkConfigMap("istio-system", "east", "network-2", `
+endpoints:
+- fromRegistry: cluster-2
+gateways:
+- registryServiceName: istio-eastwestgateway.istio-system.svc.cluster.local
+  port: 15443
+`)
+	if _, err := cms.Create(context.Background(), east, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("network-2", "cluster-2")
+	waitFor("network-1", "cluster-1")
+	if changes.Load() == 0 {
+		t.Fatal("handlers not called")
+	}
+
+	// ConfigMaps take precedence over the meshNetworks, and invalid ones are ignored.
+	override := makeNetworkConfigMap("istio-system", "override", "network-1", "endpoints:\n- fromCidr: 10.0.0.0/8\n")
+	if _, err := cms.Create(context.Background(), override, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("network-1", "10.0.0.0/8")
+	invalid := makeNetworkConfigMap("istio-system", "invalid", "network-3", "endpoints:\n- fromCidr: not-a-cidr\n")
+	if _, err := cms.Create(context.Background(), invalid, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cms.Delete(context.Background(), "east", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("network-2", "")
+	waitFor("network-3", "")
+
+	// ConfigMaps of other namespaces are ignored.
+	other := makeNetworkConfigMap("default", "other", "network-5", "endpoints:\n- fromRegistry: cluster-5\n")
+	if _, err := client.Kube().CoreV1().ConfigMaps("default").Create(context.Background(), other, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes of the base networks are merged.
+	base.SetNetworks(&meshconfig.MeshNetworks{Networks: map[string]*meshconfig.Network{
+		"network-4": {Endpoints: []*meshconfig.Network_NetworkEndpoints{{Ne: &meshconfig.Network_NetworkEndpoints_FromRegistry{
+			FromRegistry: "cluster-4",
+		}}}},
+	}})
+	waitFor("network-4", "cluster-4")
+	waitFor("network-1", "10.0.0.0/8")
+	waitFor("network-5", "")
+}
@@ -53,7 +53,7 @@ func NewConfigMapWatcher(client kube.Client, namespace, name, key string, multiW
 			log.Warnf("failed to read mesh config from ConfigMap: %v", err)
 			return
 		}
-		w.HandleMeshConfig(meshConfig)
+		w.HandleMeshConfigWithData(meshConfig, meshConfigMapData(cm, key))
 	})
 
 	go c.Run(stop)
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/pkg/log"
+)
+
+// DefaultSource is the source of the config fields set by none of the layers.
+const DefaultSource = "default"
+
+// FieldSource is a field of an effective config, along with the source which supplied its value.
+type FieldSource struct {
+	Field  string          `json:"field"`
+	Value  json.RawMessage `json:"value"`
+	Source string          `json:"source"`
+}
+
+// FieldLayer is a config merged into an effective config, as a JSON map.
+type FieldLayer struct {
+	Source string
+	Fields map[string]interface{}
+}
+
+// FieldSources returns the top level fields of the effective config, as a JSON map, sorted by name, with the source
+// of their value: the first of the layers, highest precedence first, setting the field, or fallback if none does.
+func FieldSources(effective map[string]interface{}, layers []FieldLayer, fallback func(field string) string) ([]FieldSource, error) {
+	out := make([]FieldSource, 0, len(effective))
+	for field, value := range effective {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		source := ""
+		for _, l := range layers {
+			if _, f := l.Fields[field]; f {
+				source = l.Source
+				break
+			}
+		}
+		if source == "" {
+			source = fallback(field)
+		}
+		out = append(out, FieldSource{Field: field, Value: raw, Source: source})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Field < out[j].Field
+	})
+	return out, nil
+}
+
+// MeshConfigFieldSources returns the fields of the effective mesh config, sorted by name, with the last of the layers
+// setting each of them, or DefaultSource. The fields of defaultConfig are reported individually as
+// "defaultConfig.<field>", as the proxy config of each layer is merged rather than overriding the previous one.
+func MeshConfigFieldSources(effective *meshconfig.MeshConfig, layers []Layer) ([]FieldSource, error) {
+	values, err := gogoprotomarshal.ToJSONMap(effective)
+	if err != nil {
+		return nil, err
+	}
+	proxyValues, _ := values["defaultConfig"].(map[string]interface{})
+	delete(values, "defaultConfig")
+
+	// Reverse the layers, as the last one has the highest precedence.
+	meshLayers := make([]FieldLayer, 0, len(layers))
+	proxyLayers := make([]FieldLayer, 0, len(layers))
+	for i := len(layers) - 1; i >= 0; i-- {
+		m, err := toMap(layers[i].YAML)
+		if err != nil {
+			// Invalid layers are ignored when merging the mesh config as well.
+			log.Debugf("ignoring invalid %s: %v", layers[i].Source, err)
+			continue
+		}
+		m = jsonNames(m)
+		meshLayers = append(meshLayers, FieldLayer{Source: layers[i].Source, Fields: m})
+		pc, _ := m["defaultConfig"].(map[string]interface{})
+		proxyLayers = append(proxyLayers, FieldLayer{Source: layers[i].Source, Fields: jsonNames(pc)})
+	}
+	defaultSource := func(string) string { return DefaultSource }
+
+	out, err := FieldSources(values, meshLayers, defaultSource)
+	if err != nil {
+		return nil, err
+	}
+	proxyOut, err := FieldSources(proxyValues, proxyLayers, defaultSource)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range proxyOut {
+		f.Field = "defaultConfig." + f.Field
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Field < out[j].Field
+	})
+	return out, nil
+}
+
+// jsonNames returns the map with its keys converted to the JSON names of the proto fields, as the original snake case
+// names are accepted in the mesh config too.
+func jsonNames(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		parts := strings.Split(k, "_")
+		for i := 1; i < len(parts); i++ {
+			if parts[i] != "" {
+				parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			}
+		}
+		out[strings.Join(parts, "")] = v
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh_test
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestMeshConfigFieldSources(t *testing.T) {
+	defaultMesh := mesh.DefaultMeshConfig()
+	w := mesh.NewMultiWatcher(&defaultMesh)
+	w.HandleUserMeshConfig(`
+ingressClass: user
+defaultConfig:
+  concurrency: 3
+  discoveryAddress: istiod-user:15012
+`)
+	w.HandleMeshConfigData(`
+ingress_class: revision
+defaultConfig:
+  concurrency: 4
+`)
+
+	layers := w.Layers()
+	if len(layers) != 2 || layers[0].Source != mesh.UserMeshConfigSource || layers[1].Source != mesh.RevisionMeshConfigSource {
+		t.Fatalf("unexpected layers: %+v", layers)
+	}
+	sources, err := mesh.MeshConfigFieldSources(w.Mesh(), layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]mesh.FieldSource{}
+	for i, s := range sources {
+		if i > 0 && sources[i-1].Field >= s.Field {
+			t.Fatalf("fields are not sorted: %s before %s", sources[i-1].Field, s.Field)
+		}
+		got[s.Field] = s
+	}
+
+	cases := []struct {
+		field  string
+		value  string
+		source string
+	}{
+		{"ingressClass", `"revision"`, mesh.RevisionMeshConfigSource},
+		{"connectTimeout", `"10s"`, mesh.DefaultSource},
+		{"defaultConfig.concurrency", `4`, mesh.RevisionMeshConfigSource},
+		{"defaultConfig.discoveryAddress", `"istiod-user:15012"`, mesh.UserMeshConfigSource},
+		{"defaultConfig.drainDuration", `"45s"`, mesh.DefaultSource},
+	}
+	for _, tt := range cases {
+		s, f := got[tt.field]
+		if !f {
+			t.Fatalf("missing field %s", tt.field)
+		}
+		if string(s.Value) != tt.value || s.Source != tt.source {
+			t.Errorf("%s: got %s from %q, want %s from %q", tt.field, s.Value, s.Source, tt.value, tt.source)
+		}
+	}
+	if _, f := got["defaultConfig"]; f {
+		t.Errorf("defaultConfig should be reported by field")
+	}
+}
@@ -44,6 +44,31 @@ type Watcher interface {
 	HandleUserMeshConfig(string)
 }
 
+// Sources of the mesh config layers reported by LayeredWatcher.
+const (
+	// UserMeshConfigSource is the source of the user mesh config overrides, from the SHARED_MESH_CONFIG ConfigMap.
+	UserMeshConfigSource = "shared mesh config"
+	// RevisionMeshConfigSource is the source of the standard mesh config, from the mesh config file or the ConfigMap
+	// of the revision.
+	RevisionMeshConfigSource = "revision mesh config"
+)
+
+// Layer is a mesh config source merged into the mesh config of a Watcher.
+type Layer struct {
+	// Source describes where the mesh config comes from.
+	Source string
+	// YAML is the mesh config of the source.
+	YAML string
+}
+
+// LayeredWatcher is a Watcher keeping track of the mesh config sources it merges.
+type LayeredWatcher interface {
+	Watcher
+
+	// Layers returns the mesh config sources merged on top of the defaults, lowest precedence first.
+	Layers() []Layer
+}
+
 // MultiWatcher is a struct wrapping the internal injector to let users know that both
 type MultiWatcher struct {
 	internalWatcher
@@ -56,7 +81,7 @@ func NewMultiWatcher(config *meshconfig.MeshConfig) *MultiWatcher {
 	}
 }
 
-var _ Watcher = &internalWatcher{}
+var _ LayeredWatcher = &internalWatcher{}
 
 type internalWatcher struct {
 	mutex    sync.Mutex
@@ -106,12 +131,17 @@ func NewFileWatcher(fileWatcher filewatcher.FileWatcher, filename string, multiW
 			return
 		}
 		// Reload the config file
-		meshConfig, err = ReadMeshConfig(filename)
+		meshConfigYaml, err := ReadMeshConfigData(filename)
 		if err != nil {
 			log.Warnf("failed to read mesh configuration, using default: %v", err)
 			return
 		}
-		w.HandleMeshConfig(meshConfig)
+		meshConfig, err = ApplyMeshConfigDefaults(meshConfigYaml)
+		if err != nil {
+			log.Warnf("failed to read mesh configuration, using default: %v", err)
+			return
+		}
+		w.HandleMeshConfigWithData(meshConfig, meshConfigYaml)
 	})
 	return w, nil
 }
@@ -148,6 +178,20 @@ func (w *internalWatcher) HandleUserMeshConfig(yaml string) {
 	w.handleMeshConfigInternal(merged)
 }
 
+// Layers returns the user and revision mesh configs, lowest precedence first.
+func (w *internalWatcher) Layers() []Layer {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var layers []Layer
+	if w.userMeshConfig != "" {
+		layers = append(layers, Layer{Source: UserMeshConfigSource, YAML: w.userMeshConfig})
+	}
+	if w.revMeshConfig != "" {
+		layers = append(layers, Layer{Source: RevisionMeshConfigSource, YAML: w.revMeshConfig})
+	}
+	return layers
+}
+
 // merged returns the merged user and revision config.
 func (w *internalWatcher) merged() *meshconfig.MeshConfig {
 	mc := DefaultMeshConfig()
@@ -180,6 +224,15 @@ func (w *internalWatcher) HandleMeshConfig(meshConfig *meshconfig.MeshConfig) {
 	w.handleMeshConfigInternal(meshConfig)
 }
 
+// HandleMeshConfigWithData behaves the same as HandleMeshConfig, and keeps track of the revision mesh config the
+// mesh config was read from, reported by Layers.
+func (w *internalWatcher) HandleMeshConfigWithData(meshConfig *meshconfig.MeshConfig, yaml string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.revMeshConfig = yaml
+	w.handleMeshConfigInternal(meshConfig)
+}
+
 // handleMeshConfigInternal behaves the same as HandleMeshConfig but must be called under a lock
 func (w *internalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshConfig) {
 	var handlers []func()
@@ -90,6 +90,52 @@ func ParseJwksURI(jwksURI string) (JwksInfo, error) {
 	return info, nil
 }
 
+// OutputClaimToHeadersAnnotation can be set on a RequestAuthentication to copy claims of a validated JWT
+// into request headers, so backends do not need to parse the token again. The value is a comma separated
+// list of <header>:<claim> pairs, for example "x-jwt-sub:sub,x-jwt-email:email", and applies to every
+// JWT rule of the policy.
+const OutputClaimToHeadersAnnotation = "security.istio.io/output-claim-to-headers"
+
+// ClaimToHeader maps a top level JWT claim to the request header it is copied to.
+type ClaimToHeader struct {
+	Header string
+	Claim  string
+}
+
+// ParseOutputClaimToHeaders parses the value of the OutputClaimToHeadersAnnotation.
+func ParseOutputClaimToHeaders(value string) ([]ClaimToHeader, error) {
+	var errs *multierror.Error
+	var out []ClaimToHeader
+	seen := sets.NewSet()
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			errs = multierror.Append(errs, fmt.Errorf("bad claim to header mapping (%s): should have format header:claim", pair))
+			continue
+		}
+		header, claim := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		if header == "" || claim == "" {
+			errs = multierror.Append(errs, fmt.Errorf("bad claim to header mapping (%s): header and claim must be non-empty", pair))
+			continue
+		}
+		if strings.ContainsAny(header, " \t") || strings.ContainsAny(claim, " \t") {
+			errs = multierror.Append(errs, fmt.Errorf("bad claim to header mapping (%s): must not contain whitespace", pair))
+			continue
+		}
+		if seen.Contains(header) {
+			errs = multierror.Append(errs, fmt.Errorf("header %q is mapped more than once", header))
+			continue
+		}
+		seen.Insert(header)
+		out = append(out, ClaimToHeader{Header: header, Claim: claim})
+	}
+	return out, errs.ErrorOrNil()
+}
+
 func CheckEmptyValues(key string, values []string) error {
 	for _, value := range values {
 		if value == "" {
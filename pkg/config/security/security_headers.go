@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SecurityHeadersAnnotation can be set on a ProxyConfig to add security headers to the responses of the
+// proxies it applies to. The value is a JSON SecurityHeadersPolicy, for example
+// {"headers": {"Content-Security-Policy": "default-src 'self'"}, "sidecars": true}. A ProxyConfig in the root
+// namespace sets the policy of the mesh, which a ProxyConfig of a namespace or a workload overrides as a whole.
+const SecurityHeadersAnnotation = "proxy.istio.io/securityHeaders"
+
+// DefaultSecurityHeaders are the headers added by a SecurityHeadersPolicy, unless it overrides them.
+var DefaultSecurityHeaders = map[string]string{
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"X-Content-Type-Options":    "nosniff",
+}
+
+// SecurityHeadersPolicy is the value of the SecurityHeadersAnnotation.
+type SecurityHeadersPolicy struct {
+	// Headers are added to the responses in addition to the DefaultSecurityHeaders, or override them. A header
+	// with an empty value is not added.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Sidecars adds the headers to the inbound responses of sidecars as well. By default, only gateways add them.
+	Sidecars bool `json:"sidecars,omitempty"`
+	// Disabled turns off the policy of the mesh for a namespace or a workload.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// ResponseHeaders returns the headers added by the policy.
+func (p *SecurityHeadersPolicy) ResponseHeaders() map[string]string {
+	if p == nil || p.Disabled {
+		return nil
+	}
+	headers := make(map[string]string, len(DefaultSecurityHeaders)+len(p.Headers))
+	for name, value := range DefaultSecurityHeaders {
+		headers[name] = value
+	}
+	for name, value := range p.Headers {
+		if value == "" {
+			delete(headers, name)
+			continue
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// ParseSecurityHeadersPolicy parses the value of the SecurityHeadersAnnotation.
+func ParseSecurityHeadersPolicy(value string) (*SecurityHeadersPolicy, error) {
+	p := &SecurityHeadersPolicy{}
+	if err := json.Unmarshal([]byte(value), p); err != nil {
+		return nil, err
+	}
+	for name := range p.Headers {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return p, nil
+}
+
+// validHeaderName returns whether name is a valid HTTP header name which is not a pseudo header.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security_test
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+func TestSecurityHeadersPolicy(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			value: `{}`,
+			want: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		{
+			name:  "custom headers",
+			value: `{"headers": {"Content-Security-Policy": "default-src 'self'", "Strict-Transport-Security": "max-age=600", "X-Content-Type-Options": ""}}`,
+			want: map[string]string{
+				"Content-Security-Policy":   "default-src 'self'",
+				"Strict-Transport-Security": "max-age=600",
+			},
+		},
+		{
+			name:  "disabled",
+			value: `{"disabled": true}`,
+		},
+		{
+			name:    "pseudo header",
+			value:   `{"headers": {":authority": "foo"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			value:   `headers`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := security.ParseSecurityHeadersPolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := policy.ResponseHeaders(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -87,6 +87,54 @@ func TestParseJwksURI(t *testing.T) {
 	}
 }
 
+func TestParseOutputClaimToHeaders(t *testing.T) {
+	cases := []struct {
+		in            string
+		expected      []security.ClaimToHeader
+		expectedError bool
+	}{
+		{
+			in: "",
+		},
+		{
+			in:       "x-jwt-sub:sub",
+			expected: []security.ClaimToHeader{{Header: "x-jwt-sub", Claim: "sub"}},
+		},
+		{
+			in: "X-Jwt-Sub: sub, x-jwt-email:email,",
+			expected: []security.ClaimToHeader{
+				{Header: "x-jwt-sub", Claim: "sub"},
+				{Header: "x-jwt-email", Claim: "email"},
+			},
+		},
+		{
+			in:            "x-jwt-sub",
+			expectedError: true,
+		},
+		{
+			in:            ":authority:sub",
+			expectedError: true,
+		},
+		{
+			in:            "x-jwt-sub:",
+			expectedError: true,
+		},
+		{
+			in:            "x-jwt-sub:sub,x-jwt-sub:email",
+			expectedError: true,
+		},
+	}
+	for _, c := range cases {
+		actual, err := security.ParseOutputClaimToHeaders(c.in)
+		if c.expectedError == (err == nil) {
+			t.Fatalf("ParseOutputClaimToHeaders(%s): expected error (%v), got (%v)", c.in, c.expectedError, err)
+		}
+		if !c.expectedError && !reflect.DeepEqual(c.expected, actual) {
+			t.Fatalf("expected %+v, got %+v", c.expected, actual)
+		}
+	}
+}
+
 func TestValidateCondition(t *testing.T) {
 	cases := []struct {
 		key       string
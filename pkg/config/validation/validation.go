@@ -22,7 +22,9 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -51,14 +53,18 @@ import (
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/destinationrule"
+	"istio.io/istio/pkg/config/dynamicmetadata"
 	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/httproute"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/config/visibility"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/kube/apimirror"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
 	"istio.io/pkg/log"
 )
 
@@ -673,6 +679,17 @@ var ValidateDestinationRule = registerValidateFunc("ValidateDestinationRule",
 			v = appendValidation(v, validateSubset(subset))
 		}
 
+		if value, f := cfg.Annotations[destinationrule.PreconnectPolicyAnnotation]; f {
+			if _, err := destinationrule.ParsePreconnectPolicyAnnotation(value); err != nil {
+				v = appendValidation(v, fmt.Errorf("invalid %s annotation: %v", destinationrule.PreconnectPolicyAnnotation, err))
+			}
+		}
+		if value, f := cfg.Annotations[destinationrule.UpstreamALPNProtocolsAnnotation]; f {
+			if _, err := destinationrule.ParseUpstreamALPNProtocolsAnnotation(value); err != nil {
+				v = appendValidation(v, fmt.Errorf("invalid %s annotation: %v", destinationrule.UpstreamALPNProtocolsAnnotation, err))
+			}
+		}
+
 		v = appendValidation(v, validateExportTo(cfg.Namespace, rule.ExportTo, false))
 		return v.Unwrap()
 	})
@@ -1001,9 +1018,39 @@ var ValidateSidecar = registerValidateFunc("ValidateSidecar",
 			return nil, err
 		}
 
-		if len(rule.Egress) == 0 && len(rule.Ingress) == 0 && rule.OutboundTrafficPolicy == nil {
+		egressProxy, hasEgressProxy := cfg.Annotations[gateway.EgressProxyAnnotation]
+		corsPolicy, hasCorsPolicy := cfg.Annotations[constants.DefaultCorsPolicyAnnotation]
+		tlsOrigination, hasTLSOrigination := cfg.Annotations[gateway.PassthroughTLSOriginationAnnotation]
+		if len(rule.Egress) == 0 && len(rule.Ingress) == 0 && rule.OutboundTrafficPolicy == nil &&
+			!hasEgressProxy && !hasCorsPolicy && !hasTLSOrigination {
 			return nil, fmt.Errorf("sidecar: empty configuration provided")
 		}
+		if hasEgressProxy {
+			if _, _, err := gateway.ParseEgressProxyAnnotation(egressProxy); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", gateway.EgressProxyAnnotation, err))
+			}
+		}
+		if hasCorsPolicy {
+			if err := validateDefaultCorsPolicy(corsPolicy); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", constants.DefaultCorsPolicyAnnotation, err))
+			} else if rule.WorkloadSelector != nil {
+				errs = appendValidation(errs, Warningf("%s annotation is ignored on Sidecars with a workload selector",
+					constants.DefaultCorsPolicyAnnotation))
+			}
+		}
+		if hasTLSOrigination {
+			if _, err := strconv.ParseBool(tlsOrigination); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", gateway.PassthroughTLSOriginationAnnotation, err))
+			}
+		}
+		if credential, f := cfg.Annotations[gateway.EgressProxyCredentialAnnotation]; f {
+			if !hasEgressProxy {
+				errs = appendValidation(errs, fmt.Errorf("%s annotation requires the %s annotation",
+					gateway.EgressProxyCredentialAnnotation, gateway.EgressProxyAnnotation))
+			} else if err := ValidateFQDN(credential); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", gateway.EgressProxyCredentialAnnotation, err))
+			}
+		}
 
 		portMap := make(map[uint32]struct{})
 		for _, i := range rule.Ingress {
@@ -1335,6 +1382,11 @@ func validateLoadBalancer(settings *networking.LoadBalancerSettings) (errs error
 	if err := validateLocalityLbSetting(settings.LocalityLbSetting); err != nil {
 		errs = multierror.Append(errs, err)
 	}
+	if settings.WarmupDurationSecs != nil {
+		if err := ValidateDuration(settings.WarmupDurationSecs); err != nil {
+			errs = appendErrors(errs, fmt.Errorf("invalid warmupDurationSecs: %v", err))
+		}
+	}
 	return
 }
 
@@ -1575,6 +1627,12 @@ func ValidateMeshConfig(mesh *meshconfig.MeshConfig) (errs error) {
 		errs = multierror.Append(errs, err)
 	}
 
+	if v, f := mesh.GetDefaultConfig().GetProxyMetadata()[constants.DefaultCorsPolicyKey]; f {
+		if err := validateDefaultCorsPolicy(v); err != nil {
+			errs = multierror.Append(errs, multierror.Prefix(err, fmt.Sprintf("invalid %s proxy metadata:", constants.DefaultCorsPolicyKey)))
+		}
+	}
+
 	if err := validateLocalityLbSetting(mesh.LocalityLbSetting); err != nil {
 		errs = multierror.Append(errs, err)
 	}
@@ -1603,6 +1661,14 @@ func validateTrustDomainConfig(config *meshconfig.MeshConfig) (errs error) {
 			errs = multierror.Append(errs, fmt.Errorf("trustDomainAliases[%d], domain `%s` : %v", i, tda, err))
 		}
 	}
+	for i, ca := range config.CaCertificates {
+		for j, td := range ca.TrustDomains {
+			// Wildcard trust domains match the DNS SANs of meshes not using SPIFFE identities.
+			if err := ValidateTrustDomain(strings.TrimPrefix(td, "*.")); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("caCertificates[%d].trustDomains[%d], domain `%s` : %v", i, j, td, err))
+			}
+		}
+	}
 	return
 }
 
@@ -1899,6 +1965,11 @@ var ValidateRequestAuthentication = registerValidateFunc("ValidateRequestAuthent
 		for _, rule := range in.JwtRules {
 			errs = appendErrors(errs, validateJwtRule(rule))
 		}
+		if v, f := cfg.Annotations[security.OutputClaimToHeadersAnnotation]; f {
+			if _, err := security.ParseOutputClaimToHeaders(v); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("invalid %s annotation: %v", security.OutputClaimToHeadersAnnotation, err))
+			}
+		}
 		return nil, errs
 	})
 
@@ -2066,6 +2137,36 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		if len(virtualService.Http) == 0 && len(virtualService.Tcp) == 0 && len(virtualService.Tls) == 0 {
 			errs = appendValidation(errs, errors.New("http, tcp or tls must be provided in virtual service"))
 		}
+		if v, f := cfg.Annotations[gateway.TLSRouteALPNAnnotation]; f {
+			if len(gateway.ParseTLSRouteALPN(v)) == 0 {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: at least one protocol must be set", gateway.TLSRouteALPNAnnotation))
+			}
+			if !appliesToGateway || len(virtualService.Tls) == 0 {
+				errs = appendValidation(errs, fmt.Errorf("%s annotation is only supported for tls routes bound to a gateway", gateway.TLSRouteALPNAnnotation))
+			}
+		}
+		if v, f := cfg.Annotations[gateway.GRPCHealthCheckAnnotation]; f {
+			if _, err := gateway.ParseGRPCHealthCheckPath(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", gateway.GRPCHealthCheckAnnotation, err))
+			}
+			if !appliesToGateway || len(virtualService.Http) == 0 {
+				errs = appendValidation(errs, fmt.Errorf("%s annotation is only supported for http routes bound to a gateway", gateway.GRPCHealthCheckAnnotation))
+			}
+		}
+		for _, annotation := range httpRouteAnnotations {
+			if v, f := cfg.Annotations[annotation.name]; f {
+				if routeNames, err := annotation.routeNames(v); err != nil {
+					errs = appendErrorf(errs, "invalid %s annotation: %v", annotation.name, err)
+				} else {
+					errs = appendValidation(errs, validateAnnotationRouteNames(annotation.name, routeNames, virtualService))
+				}
+			}
+		}
+		if v, f := cfg.Annotations[httproute.RetryPerTryIdleTimeoutAnnotation]; f {
+			if _, err := httproute.ParseRetryPerTryIdleTimeoutAnnotation(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", httproute.RetryPerTryIdleTimeoutAnnotation, err))
+			}
+		}
 		for _, httpRoute := range virtualService.Http {
 			if httpRoute == nil {
 				errs = appendValidation(errs, errors.New("http route may not be null"))
@@ -2073,6 +2174,7 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 			}
 			errs = appendValidation(errs, validateHTTPRoute(httpRoute, len(virtualService.Hosts) == 0))
 		}
+		errs = appendValidation(errs, validateWeightedClusterCount(virtualService.Http))
 		for _, tlsRoute := range virtualService.Tls {
 			errs = appendValidation(errs, validateTLSRoute(tlsRoute, virtualService))
 		}
@@ -3164,9 +3266,37 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 		}
 
 		errs = appendValidation(errs, validateExportTo(cfg.Namespace, serviceEntry.ExportTo, true))
+		if v, f := cfg.Annotations[gateway.EgressGatewayAnnotation]; f {
+			if _, _, err := gateway.ParseEgressGatewayAnnotation(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", gateway.EgressGatewayAnnotation, err))
+			}
+		}
+		if v, f := cfg.Annotations[constants.ServiceEntryResolutionAnnotation]; f {
+			errs = appendValidation(errs, validateServiceEntryResolutionAnnotation(v, serviceEntry))
+		}
 		return errs.Unwrap()
 	})
 
+// validateServiceEntryResolutionAnnotation validates the constants.ServiceEntryResolutionAnnotation of a
+// ServiceEntry. The endpoints of a DNS_SRV ServiceEntry are resolved from the SRV records of its hosts.
+func validateServiceEntryResolutionAnnotation(value string, serviceEntry *networking.ServiceEntry) (errs error) {
+	if value != constants.DNSSRVResolution {
+		return fmt.Errorf("invalid %s annotation: unsupported resolution %q", constants.ServiceEntryResolutionAnnotation, value)
+	}
+	if serviceEntry.Resolution != networking.ServiceEntry_STATIC {
+		errs = appendErrors(errs, fmt.Errorf("%s resolution requires the STATIC resolution", value))
+	}
+	if len(serviceEntry.Endpoints) > 0 || serviceEntry.WorkloadSelector != nil {
+		errs = appendErrors(errs, fmt.Errorf("%s resolution cannot have endpoints or a workload selector", value))
+	}
+	for _, h := range serviceEntry.Hosts {
+		if host.Name(h).IsWildCarded() {
+			errs = appendErrors(errs, fmt.Errorf("%s resolution does not support wildcard host %s", value, h))
+		}
+	}
+	return
+}
+
 // ValidatePortName validates a port name to DNS-1123
 func ValidatePortName(name string) error {
 	if !labels.IsDNS1123Label(name) {
@@ -3436,9 +3566,89 @@ var ValidateProxyConfig = registerValidateFunc("ValidateProxyConfig",
 			validateWorkloadSelector(spec.Selector),
 			validateConcurrency(spec.Concurrency.GetValue()),
 		)
+		if v, f := cfg.Annotations[security.SecurityHeadersAnnotation]; f {
+			if _, err := security.ParseSecurityHeadersPolicy(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", security.SecurityHeadersAnnotation, err))
+			}
+		}
 		return errs.Unwrap()
 	})
 
+// httpRouteAnnotations are the annotations of a VirtualService configuring its HTTP routes by name, with the function
+// parsing their value into the names of the routes they refer to.
+var httpRouteAnnotations = []struct {
+	name       string
+	routeNames func(value string) ([]string, error)
+}{
+	{dynamicmetadata.HeaderToMetadataAnnotation, headerToMetadataRouteNames},
+	{httproute.PathRewriteAnnotation, func(value string) ([]string, error) {
+		return routeNameKeys(httproute.ParsePathRewriteAnnotation(value))
+	}},
+	{httproute.RetriableResponseHeadersAnnotation, func(value string) ([]string, error) {
+		return routeNameKeys(httproute.ParseRetriableResponseHeadersAnnotation(value))
+	}},
+	{httproute.LBSubsetHeadersAnnotation, func(value string) ([]string, error) {
+		return routeNameKeys(httproute.ParseLBSubsetHeadersAnnotation(value))
+	}},
+}
+
+// headerToMetadataRouteNames returns the names of the routes the rules of a dynamicmetadata.HeaderToMetadataAnnotation
+// are restricted to.
+func headerToMetadataRouteNames(value string) ([]string, error) {
+	rules, err := dynamicmetadata.ParseHeaderToMetadataAnnotation(value)
+	if err != nil {
+		return nil, err
+	}
+	var routeNames []string
+	for _, rule := range rules {
+		routeNames = append(routeNames, rule.Routes...)
+	}
+	return routeNames, nil
+}
+
+// routeNameKeys returns the keys of a parsed annotation value keyed by route name.
+func routeNameKeys(routes interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	keys := reflect.ValueOf(routes).MapKeys()
+	routeNames := make([]string, 0, len(keys))
+	for _, key := range keys {
+		routeNames = append(routeNames, key.String())
+	}
+	return routeNames, nil
+}
+
+// validateAnnotationRouteNames warns about the names of HTTP routes an annotation of a VirtualService refers to
+// which are not defined by the VirtualService, or about the annotation if the VirtualService has no HTTP routes.
+func validateAnnotationRouteNames(annotation string, routeNames []string, virtualService *networking.VirtualService) (v Validation) {
+	if len(virtualService.Http) == 0 {
+		v = appendValidation(v, Warningf("%s annotation is ignored without http routes", annotation))
+	}
+	routes := sets.NewSet()
+	for _, httpRoute := range virtualService.Http {
+		if httpRoute != nil {
+			routes.Insert(httpRoute.Name)
+		}
+	}
+	sort.Strings(routeNames)
+	for _, name := range routeNames {
+		if !routes.Contains(name) {
+			v = appendValidation(v, Warningf("%s annotation refers to unknown http route %q", annotation, name))
+		}
+	}
+	return
+}
+
+// validateDefaultCorsPolicy validates a JSON CorsPolicy set as the default CORS policy of the mesh or of a namespace.
+func validateDefaultCorsPolicy(value string) error {
+	policy := &networking.CorsPolicy{}
+	if err := gogoprotomarshal.ApplyJSONStrict(value, policy); err != nil {
+		return err
+	}
+	return validateCORSPolicy(policy)
+}
+
 func validateConcurrency(concurrency int32) (v Validation) {
 	if concurrency < 0 {
 		v = appendErrorf(v, "concurrency must be greater than or equal to 0")
@@ -3589,6 +3799,11 @@ var ValidateWasmPlugin = registerValidateFunc("ValidateWasmPlugin",
 			validateWasmPluginSHA(spec),
 			validateWasmPluginVMConfig(spec.VmConfig),
 		)
+		if v, f := cfg.Annotations[xds.WasmPluginAnchorAnnotation]; f {
+			if _, err := xds.ParseWasmPluginAnchor(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", xds.WasmPluginAnchorAnnotation, err))
+			}
+		}
 		return errs.Unwrap()
 	})
 
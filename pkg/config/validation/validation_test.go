@@ -15,6 +15,7 @@
 package validation
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -34,6 +35,9 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/security"
+	"istio.io/istio/pkg/config/xds"
 )
 
 const (
@@ -422,11 +426,16 @@ func TestValidateMeshConfig(t *testing.T) {
 	}
 
 	invalid := meshconfig.MeshConfig{
-		ProxyListenPort:    0,
-		ConnectTimeout:     types.DurationProto(-1 * time.Second),
-		DefaultConfig:      &meshconfig.ProxyConfig{},
+		ProxyListenPort: 0,
+		ConnectTimeout:  types.DurationProto(-1 * time.Second),
+		DefaultConfig: &meshconfig.ProxyConfig{
+			ProxyMetadata: map[string]string{constants.DefaultCorsPolicyKey: `{"allowMethods": ["FETCH"]}`},
+		},
 		TrustDomain:        "",
 		TrustDomainAliases: []string{"a.$b", "a/b", ""},
+		CaCertificates: []*meshconfig.MeshConfig_CertificateData{
+			{TrustDomains: []string{"*.identity.linkerd.cluster.local", "*.a/b"}},
+		},
 		ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{
 			{
 				Name: "default",
@@ -456,10 +465,12 @@ func TestValidateMeshConfig(t *testing.T) {
 			"discovery address must be set to the proxy discovery service",
 			"invalid proxy admin port",
 			"invalid status port",
+			"invalid DEFAULT_CORS_POLICY proxy metadata",
 			"trustDomain: empty domain name not allowed",
 			"trustDomainAliases[0]",
 			"trustDomainAliases[1]",
 			"trustDomainAliases[2]",
+			"caCertificates[0].trustDomains[1]",
 		}
 		switch err := err.(type) {
 		case *multierror.Error:
@@ -2796,6 +2807,248 @@ func TestValidateVirtualService(t *testing.T) {
 	}
 }
 
+func TestValidateVirtualServiceTLSRouteALPN(t *testing.T) {
+	tlsVS := &networking.VirtualService{
+		Hosts:    []string{"foo.bar"},
+		Gateways: []string{"istio-system/gateway"},
+		Tls: []*networking.TLSRoute{{
+			Match: []*networking.TLSMatchAttributes{{SniHosts: []string{"foo.bar"}}},
+			Route: []*networking.RouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	meshVS := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Tls:   tlsVS.Tls,
+	}
+	cases := []struct {
+		name  string
+		in    *networking.VirtualService
+		alpn  string
+		valid bool
+	}{
+		{name: "single protocol", in: tlsVS, alpn: "h2", valid: true},
+		{name: "multiple protocols", in: tlsVS, alpn: "h2, http/1.1", valid: true},
+		{name: "empty", in: tlsVS, alpn: " , ", valid: false},
+		{name: "not bound to gateway", in: meshVS, alpn: "h2", valid: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateVirtualService(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{"networking.istio.io/tls-alpn": tc.alpn}},
+				Spec: tc.in,
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateVirtualServiceGRPCHealthCheck(t *testing.T) {
+	httpVS := &networking.VirtualService{
+		Hosts:    []string{"foo.bar"},
+		Gateways: []string{"istio-system/gateway"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	meshVS := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Http:  httpVS.Http,
+	}
+	cases := []struct {
+		name  string
+		in    *networking.VirtualService
+		path  string
+		valid bool
+	}{
+		{name: "path", in: httpVS, path: "/healthz", valid: true},
+		{name: "relative path", in: httpVS, path: "healthz", valid: false},
+		{name: "query", in: httpVS, path: "/healthz?full=true", valid: false},
+		{name: "not bound to gateway", in: meshVS, path: "/healthz", valid: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateVirtualService(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{"networking.istio.io/grpc-health-check": tc.path}},
+				Spec: tc.in,
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateVirtualServiceHTTPRouteAnnotations(t *testing.T) {
+	vs := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Http: []*networking.HTTPRoute{{
+			Name: "items",
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	tcpVS := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Tcp: []*networking.TCPRoute{{
+			Route: []*networking.RouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	const (
+		headerToMetadata         = "networking.istio.io/header-to-metadata"
+		pathRewrite              = "networking.istio.io/path-rewrite"
+		retriableResponseHeaders = "networking.istio.io/retriable-response-headers"
+		lbSubsetHeaders          = "networking.istio.io/lb-subset-headers"
+	)
+	cases := []struct {
+		annotation string
+		name       string
+		value      string
+		in         *networking.VirtualService
+		valid      bool
+		warning    bool
+	}{
+		{annotation: headerToMetadata, name: "rule", value: `[{"header": "x-tenant", "key": "tenant", "namespace": "envoy.lb", "routes": ["items"]}]`, valid: true},
+		{annotation: headerToMetadata, name: "number", value: `[{"header": "x-tier", "key": "tier", "type": "NUMBER", "onMissing": "0"}]`, valid: true},
+		{annotation: headerToMetadata, name: "not json", value: `x-tenant:tenant`, valid: false},
+		{annotation: headerToMetadata, name: "uppercase header", value: `[{"header": "X-Tenant", "key": "tenant"}]`, valid: false},
+		{annotation: headerToMetadata, name: "no key", value: `[{"header": "x-tenant"}]`, valid: false},
+		{annotation: headerToMetadata, name: "reserved namespace", value: `[{"header": "x-tenant", "key": "principal", "namespace": "istio_authn"}]`, valid: false},
+		{annotation: headerToMetadata, name: "invalid type", value: `[{"header": "x-tenant", "key": "tenant", "type": "BOOL"}]`, valid: false},
+		{annotation: headerToMetadata, name: "unknown route", value: `[{"header": "x-tenant", "key": "tenant", "routes": ["web"]}]`, valid: true, warning: true},
+		{annotation: headerToMetadata, name: "no http routes", value: `[{"header": "x-tenant", "key": "tenant"}]`, in: tcpVS, valid: true, warning: true},
+
+		{annotation: pathRewrite, name: "regex", value: `{"items": {"regex": "^/v1/(.*)$", "substitution": "/v2/\\1"}}`, valid: true},
+		{annotation: pathRewrite, name: "path template", value: `{"items": {"pathTemplate": "/v1/{name}", "rewrite": "/{name}"}}`, valid: true},
+		{annotation: pathRewrite, name: "not json", value: `items=/v1`, valid: false},
+		{annotation: pathRewrite, name: "invalid regex", value: `{"items": {"regex": "^/v1/(.*$"}}`, valid: false},
+		{annotation: pathRewrite, name: "unknown variable", value: `{"items": {"pathTemplate": "/v1/{name}", "rewrite": "/{id}"}}`, valid: false},
+		{annotation: pathRewrite, name: "unknown route", value: `{"other": {"regex": "^/v1/(.*)$", "substitution": "/\\1"}}`, valid: true, warning: true},
+
+		{annotation: retriableResponseHeaders, name: "exact", value: `{"items": {"x-overloaded": {"exact": "true"}}}`, valid: true},
+		{annotation: retriableResponseHeaders, name: "presence", value: `{"items": {"x-retry-after": {}}}`, valid: true},
+		{annotation: retriableResponseHeaders, name: "not json", value: `x-overloaded=true`, valid: false},
+		{annotation: retriableResponseHeaders, name: "exact and prefix", value: `{"items": {"x-overloaded": {"exact": "true", "prefix": "t"}}}`, valid: false},
+		{annotation: retriableResponseHeaders, name: "invalid regex", value: `{"items": {"x-overloaded": {"regex": "(true"}}}`, valid: false},
+		{annotation: retriableResponseHeaders, name: "invalid header", value: `{"items": {"x overloaded": {}}}`, valid: false},
+		{annotation: retriableResponseHeaders, name: "unknown route", value: `{"other": {"x-overloaded": {"exact": "true"}}}`, valid: true, warning: true},
+
+		{annotation: lbSubsetHeaders, name: "headers", value: `{"items": {"x-gpu": "gpu", "x-shard": "shard"}}`, valid: true},
+		{annotation: lbSubsetHeaders, name: "not json", value: `x-gpu=gpu`, valid: false},
+		{annotation: lbSubsetHeaders, name: "empty key", value: `{"items": {"x-gpu": ""}}`, valid: false},
+		{annotation: lbSubsetHeaders, name: "invalid header", value: `{"items": {"x-gpu:": "gpu"}}`, valid: false},
+		{annotation: lbSubsetHeaders, name: "unknown route", value: `{"other": {"x-gpu": "gpu"}}`, valid: true, warning: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.annotation+" "+tc.name, func(t *testing.T) {
+			in := tc.in
+			if in == nil {
+				in = vs
+			}
+			warn, err := ValidateVirtualService(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{tc.annotation: tc.value}},
+				Spec: in,
+			})
+			checkValidation(t, warn, err, tc.valid, tc.warning)
+		})
+	}
+}
+
+func TestValidateVirtualServiceRetryPerTryIdleTimeout(t *testing.T) {
+	vs := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	cases := []struct {
+		name    string
+		timeout string
+		valid   bool
+	}{
+		{name: "duration", timeout: "1s", valid: true},
+		{name: "not a duration", timeout: "soon", valid: false},
+		{name: "negative", timeout: "-1s", valid: false},
+		{name: "too small", timeout: "1us", valid: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateVirtualService(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{"networking.istio.io/retry-per-try-idle-timeout": tc.timeout}},
+				Spec: vs,
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateVirtualServiceWeightedDestinations(t *testing.T) {
+	destinations := func(n int, weight int32) []*networking.HTTPRouteDestination {
+		out := make([]*networking.HTTPRouteDestination, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, &networking.HTTPRouteDestination{
+				Destination: &networking.Destination{Host: "foo.baz", Subset: fmt.Sprintf("v%d", i)},
+				Weight:      weight,
+			})
+		}
+		return out
+	}
+	matches := func(n int) []*networking.HTTPMatchRequest {
+		out := make([]*networking.HTTPMatchRequest, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, &networking.HTTPMatchRequest{
+				Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: fmt.Sprintf("/%d", i)}},
+			})
+		}
+		return out
+	}
+	testCases := []struct {
+		name    string
+		in      proto.Message
+		valid   bool
+		warning bool
+	}{
+		{name: "too many destinations", in: &networking.VirtualService{
+			Hosts: []string{"foo.bar"},
+			Http: []*networking.HTTPRoute{{
+				Route: append(destinations(httpRouteDestinationsWarningThreshold-99, 0), destinations(100, 1)...),
+			}},
+		}, valid: true, warning: true},
+		{name: "duplicate destinations", in: &networking.VirtualService{
+			Hosts: []string{"foo.bar"},
+			Http: []*networking.HTTPRoute{{
+				Route: append(destinations(2, 25), destinations(2, 25)...),
+			}},
+		}, valid: true, warning: true},
+		{name: "many weighted clusters", in: &networking.VirtualService{
+			Hosts: []string{"foo.bar"},
+			Http: []*networking.HTTPRoute{{
+				Match: matches(weightedClustersWarningThreshold/50 + 1),
+				Route: destinations(50, 2),
+			}},
+		}, valid: true, warning: true},
+		{name: "weighted clusters below threshold", in: &networking.VirtualService{
+			Hosts: []string{"foo.bar"},
+			Http: []*networking.HTTPRoute{{
+				Match: matches(weightedClustersWarningThreshold / 50),
+				Route: destinations(50, 2),
+			}},
+		}, valid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateVirtualService(config.Config{Spec: tc.in})
+			checkValidation(t, warn, err, tc.valid, tc.warning)
+		})
+	}
+}
+
 func TestValidateWorkloadEntry(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -3173,6 +3426,50 @@ func TestValidateDestinationRule(t *testing.T) {
 	}
 }
 
+func TestValidateDestinationRulePreconnectPolicy(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy string
+		valid  bool
+	}{
+		{name: "ratios", policy: "perUpstream=1.5,predictive=2", valid: true},
+		{name: "ratio too large", policy: "perUpstream=4", valid: false},
+		{name: "unknown ratio", policy: "eager=2", valid: false},
+		{name: "no value", policy: "perUpstream", valid: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateDestinationRule(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{"networking.istio.io/preconnect-policy": tc.policy}},
+				Spec: &networking.DestinationRule{Host: "reviews"},
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateDestinationRuleUpstreamALPNProtocols(t *testing.T) {
+	cases := []struct {
+		name      string
+		protocols string
+		valid     bool
+	}{
+		{name: "protocols", protocols: "h2,http/1.1", valid: true},
+		{name: "no protocol", protocols: "", valid: true},
+		{name: "empty protocol", protocols: "h2,,http/1.1", valid: false},
+		{name: "space in protocol", protocols: "http 1.1", valid: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateDestinationRule(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{"networking.istio.io/upstream-alpn-protocols": tc.protocols}},
+				Spec: &networking.DestinationRule{Host: "reviews"},
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
 func TestValidateTrafficPolicy(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -3457,6 +3754,26 @@ func TestValidateLoadBalancer(t *testing.T) {
 			},
 			valid: false,
 		},
+
+		{
+			name: "valid load balancer with warmup duration", in: networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_Simple{
+					Simple: networking.LoadBalancerSettings_ROUND_ROBIN,
+				},
+				WarmupDurationSecs: &duration,
+			},
+			valid: true,
+		},
+
+		{
+			name: "invalid load balancer with zero warmup duration", in: networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_Simple{
+					Simple: networking.LoadBalancerSettings_ROUND_ROBIN,
+				},
+				WarmupDurationSecs: &types.Duration{},
+			},
+			valid: false,
+		},
 	}
 
 	for _, c := range cases {
@@ -4482,6 +4799,81 @@ func TestValidateServiceEntries(t *testing.T) {
 	}
 }
 
+func TestValidateServiceEntryEgressGateway(t *testing.T) {
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{value: "istio-egressgateway.istio-system.svc.cluster.local", valid: true},
+		{value: "istio-egressgateway.istio-system.svc.cluster.local:8443", valid: true},
+		{value: "istio-egressgateway", valid: false},
+		{value: "istio-egressgateway.istio-system.svc.cluster.local:https", valid: false},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			_, err := ValidateServiceEntry(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{gateway.EgressGatewayAnnotation: c.value},
+				},
+				Spec: &networking.ServiceEntry{
+					Hosts:      []string{"api.example.com"},
+					Ports:      []*networking.Port{{Number: 443, Protocol: "TLS", Name: "tls"}},
+					Resolution: networking.ServiceEntry_DNS,
+				},
+			})
+			if (err == nil) != c.valid {
+				t.Errorf("got valid=%v but wanted valid=%v: %v", err == nil, c.valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateServiceEntryDNSSRV(t *testing.T) {
+	cases := []struct {
+		name       string
+		value      string
+		resolution networking.ServiceEntry_Resolution
+		hosts      []string
+		endpoints  []*networking.WorkloadEntry
+		valid      bool
+	}{
+		{name: "valid", value: "DNS_SRV", resolution: networking.ServiceEntry_STATIC, valid: true},
+		{name: "unsupported value", value: "SRV", resolution: networking.ServiceEntry_STATIC, valid: false},
+		{name: "dns resolution", value: "DNS_SRV", resolution: networking.ServiceEntry_DNS, valid: false},
+		{name: "wildcard host", value: "DNS_SRV", resolution: networking.ServiceEntry_STATIC, hosts: []string{"*.service.consul"}, valid: false},
+		{
+			name: "endpoints", value: "DNS_SRV", resolution: networking.ServiceEntry_STATIC,
+			endpoints: []*networking.WorkloadEntry{{Address: "10.0.0.1"}}, valid: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hosts := c.hosts
+			if hosts == nil {
+				hosts = []string{"db.service.consul"}
+			}
+			_, err := ValidateServiceEntry(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{constants.ServiceEntryResolutionAnnotation: c.value},
+				},
+				Spec: &networking.ServiceEntry{
+					Hosts:      hosts,
+					Ports:      []*networking.Port{{Number: 5432, Protocol: "TCP", Name: "tcp-db"}},
+					Resolution: c.resolution,
+					Endpoints:  c.endpoints,
+				},
+			})
+			if (err == nil) != c.valid {
+				t.Errorf("got valid=%v but wanted valid=%v: %v", err == nil, c.valid, err)
+			}
+		})
+	}
+}
+
 func TestValidateAuthorizationPolicy(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -5926,6 +6318,110 @@ func TestValidateSidecar(t *testing.T) {
 	}
 }
 
+func TestValidateSidecarEgressProxy(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{name: "valid", annotations: map[string]string{gateway.EgressProxyAnnotation: "proxy.corp.example.com:3128"}, valid: true},
+		{name: "missing port", annotations: map[string]string{gateway.EgressProxyAnnotation: "proxy.corp.example.com"}, valid: false},
+		{name: "invalid port", annotations: map[string]string{gateway.EgressProxyAnnotation: "proxy.corp.example.com:http"}, valid: false},
+		{name: "wildcard", annotations: map[string]string{gateway.EgressProxyAnnotation: "*.corp.example.com:3128"}, valid: false},
+		{
+			name: "credential",
+			annotations: map[string]string{
+				gateway.EgressProxyAnnotation:           "proxy.corp.example.com:3128",
+				gateway.EgressProxyCredentialAnnotation: "proxy-credential",
+			},
+			valid: true,
+		},
+		{
+			name: "invalid credential",
+			annotations: map[string]string{
+				gateway.EgressProxyAnnotation:           "proxy.corp.example.com:3128",
+				gateway.EgressProxyCredentialAnnotation: "Proxy_Credential",
+			},
+			valid: false,
+		},
+		{
+			name:        "credential without proxy",
+			annotations: map[string]string{gateway.EgressProxyCredentialAnnotation: "proxy-credential"},
+			valid:       false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ValidateSidecar(config.Config{
+				Meta: config.Meta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: c.annotations,
+				},
+				Spec: &networking.Sidecar{},
+			})
+			if (err == nil) != c.valid {
+				t.Errorf("got valid=%v but wanted valid=%v: %v", err == nil, c.valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateSidecarPassthroughTLSOrigination(t *testing.T) {
+	for value, valid := range map[string]bool{"true": true, "false": true, "yes": false} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ValidateSidecar(config.Config{
+				Meta: config.Meta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: map[string]string{gateway.PassthroughTLSOriginationAnnotation: value},
+				},
+				Spec: &networking.Sidecar{},
+			})
+			if (err == nil) != valid {
+				t.Errorf("got valid=%v but wanted valid=%v: %v", err == nil, valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateSidecarDefaultCorsPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		cors     string
+		selector *networking.WorkloadSelector
+		out      string
+		warning  string
+	}{
+		{name: "valid", cors: `{"allowOrigins": [{"exact": "https://example.com"}], "allowMethods": ["GET"]}`},
+		{name: "invalid", cors: `{"allowMethods": ["FETCH"]}`, out: "invalid networking.istio.io/defaultCorsPolicy annotation"},
+		{
+			name: "unknown field",
+			cors: `{"allowOrigin": "https://example.com", "origins": []}`,
+			out:  "invalid networking.istio.io/defaultCorsPolicy annotation",
+		},
+		{
+			name:     "workload selector",
+			cors:     `{"allowMethods": ["GET"]}`,
+			selector: &networking.WorkloadSelector{Labels: map[string]string{"app": "productpage"}},
+			warning:  "annotation is ignored on Sidecars with a workload selector",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			warn, err := ValidateSidecar(config.Config{
+				Meta: config.Meta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: map[string]string{constants.DefaultCorsPolicyAnnotation: c.cors},
+				},
+				Spec: &networking.Sidecar{WorkloadSelector: c.selector},
+			})
+			checkValidationMessage(t, warn, err, c.warning, c.out)
+		})
+	}
+}
+
 func TestValidateLocalityLbSetting(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -6257,6 +6753,20 @@ func TestValidateRequestAuthentication(t *testing.T) {
 			in:          &security_beta.RequestAuthentication{},
 			valid:       false,
 		},
+		{
+			name:        "output claim to headers annotation",
+			configName:  someName,
+			annotations: map[string]string{"security.istio.io/output-claim-to-headers": "x-jwt-sub:sub,x-jwt-email:email"},
+			in:          &security_beta.RequestAuthentication{},
+			valid:       true,
+		},
+		{
+			name:        "bad output claim to headers annotation",
+			configName:  someName,
+			annotations: map[string]string{"security.istio.io/output-claim-to-headers": "x-jwt-sub"},
+			in:          &security_beta.RequestAuthentication{},
+			valid:       false,
+		},
 		{
 			name:       "default name with non empty selector",
 			configName: constants.DefaultAuthenticationPolicyName,
@@ -6929,22 +7439,35 @@ func TestValidateTelemetry(t *testing.T) {
 
 func TestValidateProxyConfig(t *testing.T) {
 	tests := []struct {
-		name    string
-		in      proto.Message
-		out     string
-		warning string
+		name        string
+		in          proto.Message
+		annotations map[string]string
+		out         string
+		warning     string
 	}{
-		{"empty", &networkingv1beta1.ProxyConfig{}, "", ""},
+		{name: "empty", in: &networkingv1beta1.ProxyConfig{}},
 		{name: "invalid concurrency", in: &networkingv1beta1.ProxyConfig{
 			Concurrency: &types.Int32Value{Value: -1},
 		}, out: "concurrency must be greater than or equal to 0"},
+		{
+			name:        "security headers",
+			in:          &networkingv1beta1.ProxyConfig{},
+			annotations: map[string]string{security.SecurityHeadersAnnotation: `{"headers": {"Content-Security-Policy": "default-src 'self'"}}`},
+		},
+		{
+			name:        "invalid security headers",
+			in:          &networkingv1beta1.ProxyConfig{},
+			annotations: map[string]string{security.SecurityHeadersAnnotation: `{"headers": {":status": "200"}}`},
+			out:         "invalid header name",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			warn, err := ValidateProxyConfig(config.Config{
 				Meta: config.Meta{
-					Name:      someName,
-					Namespace: someNamespace,
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: tt.annotations,
 				},
 				Spec: tt.in,
 			})
@@ -7117,3 +7640,31 @@ func TestValidateWasmPlugin(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateWasmPluginAnchor(t *testing.T) {
+	tests := []struct {
+		anchor string
+		out    string
+	}{
+		{anchor: "before:jwt_authn"},
+		{anchor: "after:envoy.filters.http.ext_authz"},
+		{anchor: "router-upstream"},
+		{anchor: "jwt_authn", out: "invalid anchor"},
+		{anchor: "around:rbac", out: "invalid anchor"},
+		{anchor: "after:", out: "invalid anchor filter"},
+		{anchor: "before:router", out: "invalid anchor filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			warn, err := ValidateWasmPlugin(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{xds.WasmPluginAnchorAnnotation: tt.anchor},
+				},
+				Spec: &extensions.WasmPlugin{Url: "http://test.com/test"},
+			})
+			checkValidationMessage(t, warn, err, "", tt.out)
+		})
+	}
+}
@@ -31,6 +31,15 @@ const (
 	DelegateRoute
 )
 
+const (
+	// httpRouteDestinationsWarningThreshold is the number of destinations of a single HTTP route above which a
+	// warning is returned. Existing routes above it are still accepted.
+	httpRouteDestinationsWarningThreshold = 256
+	// weightedClustersWarningThreshold is the number of weighted clusters generated for a virtual service,
+	// one per match and weighted destination, above which a warning is returned.
+	weightedClustersWarningThreshold = 2000
+)
+
 func getHTTPRouteType(http *networking.HTTPRoute, isDelegate bool) HTTPRouteType {
 	if isDelegate {
 		return DelegateRoute
@@ -100,6 +109,7 @@ func validateHTTPRoute(http *networking.HTTPRoute, delegate bool) (errs Validati
 	errs = appendValidation(errs, validateHTTPRewrite(http.Rewrite))
 	errs = appendValidation(errs, validateAuthorityRewrite(http.Rewrite, http.Headers))
 	errs = appendValidation(errs, validateHTTPRouteDestinations(http.Route))
+	errs = appendValidation(errs, validateHTTPRouteDestinationsSize(http.Route))
 	if http.Timeout != nil {
 		errs = appendValidation(errs, ValidateDuration(http.Timeout))
 	}
@@ -107,6 +117,56 @@ func validateHTTPRoute(http *networking.HTTPRoute, delegate bool) (errs Validati
 	return
 }
 
+// validateHTTPRouteDestinationsSize warns about routes with a very large number of destinations, and about
+// destinations which are listed multiple times. Those are merged into a single weighted cluster.
+func validateHTTPRouteDestinationsSize(destinations []*networking.HTTPRouteDestination) (errs Validation) {
+	if len(destinations) > httpRouteDestinationsWarningThreshold {
+		errs = appendValidation(errs, Warningf("http route has %d destinations, more than %d; consider splitting it",
+			len(destinations), httpRouteDestinationsWarningThreshold))
+	}
+	seen := map[string]struct{}{}
+	for _, dst := range destinations {
+		if dst.GetDestination() == nil || dst.Headers != nil {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%d", dst.Destination.Host, dst.Destination.Subset, dst.Destination.GetPort().GetNumber())
+		if _, f := seen[key]; f {
+			errs = appendValidation(errs, Warningf("destination %s (subset %q) is listed multiple times; its weights are merged",
+				dst.Destination.Host, dst.Destination.Subset))
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+	return errs
+}
+
+// validateWeightedClusterCount warns when a virtual service generates a very large number of weighted clusters,
+// which makes the route configuration of every proxy importing it large.
+func validateWeightedClusterCount(routes []*networking.HTTPRoute) Validation {
+	count := 0
+	for _, http := range routes {
+		weighted := 0
+		for _, dst := range http.GetRoute() {
+			if dst.GetWeight() > 0 {
+				weighted++
+			}
+		}
+		if weighted < 2 {
+			continue
+		}
+		matches := len(http.Match)
+		if matches == 0 {
+			matches = 1
+		}
+		count += matches * weighted
+	}
+	if count > weightedClustersWarningThreshold {
+		return Warningf("virtual service generates %d weighted clusters, more than %d; consider splitting it or reducing the number "+
+			"of matches with weighted destinations", count, weightedClustersWarningThreshold)
+	}
+	return Validation{}
+}
+
 // validateAuthorityRewrite ensures we only attempt rewrite authority in a single place.
 func validateAuthorityRewrite(rewrite *networking.HTTPRewrite, headers *networking.Headers) error {
 	current := rewrite.GetAuthority()
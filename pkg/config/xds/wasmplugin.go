@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WasmPluginAnchorAnnotation can be set on a WasmPlugin to insert it next to a specific HTTP filter, instead of
+// at the position of its phase. The value is "before:<filter>" or "after:<filter>", where the filter is the name
+// of an Envoy HTTP filter or one of the short names jwt_authn, istio_authn, ext_authz, rbac and stats, or
+// "router-upstream" to insert the plugin last, right before the router. If the filter is not in the filter chain,
+// the plugin is inserted at the position of its phase.
+const WasmPluginAnchorAnnotation = "extensions.istio.io/anchor"
+
+// WasmPluginRouterUpstream is the value of the WasmPluginAnchorAnnotation inserting a plugin right before the router.
+const WasmPluginRouterUpstream = "router-upstream"
+
+// wasmPluginAnchorFilters are the short names of the filters WasmPlugins can be anchored to.
+var wasmPluginAnchorFilters = map[string]string{
+	"jwt_authn":   "envoy.filters.http.jwt_authn",
+	"istio_authn": "istio_authn",
+	"ext_authz":   "envoy.filters.http.ext_authz",
+	"rbac":        "envoy.filters.http.rbac",
+	"stats":       "istio.stats",
+}
+
+// WasmPluginAnchor is the position of a WasmPlugin relative to another HTTP filter.
+type WasmPluginAnchor struct {
+	// Filter is the name of the HTTP filter the plugin is anchored to. It is empty for the router upstream anchor.
+	Filter string
+	// Before is true if the plugin is inserted before the filter, rather than after it.
+	Before bool
+}
+
+// String returns the value of the WasmPluginAnchorAnnotation for the anchor.
+func (a WasmPluginAnchor) String() string {
+	if a.Filter == "" {
+		return WasmPluginRouterUpstream
+	}
+	if a.Before {
+		return "before:" + a.Filter
+	}
+	return "after:" + a.Filter
+}
+
+// ParseWasmPluginAnchor parses the value of the WasmPluginAnchorAnnotation.
+func ParseWasmPluginAnchor(value string) (WasmPluginAnchor, error) {
+	value = strings.TrimSpace(value)
+	if value == WasmPluginRouterUpstream {
+		return WasmPluginAnchor{}, nil
+	}
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || (parts[0] != "before" && parts[0] != "after") {
+		return WasmPluginAnchor{}, fmt.Errorf("invalid anchor %q, must be before:<filter>, after:<filter> or %s",
+			value, WasmPluginRouterUpstream)
+	}
+	filter := strings.TrimSpace(parts[1])
+	if name, f := wasmPluginAnchorFilters[filter]; f {
+		filter = name
+	}
+	if filter == "" || filter == "router" || filter == "envoy.filters.http.router" {
+		return WasmPluginAnchor{}, fmt.Errorf("invalid anchor filter %q", parts[1])
+	}
+	return WasmPluginAnchor{Filter: filter, Before: parts[0] == "before"}, nil
+}
@@ -19,6 +19,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -53,6 +54,17 @@ type LocalDNSServer struct {
 	proxyDomainParts []string
 
 	respondBeforeSync bool
+
+	// synced is closed once the lookup table has been received.
+	synced     chan struct{}
+	syncedOnce sync.Once
+	// holdTimeout is how long queries received before the lookup table are held waiting for it, instead of
+	// failing right away. This avoids failing the DNS lookups of applications starting before the proxy is synced.
+	holdTimeout time.Duration
+	// held is the number of queries currently held.
+	held int32
+	// ttl is the TTL in seconds of the records answered from the lookup table.
+	ttl uint32
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -72,18 +84,36 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+
+	// searchNamespaces are the search namespaces of resolv.conf, with a leading and trailing dot. Queries for hosts
+	// expanded with them are answered with a CNAME record to the host.
+	searchNamespaces []string
+	// ttl is the TTL in seconds of the records.
+	ttl uint32
 }
 
 const (
 	// In case the client decides to honor the TTL, keep it low so that we can always serve
 	// the latest IP for a host.
-	// TODO: make it configurable
 	defaultTTLInSeconds = 30
+
+	// DefaultTTL is the default TTL of the records answered from the lookup table.
+	DefaultTTL = defaultTTLInSeconds * time.Second
+
+	// DefaultHoldTimeout is the default time queries received before the lookup table are held for.
+	// It is kept below the 5s default timeout of the glibc resolver, so applications get an answer before retrying.
+	DefaultHoldTimeout = 3 * time.Second
+
+	// maxHeldQueries bounds the number of queries held at the same time. Queries over the limit fail right away.
+	maxHeldQueries = 1024
 )
 
 func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string) (*LocalDNSServer, error) {
 	h := &LocalDNSServer{
 		proxyNamespace: proxyNamespace,
+		synced:         make(chan struct{}),
+		holdTimeout:    DefaultHoldTimeout,
+		ttl:            defaultTTLInSeconds,
 	}
 
 	registerStats()
@@ -186,6 +216,10 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		ttl:      h.ttl,
+	}
+	for _, s := range h.searchNamespaces {
+		lookupTable.searchNamespaces = append(lookupTable.searchNamespaces, "."+strings.ToLower(strings.Trim(s, "."))+".")
 	}
 	for hostname, ni := range nt.Table {
 		// Given a host
@@ -210,6 +244,7 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 	}
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
+	h.syncedOnce.Do(func() { close(h.synced) })
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
 }
 
@@ -246,6 +281,9 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 
 	lp := h.lookupTable.Load()
 	hostname := strings.ToLower(req.Question[0].Name)
+	if lp == nil && !h.respondBeforeSync && h.waitForSync() {
+		lp = h.lookupTable.Load()
+	}
 	if lp == nil {
 		if h.respondBeforeSync {
 			response = h.upstream(proxy, req, hostname)
@@ -284,6 +322,7 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		roundRobinResponse(response)
 		log.Debugf("response for hostname %q (found=true): %v", hostname, response)
 	} else {
+		lookupTableMisses.With(queryTypeTag.Value(queryType(req.Question[0].Qtype))).Increment()
 		response = h.upstream(proxy, req, hostname)
 	}
 	// Compress the response - we don't know if the incoming response was compressed or not. If it was,
@@ -293,6 +332,41 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 	_ = w.WriteMsg(response)
 }
 
+// SetHoldTimeout sets how long queries received before the lookup table are held waiting for it. Zero disables
+// holding, failing these queries right away.
+func (h *LocalDNSServer) SetHoldTimeout(timeout time.Duration) {
+	h.holdTimeout = timeout
+}
+
+// SetTTL sets the TTL of the records answered from the lookup table, rounded down to the second. A TTL under a
+// second keeps the default. It must be called before the lookup table is received.
+func (h *LocalDNSServer) SetTTL(ttl time.Duration) {
+	if ttl >= time.Second {
+		h.ttl = uint32(ttl / time.Second)
+	}
+}
+
+// waitForSync holds a query until the lookup table is received, and returns true if it was received before the
+// hold timeout.
+func (h *LocalDNSServer) waitForSync() bool {
+	if h.holdTimeout <= 0 {
+		return false
+	}
+	defer atomic.AddInt32(&h.held, -1)
+	if atomic.AddInt32(&h.held, 1) > maxHeldQueries {
+		return false
+	}
+	heldRequests.Increment()
+	timer := time.NewTimer(h.holdTimeout)
+	defer timer.Stop()
+	select {
+	case <-h.synced:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // IsReady returns true if DNS lookup table is updated atleast once.
 func (h *LocalDNSServer) IsReady() bool {
 	return h.lookupTable.Load() != nil
@@ -442,6 +516,31 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 // If it is not part of the registry, return nil so that caller queries upstream. If it is part
 // of registry, we will look it up in one of our tables, failing which we will return NXDOMAIN.
 func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, bool) {
+	if out, hostFound := table.lookupName(qtype, hostname); hostFound {
+		return out, true
+	}
+	// Only the expansions of the hosts with the first search namespace are precomputed. The host may have been
+	// expanded with another search namespace, or be matched by a wildcard host: strip the search namespace and
+	// answer with a CNAME record to the host, as for the precomputed expansions.
+	for _, searchNamespace := range table.searchNamespaces {
+		if !strings.HasSuffix(hostname, searchNamespace) {
+			continue
+		}
+		target := strings.TrimSuffix(hostname, searchNamespace) + "."
+		ipAnswers, hostFound := table.lookupName(qtype, target)
+		if !hostFound {
+			continue
+		}
+		if len(ipAnswers) == 0 {
+			return nil, true
+		}
+		return append(withTTL(cname(hostname, target), table.ttl), ipAnswers...), true
+	}
+	return nil, false
+}
+
+// lookupName looks up a host in the tables, or a wildcard host matching it.
+func (table *LookupTable) lookupName(qtype uint16, hostname string) ([]dns.RR, bool) {
 	var hostFound bool
 
 	question := host.Name(hostname)
@@ -488,11 +587,16 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 	}
 
 	if len(ipAnswers) > 0 {
-		// For wildcard hosts, set the host that is being queried for.
+		// For wildcard hosts, set the host that is being queried for. The records are shared by all the
+		// queries, so they are copied.
 		if wildcard {
+			answers := make([]dns.RR, 0, len(ipAnswers))
 			for _, answer := range ipAnswers {
+				answer = dns.Copy(answer)
 				answer.Header().Name = string(question)
+				answers = append(answers, answer)
 			}
+			ipAnswers = answers
 		}
 		// We will return a chained response. In a chained response, the first entry is the cname record,
 		// and the second one is the A/AAAA record itself. Some clients do not follow cname redirects
@@ -525,12 +629,13 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 		h = strings.ToLower(h)
 		table.allHosts[h] = struct{}{}
 		if len(ipv4) > 0 {
-			table.name4[h] = a(h, ipv4)
+			table.name4[h] = withTTL(a(h, ipv4), table.ttl)
 		}
 		if len(ipv6) > 0 {
-			table.name6[h] = aaaa(h, ipv6)
+			table.name6[h] = withTTL(aaaa(h, ipv6), table.ttl)
 		}
-		if len(searchNamespaces) > 0 {
+		// The expansions of wildcard hosts cannot be precomputed, they are resolved when queried.
+		if len(searchNamespaces) > 0 && !strings.HasPrefix(h, "*.") {
 			// NOTE: Right now, rather than storing one expanded host for each one of the search namespace
 			// entries, we are going to store just the first one (assuming that most clients will
 			// do sequential dns resolution, starting with the first search namespace)
@@ -546,7 +651,7 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 			// then the expanded host productpage.ns1.svc.cluster.local is a valid hostname
 			// that is likely to be already present in the altHosts
 			if _, exists := altHosts[expandedHost]; !exists {
-				table.cname[expandedHost] = cname(expandedHost, h)
+				table.cname[expandedHost] = withTTL(cname(expandedHost, h), table.ttl)
 				table.allHosts[expandedHost] = struct{}{}
 			}
 		}
@@ -578,6 +683,14 @@ func aaaa(host string, ips []net.IP) []dns.RR {
 	return answers
 }
 
+// withTTL sets the TTL of the records.
+func withTTL(records []dns.RR, ttl uint32) []dns.RR {
+	for _, r := range records {
+		r.Header().Ttl = ttl
+	}
+	return records
+}
+
 func cname(host string, targetHost string) []dns.RR {
 	answer := new(dns.CNAME)
 	answer.Hdr = dns.RR_Header{
@@ -174,6 +174,24 @@ func TestDNS(t *testing.T) {
 			host:     "a.b.wildcard.",
 			expected: a("a.b.wildcard.", []net.IP{net.ParseIP("11.11.11.11").To4()}),
 		},
+		{
+			name: "success: wild card with search namespace yields cname+A record",
+			host: "foo.wildcard.ns1.svc.cluster.local.",
+			expected: append(cname("foo.wildcard.ns1.svc.cluster.local.", "foo.wildcard."),
+				a("foo.wildcard.", []net.IP{net.ParseIP("10.10.10.10").To4()})...),
+		},
+		{
+			name: "success: specific wild card with other search namespace yields cname+A record",
+			host: "a.b.wildcard.svc.cluster.local.",
+			expected: append(cname("a.b.wildcard.svc.cluster.local.", "a.b.wildcard."),
+				a("a.b.wildcard.", []net.IP{net.ParseIP("11.11.11.11").To4()})...),
+		},
+		{
+			name: "success: non k8s host with other search namespace yields cname+A record",
+			host: "www.google.com.cluster.local.",
+			expected: append(cname("www.google.com.cluster.local.", "www.google.com."),
+				a("www.google.com.", []net.IP{net.ParseIP("1.1.1.1").To4()})...),
+		},
 		{
 			name:      "success: TypeAAAA query returns AAAA records only",
 			host:      "dual.localhost.",
@@ -294,6 +312,35 @@ func TestDNS(t *testing.T) {
 	}
 }
 
+func TestDNSTTL(t *testing.T) {
+	s := &LocalDNSServer{
+		synced:           make(chan struct{}),
+		ttl:              defaultTTLInSeconds,
+		searchNamespaces: []string{"ns1.svc.cluster.local"},
+	}
+	s.SetTTL(5 * time.Second)
+	s.UpdateLookupTable(&dnsProto.NameTable{Table: map[string]*dnsProto.NameTable_NameInfo{
+		"www.google.com": {Ips: []string{"1.1.1.1"}, Registry: "External"},
+		"*.wildcard":     {Ips: []string{"10.10.10.10"}, Registry: "External"},
+	}})
+	table := s.lookupTable.Load().(*LookupTable)
+	for _, hostname := range []string{"www.google.com.", "www.google.com.ns1.svc.cluster.local.", "foo.wildcard.ns1.svc.cluster.local."} {
+		answers, found := table.lookupHost(dns.TypeA, hostname)
+		if !found || len(answers) == 0 {
+			t.Fatalf("%s: expected answers", hostname)
+		}
+		for _, answer := range answers {
+			if answer.Header().Ttl != 5 {
+				t.Errorf("%s: got TTL %d, want 5", hostname, answer.Header().Ttl)
+			}
+		}
+	}
+	// Answering a wildcard query must not rename the records of the wildcard host.
+	if name := table.name4["*.wildcard."][0].Header().Name; name != "*.wildcard." {
+		t.Errorf("wildcard record renamed to %s", name)
+	}
+}
+
 // Baseline:
 //      ~150us via agent if cached for A/AAAA
 //      ~300us via agent when doing the cname redirect
@@ -538,3 +585,74 @@ func equalsDNSrecords(got []dns.RR, want []dns.RR) bool {
 	}
 	return reflect.DeepEqual(got, want)
 }
+
+// recordingWriter records the response written by the DNS server.
+type recordingWriter struct {
+	dns.ResponseWriter
+	msgs chan *dns.Msg
+}
+
+func (w *recordingWriter) WriteMsg(m *dns.Msg) error {
+	w.msgs <- m
+	return nil
+}
+
+func TestDNSHoldUntilSync(t *testing.T) {
+	newServer := func(holdTimeout time.Duration) *LocalDNSServer {
+		s := &LocalDNSServer{synced: make(chan struct{})}
+		s.SetHoldTimeout(holdTimeout)
+		return s
+	}
+	query := func(s *LocalDNSServer) chan *dns.Msg {
+		w := &recordingWriter{msgs: make(chan *dns.Msg, 1)}
+		req := new(dns.Msg)
+		req.SetQuestion("www.google.com.", dns.TypeA)
+		go s.ServeDNS(&dnsProxy{protocol: "udp"}, w, req)
+		return w.msgs
+	}
+
+	t.Run("answered once synced", func(t *testing.T) {
+		s := newServer(5 * time.Second)
+		res := query(s)
+		select {
+		case m := <-res:
+			t.Fatalf("got response before sync: %v", m)
+		case <-time.After(50 * time.Millisecond):
+		}
+		s.UpdateLookupTable(&dnsProto.NameTable{Table: map[string]*dnsProto.NameTable_NameInfo{
+			"www.google.com": {Ips: []string{"1.1.1.1"}, Registry: "External"},
+		}})
+		select {
+		case m := <-res:
+			if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+				t.Fatalf("unexpected response %v", m)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("held query was not answered after sync")
+		}
+	})
+
+	t.Run("fails after hold timeout", func(t *testing.T) {
+		s := newServer(10 * time.Millisecond)
+		select {
+		case m := <-query(s):
+			if m.Rcode != dns.RcodeServerFailure {
+				t.Fatalf("expected SERVFAIL, got %v", m)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("held query did not time out")
+		}
+	})
+
+	t.Run("holding disabled", func(t *testing.T) {
+		s := newServer(0)
+		select {
+		case m := <-query(s):
+			if m.Rcode != dns.RcodeServerFailure {
+				t.Fatalf("expected SERVFAIL, got %v", m)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("query was held with holding disabled")
+		}
+	})
+}
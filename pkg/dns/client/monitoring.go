@@ -15,10 +15,14 @@
 package client
 
 import (
+	"github.com/miekg/dns"
+
 	"istio.io/pkg/monitoring"
 )
 
 var (
+	queryTypeTag = monitoring.MustCreateLabel("type")
+
 	requests = monitoring.NewSum(
 		"dns_requests_total",
 		"Total number of DNS requests.",
@@ -29,11 +33,22 @@ var (
 		"Total number of DNS requests forwarded to upstream.",
 	)
 
+	lookupTableMisses = monitoring.NewSum(
+		"dns_lookup_table_misses_total",
+		"Total number of DNS requests for hosts not in the DNS lookup table, forwarded to upstream.",
+		monitoring.WithLabels(queryTypeTag),
+	)
+
 	failures = monitoring.NewSum(
 		"dns_upstream_failures_total",
 		"Total number of DNS requests forwarded to upstream.",
 	)
 
+	heldRequests = monitoring.NewSum(
+		"dns_held_requests_total",
+		"Total number of DNS requests held until the DNS lookup table was received.",
+	)
+
 	requestDuration = monitoring.NewDistribution(
 		"dns_upstream_request_duration_seconds",
 		"Total time in seconds Istio takes to get DNS response from upstream.",
@@ -44,6 +59,16 @@ var (
 func registerStats() {
 	monitoring.MustRegister(requests)
 	monitoring.MustRegister(upstreamRequests)
+	monitoring.MustRegister(lookupTableMisses)
 	monitoring.MustRegister(failures)
+	monitoring.MustRegister(heldRequests)
 	monitoring.MustRegister(requestDuration)
 }
+
+// queryType returns the name of the DNS query type, for the type label.
+func queryType(qtype uint16) string {
+	if name, f := dns.TypeToString[qtype]; f {
+		return name
+	}
+	return "unknown"
+}
@@ -19,7 +19,6 @@ import (
 	"os"
 	"os/exec"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/gogo/protobuf/types"
@@ -174,11 +173,7 @@ func (e *envoy) Run(epoch int, abort <-chan error) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if e.AgentIsRoot {
-		cmd.SysProcAttr = &syscall.SysProcAttr{}
-		cmd.SysProcAttr.Credential = &syscall.Credential{
-			Uid: 1337,
-			Gid: 1337,
-		}
+		runAsProxyUser(cmd)
 	}
 
 	if err := cmd.Start(); err != nil {
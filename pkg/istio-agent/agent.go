@@ -136,6 +136,10 @@ type AgentOptions struct {
 	DNSCapture bool
 	// DNSAddr is the DNS capture address
 	DNSAddr string
+	// DNSHoldTimeout is how long DNS queries received before the DNS table are held waiting for it
+	DNSHoldTimeout time.Duration
+	// DNSTTL is the TTL of the records answered by the DNS proxy from the DNS table
+	DNSTTL time.Duration
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -197,6 +201,37 @@ type AgentOptions struct {
 	IstiodSAN string
 
 	WASMInsecureRegistries []string
+
+	// WASMSignaturePublicKey is the path of the public key used to verify the signatures of Wasm OCI images.
+	WASMSignaturePublicKey string
+
+	// WASMRequireSignature refuses to load Wasm modules whose signature cannot be verified.
+	WASMRequireSignature bool
+
+	// ReportOutlierEvents enables reporting the outlier detection events logged by Envoy to istiod.
+	ReportOutlierEvents bool
+
+	// HealthCheckEventLogPath is the event log of the active health checks of clusters. If set, the
+	// health check events logged by Envoy are reported to istiod.
+	HealthCheckEventLogPath string
+
+	// MTLSReportInterval is the interval at which the inbound traffic of the services of the proxy, by
+	// connection security, is reported to istiod. Reporting is disabled if zero.
+	MTLSReportInterval time.Duration
+
+	// XDSFallbackBundle is the path or the HTTPS URL of an Envoy config dump the XDS proxy serves to Envoy when
+	// istiod cannot be reached within XDSFallbackTimeout of the agent startup. The fallback is disabled if empty.
+	XDSFallbackBundle string
+
+	// XDSFallbackBundleSHA256 is the hex SHA-256 digest XDSFallbackBundle must have. It is required for a URL.
+	XDSFallbackBundleSHA256 string
+
+	// XDSFallbackTimeout is how long the XDS proxy waits for istiod at startup before serving XDSFallbackBundle.
+	XDSFallbackTimeout time.Duration
+
+	// XDSCacheFile is the file the XDS proxy persists the last clusters, listeners, routes and endpoints ACKed by
+	// Envoy to. On restart, they are replayed to Envoy before it connects to istiod. The cache is disabled if empty.
+	XDSCacheFile string
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -501,6 +536,8 @@ func (a *Agent) initLocalDNSServer() (err error) {
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr); err != nil {
 			return err
 		}
+		a.localDNSServer.SetHoldTimeout(a.cfg.DNSHoldTimeout)
+		a.localDNSServer.SetTTL(a.cfg.DNSTTL)
 		a.localDNSServer.StartDNS()
 	}
 	return nil
@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventlog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+var eventLog = log.RegisterScope("eventlog", "Envoy event log reporting", 0)
+
+// DefaultPollInterval is the interval at which event log files are checked for new events.
+const DefaultPollInterval = 5 * time.Second
+
+// maxReadSize bounds how much of the file is read at once, so that a large backlog does not have to be
+// loaded in memory at once.
+const maxReadSize = 1024 * 1024
+
+// Tailer follows a file Envoy writes events to, one JSON document per line, such as the outlier
+// detection or health check event logs. Only events written after the tailer started are reported.
+type Tailer struct {
+	path     string
+	interval time.Duration
+
+	offset  int64
+	partial []byte
+}
+
+// NewTailer returns a tailer for the event log at path.
+func NewTailer(path string, interval time.Duration) *Tailer {
+	return &Tailer{
+		path:     path,
+		interval: interval,
+		offset:   -1,
+	}
+}
+
+// Run polls the file until stop is closed, calling handler with the complete lines written since the
+// previous poll.
+func (t *Tailer) Run(stop <-chan struct{}, handler func(lines [][]byte)) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		if lines := t.poll(); len(lines) > 0 {
+			handler(lines)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll returns the complete lines appended to the file since the last poll.
+func (t *Tailer) poll() [][]byte {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		// Envoy creates the file on the first event.
+		if t.offset < 0 {
+			t.offset = 0
+		}
+		return nil
+	}
+	if t.offset < 0 {
+		// Skip events written before we started; they have been handled by a previous agent, if any.
+		t.offset = fi.Size()
+		return nil
+	}
+	if fi.Size() < t.offset {
+		eventLog.Debugf("event log %s was truncated, reading from the start", t.path)
+		t.offset = 0
+		t.partial = nil
+	}
+	if fi.Size() == t.offset {
+		return nil
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		eventLog.Warnf("failed to open event log %s: %v", t.path, err)
+		return nil
+	}
+	defer f.Close()
+
+	size := fi.Size() - t.offset
+	if size > maxReadSize {
+		size = maxReadSize
+	}
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, t.offset)
+	if err != nil && err != io.EOF {
+		eventLog.Warnf("failed to read event log %s: %v", t.path, err)
+		return nil
+	}
+	t.offset += int64(n)
+	data := append(t.partial, buf[:n]...)
+
+	var lines [][]byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(data[:i]); len(line) > 0 {
+			lines = append(lines, line)
+		}
+		data = data[i+1:]
+	}
+	// Keep the incomplete trailing line for the next poll.
+	t.partial = append([]byte(nil), data...)
+	return lines
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventlog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func toStrings(lines [][]byte) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, string(l))
+	}
+	return out
+}
+
+func TestTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	tailer := NewTailer(path, DefaultPollInterval)
+
+	// The file does not exist yet.
+	if got := tailer.poll(); len(got) != 0 {
+		t.Fatalf("expected no lines, got %v", toStrings(got))
+	}
+
+	appendFile(t, path, "{\"a\":1}\n{\"b\":2}\n{\"c\"")
+	if got, want := toStrings(tailer.poll()), []string{`{"a":1}`, `{"b":2}`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	appendFile(t, path, ":3}\n\n")
+	if got, want := toStrings(tailer.poll()), []string{`{"c":3}`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := tailer.poll(); len(got) != 0 {
+		t.Fatalf("expected no lines, got %v", toStrings(got))
+	}
+
+	// Truncation restarts from the beginning of the file.
+	if err := os.WriteFile(path, []byte("{\"d\":4}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := toStrings(tailer.poll()), []string{`{"d":4}`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTailerSkipsExistingEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	appendFile(t, path, "{\"a\":1}\n")
+	tailer := NewTailer(path, DefaultPollInterval)
+	if got := tailer.poll(); len(got) != 0 {
+		t.Fatalf("expected no lines, got %v", toStrings(got))
+	}
+	appendFile(t, path, "{\"b\":2}\n")
+	if got, want := toStrings(tailer.poll()), []string{`{"b":2}`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -55,6 +55,18 @@ var (
 		"The total number of Xds Proxy Responses",
 	)
 
+	// XdsProxyFallbacks records the Envoy connections served from the fallback bundle as istiod was unreachable.
+	XdsProxyFallbacks = monitoring.NewSum(
+		"xds_proxy_fallbacks",
+		"The total number of Envoy connections served from the fallback bundle",
+	)
+
+	// XdsProxyCacheReplays records the Envoy connections served from the cache of the last ACKed config on restart.
+	XdsProxyCacheReplays = monitoring.NewSum(
+		"xds_proxy_cache_replays",
+		"The total number of Envoy connections served from the cache of the last ACKed config",
+	)
+
 	IstiodConnectionCancellations = istiodDisconnections.With(disconnectionTypeTag.Value(Cancel))
 	IstiodConnectionErrors        = istiodDisconnections.With(disconnectionTypeTag.Value(Error))
 	EnvoyConnectionCancellations  = envoyDisconnections.With(disconnectionTypeTag.Value(Cancel))
@@ -72,5 +84,7 @@ func init() {
 		IstiodConnectionErrors,
 		istiodDisconnections,
 		envoyDisconnections,
+		XdsProxyFallbacks,
+		XdsProxyCacheReplays,
 	)
 }
@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// mtlsReportMetrics are the Istio standard metrics counting the inbound requests and connections of the proxy.
+var mtlsReportMetrics = []string{"istio_requests_total", "istio_tcp_connections_opened_total"}
+
+// mtlsTraffic counts the inbound requests and connections to a service by connection security.
+type mtlsTraffic struct {
+	mtls      float64
+	plaintext float64
+}
+
+// parseMTLSTraffic reads the inbound traffic of each destination service from the Istio standard metrics in the
+// Prometheus text format. Traffic reported by the destination with a connection security policy other than
+// mutual_tls was accepted in plaintext, which is only possible in PERMISSIVE mode.
+func parseMTLSTraffic(r io.Reader) (map[string]*mtlsTraffic, error) {
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(r)
+	if err != nil {
+		return nil, err
+	}
+	traffic := map[string]*mtlsTraffic{}
+	for _, name := range mtlsReportMetrics {
+		family := families[name]
+		if family == nil {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := metricLabels(m)
+			service := labels["destination_service"]
+			if labels["reporter"] != "destination" || service == "" || service == "unknown" {
+				continue
+			}
+			t := traffic[service]
+			if t == nil {
+				t = &mtlsTraffic{}
+				traffic[service] = t
+			}
+			if labels["connection_security_policy"] == "mutual_tls" {
+				t.mtls += m.GetCounter().GetValue()
+			} else {
+				t.plaintext += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return traffic, nil
+}
+
+func metricLabels(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	return labels
+}
+
+// buildMTLSReports converts the inbound traffic of the services into report messages, sorted by service.
+func buildMTLSReports(traffic map[string]*mtlsTraffic) []proto.Message {
+	services := make([]string, 0, len(traffic))
+	for service := range traffic {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	reports := make([]proto.Message, 0, len(services))
+	for _, service := range services {
+		t := traffic[service]
+		reports = append(reports, &structpb.Struct{Fields: map[string]*structpb.Value{
+			"service":   structpb.NewStringValue(service),
+			"mtls":      structpb.NewNumberValue(t.mtls),
+			"plaintext": structpb.NewNumberValue(t.plaintext),
+		}})
+	}
+	return reports
+}
+
+// reportMTLSCompatibility periodically sends the inbound traffic of the services of the proxy, by connection
+// security, to istiod until stop is closed. The counters are cumulative, istiod computes the changes.
+func (p *XdsProxy) reportMTLSCompatibility(statsURL string, interval time.Duration, stop <-chan struct{}) {
+	client := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			traffic, err := scrapeMTLSTraffic(client, statsURL)
+			if err != nil {
+				proxyLog.Debugf("failed to read the mTLS compatibility stats: %v", err)
+				continue
+			}
+			for _, req := range buildEventRequests(v3.MTLSReportType, buildMTLSReports(traffic)) {
+				p.sendEventRequest(req)
+			}
+		}
+	}
+}
+
+func scrapeMTLSTraffic(client *http.Client, statsURL string) (map[string]*mtlsTraffic, error) {
+	resp, err := client.Get(statsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return parseMTLSTraffic(resp.Body)
+}
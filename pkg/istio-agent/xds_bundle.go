@@ -0,0 +1,230 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	// Register the types of the Istio filters, which the config dumps of Istio proxies reference.
+	_ "istio.io/api/envoy/config/filter/http/alpn/v2alpha1"
+	_ "istio.io/api/envoy/config/filter/http/authn/v2alpha1"
+	_ "istio.io/api/envoy/config/filter/network/metadata_exchange"
+	_ "istio.io/api/envoy/extensions/stackdriver/config/v1alpha1"
+	_ "istio.io/api/envoy/extensions/stats"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	// Register the types of the Envoy extensions, which the config dumps reference.
+	_ "istio.io/istio/pkg/config/xds"
+)
+
+// bundleResource is a resource of a config bundle.
+type bundleResource struct {
+	name     string
+	resource *any.Any
+}
+
+// configBundle is a config served to Envoy by the XDS proxy rather than istiod. It holds the dynamic clusters,
+// listeners, routes and endpoints of an Envoy config dump, by type URL.
+type configBundle struct {
+	version   string
+	resources map[string][]bundleResource
+}
+
+// newConfigBundle returns the bundle of the dynamic resources of a config dump, served with the version. The static
+// resources are part of the bootstrap config, and are not served.
+func newConfigBundle(dump *adminapi.ConfigDump, version string) (*configBundle, error) {
+	var resources []*any.Any
+	for _, cfg := range dump.Configs {
+		msg, err := cfg.UnmarshalNew()
+		if err != nil {
+			return nil, fmt.Errorf("invalid config dump: %v", err)
+		}
+		switch cd := msg.(type) {
+		case *adminapi.ClustersConfigDump:
+			for _, c := range cd.DynamicActiveClusters {
+				resources = append(resources, c.Cluster)
+			}
+		case *adminapi.ListenersConfigDump:
+			for _, l := range cd.DynamicListeners {
+				resources = append(resources, l.GetActiveState().GetListener())
+			}
+		case *adminapi.RoutesConfigDump:
+			for _, r := range cd.DynamicRouteConfigs {
+				resources = append(resources, r.RouteConfig)
+			}
+		case *adminapi.EndpointsConfigDump:
+			for _, e := range cd.DynamicEndpointConfigs {
+				resources = append(resources, e.EndpointConfig)
+			}
+		}
+	}
+	b := &configBundle{version: version, resources: map[string][]bundleResource{}}
+	for _, res := range resources {
+		if res == nil {
+			continue
+		}
+		name, err := resourceName(res)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			continue
+		}
+		b.resources[res.TypeUrl] = append(b.resources[res.TypeUrl], bundleResource{name: name, resource: res})
+	}
+	if len(b.resources[v3.ClusterType]) == 0 && len(b.resources[v3.ListenerType]) == 0 {
+		return nil, fmt.Errorf("the config dump has no dynamic cluster nor listener")
+	}
+	return b, nil
+}
+
+// resourceName returns the name of a cluster, listener, route or endpoint resource, or an empty name for the other
+// types.
+func resourceName(res *any.Any) (string, error) {
+	msg, err := res.UnmarshalNew()
+	if err != nil {
+		return "", fmt.Errorf("invalid resource %s: %v", res.TypeUrl, err)
+	}
+	switch m := msg.(type) {
+	case *cluster.Cluster:
+		return m.Name, nil
+	case *listener.Listener:
+		return m.Name, nil
+	case *route.RouteConfiguration:
+		return m.Name, nil
+	case *endpoint.ClusterLoadAssignment:
+		return m.ClusterName, nil
+	}
+	return "", nil
+}
+
+// response returns the response to a request of the type and resource names, or nil if the bundle has no resource
+// of the type. All the resources are returned if no name is requested.
+func (b *configBundle) response(typeURL string, names []string, nonce string) *discovery.DiscoveryResponse {
+	all, f := b.resources[typeURL]
+	if !f {
+		return nil
+	}
+	requested := map[string]struct{}{}
+	for _, n := range names {
+		requested[n] = struct{}{}
+	}
+	resp := &discovery.DiscoveryResponse{TypeUrl: typeURL, VersionInfo: b.version, Nonce: nonce}
+	for _, r := range all {
+		if _, f := requested[r.name]; len(requested) == 0 || f {
+			resp.Resources = append(resp.Resources, r.resource)
+		}
+	}
+	return resp
+}
+
+// deltaResponse returns the delta response to a request of the type and resource names, or nil if the bundle has
+// no resource of the type. All the resources are returned if no name is requested.
+func (b *configBundle) deltaResponse(typeURL string, names []string, nonce string) *discovery.DeltaDiscoveryResponse {
+	resp := b.response(typeURL, names, nonce)
+	if resp == nil {
+		return nil
+	}
+	delta := &discovery.DeltaDiscoveryResponse{TypeUrl: typeURL, SystemVersionInfo: b.version, Nonce: nonce}
+	for _, res := range resp.Resources {
+		name, _ := resourceName(res)
+		delta.Resources = append(delta.Resources, &discovery.Resource{Name: name, Version: b.version, Resource: res})
+	}
+	return delta
+}
+
+// serveBundle answers the requests of Envoy from the bundle. done is called every interval and whenever Envoy ACKs a
+// type, with the type URLs ACKed so far; the stream is terminated with its error once it is not nil.
+func (p *XdsProxy) serveBundle(downstream adsStream, b *configBundle, interval time.Duration,
+	done func(acked map[string]bool) error) error {
+	requests := make(chan *discovery.DiscoveryRequest)
+	recvErr := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			req, err := downstream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// sent are the resource names last sent by type URL, to not answer the ACKs.
+	sent := map[string]string{}
+	nonces := map[string]string{}
+	acked := map[string]bool{}
+	nonce := 0
+	for {
+		select {
+		case req := <-requests:
+			if req.ResponseNonce != "" && req.ResponseNonce == nonces[req.TypeUrl] {
+				if req.ErrorDetail != nil {
+					proxyLog.Warnf("Envoy rejected the %s served from the bundle: %v",
+						v3.GetShortType(req.TypeUrl), req.ErrorDetail.GetMessage())
+				} else if !acked[req.TypeUrl] {
+					acked[req.TypeUrl] = true
+					if err := done(acked); err != nil {
+						return err
+					}
+				}
+			}
+			names := append([]string{}, req.ResourceNames...)
+			sort.Strings(names)
+			key := strings.Join(names, ",")
+			if last, f := sent[req.TypeUrl]; f && last == key {
+				continue
+			}
+			nonce++
+			resp := b.response(req.TypeUrl, names, strconv.Itoa(nonce))
+			if resp == nil {
+				proxyLog.Debugf("no bundled resource for type url %s", req.TypeUrl)
+				continue
+			}
+			sent[req.TypeUrl] = key
+			nonces[req.TypeUrl] = resp.Nonce
+			if err := sendDownstream(downstream, resp); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			return err
+		case <-ticker.C:
+			if err := done(acked); err != nil {
+				return err
+			}
+		case <-p.stopChan:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestConfigBundle(t *testing.T) {
+	dump := &adminapi.ConfigDump{Configs: []*any.Any{
+		util.MessageToAny(&adminapi.ClustersConfigDump{
+			StaticClusters: []*adminapi.ClustersConfigDump_StaticCluster{{Cluster: util.MessageToAny(&cluster.Cluster{Name: "prometheus_stats"})}},
+			DynamicActiveClusters: []*adminapi.ClustersConfigDump_DynamicCluster{
+				{Cluster: util.MessageToAny(&cluster.Cluster{Name: "outbound|80||a.default.svc.cluster.local"})},
+				{Cluster: util.MessageToAny(&cluster.Cluster{Name: "outbound|80||b.default.svc.cluster.local"})},
+			},
+		}),
+		util.MessageToAny(&adminapi.ListenersConfigDump{
+			DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: util.MessageToAny(&listener.Listener{Name: "virtualOutbound"})},
+			}},
+		}),
+		util.MessageToAny(&adminapi.RoutesConfigDump{
+			DynamicRouteConfigs: []*adminapi.RoutesConfigDump_DynamicRouteConfig{{RouteConfig: util.MessageToAny(&route.RouteConfiguration{Name: "80"})}},
+		}),
+		util.MessageToAny(&adminapi.EndpointsConfigDump{
+			DynamicEndpointConfigs: []*adminapi.EndpointsConfigDump_DynamicEndpointConfig{
+				{EndpointConfig: util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: "outbound|80||a.default.svc.cluster.local"})},
+				{EndpointConfig: util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: "outbound|80||b.default.svc.cluster.local"})},
+			},
+		}),
+	}}
+	b, err := newConfigBundle(dump, cachedVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		typeURL string
+		names   []string
+		want    int
+	}{
+		{v3.ClusterType, nil, 2},
+		{v3.ListenerType, nil, 1},
+		{v3.RouteType, []string{"80"}, 1},
+		{v3.EndpointType, []string{"outbound|80||b.default.svc.cluster.local"}, 1},
+		{v3.EndpointType, []string{"outbound|80||c.default.svc.cluster.local"}, 0},
+	} {
+		resp := b.response(tt.typeURL, tt.names, "1")
+		if got := len(resp.Resources); got != tt.want {
+			t.Errorf("%s %v: got %d resources, want %d", v3.GetShortType(tt.typeURL), tt.names, got, tt.want)
+		}
+	}
+	if resp := b.response(v3.ExtensionConfigurationType, nil, "1"); resp != nil {
+		t.Errorf("unexpected response for a type missing from the bundle: %v", resp)
+	}
+
+	if _, err := newConfigBundle(&adminapi.ConfigDump{}, cachedVersion); err == nil {
+		t.Errorf("expected an empty config dump to be rejected")
+	}
+}
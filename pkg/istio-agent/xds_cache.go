@@ -0,0 +1,386 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/file"
+	"istio.io/istio/pkg/istio-agent/metrics"
+)
+
+// cachedVersion is the version of the responses replayed from the cache.
+const cachedVersion = "cached"
+
+var (
+	// xdsCacheFlushInterval is the interval the ACKed resources are persisted at, at most.
+	xdsCacheFlushInterval = time.Second
+	// xdsCacheReplayTimeout bounds the replay of the cache, if Envoy does not ACK all its types.
+	xdsCacheReplayTimeout = 5 * time.Second
+)
+
+// xdsCache persists the last clusters, listeners, routes and endpoints ACKed by Envoy, so that they are replayed to
+// Envoy when the agent restarts, before istiod is connected. A nil cache is disabled.
+type xdsCache struct {
+	path string
+
+	mu sync.Mutex
+	// sent are the last responses sent to Envoy, by type URL, until they are ACKed.
+	sent map[string]*discovery.DiscoveryResponse
+	// deltaSent are the last delta responses sent to Envoy, by type URL, until they are ACKed.
+	deltaSent map[string]*discovery.DeltaDiscoveryResponse
+	// acked are the ACKed resources by type URL and name.
+	acked map[string]map[string]*any.Any
+	dirty bool
+	// replay is the cache loaded at startup, served to Envoy until istiod is reached.
+	replay *configBundle
+}
+
+// cachedTypes are the type URLs of the cached resources.
+var cachedTypes = []string{v3.ClusterType, v3.ListenerType, v3.RouteType, v3.EndpointType}
+
+// newXdsCache returns the cache persisted to the path, loading the resources persisted by the previous agent.
+func newXdsCache(path string) *xdsCache {
+	c := &xdsCache{
+		path:      path,
+		sent:      map[string]*discovery.DiscoveryResponse{},
+		deltaSent: map[string]*discovery.DeltaDiscoveryResponse{},
+		acked:     map[string]map[string]*any.Any{},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			proxyLog.Warnf("failed to read the XDS cache %s: %v", path, err)
+		}
+		return c
+	}
+	dump := &adminapi.ConfigDump{}
+	if err := proto.Unmarshal(data, dump); err != nil {
+		proxyLog.Warnf("ignoring invalid XDS cache %s: %v", path, err)
+		return c
+	}
+	b, err := newConfigBundle(dump, cachedVersion)
+	if err != nil {
+		proxyLog.Warnf("ignoring invalid XDS cache %s: %v", path, err)
+		return c
+	}
+	c.replay = b
+	// The ACKed resources start from the cache, so that a restart before istiod sent every type does not lose them.
+	for typeURL, resources := range b.resources {
+		c.acked[typeURL] = map[string]*any.Any{}
+		for _, r := range resources {
+			c.acked[typeURL][r.name] = r.resource
+		}
+	}
+	return c
+}
+
+func isCachedType(typeURL string) bool {
+	for _, t := range cachedTypes {
+		if t == typeURL {
+			return true
+		}
+	}
+	return false
+}
+
+// onResponse records a response forwarded to Envoy, to cache its resources once Envoy ACKs it.
+func (c *xdsCache) onResponse(resp *discovery.DiscoveryResponse) {
+	if c == nil || !isCachedType(resp.TypeUrl) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent[resp.TypeUrl] = resp
+}
+
+// onRequest caches the resources of the response a request of Envoy ACKs. Clusters and listeners responses hold all
+// the resources of their type, whereas routes and endpoints responses may only hold the updated resources, so these
+// are merged, keeping the resources Envoy still subscribes to.
+func (c *xdsCache) onRequest(req *discovery.DiscoveryRequest) {
+	if c == nil || req.ResponseNonce == "" || req.ErrorDetail != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp := c.sent[req.TypeUrl]
+	if resp == nil || resp.Nonce != req.ResponseNonce {
+		return
+	}
+	delete(c.sent, req.TypeUrl)
+
+	resources := c.acked[req.TypeUrl]
+	if req.TypeUrl == v3.ClusterType || req.TypeUrl == v3.ListenerType || resources == nil {
+		resources = map[string]*any.Any{}
+	}
+	for _, res := range resp.Resources {
+		name, err := resourceName(res)
+		if err != nil {
+			proxyLog.Debugf("not caching %v", err)
+			continue
+		}
+		resources[name] = res
+	}
+	if len(req.ResourceNames) > 0 {
+		subscribed := map[string]struct{}{}
+		for _, n := range req.ResourceNames {
+			subscribed[n] = struct{}{}
+		}
+		for name := range resources {
+			if _, f := subscribed[name]; !f {
+				delete(resources, name)
+			}
+		}
+	}
+	c.acked[req.TypeUrl] = resources
+	c.dirty = true
+}
+
+// onDeltaResponse records a delta response forwarded to Envoy, to cache its resources once Envoy ACKs it.
+func (c *xdsCache) onDeltaResponse(resp *discovery.DeltaDiscoveryResponse) {
+	if c == nil || !isCachedType(resp.TypeUrl) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deltaSent[resp.TypeUrl] = resp
+}
+
+// onDeltaRequest applies the delta response a delta request of Envoy ACKs to the cached resources, and drops the
+// resources Envoy unsubscribes from.
+func (c *xdsCache) onDeltaRequest(req *discovery.DeltaDiscoveryRequest) {
+	if c == nil || !isCachedType(req.TypeUrl) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resources := c.acked[req.TypeUrl]
+	if resources == nil {
+		resources = map[string]*any.Any{}
+		c.acked[req.TypeUrl] = resources
+	}
+	for _, name := range req.ResourceNamesUnsubscribe {
+		if _, f := resources[name]; f {
+			delete(resources, name)
+			c.dirty = true
+		}
+	}
+	resp := c.deltaSent[req.TypeUrl]
+	if req.ResponseNonce == "" || req.ErrorDetail != nil || resp == nil || resp.Nonce != req.ResponseNonce {
+		return
+	}
+	delete(c.deltaSent, req.TypeUrl)
+	for _, res := range resp.Resources {
+		if res.Resource == nil {
+			continue
+		}
+		resources[res.Name] = res.Resource
+	}
+	for _, name := range resp.RemovedResources {
+		delete(resources, name)
+	}
+	c.dirty = true
+}
+
+// configDump returns the ACKed resources as a config dump, sorted by name.
+func (c *xdsCache) configDump() *adminapi.ConfigDump {
+	sorted := func(typeURL string) []*any.Any {
+		names := make([]string, 0, len(c.acked[typeURL]))
+		for name := range c.acked[typeURL] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		resources := make([]*any.Any, 0, len(names))
+		for _, name := range names {
+			resources = append(resources, c.acked[typeURL][name])
+		}
+		return resources
+	}
+	clusters := &adminapi.ClustersConfigDump{}
+	for _, r := range sorted(v3.ClusterType) {
+		clusters.DynamicActiveClusters = append(clusters.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{Cluster: r})
+	}
+	listeners := &adminapi.ListenersConfigDump{}
+	for _, r := range sorted(v3.ListenerType) {
+		listeners.DynamicListeners = append(listeners.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: r},
+		})
+	}
+	routes := &adminapi.RoutesConfigDump{}
+	for _, r := range sorted(v3.RouteType) {
+		routes.DynamicRouteConfigs = append(routes.DynamicRouteConfigs, &adminapi.RoutesConfigDump_DynamicRouteConfig{RouteConfig: r})
+	}
+	endpoints := &adminapi.EndpointsConfigDump{}
+	for _, r := range sorted(v3.EndpointType) {
+		endpoints.DynamicEndpointConfigs = append(endpoints.DynamicEndpointConfigs, &adminapi.EndpointsConfigDump_DynamicEndpointConfig{EndpointConfig: r})
+	}
+	dump := &adminapi.ConfigDump{}
+	for _, cd := range []proto.Message{clusters, listeners, routes, endpoints} {
+		a, err := any.New(cd)
+		if err != nil {
+			continue
+		}
+		dump.Configs = append(dump.Configs, a)
+	}
+	return dump
+}
+
+// flush persists the ACKed resources if they changed. The resources are marshaled in binary, as their Istio and Envoy
+// extensions may not all be registered for JSON.
+func (c *xdsCache) flush() {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return
+	}
+	dump := c.configDump()
+	c.dirty = false
+	c.mu.Unlock()
+
+	data, err := proto.Marshal(dump)
+	if err != nil {
+		proxyLog.Warnf("failed to marshal the XDS cache: %v", err)
+		return
+	}
+	if err := file.AtomicWrite(c.path, data, 0o600); err != nil {
+		proxyLog.Warnf("failed to write the XDS cache %s: %v", c.path, err)
+	}
+}
+
+// run persists the ACKed resources every xdsCacheFlushInterval, until stop is closed.
+func (c *xdsCache) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(xdsCacheFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-stop:
+			c.flush()
+			return
+		}
+	}
+}
+
+// takeReplay returns the cache loaded at startup the first time it is called, and nil afterwards. It is called once
+// istiod is reached to discard the cache, as Envoy then gets its config from istiod.
+func (c *xdsCache) takeReplay() *configBundle {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.replay
+	c.replay = nil
+	return b
+}
+
+// replayIfUnreachable replays the cache loaded at startup, or else the fallback bundle, to Envoy when istiod cannot be
+// reached on the first connection, or returns the error of the connection otherwise.
+func (p *XdsProxy) replayIfUnreachable(con *ProxyConnection, err error) error {
+	what := ""
+	b := p.xdsCache.takeReplay()
+	if b != nil {
+		// Envoy keeps the cached config, which is more recent than the fallback bundle.
+		p.xdsFallback.disable()
+		what = "the cached config " + p.xdsCache.path
+		metrics.XdsProxyCacheReplays.Increment()
+	} else if b = p.xdsFallback.take(); b != nil {
+		what = "the fallback bundle " + p.xdsFallback.source
+		metrics.XdsProxyFallbacks.Increment()
+	} else {
+		return err
+	}
+	proxyLog.Infof("failed to reach istiod %s: %v", p.istiodAddress, err)
+	if con.downstreamDeltas != nil {
+		return p.replayDeltaCache(con, b, what)
+	}
+	return p.replayCache(con.downstream, b, what)
+}
+
+// replayCache serves a bundle, the cache loaded at startup or the fallback bundle, to Envoy, until Envoy ACKed all its
+// types or xdsCacheReplayTimeout expires. The stream is then terminated, so that Envoy reconnects to istiod with the
+// replayed config applied.
+func (p *XdsProxy) replayCache(downstream adsStream, b *configBundle, what string) error {
+	proxyLog.Infof("replaying %s to Envoy", what)
+	deadline := time.Now().Add(xdsCacheReplayTimeout)
+	return p.serveBundle(downstream, b, xdsCacheReplayTimeout, func(acked map[string]bool) error {
+		if len(acked) < len(b.resources) && time.Now().Before(deadline) {
+			return nil
+		}
+		proxyLog.Infof("replayed %s, reconnecting Envoy to istiod %s", what, p.istiodAddress)
+		return status.Error(codes.Unavailable, "config replayed")
+	})
+}
+
+// replayDeltaCache serves a bundle to Envoy over a delta stream, as replayCache does. The requests of Envoy are read
+// from the requests channel of the connection, where the downstream handler forwards them.
+func (p *XdsProxy) replayDeltaCache(con *ProxyConnection, b *configBundle, what string) error {
+	proxyLog.Infof("replaying %s to Envoy", what)
+	timeout := time.NewTimer(xdsCacheReplayTimeout)
+	defer timeout.Stop()
+	nonces := map[string]string{}
+	acked := map[string]bool{}
+	nonce := 0
+	for {
+		select {
+		case req := <-con.deltaRequestsChan:
+			if req.ResponseNonce != "" && req.ResponseNonce == nonces[req.TypeUrl] {
+				if req.ErrorDetail != nil {
+					proxyLog.Warnf("Envoy rejected the %s of %s: %v", v3.GetShortType(req.TypeUrl), what, req.ErrorDetail.GetMessage())
+				} else {
+					acked[req.TypeUrl] = true
+				}
+				if len(acked) == len(b.resources) {
+					proxyLog.Infof("replayed %s, reconnecting Envoy to istiod %s", what, p.istiodAddress)
+					return status.Error(codes.Unavailable, "config replayed")
+				}
+			}
+			// ACKs and NACKs not subscribing to more resources are not answered.
+			if req.ResponseNonce != "" && len(req.ResourceNamesSubscribe) == 0 {
+				continue
+			}
+			nonce++
+			resp := b.deltaResponse(req.TypeUrl, req.ResourceNamesSubscribe, strconv.Itoa(nonce))
+			if resp == nil {
+				proxyLog.Debugf("no replayed resource for type url %s", req.TypeUrl)
+				continue
+			}
+			nonces[req.TypeUrl] = resp.Nonce
+			if err := sendDownstreamDelta(con.downstreamDeltas, resp); err != nil {
+				return err
+			}
+		case err := <-con.downstreamError:
+			return err
+		case <-timeout.C:
+			proxyLog.Infof("replayed %s, reconnecting Envoy to istiod %s", what, p.istiodAddress)
+			return status.Error(codes.Unavailable, "config replayed")
+		case <-con.stopChan:
+			return nil
+		}
+	}
+}
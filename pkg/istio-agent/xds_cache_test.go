@@ -0,0 +1,308 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func cachedNames(c *xdsCache, typeURL string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := []string{}
+	for name := range c.acked[typeURL] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestXdsCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xds-cache.pb")
+	c := newXdsCache(path)
+	if c.takeReplay() != nil {
+		t.Fatalf("unexpected replay without a persisted cache")
+	}
+
+	cla := func(name string) *any.Any {
+		return util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: name})
+	}
+	steps := []struct {
+		resp *discovery.DiscoveryResponse
+		ack  *discovery.DiscoveryRequest
+	}{
+		{
+			resp: &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Nonce: "c1", Resources: []*any.Any{
+				util.MessageToAny(&cluster.Cluster{Name: "a"}),
+				util.MessageToAny(&cluster.Cluster{Name: "b"}),
+			}},
+			ack: &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "c1"},
+		},
+		{
+			resp: &discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e1", Resources: []*any.Any{cla("a")}},
+			ack:  &discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e1", ResourceNames: []string{"a", "b"}},
+		},
+		// Endpoints responses are merged.
+		{
+			resp: &discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e2", Resources: []*any.Any{cla("b")}},
+			ack:  &discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e2", ResourceNames: []string{"a", "b"}},
+		},
+		// NACKs and stale nonces are ignored.
+		{
+			resp: &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Nonce: "c2"},
+			ack: &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "c2",
+				ErrorDetail: &google_rpc.Status{Code: int32(codes.Internal)}},
+		},
+		{
+			resp: &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Nonce: "c3"},
+			ack:  &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "c1"},
+		},
+	}
+	for _, s := range steps {
+		c.onResponse(s.resp)
+		c.onRequest(s.ack)
+	}
+	if got := cachedNames(c, v3.ClusterType); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("got clusters %v", got)
+	}
+	if got := cachedNames(c, v3.EndpointType); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("got endpoints %v", got)
+	}
+
+	// The endpoints Envoy no longer subscribes to are dropped.
+	c.onResponse(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e3", Resources: []*any.Any{cla("b")}})
+	c.onRequest(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e3", ResourceNames: []string{"b"}})
+	if got := cachedNames(c, v3.EndpointType); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("got endpoints %v", got)
+	}
+
+	c.flush()
+	b := newXdsCache(path).takeReplay()
+	if b == nil {
+		t.Fatalf("expected the persisted cache to be replayed")
+	}
+	resp := b.response(v3.ClusterType, nil, "1")
+	if len(resp.Resources) != 2 || resp.VersionInfo != cachedVersion {
+		t.Fatalf("unexpected cached clusters %v", resp)
+	}
+	if resp := b.response(v3.EndpointType, nil, "1"); len(resp.Resources) != 1 {
+		t.Fatalf("unexpected cached endpoints %v", resp)
+	}
+}
+
+func TestXdsDeltaCache(t *testing.T) {
+	c := newXdsCache(filepath.Join(t.TempDir(), "xds-cache.pb"))
+	resource := func(name string) *discovery.Resource {
+		return &discovery.Resource{Name: name, Resource: util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: name})}
+	}
+	steps := []struct {
+		resp *discovery.DeltaDiscoveryResponse
+		ack  *discovery.DeltaDiscoveryRequest
+	}{
+		{
+			resp: &discovery.DeltaDiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e1",
+				Resources: []*discovery.Resource{resource("a"), resource("b")}},
+			ack: &discovery.DeltaDiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e1"},
+		},
+		// Delta responses are applied to the cached resources.
+		{
+			resp: &discovery.DeltaDiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e2",
+				Resources: []*discovery.Resource{resource("c")}, RemovedResources: []string{"a"}},
+			ack: &discovery.DeltaDiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e2"},
+		},
+		// NACKs are ignored.
+		{
+			resp: &discovery.DeltaDiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "e3", RemovedResources: []string{"b"}},
+			ack: &discovery.DeltaDiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "e3",
+				ErrorDetail: &google_rpc.Status{Code: int32(codes.Internal)}},
+		},
+	}
+	for _, s := range steps {
+		c.onDeltaResponse(s.resp)
+		c.onDeltaRequest(s.ack)
+	}
+	if got := cachedNames(c, v3.EndpointType); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("got endpoints %v", got)
+	}
+
+	// The endpoints Envoy unsubscribes from are dropped.
+	c.onDeltaRequest(&discovery.DeltaDiscoveryRequest{TypeUrl: v3.EndpointType, ResourceNamesUnsubscribe: []string{"c"}})
+	if got := cachedNames(c, v3.EndpointType); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("got endpoints %v", got)
+	}
+}
+
+func TestXdsProxyCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xds-cache.pb")
+
+	// Envoy gets and ACKs its config from istiod, which is cached.
+	proxy := setupXdsProxy(t)
+	proxy.xdsCache = newXdsCache(path)
+	f := xdstest.NewMockServer(t)
+	setDialOptions(proxy, f.Listener)
+	downstream := stream(t, setupDownstreamConnection(t, proxy))
+	for _, resp := range []*discovery.DiscoveryResponse{
+		{TypeUrl: v3.ClusterType, Nonce: "c1", Resources: []*any.Any{util.MessageToAny(&cluster.Cluster{Name: "a"})}},
+		{TypeUrl: v3.ListenerType, Nonce: "l1", Resources: []*any.Any{util.MessageToAny(&listener.Listener{Name: "virtualOutbound"})}},
+	} {
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: resp.TypeUrl}); err != nil {
+			t.Fatal(err)
+		}
+		f.SendResponse(resp)
+		if _, err := downstream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: resp.TypeUrl, ResponseNonce: resp.Nonce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if len(cachedNames(proxy.xdsCache, v3.ListenerType)) != 1 {
+			return fmt.Errorf("listener not cached yet")
+		}
+		return nil
+	})
+	proxy.xdsCache.flush()
+
+	// On restart, the cached config is not replayed if istiod is reachable.
+	reachable := setupXdsProxy(t)
+	reachable.xdsCache = newXdsCache(path)
+	istiod := xdstest.NewMockServer(t)
+	setDialOptions(reachable, istiod.Listener)
+	downstream = stream(t, setupDownstreamConnection(t, reachable))
+	if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType}); err != nil {
+		t.Fatal(err)
+	}
+	istiod.SendResponse(&discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "v2", Nonce: "c2"})
+	if resp, err := downstream.Recv(); err != nil || resp.VersionInfo != "v2" {
+		t.Fatalf("expected the clusters of istiod, got %v, %v", resp, err)
+	}
+	if reachable.xdsCache.takeReplay() != nil {
+		t.Fatalf("expected the cache to be discarded once istiod is reached")
+	}
+
+	// On restart, the cached config is replayed if istiod is unreachable.
+	restarted := setupXdsProxy(t)
+	restarted.xdsCache = newXdsCache(path)
+	unreachable := bufconn.Listen(1024)
+	_ = unreachable.Close()
+	setDialOptions(restarted, unreachable)
+	downstream = stream(t, setupDownstreamConnection(t, restarted))
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: typeURL}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := downstream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TypeUrl != typeURL || resp.VersionInfo != cachedVersion || len(resp.Resources) != 1 {
+			t.Fatalf("expected the cached %s, got %v", v3.GetShortType(typeURL), resp)
+		}
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: typeURL, ResponseNonce: resp.Nonce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Once Envoy ACKed the cached config, the stream is terminated so that Envoy reconnects to istiod.
+	if _, err := downstream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the stream to be terminated, got %v", err)
+	}
+	if restarted.xdsCache.takeReplay() != nil {
+		t.Fatalf("expected the cache to be replayed once")
+	}
+}
+
+func TestXdsProxyDeltaCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xds-cache.pb")
+
+	// Envoy gets and ACKs its config from istiod, which is cached.
+	proxy := setupXdsProxy(t)
+	proxy.xdsCache = newXdsCache(path)
+	f := xdstest.NewMockServer(t)
+	setDialOptions(proxy, f.Listener)
+	downstream := deltaStream(t, setupDownstreamConnection(t, proxy))
+	for _, resp := range []*discovery.DeltaDiscoveryResponse{
+		{TypeUrl: v3.ClusterType, Nonce: "c1", Resources: []*discovery.Resource{
+			{Name: "a", Resource: util.MessageToAny(&cluster.Cluster{Name: "a"})},
+		}},
+		{TypeUrl: v3.ListenerType, Nonce: "l1", Resources: []*discovery.Resource{
+			{Name: "virtualOutbound", Resource: util.MessageToAny(&listener.Listener{Name: "virtualOutbound"})},
+		}},
+	} {
+		if err := downstream.Send(&discovery.DeltaDiscoveryRequest{TypeUrl: resp.TypeUrl}); err != nil {
+			t.Fatal(err)
+		}
+		f.SendDeltaResponse(resp)
+		if _, err := downstream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		if err := downstream.Send(&discovery.DeltaDiscoveryRequest{TypeUrl: resp.TypeUrl, ResponseNonce: resp.Nonce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if len(cachedNames(proxy.xdsCache, v3.ListenerType)) != 1 {
+			return fmt.Errorf("listener not cached yet")
+		}
+		return nil
+	})
+	proxy.xdsCache.flush()
+
+	// On restart, the cached config is replayed if istiod is unreachable.
+	restarted := setupXdsProxy(t)
+	restarted.xdsCache = newXdsCache(path)
+	unreachable := bufconn.Listen(1024)
+	_ = unreachable.Close()
+	setDialOptions(restarted, unreachable)
+	downstream = deltaStream(t, setupDownstreamConnection(t, restarted))
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+		if err := downstream.Send(&discovery.DeltaDiscoveryRequest{TypeUrl: typeURL}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := downstream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TypeUrl != typeURL || resp.SystemVersionInfo != cachedVersion || len(resp.Resources) != 1 {
+			t.Fatalf("expected the cached %s, got %v", v3.GetShortType(typeURL), resp)
+		}
+		if err := downstream.Send(&discovery.DeltaDiscoveryRequest{TypeUrl: typeURL, ResponseNonce: resp.Nonce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Once Envoy ACKed the cached config, the stream is terminated so that Envoy reconnects to istiod.
+	if _, err := downstream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the stream to be terminated, got %v", err)
+	}
+}
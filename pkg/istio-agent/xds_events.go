@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	cluster "github.com/envoyproxy/go-control-plane/envoy/data/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/data/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// maxEventsPerRequest bounds the number of events sent to istiod in a single request.
+const maxEventsPerRequest = 100
+
+// isAgentReportType returns whether requests of the type are generated by the agent to report state to
+// istiod, rather than by Envoy.
+func isAgentReportType(typeURL string) bool {
+	switch typeURL {
+	case v3.HealthInfoType, v3.OutlierEventType, v3.HealthCheckEventType, v3.MTLSReportType:
+		return true
+	}
+	return false
+}
+
+// parseEvents converts the lines of an Envoy event log into events created by newEvent. Lines which
+// can't be parsed are skipped.
+func parseEvents(lines [][]byte, newEvent func() proto.Message) []proto.Message {
+	events := make([]proto.Message, 0, len(lines))
+	for _, line := range lines {
+		event := newEvent()
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(line, event); err != nil {
+			proxyLog.Debugf("failed to parse event %q: %v", string(line), err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func newOutlierEvent() proto.Message {
+	return &cluster.OutlierDetectionEvent{}
+}
+
+func newHealthCheckEvent() proto.Message {
+	return &core.HealthCheckEvent{}
+}
+
+// buildEventRequests packs events into requests of the given type. As a DiscoveryRequest can't
+// otherwise hold arbitrary messages, the events are carried in the details of an OK status.
+func buildEventRequests(typeURL string, events []proto.Message) []*discovery.DiscoveryRequest {
+	var reqs []*discovery.DiscoveryRequest
+	for len(events) > 0 {
+		n := len(events)
+		if n > maxEventsPerRequest {
+			n = maxEventsPerRequest
+		}
+		details := make([]*any.Any, 0, n)
+		for _, e := range events[:n] {
+			a, err := any.New(e)
+			if err != nil {
+				proxyLog.Warnf("failed to marshal event: %v", err)
+				continue
+			}
+			details = append(details, a)
+		}
+		events = events[n:]
+		reqs = append(reqs, &discovery.DiscoveryRequest{
+			TypeUrl: typeURL,
+			ErrorDetail: &google_rpc.Status{
+				Code:    int32(codes.OK),
+				Details: details,
+			},
+		})
+	}
+	return reqs
+}
+
+// reportOutlierEvents sends the outlier detection events read from the event log to istiod.
+func (p *XdsProxy) reportOutlierEvents(lines [][]byte) {
+	for _, req := range buildEventRequests(v3.OutlierEventType, parseEvents(lines, newOutlierEvent)) {
+		p.sendEventRequest(req)
+	}
+}
+
+// reportHealthCheckEvents sends the active health check events read from the event log to istiod.
+func (p *XdsProxy) reportHealthCheckEvents(lines [][]byte) {
+	for _, req := range buildEventRequests(v3.HealthCheckEventType, parseEvents(lines, newHealthCheckEvent)) {
+		p.sendEventRequest(req)
+	}
+}
+
+// sendEventRequest sends a request to istiod over the currently connected stream, if any. Unlike
+// PersistRequest, the request is not replayed on reconnection, and is dropped rather than blocking when
+// the stream is busy: events are best effort.
+func (p *XdsProxy) sendEventRequest(req *discovery.DiscoveryRequest) {
+	p.connectedMutex.RLock()
+	con := p.connected
+	p.connectedMutex.RUnlock()
+	if con == nil {
+		return
+	}
+	// Only one of the channels is set, depending on whether Envoy uses SotW or delta xDS.
+	var deltaReq *discovery.DeltaDiscoveryRequest
+	if con.deltaRequestsChan != nil {
+		deltaReq = &discovery.DeltaDiscoveryRequest{TypeUrl: req.TypeUrl, ErrorDetail: req.ErrorDetail}
+	}
+	select {
+	case con.requestsChan <- req:
+	case con.deltaRequestsChan <- deltaReq:
+	case <-con.stopChan:
+	default:
+		proxyLog.Debugf("dropping %s event report, stream is busy", req.TypeUrl)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/data/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/data/core/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestParseOutlierEvents(t *testing.T) {
+	lines := [][]byte{
+		[]byte(`{"type":"CONSECUTIVE_5XX","cluster_name":"outbound|80||foo","upstream_url":"10.0.0.1:80",` +
+			`"action":"EJECT","num_ejections":1,"enforced":true,"eject_consecutive_event":{}}`),
+		[]byte(`not json`),
+		[]byte(`{"clusterName":"outbound|80||foo","upstreamUrl":"10.0.0.1:80","action":"UNEJECT"}`),
+	}
+	events := parseEvents(lines, newOutlierEvent)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	want := &cluster.OutlierDetectionEvent{
+		Type:         cluster.OutlierEjectionType_CONSECUTIVE_5XX,
+		ClusterName:  "outbound|80||foo",
+		UpstreamUrl:  "10.0.0.1:80",
+		Action:       cluster.Action_EJECT,
+		NumEjections: 1,
+		Enforced:     true,
+		Event:        &cluster.OutlierDetectionEvent_EjectConsecutiveEvent{EjectConsecutiveEvent: &cluster.OutlierEjectConsecutive{}},
+	}
+	if !proto.Equal(want, events[0]) {
+		t.Fatalf("got %v, want %v", events[0], want)
+	}
+	if got := events[1].(*cluster.OutlierDetectionEvent).Action; got != cluster.Action_UNEJECT {
+		t.Fatalf("expected UNEJECT, got %v", got)
+	}
+}
+
+func TestBuildEventRequests(t *testing.T) {
+	events := make([]proto.Message, 0, maxEventsPerRequest+1)
+	for i := 0; i < maxEventsPerRequest+1; i++ {
+		events = append(events, &cluster.OutlierDetectionEvent{ClusterName: "foo"})
+	}
+	reqs := buildEventRequests(v3.OutlierEventType, events)
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	if got := len(reqs[0].ErrorDetail.Details) + len(reqs[1].ErrorDetail.Details); got != len(events) {
+		t.Fatalf("expected %d events, got %d", len(events), got)
+	}
+	if reqs[0].TypeUrl != v3.OutlierEventType {
+		t.Fatalf("unexpected type %v", reqs[0].TypeUrl)
+	}
+}
+
+func TestParseHealthCheckEvents(t *testing.T) {
+	lines := [][]byte{
+		[]byte(`{"health_checker_type":"HTTP","host":{"socket_address":{"protocol":"TCP","address":"10.0.0.1","port_value":80}},` +
+			`"cluster_name":"outbound|80||foo","eject_unhealthy_event":{"failure_type":"ACTIVE"},"timestamp":"2009-11-10T23:00:00Z"}`),
+	}
+	events := parseEvents(lines, newHealthCheckEvent)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	event := events[0].(*core.HealthCheckEvent)
+	if event.ClusterName != "outbound|80||foo" || event.Host.GetSocketAddress().GetPortValue() != 80 ||
+		event.GetEjectUnhealthyEvent().GetFailureType() != core.HealthCheckFailureType_ACTIVE {
+		t.Fatalf("unexpected event %v", event)
+	}
+}
+
+func TestIsAgentReportType(t *testing.T) {
+	for _, typeURL := range []string{v3.HealthInfoType, v3.OutlierEventType, v3.HealthCheckEventType} {
+		if !isAgentReportType(typeURL) {
+			t.Errorf("expected %v to be an agent report type", typeURL)
+		}
+	}
+	if isAgentReportType(v3.ListenerType) {
+		t.Errorf("listeners are requested by Envoy")
+	}
+}
+
+func TestParseMTLSTraffic(t *testing.T) {
+	stats := `# TYPE istio_requests_total counter
+istio_requests_total{reporter="destination",destination_service="foo.default.svc.cluster.local",connection_security_policy="mutual_tls"} 10
+istio_requests_total{reporter="destination",destination_service="foo.default.svc.cluster.local",connection_security_policy="none"} 2
+istio_requests_total{reporter="source",destination_service="bar.default.svc.cluster.local",connection_security_policy="unknown"} 7
+istio_requests_total{reporter="destination",destination_service="unknown",connection_security_policy="none"} 3
+# TYPE istio_tcp_connections_opened_total counter
+istio_tcp_connections_opened_total{reporter="destination",destination_service="db.default.svc.cluster.local",connection_security_policy="mutual_tls"} 4
+istio_tcp_connections_opened_total{reporter="destination",destination_service="foo.default.svc.cluster.local",connection_security_policy="none"} 1
+`
+	traffic, err := parseMTLSTraffic(strings.NewReader(stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*mtlsTraffic{
+		"foo.default.svc.cluster.local": {mtls: 10, plaintext: 3},
+		"db.default.svc.cluster.local":  {mtls: 4},
+	}
+	if !reflect.DeepEqual(traffic, want) {
+		t.Fatalf("got %v, want %v", traffic, want)
+	}
+	reports := buildMTLSReports(traffic)
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	first := reports[0].(*structpb.Struct).GetFields()
+	if first["service"].GetStringValue() != "db.default.svc.cluster.local" || first["mtls"].GetNumberValue() != 4 {
+		t.Fatalf("unexpected first report %v", reports[0])
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+const (
+	// fallbackVersion is the version of the responses served from the fallback bundle.
+	fallbackVersion = "fallback"
+	// fallbackFetchTimeout bounds the fetch of a fallback bundle served over HTTPS.
+	fallbackFetchTimeout = 10 * time.Second
+)
+
+// xdsFallback is the last known good config served to Envoy when istiod cannot be reached at startup. The bundle is
+// an Envoy config dump of a proxy of the same workload, as the listeners of a proxy hold the addresses and ports of
+// its workload. It is served once, after the deadline, if istiod has never been reached and no cached config was
+// replayed. A nil fallback is disabled.
+type xdsFallback struct {
+	source   string
+	sha256   string
+	deadline time.Time
+
+	mu sync.Mutex
+	// done is set once istiod is reached or a config was replayed to Envoy.
+	done bool
+}
+
+// newXdsFallback returns the fallback to the bundle at the source, a path or an HTTPS URL, served once istiod could
+// not be reached within the timeout. The bundle must have the SHA-256 digest, if set.
+func newXdsFallback(source, digest string, timeout time.Duration) *xdsFallback {
+	return &xdsFallback{source: source, sha256: strings.ToLower(digest), deadline: time.Now().Add(timeout)}
+}
+
+// disable disables the fallback, as Envoy gets its config from istiod or the cache.
+func (f *xdsFallback) disable() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done = true
+}
+
+// take returns the fallback bundle if it is to be served, or nil. The bundle is loaded when first served, so that it
+// is not fetched while istiod is reachable, and is loaded again on the next connection of Envoy if it fails.
+func (f *xdsFallback) take() *configBundle {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	if f.done || time.Now().Before(f.deadline) {
+		f.mu.Unlock()
+		return nil
+	}
+	f.done = true
+	f.mu.Unlock()
+
+	b, err := loadFallbackBundle(f.source, f.sha256)
+	if err != nil {
+		proxyLog.Errorf("failed to load the fallback bundle %s: %v", f.source, err)
+		f.mu.Lock()
+		f.done = false
+		f.mu.Unlock()
+		return nil
+	}
+	return b
+}
+
+// loadFallbackBundle reads the fallback bundle from a file or, for an HTTPS URL, fetches it, and checks its digest.
+// A bundle fetched over the network must have a digest, as it holds the whole config of the proxy.
+func loadFallbackBundle(source, digest string) (*configBundle, error) {
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(source, "https://"):
+		if digest == "" {
+			return nil, fmt.Errorf("a fallback bundle fetched from a URL must have a SHA-256 digest")
+		}
+		data, err = fetchFallbackBundle(source)
+	case strings.Contains(source, "://"):
+		return nil, fmt.Errorf("only HTTPS URLs are supported")
+	default:
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if digest != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != digest {
+			return nil, fmt.Errorf("SHA-256 digest %s does not match the expected %s", got, digest)
+		}
+	}
+	dump := &adminapi.ConfigDump{}
+	if err := protomarshal.UnmarshalAllowUnknown(data, dump); err != nil {
+		return nil, fmt.Errorf("invalid config dump: %v", err)
+	}
+	return newConfigBundle(dump, fallbackVersion)
+}
+
+func fetchFallbackBundle(url string) ([]byte, error) {
+	client := &http.Client{Timeout: fallbackFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,149 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	any "google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// writeFallbackBundle writes a config dump with a cluster and a listener, and returns its path and SHA-256 digest.
+func writeFallbackBundle(t *testing.T) (string, string) {
+	dump := &adminapi.ConfigDump{Configs: []*any.Any{
+		util.MessageToAny(&adminapi.ClustersConfigDump{
+			DynamicActiveClusters: []*adminapi.ClustersConfigDump_DynamicCluster{
+				{Cluster: util.MessageToAny(&cluster.Cluster{Name: "outbound|80||a.default.svc.cluster.local"})},
+			},
+		}),
+		util.MessageToAny(&adminapi.ListenersConfigDump{
+			DynamicListeners: []*adminapi.ListenersConfigDump_DynamicListener{{
+				ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: util.MessageToAny(&listener.Listener{Name: "virtualOutbound"})},
+			}},
+		}),
+	}}
+	js, err := protomarshal.ToJSON(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config_dump.json")
+	if err := os.WriteFile(path, []byte(js), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(js))
+	return path, hex.EncodeToString(sum[:])
+}
+
+func TestLoadFallbackBundle(t *testing.T) {
+	path, digest := writeFallbackBundle(t)
+	for _, tt := range []struct {
+		name    string
+		source  string
+		digest  string
+		wantErr bool
+	}{
+		{name: "file", source: path},
+		{name: "file with digest", source: path, digest: digest},
+		{name: "file with another digest", source: path, digest: "00" + digest[2:], wantErr: true},
+		{name: "http", source: "http://example.com/config_dump.json", digest: digest, wantErr: true},
+		{name: "https without digest", source: "https://example.com/config_dump.json", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := loadFallbackBundle(tt.source, tt.digest)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %s to be rejected", tt.source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp := b.response(v3.ClusterType, nil, "1"); resp.VersionInfo != fallbackVersion || len(resp.Resources) != 1 {
+				t.Fatalf("unexpected clusters %v", resp)
+			}
+		})
+	}
+}
+
+func TestXdsFallbackDeadline(t *testing.T) {
+	path, digest := writeFallbackBundle(t)
+	if newXdsFallback(path, digest, time.Hour).take() != nil {
+		t.Fatalf("expected the bundle not to be served before the deadline")
+	}
+	reached := newXdsFallback(path, digest, 0)
+	reached.disable()
+	if reached.take() != nil {
+		t.Fatalf("expected the bundle not to be served once istiod is reached")
+	}
+	f := newXdsFallback(path, digest, 0)
+	if f.take() == nil {
+		t.Fatalf("expected the bundle to be served after the deadline")
+	}
+	if f.take() != nil {
+		t.Fatalf("expected the bundle to be served once")
+	}
+	missing := newXdsFallback(filepath.Join(t.TempDir(), "missing.json"), "", 0)
+	if missing.take() != nil || missing.done {
+		t.Fatalf("expected a bundle failing to load to be retried")
+	}
+}
+
+func TestXdsProxyFallback(t *testing.T) {
+	path, digest := writeFallbackBundle(t)
+	proxy := setupXdsProxy(t)
+	proxy.xdsFallback = newXdsFallback(path, digest, 0)
+	// istiod is unreachable.
+	unreachable := bufconn.Listen(1024)
+	_ = unreachable.Close()
+	setDialOptions(proxy, unreachable)
+
+	downstream := stream(t, setupDownstreamConnection(t, proxy))
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: typeURL}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := downstream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TypeUrl != typeURL || resp.VersionInfo != fallbackVersion || len(resp.Resources) != 1 {
+			t.Fatalf("expected the %s of the fallback bundle, got %v", v3.GetShortType(typeURL), resp)
+		}
+		if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: typeURL, ResponseNonce: resp.Nonce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Once Envoy ACKed the bundle, the stream is terminated so that Envoy reconnects to istiod.
+	if _, err := downstream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the stream to be terminated, got %v", err)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	gogotypes "github.com/gogo/protobuf/types"
 	"go.uber.org/atomic"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
@@ -50,6 +51,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/constants"
 	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/istio-agent/eventlog"
 	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/istio/pkg/istio-agent/metrics"
 	istiokeepalive "istio.io/istio/pkg/keepalive"
@@ -120,6 +122,11 @@ type XdsProxy struct {
 	ecdsLastNonce         atomic.String
 	downstreamGrpcOptions []grpc.ServerOption
 	istiodSAN             string
+
+	// xdsCache persists the config ACKed by Envoy, to replay it on restart. It is nil if disabled.
+	xdsCache *xdsCache
+	// xdsFallback is the bundle served to Envoy if istiod cannot be reached at startup. It is nil if disabled.
+	xdsFallback *xdsFallback
 }
 
 var proxyLog = log.RegisterScope("xdsproxy", "XDS Proxy in Istio Agent", 0)
@@ -143,7 +150,15 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}
 
-	cache := wasm.NewLocalFileCache(constants.IstioDataDir, wasm.DefaultWasmModulePurgeInterval, wasm.DefaultWasmModuleExpiry, ia.cfg.WASMInsecureRegistries)
+	wasmOpts := wasm.DefaultOptions()
+	wasmOpts.InsecureRegistries = ia.cfg.WASMInsecureRegistries
+	wasmOpts.RequireSignature = ia.cfg.WASMRequireSignature
+	if ia.cfg.WASMSignaturePublicKey != "" {
+		if wasmOpts.PublicKey, err = wasm.LoadPublicKey(ia.cfg.WASMSignaturePublicKey); err != nil {
+			return nil, err
+		}
+	}
+	cache := wasm.NewLocalFileCache(constants.IstioDataDir, wasmOpts)
 	proxy := &XdsProxy{
 		istiodAddress:         ia.proxyConfig.DiscoveryAddress,
 		istiodSAN:             ia.cfg.IstiodSAN,
@@ -157,6 +172,13 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		proxyAddresses:        ia.cfg.ProxyIPAddresses,
 		downstreamGrpcOptions: ia.cfg.DownstreamGrpcOptions,
 	}
+	if ia.cfg.XDSCacheFile != "" {
+		proxy.xdsCache = newXdsCache(ia.cfg.XDSCacheFile)
+		go proxy.xdsCache.run(proxy.stopChan)
+	}
+	if ia.cfg.XDSFallbackBundle != "" {
+		proxy.xdsFallback = newXdsFallback(ia.cfg.XDSFallbackBundle, ia.cfg.XDSFallbackBundleSHA256, ia.cfg.XDSFallbackTimeout)
+	}
 
 	if ia.localDNSServer != nil {
 		proxy.handlers[v3.NameTableType] = func(resp *any.Any) error {
@@ -202,6 +224,17 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}()
 
+	if ia.cfg.ReportOutlierEvents && ia.envoyOpts.OutlierLogPath != "" {
+		go eventlog.NewTailer(ia.envoyOpts.OutlierLogPath, eventlog.DefaultPollInterval).Run(proxy.stopChan, proxy.reportOutlierEvents)
+	}
+	if ia.cfg.HealthCheckEventLogPath != "" {
+		go eventlog.NewTailer(ia.cfg.HealthCheckEventLogPath, eventlog.DefaultPollInterval).Run(proxy.stopChan, proxy.reportHealthCheckEvents)
+	}
+	if ia.cfg.MTLSReportInterval > 0 && !ia.cfg.DisableEnvoy {
+		statsURL := fmt.Sprintf("http://%s:%d/stats/prometheus", localHostAddr, ia.proxyConfig.ProxyAdminPort)
+		go proxy.reportMTLSCompatibility(statsURL, ia.cfg.MTLSReportInterval, proxy.stopChan)
+	}
+
 	go proxy.healthChecker.PerformApplicationHealthCheck(func(healthEvent *health.ProbeEvent) {
 		// Store the same response as Delta and SotW. Depending on how Envoy connects we will use one or the other.
 		var req *discovery.DiscoveryRequest
@@ -336,7 +369,7 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
-		return err
+		return p.replayIfUnreachable(con, err)
 	}
 	defer upstreamConn.Close()
 
@@ -366,9 +399,12 @@ func (p *XdsProxy) HandleUpstream(ctx context.Context, con *ProxyConnection, xds
 		proxyLog.Debugf("failed to create upstream grpc client: %v", err)
 		// Increase metric when xds connection error, for example: forgot to restart ingressgateway or sidecar after changing root CA.
 		metrics.IstiodConnectionErrors.Increment()
-		return err
+		return p.replayIfUnreachable(con, err)
 	}
 	proxyLog.Infof("connected to upstream XDS server: %s", p.istiodAddress)
+	// istiod is reachable, so neither the cache nor the fallback bundle is replayed.
+	p.xdsCache.takeReplay()
+	p.xdsFallback.disable()
 	defer proxyLog.Debugf("disconnected from XDS server: %s", p.istiodAddress)
 
 	con.upstream = upstream
@@ -439,6 +475,7 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 				return
 			}
 
+			p.xdsCache.onRequest(req)
 			// forward to istiod
 			con.sendRequest(req)
 			if !initialRequestsSent.Load() && req.TypeUrl == v3.ListenerType {
@@ -471,8 +508,8 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 	for {
 		select {
 		case req := <-con.requestsChan:
-			if req.TypeUrl == v3.HealthInfoType && !initialRequestsSent.Load() {
-				// only send healthcheck probe after LDS request has been sent
+			if isAgentReportType(req.TypeUrl) && !initialRequestsSent.Load() {
+				// only send healthcheck probe and events after LDS request has been sent
 				continue
 			}
 			proxyLog.Debugf("request for type url %s", req.TypeUrl)
@@ -537,6 +574,7 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 				if strings.HasPrefix(resp.TypeUrl, "istio.io/debug") {
 					p.forwardToTap(resp)
 				} else {
+					p.xdsCache.onResponse(resp)
 					forwardToEnvoy(con, resp)
 				}
 			}
@@ -621,6 +659,7 @@ func (p *XdsProxy) initDownstreamServer() error {
 	opts = append(opts, istiogrpc.ServerOptions(istiokeepalive.DefaultOption())...)
 	grpcs := grpc.NewServer(opts...)
 	discovery.RegisterAggregatedDiscoveryServiceServer(grpcs, p)
+	lrs.RegisterLoadReportingServiceServer(grpcs, p)
 	reflection.Register(grpcs)
 	p.downstreamGrpcServer = grpcs
 	p.downstreamListener = l
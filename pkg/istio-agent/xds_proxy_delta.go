@@ -107,7 +107,7 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream discovery.AggregatedDisco
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
-		return err
+		return p.replayIfUnreachable(con, err)
 	}
 	defer upstreamConn.Close()
 
@@ -128,9 +128,12 @@ func (p *XdsProxy) HandleDeltaUpstream(ctx context.Context, con *ProxyConnection
 		proxyLog.Debugf("failed to create delta upstream grpc client: %v", err)
 		// Increase metric when xds connection error, for example: forgot to restart ingressgateway or sidecar after changing root CA.
 		metrics.IstiodConnectionErrors.Increment()
-		return err
+		return p.replayIfUnreachable(con, err)
 	}
 	proxyLog.Infof("connected to delta upstream XDS server: %s", p.istiodAddress)
+	// istiod is reachable, so neither the cache nor the fallback bundle is replayed.
+	p.xdsCache.takeReplay()
+	p.xdsFallback.disable()
 	defer proxyLog.Debugf("disconnected from delta XDS server: %s", p.istiodAddress)
 
 	con.upstreamDeltas = deltaUpstream
@@ -199,6 +202,7 @@ func (p *XdsProxy) handleUpstreamDeltaRequest(con *ProxyConnection) {
 			if req.TypeUrl == v3.ExtensionConfigurationType {
 				p.ecdsLastNonce.Store(req.ResponseNonce)
 			}
+			p.xdsCache.onDeltaRequest(req)
 			if err := sendUpstreamDelta(con.upstreamDeltas, req); err != nil {
 				proxyLog.Errorf("upstream send error for type url %s: %v", req.TypeUrl, err)
 				con.upstreamError <- err
@@ -249,6 +253,7 @@ func (p *XdsProxy) handleUpstreamDeltaResponse(con *ProxyConnection) {
 					forwardDeltaToEnvoy(con, resp)
 				}
 			default:
+				p.xdsCache.onDeltaResponse(resp)
 				forwardDeltaToEnvoy(con, resp)
 			}
 		case <-con.stopChan:
@@ -46,6 +46,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	proxyConfig "istio.io/api/networking/v1beta1"
 	opconfig "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 	"istio.io/pkg/log"
@@ -93,6 +94,8 @@ const (
 	ImageTypeDistroless = "distroless"
 	// ImageTypeDefault is the type name of the default image, sufix is elided.
 	ImageTypeDefault = "default"
+	// ImageTypeWindows is the suffix of the Windows image, run by the sidecars of Windows pods.
+	ImageTypeWindows = "windows"
 )
 
 // SidecarTemplateData is the data object to which the templated
@@ -128,6 +131,11 @@ type Config struct {
 	// DefaultTemplates defines the default template to use for pods that do not explicitly specify a template
 	DefaultTemplates []string `json:"defaultTemplates"`
 
+	// WindowsTemplates defines the templates to use, instead of DefaultTemplates, for pods that run on Windows nodes
+	// and do not explicitly specify a template, if ENABLE_WINDOWS_INJECTION is set. Defaults to the "windows" template,
+	// if there is one.
+	WindowsTemplates []string `json:"windowsTemplates"`
+
 	// Templates defines a set of templates to be used. The specified template will be run, provided with
 	// SidecarTemplateData, and merged with the original pod spec using a strategic merge patch.
 	Templates Templates `json:"templates"`
@@ -151,10 +159,15 @@ type Config struct {
 	// InjectedAnnotations are additional annotations that will be added to the pod spec after injection
 	// This is primarily to support PSP annotations.
 	InjectedAnnotations map[string]string `json:"injectedAnnotations"`
+
+	// ScopeResources, if set, sizes the resource requests of the sidecar from the size of its SidecarScope.
+	// This is only applied by the injection webhook, which has access to the mesh configuration.
+	ScopeResources *ScopeResources `json:"scopeResources,omitempty"`
 }
 
 const (
 	SidecarTemplateName = "sidecar"
+	WindowsTemplateName = "windows"
 )
 
 // UnmarshalConfig unmarshals the provided YAML configuration, while normalizing the resulting configuration
@@ -169,6 +182,12 @@ func UnmarshalConfig(yml []byte) (Config, error) {
 	if len(injectConfig.DefaultTemplates) == 0 {
 		injectConfig.DefaultTemplates = []string{SidecarTemplateName}
 	}
+	if len(injectConfig.WindowsTemplates) == 0 {
+		injectConfig.WindowsTemplates = defaultWindowsTemplates(injectConfig.Templates)
+	}
+	if err := injectConfig.ScopeResources.Validate(); err != nil {
+		return injectConfig, err
+	}
 	if len(injectConfig.Templates) == 0 {
 		log.Warnf("injection templates are empty." +
 			" This may be caused by using an injection template from an older version of Istio." +
@@ -327,7 +346,7 @@ func imageURL(hub, imageName, tag, imageType string) string {
 }
 
 // KnownImageTypes are image types that istio pubishes.
-var KnownImageTypes []string = []string{ImageTypeDistroless, ImageTypeDebug}
+var KnownImageTypes []string = []string{ImageTypeDistroless, ImageTypeDebug, ImageTypeWindows}
 
 func updateImageTypeIfPresent(tag string, imageType string) string {
 	if imageType == "" {
@@ -399,6 +418,12 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 		return nil, nil, err
 	}
 
+	image := params.proxyConfig.Image
+	if injectWindows(params) {
+		// Windows pods can only run the Windows image, whatever the image type of the mesh.
+		image = &proxyConfig.ProxyImage{ImageType: ImageTypeWindows}
+	}
+
 	data := SidecarTemplateData{
 		TypeMeta:             params.typeMeta,
 		DeploymentMeta:       params.deployMeta,
@@ -409,7 +434,7 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 		Values:               values,
 		Revision:             params.revision,
 		EstimatedConcurrency: estimateConcurrency(params.proxyConfig, metadata.Annotations, valuesStruct),
-		ProxyImage:           ProxyImage(valuesStruct, params.proxyConfig.Image, strippedPod.Annotations),
+		ProxyImage:           ProxyImage(valuesStruct, image, strippedPod.Annotations),
 	}
 	funcMap := CreateInjectionFuncmap()
 
@@ -471,9 +496,34 @@ func selectTemplates(params InjectionParameters) []string {
 		}
 		return resolveAliases(params, names)
 	}
+	if injectWindows(params) {
+		return resolveAliases(params, params.windowsTemplate)
+	}
 	return resolveAliases(params, params.defaultTemplate)
 }
 
+// defaultWindowsTemplates returns the templates of the pods running on Windows nodes, when none are configured.
+func defaultWindowsTemplates(templates Templates) []string {
+	if _, f := templates[WindowsTemplateName]; f {
+		return []string{WindowsTemplateName}
+	}
+	return nil
+}
+
+// injectWindows returns whether the pod runs on Windows nodes and is injected with the Windows templates, which is
+// only enabled by ENABLE_WINDOWS_INJECTION.
+func injectWindows(params InjectionParameters) bool {
+	return features.EnableWindowsInjection && len(params.windowsTemplate) > 0 && isWindowsPod(params.pod)
+}
+
+// isWindowsPod returns whether the pod is scheduled on Windows nodes, from its OS or its node selector.
+func isWindowsPod(pod *corev1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name == corev1.Windows
+	}
+	return pod.Spec.NodeSelector[corev1.LabelOSStable] == string(corev1.Windows)
+}
+
 func resolveAliases(params InjectionParameters, names []string) []string {
 	ret := []string{}
 	for _, name := range names {
@@ -776,6 +826,7 @@ func IntoObject(injector Injector, sidecarTemplate Templates, valuesConfig strin
 			// Todo replace with some template resolver abstraction
 			templates:           sidecarTemplate,
 			defaultTemplate:     []string{SidecarTemplateName},
+			windowsTemplate:     defaultWindowsTemplates(sidecarTemplate),
 			meshConfig:          meshconfig,
 			proxyConfig:         meshconfig.GetDefaultConfig(),
 			valuesConfig:        valuesConfig,
@@ -251,6 +251,16 @@ func TestInjection(t *testing.T) {
 			in:            "traffic-annotations-bad-excludeoutboundports.yaml",
 			expectedError: "excludeoutboundports",
 		},
+		{
+			in:   "windows.yaml",
+			want: "windows.yaml.injected",
+			setup: func() {
+				features.EnableWindowsInjection = true
+			},
+			teardown: func() {
+				features.EnableWindowsInjection = false
+			},
+		},
 		{
 			in:   "hello.yaml",
 			want: "hello-no-seccontext.yaml.injected",
@@ -570,6 +580,94 @@ spec:
 	runWebhook(t, webhook, []byte(inputAlias), []byte(fmt.Sprintf(expected, "both")), false)
 }
 
+func TestWindowsInjectionTemplates(t *testing.T) {
+	features.EnableWindowsInjection = true
+	t.Cleanup(func() {
+		features.EnableWindowsInjection = false
+	})
+	templates := map[string]string{
+		"sidecar": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: proxy
+`,
+		"windows": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: windows-proxy
+`,
+	}
+	webhook := &Webhook{
+		Config: &Config{
+			Templates:        templates,
+			DefaultTemplates: []string{SidecarTemplateName},
+			WindowsTemplates: defaultWindowsTemplates(templates),
+			Policy:           InjectionPolicyEnabled,
+		},
+		env: &model.Environment{
+			PushContext: &model.PushContext{
+				ProxyConfigs: &model.ProxyConfigs{},
+			},
+		},
+	}
+
+	input := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: hello
+spec:
+  os:
+    name: windows
+  containers:
+  - name: hello
+    image: "fake.docker.io/google-samples/hello-go-gke:1.0"
+`
+	// nolint: lll
+	expected := `
+apiVersion: v1
+kind: Pod
+metadata:
+  annotations:
+    prometheus.io/path: /stats/prometheus
+    prometheus.io/port: "0"
+    prometheus.io/scrape: "true"
+    sidecar.istio.io/status: '{"version":"","initContainers":null,"containers":["istio-proxy"],"volumes":["istio-envoy","istio-data","istio-podinfo","istio-token","istiod-ca-cert"],"imagePullSecrets":null}'
+  name: hello
+spec:
+  os:
+    name: windows
+  containers:
+    - name: hello
+      image: fake.docker.io/google-samples/hello-go-gke:1.0
+    - name: istio-proxy
+      image: windows-proxy
+`
+	runWebhook(t, webhook, []byte(input), []byte(expected), false)
+}
+
+func TestSelectWindowsTemplates(t *testing.T) {
+	params := InjectionParameters{
+		pod: &corev1.Pod{Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{corev1.LabelOSStable: string(corev1.Windows)},
+		}},
+		defaultTemplate: []string{SidecarTemplateName},
+		windowsTemplate: []string{WindowsTemplateName},
+	}
+	if got := selectTemplates(params); !cmp.Equal(got, []string{SidecarTemplateName}) {
+		t.Fatalf("expected the default templates without ENABLE_WINDOWS_INJECTION, got %v", got)
+	}
+	features.EnableWindowsInjection = true
+	t.Cleanup(func() {
+		features.EnableWindowsInjection = false
+	})
+	if got := selectTemplates(params); !cmp.Equal(got, []string{WindowsTemplateName}) {
+		t.Fatalf("expected the Windows templates, got %v", got)
+	}
+}
+
 // TestStrategicMerge ensures we can use https://github.com/kubernetes/community/blob/master/contributors/devel/sig-api-machinery/strategic-merge-patch.md
 // directives in the injection template
 func TestStrategicMerge(t *testing.T) {
@@ -1031,6 +1129,12 @@ func TestProxyImage(t *testing.T) {
 			pc:   pc("distroless"),
 			want: "docker.io/istio/proxyv2:1.12-distroless",
 		},
+		{
+			desc: "pc overrides imageType with windows, tag also has image type",
+			v:    val("docker.io/istio", "1.12-distroless"),
+			pc:   pc("windows"),
+			want: "docker.io/istio/proxyv2:1.12-windows",
+		},
 		{
 			desc: "annotation overrides imageType, tag also has the same image type",
 			v:    val("docker.io/istio", "1.12-distroless"),
@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+)
+
+// ScopeResources sizes the resource requests of the injected sidecar from the size of the SidecarScope of the pod,
+// so that proxies receiving a large configuration get more resources than the default.
+type ScopeResources struct {
+	// CPU is the formula computing the CPU request of the sidecar.
+	CPU *ResourceFormula `json:"cpu,omitempty"`
+	// Memory is the formula computing the memory request of the sidecar.
+	Memory *ResourceFormula `json:"memory,omitempty"`
+}
+
+// ResourceFormula computes a resource request as
+// base + perCluster * clusters + perListener * listeners, capped to max.
+// All the values are Kubernetes quantities, such as "100m" or "128Mi".
+type ResourceFormula struct {
+	Base        string `json:"base,omitempty"`
+	PerCluster  string `json:"perCluster,omitempty"`
+	PerListener string `json:"perListener,omitempty"`
+	Max         string `json:"max,omitempty"`
+}
+
+// compute returns the resource request, in milli units, for the number of clusters and listeners.
+func (f *ResourceFormula) compute(clusters, listeners int) (int64, error) {
+	base, err := parseMilliQuantity(f.Base)
+	if err != nil {
+		return 0, fmt.Errorf("invalid base: %v", err)
+	}
+	perCluster, err := parseMilliQuantity(f.PerCluster)
+	if err != nil {
+		return 0, fmt.Errorf("invalid perCluster: %v", err)
+	}
+	perListener, err := parseMilliQuantity(f.PerListener)
+	if err != nil {
+		return 0, fmt.Errorf("invalid perListener: %v", err)
+	}
+	max, err := parseMilliQuantity(f.Max)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max: %v", err)
+	}
+	out := base + perCluster*int64(clusters) + perListener*int64(listeners)
+	if max > 0 && out > max {
+		out = max
+	}
+	return out, nil
+}
+
+// Validate returns an error if any of the quantities of the formulas is invalid.
+func (s *ScopeResources) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.CPU != nil {
+		if _, err := s.CPU.compute(0, 0); err != nil {
+			return fmt.Errorf("scopeResources.cpu: %v", err)
+		}
+	}
+	if s.Memory != nil {
+		if _, err := s.Memory.compute(0, 0); err != nil {
+			return fmt.Errorf("scopeResources.memory: %v", err)
+		}
+	}
+	return nil
+}
+
+func parseMilliQuantity(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	return q.MilliValue(), nil
+}
+
+// apply sets the sidecar.istio.io/proxyCPU and sidecar.istio.io/proxyMemory annotations of the pod, which the
+// injection template turns into the resource requests of the sidecar. Requests explicitly set on the pod are kept.
+func (s *ScopeResources) apply(pod *corev1.Pod, clusters, listeners int) error {
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	if _, f := pod.Annotations[annotation.SidecarProxyCPU.Name]; !f && s.CPU != nil {
+		cpu, err := s.CPU.compute(clusters, listeners)
+		if err != nil {
+			return err
+		}
+		if cpu > 0 {
+			pod.Annotations[annotation.SidecarProxyCPU.Name] = fmt.Sprintf("%dm", cpu)
+		}
+	}
+	if _, f := pod.Annotations[annotation.SidecarProxyMemory.Name]; !f && s.Memory != nil {
+		memory, err := s.Memory.compute(clusters, listeners)
+		if err != nil {
+			return err
+		}
+		if memory > 0 {
+			// Round up to the next mebibyte, the milli value is in thousandths of a byte.
+			pod.Annotations[annotation.SidecarProxyMemory.Name] = fmt.Sprintf("%dMi", int64(math.Ceil(float64(memory)/(1000*1024*1024))))
+		}
+	}
+	return nil
+}
+
+// scopeSize returns the number of outbound clusters and listeners a sidecar in the namespace, with the labels,
+// is expected to receive from its SidecarScope.
+func scopeSize(push *model.PushContext, namespace string, labels map[string]string) (clusters int, listeners int) {
+	proxy := &model.Proxy{
+		Type:            model.SidecarProxy,
+		ConfigNamespace: namespace,
+		Metadata: &model.NodeMetadata{
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+	proxy.SetSidecarScope(push)
+	if proxy.SidecarScope == nil {
+		return 0, 0
+	}
+	services := map[host.Name]struct{}{}
+	ports := map[int]struct{}{}
+	for _, l := range proxy.SidecarScope.EgressListeners {
+		// An egress listener on an explicit port gets a single listener, whatever the services it imports.
+		if port := l.IstioListener.GetPort().GetNumber(); port != 0 {
+			ports[int(port)] = struct{}{}
+		}
+		for _, svc := range l.Services() {
+			if _, f := services[svc.Hostname]; f {
+				continue
+			}
+			services[svc.Hostname] = struct{}{}
+			clusters += len(svc.Ports)
+			if l.IstioListener.GetPort() == nil {
+				for _, port := range svc.Ports {
+					ports[port.Port] = struct{}{}
+				}
+			}
+		}
+	}
+	return clusters, len(ports)
+}
@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestScopeResourcesApply(t *testing.T) {
+	resources := &ScopeResources{
+		CPU:    &ResourceFormula{Base: "100m", PerCluster: "2m", PerListener: "5m", Max: "1"},
+		Memory: &ResourceFormula{Base: "64Mi", PerCluster: "100Ki", PerListener: "1Mi"},
+	}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		clusters    int
+		listeners   int
+		want        map[string]string
+	}{
+		{
+			name: "empty scope",
+			want: map[string]string{
+				annotation.SidecarProxyCPU.Name:    "100m",
+				annotation.SidecarProxyMemory.Name: "64Mi",
+			},
+		},
+		{
+			name:      "scaled",
+			clusters:  100,
+			listeners: 10,
+			want: map[string]string{
+				annotation.SidecarProxyCPU.Name:    "350m",
+				annotation.SidecarProxyMemory.Name: "84Mi",
+			},
+		},
+		{
+			name:      "capped",
+			clusters:  1000,
+			listeners: 10,
+			want: map[string]string{
+				annotation.SidecarProxyCPU.Name:    "1000m",
+				annotation.SidecarProxyMemory.Name: "172Mi",
+			},
+		},
+		{
+			name:        "explicit requests",
+			annotations: map[string]string{annotation.SidecarProxyCPU.Name: "2"},
+			clusters:    100,
+			want: map[string]string{
+				annotation.SidecarProxyCPU.Name:    "2",
+				annotation.SidecarProxyMemory.Name: "74Mi",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if err := resources.apply(pod, tt.clusters, tt.listeners); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if got := pod.Annotations[k]; got != v {
+					t.Errorf("annotation %s: got %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestScopeResourcesValidate(t *testing.T) {
+	if err := (&ScopeResources{CPU: &ResourceFormula{Base: "100m"}}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&ScopeResources{Memory: &ResourceFormula{PerCluster: "lots"}}).Validate(); err == nil {
+		t.Fatalf("expected error for invalid quantity")
+	}
+	if _, err := UnmarshalConfig([]byte("scopeResources:\n  cpu:\n    base: bad\n")); err == nil {
+		t.Fatalf("expected error for invalid config")
+	}
+}
+
+func TestScopeSize(t *testing.T) {
+	service := func(hostname, namespace string, ports ...int) *model.Service {
+		svc := &model.Service{
+			Hostname:   host.Name(hostname),
+			Attributes: model.ServiceAttributes{Namespace: namespace},
+		}
+		for _, p := range ports {
+			svc.Ports = append(svc.Ports, &model.Port{Name: "http", Port: p, Protocol: protocol.HTTP})
+		}
+		return svc
+	}
+	store := memory.Make(collections.Pilot)
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			Name:             "default",
+			Namespace:        "small",
+			GroupVersionKind: gvk.Sidecar,
+		},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{{Hosts: []string{"./*"}}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{
+		PushContext: model.NewPushContext(),
+		ServiceDiscovery: memregistry.NewServiceDiscovery([]*model.Service{
+			service("a.small.svc.cluster.local", "small", 80),
+			service("b.large.svc.cluster.local", "large", 80, 8080),
+			service("c.large.svc.cluster.local", "large", 9090),
+		}),
+		IstioConfigStore: model.MakeIstioStore(store),
+		Watcher:          mesh.NewFixedWatcher(&m),
+	}
+	env.Init()
+	if err := env.PushContext.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if clusters, listeners := scopeSize(env.PushContext, "small", nil); clusters != 1 || listeners != 1 {
+		t.Errorf("small namespace: got %d clusters and %d listeners, want 1 and 1", clusters, listeners)
+	}
+	if clusters, listeners := scopeSize(env.PushContext, "large", nil); clusters != 4 || listeners != 3 {
+		t.Errorf("large namespace: got %d clusters and %d listeners, want 4 and 3", clusters, listeners)
+	}
+}
@@ -48,6 +48,7 @@ var (
 		annotation.SidecarTrafficExcludeOutboundPorts.Name:        ValidateExcludeOutboundPorts,
 		annotation.PrometheusMergeMetrics.Name:                    validateBool,
 		annotation.ProxyConfig.Name:                               validateProxyConfig,
+		model.InboundPrincipalConcurrencyAnnotation:               validateInboundPrincipalConcurrency,
 	}
 )
 
@@ -59,6 +60,11 @@ func validateProxyConfig(value string) error {
 	return validation.ValidateMeshConfigProxyConfig(&config)
 }
 
+func validateInboundPrincipalConcurrency(value string) error {
+	_, err := model.ParseInboundPrincipalConcurrency(value)
+	return err
+}
+
 func validateAnnotations(annotations map[string]string) (err error) {
 	for name, value := range annotations {
 		if v, ok := AnnotationValidation[name]; ok {
@@ -251,6 +251,7 @@ type InjectionParameters struct {
 	typeMeta            metav1.TypeMeta
 	templates           Templates
 	defaultTemplate     []string
+	windowsTemplate     []string
 	aliases             map[string][]string
 	meshConfig          *meshconfig.MeshConfig
 	proxyConfig         *meshconfig.ProxyConfig
@@ -788,6 +789,12 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 			proxyConfig = *generatedProxyConfig
 		}
 	}
+	if wh.Config.ScopeResources != nil && wh.env.PushContext != nil {
+		clusters, listeners := scopeSize(wh.env.PushContext, pod.Namespace, pod.Labels)
+		if err := wh.Config.ScopeResources.apply(&pod, clusters, listeners); err != nil {
+			log.Warnf("failed to size sidecar resources for %s/%s: %v", pod.Namespace, podName, err)
+		}
+	}
 	deploy, typeMeta := kube.GetDeployMetaFromPod(&pod)
 	params := InjectionParameters{
 		pod:                 &pod,
@@ -795,6 +802,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		typeMeta:            typeMeta,
 		templates:           wh.Config.Templates,
 		defaultTemplate:     wh.Config.DefaultTemplates,
+		windowsTemplate:     wh.Config.WindowsTemplates,
 		aliases:             wh.Config.Aliases,
 		meshConfig:          wh.meshConfig,
 		proxyConfig:         &proxyConfig,
@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// loadgen synthesizes a mesh of the scale given by its flags and prints the measure of the generation of its
+// config, for example:
+//
+//	loadgen --namespaces 50 --services 2000 --proxies 500 --endpoints 1:70,10:25,100:5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/loadgen"
+)
+
+func main() {
+	spec := loadgen.Spec{}
+	flag.IntVar(&spec.Namespaces, "namespaces", 1, "Number of namespaces the services and proxies are spread across.")
+	flag.IntVar(&spec.Services, "services", 100, "Number of services.")
+	flag.IntVar(&spec.Proxies, "proxies", 10, "Number of sidecars the config is generated for.")
+	flag.Var(&spec.EndpointsPerService, "endpoints",
+		"Distribution of the number of endpoints of the services, as value:weight pairs such as 1:70,10:25,100:5.")
+	flag.Var(&spec.PortsPerService, "ports", "Distribution of the number of HTTP ports of the services, as value:weight pairs.")
+	flag.BoolVar(&spec.SidecarScoping, "sidecar-scoping", false,
+		"Add a Sidecar to each namespace, restricting the egress of its proxies to the services of the namespace.")
+	flag.Parse()
+
+	var result *loadgen.Result
+	if err := test.Wrap(func(t test.Failer) {
+		result = loadgen.Run(t, spec)
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to measure the mesh: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(result.String())
+}
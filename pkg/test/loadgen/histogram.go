@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bucket is a value of a Histogram, and its relative weight.
+type Bucket struct {
+	Value  int
+	Weight int
+}
+
+// Histogram is the distribution of a property of the synthesized mesh, such as the number of endpoints of the
+// services. It implements flag.Value, so that benchmarks can take it from the command line in the format of
+// ParseHistogram.
+type Histogram []Bucket
+
+// ParseHistogram parses a comma separated list of value:weight, for example "1:70,10:25,100:5" for 70% of the
+// services with an endpoint, 25% with 10 and 5% with 100. The weight can be omitted for a single value.
+func ParseHistogram(s string) (Histogram, error) {
+	var h Histogram
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		value, err := strconv.Atoi(parts[0])
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid value %q, expected a non negative number", parts[0])
+		}
+		weight := 1
+		if len(parts) == 2 {
+			weight, err = strconv.Atoi(parts[1])
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight %q, expected a positive number", parts[1])
+			}
+		}
+		h = append(h, Bucket{Value: value, Weight: weight})
+	}
+	if len(h) == 0 {
+		return nil, fmt.Errorf("empty histogram")
+	}
+	return h, nil
+}
+
+// String returns the histogram in the format of ParseHistogram.
+func (h Histogram) String() string {
+	entries := make([]string, 0, len(h))
+	for _, b := range h {
+		entries = append(entries, fmt.Sprintf("%d:%d", b.Value, b.Weight))
+	}
+	return strings.Join(entries, ",")
+}
+
+// Set parses the histogram, to implement flag.Value.
+func (h *Histogram) Set(s string) error {
+	parsed, err := ParseHistogram(s)
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Distribute returns the values of n items following the histogram. The number of items of each bucket is
+// proportional to its weight, with the rounding errors given to the buckets with the largest remainders, so that the
+// result is deterministic. The values are grouped by bucket, in the order of the histogram. An empty histogram
+// distributes the value 1.
+func (h Histogram) Distribute(n int) []int {
+	if len(h) == 0 {
+		h = Histogram{{Value: 1, Weight: 1}}
+	}
+	total := 0
+	for _, b := range h {
+		total += b.Weight
+	}
+	counts := make([]int, len(h))
+	remainders := make([]int, len(h))
+	assigned := 0
+	for i, b := range h {
+		counts[i] = n * b.Weight / total
+		remainders[i] = n * b.Weight % total
+		assigned += counts[i]
+	}
+	order := make([]int, len(h))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; assigned < n; i++ {
+		counts[order[i%len(order)]]++
+		assigned++
+	}
+	values := make([]int, 0, n)
+	for i, b := range h {
+		for c := 0; c < counts[i]; c++ {
+			values = append(values, b.Value)
+		}
+	}
+	return values
+}
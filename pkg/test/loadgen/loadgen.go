@@ -0,0 +1,338 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loadgen synthesizes meshes of a target scale and measures the generation of their config by istiod, so
+// that operators can validate the sizing of istiod before reaching that scale in production. It is driven by the
+// benchmarks and tests of CI pipelines, or run with the loadgen command.
+package loadgen
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test"
+)
+
+// Spec is the scale of a synthesized mesh.
+type Spec struct {
+	// Namespaces the services and proxies are spread across. Defaults to 1.
+	Namespaces int
+	// Services is the number of services, synthesized as ServiceEntries with static endpoints.
+	Services int
+	// Proxies is the number of sidecars the config is generated for.
+	Proxies int
+	// EndpointsPerService is the distribution of the number of endpoints of the services. Defaults to 1.
+	EndpointsPerService Histogram
+	// PortsPerService is the distribution of the number of HTTP ports of the services. Defaults to 1.
+	PortsPerService Histogram
+	// SidecarScoping adds a Sidecar to each namespace, restricting the egress of its proxies to the services of the
+	// namespace.
+	SidecarScoping bool
+}
+
+func (s Spec) namespaces() int {
+	if s.Namespaces <= 0 {
+		return 1
+	}
+	return s.Namespaces
+}
+
+func namespace(i int) string {
+	return fmt.Sprintf("ns-%d", i)
+}
+
+// Mesh is a synthesized mesh.
+type Mesh struct {
+	Configs []config.Config
+	Proxies []*model.Proxy
+	// Endpoints is the total number of endpoints of the services.
+	Endpoints int
+}
+
+// Generate synthesizes the mesh of the spec. The services and proxies are assigned to the namespaces round robin.
+func Generate(spec Spec) *Mesh {
+	m := &Mesh{}
+	endpoints := spec.EndpointsPerService.Distribute(spec.Services)
+	ports := spec.PortsPerService.Distribute(spec.Services)
+	for i := 0; i < spec.Services; i++ {
+		se := &networking.ServiceEntry{
+			Hosts:      []string{fmt.Sprintf("svc-%d.%s.example.com", i, namespace(i%spec.namespaces()))},
+			Resolution: networking.ServiceEntry_STATIC,
+			Location:   networking.ServiceEntry_MESH_INTERNAL,
+		}
+		for p := 0; p < ports[i]; p++ {
+			se.Ports = append(se.Ports, &networking.Port{Number: uint32(8080 + p), Name: fmt.Sprintf("http-%d", p), Protocol: "HTTP"})
+		}
+		for e := 0; e < endpoints[i]; e++ {
+			se.Endpoints = append(se.Endpoints, &networking.WorkloadEntry{Address: address(20, m.Endpoints)})
+			m.Endpoints++
+		}
+		m.Configs = append(m.Configs, config.Config{
+			Meta: config.Meta{
+				GroupVersionKind:  gvk.ServiceEntry,
+				Name:              fmt.Sprintf("svc-%d", i),
+				Namespace:         namespace(i % spec.namespaces()),
+				CreationTimestamp: time.Unix(int64(i), 0),
+			},
+			Spec: se,
+		})
+	}
+	if spec.SidecarScoping {
+		for n := 0; n < spec.namespaces(); n++ {
+			m.Configs = append(m.Configs, config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.Sidecar, Name: "default", Namespace: namespace(n)},
+				Spec: &networking.Sidecar{Egress: []*networking.IstioEgressListener{{Hosts: []string{"./*", "istio-system/*"}}}},
+			})
+		}
+	}
+	for i := 0; i < spec.Proxies; i++ {
+		ns := namespace(i % spec.namespaces())
+		m.Proxies = append(m.Proxies, &model.Proxy{
+			Type:            model.SidecarProxy,
+			IPAddresses:     []string{address(10, i)},
+			ID:              fmt.Sprintf("proxy-%d.%s", i, ns),
+			ConfigNamespace: ns,
+			DNSDomain:       ns + ".svc.cluster.local",
+			Metadata: &model.NodeMetadata{
+				Namespace: ns,
+				Labels:    map[string]string{"app": fmt.Sprintf("proxy-%d", i)},
+			},
+		})
+	}
+	return m
+}
+
+// address returns the i-th IPv4 address of the /8 network.
+func address(network, i int) string {
+	return fmt.Sprintf("%d.%d.%d.%d", network, (i>>16)&0xff, (i>>8)&0xff, i&0xff)
+}
+
+// Latencies summarizes the distribution of durations.
+type Latencies struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+func newLatencies(durations []time.Duration) Latencies {
+	if len(durations) == 0 {
+		return Latencies{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p int) time.Duration {
+		return durations[(len(durations)-1)*p/100]
+	}
+	return Latencies{P50: percentile(50), P90: percentile(90), P99: percentile(99), Max: durations[len(durations)-1]}
+}
+
+func (l Latencies) String() string {
+	return fmt.Sprintf("p50=%v p90=%v p99=%v max=%v", l.P50, l.P90, l.P99, l.Max)
+}
+
+// Result is the measure of the config generation of a synthesized mesh.
+type Result struct {
+	Spec      Spec
+	Configs   int
+	Endpoints int
+	// InitPushContext is the duration of the initialization of the push context, done on every full push.
+	InitPushContext time.Duration
+	// Push is the distribution of the durations of the generation of the full config of a proxy: its clusters,
+	// listeners, routes and endpoints. As in a full push, the cache of the generated config is cleared before the
+	// first proxy, so the proxies sharing the services of a previous one reuse its cached config.
+	Push Latencies
+	// ColdPush is the distribution of the durations of the generation of the full config of a proxy with an empty
+	// cache, as for the first proxy of each Sidecar scope after a config change.
+	ColdPush Latencies
+	// PushTotal is the duration of the generation of the config of all the proxies, as done sequentially.
+	PushTotal time.Duration
+	// BytesPerProxy is the average size of the config of a proxy.
+	BytesPerProxy int
+	// HeapBytes is the growth of the live heap once the mesh is loaded and its push context initialized.
+	HeapBytes uint64
+}
+
+func (r *Result) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "namespaces=%d services=%d endpoints=%d proxies=%d configs=%d\n",
+		r.Spec.namespaces(), r.Spec.Services, r.Endpoints, r.Spec.Proxies, r.Configs)
+	fmt.Fprintf(sb, "init push context: %v\n", r.InitPushContext)
+	fmt.Fprintf(sb, "push per proxy: %v\n", r.Push)
+	fmt.Fprintf(sb, "push per proxy with an empty cache: %v\n", r.ColdPush)
+	fmt.Fprintf(sb, "push of all proxies: %v\n", r.PushTotal)
+	fmt.Fprintf(sb, "config per proxy: %d bytes\n", r.BytesPerProxy)
+	fmt.Fprintf(sb, "heap: %d MB\n", r.HeapBytes/(1024*1024))
+	return sb.String()
+}
+
+// Server is a discovery server loaded with a synthesized mesh.
+type Server struct {
+	*xds.FakeDiscoveryServer
+	Mesh *Mesh
+}
+
+// NewServer synthesizes the mesh of the spec, and loads it in a discovery server whose push context is initialized.
+func NewServer(t test.Failer, spec Spec) *Server {
+	m := Generate(spec)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{Configs: m.Configs})
+	for _, p := range m.Proxies {
+		s.SetupProxy(p)
+	}
+	return &Server{FakeDiscoveryServer: s, Mesh: m}
+}
+
+// Push generates the full config of the proxy, and returns its size.
+func (s *Server) Push(t test.Failer, proxy *model.Proxy) int {
+	push := s.PushContext()
+	req := &model.PushRequest{Full: true, Push: push}
+	generate := func(typeURL string, names []string) model.Resources {
+		var w *model.WatchedResource
+		if names != nil {
+			w = &model.WatchedResource{TypeUrl: typeURL, ResourceNames: names}
+		}
+		res, _, err := s.Discovery.Generators[typeURL].Generate(proxy, push, w, req)
+		if err != nil {
+			t.Fatalf("failed to generate %s for %s: %v", typeURL, proxy.ID, err)
+		}
+		return res
+	}
+	size := func(res model.Resources) int {
+		n := 0
+		for _, r := range res {
+			n += len(r.GetResource().GetValue())
+		}
+		return n
+	}
+
+	clusters := generate(v3.ClusterType, nil)
+	listeners := generate(v3.ListenerType, nil)
+	var cl []*cluster.Cluster
+	for _, r := range clusters {
+		c := &cluster.Cluster{}
+		if err := r.GetResource().UnmarshalTo(c); err != nil {
+			t.Fatal(err)
+		}
+		cl = append(cl, c)
+	}
+	var ll []*listener.Listener
+	for _, r := range listeners {
+		l := &listener.Listener{}
+		if err := r.GetResource().UnmarshalTo(l); err != nil {
+			t.Fatal(err)
+		}
+		ll = append(ll, l)
+	}
+	routes := generate(v3.RouteType, xdstest.ExtractRoutesFromListeners(ll))
+	endpoints := generate(v3.EndpointType, xdstest.ExtractEdsClusterNames(cl))
+	return size(clusters) + size(listeners) + size(routes) + size(endpoints)
+}
+
+// PushAll generates the full config of all the proxies sequentially, and returns the duration of each generation and
+// the total size of the configs. The cache of the generated config is cleared first, as in a full push, or before
+// each proxy if coldCache is set.
+func (s *Server) PushAll(t test.Failer, coldCache bool) ([]time.Duration, int) {
+	durations := make([]time.Duration, 0, len(s.Mesh.Proxies))
+	bytes := 0
+	s.Discovery.Cache.ClearAll()
+	for _, p := range s.Mesh.Proxies {
+		if coldCache {
+			s.Discovery.Cache.ClearAll()
+		}
+		start := time.Now()
+		bytes += s.Push(t, p)
+		durations = append(durations, time.Since(start))
+	}
+	return durations, bytes
+}
+
+// Run synthesizes the mesh of the spec and measures the generation of its config.
+func Run(t test.Failer, spec Spec) *Result {
+	before := heapAlloc()
+	s := NewServer(t, spec)
+
+	env := s.Env()
+	push := model.NewPushContext()
+	start := time.Now()
+	if err := push.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	initPushContext := time.Since(start)
+	heap := heapAlloc()
+
+	cold, _ := s.PushAll(t, true)
+	start = time.Now()
+	durations, bytes := s.PushAll(t, false)
+	r := &Result{
+		Spec:            spec,
+		Configs:         len(s.Mesh.Configs),
+		Endpoints:       s.Mesh.Endpoints,
+		InitPushContext: initPushContext,
+		Push:            newLatencies(durations),
+		ColdPush:        newLatencies(cold),
+		PushTotal:       time.Since(start),
+	}
+	if heap > before {
+		r.HeapBytes = heap - before
+	}
+	if len(durations) > 0 {
+		r.BytesPerProxy = bytes / len(durations)
+	}
+	// Keep the push context alive until the heap is measured.
+	runtime.KeepAlive(push)
+	return r
+}
+
+// Benchmark measures the generation of the config of all the proxies of the synthesized mesh of the spec, as in a
+// full push, and reports the latencies of the push of a proxy and the size of its config as custom metrics.
+func Benchmark(b *testing.B, spec Spec) {
+	s := NewServer(b, spec)
+	if len(s.Mesh.Proxies) == 0 {
+		b.Fatal("the spec has no proxy")
+	}
+	b.ResetTimer()
+	var durations []time.Duration
+	bytes := 0
+	for n := 0; n < b.N; n++ {
+		d, size := s.PushAll(b, false)
+		durations = append(durations, d...)
+		bytes = size
+	}
+	b.StopTimer()
+	l := newLatencies(durations)
+	b.ReportMetric(float64(l.P50.Microseconds())/1000, "p50-ms/proxy")
+	b.ReportMetric(float64(l.P99.Microseconds())/1000, "p99-ms/proxy")
+	b.ReportMetric(float64(bytes)/float64(len(s.Mesh.Proxies))/1000, "kb/proxy")
+}
+
+// heapAlloc returns the size of the live heap, after a garbage collection.
+func heapAlloc() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
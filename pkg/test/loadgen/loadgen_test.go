@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"reflect"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestParseHistogram(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Histogram
+		wantErr bool
+	}{
+		{in: "1:70, 10:25,100:5", want: Histogram{{1, 70}, {10, 25}, {100, 5}}},
+		{in: "3", want: Histogram{{3, 1}}},
+		{in: "", wantErr: true},
+		{in: "-1:2", wantErr: true},
+		{in: "1:0", wantErr: true},
+		{in: "a:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseHistogram(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	tests := []struct {
+		name string
+		h    Histogram
+		n    int
+		want []int
+	}{
+		{name: "default", n: 3, want: []int{1, 1, 1}},
+		{name: "exact", h: Histogram{{1, 3}, {10, 1}}, n: 4, want: []int{1, 1, 1, 10}},
+		{name: "remainders", h: Histogram{{1, 1}, {2, 1}, {3, 1}}, n: 4, want: []int{1, 1, 2, 3}},
+		{name: "fewer items than buckets", h: Histogram{{1, 1}, {2, 2}}, n: 1, want: []int{2}},
+		{name: "none", h: Histogram{{1, 1}}, n: 0, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.h.Distribute(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	m := Generate(Spec{
+		Namespaces:          2,
+		Services:            4,
+		Proxies:             3,
+		EndpointsPerService: Histogram{{1, 1}, {5, 1}},
+		PortsPerService:     Histogram{{2, 1}},
+		SidecarScoping:      true,
+	})
+	if m.Endpoints != 12 {
+		t.Fatalf("got %d endpoints, want 12", m.Endpoints)
+	}
+	sidecars := 0
+	for _, c := range m.Configs {
+		switch c.GroupVersionKind {
+		case gvk.ServiceEntry:
+			if ports := len(c.Spec.(*networking.ServiceEntry).Ports); ports != 2 {
+				t.Fatalf("got %d ports for %s, want 2", ports, c.Name)
+			}
+		case gvk.Sidecar:
+			sidecars++
+		}
+	}
+	if sidecars != 2 {
+		t.Fatalf("got %d Sidecars, want one per namespace", sidecars)
+	}
+	if len(m.Proxies) != 3 || m.Proxies[1].ConfigNamespace != "ns-1" || m.Proxies[2].ConfigNamespace != "ns-0" {
+		t.Fatalf("expected the proxies to be spread across the namespaces, got %v", m.Proxies)
+	}
+}
+
+func TestRun(t *testing.T) {
+	r := Run(t, Spec{
+		Namespaces:          2,
+		Services:            10,
+		Proxies:             4,
+		EndpointsPerService: Histogram{{1, 4}, {10, 1}},
+	})
+	if r.Endpoints != 28 || r.Configs != 10 {
+		t.Fatalf("unexpected mesh: %v", r)
+	}
+	if r.InitPushContext == 0 || r.Push.Max == 0 || r.ColdPush.Max == 0 || r.PushTotal < r.Push.Max {
+		t.Fatalf("unexpected latencies: %v", r)
+	}
+	if r.BytesPerProxy == 0 {
+		t.Fatalf("expected the config of the proxies to be measured: %v", r)
+	}
+}
+
+func BenchmarkPush(b *testing.B) {
+	Benchmark(b, Spec{
+		Namespaces:          10,
+		Services:            100,
+		Proxies:             10,
+		EndpointsPerService: Histogram{{1, 70}, {10, 25}, {100, 5}},
+		PortsPerService:     Histogram{{1, 80}, {2, 20}},
+	})
+}
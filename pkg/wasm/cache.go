@@ -17,6 +17,7 @@ package wasm
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -42,6 +43,29 @@ const (
 	DefaultWasmModuleExpiry = 24 * time.Hour
 )
 
+// Options contains the configuration of the Wasm module cache.
+type Options struct {
+	// PurgeInterval is the interval for periodic stale Wasm module clean up.
+	PurgeInterval time.Duration
+	// ModuleExpiry is the duration for least recently touched Wasm module to become stale.
+	ModuleExpiry time.Duration
+	// InsecureRegistries are the registries Wasm OCI images can be pulled from without TLS verification.
+	InsecureRegistries []string
+	// PublicKey, if set, is used to verify the cosign signatures of Wasm OCI images.
+	PublicKey crypto.PublicKey
+	// RequireSignature refuses to load Wasm modules whose signature cannot be verified, including all modules
+	// downloaded over HTTP.
+	RequireSignature bool
+}
+
+// DefaultOptions returns the default Wasm module cache options.
+func DefaultOptions() Options {
+	return Options{
+		PurgeInterval: DefaultWasmModulePurgeInterval,
+		ModuleExpiry:  DefaultWasmModuleExpiry,
+	}
+}
+
 // Cache models a Wasm module cache.
 type Cache interface {
 	Get(url, checksum string, timeout time.Duration) (string, error)
@@ -49,6 +73,8 @@ type Cache interface {
 }
 
 // LocalFileCache for downloaded Wasm modules. Currently it stores the Wasm module as local file.
+// Module files are content addressed by their sha256 checksum, so the same file is shared by all the
+// cache entries of a module, for example when a plugin is updated to a new URL serving the same module.
 type LocalFileCache struct {
 	// Map from Wasm module checksum to cache entry.
 	modules map[cacheKey]cacheEntry
@@ -67,6 +93,9 @@ type LocalFileCache struct {
 	wasmModuleExpiry   time.Duration
 	insecureRegistries sets.Set
 
+	publicKey        crypto.PublicKey
+	requireSignature bool
+
 	// stopChan currently is only used by test
 	stopChan chan struct{}
 }
@@ -88,15 +117,17 @@ type cacheEntry struct {
 }
 
 // NewLocalFileCache create a new Wasm module cache which downloads and stores Wasm module files locally.
-func NewLocalFileCache(dir string, purgeInterval, moduleExpiry time.Duration, insecureRegistries []string) *LocalFileCache {
+func NewLocalFileCache(dir string, options Options) *LocalFileCache {
 	cache := &LocalFileCache{
 		httpFetcher:        NewHTTPFetcher(),
 		modules:            make(map[cacheKey]cacheEntry),
 		dir:                dir,
-		purgeInterval:      purgeInterval,
-		wasmModuleExpiry:   moduleExpiry,
+		purgeInterval:      options.PurgeInterval,
+		wasmModuleExpiry:   options.ModuleExpiry,
 		stopChan:           make(chan struct{}),
-		insecureRegistries: sets.NewSet(insecureRegistries...),
+		insecureRegistries: sets.NewSet(options.InsecureRegistries...),
+		publicKey:          options.PublicKey,
+		requireSignature:   options.RequireSignature,
 	}
 	go func() {
 		cache.purge()
@@ -129,6 +160,10 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 	var dChecksum string
 	switch u.Scheme {
 	case "http", "https":
+		if c.requireSignature {
+			wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			return "", fmt.Errorf("refusing to load Wasm module from %s: signatures are required, but only OCI images can be verified", downloadURL)
+		}
 		// Download the Wasm module with http fetcher.
 		b, err = c.httpFetcher.Fetch(downloadURL, timeout)
 		if err != nil {
@@ -152,8 +187,13 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 			insecure = true
 		}
 		// TODO: support imagePullSecret and pass it to ImageFetcherOption.
+		if c.requireSignature && c.publicKey == nil {
+			wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			return "", fmt.Errorf("refusing to load Wasm module from %s: signatures are required, but no public key is configured", downloadURL)
+		}
 		imgFetcherOps := ImageFetcherOption{
-			Insecure: insecure,
+			Insecure:  insecure,
+			PublicKey: c.publicKey,
 		}
 		wasmLog.Debugf("wasm oci fetch %s with options: %v", downloadURL, imgFetcherOps)
 		fetcher := NewImageFetcher(ctx, imgFetcherOps)
@@ -161,6 +201,8 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 		if err != nil {
 			if errors.Is(err, errWasmOCIImageDigestMismatch) {
 				wasmRemoteFetchCount.With(resultTag.Value(checksumMismatch)).Increment()
+			} else if errors.Is(err, errWasmOCIImageSignature) {
+				wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
 			} else {
 				wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
 			}
@@ -179,10 +221,14 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 
 	wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
 
-	key.checksum = dChecksum
 	f := filepath.Join(c.dir, fmt.Sprintf("%s.wasm", dChecksum))
-
-	if err := c.addEntry(key, b, f); err != nil {
+	keys := []cacheKey{{downloadURL: downloadURL, checksum: dChecksum}}
+	if checksum != "" && checksum != dChecksum {
+		// For OCI images the requested checksum is the image digest. Also cache the module under it, so that
+		// later lookups with the same digest do not pull the image again.
+		keys = append(keys, key)
+	}
+	if err := c.addEntry(keys, b, f); err != nil {
 		return "", err
 	}
 	return f, nil
@@ -193,31 +239,39 @@ func (c *LocalFileCache) Cleanup() {
 	close(c.stopChan)
 }
 
-func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte, f string) error {
+func (c *LocalFileCache) addEntry(keys []cacheKey, wasmModule []byte, f string) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	// Check if the module has already been added. If so, avoid writing the file again.
-	if ce, ok := c.modules[key]; ok {
-		// Update last touched time.
-		ce.last = time.Now()
-		return nil
-	}
-
-	// Materialize the Wasm module into a local file. Use checksum as name of the module.
-	if err := os.WriteFile(f, wasmModule, 0o644); err != nil {
-		return err
+	// The module file is shared by all entries with the same checksum. Avoid writing it again if another
+	// entry already materialized it.
+	if !c.inUse(f) {
+		// Materialize the Wasm module into a local file. Use checksum as name of the module.
+		if err := os.WriteFile(f, wasmModule, 0o644); err != nil {
+			return err
+		}
 	}
 
-	ce := cacheEntry{
-		modulePath: f,
-		last:       time.Now(),
+	for _, key := range keys {
+		c.modules[key] = cacheEntry{
+			modulePath: f,
+			last:       time.Now(),
+		}
 	}
-	c.modules[key] = ce
 	wasmCacheEntries.Record(float64(len(c.modules)))
 	return nil
 }
 
+// inUse returns true if the module file is referenced by any cache entry. Must be called with mux held.
+func (c *LocalFileCache) inUse(modulePath string) bool {
+	for _, ce := range c.modules {
+		if ce.modulePath == modulePath {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *LocalFileCache) getEntry(key cacheKey) string {
 	modulePath := ""
 	cacheHit := false
@@ -226,6 +280,7 @@ func (c *LocalFileCache) getEntry(key cacheKey) string {
 	if ce, ok := c.modules[key]; ok {
 		// Update last touched time.
 		ce.last = time.Now()
+		c.modules[key] = ce
 		modulePath = ce.modulePath
 		cacheHit = true
 	}
@@ -241,15 +296,23 @@ func (c *LocalFileCache) purge() {
 		select {
 		case <-ticker.C:
 			c.mux.Lock()
+			stale := sets.NewSet()
 			for k, m := range c.modules {
 				if m.expired(c.wasmModuleExpiry) {
-					// The module has not be touched for expiry duration, delete it from the map as well as the local dir.
-					if err := os.Remove(m.modulePath); err != nil {
-						wasmLog.Errorf("failed to purge Wasm module %v: %v", m.modulePath, err)
-					} else {
-						delete(c.modules, k)
-						wasmLog.Debugf("successfully removed stale Wasm module %v", m.modulePath)
-					}
+					// The module has not be touched for expiry duration, delete it from the map.
+					delete(c.modules, k)
+					stale.Insert(m.modulePath)
+				}
+			}
+			for modulePath := range stale {
+				// Module files are shared between entries, only remove the ones no longer referenced.
+				if c.inUse(modulePath) {
+					continue
+				}
+				if err := os.Remove(modulePath); err != nil {
+					wasmLog.Errorf("failed to purge Wasm module %v: %v", modulePath, err)
+				} else {
+					wasmLog.Debugf("successfully removed stale Wasm module %v", modulePath)
 				}
 			}
 			wasmCacheEntries.Record(float64(len(c.modules)))
@@ -232,7 +232,7 @@ func TestWasmCache(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			tmpDir := t.TempDir()
-			cache := NewLocalFileCache(tmpDir, c.purgeInterval, c.wasmModuleExpiry, nil)
+			cache := NewLocalFileCache(tmpDir, Options{PurgeInterval: c.purgeInterval, ModuleExpiry: c.wasmModuleExpiry})
 			defer close(cache.stopChan)
 			tsNumRequest = 0
 
@@ -344,7 +344,7 @@ func setupOCIRegistry(t *testing.T, host string) (wantBinaryCheckSum, dockerImag
 
 func TestWasmCacheMissChecksum(t *testing.T) {
 	tmpDir := t.TempDir()
-	cache := NewLocalFileCache(tmpDir, DefaultWasmModulePurgeInterval, DefaultWasmModuleExpiry, nil)
+	cache := NewLocalFileCache(tmpDir, DefaultOptions())
 	defer close(cache.stopChan)
 
 	gotNumRequest := 0
@@ -396,3 +396,52 @@ func TestWasmCacheMissChecksum(t *testing.T) {
 		t.Errorf("wasm download call got %v want %v", gotNumRequest, wantNumRequest)
 	}
 }
+
+func TestWasmCacheSharedModule(t *testing.T) {
+	tmpDir := t.TempDir()
+	cache := NewLocalFileCache(tmpDir, Options{PurgeInterval: time.Millisecond, ModuleExpiry: time.Hour})
+	defer close(cache.stopChan)
+
+	binary := append(wasmHeader, []byte("shared")...)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer ts.Close()
+	sum := fmt.Sprintf("%x", sha256.Sum256(binary))
+
+	// The same module served from two URLs, for example before and after a plugin update, shares one file.
+	oldPath, err := cache.Get(ts.URL+"/v1", sum, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPath, err := cache.Get(ts.URL+"/v2", sum, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldPath != newPath {
+		t.Fatalf("expected module file to be shared, got %v and %v", oldPath, newPath)
+	}
+
+	// Expire the entry of the old URL. The file must be kept for the new URL.
+	oldKey := cacheKey{downloadURL: ts.URL + "/v1", checksum: sum}
+	cache.mux.Lock()
+	ce := cache.modules[oldKey]
+	ce.last = time.Now().Add(-2 * time.Hour)
+	cache.modules[oldKey] = ce
+	cache.mux.Unlock()
+	for start := time.Now(); ; {
+		cache.mux.Lock()
+		_, found := cache.modules[oldKey]
+		cache.mux.Unlock()
+		if !found {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("stale Wasm module entry was not purged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(newPath); err != nil {
+		t.Fatalf("shared Wasm module file was removed: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -42,7 +43,9 @@ var errWasmOCIImageDigestMismatch = errors.New("fetched image's digest does not
 type ImageFetcherOption struct {
 	Username string
 	Password string
-	// TODO(mathetake) Add signature verification stuff.
+	// PublicKey, if set, is used to verify the cosign signature of the image. Images without a valid
+	// signature are rejected.
+	PublicKey crypto.PublicKey
 
 	Insecure bool
 }
@@ -53,6 +56,7 @@ func (o *ImageFetcherOption) useDefaultKeyChain() bool {
 
 type ImageFetcher struct {
 	fetchOpts []remote.Option
+	publicKey crypto.PublicKey
 }
 
 func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher {
@@ -76,6 +80,7 @@ func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher
 
 	return &ImageFetcher{
 		fetchOpts: append(fetchOpts, remote.WithContext(ctx)),
+		publicKey: opt.PublicKey,
 	}
 }
 
@@ -98,6 +103,12 @@ func (o *ImageFetcher) Fetch(url, expManifestDigest string) ([]byte, error) {
 		return nil, fmt.Errorf("%w: got %s, but want %s", errWasmOCIImageDigestMismatch, d.Hex, expManifestDigest)
 	}
 
+	if o.publicKey != nil {
+		if err := verifyImageSignature(ref, d, o.publicKey, o.fetchOpts...); err != nil {
+			return nil, err
+		}
+	}
+
 	manifest, err := img.Manifest()
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve manifest: %v", err)
@@ -22,6 +22,7 @@ const (
 	fetchSuccess     = "success"
 	downloadFailure  = "download_failure"
 	checksumMismatch = "checksum_mismatched"
+	signatureFailure = "signature_verification_failure"
 
 	// For Wasm conversion metric.
 	conversionSuccess   = "success"
@@ -48,7 +49,7 @@ var (
 
 	wasmRemoteFetchCount = monitoring.NewSum(
 		"wasm_remote_fetch_count",
-		"number of Wasm remote fetches and results, including success, download failure, and checksum mismatch.",
+		"number of Wasm remote fetches and results, including success, download failure, checksum mismatch, and signature verification failure.",
 		monitoring.WithLabels(resultTag),
 	)
 
@@ -0,0 +1,162 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// This file implements the verification of cosign signatures of Wasm OCI images.
+// Cosign stores the signatures of an image as the layers of an image tagged "sha256-<image digest>.sig" in the same
+// repository. Each layer is a "simple signing" payload referencing the image digest, and the signature of that
+// payload is stored in the layer annotations.
+// See https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md.
+
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSignatureTagSuffix  = ".sig"
+)
+
+var errWasmOCIImageSignature = errors.New("could not verify image signature")
+
+// simpleSigningPayload is the part of the cosign simple signing payload needed for verification.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// LoadPublicKey reads a PEM encoded public key used to verify the signatures of Wasm OCI images.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key %s: %v", path, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key %s: no PEM data found", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// signatureReference returns the reference of the cosign signature image of the image with the given digest.
+func signatureReference(ref name.Reference, digest v1.Hash) name.Tag {
+	return ref.Context().Tag(fmt.Sprintf("%s-%s%s", digest.Algorithm, digest.Hex, cosignSignatureTagSuffix))
+}
+
+// verifyImageSignature succeeds if at least one of the cosign signatures of the image with the given digest
+// is valid for the public key.
+func verifyImageSignature(ref name.Reference, digest v1.Hash, key crypto.PublicKey, opts ...remote.Option) error {
+	sigRef := signatureReference(ref, digest)
+	sigImg, err := remote.Image(sigRef, opts...)
+	if err != nil {
+		return fmt.Errorf("%w: could not fetch signatures %s: %v", errWasmOCIImageSignature, sigRef, err)
+	}
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return fmt.Errorf("%w: could not retrieve signature manifest: %v", errWasmOCIImageSignature, err)
+	}
+	layers, err := sigImg.Layers()
+	if err != nil {
+		return fmt.Errorf("%w: could not retrieve signature layers: %v", errWasmOCIImageSignature, err)
+	}
+	if len(layers) != len(manifest.Layers) {
+		return fmt.Errorf("%w: signature manifest has %d layers, but image has %d", errWasmOCIImageSignature, len(manifest.Layers), len(layers))
+	}
+
+	var lastErr error = fmt.Errorf("no signatures found")
+	for i, l := range layers {
+		sig, ok := manifest.Layers[i].Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		if lastErr = verifySignatureLayer(l, sig, digest, key); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v", errWasmOCIImageSignature, lastErr)
+}
+
+func verifySignatureLayer(l v1.Layer, sig string, digest v1.Hash, key crypto.PublicKey) error {
+	rc, err := l.Compressed()
+	if err != nil {
+		return fmt.Errorf("could not read signature payload: %v", err)
+	}
+	defer rc.Close()
+	payload, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("could not read signature payload: %v", err)
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("could not decode signature: %v", err)
+	}
+	if err := verifySignature(key, payload, rawSig); err != nil {
+		return err
+	}
+
+	// The signature is valid, make sure it is for this image.
+	var p simpleSigningPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("could not parse signature payload: %v", err)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest.String() {
+		return fmt.Errorf("signature is for image %s, but want %s", p.Critical.Image.DockerManifestDigest, digest)
+	}
+	return nil
+}
+
+func verifySignature(key crypto.PublicKey, payload, sig []byte) error {
+	h := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, h[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig); err != nil {
+			return fmt.Errorf("invalid signature: %v", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// signImage pushes a cosign signature for the image with the given digest. The signed payload references
+// signedDigest, which is the digest of the image itself for a genuine signature.
+func signImage(t *testing.T, ref string, digest, signedDigest v1.Hash, key *ecdsa.PrivateKey) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},`+
+		`"type":"cosign container image signature"},"optional":null}`, ref, signedDigest.String()))
+	h := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+		Annotations: map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crane.Push(img, signatureReference(r, digest).String()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pushWasmImage(t *testing.T, ref string, binary []byte) v1.Hash {
+	t.Helper()
+	l, err := newMockLayer(types.DockerLayer, map[string][]byte{"plugin.wasm": binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crane.Push(img, ref); err != nil {
+		t.Fatal(err)
+	}
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWasmCacheSignature(t *testing.T) {
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := append(wasmHeader, []byte("signed plugin")...)
+	signedRef := fmt.Sprintf("%s/test/signed:v1", u.Host)
+	signedDigest := pushWasmImage(t, signedRef, binary)
+	signImage(t, signedRef, signedDigest, signedDigest, key)
+
+	// A valid signature of another image must not be accepted for this one.
+	mismatchRef := fmt.Sprintf("%s/test/mismatch:v1", u.Host)
+	mismatchDigest := pushWasmImage(t, mismatchRef, append(wasmHeader, []byte("other plugin")...))
+	signImage(t, mismatchRef, mismatchDigest, signedDigest, key)
+
+	unsignedRef := fmt.Sprintf("%s/test/unsigned:v1", u.Host)
+	pushWasmImage(t, unsignedRef, binary)
+
+	cases := []struct {
+		name               string
+		url                string
+		options            Options
+		wantErrorMsgPrefix string
+	}{
+		{
+			name:    "signed image",
+			url:     "oci://" + signedRef,
+			options: Options{PublicKey: key.Public()},
+		},
+		{
+			name:    "unsigned image without verification",
+			url:     "oci://" + unsignedRef,
+			options: Options{},
+		},
+		{
+			name:               "unsigned image",
+			url:                "oci://" + unsignedRef,
+			options:            Options{PublicKey: key.Public()},
+			wantErrorMsgPrefix: "could not fetch Wasm OCI image: could not verify image signature: could not fetch signatures",
+		},
+		{
+			name:               "signed with another key",
+			url:                "oci://" + signedRef,
+			options:            Options{PublicKey: otherKey.Public()},
+			wantErrorMsgPrefix: "could not fetch Wasm OCI image: could not verify image signature: invalid signature",
+		},
+		{
+			name:               "signature of another image",
+			url:                "oci://" + mismatchRef,
+			options:            Options{PublicKey: key.Public()},
+			wantErrorMsgPrefix: "could not fetch Wasm OCI image: could not verify image signature: signature is for image",
+		},
+		{
+			name:               "required without public key",
+			url:                "oci://" + signedRef,
+			options:            Options{RequireSignature: true},
+			wantErrorMsgPrefix: "refusing to load Wasm module",
+		},
+		{
+			name:               "required for http",
+			url:                s.URL + "/plugin.wasm",
+			options:            Options{PublicKey: key.Public(), RequireSignature: true},
+			wantErrorMsgPrefix: "refusing to load Wasm module",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.options.PurgeInterval = DefaultWasmModulePurgeInterval
+			c.options.ModuleExpiry = DefaultWasmModuleExpiry
+			cache := NewLocalFileCache(t.TempDir(), c.options)
+			defer close(cache.stopChan)
+
+			_, err := cache.Get(c.url, "", 10*time.Second)
+			if c.wantErrorMsgPrefix == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), c.wantErrorMsgPrefix) {
+				t.Fatalf("got error %v, want error prefix %q", err, c.wantErrorMsgPrefix)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPublicKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(got) {
+		t.Fatalf("loaded public key does not match")
+	}
+
+	invalid := filepath.Join(dir, "invalid.pub")
+	if err := os.WriteFile(invalid, []byte("not a key"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPublicKey(invalid); err == nil {
+		t.Fatalf("expected error for invalid public key")
+	}
+}
@@ -65,6 +65,7 @@ var versionMap = map[string]*resourceNames{
 			"debug/inject",
 			"debug/mesh",
 			"debug/networkz",
+			"debug/pushcontext",
 		},
 		proxyDebugURLs: []string{
 			"certs",
@@ -25,11 +25,11 @@ import (
 	"istio.io/istio/tools/istio-iptables/pkg/constants"
 )
 
-// configureTProxyRoutes configures ip firewall rules to enable TPROXY support.
+// configureTProxyRoutes configures ip firewall rules to enable TPROXY support, and the original_src filter.
 // See https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/original_src_filter
 func configureTProxyRoutes(cfg *config.Config) error {
-	if cfg.InboundPortsInclude != "" {
-		if cfg.InboundInterceptionMode == constants.TPROXY {
+	if cfg.InboundPortsInclude != "" || cfg.OriginalSrc {
+		if cfg.InboundInterceptionMode == constants.TPROXY || cfg.OriginalSrc {
 			link, err := netlink.LinkByName("lo")
 			if err != nil {
 				return fmt.Errorf("failed to find 'lo' link: %v", err)
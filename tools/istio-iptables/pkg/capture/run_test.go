@@ -234,6 +234,12 @@ func TestIptables(t *testing.T) {
 				cfg.DropInvalid = true
 			},
 		},
+		{
+			"original-src",
+			func(cfg *config.Config) {
+				cfg.OriginalSrc = true
+			},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
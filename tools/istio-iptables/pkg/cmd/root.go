@@ -42,6 +42,11 @@ var (
 	// InvalidDropByIptables is the flag to enable invalid drop iptables rule to drop the out of window packets
 	InvalidDropByIptables = env.RegisterBoolVar("INVALID_DROP", false,
 		"If set to true, enable the invalid drop iptables rule, default false will cause iptables reset out of window packets")
+	// OriginalSrcByIptables is the flag to route the responses of upstream connections bound to the downstream
+	// client IP back to Envoy.
+	OriginalSrcByIptables = env.RegisterBoolVar("ISTIO_ORIGINAL_SRC", false,
+		"If set to true, route the responses of the upstream connections Envoy binds to the IP of the downstream "+
+			"client back to Envoy, as required by the original_src filter of gateways")
 )
 
 var rootCmd = &cobra.Command{
@@ -125,6 +130,7 @@ func constructConfig() *config.Config {
 		RunValidation:           viper.GetBool(constants.RunValidation),
 		RedirectDNS:             viper.GetBool(constants.RedirectDNS),
 		DropInvalid:             viper.GetBool(constants.DropInvalid),
+		OriginalSrc:             viper.GetBool(constants.OriginalSrc),
 		CaptureAllDNS:           viper.GetBool(constants.CaptureAllDNS),
 		OutputPath:              viper.GetString(constants.OutputPath),
 		NetworkNamespace:        viper.GetString(constants.NetworkNamespace),
@@ -330,6 +336,11 @@ func bindFlags(cmd *cobra.Command, args []string) {
 	}
 	viper.SetDefault(constants.DropInvalid, InvalidDropByIptables)
 
+	if err := viper.BindPFlag(constants.OriginalSrc, cmd.Flags().Lookup(constants.OriginalSrc)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.OriginalSrc, OriginalSrcByIptables)
+
 	if err := viper.BindPFlag(constants.CaptureAllDNS, cmd.Flags().Lookup(constants.CaptureAllDNS)); err != nil {
 		handleError(err)
 	}
@@ -427,6 +438,10 @@ func bindCmdlineFlags(rootCmd *cobra.Command) {
 
 	rootCmd.Flags().Bool(constants.DropInvalid, InvalidDropByIptables.Get(), "Enable invalid drop in the iptables rules")
 
+	rootCmd.Flags().Bool(constants.OriginalSrc, OriginalSrcByIptables.Get(),
+		"Route the responses of upstream connections bound to the downstream client IP back to Envoy, "+
+			"using the inbound TPROXY mark and route table")
+
 	rootCmd.Flags().Bool(constants.CaptureAllDNS, false,
 		"Instead of only capturing DNS traffic to DNS server IP, capture all DNS traffic at port 53. This setting is only effective when redirect dns is enabled.")
 